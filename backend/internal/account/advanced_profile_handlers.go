@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
 	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
@@ -15,21 +16,14 @@ import (
 
 // CreateCertification handles POST /api/v1/account/certifications
 func (h *AccountHandlers) CreateCertification(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateCertificationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -49,18 +43,12 @@ func (h *AccountHandlers) CreateCertification(c *gin.Context) {
 
 // GetMyCertifications handles GET /api/v1/account/certifications
 func (h *AccountHandlers) GetMyCertifications(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	certs, err := h.advancedProfileSvc.GetUserCertifications(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -76,18 +64,12 @@ func (h *AccountHandlers) GetMyCertifications(c *gin.Context) {
 
 // UpdateCertification handles PATCH /api/v1/account/certifications/:id
 func (h *AccountHandlers) UpdateCertification(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	certIDStr := c.Param("id")
 	certID, err := uuid.Parse(certIDStr)
 	if err != nil {
@@ -96,8 +78,7 @@ func (h *AccountHandlers) UpdateCertification(c *gin.Context) {
 	}
 
 	var req models.UpdateCertificationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -115,18 +96,12 @@ func (h *AccountHandlers) UpdateCertification(c *gin.Context) {
 
 // DeleteCertification handles DELETE /api/v1/account/certifications/:id
 func (h *AccountHandlers) DeleteCertification(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	certIDStr := c.Param("id")
 	certID, err := uuid.Parse(certIDStr)
 	if err != nil {
@@ -150,21 +125,14 @@ func (h *AccountHandlers) DeleteCertification(c *gin.Context) {
 
 // CreateSkill handles POST /api/v1/account/skills
 func (h *AccountHandlers) CreateSkill(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateSkillRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -184,18 +152,12 @@ func (h *AccountHandlers) CreateSkill(c *gin.Context) {
 
 // GetMySkills handles GET /api/v1/account/skills
 func (h *AccountHandlers) GetMySkills(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	skills, err := h.advancedProfileSvc.GetUserSkills(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -211,18 +173,12 @@ func (h *AccountHandlers) GetMySkills(c *gin.Context) {
 
 // UpdateSkill handles PATCH /api/v1/account/skills/:id
 func (h *AccountHandlers) UpdateSkill(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	skillIDStr := c.Param("id")
 	skillID, err := uuid.Parse(skillIDStr)
 	if err != nil {
@@ -231,8 +187,7 @@ func (h *AccountHandlers) UpdateSkill(c *gin.Context) {
 	}
 
 	var req models.UpdateSkillRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -250,18 +205,12 @@ func (h *AccountHandlers) UpdateSkill(c *gin.Context) {
 
 // DeleteSkill handles DELETE /api/v1/account/skills/:id
 func (h *AccountHandlers) DeleteSkill(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	skillIDStr := c.Param("id")
 	skillID, err := uuid.Parse(skillIDStr)
 	if err != nil {
@@ -285,21 +234,14 @@ func (h *AccountHandlers) DeleteSkill(c *gin.Context) {
 
 // CreateLanguage handles POST /api/v1/account/languages
 func (h *AccountHandlers) CreateLanguage(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateLanguageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -319,18 +261,12 @@ func (h *AccountHandlers) CreateLanguage(c *gin.Context) {
 
 // GetMyLanguages handles GET /api/v1/account/languages
 func (h *AccountHandlers) GetMyLanguages(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	langs, err := h.advancedProfileSvc.GetUserLanguages(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -346,18 +282,12 @@ func (h *AccountHandlers) GetMyLanguages(c *gin.Context) {
 
 // UpdateLanguage handles PATCH /api/v1/account/languages/:id
 func (h *AccountHandlers) UpdateLanguage(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	langIDStr := c.Param("id")
 	langID, err := uuid.Parse(langIDStr)
 	if err != nil {
@@ -366,8 +296,7 @@ func (h *AccountHandlers) UpdateLanguage(c *gin.Context) {
 	}
 
 	var req models.UpdateLanguageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -385,18 +314,12 @@ func (h *AccountHandlers) UpdateLanguage(c *gin.Context) {
 
 // DeleteLanguage handles DELETE /api/v1/account/languages/:id
 func (h *AccountHandlers) DeleteLanguage(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	langIDStr := c.Param("id")
 	langID, err := uuid.Parse(langIDStr)
 	if err != nil {
@@ -420,21 +343,14 @@ func (h *AccountHandlers) DeleteLanguage(c *gin.Context) {
 
 // CreateVolunteering handles POST /api/v1/account/volunteering
 func (h *AccountHandlers) CreateVolunteering(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateVolunteeringRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -454,18 +370,12 @@ func (h *AccountHandlers) CreateVolunteering(c *gin.Context) {
 
 // GetMyVolunteering handles GET /api/v1/account/volunteering
 func (h *AccountHandlers) GetMyVolunteering(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	vols, err := h.advancedProfileSvc.GetUserVolunteering(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -481,18 +391,12 @@ func (h *AccountHandlers) GetMyVolunteering(c *gin.Context) {
 
 // UpdateVolunteering handles PATCH /api/v1/account/volunteering/:id
 func (h *AccountHandlers) UpdateVolunteering(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	volIDStr := c.Param("id")
 	volID, err := uuid.Parse(volIDStr)
 	if err != nil {
@@ -501,8 +405,7 @@ func (h *AccountHandlers) UpdateVolunteering(c *gin.Context) {
 	}
 
 	var req models.UpdateVolunteeringRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -520,18 +423,12 @@ func (h *AccountHandlers) UpdateVolunteering(c *gin.Context) {
 
 // DeleteVolunteering handles DELETE /api/v1/account/volunteering/:id
 func (h *AccountHandlers) DeleteVolunteering(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	volIDStr := c.Param("id")
 	volID, err := uuid.Parse(volIDStr)
 	if err != nil {
@@ -555,21 +452,14 @@ func (h *AccountHandlers) DeleteVolunteering(c *gin.Context) {
 
 // CreatePublication handles POST /api/v1/account/publications
 func (h *AccountHandlers) CreatePublication(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreatePublicationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -589,18 +479,12 @@ func (h *AccountHandlers) CreatePublication(c *gin.Context) {
 
 // GetMyPublications handles GET /api/v1/account/publications
 func (h *AccountHandlers) GetMyPublications(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	pubs, err := h.advancedProfileSvc.GetUserPublications(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -616,18 +500,12 @@ func (h *AccountHandlers) GetMyPublications(c *gin.Context) {
 
 // UpdatePublication handles PATCH /api/v1/account/publications/:id
 func (h *AccountHandlers) UpdatePublication(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	pubIDStr := c.Param("id")
 	pubID, err := uuid.Parse(pubIDStr)
 	if err != nil {
@@ -636,8 +514,7 @@ func (h *AccountHandlers) UpdatePublication(c *gin.Context) {
 	}
 
 	var req models.UpdatePublicationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -655,18 +532,12 @@ func (h *AccountHandlers) UpdatePublication(c *gin.Context) {
 
 // DeletePublication handles DELETE /api/v1/account/publications/:id
 func (h *AccountHandlers) DeletePublication(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	pubIDStr := c.Param("id")
 	pubID, err := uuid.Parse(pubIDStr)
 	if err != nil {
@@ -690,21 +561,14 @@ func (h *AccountHandlers) DeletePublication(c *gin.Context) {
 
 // CreateInterest handles POST /api/v1/account/interests
 func (h *AccountHandlers) CreateInterest(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateInterestRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -724,18 +588,12 @@ func (h *AccountHandlers) CreateInterest(c *gin.Context) {
 
 // GetMyInterests handles GET /api/v1/account/interests
 func (h *AccountHandlers) GetMyInterests(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	interests, err := h.advancedProfileSvc.GetUserInterests(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -751,18 +609,12 @@ func (h *AccountHandlers) GetMyInterests(c *gin.Context) {
 
 // UpdateInterest handles PATCH /api/v1/account/interests/:id
 func (h *AccountHandlers) UpdateInterest(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	interestIDStr := c.Param("id")
 	interestID, err := uuid.Parse(interestIDStr)
 	if err != nil {
@@ -771,8 +623,7 @@ func (h *AccountHandlers) UpdateInterest(c *gin.Context) {
 	}
 
 	var req models.UpdateInterestRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -790,18 +641,12 @@ func (h *AccountHandlers) UpdateInterest(c *gin.Context) {
 
 // DeleteInterest handles DELETE /api/v1/account/interests/:id
 func (h *AccountHandlers) DeleteInterest(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	interestIDStr := c.Param("id")
 	interestID, err := uuid.Parse(interestIDStr)
 	if err != nil {
@@ -825,21 +670,14 @@ func (h *AccountHandlers) DeleteInterest(c *gin.Context) {
 
 // CreateAchievement handles POST /api/v1/account/achievements
 func (h *AccountHandlers) CreateAchievement(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateAchievementRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -859,18 +697,12 @@ func (h *AccountHandlers) CreateAchievement(c *gin.Context) {
 
 // GetMyAchievements handles GET /api/v1/account/achievements
 func (h *AccountHandlers) GetMyAchievements(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	achievements, err := h.advancedProfileSvc.GetUserAchievements(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -886,18 +718,12 @@ func (h *AccountHandlers) GetMyAchievements(c *gin.Context) {
 
 // UpdateAchievement handles PATCH /api/v1/account/achievements/:id
 func (h *AccountHandlers) UpdateAchievement(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	achievementIDStr := c.Param("id")
 	achievementID, err := uuid.Parse(achievementIDStr)
 	if err != nil {
@@ -906,8 +732,7 @@ func (h *AccountHandlers) UpdateAchievement(c *gin.Context) {
 	}
 
 	var req models.UpdateAchievementRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -925,18 +750,12 @@ func (h *AccountHandlers) UpdateAchievement(c *gin.Context) {
 
 // DeleteAchievement handles DELETE /api/v1/account/achievements/:id
 func (h *AccountHandlers) DeleteAchievement(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	achievementIDStr := c.Param("id")
 	achievementID, err := uuid.Parse(achievementIDStr)
 	if err != nil {
@@ -989,8 +808,7 @@ func (h *AccountHandlers) SearchCompanies(c *gin.Context) {
 // CreateCompany handles POST /api/v1/account/companies
 func (h *AccountHandlers) CreateCompany(c *gin.Context) {
 	var req models.CreateCompanyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
 	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
@@ -26,21 +27,14 @@ func NewExperienceEducationHandlers(service *ExperienceEducationService) *Experi
 
 // CreateExperience handles POST /api/v1/account/experiences
 func (h *ExperienceEducationHandlers) CreateExperience(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateExperienceRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -77,18 +71,12 @@ func (h *ExperienceEducationHandlers) GetUserExperiences(c *gin.Context) {
 
 // UpdateExperience handles PATCH /api/v1/account/experiences/:id
 func (h *ExperienceEducationHandlers) UpdateExperience(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	expID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid experience ID"})
@@ -96,8 +84,7 @@ func (h *ExperienceEducationHandlers) UpdateExperience(c *gin.Context) {
 	}
 
 	var req models.UpdateExperienceRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -115,18 +102,12 @@ func (h *ExperienceEducationHandlers) UpdateExperience(c *gin.Context) {
 
 // DeleteExperience handles DELETE /api/v1/account/experiences/:id
 func (h *ExperienceEducationHandlers) DeleteExperience(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	expID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid experience ID"})
@@ -149,21 +130,14 @@ func (h *ExperienceEducationHandlers) DeleteExperience(c *gin.Context) {
 
 // CreateEducation handles POST /api/v1/account/education
 func (h *ExperienceEducationHandlers) CreateEducation(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateEducationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -198,18 +172,12 @@ func (h *ExperienceEducationHandlers) GetUserEducation(c *gin.Context) {
 
 // UpdateEducation handles PATCH /api/v1/account/education/:id
 func (h *ExperienceEducationHandlers) UpdateEducation(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	eduID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid education ID"})
@@ -217,8 +185,7 @@ func (h *ExperienceEducationHandlers) UpdateEducation(c *gin.Context) {
 	}
 
 	var req models.UpdateEducationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -236,18 +203,12 @@ func (h *ExperienceEducationHandlers) UpdateEducation(c *gin.Context) {
 
 // DeleteEducation handles DELETE /api/v1/account/education/:id
 func (h *ExperienceEducationHandlers) DeleteEducation(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	eduID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid education ID"})
@@ -268,18 +229,12 @@ func (h *ExperienceEducationHandlers) DeleteEducation(c *gin.Context) {
 
 // GetMyExperiences handles GET /api/v1/account/experiences
 func (h *ExperienceEducationHandlers) GetMyExperiences(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	experiences, err := h.service.GetUserExperiences(c.Request.Context(), id, &id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -299,18 +254,12 @@ func (h *ExperienceEducationHandlers) GetMyExperiences(c *gin.Context) {
 
 // GetMyEducation handles GET /api/v1/account/education
 func (h *ExperienceEducationHandlers) GetMyEducation(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid user ID"})
-		return
-	}
-
 	education, err := h.service.GetUserEducation(c.Request.Context(), id)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
 	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
@@ -29,8 +30,8 @@ func NewAccountHandlers(accountSvc *AccountService, profileSvc *ProfileService,
 // GetProfile handles GET /api/v1/account/profile
 func (h *AccountHandlers) GetProfile(c *gin.Context) {
 	// Get user ID from JWT (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -38,16 +39,6 @@ func (h *AccountHandlers) GetProfile(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get profile
 	user, err := h.accountSvc.GetProfile(c.Request.Context(), id)
 	if err != nil {
@@ -68,8 +59,8 @@ func (h *AccountHandlers) GetProfile(c *gin.Context) {
 // UpdateProfile handles PATCH /api/v1/account/profile
 func (h *AccountHandlers) UpdateProfile(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -77,23 +68,9 @@ func (h *AccountHandlers) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.UpdateProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -118,8 +95,8 @@ func (h *AccountHandlers) UpdateProfile(c *gin.Context) {
 // ChangePassword handles POST /api/v1/account/change-password
 func (h *AccountHandlers) ChangePassword(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -127,23 +104,9 @@ func (h *AccountHandlers) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.ChangePasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -166,8 +129,8 @@ func (h *AccountHandlers) ChangePassword(c *gin.Context) {
 // DeleteAccount handles DELETE /api/v1/account/delete
 func (h *AccountHandlers) DeleteAccount(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -175,23 +138,9 @@ func (h *AccountHandlers) DeleteAccount(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.DeleteAccountRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -214,8 +163,8 @@ func (h *AccountHandlers) DeleteAccount(c *gin.Context) {
 // ChangeEmailHandler handles POST /api/v1/account/change-email
 func (h *AccountHandlers) ChangeEmailHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -223,23 +172,9 @@ func (h *AccountHandlers) ChangeEmailHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.ChangeEmailRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -262,8 +197,8 @@ func (h *AccountHandlers) ChangeEmailHandler(c *gin.Context) {
 // VerifyEmailChangeHandler handles POST /api/v1/account/verify-email-change
 func (h *AccountHandlers) VerifyEmailChangeHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -271,23 +206,9 @@ func (h *AccountHandlers) VerifyEmailChangeHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.VerifyEmailChangeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -310,8 +231,8 @@ func (h *AccountHandlers) VerifyEmailChangeHandler(c *gin.Context) {
 // ChangeUsernameHandler handles POST /api/v1/account/change-username
 func (h *AccountHandlers) ChangeUsernameHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -319,23 +240,9 @@ func (h *AccountHandlers) ChangeUsernameHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.ChangeUsernameRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -358,8 +265,8 @@ func (h *AccountHandlers) ChangeUsernameHandler(c *gin.Context) {
 // DeactivateAccountHandler handles POST /api/v1/account/deactivate
 func (h *AccountHandlers) DeactivateAccountHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -367,23 +274,9 @@ func (h *AccountHandlers) DeactivateAccountHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.DeactivateAccountRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -406,8 +299,8 @@ func (h *AccountHandlers) DeactivateAccountHandler(c *gin.Context) {
 // GetActiveSessionsHandler handles GET /api/v1/account/sessions
 func (h *AccountHandlers) GetActiveSessionsHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -415,16 +308,6 @@ func (h *AccountHandlers) GetActiveSessionsHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get active sessions
 	sessions, err := h.accountSvc.GetActiveSessions(c.Request.Context(), id)
 	if err != nil {
@@ -445,8 +328,8 @@ func (h *AccountHandlers) GetActiveSessionsHandler(c *gin.Context) {
 // DeleteSessionHandler handles DELETE /api/v1/account/sessions/:session_id
 func (h *AccountHandlers) DeleteSessionHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -454,16 +337,6 @@ func (h *AccountHandlers) DeleteSessionHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get session ID from URL parameter
 	sessionIDStr := c.Param("session_id")
 	sessionID, err := uuid.Parse(sessionIDStr)
@@ -494,8 +367,8 @@ func (h *AccountHandlers) DeleteSessionHandler(c *gin.Context) {
 // LogoutAllDevicesHandler handles POST /api/v1/account/logout-all
 func (h *AccountHandlers) LogoutAllDevicesHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -503,16 +376,6 @@ func (h *AccountHandlers) LogoutAllDevicesHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Optional: Get current session ID to keep current session active
 	// For now, we'll log out from ALL devices including current
 	if err := h.accountSvc.LogoutAllDevices(c.Request.Context(), id, nil); err != nil {
@@ -533,8 +396,8 @@ func (h *AccountHandlers) LogoutAllDevicesHandler(c *gin.Context) {
 // UploadProfilePictureHandler handles POST /api/v1/account/profile-picture
 func (h *AccountHandlers) UploadProfilePictureHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -542,16 +405,6 @@ func (h *AccountHandlers) UploadProfilePictureHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Parse multipart form
 	file, header, err := c.Request.FormFile("profile_picture")
 	if err != nil {
@@ -587,8 +440,8 @@ func (h *AccountHandlers) UploadProfilePictureHandler(c *gin.Context) {
 // UploadCoverPhotoHandler handles POST /api/v1/account/cover-photo
 func (h *AccountHandlers) UploadCoverPhotoHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -596,16 +449,6 @@ func (h *AccountHandlers) UploadCoverPhotoHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Parse multipart form
 	file, header, err := c.Request.FormFile("cover_photo")
 	if err != nil {
@@ -641,8 +484,8 @@ func (h *AccountHandlers) UploadCoverPhotoHandler(c *gin.Context) {
 // ExportDataHandler handles GET /api/v1/account/export-data
 func (h *AccountHandlers) ExportDataHandler(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -650,16 +493,6 @@ func (h *AccountHandlers) ExportDataHandler(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Export user data
 	data, err := h.accountSvc.ExportUserData(c.Request.Context(), id)
 	if err != nil {
@@ -681,8 +514,8 @@ func (h *AccountHandlers) ExportDataHandler(c *gin.Context) {
 // UpdateBasicProfile handles PATCH /api/v1/account/profile/basic
 func (h *AccountHandlers) UpdateBasicProfile(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -690,28 +523,14 @@ func (h *AccountHandlers) UpdateBasicProfile(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.UpdateBasicProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	// Update basic profile
-	err = h.profileSvc.UpdateBasicProfile(c.Request.Context(), id, &req)
+	err := h.profileSvc.UpdateBasicProfile(c.Request.Context(), id, &req)
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -730,8 +549,8 @@ func (h *AccountHandlers) UpdateBasicProfile(c *gin.Context) {
 // UpdatePrivacySettings handles PATCH /api/v1/account/profile/privacy
 func (h *AccountHandlers) UpdatePrivacySettings(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -739,28 +558,14 @@ func (h *AccountHandlers) UpdatePrivacySettings(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.UpdatePrivacySettingsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	// Update privacy settings
-	err = h.profileSvc.UpdatePrivacySettings(c.Request.Context(), id, &req)
+	err := h.profileSvc.UpdatePrivacySettings(c.Request.Context(), id, &req)
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -779,8 +584,8 @@ func (h *AccountHandlers) UpdatePrivacySettings(c *gin.Context) {
 // UpdateStory handles PATCH /api/v1/account/profile/story
 func (h *AccountHandlers) UpdateStory(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -788,23 +593,9 @@ func (h *AccountHandlers) UpdateStory(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.UpdateStoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -818,7 +609,7 @@ func (h *AccountHandlers) UpdateStory(c *gin.Context) {
 	}
 
 	// Update story
-	err = h.profileSvc.UpdateStory(c.Request.Context(), id, req.Story)
+	err := h.profileSvc.UpdateStory(c.Request.Context(), id, req.Story)
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -837,8 +628,8 @@ func (h *AccountHandlers) UpdateStory(c *gin.Context) {
 // UpdateAmbition handles PATCH /api/v1/account/profile/ambition
 func (h *AccountHandlers) UpdateAmbition(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -846,23 +637,9 @@ func (h *AccountHandlers) UpdateAmbition(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.UpdateAmbitionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -876,7 +653,7 @@ func (h *AccountHandlers) UpdateAmbition(c *gin.Context) {
 	}
 
 	// Update ambition
-	err = h.profileSvc.UpdateAmbition(c.Request.Context(), id, req.Ambition)
+	err := h.profileSvc.UpdateAmbition(c.Request.Context(), id, req.Ambition)
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -895,8 +672,8 @@ func (h *AccountHandlers) UpdateAmbition(c *gin.Context) {
 // UpdateSocialLinks updates user's social media links
 func (h *AccountHandlers) UpdateSocialLinks(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -904,28 +681,14 @@ func (h *AccountHandlers) UpdateSocialLinks(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req models.UpdateSocialLinksRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	// Update social links
-	err = h.profileSvc.UpdateSocialLinks(c.Request.Context(), id, &req)
+	err := h.profileSvc.UpdateSocialLinks(c.Request.Context(), id, &req)
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -954,10 +717,8 @@ func (h *AccountHandlers) GetPublicProfile(c *gin.Context) {
 
 	// Get viewer ID from JWT (optional - for privacy checks)
 	var viewerID *uuid.UUID
-	if userIDStr, exists := c.Get("user_id"); exists {
-		if id, err := uuid.Parse(userIDStr.(string)); err == nil {
-			viewerID = &id
-		}
+	if id, ok := utils.CurrentUserID(c); ok {
+		viewerID = &id
 	}
 
 	// Get public profile
@@ -980,8 +741,8 @@ func (h *AccountHandlers) GetPublicProfile(c *gin.Context) {
 // UpdateStatVisibility handles PATCH /api/v1/account/stat-visibility
 func (h *AccountHandlers) UpdateStatVisibility(c *gin.Context) {
 	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -989,25 +750,11 @@ func (h *AccountHandlers) UpdateStatVisibility(c *gin.Context) {
 		return
 	}
 
-	// Parse user ID
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Bind request
 	var req struct {
 		StatVisibility map[string]bool `json:"stat_visibility"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request body",
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -1028,7 +775,7 @@ func (h *AccountHandlers) UpdateStatVisibility(c *gin.Context) {
 	}
 
 	// Update stat visibility
-	err = h.accountSvc.UpdateStatVisibility(c.Request.Context(), id, req.StatVisibility)
+	err := h.accountSvc.UpdateStatVisibility(c.Request.Context(), id, req.StatVisibility)
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -1046,8 +793,8 @@ func (h *AccountHandlers) UpdateStatVisibility(c *gin.Context) {
 
 // UpdateLastUsed handles POST /api/v1/account/update-last-used
 func (h *AccountHandlers) UpdateLastUsed(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	id, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -1055,15 +802,6 @@ func (h *AccountHandlers) UpdateLastUsed(c *gin.Context) {
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	if err := h.accountSvc.UpdateLastUsed(c.Request.Context(), id); err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -1090,8 +828,8 @@ func (h *AccountHandlers) BlockUser(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -1099,8 +837,6 @@ func (h *AccountHandlers) BlockUser(c *gin.Context) {
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.accountSvc.BlockUser(c.Request.Context(), uid, targetUserID); err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -1127,8 +863,8 @@ func (h *AccountHandlers) UnblockUser(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -1136,8 +872,6 @@ func (h *AccountHandlers) UnblockUser(c *gin.Context) {
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.accountSvc.UnblockUser(c.Request.Context(), uid, targetUserID); err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -1164,8 +898,8 @@ func (h *AccountHandlers) RestrictUser(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -1173,8 +907,6 @@ func (h *AccountHandlers) RestrictUser(c *gin.Context) {
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.accountSvc.RestrictUser(c.Request.Context(), uid, targetUserID); err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -1201,8 +933,8 @@ func (h *AccountHandlers) UnrestrictUser(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -1210,8 +942,6 @@ func (h *AccountHandlers) UnrestrictUser(c *gin.Context) {
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.accountSvc.UnrestrictUser(c.Request.Context(), uid, targetUserID); err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -1238,8 +968,8 @@ func (h *AccountHandlers) ReportUser(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -1247,18 +977,12 @@ func (h *AccountHandlers) ReportUser(c *gin.Context) {
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req struct {
 		Reason      string `json:"reason" binding:"required"`
 		Description string `json:"description"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": err.Error(),
-		})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
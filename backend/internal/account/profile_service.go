@@ -2,8 +2,11 @@ package account
 
 import (
 	"context"
+	"log"
+
 	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
 	apperr "histeeria-backend/pkg/errors"
 
 	"github.com/google/uuid"
@@ -12,6 +15,7 @@ import (
 // ProfileService handles profile-related business logic
 type ProfileService struct {
 	userRepo repository.UserRepository
+	feeds    FeedInvalidator
 }
 
 // NewProfileService creates a new profile service
@@ -21,6 +25,12 @@ func NewProfileService(userRepo repository.UserRepository) *ProfileService {
 	}
 }
 
+// SetFeedInvalidator sets what clears a user's cached feed settings after they change
+// their privacy settings, so their feeds follow the change straight away
+func (s *ProfileService) SetFeedInvalidator(feeds FeedInvalidator) {
+	s.feeds = feeds
+}
+
 // GetPublicProfile retrieves a user's public profile with privacy filtering applied
 func (s *ProfileService) GetPublicProfile(ctx context.Context, username string, viewerID *uuid.UUID) (*models.PublicProfileResponse, error) {
 	// Fetch user by username
@@ -35,6 +45,17 @@ func (s *ProfileService) GetPublicProfile(ctx context.Context, username string,
 		isOwner = true
 	}
 
+	// Someone the owner has blocked sees the profile as if it didn't exist
+	if !isOwner && viewerID != nil {
+		blocked, err := s.userRepo.IsUserBlocked(ctx, user.ID, *viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, apperr.ErrUserNotFound
+		}
+	}
+
 	// Apply privacy checks
 	if !isOwner {
 		// Check profile privacy level
@@ -139,12 +160,30 @@ func (s *ProfileService) UpdateBasicProfile(ctx context.Context, userID uuid.UUI
 		}
 	}
 
+	// Timezone must be an IANA name so scheduled times resolve correctly
+	if req.Timezone != nil {
+		loc, err := utils.LoadUserLocation(*req.Timezone)
+		if err != nil {
+			return err
+		}
+		tz := loc.String()
+		req.Timezone = &tz
+	}
+
 	return s.userRepo.UpdateBasicProfile(ctx, userID, req)
 }
 
 // UpdatePrivacySettings updates user privacy settings
 func (s *ProfileService) UpdatePrivacySettings(ctx context.Context, userID uuid.UUID, req *models.UpdatePrivacySettingsRequest) error {
-	return s.userRepo.UpdatePrivacySettings(ctx, userID, req)
+	if err := s.userRepo.UpdatePrivacySettings(ctx, userID, req); err != nil {
+		return err
+	}
+	if s.feeds != nil {
+		if err := s.feeds.InvalidateUserFeed(ctx, userID); err != nil {
+			log.Printf("[ProfileService] Failed to invalidate feed of %s after settings change: %v", userID, err)
+		}
+	}
+	return nil
 }
 
 // UpdateStory updates the user's story section
@@ -159,14 +198,5 @@ func (s *ProfileService) UpdateAmbition(ctx context.Context, userID uuid.UUID, a
 
 // UpdateSocialLinks updates the user's social media links
 func (s *ProfileService) UpdateSocialLinks(ctx context.Context, userID uuid.UUID, req *models.UpdateSocialLinksRequest) error {
-	// Build social links map
-	socialLinks := make(map[string]*string)
-	socialLinks["twitter"] = req.Twitter
-	socialLinks["instagram"] = req.Instagram
-	socialLinks["facebook"] = req.Facebook
-	socialLinks["linkedin"] = req.LinkedIn
-	socialLinks["github"] = req.GitHub
-	socialLinks["youtube"] = req.YouTube
-
-	return s.userRepo.UpdateSocialLinks(ctx, userID, socialLinks)
+	return s.userRepo.UpdateSocialLinks(ctx, userID, req.Links())
 }
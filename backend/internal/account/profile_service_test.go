@@ -0,0 +1,47 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakeProfileRepo accepts privacy settings updates; anything else panics through the
+// nil embedded interface
+type fakeProfileRepo struct {
+	repository.UserRepository
+}
+
+func (r *fakeProfileRepo) UpdatePrivacySettings(ctx context.Context, userID uuid.UUID, req *models.UpdatePrivacySettingsRequest) error {
+	return nil
+}
+
+// fakeFeedInvalidator records whose feeds were invalidated
+type fakeFeedInvalidator struct {
+	invalidated []uuid.UUID
+}
+
+func (f *fakeFeedInvalidator) InvalidateUserFeed(ctx context.Context, userID uuid.UUID) error {
+	f.invalidated = append(f.invalidated, userID)
+	return nil
+}
+
+func TestUpdatePrivacySettingsInvalidatesFeedPrefs(t *testing.T) {
+	userID := uuid.New()
+	feeds := &fakeFeedInvalidator{}
+	svc := NewProfileService(&fakeProfileRepo{})
+	svc.SetFeedInvalidator(feeds)
+
+	hide := true
+	err := svc.UpdatePrivacySettings(context.Background(), userID, &models.UpdatePrivacySettingsRequest{HideInteracted: &hide})
+	if err != nil {
+		t.Fatalf("UpdatePrivacySettings: %v", err)
+	}
+	if len(feeds.invalidated) != 1 || feeds.invalidated[0] != userID {
+		t.Errorf("invalidated %v, want just %s", feeds.invalidated, userID)
+	}
+}
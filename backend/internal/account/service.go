@@ -21,6 +21,29 @@ type AccountService struct {
 	sessionRepo repository.SessionRepository
 	emailSvc    *utils.EmailService
 	storageSvc  *utils.StorageService
+
+	// Caches cleared when a block severs two users
+	feeds         FeedInvalidator
+	conversations ConversationInvalidator
+
+	sessions SessionRevoker
+}
+
+// SessionRevoker logs sessions out, revoking their access tokens along with their
+// refresh tokens
+type SessionRevoker interface {
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) (*models.MessageResponse, error)
+	RevokeOtherSessions(ctx context.Context, userID uuid.UUID, currentSID string) (*models.MessageResponse, error)
+}
+
+// FeedInvalidator drops a user's cached home feed
+type FeedInvalidator interface {
+	InvalidateUserFeed(ctx context.Context, userID uuid.UUID) error
+}
+
+// ConversationInvalidator drops a user's cached conversation list
+type ConversationInvalidator interface {
+	InvalidateConversations(ctx context.Context, userID uuid.UUID) error
 }
 
 // NewAccountService creates a new account service
@@ -33,6 +56,24 @@ func NewAccountService(userRepo repository.UserRepository, sessionRepo repositor
 	}
 }
 
+// SetFeedInvalidator sets what clears cached home feeds after a block, so neither
+// user keeps seeing the other's posts until the cache expires
+func (s *AccountService) SetFeedInvalidator(feeds FeedInvalidator) {
+	s.feeds = feeds
+}
+
+// SetConversationInvalidator sets what clears cached conversation lists after a
+// block, so the conversation between the two disappears straight away
+func (s *AccountService) SetConversationInvalidator(conversations ConversationInvalidator) {
+	s.conversations = conversations
+}
+
+// SetSessionRevoker sets what logs sessions out. Deleting a session's rows alone would
+// leave its access token working until it expires.
+func (s *AccountService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
 // GetProfile retrieves the user's profile
 func (s *AccountService) GetProfile(ctx context.Context, userID uuid.UUID) (*models.User, error) {
 	user, err := s.userRepo.GetUserByID(ctx, userID)
@@ -42,46 +83,81 @@ func (s *AccountService) GetProfile(ctx context.Context, userID uuid.UUID) (*mod
 	return user.ToSafeUser(), nil
 }
 
-// UpdateProfile updates user profile fields
+// UpdateProfile applies any subset of profile fields in a single update and returns the
+// updated profile. Omitted fields are left unchanged.
 func (s *AccountService) UpdateProfile(ctx context.Context, userID uuid.UUID, req *models.UpdateProfileRequest) (*models.User, error) {
-	// Validate the request
-	if err := validateUpdateProfile(req); err != nil {
-		return nil, err
-	}
-
 	// Get current user to verify existence
 	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Validate the request
+	if err := validateUpdateProfile(req, user); err != nil {
+		return nil, err
+	}
+
 	// Build updates map with only provided fields
 	updates := make(map[string]interface{})
 
 	if req.DisplayName != nil {
 		updates["display_name"] = *req.DisplayName
 	}
-
 	if req.Age != nil {
 		updates["age"] = *req.Age
 	}
-
 	if req.ProfilePicture != nil {
 		updates["profile_picture"] = *req.ProfilePicture
 	}
+	if req.Bio != nil {
+		updates["bio"] = *req.Bio
+	}
+	if req.Location != nil {
+		updates["location"] = *req.Location
+	}
+	if req.Gender != nil {
+		updates["gender"] = *req.Gender
+	}
+	if req.GenderCustom != nil {
+		updates["gender_custom"] = *req.GenderCustom
+	}
+	if req.Website != nil {
+		updates["website"] = *req.Website
+	}
+	if req.Timezone != nil {
+		// Store the canonical IANA name
+		loc, err := utils.LoadUserLocation(*req.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		updates["timezone"] = loc.String()
+	}
+	if req.Story != nil {
+		updates["story"] = *req.Story
+	}
+	if req.Ambition != nil {
+		updates["ambition"] = *req.Ambition
+	}
+	if req.SocialLinks != nil {
+		updates["social_links"] = mergeSocialLinks(user.SocialLinks, req.SocialLinks.Links())
+	}
+	if req.ProfilePrivacy != nil {
+		updates["profile_privacy"] = *req.ProfilePrivacy
+	}
+	if req.FieldVisibility != nil {
+		updates["field_visibility"] = mergeVisibility(user.FieldVisibility, req.FieldVisibility)
+	}
+	if req.StatVisibility != nil {
+		updates["stat_visibility"] = mergeVisibility(user.StatVisibility, req.StatVisibility)
+	}
 
 	// If no fields to update, return current user
 	if len(updates) == 0 {
 		return user.ToSafeUser(), nil
 	}
 
-	// Update profile
-	if err := s.userRepo.UpdateProfile(ctx, userID, updates); err != nil {
-		return nil, err
-	}
-
-	// Fetch updated user
-	updatedUser, err := s.userRepo.GetUserByID(ctx, userID)
+	// Update profile; the repository returns the updated row, so no re-read is needed
+	updatedUser, err := s.userRepo.UpdateProfile(ctx, userID, updates)
 	if err != nil {
 		return nil, err
 	}
@@ -237,6 +313,11 @@ func (s *AccountService) VerifyEmailChange(ctx context.Context, userID uuid.UUID
 		return err
 	}
 
+	req.VerificationCode = s.emailSvc.NormalizeVerificationCode(req.VerificationCode)
+	if !s.emailSvc.IsValidVerificationCode(req.VerificationCode) {
+		return errors.ErrInvalidVerificationCode
+	}
+
 	// Complete the email change
 	if err := s.userRepo.VerifyEmailChange(ctx, userID, req.VerificationCode); err != nil {
 		return err
@@ -355,6 +436,10 @@ func (s *AccountService) GetActiveSessions(ctx context.Context, userID uuid.UUID
 
 // DeleteSession logs out from a specific session/device
 func (s *AccountService) DeleteSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if s.sessions == nil {
+		return errors.ErrInternalServer
+	}
+
 	// Get the session to verify it belongs to the user
 	session, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
 	if err != nil {
@@ -366,53 +451,98 @@ func (s *AccountService) DeleteSession(ctx context.Context, userID, sessionID uu
 		return errors.NewAppError(403, "You can only delete your own sessions")
 	}
 
-	// Delete the session
-	if err := s.sessionRepo.DeleteSession(ctx, sessionID); err != nil {
-		return err
-	}
-
-	return nil
+	// Log out the session with all its rotated refresh tokens
+	_, err = s.sessions.RevokeSession(ctx, userID, session.FamilyID)
+	return err
 }
 
 // LogoutAllDevices logs out from all devices except current (optional)
-func (s *AccountService) LogoutAllDevices(ctx context.Context, userID uuid.UUID, currentSessionID *uuid.UUID) error {
-	// Delete all sessions for the user, optionally except the current one
-	if err := s.sessionRepo.DeleteAllUserSessions(ctx, userID, currentSessionID); err != nil {
-		return err
+func (s *AccountService) LogoutAllDevices(ctx context.Context, userID uuid.UUID, currentFamilyID *uuid.UUID) error {
+	if s.sessions == nil {
+		return errors.ErrInternalServer
 	}
 
-	return nil
+	currentSID := ""
+	if currentFamilyID != nil {
+		currentSID = currentFamilyID.String()
+	}
+	_, err := s.sessions.RevokeOtherSessions(ctx, userID, currentSID)
+	return err
 }
 
 // Validation functions
 
-func validateUpdateProfile(req *models.UpdateProfileRequest) error {
+// validateUpdateProfile checks the rules that span fields or depend on the stored profile.
+// Per-field formats and lengths are enforced by the binding tags.
+func validateUpdateProfile(req *models.UpdateProfileRequest, user *models.User) error {
 	// At least one field must be provided
-	if req.DisplayName == nil && req.Age == nil && req.ProfilePicture == nil {
+	if req.DisplayName == nil && req.Age == nil && req.ProfilePicture == nil &&
+		req.Bio == nil && req.Location == nil && req.Gender == nil && req.GenderCustom == nil &&
+		req.Website == nil && req.Timezone == nil && req.Story == nil && req.Ambition == nil &&
+		req.SocialLinks == nil && req.ProfilePrivacy == nil && req.FieldVisibility == nil &&
+		req.StatVisibility == nil {
 		return errors.NewAppError(400, "At least one field must be provided for update")
 	}
 
-	// Validate display name
-	if req.DisplayName != nil {
-		name := *req.DisplayName
-		if len(name) < 2 || len(name) > 50 {
-			return errors.NewAppError(400, "Display name must be between 2 and 50 characters")
+	// Custom gender needs its text, either in this request or already stored
+	if req.Gender != nil && *req.Gender == "custom" {
+		customText := req.GenderCustom
+		if customText == nil {
+			customText = user.GenderCustom
+		}
+		if customText == nil || *customText == "" {
+			return errors.NewAppError(400, "Custom gender text is required when gender is 'custom'")
 		}
 	}
 
-	// Validate age
-	if req.Age != nil {
-		age := *req.Age
-		if age < 13 || age > 120 {
-			return errors.NewAppError(400, "Age must be between 13 and 120")
+	// Same minimum as the dedicated stat visibility endpoint, checked after merging
+	if req.StatVisibility != nil {
+		visibleCount := 0
+		for _, visible := range mergeVisibility(user.StatVisibility, req.StatVisibility) {
+			if visible {
+				visibleCount++
+			}
+		}
+		if visibleCount < 3 {
+			return errors.NewAppError(400, "At least 3 stats must be visible on your profile")
 		}
 	}
 
-	// Profile picture validation (URL format) is handled by validator tag
-
 	return nil
 }
 
+// mergeSocialLinks overlays the provided links on the stored ones. An empty string
+// removes a link; links not in the request are kept.
+func mergeSocialLinks(current, provided map[string]*string) map[string]*string {
+	merged := make(map[string]*string, len(current)+len(provided))
+	for key, link := range current {
+		merged[key] = link
+	}
+	for key, link := range provided {
+		switch {
+		case link == nil:
+			continue
+		case *link == "":
+			merged[key] = nil
+		default:
+			merged[key] = link
+		}
+	}
+	return merged
+}
+
+// mergeVisibility overlays the provided visibility flags on the stored ones
+func mergeVisibility(current, provided map[string]bool) map[string]bool {
+	merged := make(map[string]bool, len(current)+len(provided))
+	for key, visible := range current {
+		merged[key] = visible
+	}
+	for key, visible := range provided {
+		merged[key] = visible
+	}
+	return merged
+}
+
 func validateChangePassword(req *models.ChangePasswordRequest) error {
 	if req.CurrentPassword == "" {
 		return errors.NewAppError(400, "Current password is required")
@@ -469,7 +599,7 @@ func (s *AccountService) UploadProfilePicture(ctx context.Context, userID uuid.U
 	updates := map[string]interface{}{
 		"profile_picture": pictureURL,
 	}
-	if err := s.userRepo.UpdateProfile(ctx, userID, updates); err != nil {
+	if _, err := s.userRepo.UpdateProfile(ctx, userID, updates); err != nil {
 		log.Printf("[AccountService] Failed to update profile with picture URL: %v", err)
 		// If profile update fails, try to clean up uploaded file
 		go s.storageSvc.DeleteProfilePicture(context.Background(), pictureURL)
@@ -505,7 +635,7 @@ func (s *AccountService) UploadCoverPhoto(ctx context.Context, userID uuid.UUID,
 	updates := map[string]interface{}{
 		"cover_photo": coverPhotoURL,
 	}
-	if err := s.userRepo.UpdateProfile(ctx, userID, updates); err != nil {
+	if _, err := s.userRepo.UpdateProfile(ctx, userID, updates); err != nil {
 		log.Printf("[AccountService] Failed to update profile with cover photo URL: %v", err)
 		// If profile update fails, try to clean up uploaded file
 		go s.storageSvc.DeleteCoverPhoto(context.Background(), coverPhotoURL)
@@ -528,12 +658,32 @@ func (s *AccountService) UpdateLastUsed(ctx context.Context, userID uuid.UUID) e
 	return s.userRepo.UpdateLastUsed(ctx, userID)
 }
 
-// BlockUser blocks a user (complete barrier - no messaging, content hidden)
+// BlockUser blocks a user (complete barrier - no messaging, content hidden). Follows,
+// follow requests and connections between the two go with it, in one transaction,
+// and both users' cached feeds and conversation lists are dropped.
 func (s *AccountService) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
 	if blockerID == blockedID {
 		return fmt.Errorf("cannot block yourself")
 	}
-	return s.userRepo.BlockUser(ctx, blockerID, blockedID)
+	if err := s.userRepo.BlockUser(ctx, blockerID, blockedID); err != nil {
+		return err
+	}
+
+	// The block is in place; stale caches only delay it taking effect, so failures
+	// here are logged rather than returned
+	for _, userID := range []uuid.UUID{blockerID, blockedID} {
+		if s.feeds != nil {
+			if err := s.feeds.InvalidateUserFeed(ctx, userID); err != nil {
+				log.Printf("[AccountService] Failed to invalidate feed of %s after block: %v", userID, err)
+			}
+		}
+		if s.conversations != nil {
+			if err := s.conversations.InvalidateConversations(ctx, userID); err != nil {
+				log.Printf("[AccountService] Failed to invalidate conversations of %s after block: %v", userID, err)
+			}
+		}
+	}
+	return nil
 }
 
 // UnblockUser unblocks a user
@@ -630,10 +780,6 @@ func validateVerifyEmailChange(req *models.VerifyEmailChangeRequest) error {
 		return errors.NewAppError(400, "Verification code is required")
 	}
 
-	if len(req.VerificationCode) != 6 {
-		return errors.NewAppError(400, "Verification code must be 6 digits")
-	}
-
 	return nil
 }
 
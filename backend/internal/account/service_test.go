@@ -0,0 +1,234 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// fakeSessionRepo serves sessions from a map; anything else panics through the nil
+// embedded interface
+type fakeSessionRepo struct {
+	repository.SessionRepository
+
+	sessions map[uuid.UUID]*models.UserSession
+}
+
+func (r *fakeSessionRepo) GetSessionByID(ctx context.Context, id uuid.UUID) (*models.UserSession, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, errors.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// fakeRevoker records the sessions it was asked to log out
+type fakeRevoker struct {
+	revoked []uuid.UUID
+	kept    []string
+}
+
+func (r *fakeRevoker) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) (*models.MessageResponse, error) {
+	r.revoked = append(r.revoked, sessionID)
+	return &models.MessageResponse{Success: true}, nil
+}
+
+func (r *fakeRevoker) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, currentSID string) (*models.MessageResponse, error) {
+	r.kept = append(r.kept, currentSID)
+	return &models.MessageResponse{Success: true}, nil
+}
+
+func TestDeleteSessionRevokesTheSessionsFamily(t *testing.T) {
+	owner := uuid.New()
+	session := &models.UserSession{ID: uuid.New(), UserID: owner, FamilyID: uuid.New()}
+	revoker := &fakeRevoker{}
+	svc := NewAccountService(nil, &fakeSessionRepo{sessions: map[uuid.UUID]*models.UserSession{session.ID: session}}, nil, nil)
+	svc.SetSessionRevoker(revoker)
+
+	if err := svc.DeleteSession(context.Background(), uuid.New(), session.ID); err == nil {
+		t.Error("someone else's session shouldn't be deletable")
+	}
+	if err := svc.DeleteSession(context.Background(), owner, session.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if len(revoker.revoked) != 1 || revoker.revoked[0] != session.FamilyID {
+		t.Errorf("revoked %v, want just family %s", revoker.revoked, session.FamilyID)
+	}
+}
+
+func TestLogoutAllDevicesRevokesEverySession(t *testing.T) {
+	revoker := &fakeRevoker{}
+	svc := NewAccountService(nil, &fakeSessionRepo{}, nil, nil)
+	svc.SetSessionRevoker(revoker)
+
+	if err := svc.LogoutAllDevices(context.Background(), uuid.New(), nil); err != nil {
+		t.Fatalf("LogoutAllDevices: %v", err)
+	}
+	if len(revoker.kept) != 1 || revoker.kept[0] != "" {
+		t.Errorf("kept %q, want no session kept", revoker.kept)
+	}
+}
+
+// fakeBlockRepo records the blocks made through it
+type fakeBlockRepo struct {
+	repository.UserRepository
+
+	blocks [][2]uuid.UUID
+}
+
+func (r *fakeBlockRepo) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	r.blocks = append(r.blocks, [2]uuid.UUID{blockerID, blockedID})
+	return nil
+}
+
+// fakeConversationInvalidator records whose conversation lists were invalidated
+type fakeConversationInvalidator struct {
+	invalidated []uuid.UUID
+}
+
+func (f *fakeConversationInvalidator) InvalidateConversations(ctx context.Context, userID uuid.UUID) error {
+	f.invalidated = append(f.invalidated, userID)
+	return nil
+}
+
+func TestBlockUserInvalidatesBothUsersCaches(t *testing.T) {
+	blocker, blocked := uuid.New(), uuid.New()
+	users := &fakeBlockRepo{}
+	feeds := &fakeFeedInvalidator{}
+	conversations := &fakeConversationInvalidator{}
+	svc := NewAccountService(users, nil, nil, nil)
+	svc.SetFeedInvalidator(feeds)
+	svc.SetConversationInvalidator(conversations)
+
+	if err := svc.BlockUser(context.Background(), blocker, blocker); err == nil {
+		t.Error("blocking yourself should fail")
+	}
+	if err := svc.BlockUser(context.Background(), blocker, blocked); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+
+	if len(users.blocks) != 1 || users.blocks[0] != [2]uuid.UUID{blocker, blocked} {
+		t.Errorf("blocks = %v, want just %s blocking %s", users.blocks, blocker, blocked)
+	}
+	for name, got := range map[string][]uuid.UUID{"feeds": feeds.invalidated, "conversations": conversations.invalidated} {
+		if len(got) != 2 || got[0] != blocker || got[1] != blocked {
+			t.Errorf("invalidated %s of %v, want blocker and blocked", name, got)
+		}
+	}
+}
+
+// fakeProfileUpdateRepo stores one user and applies profile updates to it the way the
+// users table would, column by column
+type fakeProfileUpdateRepo struct {
+	repository.UserRepository
+
+	user    *models.User
+	updates []map[string]interface{}
+}
+
+func (r *fakeProfileUpdateRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return r.user, nil
+}
+
+func (r *fakeProfileUpdateRepo) UpdateProfile(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) (*models.User, error) {
+	r.updates = append(r.updates, updates)
+	row := map[string]interface{}{}
+	stored, _ := json.Marshal(r.user)
+	json.Unmarshal(stored, &row)
+	for column, value := range updates {
+		row[column] = value
+	}
+	merged, _ := json.Marshal(row)
+	updated := &models.User{}
+	if err := json.Unmarshal(merged, updated); err != nil {
+		return nil, err
+	}
+	r.user = updated
+	return updated, nil
+}
+
+func TestUpdateProfileAppliesOnlyProvidedFields(t *testing.T) {
+	bio, location, story := "Old bio", "Lisbon", "Started out in 2019"
+	twitter, github := "https://twitter.com/me", "https://github.com/me"
+	repo := &fakeProfileUpdateRepo{user: &models.User{
+		ID:              uuid.New(),
+		DisplayName:     "Old Name",
+		Bio:             &bio,
+		Location:        &location,
+		Story:           &story,
+		Timezone:        "UTC",
+		SocialLinks:     map[string]*string{"twitter": &twitter},
+		FieldVisibility: map[string]bool{"location": true, "story": true},
+	}}
+	svc := NewAccountService(repo, nil, nil, nil)
+
+	newBio, empty := "New bio", ""
+	updated, err := svc.UpdateProfile(context.Background(), repo.user.ID, &models.UpdateProfileRequest{
+		DisplayName:     ptr("New Name"),
+		Bio:             &newBio,
+		Timezone:        ptr("Europe/Berlin"),
+		SocialLinks:     &models.UpdateSocialLinksRequest{GitHub: &github, Twitter: &empty},
+		FieldVisibility: map[string]bool{"location": false},
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+
+	if len(repo.updates) != 1 {
+		t.Fatalf("made %d updates, want one", len(repo.updates))
+	}
+	for _, column := range []string{"location", "story", "age", "website", "stat_visibility"} {
+		if _, ok := repo.updates[0][column]; ok {
+			t.Errorf("omitted field %s was written", column)
+		}
+	}
+
+	if updated.DisplayName != "New Name" || *updated.Bio != "New bio" || updated.Timezone != "Europe/Berlin" {
+		t.Errorf("provided fields = %q, %q, %q", updated.DisplayName, *updated.Bio, updated.Timezone)
+	}
+	if updated.Location == nil || *updated.Location != location || updated.Story == nil || *updated.Story != story {
+		t.Error("omitted fields should be unchanged")
+	}
+	if updated.SocialLinks["twitter"] != nil || updated.SocialLinks["github"] == nil || *updated.SocialLinks["github"] != github {
+		t.Errorf("social links = %v, want twitter removed and github added", updated.SocialLinks)
+	}
+	if updated.FieldVisibility["location"] || !updated.FieldVisibility["story"] {
+		t.Errorf("field visibility = %v, want location hidden and story kept", updated.FieldVisibility)
+	}
+}
+
+func TestUpdateProfileRejectsInvalidFields(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.UpdateProfileRequest
+	}{
+		{"nothing provided", models.UpdateProfileRequest{}},
+		{"custom gender without text", models.UpdateProfileRequest{Gender: ptr("custom")}},
+		{"too few visible stats", models.UpdateProfileRequest{StatVisibility: map[string]bool{"posts": true}}},
+		{"unknown timezone", models.UpdateProfileRequest{Bio: ptr("fine"), Timezone: ptr("Mars/Olympus")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeProfileUpdateRepo{user: &models.User{ID: uuid.New()}}
+			svc := NewAccountService(repo, nil, nil, nil)
+
+			if _, err := svc.UpdateProfile(context.Background(), repo.user.ID, &tt.req); err == nil {
+				t.Fatal("want an error")
+			}
+			if len(repo.updates) != 0 {
+				t.Error("nothing should be written when a field is invalid")
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -15,8 +15,8 @@ import (
 func AdminAuthMiddleware(jwtSvc *utils.JWTService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// First verify JWT token
-		userID, exists := c.Get("user_id")
-		if !exists {
+		user, err := utils.CurrentUser(c)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"message": "Authentication required",
@@ -45,7 +45,7 @@ func AdminAuthMiddleware(jwtSvc *utils.JWTService) gin.HandlerFunc {
 
 		// For now, this middleware will allow all authenticated users
 		// SECURITY WARNING: This is a placeholder. Implement proper admin checking before production!
-		_ = userID
+		_ = user
 
 		// Set admin flag in context
 		c.Set("is_admin", true)
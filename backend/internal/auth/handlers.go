@@ -1,16 +1,15 @@
 package auth
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
 
 	"histeeria-backend/internal/cache"
 	"histeeria-backend/internal/config"
 	"histeeria-backend/internal/models"
-	"histeeria-backend/internal/repository"
 	"histeeria-backend/internal/utils"
 	"histeeria-backend/pkg/errors"
 
@@ -29,7 +28,8 @@ type AuthHandlers struct {
 	googleOAuth       *GoogleOAuthService
 	githubOAuth       *GitHubOAuthService
 	linkedinOAuth     *LinkedInOAuthService
-	sessionRepo       repository.SessionRepository
+	appleOAuth        *AppleOAuthService
+	twitterOAuth      *TwitterOAuthService
 }
 
 // NewAuthHandlers creates new authentication handlers
@@ -43,7 +43,8 @@ func NewAuthHandlers(
 	googleOAuth *GoogleOAuthService,
 	githubOAuth *GitHubOAuthService,
 	linkedinOAuth *LinkedInOAuthService,
-	sessionRepo repository.SessionRepository,
+	appleOAuth *AppleOAuthService,
+	twitterOAuth *TwitterOAuthService,
 ) *AuthHandlers {
 	return &AuthHandlers{
 		authSvc:           authSvc,
@@ -55,30 +56,39 @@ func NewAuthHandlers(
 		googleOAuth:       googleOAuth,
 		githubOAuth:       githubOAuth,
 		linkedinOAuth:     linkedinOAuth,
-		sessionRepo:       sessionRepo,
+		appleOAuth:        appleOAuth,
+		twitterOAuth:      twitterOAuth,
 	}
 }
 
-// Helper function to create session from login
-func (h *AuthHandlers) createSession(ctx context.Context, userID uuid.UUID, token string, c *gin.Context) error {
-	// Hash the token for storage
-	tokenHash := utils.HashToken(token)
+// respondWithSession starts a session for the login in response, adds its refresh
+// token and writes the response. Responses without an access token (e.g. a signup
+// still awaiting verification) are written as they are.
+func (h *AuthHandlers) respondWithSession(c *gin.Context, response *models.AuthResponse) {
+	if response.Token != "" {
+		refreshToken, expiresAt, err := h.authSvc.StartSession(c.Request.Context(), response.Token, sessionDevice(c))
+		if err != nil {
+			appErr := errors.GetAppError(err)
+			c.JSON(appErr.Code, gin.H{
+				"success": false,
+				"message": "Failed to start session",
+				"error":   appErr.Details,
+			})
+			return
+		}
+		response.RefreshToken = refreshToken
+		response.RefreshExpiresAt = &expiresAt
+	}
 
-	// Extract device info from request
-	userAgent := c.GetHeader("User-Agent")
-	ipAddress := c.ClientIP()
+	c.JSON(http.StatusOK, response)
+}
 
-	// Create session
-	session := &models.UserSession{
-		UserID:    userID,
-		TokenHash: tokenHash,
-		UserAgent: &userAgent,
-		IPAddress: &ipAddress,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour), // 30 days - same as JWT
-		CreatedAt: time.Now(),
+// sessionDevice describes the client making the request
+func sessionDevice(c *gin.Context) SessionDevice {
+	return SessionDevice{
+		UserAgent: c.GetHeader("User-Agent"),
+		IPAddress: c.ClientIP(),
 	}
-
-	return h.sessionRepo.CreateSession(ctx, session)
 }
 
 // RegisterHandler handles user registration
@@ -104,7 +114,7 @@ func (h *AuthHandlers) RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	h.respondWithSession(c, response)
 }
 
 // VerifyEmailHandler handles email verification
@@ -130,12 +140,7 @@ func (h *AuthHandlers) VerifyEmailHandler(c *gin.Context) {
 		return
 	}
 
-	// Create session after successful email verification (login)
-	if response.User != nil && response.Token != "" {
-		go h.createSession(c.Request.Context(), response.User.ID, response.Token, c)
-	}
-
-	c.JSON(http.StatusOK, response)
+	h.respondWithSession(c, response)
 }
 
 // LoginHandler handles user login
@@ -152,21 +157,11 @@ func (h *AuthHandlers) LoginHandler(c *gin.Context) {
 
 	response, err := h.authSvc.LoginUser(c.Request.Context(), &req)
 	if err != nil {
-		appErr := errors.GetAppError(err)
-		c.JSON(appErr.Code, gin.H{
-			"success": false,
-			"message": appErr.Message,
-			"error":   appErr.Details,
-		})
+		respondAuthError(c, err)
 		return
 	}
 
-	// Create session to track this login
-	if response.User != nil && response.Token != "" {
-		go h.createSession(c.Request.Context(), response.User.ID, response.Token, c)
-	}
-
-	c.JSON(http.StatusOK, response)
+	h.respondWithLogin(c, response)
 }
 
 // ForgotPasswordHandler handles password reset request
@@ -223,8 +218,8 @@ func (h *AuthHandlers) ResetPasswordHandler(c *gin.Context) {
 
 // MeHandler handles getting current user information
 func (h *AuthHandlers) MeHandler(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "User not authenticated",
@@ -232,15 +227,6 @@ func (h *AuthHandlers) MeHandler(c *gin.Context) {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	response, err := h.authSvc.GetCurrentUser(c.Request.Context(), userID)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -255,27 +241,19 @@ func (h *AuthHandlers) MeHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// RefreshHandler handles token refresh
+// RefreshHandler exchanges a refresh token for a new access token and refresh token
 func (h *AuthHandlers) RefreshHandler(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Invalid user ID",
+			"message": "Invalid request data",
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	response, err := h.authSvc.RefreshToken(c.Request.Context(), userID)
+	response, err := h.authSvc.RefreshSession(c.Request.Context(), req.RefreshToken, sessionDevice(c))
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -291,8 +269,8 @@ func (h *AuthHandlers) RefreshHandler(c *gin.Context) {
 
 // LogoutHandler handles user logout
 func (h *AuthHandlers) LogoutHandler(c *gin.Context) {
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "User not authenticated",
@@ -300,15 +278,6 @@ func (h *AuthHandlers) LogoutHandler(c *gin.Context) {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Extract token from Authorization header for blacklisting
 	authHeader := c.GetHeader("Authorization")
 	var token string
@@ -330,12 +299,6 @@ func (h *AuthHandlers) LogoutHandler(c *gin.Context) {
 		return
 	}
 
-	// Delete session from database
-	if token != "" {
-		tokenHash := utils.HashToken(token)
-		go h.sessionRepo.DeleteSessionByTokenHash(c.Request.Context(), tokenHash)
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
@@ -381,12 +344,7 @@ func (h *AuthHandlers) GoogleExchangeHandler(c *gin.Context) {
 		return
 	}
 
-	// Create session for OAuth login
-	if response.User != nil && response.Token != "" {
-		go h.createSession(c.Request.Context(), response.User.ID, response.Token, c)
-	}
-
-	c.JSON(http.StatusOK, response)
+	h.respondWithLogin(c, response)
 }
 
 // GoogleCallbackHandler handles Google OAuth callback
@@ -449,12 +407,7 @@ func (h *AuthHandlers) GitHubExchangeHandler(c *gin.Context) {
 		return
 	}
 
-	// Create session for OAuth login
-	if response.User != nil && response.Token != "" {
-		go h.createSession(c.Request.Context(), response.User.ID, response.Token, c)
-	}
-
-	c.JSON(http.StatusOK, response)
+	h.respondWithLogin(c, response)
 }
 
 // GitHubCallbackHandler handles GitHub OAuth callback
@@ -514,12 +467,7 @@ func (h *AuthHandlers) LinkedInExchangeHandler(c *gin.Context) {
 		return
 	}
 
-	// Create session for OAuth login
-	if response.User != nil && response.Token != "" {
-		go h.createSession(c.Request.Context(), response.User.ID, response.Token, c)
-	}
-
-	c.JSON(http.StatusOK, response)
+	h.respondWithLogin(c, response)
 }
 
 // LinkedInCallbackHandler handles LinkedIn OAuth callback
@@ -538,6 +486,185 @@ func (h *AuthHandlers) LinkedInCallbackHandler(c *gin.Context) {
 	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 }
 
+// startOAuthLogin begins a login with provider and binds it to this browser with the
+// OAuthBindingCookie. On failure it writes the error and returns nil.
+func (h *AuthHandlers) startOAuthLogin(c *gin.Context, provider string, pkce bool) *OAuthLogin {
+	login, err := h.authSvc.BeginOAuthLogin(c.Request.Context(), provider, pkce)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return nil
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(OAuthBindingCookie, login.Binding, int(oauthStateTTL.Seconds()), "/", "", isSecureRequest(c), true)
+	return login
+}
+
+// finishOAuthLogin spends state, which must belong to a login with provider started
+// from this browser, and returns its PKCE verifier. On failure it writes the error
+// and returns false.
+func (h *AuthHandlers) finishOAuthLogin(c *gin.Context, provider, state string) (string, bool) {
+	binding, _ := c.Cookie(OAuthBindingCookie)
+	verifier, err := h.authSvc.FinishOAuthLogin(c.Request.Context(), provider, state, binding)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return "", false
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(OAuthBindingCookie, "", -1, "/", "", isSecureRequest(c), true)
+	return verifier, true
+}
+
+// isSecureRequest reports whether the request reached us over HTTPS, directly or
+// through a proxy
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
+
+// AppleLoginHandler initiates Sign in with Apple
+func (h *AuthHandlers) AppleLoginHandler(c *gin.Context) {
+	login := h.startOAuthLogin(c, "apple", false)
+	if login == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"auth_url": h.appleOAuth.GetAuthURL(login.State),
+		"state":    login.State,
+	})
+}
+
+// AppleExchangeHandler exchanges an Apple authorization code for token. user is the
+// JSON Apple posted to the callback, which it only sends on the first authorization.
+func (h *AuthHandlers) AppleExchangeHandler(c *gin.Context) {
+	type ExchangeRequest struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+		User  string `json:"user"`
+	}
+
+	var req ExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	if _, ok := h.finishOAuthLogin(c, "apple", req.State); !ok {
+		return
+	}
+
+	var userInfo *AppleUserInfo
+	if req.User != "" {
+		userInfo = &AppleUserInfo{}
+		if err := json.Unmarshal([]byte(req.User), userInfo); err != nil {
+			userInfo = nil // Only the name comes from here; sign in without it
+		}
+	}
+
+	response, err := h.appleOAuth.HandleCallback(c.Request.Context(), req.Code, userInfo)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+			"error":   appErr.Details,
+		})
+		return
+	}
+
+	h.respondWithLogin(c, response)
+}
+
+// AppleCallbackHandler handles the form Apple POSTs back (response_mode=form_post)
+func (h *AuthHandlers) AppleCallbackHandler(c *gin.Context) {
+	h.redirectOAuthCallback(c, "apple", c.PostForm("code"), c.PostForm("state"), c.PostForm("user"))
+}
+
+// TwitterLoginHandler initiates X (Twitter) OAuth flow
+func (h *AuthHandlers) TwitterLoginHandler(c *gin.Context) {
+	login := h.startOAuthLogin(c, "twitter", true)
+	if login == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"auth_url": h.twitterOAuth.GetAuthURL(login.State, login.Verifier),
+		"state":    login.State,
+	})
+}
+
+// TwitterExchangeHandler exchanges X OAuth code for token
+func (h *AuthHandlers) TwitterExchangeHandler(c *gin.Context) {
+	type ExchangeRequest struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+
+	var req ExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	verifier, ok := h.finishOAuthLogin(c, "twitter", req.State)
+	if !ok {
+		return
+	}
+
+	response, err := h.twitterOAuth.HandleCallback(c.Request.Context(), req.Code, verifier)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+			"error":   appErr.Details,
+		})
+		return
+	}
+
+	h.respondWithLogin(c, response)
+}
+
+// TwitterCallbackHandler handles X OAuth callback
+func (h *AuthHandlers) TwitterCallbackHandler(c *gin.Context) {
+	h.redirectOAuthCallback(c, "twitter", c.Query("code"), c.Query("state"), "")
+}
+
+// redirectOAuthCallback passes a provider's callback on to the frontend, which then
+// calls the matching exchange endpoint
+func (h *AuthHandlers) redirectOAuthCallback(c *gin.Context, provider, code, state, user string) {
+	params := url.Values{"provider": {provider}}
+	if code == "" {
+		params.Set("error", "no_code")
+	} else {
+		params.Set("code", code)
+		params.Set("state", state)
+		if user != "" {
+			params.Set("user", user)
+		}
+	}
+
+	// 303 so that Apple's POST is followed with a GET
+	c.Redirect(http.StatusSeeOther, strings.TrimRight(h.config.Email.FrontendURL, "/")+"/auth/callback?"+params.Encode())
+}
+
 // SendSignupOTPHandler sends OTP for signup flow
 func (h *AuthHandlers) SendSignupOTPHandler(c *gin.Context) {
 	var req struct {
@@ -571,7 +698,7 @@ func (h *AuthHandlers) SendSignupOTPHandler(c *gin.Context) {
 func (h *AuthHandlers) VerifySignupOTPHandler(c *gin.Context) {
 	var req struct {
 		Email string `json:"email" validate:"required,email"`
-		Code  string `json:"code" validate:"required,len=6"`
+		Code  string `json:"code" validate:"required,min=4,max=12"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -653,8 +780,8 @@ func (h *AuthHandlers) CheckUsernameHandler(c *gin.Context) {
 // OAuthCompleteProfileHandler handles OAuth profile completion
 func (h *AuthHandlers) OAuthCompleteProfileHandler(c *gin.Context) {
 	// Get user ID from JWT (set by auth middleware)
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "User not authenticated",
@@ -662,15 +789,6 @@ func (h *AuthHandlers) OAuthCompleteProfileHandler(c *gin.Context) {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	var req struct {
 		Password       string  `json:"password" validate:"required,min=8"`
 		Username       string  `json:"username" validate:"required,min=3,max=20,alphanum"`
@@ -720,7 +838,7 @@ func (h *AuthHandlers) OAuthCompleteProfileHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	h.respondWithSession(c, response)
 }
 
 // SetupRoutes sets up authentication routes with rate limiting
@@ -740,6 +858,18 @@ func (h *AuthHandlers) SetupRoutes(r *gin.RouterGroup) {
 		auth.POST("/reset-password",
 			PasswordResetRateLimitMiddleware(h.distributedLimiter),
 			h.ResetPasswordHandler)
+		auth.POST("/refresh", h.RefreshHandler) // Authenticated by the refresh token in the body
+		auth.POST("/2fa/login",
+			LoginRateLimitMiddleware(h.distributedLimiter),
+			h.TwoFactorLoginHandler) // Authenticated by the interim token in the body
+
+		// Passwordless login by emailed link
+		auth.POST("/magic-link",
+			LoginRateLimitMiddleware(h.distributedLimiter),
+			h.SendMagicLinkHandler)
+		auth.GET("/magic-link/verify",
+			LoginRateLimitMiddleware(h.distributedLimiter),
+			h.VerifyMagicLinkHandler)
 
 		// Send OTP for signup (before registration)
 		auth.POST("/send-signup-otp", h.SendSignupOTPHandler)
@@ -760,13 +890,28 @@ func (h *AuthHandlers) SetupRoutes(r *gin.RouterGroup) {
 		auth.GET("/linkedin/login", h.LinkedInLoginHandler)
 		auth.GET("/linkedin/callback", h.LinkedInCallbackHandler)
 		auth.POST("/linkedin/exchange", h.LinkedInExchangeHandler)
+		auth.GET("/apple/login", h.AppleLoginHandler)
+		auth.POST("/apple/callback", h.AppleCallbackHandler) // Apple POSTs the result (form_post)
+		auth.POST("/apple/exchange", h.AppleExchangeHandler)
+		auth.GET("/twitter/login", h.TwitterLoginHandler)
+		auth.GET("/twitter/callback", h.TwitterCallbackHandler)
+		auth.POST("/twitter/exchange", h.TwitterExchangeHandler)
 
 		// Protected routes (authentication required)
 		auth.GET("/me", JWTAuthMiddleware(h.jwtSvc), h.MeHandler)
-		auth.POST("/refresh", JWTAuthMiddleware(h.jwtSvc), h.RefreshHandler)
 		auth.POST("/logout", JWTAuthMiddleware(h.jwtSvc), h.LogoutHandler)
 		auth.POST("/oauth/complete-profile", JWTAuthMiddleware(h.jwtSvc), h.OAuthCompleteProfileHandler)
 
+		// Two-factor authentication (TOTP)
+		auth.POST("/2fa/setup", JWTAuthMiddleware(h.jwtSvc), h.SetupTwoFactorHandler)
+		auth.POST("/2fa/verify", JWTAuthMiddleware(h.jwtSvc), h.VerifyTwoFactorHandler)
+		auth.POST("/2fa/disable", JWTAuthMiddleware(h.jwtSvc), h.DisableTwoFactorHandler)
+
+		// Logged-in devices
+		auth.GET("/sessions", JWTAuthMiddleware(h.jwtSvc), h.ListSessionsHandler)
+		auth.DELETE("/sessions", JWTAuthMiddleware(h.jwtSvc), h.RevokeOtherSessionsHandler)
+		auth.DELETE("/sessions/:id", JWTAuthMiddleware(h.jwtSvc), h.RevokeSessionHandler)
+
 		// Multi-account routes (Instagram-style account switching)
 		auth.GET("/accounts", JWTAuthMiddleware(h.jwtSvc), h.GetLinkedAccounts)
 		auth.POST("/accounts/link", JWTAuthMiddleware(h.jwtSvc), h.LinkAccount)
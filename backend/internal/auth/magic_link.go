@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/queue"
+	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// Cache key prefixes for login links. An issued link is recorded under its jti with
+// the email it was sent to; spending it claims the spent key first, so two clicks
+// racing on the same link can't both log in.
+const (
+	magicLinkPrefix      = "magic_link:"
+	magicLinkSpentPrefix = "magic_link_spent:"
+)
+
+// magicLinkSentMessage answers every link request, so it can't be used to find out
+// which emails have accounts
+const magicLinkSentMessage = "If an account exists for that email, a login link has been sent."
+
+// SendMagicLink emails a single-use login link to email if it belongs to an active,
+// verified account. The answer is the same either way.
+func (s *AuthService) SendMagicLink(ctx context.Context, req *models.MagicLinkRequest) (*models.MessageResponse, error) {
+	if s.cacheProvider == nil {
+		return nil, errors.ErrMagicLinkDisabled
+	}
+
+	sent := &models.MessageResponse{
+		Success: true,
+		Message: magicLinkSentMessage,
+	}
+
+	email := utils.NormalizeEmail(req.Email)
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil || !user.IsActive || !user.IsEmailVerified {
+		return sent, nil
+	}
+
+	linkID := uuid.New().String()
+	token, err := s.jwtSvc.GenerateMagicLinkToken(user, linkID)
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	if err := s.cacheProvider.Set(ctx, magicLinkPrefix+linkID, user.Email, utils.MagicLinkTTL); err != nil {
+		log.Printf("[AuthService] Failed to record login link: %v", err)
+		return nil, errors.ErrInternalServer
+	}
+
+	if s.queueProvider != nil {
+		if err := queue.QueueMagicLinkEmail(ctx, s.queueProvider, user.Email, token); err != nil {
+			log.Printf("[AuthService] Failed to queue login link email: %v", err)
+			if fallbackErr := s.emailSvc.SendMagicLinkEmail(user.Email, token); fallbackErr != nil {
+				log.Printf("[AuthService] Failed to send login link email (fallback): %v", fallbackErr)
+			}
+		}
+	} else if err := s.emailSvc.SendMagicLinkEmail(user.Email, token); err != nil {
+		log.Printf("[AuthService] Failed to send login link email: %v", err)
+	}
+
+	return sent, nil
+}
+
+// VerifyMagicLink spends a login link and logs its user in. The link only works for
+// the address it was sent to, so it dies if the account's email changes meanwhile.
+func (s *AuthService) VerifyMagicLink(ctx context.Context, token string) (*models.AuthResponse, error) {
+	if s.cacheProvider == nil {
+		return nil, errors.ErrMagicLinkDisabled
+	}
+
+	claims, err := s.jwtSvc.ValidateMagicLinkToken(token)
+	if err != nil {
+		return nil, errors.ErrMagicLinkInvalid
+	}
+
+	sentTo, err := s.cacheProvider.Get(ctx, magicLinkPrefix+claims.ID)
+	if err != nil || sentTo == "" || sentTo != claims.Email {
+		return nil, errors.ErrMagicLinkInvalid
+	}
+	claimed, err := s.cacheProvider.SetNX(ctx, magicLinkSpentPrefix+claims.ID, "1", time.Until(claims.ExpiresAt.Time))
+	if err != nil {
+		log.Printf("[AuthService] Failed to spend login link: %v", err)
+		return nil, errors.ErrInternalServer
+	}
+	if !claimed {
+		return nil, errors.ErrMagicLinkInvalid
+	}
+	s.cacheProvider.Delete(ctx, magicLinkPrefix+claims.ID)
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errors.ErrMagicLinkInvalid
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user.Email != sentTo {
+		return nil, errors.ErrMagicLinkInvalid
+	}
+	if !user.IsActive {
+		return nil, errors.ErrUserInactive
+	}
+
+	accessToken, err := s.jwtSvc.GenerateToken(user)
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	s.userRepo.UpdateLastLogin(ctx, user.ID)
+
+	return &models.AuthResponse{
+		Success:   true,
+		Message:   "Login successful",
+		Token:     accessToken,
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
+		User:      user.ToSafeUser(),
+	}, nil
+}
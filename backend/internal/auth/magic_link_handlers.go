@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"net/http"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SendMagicLinkHandler handles POST /api/v1/auth/magic-link
+func (h *AuthHandlers) SendMagicLinkHandler(c *gin.Context) {
+	var req models.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authSvc.SendMagicLink(c.Request.Context(), &req)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyMagicLinkHandler handles GET /api/v1/auth/magic-link/verify?token=. The link
+// stands in for the password only, so accounts with 2FA still owe their code.
+func (h *AuthHandlers) VerifyMagicLinkHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   "token is required",
+		})
+		return
+	}
+
+	response, err := h.authSvc.VerifyMagicLink(c.Request.Context(), token)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	h.respondWithLogin(c, response)
+}
@@ -1,20 +1,16 @@
 package auth
 
 import (
-	"log"
 	"net/http"
 	"strings"
-	"time"
 
-	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/utils"
-	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
-// JWTAuthMiddleware validates JWT tokens
+// JWTAuthMiddleware validates JWT access tokens. They are short-lived; clients renew
+// them with a refresh token at POST /auth/refresh.
 func JWTAuthMiddleware(jwtSvc *utils.JWTService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
@@ -53,30 +49,13 @@ func JWTAuthMiddleware(jwtSvc *utils.JWTService) gin.HandlerFunc {
 		}
 
 		// Set user information in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_username", claims.Username)
-
-		// Sliding Window Token Refresh
-		// If token is more than halfway to expiry (15+ days old), issue a new one
-		timeUntilExpiry := time.Until(claims.ExpiresAt.Time)
-		halfLife := 15 * 24 * time.Hour // Half of 30 days
-
-		if timeUntilExpiry < halfLife {
-			// Token is past halfway - issue a fresh 30-day token
-			userID, _ := uuid.Parse(claims.UserID)
-			user := &models.User{
-				ID:       userID,
-				Email:    claims.Email,
-				Username: claims.Username,
-			}
-
-			newToken, err := jwtSvc.GenerateToken(user)
-			if err == nil {
-				// Send new token in response header for frontend to update
-				c.Header("X-New-Token", newToken)
-				log.Printf("[Auth] Token refreshed for user %s (was expiring in %v, refreshed to 30 days)", claims.UserID, timeUntilExpiry)
-			}
+		if err := utils.SetCurrentUser(c, claims); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Invalid or expired token",
+			})
+			c.Abort()
+			return
 		}
 
 		// Continue to next handler
@@ -107,59 +86,12 @@ func OptionalJWTAuthMiddleware(jwtSvc *utils.JWTService) gin.HandlerFunc {
 			return
 		}
 
-		// Set user information in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_username", claims.Username)
+		// Set user information in context (a malformed token is treated as anonymous)
+		utils.SetCurrentUser(c, claims)
 
 		c.Next()
 	}
 }
 
-// GetCurrentUserID extracts user ID from context
-func GetCurrentUserID(c *gin.Context) (string, error) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		return "", errors.ErrUnauthorized
-	}
-
-	userIDStr, ok := userID.(string)
-	if !ok {
-		return "", errors.ErrUnauthorized
-	}
-
-	return userIDStr, nil
-}
-
-// GetCurrentUserEmail extracts user email from context
-func GetCurrentUserEmail(c *gin.Context) (string, error) {
-	email, exists := c.Get("user_email")
-	if !exists {
-		return "", errors.ErrUnauthorized
-	}
-
-	emailStr, ok := email.(string)
-	if !ok {
-		return "", errors.ErrUnauthorized
-	}
-
-	return emailStr, nil
-}
-
-// GetCurrentUserUsername extracts username from context
-func GetCurrentUserUsername(c *gin.Context) (string, error) {
-	username, exists := c.Get("user_username")
-	if !exists {
-		return "", errors.ErrUnauthorized
-	}
-
-	usernameStr, ok := username.(string)
-	if !ok {
-		return "", errors.ErrUnauthorized
-	}
-
-	return usernameStr, nil
-}
-
 // Note: jwtService needs to be initialized in the handlers file
 // This will be resolved when we update the handlers to include the JWT service
@@ -2,7 +2,9 @@ package auth
 
 import (
 	"net/http"
+	"time"
 
+	"histeeria-backend/internal/utils"
 	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
@@ -12,8 +14,8 @@ import (
 // GetLinkedAccounts handles GET /api/v1/auth/accounts
 // Returns all accounts linked to the current user's account group
 func (h *AuthHandlers) GetLinkedAccounts(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -21,15 +23,6 @@ func (h *AuthHandlers) GetLinkedAccounts(c *gin.Context) {
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	accounts, err := h.multiAccountSvc.GetLinkedAccounts(c.Request.Context(), uid)
 	if err != nil {
 		appErr := errors.GetAppError(err)
@@ -50,8 +43,8 @@ func (h *AuthHandlers) GetLinkedAccounts(c *gin.Context) {
 // LinkAccount handles POST /api/v1/auth/accounts/link
 // Links another account to the current user's account group
 func (h *AuthHandlers) LinkAccount(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -59,15 +52,6 @@ func (h *AuthHandlers) LinkAccount(c *gin.Context) {
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	var req LinkAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -95,8 +79,8 @@ func (h *AuthHandlers) LinkAccount(c *gin.Context) {
 // SwitchAccount handles POST /api/v1/auth/accounts/switch
 // Switches to another account in the same account group
 func (h *AuthHandlers) SwitchAccount(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -104,15 +88,6 @@ func (h *AuthHandlers) SwitchAccount(c *gin.Context) {
 		return
 	}
 
-	currentUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	var req struct {
 		TargetUserID string `json:"target_user_id" binding:"required"`
 	}
@@ -145,14 +120,28 @@ func (h *AuthHandlers) SwitchAccount(c *gin.Context) {
 		return
 	}
 
+	// The switched-to account gets its own session and refresh token
+	refreshToken, expiresAt, err := h.authSvc.StartSession(c.Request.Context(), response.Token, sessionDevice(c))
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": "Failed to start session",
+			"error":   appErr.Details,
+		})
+		return
+	}
+	response.RefreshToken = refreshToken
+	response.RefreshExpiresAt = expiresAt.Format(time.RFC3339)
+
 	c.JSON(http.StatusOK, response)
 }
 
 // UnlinkAccount handles DELETE /api/v1/auth/accounts/unlink/:userId
 // Removes an account from the account group
 func (h *AuthHandlers) UnlinkAccount(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -160,15 +149,6 @@ func (h *AuthHandlers) UnlinkAccount(c *gin.Context) {
 		return
 	}
 
-	currentUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	targetUserID := c.Param("userId")
 	targetUID, err := uuid.Parse(targetUserID)
 	if err != nil {
@@ -199,8 +179,8 @@ func (h *AuthHandlers) UnlinkAccount(c *gin.Context) {
 // SetPrimaryAccount handles POST /api/v1/auth/accounts/primary
 // Sets which account is primary in the account group
 func (h *AuthHandlers) SetPrimaryAccount(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -208,15 +188,6 @@ func (h *AuthHandlers) SetPrimaryAccount(c *gin.Context) {
 		return
 	}
 
-	currentUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	var req struct {
 		TargetUserID string `json:"target_user_id" binding:"required"`
 	}
@@ -45,11 +45,13 @@ type LinkAccountRequest struct {
 
 // SwitchAccountResponse represents the response when switching accounts
 type SwitchAccountResponse struct {
-	Success   bool         `json:"success"`
-	Message   string       `json:"message"`
-	Token     string       `json:"token"`
-	ExpiresAt string       `json:"expires_at"`
-	User      *models.User `json:"user"`
+	Success          bool         `json:"success"`
+	Message          string       `json:"message"`
+	Token            string       `json:"token"`
+	ExpiresAt        string       `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token,omitempty"`
+	RefreshExpiresAt string       `json:"refresh_expires_at,omitempty"`
+	User             *models.User `json:"user"`
 }
 
 // GetLinkedAccounts returns all accounts linked to the current user's account group
@@ -163,7 +165,7 @@ func (s *MultiAccountService) SwitchAccount(ctx context.Context, currentUserID,
 		Success:   true,
 		Message:   "Account switched successfully",
 		Token:     token,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour).Format(time.RFC3339),
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()).Format(time.RFC3339),
 		User:      targetUser.ToSafeUser(),
 	}, nil
 }
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Cache key prefix for the new account verdict, and how long it is trusted. A user
+// who gains followers or verifies their email is let out at most this much later.
+const (
+	newAccountPrefix     = "new_account:"
+	newAccountVerdictTTL = 5 * time.Minute
+)
+
+// NewAccountGuard applies the new account restrictions to routes: tighter rate limits
+// during the probation period, and no bulk operations.
+type NewAccountGuard struct {
+	rules         models.NewAccountRestrictions
+	userRepo      repository.UserRepository
+	limiter       cache.RateLimiterInterface
+	cacheProvider cache.CacheProvider // Remembers verdicts; nil looks the user up every time
+}
+
+// NewNewAccountGuard creates a guard for rules
+func NewNewAccountGuard(rules models.NewAccountRestrictions, userRepo repository.UserRepository, limiter cache.RateLimiterInterface, cacheProvider cache.CacheProvider) *NewAccountGuard {
+	return &NewAccountGuard{
+		rules:         rules,
+		userRepo:      userRepo,
+		limiter:       limiter,
+		cacheProvider: cacheProvider,
+	}
+}
+
+// IsRestricted reports whether userID is still in the probation period. Lookup errors
+// let the user through; the regular limits still apply.
+func (g *NewAccountGuard) IsRestricted(ctx context.Context, userID uuid.UUID) bool {
+	if g.rules.Period <= 0 {
+		return false
+	}
+
+	key := newAccountPrefix + userID.String()
+	if g.cacheProvider != nil {
+		if verdict, err := g.cacheProvider.Get(ctx, key); err == nil {
+			return verdict == "1"
+		}
+	}
+
+	user, err := g.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("[NewAccountGuard] Failed to load user %s: %v", userID, err)
+		return false
+	}
+
+	restricted := g.rules.Applies(user)
+	if g.cacheProvider != nil {
+		verdict, ttl := "0", newAccountVerdictTTL
+		if restricted {
+			verdict = "1"
+			// Don't hold the restriction past the end of the period
+			if left := time.Until(g.rules.Until(user)); left < ttl {
+				ttl = left
+			}
+		}
+		if err := g.cacheProvider.Set(ctx, key, verdict, ttl); err != nil {
+			log.Printf("[NewAccountGuard] Failed to cache verdict for %s: %v", userID, err)
+		}
+	}
+	return restricted
+}
+
+// Limit rate limits action to limit per window for accounts in the probation period.
+// Everyone else, and every account when limit is 0, passes straight through.
+func (g *NewAccountGuard) Limit(action string, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := utils.CurrentUserID(c)
+		if limit <= 0 || !ok || !g.IsRestricted(c.Request.Context(), userID) {
+			c.Next()
+			return
+		}
+
+		key := cache.NewAccountRateLimitKey(userID.String(), action)
+
+		allowed, remaining, resetTime := g.limiter.Allow(c.Request.Context(), key, limit, window)
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
+
+		if !allowed {
+			retryAfter := int(time.Until(resetTime).Seconds())
+			if retryAfter < 0 {
+				retryAfter = 0
+			}
+
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success":     false,
+				"message":     "New accounts have lower limits for a while. Please slow down.",
+				"error":       "new_account_rate_limit_exceeded",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// BlockBulk refuses bulk operations to accounts in the probation period, unless the
+// rules allow them
+func (g *NewAccountGuard) BlockBulk() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := utils.CurrentUserID(c)
+		if g.rules.AllowBulk || !ok || !g.IsRestricted(c.Request.Context(), userID) {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "This isn't available to new accounts yet.",
+			"error":   "new_account_restricted",
+		})
+		c.Abort()
+	}
+}
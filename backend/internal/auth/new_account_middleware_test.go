@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// fakeUserRepo serves users from a map and counts lookups; anything else panics
+// through the nil embedded interface
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	users   map[uuid.UUID]*models.User
+	lookups int
+}
+
+func (r *fakeUserRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.lookups++
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// newGuardedRouter serves POST /posts and POST /bulk behind guard, signed in as the
+// user in the X-User header
+func newGuardedRouter(guard *NewAccountGuard, postLimit int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	signIn := func(c *gin.Context) {
+		utils.SetCurrentUser(c, &models.JWTClaims{UserID: c.GetHeader("X-User")})
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	r := gin.New()
+	r.POST("/posts", signIn, guard.Limit("post", postLimit, time.Hour), ok)
+	r.POST("/bulk", signIn, guard.BlockBulk(), ok)
+	return r
+}
+
+func serveAs(r *gin.Engine, path string, userID uuid.UUID) int {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.Header.Set("X-User", userID.String())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestNewAccountGuardLimitsFreshAccountsOnly(t *testing.T) {
+	fresh := &models.User{ID: uuid.New(), CreatedAt: time.Now().Add(-time.Hour)}
+	matured := &models.User{ID: uuid.New(), CreatedAt: time.Now().Add(-30 * 24 * time.Hour)}
+	users := &fakeUserRepo{users: map[uuid.UUID]*models.User{fresh.ID: fresh, matured.ID: matured}}
+	limiter := cache.NewInMemoryRateLimiter(0)
+	t.Cleanup(limiter.Stop)
+	guard := NewNewAccountGuard(models.NewAccountRestrictions{Period: 7 * 24 * time.Hour}, users, limiter, cache.NewMemoryProvider())
+	r := newGuardedRouter(guard, 2)
+
+	for i := 0; i < 2; i++ {
+		if code := serveAs(r, "/posts", fresh.ID); code != http.StatusOK {
+			t.Fatalf("fresh post %d: status %d, want 200", i+1, code)
+		}
+	}
+	if code := serveAs(r, "/posts", fresh.ID); code != http.StatusTooManyRequests {
+		t.Errorf("fresh post past the limit: status %d, want 429", code)
+	}
+	if code := serveAs(r, "/bulk", fresh.ID); code != http.StatusForbidden {
+		t.Errorf("fresh bulk operation: status %d, want 403", code)
+	}
+
+	for i := 0; i < 5; i++ {
+		if code := serveAs(r, "/posts", matured.ID); code != http.StatusOK {
+			t.Fatalf("matured post %d: status %d, want 200", i+1, code)
+		}
+	}
+	if code := serveAs(r, "/bulk", matured.ID); code != http.StatusOK {
+		t.Errorf("matured bulk operation: status %d, want 200", code)
+	}
+
+	// Verdicts are cached, so each user is looked up once
+	if users.lookups != 2 {
+		t.Errorf("looked users up %d times, want once each", users.lookups)
+	}
+}
+
+func TestNewAccountGuardDisabled(t *testing.T) {
+	fresh := &models.User{ID: uuid.New(), CreatedAt: time.Now()}
+	users := &fakeUserRepo{users: map[uuid.UUID]*models.User{fresh.ID: fresh}}
+	limiter := cache.NewInMemoryRateLimiter(0)
+	t.Cleanup(limiter.Stop)
+	r := newGuardedRouter(NewNewAccountGuard(models.NewAccountRestrictions{}, users, limiter, nil), 1)
+
+	for i := 0; i < 3; i++ {
+		if code := serveAs(r, "/posts", fresh.ID); code != http.StatusOK {
+			t.Fatalf("post %d: status %d, want 200 with no probation period", i+1, code)
+		}
+	}
+	if code := serveAs(r, "/bulk", fresh.ID); code != http.StatusOK {
+		t.Errorf("bulk operation: status %d, want 200", code)
+	}
+	if users.lookups != 0 {
+		t.Errorf("looked the user up %d times, want none", users.lookups)
+	}
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"histeeria-backend/internal/config"
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	appleIssuer  = "https://appleid.apple.com"
+	appleKeysURL = "https://appleid.apple.com/auth/keys"
+
+	// appleRelayDomain is where "Hide My Email" addresses live. Each one is unique to
+	// this app, so it can never match an account made some other way.
+	appleRelayDomain = "@privaterelay.appleid.com"
+
+	// appleKeysRefreshInterval limits refetching Apple's keys when a token names one
+	// we don't have, so forged kids can't make us hammer the endpoint
+	appleKeysRefreshInterval = time.Minute
+)
+
+// AppleOAuthService handles Sign in with Apple
+type AppleOAuthService struct {
+	config     *oauth2.Config
+	teamID     string
+	keyID      string
+	signingKey *ecdsa.PrivateKey
+	userRepo   repository.UserRepository
+	jwtSvc     *utils.JWTService
+	http       *http.Client
+
+	keysMu        sync.Mutex
+	keys          map[string]*rsa.PublicKey // Apple's identity token keys by kid
+	keysFetchedAt time.Time
+}
+
+// AppleUserInfo is the user JSON Apple posts to the callback, only on the first
+// authorization. It's the only place Apple ever gives the user's name.
+type AppleUserInfo struct {
+	Name struct {
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+	} `json:"name"`
+	Email string `json:"email"`
+}
+
+// appleIDTokenClaims are the claims of Apple's identity token. Apple has sent the
+// boolean claims both as booleans and as "true"/"false" strings.
+type appleIDTokenClaims struct {
+	Email          string      `json:"email"`
+	EmailVerified  interface{} `json:"email_verified"`
+	IsPrivateEmail interface{} `json:"is_private_email"`
+	jwt.RegisteredClaims
+}
+
+// NewAppleOAuthService creates a new Sign in with Apple service. An unparseable key
+// leaves the service unconfigured; config validation reports it at startup.
+func NewAppleOAuthService(cfg *config.AppleConfig, userRepo repository.UserRepository, jwtSvc *utils.JWTService) *AppleOAuthService {
+	signingKey, _ := cfg.SigningKey()
+	return &AppleOAuthService{
+		config: &oauth2.Config{
+			ClientID:    cfg.ClientID,
+			RedirectURL: cfg.RedirectURL,
+			Scopes:      []string{"name", "email"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   appleIssuer + "/auth/authorize",
+				TokenURL:  appleIssuer + "/auth/token",
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		teamID:     cfg.TeamID,
+		keyID:      cfg.KeyID,
+		signingKey: signingKey,
+		userRepo:   userRepo,
+		jwtSvc:     jwtSvc,
+		http:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetAuthURL returns the Apple authorization URL. Asking for name and email requires
+// form_post, so Apple POSTs the result to the callback instead of redirecting.
+func (s *AppleOAuthService) GetAuthURL(state string) string {
+	return s.config.AuthCodeURL(state, oauth2.SetAuthURLParam("response_mode", "form_post"))
+}
+
+// HandleCallback exchanges an authorization code, verifies the identity token that
+// comes back and signs its user in. userInfo is the user JSON from the callback, if
+// there was one.
+func (s *AppleOAuthService) HandleCallback(ctx context.Context, code string, userInfo *AppleUserInfo) (*models.AuthResponse, error) {
+	if s.signingKey == nil {
+		return nil, fmt.Errorf("sign in with Apple is not configured")
+	}
+
+	clientSecret, err := s.clientSecret()
+	if err != nil {
+		log.Printf("[AppleOAuth] Failed to sign client secret: %v", err)
+		return nil, fmt.Errorf("failed to sign client secret: %w", err)
+	}
+	cfg := *s.config
+	cfg.ClientSecret = clientSecret
+
+	token, err := cfg.Exchange(ctx, code)
+	if err != nil {
+		log.Printf("[AppleOAuth] Failed to exchange code: %v", err)
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	idToken, _ := token.Extra("id_token").(string)
+	if idToken == "" {
+		return nil, fmt.Errorf("apple returned no identity token")
+	}
+
+	claims, err := s.verifyIDToken(ctx, idToken)
+	if err != nil {
+		log.Printf("[AppleOAuth] Invalid identity token: %v", err)
+		return nil, fmt.Errorf("invalid identity token: %w", err)
+	}
+
+	email := strings.ToLower(claims.Email)
+	private := appleBool(claims.IsPrivateEmail) || strings.HasSuffix(email, appleRelayDomain)
+	identity := &oauthIdentity{
+		Provider:   "apple",
+		ProviderID: claims.Subject,
+		Email:      email,
+		// Relay addresses are new to us by construction, so only a real, verified
+		// address may claim an existing account
+		LinkByEmail: email != "" && appleBool(claims.EmailVerified) && !private,
+	}
+	if userInfo != nil {
+		identity.DisplayName = strings.TrimSpace(userInfo.Name.FirstName + " " + userInfo.Name.LastName)
+	}
+	if private {
+		// A relay address's local part is random, so base the username on the name
+		identity.Username = identity.DisplayName
+		if identity.Username == "" {
+			identity.Username = "appleuser"
+		}
+	}
+
+	return signInWithOAuth(ctx, s.userRepo, s.jwtSvc, identity)
+}
+
+// clientSecret signs the short-lived JWT Apple takes in place of a client secret
+func (s *AppleOAuthService) clientSecret() (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer:    s.teamID,
+		Subject:   s.config.ClientID,
+		Audience:  jwt.ClaimStrings{appleIssuer},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	})
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.signingKey)
+}
+
+// verifyIDToken checks an identity token's signature against Apple's published keys,
+// and that it was issued by Apple for this app
+func (s *AppleOAuthService) verifyIDToken(ctx context.Context, idToken string) (*appleIDTokenClaims, error) {
+	claims := &appleIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(appleIssuer),
+		jwt.WithAudience(s.config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("identity token has no subject")
+	}
+	return claims, nil
+}
+
+// publicKey returns Apple's key kid, refetching the key set if it's new to us
+func (s *AppleOAuthService) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(s.keysFetchedAt) < appleKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	keys, err := s.fetchKeys(ctx)
+	s.keysFetchedAt = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	s.keys = keys
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// fetchKeys downloads Apple's identity token key set (a JWKS)
+func (s *AppleOAuthService) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, appleKeysURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Apple keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch Apple keys: HTTP %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to parse Apple keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// appleBool reads one of Apple's boolean claims
+func appleBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
@@ -216,7 +216,7 @@ func (s *GitHubOAuthService) HandleCallback(ctx context.Context, code string) (*
 		Success:   true,
 		Message:   "GitHub authentication successful",
 		Token:     jwtToken,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
 		User:      user.ToSafeUser(),
 	}, nil
 }
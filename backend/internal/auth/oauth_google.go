@@ -173,7 +173,7 @@ func (s *GoogleOAuthService) HandleCallback(ctx context.Context, code string) (*
 		Success:   true,
 		Message:   "Google authentication successful",
 		Token:     jwtToken,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
 		User:      user.ToSafeUser(),
 	}, nil
 }
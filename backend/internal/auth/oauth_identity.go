@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// oauthIdentity is what a provider told us about the account signing in
+type oauthIdentity struct {
+	Provider    string // Column prefix of the provider's ID, e.g. "apple"
+	ProviderID  string
+	Email       string // May be empty if the provider didn't share one
+	LinkByEmail bool   // Email is verified and may claim an existing account with it
+	Username    string // Preferred username; taken ones get a suffix
+	DisplayName string
+	AvatarURL   string
+}
+
+// placeholderEmailDomain is used for accounts whose provider shares no email. The
+// .invalid TLD can never resolve, so nothing is ever delivered there; the user can
+// set a real address from account settings.
+const placeholderEmailDomain = "users.histeeria.invalid"
+
+// signInWithOAuth finds the account for identity, linking or creating one as needed,
+// and issues its access token. Accounts are matched by provider ID first, then by
+// email when the provider vouches for it.
+func signInWithOAuth(ctx context.Context, userRepo repository.UserRepository, jwtSvc *utils.JWTService, identity *oauthIdentity) (*models.AuthResponse, error) {
+	user, err := getUserByOAuthID(ctx, userRepo, identity.Provider, identity.ProviderID)
+	if err != nil && identity.LinkByEmail && identity.Email != "" {
+		if existing, emailErr := userRepo.GetUserByEmail(ctx, identity.Email); emailErr == nil {
+			if err := userRepo.LinkOAuthID(ctx, existing.ID, identity.Provider, identity.ProviderID); err != nil {
+				log.Printf("[OAuth] Failed to link %s account: %v", identity.Provider, err)
+				return nil, errors.ErrDatabaseError
+			}
+			user, err = existing, nil
+		}
+	}
+	if err != nil {
+		user, err = createOAuthUser(ctx, userRepo, identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !user.IsActive {
+		return nil, errors.ErrUserInactive
+	}
+
+	token, err := jwtSvc.GenerateToken(user)
+	if err != nil {
+		log.Printf("[OAuth] Failed to generate token: %v", err)
+		return nil, errors.ErrInternalServer
+	}
+	userRepo.UpdateLastLogin(ctx, user.ID)
+
+	return &models.AuthResponse{
+		Success:   true,
+		Message:   "Authentication successful",
+		Token:     token,
+		ExpiresAt: time.Now().Add(jwtSvc.Expiry()),
+		User:      user.ToSafeUser(),
+	}, nil
+}
+
+func getUserByOAuthID(ctx context.Context, userRepo repository.UserRepository, provider, providerID string) (*models.User, error) {
+	switch provider {
+	case "apple":
+		return userRepo.GetUserByAppleID(ctx, providerID)
+	case "twitter":
+		return userRepo.GetUserByTwitterID(ctx, providerID)
+	}
+	return nil, errors.ErrUserNotFound
+}
+
+// createOAuthUser signs up a new account from identity
+func createOAuthUser(ctx context.Context, userRepo repository.UserRepository, identity *oauthIdentity) (*models.User, error) {
+	email := identity.Email
+	verified := email != ""
+	if email == "" {
+		email = identity.Provider + "_" + identity.ProviderID + "@" + placeholderEmailDomain
+	}
+
+	username := identity.Username
+	if username == "" {
+		username = utils.GenerateUsernameFromEmail(email)
+	}
+	username = utils.GenerateUniqueUsername(username, func(u string) bool {
+		exists, _ := userRepo.CheckUsernameExists(ctx, u)
+		return exists
+	})
+
+	displayName := identity.DisplayName
+	if displayName == "" {
+		displayName = username
+	}
+
+	provider := identity.Provider
+	providerID := identity.ProviderID
+	user := &models.User{
+		ID:              uuid.New(),
+		Email:           email,
+		EmailHash:       utils.GenerateEmailHash(email),
+		Username:        username,
+		DisplayName:     displayName,
+		Age:             18,
+		IsEmailVerified: verified,
+		OAuthProvider:   &provider,
+		IsActive:        true,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	switch provider {
+	case "apple":
+		user.AppleID = &providerID
+	case "twitter":
+		user.TwitterID = &providerID
+	}
+	if identity.AvatarURL != "" {
+		user.ProfilePicture = &identity.AvatarURL
+	}
+
+	if err := userRepo.CreateUser(ctx, user); err != nil {
+		log.Printf("[OAuth] Failed to create %s user: %v", provider, err)
+		return nil, err
+	}
+	return user, nil
+}
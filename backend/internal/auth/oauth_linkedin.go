@@ -180,7 +180,7 @@ func (s *LinkedInOAuthService) HandleCallback(ctx context.Context, code string)
 		Success:   true,
 		Message:   "LinkedIn authentication successful",
 		Token:     jwtToken,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
 		User:      user.ToSafeUser(),
 	}, nil
 }
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"time"
+
+	"histeeria-backend/pkg/errors"
+
+	"golang.org/x/oauth2"
+)
+
+// Cache key prefixes for OAuth logins in progress. A login is recorded under its
+// state; finishing it claims the spent key first, so each state works once.
+const (
+	oauthStatePrefix      = "oauth_state:"
+	oauthStateSpentPrefix = "oauth_state_spent:"
+)
+
+// oauthStateTTL is how long a user has to finish logging in with a provider
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthBindingCookie ties an OAuth login to the browser that started it. A state
+// from someone else's login is useless without that browser's cookie.
+const OAuthBindingCookie = "oauth_binding"
+
+// OAuthLogin is an OAuth login in progress
+type OAuthLogin struct {
+	State    string // Sent to the provider, which hands it back with the code
+	Binding  string // Goes in the OAuthBindingCookie of the browser logging in
+	Verifier string // PKCE code verifier; never leaves the server
+}
+
+// oauthLoginRecord is what's remembered about a login while the user is away at the
+// provider. Only a hash of the binding is kept.
+type oauthLoginRecord struct {
+	Provider    string `json:"provider"`
+	BindingHash string `json:"binding_hash"`
+	Verifier    string `json:"verifier,omitempty"`
+}
+
+// BeginOAuthLogin starts a login with provider: a random state, the binding for the
+// browser's cookie and, with pkce, a random code verifier, all remembered for
+// oauthStateTTL
+func (s *AuthService) BeginOAuthLogin(ctx context.Context, provider string, pkce bool) (*OAuthLogin, error) {
+	if s.cacheProvider == nil {
+		log.Printf("[AuthService] OAuth login needs a cache to keep its state in")
+		return nil, errors.ErrInternalServer
+	}
+
+	login := &OAuthLogin{State: randomToken(), Binding: randomToken()}
+	if pkce {
+		login.Verifier = oauth2.GenerateVerifier()
+	}
+
+	record, err := json.Marshal(oauthLoginRecord{
+		Provider:    provider,
+		BindingHash: hashBinding(login.Binding),
+		Verifier:    login.Verifier,
+	})
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	if err := s.cacheProvider.Set(ctx, oauthStatePrefix+login.State, string(record), oauthStateTTL); err != nil {
+		log.Printf("[AuthService] Failed to record OAuth state: %v", err)
+		return nil, errors.ErrInternalServer
+	}
+	return login, nil
+}
+
+// FinishOAuthLogin spends a state started by BeginOAuthLogin for provider, from the
+// browser holding binding, and returns its code verifier. Unknown, expired, reused and
+// other browsers' states are all ErrOAuthStateInvalid.
+func (s *AuthService) FinishOAuthLogin(ctx context.Context, provider, state, binding string) (string, error) {
+	if s.cacheProvider == nil || state == "" || binding == "" {
+		return "", errors.ErrOAuthStateInvalid
+	}
+
+	data, err := s.cacheProvider.Get(ctx, oauthStatePrefix+state)
+	if err != nil || data == "" {
+		return "", errors.ErrOAuthStateInvalid
+	}
+	var record oauthLoginRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return "", errors.ErrOAuthStateInvalid
+	}
+	if record.Provider != provider || subtle.ConstantTimeCompare([]byte(record.BindingHash), []byte(hashBinding(binding))) != 1 {
+		return "", errors.ErrOAuthStateInvalid
+	}
+
+	claimed, err := s.cacheProvider.SetNX(ctx, oauthStateSpentPrefix+state, "1", oauthStateTTL)
+	if err != nil {
+		log.Printf("[AuthService] Failed to spend OAuth state: %v", err)
+		return "", errors.ErrInternalServer
+	}
+	if !claimed {
+		return "", errors.ErrOAuthStateInvalid
+	}
+	s.cacheProvider.Delete(ctx, oauthStatePrefix+state)
+
+	return record.Verifier, nil
+}
+
+// randomToken returns 32 random bytes, URL-safe encoded
+func randomToken() string {
+	b := make([]byte, 32)
+	// crypto/rand.Read never returns an error on supported platforms
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashBinding(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
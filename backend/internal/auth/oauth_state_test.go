@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/pkg/errors"
+)
+
+func newOAuthStateService() *AuthService {
+	s := &AuthService{}
+	s.SetCacheProvider(cache.NewMemoryProvider())
+	return s
+}
+
+func TestOAuthLoginVerifierIsRandomAndServerSide(t *testing.T) {
+	s := newOAuthStateService()
+	ctx := context.Background()
+
+	first, err := s.BeginOAuthLogin(ctx, "twitter", true)
+	if err != nil {
+		t.Fatalf("BeginOAuthLogin: %v", err)
+	}
+	second, _ := s.BeginOAuthLogin(ctx, "twitter", true)
+	if first.Verifier == "" || first.Verifier == second.Verifier || first.State == second.State {
+		t.Fatalf("logins should get fresh states and verifiers: %+v %+v", first, second)
+	}
+
+	verifier, err := s.FinishOAuthLogin(ctx, "twitter", first.State, first.Binding)
+	if err != nil {
+		t.Fatalf("FinishOAuthLogin: %v", err)
+	}
+	if verifier != first.Verifier {
+		t.Errorf("verifier = %q, want the one the login started with", verifier)
+	}
+}
+
+func TestFinishOAuthLoginRejects(t *testing.T) {
+	s := newOAuthStateService()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		finish func(login *OAuthLogin) error
+	}{
+		{"unknown state", func(login *OAuthLogin) error {
+			_, err := s.FinishOAuthLogin(ctx, "twitter", "made-up", login.Binding)
+			return err
+		}},
+		{"another browser", func(login *OAuthLogin) error {
+			_, err := s.FinishOAuthLogin(ctx, "twitter", login.State, "someone-elses-cookie")
+			return err
+		}},
+		{"no cookie", func(login *OAuthLogin) error {
+			_, err := s.FinishOAuthLogin(ctx, "twitter", login.State, "")
+			return err
+		}},
+		{"another provider", func(login *OAuthLogin) error {
+			_, err := s.FinishOAuthLogin(ctx, "apple", login.State, login.Binding)
+			return err
+		}},
+		{"reused state", func(login *OAuthLogin) error {
+			if _, err := s.FinishOAuthLogin(ctx, "twitter", login.State, login.Binding); err != nil {
+				t.Fatalf("first use: %v", err)
+			}
+			_, err := s.FinishOAuthLogin(ctx, "twitter", login.State, login.Binding)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login, err := s.BeginOAuthLogin(ctx, "twitter", true)
+			if err != nil {
+				t.Fatalf("BeginOAuthLogin: %v", err)
+			}
+			if err := tt.finish(login); err != errors.ErrOAuthStateInvalid {
+				t.Errorf("err = %v, want ErrOAuthStateInvalid", err)
+			}
+		})
+	}
+}
+
+func TestAppleLoginHasNoVerifier(t *testing.T) {
+	s := newOAuthStateService()
+	ctx := context.Background()
+
+	login, err := s.BeginOAuthLogin(ctx, "apple", false)
+	if err != nil {
+		t.Fatalf("BeginOAuthLogin: %v", err)
+	}
+	verifier, err := s.FinishOAuthLogin(ctx, "apple", login.State, login.Binding)
+	if err != nil || verifier != "" {
+		t.Errorf("FinishOAuthLogin = %q, %v; want no verifier", verifier, err)
+	}
+}
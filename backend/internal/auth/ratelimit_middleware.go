@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -73,14 +74,12 @@ func DistributedRateLimitMiddleware(limiter cache.RateLimiterInterface, limit in
 func UserRateLimitMiddleware(limiter cache.RateLimiterInterface, limit int, window time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get user ID from context (set by JWT middleware)
-		userID, exists := c.Get("user_id")
-		if !exists {
-			// Fallback to IP-based if no user
-			ip := extractClientIP(c)
-			userID = ip
+		subject := extractClientIP(c) // Fallback to IP-based if no user
+		if userID, ok := utils.CurrentUserID(c); ok {
+			subject = userID.String()
 		}
 
-		key := cache.UserRateLimitKey(userID.(string))
+		key := cache.UserRateLimitKey(subject)
 
 		allowed, remaining, resetTime := limiter.Allow(c.Request.Context(), key, limit, window)
 
@@ -115,9 +114,8 @@ func EndpointRateLimitMiddleware(limiter cache.RateLimiterInterface, endpoint st
 		// Combine user/IP with endpoint
 		var key string
 
-		userID, exists := c.Get("user_id")
-		if exists {
-			key = cache.APIRateLimitKey(userID.(string), endpoint)
+		if userID, ok := utils.CurrentUserID(c); ok {
+			key = cache.APIRateLimitKey(userID.String(), endpoint)
 		} else {
 			ip := extractClientIP(c)
 			key = cache.APIRateLimitKey(ip, endpoint)
@@ -258,8 +256,8 @@ func PasswordResetRateLimitMiddleware(limiter cache.RateLimiterInterface) gin.Ha
 func MessageRateLimitMiddleware(limiter cache.RateLimiterInterface) gin.HandlerFunc {
 	// 60 messages per minute per user
 	return func(c *gin.Context) {
-		userID, exists := c.Get("user_id")
-		if !exists {
+		userID, ok := utils.CurrentUserID(c)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"message": "Authentication required",
@@ -269,7 +267,7 @@ func MessageRateLimitMiddleware(limiter cache.RateLimiterInterface) gin.HandlerF
 			return
 		}
 
-		key := cache.MessageRateLimitKey(userID.(string))
+		key := cache.MessageRateLimitKey(userID.String())
 
 		allowed, remaining, resetTime := limiter.Allow(c.Request.Context(), key, 60, time.Minute)
 
@@ -298,6 +296,56 @@ func MessageRateLimitMiddleware(limiter cache.RateLimiterInterface) gin.HandlerF
 	}
 }
 
+// CommentRateLimitMiddleware limits how many comments a user can post on one post
+// (the :id route param) per window, so a single thread can't be flooded. A limit of 0
+// disables it.
+func CommentRateLimitMiddleware(limiter cache.RateLimiterInterface, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		userID, ok := utils.CurrentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Authentication required",
+				"error":   "unauthorized",
+			})
+			c.Abort()
+			return
+		}
+
+		key := cache.CommentRateLimitKey(userID.String(), c.Param("id"))
+
+		allowed, remaining, resetTime := limiter.Allow(c.Request.Context(), key, limit, window)
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
+
+		if !allowed {
+			retryAfter := int(time.Until(resetTime).Seconds())
+			if retryAfter < 0 {
+				retryAfter = 0
+			}
+
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success":     false,
+				"message":     "You're commenting too fast on this post. Please slow down.",
+				"error":       "comment_rate_limit_exceeded",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // ============================================
 // LEGACY MIDDLEWARE (for backward compatibility)
 // ============================================
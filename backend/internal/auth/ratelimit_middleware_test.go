@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newCommentRouter serves POST /posts/:id/comments behind CommentRateLimitMiddleware,
+// signed in as the user in the X-User header
+func newCommentRouter(t *testing.T, limit int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	limiter := cache.NewInMemoryRateLimiter(0)
+	t.Cleanup(limiter.Stop)
+
+	r := gin.New()
+	r.POST("/posts/:id/comments", func(c *gin.Context) {
+		if id := c.GetHeader("X-User"); id != "" {
+			utils.SetCurrentUser(c, &models.JWTClaims{UserID: id})
+		}
+	}, CommentRateLimitMiddleware(limiter, limit, time.Minute), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	return r
+}
+
+func postComment(r *gin.Engine, postID, userID uuid.UUID) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/posts/"+postID.String()+"/comments", nil)
+	if userID != uuid.Nil {
+		req.Header.Set("X-User", userID.String())
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCommentRateLimitPerUserPerPost(t *testing.T) {
+	r := newCommentRouter(t, 3)
+	user, post := uuid.New(), uuid.New()
+
+	for i := 0; i < 3; i++ {
+		if w := postComment(r, post, user); w.Code != http.StatusCreated {
+			t.Fatalf("comment %d: status %d, want 201", i+1, w.Code)
+		}
+	}
+
+	w := postComment(r, post, user)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("comment past the limit: status %d, want 429", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter <= 0 || retryAfter > 60 {
+		t.Errorf("Retry-After = %q, want seconds within the window", w.Header().Get("Retry-After"))
+	}
+	var body struct {
+		Error      string `json:"error"`
+		RetryAfter int    `json:"retry_after"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Error != "comment_rate_limit_exceeded" || body.RetryAfter != retryAfter {
+		t.Errorf("body = %+v, want the error code and the same retry hint", body)
+	}
+
+	// The limit is per thread and per user
+	if w := postComment(r, uuid.New(), user); w.Code != http.StatusCreated {
+		t.Errorf("another post: status %d, want 201", w.Code)
+	}
+	if w := postComment(r, post, uuid.New()); w.Code != http.StatusCreated {
+		t.Errorf("another user: status %d, want 201", w.Code)
+	}
+}
+
+func TestCommentRateLimitDisabledAndAnonymous(t *testing.T) {
+	r := newCommentRouter(t, 0)
+	user, post := uuid.New(), uuid.New()
+	for i := 0; i < 10; i++ {
+		if w := postComment(r, post, user); w.Code != http.StatusCreated {
+			t.Fatalf("a zero limit should disable the check, got %d", w.Code)
+		}
+	}
+
+	if w := postComment(newCommentRouter(t, 3), post, uuid.Nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: status %d, want 401", w.Code)
+	}
+}
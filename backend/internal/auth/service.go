@@ -27,6 +27,22 @@ type AuthService struct {
 	jwtSvc        *utils.JWTService
 	blacklist     *utils.TokenBlacklist
 	cacheProvider cache.CacheProvider // Generic cache provider (Redis or Memory)
+	sessionRepo   repository.SessionRepository
+	refreshExpiry time.Duration // Lifetime of a refresh token; each rotation starts a new one
+
+	twoFactorRepo   repository.TwoFactorRepository
+	twoFactorKey    []byte // Encrypts TOTP secrets; nil disables setup
+	twoFactorIssuer string
+
+	lockoutMaxFailures int // Failed logins that lock an account; 0 disables lockout
+	lockoutWindow      time.Duration
+	lockoutDuration    time.Duration
+}
+
+// SessionDevice identifies the client a session was started or refreshed from
+type SessionDevice struct {
+	UserAgent string
+	IPAddress string
 }
 
 // NewAuthService creates a new authentication service
@@ -52,6 +68,12 @@ func (s *AuthService) SetCacheProvider(provider cache.CacheProvider) {
 	s.cacheProvider = provider
 }
 
+// SetSessions enables refresh tokens, stored in sessionRepo and valid for refreshExpiry
+func (s *AuthService) SetSessions(sessionRepo repository.SessionRepository, refreshExpiry time.Duration) {
+	s.sessionRepo = sessionRepo
+	s.refreshExpiry = refreshExpiry
+}
+
 // RegisterUser handles user registration
 func (s *AuthService) RegisterUser(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error) {
 	// Validate input
@@ -187,7 +209,7 @@ func (s *AuthService) RegisterUser(ctx context.Context, req *models.RegisterRequ
 				Success:   true,
 				Message:   "Registration and email verification successful",
 				Token:     token,
-				ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+				ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
 				User:      user.ToSafeUser(),
 				UserID:    user.ID.String(),
 			}, nil
@@ -229,11 +251,15 @@ func (s *AuthService) VerifyEmail(ctx context.Context, req *models.VerifyEmailRe
 		return nil, err
 	}
 
-	// Normalize email
+	// Normalize email and code
 	email := utils.NormalizeEmail(req.Email)
+	code := s.emailSvc.NormalizeVerificationCode(req.VerificationCode)
+	if !s.emailSvc.IsValidVerificationCode(code) {
+		return nil, errors.ErrInvalidVerificationCode
+	}
 
 	// Verify email with code
-	if err := s.userRepo.VerifyEmail(ctx, email, req.VerificationCode); err != nil {
+	if err := s.userRepo.VerifyEmail(ctx, email, code); err != nil {
 		return nil, err
 	}
 
@@ -269,7 +295,7 @@ func (s *AuthService) VerifyEmail(ctx context.Context, req *models.VerifyEmailRe
 		Success:   true,
 		Message:   "Email verified successfully",
 		Token:     token,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour), // 30 days
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
 		User:      user.ToSafeUser(),
 	}, nil
 }
@@ -286,7 +312,21 @@ func (s *AuthService) LoginUser(ctx context.Context, req *models.LoginRequest) (
 
 	// Get user by email or username
 	user, err := s.userRepo.GetUserByEmailOrUsername(ctx, emailOrUsername)
+
+	// Failures count per account, so switching between email and username doesn't reset
+	// them; unknown identifiers are counted too, so a lock doesn't reveal an account exists
+	lockSubject := "identifier:" + emailOrUsername
+	if err == nil {
+		lockSubject = user.ID.String()
+	}
+	if lockErr := s.checkLoginLock(ctx, lockSubject); lockErr != nil {
+		return nil, lockErr
+	}
+
 	if err != nil {
+		if lockErr := s.recordLoginFailure(ctx, lockSubject); lockErr != nil {
+			return nil, lockErr
+		}
 		return nil, errors.ErrInvalidCredentials
 	}
 
@@ -297,8 +337,12 @@ func (s *AuthService) LoginUser(ctx context.Context, req *models.LoginRequest) (
 
 	// Verify password
 	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+		if lockErr := s.recordLoginFailure(ctx, lockSubject); lockErr != nil {
+			return nil, lockErr
+		}
 		return nil, errors.ErrInvalidCredentials
 	}
+	s.clearLoginFailures(ctx, lockSubject)
 
 	// Check if email is verified
 	if !user.IsEmailVerified {
@@ -318,7 +362,7 @@ func (s *AuthService) LoginUser(ctx context.Context, req *models.LoginRequest) (
 		Success:   true,
 		Message:   "Login successful",
 		Token:     token,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour), // 30 days
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
 		User:      user.ToSafeUser(),
 	}, nil
 }
@@ -416,33 +460,127 @@ func (s *AuthService) GetCurrentUser(ctx context.Context, userID uuid.UUID) (*mo
 	}, nil
 }
 
-// RefreshToken handles token refresh
-func (s *AuthService) RefreshToken(ctx context.Context, userID uuid.UUID) (*models.TokenResponse, error) {
-	// Get user
-	user, err := s.userRepo.GetUserByID(ctx, userID)
+// StartSession records a login and returns its first refresh token. The session
+// family is the access token's sid, so revoking the family also cuts off access tokens.
+func (s *AuthService) StartSession(ctx context.Context, accessToken string, device SessionDevice) (string, time.Time, error) {
+	if s.sessionRepo == nil {
+		return "", time.Time{}, errors.ErrInternalServer
+	}
+
+	claims, err := s.jwtSvc.ValidateToken(accessToken)
+	if err != nil {
+		return "", time.Time{}, errors.ErrInvalidToken
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return "", time.Time{}, errors.ErrInvalidToken
+	}
+	familyID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return "", time.Time{}, errors.ErrInvalidToken
+	}
+
+	return s.issueRefreshToken(ctx, userID, familyID, time.Now(), device)
+}
+
+// RefreshSession exchanges a refresh token for a new access token and rotates the
+// refresh token. Presenting a token that was already rotated means it was copied, so
+// the whole session is revoked and both holders have to log in again.
+func (s *AuthService) RefreshSession(ctx context.Context, refreshToken string, device SessionDevice) (*models.TokenResponse, error) {
+	if s.sessionRepo == nil {
+		return nil, errors.ErrInvalidRefresh
+	}
+
+	session, err := s.sessionRepo.GetSessionByTokenHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, errors.ErrInvalidRefresh
+	}
+
+	if session.ReplacedAt != nil {
+		s.revokeSessionFamily(ctx, session, "reused refresh token")
+		return nil, errors.ErrRefreshReused
+	}
+	if time.Now().After(session.ExpiresAt) {
+		s.sessionRepo.DeleteSessionFamily(ctx, session.FamilyID)
+		return nil, errors.ErrInvalidRefresh
+	}
+
+	// Only one of two racing refreshes can claim the token; the loser is treated as reuse
+	claimed, err := s.sessionRepo.MarkSessionReplaced(ctx, session.ID)
 	if err != nil {
 		return nil, err
 	}
+	if !claimed {
+		s.revokeSessionFamily(ctx, session, "concurrent refresh")
+		return nil, errors.ErrRefreshReused
+	}
 
-	// Check if user is active
+	user, err := s.userRepo.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
 	if !user.IsActive {
+		s.revokeSessionFamily(ctx, session, "inactive user")
 		return nil, errors.ErrUserInactive
 	}
 
-	// Generate new token
-	token, err := s.jwtSvc.GenerateToken(user)
+	token, err := s.jwtSvc.GenerateSessionToken(user, session.FamilyID)
 	if err != nil {
 		return nil, errors.ErrInternalServer
 	}
 
+	newRefresh, refreshExpiresAt, err := s.issueRefreshToken(ctx, user.ID, session.FamilyID, session.StartedAt, device)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.TokenResponse{
-		Success:   true,
-		Message:   "Token refreshed successfully",
-		Token:     token,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour), // 30 days
+		Success:          true,
+		Message:          "Token refreshed successfully",
+		Token:            token,
+		ExpiresAt:        time.Now().Add(s.jwtSvc.Expiry()),
+		RefreshToken:     newRefresh,
+		RefreshExpiresAt: refreshExpiresAt,
 	}, nil
 }
 
+// issueRefreshToken stores a new current refresh token for a session family
+func (s *AuthService) issueRefreshToken(ctx context.Context, userID, familyID uuid.UUID, startedAt time.Time, device SessionDevice) (string, time.Time, error) {
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", time.Time{}, errors.ErrInternalServer
+	}
+
+	expiresAt := time.Now().Add(s.refreshExpiry)
+	session := &models.UserSession{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		UserAgent: &device.UserAgent,
+		IPAddress: &device.IPAddress,
+		StartedAt: startedAt,
+		ExpiresAt: expiresAt,
+	}
+	if label := utils.DescribeUserAgent(device.UserAgent); label != "" {
+		session.DeviceInfo = &label
+	}
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return refreshToken, expiresAt, nil
+}
+
+// revokeSessionFamily ends a login everywhere: its refresh tokens are deleted and
+// access tokens already issued for it stop validating
+func (s *AuthService) revokeSessionFamily(ctx context.Context, session *models.UserSession, reason string) {
+	log.Printf("[AuthService] Revoking session %s of user %s: %s", session.FamilyID, session.UserID, reason)
+	s.jwtSvc.RevokeSession(session.FamilyID)
+	if err := s.sessionRepo.DeleteSessionFamily(ctx, session.FamilyID); err != nil {
+		log.Printf("[AuthService] Failed to delete session %s: %v", session.FamilyID, err)
+	}
+}
+
 // LogoutUser handles user logout by blacklisting the token
 func (s *AuthService) LogoutUser(ctx context.Context, userID uuid.UUID, token string) (*models.MessageResponse, error) {
 	// Validate the token first to get expiry
@@ -462,6 +600,13 @@ func (s *AuthService) LogoutUser(ctx context.Context, userID uuid.UUID, token st
 		s.blacklist.Add(token, claims.ExpiresAt.Time)
 	}
 
+	// Drop the session's refresh tokens so it can't be renewed
+	if familyID, err := uuid.Parse(claims.SessionID); err == nil && s.sessionRepo != nil {
+		if err := s.sessionRepo.DeleteSessionFamily(ctx, familyID); err != nil {
+			log.Printf("[AuthService] Failed to delete session %s on logout: %v", familyID, err)
+		}
+	}
+
 	return &models.MessageResponse{
 		Success: true,
 		Message: "Logged out successfully",
@@ -529,6 +674,7 @@ func (s *AuthService) SendOTPForSignup(ctx context.Context, email string) (*mode
 // This allows the OTP to be verified on the OTP screen and then used during registration
 func (s *AuthService) VerifySignupOTP(ctx context.Context, email, code string) (bool, error) {
 	normalizedEmail := utils.NormalizeEmail(email)
+	code = s.emailSvc.NormalizeVerificationCode(code)
 
 	if s.cacheProvider == nil {
 		return false, errors.NewAppError(http.StatusInternalServerError, "Cache provider not configured", "Server error")
@@ -557,6 +703,7 @@ func (s *AuthService) VerifySignupOTP(ctx context.Context, email, code string) (
 // ConsumeSignupOTP verifies and deletes OTP from signup flow (used during registration)
 func (s *AuthService) ConsumeSignupOTP(ctx context.Context, email, code string) (bool, error) {
 	normalizedEmail := utils.NormalizeEmail(email)
+	code = s.emailSvc.NormalizeVerificationCode(code)
 
 	if s.cacheProvider == nil {
 		return false, errors.NewAppError(http.StatusInternalServerError, "Cache provider not configured", "Server error")
@@ -656,7 +803,7 @@ func (s *AuthService) CompleteOAuthProfile(ctx context.Context, userID uuid.UUID
 		Success:   true,
 		Message:   "Profile completed successfully",
 		Token:     token,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
 		User:      user.ToSafeUser(),
 	}, nil
 }
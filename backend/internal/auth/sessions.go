@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// ListSessions returns the user's unexpired logins. currentSID is the sid of the
+// caller's access token, used to mark the session the request came from.
+func (s *AuthService) ListSessions(ctx context.Context, userID uuid.UUID, currentSID string) (*models.ActiveSessionsResponse, error) {
+	if s.sessionRepo == nil {
+		return nil, errors.ErrInternalServer
+	}
+
+	rows, err := s.sessionRepo.GetSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := make([]*models.ActiveSession, 0, len(rows))
+	for _, row := range rows {
+		if !row.ExpiresAt.After(now) {
+			continue
+		}
+		startedAt := row.StartedAt
+		if startedAt.IsZero() {
+			startedAt = row.CreatedAt
+		}
+		sessions = append(sessions, &models.ActiveSession{
+			ID:         row.FamilyID,
+			DeviceInfo: row.DeviceInfo,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IPAddress,
+			CreatedAt:  startedAt,
+			LastSeenAt: row.CreatedAt,
+			ExpiresAt:  row.ExpiresAt,
+			IsCurrent:  row.FamilyID.String() == currentSID,
+		})
+	}
+
+	return &models.ActiveSessionsResponse{
+		Success:  true,
+		Sessions: sessions,
+	}, nil
+}
+
+// RevokeSession logs one of the user's sessions out: it can't be refreshed any more,
+// and access tokens already issued for it stop validating
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) (*models.MessageResponse, error) {
+	if s.sessionRepo == nil {
+		return nil, errors.ErrInternalServer
+	}
+
+	deleted, err := s.sessionRepo.DeleteUserSessionFamily(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, errors.ErrSessionNotFound
+	}
+	s.jwtSvc.RevokeSession(sessionID)
+
+	return &models.MessageResponse{
+		Success: true,
+		Message: "Session logged out",
+	}, nil
+}
+
+// RevokeOtherSessions logs the user out everywhere except the session currentSID
+// belongs to
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, currentSID string) (*models.MessageResponse, error) {
+	if s.sessionRepo == nil {
+		return nil, errors.ErrInternalServer
+	}
+
+	var keep *uuid.UUID
+	if current, err := uuid.Parse(currentSID); err == nil {
+		keep = &current
+	}
+
+	// The families have to be known before their rows go, to revoke their access tokens
+	rows, err := s.sessionRepo.GetSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.DeleteAllUserSessions(ctx, userID, keep); err != nil {
+		return nil, err
+	}
+
+	revoked := 0
+	for _, row := range rows {
+		if keep != nil && row.FamilyID == *keep {
+			continue
+		}
+		s.jwtSvc.RevokeSession(row.FamilyID)
+		revoked++
+	}
+	log.Printf("[AuthService] Logged user %s out of %d other sessions", userID, revoked)
+
+	return &models.MessageResponse{
+		Success: true,
+		Message: "Logged out of all other sessions",
+	}, nil
+}
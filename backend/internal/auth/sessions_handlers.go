@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http"
+
+	"histeeria-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListSessionsHandler handles GET /api/v1/auth/sessions
+func (h *AuthHandlers) ListSessionsHandler(c *gin.Context) {
+	user, err := utils.CurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	response, err := h.authSvc.ListSessions(c.Request.Context(), user.ID, user.SessionID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSessionHandler handles DELETE /api/v1/auth/sessions/:id
+func (h *AuthHandlers) RevokeSessionHandler(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid session ID",
+		})
+		return
+	}
+
+	response, err := h.authSvc.RevokeSession(c.Request.Context(), uid, sessionID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeOtherSessionsHandler handles DELETE /api/v1/auth/sessions, logging out every
+// session but the caller's
+func (h *AuthHandlers) RevokeOtherSessionsHandler(c *gin.Context) {
+	user, err := utils.CurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	response, err := h.authSvc.RevokeOtherSessions(c.Request.Context(), user.ID, user.SessionID)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,339 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/encryption"
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// recoveryCodeCount is how many recovery codes enabling 2FA hands out
+const recoveryCodeCount = 10
+
+// maxTwoFactorAttempts is how many wrong codes an interim token takes before it's
+// spent, and twoFactorAttemptsPrefix keys the count per token
+const (
+	maxTwoFactorAttempts    = 5
+	twoFactorAttemptsPrefix = "two_factor_attempts:"
+)
+
+// SetTwoFactor enables TOTP 2FA. key encrypts the secrets at rest and may be nil, in
+// which case setup is refused and TOTP codes can't be checked; the repository is still
+// needed so that accounts with 2FA on are never let in on a password alone.
+func (s *AuthService) SetTwoFactor(repo repository.TwoFactorRepository, key []byte, issuer string) {
+	s.twoFactorRepo = repo
+	s.twoFactorKey = key
+	s.twoFactorIssuer = issuer
+}
+
+// SetupTwoFactor starts 2FA setup with a new secret. Nothing changes for logins until
+// VerifyTwoFactor confirms a code from it; calling this again replaces the secret.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactorSetupResponse, error) {
+	if s.twoFactorRepo == nil || s.twoFactorKey == nil {
+		return nil, errors.ErrTwoFactorUnavailable
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	existing, err := s.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Enabled() {
+		return nil, errors.ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	encrypted, iv, err := encryption.EncryptProfileField(secret, s.twoFactorKey)
+	if err != nil {
+		log.Printf("[Auth] Failed to encrypt 2FA secret: %v", err)
+		return nil, errors.ErrInternalServer
+	}
+
+	if err := s.twoFactorRepo.SavePendingTwoFactor(ctx, &models.TwoFactorSecret{
+		UserID:          userID,
+		SecretEncrypted: encrypted,
+		SecretIV:        iv,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetupResponse{
+		Success:    true,
+		Message:    "Scan the code with your authenticator app, then confirm with a code from it",
+		Secret:     secret,
+		OTPAuthURL: utils.TOTPProvisioningURL(s.twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// VerifyTwoFactor confirms a pending secret with a code from it and turns 2FA on,
+// handing out a fresh set of recovery codes
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, userID uuid.UUID, code string) (*models.TwoFactorEnabledResponse, error) {
+	if s.twoFactorRepo == nil {
+		return nil, errors.ErrTwoFactorUnavailable
+	}
+
+	pending, err := s.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return nil, errors.ErrTwoFactorNotSetUp
+	}
+	if pending.Enabled() {
+		return nil, errors.ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := s.decryptTOTPSecret(pending)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, errors.ErrTwoFactorInvalidCode
+	}
+
+	// Store the recovery codes before enabling, so 2FA is never on without them
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(c))
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.twoFactorRepo.EnableTwoFactor(ctx, userID, step)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, errors.ErrTwoFactorAlreadyEnabled
+	}
+
+	return &models.TwoFactorEnabledResponse{
+		Success:       true,
+		Message:       "Two-factor authentication enabled. Store these recovery codes somewhere safe; each works once.",
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// DisableTwoFactor turns 2FA off. It takes a current code, or a recovery code, so a
+// stolen access token alone can't strip the second factor.
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID uuid.UUID, code string) (*models.MessageResponse, error) {
+	if s.twoFactorRepo == nil {
+		return nil, errors.ErrTwoFactorUnavailable
+	}
+	if err := s.checkSecondFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.DeleteTwoFactor(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return &models.MessageResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	}, nil
+}
+
+// ChallengeTwoFactor holds back a login's access token if the account has 2FA on,
+// answering with an interim token to redeem at CompleteTwoFactorLogin instead. Every
+// login path that issues a token goes through here.
+func (s *AuthService) ChallengeTwoFactor(ctx context.Context, response *models.AuthResponse) (*models.AuthResponse, error) {
+	if s.twoFactorRepo == nil || response.Token == "" || response.User == nil {
+		return response, nil
+	}
+
+	tf, err := s.twoFactorRepo.GetTwoFactor(ctx, response.User.ID)
+	if err != nil {
+		// Fail closed: an account we can't check might have 2FA on
+		return nil, err
+	}
+	if !tf.Enabled() {
+		return response, nil
+	}
+
+	// The password was right, so the access token already signed is just dropped
+	interim, err := s.jwtSvc.GenerateTwoFactorToken(response.User)
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	return &models.AuthResponse{
+		Success:           true,
+		Message:           "Enter the code from your authenticator app",
+		TwoFactorRequired: true,
+		TwoFactorToken:    interim,
+		UserID:            response.User.ID.String(),
+	}, nil
+}
+
+// CompleteTwoFactorLogin exchanges an interim token and a TOTP or recovery code for
+// the login's access token. A successful exchange spends the interim token, as do
+// maxTwoFactorAttempts wrong codes.
+func (s *AuthService) CompleteTwoFactorLogin(ctx context.Context, twoFactorToken, code string) (*models.AuthResponse, error) {
+	if s.twoFactorRepo == nil {
+		return nil, errors.ErrTwoFactorUnavailable
+	}
+
+	claims, err := s.jwtSvc.ValidateTwoFactorToken(twoFactorToken)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	if s.twoFactorAttemptsSpent(ctx, twoFactorToken) {
+		return nil, errors.ErrTwoFactorTooManyCodes
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	if !user.IsActive {
+		return nil, errors.ErrUserInactive
+	}
+
+	if err := s.checkSecondFactor(ctx, userID, code); err != nil {
+		if err == errors.ErrTwoFactorInvalidCode {
+			return nil, s.recordTwoFactorFailure(ctx, twoFactorToken, claims.ExpiresAt.Time)
+		}
+		return nil, err
+	}
+	if s.blacklist != nil {
+		s.blacklist.Add(twoFactorToken, claims.ExpiresAt.Time)
+	}
+
+	token, err := s.jwtSvc.GenerateToken(user)
+	if err != nil {
+		return nil, errors.ErrInternalServer
+	}
+	s.userRepo.UpdateLastLogin(ctx, user.ID)
+
+	return &models.AuthResponse{
+		Success:   true,
+		Message:   "Login successful",
+		Token:     token,
+		ExpiresAt: time.Now().Add(s.jwtSvc.Expiry()),
+		User:      user.ToSafeUser(),
+	}, nil
+}
+
+// twoFactorAttemptsSpent reports whether the interim token has had its share of wrong
+// codes. The count lives in the cache, so it holds across instances where the local
+// blacklist doesn't; cache errors let the attempt through, like the login lockout.
+func (s *AuthService) twoFactorAttemptsSpent(ctx context.Context, twoFactorToken string) bool {
+	if s.cacheProvider == nil {
+		return false
+	}
+
+	value, err := s.cacheProvider.Get(ctx, twoFactorAttemptsPrefix+utils.HashToken(twoFactorToken))
+	if err != nil {
+		if !cache.IsCacheMiss(err) {
+			log.Printf("[Auth] Failed to check 2FA attempts: %v", err)
+		}
+		return false
+	}
+	attempts, _ := strconv.Atoi(value)
+	return attempts >= maxTwoFactorAttempts
+}
+
+// recordTwoFactorFailure counts a wrong code against the interim token, revoking the
+// token on the one that uses up its attempts. It returns the error for the caller.
+func (s *AuthService) recordTwoFactorFailure(ctx context.Context, twoFactorToken string, expiresAt time.Time) error {
+	if s.cacheProvider == nil {
+		return errors.ErrTwoFactorInvalidCode
+	}
+
+	key := twoFactorAttemptsPrefix + utils.HashToken(twoFactorToken)
+	attempts, err := s.cacheProvider.Incr(ctx, key)
+	if err != nil {
+		log.Printf("[Auth] Failed to record 2FA attempt: %v", err)
+		return errors.ErrTwoFactorInvalidCode
+	}
+	if attempts == 1 {
+		// Nothing to count once the token has expired anyway
+		s.cacheProvider.Expire(ctx, key, time.Until(expiresAt))
+	}
+	if attempts < maxTwoFactorAttempts {
+		return errors.ErrTwoFactorInvalidCode
+	}
+
+	if s.blacklist != nil {
+		s.blacklist.Add(twoFactorToken, expiresAt)
+	}
+	return errors.ErrTwoFactorTooManyCodes
+}
+
+// checkSecondFactor accepts a 6-digit TOTP code, each at most once, or an unused
+// recovery code, which is then spent
+func (s *AuthService) checkSecondFactor(ctx context.Context, userID uuid.UUID, code string) error {
+	tf, err := s.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled() {
+		return errors.ErrTwoFactorNotSetUp
+	}
+
+	if !utils.IsTOTPCode(code) {
+		consumed, err := s.twoFactorRepo.ConsumeRecoveryCode(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return errors.ErrTwoFactorInvalidCode
+		}
+		return nil
+	}
+
+	secret, err := s.decryptTOTPSecret(tf)
+	if err != nil {
+		return err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok || step <= tf.LastUsedStep {
+		return errors.ErrTwoFactorInvalidCode
+	}
+	fresh, err := s.twoFactorRepo.MarkTOTPStepUsed(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errors.ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+// decryptTOTPSecret opens a stored secret with the server key
+func (s *AuthService) decryptTOTPSecret(tf *models.TwoFactorSecret) (string, error) {
+	if s.twoFactorKey == nil {
+		return "", errors.ErrTwoFactorUnavailable
+	}
+	secret, err := encryption.DecryptProfileField(tf.SecretEncrypted, tf.SecretIV, s.twoFactorKey)
+	if err != nil {
+		log.Printf("[Auth] Failed to decrypt 2FA secret for %s: %v", tf.UserID, err)
+		return "", errors.ErrInternalServer
+	}
+	return secret, nil
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondWithLogin finishes a login: accounts with 2FA on get an interim token to
+// redeem at POST /auth/2fa/login, everyone else a session
+func (h *AuthHandlers) respondWithLogin(c *gin.Context, response *models.AuthResponse) {
+	response, err := h.authSvc.ChallengeTwoFactor(c.Request.Context(), response)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+	h.respondWithSession(c, response)
+}
+
+// respondAuthError writes err in the auth envelope. A locked account also gets
+// Retry-After and retry_after (seconds).
+func respondAuthError(c *gin.Context, err error) {
+	appErr := errors.GetAppError(err)
+	body := gin.H{
+		"success": false,
+		"message": appErr.Message,
+		"error":   appErr.Details,
+	}
+	if locked, ok := err.(*errors.AccountLockedError); ok {
+		seconds := int(locked.RetryAfter.Round(time.Second) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		c.Header("Retry-After", fmt.Sprintf("%d", seconds))
+		body["retry_after"] = seconds
+	}
+	c.JSON(appErr.Code, body)
+}
+
+// SetupTwoFactorHandler handles POST /api/v1/auth/2fa/setup
+func (h *AuthHandlers) SetupTwoFactorHandler(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	response, err := h.authSvc.SetupTwoFactor(c.Request.Context(), uid)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyTwoFactorHandler handles POST /api/v1/auth/2fa/verify
+func (h *AuthHandlers) VerifyTwoFactorHandler(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authSvc.VerifyTwoFactor(c.Request.Context(), uid, req.Code)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DisableTwoFactorHandler handles POST /api/v1/auth/2fa/disable
+func (h *AuthHandlers) DisableTwoFactorHandler(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authSvc.DisableTwoFactor(c.Request.Context(), uid, req.Code)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// TwoFactorLoginHandler handles POST /api/v1/auth/2fa/login, the second step of a
+// login answered with two_factor_required
+func (h *AuthHandlers) TwoFactorLoginHandler(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authSvc.CompleteTwoFactorLogin(c.Request.Context(), req.TwoFactorToken, req.Code)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	h.respondWithSession(c, response)
+}
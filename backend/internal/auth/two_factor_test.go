@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// fakeTwoFactorRepo has 2FA on for every user, with one unused recovery code; anything
+// else panics through the nil embedded interface
+type fakeTwoFactorRepo struct {
+	repository.TwoFactorRepository
+
+	recoveryCodeHash string
+}
+
+func (r *fakeTwoFactorRepo) GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactorSecret, error) {
+	enabledAt := time.Now()
+	return &models.TwoFactorSecret{UserID: userID, EnabledAt: &enabledAt}, nil
+}
+
+func (r *fakeTwoFactorRepo) ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	return codeHash == r.recoveryCodeHash, nil
+}
+
+func TestCompleteTwoFactorLoginSpendsTheTokenAfterTooManyWrongCodes(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "ada@example.com", Username: "ada", IsActive: true}
+	jwtSvc := utils.NewJWTService("secret", time.Hour)
+	recoveryCode := "abcd-efgh-ijkl"
+
+	// No blacklist, as on another instance: the shared count alone has to hold
+	svc := NewAuthService(&fakeUserRepo{users: map[uuid.UUID]*models.User{user.ID: user}}, nil, nil, jwtSvc, nil)
+	svc.SetCacheProvider(cache.NewMemoryProvider())
+	svc.SetTwoFactor(&fakeTwoFactorRepo{recoveryCodeHash: utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))}, nil, "Histeeria")
+
+	interim, err := jwtSvc.GenerateTwoFactorToken(user)
+	if err != nil {
+		t.Fatalf("GenerateTwoFactorToken: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 1; i < maxTwoFactorAttempts; i++ {
+		if _, err := svc.CompleteTwoFactorLogin(ctx, interim, "wrong-code"); err != errors.ErrTwoFactorInvalidCode {
+			t.Fatalf("wrong code %d: err = %v, want ErrTwoFactorInvalidCode", i, err)
+		}
+	}
+	if _, err := svc.CompleteTwoFactorLogin(ctx, interim, "wrong-code"); err != errors.ErrTwoFactorTooManyCodes {
+		t.Fatalf("last wrong code: err = %v, want ErrTwoFactorTooManyCodes", err)
+	}
+
+	// Even the right code no longer redeems it
+	if _, err := svc.CompleteTwoFactorLogin(ctx, interim, recoveryCode); err != errors.ErrTwoFactorTooManyCodes {
+		t.Errorf("right code after the limit: err = %v, want ErrTwoFactorTooManyCodes", err)
+	}
+}
@@ -18,6 +18,8 @@ type CacheProvider interface {
 	MGet(ctx context.Context, keys []string) ([]string, error)
 	MSet(ctx context.Context, items map[string]string, ttl time.Duration) error
 	MDelete(ctx context.Context, keys []string) error
+	// MHSet sets fields on several hashes and gives each hash ttl, in one round trip
+	MHSet(ctx context.Context, hashes map[string]map[string]string, ttl time.Duration) error
 
 	// List operations
 	LPush(ctx context.Context, key string, values ...string) error
@@ -52,6 +52,7 @@ const (
 	keyHashtagFeed     = "feed:hashtag:%s"
 	keyTrendingHashtags = "trending:hashtags"
 	keyUserPosts       = "posts:user:%s"
+	keyViewerPrefs     = "feed:prefs:%s"
 )
 
 // TTL values (configurable via FeedCacheConfig in production)
@@ -61,6 +62,7 @@ var (
 	postTTL          = 30 * time.Minute
 	hashtagFeedTTL   = 10 * time.Minute
 	trendingTTL      = 5 * time.Minute
+	viewerPrefsTTL   = 10 * time.Minute
 )
 
 // NewFeedCacheService creates a new feed cache service
@@ -97,13 +99,19 @@ func (s *FeedCacheService) IsEnabled() bool {
 // CachedFeedResult represents a cached feed with metadata
 type CachedFeedResult struct {
 	PostIDs   []uuid.UUID `json:"post_ids"`
-	Total     int         `json:"total"`
+	Total     int         `json:"total"` // models.TotalUnknown if the feed wasn't counted
+	HasMore   bool        `json:"has_more"`
 	CachedAt  time.Time   `json:"cached_at"`
 	ExpiresAt time.Time   `json:"expires_at"`
 }
 
+// Page returns the page info for serving the first limit cached posts
+func (r *CachedFeedResult) Page(limit int) models.Page {
+	return models.Page{Total: r.Total, HasMore: r.HasMore || len(r.PostIDs) > limit}
+}
+
 // CacheHomeFeed caches a user's home feed post IDs
-func (s *FeedCacheService) CacheHomeFeed(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID, total int) error {
+func (s *FeedCacheService) CacheHomeFeed(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID, page models.Page) error {
 	if !s.enabled {
 		return nil
 	}
@@ -112,7 +120,8 @@ func (s *FeedCacheService) CacheHomeFeed(ctx context.Context, userID uuid.UUID,
 
 	result := CachedFeedResult{
 		PostIDs:   postIDs,
-		Total:     total,
+		Total:     page.Total,
+		HasMore:   page.HasMore,
 		CachedAt:  time.Now(),
 		ExpiresAt: time.Now().Add(homeFeedTTL),
 	}
@@ -305,14 +314,15 @@ func (s *FeedCacheService) UpdatePostEngagement(ctx context.Context, postID uuid
 // ============================================
 
 // CacheExploreFeed caches the explore/trending feed
-func (s *FeedCacheService) CacheExploreFeed(ctx context.Context, postIDs []uuid.UUID, total int) error {
+func (s *FeedCacheService) CacheExploreFeed(ctx context.Context, postIDs []uuid.UUID, page models.Page) error {
 	if !s.enabled {
 		return nil
 	}
 
 	result := CachedFeedResult{
 		PostIDs:   postIDs,
-		Total:     total,
+		Total:     page.Total,
+		HasMore:   page.HasMore,
 		CachedAt:  time.Now(),
 		ExpiresAt: time.Now().Add(exploreFeedTTL),
 	}
@@ -352,7 +362,7 @@ func (s *FeedCacheService) GetCachedExploreFeed(ctx context.Context) (*CachedFee
 // ============================================
 
 // CacheHashtagFeed caches posts for a hashtag
-func (s *FeedCacheService) CacheHashtagFeed(ctx context.Context, hashtag string, postIDs []uuid.UUID, total int) error {
+func (s *FeedCacheService) CacheHashtagFeed(ctx context.Context, hashtag string, postIDs []uuid.UUID, page models.Page) error {
 	if !s.enabled {
 		return nil
 	}
@@ -361,7 +371,8 @@ func (s *FeedCacheService) CacheHashtagFeed(ctx context.Context, hashtag string,
 
 	result := CachedFeedResult{
 		PostIDs:   postIDs,
-		Total:     total,
+		Total:     page.Total,
+		HasMore:   page.HasMore,
 		CachedAt:  time.Now(),
 		ExpiresAt: time.Now().Add(hashtagFeedTTL),
 	}
@@ -446,12 +457,74 @@ func (s *FeedCacheService) GetCachedTrendingHashtags(ctx context.Context) ([]Tre
 	return hashtags, nil
 }
 
+// ============================================
+// VIEWER PREFERENCES
+// ============================================
+
+// ViewerFeedPrefs are a viewer's feed settings; the zero value is the default for
+// everyone, including anonymous viewers
+type ViewerFeedPrefs struct {
+	ShowNSFW       bool     `json:"show_nsfw"`       // Opted in to unblurred NSFW posts
+	HideInteracted bool     `json:"hide_interacted"` // Leave out posts already liked, saved or commented on
+	Languages      []string `json:"languages"`       // Only show posts in these languages; empty means all
+}
+
+// CacheViewerPrefs caches a viewer's feed settings, which every feed request reads
+func (s *FeedCacheService) CacheViewerPrefs(ctx context.Context, userID uuid.UUID, prefs ViewerFeedPrefs) error {
+	if !s.enabled {
+		return nil
+	}
+
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf(keyViewerPrefs, userID.String())
+	return s.cache.Set(ctx, key, string(data), viewerPrefsTTL)
+}
+
+// GetCachedViewerPrefs retrieves a viewer's cached feed settings, or nil on a miss
+func (s *FeedCacheService) GetCachedViewerPrefs(ctx context.Context, userID uuid.UUID) (*ViewerFeedPrefs, error) {
+	if !s.enabled {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf(keyViewerPrefs, userID.String())
+
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		if IsCacheMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var prefs ViewerFeedPrefs
+	if err := json.Unmarshal([]byte(data), &prefs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal feed prefs: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+// InvalidateViewerPrefs removes a viewer's cached feed settings; call it when they
+// change
+func (s *FeedCacheService) InvalidateViewerPrefs(ctx context.Context, userID uuid.UUID) error {
+	if !s.enabled {
+		return nil
+	}
+
+	key := fmt.Sprintf(keyViewerPrefs, userID.String())
+	return s.cache.Delete(ctx, key)
+}
+
 // ============================================
 // CACHE WARMING & UTILITIES
 // ============================================
 
 // WarmUserFeed pre-caches a user's feed (call during login or background)
-func (s *FeedCacheService) WarmUserFeed(ctx context.Context, userID uuid.UUID, posts []models.Post, total int) error {
+func (s *FeedCacheService) WarmUserFeed(ctx context.Context, userID uuid.UUID, posts []models.Post, page models.Page) error {
 	if !s.enabled || len(posts) == 0 {
 		return nil
 	}
@@ -481,7 +554,7 @@ func (s *FeedCacheService) WarmUserFeed(ctx context.Context, userID uuid.UUID, p
 	}
 
 	// Cache feed index
-	return s.CacheHomeFeed(ctx, userID, postIDs, total)
+	return s.CacheHomeFeed(ctx, userID, postIDs, page)
 }
 
 // ClearAllFeeds clears all feed caches (use sparingly)
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"histeeria-backend/internal/metrics"
 )
 
 // MemoryProvider implements CacheProvider using in-memory storage
@@ -69,14 +71,12 @@ func (m *MemoryProvider) Get(ctx context.Context, key string) (string, error) {
 	defer m.mu.RUnlock()
 
 	item, ok := m.data[key]
-	if !ok {
-		return "", ErrCacheMiss
-	}
-
-	if !item.expires.IsZero() && item.expires.Before(time.Now()) {
+	if !ok || (!item.expires.IsZero() && item.expires.Before(time.Now())) {
+		metrics.Cache.Miss()
 		return "", ErrCacheMiss
 	}
 
+	metrics.Cache.Hit()
 	return item.value, nil
 }
 
@@ -133,10 +133,11 @@ func (m *MemoryProvider) MGet(ctx context.Context, keys []string) ([]string, err
 	now := time.Now()
 
 	for i, key := range keys {
-		if item, ok := m.data[key]; ok {
-			if item.expires.IsZero() || item.expires.After(now) {
-				result[i] = item.value
-			}
+		if item, ok := m.data[key]; ok && (item.expires.IsZero() || item.expires.After(now)) {
+			result[i] = item.value
+			metrics.Cache.Hit()
+		} else {
+			metrics.Cache.Miss()
 		}
 	}
 
@@ -175,6 +176,23 @@ func (m *MemoryProvider) MDelete(ctx context.Context, keys []string) error {
 	return nil
 }
 
+// MHSet sets the fields; like Expire, the memory provider keeps no TTL on hashes
+func (m *MemoryProvider) MHSet(ctx context.Context, hashes map[string]map[string]string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, fields := range hashes {
+		if _, ok := m.hashes[key]; !ok {
+			m.hashes[key] = make(map[string]string)
+		}
+		for k, v := range fields {
+			m.hashes[key][k] = v
+		}
+	}
+
+	return nil
+}
+
 // ============================================
 // LIST OPERATIONS
 // ============================================
@@ -270,16 +288,13 @@ func (m *MemoryProvider) HGet(ctx context.Context, key, field string) (string, e
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	hash, ok := m.hashes[key]
-	if !ok {
-		return "", ErrCacheMiss
-	}
-
-	value, ok := hash[field]
+	value, ok := m.hashes[key][field]
 	if !ok {
+		metrics.Cache.Miss()
 		return "", ErrCacheMiss
 	}
 
+	metrics.Cache.Hit()
 	return value, nil
 }
 
@@ -359,13 +374,16 @@ func (m *MemoryProvider) Incr(ctx context.Context, key string) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// Like Redis, an expired key starts over and a live one keeps its expiry
 	var current int64
-	if item, ok := m.data[key]; ok {
+	var expires time.Time
+	if item, ok := m.data[key]; ok && (item.expires.IsZero() || item.expires.After(time.Now())) {
 		fmt.Sscanf(item.value, "%d", &current)
+		expires = item.expires
 	}
 
 	current++
-	m.data[key] = &memoryItem{value: fmt.Sprintf("%d", current)}
+	m.data[key] = &memoryItem{value: fmt.Sprintf("%d", current), expires: expires}
 
 	return current, nil
 }
@@ -375,12 +393,14 @@ func (m *MemoryProvider) Decr(ctx context.Context, key string) (int64, error) {
 	defer m.mu.Unlock()
 
 	var current int64
-	if item, ok := m.data[key]; ok {
+	var expires time.Time
+	if item, ok := m.data[key]; ok && (item.expires.IsZero() || item.expires.After(time.Now())) {
 		fmt.Sscanf(item.value, "%d", &current)
+		expires = item.expires
 	}
 
 	current--
-	m.data[key] = &memoryItem{value: fmt.Sprintf("%d", current)}
+	m.data[key] = &memoryItem{value: fmt.Sprintf("%d", current), expires: expires}
 
 	return current, nil
 }
@@ -389,7 +409,7 @@ func (m *MemoryProvider) SetNX(ctx context.Context, key string, value string, tt
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.data[key]; ok {
+	if item, ok := m.data[key]; ok && (item.expires.IsZero() || item.expires.After(time.Now())) {
 		return false, nil
 	}
 
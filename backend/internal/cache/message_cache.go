@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"histeeria-backend/internal/models"
@@ -32,10 +33,12 @@ const (
 	keyUserConversations = "conv:list:%s" // STRING (JSON)
 
 	// Presence tracking - online status and last seen
-	keyUserPresence = "presence:%s" // HASH (is_online, last_seen)
+	keyUserPresence = "presence:%s"  // HASH (instance_id -> heartbeat unix time, PresenceTTL)
+	keyUserLastSeen = "last_seen:%s" // STRING (unix time, lastSeenTTL)
 
 	// Typing indicators - short-lived keys
-	keyTyping = "typing:%s:%s" // STRING (3s TTL), conversation_id:user_id
+	keyTyping          = "typing:%s:%s"      // STRING ("typing" or "recording", TypingTTL), conversation_id:user_id
+	keyTypingBroadcast = "typing_sent:%s:%s" // STRING (typingBroadcastInterval), conversation_id:user_id
 
 	// Unread counts - per conversation per user
 	keyUnreadCounts = "unread:%s" // HASH (conversation_id -> count)
@@ -185,39 +188,89 @@ func (s *MessageCacheService) InvalidateUserConversations(ctx context.Context, u
 // PRESENCE TRACKING
 // ============================================
 
-// SetUserOnline marks a user as online
-func (s *MessageCacheService) SetUserOnline(ctx context.Context, userID uuid.UUID) error {
-	key := fmt.Sprintf(keyUserPresence, userID.String())
+// How long an instance's presence heartbeat keeps a user online. Instances refresh
+// their users every PresenceHeartbeatInterval, so a user only goes offline by missing
+// a few in a row: their last connection closed, or their instance went down.
+const (
+	PresenceTTL               = 90 * time.Second
+	PresenceHeartbeatInterval = 30 * time.Second
+	PresenceRefreshBatchSize  = 500
 
-	// Set online status with 90s TTL (auto-expire if not refreshed)
-	err := s.provider.HSet(ctx, key, map[string]string{
-		"is_online": "true",
-		"last_seen": fmt.Sprintf("%d", time.Now().Unix()),
-	})
+	lastSeenTTL = 30 * 24 * time.Hour
+)
 
-	if err != nil {
-		return err
+// SetUserOnline records that userID has a connection on instanceID. changed is true
+// if they weren't online on any instance before.
+func (s *MessageCacheService) SetUserOnline(ctx context.Context, userID uuid.UUID, instanceID string) (changed bool, err error) {
+	fields, err := s.provider.HGetAll(ctx, fmt.Sprintf(keyUserPresence, userID.String()))
+	if err != nil && !IsCacheMiss(err) {
+		return false, err
 	}
+	_, wasOnline := latestHeartbeat(fields)
 
-	// Set TTL
-	return s.provider.Expire(ctx, key, 90*time.Second)
+	if err := s.RefreshPresence(ctx, instanceID, []uuid.UUID{userID}); err != nil {
+		return false, err
+	}
+	return !wasOnline, nil
 }
 
-// SetUserOffline marks a user as offline
-func (s *MessageCacheService) SetUserOffline(ctx context.Context, userID uuid.UUID) error {
+// SetUserOffline records that userID's last connection on instanceID closed. changed
+// is true if that took them offline, that is, no other instance still has them.
+func (s *MessageCacheService) SetUserOffline(ctx context.Context, userID uuid.UUID, instanceID string) (changed bool, err error) {
 	key := fmt.Sprintf(keyUserPresence, userID.String())
 
-	return s.provider.HSet(ctx, key, map[string]string{
-		"is_online": "false",
-		"last_seen": fmt.Sprintf("%d", time.Now().Unix()),
-	})
+	if err := s.provider.HDel(ctx, key, instanceID); err != nil {
+		return false, err
+	}
+	if err := s.TouchLastSeen(ctx, userID); err != nil {
+		return false, err
+	}
+
+	fields, err := s.provider.HGetAll(ctx, key)
+	if err != nil && !IsCacheMiss(err) {
+		return false, err
+	}
+	_, stillOnline := latestHeartbeat(fields)
+	return !stillOnline, nil
+}
+
+// RefreshPresence is an instance's heartbeat for the users connected to it. The
+// presence hashes and last seen times are each written in one round trip, so
+// callers with many users should pass them in chunks of PresenceRefreshBatchSize.
+func (s *MessageCacheService) RefreshPresence(ctx context.Context, instanceID string, userIDs []uuid.UUID) error {
+	now := fmt.Sprintf("%d", time.Now().Unix())
+
+	presence := make(map[string]map[string]string, len(userIDs))
+	lastSeen := make(map[string]string, len(userIDs))
+	for _, userID := range userIDs {
+		presence[fmt.Sprintf(keyUserPresence, userID.String())] = map[string]string{instanceID: now}
+		lastSeen[fmt.Sprintf(keyUserLastSeen, userID.String())] = now
+	}
+
+	// The whole hash goes once no instance refreshes it
+	if err := s.provider.MHSet(ctx, presence, PresenceTTL); err != nil {
+		return err
+	}
+	return s.provider.MSet(ctx, lastSeen, lastSeenTTL)
+}
+
+// TouchLastSeen records activity by userID without changing whether they're online
+func (s *MessageCacheService) TouchLastSeen(ctx context.Context, userID uuid.UUID) error {
+	key := fmt.Sprintf(keyUserLastSeen, userID.String())
+	return s.provider.Set(ctx, key, fmt.Sprintf("%d", time.Now().Unix()), lastSeenTTL)
 }
 
 // GetUserPresence retrieves a user's presence status
 func (s *MessageCacheService) GetUserPresence(ctx context.Context, userID uuid.UUID) (isOnline bool, lastSeen time.Time, err error) {
-	key := fmt.Sprintf(keyUserPresence, userID.String())
+	fields, err := s.provider.HGetAll(ctx, fmt.Sprintf(keyUserPresence, userID.String()))
+	if err != nil && !IsCacheMiss(err) {
+		return false, time.Time{}, err
+	}
+	if heartbeat, online := latestHeartbeat(fields); online {
+		return true, heartbeat, nil
+	}
 
-	result, err := s.provider.HGetAll(ctx, key)
+	value, err := s.provider.Get(ctx, fmt.Sprintf(keyUserLastSeen, userID.String()))
 	if err != nil {
 		if IsCacheMiss(err) {
 			return false, time.Time{}, nil
@@ -225,74 +278,155 @@ func (s *MessageCacheService) GetUserPresence(ctx context.Context, userID uuid.U
 		return false, time.Time{}, err
 	}
 
-	if len(result) == 0 {
-		return false, time.Time{}, nil
-	}
-
-	isOnline = result["is_online"] == "true"
-
-	if lastSeenStr, ok := result["last_seen"]; ok {
-		var lastSeenUnix int64
-		fmt.Sscanf(lastSeenStr, "%d", &lastSeenUnix)
-		lastSeen = time.Unix(lastSeenUnix, 0)
-	}
-
-	return isOnline, lastSeen, nil
+	return false, parseUnix(value), nil
 }
 
 // GetMultiplePresence retrieves presence for multiple users (batch operation)
 func (s *MessageCacheService) GetMultiplePresence(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.PresenceInfo, error) {
-	result := make(map[uuid.UUID]*models.PresenceInfo)
+	result := make(map[uuid.UUID]*models.PresenceInfo, len(userIDs))
 
 	// Since we are using an abstraction, we'll do sequential HGetAll for now
-	// Alternatively, we could add Batch methods to CacheProvider
+	var offline []uuid.UUID
 	for _, userID := range userIDs {
-		key := fmt.Sprintf(keyUserPresence, userID.String())
-		data, err := s.provider.HGetAll(ctx, key)
-		if err != nil || len(data) == 0 {
-			result[userID] = &models.PresenceInfo{
-				UserID:   userID,
-				IsOnline: false,
-			}
-			continue
+		// A user whose presence can't be read shows offline rather than failing the batch
+		fields, _ := s.provider.HGetAll(ctx, fmt.Sprintf(keyUserPresence, userID.String()))
+
+		info := &models.PresenceInfo{UserID: userID}
+		if heartbeat, online := latestHeartbeat(fields); online {
+			info.IsOnline = true
+			info.LastSeen = &heartbeat
+		} else {
+			offline = append(offline, userID)
 		}
+		result[userID] = info
+	}
 
-		isOnline := data["is_online"] == "true"
-		var lastSeen *time.Time
+	if len(offline) == 0 {
+		return result, nil
+	}
 
-		if lastSeenStr, ok := data["last_seen"]; ok {
-			var lastSeenUnix int64
-			fmt.Sscanf(lastSeenStr, "%d", &lastSeenUnix)
-			t := time.Unix(lastSeenUnix, 0)
-			lastSeen = &t
+	// Last seen of everyone offline in one round trip
+	keys := make([]string, len(offline))
+	for i, userID := range offline {
+		keys[i] = fmt.Sprintf(keyUserLastSeen, userID.String())
+	}
+	values, err := s.provider.MGet(ctx, keys)
+	if err != nil {
+		return result, nil
+	}
+	for i, userID := range offline {
+		if i < len(values) && values[i] != "" {
+			lastSeen := parseUnix(values[i])
+			result[userID].LastSeen = &lastSeen
 		}
+	}
 
-		result[userID] = &models.PresenceInfo{
-			UserID:   userID,
-			IsOnline: isOnline,
-			LastSeen: lastSeen,
+	return result, nil
+}
+
+// latestHeartbeat returns the most recent instance heartbeat in a presence hash, and
+// whether it's recent enough for the user to count as online. Checking the age here,
+// not only relying on the key's TTL, lets a user drop offline when one instance stops
+// heartbeating while another keeps the hash alive.
+func latestHeartbeat(fields map[string]string) (time.Time, bool) {
+	var latest time.Time
+	for _, value := range fields {
+		if t := parseUnix(value); t.After(latest) {
+			latest = t
 		}
 	}
+	return latest, !latest.IsZero() && time.Since(latest) < PresenceTTL
+}
 
-	return result, nil
+// parseUnix reads a timestamp stored as unix seconds
+func parseUnix(value string) time.Time {
+	var unix int64
+	if _, err := fmt.Sscanf(value, "%d", &unix); err != nil || unix <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
 }
 
 // ============================================
 // TYPING INDICATORS
 // ============================================
 
-// SetTyping sets a user as typing in a conversation
-func (s *MessageCacheService) SetTyping(ctx context.Context, conversationID, userID uuid.UUID) error {
+// TypingTTL is how long a typing indicator lasts without a refresh. Clients refresh
+// it while the user keeps typing.
+const TypingTTL = 6 * time.Second
+
+// typingBroadcastInterval is the least time between two typing broadcasts for the
+// same user while their state doesn't change
+const typingBroadcastInterval = 3 * time.Second
+
+// SetTyping marks a user as typing (or recording) in a conversation for TypingTTL.
+// changed reports whether they weren't already in that state.
+func (s *MessageCacheService) SetTyping(ctx context.Context, conversationID, userID uuid.UUID, isRecording bool) (changed bool, err error) {
+	key := fmt.Sprintf(keyTyping, conversationID.String(), userID.String())
+
+	state := "typing"
+	if isRecording {
+		state = "recording"
+	}
+
+	previous, _ := s.provider.Get(ctx, key)
+	if err := s.provider.Set(ctx, key, state, TypingTTL); err != nil {
+		return true, err
+	}
+	return previous != state, nil
+}
+
+// IsTyping reports whether a user's typing indicator in a conversation is still live
+func (s *MessageCacheService) IsTyping(ctx context.Context, conversationID, userID uuid.UUID) (bool, error) {
 	key := fmt.Sprintf(keyTyping, conversationID.String(), userID.String())
+	return s.provider.Exists(ctx, key)
+}
+
+// TypingBroadcastDue reports whether a refreshed typing indicator should be sent out
+// again, at most once per typingBroadcastInterval, so keystrokes don't each turn into
+// a broadcast
+func (s *MessageCacheService) TypingBroadcastDue(ctx context.Context, conversationID, userID uuid.UUID) bool {
+	key := fmt.Sprintf(keyTypingBroadcast, conversationID.String(), userID.String())
+	due, err := s.provider.SetNX(ctx, key, "1", typingBroadcastInterval)
+	return err != nil || due
+}
+
+// GetTypingConversations returns the conversations a user is currently typing in
+func (s *MessageCacheService) GetTypingConversations(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	pattern := fmt.Sprintf(keyTyping, "*", userID.String())
+
+	var conversationIDs []uuid.UUID
+	var cursor uint64
+	for {
+		keys, next, err := s.provider.Scan(ctx, cursor, pattern, 100)
+		if err != nil {
+			return nil, err
+		}
+
+		// Key format: typing:{conversation_id}:{user_id}
+		for _, key := range keys {
+			parts := strings.Split(key, ":")
+			if len(parts) != 3 {
+				continue
+			}
+			if conversationID, err := uuid.Parse(parts[1]); err == nil {
+				conversationIDs = append(conversationIDs, conversationID)
+			}
+		}
 
-	// Set with 3s TTL (auto-expire if not refreshed)
-	return s.provider.Set(ctx, key, "1", 3*time.Second)
+		if next == 0 {
+			return conversationIDs, nil
+		}
+		cursor = next
+	}
 }
 
 // ClearTyping removes typing indicator for a user
 func (s *MessageCacheService) ClearTyping(ctx context.Context, conversationID, userID uuid.UUID) error {
-	key := fmt.Sprintf(keyTyping, conversationID.String(), userID.String())
-	return s.provider.Delete(ctx, key)
+	return s.provider.MDelete(ctx, []string{
+		fmt.Sprintf(keyTyping, conversationID.String(), userID.String()),
+		fmt.Sprintf(keyTypingBroadcast, conversationID.String(), userID.String()),
+	})
 }
 
 // GetTypingUsers returns list of users currently typing in a conversation
@@ -474,6 +474,16 @@ func MessageRateLimitKey(userID string) string {
 	return fmt.Sprintf("message:%s", userID)
 }
 
+// CommentRateLimitKey creates a key for one user's comments on one post
+func CommentRateLimitKey(userID, postID string) string {
+	return fmt.Sprintf("comment:%s:%s", userID, postID)
+}
+
+// NewAccountRateLimitKey creates a key for an action of an account in its probation period
+func NewAccountRateLimitKey(userID, action string) string {
+	return fmt.Sprintf("new_account:%s:%s", userID, action)
+}
+
 // IPRateLimitKey creates a key for IP-based rate limiting
 func IPRateLimitKey(ip string) string {
 	return fmt.Sprintf("ip:%s", ip)
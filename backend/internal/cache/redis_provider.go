@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"histeeria-backend/internal/metrics"
+
 	"github.com/go-redis/redis/v8"
 )
 
@@ -69,11 +71,13 @@ func (r *RedisProvider) Get(ctx context.Context, key string) (string, error) {
 
 	val, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
+		metrics.Cache.Miss()
 		return "", ErrCacheMiss
 	}
 	if err != nil {
 		return "", &CacheError{Code: "GET_ERROR", Message: "failed to get key", Err: err}
 	}
+	metrics.Cache.Hit()
 	return val, nil
 }
 
@@ -129,6 +133,9 @@ func (r *RedisProvider) MGet(ctx context.Context, keys []string) ([]string, erro
 	for i, v := range vals {
 		if v != nil {
 			result[i] = v.(string)
+			metrics.Cache.Hit()
+		} else {
+			metrics.Cache.Miss()
 		}
 	}
 	return result, nil
@@ -161,6 +168,29 @@ func (r *RedisProvider) MDelete(ctx context.Context, keys []string) error {
 	return nil
 }
 
+func (r *RedisProvider) MHSet(ctx context.Context, hashes map[string]map[string]string, ttl time.Duration) error {
+	if !r.available {
+		return ErrCacheUnavailable
+	}
+
+	pipe := r.client.Pipeline()
+	for key, fields := range hashes {
+		args := make([]interface{}, 0, len(fields)*2)
+		for k, v := range fields {
+			args = append(args, k, v)
+		}
+		pipe.HSet(ctx, key, args...)
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return &CacheError{Code: "MHSET_ERROR", Message: "failed to set multiple hashes", Err: err}
+	}
+	return nil
+}
+
 // ============================================
 // LIST OPERATIONS
 // ============================================
@@ -215,11 +245,13 @@ func (r *RedisProvider) HGet(ctx context.Context, key, field string) (string, er
 
 	val, err := r.client.HGet(ctx, key, field).Result()
 	if err == redis.Nil {
+		metrics.Cache.Miss()
 		return "", ErrCacheMiss
 	}
 	if err != nil {
 		return "", &CacheError{Code: "HGET_ERROR", Message: "failed to get hash field", Err: err}
 	}
+	metrics.Cache.Hit()
 	return val, nil
 }
 
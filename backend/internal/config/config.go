@@ -1,9 +1,18 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"log"
 	"os"
 	"strings"
+	"time"
+
+	"histeeria-backend/internal/models"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -11,18 +20,32 @@ import (
 
 // Config holds all configuration for our application
 type Config struct {
-	Database  DatabaseConfig  `mapstructure:"database"`
-	JWT       JWTConfig       `mapstructure:"jwt"`
-	Email     EmailConfig     `mapstructure:"email"`
-	Server    ServerConfig    `mapstructure:"server"`
-	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
-	Google    GoogleConfig    `mapstructure:"google"`
-	GitHub    GitHubConfig    `mapstructure:"github"`
-	LinkedIn  LinkedInConfig  `mapstructure:"linkedin"`
-	Storage   StorageConfig   `mapstructure:"storage"`
-	Redis     RedisConfig     `mapstructure:"redis"`
-	R2        R2Config        `mapstructure:"r2"`
-	Logging   LoggingConfig   `mapstructure:"logging"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	TwoFactor  TwoFactorConfig  `mapstructure:"two_factor"`
+	Email      EmailConfig      `mapstructure:"email"`
+	Server     ServerConfig     `mapstructure:"server"`
+	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
+	Lockout    LockoutConfig    `mapstructure:"lockout"`
+	Google     GoogleConfig     `mapstructure:"google"`
+	GitHub     GitHubConfig     `mapstructure:"github"`
+	LinkedIn   LinkedInConfig   `mapstructure:"linkedin"`
+	Apple      AppleConfig      `mapstructure:"apple"`
+	Twitter    TwitterConfig    `mapstructure:"twitter"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	R2         R2Config         `mapstructure:"r2"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
+	Feed       FeedConfig       `mapstructure:"feed"`
+	HTTPCache  HTTPCacheConfig  `mapstructure:"http_cache"`
+	Status     StatusConfig     `mapstructure:"status"`
+	Posts      PostsConfig      `mapstructure:"posts"`
+	Pins       PinsConfig       `mapstructure:"pins"`
+	Comments   CommentsConfig   `mapstructure:"comments"`
+	NewAccount NewAccountConfig `mapstructure:"new_account"`
+	Push       PushConfig       `mapstructure:"push"`
+	WebSocket  WebSocketConfig  `mapstructure:"websocket"`
+	Metrics    MetricsConfig    `mapstructure:"metrics"`
 }
 
 // R2Config holds Cloudflare R2 storage configuration
@@ -42,6 +65,101 @@ type LoggingConfig struct {
 	Format string `mapstructure:"format"` // json, text
 }
 
+// FeedConfig controls author diversity in home/explore feeds (0 disables a limit)
+type FeedConfig struct {
+	MaxConsecutivePerAuthor int `mapstructure:"max_consecutive_per_author"`
+	MaxPerAuthorPerPage     int `mapstructure:"max_per_author_per_page"`
+	CandidateMultiplier     int `mapstructure:"candidate_multiplier"`
+	TrendingHalfLifeHours   int `mapstructure:"trending_half_life_hours"` // Hashtag trending score decay
+	TrendingWindowHours     int `mapstructure:"trending_window_hours"`    // Posts older than this don't count
+
+	Ranking    RankingWeightsConfig    `mapstructure:"ranking"`    // Explore ranking weights served by default
+	Experiment RankingExperimentConfig `mapstructure:"experiment"` // Alternative weights for a share of users
+}
+
+// RankingWeightsConfig holds explore feed scoring weights (0 turns a signal off)
+type RankingWeightsConfig struct {
+	HalfLifeHours  float64 `mapstructure:"half_life_hours"` // Recency decay
+	VelocityWeight float64 `mapstructure:"velocity_weight"` // Engagement per hour
+	AffinityWeight float64 `mapstructure:"affinity_weight"` // Authors the viewer follows
+	MaxPerAuthor   int     `mapstructure:"max_per_author"`  // Overrides the per-page author cap when set
+}
+
+// RankingExperimentConfig serves Weights to Percent of users, bucketed by user ID
+type RankingExperimentConfig struct {
+	Name    string               `mapstructure:"name"`
+	Percent int                  `mapstructure:"percent"` // 0 disables the experiment
+	Weights RankingWeightsConfig `mapstructure:"weights"`
+}
+
+// HTTPCacheConfig controls Cache-Control headers on public, viewer-independent responses
+// (all values in seconds)
+type HTTPCacheConfig struct {
+	Enabled              bool `mapstructure:"enabled"`
+	MaxAge               int  `mapstructure:"max_age"`                // Browser cache lifetime
+	SharedMaxAge         int  `mapstructure:"shared_max_age"`         // CDN cache lifetime (s-maxage)
+	StaleWhileRevalidate int  `mapstructure:"stale_while_revalidate"` // How long CDNs may serve stale while refetching
+}
+
+// StatusConfig holds anti-spam limits and the lifetime of statuses (stories)
+type StatusConfig struct {
+	MaxPerDay      int  `mapstructure:"max_per_day"`     // Statuses per user per rolling 24 hours, 0 disables
+	ExemptVerified bool `mapstructure:"exempt_verified"` // Don't limit verified accounts
+	LifetimeHours  int  `mapstructure:"lifetime_hours"`  // How long a status is shown before it expires
+}
+
+// PostsConfig controls how long soft-deleted posts are kept before being purged
+type PostsConfig struct {
+	PurgeRetentionDays int `mapstructure:"purge_retention_days"` // 0 keeps deleted posts forever
+	PurgeBatchSize     int `mapstructure:"purge_batch_size"`     // Posts deleted per transaction
+}
+
+// PinsConfig caps how much can be pinned, so pinned items stay a short highlight
+type PinsConfig struct {
+	MaxPerConversation int `mapstructure:"max_per_conversation"` // Pinned messages; pinning another unpins the oldest
+	MaxPerProfile      int `mapstructure:"max_per_profile"`      // Pinned posts; pinning another is refused
+}
+
+// CommentsConfig bounds comment threads and how fast one user can post into them
+type CommentsConfig struct {
+	MaxDepth   int    `mapstructure:"max_depth"`   // Thread levels, 1 for flat comments; deeper replies are refused
+	RateLimit  int    `mapstructure:"rate_limit"`  // Comments per user per post in RateWindow; 0 disables
+	RateWindow string `mapstructure:"rate_window"` // e.g. "1m"
+}
+
+// RateWindowTTL returns the parsed comment rate window (validated on load)
+func (c CommentsConfig) RateWindowTTL() time.Duration {
+	d, _ := time.ParseDuration(c.RateWindow)
+	return d
+}
+
+// NewAccountConfig sets the probation period after signup and what it restricts
+type NewAccountConfig struct {
+	Period            string `mapstructure:"period"`             // e.g. "72h"; "0" disables the restrictions
+	TrustedFollowers  int    `mapstructure:"trusted_followers"`  // With a verified email, ends the period early
+	PostLimit         int    `mapstructure:"post_limit"`         // Per hour
+	MessageLimit      int    `mapstructure:"message_limit"`      // Per minute
+	ConversationLimit int    `mapstructure:"conversation_limit"` // New conversations per hour
+	FollowLimit       int    `mapstructure:"follow_limit"`       // Per day, for each of follow, connect and collaborate
+	AllowLinks        bool   `mapstructure:"allow_links"`
+	AllowBulk         bool   `mapstructure:"allow_bulk"` // e.g. contact matching
+}
+
+// Restrictions returns the parsed restrictions (validated on load)
+func (c NewAccountConfig) Restrictions() models.NewAccountRestrictions {
+	period, _ := time.ParseDuration(c.Period)
+	return models.NewAccountRestrictions{
+		Period:            period,
+		TrustedFollowers:  c.TrustedFollowers,
+		PostLimit:         c.PostLimit,
+		MessageLimit:      c.MessageLimit,
+		ConversationLimit: c.ConversationLimit,
+		FollowLimit:       c.FollowLimit,
+		AllowLinks:        c.AllowLinks,
+		AllowBulk:         c.AllowBulk,
+	}
+}
+
 type DatabaseConfig struct {
 	SupabaseURL        string `mapstructure:"supabase_url"`
 	SupabaseAnonKey    string `mapstructure:"supabase_anon_key"`
@@ -52,12 +170,50 @@ type StorageConfig struct {
 	BucketName       string `mapstructure:"bucket_name"`
 	MaxFileSize      int64  `mapstructure:"max_file_size"`
 	AllowedFileTypes string `mapstructure:"allowed_file_types"`
+	UserQuotaBytes   int64  `mapstructure:"user_quota_bytes"` // Per-user upload quota, 0 disables
+	// Comma-separated upload types (posts, messages, statuses) whose images have EXIF stripped.
+	// Profile pictures are always stripped.
+	StripMetadataTypes string `mapstructure:"strip_metadata_types"`
+	// Comma-separated content types allowed per upload category, checked against the
+	// sniffed file bytes. Empty uses the built-in defaults.
+	UploadImageTypes    string `mapstructure:"upload_image_types"`
+	UploadAudioTypes    string `mapstructure:"upload_audio_types"`
+	UploadVideoTypes    string `mapstructure:"upload_video_types"`
+	UploadDocumentTypes string `mapstructure:"upload_document_types"`
 }
 
 type JWTConfig struct {
 	Secret        string `mapstructure:"secret"`
-	Expiry        string `mapstructure:"expiry"`
-	RefreshExpiry string `mapstructure:"refresh_expiry"`
+	Expiry        string `mapstructure:"expiry"`         // Access token lifetime, e.g. "15m"
+	RefreshExpiry string `mapstructure:"refresh_expiry"` // Refresh token lifetime, e.g. "720h"
+}
+
+// AccessTTL returns the parsed access token lifetime (validated on load)
+func (j JWTConfig) AccessTTL() time.Duration {
+	d, _ := time.ParseDuration(j.Expiry)
+	return d
+}
+
+// RefreshTTL returns the parsed refresh token lifetime (validated on load)
+func (j JWTConfig) RefreshTTL() time.Duration {
+	d, _ := time.ParseDuration(j.RefreshExpiry)
+	return d
+}
+
+// TwoFactorConfig holds TOTP settings. Without an encryption key users can't set up
+// 2FA, and those who have it can only sign in with recovery codes.
+type TwoFactorConfig struct {
+	EncryptionKey string `mapstructure:"encryption_key"` // Base64 of 32 random bytes; encrypts TOTP secrets
+	Issuer        string `mapstructure:"issuer"`         // Account label shown in authenticator apps
+}
+
+// Key returns the decoded encryption key, or nil if none is configured (validated on load)
+func (t TwoFactorConfig) Key() []byte {
+	key, err := base64.StdEncoding.DecodeString(t.EncryptionKey)
+	if err != nil || len(key) == 0 {
+		return nil
+	}
+	return key
 }
 
 type EmailConfig struct {
@@ -68,6 +224,13 @@ type EmailConfig struct {
 	FromName    string `mapstructure:"from_name"`
 	FromEmail   string `mapstructure:"from_email"`
 	FrontendURL string `mapstructure:"frontend_url"`
+
+	// Verification codes (signup, email change)
+	VerificationCodeLength  int    `mapstructure:"verification_code_length"`
+	VerificationCodeCharset string `mapstructure:"verification_code_charset"` // numeric, alphanumeric
+
+	Language   string `mapstructure:"language"`    // Language emails are written in, e.g. "en"; English if untranslated
+	PreviewDir string `mapstructure:"preview_dir"` // If set, emails are written here as HTML files instead of sent (development)
 }
 
 type ServerConfig struct {
@@ -85,6 +248,26 @@ type RateLimitConfig struct {
 	Forgiveness int    `mapstructure:"forgiveness"`
 }
 
+// LockoutConfig locks an account after repeated failed logins, whichever IP they come
+// from. The counters live in the cache, so they're shared when Redis is configured.
+type LockoutConfig struct {
+	MaxFailures int    `mapstructure:"max_failures"` // Failed logins that lock the account; 0 disables lockout
+	Window      string `mapstructure:"window"`       // Period the failures are counted over, e.g. "15m"
+	Duration    string `mapstructure:"duration"`     // How long the lock lasts, e.g. "15m"
+}
+
+// WindowTTL returns the parsed failure counting window (validated on load)
+func (l LockoutConfig) WindowTTL() time.Duration {
+	d, _ := time.ParseDuration(l.Window)
+	return d
+}
+
+// LockTTL returns the parsed lock duration (validated on load)
+func (l LockoutConfig) LockTTL() time.Duration {
+	d, _ := time.ParseDuration(l.Duration)
+	return d
+}
+
 type GoogleConfig struct {
 	ClientID     string `mapstructure:"client_id"`
 	ClientSecret string `mapstructure:"client_secret"`
@@ -103,6 +286,104 @@ type LinkedInConfig struct {
 	RedirectURL  string `mapstructure:"redirect_url"`
 }
 
+// AppleConfig holds Sign in with Apple settings. Apple has no static client secret;
+// each token exchange signs one with the private key from the developer account.
+type AppleConfig struct {
+	ClientID    string `mapstructure:"client_id"` // Services ID, e.g. com.histeeria.web
+	TeamID      string `mapstructure:"team_id"`
+	KeyID       string `mapstructure:"key_id"`
+	PrivateKey  string `mapstructure:"private_key"` // Contents of the .p8 key file
+	RedirectURL string `mapstructure:"redirect_url"`
+}
+
+// SigningKey parses the .p8 private key. Newlines may be written as \n so the key
+// fits in a single environment variable.
+func (a AppleConfig) SigningKey() (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(a.PrivateKey, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an EC private key")
+	}
+	return ecKey, nil
+}
+
+// TwitterConfig holds X (Twitter) OAuth 2.0 settings
+type TwitterConfig struct {
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	RedirectURL  string `mapstructure:"redirect_url"`
+}
+
+// WebSocketConfig bounds what's buffered for each WebSocket client, so a slow one
+// can't hold up delivery to everyone else
+type WebSocketConfig struct {
+	SendBuffer int    `mapstructure:"send_buffer"` // Messages queued per connection before it counts as slow
+	SlowClient string `mapstructure:"slow_client"` // "resync" drops messages and tells the client to resync; "disconnect" closes it
+}
+
+// MetricsConfig controls where the Prometheus metrics are served. They're kept off the
+// public API: on their own listener, or behind a bearer token if served by the API.
+type MetricsConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	BindAddress string `mapstructure:"bind_address"` // host:port of the metrics listener; empty serves /metrics on the API port
+	Token       string `mapstructure:"token"`        // Bearer token scrapers must send; required when BindAddress is empty
+
+	LargeResponseBytes int    `mapstructure:"large_response_bytes"` // Response bodies over this are counted and logged; 0 disables
+	SlowRequest        string `mapstructure:"slow_request"`         // Requests taking longer are counted and logged, e.g. "2s"; "0" disables
+}
+
+// SlowRequestThreshold returns the parsed slow request threshold (validated on load)
+func (m MetricsConfig) SlowRequestThreshold() time.Duration {
+	d, _ := time.ParseDuration(m.SlowRequest)
+	return d
+}
+
+// PushConfig holds push notification provider settings
+type PushConfig struct {
+	FCMServiceAccount string `mapstructure:"fcm_service_account"` // Service account key JSON, or a path to it
+}
+
+// FCMServiceAccount is the part of a Firebase service account key that sending
+// through FCM needs
+type FCMServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// ServiceAccount loads the FCM service account key, or returns nil if FCM isn't
+// configured
+func (p PushConfig) ServiceAccount() (*FCMServiceAccount, error) {
+	raw := strings.TrimSpace(p.FCMServiceAccount)
+	if raw == "" {
+		return nil, nil
+	}
+	data := []byte(raw)
+	if !strings.HasPrefix(raw, "{") {
+		var err error
+		if data, err = os.ReadFile(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	var account FCMServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, err
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("project_id, client_email and private_key are required")
+	}
+	return &account, nil
+}
+
 type RedisConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
@@ -120,8 +401,9 @@ func LoadConfig() (*Config, error) {
 	// Set default values
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.gin_mode", "debug")
-	viper.SetDefault("jwt.expiry", "720h")         // 30 days (720 hours)
-	viper.SetDefault("jwt.refresh_expiry", "720h") // Same as expiry for sliding window
+	viper.SetDefault("jwt.expiry", "15m")          // Access tokens, renewed with a refresh token
+	viper.SetDefault("jwt.refresh_expiry", "720h") // 30 days since the last refresh
+	viper.SetDefault("two_factor.issuer", "Histeeria")
 	viper.SetDefault("email.host", "smtp.gmail.com")
 	viper.SetDefault("email.port", 587)
 	viper.SetDefault("rate_limit.login", 5)
@@ -129,10 +411,19 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("rate_limit.reset", 3)
 	viper.SetDefault("rate_limit.window", "1m")
 	viper.SetDefault("rate_limit.forgiveness", 2)
+	viper.SetDefault("lockout.max_failures", 10)
+	viper.SetDefault("lockout.window", "15m")
+	viper.SetDefault("lockout.duration", "15m")
 	viper.SetDefault("email.frontend_url", "http://localhost:3001")
+	viper.SetDefault("email.verification_code_length", 6)
+	viper.SetDefault("email.verification_code_charset", "numeric")
+	viper.SetDefault("email.language", "en")
+	viper.SetDefault("email.preview_dir", "")
 	viper.SetDefault("storage.bucket_name", "profile-pictures")
 	viper.SetDefault("storage.max_file_size", 5242880) // 5MB
 	viper.SetDefault("storage.allowed_file_types", "image/jpeg,image/png,image/gif,image/webp")
+	viper.SetDefault("storage.user_quota_bytes", 2147483648) // 2GB
+	viper.SetDefault("storage.strip_metadata_types", "posts,messages,statuses")
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", "6379")
 	viper.SetDefault("redis.password", "")
@@ -142,6 +433,67 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("r2.bucket_name", "histeeria-media")
 	viper.SetDefault("r2.enabled", false)
 
+	// Feed diversity defaults
+	viper.SetDefault("feed.max_consecutive_per_author", 2)
+	viper.SetDefault("feed.max_per_author_per_page", 3)
+	viper.SetDefault("feed.candidate_multiplier", 2)
+	viper.SetDefault("feed.trending_half_life_hours", 6)
+	viper.SetDefault("feed.trending_window_hours", 24)
+	viper.SetDefault("feed.ranking.half_life_hours", 24)
+	viper.SetDefault("feed.ranking.velocity_weight", 1.0)
+	viper.SetDefault("feed.ranking.affinity_weight", 0.5)
+	viper.SetDefault("feed.ranking.max_per_author", 0)
+	viper.SetDefault("feed.experiment.name", "")
+	viper.SetDefault("feed.experiment.percent", 0)
+	viper.SetDefault("feed.experiment.weights.half_life_hours", 24)
+	viper.SetDefault("feed.experiment.weights.velocity_weight", 1.0)
+	viper.SetDefault("feed.experiment.weights.affinity_weight", 0.5)
+	viper.SetDefault("feed.experiment.weights.max_per_author", 0)
+
+	// Public response caching defaults
+	viper.SetDefault("http_cache.enabled", true)
+	viper.SetDefault("http_cache.max_age", 60)
+	viper.SetDefault("http_cache.shared_max_age", 300)
+	viper.SetDefault("http_cache.stale_while_revalidate", 60)
+
+	// Status anti-spam defaults
+	viper.SetDefault("status.max_per_day", 30)
+	viper.SetDefault("status.exempt_verified", false)
+	viper.SetDefault("status.lifetime_hours", 24)
+
+	// Deleted post retention defaults
+	viper.SetDefault("posts.purge_retention_days", 30)
+	viper.SetDefault("posts.purge_batch_size", 100)
+
+	// Pin limits
+	viper.SetDefault("pins.max_per_conversation", 3)
+	viper.SetDefault("pins.max_per_profile", 3)
+	viper.SetDefault("comments.max_depth", 2)
+	viper.SetDefault("comments.rate_limit", 5)
+	viper.SetDefault("comments.rate_window", "1m")
+	viper.SetDefault("new_account.period", "72h")
+	viper.SetDefault("new_account.trusted_followers", 5)
+	viper.SetDefault("new_account.post_limit", 5)
+	viper.SetDefault("new_account.message_limit", 10)
+	viper.SetDefault("new_account.conversation_limit", 5)
+	viper.SetDefault("new_account.follow_limit", 10)
+	viper.SetDefault("new_account.allow_links", false)
+	viper.SetDefault("new_account.allow_bulk", false)
+
+	// Push defaults (FCM off until a service account is set)
+	viper.SetDefault("push.fcm_service_account", "")
+
+	// WebSocket slow client defaults
+	viper.SetDefault("websocket.send_buffer", 256)
+	viper.SetDefault("websocket.slow_client", "resync")
+
+	// Metrics defaults (a loopback listener, so nothing is exposed by default)
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.bind_address", "127.0.0.1:9090")
+	viper.SetDefault("metrics.token", "")
+	viper.SetDefault("metrics.large_response_bytes", 512*1024)
+	viper.SetDefault("metrics.slow_request", "2s")
+
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
@@ -157,6 +509,8 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("jwt.secret", "JWT_SECRET")
 	viper.BindEnv("jwt.expiry", "JWT_EXPIRY")
 	viper.BindEnv("jwt.refresh_expiry", "REFRESH_TOKEN_EXPIRY")
+	viper.BindEnv("two_factor.encryption_key", "TWO_FACTOR_ENCRYPTION_KEY")
+	viper.BindEnv("two_factor.issuer", "TWO_FACTOR_ISSUER")
 	viper.BindEnv("email.host", "SMTP_HOST")
 	viper.BindEnv("email.port", "SMTP_PORT")
 	viper.BindEnv("email.username", "SMTP_USERNAME")
@@ -164,6 +518,10 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("email.from_name", "SMTP_FROM_NAME")
 	viper.BindEnv("email.from_email", "SMTP_FROM_EMAIL")
 	viper.BindEnv("email.frontend_url", "FRONTEND_URL")
+	viper.BindEnv("email.verification_code_length", "VERIFICATION_CODE_LENGTH")
+	viper.BindEnv("email.verification_code_charset", "VERIFICATION_CODE_CHARSET")
+	viper.BindEnv("email.language", "EMAIL_LANGUAGE")
+	viper.BindEnv("email.preview_dir", "EMAIL_PREVIEW_DIR")
 	viper.BindEnv("server.port", "PORT")
 	viper.BindEnv("server.gin_mode", "GIN_MODE")
 	viper.BindEnv("server.cors_allowed_origins", "CORS_ALLOWED_ORIGINS")
@@ -172,6 +530,9 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("rate_limit.reset", "RATE_LIMIT_RESET")
 	viper.BindEnv("rate_limit.window", "RATE_LIMIT_WINDOW")
 	viper.BindEnv("rate_limit.forgiveness", "RATE_LIMIT_FORGIVENESS")
+	viper.BindEnv("lockout.max_failures", "LOGIN_LOCKOUT_MAX_FAILURES")
+	viper.BindEnv("lockout.window", "LOGIN_LOCKOUT_WINDOW")
+	viper.BindEnv("lockout.duration", "LOGIN_LOCKOUT_DURATION")
 	viper.BindEnv("google.client_id", "GOOGLE_CLIENT_ID")
 	viper.BindEnv("google.client_secret", "GOOGLE_CLIENT_SECRET")
 	viper.BindEnv("google.redirect_url", "GOOGLE_REDIRECT_URL")
@@ -181,9 +542,23 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("linkedin.client_id", "LINKEDIN_CLIENT_ID")
 	viper.BindEnv("linkedin.client_secret", "LINKEDIN_CLIENT_SECRET")
 	viper.BindEnv("linkedin.redirect_url", "LINKEDIN_REDIRECT_URL")
+	viper.BindEnv("apple.client_id", "APPLE_CLIENT_ID")
+	viper.BindEnv("apple.team_id", "APPLE_TEAM_ID")
+	viper.BindEnv("apple.key_id", "APPLE_KEY_ID")
+	viper.BindEnv("apple.private_key", "APPLE_PRIVATE_KEY")
+	viper.BindEnv("apple.redirect_url", "APPLE_REDIRECT_URL")
+	viper.BindEnv("twitter.client_id", "TWITTER_CLIENT_ID")
+	viper.BindEnv("twitter.client_secret", "TWITTER_CLIENT_SECRET")
+	viper.BindEnv("twitter.redirect_url", "TWITTER_REDIRECT_URL")
 	viper.BindEnv("storage.bucket_name", "STORAGE_BUCKET_NAME")
 	viper.BindEnv("storage.max_file_size", "STORAGE_MAX_FILE_SIZE")
 	viper.BindEnv("storage.allowed_file_types", "STORAGE_ALLOWED_FILE_TYPES")
+	viper.BindEnv("storage.user_quota_bytes", "STORAGE_USER_QUOTA_BYTES")
+	viper.BindEnv("storage.strip_metadata_types", "STORAGE_STRIP_METADATA_TYPES")
+	viper.BindEnv("storage.upload_image_types", "STORAGE_UPLOAD_IMAGE_TYPES")
+	viper.BindEnv("storage.upload_audio_types", "STORAGE_UPLOAD_AUDIO_TYPES")
+	viper.BindEnv("storage.upload_video_types", "STORAGE_UPLOAD_VIDEO_TYPES")
+	viper.BindEnv("storage.upload_document_types", "STORAGE_UPLOAD_DOCUMENT_TYPES")
 	viper.BindEnv("redis.host", "REDIS_HOST")
 	viper.BindEnv("redis.port", "REDIS_PORT")
 	viper.BindEnv("redis.password", "REDIS_PASSWORD")
@@ -197,6 +572,53 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("r2.public_url", "R2_PUBLIC_URL")
 	viper.BindEnv("r2.enabled", "R2_ENABLED")
 
+	// Feed environment variables
+	viper.BindEnv("feed.max_consecutive_per_author", "FEED_MAX_CONSECUTIVE_PER_AUTHOR")
+	viper.BindEnv("feed.max_per_author_per_page", "FEED_MAX_PER_AUTHOR_PER_PAGE")
+	viper.BindEnv("feed.candidate_multiplier", "FEED_CANDIDATE_MULTIPLIER")
+	viper.BindEnv("feed.trending_half_life_hours", "FEED_TRENDING_HALF_LIFE_HOURS")
+	viper.BindEnv("feed.trending_window_hours", "FEED_TRENDING_WINDOW_HOURS")
+	viper.BindEnv("feed.ranking.half_life_hours", "FEED_RANKING_HALF_LIFE_HOURS")
+	viper.BindEnv("feed.ranking.velocity_weight", "FEED_RANKING_VELOCITY_WEIGHT")
+	viper.BindEnv("feed.ranking.affinity_weight", "FEED_RANKING_AFFINITY_WEIGHT")
+	viper.BindEnv("feed.ranking.max_per_author", "FEED_RANKING_MAX_PER_AUTHOR")
+	viper.BindEnv("feed.experiment.name", "FEED_EXPERIMENT_NAME")
+	viper.BindEnv("feed.experiment.percent", "FEED_EXPERIMENT_PERCENT")
+	viper.BindEnv("feed.experiment.weights.half_life_hours", "FEED_EXPERIMENT_HALF_LIFE_HOURS")
+	viper.BindEnv("feed.experiment.weights.velocity_weight", "FEED_EXPERIMENT_VELOCITY_WEIGHT")
+	viper.BindEnv("feed.experiment.weights.affinity_weight", "FEED_EXPERIMENT_AFFINITY_WEIGHT")
+	viper.BindEnv("feed.experiment.weights.max_per_author", "FEED_EXPERIMENT_MAX_PER_AUTHOR")
+	viper.BindEnv("http_cache.enabled", "HTTP_CACHE_ENABLED")
+	viper.BindEnv("http_cache.max_age", "HTTP_CACHE_MAX_AGE")
+	viper.BindEnv("http_cache.shared_max_age", "HTTP_CACHE_SHARED_MAX_AGE")
+	viper.BindEnv("http_cache.stale_while_revalidate", "HTTP_CACHE_STALE_WHILE_REVALIDATE")
+	viper.BindEnv("status.max_per_day", "STATUS_MAX_PER_DAY")
+	viper.BindEnv("status.exempt_verified", "STATUS_LIMIT_EXEMPT_VERIFIED")
+	viper.BindEnv("status.lifetime_hours", "STATUS_LIFETIME_HOURS")
+	viper.BindEnv("posts.purge_retention_days", "POST_PURGE_RETENTION_DAYS")
+	viper.BindEnv("posts.purge_batch_size", "POST_PURGE_BATCH_SIZE")
+	viper.BindEnv("pins.max_per_conversation", "PINS_MAX_PER_CONVERSATION")
+	viper.BindEnv("pins.max_per_profile", "PINS_MAX_PER_PROFILE")
+	viper.BindEnv("comments.max_depth", "COMMENT_MAX_DEPTH")
+	viper.BindEnv("comments.rate_limit", "COMMENT_RATE_LIMIT")
+	viper.BindEnv("comments.rate_window", "COMMENT_RATE_WINDOW")
+	viper.BindEnv("new_account.period", "NEW_ACCOUNT_PERIOD")
+	viper.BindEnv("new_account.trusted_followers", "NEW_ACCOUNT_TRUSTED_FOLLOWERS")
+	viper.BindEnv("new_account.post_limit", "NEW_ACCOUNT_POST_LIMIT")
+	viper.BindEnv("new_account.message_limit", "NEW_ACCOUNT_MESSAGE_LIMIT")
+	viper.BindEnv("new_account.conversation_limit", "NEW_ACCOUNT_CONVERSATION_LIMIT")
+	viper.BindEnv("new_account.follow_limit", "NEW_ACCOUNT_FOLLOW_LIMIT")
+	viper.BindEnv("new_account.allow_links", "NEW_ACCOUNT_ALLOW_LINKS")
+	viper.BindEnv("new_account.allow_bulk", "NEW_ACCOUNT_ALLOW_BULK")
+	viper.BindEnv("push.fcm_service_account", "FCM_SERVICE_ACCOUNT")
+	viper.BindEnv("websocket.send_buffer", "WS_SEND_BUFFER")
+	viper.BindEnv("websocket.slow_client", "WS_SLOW_CLIENT")
+	viper.BindEnv("metrics.enabled", "METRICS_ENABLED")
+	viper.BindEnv("metrics.bind_address", "METRICS_BIND_ADDRESS")
+	viper.BindEnv("metrics.token", "METRICS_TOKEN")
+	viper.BindEnv("metrics.large_response_bytes", "METRICS_LARGE_RESPONSE_BYTES")
+	viper.BindEnv("metrics.slow_request", "METRICS_SLOW_REQUEST")
+
 	// Logging environment variables
 	viper.BindEnv("logging.level", "LOG_LEVEL")
 	viper.BindEnv("logging.format", "LOG_FORMAT")
@@ -206,45 +628,15 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	// Validate required configuration
-	if err := validateConfig(&config); err != nil {
+	// Fail fast on a bad configuration rather than deep inside a request
+	config.normalize()
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 
 	return &config, nil
 }
 
-// validateConfig validates that all required configuration is present
-func validateConfig(config *Config) error {
-	requiredFields := map[string]string{
-		"SUPABASE_URL":              config.Database.SupabaseURL,
-		"SUPABASE_ANON_KEY":         config.Database.SupabaseAnonKey,
-		"SUPABASE_SERVICE_ROLE_KEY": config.Database.SupabaseServiceKey,
-		"JWT_SECRET":                config.JWT.Secret,
-		"SMTP_USERNAME":             config.Email.Username,
-		"SMTP_PASSWORD":             config.Email.Password,
-	}
-
-	for field, value := range requiredFields {
-		if value == "" {
-			return &ConfigError{
-				Field: field,
-				Msg:   "required configuration field is missing",
-			}
-		}
-	}
-
-	// Validate JWT secret length
-	if len(config.JWT.Secret) < 32 {
-		return &ConfigError{
-			Field: "JWT_SECRET",
-			Msg:   "JWT secret must be at least 32 characters long",
-		}
-	}
-
-	return nil
-}
-
 // GetCORSOrigins returns a slice of allowed CORS origins
 func (c *Config) GetCORSOrigins() []string {
 	if c.Server.CORSAllowedOrigins == "" {
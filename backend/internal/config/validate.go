@@ -0,0 +1,350 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every problem found in a configuration, so a bad deploy is
+// fixed in one pass rather than one restart per missing variable
+type ValidationError struct {
+	Problems []*ConfigError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - " + p.Field + ": " + p.Msg)
+	}
+	return b.String()
+}
+
+// problems collects ConfigErrors while Validate walks the config
+type problems []*ConfigError
+
+func (p *problems) add(field, msg string) {
+	*p = append(*p, &ConfigError{Field: field, Msg: msg})
+}
+
+// normalize fixes up values that are unambiguous to correct, before Validate sees them
+func (c *Config) normalize() {
+	// Repositories append /rest/v1/... to the URL, so a trailing slash doubles it
+	supabaseURL := strings.TrimRight(strings.TrimSpace(c.Database.SupabaseURL), "/")
+	if supabaseURL != "" && !strings.Contains(supabaseURL, "://") && strings.Contains(supabaseURL, ".supabase.co") {
+		log.Printf("[Config] SUPABASE_URL has no scheme, assuming https://%s", supabaseURL)
+		supabaseURL = "https://" + supabaseURL
+	}
+	c.Database.SupabaseURL = supabaseURL
+}
+
+// Validate checks the whole configuration and returns a *ValidationError naming every
+// invalid or missing setting, or nil
+func (c *Config) Validate() error {
+	var p problems
+
+	// Required settings, in a fixed order so the report is stable
+	required := []struct{ field, value string }{
+		{"SUPABASE_URL", c.Database.SupabaseURL},
+		{"SUPABASE_ANON_KEY", c.Database.SupabaseAnonKey},
+		{"SUPABASE_SERVICE_ROLE_KEY", c.Database.SupabaseServiceKey},
+		{"JWT_SECRET", c.JWT.Secret},
+		{"SMTP_USERNAME", c.Email.Username},
+		{"SMTP_PASSWORD", c.Email.Password},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			p.add(r.field, "required configuration field is missing")
+		}
+	}
+
+	if c.Database.SupabaseURL != "" && !isHTTPURL(c.Database.SupabaseURL) {
+		p.add("SUPABASE_URL", "must be an http(s) URL such as https://xxx.supabase.co")
+	}
+	if c.JWT.Secret != "" && len(c.JWT.Secret) < 32 {
+		p.add("JWT_SECRET", "JWT secret must be at least 32 characters long")
+	}
+
+	c.validateServer(&p)
+	c.validateAuth(&p)
+	c.validateEmail(&p)
+	c.validateStorage(&p)
+	c.validateFeed(&p)
+
+	if len(p) > 0 {
+		return &ValidationError{Problems: p}
+	}
+	return nil
+}
+
+func (c *Config) validateServer(p *problems) {
+	if !isPort(c.Server.Port) {
+		p.add("PORT", "must be a port number between 1 and 65535")
+	}
+	switch c.Server.GinMode {
+	case "debug", "release", "test":
+	default:
+		p.add("GIN_MODE", "must be debug, release or test")
+	}
+	for _, origin := range c.GetCORSOrigins() {
+		if origin != "*" && !isHTTPURL(origin) {
+			p.add("CORS_ALLOWED_ORIGINS", fmt.Sprintf("%q is not an http(s) origin", origin))
+		}
+	}
+	if c.Redis.Host != "" && !isPort(c.Redis.Port) {
+		p.add("REDIS_PORT", "must be a port number between 1 and 65535")
+	}
+	if c.Redis.DB < 0 {
+		p.add("REDIS_DB", "cannot be negative")
+	}
+
+	if c.RateLimit.Login <= 0 || c.RateLimit.Register <= 0 || c.RateLimit.Reset <= 0 {
+		p.add("RATE_LIMIT_*", "login, register and reset limits must be positive")
+	}
+	if d, err := time.ParseDuration(c.RateLimit.Window); err != nil || d <= 0 {
+		p.add("RATE_LIMIT_WINDOW", "must be a positive duration such as 1m")
+	}
+	if c.RateLimit.Forgiveness < 0 {
+		p.add("RATE_LIMIT_FORGIVENESS", "cannot be negative")
+	}
+	if c.HTTPCache.MaxAge < 0 || c.HTTPCache.SharedMaxAge < 0 || c.HTTPCache.StaleWhileRevalidate < 0 {
+		p.add("HTTP_CACHE_*", "cache lifetimes cannot be negative")
+	}
+}
+
+func (c *Config) validateAuth(p *problems) {
+	// Token lifetimes
+	if d, err := time.ParseDuration(c.JWT.Expiry); err != nil || d <= 0 {
+		p.add("JWT_EXPIRY", "access token expiry must be a positive duration such as 15m")
+	} else if d, err := time.ParseDuration(c.JWT.RefreshExpiry); err != nil || d < c.JWT.AccessTTL() {
+		p.add("REFRESH_TOKEN_EXPIRY", "refresh token expiry must be a duration such as 720h, no shorter than JWT_EXPIRY")
+	}
+
+	// Changing the 2FA key later locks everyone out of their authenticator
+	if c.TwoFactor.EncryptionKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.TwoFactor.EncryptionKey); err != nil || len(key) != 32 {
+			p.add("TWO_FACTOR_ENCRYPTION_KEY", "2FA encryption key must be 32 bytes, base64-encoded (e.g. openssl rand -base64 32)")
+		}
+	}
+
+	// Login lockout
+	if c.Lockout.MaxFailures < 0 {
+		p.add("LOGIN_LOCKOUT_MAX_FAILURES", "lockout threshold must be 0 (disabled) or more")
+	}
+	if c.Lockout.MaxFailures > 0 {
+		if d, err := time.ParseDuration(c.Lockout.Window); err != nil || d <= 0 {
+			p.add("LOGIN_LOCKOUT_WINDOW", "lockout window must be a positive duration such as 15m")
+		}
+		if d, err := time.ParseDuration(c.Lockout.Duration); err != nil || d <= 0 {
+			p.add("LOGIN_LOCKOUT_DURATION", "lockout duration must be a positive duration such as 15m")
+		}
+	}
+
+	// An OAuth provider is on when it has a client ID, and then needs the rest
+	providers := []struct {
+		prefix               string
+		id, secret, redirect string
+	}{
+		{"GOOGLE", c.Google.ClientID, c.Google.ClientSecret, c.Google.RedirectURL},
+		{"GITHUB", c.GitHub.ClientID, c.GitHub.ClientSecret, c.GitHub.RedirectURL},
+		{"LINKEDIN", c.LinkedIn.ClientID, c.LinkedIn.ClientSecret, c.LinkedIn.RedirectURL},
+		{"TWITTER", c.Twitter.ClientID, c.Twitter.ClientSecret, c.Twitter.RedirectURL},
+	}
+	for _, o := range providers {
+		if (o.id == "") != (o.secret == "") {
+			p.add(o.prefix+"_CLIENT_ID", o.prefix+"_CLIENT_ID and "+o.prefix+"_CLIENT_SECRET must be set together")
+		}
+		if o.redirect != "" && !isHTTPURL(o.redirect) {
+			p.add(o.prefix+"_REDIRECT_URL", "must be an http(s) URL")
+		}
+	}
+
+	// Apple signs its client secret with a key instead, so it needs all four
+	apple := []struct{ field, value string }{
+		{"APPLE_CLIENT_ID", c.Apple.ClientID},
+		{"APPLE_TEAM_ID", c.Apple.TeamID},
+		{"APPLE_KEY_ID", c.Apple.KeyID},
+		{"APPLE_PRIVATE_KEY", c.Apple.PrivateKey},
+	}
+	appleSet := false
+	for _, f := range apple {
+		appleSet = appleSet || f.value != ""
+	}
+	if appleSet {
+		for _, f := range apple {
+			if f.value == "" {
+				p.add(f.field, "required once any APPLE_* setting is set")
+			}
+		}
+		if c.Apple.PrivateKey != "" {
+			if _, err := c.Apple.SigningKey(); err != nil {
+				p.add("APPLE_PRIVATE_KEY", "must be the PEM contents of the .p8 key file: "+err.Error())
+			}
+		}
+	}
+	if c.Apple.RedirectURL != "" && !isHTTPURL(c.Apple.RedirectURL) {
+		p.add("APPLE_REDIRECT_URL", "must be an http(s) URL")
+	}
+}
+
+func (c *Config) validateEmail(p *problems) {
+	if c.Email.Port < 1 || c.Email.Port > 65535 {
+		p.add("SMTP_PORT", "must be a port number between 1 and 65535")
+	}
+	if !isHTTPURL(c.Email.FrontendURL) {
+		p.add("FRONTEND_URL", "must be an http(s) URL; links in emails point to it")
+	}
+	if c.Email.VerificationCodeLength < 4 || c.Email.VerificationCodeLength > 12 {
+		p.add("VERIFICATION_CODE_LENGTH", "verification code length must be between 4 and 12")
+	}
+	if c.Email.VerificationCodeCharset != "numeric" && c.Email.VerificationCodeCharset != "alphanumeric" {
+		p.add("VERIFICATION_CODE_CHARSET", "verification code charset must be 'numeric' or 'alphanumeric'")
+	}
+	if c.Email.PreviewDir != "" && c.Server.GinMode == "release" {
+		p.add("EMAIL_PREVIEW_DIR", "must be empty in release mode, or emails are written to disk instead of sent")
+	}
+}
+
+func (c *Config) validateStorage(p *problems) {
+	if c.Storage.MaxFileSize <= 0 {
+		p.add("STORAGE_MAX_FILE_SIZE", "must be positive")
+	}
+	if c.Storage.UserQuotaBytes < 0 {
+		p.add("STORAGE_USER_QUOTA_BYTES", "cannot be negative (use 0 to disable)")
+	}
+
+	// R2 is used once any credential is set, and then needs all of them
+	r2 := []struct{ field, value string }{
+		{"R2_ACCOUNT_ID", c.R2.AccountID},
+		{"R2_ACCESS_KEY_ID", c.R2.AccessKeyID},
+		{"R2_SECRET_ACCESS_KEY", c.R2.SecretAccessKey},
+		{"R2_BUCKET_NAME", c.R2.BucketName},
+	}
+	if c.R2.Enabled || c.R2.AccountID != "" || c.R2.AccessKeyID != "" || c.R2.SecretAccessKey != "" {
+		for _, f := range r2 {
+			if f.value == "" {
+				p.add(f.field, "required once any R2 credential is set or R2_ENABLED is true")
+			}
+		}
+	}
+	if c.R2.PublicURL != "" && !isHTTPURL(c.R2.PublicURL) {
+		p.add("R2_PUBLIC_URL", "must be an http(s) URL")
+	}
+}
+
+func (c *Config) validateFeed(p *problems) {
+	if c.Feed.MaxConsecutivePerAuthor < 0 || c.Feed.MaxPerAuthorPerPage < 0 {
+		p.add("FEED_MAX_*", "author limits cannot be negative (use 0 to disable)")
+	}
+	if c.Feed.CandidateMultiplier < 1 {
+		p.add("FEED_CANDIDATE_MULTIPLIER", "must be at least 1")
+	}
+	if c.Feed.TrendingHalfLifeHours <= 0 || c.Feed.TrendingWindowHours <= 0 {
+		p.add("FEED_TRENDING_HALF_LIFE_HOURS", "trending half-life and window must be positive")
+	}
+	validateRankingWeights(p, c.Feed.Ranking, "FEED_RANKING")
+	if c.Feed.Experiment.Percent < 0 || c.Feed.Experiment.Percent > 100 {
+		p.add("FEED_EXPERIMENT_PERCENT", "experiment percent must be between 0 and 100")
+	}
+	if c.Feed.Experiment.Percent > 0 {
+		if c.Feed.Experiment.Name == "" {
+			p.add("FEED_EXPERIMENT_NAME", "a running experiment needs a name")
+		}
+		validateRankingWeights(p, c.Feed.Experiment.Weights, "FEED_EXPERIMENT")
+	}
+
+	if c.Status.MaxPerDay < 0 {
+		p.add("STATUS_MAX_PER_DAY", "status limit cannot be negative (use 0 to disable)")
+	}
+	if c.Posts.PurgeRetentionDays < 0 {
+		p.add("POST_PURGE_RETENTION_DAYS", "post purge retention cannot be negative (use 0 to disable)")
+	}
+	if c.Posts.PurgeBatchSize < 1 || c.Posts.PurgeBatchSize > 1000 {
+		p.add("POST_PURGE_BATCH_SIZE", "post purge batch size must be between 1 and 1000")
+	}
+	if c.Pins.MaxPerConversation < 0 {
+		p.add("PINS_MAX_PER_CONVERSATION", "pin limit cannot be negative (use 0 for no limit)")
+	}
+	if c.Pins.MaxPerProfile < 0 {
+		p.add("PINS_MAX_PER_PROFILE", "pin limit cannot be negative (use 0 for no limit)")
+	}
+	if c.Comments.MaxDepth < 1 {
+		p.add("COMMENT_MAX_DEPTH", "comment depth must be at least 1 (flat comments)")
+	}
+	if c.Comments.RateLimit < 0 {
+		p.add("COMMENT_RATE_LIMIT", "comment rate limit cannot be negative (use 0 to disable)")
+	}
+	if d, err := time.ParseDuration(c.Comments.RateWindow); err != nil || d <= 0 {
+		p.add("COMMENT_RATE_WINDOW", "must be a positive duration such as 1m")
+	}
+	if d, err := time.ParseDuration(c.NewAccount.Period); err != nil || d < 0 {
+		p.add("NEW_ACCOUNT_PERIOD", "must be a duration such as 72h (0 disables the restrictions)")
+	}
+	if c.NewAccount.TrustedFollowers < 0 {
+		p.add("NEW_ACCOUNT_TRUSTED_FOLLOWERS", "follower count cannot be negative")
+	}
+	if c.NewAccount.PostLimit < 0 {
+		p.add("NEW_ACCOUNT_POST_LIMIT", "limit cannot be negative (use 0 for no extra limit)")
+	}
+	if c.NewAccount.MessageLimit < 0 {
+		p.add("NEW_ACCOUNT_MESSAGE_LIMIT", "limit cannot be negative (use 0 for no extra limit)")
+	}
+	if c.NewAccount.ConversationLimit < 0 {
+		p.add("NEW_ACCOUNT_CONVERSATION_LIMIT", "limit cannot be negative (use 0 for no extra limit)")
+	}
+	if c.NewAccount.FollowLimit < 0 {
+		p.add("NEW_ACCOUNT_FOLLOW_LIMIT", "limit cannot be negative (use 0 for no extra limit)")
+	}
+	if _, err := c.Push.ServiceAccount(); err != nil {
+		p.add("FCM_SERVICE_ACCOUNT", "must be a Firebase service account key (JSON or a path to it): "+err.Error())
+	}
+	if c.WebSocket.SendBuffer < 1 {
+		p.add("WS_SEND_BUFFER", "send buffer must hold at least 1 message")
+	}
+	if c.WebSocket.SlowClient != "resync" && c.WebSocket.SlowClient != "disconnect" {
+		p.add("WS_SLOW_CLIENT", `must be "resync" or "disconnect"`)
+	}
+	if c.Metrics.Enabled {
+		if c.Metrics.BindAddress != "" {
+			if _, _, err := net.SplitHostPort(c.Metrics.BindAddress); err != nil {
+				p.add("METRICS_BIND_ADDRESS", "must be host:port, such as 127.0.0.1:9090")
+			}
+		} else if c.Metrics.Token == "" {
+			p.add("METRICS_TOKEN", "required when metrics are served on the API port (METRICS_BIND_ADDRESS empty)")
+		}
+	}
+	if c.Metrics.LargeResponseBytes < 0 {
+		p.add("METRICS_LARGE_RESPONSE_BYTES", "cannot be negative (use 0 to flag no responses)")
+	}
+	if d, err := time.ParseDuration(c.Metrics.SlowRequest); err != nil || d < 0 {
+		p.add("METRICS_SLOW_REQUEST", `must be a duration such as "2s" (use "0" to flag no requests)`)
+	}
+}
+
+// validateRankingWeights checks one set of explore ranking weights. prefix is the
+// env var prefix the weights were read from.
+func validateRankingWeights(p *problems, w RankingWeightsConfig, prefix string) {
+	if w.HalfLifeHours < 0 || w.VelocityWeight < 0 || w.AffinityWeight < 0 {
+		p.add(prefix+"_*", "ranking half-life and weights cannot be negative (use 0 to turn one off)")
+	}
+	if w.MaxPerAuthor < 0 {
+		p.add(prefix+"_MAX_PER_AUTHOR", "ranking per-author cap cannot be negative (use 0 for the feed default)")
+	}
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL with a host
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isPort(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 1 && n <= 65535
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// validConfig returns a configuration Validate accepts, for tests to break one
+// setting of
+func validConfig() *Config {
+	return &Config{
+		Database: DatabaseConfig{SupabaseURL: "https://xyz.supabase.co", SupabaseAnonKey: "anon", SupabaseServiceKey: "service"},
+		JWT:      JWTConfig{Secret: strings.Repeat("s", 32), Expiry: "15m", RefreshExpiry: "720h"},
+		Email: EmailConfig{
+			Port: 587, Username: "mailer", Password: "secret", FrontendURL: "https://histeeria.app",
+			VerificationCodeLength: 6, VerificationCodeCharset: "numeric",
+		},
+		Server:     ServerConfig{Port: "8080", GinMode: "release", CORSAllowedOrigins: "https://histeeria.app"},
+		RateLimit:  RateLimitConfig{Login: 5, Register: 3, Reset: 3, Window: "1m"},
+		Lockout:    LockoutConfig{MaxFailures: 5, Window: "15m", Duration: "15m"},
+		Storage:    StorageConfig{MaxFileSize: 1 << 20},
+		Feed:       FeedConfig{CandidateMultiplier: 2, TrendingHalfLifeHours: 6, TrendingWindowHours: 48},
+		Posts:      PostsConfig{PurgeBatchSize: 100},
+		Comments:   CommentsConfig{MaxDepth: 3, RateWindow: "1m"},
+		NewAccount: NewAccountConfig{Period: "72h"},
+		WebSocket:  WebSocketConfig{SendBuffer: 256, SlowClient: "resync"},
+		Metrics:    MetricsConfig{SlowRequest: "2s"},
+	}
+}
+
+// appleKey returns a freshly generated .p8 style key
+func appleKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	p8 := appleKey(t)
+	tests := []struct {
+		field  string
+		mutate func(c *Config)
+	}{
+		{"SUPABASE_URL", func(c *Config) { c.Database.SupabaseURL = "" }},
+		{"SUPABASE_URL", func(c *Config) { c.Database.SupabaseURL = "ftp://xyz.supabase.co" }},
+		{"SUPABASE_ANON_KEY", func(c *Config) { c.Database.SupabaseAnonKey = "" }},
+		{"SUPABASE_SERVICE_ROLE_KEY", func(c *Config) { c.Database.SupabaseServiceKey = "" }},
+		{"JWT_SECRET", func(c *Config) { c.JWT.Secret = "" }},
+		{"JWT_SECRET", func(c *Config) { c.JWT.Secret = "short" }},
+		{"SMTP_USERNAME", func(c *Config) { c.Email.Username = "" }},
+		{"SMTP_PASSWORD", func(c *Config) { c.Email.Password = "" }},
+		{"PORT", func(c *Config) { c.Server.Port = "70000" }},
+		{"GIN_MODE", func(c *Config) { c.Server.GinMode = "production" }},
+		{"CORS_ALLOWED_ORIGINS", func(c *Config) { c.Server.CORSAllowedOrigins = "histeeria.app" }},
+		{"REDIS_PORT", func(c *Config) { c.Redis = RedisConfig{Host: "localhost", Port: "redis"} }},
+		{"REDIS_DB", func(c *Config) { c.Redis.DB = -1 }},
+		{"RATE_LIMIT_*", func(c *Config) { c.RateLimit.Login = 0 }},
+		{"RATE_LIMIT_WINDOW", func(c *Config) { c.RateLimit.Window = "soon" }},
+		{"RATE_LIMIT_FORGIVENESS", func(c *Config) { c.RateLimit.Forgiveness = -1 }},
+		{"HTTP_CACHE_*", func(c *Config) { c.HTTPCache.MaxAge = -1 }},
+		{"JWT_EXPIRY", func(c *Config) { c.JWT.Expiry = "0s" }},
+		{"REFRESH_TOKEN_EXPIRY", func(c *Config) { c.JWT.RefreshExpiry = "1m" }},
+		{"TWO_FACTOR_ENCRYPTION_KEY", func(c *Config) { c.TwoFactor.EncryptionKey = "dG9vIHNob3J0" }},
+		{"LOGIN_LOCKOUT_MAX_FAILURES", func(c *Config) { c.Lockout.MaxFailures = -1 }},
+		{"LOGIN_LOCKOUT_WINDOW", func(c *Config) { c.Lockout.Window = "" }},
+		{"LOGIN_LOCKOUT_DURATION", func(c *Config) { c.Lockout.Duration = "-1m" }},
+		{"GOOGLE_CLIENT_ID", func(c *Config) { c.Google.ClientID = "id" }},
+		{"GITHUB_REDIRECT_URL", func(c *Config) { c.GitHub.RedirectURL = "/callback" }},
+		{"APPLE_TEAM_ID", func(c *Config) {
+			c.Apple = AppleConfig{ClientID: "com.histeeria.web", KeyID: "key", PrivateKey: p8}
+		}},
+		{"APPLE_PRIVATE_KEY", func(c *Config) {
+			c.Apple = AppleConfig{ClientID: "com.histeeria.web", TeamID: "team", KeyID: "key", PrivateKey: "not a key"}
+		}},
+		{"APPLE_REDIRECT_URL", func(c *Config) { c.Apple.RedirectURL = "callback" }},
+		{"SMTP_PORT", func(c *Config) { c.Email.Port = 0 }},
+		{"FRONTEND_URL", func(c *Config) { c.Email.FrontendURL = "histeeria.app" }},
+		{"VERIFICATION_CODE_LENGTH", func(c *Config) { c.Email.VerificationCodeLength = 3 }},
+		{"VERIFICATION_CODE_CHARSET", func(c *Config) { c.Email.VerificationCodeCharset = "emoji" }},
+		{"EMAIL_PREVIEW_DIR", func(c *Config) { c.Email.PreviewDir = "/tmp/emails" }},
+		{"STORAGE_MAX_FILE_SIZE", func(c *Config) { c.Storage.MaxFileSize = 0 }},
+		{"STORAGE_USER_QUOTA_BYTES", func(c *Config) { c.Storage.UserQuotaBytes = -1 }},
+		{"R2_BUCKET_NAME", func(c *Config) {
+			c.R2 = R2Config{AccountID: "acct", AccessKeyID: "key", SecretAccessKey: "secret"}
+		}},
+		{"R2_PUBLIC_URL", func(c *Config) { c.R2.PublicURL = "cdn.histeeria.app" }},
+		{"FEED_MAX_*", func(c *Config) { c.Feed.MaxPerAuthorPerPage = -1 }},
+		{"FEED_CANDIDATE_MULTIPLIER", func(c *Config) { c.Feed.CandidateMultiplier = 0 }},
+		{"FEED_TRENDING_HALF_LIFE_HOURS", func(c *Config) { c.Feed.TrendingWindowHours = 0 }},
+		{"FEED_RANKING_*", func(c *Config) { c.Feed.Ranking.VelocityWeight = -1 }},
+		{"FEED_RANKING_MAX_PER_AUTHOR", func(c *Config) { c.Feed.Ranking.MaxPerAuthor = -1 }},
+		{"FEED_EXPERIMENT_PERCENT", func(c *Config) { c.Feed.Experiment = RankingExperimentConfig{Name: "fresh", Percent: 101} }},
+		{"FEED_EXPERIMENT_NAME", func(c *Config) { c.Feed.Experiment.Percent = 10 }},
+		{"FEED_EXPERIMENT_*", func(c *Config) {
+			c.Feed.Experiment = RankingExperimentConfig{Name: "fresh", Percent: 10, Weights: RankingWeightsConfig{HalfLifeHours: -1}}
+		}},
+		{"STATUS_MAX_PER_DAY", func(c *Config) { c.Status.MaxPerDay = -1 }},
+		{"POST_PURGE_RETENTION_DAYS", func(c *Config) { c.Posts.PurgeRetentionDays = -1 }},
+		{"POST_PURGE_BATCH_SIZE", func(c *Config) { c.Posts.PurgeBatchSize = 1001 }},
+		{"PINS_MAX_PER_CONVERSATION", func(c *Config) { c.Pins.MaxPerConversation = -1 }},
+		{"PINS_MAX_PER_PROFILE", func(c *Config) { c.Pins.MaxPerProfile = -1 }},
+		{"COMMENT_MAX_DEPTH", func(c *Config) { c.Comments.MaxDepth = 0 }},
+		{"COMMENT_RATE_LIMIT", func(c *Config) { c.Comments.RateLimit = -1 }},
+		{"COMMENT_RATE_WINDOW", func(c *Config) { c.Comments.RateWindow = "0s" }},
+		{"NEW_ACCOUNT_PERIOD", func(c *Config) { c.NewAccount.Period = "three days" }},
+		{"NEW_ACCOUNT_TRUSTED_FOLLOWERS", func(c *Config) { c.NewAccount.TrustedFollowers = -1 }},
+		{"NEW_ACCOUNT_POST_LIMIT", func(c *Config) { c.NewAccount.PostLimit = -1 }},
+		{"NEW_ACCOUNT_MESSAGE_LIMIT", func(c *Config) { c.NewAccount.MessageLimit = -1 }},
+		{"NEW_ACCOUNT_CONVERSATION_LIMIT", func(c *Config) { c.NewAccount.ConversationLimit = -1 }},
+		{"NEW_ACCOUNT_FOLLOW_LIMIT", func(c *Config) { c.NewAccount.FollowLimit = -1 }},
+		{"FCM_SERVICE_ACCOUNT", func(c *Config) { c.Push.FCMServiceAccount = "{not json" }},
+		{"WS_SEND_BUFFER", func(c *Config) { c.WebSocket.SendBuffer = 0 }},
+		{"WS_SLOW_CLIENT", func(c *Config) { c.WebSocket.SlowClient = "drop" }},
+		{"METRICS_BIND_ADDRESS", func(c *Config) { c.Metrics = MetricsConfig{Enabled: true, BindAddress: "9090", SlowRequest: "2s"} }},
+		{"METRICS_TOKEN", func(c *Config) { c.Metrics.Enabled = true }},
+		{"METRICS_LARGE_RESPONSE_BYTES", func(c *Config) { c.Metrics.LargeResponseBytes = -1 }},
+		{"METRICS_SLOW_REQUEST", func(c *Config) { c.Metrics.SlowRequest = "slow" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			c := validConfig()
+			tt.mutate(c)
+
+			var verr *ValidationError
+			if err := c.Validate(); !errors.As(err, &verr) {
+				t.Fatalf("Validate = %v, want a *ValidationError", err)
+			}
+			if len(verr.Problems) != 1 || verr.Problems[0].Field != tt.field {
+				t.Errorf("problems = %v, want just %s", verr, tt.field)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := validConfig()
+	c.JWT.Secret = ""
+	c.Server.GinMode = "production"
+	c.WebSocket.SendBuffer = 0
+
+	var verr *ValidationError
+	if err := c.Validate(); !errors.As(err, &verr) || len(verr.Problems) != 3 {
+		t.Fatalf("Validate = %v, want all 3 problems", err)
+	}
+}
@@ -133,12 +133,6 @@ func EncryptProfileField(plaintext string, userEncryptionKey []byte) (encrypted
 		return "", "", fmt.Errorf("encryption key must be 32 bytes (AES-256)")
 	}
 
-	// Generate random IV
-	ivBytes := make([]byte, aes.BlockSize)
-	if _, err := io.ReadFull(rand.Reader, ivBytes); err != nil {
-		return "", "", fmt.Errorf("failed to generate IV: %w", err)
-	}
-
 	// Encrypt with AES-GCM
 	block, err := aes.NewCipher(userEncryptionKey)
 	if err != nil {
@@ -150,6 +144,12 @@ func EncryptProfileField(plaintext string, userEncryptionKey []byte) (encrypted
 		return "", "", fmt.Errorf("failed to create GCM: %w", err)
 	}
 
+	// Generate random IV (GCM takes a 12-byte nonce, not a full AES block)
+	ivBytes := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, ivBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate IV: %w", err)
+	}
+
 	ciphertext := gcm.Seal(nil, ivBytes, []byte(plaintext), nil)
 
 	return base64.StdEncoding.EncodeToString(ciphertext), base64.StdEncoding.EncodeToString(ivBytes), nil
@@ -183,6 +183,10 @@ func DecryptProfileField(encrypted string, iv string, userEncryptionKey []byte)
 		return "", fmt.Errorf("failed to create GCM: %w", err)
 	}
 
+	if len(ivBytes) != gcm.NonceSize() {
+		return "", fmt.Errorf("invalid IV length %d", len(ivBytes))
+	}
+
 	plaintextBytes, err := gcm.Open(nil, ivBytes, ciphertext, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt profile field: %w", err)
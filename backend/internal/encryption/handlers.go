@@ -4,6 +4,8 @@ import (
 	"encoding/base64"
 	"net/http"
 
+	"histeeria-backend/internal/utils"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -394,15 +396,9 @@ func (h *Handlers) GetKeyFingerprint(c *gin.Context) {
 
 // getUserIDFromContext extracts user ID from Gin context
 func getUserIDFromContext(c *gin.Context) (uuid.UUID, error) {
-	userIDInterface, exists := c.Get("user_id")
-	if !exists {
-		return uuid.Nil, http.ErrNoCookie
-	}
-
-	userIDStr, ok := userIDInterface.(string)
-	if !ok {
-		return uuid.Nil, http.ErrNoCookie
+	user, err := utils.CurrentUser(c)
+	if err != nil {
+		return uuid.Nil, err
 	}
-
-	return uuid.Parse(userIDStr)
+	return user.ID, nil
 }
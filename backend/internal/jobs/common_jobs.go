@@ -7,7 +7,9 @@ import (
 
 	"histeeria-backend/internal/cache"
 	"histeeria-backend/internal/messaging"
+	"histeeria-backend/internal/posts"
 	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/status"
 )
 
 // JobFactory creates common background jobs
@@ -17,6 +19,11 @@ type JobFactory struct {
 	statusRepo       repository.StatusRepository
 	feedCache        *cache.FeedCacheService
 	deliveryService  *messaging.DeliveryService
+	messagingService *messaging.MessagingService
+	postService      *posts.Service
+	statusService    *status.Service
+	postRetention    time.Duration // How long soft-deleted posts are kept; 0 disables the purge
+	postPurgeBatch   int
 }
 
 // NewJobFactory creates a new job factory
@@ -36,6 +43,42 @@ func NewJobFactory(
 	}
 }
 
+// expiredMessageBatch is how many disappearing messages are deleted per round trip
+const expiredMessageBatch = 200
+
+// scheduledMessageBatch is how many due scheduled messages are claimed per round trip
+const scheduledMessageBatch = 100
+
+// scheduledPostBatch is how many due scheduled posts are published per run
+const scheduledPostBatch = 100
+
+// expiredStatusBatch is how many expired statuses are purged per transaction
+const expiredStatusBatch = 200
+
+// SetMessagingService enables the purge of disappearing messages and the sending of
+// scheduled messages. Call before RegisterCommonJobs.
+func (f *JobFactory) SetMessagingService(messagingService *messaging.MessagingService) {
+	f.messagingService = messagingService
+}
+
+// SetPostService enables post jobs (poll auto-close). Call before RegisterCommonJobs.
+func (f *JobFactory) SetPostService(postService *posts.Service) {
+	f.postService = postService
+}
+
+// SetStatusService enables the purge of expired statuses and their media. Call before
+// RegisterCommonJobs.
+func (f *JobFactory) SetStatusService(statusService *status.Service) {
+	f.statusService = statusService
+}
+
+// SetPostPurge enables the permanent purge of posts soft-deleted longer than retention,
+// batchSize posts per transaction. Needs SetPostService; call before RegisterCommonJobs.
+func (f *JobFactory) SetPostPurge(retention time.Duration, batchSize int) {
+	f.postRetention = retention
+	f.postPurgeBatch = batchSize
+}
+
 // RegisterCommonJobs registers all common background jobs
 func (f *JobFactory) RegisterCommonJobs(scheduler *JobScheduler) {
 	// Message cleanup (WhatsApp-style - delete after delivery)
@@ -61,8 +104,32 @@ func (f *JobFactory) RegisterCommonJobs(scheduler *JobScheduler) {
 		})
 	}
 
+	// Disappearing messages, deleted soon after their timer runs out
+	if f.messagingService != nil {
+		scheduler.RegisterJob(&ScheduledJob{
+			Name:       "purge-expired-messages",
+			Interval:   1 * time.Minute,
+			Handler:    f.PurgeExpiredMessages,
+			Timeout:    1 * time.Minute,
+			RetryCount: 1,
+			RetryDelay: 10 * time.Second,
+			RunOnStart: true,
+		})
+
+		// Send-later messages go out within a minute of their time
+		scheduler.RegisterJob(&ScheduledJob{
+			Name:       "send-scheduled-messages",
+			Interval:   1 * time.Minute,
+			Handler:    f.SendScheduledMessages,
+			Timeout:    1 * time.Minute,
+			RetryCount: 1,
+			RetryDelay: 10 * time.Second,
+			RunOnStart: true,
+		})
+	}
+
 	// Status cleanup (24h stories)
-	if f.statusRepo != nil {
+	if f.statusService != nil {
 		scheduler.RegisterJob(&ScheduledJob{
 			Name:       "cleanup-expired-statuses",
 			Interval:   1 * time.Hour,
@@ -87,6 +154,45 @@ func (f *JobFactory) RegisterCommonJobs(scheduler *JobScheduler) {
 		})
 	}
 
+	// Poll auto-close
+	if f.postService != nil {
+		scheduler.RegisterJob(&ScheduledJob{
+			Name:       "close-expired-polls",
+			Interval:   1 * time.Minute,
+			Handler:    f.CloseExpiredPolls,
+			Timeout:    1 * time.Minute,
+			RetryCount: 1,
+			RetryDelay: 10 * time.Second,
+			RunOnStart: true,
+		})
+	}
+
+	// Scheduled posts going out
+	if f.postService != nil {
+		scheduler.RegisterJob(&ScheduledJob{
+			Name:       "publish-scheduled-posts",
+			Interval:   1 * time.Minute,
+			Handler:    f.PublishScheduledPosts,
+			Timeout:    1 * time.Minute,
+			RetryCount: 1,
+			RetryDelay: 10 * time.Second,
+			RunOnStart: true,
+		})
+	}
+
+	// Purge of soft-deleted posts past the restore window
+	if f.postService != nil && f.postRetention > 0 && f.postPurgeBatch > 0 {
+		scheduler.RegisterJob(&ScheduledJob{
+			Name:       "purge-deleted-posts",
+			Interval:   24 * time.Hour,
+			Handler:    f.PurgeDeletedPosts,
+			Timeout:    30 * time.Minute,
+			RetryCount: 1,
+			RetryDelay: 10 * time.Minute,
+			RunOnStart: false,
+		})
+	}
+
 	// Feed cache warming (if cache is enabled)
 	if f.feedCache != nil && f.feedCache.IsEnabled() {
 		scheduler.RegisterJob(&ScheduledJob{
@@ -155,20 +261,51 @@ func (f *JobFactory) CleanupUndeliveredMessages(ctx context.Context) error {
 	return nil
 }
 
+// PurgeExpiredMessages permanently deletes disappearing messages, and their media,
+// once their timer has run out. Starred messages are kept.
+func (f *JobFactory) PurgeExpiredMessages(ctx context.Context) error {
+	if f.messagingService == nil {
+		return nil
+	}
+
+	count, err := f.messagingService.PurgeExpiredMessages(ctx, expiredMessageBatch)
+	if count > 0 {
+		log.Printf("[Jobs] Purged %d expired messages", count)
+	}
+	return err
+}
+
+// SendScheduledMessages sends the scheduled messages that are due. Each goes through
+// the same fan-out as a message sent now, so deliveries that fail are retried by the
+// delivery service.
+func (f *JobFactory) SendScheduledMessages(ctx context.Context) error {
+	if f.messagingService == nil {
+		return nil
+	}
+
+	count, err := f.messagingService.SendDueScheduledMessages(ctx, scheduledMessageBatch)
+	if count > 0 {
+		log.Printf("[Jobs] Sent %d scheduled messages", count)
+	}
+	return err
+}
+
 // ============================================
 // STATUS CLEANUP JOB
 // ============================================
 
-// CleanupExpiredStatuses removes statuses that have expired (24 hours)
+// CleanupExpiredStatuses permanently deletes expired statuses and their media. Their
+// creators keep a summary of views and reactions.
 func (f *JobFactory) CleanupExpiredStatuses(ctx context.Context) error {
-	if f.statusRepo == nil {
+	if f.statusService == nil {
 		return nil
 	}
 
-	// This would call the repository method to delete expired statuses
-	// The repository should call the database function: cleanup_expired_statuses()
-	log.Println("[Jobs] Status cleanup job executed")
-	return nil
+	count, err := f.statusService.PurgeExpiredStatuses(ctx, expiredStatusBatch)
+	if count > 0 {
+		log.Printf("[Jobs] Purged %d expired statuses", count)
+	}
+	return err
 }
 
 // ============================================
@@ -189,6 +326,55 @@ func (f *JobFactory) CleanupOldNotifications(ctx context.Context) error {
 	return nil
 }
 
+// ============================================
+// POLL JOBS
+// ============================================
+
+// CloseExpiredPolls closes polls whose end time has passed and broadcasts final results
+func (f *JobFactory) CloseExpiredPolls(ctx context.Context) error {
+	if f.postService == nil {
+		return nil
+	}
+
+	count, err := f.postService.CloseExpiredPolls(ctx)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.Printf("[Jobs] Closed %d expired polls", count)
+	}
+
+	return nil
+}
+
+// PublishScheduledPosts publishes the scheduled posts whose time has come
+func (f *JobFactory) PublishScheduledPosts(ctx context.Context) error {
+	if f.postService == nil {
+		return nil
+	}
+
+	count, err := f.postService.PublishScheduledPosts(ctx, scheduledPostBatch)
+	if count > 0 {
+		log.Printf("[Jobs] Published %d scheduled posts", count)
+	}
+	return err
+}
+
+// PurgeDeletedPosts permanently deletes posts, with their engagement rows and media,
+// once they have been soft-deleted for longer than the retention period
+func (f *JobFactory) PurgeDeletedPosts(ctx context.Context) error {
+	if f.postService == nil || f.postRetention <= 0 {
+		return nil
+	}
+
+	count, bytes, err := f.postService.PurgeDeletedPosts(ctx, f.postRetention, f.postPurgeBatch)
+	if count > 0 {
+		log.Printf("[Jobs] Purged %d deleted posts (%d bytes of media)", count, bytes)
+	}
+	return err
+}
+
 // ============================================
 // FEED CACHE WARMING JOBS
 // ============================================
@@ -228,38 +414,217 @@ func (f *JobFactory) WarmTrendingHashtagsCache(ctx context.Context) error {
 // CreateDatabaseHealthCheckJob creates a job to check database health
 func CreateDatabaseHealthCheckJob(checkFn func(ctx context.Context) error) *ScheduledJob {
 	return &ScheduledJob{
-		Name:       "database-health-check",
-		Interval:   5 * time.Minute,
-		Handler:    checkFn,
-		Timeout:    30 * time.Second,
-		RetryCount: 3,
-		RetryDelay: 10 * time.Second,
-		RunOnStart: true,
+		Name:        "database-health-check",
+		Interval:    5 * time.Minute,
+		Handler:     checkFn,
+		Timeout:     30 * time.Second,
+		RetryCount:  3,
+		RetryDelay:  10 * time.Second,
+		RunOnStart:  true,
+		PerInstance: true,
 	}
 }
 
 // CreateCacheHealthCheckJob creates a job to check cache health
 func CreateCacheHealthCheckJob(checkFn func(ctx context.Context) error) *ScheduledJob {
 	return &ScheduledJob{
-		Name:       "cache-health-check",
-		Interval:   5 * time.Minute,
-		Handler:    checkFn,
-		Timeout:    10 * time.Second,
-		RetryCount: 2,
-		RetryDelay: 5 * time.Second,
-		RunOnStart: true,
+		Name:        "cache-health-check",
+		Interval:    5 * time.Minute,
+		Handler:     checkFn,
+		Timeout:     10 * time.Second,
+		RetryCount:  2,
+		RetryDelay:  5 * time.Second,
+		RunOnStart:  true,
+		PerInstance: true,
 	}
 }
 
 // CreateMetricsCollectionJob creates a job to collect metrics
 func CreateMetricsCollectionJob(collectFn func(ctx context.Context) error) *ScheduledJob {
 	return &ScheduledJob{
-		Name:       "metrics-collection",
-		Interval:   1 * time.Minute,
-		Handler:    collectFn,
-		Timeout:    30 * time.Second,
+		Name:        "metrics-collection",
+		Interval:    1 * time.Minute,
+		Handler:     collectFn,
+		Timeout:     30 * time.Second,
+		RetryCount:  1,
+		RetryDelay:  5 * time.Second,
+		RunOnStart:  false,
+		PerInstance: true,
+	}
+}
+
+// CreateSupabaseAlertJob creates a job that checks Supabase error rate and latency
+// since its previous run and fails (with a logged alert) when thresholds are exceeded
+func CreateSupabaseAlertJob(checkFn func(ctx context.Context) error) *ScheduledJob {
+	return &ScheduledJob{
+		Name:        "supabase-alerts",
+		Interval:    1 * time.Minute,
+		Handler:     checkFn,
+		Timeout:     5 * time.Second,
+		RetryCount:  0,
+		RunOnStart:  false,
+		PerInstance: true,
+	}
+}
+
+// CreateStorageProbeJob creates a job that probes the primary storage provider
+// while its circuit breaker is open, so traffic returns to it once it recovers
+func CreateStorageProbeJob(probeFn func(ctx context.Context) error) *ScheduledJob {
+	return &ScheduledJob{
+		Name:        "storage-primary-probe",
+		Interval:    30 * time.Second,
+		Handler:     probeFn,
+		Timeout:     10 * time.Second,
+		RetryCount:  0,
+		RunOnStart:  false,
+		PerInstance: true,
+	}
+}
+
+// CreateCourseCounterReconciliationJob creates a job that recomputes course
+// enrollment and completion counters from the enrollments table
+func CreateCourseCounterReconciliationJob(reconcileFn func(ctx context.Context) (int, error)) *ScheduledJob {
+	return &ScheduledJob{
+		Name:     "course-counter-reconciliation",
+		Interval: 24 * time.Hour,
+		Handler: func(ctx context.Context) error {
+			corrected, err := reconcileFn(ctx)
+			if err != nil {
+				return err
+			}
+			log.Printf("[Jobs] Reconciled enrollment counters for %d courses", corrected)
+			return nil
+		},
+		Timeout:    10 * time.Minute,
+		RetryCount: 1,
+		RetryDelay: 5 * time.Minute,
+		RunOnStart: false,
+	}
+}
+
+// CreateCourseRatingReconciliationJob creates a job that repairs course average
+// ratings and review counts that drifted from the reviews table
+func CreateCourseRatingReconciliationJob(reconcileFn func(ctx context.Context) (int, error)) *ScheduledJob {
+	return &ScheduledJob{
+		Name:     "course-rating-reconciliation",
+		Interval: 24 * time.Hour,
+		Handler: func(ctx context.Context) error {
+			corrected, err := reconcileFn(ctx)
+			if err != nil {
+				return err
+			}
+			if corrected > 0 {
+				log.Printf("[Jobs] Reconciled ratings for %d courses", corrected)
+			}
+			return nil
+		},
+		Timeout:    10 * time.Minute,
+		RetryCount: 1,
+		RetryDelay: 5 * time.Minute,
+		RunOnStart: false,
+	}
+}
+
+// CreateTrendingHashtagsJob creates a job that recomputes decayed hashtag trending
+// scores so the trending list follows recent activity
+func CreateTrendingHashtagsJob(recomputeFn func(ctx context.Context) (int, error)) *ScheduledJob {
+	return &ScheduledJob{
+		Name:     "recompute-trending-hashtags",
+		Interval: 15 * time.Minute,
+		Handler: func(ctx context.Context) error {
+			updated, err := recomputeFn(ctx)
+			if err != nil {
+				return err
+			}
+			if updated > 0 {
+				log.Printf("[Jobs] Recomputed trending scores for %d hashtags", updated)
+			}
+			return nil
+		},
+		Timeout:    5 * time.Minute,
+		RetryCount: 1,
+		RetryDelay: 1 * time.Minute,
+		RunOnStart: true,
+	}
+}
+
+// CreatePostViewFlushJob creates a job that writes buffered unique post views to the
+// database in one batch
+func CreatePostViewFlushJob(flushFn func(ctx context.Context) (int, error)) *ScheduledJob {
+	return &ScheduledJob{
+		Name:     "flush-post-views",
+		Interval: 30 * time.Second,
+		Handler: func(ctx context.Context) error {
+			inserted, err := flushFn(ctx)
+			if err != nil {
+				return err
+			}
+			if inserted > 0 {
+				log.Printf("[Jobs] Recorded %d new unique post views", inserted)
+			}
+			return nil
+		},
+		Timeout:     1 * time.Minute,
+		RetryCount:  0,
+		RunOnStart:  false,
+		PerInstance: true,
+	}
+}
+
+// CreateNotificationDigestJob creates a job that sends daily and weekly notification
+// digest emails. It runs hourly; each user gets at most one digest per period.
+func CreateNotificationDigestJob(sendFn func(ctx context.Context) (int, error)) *ScheduledJob {
+	return &ScheduledJob{
+		Name:     "notification-digests",
+		Interval: 1 * time.Hour,
+		Handler: func(ctx context.Context) error {
+			sent, err := sendFn(ctx)
+			if err != nil {
+				return err
+			}
+			if sent > 0 {
+				log.Printf("[Jobs] Sent %d notification digests", sent)
+			}
+			return nil
+		},
+		Timeout:    30 * time.Minute,
+		RetryCount: 1,
+		RetryDelay: 5 * time.Minute,
+		RunOnStart: false,
+	}
+}
+
+// CreateLikeCountReconciliationJob creates a job that recomputes post likes_count from
+// post_likes, correcting any drift in the denormalized counter
+func CreateLikeCountReconciliationJob(reconcileFn func(ctx context.Context) (int, error)) *ScheduledJob {
+	return &ScheduledJob{
+		Name:     "like-count-reconciliation",
+		Interval: 24 * time.Hour,
+		Handler: func(ctx context.Context) error {
+			corrected, err := reconcileFn(ctx)
+			if err != nil {
+				return err
+			}
+			log.Printf("[Jobs] Reconciled like counts for %d posts", corrected)
+			return nil
+		},
+		Timeout:    15 * time.Minute,
+		RetryCount: 1,
+		RetryDelay: 5 * time.Minute,
+		RunOnStart: false,
+	}
+}
+
+// CreateStorageReconciliationJob creates a job that recomputes per-user storage usage
+// from object storage listings to correct drift in the tracked totals
+func CreateStorageReconciliationJob(reconcileFn func(ctx context.Context) error) *ScheduledJob {
+	return &ScheduledJob{
+		Name:       "storage-usage-reconciliation",
+		Interval:   24 * time.Hour,
+		Handler:    reconcileFn,
+		Timeout:    30 * time.Minute,
 		RetryCount: 1,
-		RetryDelay: 5 * time.Second,
+		RetryDelay: 5 * time.Minute,
 		RunOnStart: false,
 	}
 }
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// JobLock keeps a scheduled job to one instance when several run the same schedule
+type JobLock interface {
+	// Acquire takes or renews this instance's hold on a job for ttl. It returns false
+	// if another instance holds it.
+	Acquire(ctx context.Context, jobName string, ttl time.Duration) (bool, error)
+
+	// Release gives up this instance's hold on a job, if it still has it
+	Release(ctx context.Context, jobName string) error
+}
+
+// jobLockPrefix namespaces job lock keys in Redis
+const jobLockPrefix = "jobs:lock:"
+
+// Renews the lock only if this instance holds it
+var renewJobLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Deletes the lock only if this instance holds it, so a lock that expired and was
+// taken by another instance is left alone
+var releaseJobLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisJobLock implements JobLock with a Redis key per job, set with SET NX and a TTL
+// and holding the owning instance's ID
+type RedisJobLock struct {
+	client *redis.Client
+	owner  string
+}
+
+// NewRedisJobLock creates a job lock on a Redis client
+func NewRedisJobLock(client *redis.Client) *RedisJobLock {
+	b := make([]byte, 4)
+	rand.Read(b)
+	host, _ := os.Hostname()
+
+	return &RedisJobLock{
+		client: client,
+		owner:  fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b)),
+	}
+}
+
+// Acquire takes the job's lock, or renews it if this instance already holds it
+func (l *RedisJobLock) Acquire(ctx context.Context, jobName string, ttl time.Duration) (bool, error) {
+	key := jobLockPrefix + jobName
+
+	acquired, err := l.client.SetNX(ctx, key, l.owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	if acquired {
+		return true, nil
+	}
+
+	renewed, err := renewJobLockScript.Run(ctx, l.client, []string{key}, l.owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew job lock: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// Release deletes the job's lock if this instance holds it
+func (l *RedisJobLock) Release(ctx context.Context, jobName string) error {
+	err := releaseJobLockScript.Run(ctx, l.client, []string{jobLockPrefix + jobName}, l.owner).Err()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to release job lock: %w", err)
+	}
+	return nil
+}
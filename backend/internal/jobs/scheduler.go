@@ -6,6 +6,8 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"histeeria-backend/internal/metrics"
 )
 
 // JobScheduler manages background jobs with scheduling, retry, and monitoring
@@ -18,6 +20,7 @@ type JobScheduler struct {
 	running    bool
 	errorLog   []JobError
 	errorLogMu sync.Mutex
+	lock       JobLock // Nil when this is the only instance
 }
 
 // ScheduledJob represents a job to be run on a schedule
@@ -26,24 +29,34 @@ type ScheduledJob struct {
 	Interval   time.Duration
 	Handler    JobHandler
 	Timeout    time.Duration
-	RetryCount int
-	RetryDelay time.Duration
+	RetryCount int           // Retries after a failed attempt, within the same run
+	RetryDelay time.Duration // Wait before the first retry; doubles for each one after
 	LastRun    time.Time
 	LastError  error
 	RunCount   int64
 	ErrorCount int64
+	Retries    int64 // Retries made, across all runs
+	SkipCount  int64 // Ticks skipped because another instance holds the job
 	Enabled    bool
 	RunOnStart bool // Whether to run immediately on start
+	// PerInstance jobs work on this instance's own state, so they run on every
+	// instance and ignore the job lock
+	PerInstance bool
+	// MaxRetryDelay caps the retry delay; no cap if zero. Retries never run past the
+	// next scheduled run either way.
+	MaxRetryDelay time.Duration
 }
 
 // JobHandler is the function signature for job handlers
 type JobHandler func(ctx context.Context) error
 
-// JobError records a job execution error
+// JobError records a failed attempt at running a job
 type JobError struct {
-	JobName   string
-	Timestamp time.Time
-	Error     error
+	JobName   string    `json:"job_name"`
+	Timestamp time.Time `json:"timestamp"`
+	Attempt   int       `json:"attempt"`
+	Error     error     `json:"-"`
+	Message   string    `json:"error"`
 }
 
 // JobResult represents the result of a job execution
@@ -67,6 +80,12 @@ func NewJobScheduler() *JobScheduler {
 	}
 }
 
+// SetLock makes each job run on one instance at a time when several instances share
+// the schedule. Without a lock every instance runs every job. Call before Start.
+func (s *JobScheduler) SetLock(lock JobLock) {
+	s.lock = lock
+}
+
 // RegisterJob registers a new scheduled job
 func (s *JobScheduler) RegisterJob(job *ScheduledJob) error {
 	s.mu.Lock()
@@ -88,6 +107,10 @@ func (s *JobScheduler) RegisterJob(job *ScheduledJob) error {
 		job.Timeout = 5 * time.Minute // Default timeout
 	}
 
+	if job.RetryCount > 0 && job.RetryDelay <= 0 {
+		job.RetryDelay = 5 * time.Second // Default first retry delay
+	}
+
 	if _, exists := s.jobs[job.Name]; exists {
 		return fmt.Errorf("job %s already registered", job.Name)
 	}
@@ -147,6 +170,17 @@ func (s *JobScheduler) Stop() {
 	log.Println("[Jobs] Stopping scheduler...")
 	s.cancel()
 	s.wg.Wait()
+
+	// Hand the jobs over to another instance straight away rather than when the locks expire
+	if s.lock != nil {
+		s.mu.RLock()
+		for _, job := range s.jobs {
+			if !job.PerInstance {
+				s.releaseLock(job)
+			}
+		}
+		s.mu.RUnlock()
+	}
 	log.Println("[Jobs] Scheduler stopped")
 }
 
@@ -177,41 +211,75 @@ func (s *JobScheduler) runJob(job *ScheduledJob) {
 
 // executeJob executes a job with timeout and retry
 func (s *JobScheduler) executeJob(job *ScheduledJob) {
+	locked := s.lock != nil && !job.PerInstance
+	if locked && !s.acquireLock(job) {
+		s.mu.Lock()
+		job.SkipCount++
+		s.mu.Unlock()
+		return
+	}
+
+	// A run that fails, panics included, gives the lock up so that any instance can
+	// try the job on its next tick. A successful run keeps it.
+	failed := true
+	defer func() {
+		if locked && failed {
+			s.releaseLock(job)
+		}
+	}()
+
 	start := time.Now()
 	log.Printf("[Jobs] Running job: %s", job.Name)
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(s.ctx, job.Timeout)
-	defer cancel()
-
 	var err error
 	attempts := 0
 	maxAttempts := job.RetryCount + 1
 
-	for attempts < maxAttempts {
+	for {
 		attempts++
 
 		// Execute the job
-		err = s.safeExecute(ctx, job)
-
+		err = s.runAttempt(job)
 		if err == nil {
 			break
 		}
+		s.recordError(job.Name, attempts, err)
 
-		// Log retry
-		if attempts < maxAttempts {
-			log.Printf("[Jobs] Job %s failed (attempt %d/%d): %v. Retrying in %s",
-				job.Name, attempts, maxAttempts, err, job.RetryDelay)
+		if attempts >= maxAttempts {
+			break
+		}
 
-			select {
-			case <-time.After(job.RetryDelay):
-			case <-ctx.Done():
-				err = ctx.Err()
-				break
-			}
+		// Retries back off, and give way to the next scheduled run
+		delay := retryDelay(job, attempts)
+		if time.Since(start)+delay >= job.Interval {
+			log.Printf("[Jobs] Job %s failed (attempt %d/%d): %v. Not retrying, the next run is due first",
+				job.Name, attempts, maxAttempts, err)
+			break
+		}
+		log.Printf("[Jobs] Job %s failed (attempt %d/%d): %v. Retrying in %s",
+			job.Name, attempts, maxAttempts, err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-s.ctx.Done():
+		}
+		if s.ctx.Err() != nil {
+			break
+		}
+
+		// The lock may have lapsed while waiting; retry only if it's still ours
+		if locked && !s.acquireLock(job) {
+			log.Printf("[Jobs] Not retrying job %s: another instance has taken it over", job.Name)
+			break
 		}
+
+		s.mu.Lock()
+		job.Retries++
+		s.mu.Unlock()
 	}
 
+	failed = err != nil
+
 	// Update job stats
 	s.mu.Lock()
 	job.LastRun = time.Now()
@@ -226,14 +294,63 @@ func (s *JobScheduler) executeJob(job *ScheduledJob) {
 
 	// Log result
 	duration := time.Since(start)
+	metrics.Jobs.Observe(job.Name, err, duration)
 	if err != nil {
 		log.Printf("[Jobs] Job %s completed with error in %s: %v", job.Name, duration, err)
-		s.recordError(job.Name, err)
 	} else {
 		log.Printf("[Jobs] Job %s completed successfully in %s", job.Name, duration)
 	}
 }
 
+// runAttempt runs the job once, within its timeout
+func (s *JobScheduler) runAttempt(job *ScheduledJob) error {
+	ctx, cancel := context.WithTimeout(s.ctx, job.Timeout)
+	defer cancel()
+
+	return s.safeExecute(ctx, job)
+}
+
+// retryDelay is how long to wait after a job's attempt failed: RetryDelay, doubled
+// for every attempt after the first, up to MaxRetryDelay
+func retryDelay(job *ScheduledJob, attempt int) time.Duration {
+	delay := job.RetryDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+	}
+	if job.MaxRetryDelay > 0 && delay > job.MaxRetryDelay {
+		delay = job.MaxRetryDelay
+	}
+	return delay
+}
+
+// acquireLock reports whether this instance should run the job on this tick. The lock
+// lasts the job's interval (or its timeout, if longer) and each run renews it, so the
+// instance holding it keeps the job and another takes over if it goes away. If the
+// lock can't be reached the job runs anyway, as it would on a single instance.
+func (s *JobScheduler) acquireLock(job *ScheduledJob) bool {
+	ttl := job.Interval
+	if job.Timeout > ttl {
+		ttl = job.Timeout
+	}
+
+	held, err := s.lock.Acquire(s.ctx, job.Name, ttl)
+	if err != nil {
+		log.Printf("[Jobs] Running job %s without its lock: %v", job.Name, err)
+		return true
+	}
+	return held
+}
+
+// releaseLock gives up this instance's lock on a job
+func (s *JobScheduler) releaseLock(job *ScheduledJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.lock.Release(ctx, job.Name); err != nil {
+		log.Printf("[Jobs] Failed to release lock for job %s: %v", job.Name, err)
+	}
+}
+
 // safeExecute runs a job handler with panic recovery
 func (s *JobScheduler) safeExecute(ctx context.Context, job *ScheduledJob) (err error) {
 	defer func() {
@@ -245,15 +362,17 @@ func (s *JobScheduler) safeExecute(ctx context.Context, job *ScheduledJob) (err
 	return job.Handler(ctx)
 }
 
-// recordError records a job error for monitoring
-func (s *JobScheduler) recordError(jobName string, err error) {
+// recordError records a failed attempt for monitoring
+func (s *JobScheduler) recordError(jobName string, attempt int, err error) {
 	s.errorLogMu.Lock()
 	defer s.errorLogMu.Unlock()
 
 	s.errorLog = append(s.errorLog, JobError{
 		JobName:   jobName,
 		Timestamp: time.Now(),
+		Attempt:   attempt,
 		Error:     err,
+		Message:   err.Error(),
 	})
 
 	// Keep only last 100 errors
@@ -315,6 +434,9 @@ func (s *JobScheduler) GetJobStats() []JobStats {
 			LastRun:    job.LastRun,
 			RunCount:   job.RunCount,
 			ErrorCount: job.ErrorCount,
+			MaxRetries: job.RetryCount,
+			Retries:    job.Retries,
+			SkipCount:  job.SkipCount,
 			LastError:  lastError,
 		})
 	}
@@ -330,6 +452,9 @@ type JobStats struct {
 	LastRun    time.Time `json:"last_run"`
 	RunCount   int64     `json:"run_count"`
 	ErrorCount int64     `json:"error_count"`
+	MaxRetries int       `json:"max_retries"` // Retries allowed per run
+	Retries    int64     `json:"retry_count"` // Retries made, across all runs
+	SkipCount  int64     `json:"skip_count"`
 	LastError  string    `json:"last_error,omitempty"`
 }
 
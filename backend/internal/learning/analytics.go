@@ -0,0 +1,47 @@
+package learning
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// Analytics periods, in days of enrollment history
+const (
+	DefaultAnalyticsDays = 30
+	MaxAnalyticsDays     = 365
+)
+
+// GetCourseAnalytics returns a course's stats to its creator or an accepted
+// collaborator
+func (s *Service) GetCourseAnalytics(ctx context.Context, courseID, userID uuid.UUID, days int) (*models.CourseAnalytics, error) {
+	course, err := s.courseRepo.GetCourseByID(ctx, courseID)
+	if err != nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Course not found")
+	}
+	canEdit, err := s.canEditCourse(ctx, courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, apperr.NewAppError(http.StatusForbidden, "Only the course's instructors can see its analytics")
+	}
+
+	if days <= 0 {
+		days = DefaultAnalyticsDays
+	} else if days > MaxAnalyticsDays {
+		days = MaxAnalyticsDays
+	}
+
+	analytics, err := s.courseRepo.GetCourseAnalytics(ctx, courseID, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get course analytics: %w", err)
+	}
+	analytics.Currency = course.Currency
+	return analytics, nil
+}
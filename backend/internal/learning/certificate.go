@@ -0,0 +1,158 @@
+package learning
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/storage"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// CertificateURLTTL is how long a certificate download link works
+const CertificateURLTTL = 15 * time.Minute
+
+// certificateRenderTimeout bounds rendering and uploading a certificate in the background
+const certificateRenderTimeout = time.Minute
+
+// GetCertificate returns a download link for the certificate of a course the user has
+// completed. The certificate is generated now if it hasn't been yet.
+func (s *Service) GetCertificate(ctx context.Context, courseID, userID uuid.UUID) (*models.CertificateDownload, error) {
+	enrollment, err := s.courseRepo.GetEnrollment(ctx, courseID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enrollment: %w", err)
+	}
+	if enrollment == nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "You're not enrolled in this course")
+	}
+	if enrollment.CompletedAt == nil {
+		return nil, apperr.NewAppError(http.StatusConflict, "Complete the course to get its certificate")
+	}
+
+	certificate, err := s.IssueCertificate(ctx, enrollment)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := s.storageKey(*certificate.CertificateURL)
+	if !ok {
+		return nil, fmt.Errorf("certificate %s is not in storage", certificate.ID)
+	}
+	signedURL, err := s.storage.GetSignedDownloadURL(ctx, key, &storage.SignedURLOptions{Expiration: CertificateURLTTL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate URL: %w", err)
+	}
+
+	return &models.CertificateDownload{
+		Certificate: certificate,
+		URL:         signedURL,
+		ExpiresAt:   time.Now().Add(CertificateURLTTL),
+	}, nil
+}
+
+// IssueCertificate makes sure a completed enrollment has its certificate, with the PDF
+// rendered and stored. It's idempotent: an enrollment keeps one certificate, number
+// and file, however often it's called.
+func (s *Service) IssueCertificate(ctx context.Context, enrollment *models.CourseEnrollment) (*models.CourseCertificate, error) {
+	if s.storage == nil {
+		return nil, apperr.NewAppError(http.StatusServiceUnavailable, "Certificates are not available")
+	}
+
+	certificate, err := s.courseRepo.GetCertificate(ctx, enrollment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate: %w", err)
+	}
+	if certificate == nil {
+		certificate = &models.CourseCertificate{
+			EnrollmentID: enrollment.ID,
+			CourseID:     enrollment.CourseID,
+			UserID:       enrollment.UserID,
+		}
+		if err := s.courseRepo.CreateCertificate(ctx, certificate); err != nil {
+			// Lost a race with another request issuing it; the certificate is one per enrollment
+			existing, getErr := s.courseRepo.GetCertificate(ctx, enrollment.ID)
+			if getErr != nil || existing == nil {
+				return nil, fmt.Errorf("failed to create certificate: %w", err)
+			}
+			certificate = existing
+		}
+	}
+
+	if certificate.CertificateURL == nil || *certificate.CertificateURL == "" {
+		if err := s.renderCertificate(ctx, certificate, enrollment.CompletedAt); err != nil {
+			return nil, err
+		}
+	}
+	return certificate, nil
+}
+
+// RenderCertificateInBackground renders a newly issued certificate without holding up
+// the request that completed the course. GetCertificate renders it if this fails.
+func (s *Service) RenderCertificateInBackground(ctx context.Context, certificate *models.CourseCertificate) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), certificateRenderTimeout)
+		defer cancel()
+
+		enrollment, err := s.courseRepo.GetEnrollmentByID(ctx, certificate.EnrollmentID)
+		if err != nil {
+			log.Printf("[Learning] Failed to get enrollment %s for its certificate: %v", certificate.EnrollmentID, err)
+			return
+		}
+		if _, err := s.IssueCertificate(ctx, enrollment); err != nil {
+			log.Printf("[Learning] Failed to render certificate for enrollment %s: %v", certificate.EnrollmentID, err)
+		}
+	}()
+}
+
+// renderCertificate draws the certificate PDF, uploads it and records its URL. The
+// file goes to a fixed key per enrollment, so rendering twice overwrites the same
+// file rather than leaving a second one behind.
+func (s *Service) renderCertificate(ctx context.Context, certificate *models.CourseCertificate, completedAt *time.Time) error {
+	course, err := s.courseRepo.GetCourseByID(ctx, certificate.CourseID)
+	if err != nil {
+		return fmt.Errorf("failed to get course: %w", err)
+	}
+	learner, err := s.users.GetUserByID(ctx, certificate.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get learner: %w", err)
+	}
+
+	name := learner.DisplayName
+	if name == "" {
+		name = learner.Username
+	}
+	completed := certificate.IssuedAt
+	if completedAt != nil {
+		completed = *completedAt
+	}
+
+	pdf := renderCertificatePDF(certificateContent{
+		LearnerName: name,
+		CourseTitle: course.Title,
+		CompletedAt: completed,
+		Number:      certificate.CertificateNumber,
+	})
+
+	key := fmt.Sprintf("certificates/%s/%s.pdf", certificate.CourseID, certificate.EnrollmentID)
+	if _, err := s.storage.Upload(ctx, key, bytes.NewReader(pdf), &storage.UploadOptions{
+		ContentType: "application/pdf",
+		ACL:         "private",
+	}); err != nil {
+		return fmt.Errorf("failed to upload certificate: %w", err)
+	}
+
+	certificateURL := s.storage.GetPublicURL(key)
+	if err := s.courseRepo.SetCertificateURL(ctx, certificate.ID, certificateURL); err != nil {
+		return fmt.Errorf("failed to save certificate URL: %w", err)
+	}
+	certificate.CertificateURL = &certificateURL
+
+	log.Printf("[Learning] Rendered certificate %s for enrollment %s", certificate.CertificateNumber, certificate.EnrollmentID)
+	return nil
+}
@@ -0,0 +1,191 @@
+package learning
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// certificateContent is what's printed on a course certificate
+type certificateContent struct {
+	LearnerName string
+	CourseTitle string
+	CompletedAt time.Time
+	Number      string
+}
+
+// A4 landscape, in points
+const (
+	certificatePageWidth  = 842.0
+	certificatePageHeight = 595.0
+	certificateTextWidth  = 700.0 // Widest a line may run before its font is shrunk
+)
+
+// The PDF standard fonts every reader has, so nothing needs embedding. They cover
+// Windows-1252 only; other characters are approximated, see winAnsi.
+const (
+	fontRegular = "F1" // Helvetica
+	fontBold    = "F2" // Helvetica-Bold
+)
+
+// renderCertificatePDF lays a certificate out on one page and returns the PDF
+func renderCertificatePDF(c certificateContent) []byte {
+	var page bytes.Buffer
+
+	// Double border
+	page.WriteString("q 0.16 0.22 0.45 RG 3 w 24 24 794 547 re S 0.8 w 34 34 774 527 re S Q\n")
+
+	line := func(font string, size, maxSize, y float64, text string) {
+		if maxSize > 0 {
+			size = fitFontSize(font, text, size, maxSize)
+		}
+		encoded := winAnsi(text)
+		x := (certificatePageWidth - textWidth(font, encoded, size)) / 2
+		fmt.Fprintf(&page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(encoded))
+	}
+
+	page.WriteString("0.16 0.22 0.45 rg\n")
+	line(fontBold, 30, 0, 440, "CERTIFICATE OF COMPLETION")
+	page.WriteString("0.2 0.2 0.2 rg\n")
+	line(fontRegular, 14, 0, 385, "This certifies that")
+	line(fontBold, 28, 14, 340, c.LearnerName)
+	line(fontRegular, 14, 0, 300, "has successfully completed the course")
+	line(fontBold, 20, 11, 262, c.CourseTitle)
+	line(fontRegular, 12, 0, 190, "Completed on "+c.CompletedAt.UTC().Format("January 2, 2006"))
+	page.WriteString("0.45 0.45 0.45 rg\n")
+	line(fontRegular, 10, 0, 80, "Certificate No. "+c.Number)
+	line(fontRegular, 10, 0, 64, "Issued by Histeeria")
+
+	return buildPDF(page.Bytes(), "Certificate of Completion - "+c.CourseTitle)
+}
+
+// fitFontSize shrinks size until text fits certificateTextWidth, down to minSize
+func fitFontSize(font, text string, size, minSize float64) float64 {
+	encoded := winAnsi(text)
+	for size > minSize && textWidth(font, encoded, size) > certificateTextWidth {
+		size--
+	}
+	return size
+}
+
+// buildPDF wraps one page's content stream in a minimal PDF document
+func buildPDF(content []byte, title string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Contents 4 0 R "+
+			"/Resources << /Font << /%s 5 0 R /%s 6 0 R >> >> >>",
+			certificatePageWidth, certificatePageHeight, fontRegular, fontBold),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (Histeeria) /CreationDate (D:%s) >>",
+			pdfEscape(winAnsi(title)), time.Now().UTC().Format("20060102150405Z")),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, len(objects), xref)
+
+	return out.Bytes()
+}
+
+// winAnsi encodes text in Windows-1252, the encoding of the standard fonts.
+// Characters it lacks lose their accents if that's enough, and become '?' if not.
+func winAnsi(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if b, ok := charmap.Windows1252.EncodeRune(r); ok {
+			out = append(out, b)
+			continue
+		}
+
+		var base []byte
+		for _, d := range norm.NFD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				continue
+			}
+			if b, ok := charmap.Windows1252.EncodeRune(d); ok {
+				base = append(base, b)
+			}
+		}
+		if len(base) == 0 {
+			base = []byte{'?'}
+		}
+		out = append(out, base...)
+	}
+	return out
+}
+
+// pdfEscape makes encoded text safe inside a PDF literal string
+func pdfEscape(encoded []byte) string {
+	var b strings.Builder
+	for _, c := range encoded {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7F:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// textWidth measures encoded text set in font at size, in points
+func textWidth(font string, encoded []byte, size float64) float64 {
+	widths := &helveticaWidths
+	if font == fontBold {
+		widths = &helveticaBoldWidths
+	}
+
+	units := 0
+	for _, c := range encoded {
+		if c >= 32 && c <= 126 {
+			units += widths[c-32]
+		} else {
+			units += 556 // Close enough for accented letters
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// Glyph widths of the printable ASCII characters, from the Adobe font metrics, in
+// thousandths of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,157 @@
+package learning
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// couponCodePattern is what a coupon code may contain once upper-cased
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]+$`)
+
+// CreateCoupon creates a discount code for a paid course. Only the course's creator
+// and collaborators can. Coupons valid for every course are created by operators in
+// the database, not through the API.
+func (s *Service) CreateCoupon(ctx context.Context, courseID, userID uuid.UUID, req *models.CreateCouponRequest) (*models.CourseCoupon, error) {
+	course, err := s.courseRepo.GetCourseByID(ctx, courseID)
+	if err != nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Course not found")
+	}
+	canEdit, err := s.canEditCourse(ctx, courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, apperr.NewAppError(http.StatusForbidden, "Only the course's instructors can create coupons")
+	}
+	if course.IsFree {
+		return nil, apperr.NewAppError(http.StatusBadRequest, "Free courses can't have coupons")
+	}
+
+	code := models.NormalizeCouponCode(req.Code)
+	if !couponCodePattern.MatchString(code) {
+		return nil, apperr.NewAppError(http.StatusBadRequest, "Coupon codes may only contain letters, digits, - and _")
+	}
+	if req.DiscountType == models.CouponDiscountPercent && req.DiscountValue > 100 {
+		return nil, apperr.NewAppError(http.StatusBadRequest, "A percent discount can't be more than 100")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, apperr.NewAppError(http.StatusBadRequest, "Expiry must be in the future")
+	}
+
+	coupon := &models.CourseCoupon{
+		Code:          code,
+		CourseID:      &courseID,
+		CreatedBy:     &userID,
+		DiscountType:  req.DiscountType,
+		DiscountValue: req.DiscountValue,
+		MaxUses:       req.MaxUses,
+		ExpiresAt:     req.ExpiresAt,
+	}
+	if req.DiscountType == models.CouponDiscountFixed {
+		// Fixed amounts are in the course's currency
+		currency := course.Currency
+		coupon.Currency = &currency
+	}
+
+	if err := s.courseRepo.CreateCoupon(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+	return coupon, nil
+}
+
+// QuoteCoupon checks a coupon code against a course and returns the discounted price,
+// without using the coupon
+func (s *Service) QuoteCoupon(ctx context.Context, courseID uuid.UUID, code string) (*models.CouponQuote, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, apperr.ErrCouponNotFound
+	}
+	quote, err := s.courseRepo.QuoteCoupon(ctx, courseID, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check coupon: %w", err)
+	}
+
+	// Learners only need to know the discount, not how much the coupon is used
+	quote.Coupon = &models.CourseCoupon{
+		ID:            quote.Coupon.ID,
+		Code:          quote.Coupon.Code,
+		CourseID:      quote.Coupon.CourseID,
+		DiscountType:  quote.Coupon.DiscountType,
+		DiscountValue: quote.Coupon.DiscountValue,
+		Currency:      quote.Coupon.Currency,
+		ExpiresAt:     quote.Coupon.ExpiresAt,
+		IsActive:      quote.Coupon.IsActive,
+	}
+	return quote, nil
+}
+
+// Enroll enrolls the user in a published course. Paid courses start out pending
+// payment of their price, less the coupon's discount if one is given; a coupon that
+// covers the whole price enrolls them for free.
+func (s *Service) Enroll(ctx context.Context, courseID, userID uuid.UUID, req *models.EnrollRequest) (*models.CourseEnrollment, error) {
+	course, err := s.courseRepo.GetCourseByID(ctx, courseID)
+	if err != nil || course.Status != "published" {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Course not found")
+	}
+
+	existing, err := s.courseRepo.GetEnrollment(ctx, courseID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check enrollment: %w", err)
+	}
+	if existing != nil {
+		return nil, apperr.NewAppError(http.StatusConflict, "You're already enrolled in this course")
+	}
+
+	enrollment := &models.CourseEnrollment{
+		CourseID:      courseID,
+		UserID:        userID,
+		PaymentStatus: "free",
+		CouponCode:    strings.TrimSpace(req.CouponCode),
+	}
+	if !course.IsFree && course.Price != nil && *course.Price > 0 {
+		currency := course.Currency
+		enrollment.PaymentStatus = "pending"
+		enrollment.PaymentAmount = course.Price
+		enrollment.PaymentCurrency = &currency
+	}
+
+	if err := s.courseRepo.CreateEnrollment(ctx, enrollment); err != nil {
+		return nil, fmt.Errorf("failed to enroll: %w", err)
+	}
+
+	// It's no longer something they're saving for later
+	if _, err := s.courseRepo.RemoveFromWishlist(ctx, courseID, userID); err != nil {
+		log.Printf("[Learning] Failed to remove course %s from wishlist after enrolling: %v", courseID, err)
+	}
+	return enrollment, nil
+}
+
+// Unenroll withdraws the user from a course they haven't completed; a completed
+// course has their certificate hanging off it. Reports whether they were enrolled.
+func (s *Service) Unenroll(ctx context.Context, courseID, userID uuid.UUID) (bool, error) {
+	existing, err := s.courseRepo.GetEnrollment(ctx, courseID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check enrollment: %w", err)
+	}
+	if existing == nil {
+		return false, nil
+	}
+	if existing.CompletedAt != nil {
+		return false, apperr.NewAppError(http.StatusConflict, "You can't leave a course you've completed")
+	}
+
+	removed, err := s.courseRepo.DeleteEnrollment(ctx, courseID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unenroll: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,56 @@
+package learning
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// fakeCourseRepo implements the parts of repository.CourseRepository the tests reach;
+// anything else panics through the nil embedded interface
+type fakeCourseRepo struct {
+	repository.CourseRepository
+
+	course      *models.Course
+	lesson      *models.CourseLesson
+	enrollments map[uuid.UUID]*models.CourseEnrollment // Per user
+}
+
+func (r *fakeCourseRepo) GetCourseByID(ctx context.Context, id uuid.UUID) (*models.Course, error) {
+	return r.course, nil
+}
+
+func (r *fakeCourseRepo) GetLessonByID(ctx context.Context, id uuid.UUID) (*models.CourseLesson, error) {
+	return r.lesson, nil
+}
+
+func (r *fakeCourseRepo) GetEnrollment(ctx context.Context, courseID, userID uuid.UUID) (*models.CourseEnrollment, error) {
+	return r.enrollments[userID], nil
+}
+
+func (r *fakeCourseRepo) CheckCollaborator(ctx context.Context, courseID, userID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+// fakeStorageProvider signs keys into URLs that record the requested lifetime
+type fakeStorageProvider struct {
+	storage.StorageProvider
+}
+
+func (p *fakeStorageProvider) GetProviderName() string {
+	return "fake"
+}
+
+func (p *fakeStorageProvider) GetPublicURL(key string) string {
+	return "https://cdn.example.com/" + key
+}
+
+func (p *fakeStorageProvider) GetSignedDownloadURL(ctx context.Context, key string, opts *storage.SignedURLOptions) (string, error) {
+	return fmt.Sprintf("https://cdn.example.com/%s?expires=%d", key, opts.Expiration/time.Second), nil
+}
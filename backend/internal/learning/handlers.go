@@ -0,0 +1,399 @@
+package learning
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handlers handles HTTP requests for the learning platform
+type Handlers struct {
+	service *Service
+}
+
+// NewHandlers creates new learning handlers
+func NewHandlers(service *Service) *Handlers {
+	return &Handlers{service: service}
+}
+
+// GetLessonVideoURL handles GET /api/v1/lessons/:id/video-url
+// Returns a short-lived signed video URL; call again to re-sign when it expires.
+// Optional query: expires_in (seconds, capped at MaxVideoURLTTL)
+func (h *Handlers) GetLessonVideoURL(c *gin.Context) {
+	lessonID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lesson ID"})
+		return
+	}
+
+	// Viewer is optional: preview lessons can be watched without signing in
+	viewerID, _ := utils.CurrentUserID(c)
+
+	var ttl time.Duration
+	if expStr := c.Query("expires_in"); expStr != "" {
+		if exp, err := strconv.Atoi(expStr); err == nil && exp > 0 {
+			ttl = time.Duration(exp) * time.Second
+		}
+	}
+
+	videoURL, err := h.service.GetLessonVideoURL(c.Request.Context(), lessonID, viewerID, ttl)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"video":   videoURL,
+	})
+}
+
+// GetCourseCertificate handles GET /api/v1/courses/:id/certificate
+// Returns a short-lived signed link to the user's certificate for a completed course,
+// generating it on first request if it isn't ready yet.
+func (h *Handlers) GetCourseCertificate(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	download, err := h.service.GetCertificate(c.Request.Context(), courseID, userID)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"certificate": download,
+	})
+}
+
+// CreateLessonQuiz handles POST /api/v1/lessons/:id/quiz
+// Adds a quiz to a quiz lesson; instructors only.
+func (h *Handlers) CreateLessonQuiz(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	lessonID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lesson ID"})
+		return
+	}
+
+	var req models.CreateQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	quiz, err := h.service.CreateQuiz(c.Request.Context(), lessonID, userID, &req)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"quiz":    quiz,
+	})
+}
+
+// GetLessonQuiz handles GET /api/v1/lessons/:id/quiz
+// Learners get the questions without their answers.
+func (h *Handlers) GetLessonQuiz(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	lessonID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lesson ID"})
+		return
+	}
+
+	quiz, err := h.service.GetQuiz(c.Request.Context(), lessonID, userID)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"quiz":    quiz,
+	})
+}
+
+// SubmitLessonQuiz handles POST /api/v1/lessons/:id/quiz/attempts
+// Grades the answers and returns the scored attempt; passing completes the lesson.
+func (h *Handlers) SubmitLessonQuiz(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	lessonID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lesson ID"})
+		return
+	}
+
+	var req models.SubmitQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	attempt, err := h.service.SubmitQuiz(c.Request.Context(), lessonID, userID, &req)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"attempt": attempt,
+	})
+}
+
+// GetLessonQuizAttempts handles GET /api/v1/lessons/:id/quiz/attempts
+// Returns the user's attempts and scores, newest first.
+func (h *Handlers) GetLessonQuizAttempts(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	lessonID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lesson ID"})
+		return
+	}
+
+	attempts, err := h.service.GetQuizAttempts(c.Request.Context(), lessonID, userID)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	passed := false
+	for _, attempt := range attempts {
+		passed = passed || attempt.Passed
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"attempts": attempts,
+		"passed":   passed,
+	})
+}
+
+// Enroll handles POST /api/v1/courses/:id/enroll
+// Optional body: coupon_code, redeemed against the course's price.
+func (h *Handlers) Enroll(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	var req models.EnrollRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+	}
+
+	enrollment, err := h.service.Enroll(c.Request.Context(), courseID, userID, &req)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":    true,
+		"enrollment": enrollment,
+	})
+}
+
+// Unenroll handles DELETE /api/v1/courses/:id/enroll
+func (h *Handlers) Unenroll(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	changed, err := h.service.Unenroll(c.Request.Context(), courseID, userID)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Unenrolled from course",
+		"changed": changed,
+	})
+}
+
+// CreateCourseCoupon handles POST /api/v1/courses/:id/coupons
+// Instructors only.
+func (h *Handlers) CreateCourseCoupon(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	var req models.CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	coupon, err := h.service.CreateCoupon(c.Request.Context(), courseID, userID, &req)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"coupon":  coupon,
+	})
+}
+
+// ValidateCourseCoupon handles GET /api/v1/courses/:id/coupons/:code
+// Returns the course's price with the coupon applied, without redeeming it.
+func (h *Handlers) ValidateCourseCoupon(c *gin.Context) {
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	quote, err := h.service.QuoteCoupon(c.Request.Context(), courseID, c.Param("code"))
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"quote":   quote,
+	})
+}
+
+// AddToWishlist handles POST /api/v1/courses/:id/wishlist
+func (h *Handlers) AddToWishlist(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	if err := h.service.AddToWishlist(c.Request.Context(), courseID, userID); err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Course added to wishlist",
+	})
+}
+
+// RemoveFromWishlist handles DELETE /api/v1/courses/:id/wishlist
+func (h *Handlers) RemoveFromWishlist(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	changed, err := h.service.RemoveFromWishlist(c.Request.Context(), courseID, userID)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Course removed from wishlist",
+		"changed": changed,
+	})
+}
+
+// GetWishlist handles GET /api/v1/courses/wishlist
+// Query: limit (max 50), offset
+func (h *Handlers) GetWishlist(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	courses, total, err := h.service.GetWishlist(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"courses":  courses,
+		"total":    total,
+		"has_more": offset+len(courses) < total,
+	})
+}
+
+// GetCourseAnalytics handles GET /api/v1/courses/:id/analytics
+// Instructors only. Optional query: days of enrollment history (default 30, max 365)
+func (h *Handlers) GetCourseAnalytics(c *gin.Context) {
+	userID := utils.MustUserID(c)
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.Query("days"))
+
+	analytics, err := h.service.GetCourseAnalytics(c.Request.Context(), courseID, userID, days)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"analytics": analytics,
+	})
+}
@@ -0,0 +1,315 @@
+package learning
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// DefaultQuizPassThreshold is the percentage of points needed to pass a quiz when its
+// creator doesn't set one
+const DefaultQuizPassThreshold = 70.0
+
+// CreateQuiz adds a quiz to a quiz lesson. Only the course's creator and collaborators
+// can, and a lesson has at most one quiz.
+func (s *Service) CreateQuiz(ctx context.Context, lessonID, userID uuid.UUID, req *models.CreateQuizRequest) (*models.Quiz, error) {
+	lesson, err := s.courseRepo.GetLessonByID(ctx, lessonID)
+	if err != nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Lesson not found")
+	}
+	canEdit, err := s.canEditCourse(ctx, lesson.CourseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, apperr.NewAppError(http.StatusForbidden, "Only the course's instructors can add a quiz")
+	}
+	if lesson.LessonType != "quiz" {
+		return nil, apperr.ErrNotQuizLesson
+	}
+
+	existing, err := s.courseRepo.GetQuizByLesson(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiz: %w", err)
+	}
+	if existing != nil {
+		return nil, apperr.ErrQuizExists
+	}
+
+	quiz, err := buildQuiz(lesson, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.courseRepo.CreateQuiz(ctx, quiz); err != nil {
+		return nil, fmt.Errorf("failed to create quiz: %w", err)
+	}
+	return quiz, nil
+}
+
+// GetQuiz returns a lesson's quiz. Correct answers are left out unless the user is one
+// of the course's instructors.
+func (s *Service) GetQuiz(ctx context.Context, lessonID, userID uuid.UUID) (*models.Quiz, error) {
+	lesson, err := s.courseRepo.GetLessonByID(ctx, lessonID)
+	if err != nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Lesson not found")
+	}
+	quiz, err := s.courseRepo.GetQuizByLesson(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiz: %w", err)
+	}
+	if quiz == nil {
+		return nil, apperr.ErrQuizNotFound
+	}
+
+	canEdit, err := s.canEditCourse(ctx, lesson.CourseID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if canEdit {
+		return quiz, nil
+	}
+
+	if !lesson.IsPreview {
+		allowed, err := s.canAccessCourse(ctx, lesson.CourseID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, apperr.NewAppError(http.StatusForbidden, "Enroll in this course to take this quiz")
+		}
+	}
+	for _, q := range quiz.Questions {
+		q.CorrectOptions = nil
+	}
+	return quiz, nil
+}
+
+// SubmitQuiz grades a learner's answers and records the attempt. Passing completes the
+// lesson, which may complete the course.
+func (s *Service) SubmitQuiz(ctx context.Context, lessonID, userID uuid.UUID, req *models.SubmitQuizRequest) (*models.QuizAttempt, error) {
+	lesson, err := s.courseRepo.GetLessonByID(ctx, lessonID)
+	if err != nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Lesson not found")
+	}
+	enrollment, err := s.activeEnrollment(ctx, lesson.CourseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quiz, err := s.courseRepo.GetQuizByLesson(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiz: %w", err)
+	}
+	if quiz == nil {
+		return nil, apperr.ErrQuizNotFound
+	}
+
+	attempt := gradeQuiz(quiz, req.Answers)
+	attempt.LessonID = lessonID
+	attempt.EnrollmentID = enrollment.ID
+	attempt.UserID = userID
+	if err := s.courseRepo.CreateQuizAttempt(ctx, attempt); err != nil {
+		return nil, fmt.Errorf("failed to save quiz attempt: %w", err)
+	}
+
+	if attempt.Passed {
+		if err := s.completeLesson(ctx, enrollment, lesson); err != nil {
+			return nil, err
+		}
+	}
+	return attempt, nil
+}
+
+// GetQuizAttempts returns the user's attempts at a lesson's quiz, newest first
+func (s *Service) GetQuizAttempts(ctx context.Context, lessonID, userID uuid.UUID) ([]*models.QuizAttempt, error) {
+	lesson, err := s.courseRepo.GetLessonByID(ctx, lessonID)
+	if err != nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Lesson not found")
+	}
+	enrollment, err := s.activeEnrollment(ctx, lesson.CourseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := s.courseRepo.GetQuizAttempts(ctx, enrollment.ID, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiz attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// activeEnrollment returns the user's enrollment in a course if it grants access
+func (s *Service) activeEnrollment(ctx context.Context, courseID, userID uuid.UUID) (*models.CourseEnrollment, error) {
+	enrollment, err := s.courseRepo.GetEnrollment(ctx, courseID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enrollment: %w", err)
+	}
+	if enrollment == nil || (enrollment.PaymentStatus != "free" && enrollment.PaymentStatus != "paid") {
+		return nil, apperr.NewAppError(http.StatusForbidden, "Enroll in this course to take this quiz")
+	}
+	return enrollment, nil
+}
+
+// completeLesson marks a lesson completed for an enrollment, keeping the time spent
+// and position already recorded
+func (s *Service) completeLesson(ctx context.Context, enrollment *models.CourseEnrollment, lesson *models.CourseLesson) error {
+	progress, err := s.courseRepo.GetProgress(ctx, enrollment.ID, lesson.ID)
+	if err != nil || progress == nil {
+		progress = &models.LessonProgress{
+			EnrollmentID: enrollment.ID,
+			LessonID:     lesson.ID,
+			UserID:       enrollment.UserID,
+			CourseID:     lesson.CourseID,
+		}
+	}
+	if progress.IsCompleted {
+		return nil
+	}
+
+	now := time.Now()
+	progress.IsCompleted = true
+	progress.CompletionPercentage = 100
+	progress.CompletedAt = &now
+	if progress.StartedAt == nil {
+		progress.StartedAt = &now
+	}
+	if err := s.courseRepo.CreateOrUpdateProgress(ctx, progress); err != nil {
+		return fmt.Errorf("failed to complete lesson: %w", err)
+	}
+	return nil
+}
+
+// buildQuiz validates a quiz request and turns it into a quiz for the lesson.
+// Options get IDs from their position ("1", "2", ...), which is all a question
+// needs since its options are fixed once created.
+func buildQuiz(lesson *models.CourseLesson, req *models.CreateQuizRequest) (*models.Quiz, error) {
+	threshold := DefaultQuizPassThreshold
+	if req.PassThreshold != nil {
+		threshold = *req.PassThreshold
+	}
+	if threshold <= 0 || threshold > 100 {
+		return nil, apperr.NewAppError(http.StatusBadRequest, "Pass threshold must be above 0 and at most 100")
+	}
+
+	quiz := &models.Quiz{
+		LessonID:      lesson.ID,
+		CourseID:      lesson.CourseID,
+		Title:         strings.TrimSpace(req.Title),
+		Description:   req.Description,
+		PassThreshold: threshold,
+		Questions:     make([]*models.QuizQuestion, len(req.Questions)),
+	}
+
+	for i, q := range req.Questions {
+		invalid := func(reason string) error {
+			return apperr.NewAppError(http.StatusBadRequest, fmt.Sprintf("Question %d: %s", i+1, reason))
+		}
+
+		questionType := q.QuestionType
+		if questionType == "" {
+			questionType = models.QuizQuestionSingleChoice
+		}
+		if questionType != models.QuizQuestionSingleChoice && questionType != models.QuizQuestionMultipleChoice {
+			return nil, invalid(fmt.Sprintf("unknown question type %q", questionType))
+		}
+		if strings.TrimSpace(q.QuestionText) == "" {
+			return nil, invalid("question text is required")
+		}
+		if questionType == models.QuizQuestionSingleChoice && len(q.CorrectOptions) != 1 {
+			return nil, invalid("single choice questions have exactly one correct option")
+		}
+		points := q.Points
+		if points == 0 {
+			points = 1
+		}
+		if points < 0 {
+			return nil, invalid("points must be positive")
+		}
+
+		options := make([]models.QuizOption, len(q.Options))
+		for j, text := range q.Options {
+			if strings.TrimSpace(text) == "" {
+				return nil, invalid("options can't be empty")
+			}
+			options[j] = models.QuizOption{ID: strconv.Itoa(j + 1), Text: strings.TrimSpace(text)}
+		}
+
+		seen := make(map[int]bool, len(q.CorrectOptions))
+		correct := make([]string, 0, len(q.CorrectOptions))
+		for _, index := range q.CorrectOptions {
+			if index < 0 || index >= len(options) {
+				return nil, invalid("correct options must be indexes of its options")
+			}
+			if !seen[index] {
+				seen[index] = true
+				correct = append(correct, options[index].ID)
+			}
+		}
+
+		quiz.Questions[i] = &models.QuizQuestion{
+			QuestionText:   strings.TrimSpace(q.QuestionText),
+			QuestionType:   questionType,
+			Options:        options,
+			CorrectOptions: correct,
+			Points:         points,
+			Explanation:    q.Explanation,
+			OrderIndex:     i,
+		}
+	}
+	return quiz, nil
+}
+
+// gradeQuiz scores answers against a quiz. A question earns its points only when the
+// chosen options are exactly its correct ones; there's no partial credit on multiple
+// choice. Answers to questions not in the quiz are dropped.
+func gradeQuiz(quiz *models.Quiz, answers map[uuid.UUID][]string) *models.QuizAttempt {
+	attempt := &models.QuizAttempt{
+		QuizID:  quiz.ID,
+		Answers: make(map[uuid.UUID][]string, len(quiz.Questions)),
+		Results: make([]*models.QuizQuestionResult, len(quiz.Questions)),
+	}
+
+	for i, q := range quiz.Questions {
+		chosen := make(map[string]bool)
+		for _, id := range answers[q.ID] {
+			chosen[id] = true
+		}
+		if len(answers[q.ID]) > 0 {
+			attempt.Answers[q.ID] = answers[q.ID]
+		}
+
+		correct := len(chosen) == len(q.CorrectOptions)
+		for _, id := range q.CorrectOptions {
+			if !chosen[id] {
+				correct = false
+			}
+		}
+
+		result := &models.QuizQuestionResult{
+			QuestionID:  q.ID,
+			Correct:     correct,
+			Explanation: q.Explanation,
+		}
+		if correct {
+			result.PointsEarned = q.Points
+		}
+		attempt.Results[i] = result
+		attempt.PointsEarned += result.PointsEarned
+		attempt.PointsPossible += q.Points
+	}
+
+	if attempt.PointsPossible > 0 {
+		attempt.Score = math.Round(float64(attempt.PointsEarned)*10000/float64(attempt.PointsPossible)) / 100
+	}
+	attempt.Passed = attempt.Score >= quiz.PassThreshold
+	return attempt
+}
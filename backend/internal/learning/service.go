@@ -0,0 +1,150 @@
+package learning
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/storage"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// Lesson video URL lifetimes. URLs are short-lived so they can't be shared for long;
+// players re-sign through the API when a URL expires mid-lesson.
+const (
+	DefaultVideoURLTTL = 30 * time.Minute
+	MaxVideoURLTTL     = 2 * time.Hour
+)
+
+// Service handles learning platform business logic
+type Service struct {
+	courseRepo repository.CourseRepository
+	users      repository.UserRepository // Learner names for certificates
+	storage    *storage.StorageService
+}
+
+// NewService creates a new learning service
+func NewService(courseRepo repository.CourseRepository, userRepo repository.UserRepository, storageService *storage.StorageService) *Service {
+	return &Service{
+		courseRepo: courseRepo,
+		users:      userRepo,
+		storage:    storageService,
+	}
+}
+
+// GetLessonVideoURL returns a playback URL for a lesson's video. Preview lessons are open
+// to everyone; other lessons require the viewer to be enrolled, the course creator or an
+// accepted collaborator. The signed URL is served by the storage provider directly, which
+// honours Range requests so players can seek.
+func (s *Service) GetLessonVideoURL(ctx context.Context, lessonID, viewerID uuid.UUID, ttl time.Duration) (*models.LessonVideoURL, error) {
+	lesson, err := s.courseRepo.GetLessonByID(ctx, lessonID)
+	if err != nil {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Lesson not found")
+	}
+	if lesson.VideoURL == nil || *lesson.VideoURL == "" {
+		return nil, apperr.NewAppError(http.StatusNotFound, "Lesson has no video")
+	}
+
+	if !lesson.IsPreview {
+		if viewerID == uuid.Nil {
+			return nil, apperr.ErrUnauthorized
+		}
+		allowed, err := s.canAccessCourse(ctx, lesson.CourseID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, apperr.NewAppError(http.StatusForbidden, "Enroll in this course to watch this lesson")
+		}
+	}
+
+	key, stored := s.storageKey(*lesson.VideoURL)
+	if !stored {
+		return &models.LessonVideoURL{LessonID: lesson.ID, URL: *lesson.VideoURL}, nil
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultVideoURLTTL
+	} else if ttl > MaxVideoURLTTL {
+		ttl = MaxVideoURLTTL
+	}
+
+	signedURL, err := s.storage.GetSignedDownloadURL(ctx, key, &storage.SignedURLOptions{Expiration: ttl})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign lesson video URL: %w", err)
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	return &models.LessonVideoURL{
+		LessonID:  lesson.ID,
+		URL:       signedURL,
+		Signed:    true,
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// canAccessCourse reports whether the user may view a course's paid lessons. Pending and
+// refunded enrollments don't grant access.
+func (s *Service) canAccessCourse(ctx context.Context, courseID, userID uuid.UUID) (bool, error) {
+	enrollment, err := s.courseRepo.GetEnrollment(ctx, courseID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check enrollment: %w", err)
+	}
+	if enrollment != nil && (enrollment.PaymentStatus == "free" || enrollment.PaymentStatus == "paid") {
+		return true, nil
+	}
+
+	return s.canEditCourse(ctx, courseID, userID)
+}
+
+// canEditCourse reports whether the user is the course's creator or an accepted
+// collaborator
+func (s *Service) canEditCourse(ctx context.Context, courseID, userID uuid.UUID) (bool, error) {
+	course, err := s.courseRepo.GetCourseByID(ctx, courseID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get course: %w", err)
+	}
+	if course.CreatorID == userID {
+		return true, nil
+	}
+
+	return s.courseRepo.CheckCollaborator(ctx, courseID, userID)
+}
+
+// storageKey resolves a stored lesson video or certificate URL to an object key. Lessons
+// may store either the key itself or the provider's public URL; any other absolute URL
+// is an externally hosted video and can't be signed.
+func (s *Service) storageKey(videoURL string) (string, bool) {
+	if s.storage == nil {
+		return "", false
+	}
+	if !strings.HasPrefix(videoURL, "http://") && !strings.HasPrefix(videoURL, "https://") {
+		return strings.TrimPrefix(videoURL, "/"), true
+	}
+
+	providers := []storage.StorageProvider{s.storage.Primary(), s.storage.Fallback()}
+	for _, provider := range providers {
+		if provider == nil {
+			continue
+		}
+		// Providers without a public base URL return a signed URL here; only the path matters
+		base := provider.GetPublicURL("")
+		if idx := strings.Index(base, "?"); idx >= 0 {
+			base = base[:idx]
+		}
+		if base != "" && base != "/" && strings.HasPrefix(videoURL, base) {
+			key := strings.TrimPrefix(videoURL, base)
+			if idx := strings.Index(key, "?"); idx >= 0 {
+				key = key[:idx]
+			}
+			return strings.TrimPrefix(key, "/"), true
+		}
+	}
+	return "", false
+}
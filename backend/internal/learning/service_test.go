@@ -0,0 +1,81 @@
+package learning
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/storage"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+func TestGetLessonVideoURLAccess(t *testing.T) {
+	creator, enrolled, pending, stranger := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name       string
+		preview    bool
+		viewer     uuid.UUID
+		wantStatus int // 0 when a signed URL is expected
+	}{
+		{"enrolled learner", false, enrolled, 0},
+		{"course creator", false, creator, 0},
+		{"pending payment", false, pending, http.StatusForbidden},
+		{"not enrolled", false, stranger, http.StatusForbidden},
+		{"anonymous", false, uuid.Nil, http.StatusUnauthorized},
+		{"preview for a stranger", true, stranger, 0},
+		{"preview for anonymous", true, uuid.Nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			video := "courses/intro.mp4"
+			lesson := &models.CourseLesson{ID: uuid.New(), CourseID: uuid.New(), VideoURL: &video, IsPreview: tt.preview}
+			repo := &fakeCourseRepo{
+				course: &models.Course{ID: lesson.CourseID, CreatorID: creator},
+				lesson: lesson,
+				enrollments: map[uuid.UUID]*models.CourseEnrollment{
+					enrolled: {PaymentStatus: "paid"},
+					pending:  {PaymentStatus: "pending"},
+				},
+			}
+			svc := NewService(repo, nil, storage.NewStorageService(&fakeStorageProvider{}))
+
+			got, err := svc.GetLessonVideoURL(context.Background(), lesson.ID, tt.viewer, 0)
+			if tt.wantStatus != 0 {
+				appErr, ok := err.(*apperr.AppError)
+				if !ok || appErr.Code != tt.wantStatus {
+					t.Fatalf("err = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetLessonVideoURL: %v", err)
+			}
+			if !got.Signed || got.URL != "https://cdn.example.com/courses/intro.mp4?expires=1800" {
+				t.Errorf("URL = %q (signed %v), want a 30 minute signed URL", got.URL, got.Signed)
+			}
+			if got.ExpiresAt == nil || time.Until(*got.ExpiresAt) > DefaultVideoURLTTL {
+				t.Errorf("ExpiresAt = %v, want within %v", got.ExpiresAt, DefaultVideoURLTTL)
+			}
+		})
+	}
+}
+
+func TestGetLessonVideoURLCapsLifetime(t *testing.T) {
+	video := "https://cdn.example.com/courses/intro.mp4"
+	lesson := &models.CourseLesson{ID: uuid.New(), VideoURL: &video, IsPreview: true}
+	svc := NewService(&fakeCourseRepo{lesson: lesson}, nil, storage.NewStorageService(&fakeStorageProvider{}))
+
+	got, err := svc.GetLessonVideoURL(context.Background(), lesson.ID, uuid.Nil, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetLessonVideoURL: %v", err)
+	}
+	if got.URL != "https://cdn.example.com/courses/intro.mp4?expires=7200" {
+		t.Errorf("URL = %q, want the public URL re-signed for MaxVideoURLTTL", got.URL)
+	}
+}
@@ -0,0 +1,45 @@
+package learning
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// maxWishlistPageSize caps a page of the wishlist
+const maxWishlistPageSize = 50
+
+// AddToWishlist saves a published course to the user's wishlist
+func (s *Service) AddToWishlist(ctx context.Context, courseID, userID uuid.UUID) error {
+	course, err := s.courseRepo.GetCourseByID(ctx, courseID)
+	if err != nil || course.Status != "published" {
+		return apperr.NewAppError(http.StatusNotFound, "Course not found")
+	}
+	return s.courseRepo.AddToWishlist(ctx, courseID, userID)
+}
+
+// RemoveFromWishlist removes a course from the user's wishlist, reporting whether it
+// was on it
+func (s *Service) RemoveFromWishlist(ctx context.Context, courseID, userID uuid.UUID) (bool, error) {
+	return s.courseRepo.RemoveFromWishlist(ctx, courseID, userID)
+}
+
+// GetWishlist returns a page of the user's wishlist and its total size
+func (s *Service) GetWishlist(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Course, int, error) {
+	if limit <= 0 || limit > maxWishlistPageSize {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	courses, total, err := s.courseRepo.GetWishlist(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get wishlist: %w", err)
+	}
+	return courses, total, nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/queue"
 	"histeeria-backend/internal/websocket"
 
 	"github.com/google/uuid"
@@ -18,8 +19,8 @@ import (
 //
 // This service extends the base MessageRepository with delivery tracking capabilities.
 // It requires the following additional methods to be implemented in the repository:
-// - GetPendingMessagesForUser(ctx, userID) ([]*models.Message, error)
-// - MarkMessageDelivered(ctx, messageID) error
+// - GetPendingMessagesForUser(ctx, userID, since) ([]*models.Message, error)
+// - MarkMessageDeliveredTo(ctx, messageID, recipientID) error
 // - MarkConversationDelivered(ctx, conversationID, recipientID) (int, error)
 // - CleanupDeliveredMessages(ctx) (int, error)
 // - CleanupUndeliveredMessages(ctx) (int, error)
@@ -34,19 +35,32 @@ type DeliveryRepository interface {
 	GetConversationMessages(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*models.Message, error)
 	UpdateMessageStatus(ctx context.Context, messageID uuid.UUID, status models.MessageStatus) error
 	MarkMessagesAsRead(ctx context.Context, conversationID, readerID uuid.UUID) error
+	MarkMessageDeliveredTo(ctx context.Context, messageID, recipientID uuid.UUID) error
 
 	// New delivery tracking methods
-	GetPendingMessagesForUser(ctx context.Context, userID uuid.UUID) ([]*models.Message, error)
-	MarkMessageDelivered(ctx context.Context, messageID uuid.UUID) error
+	GetPendingMessagesForUser(ctx context.Context, userID uuid.UUID, since *time.Time) ([]*models.Message, error)
 	MarkConversationDelivered(ctx context.Context, conversationID uuid.UUID, recipientID uuid.UUID) (int, error)
 	CleanupDeliveredMessages(ctx context.Context) (int, error)
 	CleanupUndeliveredMessages(ctx context.Context) (int, error)
+
+	// Delivery retries
+	RecordDeliveryFailure(ctx context.Context, messageID, recipientID uuid.UUID, reason string, maxAttempts int) (attempts int, failed bool, err error)
+	ClearDeliveryAttempts(ctx context.Context, messageID, recipientID uuid.UUID) error
 }
 
+// A failed delivery is retried after deliveryRetryBase, then after twice as long each
+// time up to deliveryRetryMax, until maxDeliveryAttempts have been made in all
+const (
+	maxDeliveryAttempts = 5
+	deliveryRetryBase   = 2 * time.Second
+	deliveryRetryMax    = time.Minute
+)
+
 // DeliveryService handles message delivery tracking
 type DeliveryService struct {
 	repo      DeliveryRepository
 	wsManager *websocket.Manager
+	retries   queue.QueueProvider // Failed deliveries are retried through it; nil leaves them to the pending sync
 }
 
 // NewDeliveryService creates a new delivery service
@@ -57,6 +71,11 @@ func NewDeliveryService(repo DeliveryRepository, wsManager *websocket.Manager) *
 	}
 }
 
+// SetRetryQueue sets the queue failed deliveries are retried through
+func (s *DeliveryService) SetRetryQueue(provider queue.QueueProvider) {
+	s.retries = provider
+}
+
 // PendingMessage represents a message awaiting delivery
 type PendingMessage struct {
 	ID             uuid.UUID          `json:"id"`
@@ -69,15 +88,17 @@ type PendingMessage struct {
 	AttachmentType *string            `json:"attachment_type,omitempty"`
 	CreatedAt      time.Time          `json:"created_at"`
 	ReplyToID      *uuid.UUID         `json:"reply_to_id,omitempty"`
+	ExpiresAt      *time.Time         `json:"expires_at,omitempty"` // Disappearing messages: when it's deleted
 }
 
 // DeliveryStatus represents the delivery status response
 type DeliveryStatus struct {
 	MessageID       uuid.UUID  `json:"message_id"`
-	Status          string     `json:"status"` // sent, delivered, read
+	Status          string     `json:"status"` // sent, delivered, read, failed
 	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
 	ReadAt          *time.Time `json:"read_at,omitempty"`
 	DeleteScheduled *time.Time `json:"delete_scheduled,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"` // Disappearing messages: when it's deleted
 }
 
 // MessageSyncResponse is sent when user syncs pending messages
@@ -92,9 +113,14 @@ type MessageSyncResponse struct {
 // ============================================
 
 // GetPendingMessages retrieves all pending messages for a user
-// Called when user connects/opens app to sync their messages
-func (s *DeliveryService) GetPendingMessages(ctx context.Context, userID uuid.UUID) (*MessageSyncResponse, error) {
-	messages, err := s.repo.GetPendingMessagesForUser(ctx, userID)
+// Called when user connects/opens app to sync their messages. since is the SyncedAt
+// of the client's previous sync: messages pushed to a socket that dropped count as
+// delivered, so everything sent after it is included again. Pass nil on first sync.
+func (s *DeliveryService) GetPendingMessages(ctx context.Context, userID uuid.UUID, since *time.Time) (*MessageSyncResponse, error) {
+	// Taken before querying so the next sync can't skip a message sent meanwhile
+	syncedAt := time.Now()
+
+	messages, err := s.repo.GetPendingMessagesForUser(ctx, userID, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending messages: %w", err)
 	}
@@ -113,21 +139,23 @@ func (s *DeliveryService) GetPendingMessages(ctx context.Context, userID uuid.UU
 			AttachmentType: msg.AttachmentType,
 			CreatedAt:      msg.CreatedAt,
 			ReplyToID:      msg.ReplyToID,
+			ExpiresAt:      msg.ExpiresAt,
 		})
 	}
 
 	return &MessageSyncResponse{
 		Messages:     pending,
 		TotalPending: len(pending),
-		SyncedAt:     time.Now(),
+		SyncedAt:     syncedAt,
 	}, nil
 }
 
 // MarkMessageDelivered marks a message as delivered and schedules deletion
-// Called when recipient downloads/receives the message
+// Called when recipient downloads/receives the message. A group message only counts
+// as delivered, and is only scheduled for deletion, once every member has it.
 func (s *DeliveryService) MarkMessageDelivered(ctx context.Context, messageID uuid.UUID, recipientID uuid.UUID) (*DeliveryStatus, error) {
 	// Update message status
-	err := s.repo.MarkMessageDelivered(ctx, messageID)
+	err := s.repo.MarkMessageDeliveredTo(ctx, messageID, recipientID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to mark message delivered: %w", err)
 	}
@@ -135,19 +163,30 @@ func (s *DeliveryService) MarkMessageDelivered(ctx context.Context, messageID uu
 	now := time.Now()
 	deleteTime := now.Add(24 * time.Hour)
 
+	status := &DeliveryStatus{
+		MessageID:       messageID,
+		Status:          "delivered",
+		DeliveredAt:     &now,
+		DeleteScheduled: &deleteTime,
+	}
+
 	// Get message to notify sender
 	msg, err := s.repo.GetMessage(ctx, messageID)
 	if err == nil && msg != nil {
+		status.ExpiresAt = msg.ExpiresAt
 		// Notify sender of delivery (double tick ✓✓)
-		s.notifyDeliveryStatus(msg.SenderID, messageID, "delivered", &now, nil)
+		s.notifyDeliveryStatus(msg, recipientID, "delivered", &now, nil)
 	}
 
-	return &DeliveryStatus{
-		MessageID:       messageID,
-		Status:          "delivered",
-		DeliveredAt:     &now,
-		DeleteScheduled: &deleteTime,
-	}, nil
+	return status, nil
+}
+
+// Deliver pushes a new message to recipientID if they're online and marks it
+// delivered to them. A push or mark that fails is retried with backoff, and given
+// up on as failed. A recipient who isn't online gets the message from the pending
+// sync when they connect. Reports whether it reached their socket.
+func (s *DeliveryService) Deliver(ctx context.Context, msg *models.Message, recipientID uuid.UUID) bool {
+	return s.attemptDelivery(ctx, msg, recipientID, 1, false)
 }
 
 // MarkMessagesDelivered marks multiple messages as delivered (batch)
@@ -166,25 +205,169 @@ func (s *DeliveryService) MarkMessagesDelivered(ctx context.Context, conversatio
 	return count, nil
 }
 
-// MarkMessageRead marks a message as read
+// MarkMessageRead marks a message as read. In a group, reading a message reads
+// everything before it too, so the reader's read watermark moves up instead.
 func (s *DeliveryService) MarkMessageRead(ctx context.Context, messageID uuid.UUID, readerID uuid.UUID) error {
-	err := s.repo.UpdateMessageStatus(ctx, messageID, models.MessageStatusRead)
+	msg, err := s.repo.GetMessage(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+
+	conv, err := s.repo.GetConversation(ctx, msg.ConversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	if conv.IsGroup {
+		err = s.repo.MarkMessagesAsRead(ctx, msg.ConversationID, readerID)
+	} else {
+		err = s.repo.UpdateMessageStatus(ctx, messageID, models.MessageStatusRead)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to mark message read: %w", err)
 	}
 
 	now := time.Now()
 
-	// Get message to notify sender
+	// Notify sender of read (blue ticks)
+	s.notifyDeliveryStatus(msg, readerID, "read", nil, &now)
+
+	return nil
+}
+
+// ============================================
+// DELIVERY RETRIES
+// ============================================
+
+// DeliveryFailed records that delivering msg to recipientID failed on attempt number
+// attempt and schedules the next try, which pushes the message again unless it was
+// pushed already. When the attempts run out the message is marked failed and its
+// sender told.
+func (s *DeliveryService) DeliveryFailed(ctx context.Context, msg *models.Message, recipientID uuid.UUID, attempt int, pushed bool, cause error) {
+	attempts, failed, err := s.repo.RecordDeliveryFailure(ctx, msg.ID, recipientID, cause.Error(), maxDeliveryAttempts)
+	if err != nil {
+		// Go by the count the retry carries instead
+		log.Printf("[Delivery] Failed to record delivery failure of message %s: %v", msg.ID, err)
+		attempts, failed = attempt, attempt >= maxDeliveryAttempts
+	}
+
+	if failed {
+		log.Printf("[Delivery] Giving up on message %s to %s after %d attempts: %v", msg.ID, recipientID, attempts, cause)
+		if msg.SenderID != uuid.Nil {
+			s.notifyDeliveryStatus(msg, recipientID, string(models.MessageStatusFailed), nil, nil)
+		}
+		return
+	}
+
+	if s.retries == nil {
+		log.Printf("[Delivery] Message %s to %s failed, leaving it to the pending sync: %v", msg.ID, recipientID, cause)
+		return
+	}
+
+	payload := &queue.MessageDeliveryJobPayload{
+		MessageID:   msg.ID.String(),
+		RecipientID: recipientID.String(),
+		Attempt:     attempts,
+		Pushed:      pushed,
+	}
+	delay := deliveryBackoff(attempts)
+	if err := queue.QueueMessageDeliveryRetry(s.retries, payload, delay); err != nil {
+		log.Printf("[Delivery] Failed to schedule retry of message %s: %v", msg.ID, err)
+		return
+	}
+
+	log.Printf("[Delivery] Message %s to %s failed (attempt %d/%d), retrying in %v: %v",
+		msg.ID, recipientID, attempts, maxDeliveryAttempts, delay, cause)
+}
+
+// RetryDelivery makes another attempt at delivering a message (implements
+// queue.MessageDeliverer). It is safe to run for a message that has since been
+// delivered some other way, such as the recipient syncing: that is left as it is.
+func (s *DeliveryService) RetryDelivery(ctx context.Context, payload *queue.MessageDeliveryJobPayload) error {
+	messageID, err := uuid.Parse(payload.MessageID)
+	if err != nil {
+		return fmt.Errorf("invalid message ID: %w", err)
+	}
+	recipientID, err := uuid.Parse(payload.RecipientID)
+	if err != nil {
+		return fmt.Errorf("invalid recipient ID: %w", err)
+	}
+	attempt := payload.Attempt + 1
+
 	msg, err := s.repo.GetMessage(ctx, messageID)
-	if err == nil && msg != nil {
-		// Notify sender of read (blue ticks)
-		s.notifyDeliveryStatus(msg.SenderID, messageID, "read", nil, &now)
+	if err != nil {
+		s.DeliveryFailed(ctx, &models.Message{ID: messageID}, recipientID, attempt, payload.Pushed, err)
+		return nil
+	}
+
+	// Already there, or gone: nothing left to deliver
+	alreadyDelivered := msg.Status == models.MessageStatusDelivered || msg.Status == models.MessageStatusRead
+	expired := msg.ExpiresAt != nil && !msg.ExpiresAt.After(time.Now())
+	if alreadyDelivered || expired {
+		s.clearDeliveryAttempts(ctx, messageID, recipientID)
+		return nil
 	}
 
+	s.attemptDelivery(ctx, msg, recipientID, attempt, payload.Pushed)
 	return nil
 }
 
+// attemptDelivery makes attempt number attempt at delivering msg to recipientID:
+// pushing it to their socket, unless an earlier attempt already did, then marking it
+// delivered. When either fails the next attempt is scheduled. Only a push that was
+// dropped is made again, and the client can tell a repeat by its envelope ID, so a
+// recipient never gets the message twice. Reports whether it reached their socket.
+func (s *DeliveryService) attemptDelivery(ctx context.Context, msg *models.Message, recipientID uuid.UUID, attempt int, pushed bool) bool {
+	if !pushed {
+		forRecipient := *msg
+		forRecipient.IsMine = false
+		err := s.wsManager.PushToUser(recipientID, newMessageEnvelope(&forRecipient))
+		if err == websocket.ErrUserNotConnected {
+			// Stored until they connect and sync
+			if attempt > 1 {
+				s.clearDeliveryAttempts(ctx, msg.ID, recipientID)
+			}
+			return false
+		}
+		if err != nil {
+			s.DeliveryFailed(ctx, msg, recipientID, attempt, false, err)
+			return false
+		}
+	}
+
+	// Idempotent: marking a message delivered twice changes nothing
+	if err := s.repo.MarkMessageDeliveredTo(ctx, msg.ID, recipientID); err != nil {
+		s.DeliveryFailed(ctx, msg, recipientID, attempt, true, err)
+		return true
+	}
+
+	now := time.Now()
+	s.notifyDeliveryStatus(msg, recipientID, string(models.MessageStatusDelivered), &now, nil)
+	if attempt > 1 {
+		s.clearDeliveryAttempts(ctx, msg.ID, recipientID)
+		log.Printf("[Delivery] Message %s delivered to %s on attempt %d", msg.ID, recipientID, attempt)
+	}
+	return true
+}
+
+func (s *DeliveryService) clearDeliveryAttempts(ctx context.Context, messageID, recipientID uuid.UUID) {
+	if err := s.repo.ClearDeliveryAttempts(ctx, messageID, recipientID); err != nil {
+		log.Printf("[Delivery] Failed to clear delivery attempts of message %s: %v", messageID, err)
+	}
+}
+
+// deliveryBackoff is how long to wait before the attempt after attempts failed ones
+func deliveryBackoff(attempts int) time.Duration {
+	delay := deliveryRetryBase
+	for i := 1; i < attempts && delay < deliveryRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > deliveryRetryMax {
+		delay = deliveryRetryMax
+	}
+	return delay
+}
+
 // ============================================
 // CLEANUP JOBS
 // ============================================
@@ -225,15 +408,17 @@ func (s *DeliveryService) CleanupUndeliveredMessages(ctx context.Context) (int,
 // WEBSOCKET NOTIFICATIONS
 // ============================================
 
-// notifyDeliveryStatus sends delivery status update via WebSocket
-func (s *DeliveryService) notifyDeliveryStatus(userID uuid.UUID, messageID uuid.UUID, status string, deliveredAt, readAt *time.Time) {
+// notifyDeliveryStatus sends delivery status update to the message's sender via WebSocket
+func (s *DeliveryService) notifyDeliveryStatus(msg *models.Message, recipientID uuid.UUID, status string, deliveredAt, readAt *time.Time) {
 	notification := models.WSMessage{
 		Type: "message_status",
 		Data: map[string]interface{}{
-			"message_id":   messageID.String(),
+			"message_id":   msg.ID.String(),
+			"user_id":      recipientID.String(), // Whose copy this is about; matters in groups
 			"status":       status,
 			"delivered_at": deliveredAt,
 			"read_at":      readAt,
+			"expires_at":   msg.ExpiresAt,
 		},
 	}
 
@@ -243,7 +428,7 @@ func (s *DeliveryService) notifyDeliveryStatus(userID uuid.UUID, messageID uuid.
 		return
 	}
 
-	s.wsManager.BroadcastToUser(userID, data)
+	s.wsManager.BroadcastToUser(msg.SenderID, data)
 }
 
 // notifyConversationDelivered notifies sender about bulk delivery
@@ -253,18 +438,11 @@ func (s *DeliveryService) notifyConversationDelivered(ctx context.Context, conve
 		return
 	}
 
-	// Determine the sender (the other participant)
-	var senderID uuid.UUID
-	if conv.Participant1ID == recipientID {
-		senderID = conv.Participant2ID
-	} else {
-		senderID = conv.Participant1ID
-	}
-
 	notification := models.WSMessage{
 		Type: "conversation_delivered",
 		Data: map[string]interface{}{
 			"conversation_id": conversationID.String(),
+			"user_id":         recipientID.String(),
 			"delivered_at":    time.Now(),
 		},
 	}
@@ -274,5 +452,8 @@ func (s *DeliveryService) notifyConversationDelivered(ctx context.Context, conve
 		return
 	}
 
-	s.wsManager.BroadcastToUser(senderID, data)
+	// The senders are everyone else in the conversation
+	for _, senderID := range conv.OtherParticipantIDs(recipientID) {
+		s.wsManager.BroadcastToUser(senderID, data)
+	}
 }
@@ -0,0 +1,108 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/queue"
+
+	"github.com/google/uuid"
+)
+
+func TestDeliverPushesAndMarksDelivered(t *testing.T) {
+	manager := newRunningManager(t)
+	recipient := uuid.New()
+	client := connectUser(t, manager, recipient)
+	repo := &fakeDeliveryRepo{}
+	deliveries := NewDeliveryService(repo, manager)
+
+	msg := &models.Message{ID: uuid.New(), ConversationID: uuid.New(), SenderID: uuid.New(), IsMine: true, CreatedAt: time.Now()}
+	if !deliveries.Deliver(context.Background(), msg, recipient) {
+		t.Fatal("an online recipient should have been pushed to")
+	}
+
+	envelope := readEnvelope(t, client, string(models.WSMessageTypeNewMessage))
+	if envelope.ID != msg.ID.String() {
+		t.Errorf("pushed envelope %s, want the message's ID %s", envelope.ID, msg.ID)
+	}
+	if data, _ := envelope.Data.(map[string]interface{}); data["is_mine"] == true {
+		t.Error("the recipient's copy shouldn't be marked as theirs")
+	}
+	if len(repo.delivered) != 1 || repo.delivered[0] != recipient {
+		t.Errorf("delivered to %v, want %s", repo.delivered, recipient)
+	}
+}
+
+func TestDeliverRecordsAFailedMarkForRetry(t *testing.T) {
+	manager := newRunningManager(t)
+	recipient := uuid.New()
+	connectUser(t, manager, recipient)
+	repo := &fakeDeliveryRepo{markErr: errors.New("database unavailable")}
+	deliveries := NewDeliveryService(repo, manager)
+
+	deliveries.Deliver(context.Background(), &models.Message{ID: uuid.New(), SenderID: uuid.New()}, recipient)
+	if len(repo.failures) != 1 || repo.failures[0] != recipient {
+		t.Errorf("failures %v, want one for %s", repo.failures, recipient)
+	}
+}
+
+func TestDeliverLeavesOfflineRecipientsToTheSync(t *testing.T) {
+	repo := &fakeDeliveryRepo{}
+	deliveries := NewDeliveryService(repo, newRunningManager(t))
+
+	if deliveries.Deliver(context.Background(), &models.Message{ID: uuid.New()}, uuid.New()) {
+		t.Error("an offline recipient can't be pushed to")
+	}
+	if len(repo.delivered)+len(repo.failures) != 0 {
+		t.Errorf("an offline recipient was marked: delivered %v, failed %v", repo.delivered, repo.failures)
+	}
+}
+
+func TestRetryDeliveryPushesAgainOnlyIfThePushFailed(t *testing.T) {
+	manager := newRunningManager(t)
+	recipient := uuid.New()
+	client := connectUser(t, manager, recipient)
+	msg := &models.Message{ID: uuid.New(), SenderID: uuid.New(), Status: models.MessageStatusSent, CreatedAt: time.Now()}
+	repo := &fakeDeliveryRepo{messages: map[uuid.UUID]*models.Message{msg.ID: msg}}
+	deliveries := NewDeliveryService(repo, manager)
+
+	// The first push was dropped: this one makes it
+	payload := &queue.MessageDeliveryJobPayload{MessageID: msg.ID.String(), RecipientID: recipient.String(), Attempt: 1}
+	if err := deliveries.RetryDelivery(context.Background(), payload); err != nil {
+		t.Fatalf("RetryDelivery: %v", err)
+	}
+	if envelope := readEnvelope(t, client, string(models.WSMessageTypeNewMessage)); envelope.ID != msg.ID.String() {
+		t.Errorf("pushed %s, want %s", envelope.ID, msg.ID)
+	}
+
+	// The push landed and only the mark failed: the message isn't pushed a second time
+	payload.Pushed = true
+	if err := deliveries.RetryDelivery(context.Background(), payload); err != nil {
+		t.Fatalf("RetryDelivery: %v", err)
+	}
+	client.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, frame, err := client.ReadMessage(); err == nil {
+		t.Errorf("got %s, want nothing pushed", frame)
+	}
+
+	if len(repo.delivered) != 2 {
+		t.Errorf("marked delivered %d times, want once per retry", len(repo.delivered))
+	}
+}
+
+func TestRetryDeliveryLeavesADisconnectedRecipientToTheSync(t *testing.T) {
+	msg := &models.Message{ID: uuid.New(), SenderID: uuid.New(), Status: models.MessageStatusSent}
+	repo := &fakeDeliveryRepo{messages: map[uuid.UUID]*models.Message{msg.ID: msg}}
+	deliveries := NewDeliveryService(repo, newRunningManager(t))
+
+	payload := &queue.MessageDeliveryJobPayload{MessageID: msg.ID.String(), RecipientID: uuid.NewString(), Attempt: 1}
+	if err := deliveries.RetryDelivery(context.Background(), payload); err != nil {
+		t.Fatalf("RetryDelivery: %v", err)
+	}
+	if len(repo.delivered)+len(repo.failures) != 0 {
+		t.Errorf("delivered %v, failed %v; want it left for the sync", repo.delivered, repo.failures)
+	}
+}
@@ -0,0 +1,204 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/websocket"
+
+	"github.com/google/uuid"
+	gorilla "github.com/gorilla/websocket"
+)
+
+// fakeDeliveryRepo records delivery marks and failures; anything else panics through
+// the nil embedded interface
+type fakeDeliveryRepo struct {
+	DeliveryRepository
+
+	mu        sync.Mutex
+	messages  map[uuid.UUID]*models.Message
+	markErr   error // Returned by every MarkMessageDeliveredTo
+	delivered []uuid.UUID
+	failures  []uuid.UUID
+}
+
+func (r *fakeDeliveryRepo) GetMessage(ctx context.Context, messageID uuid.UUID) (*models.Message, error) {
+	msg, ok := r.messages[messageID]
+	if !ok {
+		return nil, errors.New("message not found")
+	}
+	return msg, nil
+}
+
+func (r *fakeDeliveryRepo) MarkMessageDeliveredTo(ctx context.Context, messageID, recipientID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.markErr != nil {
+		return r.markErr
+	}
+	r.delivered = append(r.delivered, recipientID)
+	return nil
+}
+
+func (r *fakeDeliveryRepo) RecordDeliveryFailure(ctx context.Context, messageID, recipientID uuid.UUID, reason string, maxAttempts int) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, recipientID)
+	return len(r.failures), len(r.failures) >= maxAttempts, nil
+}
+
+func (r *fakeDeliveryRepo) ClearDeliveryAttempts(ctx context.Context, messageID, recipientID uuid.UUID) error {
+	return nil
+}
+
+// fakeMessageRepo serves one conversation and a batch of due scheduled messages,
+// recording what's sent
+type fakeMessageRepo struct {
+	repository.MessageRepository
+
+	conversation *models.Conversation
+	due          []*models.ScheduledMessage
+	created      []*models.Message
+	finished     map[uuid.UUID]*uuid.UUID // Scheduled message -> message it was sent as
+
+	messages map[uuid.UUID]*models.Message
+	pins     int // Pins so far; each is stamped a second after the last
+}
+
+func (r *fakeMessageRepo) ClaimDueScheduledMessages(ctx context.Context, limit int) ([]*models.ScheduledMessage, error) {
+	batch := r.due
+	r.due = nil
+	return batch, nil
+}
+
+func (r *fakeMessageRepo) GetConversation(ctx context.Context, id uuid.UUID) (*models.Conversation, error) {
+	if r.conversation == nil || r.conversation.ID != id {
+		return nil, errors.New("conversation not found")
+	}
+	return r.conversation, nil
+}
+
+func (r *fakeMessageRepo) CreateMessage(ctx context.Context, message *models.Message) error {
+	message.ID = uuid.New()
+	r.created = append(r.created, message)
+	return nil
+}
+
+func (r *fakeMessageRepo) FinishScheduledMessage(ctx context.Context, id uuid.UUID, messageID *uuid.UUID, failureReason string) error {
+	if r.finished == nil {
+		r.finished = map[uuid.UUID]*uuid.UUID{}
+	}
+	r.finished[id] = messageID
+	return nil
+}
+
+func (r *fakeMessageRepo) GetMessage(ctx context.Context, messageID uuid.UUID) (*models.Message, error) {
+	msg, ok := r.messages[messageID]
+	if !ok {
+		return nil, errors.New("message not found")
+	}
+	return msg, nil
+}
+
+func (r *fakeMessageRepo) PinMessage(ctx context.Context, messageID, userID uuid.UUID) error {
+	r.pins++
+	pinnedAt := time.Date(2026, 1, 1, 0, 0, r.pins, 0, time.UTC)
+	r.messages[messageID].PinnedAt = &pinnedAt
+	r.messages[messageID].PinnedBy = &userID
+	return nil
+}
+
+func (r *fakeMessageRepo) UnpinMessage(ctx context.Context, messageID, userID uuid.UUID) error {
+	r.messages[messageID].PinnedAt = nil
+	r.messages[messageID].PinnedBy = nil
+	return nil
+}
+
+func (r *fakeMessageRepo) GetPinnedMessages(ctx context.Context, conversationID uuid.UUID) ([]*models.Message, error) {
+	var pinned []*models.Message
+	for _, msg := range r.messages {
+		if msg.ConversationID == conversationID && msg.PinnedAt != nil {
+			pinned = append(pinned, msg)
+		}
+	}
+	sort.Slice(pinned, func(i, j int) bool { return pinned[i].PinnedAt.After(*pinned[j].PinnedAt) })
+	return pinned, nil
+}
+
+// newGroup returns a group conversation of the given members
+func newGroup(members ...uuid.UUID) *models.Conversation {
+	group := &models.Conversation{ID: uuid.New(), IsGroup: true}
+	for _, id := range members {
+		group.Members = append(group.Members, &models.ConversationMember{ConversationID: group.ID, UserID: id})
+	}
+	return group
+}
+
+// newRunningManager starts a WebSocket manager for the test
+func newRunningManager(t *testing.T) *websocket.Manager {
+	t.Helper()
+	manager := websocket.NewManager()
+	go manager.Run()
+	t.Cleanup(manager.Shutdown)
+	return manager
+}
+
+// connectUser opens a real WebSocket connection for userID to manager and returns
+// the client's end
+func connectUser(t *testing.T, manager *websocket.Manager, userID uuid.UUID) *gorilla.Conn {
+	t.Helper()
+	upgrader := gorilla.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		manager.Register(userID, conn)
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	for deadline := time.Now().Add(2 * time.Second); !manager.IsUserConnected(userID); {
+		if time.Now().After(deadline) {
+			t.Fatal("user never connected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return client
+}
+
+// readEnvelope reads the next message of type wantType off a client connection. The
+// manager batches queued messages into one frame, a line each.
+func readEnvelope(t *testing.T, client *gorilla.Conn, wantType string) models.WSMessageEnvelope {
+	t.Helper()
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, frame, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", wantType, err)
+		}
+		for _, line := range bytes.Split(frame, []byte("\n")) {
+			var envelope models.WSMessageEnvelope
+			if json.Unmarshal(line, &envelope) == nil && string(envelope.Type) == wantType {
+				return envelope
+			}
+		}
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"strings"
 	"time"
 
+	"histeeria-backend/internal/cache"
 	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -86,18 +88,12 @@ func NewMessageHandlers(
 // GetConversations handles GET /api/v1/conversations
 func (h *MessageHandlers) GetConversations(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Parse pagination
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
@@ -125,8 +121,7 @@ func (h *MessageHandlers) GetConversations(c *gin.Context) {
 
 // GetConversation handles GET /api/v1/conversations/:id
 func (h *MessageHandlers) GetConversation(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -148,8 +143,7 @@ func (h *MessageHandlers) GetConversation(c *gin.Context) {
 
 // StartConversation handles POST /api/v1/conversations/:userId
 func (h *MessageHandlers) StartConversation(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	otherUserID, err := uuid.Parse(c.Param("userId"))
 	if err != nil {
@@ -164,6 +158,11 @@ func (h *MessageHandlers) StartConversation(c *gin.Context) {
 
 	conversation, err := h.service.StartConversation(c.Request.Context(), uid, otherUserID)
 	if err != nil {
+		if errors.IsAppError(err) {
+			appErr := errors.GetAppError(err)
+			c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+			return
+		}
 		log.Printf("[MessageHandlers] Failed to start conversation: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -177,8 +176,7 @@ func (h *MessageHandlers) StartConversation(c *gin.Context) {
 
 // GetUnreadCount handles GET /api/v1/conversations/unread-count
 func (h *MessageHandlers) GetUnreadCount(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	count, err := h.service.GetUnreadCount(c.Request.Context(), uid)
 	if err != nil {
@@ -192,14 +190,189 @@ func (h *MessageHandlers) GetUnreadCount(c *gin.Context) {
 	})
 }
 
+// ============================================
+// GROUPS
+// ============================================
+
+// writeGroupError writes err with the status of a group or scheduled message error,
+// or 500
+func writeGroupError(c *gin.Context, err error) {
+	appErr := errors.GetAppError(err)
+	c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+}
+
+// CreateGroup handles POST /api/v1/conversations/group
+func (h *MessageHandlers) CreateGroup(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	var req models.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	conversation, err := h.service.CreateGroup(c.Request.Context(), uid, req.Name, req.MemberIDs)
+	if err != nil {
+		log.Printf("[MessageHandlers] Failed to create group: %v", err)
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":      true,
+		"conversation": conversation,
+	})
+}
+
+// RenameGroup handles PATCH /api/v1/conversations/:id
+func (h *MessageHandlers) RenameGroup(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req models.RenameGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	conversation, err := h.service.RenameGroup(c.Request.Context(), conversationID, uid, req.Name)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"conversation": conversation,
+	})
+}
+
+// SetDisappearingMessages handles PUT /api/v1/conversations/:id/disappearing
+func (h *MessageHandlers) SetDisappearingMessages(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req models.SetDisappearingMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	conversation, err := h.service.SetDisappearingMessages(c.Request.Context(), conversationID, uid, ttl)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"conversation": conversation,
+	})
+}
+
+// AddGroupMembers handles POST /api/v1/conversations/:id/members
+func (h *MessageHandlers) AddGroupMembers(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req models.AddMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	conversation, err := h.service.AddGroupMembers(c.Request.Context(), conversationID, uid, req.UserIDs)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"conversation": conversation,
+	})
+}
+
+// RemoveGroupMember handles DELETE /api/v1/conversations/:id/members/:userId
+// Members leave a group by removing themselves
+func (h *MessageHandlers) RemoveGroupMember(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.service.RemoveGroupMember(c.Request.Context(), conversationID, uid, userID); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	message := "Member removed"
+	if userID == uid {
+		message = "Left the group"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+	})
+}
+
+// GetMessageReceipts handles GET /api/v1/messages/:id/receipts
+// Returns the delivery and read state of the current user's message for each recipient
+func (h *MessageHandlers) GetMessageReceipts(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	receipts, err := h.service.GetMessageReceipts(c.Request.Context(), messageID, uid)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"receipts": receipts,
+	})
+}
+
 // ============================================
 // MESSAGES
 // ============================================
 
-// GetMessages handles GET /api/v1/conversations/:id/messages
+// GetMessages handles GET /api/v1/conversations/:id/messages. With ?after={messageId}
+// it returns the messages after that one, oldest first, for catching up on reconnect.
 func (h *MessageHandlers) GetMessages(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -215,6 +388,34 @@ func (h *MessageHandlers) GetMessages(c *gin.Context) {
 		limit = 100
 	}
 
+	// ?after= catches up from a message the client already has instead of paging
+	if afterStr := c.Query("after"); afterStr != "" {
+		afterID, err := uuid.Parse(afterStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after message ID"})
+			return
+		}
+		if limit < 1 {
+			limit = 50
+		}
+
+		messages, hasMore, err := h.service.GetMessagesAfter(c.Request.Context(), conversationID, uid, afterID, limit)
+		if err != nil {
+			writeGroupError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"messages": messages,
+			"total":    len(messages),
+			"limit":    limit,
+			"after":    afterID,
+			"has_more": hasMore,
+		})
+		return
+	}
+
 	messages, err := h.service.GetMessages(c.Request.Context(), conversationID, uid, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -233,8 +434,7 @@ func (h *MessageHandlers) GetMessages(c *gin.Context) {
 
 // SendMessage handles POST /api/v1/conversations/:id/messages
 func (h *MessageHandlers) SendMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -248,9 +448,8 @@ func (h *MessageHandlers) SendMessage(c *gin.Context) {
 	log.Printf("[MessageHandlers] Raw request body: %s", string(bodyBytes))
 
 	var req models.MessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("[MessageHandlers] JSON parsing error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
+		log.Printf("[MessageHandlers] Invalid message request body")
 		return
 	}
 
@@ -271,8 +470,28 @@ func (h *MessageHandlers) SendMessage(c *gin.Context) {
 		req.MessageType = models.MessageTypeText
 	}
 
+	if req.SendAt != nil {
+		scheduled, err := h.service.ScheduleMessage(c.Request.Context(), conversationID, uid, &req)
+		if err != nil {
+			writeGroupError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success":           true,
+			"scheduled_message": scheduled,
+			"temp_id":           req.TempID,
+		})
+		return
+	}
+
 	message, err := h.service.SendMessage(c.Request.Context(), conversationID, uid, &req)
 	if err != nil {
+		if errors.IsAppError(err) {
+			appErr := errors.GetAppError(err)
+			c.JSON(appErr.Code, gin.H{"error": appErr.Message})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -284,10 +503,79 @@ func (h *MessageHandlers) SendMessage(c *gin.Context) {
 	})
 }
 
+// GetScheduledMessages handles GET /api/v1/conversations/:id/scheduled
+func (h *MessageHandlers) GetScheduledMessages(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	scheduled, err := h.service.GetScheduledMessages(c.Request.Context(), conversationID, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":            true,
+		"scheduled_messages": scheduled,
+	})
+}
+
+// EditScheduledMessage handles PATCH /api/v1/messages/scheduled/:id
+func (h *MessageHandlers) EditScheduledMessage(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	scheduledID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled message ID"})
+		return
+	}
+
+	var req models.EditScheduledMessageRequest
+	if !utils.BindJSON(c, &req) {
+		return
+	}
+
+	scheduled, err := h.service.EditScheduledMessage(c.Request.Context(), scheduledID, uid, &req)
+	if err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"scheduled_message": scheduled,
+	})
+}
+
+// CancelScheduledMessage handles DELETE /api/v1/messages/scheduled/:id
+func (h *MessageHandlers) CancelScheduledMessage(c *gin.Context) {
+	uid := utils.MustUserID(c)
+
+	scheduledID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled message ID"})
+		return
+	}
+
+	if err := h.service.CancelScheduledMessage(c.Request.Context(), scheduledID, uid); err != nil {
+		writeGroupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Scheduled message cancelled",
+	})
+}
+
 // MarkAsRead handles PATCH /api/v1/conversations/:id/read
 func (h *MessageHandlers) MarkAsRead(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -308,8 +596,7 @@ func (h *MessageHandlers) MarkAsRead(c *gin.Context) {
 
 // DeleteMessage handles DELETE /api/v1/messages/:id
 func (h *MessageHandlers) DeleteMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -330,8 +617,7 @@ func (h *MessageHandlers) DeleteMessage(c *gin.Context) {
 
 // SearchMessages handles GET /api/v1/messages/search
 func (h *MessageHandlers) SearchMessages(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	query := c.Query("q")
 	if query == "" {
@@ -339,10 +625,13 @@ func (h *MessageHandlers) SearchMessages(c *gin.Context) {
 		return
 	}
 
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, offset := searchPage(c, 20)
+	filter, ok := searchFilter(c)
+	if !ok {
+		return
+	}
 
-	messages, err := h.service.SearchMessages(c.Request.Context(), uid, query, limit, offset)
+	messages, total, err := h.service.SearchMessages(c.Request.Context(), uid, query, filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -352,17 +641,85 @@ func (h *MessageHandlers) SearchMessages(c *gin.Context) {
 		"success":  true,
 		"messages": messages,
 		"query":    query,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"has_more": offset+len(messages) < total,
 	})
 }
 
+// searchPage reads a message search's limit and offset, keeping a page small enough
+// that a search never pulls a large share of someone's history in one request
+func searchPage(c *gin.Context, defaultLimit int) (limit, offset int) {
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit < 1 || limit > 100 {
+		limit = defaultLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// searchFilter reads a message search's optional sender_id, from and to, writing a
+// 400 response if one is malformed. from and to take RFC 3339 times or dates; a
+// date as to includes that whole day.
+func searchFilter(c *gin.Context) (models.MessageSearchFilter, bool) {
+	var filter models.MessageSearchFilter
+
+	if raw := c.Query("sender_id"); raw != "" {
+		senderID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender_id"})
+			return filter, false
+		}
+		filter.SenderID = &senderID
+	}
+
+	var err error
+	if filter.From, err = parseSearchTime(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected an RFC 3339 time or a YYYY-MM-DD date"})
+		return filter, false
+	}
+	if filter.To, err = parseSearchTime(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected an RFC 3339 time or a YYYY-MM-DD date"})
+		return filter, false
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return filter, false
+	}
+	return filter, true
+}
+
+// parseSearchTime parses a search time bound, nil when raw is empty. A date means the
+// start of that day, or the end of it for an upper bound.
+func parseSearchTime(raw string, upper bool) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, err
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
 // ============================================
 // ATTACHMENTS
 // ============================================
 
 // UploadImage handles POST /api/v1/messages/upload-image
 func (h *MessageHandlers) UploadImage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	// Get quality parameter
 	quality := c.DefaultQuery("quality", "standard")
@@ -394,6 +751,11 @@ func (h *MessageHandlers) UploadImage(c *gin.Context) {
 		return
 	}
 
+	// Check the actual bytes, not the client-declared type
+	if _, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryImage); !ok {
+		return
+	}
+
 	// Validate image
 	isValid, _, err := h.mediaOptimizer.ValidateImage(fileData)
 	if !isValid || err != nil {
@@ -402,7 +764,7 @@ func (h *MessageHandlers) UploadImage(c *gin.Context) {
 	}
 
 	// Optimize image
-	optimizedData, newFormat, err := h.mediaOptimizer.OptimizeImageFromBytes(fileData, optimizeQuality)
+	optimizedData, newFormat, err := h.mediaOptimizer.OptimizeUploadImage(fileData, optimizeQuality, UploadTypeMessage)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to optimize image"})
 		return
@@ -410,26 +772,25 @@ func (h *MessageHandlers) UploadImage(c *gin.Context) {
 
 	// Upload to Supabase Storage (private bucket)
 	fileName := fmt.Sprintf("messages/%s/%s_%d.%s", uid.String(), uuid.New().String(), time.Now().Unix(), newFormat)
-	filePath, err := h.storageService.UploadFile(c.Request.Context(), "chat-attachments", fileName, bytes.NewReader(optimizedData), "image/"+newFormat)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
+	filePath, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "chat-attachments", fileName, optimizedData, "image/"+newFormat, "Failed to upload image")
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"url":     filePath, // Store file path, not public URL
-		"name":    header.Filename,
-		"size":    len(optimizedData),
-		"type":    "image/" + newFormat,
-		"format":  newFormat,
+		"success":       true,
+		"url":           filePath, // Store file path, not public URL
+		"name":          header.Filename,
+		"size":          len(optimizedData),
+		"type":          "image/" + newFormat,
+		"format":        newFormat,
+		"storage_quota": quota,
 	})
 }
 
 // UploadAudio handles POST /api/v1/messages/upload-audio
 func (h *MessageHandlers) UploadAudio(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	log.Printf("[UploadAudio] Request from user: %s", uid.String())
 
@@ -461,8 +822,13 @@ func (h *MessageHandlers) UploadAudio(c *gin.Context) {
 
 	log.Printf("[UploadAudio] File data read successfully: %d bytes", len(fileData))
 
+	contentType, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryAudio)
+	if !ok {
+		return
+	}
+
 	// Validate audio
-	isValid, err := h.mediaOptimizer.ValidateAudio(fileData, header.Header.Get("Content-Type"))
+	isValid, err := h.mediaOptimizer.ValidateAudio(fileData, contentType)
 	if !isValid {
 		log.Printf("[UploadAudio] Audio validation failed: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audio format: " + err.Error()})
@@ -472,7 +838,6 @@ func (h *MessageHandlers) UploadAudio(c *gin.Context) {
 	log.Printf("[UploadAudio] Audio validation passed")
 
 	// Determine file extension from content type
-	contentType := header.Header.Get("Content-Type")
 	ext := ".webm" // default
 	if contentType == "audio/m4a" || contentType == "audio/aac" || contentType == "audio/x-m4a" || contentType == "audio/mp4" {
 		ext = ".m4a"
@@ -487,28 +852,30 @@ func (h *MessageHandlers) UploadAudio(c *gin.Context) {
 	log.Printf("[UploadAudio] Uploading to Supabase: bucket=chat-attachments, fileName=%s, contentType=%s", fileName, contentType)
 
 	// Upload returns file path (bucket/path) for private bucket
-	filePath, err := h.storageService.UploadFile(c.Request.Context(), "chat-attachments", fileName, bytes.NewReader(fileData), contentType)
-	if err != nil {
-		log.Printf("[UploadAudio] Supabase upload failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to upload audio: %v", err)})
+	filePath, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "chat-attachments", fileName, fileData, contentType, "Failed to upload audio")
+	if !ok {
 		return
 	}
 
 	log.Printf("[UploadAudio] Upload successful: %s", filePath)
 
+	// Empty if the audio couldn't be decoded; send it back with the message either way
+	waveform := h.mediaOptimizer.ExtractWaveform(c.Request.Context(), fileData)
+
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"url":     filePath, // Store file path, not public URL
-		"name":    header.Filename,
-		"size":    len(fileData),
-		"type":    contentType,
+		"success":       true,
+		"url":           filePath, // Store file path, not public URL
+		"name":          header.Filename,
+		"size":          len(fileData),
+		"type":          contentType,
+		"waveform":      waveform,
+		"storage_quota": quota,
 	})
 }
 
 // UploadFile handles POST /api/v1/messages/upload-file
 func (h *MessageHandlers) UploadFile(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	log.Printf("[UploadFile] Request from user: %s", uid.String())
 
@@ -540,6 +907,11 @@ func (h *MessageHandlers) UploadFile(c *gin.Context) {
 
 	log.Printf("[UploadFile] File data read successfully: %d bytes", len(fileData))
 
+	contentType, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryDocument)
+	if !ok {
+		return
+	}
+
 	// Sanitize filename - remove non-ASCII characters and spaces
 	sanitizedFilename := sanitizeFilename(header.Filename)
 
@@ -548,28 +920,26 @@ func (h *MessageHandlers) UploadFile(c *gin.Context) {
 	log.Printf("[UploadFile] Original filename: %s, Sanitized: %s", header.Filename, sanitizedFilename)
 	log.Printf("[UploadFile] Uploading to Supabase: bucket=chat-attachments, fileName=%s", fileName)
 
-	filePath, err := h.storageService.UploadFile(c.Request.Context(), "chat-attachments", fileName, bytes.NewReader(fileData), header.Header.Get("Content-Type"))
-	if err != nil {
-		log.Printf("[UploadFile] Supabase upload failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to upload file: %v", err)})
+	filePath, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "chat-attachments", fileName, fileData, contentType, "Failed to upload file")
+	if !ok {
 		return
 	}
 
 	log.Printf("[UploadFile] Upload successful: %s", filePath)
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"url":     filePath, // Store file path, not public URL
-		"name":    header.Filename, // Return original filename for display
-		"size":    header.Size,
-		"type":    header.Header.Get("Content-Type"),
+		"success":       true,
+		"url":           filePath,        // Store file path, not public URL
+		"name":          header.Filename, // Return original filename for display
+		"size":          header.Size,
+		"type":          contentType,
+		"storage_quota": quota,
 	})
 }
 
 // UploadVideo handles POST /api/v1/messages/upload-video
 func (h *MessageHandlers) UploadVideo(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	log.Printf("[UploadVideo] Request from user: %s", uid.String())
 
@@ -602,6 +972,11 @@ func (h *MessageHandlers) UploadVideo(c *gin.Context) {
 
 	log.Printf("[UploadVideo] File data read successfully: %d bytes", len(fileData))
 
+	contentType, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryVideo)
+	if !ok {
+		return
+	}
+
 	// Sanitize filename
 	sanitizedFilename := sanitizeFilename(header.Filename)
 
@@ -609,10 +984,8 @@ func (h *MessageHandlers) UploadVideo(c *gin.Context) {
 	videoFileName := fmt.Sprintf("messages/%s/video_%s_%s", uid.String(), uuid.New().String(), sanitizedFilename)
 	log.Printf("[UploadVideo] Uploading to Supabase: bucket=chat-attachments, fileName=%s", videoFileName)
 
-	filePath, err := h.storageService.UploadFile(c.Request.Context(), "chat-attachments", videoFileName, bytes.NewReader(fileData), header.Header.Get("Content-Type"))
-	if err != nil {
-		log.Printf("[UploadVideo] Supabase upload failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to upload video: %v", err)})
+	filePath, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "chat-attachments", videoFileName, fileData, contentType, "Failed to upload video")
+	if !ok {
 		return
 	}
 
@@ -627,20 +1000,27 @@ func (h *MessageHandlers) UploadVideo(c *gin.Context) {
 		// Read thumbnail
 		thumbnailData, err := io.ReadAll(thumbnailFile)
 		if err == nil {
-			// Upload thumbnail (private bucket)
-			thumbnailFileName := fmt.Sprintf("messages/%s/thumb_%s.jpg", uid.String(), uuid.New().String())
-			thumbnailPath, _ = h.storageService.UploadFile(c.Request.Context(), "chat-attachments", thumbnailFileName, bytes.NewReader(thumbnailData), thumbnailHeader.Header.Get("Content-Type"))
-			log.Printf("[UploadVideo] Thumbnail uploaded: %s", thumbnailPath)
+			// An invalid thumbnail is dropped rather than failing the video upload
+			thumbnailType, err := h.storageService.ValidateUploadContent(thumbnailData, thumbnailHeader.Header.Get("Content-Type"), utils.UploadCategoryImage)
+			if err != nil {
+				log.Printf("[UploadVideo] Rejected thumbnail: %v", err)
+			} else {
+				// Upload thumbnail (private bucket)
+				thumbnailFileName := fmt.Sprintf("messages/%s/thumb_%s.jpg", uid.String(), uuid.New().String())
+				thumbnailPath, _, _ = h.storageService.UploadUserFile(c.Request.Context(), uid, "chat-attachments", thumbnailFileName, thumbnailData, thumbnailType)
+				log.Printf("[UploadVideo] Thumbnail uploaded: %s", thumbnailPath)
+			}
 		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":       true,
-		"url":           filePath, // Store file path, not public URL
+		"url":           filePath,      // Store file path, not public URL
 		"thumbnail_url": thumbnailPath, // Store thumbnail path, not public URL
 		"name":          header.Filename,
 		"size":          header.Size,
-		"type":          header.Header.Get("Content-Type"),
+		"type":          contentType,
+		"storage_quota": quota,
 	})
 }
 
@@ -650,8 +1030,7 @@ func (h *MessageHandlers) UploadVideo(c *gin.Context) {
 
 // AddReaction handles POST /api/v1/messages/:id/reactions
 func (h *MessageHandlers) AddReaction(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -660,8 +1039,7 @@ func (h *MessageHandlers) AddReaction(c *gin.Context) {
 	}
 
 	var req models.ReactionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -689,8 +1067,7 @@ func (h *MessageHandlers) AddReaction(c *gin.Context) {
 
 // RemoveReaction handles DELETE /api/v1/messages/:id/reactions
 func (h *MessageHandlers) RemoveReaction(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -715,8 +1092,7 @@ func (h *MessageHandlers) RemoveReaction(c *gin.Context) {
 
 // StarMessage handles POST /api/v1/messages/:id/star
 func (h *MessageHandlers) StarMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -737,8 +1113,7 @@ func (h *MessageHandlers) StarMessage(c *gin.Context) {
 
 // UnstarMessage handles DELETE /api/v1/messages/:id/star
 func (h *MessageHandlers) UnstarMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -759,8 +1134,7 @@ func (h *MessageHandlers) UnstarMessage(c *gin.Context) {
 
 // GetStarredMessages handles GET /api/v1/messages/starred
 func (h *MessageHandlers) GetStarredMessages(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
@@ -781,10 +1155,10 @@ func (h *MessageHandlers) GetStarredMessages(c *gin.Context) {
 // TYPING INDICATORS
 // ============================================
 
-// StartTyping handles POST /api/v1/conversations/:id/typing/start
+// StartTyping handles POST /api/v1/conversations/:id/typing/start. Clients repeat it
+// while the user keeps typing.
 func (h *MessageHandlers) StartTyping(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -803,13 +1177,13 @@ func (h *MessageHandlers) StartTyping(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	// The indicator stops after expires_in seconds unless the client calls again
+	c.JSON(http.StatusOK, gin.H{"success": true, "expires_in": int(cache.TypingTTL.Seconds())})
 }
 
 // StopTyping handles POST /api/v1/conversations/:id/typing/stop
 func (h *MessageHandlers) StopTyping(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -921,8 +1295,7 @@ func splitByComma(s string) []string {
 
 // PinMessage handles POST /api/v1/messages/:id/pin
 func (h *MessageHandlers) PinMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -930,18 +1303,19 @@ func (h *MessageHandlers) PinMessage(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.PinMessage(c.Request.Context(), messageID, uid); err != nil {
+	unpinned, err := h.service.PinMessage(c.Request.Context(), messageID, uid)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Message pinned"})
+	// unpinned lists messages removed to stay within the conversation's pin limit
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Message pinned", "unpinned": unpinned})
 }
 
 // UnpinMessage handles DELETE /api/v1/messages/:id/pin
 func (h *MessageHandlers) UnpinMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -959,8 +1333,7 @@ func (h *MessageHandlers) UnpinMessage(c *gin.Context) {
 
 // GetPinnedMessages handles GET /api/v1/conversations/:id/pinned
 func (h *MessageHandlers) GetPinnedMessages(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -986,8 +1359,7 @@ func (h *MessageHandlers) GetPinnedMessages(c *gin.Context) {
 
 // EditMessage handles PATCH /api/v1/messages/:id
 func (h *MessageHandlers) EditMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -996,8 +1368,7 @@ func (h *MessageHandlers) EditMessage(c *gin.Context) {
 	}
 
 	var req models.EditMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -1011,8 +1382,7 @@ func (h *MessageHandlers) EditMessage(c *gin.Context) {
 
 // GetMessageEditHistory handles GET /api/v1/messages/:id/edit-history
 func (h *MessageHandlers) GetMessageEditHistory(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -1038,8 +1408,7 @@ func (h *MessageHandlers) GetMessageEditHistory(c *gin.Context) {
 
 // ForwardMessage handles POST /api/v1/messages/:id/forward
 func (h *MessageHandlers) ForwardMessage(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	messageID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -1048,8 +1417,7 @@ func (h *MessageHandlers) ForwardMessage(c *gin.Context) {
 	}
 
 	var req models.ForwardMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -1071,8 +1439,7 @@ func (h *MessageHandlers) ForwardMessage(c *gin.Context) {
 
 // SearchConversationMessages handles GET /api/v1/conversations/:id/search
 func (h *MessageHandlers) SearchConversationMessages(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	uid, _ := uuid.Parse(userID.(string))
+	uid := utils.MustUserID(c)
 
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -1086,18 +1453,13 @@ func (h *MessageHandlers) SearchConversationMessages(c *gin.Context) {
 		return
 	}
 
-	limit := 50
-	offset := 0
-
-	if limitStr := c.Query("limit"); limitStr != "" {
-		fmt.Sscanf(limitStr, "%d", &limit)
-	}
-
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		fmt.Sscanf(offsetStr, "%d", &offset)
+	limit, offset := searchPage(c, 50)
+	filter, ok := searchFilter(c)
+	if !ok {
+		return
 	}
 
-	messages, err := h.service.SearchConversationMessages(c.Request.Context(), conversationID, uid, query, limit, offset)
+	messages, total, err := h.service.SearchConversationMessages(c.Request.Context(), conversationID, uid, query, filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1106,7 +1468,10 @@ func (h *MessageHandlers) SearchConversationMessages(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"messages": messages,
-		"total":    len(messages),
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"has_more": offset+len(messages) < total,
 	})
 }
 
@@ -1114,8 +1479,8 @@ func (h *MessageHandlers) SearchConversationMessages(c *gin.Context) {
 // DELIVERY TRACKING ENDPOINTS (WhatsApp-Style)
 // ============================================
 
-// GetPendingMessages handles GET /api/v1/messages/pending
-// Returns all pending messages for the current user (for sync on app open)
+// GetPendingMessages handles GET /api/v1/messages/pending?since=
+// Returns all pending messages for the current user (for sync on app open and reconnect)
 func (h *MessageHandlers) GetPendingMessages(c *gin.Context) {
 	if h.deliverySvc == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Delivery service not available"})
@@ -1123,20 +1488,26 @@ func (h *MessageHandlers) GetPendingMessages(c *gin.Context) {
 	}
 
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
+	// ?since= is the synced_at of the previous sync, to also cover messages pushed
+	// while the client's socket was dropping
+	var since *time.Time
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		t, err := time.Parse(time.RFC3339Nano, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time"})
+			return
+		}
+		since = &t
 	}
 
 	// Get pending messages
-	response, err := h.deliverySvc.GetPendingMessages(c.Request.Context(), uid)
+	response, err := h.deliverySvc.GetPendingMessages(c.Request.Context(), uid, since)
 	if err != nil {
 		log.Printf("[Delivery] Failed to get pending messages: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pending messages"})
@@ -1160,18 +1531,12 @@ func (h *MessageHandlers) MarkMessageDelivered(c *gin.Context) {
 	}
 
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get message ID from URL
 	messageIDStr := c.Param("id")
 	messageID, err := uuid.Parse(messageIDStr)
@@ -1203,18 +1568,12 @@ func (h *MessageHandlers) MarkConversationDelivered(c *gin.Context) {
 	}
 
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get conversation ID from URL
 	conversationIDStr := c.Param("id")
 	conversationID, err := uuid.Parse(conversationIDStr)
@@ -1247,18 +1606,12 @@ func (h *MessageHandlers) MarkMessageReadEndpoint(c *gin.Context) {
 	}
 
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get message ID from URL
 	messageIDStr := c.Param("id")
 	messageID, err := uuid.Parse(messageIDStr)
@@ -1289,18 +1642,12 @@ func (h *MessageHandlers) MarkMessageReadEndpoint(c *gin.Context) {
 // Stores the current user's public key for the conversation
 func (h *MessageHandlers) ExchangePublicKey(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get conversation ID
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -1319,8 +1666,7 @@ func (h *MessageHandlers) ExchangePublicKey(c *gin.Context) {
 	var req struct {
 		PublicKey string `json:"public_key" binding:"required"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: public_key is required"})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -1339,21 +1685,16 @@ func (h *MessageHandlers) ExchangePublicKey(c *gin.Context) {
 }
 
 // GetConversationPublicKey handles GET /api/v1/conversations/:id/keys
-// Retrieves the other user's public key for the conversation
+// Retrieves the other user's public key for the conversation. Groups have no single
+// other user, so ?user_id= picks the member.
 func (h *MessageHandlers) GetConversationPublicKey(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get conversation ID
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -1370,10 +1711,14 @@ func (h *MessageHandlers) GetConversationPublicKey(c *gin.Context) {
 
 	// Determine the other user's ID
 	var otherUserID uuid.UUID
-	if conversation.Participant1ID == uid {
-		otherUserID = conversation.Participant2ID
+	if conversation.IsGroup {
+		otherUserID, err = uuid.Parse(c.Query("user_id"))
+		if err != nil || !conversation.IsParticipant(otherUserID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a member of this group"})
+			return
+		}
 	} else {
-		otherUserID = conversation.Participant1ID
+		otherUserID = conversation.OtherParticipantIDs(uid)[0]
 	}
 
 	// Get the other user's public key
@@ -1395,18 +1740,12 @@ func (h *MessageHandlers) GetConversationPublicKey(c *gin.Context) {
 // Generates a signed URL for a file attachment with authorization checks
 func (h *MessageHandlers) GetFileSignedURL(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get message ID from URL
 	messageID, err := uuid.Parse(c.Param("messageId"))
 	if err != nil {
@@ -2,12 +2,25 @@ package messaging
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
+	"image/png"
 	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/utils"
 
 	"github.com/disintegration/imaging"
+	"github.com/google/uuid"
 )
 
 // MediaOptimizer handles media file optimization
@@ -20,8 +33,21 @@ type MediaOptimizer struct {
 	standardQuality   int
 	hdQuality         int
 	thumbnailSize     int
+
+	// Upload types whose images have EXIF/GPS metadata stripped
+	stripMetadata map[string]bool
+
+	// ffmpeg binary used to decode audio and encode WebP; empty when it isn't installed
+	ffmpegPath string
 }
 
+// Upload types used to decide whether image metadata is stripped
+const (
+	UploadTypePost    = "posts"
+	UploadTypeMessage = "messages"
+	UploadTypeStatus  = "statuses"
+)
+
 // NewMediaOptimizer creates a new media optimizer with default settings
 func NewMediaOptimizer() *MediaOptimizer {
 	return &MediaOptimizer{
@@ -32,9 +58,40 @@ func NewMediaOptimizer() *MediaOptimizer {
 		standardQuality:   85,  // JPEG quality
 		hdQuality:         95,  // JPEG quality
 		thumbnailSize:     150, // 150x150 thumbnail
+		stripMetadata: map[string]bool{
+			UploadTypePost:    true,
+			UploadTypeMessage: true,
+			UploadTypeStatus:  true,
+		},
+		ffmpegPath: lookupFFmpeg(),
 	}
 }
 
+func lookupFFmpeg() string {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		log.Println("[MediaOptimizer] WARNING: ffmpeg not found, voice messages will have no waveform and images no resized variants")
+		return ""
+	}
+	return path
+}
+
+// SetMetadataStripping sets which upload types have image metadata stripped.
+// Upload types not listed keep their original EXIF data.
+func (m *MediaOptimizer) SetMetadataStripping(uploadTypes []string) {
+	m.stripMetadata = make(map[string]bool, len(uploadTypes))
+	for _, t := range uploadTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			m.stripMetadata[t] = true
+		}
+	}
+}
+
+// ShouldStripMetadata reports whether images of the given upload type have metadata stripped
+func (m *MediaOptimizer) ShouldStripMetadata(uploadType string) bool {
+	return m.stripMetadata[uploadType]
+}
+
 // OptimizeImageQuality represents the quality level for image optimization
 type OptimizeImageQuality string
 
@@ -43,13 +100,36 @@ const (
 	QualityHD       OptimizeImageQuality = "hd"
 )
 
-// OptimizeImage resizes and compresses an image
+// OptimizeImage resizes and compresses an image.
+// Re-encoding always drops EXIF metadata; orientation is applied to the pixels first.
 func (m *MediaOptimizer) OptimizeImage(reader io.Reader, quality OptimizeImageQuality) ([]byte, string, error) {
-	// Decode image
-	img, format, err := image.Decode(reader)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	return m.optimizeImage(data, quality, true)
+}
+
+// OptimizeUploadImage optimizes an uploaded image, stripping EXIF/GPS metadata
+// unless stripping is disabled for uploadType (then the original EXIF block is kept)
+func (m *MediaOptimizer) OptimizeUploadImage(data []byte, quality OptimizeImageQuality, uploadType string) ([]byte, string, error) {
+	return m.optimizeImage(data, quality, m.ShouldStripMetadata(uploadType))
+}
+
+func (m *MediaOptimizer) optimizeImage(data []byte, quality OptimizeImageQuality, stripMetadata bool) ([]byte, string, error) {
+	// Decode image. When stripping, bake the EXIF orientation into the pixels since the tag is lost.
+	var img image.Image
+	var format string
+	var err error
+	if stripMetadata {
+		img, format, err = utils.DecodeImageOriented(data)
+	} else {
+		img, format, err = image.Decode(bytes.NewReader(data))
+	}
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to decode image: %w", err)
 	}
+	srcFormat := format
 
 	// Determine target dimensions and quality
 	var maxWidth, maxHeight, jpegQuality int
@@ -96,6 +176,11 @@ func (m *MediaOptimizer) OptimizeImage(reader io.Reader, quality OptimizeImageQu
 		return nil, "", fmt.Errorf("failed to encode image: %w", err)
 	}
 
+	if !stripMetadata && srcFormat == "jpeg" {
+		// The encoder never writes EXIF, so carry the original block (including orientation) over
+		return utils.InsertJPEGExif(buf.Bytes(), utils.ExtractJPEGExif(data)), format, nil
+	}
+
 	return buf.Bytes(), format, nil
 }
 
@@ -121,8 +206,7 @@ func (m *MediaOptimizer) GenerateThumbnail(reader io.Reader) ([]byte, error) {
 
 // OptimizeImageFromBytes optimizes image from byte array
 func (m *MediaOptimizer) OptimizeImageFromBytes(data []byte, quality OptimizeImageQuality) ([]byte, string, error) {
-	reader := bytes.NewReader(data)
-	return m.OptimizeImage(reader, quality)
+	return m.optimizeImage(data, quality, true)
 }
 
 // ValidateImage checks if the data is a valid image
@@ -157,6 +241,107 @@ func (m *MediaOptimizer) ConvertToWebP(reader io.Reader) ([]byte, error) {
 	return data, err
 }
 
+// ============================================
+// IMAGE VARIANTS
+// ============================================
+
+// DefaultVariantSizes are the small/medium/large sizes (longest edge, in pixels)
+// generated for uploaded post images
+var DefaultVariantSizes = []int{320, 640, 1280}
+
+// variantTimeout bounds encoding one variant
+const variantTimeout = 15 * time.Second
+
+// CanGenerateVariants reports whether ffmpeg is available to encode image variants
+func (m *MediaOptimizer) CanGenerateVariants() bool {
+	return m.ffmpegPath != ""
+}
+
+// GenerateVariants resizes img to each of the given sizes (longest edge, aspect
+// ratio preserved), encodes each as WebP and uploads it next to the original at
+// filePath (see utils.ImageVariantPath), counting it towards userID's storage quota.
+// Sizes at or above the original dimensions are skipped so images are never upscaled.
+// Returns a map of size -> uploaded URL.
+func (m *MediaOptimizer) GenerateVariants(ctx context.Context, storage *utils.StorageService, userID uuid.UUID, bucket, filePath string, img image.Image, sizes []int) (map[int]string, error) {
+	variants := make(map[int]string, len(sizes))
+	if img == nil || storage == nil {
+		return variants, nil
+	}
+	if m.ffmpegPath == "" {
+		return variants, errNoWebPEncoder
+	}
+
+	bounds := img.Bounds()
+	longestEdge := bounds.Dx()
+	if bounds.Dy() > longestEdge {
+		longestEdge = bounds.Dy()
+	}
+
+	for _, size := range sizes {
+		if size <= 0 || size >= longestEdge {
+			continue
+		}
+		if _, done := variants[size]; done {
+			continue
+		}
+
+		resized := imaging.Fit(img, size, size, imaging.Lanczos)
+		data, err := m.encodeWebP(ctx, resized)
+		if err != nil {
+			return variants, fmt.Errorf("failed to encode %dpx variant: %w", size, err)
+		}
+
+		url, _, err := storage.UploadUserFile(ctx, userID, bucket, utils.ImageVariantPath(filePath, size), data, "image/webp")
+		if err != nil {
+			return variants, fmt.Errorf("failed to upload %dpx variant: %w", size, err)
+		}
+		variants[size] = url
+	}
+
+	return variants, nil
+}
+
+// GenerateVariantsFromBytes decodes data and generates variants for it.
+// Data that is not a decodable image (video, audio, documents) is skipped
+// and yields an empty map without an error.
+func (m *MediaOptimizer) GenerateVariantsFromBytes(ctx context.Context, storage *utils.StorageService, userID uuid.UUID, bucket, filePath string, data []byte, sizes []int) (map[int]string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return map[int]string{}, nil
+	}
+	return m.GenerateVariants(ctx, storage, userID, bucket, filePath, img, sizes)
+}
+
+// errNoWebPEncoder is returned for variants on a server without ffmpeg, which does the
+// WebP encoding the standard library can't
+var errNoWebPEncoder = errors.New("ffmpeg not installed, can't encode WebP")
+
+// encodeWebP encodes img as a lossy WebP at the standard quality. It's handed to
+// ffmpeg as PNG, so nothing is lost on the way.
+func (m *MediaOptimizer) encodeWebP(ctx context.Context, img image.Image) ([]byte, error) {
+	var input bytes.Buffer
+	if err := png.Encode(&input, img); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, variantTimeout)
+	defer cancel()
+
+	var output, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-f", "png_pipe", "-i", "pipe:0",
+		"-c:v", "libwebp", "-quality", fmt.Sprint(m.standardQuality),
+		"-f", "webp", "pipe:1")
+	cmd.Stdin = &input
+	cmd.Stdout = &output
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output.Bytes(), nil
+}
+
 // ============================================
 // AUDIO PROCESSING (PLACEHOLDER)
 // ============================================
@@ -210,6 +395,114 @@ func (m *MediaOptimizer) GetAudioDuration(data []byte) (float64, error) {
 	return 0, fmt.Errorf("audio duration detection not yet implemented")
 }
 
+// Voice message waveforms are WaveformBuckets bars. Audio is decoded to mono at
+// waveformSampleRate, which is plenty for the shape of speech.
+const (
+	WaveformBuckets    = 50
+	waveformSampleRate = 8000
+	waveformTimeout    = 15 * time.Second
+)
+
+// ExtractWaveform decodes audio (m4a, ogg, webm, mp3 - anything ffmpeg reads) and
+// returns the peak amplitude of each of WaveformBuckets equal slices, scaled so the
+// loudest is 1. The message plays fine without a waveform, so audio that can't be
+// decoded, or a server without ffmpeg, gets an empty one instead of an error.
+func (m *MediaOptimizer) ExtractWaveform(ctx context.Context, data []byte) []float64 {
+	if m.ffmpegPath == "" {
+		return []float64{}
+	}
+
+	samples, err := m.decodeAudio(ctx, data)
+	if err != nil {
+		log.Printf("[MediaOptimizer] Failed to decode audio for waveform: %v", err)
+		return []float64{}
+	}
+	return waveformPeaks(samples, WaveformBuckets)
+}
+
+// decodeAudio decodes audio to mono 16-bit PCM. The input goes through a temporary
+// file rather than a pipe: m4a files often keep their index at the end, which ffmpeg
+// can only reach by seeking.
+func (m *MediaOptimizer) decodeAudio(ctx context.Context, data []byte) ([]int16, error) {
+	input, err := os.CreateTemp("", "waveform-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(input.Name())
+
+	_, err = input.Write(data)
+	if closeErr := input.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, waveformTimeout)
+	defer cancel()
+
+	var pcm, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, m.ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-i", input.Name(),
+		"-vn", "-ac", "1", "-ar", fmt.Sprint(waveformSampleRate),
+		"-f", "s16le", "pipe:1")
+	cmd.Stdout = &pcm
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	samples := make([]int16, pcm.Len()/2)
+	if err := binary.Read(&pcm, binary.LittleEndian, samples); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// waveformPeaks splits samples into buckets slices and returns each slice's peak,
+// scaled so the loudest slice is 1 and rounded to two decimals to keep messages
+// small. Audio too short to fill every bucket has no waveform.
+func waveformPeaks(samples []int16, buckets int) []float64 {
+	if len(samples) < buckets {
+		return []float64{}
+	}
+
+	peaks := make([]float64, buckets)
+	loudest := 0.0
+	for i := range peaks {
+		start := i * len(samples) / buckets
+		end := (i + 1) * len(samples) / buckets
+		for _, sample := range samples[start:end] {
+			peaks[i] = math.Max(peaks[i], math.Abs(float64(sample)))
+		}
+		loudest = math.Max(loudest, peaks[i])
+	}
+
+	// Silence stays flat
+	if loudest == 0 {
+		return peaks
+	}
+	for i := range peaks {
+		peaks[i] = math.Round(peaks[i]/loudest*100) / 100
+	}
+	return peaks
+}
+
+// ValidWaveform reports whether a client-supplied waveform looks like one
+// ExtractWaveform made
+func ValidWaveform(peaks []float64) bool {
+	if len(peaks) > WaveformBuckets {
+		return false
+	}
+	for _, peak := range peaks {
+		if math.IsNaN(peak) || peak < 0 || peak > 1 {
+			return false
+		}
+	}
+	return true
+}
+
 // ============================================
 // FILE SIZE LIMITS
 // ============================================
@@ -0,0 +1,92 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"histeeria-backend/internal/config"
+	"histeeria-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// variantStorage fakes Supabase storage and the usage RPCs, recording uploads by path
+type variantStorage struct {
+	uploads  map[string]string // Path -> content type
+	recorded int64
+}
+
+func newVariantStorage(t *testing.T) (*utils.StorageService, *variantStorage) {
+	t.Helper()
+	fake := &variantStorage{uploads: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/rest/v1/rpc/reserve_storage_usage":
+			var body struct {
+				Bytes int64 `json:"p_bytes"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			fake.recorded += body.Bytes
+			w.Write([]byte(`[{"reserved": true, "used_bytes": 0}]`))
+		case r.URL.Path == "/rest/v1/rpc/increment_storage_usage":
+			var body struct {
+				Delta int64 `json:"p_delta"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			fake.recorded += body.Delta
+			w.Write([]byte("0"))
+		case r.Method == http.MethodPost:
+			fake.uploads[strings.TrimPrefix(r.URL.Path, "/storage/v1/object/media/")] = r.Header.Get("Content-Type")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return utils.NewStorageService(&config.StorageConfig{BucketName: "media", UserQuotaBytes: 1 << 20}, server.URL, "key"), fake
+}
+
+func TestGenerateVariantsUploadsWebPAgainstTheQuota(t *testing.T) {
+	// Stands in for ffmpeg, answering every encode with the same bytes
+	ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte("#!/bin/sh\ncat >/dev/null\nprintf RIFFWEBP\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m := NewMediaOptimizer()
+	m.ffmpegPath = ffmpeg
+
+	storage, fake := newVariantStorage(t)
+	userID := uuid.New()
+	filePath := "posts/" + userID.String() + "/a.jpeg"
+
+	variants, err := m.GenerateVariants(context.Background(), storage, userID, "media", filePath, image.NewRGBA(image.Rect(0, 0, 800, 600)), []int{320, 640, 1280})
+	if err != nil {
+		t.Fatalf("GenerateVariants: %v", err)
+	}
+	if len(variants) != 2 {
+		t.Errorf("variants = %v, want 320 and 640 but no upscaled 1280", variants)
+	}
+	for _, size := range []int{320, 640} {
+		if got := fake.uploads[utils.ImageVariantPath(filePath, size)]; got != "image/webp" {
+			t.Errorf("%dpx variant uploaded as %q, want image/webp", size, got)
+		}
+	}
+	if fake.recorded != 2*int64(len("RIFFWEBP")) {
+		t.Errorf("recorded %d bytes of usage, want both variants", fake.recorded)
+	}
+}
+
+func TestGenerateVariantsWithoutFFmpegUploadsNothing(t *testing.T) {
+	m := NewMediaOptimizer()
+	m.ffmpegPath = ""
+	storage, fake := newVariantStorage(t)
+
+	variants, err := m.GenerateVariants(context.Background(), storage, uuid.New(), "media", "posts/a.jpeg", image.NewRGBA(image.Rect(0, 0, 800, 600)), DefaultVariantSizes)
+	if err == nil || len(variants) != 0 || len(fake.uploads) != 0 {
+		t.Errorf("got %v, %v with uploads %v; want an error and no JPEG fallback", variants, err, fake.uploads)
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"histeeria-backend/internal/cache"
 	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
 	"histeeria-backend/internal/websocket"
+	"histeeria-backend/pkg/errors"
 
 	"github.com/google/uuid"
 )
@@ -27,8 +32,14 @@ type MessagingService struct {
 	wsManager    *websocket.Manager
 	userRepo     repository.UserRepository
 	notifService NotificationService
+	maxPinned    int                   // Pinned messages per conversation, 0 for no limit
+	attachments  *utils.StorageService // Deletes the media of expired messages; nil leaves it in place
+	deliveries   *DeliveryService      // Retries deliveries that fail; nil only logs them
 	// E2EE public key storage (conversationID:userID -> publicKey)
 	publicKeys sync.Map
+	// Typing indicators started through this instance, stopped if not refreshed
+	typing   map[typingKey]*typingWatch
+	typingMu sync.Mutex
 }
 
 // NewMessagingService creates a new messaging service
@@ -45,6 +56,7 @@ func NewMessagingService(
 		wsManager:    wsManager,
 		userRepo:     userRepo,
 		notifService: notifService,
+		typing:       make(map[typingKey]*typingWatch),
 	}
 }
 
@@ -53,6 +65,32 @@ func (s *MessagingService) SetNotificationService(notificationService Notificati
 	s.notifService = notificationService
 }
 
+// SetAttachmentStorage sets the storage chat attachments are uploaded to, so messages
+// that disappear take their media with them
+func (s *MessagingService) SetAttachmentStorage(attachments *utils.StorageService) {
+	s.attachments = attachments
+}
+
+// SetDeliveryService sets the delivery service, which delivers scheduled messages
+// and retries marking messages delivered when it fails
+func (s *MessagingService) SetDeliveryService(deliveries *DeliveryService) {
+	s.deliveries = deliveries
+}
+
+// SetMaxPinnedMessages caps how many messages a conversation can have pinned. 0
+// removes the cap.
+func (s *MessagingService) SetMaxPinnedMessages(limit int) {
+	s.maxPinned = limit
+}
+
+// InvalidateConversations drops userID's cached conversation list
+func (s *MessagingService) InvalidateConversations(ctx context.Context, userID uuid.UUID) error {
+	if s.cache == nil {
+		return nil
+	}
+	return s.cache.InvalidateUserConversations(ctx, userID)
+}
+
 // ============================================
 // CONVERSATIONS
 // ============================================
@@ -73,23 +111,11 @@ func (s *MessagingService) GetConversations(ctx context.Context, userID uuid.UUI
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversations: %w", err)
 	}
+	conversations = s.withoutBlocked(ctx, userID, conversations)
 
-	// Enrich with presence information - use WebSocket manager for real-time status
-	for _, conv := range conversations {
-		if conv.OtherUser != nil {
-			// Check if user is currently connected via WebSocket
-			isOnline := s.wsManager.IsUserConnected(conv.OtherUser.ID)
-			conv.IsOnline = isOnline
-
-			// Get last seen from cache if offline
-			if !isOnline && s.cache != nil {
-				_, lastSeen, _ := s.cache.GetUserPresence(ctx, conv.OtherUser.ID)
-				if !lastSeen.IsZero() {
-					conv.LastSeen = &lastSeen
-				}
-			}
-		}
-	}
+	// Enrich with presence information - from the shared presence store, so users
+	// connected to other instances show online too
+	s.setConversationPresence(ctx, conversations)
 
 	// Cache for next time (only first page)
 	if offset == 0 && s.cache != nil {
@@ -107,52 +133,36 @@ func (s *MessagingService) GetConversation(ctx context.Context, conversationID,
 	}
 
 	// Verify user is participant
-	if conversation.Participant1ID != userID && conversation.Participant2ID != userID {
+	if !conversation.IsParticipant(userID) {
 		return nil, fmt.Errorf("user is not a participant in this conversation")
 	}
 
 	// Set other user
-	if conversation.Participant1ID == userID {
-		conversation.OtherUser = conversation.Participant2
-		conversation.UnreadCount = conversation.UnreadCountP1
-	} else {
-		conversation.OtherUser = conversation.Participant1
-		conversation.UnreadCount = conversation.UnreadCountP2
-	}
+	conversation.SetViewer(userID)
 
-	// Get presence - use WebSocket manager for real-time status
-	if conversation.OtherUser != nil {
-		// Check if user is currently connected via WebSocket
-		isOnline := s.wsManager.IsUserConnected(conversation.OtherUser.ID)
-		conversation.IsOnline = isOnline
-
-		// Get last seen from cache if offline
-		if !isOnline && s.cache != nil {
-			_, lastSeen, _ := s.cache.GetUserPresence(ctx, conversation.OtherUser.ID)
-			if !lastSeen.IsZero() {
-				conversation.LastSeen = &lastSeen
-			}
-		}
-	}
+	// Get presence
+	s.setConversationPresence(ctx, []*models.Conversation{conversation})
 
 	return conversation, nil
 }
 
 // StartConversation creates or retrieves a conversation with another user
 func (s *MessagingService) StartConversation(ctx context.Context, user1ID, user2ID uuid.UUID) (*models.Conversation, error) {
+	blocked, err := s.isBlockedEitherWay(ctx, user1ID, user2ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return nil, errors.ErrCannotMessageUser
+	}
+
 	conversation, err := s.repo.GetOrCreateConversation(ctx, user1ID, user2ID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set other user
-	if conversation.Participant1ID == user1ID {
-		conversation.OtherUser = conversation.Participant2
-		conversation.UnreadCount = conversation.UnreadCountP1
-	} else {
-		conversation.OtherUser = conversation.Participant1
-		conversation.UnreadCount = conversation.UnreadCountP2
-	}
+	conversation.SetViewer(user1ID)
 
 	// Invalidate cache
 	if s.cache != nil {
@@ -177,31 +187,521 @@ func (s *MessagingService) GetUnreadCount(ctx context.Context, userID uuid.UUID)
 	return s.repo.GetUnreadCount(ctx, userID)
 }
 
+// ============================================
+// GROUPS
+// ============================================
+
+const (
+	maxGroupMembers    = 256 // Including the creator
+	maxGroupNameLength = 100
+)
+
+// CreateGroup creates a group conversation with creatorID as its admin
+func (s *MessagingService) CreateGroup(ctx context.Context, creatorID uuid.UUID, name string, memberIDs []uuid.UUID) (*models.Conversation, error) {
+	name, err := groupName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs = distinctUserIDs(memberIDs, creatorID)
+	if len(memberIDs) == 0 {
+		return nil, errors.ErrGroupNeedsMembers
+	}
+	if len(memberIDs)+1 > maxGroupMembers {
+		return nil, errors.ErrGroupFull
+	}
+	if err := s.checkUsersExist(ctx, memberIDs); err != nil {
+		return nil, err
+	}
+
+	conversation, err := s.repo.CreateGroupConversation(ctx, creatorID, name, memberIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	// Gives the group a first message, so it shows up in everyone's list
+	s.postSystemMessage(ctx, conversation, creatorID, fmt.Sprintf("%s created the group \"%s\"", s.displayName(ctx, creatorID), name))
+
+	conversation.SetViewer(creatorID)
+	log.Printf("[Messaging] Group %s created by %s with %d members", conversation.ID, creatorID, len(memberIDs)+1)
+	return conversation, nil
+}
+
+// AddGroupMembers adds users to a group. Only admins can add members; users already
+// in the group are skipped.
+func (s *MessagingService) AddGroupMembers(ctx context.Context, conversationID, actorID uuid.UUID, userIDs []uuid.UUID) (*models.Conversation, error) {
+	conversation, err := s.groupForAdmin(ctx, conversationID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	toAdd := make([]uuid.UUID, 0, len(userIDs))
+	for _, id := range distinctUserIDs(userIDs, actorID) {
+		if !conversation.IsParticipant(id) {
+			toAdd = append(toAdd, id)
+		}
+	}
+	if len(toAdd) == 0 {
+		conversation.SetViewer(actorID)
+		return conversation, nil
+	}
+
+	if len(conversation.OtherParticipantIDs(actorID))+1+len(toAdd) > maxGroupMembers {
+		return nil, errors.ErrGroupFull
+	}
+	if err := s.checkUsersExist(ctx, toAdd); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddConversationMembers(ctx, conversationID, toAdd); err != nil {
+		return nil, fmt.Errorf("failed to add members: %w", err)
+	}
+
+	names := make([]string, 0, len(toAdd))
+	for _, id := range toAdd {
+		names = append(names, s.displayName(ctx, id))
+	}
+
+	return s.groupChanged(ctx, conversationID, actorID, nil,
+		fmt.Sprintf("%s added %s", s.displayName(ctx, actorID), strings.Join(names, ", ")))
+}
+
+// RemoveGroupMember takes userID out of a group. Admins can remove anyone; any
+// member can remove themselves, which is how they leave. If the last admin leaves,
+// the longest-standing remaining member becomes admin.
+func (s *MessagingService) RemoveGroupMember(ctx context.Context, conversationID, actorID, userID uuid.UUID) error {
+	conversation, err := s.repo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	if !conversation.IsGroup {
+		return errors.ErrNotGroupConversation
+	}
+	if !conversation.IsParticipant(actorID) {
+		return fmt.Errorf("user is not a participant in this conversation")
+	}
+
+	target := conversation.Member(userID)
+	if target == nil || !target.IsActive() {
+		return errors.ErrNotGroupMember
+	}
+	if actorID != userID && !conversation.Member(actorID).IsAdmin() {
+		return errors.ErrNotGroupAdmin
+	}
+
+	if err := s.repo.RemoveConversationMember(ctx, conversationID, userID); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	if target.IsAdmin() {
+		s.promoteIfNoAdmin(ctx, conversation, userID)
+	}
+
+	if s.cache != nil {
+		s.cache.ResetUnread(ctx, conversationID, userID)
+	}
+
+	text := fmt.Sprintf("%s left", s.displayName(ctx, userID))
+	if actorID != userID {
+		text = fmt.Sprintf("%s removed %s", s.displayName(ctx, actorID), s.displayName(ctx, userID))
+	}
+
+	_, err = s.groupChanged(ctx, conversationID, actorID, []uuid.UUID{userID}, text)
+	return err
+}
+
+// RenameGroup changes a group's name. Only admins can rename a group.
+func (s *MessagingService) RenameGroup(ctx context.Context, conversationID, actorID uuid.UUID, name string) (*models.Conversation, error) {
+	name, err := groupName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.groupForAdmin(ctx, conversationID, actorID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RenameConversation(ctx, conversationID, name); err != nil {
+		return nil, fmt.Errorf("failed to rename group: %w", err)
+	}
+
+	return s.groupChanged(ctx, conversationID, actorID, nil,
+		fmt.Sprintf("%s renamed the group to \"%s\"", s.displayName(ctx, actorID), name))
+}
+
+// GetMessageReceipts returns how far a message has got with each recipient. Only the
+// sender can see them.
+func (s *MessagingService) GetMessageReceipts(ctx context.Context, messageID, userID uuid.UUID) ([]*models.MessageReceipt, error) {
+	message, err := s.repo.GetMessage(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("message not found: %w", err)
+	}
+	if message.SenderID != userID {
+		return nil, errors.ErrForbidden
+	}
+
+	conversation, err := s.repo.GetConversation(ctx, message.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found: %w", err)
+	}
+
+	if !conversation.IsGroup {
+		receipts := make([]*models.MessageReceipt, 0, 1)
+		for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+			receipts = append(receipts, &models.MessageReceipt{
+				UserID:      otherUserID,
+				Status:      message.Status,
+				DeliveredAt: message.DeliveredAt,
+				ReadAt:      message.ReadAt,
+			})
+		}
+		return receipts, nil
+	}
+
+	// Everyone who was in the group for some of the message's life: members who
+	// joined later never got it, and members who left before it was sent never will
+	receipts := make([]*models.MessageReceipt, 0, len(conversation.Members))
+	for _, member := range conversation.Members {
+		if member.UserID == userID || (member.LeftAt != nil && member.LeftAt.Before(message.CreatedAt)) {
+			continue
+		}
+		if receipt := member.Receipt(message); receipt != nil {
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts, nil
+}
+
+// groupForAdmin loads a group and checks that actorID is one of its admins
+func (s *MessagingService) groupForAdmin(ctx context.Context, conversationID, actorID uuid.UUID) (*models.Conversation, error) {
+	conversation, err := s.repo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if !conversation.IsGroup {
+		return nil, errors.ErrNotGroupConversation
+	}
+	if !conversation.IsParticipant(actorID) {
+		return nil, fmt.Errorf("user is not a participant in this conversation")
+	}
+	if !conversation.Member(actorID).IsAdmin() {
+		return nil, errors.ErrNotGroupAdmin
+	}
+	return conversation, nil
+}
+
+// promoteIfNoAdmin makes the longest-standing member an admin when the group has
+// none left after leftID went
+func (s *MessagingService) promoteIfNoAdmin(ctx context.Context, conversation *models.Conversation, leftID uuid.UUID) {
+	var successor *models.ConversationMember
+	for _, m := range conversation.Members { // Oldest first
+		if m.UserID == leftID || !m.IsActive() {
+			continue
+		}
+		if m.IsAdmin() {
+			return
+		}
+		if successor == nil {
+			successor = m
+		}
+	}
+	if successor == nil {
+		return
+	}
+
+	if err := s.repo.SetConversationMemberRole(ctx, conversation.ID, successor.UserID, models.ConversationRoleAdmin); err != nil {
+		log.Printf("[Messaging] Failed to promote %s in group %s: %v", successor.UserID, conversation.ID, err)
+	}
+}
+
+// groupChanged announces a change to a group: a system message from actorID, and a
+// group_updated event with the new member list to every member and to removed.
+// Returns the group as actorID sees it.
+func (s *MessagingService) groupChanged(ctx context.Context, conversationID, actorID uuid.UUID, removed []uuid.UUID, text string) (*models.Conversation, error) {
+	conversation, err := s.repo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.postSystemMessage(ctx, conversation, actorID, text)
+
+	notify := append(conversation.OtherParticipantIDs(uuid.Nil), removed...) // Excluding no one: every current member
+	for _, userID := range notify {
+		if s.cache != nil {
+			s.cache.InvalidateUserConversations(ctx, userID)
+		}
+		go s.broadcastGroupUpdated(userID, conversation)
+	}
+
+	conversation.SetViewer(actorID)
+	return conversation, nil
+}
+
+// postSystemMessage records a group event as a system message and fans it out.
+// Failures are logged; the event itself has already happened.
+func (s *MessagingService) postSystemMessage(ctx context.Context, conversation *models.Conversation, actorID uuid.UUID, text string) {
+	message := &models.Message{
+		ConversationID: conversation.ID,
+		SenderID:       actorID,
+		Content:        text,
+		MessageType:    models.MessageTypeSystem,
+		Status:         models.MessageStatusSent,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := s.repo.CreateMessage(ctx, message); err != nil {
+		log.Printf("[Messaging] Failed to post system message in %s: %v", conversation.ID, err)
+		return
+	}
+	if s.cache != nil {
+		s.cache.TouchLastSeen(ctx, actorID)
+	}
+	s.fanOut(ctx, conversation, message)
+}
+
+// checkUsersExist returns ErrUserNotFound if any of userIDs isn't a user
+func (s *MessagingService) checkUsersExist(ctx context.Context, userIDs []uuid.UUID) error {
+	for _, id := range userIDs {
+		if _, err := s.userRepo.GetUserByID(ctx, id); err != nil {
+			return errors.ErrUserNotFound
+		}
+	}
+	return nil
+}
+
+// displayName returns a user's display name for system messages
+func (s *MessagingService) displayName(ctx context.Context, userID uuid.UUID) string {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user.DisplayName == "" {
+		return "Someone"
+	}
+	return user.DisplayName
+}
+
+// groupName trims and checks a group name
+func groupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxGroupNameLength {
+		return "", errors.ErrInvalidGroupName
+	}
+	return name, nil
+}
+
+// distinctUserIDs drops duplicates and exclude from ids, keeping their order
+func distinctUserIDs(ids []uuid.UUID, exclude uuid.UUID) []uuid.UUID {
+	seen := map[uuid.UUID]bool{exclude: true, uuid.Nil: true}
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// ============================================
+// DISAPPEARING MESSAGES
+// ============================================
+
+// Bounds on the disappearing message timer
+const (
+	minMessageTTL = time.Minute
+	maxMessageTTL = 90 * 24 * time.Hour
+)
+
+// SetDisappearingMessages makes new messages in a conversation disappear ttl after
+// they're sent, or turns that off with a ttl of 0. Messages already sent keep their
+// timer. Either side of a 1-on-1 chat can change it; in a group only admins can.
+func (s *MessagingService) SetDisappearingMessages(ctx context.Context, conversationID, actorID uuid.UUID, ttl time.Duration) (*models.Conversation, error) {
+	if ttl != 0 && (ttl < minMessageTTL || ttl > maxMessageTTL) {
+		return nil, errors.ErrInvalidMessageTTL
+	}
+
+	conversation, err := s.repo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if !conversation.IsParticipant(actorID) {
+		return nil, fmt.Errorf("user is not a participant in this conversation")
+	}
+	if conversation.IsGroup && !conversation.Member(actorID).IsAdmin() {
+		return nil, errors.ErrNotGroupAdmin
+	}
+
+	var ttlSeconds *int
+	if ttl > 0 {
+		seconds := int(ttl / time.Second)
+		ttlSeconds = &seconds
+	}
+	if sameTTL(conversation.MessageTTLSeconds, ttlSeconds) {
+		conversation.SetViewer(actorID)
+		return conversation, nil
+	}
+
+	if err := s.repo.SetConversationMessageTTL(ctx, conversationID, ttlSeconds); err != nil {
+		return nil, fmt.Errorf("failed to set disappearing messages: %w", err)
+	}
+	conversation.MessageTTLSeconds = ttlSeconds
+
+	// Starred messages outlive the timer, so say so where everyone will see it
+	text := fmt.Sprintf("%s turned off disappearing messages", s.displayName(ctx, actorID))
+	if ttlSeconds != nil {
+		text = fmt.Sprintf("%s turned on disappearing messages. New messages will disappear %s after they're sent. Starred messages are kept until they're unstarred.",
+			s.displayName(ctx, actorID), formatTTL(ttl))
+	}
+	s.postSystemMessage(ctx, conversation, actorID, text)
+
+	for _, userID := range append(conversation.OtherParticipantIDs(actorID), actorID) {
+		if s.cache != nil {
+			s.cache.InvalidateUserConversations(ctx, userID)
+		}
+		go s.broadcastSettingsUpdated(userID, conversation)
+	}
+
+	conversation.SetViewer(actorID)
+	return conversation, nil
+}
+
+// PurgeExpiredMessages permanently deletes messages whose disappearing timer has run
+// out, with their attachments, batchSize messages at a time, and returns how many
+// were deleted. Starred messages are skipped, and a message whose attachment can't
+// be deleted is kept for the next run so the file isn't orphaned.
+func (s *MessagingService) PurgeExpiredMessages(ctx context.Context, batchSize int) (int, error) {
+	purged := 0
+
+	for ctx.Err() == nil {
+		batch, err := s.repo.GetExpiredMessages(ctx, batchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		ids := make([]uuid.UUID, 0, len(batch))
+		conversations := make(map[uuid.UUID]bool)
+		for _, message := range batch {
+			if err := s.deleteMessageMedia(ctx, message); err != nil {
+				log.Printf("[Messaging] Keeping expired message %s for the next purge, media delete failed: %v", message.ID, err)
+				continue
+			}
+			ids = append(ids, message.ID)
+			conversations[message.ConversationID] = true
+		}
+
+		count, err := s.repo.DeleteExpiredMessages(ctx, ids)
+		if err != nil {
+			return purged, err
+		}
+		purged += count
+
+		if s.cache != nil {
+			for conversationID := range conversations {
+				s.cache.InvalidateConversationCache(ctx, conversationID)
+			}
+		}
+
+		// A short batch is the last one; a batch where nothing could be purged would
+		// just come back again
+		if len(batch) < batchSize || count == 0 {
+			break
+		}
+	}
+
+	return purged, ctx.Err()
+}
+
+// deleteMessageMedia deletes a message's attachment and video thumbnail
+func (s *MessagingService) deleteMessageMedia(ctx context.Context, message *models.Message) error {
+	if s.attachments == nil {
+		return nil
+	}
+	for _, path := range []*string{message.AttachmentURL, message.ThumbnailURL} {
+		if path == nil || *path == "" {
+			continue
+		}
+		if err := s.attachments.DeleteFile(ctx, *path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameTTL reports whether two disappearing message settings are the same
+func sameTTL(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// formatTTL describes a disappearing message timer in the largest whole unit, such
+// as "24 hours" or "7 days"
+func formatTTL(ttl time.Duration) string {
+	unit, name := time.Second, "second"
+	switch {
+	case ttl%(24*time.Hour) == 0:
+		unit, name = 24*time.Hour, "day"
+	case ttl%time.Hour == 0:
+		unit, name = time.Hour, "hour"
+	case ttl%time.Minute == 0:
+		unit, name = time.Minute, "minute"
+	}
+
+	n := int(ttl / unit)
+	if n == 1 {
+		return "1 " + name
+	}
+	return fmt.Sprintf("%d %ss", n, name)
+}
+
 // ============================================
 // MESSAGES
 // ============================================
 
 // SendMessage sends a new message in a conversation
 func (s *MessagingService) SendMessage(ctx context.Context, conversationID, senderID uuid.UUID, req *models.MessageRequest) (*models.Message, error) {
-	// Get conversation to find recipient
+	// Get conversation to find recipients
 	conversation, err := s.repo.GetConversation(ctx, conversationID)
 	if err != nil {
 		return nil, fmt.Errorf("conversation not found: %w", err)
 	}
 
-	// Verify sender is participant
-	if conversation.Participant1ID != senderID && conversation.Participant2ID != senderID {
+	// Verify sender is participant. Group members who left can no longer post.
+	if !conversation.IsParticipant(senderID) {
 		return nil, fmt.Errorf("sender is not a participant in this conversation")
 	}
+	if err := s.checkNotBlocked(ctx, conversation, senderID); err != nil {
+		return nil, err
+	}
 
-	// Determine recipient
-	var recipientID uuid.UUID
-	if conversation.Participant1ID == senderID {
-		recipientID = conversation.Participant2ID
-	} else {
-		recipientID = conversation.Participant1ID
+	message, err := buildMessage(conversationID, senderID, req)
+	if err != nil {
+		return nil, err
 	}
 
+	// Save to database
+	if err := s.repo.CreateMessage(ctx, message); err != nil {
+		return nil, fmt.Errorf("failed to create message: %w", err)
+	}
+
+	// Update sender's last seen (they're active sending messages)
+	if s.cache != nil {
+		s.cache.TouchLastSeen(ctx, senderID)
+	}
+
+	recipients := s.fanOut(ctx, conversation, message)
+
+	// Set IsMine = true for sender (HTTP response)
+	message.IsMine = true
+
+	log.Printf("[Messaging] Message %s sent from %s to %d recipient(s) in conversation %s",
+		message.ID, senderID, len(recipients), conversationID)
+
+	return message, nil
+}
+
+// buildMessage turns a send request into a message ready to save, checking it has
+// something to send
+func buildMessage(conversationID, senderID uuid.UUID, req *models.MessageRequest) (*models.Message, error) {
 	// Create message - prioritize encrypted content over plaintext
 	message := &models.Message{
 		ConversationID: conversationID,
@@ -215,6 +715,16 @@ func (s *MessagingService) SendMessage(ctx context.Context, conversationID, send
 		Status:         models.MessageStatusSent,
 		CreatedAt:      time.Now(),
 	}
+
+	// The waveform comes from the audio upload; one that doesn't look like it is dropped
+	// rather than failing the message
+	if req.MessageType == models.MessageTypeAudio && len(req.Waveform) > 0 {
+		if ValidWaveform(req.Waveform) {
+			message.Waveform = req.Waveform
+		} else {
+			log.Printf("[Messaging] Ignoring invalid waveform (%d bars) from %s", len(req.Waveform), senderID)
+		}
+	}
 	
 	// Determine if message is encrypted or plaintext
 	// Check for IV first (more reliable indicator of encryption)
@@ -251,57 +761,396 @@ func (s *MessagingService) SendMessage(ctx context.Context, conversationID, send
 		log.Printf("[Messaging] ⚠️ Message sent in PLAINTEXT (no E2EE), content length: %d", len(req.Content))
 	}
 
-	// Save to database
-	if err := s.repo.CreateMessage(ctx, message); err != nil {
-		return nil, fmt.Errorf("failed to create message: %w", err)
+	return message, nil
+}
+
+// fanOut hands a saved message to everyone in the conversation: caches, WebSocket
+// pushes and delivery marks for recipients who are online, through the
+// DeliveryService so failures are retried, and notifications for those who aren't.
+// Returns the recipients. It doesn't touch the sender's presence, since system
+// messages go out without them.
+func (s *MessagingService) fanOut(ctx context.Context, conversation *models.Conversation, message *models.Message) []uuid.UUID {
+	recipients := s.recordNewMessage(ctx, conversation, message)
+
+	if s.deliveries != nil {
+		for _, recipientID := range recipients {
+			// A copy, since the sender's is changed for their response, and a context
+			// that outlives the request
+			forRecipient := *message
+			go s.deliveries.Deliver(context.Background(), &forRecipient, recipientID)
+		}
+		s.notifyRecipients(message, recipients)
+		return recipients
 	}
 
-	// Update sender's last seen (they're active sending messages)
-	if s.cache != nil {
-		s.cache.SetUserOnline(ctx, senderID)
+	// CRITICAL: Broadcast to the sender and every recipient for real-time consistency
+	// This ensures all clients see the message instantly, preventing ordering issues
+	for _, recipientID := range recipients {
+		// Broadcast to recipient (IsMine = false)
+		messageCopyForRecipient := *message
+		messageCopyForRecipient.IsMine = false
+		go s.broadcastNewMessage(recipientID, &messageCopyForRecipient)
+	}
+
+	for _, recipientID := range recipients {
+		// Mark as delivered if recipient is online. A group message is delivered to
+		// each member separately.
+		if s.wsManager.IsUserConnected(recipientID) {
+			if conversation.IsGroup {
+				go s.markAsDeliveredTo(ctx, message, recipientID)
+			} else {
+				go s.markAsDelivered(ctx, message, recipientID)
+			}
+		}
+	}
+
+	s.notifyRecipients(message, recipients)
+	return recipients
+}
 
+// recordNewMessage puts a saved message in the caches and echoes it to its sender's
+// other devices. Returns the recipients.
+func (s *MessagingService) recordNewMessage(ctx context.Context, conversation *models.Conversation, message *models.Message) []uuid.UUID {
+	senderID := message.SenderID
+	recipients := conversation.OtherParticipantIDs(senderID)
+
+	if s.cache != nil {
 		// Update cache
 		s.cache.PrependMessage(ctx, message)
-		s.cache.IncrementUnread(ctx, conversationID, recipientID)
-
-		// Invalidate conversation list cache
 		s.cache.InvalidateUserConversations(ctx, senderID)
-		s.cache.InvalidateUserConversations(ctx, recipientID)
+		for _, recipientID := range recipients {
+			s.cache.IncrementUnread(ctx, conversation.ID, recipientID)
+			s.cache.InvalidateUserConversations(ctx, recipientID)
+		}
 	}
 
-	// CRITICAL: Broadcast to BOTH sender and recipient for real-time consistency
-	// This ensures both clients see the message instantly, preventing ordering issues
-	
-	// Broadcast to recipient (IsMine = false)
-	messageCopyForRecipient := *message
-	messageCopyForRecipient.IsMine = false
-	go s.broadcastNewMessage(recipientID, &messageCopyForRecipient)
-	
 	// ALSO broadcast to sender (IsMine = true) - ensures sender sees their own message via WebSocket too
 	// This prevents the need for page reload and ensures consistency
 	messageCopyForSender := *message
 	messageCopyForSender.IsMine = true
 	go s.broadcastNewMessage(senderID, &messageCopyForSender)
 
-	// Set IsMine = true for sender (HTTP response)
-	message.IsMine = true
+	return recipients
+}
 
-	// Mark as delivered if recipient is online
-	if s.wsManager.IsUserConnected(recipientID) {
-		go s.markAsDelivered(ctx, message.ID)
+// notifyRecipients creates notifications of a new message for the recipients who
+// aren't online (async to not block)
+func (s *MessagingService) notifyRecipients(message *models.Message, recipients []uuid.UUID) {
+	if s.notifService == nil || message.MessageType == models.MessageTypeSystem {
+		return
 	}
+	for _, recipientID := range recipients {
+		go s.createMessageNotification(recipientID, message.SenderID, message)
+	}
+}
 
-	// Create notification for recipient (async to not block)
-	if s.notifService != nil {
-		go s.createMessageNotification(recipientID, senderID, message)
+// ============================================
+// SCHEDULED MESSAGES
+// ============================================
+
+// maxScheduleAhead is how far ahead a message can be scheduled
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// ScheduleMessage saves a message to be sent at req.SendAt instead of now. It's
+// checked like any other message now, and again for blocks and membership when it
+// sends.
+func (s *MessagingService) ScheduleMessage(ctx context.Context, conversationID, senderID uuid.UUID, req *models.MessageRequest) (*models.ScheduledMessage, error) {
+	if req.SendAt == nil {
+		return nil, errors.ErrInvalidSendAt
+	}
+	timezone := s.senderTimezone(ctx, senderID, req.Timezone)
+	sendAt, err := resolveSendAt(*req.SendAt, timezone)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("[Messaging] Message %s sent from %s to %s in conversation %s",
-		message.ID, senderID, recipientID, conversationID)
+	conversation, err := s.repo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found: %w", err)
+	}
+	if !conversation.IsParticipant(senderID) {
+		return nil, errors.ErrForbidden
+	}
+	if _, err := buildMessage(conversationID, senderID, req); err != nil {
+		return nil, errors.NewAppError(http.StatusBadRequest, err.Error())
+	}
+
+	scheduled := &models.ScheduledMessage{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Message:        *req,
+		SendAt:         sendAt,
+	}
+	// The time is kept on the scheduled message, and the temp ID only matters to this response
+	scheduled.Message.SendAt = nil
+	scheduled.Message.Timezone = ""
+	scheduled.Message.TempID = nil
+
+	if err := s.repo.CreateScheduledMessage(ctx, scheduled); err != nil {
+		return nil, fmt.Errorf("failed to schedule message: %w", err)
+	}
+
+	log.Printf("[Messaging] Message %s from %s scheduled for %s in conversation %s",
+		scheduled.ID, senderID, scheduled.SendAt.Format(time.RFC3339), conversationID)
+	scheduled.SendAt = utils.InUserTimezone(scheduled.SendAt, timezone)
+	return scheduled, nil
+}
+
+// GetScheduledMessages lists the messages userID has scheduled in a conversation
+// that haven't been sent, soonest first, with their times in userID's timezone.
+// Nobody else sees them.
+func (s *MessagingService) GetScheduledMessages(ctx context.Context, conversationID, userID uuid.UUID) ([]*models.ScheduledMessage, error) {
+	scheduled, err := s.repo.GetPendingScheduledMessages(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	timezone := s.senderTimezone(ctx, userID, "")
+	for _, m := range scheduled {
+		m.SendAt = utils.InUserTimezone(m.SendAt, timezone)
+	}
+	return scheduled, nil
+}
+
+// EditScheduledMessage changes the content or time of one of userID's scheduled
+// messages, as long as it hasn't started sending
+func (s *MessagingService) EditScheduledMessage(ctx context.Context, scheduledID, userID uuid.UUID, req *models.EditScheduledMessageRequest) (*models.ScheduledMessage, error) {
+	scheduled, err := s.pendingScheduledMessage(ctx, scheduledID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	timezone := s.senderTimezone(ctx, userID, req.Timezone)
+	if req.SendAt != nil {
+		sendAt, err := resolveSendAt(*req.SendAt, timezone)
+		if err != nil {
+			return nil, err
+		}
+		scheduled.SendAt = sendAt
+	}
 
+	// New encrypted content replaces the content whichever way it was sent, and the
+	// other way round
+	switch {
+	case req.EncryptedContent != nil || req.IV != nil:
+		scheduled.Message.Content = ""
+		scheduled.Message.EncryptedContent = req.EncryptedContent
+		scheduled.Message.IV = req.IV
+	case req.Content != nil:
+		scheduled.Message.Content = *req.Content
+		scheduled.Message.EncryptedContent = nil
+		scheduled.Message.IV = nil
+	}
+	if _, err := buildMessage(scheduled.ConversationID, userID, &scheduled.Message); err != nil {
+		return nil, errors.NewAppError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := s.repo.UpdateScheduledMessage(ctx, scheduled); err != nil {
+		return nil, err
+	}
+	scheduled.UpdatedAt = time.Now().UTC()
+	scheduled.SendAt = utils.InUserTimezone(scheduled.SendAt, timezone)
+	return scheduled, nil
+}
+
+// CancelScheduledMessage cancels one of userID's scheduled messages, as long as it
+// hasn't started sending
+func (s *MessagingService) CancelScheduledMessage(ctx context.Context, scheduledID, userID uuid.UUID) error {
+	if _, err := s.pendingScheduledMessage(ctx, scheduledID, userID); err != nil {
+		return err
+	}
+	return s.repo.CancelScheduledMessage(ctx, scheduledID)
+}
+
+// pendingScheduledMessage loads a scheduled message for its sender to change. Other
+// users get not found, so they can't tell it exists.
+func (s *MessagingService) pendingScheduledMessage(ctx context.Context, scheduledID, userID uuid.UUID) (*models.ScheduledMessage, error) {
+	scheduled, err := s.repo.GetScheduledMessage(ctx, scheduledID)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled.SenderID != userID {
+		return nil, errors.ErrScheduledMessageNotFound
+	}
+	if scheduled.Status != models.ScheduledMessagePending {
+		return nil, errors.ErrScheduledMessageNotPending
+	}
+	return scheduled, nil
+}
+
+// SendDueScheduledMessages sends the scheduled messages whose time has come, up to
+// batchSize at a time, and returns how many went out. They're delivered through the
+// DeliveryService, so deliveries that fail are retried like any other message's. A
+// message that can't be sent, because the sender left the conversation or a block
+// now stands between them and the recipient, is marked failed and its sender told.
+func (s *MessagingService) SendDueScheduledMessages(ctx context.Context, batchSize int) (int, error) {
+	if s.deliveries == nil {
+		return 0, fmt.Errorf("scheduled messages need the delivery service")
+	}
+	sent := 0
+
+	for ctx.Err() == nil {
+		batch, err := s.repo.ClaimDueScheduledMessages(ctx, batchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, scheduled := range batch {
+			message, err := s.sendScheduledMessage(ctx, scheduled)
+			if err != nil {
+				log.Printf("[Messaging] Scheduled message %s not sent: %v", scheduled.ID, err)
+				if err := s.repo.FinishScheduledMessage(ctx, scheduled.ID, nil, err.Error()); err != nil {
+					log.Printf("[Messaging] Failed to mark scheduled message %s failed: %v", scheduled.ID, err)
+				}
+				go s.broadcastScheduledFailed(scheduled, err.Error())
+				continue
+			}
+
+			if err := s.repo.FinishScheduledMessage(ctx, scheduled.ID, &message.ID, ""); err != nil {
+				log.Printf("[Messaging] Failed to mark scheduled message %s sent: %v", scheduled.ID, err)
+			}
+			sent++
+		}
+
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	return sent, ctx.Err()
+}
+
+// sendScheduledMessage sends a scheduled message as if its sender sent it now. Who
+// may send to whom is decided now rather than when it was scheduled.
+func (s *MessagingService) sendScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) (*models.Message, error) {
+	conversation, err := s.repo.GetConversation(ctx, scheduled.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation not found")
+	}
+	if !conversation.IsParticipant(scheduled.SenderID) {
+		return nil, fmt.Errorf("sender is no longer in this conversation")
+	}
+
+	if err := s.checkNotBlocked(ctx, conversation, scheduled.SenderID); err != nil {
+		return nil, err
+	}
+
+	message, err := buildMessage(scheduled.ConversationID, scheduled.SenderID, &scheduled.Message)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateMessage(ctx, message); err != nil {
+		return nil, fmt.Errorf("failed to create message: %w", err)
+	}
+
+	recipients := s.recordNewMessage(ctx, conversation, message)
+	pushed := 0
+	for _, recipientID := range recipients {
+		if s.deliveries.Deliver(ctx, message, recipientID) {
+			pushed++
+		}
+	}
+	s.notifyRecipients(message, recipients)
+
+	log.Printf("[Messaging] Scheduled message %s sent as %s to %d recipient(s), %d online, in conversation %s",
+		scheduled.ID, message.ID, len(recipients), pushed, scheduled.ConversationID)
 	return message, nil
 }
 
+// checkNotBlocked returns ErrCannotMessageUser if senderID and the other user of a
+// 1-on-1 conversation have blocked each other either way
+func (s *MessagingService) checkNotBlocked(ctx context.Context, conversation *models.Conversation, senderID uuid.UUID) error {
+	// Blocks stand between two people, so they stop 1-on-1 messages; groups are shared
+	// with everyone in them and don't check blocks
+	if conversation.IsGroup {
+		return nil
+	}
+	for _, recipientID := range conversation.OtherParticipantIDs(senderID) {
+		blocked, err := s.isBlockedEitherWay(ctx, senderID, recipientID)
+		if err != nil {
+			return fmt.Errorf("failed to check blocks: %w", err)
+		}
+		if blocked {
+			return errors.ErrCannotMessageUser
+		}
+	}
+	return nil
+}
+
+// withoutBlocked drops 1-on-1 conversations with anyone userID has blocked or been
+// blocked by. The messages stay in the database and come back if the block is lifted.
+func (s *MessagingService) withoutBlocked(ctx context.Context, userID uuid.UUID, conversations []*models.Conversation) []*models.Conversation {
+	if len(conversations) == 0 {
+		return conversations
+	}
+	blockedIDs, err := s.userRepo.GetBlockedEitherWayIDs(ctx, userID)
+	if err != nil {
+		log.Printf("[Messaging] Failed to get blocks of %s, not hiding conversations: %v", userID, err)
+		return conversations
+	}
+	if len(blockedIDs) == 0 {
+		return conversations
+	}
+
+	blocked := make(map[uuid.UUID]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+
+	kept := conversations[:0]
+	for _, conversation := range conversations {
+		if !conversation.IsGroup && blockedWith(conversation, userID, blocked) {
+			continue
+		}
+		kept = append(kept, conversation)
+	}
+	return kept
+}
+
+// blockedWith reports whether any other participant of conversation is in blocked
+func blockedWith(conversation *models.Conversation, userID uuid.UUID, blocked map[uuid.UUID]bool) bool {
+	for _, otherID := range conversation.OtherParticipantIDs(userID) {
+		if blocked[otherID] {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlockedEitherWay reports whether either user has blocked the other
+func (s *MessagingService) isBlockedEitherWay(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	if blocked, err := s.userRepo.IsUserBlocked(ctx, a, b); err != nil || blocked {
+		return blocked, err
+	}
+	return s.userRepo.IsUserBlocked(ctx, b, a)
+}
+
+// resolveSendAt turns a requested send time, RFC3339 or local to timezone, into the
+// UTC instant to send at. It must be in the future and within maxScheduleAhead.
+func resolveSendAt(value, timezone string) (time.Time, error) {
+	now := time.Now()
+	t, err := utils.ParseLocalTime(value, timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !t.After(now) || !t.Before(now.Add(maxScheduleAhead)) {
+		return time.Time{}, errors.ErrInvalidSendAt
+	}
+	return t, nil
+}
+
+// senderTimezone is the timezone a user's scheduled times are read and shown in:
+// the one the request names, or else the one on their profile
+func (s *MessagingService) senderTimezone(ctx context.Context, userID uuid.UUID, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return utils.DefaultTimezone
+	}
+	return user.Timezone
+}
+
 // GetMessages retrieves messages for a conversation with caching
 func (s *MessagingService) GetMessages(ctx context.Context, conversationID, userID uuid.UUID, limit, offset int) ([]*models.Message, error) {
 	// Verify user is participant
@@ -310,7 +1159,7 @@ func (s *MessagingService) GetMessages(ctx context.Context, conversationID, user
 		return nil, err
 	}
 
-	if conversation.Participant1ID != userID && conversation.Participant2ID != userID {
+	if !conversation.IsParticipant(userID) {
 		return nil, fmt.Errorf("user is not a participant in this conversation")
 	}
 
@@ -324,6 +1173,7 @@ func (s *MessagingService) GetMessages(ctx context.Context, conversationID, user
 			for _, msg := range cached {
 				msg.IsMine = msg.SenderID == userID
 			}
+			s.setReactionCounts(ctx, cached, userID)
 
 			// Mark as read in background
 			go s.markConversationAsRead(ctx, conversationID, userID)
@@ -332,26 +1182,91 @@ func (s *MessagingService) GetMessages(ctx context.Context, conversationID, user
 		}
 	}
 
-	// Fetch from database
-	messages, err := s.repo.GetConversationMessages(ctx, conversationID, limit, offset)
+	// Fetch from database
+	messages, err := s.repo.GetConversationMessages(ctx, conversationID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	// CRITICAL: Set IsMine field for ALL messages
+	for _, msg := range messages {
+		msg.IsMine = msg.SenderID == userID
+	}
+
+	// Cache if first page
+	if offset == 0 && s.cache != nil {
+		s.cache.CacheMessages(ctx, conversationID, messages)
+	}
+
+	// Counted after caching: they depend on the viewer, and reactions change more
+	// often than the messages do
+	s.setReactionCounts(ctx, messages, userID)
+
+	// Mark as read in background
+	go s.markConversationAsRead(ctx, conversationID, userID)
+
+	return messages, nil
+}
+
+// GetMessagesAfter returns up to limit messages posted in a conversation after
+// afterID, oldest first, and whether more follow. A client that reconnects calls it
+// with the last message it has to fetch what it missed while it was away.
+func (s *MessagingService) GetMessagesAfter(ctx context.Context, conversationID, userID, afterID uuid.UUID, limit int) ([]*models.Message, bool, error) {
+	conversation, err := s.repo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !conversation.IsParticipant(userID) {
+		return nil, false, errors.ErrForbidden
+	}
+
+	after, err := s.repo.GetMessage(ctx, afterID)
+	if err != nil || after.ConversationID != conversationID {
+		return nil, false, errors.ErrInvalidAfterMessage
+	}
+
+	// One extra tells whether there's another page
+	messages, err := s.repo.GetConversationMessagesAfter(ctx, conversationID, after, limit+1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get messages: %w", err)
+		return nil, false, fmt.Errorf("failed to get messages: %w", err)
+	}
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
 	}
 
-	// CRITICAL: Set IsMine field for ALL messages
 	for _, msg := range messages {
 		msg.IsMine = msg.SenderID == userID
 	}
+	s.setReactionCounts(ctx, messages, userID)
 
-	// Cache if first page
-	if offset == 0 && s.cache != nil {
-		s.cache.CacheMessages(ctx, conversationID, messages)
+	return messages, hasMore, nil
+}
+
+// maxReactionEmojis is how many different emojis a listed message shows counts for
+const maxReactionEmojis = 6
+
+// setReactionCounts fills in each message's reaction counts as viewerID sees them. A
+// failure leaves the counts out rather than failing the page.
+func (s *MessagingService) setReactionCounts(ctx context.Context, messages []*models.Message, viewerID uuid.UUID) {
+	if len(messages) == 0 {
+		return
 	}
 
-	// Mark as read in background
-	go s.markConversationAsRead(ctx, conversationID, userID)
+	messageIDs := make([]uuid.UUID, len(messages))
+	for i, msg := range messages {
+		messageIDs[i] = msg.ID
+	}
 
-	return messages, nil
+	summaries, err := s.repo.GetReactionSummaries(ctx, messageIDs, viewerID, maxReactionEmojis)
+	if err != nil {
+		log.Printf("[Messaging] Failed to count reactions: %v", err)
+		return
+	}
+
+	for _, msg := range messages {
+		msg.ReactionCounts = summaries[msg.ID]
+	}
 }
 
 // MarkAsRead marks messages in a conversation as read
@@ -367,23 +1282,18 @@ func (s *MessagingService) MarkAsRead(ctx context.Context, conversationID, userI
 
 	// Update user's last seen (they're active reading messages)
 	if s.cache != nil {
-		s.cache.SetUserOnline(ctx, userID)
+		s.cache.TouchLastSeen(ctx, userID)
 
 		// Update cache
 		s.cache.ResetUnread(ctx, conversationID, userID)
 	}
 
-	// Broadcast read receipt to sender
+	// Broadcast read receipt to the senders
 	conversation, _ := s.repo.GetConversation(ctx, conversationID)
 	if conversation != nil {
-		var otherUserID uuid.UUID
-		if conversation.Participant1ID == userID {
-			otherUserID = conversation.Participant2ID
-		} else {
-			otherUserID = conversation.Participant1ID
+		for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+			go s.broadcastReadReceipt(otherUserID, conversationID, userID)
 		}
-
-		go s.broadcastReadReceipt(otherUserID, conversationID)
 	}
 
 	log.Printf("[Messaging] Messages marked as read for user %s in conversation %s", userID, conversationID)
@@ -405,7 +1315,7 @@ func (s *MessagingService) DeleteMessage(ctx context.Context, messageID, userID
 	}
 
 	isSender := message.SenderID == userID
-	isParticipant := conversation.Participant1ID == userID || conversation.Participant2ID == userID
+	isParticipant := conversation.IsParticipant(userID)
 
 	if !isParticipant {
 		return fmt.Errorf("user is not a participant in this conversation")
@@ -420,51 +1330,66 @@ func (s *MessagingService) DeleteMessage(ctx context.Context, messageID, userID
 	s.cache.InvalidateConversationCache(ctx, message.ConversationID)
 
 	// Broadcast deletion via WebSocket
-	var otherUserID uuid.UUID
-	if conversation.Participant1ID == userID {
-		otherUserID = conversation.Participant2ID
-	} else {
-		otherUserID = conversation.Participant1ID
-	}
-
-	// If sender deleted (unsend), notify the other person
+	// If sender deleted (unsend), notify everyone else
 	if isSender {
-		go s.broadcastMessageDeleted(otherUserID, message.ConversationID, messageID, true) // deleted for everyone
+		for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+			go s.broadcastMessageDeleted(otherUserID, message.ConversationID, messageID, true) // deleted for everyone
+		}
 	}
 
 	log.Printf("[Messaging] 🗑️ Message %s deleted for user %s (sender: %v)", messageID, userID, isSender)
 	return nil
 }
 
-// SearchMessages searches messages by content
-func (s *MessagingService) SearchMessages(ctx context.Context, userID uuid.UUID, query string, limit, offset int) ([]*models.Message, error) {
-	messages, err := s.repo.SearchMessages(ctx, userID, query, limit, offset)
+// SearchMessages full-text searches the messages a user can read, best matches first
+func (s *MessagingService) SearchMessages(ctx context.Context, userID uuid.UUID, query string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error) {
+	hits, total, err := s.repo.SearchMessages(ctx, userID, query, filter, limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	// Set IsMine field for search results
-	for _, msg := range messages {
-		msg.IsMine = msg.SenderID == userID
-	}
+	setSearchHitsMine(hits, userID)
+	return hits, total, nil
+}
 
-	return messages, nil
+// setSearchHitsMine sets IsMine on search hits and the messages around them
+func setSearchHitsMine(hits []*models.MessageSearchHit, userID uuid.UUID) {
+	for _, hit := range hits {
+		for _, msg := range []*models.Message{hit.Message, hit.Before, hit.After} {
+			if msg != nil {
+				msg.IsMine = msg.SenderID == userID
+			}
+		}
+	}
 }
 
 // ============================================
 // TYPING INDICATORS
 // ============================================
 
-// StartTyping sets typing indicator for a user in a conversation
+// StartTyping sets typing indicator for a user in a conversation. Clients call it
+// again while the user keeps typing; an indicator that isn't refreshed within
+// cache.TypingTTL is stopped for them.
 func (s *MessagingService) StartTyping(ctx context.Context, conversationID, userID uuid.UUID, isRecording bool) error {
-	// Update database
-	if err := s.repo.UpdateConversationTyping(ctx, conversationID, userID, true); err != nil {
-		return err
+	// A refresh in the same state only goes out again once the debounce interval has
+	// passed, and skips the database
+	changed := true
+	if s.cache != nil {
+		var err error
+		if changed, err = s.cache.SetTyping(ctx, conversationID, userID, isRecording); err != nil {
+			log.Printf("[MessagingService] Failed to cache typing state: %v", err)
+		}
 	}
 
-	// Update cache
-	if s.cache != nil {
-		s.cache.SetTyping(ctx, conversationID, userID)
+	if changed {
+		if err := s.repo.UpdateConversationTyping(ctx, conversationID, userID, true); err != nil {
+			return err
+		}
+	}
+	s.watchTyping(conversationID, userID)
+
+	if !changed && !s.cache.TypingBroadcastDue(ctx, conversationID, userID) {
+		return nil
 	}
 
 	// Get other user
@@ -473,21 +1398,18 @@ func (s *MessagingService) StartTyping(ctx context.Context, conversationID, user
 		return err
 	}
 
-	var otherUserID uuid.UUID
-	if conversation.Participant1ID == userID {
-		otherUserID = conversation.Participant2ID
-	} else {
-		otherUserID = conversation.Participant1ID
-	}
-
 	// Broadcast typing indicator with recording status
-	go s.broadcastTyping(otherUserID, conversationID, userID, true, isRecording)
+	for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+		go s.broadcastTyping(otherUserID, conversationID, userID, true, isRecording)
+	}
 
 	return nil
 }
 
 // StopTyping removes typing indicator
 func (s *MessagingService) StopTyping(ctx context.Context, conversationID, userID uuid.UUID) error {
+	s.unwatchTyping(conversationID, userID)
+
 	// Update database
 	if err := s.repo.UpdateConversationTyping(ctx, conversationID, userID, false); err != nil {
 		return err
@@ -504,19 +1426,120 @@ func (s *MessagingService) StopTyping(ctx context.Context, conversationID, userI
 		return err
 	}
 
-	var otherUserID uuid.UUID
-	if conversation.Participant1ID == userID {
-		otherUserID = conversation.Participant2ID
-	} else {
-		otherUserID = conversation.Participant1ID
-	}
-
 	// Broadcast stop typing (not recording)
-	go s.broadcastTyping(otherUserID, conversationID, userID, false, false)
+	for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+		go s.broadcastTyping(otherUserID, conversationID, userID, false, false)
+	}
 
 	return nil
 }
 
+// StopAllTyping stops every typing indicator userID has running. Called when their
+// last WebSocket connection closes, so a client that drops without saying it stopped
+// doesn't leave anyone watching "typing…".
+func (s *MessagingService) StopAllTyping(userID uuid.UUID) {
+	ctx := context.Background()
+
+	conversationIDs := make(map[uuid.UUID]bool)
+	s.typingMu.Lock()
+	for key := range s.typing {
+		if key.userID == userID {
+			conversationIDs[key.conversationID] = true
+		}
+	}
+	s.typingMu.Unlock()
+
+	// Indicators refreshed through another instance are only in the cache
+	if s.cache != nil {
+		cached, err := s.cache.GetTypingConversations(ctx, userID)
+		if err != nil {
+			log.Printf("[MessagingService] Failed to look up typing state for %s: %v", userID, err)
+		}
+		for _, conversationID := range cached {
+			conversationIDs[conversationID] = true
+		}
+	}
+
+	for conversationID := range conversationIDs {
+		if err := s.StopTyping(ctx, conversationID, userID); err != nil {
+			log.Printf("[MessagingService] Failed to stop typing in %s for %s: %v", conversationID, userID, err)
+		}
+	}
+}
+
+// typingKey identifies one user's typing indicator in one conversation
+type typingKey struct {
+	conversationID uuid.UUID
+	userID         uuid.UUID
+}
+
+// typingWatch stops an indicator once it goes unrefreshed past its deadline
+type typingWatch struct {
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// watchTyping (re)starts the countdown on a typing indicator
+func (s *MessagingService) watchTyping(conversationID, userID uuid.UUID) {
+	key := typingKey{conversationID: conversationID, userID: userID}
+
+	s.typingMu.Lock()
+	defer s.typingMu.Unlock()
+
+	if watch, ok := s.typing[key]; ok {
+		watch.deadline = time.Now().Add(cache.TypingTTL)
+		return
+	}
+	s.typing[key] = &typingWatch{
+		deadline: time.Now().Add(cache.TypingTTL),
+		timer:    time.AfterFunc(cache.TypingTTL, func() { s.typingTimedOut(key) }),
+	}
+}
+
+// unwatchTyping drops the countdown on a typing indicator
+func (s *MessagingService) unwatchTyping(conversationID, userID uuid.UUID) {
+	key := typingKey{conversationID: conversationID, userID: userID}
+
+	s.typingMu.Lock()
+	defer s.typingMu.Unlock()
+
+	if watch, ok := s.typing[key]; ok {
+		watch.timer.Stop()
+		delete(s.typing, key)
+	}
+}
+
+// typingTimedOut stops an indicator whose client went quiet. Refreshes only move the
+// deadline, so the timer rearms itself until the deadline really passes.
+func (s *MessagingService) typingTimedOut(key typingKey) {
+	s.typingMu.Lock()
+	watch, ok := s.typing[key]
+	if !ok {
+		s.typingMu.Unlock()
+		return
+	}
+	if left := time.Until(watch.deadline); left > 0 {
+		watch.timer.Reset(left)
+		s.typingMu.Unlock()
+		return
+	}
+	delete(s.typing, key)
+	s.typingMu.Unlock()
+
+	ctx := context.Background()
+
+	// The client may have moved to another instance, which is now watching it
+	if s.cache != nil {
+		if typing, err := s.cache.IsTyping(ctx, key.conversationID, key.userID); err == nil && typing {
+			return
+		}
+	}
+
+	if err := s.StopTyping(ctx, key.conversationID, key.userID); err != nil {
+		log.Printf("[MessagingService] Failed to expire typing in %s for %s: %v", key.conversationID, key.userID, err)
+	}
+}
+
 // ============================================
 // REACTIONS
 // ============================================
@@ -536,23 +1559,22 @@ func (s *MessagingService) AddReaction(ctx context.Context, messageID, userID uu
 			s.cache.InvalidateConversationCache(ctx, message.ConversationID)
 		}
 
-		// Broadcast reaction to other user
+		// Broadcast reaction to everyone else
 		conversation, _ := s.repo.GetConversation(ctx, message.ConversationID)
 		if conversation != nil {
-			var otherUserID uuid.UUID
-			if conversation.Participant1ID == userID {
-				otherUserID = conversation.Participant2ID
-			} else {
-				otherUserID = conversation.Participant1ID
-			}
-
 			// If reaction is nil, it was toggled off (removed)
 			if reaction == nil {
 				log.Printf("[Messaging] Reaction %s toggled off (removed) from message %s by user %s", emoji, messageID, userID)
-				go s.broadcastReactionRemoved(otherUserID, message.ConversationID, messageID, emoji)
 			} else {
 				log.Printf("[Messaging] Reaction %s added to message %s by user %s", emoji, messageID, userID)
-				go s.broadcastReaction(otherUserID, message.ConversationID, messageID, reaction)
+			}
+
+			for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+				if reaction == nil {
+					go s.broadcastReactionRemoved(otherUserID, message.ConversationID, messageID, emoji)
+				} else {
+					go s.broadcastReaction(otherUserID, message.ConversationID, messageID, reaction)
+				}
 			}
 		}
 	}
@@ -613,22 +1635,6 @@ func (s *MessagingService) GetStarredMessages(ctx context.Context, userID uuid.U
 // PRESENCE
 // ============================================
 
-// SetUserOnline marks a user as online
-func (s *MessagingService) SetUserOnline(ctx context.Context, userID uuid.UUID) error {
-	if s.cache != nil {
-		return s.cache.SetUserOnline(ctx, userID)
-	}
-	return nil
-}
-
-// SetUserOffline marks a user as offline
-func (s *MessagingService) SetUserOffline(ctx context.Context, userID uuid.UUID) error {
-	if s.cache != nil {
-		return s.cache.SetUserOffline(ctx, userID)
-	}
-	return nil
-}
-
 // GetUserPresence retrieves a user's presence status
 func (s *MessagingService) GetUserPresence(ctx context.Context, userID uuid.UUID) (bool, time.Time, error) {
 	if s.cache != nil {
@@ -645,14 +1651,71 @@ func (s *MessagingService) GetMultiplePresence(ctx context.Context, userIDs []uu
 	return make(map[uuid.UUID]*models.PresenceInfo), nil
 }
 
+// setConversationPresence fills in whether the other user of each one-to-one
+// conversation is online, or when they were last seen. Without the cache only
+// connections to this instance are known.
+func (s *MessagingService) setConversationPresence(ctx context.Context, conversations []*models.Conversation) {
+	userIDs := make([]uuid.UUID, 0, len(conversations))
+	for _, conv := range conversations {
+		if conv.OtherUser != nil {
+			userIDs = append(userIDs, conv.OtherUser.ID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	presence, err := s.GetMultiplePresence(ctx, userIDs)
+	if err != nil {
+		log.Printf("[MessagingService] Failed to get presence: %v", err)
+	}
+
+	for _, conv := range conversations {
+		if conv.OtherUser == nil {
+			continue
+		}
+		if info, ok := presence[conv.OtherUser.ID]; ok {
+			conv.IsOnline = info.IsOnline
+			if !info.IsOnline {
+				conv.LastSeen = info.LastSeen
+			}
+		} else {
+			conv.IsOnline = s.wsManager.IsUserConnected(conv.OtherUser.ID)
+		}
+	}
+}
+
+// presenceAudienceLimit caps how many conversation partners hear about a user
+// going online or offline
+const presenceAudienceLimit = 100
+
+// PresenceAudience returns the users who should be told when userID goes online or
+// offline: the other person in each of their most recent one-to-one conversations
+func (s *MessagingService) PresenceAudience(ctx context.Context, userID uuid.UUID) []uuid.UUID {
+	conversations, err := s.repo.GetUserConversations(ctx, userID, presenceAudienceLimit, 0)
+	if err != nil {
+		log.Printf("[MessagingService] Failed to get presence audience for %s: %v", userID, err)
+		return nil
+	}
+
+	audience := make([]uuid.UUID, 0, len(conversations))
+	for _, conv := range conversations {
+		if conv.OtherUser != nil && conv.OtherUser.ID != userID {
+			audience = append(audience, conv.OtherUser.ID)
+		}
+	}
+	return audience
+}
+
 // ============================================
 // WEBSOCKET HELPERS
 // ============================================
 
-func (s *MessagingService) broadcastNewMessage(recipientID uuid.UUID, message *models.Message) {
-	// CRITICAL: Broadcast IMMEDIATELY via WebSocket for real-time delivery
+// newMessageEnvelope wraps a message for the WebSocket. Its ID is the message's, so
+// a client that gets it twice can tell.
+func newMessageEnvelope(message *models.Message) models.WSMessageEnvelope {
 	// Use message's actual timestamp for consistency (not current time)
-	envelope := models.WSMessageEnvelope{
+	return models.WSMessageEnvelope{
 		ID:             message.ID.String(),
 		Type:           models.WSMessageTypeNewMessage,
 		Channel:        "messaging",
@@ -660,6 +1723,11 @@ func (s *MessagingService) broadcastNewMessage(recipientID uuid.UUID, message *m
 		Data:           message,
 		Timestamp:      message.CreatedAt.Unix(), // Use message timestamp for proper ordering
 	}
+}
+
+func (s *MessagingService) broadcastNewMessage(recipientID uuid.UUID, message *models.Message) {
+	// CRITICAL: Broadcast IMMEDIATELY via WebSocket for real-time delivery
+	envelope := newMessageEnvelope(message)
 
 	// Broadcast to recipient (non-blocking, instant)
 	// Check if user is connected - if not, they'll get it on next connection via pending messages
@@ -737,6 +1805,11 @@ func (s *MessagingService) broadcastTyping(recipientID, conversationID, typerID
 		msgType = models.WSMessageTypeStopTyping
 	}
 
+	expiresIn := 0
+	if isTyping {
+		expiresIn = int(cache.TypingTTL.Seconds())
+	}
+
 	// Fetch typing user details
 	typer, err := s.userRepo.GetUserByID(context.Background(), typerID)
 	if err != nil {
@@ -755,6 +1828,7 @@ func (s *MessagingService) broadcastTyping(recipientID, conversationID, typerID
 			DisplayName:    typer.DisplayName,
 			IsTyping:       isTyping,
 			IsRecording:    isRecording, // Now properly set!
+			ExpiresIn:      expiresIn,
 		},
 		Timestamp: time.Now().Unix(),
 	}
@@ -763,7 +1837,7 @@ func (s *MessagingService) broadcastTyping(recipientID, conversationID, typerID
 	s.wsManager.BroadcastToUserWithData(recipientID, envelope)
 }
 
-func (s *MessagingService) broadcastReadReceipt(recipientID, conversationID uuid.UUID) {
+func (s *MessagingService) broadcastReadReceipt(recipientID, conversationID, readerID uuid.UUID) {
 	readAt := time.Now().Format(time.RFC3339)
 
 	envelope := models.WSMessageEnvelope{
@@ -773,6 +1847,7 @@ func (s *MessagingService) broadcastReadReceipt(recipientID, conversationID uuid
 		ConversationID: &conversationID,
 		Data: map[string]interface{}{
 			"conversation_id": conversationID.String(),
+			"user_id":         readerID.String(), // Who read it; tells group receipts apart
 			"read_at":         readAt,
 		},
 		Timestamp: time.Now().Unix(),
@@ -782,6 +1857,57 @@ func (s *MessagingService) broadcastReadReceipt(recipientID, conversationID uuid
 	log.Printf("[Messaging] 💙 Broadcasted read receipt for conversation %s to sender %s", conversationID, recipientID)
 }
 
+func (s *MessagingService) broadcastGroupUpdated(recipientID uuid.UUID, conversation *models.Conversation) {
+	envelope := models.WSMessageEnvelope{
+		ID:             uuid.New().String(),
+		Type:           models.WSMessageTypeGroupUpdated,
+		Channel:        "messaging",
+		ConversationID: &conversation.ID,
+		Data: map[string]interface{}{
+			"conversation_id": conversation.ID.String(),
+			"name":            conversation.Name,
+			"members":         conversation.Members,
+			"is_member":       conversation.IsParticipant(recipientID),
+		},
+		Timestamp: time.Now().Unix(),
+	}
+
+	s.wsManager.BroadcastToUserWithData(recipientID, envelope)
+}
+
+func (s *MessagingService) broadcastScheduledFailed(scheduled *models.ScheduledMessage, reason string) {
+	envelope := models.WSMessageEnvelope{
+		ID:             uuid.New().String(),
+		Type:           models.WSMessageTypeScheduledFailed,
+		Channel:        "messaging",
+		ConversationID: &scheduled.ConversationID,
+		Data: map[string]interface{}{
+			"scheduled_message_id": scheduled.ID.String(),
+			"conversation_id":      scheduled.ConversationID.String(),
+			"reason":               reason,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+
+	s.wsManager.BroadcastToUserWithData(scheduled.SenderID, envelope)
+}
+
+func (s *MessagingService) broadcastSettingsUpdated(recipientID uuid.UUID, conversation *models.Conversation) {
+	envelope := models.WSMessageEnvelope{
+		ID:             uuid.New().String(),
+		Type:           models.WSMessageTypeSettingsUpdated,
+		Channel:        "messaging",
+		ConversationID: &conversation.ID,
+		Data: map[string]interface{}{
+			"conversation_id":     conversation.ID.String(),
+			"message_ttl_seconds": conversation.MessageTTLSeconds,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+
+	s.wsManager.BroadcastToUserWithData(recipientID, envelope)
+}
+
 func (s *MessagingService) broadcastReaction(recipientID, conversationID, messageID uuid.UUID, reaction *models.MessageReaction) {
 	envelope := models.WSMessageEnvelope{
 		ID:             uuid.New().String(),
@@ -834,19 +1960,13 @@ func (s *MessagingService) broadcastMessageDeleted(recipientID, conversationID,
 	log.Printf("[Messaging] 🗑️ Broadcasted message_deleted for message %s to user %s", messageID, recipientID)
 }
 
-func (s *MessagingService) markAsDelivered(ctx context.Context, messageID uuid.UUID) {
+func (s *MessagingService) markAsDelivered(ctx context.Context, message *models.Message, recipientID uuid.UUID) {
 	time.Sleep(100 * time.Millisecond) // Small delay to ensure message is received
 
 	// Update status in database
-	if err := s.repo.UpdateMessageStatus(ctx, messageID, models.MessageStatusDelivered); err != nil {
+	if err := s.repo.UpdateMessageStatus(ctx, message.ID, models.MessageStatusDelivered); err != nil {
 		log.Printf("[Messaging] Failed to mark message as delivered: %v", err)
-		return
-	}
-
-	// Get message to find conversation and sender
-	message, err := s.repo.GetMessage(ctx, messageID)
-	if err != nil {
-		log.Printf("[Messaging] Failed to get message for delivery broadcast: %v", err)
+		s.deliveryFailed(message, recipientID, err)
 		return
 	}
 
@@ -854,12 +1974,12 @@ func (s *MessagingService) markAsDelivered(ctx context.Context, messageID uuid.U
 
 	// Broadcast to sender (delivered status update)
 	envelope := models.WSMessageEnvelope{
-		ID:             messageID.String(),
+		ID:             message.ID.String(),
 		Type:           "message_delivered",
 		Channel:        "messaging",
 		ConversationID: &message.ConversationID,
 		Data: map[string]interface{}{
-			"message_id":      messageID.String(),
+			"message_id":      message.ID.String(),
 			"conversation_id": message.ConversationID.String(),
 			"delivered_at":    deliveredAt,
 		},
@@ -867,7 +1987,42 @@ func (s *MessagingService) markAsDelivered(ctx context.Context, messageID uuid.U
 	}
 
 	s.wsManager.BroadcastToUserWithData(message.SenderID, envelope)
-	log.Printf("[Messaging] 📬 Broadcasted delivered status for message %s to sender %s", messageID, message.SenderID)
+	log.Printf("[Messaging] 📬 Broadcasted delivered status for message %s to sender %s", message.ID, message.SenderID)
+}
+
+// markAsDeliveredTo records that recipientID has received a group message, and tells
+// the sender who it reached
+func (s *MessagingService) markAsDeliveredTo(ctx context.Context, message *models.Message, recipientID uuid.UUID) {
+	time.Sleep(100 * time.Millisecond) // Small delay to ensure message is received
+
+	if err := s.repo.MarkMessageDeliveredTo(ctx, message.ID, recipientID); err != nil {
+		log.Printf("[Messaging] Failed to mark message %s delivered to %s: %v", message.ID, recipientID, err)
+		s.deliveryFailed(message, recipientID, err)
+		return
+	}
+
+	envelope := models.WSMessageEnvelope{
+		ID:             uuid.New().String(),
+		Type:           models.WSMessageTypeMessageDelivered,
+		Channel:        "messaging",
+		ConversationID: &message.ConversationID,
+		Data: map[string]interface{}{
+			"message_id":      message.ID.String(),
+			"conversation_id": message.ConversationID.String(),
+			"user_id":         recipientID.String(),
+			"delivered_at":    time.Now().Format(time.RFC3339),
+		},
+		Timestamp: time.Now().Unix(),
+	}
+
+	s.wsManager.BroadcastToUserWithData(message.SenderID, envelope)
+}
+
+// deliveryFailed hands a delivery that failed on its first attempt over for retries
+func (s *MessagingService) deliveryFailed(message *models.Message, recipientID uuid.UUID, cause error) {
+	if s.deliveries != nil {
+		s.deliveries.DeliveryFailed(context.Background(), message, recipientID, 1, true, cause)
+	}
 }
 
 func (s *MessagingService) markConversationAsRead(ctx context.Context, conversationID, userID uuid.UUID) {
@@ -878,42 +2033,54 @@ func (s *MessagingService) markConversationAsRead(ctx context.Context, conversat
 // PIN MESSAGES
 // ============================================
 
-// PinMessage pins a message in a conversation
-func (s *MessagingService) PinMessage(ctx context.Context, messageID, userID uuid.UUID) error {
-	// Pin first (most critical operation)
-	if err := s.repo.PinMessage(ctx, messageID, userID); err != nil {
-		return err
+// PinMessage pins a message in a conversation. A conversation already at its pin
+// limit has its oldest pins removed to make room; their IDs are returned.
+func (s *MessagingService) PinMessage(ctx context.Context, messageID, userID uuid.UUID) ([]uuid.UUID, error) {
+	message, err := s.repo.GetMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Everything else happens asynchronously for speed
-	go func() {
-		message, err := s.repo.GetMessage(context.Background(), messageID)
+	unpinned := []uuid.UUID{}
+	if s.maxPinned > 0 && message.PinnedAt == nil {
+		// Newest pin first
+		pinned, err := s.repo.GetPinnedMessages(ctx, message.ConversationID)
 		if err != nil {
-			log.Printf("[Messaging] Failed to get message for pin broadcast: %v", err)
-			return
+			return nil, err
 		}
+		for i := s.maxPinned - 1; i < len(pinned); i++ {
+			if err := s.repo.UnpinMessage(ctx, pinned[i].ID, userID); err != nil {
+				return nil, err
+			}
+			unpinned = append(unpinned, pinned[i].ID)
+		}
+	}
+
+	if err := s.repo.PinMessage(ctx, messageID, userID); err != nil {
+		return nil, err
+	}
 
+	// Everything else happens asynchronously for speed
+	go func() {
 		if s.cache != nil {
 			s.cache.InvalidateConversationCache(context.Background(), message.ConversationID)
 		}
 
-		// Broadcast pin event to other user
+		// Broadcast pin event to everyone else
 		conversation, _ := s.repo.GetConversation(context.Background(), message.ConversationID)
 		if conversation != nil {
-			var otherUserID uuid.UUID
-			if conversation.Participant1ID == userID {
-				otherUserID = conversation.Participant2ID
-			} else {
-				otherUserID = conversation.Participant1ID
+			for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+				for _, id := range unpinned {
+					s.broadcastMessageUnpinned(otherUserID, message.ConversationID, id)
+				}
+				s.broadcastMessagePinned(otherUserID, message.ConversationID, messageID)
 			}
-
-			s.broadcastMessagePinned(otherUserID, message.ConversationID, messageID)
 		}
 
-		log.Printf("[Messaging] Message %s pinned by user %s", messageID, userID)
+		log.Printf("[Messaging] Message %s pinned by user %s (%d unpinned to make room)", messageID, userID, len(unpinned))
 	}()
 
-	return nil
+	return unpinned, nil
 }
 
 // UnpinMessage unpins a message
@@ -938,14 +2105,9 @@ func (s *MessagingService) UnpinMessage(ctx context.Context, messageID, userID u
 		// Broadcast unpin event
 		conversation, _ := s.repo.GetConversation(context.Background(), message.ConversationID)
 		if conversation != nil {
-			var otherUserID uuid.UUID
-			if conversation.Participant1ID == userID {
-				otherUserID = conversation.Participant2ID
-			} else {
-				otherUserID = conversation.Participant1ID
+			for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+				s.broadcastMessageUnpinned(otherUserID, message.ConversationID, messageID)
 			}
-
-			s.broadcastMessageUnpinned(otherUserID, message.ConversationID, messageID)
 		}
 
 		log.Printf("[Messaging] Message %s unpinned by user %s", messageID, userID)
@@ -1003,17 +2165,12 @@ func (s *MessagingService) EditMessage(ctx context.Context, messageID, userID uu
 			return
 		}
 
-		// Broadcast edit event to other user
+		// Broadcast edit event to everyone else
 		conversation, _ := s.repo.GetConversation(context.Background(), conversationID)
 		if conversation != nil {
-			var otherUserID uuid.UUID
-			if conversation.Participant1ID == userID {
-				otherUserID = conversation.Participant2ID
-			} else {
-				otherUserID = conversation.Participant1ID
+			for _, otherUserID := range conversation.OtherParticipantIDs(userID) {
+				s.broadcastMessageEdited(otherUserID, conversationID, updatedMsg)
 			}
-
-			s.broadcastMessageEdited(otherUserID, conversationID, updatedMsg)
 		}
 
 		log.Printf("[Messaging] Message %s edited by user %s", messageID, userID)
@@ -1035,7 +2192,7 @@ func (s *MessagingService) GetMessageEditHistory(ctx context.Context, messageID,
 		return nil, err
 	}
 
-	if conversation.Participant1ID != userID && conversation.Participant2ID != userID {
+	if !conversation.IsParticipant(userID) {
 		return nil, fmt.Errorf("unauthorized: not part of this conversation")
 	}
 
@@ -1059,7 +2216,7 @@ func (s *MessagingService) ForwardMessage(ctx context.Context, messageID, toConv
 		return nil, err
 	}
 
-	if originalConv.Participant1ID != userID && originalConv.Participant2ID != userID {
+	if !originalConv.IsParticipant(userID) {
 		return nil, fmt.Errorf("unauthorized: not part of original conversation")
 	}
 
@@ -1069,7 +2226,7 @@ func (s *MessagingService) ForwardMessage(ctx context.Context, messageID, toConv
 		return nil, err
 	}
 
-	if targetConv.Participant1ID != userID && targetConv.Participant2ID != userID {
+	if !targetConv.IsParticipant(userID) {
 		return nil, fmt.Errorf("unauthorized: not part of target conversation")
 	}
 
@@ -1083,31 +2240,28 @@ func (s *MessagingService) ForwardMessage(ctx context.Context, messageID, toConv
 		s.cache.InvalidateConversationCache(ctx, toConversationID)
 	}
 
-	// Broadcast to the other participant in target conversation
-	var otherUserID uuid.UUID
-	if targetConv.Participant1ID == userID {
-		otherUserID = targetConv.Participant2ID
-	} else {
-		otherUserID = targetConv.Participant1ID
-	}
-
-	// Mark as delivered immediately (before returning)
-	if err := s.repo.UpdateMessageStatus(ctx, forwardedMsg.ID, models.MessageStatusDelivered); err != nil {
-		log.Printf("[Messaging] Warning: Failed to mark forwarded message as delivered: %v", err)
-	} else {
-		// Refresh message to get updated delivered_at timestamp
-		updatedMsg, err := s.repo.GetMessage(ctx, forwardedMsg.ID)
-		if err == nil {
-			forwardedMsg = updatedMsg
+	// Mark as delivered immediately (before returning). In a group, delivery is
+	// tracked per member as they receive it.
+	if !targetConv.IsGroup {
+		if err := s.repo.UpdateMessageStatus(ctx, forwardedMsg.ID, models.MessageStatusDelivered); err != nil {
+			log.Printf("[Messaging] Warning: Failed to mark forwarded message as delivered: %v", err)
+		} else {
+			// Refresh message to get updated delivered_at timestamp
+			updatedMsg, err := s.repo.GetMessage(ctx, forwardedMsg.ID)
+			if err == nil {
+				forwardedMsg = updatedMsg
+			}
 		}
 	}
 
-	// Broadcast new message
-	go s.broadcastNewMessage(otherUserID, forwardedMsg)
+	// Broadcast to everyone else in the target conversation
+	for _, otherUserID := range targetConv.OtherParticipantIDs(userID) {
+		go s.broadcastNewMessage(otherUserID, forwardedMsg)
 
-	// Check if recipient is online, if not send notification
-	if !s.wsManager.IsUserConnected(otherUserID) && s.notifService != nil {
-		go s.createMessageNotification(otherUserID, userID, forwardedMsg)
+		// Check if recipient is online, if not send notification
+		if !s.wsManager.IsUserConnected(otherUserID) && s.notifService != nil {
+			go s.createMessageNotification(otherUserID, userID, forwardedMsg)
+		}
 	}
 
 	log.Printf("[Messaging] Message %s forwarded to conversation %s by user %s", messageID, toConversationID, userID)
@@ -1119,29 +2273,25 @@ func (s *MessagingService) ForwardMessage(ctx context.Context, messageID, toConv
 // SEARCH IN CONVERSATION
 // ============================================
 
-// SearchConversationMessages searches messages within a specific conversation
-func (s *MessagingService) SearchConversationMessages(ctx context.Context, conversationID, userID uuid.UUID, query string, limit, offset int) ([]*models.Message, error) {
+// SearchConversationMessages full-text searches one conversation, best matches first
+func (s *MessagingService) SearchConversationMessages(ctx context.Context, conversationID, userID uuid.UUID, query string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error) {
 	// Verify user is part of the conversation
 	conversation, err := s.repo.GetConversation(ctx, conversationID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	if conversation.Participant1ID != userID && conversation.Participant2ID != userID {
-		return nil, fmt.Errorf("unauthorized: not part of this conversation")
+	if !conversation.IsParticipant(userID) {
+		return nil, 0, fmt.Errorf("unauthorized: not part of this conversation")
 	}
 
-	messages, err := s.repo.SearchConversationMessages(ctx, conversationID, query, limit, offset)
+	hits, total, err := s.repo.SearchConversationMessages(ctx, conversationID, userID, query, filter, limit, offset)
 	if err != nil {
-		return nil, err
-	}
-
-	// Set IsMine flag
-	for _, msg := range messages {
-		msg.IsMine = msg.SenderID == userID
+		return nil, 0, err
 	}
 
-	return messages, nil
+	setSearchHitsMine(hits, userID)
+	return hits, total, nil
 }
 
 // ============================================
@@ -1259,7 +2409,7 @@ func (s *MessagingService) GetMessageByID(ctx context.Context, messageID, userID
 		return nil, fmt.Errorf("conversation not found: %w", err)
 	}
 
-	if conversation.Participant1ID != userID && conversation.Participant2ID != userID {
+	if !conversation.IsParticipant(userID) {
 		return nil, fmt.Errorf("user is not a participant in this conversation")
 	}
 
@@ -0,0 +1,134 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+func TestResolveSendAt(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	day := tomorrow.Format("2006-01-02")
+
+	got, err := resolveSendAt(day+"T09:00", "Europe/Berlin")
+	if err != nil {
+		t.Fatalf("resolveSendAt: %v", err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	if want, _ := time.ParseInLocation("2006-01-02T15:04", day+"T09:00", berlin); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want.UTC())
+	}
+
+	if _, err := resolveSendAt(time.Now().Add(-time.Minute).Format(time.RFC3339), ""); err != errors.ErrInvalidSendAt {
+		t.Errorf("past time: err = %v, want ErrInvalidSendAt", err)
+	}
+	if _, err := resolveSendAt(time.Now().AddDate(2, 0, 0).Format(time.RFC3339), ""); err != errors.ErrInvalidSendAt {
+		t.Errorf("two years ahead: err = %v, want ErrInvalidSendAt", err)
+	}
+	if _, err := resolveSendAt(day+"T09:00", "Mars/Olympus"); err == nil {
+		t.Error("an unknown timezone should be rejected")
+	}
+}
+
+func TestSendDueScheduledMessagesDeliversThroughDeliveryService(t *testing.T) {
+	sender, online, offline := uuid.New(), uuid.New(), uuid.New()
+	group := newGroup(sender, online, offline)
+	scheduled := &models.ScheduledMessage{
+		ID:             uuid.New(),
+		ConversationID: group.ID,
+		SenderID:       sender,
+		Message:        models.MessageRequest{Content: "Happy birthday!", MessageType: models.MessageTypeText},
+	}
+
+	manager := newRunningManager(t)
+	client := connectUser(t, manager, online)
+	messages := &fakeMessageRepo{conversation: group, due: []*models.ScheduledMessage{scheduled}}
+	deliveries := &fakeDeliveryRepo{}
+	svc := NewMessagingService(messages, nil, manager, nil, nil)
+	svc.SetDeliveryService(NewDeliveryService(deliveries, manager))
+
+	sent, err := svc.SendDueScheduledMessages(context.Background(), 10)
+	if err != nil || sent != 1 {
+		t.Fatalf("SendDueScheduledMessages = %d, %v; want 1 sent", sent, err)
+	}
+
+	message := messages.created[0]
+	if id := messages.finished[scheduled.ID]; id == nil || *id != message.ID {
+		t.Errorf("scheduled message finished as %v, want %s", id, message.ID)
+	}
+	if envelope := readEnvelope(t, client, string(models.WSMessageTypeNewMessage)); envelope.ID != message.ID.String() {
+		t.Errorf("online member got %s, want %s", envelope.ID, message.ID)
+	}
+	if len(deliveries.delivered) != 1 || deliveries.delivered[0] != online {
+		t.Errorf("delivered to %v, want just the online member", deliveries.delivered)
+	}
+}
+
+func TestSendDueScheduledMessagesNeedsDeliveryService(t *testing.T) {
+	messages := &fakeMessageRepo{due: []*models.ScheduledMessage{{ID: uuid.New()}}}
+	svc := NewMessagingService(messages, nil, newRunningManager(t), nil, nil)
+
+	if _, err := svc.SendDueScheduledMessages(context.Background(), 10); err == nil {
+		t.Error("expected an error without a delivery service")
+	}
+	if len(messages.due) != 1 {
+		t.Error("nothing should have been claimed")
+	}
+}
+
+func TestPinMessageUnpinsTheOldestAtTheCap(t *testing.T) {
+	user, conversationID := uuid.New(), uuid.New()
+	repo := &fakeMessageRepo{messages: map[uuid.UUID]*models.Message{}}
+	var ids []uuid.UUID
+	for i := 0; i < 4; i++ {
+		msg := &models.Message{ID: uuid.New(), ConversationID: conversationID}
+		repo.messages[msg.ID] = msg
+		ids = append(ids, msg.ID)
+	}
+	svc := NewMessagingService(repo, nil, nil, nil, nil)
+	svc.SetMaxPinnedMessages(2)
+	ctx := context.Background()
+
+	for _, id := range ids[:2] {
+		if unpinned, err := svc.PinMessage(ctx, id, user); err != nil || len(unpinned) != 0 {
+			t.Fatalf("pinning under the cap: unpinned %v, err %v", unpinned, err)
+		}
+	}
+
+	unpinned, err := svc.PinMessage(ctx, ids[2], user)
+	if err != nil {
+		t.Fatalf("PinMessage: %v", err)
+	}
+	if len(unpinned) != 1 || unpinned[0] != ids[0] {
+		t.Errorf("unpinned %v, want only the oldest pin %s", unpinned, ids[0])
+	}
+
+	// Pinning an already pinned message makes no room
+	if unpinned, _ := svc.PinMessage(ctx, ids[2], user); len(unpinned) != 0 {
+		t.Errorf("re-pinning unpinned %v", unpinned)
+	}
+
+	pinned, _ := repo.GetPinnedMessages(ctx, conversationID)
+	if len(pinned) != 2 || pinned[0].ID != ids[2] || pinned[1].ID != ids[1] {
+		t.Errorf("pinned %d messages, want the two newest, newest first", len(pinned))
+	}
+}
+
+func TestPinMessageWithoutCap(t *testing.T) {
+	conversationID := uuid.New()
+	repo := &fakeMessageRepo{messages: map[uuid.UUID]*models.Message{}}
+	svc := NewMessagingService(repo, nil, nil, nil, nil)
+
+	for i := 0; i < 5; i++ {
+		msg := &models.Message{ID: uuid.New(), ConversationID: conversationID}
+		repo.messages[msg.ID] = msg
+		if unpinned, err := svc.PinMessage(context.Background(), msg.ID, uuid.New()); err != nil || len(unpinned) != 0 {
+			t.Fatalf("unpinned %v, err %v; nothing should be unpinned without a cap", unpinned, err)
+		}
+	}
+}
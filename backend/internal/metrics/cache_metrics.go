@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Cache is the process-wide collector for cache lookups
+var Cache = &CacheMetrics{}
+
+// CacheMetrics counts cache lookups that found their key and ones that didn't
+type CacheMetrics struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// Hit records a lookup that found its key
+func (m *CacheMetrics) Hit() {
+	m.hits.Add(1)
+}
+
+// Miss records a lookup that didn't
+func (m *CacheMetrics) Miss() {
+	m.misses.Add(1)
+}
+
+// HitRatio is the share of lookups so far that were hits, 0 before any
+func (m *CacheMetrics) HitRatio() float64 {
+	hits, misses := m.hits.Load(), m.misses.Load()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// WritePrometheus appends the cache metrics to sb in Prometheus text format
+func (m *CacheMetrics) WritePrometheus(sb *strings.Builder) {
+	writeHeader(sb, "histeeria_cache_lookups_total", "Cache lookups by result", "counter")
+	sb.WriteString(fmt.Sprintf("histeeria_cache_lookups_total{result=\"hit\"} %d\n", m.hits.Load()))
+	sb.WriteString(fmt.Sprintf("histeeria_cache_lookups_total{result=\"miss\"} %d\n", m.misses.Load()))
+
+	writeHeader(sb, "histeeria_cache_hit_ratio", "Share of cache lookups that were hits since start", "gauge")
+	sb.WriteString(fmt.Sprintf("histeeria_cache_hit_ratio %g\n", m.HitRatio()))
+}
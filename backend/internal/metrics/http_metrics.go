@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latency histogram buckets (seconds) for API requests
+var httpLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Response body size histogram buckets (bytes)
+var httpSizeBuckets = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+
+// HTTP is the process-wide collector for API request metrics
+var HTTP = NewHTTPMetrics()
+
+type httpRequestKey struct {
+	method string
+	route  string
+	status string
+}
+
+type httpRouteKey struct {
+	method string
+	route  string
+}
+
+// httpRouteStats is what's kept per route beyond the request count
+type httpRouteStats struct {
+	latency *histogram
+	size    *histogram
+	large   uint64 // Responses over the large response threshold
+	slow    uint64 // Requests over the slow request threshold
+}
+
+// HTTPMetrics records API request counts, latency and response sizes by route, and
+// counts the requests that were slow or answered with an oversized body
+type HTTPMetrics struct {
+	mu       sync.RWMutex
+	requests map[httpRequestKey]uint64
+	routes   map[httpRouteKey]*httpRouteStats
+
+	largeResponseBytes int           // 0 flags nothing
+	slowRequest        time.Duration // 0 flags nothing
+}
+
+// NewHTTPMetrics creates an empty metrics collector
+func NewHTTPMetrics() *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: make(map[httpRequestKey]uint64),
+		routes:   make(map[httpRouteKey]*httpRouteStats),
+	}
+}
+
+// SetThresholds sets the response size and latency past which a request is flagged
+// as large or slow. Zero disables either.
+func (m *HTTPMetrics) SetThresholds(largeResponseBytes int, slowRequest time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.largeResponseBytes = largeResponseBytes
+	m.slowRequest = slowRequest
+}
+
+// Observe records one request and reports whether it crossed the large response or
+// slow request threshold. route is the route pattern, such as /api/v1/posts/:id, not
+// the path, so that IDs don't each become a series. Requests that matched no route
+// share the route "unmatched".
+func (m *HTTPMetrics) Observe(method, route string, status int, duration time.Duration, responseBytes int) (large, slow bool) {
+	if route == "" {
+		route = "unmatched"
+	}
+	rk := httpRouteKey{method: method, route: route}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[httpRequestKey{method: method, route: route, status: strconv.Itoa(status)}]++
+
+	stats, ok := m.routes[rk]
+	if !ok {
+		stats = &httpRouteStats{
+			latency: newHistogram(httpLatencyBuckets),
+			size:    newHistogram(httpSizeBuckets),
+		}
+		m.routes[rk] = stats
+	}
+	stats.latency.observe(httpLatencyBuckets, duration.Seconds())
+	stats.size.observe(httpSizeBuckets, float64(responseBytes))
+
+	large = m.largeResponseBytes > 0 && responseBytes > m.largeResponseBytes
+	slow = m.slowRequest > 0 && duration > m.slowRequest
+	if large {
+		stats.large++
+	}
+	if slow {
+		stats.slow++
+	}
+	return large, slow
+}
+
+// WritePrometheus appends the API request metrics to sb in Prometheus text format
+func (m *HTTPMetrics) WritePrometheus(sb *strings.Builder) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reqKeys := make([]httpRequestKey, 0, len(m.requests))
+	for k := range m.requests {
+		reqKeys = append(reqKeys, k)
+	}
+	sort.Slice(reqKeys, func(i, j int) bool {
+		a, b := reqKeys[i], reqKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	writeHeader(sb, "histeeria_http_requests_total", "API requests by method, route and status", "counter")
+	for _, k := range reqKeys {
+		sb.WriteString(fmt.Sprintf("histeeria_http_requests_total{method=%q,route=%q,status=%q} %d\n",
+			k.method, k.route, k.status, m.requests[k]))
+	}
+
+	routeKeys := make([]httpRouteKey, 0, len(m.routes))
+	for k := range m.routes {
+		routeKeys = append(routeKeys, k)
+	}
+	sort.Slice(routeKeys, func(i, j int) bool {
+		if routeKeys[i].route != routeKeys[j].route {
+			return routeKeys[i].route < routeKeys[j].route
+		}
+		return routeKeys[i].method < routeKeys[j].method
+	})
+	labels := func(k httpRouteKey) string {
+		return fmt.Sprintf("method=%q,route=%q", k.method, k.route)
+	}
+
+	writeHeader(sb, "histeeria_http_request_duration_seconds", "API request latency by method and route", "histogram")
+	for _, k := range routeKeys {
+		m.routes[k].latency.write(sb, "histeeria_http_request_duration_seconds", labels(k), httpLatencyBuckets)
+	}
+
+	// Read off the histogram, so it's as coarse as its buckets, and covers every
+	// request since start
+	writeHeader(sb, "histeeria_http_request_duration_p95_seconds", "Estimated 95th percentile API request latency by method and route", "gauge")
+	for _, k := range routeKeys {
+		sb.WriteString(fmt.Sprintf("histeeria_http_request_duration_p95_seconds{%s} %g\n",
+			labels(k), m.routes[k].latency.quantile(httpLatencyBuckets, 0.95)))
+	}
+
+	writeHeader(sb, "histeeria_http_response_size_bytes", "API response body size by method and route", "histogram")
+	for _, k := range routeKeys {
+		m.routes[k].size.write(sb, "histeeria_http_response_size_bytes", labels(k), httpSizeBuckets)
+	}
+
+	writeHeader(sb, "histeeria_http_large_responses_total", "API responses over the large response threshold", "counter")
+	for _, k := range routeKeys {
+		if n := m.routes[k].large; n > 0 {
+			sb.WriteString(fmt.Sprintf("histeeria_http_large_responses_total{%s} %d\n", labels(k), n))
+		}
+	}
+
+	writeHeader(sb, "histeeria_http_slow_requests_total", "API requests over the slow request threshold", "counter")
+	for _, k := range routeKeys {
+		if n := m.routes[k].slow; n > 0 {
+			sb.WriteString(fmt.Sprintf("histeeria_http_slow_requests_total{%s} %d\n", labels(k), n))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run duration histogram buckets (seconds) for scheduled jobs
+var jobDurationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900}
+
+// Jobs is the process-wide collector for scheduled job runs
+var Jobs = NewJobMetrics()
+
+type jobRunKey struct {
+	job     string
+	outcome string // "success" or "failure"
+}
+
+// JobMetrics records how often scheduled jobs run, whether they succeed and how long
+// they take, retries included
+type JobMetrics struct {
+	mu        sync.RWMutex
+	runs      map[jobRunKey]uint64
+	durations map[string]*histogram
+}
+
+// NewJobMetrics creates an empty metrics collector
+func NewJobMetrics() *JobMetrics {
+	return &JobMetrics{
+		runs:      make(map[jobRunKey]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+// Observe records one run of job
+func (m *JobMetrics) Observe(job string, err error, duration time.Duration) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runs[jobRunKey{job: job, outcome: outcome}]++
+
+	hist, ok := m.durations[job]
+	if !ok {
+		hist = newHistogram(jobDurationBuckets)
+		m.durations[job] = hist
+	}
+	hist.observe(jobDurationBuckets, duration.Seconds())
+}
+
+// WritePrometheus appends the job metrics to sb in Prometheus text format
+func (m *JobMetrics) WritePrometheus(sb *strings.Builder) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runKeys := make([]jobRunKey, 0, len(m.runs))
+	for k := range m.runs {
+		runKeys = append(runKeys, k)
+	}
+	sort.Slice(runKeys, func(i, j int) bool {
+		if runKeys[i].job != runKeys[j].job {
+			return runKeys[i].job < runKeys[j].job
+		}
+		return runKeys[i].outcome < runKeys[j].outcome
+	})
+
+	writeHeader(sb, "histeeria_job_runs_total", "Scheduled job runs by job and outcome", "counter")
+	for _, k := range runKeys {
+		sb.WriteString(fmt.Sprintf("histeeria_job_runs_total{job=%q,outcome=%q} %d\n", k.job, k.outcome, m.runs[k]))
+	}
+
+	jobs := make([]string, 0, len(m.durations))
+	for job := range m.durations {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+
+	writeHeader(sb, "histeeria_job_duration_seconds", "Scheduled job run time, retries included", "histogram")
+	for _, job := range jobs {
+		m.durations[job].write(sb, "histeeria_job_duration_seconds", fmt.Sprintf("job=%q", job), jobDurationBuckets)
+	}
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Collector writes a group of metrics in Prometheus text format
+type Collector interface {
+	WritePrometheus(sb *strings.Builder)
+}
+
+// Registry is the set of metrics served for scraping
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// Default is the process-wide registry. The metrics this package records itself are
+// on it from the start; main adds the ones that read a running component.
+var Default = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(HTTP)
+	r.Register(Cache)
+	r.Register(Jobs)
+	r.Register(Supabase)
+	return r
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a collector
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// RegisterGauge adds a gauge whose value is read at scrape time
+func (r *Registry) RegisterGauge(name, help string, value func() float64) {
+	r.Register(&funcMetric{name: name, help: help, kind: "gauge", value: value})
+}
+
+// RegisterCounter adds a counter kept elsewhere, read at scrape time
+func (r *Registry) RegisterCounter(name, help string, value func() float64) {
+	r.Register(&funcMetric{name: name, help: help, kind: "counter", value: value})
+}
+
+// RegisterGaugeVec adds a gauge with one label, its values read at scrape time
+func (r *Registry) RegisterGaugeVec(name, help, label string, values func() map[string]float64) {
+	r.Register(&funcVecMetric{name: name, help: help, label: label, values: values})
+}
+
+// WritePrometheus writes every registered metric
+func (r *Registry) WritePrometheus(sb *strings.Builder) {
+	r.mu.RLock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	for _, c := range collectors {
+		c.WritePrometheus(sb)
+	}
+}
+
+// Handler serves the registry in Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var sb strings.Builder
+		r.WritePrometheus(&sb)
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(sb.String()))
+	})
+}
+
+// funcMetric is a single unlabelled value read when scraped
+type funcMetric struct {
+	name, help, kind string
+	value            func() float64
+}
+
+func (m *funcMetric) WritePrometheus(sb *strings.Builder) {
+	writeHeader(sb, m.name, m.help, m.kind)
+	sb.WriteString(fmt.Sprintf("%s %g\n", m.name, m.value()))
+}
+
+// funcVecMetric is a gauge with one label, read when scraped
+type funcVecMetric struct {
+	name, help, label string
+	values            func() map[string]float64
+}
+
+func (m *funcVecMetric) WritePrometheus(sb *strings.Builder) {
+	values := m.values()
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	writeHeader(sb, m.name, m.help, "gauge")
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("%s{%s=%q} %g\n", m.name, m.label, k, values[k]))
+	}
+}
+
+func writeHeader(sb *strings.Builder, name, help, kind string) {
+	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", name, help))
+	sb.WriteString(fmt.Sprintf("# TYPE %s %s\n", name, kind))
+}
+
+// histogram counts observations into cumulative buckets
+type histogram struct {
+	buckets []uint64 // cumulative counts per bound
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{buckets: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	for i, bound := range bounds {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// quantile estimates the q-quantile the way Prometheus's histogram_quantile does,
+// interpolating linearly within the bucket it falls in. Past the last bound it
+// returns that bound.
+func (h *histogram) quantile(bounds []float64, q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := q * float64(h.count)
+
+	var lower float64
+	var below uint64
+	for i, bound := range bounds {
+		if float64(h.buckets[i]) >= rank {
+			inBucket := h.buckets[i] - below
+			if inBucket == 0 {
+				return bound
+			}
+			return lower + (bound-lower)*(rank-float64(below))/float64(inBucket)
+		}
+		lower, below = bound, h.buckets[i]
+	}
+	return bounds[len(bounds)-1]
+}
+
+// write appends the histogram's series; labels is the label list without braces,
+// such as `table="posts",method="GET"`
+func (h *histogram) write(sb *strings.Builder, name, labels string, bounds []float64) {
+	for i, bound := range bounds {
+		sb.WriteString(fmt.Sprintf("%s_bucket{%s,le=\"%g\"} %d\n", name, labels, bound, h.buckets[i]))
+	}
+	sb.WriteString(fmt.Sprintf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count))
+	sb.WriteString(fmt.Sprintf("%s_sum{%s} %f\n", name, labels, h.sum))
+	sb.WriteString(fmt.Sprintf("%s_count{%s} %d\n", name, labels, h.count))
+}
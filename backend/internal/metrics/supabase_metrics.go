@@ -0,0 +1,291 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latency histogram buckets (seconds) for Supabase requests
+var supabaseLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Alert thresholds evaluated by CheckAlerts over each check interval
+const (
+	alertMinRequests      = 20   // Ignore windows with too little traffic to be meaningful
+	alertServerErrorRatio = 0.05 // 5xx + network errors above 5% of requests
+	alertAvgLatency       = 2 * time.Second
+)
+
+// Supabase is the process-wide collector for PostgREST request metrics
+var Supabase = NewSupabaseMetrics()
+
+type requestKey struct {
+	table       string
+	method      string
+	statusClass string
+}
+
+type throttleKey struct {
+	table   string
+	method  string
+	outcome string // "retried" or "surfaced"
+}
+
+type latencyKey struct {
+	table  string
+	method string
+}
+
+// SupabaseMetrics records request counts, latency and error rates for Supabase calls
+type SupabaseMetrics struct {
+	mu        sync.RWMutex
+	requests  map[requestKey]uint64
+	throttles map[throttleKey]uint64
+	latencies map[latencyKey]*histogram
+
+	// Totals at the last alert check, used to compute per-window rates
+	lastTotal       uint64
+	lastServerError uint64
+	lastLatencySum  float64
+}
+
+// NewSupabaseMetrics creates an empty metrics collector
+func NewSupabaseMetrics() *SupabaseMetrics {
+	return &SupabaseMetrics{
+		requests:  make(map[requestKey]uint64),
+		throttles: make(map[throttleKey]uint64),
+		latencies: make(map[latencyKey]*histogram),
+	}
+}
+
+// StatusClass buckets an HTTP status into "2xx".."5xx", or "network_error" when the request never got a response
+func StatusClass(status int, err error) string {
+	if err != nil || status == 0 {
+		return "network_error"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// Observe records one completed request
+func (m *SupabaseMetrics) Observe(table, method string, status int, err error, duration time.Duration) {
+	seconds := duration.Seconds()
+	rk := requestKey{table: table, method: method, statusClass: StatusClass(status, err)}
+	lk := latencyKey{table: table, method: method}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[rk]++
+
+	hist, ok := m.latencies[lk]
+	if !ok {
+		hist = newHistogram(supabaseLatencyBuckets)
+		m.latencies[lk] = hist
+	}
+	hist.observe(supabaseLatencyBuckets, seconds)
+}
+
+// ObserveThrottle records a 429 from Supabase, and whether it was retried or passed on
+// to the caller
+func (m *SupabaseMetrics) ObserveThrottle(table, method string, retried bool) {
+	outcome := "surfaced"
+	if retried {
+		outcome = "retried"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.throttles[throttleKey{table: table, method: method, outcome: outcome}]++
+}
+
+// ThrottleCount returns the number of 429s recorded for the given labels
+func (m *SupabaseMetrics) ThrottleCount(table, method, outcome string) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.throttles[throttleKey{table: table, method: method, outcome: outcome}]
+}
+
+// RequestCount returns the number of requests recorded for the given labels
+func (m *SupabaseMetrics) RequestCount(table, method, statusClass string) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.requests[requestKey{table: table, method: method, statusClass: statusClass}]
+}
+
+// WritePrometheus appends all Supabase metrics to sb in Prometheus text format
+func (m *SupabaseMetrics) WritePrometheus(sb *strings.Builder) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reqKeys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		reqKeys = append(reqKeys, k)
+	}
+	sort.Slice(reqKeys, func(i, j int) bool {
+		a, b := reqKeys[i], reqKeys[j]
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.statusClass < b.statusClass
+	})
+
+	sb.WriteString("# HELP histeeria_supabase_requests_total Supabase REST requests by table, method and status class\n")
+	sb.WriteString("# TYPE histeeria_supabase_requests_total counter\n")
+	for _, k := range reqKeys {
+		sb.WriteString(fmt.Sprintf("histeeria_supabase_requests_total{table=%q,method=%q,status_class=%q} %d\n",
+			k.table, k.method, k.statusClass, m.requests[k]))
+	}
+
+	sb.WriteString("# HELP histeeria_supabase_errors_total Supabase REST requests that failed (4xx, 5xx or network error)\n")
+	sb.WriteString("# TYPE histeeria_supabase_errors_total counter\n")
+	for _, k := range reqKeys {
+		if k.statusClass == "4xx" || k.statusClass == "5xx" || k.statusClass == "network_error" {
+			sb.WriteString(fmt.Sprintf("histeeria_supabase_errors_total{table=%q,method=%q,status_class=%q} %d\n",
+				k.table, k.method, k.statusClass, m.requests[k]))
+		}
+	}
+
+	throttleKeys := make([]throttleKey, 0, len(m.throttles))
+	for k := range m.throttles {
+		throttleKeys = append(throttleKeys, k)
+	}
+	sort.Slice(throttleKeys, func(i, j int) bool {
+		a, b := throttleKeys[i], throttleKeys[j]
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.outcome < b.outcome
+	})
+
+	sb.WriteString("# HELP histeeria_supabase_throttled_total Supabase REST requests answered with 429, by whether they were retried or surfaced\n")
+	sb.WriteString("# TYPE histeeria_supabase_throttled_total counter\n")
+	for _, k := range throttleKeys {
+		sb.WriteString(fmt.Sprintf("histeeria_supabase_throttled_total{table=%q,method=%q,outcome=%q} %d\n",
+			k.table, k.method, k.outcome, m.throttles[k]))
+	}
+
+	latKeys := make([]latencyKey, 0, len(m.latencies))
+	for k := range m.latencies {
+		latKeys = append(latKeys, k)
+	}
+	sort.Slice(latKeys, func(i, j int) bool {
+		if latKeys[i].table != latKeys[j].table {
+			return latKeys[i].table < latKeys[j].table
+		}
+		return latKeys[i].method < latKeys[j].method
+	})
+
+	sb.WriteString("# HELP histeeria_supabase_request_duration_seconds Supabase REST request latency\n")
+	sb.WriteString("# TYPE histeeria_supabase_request_duration_seconds histogram\n")
+	for _, k := range latKeys {
+		m.latencies[k].write(sb, "histeeria_supabase_request_duration_seconds",
+			fmt.Sprintf("table=%q,method=%q", k.table, k.method), supabaseLatencyBuckets)
+	}
+}
+
+// CheckAlerts compares traffic since the previous check against the alert thresholds.
+// It logs and returns an error when the server error ratio or average latency is too high,
+// so it can run as a scheduled job and surface in job error stats.
+func (m *SupabaseMetrics) CheckAlerts(ctx context.Context) error {
+	m.mu.Lock()
+	var total, serverErrors uint64
+	for k, count := range m.requests {
+		total += count
+		if k.statusClass == "5xx" || k.statusClass == "network_error" {
+			serverErrors += count
+		}
+	}
+	var latencySum float64
+	for _, hist := range m.latencies {
+		latencySum += hist.sum
+	}
+
+	windowTotal := total - m.lastTotal
+	windowErrors := serverErrors - m.lastServerError
+	windowLatency := latencySum - m.lastLatencySum
+	m.lastTotal, m.lastServerError, m.lastLatencySum = total, serverErrors, latencySum
+	m.mu.Unlock()
+
+	if windowTotal < alertMinRequests {
+		return nil
+	}
+
+	var problems []string
+	if ratio := float64(windowErrors) / float64(windowTotal); ratio > alertServerErrorRatio {
+		problems = append(problems, fmt.Sprintf("server error rate %.1f%% (%d/%d requests)", ratio*100, windowErrors, windowTotal))
+	}
+	if avg := time.Duration(windowLatency / float64(windowTotal) * float64(time.Second)); avg > alertAvgLatency {
+		problems = append(problems, fmt.Sprintf("average latency %s", avg.Round(time.Millisecond)))
+	}
+
+	if len(problems) > 0 {
+		msg := strings.Join(problems, ", ")
+		log.Printf("[SupabaseMetrics] ALERT: %s", msg)
+		return fmt.Errorf("supabase degraded: %s", msg)
+	}
+	return nil
+}
+
+// supabaseTransport is an http.RoundTripper that records metrics for Supabase REST calls
+type supabaseTransport struct {
+	base    http.RoundTripper
+	metrics *SupabaseMetrics
+}
+
+// NewSupabaseTransport wraps base (http.DefaultTransport if nil) so every request is recorded in Supabase metrics
+func NewSupabaseTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &supabaseTransport{base: base, metrics: Supabase}
+}
+
+// RoundTrip executes the request and records its table, method, status and latency
+func (t *supabaseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.metrics.Observe(TableFromPath(req.URL.Path), req.Method, status, err, time.Since(start))
+
+	return resp, err
+}
+
+// TableFromPath extracts the PostgREST table (or "rpc/<function>") from a request path
+// such as /rest/v1/posts or /rest/v1/rpc/get_feed. Other Supabase APIs are labelled by service.
+func TableFromPath(path string) string {
+	const restPrefix = "/rest/v1/"
+	if idx := strings.Index(path, restPrefix); idx >= 0 {
+		rest := strings.Trim(path[idx+len(restPrefix):], "/")
+		if strings.HasPrefix(rest, "rpc/") {
+			return rest
+		}
+		if slash := strings.Index(rest, "/"); slash >= 0 {
+			rest = rest[:slash]
+		}
+		if rest != "" {
+			return rest
+		}
+	}
+	if strings.Contains(path, "/storage/v1/") {
+		return "storage"
+	}
+	if strings.Contains(path, "/auth/v1/") {
+		return "auth"
+	}
+	return "other"
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailingRequestCountsAsErrorWithLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	m := NewSupabaseMetrics()
+	client := &http.Client{Transport: &supabaseTransport{base: http.DefaultTransport, metrics: m}}
+	resp, err := client.Get(server.URL + "/rest/v1/posts?select=id")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := m.RequestCount("posts", http.MethodGet, "5xx"); got != 1 {
+		t.Errorf("recorded %d posts GET 5xx requests, want 1", got)
+	}
+	var sb strings.Builder
+	m.WritePrometheus(&sb)
+	want := `histeeria_supabase_errors_total{table="posts",method="GET",status_class="5xx"} 1`
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("metrics output is missing %s:\n%s", want, sb.String())
+	}
+}
+
+func TestUnreachableSupabaseCountsAsNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	m := NewSupabaseMetrics()
+	client := &http.Client{Transport: &supabaseTransport{base: http.DefaultTransport, metrics: m}}
+	if _, err := client.Post(url+"/rest/v1/rpc/block_user", "application/json", nil); err == nil {
+		t.Fatal("request to a closed server should fail")
+	}
+	if got := m.RequestCount("rpc/block_user", http.MethodPost, "network_error"); got != 1 {
+		t.Errorf("recorded %d network errors, want 1", got)
+	}
+}
+
+func TestTableFromPath(t *testing.T) {
+	tests := map[string]string{
+		"/rest/v1/posts":                   "posts",
+		"/rest/v1/posts/":                  "posts",
+		"/rest/v1/rpc/get_feed":            "rpc/get_feed",
+		"/storage/v1/object/avatars/a.jpg": "storage",
+		"/auth/v1/token":                   "auth",
+		"/elsewhere":                       "other",
+	}
+	for path, want := range tests {
+		if got := TableFromPath(path); got != want {
+			t.Errorf("TableFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestCheckAlertsOnServerErrorRate(t *testing.T) {
+	m := NewSupabaseMetrics()
+	for i := 0; i < alertMinRequests; i++ {
+		m.Observe("posts", http.MethodGet, http.StatusOK, nil, time.Millisecond)
+	}
+	if err := m.CheckAlerts(context.Background()); err != nil {
+		t.Fatalf("healthy traffic alerted: %v", err)
+	}
+
+	for i := 0; i < alertMinRequests; i++ {
+		status := http.StatusOK
+		if i%4 == 0 {
+			status = http.StatusInternalServerError
+		}
+		m.Observe("posts", http.MethodGet, status, nil, time.Millisecond)
+	}
+	if err := m.CheckAlerts(context.Background()); err == nil {
+		t.Error("a 25% server error rate should alert")
+	}
+}
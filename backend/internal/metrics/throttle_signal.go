@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type throttleSignalKey struct{}
+
+// ThrottleSignal records whether Supabase throttled any call made on behalf of one API
+// request. Repositories turn a 429 into a generic database error; the signal lets the
+// HTTP layer still tell the client to back off.
+type ThrottleSignal struct {
+	mu         sync.Mutex
+	throttled  bool
+	retryAfter time.Duration
+}
+
+// WithThrottleSignal attaches a fresh signal to ctx
+func WithThrottleSignal(ctx context.Context) (context.Context, *ThrottleSignal) {
+	signal := &ThrottleSignal{}
+	return context.WithValue(ctx, throttleSignalKey{}, signal), signal
+}
+
+// MarkThrottled flags the request behind ctx as throttled, keeping the longest wait
+// Supabase asked for. It does nothing for contexts without a signal, such as jobs.
+func MarkThrottled(ctx context.Context, retryAfter time.Duration) {
+	signal, ok := ctx.Value(throttleSignalKey{}).(*ThrottleSignal)
+	if !ok {
+		return
+	}
+	signal.mu.Lock()
+	defer signal.mu.Unlock()
+	signal.throttled = true
+	if retryAfter > signal.retryAfter {
+		signal.retryAfter = retryAfter
+	}
+}
+
+// Throttled reports whether any call was throttled, and how long Supabase asked to wait
+func (s *ThrottleSignal) Throttled() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.retryAfter, s.throttled
+}
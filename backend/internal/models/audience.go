@@ -0,0 +1,41 @@
+package models
+
+import "github.com/google/uuid"
+
+// Who a post or status is shared with
+const (
+	VisibilityPublic       = "public"
+	VisibilityConnections  = "connections"
+	VisibilityPrivate      = "private"
+	VisibilityCloseFriends = "close_friends" // The author's close friends list
+)
+
+// CloseFriendsOf is the set of users whose close friends lists include a viewer
+type CloseFriendsOf map[uuid.UUID]bool
+
+// NewCloseFriendsOf builds the set from the IDs of the lists' owners
+func NewCloseFriendsOf(ownerIDs []uuid.UUID) CloseFriendsOf {
+	set := make(CloseFriendsOf, len(ownerIDs))
+	for _, id := range ownerIDs {
+		set[id] = true
+	}
+	return set
+}
+
+// CanSee reports whether the viewer may see content ownerID shared with visibility.
+// Authors always see their own content; public content is for everyone and
+// close_friends content for the owner's close friends. Anything else stays with the
+// author.
+func (c CloseFriendsOf) CanSee(ownerID, viewerID uuid.UUID, visibility string) bool {
+	if ownerID == viewerID && viewerID != uuid.Nil {
+		return true
+	}
+	switch visibility {
+	case VisibilityPublic, "":
+		return true
+	case VisibilityCloseFriends:
+		return c[ownerID]
+	default:
+		return false
+	}
+}
@@ -15,6 +15,7 @@ type Comment struct {
 	Content         string     `json:"content"`
 	MediaURL        string     `json:"media_url,omitempty"`
 	MediaType       string     `json:"media_type,omitempty"` // 'gif', 'image'
+	Language        string     `json:"language,omitempty"`   // Detected ISO 639-1 code, empty if unknown
 	LikesCount      int        `json:"likes_count"`
 	RepliesCount    int        `json:"replies_count"`
 	IsEdited        bool       `json:"is_edited"`
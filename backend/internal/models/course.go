@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -59,6 +60,7 @@ type Course struct {
 
 	// Computed fields
 	IsEnrolled       bool              `json:"is_enrolled,omitempty"`     // For authenticated users
+	IsWishlisted     bool              `json:"is_wishlisted,omitempty"`   // For authenticated users
 	Enrollment       *CourseEnrollment `json:"enrollment,omitempty"`      // If enrolled
 	IsCollaborator   bool              `json:"is_collaborator,omitempty"` // If user is collaborator
 	CollaboratorRole *string           `json:"collaborator_role,omitempty"`
@@ -101,6 +103,15 @@ type CourseLesson struct {
 	Progress *LessonProgress `json:"progress,omitempty"` // For enrolled users
 }
 
+// LessonVideoURL is a playback URL for a lesson video. Stored videos get a short-lived
+// signed URL (Signed=true); externally hosted videos are returned as stored.
+type LessonVideoURL struct {
+	LessonID  uuid.UUID  `json:"lesson_id"`
+	URL       string     `json:"url"`
+	Signed    bool       `json:"signed"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
 // CourseEnrollment represents a user's enrollment in a course
 type CourseEnrollment struct {
 	ID                 uuid.UUID  `json:"id"`
@@ -109,7 +120,7 @@ type CourseEnrollment struct {
 	User               *User      `json:"user,omitempty"`
 	EnrolledAt         time.Time  `json:"enrolled_at"`
 	CompletedAt        *time.Time `json:"completed_at,omitempty"`
-	ProgressPercentage float64    `json:"progress_percentage"` // 0.00 to 100.00
+	ProgressPercentage float64    `json:"progress_percentage"` // 0.00 to 100.00, share of the course's lessons completed
 
 	// Payment info
 	PaymentStatus        string   `json:"payment_status"` // free, pending, paid, refunded
@@ -119,6 +130,9 @@ type CourseEnrollment struct {
 
 	LastAccessedAt       *time.Time `json:"last_accessed_at,omitempty"`
 	LastAccessedLessonID *uuid.UUID `json:"last_accessed_lesson_id,omitempty"`
+
+	// Set before creating the enrollment to redeem a coupon against PaymentAmount
+	CouponCode string `json:"-"`
 }
 
 // CourseCollaborator represents a collaborator on a course
@@ -204,6 +218,145 @@ type CourseCertificate struct {
 	CertificateNumber string    `json:"certificate_number"`
 	CertificateURL    *string   `json:"certificate_url,omitempty"`
 	IssuedAt          time.Time `json:"issued_at"`
+	Course            *Course   `json:"course,omitempty"`
+}
+
+// CertificateDownload is a short-lived signed link to a certificate's PDF
+type CertificateDownload struct {
+	Certificate *CourseCertificate `json:"certificate"`
+	URL         string             `json:"url"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+}
+
+// CourseAnalytics is how a course is doing, for its instructors
+type CourseAnalytics struct {
+	CourseID             uuid.UUID                `json:"course_id"`
+	TotalEnrollments     int                      `json:"total_enrollments"`
+	CompletedEnrollments int                      `json:"completed_enrollments"`
+	CompletionRate       float64                  `json:"completion_rate"`  // Percent of enrollments completed
+	AverageProgress      float64                  `json:"average_progress"` // Mean progress_percentage
+	PaidEnrollments      int                      `json:"paid_enrollments"`
+	Revenue              float64                  `json:"revenue"` // Sum of paid enrollments, in Currency
+	Currency             string                   `json:"currency"`
+	EnrollmentsByDay     []CourseEnrollmentsOnDay `json:"enrollments_by_day"`  // Oldest first
+	RatingDistribution   map[string]int           `json:"rating_distribution"` // Stars ("1" to "5") to review count
+	MostDroppedLesson    *LessonDropOff           `json:"most_dropped_lesson,omitempty"`
+}
+
+// CourseEnrollmentsOnDay is how many learners enrolled on a day (UTC)
+type CourseEnrollmentsOnDay struct {
+	Date        string `json:"date"` // YYYY-MM-DD
+	Enrollments int    `json:"enrollments"`
+}
+
+// LessonDropOff is the lesson the fewest enrollees have completed
+type LessonDropOff struct {
+	LessonID       uuid.UUID `json:"lesson_id"`
+	Title          string    `json:"title"`
+	Completions    int       `json:"completions"`
+	CompletionRate float64   `json:"completion_rate"` // Percent of enrollments
+}
+
+// Coupon discount types
+const (
+	CouponDiscountPercent = "percent"
+	CouponDiscountFixed   = "fixed"
+)
+
+// CourseCoupon is a discount code for one course, or for every paid course when
+// CourseID is nil
+type CourseCoupon struct {
+	ID            uuid.UUID  `json:"id"`
+	Code          string     `json:"code"` // Upper case
+	CourseID      *uuid.UUID `json:"course_id,omitempty"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty"`
+	DiscountType  string     `json:"discount_type"`      // percent, fixed
+	DiscountValue float64    `json:"discount_value"`     // Percent off, or amount off in Currency
+	Currency      *string    `json:"currency,omitempty"` // Fixed discounts only
+	MaxUses       *int       `json:"max_uses,omitempty"` // Unlimited if nil
+	UsedCount     int        `json:"used_count"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	IsActive      bool       `json:"is_active"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NormalizeCouponCode puts a coupon code in its stored form
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CouponQuote is what a course costs with a coupon applied
+type CouponQuote struct {
+	Coupon         *CourseCoupon `json:"coupon"`
+	OriginalAmount float64       `json:"original_amount"`
+	DiscountAmount float64       `json:"discount_amount"`
+	FinalAmount    float64       `json:"final_amount"`
+	Currency       string        `json:"currency"`
+}
+
+// Quiz question types
+const (
+	QuizQuestionSingleChoice   = "single_choice"
+	QuizQuestionMultipleChoice = "multiple_choice"
+)
+
+// Quiz is the assessment of a quiz lesson. Learners complete the lesson by scoring at
+// least PassThreshold percent of its points.
+type Quiz struct {
+	ID            uuid.UUID       `json:"id"`
+	LessonID      uuid.UUID       `json:"lesson_id"`
+	CourseID      uuid.UUID       `json:"course_id"`
+	Title         string          `json:"title"`
+	Description   *string         `json:"description,omitempty"`
+	PassThreshold float64         `json:"pass_threshold"` // 0.00 to 100.00
+	Questions     []*QuizQuestion `json:"questions,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// QuizQuestion is a single or multiple choice question of a quiz
+type QuizQuestion struct {
+	ID             uuid.UUID    `json:"id"`
+	QuizID         uuid.UUID    `json:"quiz_id"`
+	QuestionText   string       `json:"question_text"`
+	QuestionType   string       `json:"question_type"` // single_choice, multiple_choice
+	Options        []QuizOption `json:"options"`
+	CorrectOptions []string     `json:"correct_options,omitempty"` // Option IDs; only shown to instructors
+	Points         int          `json:"points"`
+	Explanation    *string      `json:"explanation,omitempty"`
+	OrderIndex     int          `json:"order_index"`
+}
+
+// QuizOption is one of the answers offered by a quiz question
+type QuizOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// QuizAttempt is a learner's graded submission of a quiz
+type QuizAttempt struct {
+	ID             uuid.UUID              `json:"id"`
+	QuizID         uuid.UUID              `json:"quiz_id"`
+	LessonID       uuid.UUID              `json:"lesson_id"`
+	EnrollmentID   uuid.UUID              `json:"enrollment_id"`
+	UserID         uuid.UUID              `json:"user_id"`
+	Answers        map[uuid.UUID][]string `json:"answers"` // Question ID to chosen option IDs
+	PointsEarned   int                    `json:"points_earned"`
+	PointsPossible int                    `json:"points_possible"`
+	Score          float64                `json:"score"` // 0.00 to 100.00
+	Passed         bool                   `json:"passed"`
+	CreatedAt      time.Time              `json:"created_at"`
+
+	// Populated when the attempt is submitted
+	Results []*QuizQuestionResult `json:"results,omitempty"`
+}
+
+// QuizQuestionResult is how one question of an attempt was graded
+type QuizQuestionResult struct {
+	QuestionID   uuid.UUID `json:"question_id"`
+	Correct      bool      `json:"correct"`
+	PointsEarned int       `json:"points_earned"`
+	Explanation  *string   `json:"explanation,omitempty"`
 }
 
 // Request/Response Models
@@ -251,12 +404,16 @@ type UpdateCourseRequest struct {
 // CourseFilter represents filters for listing courses
 type CourseFilter struct {
 	Category        *string  `json:"category,omitempty"`
+	Categories      []string `json:"categories,omitempty"` // Any of these; combined with Category. Courses only
 	DifficultyLevel *string  `json:"difficulty_level,omitempty"`
 	IsFree          *bool    `json:"is_free,omitempty"`
+	MinPrice        *float64 `json:"min_price,omitempty"`  // Courses only
+	MaxPrice        *float64 `json:"max_price,omitempty"`  // Courses only
+	MinRating       *float64 `json:"min_rating,omitempty"` // Courses only
 	Language        *string  `json:"language,omitempty"`
 	Search          *string  `json:"search,omitempty"`
-	Tags            []string `json:"tags,omitempty"`
-	SortBy          string   `json:"sort_by"` // newest, popular, rating, price_asc, price_desc
+	Tags            []string `json:"tags,omitempty"` // All of these. Courses only
+	SortBy          string   `json:"sort_by"`        // newest, popular, rating, price_asc, price_desc
 	Limit           int      `json:"limit"`
 	Offset          int      `json:"offset"`
 }
@@ -283,6 +440,43 @@ type CreateLessonRequest struct {
 	Attachments   []string               `json:"attachments,omitempty"`
 }
 
+// CreateQuizRequest represents the request to add a quiz to a quiz lesson
+type CreateQuizRequest struct {
+	Title         string                      `json:"title" binding:"required,min=3,max=255"`
+	Description   *string                     `json:"description,omitempty"`
+	PassThreshold *float64                    `json:"pass_threshold,omitempty"` // Percent, defaults to 70
+	Questions     []CreateQuizQuestionRequest `json:"questions" binding:"required,min=1,max=100,dive"`
+}
+
+// CreateQuizQuestionRequest represents one question of a new quiz
+type CreateQuizQuestionRequest struct {
+	QuestionText   string   `json:"question_text" binding:"required"`
+	QuestionType   string   `json:"question_type"` // single_choice (default), multiple_choice
+	Options        []string `json:"options" binding:"required,min=2,max=10"`
+	CorrectOptions []int    `json:"correct_options" binding:"required,min=1"` // Indexes into Options
+	Points         int      `json:"points"`                                   // Defaults to 1
+	Explanation    *string  `json:"explanation,omitempty"`
+}
+
+// CreateCouponRequest represents the request to create a coupon for a course
+type CreateCouponRequest struct {
+	Code          string     `json:"code" binding:"required,min=3,max=50"`
+	DiscountType  string     `json:"discount_type" binding:"required,oneof=percent fixed"`
+	DiscountValue float64    `json:"discount_value" binding:"required,gt=0"`
+	MaxUses       *int       `json:"max_uses,omitempty" binding:"omitempty,min=1"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// EnrollRequest represents the request to enroll in a course
+type EnrollRequest struct {
+	CouponCode string `json:"coupon_code,omitempty"`
+}
+
+// SubmitQuizRequest represents a learner's answers to a quiz
+type SubmitQuizRequest struct {
+	Answers map[uuid.UUID][]string `json:"answers" binding:"required"` // Question ID to chosen option IDs
+}
+
 // CreateReviewRequest represents the request to create a course review
 type CreateReviewRequest struct {
 	Rating     int     `json:"rating" binding:"required,min=1,max=5"`
@@ -26,13 +26,38 @@ const (
 	MessageStatusSent      MessageStatus = "sent"      // Message sent from sender (✓)
 	MessageStatusDelivered MessageStatus = "delivered" // Message received by server/recipient (✓✓)
 	MessageStatusRead      MessageStatus = "read"      // Message read by recipient (✓✓ blue)
+	MessageStatusFailed    MessageStatus = "failed"    // Delivery retries ran out (!)
 )
 
-// Conversation represents a 1-on-1 chat between two users
+// ScheduledMessageStatus is where a scheduled message is on its way to being sent
+type ScheduledMessageStatus string
+
+const (
+	ScheduledMessagePending   ScheduledMessageStatus = "pending"   // Waiting for its time; can be edited or cancelled
+	ScheduledMessageSending   ScheduledMessageStatus = "sending"   // Claimed by the send job
+	ScheduledMessageSent      ScheduledMessageStatus = "sent"      // In the conversation as MessageID
+	ScheduledMessageCancelled ScheduledMessageStatus = "cancelled" // Cancelled by the sender
+	ScheduledMessageFailed    ScheduledMessageStatus = "failed"    // Couldn't be sent, see FailureReason
+)
+
+// ConversationRole is a member's role in a group conversation
+type ConversationRole string
+
+const (
+	ConversationRoleAdmin  ConversationRole = "admin" // Can add and remove members and rename the group
+	ConversationRoleMember ConversationRole = "member"
+)
+
+// Conversation represents a 1-on-1 chat between two users, or a group chat. Groups
+// have no participants; their members are in Members.
 type Conversation struct {
 	ID                   uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Participant1ID       uuid.UUID  `json:"participant1_id" gorm:"type:uuid;not null"`
-	Participant2ID       uuid.UUID  `json:"participant2_id" gorm:"type:uuid;not null"`
+	Participant1ID       uuid.UUID  `json:"participant1_id" gorm:"type:uuid"`
+	Participant2ID       uuid.UUID  `json:"participant2_id" gorm:"type:uuid"`
+	IsGroup              bool       `json:"is_group" gorm:"default:false"`
+	Name                 *string    `json:"name,omitempty"`
+	CreatedBy            *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+	MessageTTLSeconds    *int       `json:"message_ttl_seconds"` // Disappearing messages; nil when off
 	LastMessageContent   *string    `json:"last_message_content"`
 	LastMessageEncrypted *string    `json:"last_message_encrypted"`
 	LastMessageIV        *string    `json:"last_message_iv"`
@@ -48,8 +73,9 @@ type Conversation struct {
 	UpdatedAt            time.Time  `json:"updated_at" gorm:"default:now()"`
 
 	// Joined data (not in database, populated in queries)
-	Participant1 *User `json:"participant1,omitempty" gorm:"foreignKey:Participant1ID"`
-	Participant2 *User `json:"participant2,omitempty" gorm:"foreignKey:Participant2ID"`
+	Participant1 *User                 `json:"participant1,omitempty" gorm:"foreignKey:Participant1ID"`
+	Participant2 *User                 `json:"participant2,omitempty" gorm:"foreignKey:Participant2ID"`
+	Members      []*ConversationMember `json:"members,omitempty" gorm:"-"` // Groups only, including members who left
 
 	// Computed fields (set in business logic based on current user)
 	OtherUser   *User      `json:"other_user,omitempty" gorm:"-"` // The other person in the conversation
@@ -59,6 +85,120 @@ type Conversation struct {
 	LastSeen    *time.Time `json:"last_seen,omitempty" gorm:"-"`  // Other user's last seen (from Redis)
 }
 
+// Member returns userID's membership of a group conversation, or nil
+func (c *Conversation) Member(userID uuid.UUID) *ConversationMember {
+	for _, m := range c.Members {
+		if m.UserID == userID {
+			return m
+		}
+	}
+	return nil
+}
+
+// IsParticipant reports whether userID can read and post in the conversation.
+// Group members stop being participants when they leave.
+func (c *Conversation) IsParticipant(userID uuid.UUID) bool {
+	if c.IsGroup {
+		m := c.Member(userID)
+		return m != nil && m.IsActive()
+	}
+	return c.Participant1ID == userID || c.Participant2ID == userID
+}
+
+// OtherParticipantIDs returns who else is in the conversation: the other user of a
+// 1-on-1 chat, or the group's current members except userID
+func (c *Conversation) OtherParticipantIDs(userID uuid.UUID) []uuid.UUID {
+	if !c.IsGroup {
+		if c.Participant1ID == userID {
+			return []uuid.UUID{c.Participant2ID}
+		}
+		return []uuid.UUID{c.Participant1ID}
+	}
+
+	ids := make([]uuid.UUID, 0, len(c.Members))
+	for _, m := range c.Members {
+		if m.UserID != userID && m.IsActive() {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids
+}
+
+// SetViewer fills in the computed fields for userID: the other user and unread
+// count of a 1-on-1 chat, or userID's unread count in a group
+func (c *Conversation) SetViewer(userID uuid.UUID) {
+	if c.IsGroup {
+		if m := c.Member(userID); m != nil {
+			c.UnreadCount = m.UnreadCount
+		}
+		return
+	}
+
+	if c.Participant1ID == userID {
+		c.OtherUser = c.Participant2
+		c.UnreadCount = c.UnreadCountP1
+	} else {
+		c.OtherUser = c.Participant1
+		c.UnreadCount = c.UnreadCountP2
+	}
+}
+
+// ConversationMember is one member of a group conversation. Members who leave keep
+// their row, with LeftAt set.
+type ConversationMember struct {
+	ConversationID uuid.UUID        `json:"conversation_id"`
+	UserID         uuid.UUID        `json:"user_id"`
+	Role           ConversationRole `json:"role"`
+	UnreadCount    int              `json:"unread_count"`
+	JoinedAt       time.Time        `json:"joined_at"`
+	LeftAt         *time.Time       `json:"left_at,omitempty"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"` // Everything sent up to here has reached the member
+	ReadAt         *time.Time       `json:"read_at,omitempty"`      // Everything sent up to here has been read
+
+	// Joined data
+	User *User `json:"user,omitempty"`
+}
+
+// IsActive reports whether the member is still in the group
+func (m *ConversationMember) IsActive() bool {
+	return m.LeftAt == nil
+}
+
+// IsAdmin reports whether the member can manage the group
+func (m *ConversationMember) IsAdmin() bool {
+	return m.IsActive() && m.Role == ConversationRoleAdmin
+}
+
+// Receipt returns how far msg has got with the member, or nil if the member wasn't
+// in the group when it was sent
+func (m *ConversationMember) Receipt(msg *Message) *MessageReceipt {
+	if msg.CreatedAt.Before(m.JoinedAt) {
+		return nil
+	}
+
+	receipt := &MessageReceipt{UserID: m.UserID, Status: MessageStatusSent}
+	if m.DeliveredAt != nil && !m.DeliveredAt.Before(msg.CreatedAt) {
+		receipt.Status = MessageStatusDelivered
+		receipt.DeliveredAt = m.DeliveredAt
+	}
+	if m.ReadAt != nil && !m.ReadAt.Before(msg.CreatedAt) {
+		receipt.Status = MessageStatusRead
+		receipt.ReadAt = m.ReadAt
+		if receipt.DeliveredAt == nil {
+			receipt.DeliveredAt = m.ReadAt
+		}
+	}
+	return receipt
+}
+
+// MessageReceipt is one recipient's delivery state for a message
+type MessageReceipt struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	Status      MessageStatus `json:"status"`
+	DeliveredAt *time.Time    `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time    `json:"read_at,omitempty"`
+}
+
 // Message represents a single message in a conversation
 type Message struct {
 	ID               uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -98,10 +238,17 @@ type Message struct {
 	ForwardedFromID *uuid.UUID `json:"forwarded_from_id,omitempty" gorm:"type:uuid"`
 	IsForwarded     bool       `json:"is_forwarded" gorm:"default:false"`
 
+	// Disappearing messages: set on send from the conversation's TTL
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Voice messages: peak amplitude of each waveform bar, 0-1
+	Waveform []float64 `json:"waveform,omitempty" gorm:"type:real[]"`
+
 	// Joined data (populated in queries)
 	Sender         *User              `json:"sender,omitempty" gorm:"foreignKey:SenderID"`
 	ReplyToMessage *Message           `json:"reply_to,omitempty" gorm:"foreignKey:ReplyToID"`
 	Reactions      []*MessageReaction `json:"reactions,omitempty" gorm:"foreignKey:MessageID"`
+	ReactionCounts *ReactionSummary   `json:"reaction_counts,omitempty" gorm:"-"` // Set when listing messages, for the viewer
 
 	// Computed fields
 	IsMine    bool `json:"is_mine" gorm:"-"`    // Is this message from current user
@@ -109,6 +256,39 @@ type Message struct {
 	IsPinned  bool `json:"is_pinned" gorm:"-"`  // Is this message pinned in conversation
 }
 
+// ScheduledMessage is a message its sender asked to send later. It isn't part of the
+// conversation until it sends, at which point MessageID is the message it became.
+type ScheduledMessage struct {
+	ID             uuid.UUID              `json:"id"`
+	ConversationID uuid.UUID              `json:"conversation_id"`
+	SenderID       uuid.UUID              `json:"sender_id"`
+	Message        MessageRequest         `json:"message"` // What to send, as the sender asked for it
+	SendAt         time.Time              `json:"send_at"`
+	Status         ScheduledMessageStatus `json:"status"`
+	MessageID      *uuid.UUID             `json:"message_id,omitempty"`
+	FailureReason  *string                `json:"failure_reason,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// MessageSearchFilter narrows a message search. Nil fields don't filter.
+type MessageSearchFilter struct {
+	ConversationID *uuid.UUID
+	SenderID       *uuid.UUID
+	From           *time.Time // Inclusive
+	To             *time.Time // Exclusive
+}
+
+// MessageSearchHit is a message matching a search, with what the UI needs to show the
+// match in context and jump to it in its conversation
+type MessageSearchHit struct {
+	*Message
+	Rank    float64  `json:"rank"`             // Relevance; higher is better
+	Snippet string   `json:"snippet"`          // Best fragments of the content, matched terms wrapped in <mark></mark>
+	Before  *Message `json:"before,omitempty"` // The message just before the match, if any
+	After   *Message `json:"after,omitempty"`  // The message just after the match, if any
+}
+
 // MessageReaction represents an emoji reaction on a message
 type MessageReaction struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -121,6 +301,19 @@ type MessageReaction struct {
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// ReactionSummary sums up the reactions on a message as one user sees them
+type ReactionSummary struct {
+	Emojis       []*EmojiReactionCount `json:"emojis"`        // Most used first; capped, so may not cover every reactor
+	ReactorCount int                   `json:"reactor_count"` // Everyone who reacted, with any emoji
+}
+
+// EmojiReactionCount is how many users reacted to a message with one emoji
+type EmojiReactionCount struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
 // StarredMessage represents a user's starred/bookmarked message
 type StarredMessage struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -147,6 +340,9 @@ const (
 	WSMessageTypeMessageEdited    WSMessageType = "message_edited"
 	WSMessageTypeMessagePinned    WSMessageType = "message_pinned"
 	WSMessageTypeMessageUnpinned  WSMessageType = "message_unpinned"
+	WSMessageTypeGroupUpdated     WSMessageType = "group_updated"            // Members or name changed
+	WSMessageTypeSettingsUpdated  WSMessageType = "settings_updated"         // Conversation settings such as disappearing messages changed
+	WSMessageTypeScheduledFailed  WSMessageType = "scheduled_message_failed" // A scheduled message couldn't be sent, to its sender
 	WSMessageTypeOnline           WSMessageType = "online"
 	WSMessageTypeOffline          WSMessageType = "offline"
 	WSMessageTypeACK              WSMessageType = "ack"
@@ -176,6 +372,7 @@ type TypingInfo struct {
 	DisplayName    string    `json:"display_name"`
 	IsTyping       bool      `json:"is_typing"`
 	IsRecording    bool      `json:"is_recording"`
+	ExpiresIn      int       `json:"expires_in,omitempty"` // Seconds to show the indicator for unless it's refreshed
 }
 
 // MessageRequest represents a request to send a message
@@ -189,7 +386,42 @@ type MessageRequest struct {
 	AttachmentSize   *int        `json:"attachment_size"`
 	AttachmentType   *string     `json:"attachment_type"`
 	ReplyToID        *uuid.UUID  `json:"reply_to_id"`
-	TempID           *string     `json:"temp_id"` // For optimistic UI tracking
+	TempID           *string     `json:"temp_id"`            // For optimistic UI tracking
+	Waveform         []float64   `json:"waveform"`           // Audio messages: the waveform returned by the upload
+	SendAt           *string     `json:"send_at,omitempty"`  // Schedules the message to send then instead of now, see Timezone
+	Timezone         string      `json:"timezone,omitempty"` // IANA zone a local send_at is in; the sender's own zone if empty
+}
+
+// EditScheduledMessageRequest changes a scheduled message that hasn't been sent.
+// Encrypted messages are edited by sending new encrypted content with its IV.
+type EditScheduledMessageRequest struct {
+	Content          *string `json:"content"`
+	EncryptedContent *string `json:"encrypted_content"`
+	IV               *string `json:"iv"`
+	SendAt           *string `json:"send_at"`  // RFC3339, or a local time in Timezone
+	Timezone         string  `json:"timezone"` // The sender's own zone if empty
+}
+
+// CreateGroupRequest represents a request to create a group conversation
+type CreateGroupRequest struct {
+	Name      string      `json:"name" binding:"required"`
+	MemberIDs []uuid.UUID `json:"member_ids" binding:"required"` // Not including the creator
+}
+
+// AddMembersRequest represents a request to add members to a group
+type AddMembersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" binding:"required"`
+}
+
+// RenameGroupRequest represents a request to rename a group
+type RenameGroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// SetDisappearingMessagesRequest turns disappearing messages on for a conversation,
+// or off with a TTL of 0
+type SetDisappearingMessagesRequest struct {
+	TTLSeconds int `json:"ttl_seconds" binding:"min=0"`
 }
 
 // ReactionRequest represents a request to add a reaction
@@ -255,3 +487,7 @@ func (MessageReaction) TableName() string {
 func (StarredMessage) TableName() string {
 	return "starred_messages"
 }
+
+func (ConversationMember) TableName() string {
+	return "conversation_members"
+}
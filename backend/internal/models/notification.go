@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/base64"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +20,8 @@ const (
 	// Social notifications
 	NotificationFollow                NotificationType = "follow"
 	NotificationFollowBack            NotificationType = "follow_back"
+	NotificationFollowRequest         NotificationType = "follow_request"
+	NotificationFollowRequestAccepted NotificationType = "follow_request_accepted"
 	NotificationConnectionRequest     NotificationType = "connection_request"
 	NotificationConnectionAccepted    NotificationType = "connection_accepted"
 	NotificationConnectionRejected    NotificationType = "connection_rejected"
@@ -70,6 +75,19 @@ const (
 	EmailFrequencyNever   EmailFrequency = "never"
 )
 
+// DigestPeriod is how often a digest goes out at this frequency, or 0 if f isn't a
+// digest frequency
+func (f EmailFrequency) DigestPeriod() time.Duration {
+	switch f {
+	case EmailFrequencyDaily:
+		return 24 * time.Hour
+	case EmailFrequencyWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
 // =====================================================
 // CORE MODELS
 // =====================================================
@@ -88,6 +106,7 @@ type Notification struct {
 	TargetType   *string                `json:"target_type,omitempty" db:"target_type"`
 	ActionURL    *string                `json:"action_url,omitempty" db:"action_url"`
 	IsRead       bool                   `json:"is_read" db:"is_read"`
+	SeenAt       *time.Time             `json:"seen_at,omitempty" db:"seen_at"` // When the badge that counted it was cleared
 	IsActionable bool                   `json:"is_actionable" db:"is_actionable"`
 	ActionType   *string                `json:"action_type,omitempty" db:"action_type"`
 	ActionTaken  bool                   `json:"action_taken" db:"action_taken"`
@@ -104,8 +123,10 @@ type NotificationPreferences struct {
 	EmailFrequency       EmailFrequency  `json:"email_frequency" db:"email_frequency"`
 	InAppEnabled         bool            `json:"in_app_enabled" db:"in_app_enabled"`
 	PushEnabled          bool            `json:"push_enabled" db:"push_enabled"`
+	PushTypes            map[string]bool `json:"push_types" db:"push_types"` // Types missing from the map are pushed
 	InlineActionsEnabled bool            `json:"inline_actions_enabled" db:"inline_actions_enabled"`
 	CategoriesEnabled    map[string]bool `json:"categories_enabled" db:"categories_enabled"`
+	MutedUntil           *time.Time      `json:"muted_until,omitempty" db:"muted_until"` // No push or email until then
 	UpdatedAt            time.Time       `json:"updated_at" db:"updated_at"`
 }
 
@@ -123,6 +144,7 @@ type NotificationResponse struct {
 	Actor        *NotificationActor     `json:"actor,omitempty"`
 	ActionURL    *string                `json:"action_url,omitempty"`
 	IsRead       bool                   `json:"is_read"`
+	IsSeen       bool                   `json:"is_seen"` // Counted by the badge no more; it can still be unread
 	IsActionable bool                   `json:"is_actionable"`
 	ActionType   *string                `json:"action_type,omitempty"`
 	ActionTaken  bool                   `json:"action_taken"`
@@ -147,12 +169,75 @@ type NotificationListResponse struct {
 	Unread        int                     `json:"unread"`
 	Limit         int                     `json:"limit"`
 	Offset        int                     `json:"offset"`
+	NextCursor    *string                 `json:"next_cursor"` // Null on the last page
+}
+
+// NotificationFilter narrows and pages a user's notification list
+type NotificationFilter struct {
+	Category    *NotificationCategory
+	Types       []NotificationType // Any of these; empty means every type
+	Unread      *bool
+	UnreadFirst bool // Order unread notifications before read ones
+	Limit       int
+	Offset      int                 // Only used without a Cursor
+	Cursor      *NotificationCursor // Continue after this position instead of paging by offset
+	After       *NotificationCursor // Only notifications newer than this, oldest first; replaces Offset and Cursor
+}
+
+// NotificationCursor marks the last notification of a page. Paging by it keeps the
+// list stable while new notifications arrive at the top.
+type NotificationCursor struct {
+	IsRead    bool // Only meaningful when the list is ordered unread first
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// ErrInvalidNotificationCursor is returned for a cursor that wasn't issued by NextCursor
+var ErrInvalidNotificationCursor = errors.New("invalid notification cursor")
+
+const cursorTimeLayout = "2006-01-02T15:04:05.999999Z07:00"
+
+// NextCursor returns the cursor that continues after n
+func (n *Notification) NextCursor() *NotificationCursor {
+	return &NotificationCursor{IsRead: n.IsRead, CreatedAt: n.CreatedAt, ID: n.ID}
+}
+
+// Encode returns the opaque form handed to clients
+func (c *NotificationCursor) Encode() string {
+	read := "0"
+	if c.IsRead {
+		read = "1"
+	}
+	raw := read + "|" + c.CreatedAt.UTC().Format(cursorTimeLayout) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeNotificationCursor parses a cursor produced by Encode
+func DecodeNotificationCursor(s string) (*NotificationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidNotificationCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || (parts[0] != "0" && parts[0] != "1") {
+		return nil, ErrInvalidNotificationCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, ErrInvalidNotificationCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, ErrInvalidNotificationCursor
+	}
+	return &NotificationCursor{IsRead: parts[0] == "1", CreatedAt: createdAt, ID: id}, nil
 }
 
 // NotificationCountResponse returns unread counts
 type NotificationCountResponse struct {
 	Success        bool           `json:"success"`
 	Total          int            `json:"total"`
+	Unseen         int            `json:"unseen"` // The badge count: unread and not yet seen
 	CategoryCounts map[string]int `json:"category_counts"`
 }
 
@@ -179,10 +264,15 @@ type UpdateNotificationPreferencesRequest struct {
 	EmailFrequency       *EmailFrequency `json:"email_frequency,omitempty"`
 	InAppEnabled         *bool           `json:"in_app_enabled,omitempty"`
 	PushEnabled          *bool           `json:"push_enabled,omitempty"`
+	PushTypes            map[string]bool `json:"push_types,omitempty"`
 	InlineActionsEnabled *bool           `json:"inline_actions_enabled,omitempty"`
 	CategoriesEnabled    map[string]bool `json:"categories_enabled,omitempty"`
+	Mute                 *bool           `json:"mute,omitempty"` // true mutes push and email for MuteDuration, false unmutes
 }
 
+// MuteDuration is how long the "mute all" toggle silences push and email
+const MuteDuration = 24 * time.Hour
+
 // =====================================================
 // WEBSOCKET MESSAGES
 // =====================================================
@@ -203,6 +293,7 @@ type WSNotificationMessage struct {
 type WSCountUpdateMessage struct {
 	Type           string         `json:"type"`
 	Total          int            `json:"total"`
+	Unseen         int            `json:"unseen"`
 	CategoryCounts map[string]int `json:"category_counts"`
 }
 
@@ -220,6 +311,7 @@ func (n *Notification) ToResponse() *NotificationResponse {
 		Message:      n.Message,
 		ActionURL:    n.ActionURL,
 		IsRead:       n.IsRead,
+		IsSeen:       n.IsRead || n.SeenAt != nil,
 		IsActionable: n.IsActionable,
 		ActionType:   n.ActionType,
 		ActionTaken:  n.ActionTaken,
@@ -246,6 +338,8 @@ func GetCategoryForType(notifType NotificationType) NotificationCategory {
 	switch notifType {
 	case NotificationFollow,
 		NotificationFollowBack,
+		NotificationFollowRequest,
+		NotificationFollowRequestAccepted,
 		NotificationConnectionRequest,
 		NotificationConnectionAccepted,
 		NotificationConnectionRejected,
@@ -282,9 +376,28 @@ func GetCategoryForType(notifType NotificationType) NotificationCategory {
 	}
 }
 
+// IsMuted reports whether push and email are muted at the moment
+func (p *NotificationPreferences) IsMuted() bool {
+	return p.MutedUntil != nil && time.Now().Before(*p.MutedUntil)
+}
+
+// ShouldPush checks if this notification type should be pushed to the user's devices
+func (p *NotificationPreferences) ShouldPush(notifType NotificationType) bool {
+	if !p.PushEnabled || p.IsMuted() {
+		return false
+	}
+
+	if enabled, exists := p.PushTypes[string(notifType)]; exists {
+		return enabled
+	}
+
+	// Default to true for types the user hasn't set
+	return true
+}
+
 // ShouldSendEmail checks if this notification type should send email
 func (p *NotificationPreferences) ShouldSendEmail(notifType NotificationType) bool {
-	if !p.EmailEnabled {
+	if !p.EmailEnabled || p.IsMuted() {
 		return false
 	}
 
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationResponseSeenAndReadStates(t *testing.T) {
+	seenAt := time.Now()
+	tests := []struct {
+		name         string
+		notification Notification
+		wantSeen     bool
+		wantRead     bool
+	}{
+		{"new", Notification{}, false, false},
+		{"seen", Notification{SeenAt: &seenAt}, true, false},
+		{"read", Notification{IsRead: true}, true, true}, // Reading implies seeing
+	}
+
+	for _, tt := range tests {
+		resp := tt.notification.ToResponse()
+		if resp.IsSeen != tt.wantSeen || resp.IsRead != tt.wantRead {
+			t.Errorf("%s: seen %v, read %v; want %v, %v", tt.name, resp.IsSeen, resp.IsRead, tt.wantSeen, tt.wantRead)
+		}
+	}
+}
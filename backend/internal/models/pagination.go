@@ -0,0 +1,45 @@
+package models
+
+// TotalUnknown is the Page.Total of a read that skipped counting its rows
+const TotalUnknown = -1
+
+// Page describes where a paginated read stands. Counted reads fill in Total; count-less
+// reads fetch one row past the page instead, which is far cheaper than an exact count
+// on a large table, and only know whether another page follows.
+type Page struct {
+	Total   int // Exact row count, or TotalUnknown
+	HasMore bool
+}
+
+// CountedPage builds the Page for a read that counted its rows
+func CountedPage(total, offset, limit int) Page {
+	return Page{Total: total, HasMore: offset+limit < total}
+}
+
+// LookaheadPage trims a read that fetched limit+1 rows back to limit. The extra row,
+// if it came back, is what tells us another page follows.
+func LookaheadPage[T any](rows []T, limit int) ([]T, Page) {
+	if limit >= 0 && len(rows) > limit {
+		return rows[:limit], Page{Total: TotalUnknown, HasMore: true}
+	}
+	return rows, Page{Total: TotalUnknown}
+}
+
+// Counted reports whether Total is exact
+func (p Page) Counted() bool {
+	return p.Total != TotalUnknown
+}
+
+// Filtered accounts for removed rows dropped from a page that now holds kept. An
+// unknown total stays unknown, and HasMore is untouched: the dropped rows were on
+// this page, not the next.
+func (p Page) Filtered(removed, kept int) Page {
+	if !p.Counted() {
+		return p
+	}
+	p.Total -= removed
+	if p.Total < kept {
+		p.Total = kept
+	}
+	return p
+}
@@ -0,0 +1,61 @@
+package models
+
+import "testing"
+
+func TestLookaheadPageAtBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		fetched  int // Rows the limit+1 read returned
+		limit    int
+		wantRows int
+		wantMore bool
+	}{
+		{"empty", 0, 5, 0, false},
+		{"short page", 3, 5, 3, false},
+		{"exactly full", 5, 5, 5, false},
+		{"one past", 6, 5, 5, true},
+		{"zero limit", 1, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, page := LookaheadPage(make([]int, tt.fetched), tt.limit)
+			if len(rows) != tt.wantRows || page.HasMore != tt.wantMore {
+				t.Errorf("got %d rows, HasMore %v; want %d, %v", len(rows), page.HasMore, tt.wantRows, tt.wantMore)
+			}
+			if page.Counted() {
+				t.Error("a lookahead page shouldn't claim an exact total")
+			}
+		})
+	}
+}
+
+func TestCountedPageAtBoundaries(t *testing.T) {
+	tests := []struct {
+		total, offset, limit int
+		wantMore             bool
+	}{
+		{10, 0, 5, true},
+		{10, 5, 5, false}, // Last page ends exactly on the total
+		{11, 5, 5, true},
+		{0, 0, 5, false},
+	}
+
+	for _, tt := range tests {
+		if page := CountedPage(tt.total, tt.offset, tt.limit); page.HasMore != tt.wantMore || page.Total != tt.total {
+			t.Errorf("CountedPage(%d, %d, %d) = %+v, want HasMore %v", tt.total, tt.offset, tt.limit, page, tt.wantMore)
+		}
+	}
+}
+
+func TestFilteredPage(t *testing.T) {
+	if page := (Page{Total: 10, HasMore: true}).Filtered(2, 3); page.Total != 8 || !page.HasMore {
+		t.Errorf("counted page = %+v, want 8 with more to come", page)
+	}
+	if page := (Page{Total: 2}).Filtered(3, 1); page.Total != 1 {
+		t.Errorf("total = %d, should never drop below what's kept", page.Total)
+	}
+	if page := (Page{Total: TotalUnknown, HasMore: true}).Filtered(2, 3); page.Counted() || !page.HasMore {
+		t.Errorf("unknown page = %+v, should stay unknown with HasMore kept", page)
+	}
+}
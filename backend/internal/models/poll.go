@@ -61,13 +61,20 @@ type VotePollRequest struct {
 
 // PollResults represents the results of a poll
 type PollResults struct {
-	Poll       *Poll              `json:"poll"`
-	Options    []PollOptionResult `json:"options"`
-	TotalVotes int                `json:"total_votes"`
-	UserVoted  bool               `json:"user_voted"`
-	UserVoteID *uuid.UUID         `json:"user_vote_id,omitempty"`
-	TimeLeft   *time.Duration     `json:"time_left,omitempty"`
-	IsEnded    bool               `json:"is_ended"`
+	Poll          *Poll              `json:"poll"`
+	Options       []PollOptionResult `json:"options"`
+	TotalVotes    int                `json:"total_votes"`
+	UserVoted     bool               `json:"user_voted"`
+	UserVoteID    *uuid.UUID         `json:"user_vote_id,omitempty"`
+	UserVoteIDs   []uuid.UUID        `json:"user_vote_ids,omitempty"` // All options voted for (multiple-vote polls)
+	TimeLeft      *time.Duration     `json:"time_left,omitempty"`
+	IsEnded       bool               `json:"is_ended"`
+	ResultsHidden bool               `json:"results_hidden,omitempty"` // Tallies withheld until the viewer votes
+}
+
+// PollResultsBatchRequest is the request body for fetching results of several polls
+type PollResultsBatchRequest struct {
+	PollIDs []uuid.UUID `json:"poll_ids" binding:"required,min=1,max=50"`
 }
 
 // PollOptionResult represents poll option with percentage
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,15 +10,17 @@ import (
 
 // Post represents a social media post (short post, poll, or article)
 type Post struct {
-	ID             uuid.UUID      `json:"id"`
-	UserID         uuid.UUID      `json:"user_id"`
-	PostType       string         `json:"post_type"` // 'post', 'poll', 'article'
-	Content        string         `json:"content"`
-	MediaURLs      pq.StringArray `json:"media_urls" db:"media_urls"`
-	MediaTypes     pq.StringArray `json:"media_types" db:"media_types"`
-	Visibility     string         `json:"visibility"`
-	AllowsComments bool           `json:"allows_comments"`
-	AllowsSharing  bool           `json:"allows_sharing"`
+	ID         uuid.UUID      `json:"id"`
+	UserID     uuid.UUID      `json:"user_id"`
+	PostType   string         `json:"post_type"` // 'post', 'poll', 'article' ('quote' for quote reposts)
+	Content    string         `json:"content"`
+	MediaURLs  pq.StringArray `json:"media_urls" db:"media_urls"`
+	MediaTypes pq.StringArray `json:"media_types" db:"media_types"`
+	// MediaVariants is parallel to MediaURLs: resized image URLs keyed by size in pixels ("320", "640", ...)
+	MediaVariants  []map[string]string `json:"media_variants,omitempty" db:"media_variants"`
+	Visibility     string              `json:"visibility"`
+	AllowsComments bool                `json:"allows_comments"`
+	AllowsSharing  bool                `json:"allows_sharing"`
 
 	// Engagement stats (denormalized)
 	LikesCount    int `json:"likes_count"`
@@ -31,10 +34,15 @@ type Post struct {
 	IsFeatured bool `json:"is_featured"`
 	IsNSFW     bool `json:"is_nsfw"`
 
+	// Detected ISO 639-1 code; empty when detection was uncertain
+	Language string `json:"language,omitempty"`
+
 	// Status
-	IsPublished bool       `json:"is_published"`
-	IsDraft     bool       `json:"is_draft"`
-	PublishedAt *time.Time `json:"published_at"`
+	IsPublished  bool       `json:"is_published"`
+	IsDraft      bool       `json:"is_draft"`
+	DraftVersion int        `json:"draft_version"` // Bumped by each autosave (see SaveDraftRequest)
+	PublishedAt  *time.Time `json:"published_at"`
+	ScheduledAt  *time.Time `json:"scheduled_at,omitempty"` // When a scheduled post goes out; unpublished until then
 
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at"`
@@ -45,10 +53,30 @@ type Post struct {
 	Author      *User     `json:"author,omitempty"`
 	Poll        *Poll     `json:"poll,omitempty"`
 	Article     *Article  `json:"article,omitempty"`
-	IsLiked     bool      `json:"is_liked"` // Current user liked
-	IsSaved     bool      `json:"is_saved"` // Current user saved
+	IsLiked     bool      `json:"is_liked"`  // Current user liked
+	IsSaved     bool      `json:"is_saved"`  // Current user saved
+	IsShared    bool      `json:"is_shared"` // Current user reposted
+	Blur        bool      `json:"blur"`      // NSFW post the viewer hasn't opted in to; render blurred
 	TopComments []Comment `json:"top_comments,omitempty"`
 	Hashtags    []string  `json:"hashtags,omitempty"`
+
+	// Quote reposts: the post being quoted (ID is the share ID, Content the repost comment)
+	QuotedPost *QuotedPost `json:"quoted_post,omitempty"`
+}
+
+// QuotedPost is the original post embedded in a quote repost. If the original was
+// deleted or restricted only ID and Unavailable are set, so clients can render a
+// "post unavailable" placeholder.
+type QuotedPost struct {
+	ID          uuid.UUID      `json:"id"`
+	Unavailable bool           `json:"unavailable"`
+	UserID      *uuid.UUID     `json:"user_id,omitempty"`
+	PostType    string         `json:"post_type,omitempty"`
+	Content     string         `json:"content,omitempty"`
+	MediaURLs   pq.StringArray `json:"media_urls,omitempty"`
+	MediaTypes  pq.StringArray `json:"media_types,omitempty"`
+	CreatedAt   *time.Time     `json:"created_at,omitempty"`
+	Author      *User          `json:"author,omitempty"`
 }
 
 // CreatePostRequest is the request body for creating a post
@@ -57,11 +85,13 @@ type CreatePostRequest struct {
 	Content        string   `json:"content" binding:"required,max=125000"`
 	MediaURLs      []string `json:"media_urls"`
 	MediaTypes     []string `json:"media_types"`
-	Visibility     string   `json:"visibility" binding:"oneof=public connections private"`
+	Visibility     string   `json:"visibility" binding:"oneof=public connections private close_friends"`
 	AllowsComments bool     `json:"allows_comments"`
 	AllowsSharing  bool     `json:"allows_sharing"`
 	IsDraft        bool     `json:"is_draft"`
 	IsNSFW         bool     `json:"is_nsfw"`
+	ScheduledAt    *string  `json:"scheduled_at,omitempty"` // RFC3339, or a local time in Timezone
+	Timezone       string   `json:"timezone,omitempty"`     // IANA zone for ScheduledAt; the author's own if empty
 
 	// For polls
 	Poll *CreatePollRequest `json:"poll,omitempty"`
@@ -73,13 +103,30 @@ type CreatePostRequest struct {
 // UpdatePostRequest is the request body for updating a post
 type UpdatePostRequest struct {
 	Content        *string `json:"content,omitempty"`
-	Visibility     *string `json:"visibility,omitempty"`
+	Visibility     *string `json:"visibility,omitempty" binding:"omitempty,oneof=public connections private close_friends"`
 	AllowsComments *bool   `json:"allows_comments,omitempty"`
 	AllowsSharing  *bool   `json:"allows_sharing,omitempty"`
 	IsNSFW         *bool   `json:"is_nsfw,omitempty"`
 	IsPinned       *bool   `json:"is_pinned,omitempty"`
 }
 
+// SaveDraftRequest is the request body for autosaving a draft. DraftVersion is the
+// version the client last loaded or saved; saves from a stale version are rejected.
+type SaveDraftRequest struct {
+	DraftVersion *int                  `json:"draft_version" binding:"required,min=0"`
+	Content      *string               `json:"content,omitempty"`
+	Article      *UpdateArticleRequest `json:"article,omitempty"`
+}
+
+// DraftConflictError is returned when a draft save carries a stale draft_version
+type DraftConflictError struct {
+	CurrentVersion int `json:"current_version"`
+}
+
+func (e *DraftConflictError) Error() string {
+	return fmt.Sprintf("draft has been saved elsewhere (current version %d)", e.CurrentVersion)
+}
+
 // PostResponse is the API response for a post
 type PostResponse struct {
 	Success bool   `json:"success"`
@@ -87,16 +134,35 @@ type PostResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
-// PostsResponse is the API response for multiple posts
+// PostsResponse is the API response for multiple posts. Total is left out when the
+// read skipped counting; has_more is always set.
 type PostsResponse struct {
 	Success bool   `json:"success"`
 	Posts   []Post `json:"posts"`
-	Total   int    `json:"total"`
+	Total   *int   `json:"total,omitempty"`
 	Page    int    `json:"page"`
 	Limit   int    `json:"limit"`
 	HasMore bool   `json:"has_more"`
 }
 
+// NewPostsResponse builds the response for one page of posts
+func NewPostsResponse(posts []Post, page Page, offset, limit int) PostsResponse {
+	resp := PostsResponse{
+		Success: true,
+		Posts:   posts,
+		Limit:   limit,
+		HasMore: page.HasMore,
+	}
+	if limit > 0 {
+		resp.Page = offset / limit
+	}
+	if page.Counted() {
+		total := page.Total
+		resp.Total = &total
+	}
+	return resp
+}
+
 // PostLike represents a like on a post
 type PostLike struct {
 	ID        uuid.UUID `json:"id"`
@@ -127,6 +193,23 @@ type SavedPost struct {
 	Post           *Post     `json:"post,omitempty"`
 }
 
+// DefaultSavedCollection is where bookmarks go when no collection is given. It always
+// exists and can't be renamed or deleted.
+const DefaultSavedCollection = "Saved"
+
+// SavedCollection is a named group of a user's bookmarks
+type SavedCollection struct {
+	Name      string     `json:"name"`
+	PostCount int        `json:"post_count"`
+	IsDefault bool       `json:"is_default"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// SavedCollectionRequest names a collection to create, or the new name when renaming
+type SavedCollectionRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=50"`
+}
+
 // Validate validates the post creation request
 func (r *CreatePostRequest) Validate() error {
 	// Validate content length based on type
@@ -151,6 +234,10 @@ func (r *CreatePostRequest) Validate() error {
 		}
 	}
 
+	if r.ScheduledAt != nil && r.IsDraft {
+		return ErrScheduledDraft
+	}
+
 	// Poll must have poll data
 	if r.PostType == "poll" && r.Poll == nil {
 		return ErrPollDataRequired
@@ -173,6 +260,17 @@ var (
 	ErrArticleDataRequired = &AppError{Code: "ARTICLE_DATA_REQUIRED", Message: "Article data is required for article posts"}
 	ErrPostNotFound        = &AppError{Code: "POST_NOT_FOUND", Message: "Post not found"}
 	ErrUnauthorized        = &AppError{Code: "UNAUTHORIZED", Message: "Not authorized to perform this action"}
+	ErrPostNotDraft        = &AppError{Code: "POST_NOT_DRAFT", Message: "Only unpublished drafts can be autosaved"}
+	ErrScheduledDraft      = &AppError{Code: "SCHEDULED_DRAFT", Message: "A draft can't be scheduled; schedule it when it's ready"}
+	ErrTooManyPinnedPosts  = &AppError{Code: "TOO_MANY_PINNED_POSTS", Message: "Pinned post limit reached; unpin a post first"}
+	ErrCollectionNotFound  = &AppError{Code: "COLLECTION_NOT_FOUND", Message: "Collection not found"}
+	ErrCollectionExists    = &AppError{Code: "COLLECTION_EXISTS", Message: "A collection with this name already exists"}
+	ErrCollectionName      = &AppError{Code: "INVALID_COLLECTION_NAME", Message: "Collection name must be 1-50 characters"}
+	ErrDefaultCollection   = &AppError{Code: "DEFAULT_COLLECTION", Message: "The default collection can't be renamed or deleted"}
+	ErrCommentNotFound     = &AppError{Code: "COMMENT_NOT_FOUND", Message: "Comment not found"}
+	ErrParentCommentPost   = &AppError{Code: "PARENT_COMMENT_MISMATCH", Message: "Parent comment belongs to a different post"}
+	ErrCommentTooDeep      = &AppError{Code: "COMMENT_TOO_DEEP", Message: "Replies can't be nested this deep"}
+	ErrNewAccountLinks     = &AppError{Code: "NEW_ACCOUNT_LINKS", Message: "New accounts can't post links yet"}
 )
 
 // AppError represents a custom application error
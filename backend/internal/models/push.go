@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PushPlatform is the push service a device token belongs to
+type PushPlatform string
+
+const (
+	PushPlatformIOS     PushPlatform = "ios"     // APNs
+	PushPlatformAndroid PushPlatform = "android" // FCM
+	PushPlatformWeb     PushPlatform = "web"     // FCM, through the Firebase JS SDK
+)
+
+// DeviceToken is a push token registered by one of a user's devices
+type DeviceToken struct {
+	ID         uuid.UUID    `json:"id" db:"id"`
+	UserID     uuid.UUID    `json:"user_id" db:"user_id"`
+	Platform   PushPlatform `json:"platform" db:"platform"`
+	Token      string       `json:"token" db:"token"`
+	AppVersion *string      `json:"app_version,omitempty" db:"app_version"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time    `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// RegisterDeviceTokenRequest registers a device for push notifications
+type RegisterDeviceTokenRequest struct {
+	Platform   PushPlatform `json:"platform" binding:"required,oneof=ios android web"`
+	Token      string       `json:"token" binding:"required,max=4096"`
+	AppVersion *string      `json:"app_version,omitempty" binding:"omitempty,max=50"`
+}
+
+// UnregisterDeviceTokenRequest stops pushes to a device, e.g. on logout
+type UnregisterDeviceTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// PushPayload is what a push provider delivers to a device
+type PushPayload struct {
+	Title     string            `json:"title"`
+	Body      string            `json:"body,omitempty"`
+	ActionURL string            `json:"action_url,omitempty"`
+	Tag       string            `json:"tag,omitempty"`  // A push with the same tag replaces the one shown
+	Data      map[string]string `json:"data,omitempty"` // Delivered to the app, not shown
+}
@@ -66,6 +66,7 @@ type RelationshipStatusResponse struct {
 	IsCollaborating bool `json:"is_collaborating"`
 	HasPending      bool `json:"has_pending"`
 	IsBlocked       bool `json:"is_blocked"`
+	IsMutual        bool `json:"is_mutual"` // Each follows the other
 }
 
 // RateLimitStatus represents the current rate limit status for a user
@@ -115,6 +116,55 @@ type RelationshipListItem struct {
 	CreatedAt        time.Time          `json:"created_at"`
 }
 
+// FollowListUser is a user in a followers, following or mutual followers list
+type FollowListUser struct {
+	ID             uuid.UUID           `json:"id"`
+	Username       string              `json:"username"`
+	DisplayName    string              `json:"display_name"`
+	ProfilePicture *string             `json:"profile_picture"`
+	Bio            *string             `json:"bio"`
+	IsVerified     bool                `json:"is_verified"`
+	FollowedAt     time.Time           `json:"followed_at"`
+	Viewer         *ViewerFollowStatus `json:"viewer,omitempty"` // Only for signed-in viewers
+}
+
+// ViewerFollowStatus is how the viewer of a list relates to a user in it
+type ViewerFollowStatus struct {
+	IsSelf          bool `json:"is_self"`
+	IsFollowing     bool `json:"is_following"`
+	IsFollower      bool `json:"is_follower"`
+	IsMutual        bool `json:"is_mutual"`
+	FollowRequested bool `json:"follow_requested"` // A follow request is waiting for approval
+}
+
+// FollowListResponse is a page of a followers, following or mutual followers list
+type FollowListResponse struct {
+	Success bool              `json:"success"`
+	Users   []*FollowListUser `json:"users"`
+	Total   int               `json:"total"`
+	Page    int               `json:"page"`
+	Limit   int               `json:"limit"`
+}
+
+// CloseFriend is a user on someone's close friends list
+type CloseFriend struct {
+	ID             uuid.UUID `json:"id"`
+	Username       string    `json:"username"`
+	DisplayName    string    `json:"display_name"`
+	ProfilePicture *string   `json:"profile_picture"`
+	IsVerified     bool      `json:"is_verified"`
+	AddedAt        time.Time `json:"added_at"`
+}
+
+// CloseFriendsResponse is a page of the signed-in user's close friends list
+type CloseFriendsResponse struct {
+	Success bool           `json:"success"`
+	Users   []*CloseFriend `json:"users"`
+	Total   int            `json:"total"`
+	Page    int            `json:"page"`
+	Limit   int            `json:"limit"`
+}
+
 // RelationshipRequest represents a pending relationship request
 type RelationshipRequest struct {
 	ID               uuid.UUID        `json:"id"`
@@ -149,6 +199,29 @@ type RelationshipStatsResponse struct {
 	Stats   *RelationshipStats `json:"stats"`
 }
 
+// FindByContactsRequest represents a contact import using client-side SHA-256 email hashes
+type FindByContactsRequest struct {
+	EmailHashes []string `json:"email_hashes" validate:"required,min=1"`
+}
+
+// ContactMatch represents an existing user matched from an imported contact
+type ContactMatch struct {
+	EmailHash      string  `json:"email_hash"`
+	UserID         string  `json:"user_id"`
+	Username       string  `json:"username"`
+	DisplayName    string  `json:"display_name"`
+	ProfilePicture *string `json:"profile_picture,omitempty"`
+	IsVerified     bool    `json:"is_verified"`
+	IsFollowing    bool    `json:"is_following"`
+}
+
+// FindByContactsResponse represents the result of contact matching
+type FindByContactsResponse struct {
+	Success bool            `json:"success"`
+	Matches []*ContactMatch `json:"matches"`
+	Checked int             `json:"checked"`
+}
+
 // RateLimitError represents a rate limit error with details
 type RateLimitError struct {
 	Message   string           `json:"message"`
@@ -15,13 +15,19 @@ type Status struct {
 	MediaURL        *string   `json:"media_url,omitempty" db:"media_url"`
 	MediaType       *string   `json:"media_type,omitempty" db:"media_type"`
 	BackgroundColor string    `json:"background_color" db:"background_color"`
+	Visibility      string    `json:"visibility" db:"visibility"` // 'public' or 'close_friends'
+
+	// Interaction settings (chosen by the author at creation)
+	AllowReactions    bool `json:"allow_reactions" db:"allow_reactions"`
+	AllowReplies      bool `json:"allow_replies" db:"allow_replies"`
+	EngagementPrivate bool `json:"engagement_private" db:"engagement_private"` // Views/reactions lists are author-only
 
 	// Engagement stats
 	ViewsCount     int `json:"views_count" db:"views_count"`
 	ReactionsCount int `json:"reactions_count" db:"reactions_count"`
 	CommentsCount  int `json:"comments_count" db:"comments_count"`
 
-	// Expiration (24 hours from creation)
+	// Expiration (24 hours from creation unless configured otherwise)
 	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
 
 	// Timestamps
@@ -66,10 +72,25 @@ type StatusView struct {
 	ID       uuid.UUID `json:"id" db:"id"`
 	StatusID uuid.UUID `json:"status_id" db:"status_id"`
 	UserID   uuid.UUID `json:"user_id" db:"user_id"`
-	ViewedAt time.Time `json:"viewed_at" db:"viewed_at"`
+	ViewedAt time.Time `json:"viewed_at" db:"viewed_at"` // First view; later views don't move it
 
 	// Relations
-	User *User `json:"user,omitempty"`
+	User         *User               `json:"user,omitempty"`
+	Relationship *ViewerFollowStatus `json:"relationship,omitempty"` // How the requester relates to this viewer
+}
+
+// StatusSummary is what its creator keeps of a status once it has expired and been
+// purged
+type StatusSummary struct {
+	StatusID       uuid.UUID      `json:"status_id" db:"status_id"`
+	UserID         uuid.UUID      `json:"user_id" db:"user_id"`
+	StatusType     string         `json:"status_type" db:"status_type"`
+	ViewsCount     int            `json:"views_count" db:"views_count"`
+	ReactionsCount int            `json:"reactions_count" db:"reactions_count"`
+	CommentsCount  int            `json:"comments_count" db:"comments_count"`
+	Reactions      map[string]int `json:"reactions" db:"reactions"` // Count per emoji
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	ExpiredAt      time.Time      `json:"expired_at" db:"expired_at"`
 }
 
 // CreateStatusRequest is the request body for creating a status
@@ -79,6 +100,12 @@ type CreateStatusRequest struct {
 	MediaURL        *string `json:"media_url,omitempty"`
 	MediaType       *string `json:"media_type,omitempty"`
 	BackgroundColor *string `json:"background_color,omitempty"` // Hex color for text statuses
+	Visibility      string  `json:"visibility,omitempty" binding:"omitempty,oneof=public close_friends"`
+
+	// Interaction settings; reactions and replies default to allowed
+	AllowReactions    *bool `json:"allow_reactions,omitempty"`
+	AllowReplies      *bool `json:"allow_replies,omitempty"`
+	EngagementPrivate bool  `json:"engagement_private"`
 }
 
 // UpdateStatusRequest is the request body for updating a status (limited updates)
@@ -120,6 +147,13 @@ type StatusCommentsResponse struct {
 	HasMore  bool            `json:"has_more"`
 }
 
+// StatusSummariesResponse is the API response for a creator's expired statuses
+type StatusSummariesResponse struct {
+	Success   bool            `json:"success"`
+	Summaries []StatusSummary `json:"summaries"`
+	HasMore   bool            `json:"has_more"`
+}
+
 // Validate validates the status creation request
 func (r *CreateStatusRequest) Validate() error {
 	if r.StatusType == "text" {
@@ -148,4 +182,20 @@ var (
 	ErrStatusUnauthorized = &AppError{Code: "STATUS_UNAUTHORIZED", Message: "Not authorized to access this status"}
 	ErrInvalidEmoji       = &AppError{Code: "INVALID_EMOJI", Message: "Invalid reaction emoji"}
 	ErrCommentTooLong     = &AppError{Code: "COMMENT_TOO_LONG", Message: "Comment must be 500 characters or less"}
+
+	ErrStatusReactionsDisabled = &AppError{Code: "STATUS_REACTIONS_DISABLED", Message: "The author has turned off reactions for this status"}
+	ErrStatusRepliesDisabled   = &AppError{Code: "STATUS_REPLIES_DISABLED", Message: "The author has turned off replies for this status"}
+	ErrStatusEngagementPrivate = &AppError{Code: "STATUS_ENGAGEMENT_PRIVATE", Message: "Only the author can see who engaged with this status"}
 )
+
+// StatusLimitError is returned when a user has posted their maximum number of statuses
+// for the rolling 24-hour window
+type StatusLimitError struct {
+	Message string    `json:"message"`
+	Limit   int       `json:"limit"`
+	ResetAt time.Time `json:"reset_at"` // When the oldest counted status leaves the window
+}
+
+func (e *StatusLimitError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactorSecret is a user's TOTP secret, encrypted at rest. It stays pending until
+// the first code from the authenticator app confirms it.
+type TwoFactorSecret struct {
+	UserID          uuid.UUID  `json:"user_id" db:"user_id"`
+	SecretEncrypted string     `json:"secret_encrypted" db:"secret_encrypted"`
+	SecretIV        string     `json:"secret_iv" db:"secret_iv"`
+	EnabledAt       *time.Time `json:"enabled_at" db:"enabled_at"`
+	LastUsedStep    int64      `json:"last_used_step" db:"last_used_step"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Enabled reports whether logins require a code
+func (t *TwoFactorSecret) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// TwoFactorCodeRequest carries a 6-digit TOTP code or, where accepted, a recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest completes a login that was answered with two_factor_required
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP or recovery code
+}
+
+// TwoFactorSetupResponse is returned when 2FA setup starts. The secret is shown this
+// once; the client renders OTPAuthURL as a QR code.
+type TwoFactorSetupResponse struct {
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorEnabledResponse is returned when 2FA is turned on. The recovery codes are
+// never shown again.
+type TwoFactorEnabledResponse struct {
+	Success       bool     `json:"success"`
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -28,6 +28,8 @@ type User struct {
 	GoogleID                   *string    `json:"-" db:"google_id"`
 	GitHubID                   *string    `json:"-" db:"github_id"`
 	LinkedInID                 *string    `json:"-" db:"linkedin_id"`
+	AppleID                    *string    `json:"-" db:"apple_id"`
+	TwitterID                  *string    `json:"-" db:"twitter_id"`
 	OAuthProvider              *string    `json:"oauth_provider,omitempty" db:"oauth_provider"`
 	ProfilePicture             *string    `json:"profile_picture,omitempty" db:"profile_picture"`
 	CoverPhoto                 *string    `json:"cover_photo,omitempty" db:"cover_photo"`
@@ -43,8 +45,13 @@ type User struct {
 	Gender          *string            `json:"gender,omitempty" db:"gender"`
 	GenderCustom    *string            `json:"gender_custom,omitempty" db:"gender_custom"`
 	Website         *string            `json:"website,omitempty" db:"website"`
+	Timezone        string             `json:"timezone" db:"timezone"` // IANA name, e.g. "Europe/Berlin"
 	IsVerified      bool               `json:"is_verified" db:"is_verified"`
 	ProfilePrivacy  string             `json:"profile_privacy" db:"profile_privacy"`
+	Discoverable    bool               `json:"discoverable_by_email" db:"discoverable_by_email"`
+	ShowNSFW        bool               `json:"show_nsfw" db:"show_nsfw"`
+	HideInteracted  bool               `json:"hide_interacted_posts" db:"hide_interacted_posts"`
+	FeedLanguages   []string           `json:"feed_languages" db:"feed_languages"` // Empty shows every language
 	FieldVisibility map[string]bool    `json:"field_visibility" db:"field_visibility"`
 	StatVisibility  map[string]bool    `json:"stat_visibility" db:"stat_visibility"`
 	Story           *string            `json:"story,omitempty" db:"story"`
@@ -56,16 +63,51 @@ type User struct {
 	SocialLinks     map[string]*string `json:"social_links,omitempty" db:"social_links"`
 }
 
-// UserSession represents a user session (optional for advanced session management)
+// NewAccountRestrictions throttle accounts in a probation period after signup, a common
+// spam vector: lower posting, messaging and follow limits, no links and no bulk
+// operations. The period ends after Period, or earlier for a verified account or one
+// with a verified email and TrustedFollowers followers.
+type NewAccountRestrictions struct {
+	Period            time.Duration // 0 disables the restrictions
+	TrustedFollowers  int
+	PostLimit         int // Posts per hour; 0 for no extra limit
+	MessageLimit      int // Messages per minute; 0 for no extra limit
+	ConversationLimit int // New conversations per hour; 0 for no extra limit
+	FollowLimit       int // Follow, connect and collaborate requests each per day; 0 for no extra limit
+	AllowLinks        bool
+	AllowBulk         bool
+}
+
+// Applies reports whether u is still in the probation period
+func (r NewAccountRestrictions) Applies(u *User) bool {
+	if r.Period <= 0 || u == nil || u.IsVerified {
+		return false
+	}
+	if time.Since(u.CreatedAt) >= r.Period {
+		return false
+	}
+	return !u.IsEmailVerified || u.FollowersCount < r.TrustedFollowers
+}
+
+// Until returns when the probation period of u ends at the latest
+func (r NewAccountRestrictions) Until(u *User) time.Time {
+	return u.CreatedAt.Add(r.Period)
+}
+
+// UserSession is one refresh token of a login. Every refresh rotates the token into a
+// new row of the same family; the old row is kept, marked replaced, to detect reuse.
 type UserSession struct {
-	ID         uuid.UUID `json:"id" db:"id"`
-	UserID     uuid.UUID `json:"user_id" db:"user_id"`
-	TokenHash  string    `json:"-" db:"token_hash"`
-	DeviceInfo *string   `json:"device_info" db:"device_info"`
-	IPAddress  *string   `json:"ip_address" db:"ip_address"`
-	UserAgent  *string   `json:"user_agent" db:"user_agent"`
-	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	FamilyID   uuid.UUID  `json:"family_id" db:"family_id"` // The login; also the access tokens' sid
+	TokenHash  string     `json:"-" db:"token_hash"`
+	DeviceInfo *string    `json:"device_info" db:"device_info"`
+	IPAddress  *string    `json:"ip_address" db:"ip_address"`
+	UserAgent  *string    `json:"user_agent" db:"user_agent"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	ReplacedAt *time.Time `json:"replaced_at,omitempty" db:"replaced_at"` // Set once the token was rotated
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`             // The login; carried across rotations
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`             // This token's issue, i.e. the last refresh
 }
 
 // RegisterRequest represents the request payload for user registration
@@ -78,7 +120,7 @@ type RegisterRequest struct {
 	Gender           *string `json:"gender,omitempty" validate:"omitempty,oneof=male female non-binary prefer-not-to-say custom"`
 	Bio              *string `json:"bio,omitempty" validate:"omitempty,max=200"`
 	ProfilePicture   *string `json:"profile_picture,omitempty" validate:"omitempty,url"`
-	VerificationCode *string `json:"verification_code,omitempty" validate:"omitempty,len=6"` // Optional: if provided, verify immediately
+	VerificationCode *string `json:"verification_code,omitempty" validate:"omitempty,min=4,max=12"` // Optional: if provided, verify immediately
 }
 
 // LoginRequest represents the request payload for user login
@@ -90,7 +132,7 @@ type LoginRequest struct {
 // VerifyEmailRequest represents the request payload for email verification
 type VerifyEmailRequest struct {
 	Email            string `json:"email" validate:"required,email"`
-	VerificationCode string `json:"verification_code" validate:"required,len=6"`
+	VerificationCode string `json:"verification_code" validate:"required,min=4,max=12"`
 }
 
 // ForgotPasswordRequest represents the request payload for password reset request
@@ -104,27 +146,41 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password" validate:"required,min=6"`
 }
 
+// MagicLinkRequest asks for a passwordless login link by email
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 // RefreshTokenRequest represents the request payload for token refresh
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token" validate:"required"`
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // AuthResponse represents the response for authentication operations
 type AuthResponse struct {
-	Success   bool      `json:"success"`
-	Message   string    `json:"message"`
-	Token     string    `json:"token,omitempty"`
-	ExpiresAt time.Time `json:"expires_at,omitempty"`
-	User      *User     `json:"user,omitempty"`
-	UserID    string    `json:"user_id,omitempty"`
+	Success          bool       `json:"success"`
+	Message          string     `json:"message"`
+	Token            string     `json:"token,omitempty"`
+	ExpiresAt        time.Time  `json:"expires_at,omitempty"`
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+	User             *User      `json:"user,omitempty"`
+	UserID           string     `json:"user_id,omitempty"`
+
+	// Set instead of Token when the account has 2FA on: the client exchanges
+	// TwoFactorToken and a code at POST /auth/2fa/login
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	TwoFactorToken    string `json:"two_factor_token,omitempty"`
 }
 
 // TokenResponse represents the response for token operations
 type TokenResponse struct {
-	Success   bool      `json:"success"`
-	Message   string    `json:"message"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Success          bool      `json:"success"`
+	Message          string    `json:"message"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 // UserResponse represents the response for user operations
@@ -141,17 +197,48 @@ type MessageResponse struct {
 
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
-	UserID   string `json:"user_id"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`   // Stable across sliding refreshes of the same login
+	Scope     string `json:"scope,omitempty"` // Set on restricted tokens, which never pass as access tokens
 	jwt.RegisteredClaims
 }
 
-// UpdateProfileRequest represents the request payload for updating user profile
+// ScopeTwoFactorPending marks the interim token of a login waiting for its 2FA code
+const ScopeTwoFactorPending = "2fa_pending"
+
+// ScopeMagicLink marks the token carried by an emailed login link
+const ScopeMagicLink = "magic_link"
+
+// RoleUser is the role carried by every regular account's tokens
+const RoleUser = "user"
+
+// UpdateProfileRequest is a partial update of any profile fields in one call. Omitted
+// fields are left unchanged; each provided field follows the same rules as its dedicated
+// endpoint. Maps (social links, visibility) replace the stored values key by key.
 type UpdateProfileRequest struct {
-	DisplayName    *string `json:"display_name,omitempty" validate:"omitempty,min=2,max=50"`
-	Age            *int    `json:"age,omitempty" validate:"omitempty,min=13,max=120"`
-	ProfilePicture *string `json:"profile_picture,omitempty" validate:"omitempty,url"`
+	// Basic info
+	DisplayName    *string `json:"display_name,omitempty" binding:"omitempty,min=2,max=100"`
+	Age            *int    `json:"age,omitempty" binding:"omitempty,min=13,max=120"`
+	ProfilePicture *string `json:"profile_picture,omitempty" binding:"omitempty,url"`
+	Bio            *string `json:"bio,omitempty" binding:"omitempty,max=150"`
+	Location       *string `json:"location,omitempty" binding:"omitempty,max=100"`
+	Gender         *string `json:"gender,omitempty" binding:"omitempty,oneof=male female non-binary prefer-not-to-say custom"`
+	GenderCustom   *string `json:"gender_custom,omitempty" binding:"omitempty,max=50"`
+	Website        *string `json:"website,omitempty" binding:"omitempty,url"`
+	Timezone       *string `json:"timezone,omitempty" binding:"omitempty,max=64"`
+
+	// About sections
+	Story    *string `json:"story,omitempty" binding:"omitempty,max=1000"`
+	Ambition *string `json:"ambition,omitempty" binding:"omitempty,max=200"`
+
+	// Links and visibility
+	SocialLinks     *UpdateSocialLinksRequest `json:"social_links,omitempty"`
+	ProfilePrivacy  *string                   `json:"profile_privacy,omitempty" binding:"omitempty,oneof=public private connections"`
+	FieldVisibility map[string]bool           `json:"field_visibility,omitempty"`
+	StatVisibility  map[string]bool           `json:"stat_visibility,omitempty"`
 }
 
 // ChangePasswordRequest represents the request payload for changing password
@@ -175,7 +262,7 @@ type ChangeEmailRequest struct {
 
 // VerifyEmailChangeRequest represents the request payload for verifying new email
 type VerifyEmailChangeRequest struct {
-	VerificationCode string `json:"verification_code" validate:"required,len=6"`
+	VerificationCode string `json:"verification_code" validate:"required,min=4,max=12"`
 }
 
 // ChangeUsernameRequest represents the request payload for changing username
@@ -196,6 +283,25 @@ type SessionsResponse struct {
 	Sessions []*UserSession `json:"sessions"`
 }
 
+// ActiveSession is one login as listed to its user. ID is the session family, which
+// stays the same across refreshes, so it can be revoked with the ID from any listing.
+type ActiveSession struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceInfo *string   `json:"device_info"`
+	UserAgent  *string   `json:"user_agent"`
+	IPAddress  *string   `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	IsCurrent  bool      `json:"is_current"`
+}
+
+// ActiveSessionsResponse lists a user's logins, most recently used first
+type ActiveSessionsResponse struct {
+	Success  bool             `json:"success"`
+	Sessions []*ActiveSession `json:"sessions"`
+}
+
 // UpdateBasicProfileRequest represents the request payload for updating basic profile info
 type UpdateBasicProfileRequest struct {
 	DisplayName  *string `json:"display_name,omitempty" validate:"omitempty,min=2,max=100"`
@@ -205,12 +311,18 @@ type UpdateBasicProfileRequest struct {
 	GenderCustom *string `json:"gender_custom,omitempty" validate:"omitempty,max=50"`
 	Age          *int    `json:"age,omitempty" validate:"omitempty,min=13,max=120"`
 	Website      *string `json:"website,omitempty" validate:"omitempty,url"`
+	Timezone     *string `json:"timezone,omitempty" validate:"omitempty,max=64"`
 }
 
 // UpdatePrivacySettingsRequest represents the request payload for updating privacy settings
 type UpdatePrivacySettingsRequest struct {
 	ProfilePrivacy  string          `json:"profile_privacy" validate:"required,oneof=public private connections"`
 	FieldVisibility map[string]bool `json:"field_visibility" validate:"required"`
+	Discoverable    *bool           `json:"discoverable_by_email,omitempty"` // Opt in/out of contact matching
+	ShowNSFW        *bool           `json:"show_nsfw,omitempty"`             // Opt in to unblurred NSFW posts in feeds
+	HideInteracted  *bool           `json:"hide_interacted_posts,omitempty"` // Leave liked/saved/commented posts out of feeds
+	// Feed language filter: omit to keep the current one, [] to show every language
+	FeedLanguages []string `json:"feed_languages" validate:"omitempty,max=20,dive,alpha,min=2,max=3"`
 }
 
 // UpdateStoryRequest represents the request payload for updating story section
@@ -225,12 +337,24 @@ type UpdateAmbitionRequest struct {
 
 // UpdateSocialLinksRequest represents the request payload for updating social media links
 type UpdateSocialLinksRequest struct {
-	Twitter   *string `json:"twitter,omitempty" validate:"omitempty,url"`
-	Instagram *string `json:"instagram,omitempty" validate:"omitempty,url"`
-	Facebook  *string `json:"facebook,omitempty" validate:"omitempty,url"`
-	LinkedIn  *string `json:"linkedin,omitempty" validate:"omitempty,url"`
-	GitHub    *string `json:"github,omitempty" validate:"omitempty,url"`
-	YouTube   *string `json:"youtube,omitempty" validate:"omitempty,url"`
+	Twitter   *string `json:"twitter,omitempty" validate:"omitempty,url" binding:"omitempty,url"`
+	Instagram *string `json:"instagram,omitempty" validate:"omitempty,url" binding:"omitempty,url"`
+	Facebook  *string `json:"facebook,omitempty" validate:"omitempty,url" binding:"omitempty,url"`
+	LinkedIn  *string `json:"linkedin,omitempty" validate:"omitempty,url" binding:"omitempty,url"`
+	GitHub    *string `json:"github,omitempty" validate:"omitempty,url" binding:"omitempty,url"`
+	YouTube   *string `json:"youtube,omitempty" validate:"omitempty,url" binding:"omitempty,url"`
+}
+
+// Links returns the links keyed the way they are stored in users.social_links
+func (r *UpdateSocialLinksRequest) Links() map[string]*string {
+	return map[string]*string{
+		"twitter":   r.Twitter,
+		"instagram": r.Instagram,
+		"facebook":  r.Facebook,
+		"linkedin":  r.LinkedIn,
+		"github":    r.GitHub,
+		"youtube":   r.YouTube,
+	}
 }
 
 // PublicProfileResponse represents a public-facing user profile with privacy filtering applied
@@ -463,8 +587,13 @@ func (u *User) ToSafeUser() *User {
 		Gender:          u.Gender,
 		GenderCustom:    u.GenderCustom,
 		Website:         u.Website,
+		Timezone:        u.Timezone,
 		IsVerified:      u.IsVerified,
 		ProfilePrivacy:  u.ProfilePrivacy,
+		Discoverable:    u.Discoverable,
+		ShowNSFW:        u.ShowNSFW,
+		HideInteracted:  u.HideInteracted,
+		FeedLanguages:   u.FeedLanguages,
 		FieldVisibility: u.FieldVisibility,
 		StatVisibility:  u.StatVisibility,
 		Story:           u.Story,
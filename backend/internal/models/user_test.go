@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAccountRestrictionsApply(t *testing.T) {
+	rules := NewAccountRestrictions{Period: 7 * 24 * time.Hour, TrustedFollowers: 10}
+	fresh := time.Now().Add(-time.Hour)
+	matured := time.Now().Add(-8 * 24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		rules NewAccountRestrictions
+		user  User
+		want  bool
+	}{
+		{"fresh", rules, User{CreatedAt: fresh}, true},
+		{"matured", rules, User{CreatedAt: matured}, false},
+		{"verified account", rules, User{CreatedAt: fresh, IsVerified: true}, false},
+		{"verified email and followers", rules, User{CreatedAt: fresh, IsEmailVerified: true, FollowersCount: 10}, false},
+		{"verified email alone", rules, User{CreatedAt: fresh, IsEmailVerified: true, FollowersCount: 9}, true},
+		{"followers alone", rules, User{CreatedAt: fresh, FollowersCount: 50}, true},
+		{"disabled", NewAccountRestrictions{}, User{CreatedAt: fresh}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rules.Applies(&tt.user); got != tt.want {
+			t.Errorf("%s: Applies = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
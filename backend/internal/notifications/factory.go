@@ -63,6 +63,54 @@ func (f *NotificationFactory) NewFollowBackNotification(followerID, followedID u
 	}
 }
 
+// NewFollowRequestNotification creates a notification when someone asks to follow a
+// private account
+func (f *NotificationFactory) NewFollowRequestNotification(requesterID, targetID, requestID uuid.UUID, requesterUsername string) *models.Notification {
+	actionURL := "/relationships/requests"
+	actionType := "accept_follow_request"
+	message := "wants to follow you"
+
+	return &models.Notification{
+		UserID:       targetID,
+		Type:         models.NotificationFollowRequest,
+		Category:     models.CategorySocial,
+		Title:        fmt.Sprintf("%s requested to follow you", requesterUsername),
+		Message:      &message,
+		ActorID:      &requesterID,
+		ActionURL:    &actionURL,
+		IsActionable: true,
+		ActionType:   &actionType,
+		ActionTaken:  false,
+		Metadata: map[string]interface{}{
+			"request_id": requestID.String(),
+		},
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().AddDate(0, 0, 30),
+	}
+}
+
+// NewFollowRequestAcceptedNotification creates a notification when a private account
+// accepts a follow request
+func (f *NotificationFactory) NewFollowRequestAcceptedNotification(acceptorID, requesterID uuid.UUID, acceptorUsername string) *models.Notification {
+	actionURL := fmt.Sprintf("/profile/%s", acceptorUsername)
+	message := "accepted your follow request"
+
+	return &models.Notification{
+		UserID:       requesterID,
+		Type:         models.NotificationFollowRequestAccepted,
+		Category:     models.CategorySocial,
+		Title:        fmt.Sprintf("%s accepted your follow request", acceptorUsername),
+		Message:      &message,
+		ActorID:      &acceptorID,
+		ActionURL:    &actionURL,
+		IsActionable: false,
+		ActionTaken:  false,
+		Metadata:     map[string]interface{}{},
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().AddDate(0, 0, 30),
+	}
+}
+
 // NewConnectionRequestNotification creates a notification for connection requests
 func (f *NotificationFactory) NewConnectionRequestNotification(fromID, toID uuid.UUID, fromUsername string) *models.Notification {
 	actionURL := fmt.Sprintf("/profile/%s", fromUsername)
@@ -274,6 +322,39 @@ func (f *NotificationFactory) NewPostCommentNotification(commenterID, postOwnerI
 	}
 }
 
+// NewMentionNotification creates a notification when someone @mentions a user in a post,
+// or in a comment on it when commentID is set
+func (f *NotificationFactory) NewMentionNotification(actorID, mentionedID uuid.UUID, actorUsername string, postID uuid.UUID, commentID *uuid.UUID, snippet string) *models.Notification {
+	actionURL := fmt.Sprintf("/posts/%s", postID.String())
+	targetType := "post"
+	title := fmt.Sprintf("%s mentioned you in a post", actorUsername)
+	metadata := map[string]interface{}{
+		"post_id": postID.String(),
+		"snippet": snippet,
+	}
+	if commentID != nil {
+		title = fmt.Sprintf("%s mentioned you in a comment", actorUsername)
+		metadata["comment_id"] = commentID.String()
+	}
+
+	return &models.Notification{
+		UserID:       mentionedID,
+		Type:         models.NotificationPostMention,
+		Category:     models.CategorySocial,
+		Title:        title,
+		Message:      &snippet,
+		ActorID:      &actorID,
+		TargetID:     &postID,
+		TargetType:   &targetType,
+		ActionURL:    &actionURL,
+		IsActionable: false,
+		ActionTaken:  false,
+		Metadata:     metadata,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().AddDate(0, 0, 30),
+	}
+}
+
 // =====================================================
 // FUTURE: PROJECT NOTIFICATIONS
 // =====================================================
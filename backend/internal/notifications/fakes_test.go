@@ -0,0 +1,119 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakeNotificationRepo stores created notifications and serves one set of preferences;
+// anything else panics through the nil embedded interface
+type fakeNotificationRepo struct {
+	repository.NotificationRepository
+
+	prefs   *models.NotificationPreferences
+	created []*models.Notification
+}
+
+func (r *fakeNotificationRepo) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	return r.prefs, nil
+}
+
+func (r *fakeNotificationRepo) UpsertPreferences(ctx context.Context, prefs *models.NotificationPreferences) error {
+	r.prefs = prefs
+	return nil
+}
+
+func (r *fakeNotificationRepo) Create(ctx context.Context, notification *models.Notification) error {
+	notification.ID = uuid.New()
+	r.created = append(r.created, notification)
+	return nil
+}
+
+// fakeUserRepo serves users from a map
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	users map[uuid.UUID]*models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// fakeDeviceRepo keeps device tokens in memory
+type fakeDeviceRepo struct {
+	repository.DeviceTokenRepository
+
+	devices []*models.DeviceToken
+}
+
+func (r *fakeDeviceRepo) SaveDeviceToken(ctx context.Context, token *models.DeviceToken) error {
+	r.devices = append(r.devices, token)
+	return nil
+}
+
+func (r *fakeDeviceRepo) GetDeviceTokens(ctx context.Context, userID uuid.UUID) ([]*models.DeviceToken, error) {
+	var tokens []*models.DeviceToken
+	for _, d := range r.devices {
+		if d.UserID == userID {
+			tokens = append(tokens, d)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *fakeDeviceRepo) DeleteDeviceTokens(ctx context.Context, platform models.PushPlatform, tokens []string) error {
+	prune := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		prune[token] = true
+	}
+	kept := r.devices[:0]
+	for _, d := range r.devices {
+		if d.Platform != platform || !prune[d.Token] {
+			kept = append(kept, d)
+		}
+	}
+	r.devices = kept
+	return nil
+}
+
+// mockPushProvider records what it was asked to send and reports the tokens in invalid
+// as rejected
+type mockPushProvider struct {
+	platform models.PushPlatform
+	invalid  map[string]bool
+	rejects  map[string]bool // Refused at registration
+
+	sent     [][]string // Tokens of each Send
+	payloads []*models.PushPayload
+}
+
+func (p *mockPushProvider) Platform() models.PushPlatform { return p.platform }
+
+func (p *mockPushProvider) RegisterToken(ctx context.Context, token string) error {
+	if p.rejects[token] {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+func (p *mockPushProvider) Send(ctx context.Context, tokens []string, payload *models.PushPayload) ([]string, error) {
+	p.sent = append(p.sent, tokens)
+	p.payloads = append(p.payloads, payload)
+	var invalid []string
+	for _, token := range tokens {
+		if p.invalid[token] {
+			invalid = append(invalid, token)
+		}
+	}
+	return invalid, nil
+}
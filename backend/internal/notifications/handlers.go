@@ -3,8 +3,11 @@ package notifications
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -26,11 +29,13 @@ func NewHandlers(service *NotificationService) *Handlers {
 // GET NOTIFICATIONS
 // =====================================================
 
-// GetNotifications handles GET /api/v1/notifications
+// GetNotifications handles GET /api/v1/notifications. It pages by offset, or by the
+// next_cursor of the previous page, which stays stable as new notifications arrive.
+// With ?after={notificationId} it returns what arrived since that one instead.
 func (h *Handlers) GetNotifications(c *gin.Context) {
 	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -38,15 +43,6 @@ func (h *Handlers) GetNotifications(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Parse query parameters
 	categoryStr := c.Query("category")
 	unreadStr := c.Query("unread")
@@ -64,28 +60,83 @@ func (h *Handlers) GetNotifications(c *gin.Context) {
 		offset = 0
 	}
 
+	filter := models.NotificationFilter{
+		Limit:       limit,
+		Offset:      offset,
+		UnreadFirst: c.Query("unread_first") == "true",
+	}
+
 	// Parse category
-	var category *models.NotificationCategory
 	if categoryStr != "" {
 		cat := models.NotificationCategory(categoryStr)
-		category = &cat
+		filter.Category = &cat
+	}
+
+	// Parse type filter: one type or a comma-separated list, e.g. ?type=post_mention
+	for _, t := range strings.Split(c.Query("type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.Types = append(filter.Types, models.NotificationType(t))
+		}
 	}
 
 	// Parse unread filter
-	var unread *bool
 	if unreadStr != "" {
 		unreadBool := unreadStr == "true"
-		unread = &unreadBool
+		filter.Unread = &unreadBool
+	}
+
+	// ?after= catches up on the notifications newer than one the client already has,
+	// oldest first, after a reconnect. Other filters don't apply.
+	if afterStr := c.Query("after"); afterStr != "" {
+		afterID, err := uuid.Parse(afterStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid after notification ID",
+			})
+			return
+		}
+
+		notifications, hasMore, err := h.service.GetNotificationsAfter(c.Request.Context(), currentUserID, afterID, limit)
+		if err != nil {
+			appErr := errors.GetAppError(err)
+			c.JSON(appErr.Code, gin.H{
+				"success": false,
+				"message": appErr.Message,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":       true,
+			"notifications": notifications,
+			"total":         len(notifications),
+			"limit":         limit,
+			"after":         afterID,
+			"has_more":      hasMore,
+		})
+		return
+	}
+
+	// A cursor from a previous page takes the place of offset
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := models.DecodeNotificationCursor(cursorStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid cursor",
+			})
+			return
+		}
+		filter.Cursor = cursor
+		filter.Offset = 0
 	}
 
 	// Get notifications
-	notifications, total, unreadCount, err := h.service.GetNotifications(
+	notifications, next, total, unreadCount, err := h.service.GetNotifications(
 		c.Request.Context(),
 		currentUserID,
-		category,
-		unread,
-		limit,
-		offset,
+		filter,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -95,21 +146,28 @@ func (h *Handlers) GetNotifications(c *gin.Context) {
 		return
 	}
 
+	var nextCursor *string
+	if next != nil {
+		encoded := next.Encode()
+		nextCursor = &encoded
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":       true,
 		"notifications": notifications,
 		"total":         total,
 		"unread":        unreadCount,
 		"limit":         limit,
-		"offset":        offset,
+		"offset":        filter.Offset,
+		"next_cursor":   nextCursor,
 	})
 }
 
 // GetUnreadCount handles GET /api/v1/notifications/unread-count
 func (h *Handlers) GetUnreadCount(c *gin.Context) {
 	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -117,15 +175,6 @@ func (h *Handlers) GetUnreadCount(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Parse category filter (optional)
 	categoryStr := c.Query("category")
 	var category *models.NotificationCategory
@@ -144,6 +193,15 @@ func (h *Handlers) GetUnreadCount(c *gin.Context) {
 		return
 	}
 
+	unseen, err := h.service.GetUnseenCount(c.Request.Context(), currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to fetch unseen count",
+		})
+		return
+	}
+
 	// Get category counts
 	categoryCounts, err := h.service.GetCategoryCounts(c.Request.Context(), currentUserID)
 	if err != nil {
@@ -157,6 +215,7 @@ func (h *Handlers) GetUnreadCount(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success":         true,
 		"total":           total,
+		"unseen":          unseen,
 		"category_counts": categoryCounts,
 	})
 }
@@ -168,8 +227,8 @@ func (h *Handlers) GetUnreadCount(c *gin.Context) {
 // MarkAsRead handles PATCH /api/v1/notifications/:id/read
 func (h *Handlers) MarkAsRead(c *gin.Context) {
 	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -177,15 +236,6 @@ func (h *Handlers) MarkAsRead(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get notification ID from URL
 	notificationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -211,11 +261,11 @@ func (h *Handlers) MarkAsRead(c *gin.Context) {
 	})
 }
 
-// MarkAllAsRead handles PATCH /api/v1/notifications/read-all
-func (h *Handlers) MarkAllAsRead(c *gin.Context) {
-	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+// MarkAllSeen handles POST /api/v1/notifications/seen. It clears the badge without
+// marking anything read.
+func (h *Handlers) MarkAllSeen(c *gin.Context) {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -223,11 +273,30 @@ func (h *Handlers) MarkAllAsRead(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
+	updated, err := h.service.MarkAllSeen(c.Request.Context(), currentUserID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to mark notifications seen",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Notifications marked as seen",
+		"updated": updated,
+	})
+}
+
+// MarkAllAsRead handles POST (and PATCH) /api/v1/notifications/read-all
+func (h *Handlers) MarkAllAsRead(c *gin.Context) {
+	// Get current user ID from context
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
-			"message": "Invalid user ID",
+			"message": "Unauthorized",
 		})
 		return
 	}
@@ -248,7 +317,8 @@ func (h *Handlers) MarkAllAsRead(c *gin.Context) {
 	}
 
 	// Mark all as read
-	if err := h.service.MarkAllAsRead(c.Request.Context(), currentUserID, category); err != nil {
+	updated, err := h.service.MarkAllAsRead(c.Request.Context(), currentUserID, category)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Failed to mark all as read",
@@ -259,6 +329,7 @@ func (h *Handlers) MarkAllAsRead(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "All notifications marked as read",
+		"updated": updated,
 	})
 }
 
@@ -269,8 +340,8 @@ func (h *Handlers) MarkAllAsRead(c *gin.Context) {
 // DeleteNotification handles DELETE /api/v1/notifications/:id
 func (h *Handlers) DeleteNotification(c *gin.Context) {
 	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -278,15 +349,6 @@ func (h *Handlers) DeleteNotification(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get notification ID from URL
 	notificationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -319,8 +381,8 @@ func (h *Handlers) DeleteNotification(c *gin.Context) {
 // TakeAction handles POST /api/v1/notifications/:id/action
 func (h *Handlers) TakeAction(c *gin.Context) {
 	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -328,15 +390,6 @@ func (h *Handlers) TakeAction(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get notification ID from URL
 	notificationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -379,8 +432,8 @@ func (h *Handlers) TakeAction(c *gin.Context) {
 // GetPreferences handles GET /api/v1/notifications/preferences
 func (h *Handlers) GetPreferences(c *gin.Context) {
 	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -388,36 +441,70 @@ func (h *Handlers) GetPreferences(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
+	// Get preferences
+	prefs, err := h.service.GetPreferences(c.Request.Context(), currentUserID)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to fetch preferences",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"preferences": prefs,
+	})
+}
+
+// UpdatePreferences handles PATCH and PUT /api/v1/notifications/preferences
+func (h *Handlers) UpdatePreferences(c *gin.Context) {
+	// Get current user ID from context
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	// Parse request body
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Invalid user ID",
+			"message": "Invalid request body",
 		})
 		return
 	}
 
-	// Get preferences
-	prefs, err := h.service.GetPreferences(c.Request.Context(), currentUserID)
+	// Update preferences
+	prefs, err := h.service.UpdatePreferences(c.Request.Context(), currentUserID, &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"message": "Failed to fetch preferences",
+			"message": "Failed to update preferences",
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
+		"message":     "Preferences updated successfully",
 		"preferences": prefs,
 	})
 }
 
-// UpdatePreferences handles PATCH /api/v1/notifications/preferences
-func (h *Handlers) UpdatePreferences(c *gin.Context) {
+// =====================================================
+// PUSH DEVICES
+// =====================================================
+
+// RegisterDevice handles POST /api/v1/notifications/devices
+func (h *Handlers) RegisterDevice(c *gin.Context) {
 	// Get current user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -425,17 +512,43 @@ func (h *Handlers) UpdatePreferences(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
+	var req models.RegisterDeviceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Invalid user ID",
+			"message": "Invalid request body",
 		})
 		return
 	}
 
-	// Parse request body
-	var req models.UpdateNotificationPreferencesRequest
+	if err := h.service.RegisterDevice(c.Request.Context(), currentUserID, &req); err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Device registered for push notifications",
+	})
+}
+
+// UnregisterDevice handles DELETE /api/v1/notifications/devices
+func (h *Handlers) UnregisterDevice(c *gin.Context) {
+	// Get current user ID from context
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	var req models.UnregisterDeviceTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -444,18 +557,18 @@ func (h *Handlers) UpdatePreferences(c *gin.Context) {
 		return
 	}
 
-	// Update preferences
-	if err := h.service.UpdatePreferences(c.Request.Context(), currentUserID, &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if err := h.service.UnregisterDevice(c.Request.Context(), currentUserID, req.Token); err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
 			"success": false,
-			"message": "Failed to update preferences",
+			"message": appErr.Message,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Preferences updated successfully",
+		"message": "Device unregistered",
 	})
 }
 
@@ -474,6 +587,8 @@ func (h *Handlers) SetupRoutes(router *gin.RouterGroup) {
 		// Update
 		notifications.PATCH("/:id/read", h.MarkAsRead)
 		notifications.PATCH("/read-all", h.MarkAllAsRead)
+		notifications.POST("/read-all", h.MarkAllAsRead)
+		notifications.POST("/seen", h.MarkAllSeen)
 
 		// Delete
 		notifications.DELETE("/:id", h.DeleteNotification)
@@ -484,5 +599,10 @@ func (h *Handlers) SetupRoutes(router *gin.RouterGroup) {
 		// Preferences
 		notifications.GET("/preferences", h.GetPreferences)
 		notifications.PATCH("/preferences", h.UpdatePreferences)
+		notifications.PUT("/preferences", h.UpdatePreferences)
+
+		// Push devices
+		notifications.POST("/devices", h.RegisterDevice)
+		notifications.DELETE("/devices", h.UnregisterDevice)
 	}
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/queue"
 	"histeeria-backend/internal/repository"
 	"histeeria-backend/internal/websocket"
+	"histeeria-backend/pkg/errors"
 
 	"github.com/google/uuid"
 )
@@ -21,6 +23,9 @@ type NotificationService struct {
 	factory       *NotificationFactory
 	emailSvc      *EmailService
 	queueProvider queue.QueueProvider // Queue provider for async email sending
+
+	deviceRepo    repository.DeviceTokenRepository // Nil disables push
+	pushProviders map[models.PushPlatform]PushProvider
 }
 
 // NewNotificationService creates a new notification service
@@ -92,6 +97,11 @@ func (s *NotificationService) CreateNotification(ctx context.Context, notificati
 	// Send via WebSocket (real-time)
 	s.sendViaWebSocket(notification)
 
+	// Push to the user's devices (through the push worker)
+	if s.shouldPush(notification, prefs) {
+		s.queuePush(ctx, notification)
+	}
+
 	// Send via email if enabled and instant delivery
 	if prefs.ShouldSendEmail(notification.Type) && prefs.EmailFrequency == models.EmailFrequencyInstant {
 		// Get user email
@@ -161,23 +171,31 @@ func (s *NotificationService) CreateBulkNotifications(ctx context.Context, notif
 // READ NOTIFICATIONS
 // =====================================================
 
-// GetNotifications retrieves notifications for a user with filters
+// GetNotifications retrieves a page of notifications for a user with filters, and the
+// cursor for the next page (nil when there is none)
 func (s *NotificationService) GetNotifications(
 	ctx context.Context,
 	userID uuid.UUID,
-	category *models.NotificationCategory,
-	unread *bool,
-	limit int,
-	offset int,
-) ([]*models.NotificationResponse, int, int, error) {
+	filter models.NotificationFilter,
+) ([]*models.NotificationResponse, *models.NotificationCursor, int, int, error) {
+	// Ask for one more than the page to learn whether another page follows
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
 	// Get notifications from repository
-	notifications, total, err := s.repo.GetByUser(ctx, userID, category, unread, limit, offset)
+	notifications, total, err := s.repo.GetByUser(ctx, userID, filter)
 	if err != nil {
-		return nil, 0, 0, fmt.Errorf("failed to get notifications: %w", err)
+		return nil, nil, 0, 0, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	var next *models.NotificationCursor
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		next = notifications[limit-1].NextCursor()
 	}
 
 	// Get unread count
-	unreadCount, err := s.repo.GetUnreadCount(ctx, userID, category)
+	unreadCount, err := s.repo.GetUnreadCount(ctx, userID, filter.Category)
 	if err != nil {
 		log.Printf("[NotificationService] Failed to get unread count: %v", err)
 		unreadCount = 0
@@ -189,7 +207,36 @@ func (s *NotificationService) GetNotifications(
 		responses[i] = notif.ToResponse()
 	}
 
-	return responses, total, unreadCount, nil
+	return responses, next, total, unreadCount, nil
+}
+
+// GetNotificationsAfter returns up to limit of a user's notifications that arrived
+// after afterID, oldest first, and whether more follow. Clients call it when they
+// reconnect, with the newest notification they have, to catch up on what they missed.
+func (s *NotificationService) GetNotificationsAfter(ctx context.Context, userID, afterID uuid.UUID, limit int) ([]*models.NotificationResponse, bool, error) {
+	after, err := s.repo.GetByID(ctx, afterID, userID)
+	if err != nil {
+		return nil, false, errors.ErrInvalidAfterNotification
+	}
+
+	notifications, _, err := s.repo.GetByUser(ctx, userID, models.NotificationFilter{
+		Limit: limit + 1, // One extra tells whether there's another page
+		After: after.NextCursor(),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	hasMore := len(notifications) > limit
+	if hasMore {
+		notifications = notifications[:limit]
+	}
+
+	responses := make([]*models.NotificationResponse, len(notifications))
+	for i, notif := range notifications {
+		responses[i] = notif.ToResponse()
+	}
+	return responses, hasMore, nil
 }
 
 // GetNotificationByID retrieves a single notification
@@ -228,16 +275,40 @@ func (s *NotificationService) MarkAsRead(ctx context.Context, id uuid.UUID, user
 	return nil
 }
 
-// MarkAllAsRead marks all notifications as read for a user
-func (s *NotificationService) MarkAllAsRead(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory) error {
-	if err := s.repo.MarkAllAsRead(ctx, userID, category); err != nil {
-		return fmt.Errorf("failed to mark all as read: %w", err)
+// MarkAllAsRead marks all of a user's unread notifications as read and returns how
+// many there were
+func (s *NotificationService) MarkAllAsRead(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory) (int, error) {
+	updated, err := s.repo.MarkAllAsRead(ctx, userID, category)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark all as read: %w", err)
 	}
 
 	// Send count update via WebSocket
-	s.sendCountUpdate(ctx, userID)
+	if updated > 0 {
+		s.sendCountUpdate(ctx, userID)
+	}
 
-	return nil
+	return updated, nil
+}
+
+// MarkAllSeen clears the badge: every notification the user has now counts as seen,
+// but stays unread until opened. Returns how many were marked.
+func (s *NotificationService) MarkAllSeen(ctx context.Context, userID uuid.UUID) (int, error) {
+	updated, err := s.repo.MarkAllSeen(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications seen: %w", err)
+	}
+
+	if updated > 0 {
+		s.sendCountUpdate(ctx, userID)
+	}
+
+	return updated, nil
+}
+
+// GetUnseenCount gets the badge count: unread notifications not yet seen
+func (s *NotificationService) GetUnseenCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	return s.repo.GetUnseenCount(ctx, userID)
 }
 
 // DeleteNotification deletes a notification
@@ -300,8 +371,10 @@ func (s *NotificationService) GetPreferences(ctx context.Context, userID uuid.UU
 	return s.repo.GetPreferences(ctx, userID)
 }
 
-// UpdatePreferences updates notification preferences
-func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uuid.UUID, req *models.UpdateNotificationPreferencesRequest) error {
+// UpdatePreferences applies req to a user's notification preferences and returns
+// them as saved. The per-type and per-category maps are merged key by key, so a
+// client can flip one type without resending the rest.
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uuid.UUID, req *models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
 	// Get current preferences
 	prefs, err := s.repo.GetPreferences(ctx, userID)
 	if err != nil {
@@ -312,7 +385,8 @@ func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uuid
 			EmailTypes:           make(map[string]bool),
 			EmailFrequency:       models.EmailFrequencyInstant,
 			InAppEnabled:         true,
-			PushEnabled:          false,
+			PushEnabled:          true,
+			PushTypes:            make(map[string]bool),
 			InlineActionsEnabled: true,
 			CategoriesEnabled:    make(map[string]bool),
 		}
@@ -322,9 +396,7 @@ func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uuid
 	if req.EmailEnabled != nil {
 		prefs.EmailEnabled = *req.EmailEnabled
 	}
-	if req.EmailTypes != nil {
-		prefs.EmailTypes = req.EmailTypes
-	}
+	prefs.EmailTypes = mergeToggles(prefs.EmailTypes, req.EmailTypes)
 	if req.EmailFrequency != nil {
 		prefs.EmailFrequency = *req.EmailFrequency
 	}
@@ -334,20 +406,122 @@ func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uuid
 	if req.PushEnabled != nil {
 		prefs.PushEnabled = *req.PushEnabled
 	}
+	prefs.PushTypes = mergeToggles(prefs.PushTypes, req.PushTypes)
 	if req.InlineActionsEnabled != nil {
 		prefs.InlineActionsEnabled = *req.InlineActionsEnabled
 	}
-	if req.CategoriesEnabled != nil {
-		prefs.CategoriesEnabled = req.CategoriesEnabled
+	prefs.CategoriesEnabled = mergeToggles(prefs.CategoriesEnabled, req.CategoriesEnabled)
+	if req.Mute != nil {
+		prefs.MutedUntil = nil
+		if *req.Mute {
+			until := time.Now().Add(models.MuteDuration)
+			prefs.MutedUntil = &until
+		}
 	}
 
 	// Save preferences
 	if err := s.repo.UpsertPreferences(ctx, prefs); err != nil {
-		return fmt.Errorf("failed to update preferences: %w", err)
+		return nil, fmt.Errorf("failed to update preferences: %w", err)
 	}
 
 	log.Printf("[NotificationService] Preferences updated for user %s", userID)
-	return nil
+	return prefs, nil
+}
+
+// mergeToggles sets each of updates' keys in toggles
+func mergeToggles(toggles, updates map[string]bool) map[string]bool {
+	if toggles == nil {
+		toggles = make(map[string]bool, len(updates))
+	}
+	for k, v := range updates {
+		toggles[k] = v
+	}
+	return toggles
+}
+
+// =====================================================
+// EMAIL DIGESTS
+// =====================================================
+
+// SendDigests emails a summary of unread notifications to every user on a daily or
+// weekly digest whose period is up, returning how many digests went out. Only the email
+// channel is batched; WebSocket and push delivery happen as notifications are created.
+func (s *NotificationService) SendDigests(ctx context.Context) (int, error) {
+	sent := 0
+	for _, frequency := range []models.EmailFrequency{models.EmailFrequencyDaily, models.EmailFrequencyWeekly} {
+		digests, err := s.repo.GetUnreadForDigest(ctx, frequency)
+		if err != nil {
+			return sent, fmt.Errorf("failed to load %s digests: %w", frequency, err)
+		}
+
+		for userID, notifications := range digests {
+			ok, err := s.sendDigest(ctx, userID, notifications, frequency)
+			if err != nil {
+				log.Printf("[NotificationService] Failed to send %s digest to user %s: %v", frequency, userID, err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	return sent, nil
+}
+
+// sendDigest emails one user's digest and marks its notifications as digested. Types the
+// user doesn't want emailed are dropped from it; while the user is muted nothing is
+// sent or marked, so the digest goes out once the mute ends.
+func (s *NotificationService) sendDigest(ctx context.Context, userID uuid.UUID, notifications []*models.Notification, frequency models.EmailFrequency) (bool, error) {
+	prefs, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if prefs.IsMuted() {
+		return false, nil
+	}
+
+	var included []*models.Notification
+	ids := make([]uuid.UUID, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+		if prefs.ShouldSendEmail(n.Type) {
+			included = append(included, n)
+		}
+	}
+
+	if len(included) > 0 {
+		user, err := s.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		if user.Email == "" {
+			included = nil
+		} else if err := s.deliverDigest(ctx, user.Email, included, frequency); err != nil {
+			return false, err
+		}
+	}
+
+	// Mark everything, including the dropped types, so none of it is considered again
+	if err := s.repo.MarkDigestSent(ctx, userID, ids); err != nil {
+		return false, err
+	}
+	return len(included) > 0, nil
+}
+
+// deliverDigest queues the digest email, sending it directly if there is no queue
+func (s *NotificationService) deliverDigest(ctx context.Context, email string, notifications []*models.Notification, frequency models.EmailFrequency) error {
+	if s.queueProvider != nil {
+		subject, htmlBody, textBody, err := s.emailSvc.buildDigestEmailContent(notifications, frequency)
+		if err != nil {
+			return fmt.Errorf("failed to render digest email: %w", err)
+		}
+		err = queue.QueueDigestEmail(ctx, s.queueProvider, email, subject, htmlBody, textBody)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[NotificationService] Failed to queue digest to %s, sending directly: %v", email, err)
+	}
+	return s.emailSvc.SendDigestEmail(ctx, email, notifications, frequency)
 }
 
 // =====================================================
@@ -394,6 +568,12 @@ func (s *NotificationService) sendCountUpdate(ctx context.Context, userID uuid.U
 		return
 	}
 
+	unseen, err := s.repo.GetUnseenCount(ctx, userID)
+	if err != nil {
+		log.Printf("[NotificationService] Failed to get unseen count: %v", err)
+		return
+	}
+
 	// Get category counts
 	categoryCounts, err := s.repo.GetCategoryCounts(ctx, userID)
 	if err != nil {
@@ -401,7 +581,7 @@ func (s *NotificationService) sendCountUpdate(ctx context.Context, userID uuid.U
 		return
 	}
 
-	s.wsManager.BroadcastCountUpdate(userID, total, categoryCounts)
+	s.wsManager.BroadcastCountUpdate(userID, total, unseen, categoryCounts)
 
 	log.Printf("[NotificationService] Sent count update via WebSocket to user %s (total: %d)", userID, total)
 }
@@ -418,6 +598,18 @@ func (s *NotificationService) CreateFollowNotification(ctx context.Context, foll
 	return s.CreateNotification(ctx, notification)
 }
 
+// CreateFollowRequestNotification creates and sends a follow request notification
+func (s *NotificationService) CreateFollowRequestNotification(ctx context.Context, requesterID, targetID, requestID uuid.UUID, requesterUsername string) error {
+	notification := s.factory.NewFollowRequestNotification(requesterID, targetID, requestID, requesterUsername)
+	return s.CreateNotification(ctx, notification)
+}
+
+// CreateFollowRequestAcceptedNotification creates and sends a follow request accepted notification
+func (s *NotificationService) CreateFollowRequestAcceptedNotification(ctx context.Context, acceptorID, requesterID uuid.UUID, acceptorUsername string) error {
+	notification := s.factory.NewFollowRequestAcceptedNotification(acceptorID, requesterID, acceptorUsername)
+	return s.CreateNotification(ctx, notification)
+}
+
 // CreateConnectionRequestNotification creates and sends a connection request notification
 func (s *NotificationService) CreateConnectionRequestNotification(ctx context.Context, fromID, toID uuid.UUID, fromUsername string) error {
 	notification := s.factory.NewConnectionRequestNotification(fromID, toID, fromUsername)
@@ -441,3 +633,9 @@ func (s *NotificationService) CreateCollaborationAcceptedNotification(ctx contex
 	notification := s.factory.NewCollaborationAcceptedNotification(acceptorID, requesterID, acceptorUsername)
 	return s.CreateNotification(ctx, notification)
 }
+
+// CreateMentionNotification creates and sends a mention notification
+func (s *NotificationService) CreateMentionNotification(ctx context.Context, actorID, mentionedID uuid.UUID, actorUsername string, postID uuid.UUID, commentID *uuid.UUID, snippet string) error {
+	notification := s.factory.NewMentionNotification(actorID, mentionedID, actorUsername, postID, commentID, snippet)
+	return s.CreateNotification(ctx, notification)
+}
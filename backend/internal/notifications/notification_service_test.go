@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/queue"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateNotificationQueuesEmailOnlyForEnabledTypes(t *testing.T) {
+	follower := uuid.New()
+	followed := &models.User{ID: uuid.New(), Email: "ada@example.com"}
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		prefs     models.NotificationPreferences
+		wantEmail bool
+		wantPush  bool
+	}{
+		{"type on", emailPrefs(true, nil), true, true},
+		{"type off", emailPrefs(false, nil), false, true},
+		{"muted", emailPrefs(true, &later), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeNotificationRepo{prefs: &tt.prefs}
+			jobs := queue.NewMemoryQueueProvider()
+			svc := NewNotificationService(repo, &fakeUserRepo{users: map[uuid.UUID]*models.User{followed.ID: followed}}, nil, nil, jobs)
+			svc.SetPush(&fakeDeviceRepo{})
+			ctx := context.Background()
+
+			if err := svc.CreateFollowNotification(ctx, follower, followed.ID, "grace"); err != nil {
+				t.Fatalf("CreateFollowNotification: %v", err)
+			}
+			if len(repo.created) != 1 {
+				t.Errorf("stored %d notifications, want the in-app one regardless", len(repo.created))
+			}
+			emails, _ := jobs.GetPendingCount(ctx, queue.QueueEmail)
+			if (emails == 1) != tt.wantEmail {
+				t.Errorf("queued %d emails, want email %v", emails, tt.wantEmail)
+			}
+			pushes, _ := jobs.GetPendingCount(ctx, queue.QueueNotification)
+			if (pushes == 1) != tt.wantPush {
+				t.Errorf("queued %d pushes, want push %v", pushes, tt.wantPush)
+			}
+		})
+	}
+}
+
+func TestUpdatePreferencesMergesTypesAndMutes(t *testing.T) {
+	user := uuid.New()
+	prefs := emailPrefs(true, nil)
+	repo := &fakeNotificationRepo{prefs: &prefs}
+	svc := NewNotificationService(repo, nil, nil, nil, nil)
+	mute := true
+
+	saved, err := svc.UpdatePreferences(context.Background(), user, &models.UpdateNotificationPreferencesRequest{
+		EmailTypes: map[string]bool{string(models.NotificationPostLike): false},
+		Mute:       &mute,
+	})
+	if err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+	if !saved.EmailTypes[string(models.NotificationFollow)] || saved.EmailTypes[string(models.NotificationPostLike)] {
+		t.Errorf("email types = %v, want follows kept on and likes turned off", saved.EmailTypes)
+	}
+	if !saved.IsMuted() || saved.MutedUntil.After(time.Now().Add(models.MuteDuration)) {
+		t.Errorf("muted until %v, want about %v from now", saved.MutedUntil, models.MuteDuration)
+	}
+	if saved.ShouldSendEmail(models.NotificationFollow) {
+		t.Error("a muted user shouldn't be emailed")
+	}
+
+	mute = false
+	saved, _ = svc.UpdatePreferences(context.Background(), user, &models.UpdateNotificationPreferencesRequest{Mute: &mute})
+	if saved.IsMuted() || !saved.ShouldSendEmail(models.NotificationFollow) {
+		t.Error("unmuting should restore email")
+	}
+}
+
+// emailPrefs returns preferences with instant email and push on, follow emails set
+// to followEmails, muted until mutedUntil if it's set
+func emailPrefs(followEmails bool, mutedUntil *time.Time) models.NotificationPreferences {
+	return models.NotificationPreferences{
+		InAppEnabled:   true,
+		EmailEnabled:   true,
+		EmailFrequency: models.EmailFrequencyInstant,
+		EmailTypes:     map[string]bool{string(models.NotificationFollow): followEmails},
+		PushEnabled:    true,
+		MutedUntil:     mutedUntil,
+	}
+}
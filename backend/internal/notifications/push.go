@@ -0,0 +1,200 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/queue"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// PushProvider delivers push notifications for one platform, such as APNs for iOS or
+// FCM for Android. NotificationService picks the provider by each device token's
+// platform, so adding one needs no changes to the service.
+type PushProvider interface {
+	// Platform is the device platform this provider serves
+	Platform() models.PushPlatform
+
+	// RegisterToken checks a token when a device registers it. Providers that can't
+	// tell return nil; an error rejects the registration.
+	RegisterToken(ctx context.Context, token string) error
+
+	// Send delivers payload to each token. It returns the tokens the push service
+	// reported as invalid (uninstalled app, expired registration), which are pruned;
+	// err is for failures of the send as a whole.
+	Send(ctx context.Context, tokens []string, payload *models.PushPayload) (invalid []string, err error)
+}
+
+// NoopPushProvider accepts tokens and drops pushes. It stands in for platforms with no
+// provider configured, so devices can register before their push service is set up.
+type NoopPushProvider struct {
+	platform models.PushPlatform
+}
+
+// NewNoopPushProvider creates a no-op provider for platform
+func NewNoopPushProvider(platform models.PushPlatform) *NoopPushProvider {
+	return &NoopPushProvider{platform: platform}
+}
+
+func (p *NoopPushProvider) Platform() models.PushPlatform { return p.platform }
+
+func (p *NoopPushProvider) RegisterToken(ctx context.Context, token string) error { return nil }
+
+func (p *NoopPushProvider) Send(ctx context.Context, tokens []string, payload *models.PushPayload) ([]string, error) {
+	return nil, nil
+}
+
+// SetPush enables push delivery to the devices stored in deviceRepo. Every platform
+// starts with a no-op provider; RegisterPushProvider replaces them.
+func (s *NotificationService) SetPush(deviceRepo repository.DeviceTokenRepository) {
+	s.deviceRepo = deviceRepo
+	s.pushProviders = map[models.PushPlatform]PushProvider{}
+	for _, platform := range []models.PushPlatform{models.PushPlatformIOS, models.PushPlatformAndroid, models.PushPlatformWeb} {
+		s.pushProviders[platform] = NewNoopPushProvider(platform)
+	}
+}
+
+// RegisterPushProvider makes provider the one used for its platform
+func (s *NotificationService) RegisterPushProvider(provider PushProvider) {
+	if s.pushProviders == nil {
+		s.pushProviders = map[models.PushPlatform]PushProvider{}
+	}
+	s.pushProviders[provider.Platform()] = provider
+}
+
+// RegisterDevice stores a device's push token for userID
+func (s *NotificationService) RegisterDevice(ctx context.Context, userID uuid.UUID, req *models.RegisterDeviceTokenRequest) error {
+	if s.deviceRepo == nil {
+		return errors.ErrPushUnavailable
+	}
+	provider, ok := s.pushProviders[req.Platform]
+	if !ok {
+		return errors.ErrPushUnavailable
+	}
+	if err := provider.RegisterToken(ctx, req.Token); err != nil {
+		return errors.ErrInvalidDeviceToken
+	}
+
+	return s.deviceRepo.SaveDeviceToken(ctx, &models.DeviceToken{
+		UserID:     userID,
+		Platform:   req.Platform,
+		Token:      req.Token,
+		AppVersion: req.AppVersion,
+	})
+}
+
+// UnregisterDevice stops pushes to one of userID's devices
+func (s *NotificationService) UnregisterDevice(ctx context.Context, userID uuid.UUID, token string) error {
+	if s.deviceRepo == nil {
+		return errors.ErrPushUnavailable
+	}
+	return s.deviceRepo.DeleteDeviceToken(ctx, userID, token)
+}
+
+// shouldPush reports whether a notification that passed the in-app checks also goes
+// to the user's devices: push must be on for its type and not muted, and a user with
+// the app open already got it over the WebSocket. Connections are per instance, so a
+// user connected elsewhere may get both.
+func (s *NotificationService) shouldPush(notification *models.Notification, prefs *models.NotificationPreferences) bool {
+	if s.deviceRepo == nil || !prefs.ShouldPush(notification.Type) {
+		return false
+	}
+	return s.wsManager == nil || !s.wsManager.IsUserConnected(notification.UserID)
+}
+
+// queuePush hands a notification to the push worker. Without a queue, or if queueing
+// fails, it's sent directly instead.
+func (s *NotificationService) queuePush(ctx context.Context, notification *models.Notification) {
+	payload := pushJobPayload(notification)
+	if s.queueProvider != nil {
+		err := queue.QueuePushNotification(ctx, s.queueProvider, payload)
+		if err == nil {
+			return
+		}
+		log.Printf("[NotificationService] Failed to queue push for user %s: %v", notification.UserID, err)
+	}
+
+	go func() {
+		if err := s.SendPush(context.Background(), payload); err != nil {
+			log.Printf("[NotificationService] Push to user %s failed: %v", notification.UserID, err)
+		}
+	}()
+}
+
+// SendPush delivers a queued push to each of the user's devices through the provider
+// for its platform, pruning tokens the providers report as invalid. It implements
+// queue.PushSender; an error makes the worker retry the whole job, which devices that
+// already got it absorb, since pushes are tagged with the notification ID.
+func (s *NotificationService) SendPush(ctx context.Context, payload *queue.NotificationJobPayload) error {
+	if s.deviceRepo == nil {
+		return nil
+	}
+	userID, err := uuid.Parse(payload.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	devices, err := s.deviceRepo.GetDeviceTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load devices: %w", err)
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+
+	byPlatform := make(map[models.PushPlatform][]string)
+	for _, d := range devices {
+		byPlatform[d.Platform] = append(byPlatform[d.Platform], d.Token)
+	}
+
+	push := &models.PushPayload{
+		Title:     payload.Title,
+		Body:      payload.Body,
+		ActionURL: payload.ActionURL,
+		Tag:       payload.Data["notification_id"],
+		Data:      payload.Data,
+	}
+	var sendErr error
+	for platform, tokens := range byPlatform {
+		provider, ok := s.pushProviders[platform]
+		if !ok {
+			continue
+		}
+		invalid, err := provider.Send(ctx, tokens, push)
+		if err != nil {
+			log.Printf("[NotificationService] %s push to user %s failed: %v", platform, userID, err)
+			sendErr = err
+		}
+		if len(invalid) > 0 {
+			if err := s.deviceRepo.DeleteDeviceTokens(ctx, platform, invalid); err != nil {
+				log.Printf("[NotificationService] Failed to prune %d %s tokens: %v", len(invalid), platform, err)
+			}
+		}
+	}
+	return sendErr
+}
+
+// pushJobPayload renders a notification for a device's notification tray
+func pushJobPayload(notification *models.Notification) *queue.NotificationJobPayload {
+	payload := &queue.NotificationJobPayload{
+		UserID: notification.UserID.String(),
+		Type:   string(notification.Type),
+		Title:  notification.Title,
+		Data: map[string]string{
+			"notification_id": notification.ID.String(),
+			"type":            string(notification.Type),
+		},
+	}
+	if notification.Message != nil {
+		payload.Body = *notification.Message
+	}
+	if notification.ActionURL != nil {
+		payload.ActionURL = *notification.ActionURL
+	}
+	return payload
+}
@@ -0,0 +1,187 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/config"
+	"histeeria-backend/internal/models"
+
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmTokenURL = "https://oauth2.googleapis.com/token"
+
+	// FCM error codes. An unregistered token belongs to an uninstalled app or an expired
+	// registration and never works again.
+	fcmUnregistered    = "UNREGISTERED"
+	fcmInvalidArgument = "INVALID_ARGUMENT"
+)
+
+// FCMProvider sends pushes through the Firebase Cloud Messaging HTTP v1 API. FCM
+// serves both Android apps and browsers (through the Firebase JS SDK), so one
+// provider is registered for each of those platforms.
+type FCMProvider struct {
+	platform   models.PushPlatform
+	sendURL    string
+	webBaseURL string
+	http       *http.Client // Authenticates as the service account
+}
+
+// NewFCMProvider creates an FCM provider for platform, sending as account. Web pushes
+// can only open absolute URLs, so relative action URLs are resolved against
+// webBaseURL; apps get them as they are.
+func NewFCMProvider(account *config.FCMServiceAccount, platform models.PushPlatform, webBaseURL string) *FCMProvider {
+	tokenURL := account.TokenURI
+	if tokenURL == "" {
+		tokenURL = fcmTokenURL
+	}
+	creds := &jwt.Config{
+		Email:      account.ClientEmail,
+		PrivateKey: []byte(account.PrivateKey),
+		TokenURL:   tokenURL,
+		Scopes:     []string{fcmScope},
+	}
+
+	client := creds.Client(context.Background())
+	client.Timeout = 10 * time.Second
+	return &FCMProvider{
+		platform:   platform,
+		sendURL:    fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", account.ProjectID),
+		webBaseURL: strings.TrimRight(webBaseURL, "/"),
+		http:       client,
+	}
+}
+
+func (p *FCMProvider) Platform() models.PushPlatform { return p.platform }
+
+// RegisterToken checks the token with a dry-run send. Only a token FCM rejects fails
+// registration; if FCM can't be reached the token is accepted as is.
+func (p *FCMProvider) RegisterToken(ctx context.Context, token string) error {
+	code, _ := p.send(ctx, map[string]interface{}{
+		"validate_only": true,
+		"message":       map[string]interface{}{"token": token},
+	})
+	// The dry run carries nothing but the token, so an invalid argument can only be it
+	if code == fcmUnregistered || code == fcmInvalidArgument {
+		return fmt.Errorf("token rejected by FCM: %s", code)
+	}
+	return nil
+}
+
+// Send delivers payload to each token. FCM v1 takes one token per request.
+func (p *FCMProvider) Send(ctx context.Context, tokens []string, payload *models.PushPayload) ([]string, error) {
+	var invalid []string
+	var lastErr error
+	for _, token := range tokens {
+		code, err := p.send(ctx, map[string]interface{}{"message": p.message(token, payload)})
+		if code == fcmUnregistered {
+			invalid = append(invalid, token)
+		} else if err != nil {
+			lastErr = err
+		}
+	}
+	return invalid, lastErr
+}
+
+// message builds the FCM message for one token, in the shape its platform expects
+func (p *FCMProvider) message(token string, payload *models.PushPayload) map[string]interface{} {
+	message := map[string]interface{}{
+		"token": token,
+		"notification": map[string]string{
+			"title": payload.Title,
+			"body":  payload.Body,
+		},
+	}
+	data := make(map[string]string, len(payload.Data)+1)
+	for k, v := range payload.Data {
+		data[k] = v
+	}
+	if payload.ActionURL != "" {
+		data["action_url"] = payload.ActionURL
+	}
+	if len(data) > 0 {
+		message["data"] = data
+	}
+
+	switch p.platform {
+	case models.PushPlatformWeb:
+		webpush := map[string]interface{}{}
+		if payload.Tag != "" {
+			webpush["notification"] = map[string]string{"tag": payload.Tag}
+		}
+		if link := p.webLink(payload.ActionURL); link != "" {
+			webpush["fcm_options"] = map[string]string{"link": link}
+		}
+		message["webpush"] = webpush
+	default:
+		android := map[string]interface{}{"priority": "high"}
+		if payload.Tag != "" {
+			android["notification"] = map[string]string{"tag": payload.Tag}
+		}
+		message["android"] = android
+	}
+	return message
+}
+
+// webLink makes actionURL absolute for a web push, which FCM requires to be HTTPS
+func (p *FCMProvider) webLink(actionURL string) string {
+	if strings.HasPrefix(actionURL, "/") {
+		actionURL = p.webBaseURL + actionURL
+	}
+	if !strings.HasPrefix(actionURL, "https://") {
+		return ""
+	}
+	return actionURL
+}
+
+// send posts one request to FCM and returns FCM's error code for the failures that
+// concern the token, fcmUnregistered or fcmInvalidArgument; err covers every failure
+func (p *FCMProvider) send(ctx context.Context, body map[string]interface{}) (code string, err error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.sendURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return "", nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var fcmErr struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.Unmarshal(respBody, &fcmErr)
+
+	err = fmt.Errorf("fcm returned %d %s: %s", resp.StatusCode, fcmErr.Error.Status, fcmErr.Error.Message)
+	for _, d := range fcmErr.Error.Details {
+		if d.ErrorCode == fcmUnregistered || d.ErrorCode == fcmInvalidArgument {
+			return d.ErrorCode, err
+		}
+	}
+	return "", err
+}
@@ -0,0 +1,123 @@
+package notifications
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/queue"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+func TestSendPushTargetsEachPlatformsTokensAndPrunesInvalidOnes(t *testing.T) {
+	user, other := uuid.New(), uuid.New()
+	devices := &fakeDeviceRepo{devices: []*models.DeviceToken{
+		{UserID: user, Platform: models.PushPlatformIOS, Token: "ios-1"},
+		{UserID: user, Platform: models.PushPlatformIOS, Token: "ios-stale"},
+		{UserID: user, Platform: models.PushPlatformAndroid, Token: "android-1"},
+		{UserID: other, Platform: models.PushPlatformIOS, Token: "ios-other"},
+	}}
+	ios := &mockPushProvider{platform: models.PushPlatformIOS, invalid: map[string]bool{"ios-stale": true}}
+	android := &mockPushProvider{platform: models.PushPlatformAndroid}
+
+	svc := NewNotificationService(&fakeNotificationRepo{}, nil, nil, nil, nil)
+	svc.SetPush(devices)
+	svc.RegisterPushProvider(ios)
+	svc.RegisterPushProvider(android)
+
+	notificationID := uuid.New()
+	err := svc.SendPush(context.Background(), &queue.NotificationJobPayload{
+		UserID: user.String(),
+		Title:  "New follower",
+		Data:   map[string]string{"notification_id": notificationID.String()},
+	})
+	if err != nil {
+		t.Fatalf("SendPush: %v", err)
+	}
+
+	if len(ios.sent) != 1 || !sameTokens(ios.sent[0], "ios-1", "ios-stale") {
+		t.Errorf("iOS sends = %v, want one to the user's two iOS tokens", ios.sent)
+	}
+	if len(android.sent) != 1 || !sameTokens(android.sent[0], "android-1") {
+		t.Errorf("Android sends = %v, want one to the user's Android token", android.sent)
+	}
+	if tag := ios.payloads[0].Tag; tag != notificationID.String() {
+		t.Errorf("push tag = %q, want the notification ID so retries collapse", tag)
+	}
+
+	var left []string
+	for _, d := range devices.devices {
+		left = append(left, d.Token)
+	}
+	if !sameTokens(left, "ios-1", "android-1", "ios-other") {
+		t.Errorf("tokens left = %v, want only the invalid one pruned", left)
+	}
+}
+
+func TestCreateNotificationPushesOnlyWhenPreferencesAllow(t *testing.T) {
+	follower, followed := uuid.New(), uuid.New()
+	tests := []struct {
+		name     string
+		prefs    models.NotificationPreferences
+		wantPush bool
+	}{
+		{"push on", models.NotificationPreferences{InAppEnabled: true, PushEnabled: true}, true},
+		{"push off", models.NotificationPreferences{InAppEnabled: true}, false},
+		{"type off", models.NotificationPreferences{InAppEnabled: true, PushEnabled: true,
+			PushTypes: map[string]bool{string(models.NotificationFollow): false}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := queue.NewMemoryQueueProvider()
+			svc := NewNotificationService(&fakeNotificationRepo{prefs: &tt.prefs}, &fakeUserRepo{}, nil, nil, jobs)
+			svc.SetPush(&fakeDeviceRepo{})
+
+			if err := svc.CreateFollowNotification(context.Background(), follower, followed, "ada"); err != nil {
+				t.Fatalf("CreateFollowNotification: %v", err)
+			}
+			queued, _ := jobs.GetPendingCount(context.Background(), queue.QueueNotification)
+			if (queued == 1) != tt.wantPush {
+				t.Errorf("queued %d pushes, want push %v", queued, tt.wantPush)
+			}
+		})
+	}
+}
+
+func TestRegisterDevice(t *testing.T) {
+	user := uuid.New()
+	devices := &fakeDeviceRepo{}
+	svc := NewNotificationService(&fakeNotificationRepo{}, nil, nil, nil, nil)
+	svc.SetPush(devices)
+	svc.RegisterPushProvider(&mockPushProvider{platform: models.PushPlatformAndroid, rejects: map[string]bool{"bad": true}})
+	ctx := context.Background()
+
+	// Platforms without a real provider still accept tokens
+	if err := svc.RegisterDevice(ctx, user, &models.RegisterDeviceTokenRequest{Platform: models.PushPlatformIOS, Token: "ios-1"}); err != nil {
+		t.Errorf("iOS registration: %v", err)
+	}
+	if err := svc.RegisterDevice(ctx, user, &models.RegisterDeviceTokenRequest{Platform: models.PushPlatformAndroid, Token: "bad"}); err != errors.ErrInvalidDeviceToken {
+		t.Errorf("err = %v, want ErrInvalidDeviceToken", err)
+	}
+	if len(devices.devices) != 1 || devices.devices[0].Platform != models.PushPlatformIOS {
+		t.Errorf("stored %d devices, want only the iOS one", len(devices.devices))
+	}
+}
+
+func sameTokens(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	got = append([]string(nil), got...)
+	sort.Strings(got)
+	sort.Strings(want)
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,217 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakePostRepo implements the parts of repository.PostRepository the tests reach;
+// anything else panics through the nil embedded interface
+type fakePostRepo struct {
+	repository.PostRepository
+
+	created []*models.Post
+	due     []models.Post
+	feed    []models.Post // Served by the feed queries, in ranked order
+	posts   map[uuid.UUID]*models.Post
+
+	variants       map[uuid.UUID]map[string]map[string]string // Saved media variants by uploader and URL
+	variantLookups int
+
+	hideInteracted bool // What the last feed query was asked to do
+}
+
+func (r *fakePostRepo) CreatePost(ctx context.Context, post *models.Post) error {
+	post.ID = uuid.New()
+	r.created = append(r.created, post)
+	return nil
+}
+
+func (r *fakePostRepo) GetPostByID(ctx context.Context, postID uuid.UUID) (*models.Post, error) {
+	post, ok := r.posts[postID]
+	if !ok {
+		return nil, models.ErrPostNotFound
+	}
+	copied := *post
+	return &copied, nil
+}
+
+// UpdatePost applies the pin flag, the only update the tests make
+func (r *fakePostRepo) UpdatePost(ctx context.Context, postID uuid.UUID, updates map[string]interface{}) (*models.Post, error) {
+	post, ok := r.posts[postID]
+	if !ok {
+		return nil, models.ErrPostNotFound
+	}
+	if pinned, ok := updates["is_pinned"].(bool); ok {
+		post.IsPinned = pinned
+	}
+	copied := *post
+	return &copied, nil
+}
+
+func (r *fakePostRepo) SaveMediaVariants(ctx context.Context, userID uuid.UUID, mediaURL string, variants map[string]string) error {
+	if r.variants == nil {
+		r.variants = map[uuid.UUID]map[string]map[string]string{}
+	}
+	if r.variants[userID] == nil {
+		r.variants[userID] = map[string]map[string]string{}
+	}
+	r.variants[userID][mediaURL] = variants
+	return nil
+}
+
+func (r *fakePostRepo) GetMediaVariants(ctx context.Context, userID uuid.UUID, mediaURLs []string) (map[string]map[string]string, error) {
+	r.variantLookups++
+	found := map[string]map[string]string{}
+	for _, url := range mediaURLs {
+		if variants, ok := r.variants[userID][url]; ok {
+			found[url] = variants
+		}
+	}
+	return found, nil
+}
+
+func (r *fakePostRepo) CountPinnedPosts(ctx context.Context, userID uuid.UUID) (int, error) {
+	count := 0
+	for _, post := range r.posts {
+		if post.UserID == userID && post.IsPinned {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakePostRepo) ExtractAndCreateHashtags(ctx context.Context, postID uuid.UUID, content string) error {
+	return nil
+}
+
+func (r *fakePostRepo) ExtractAndCreateMentions(ctx context.Context, postID uuid.UUID, content string) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (r *fakePostRepo) ResolveMentions(ctx context.Context, content string) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (r *fakePostRepo) PublishDueScheduledPosts(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	var published, waiting []models.Post
+	for _, post := range r.due {
+		if len(published) < limit && !post.ScheduledAt.After(now) {
+			post.IsPublished = true
+			published = append(published, post)
+		} else {
+			waiting = append(waiting, post)
+		}
+	}
+	r.due = waiting
+	return published, nil
+}
+
+func (r *fakePostRepo) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int, hideInteracted, withCount bool) ([]models.Post, models.Page, error) {
+	r.hideInteracted = hideInteracted
+	end := min(offset+limit, len(r.feed))
+	if offset >= end {
+		return []models.Post{}, models.Page{}, nil
+	}
+	return r.feed[offset:end], models.Page{HasMore: end < len(r.feed)}, nil
+}
+
+func (r *fakePostRepo) GetExploreFeed(ctx context.Context, userID uuid.UUID, limit, offset int, filter string, hideInteracted, withCount bool) ([]models.Post, models.Page, error) {
+	return r.GetHomeFeed(ctx, userID, limit, offset, hideInteracted, withCount)
+}
+
+func (r *fakePostRepo) GetHashtagFeed(ctx context.Context, hashtag string, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
+	return r.GetHomeFeed(ctx, uuid.Nil, limit, offset, false, withCount)
+}
+
+func (r *fakePostRepo) SearchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
+	return r.GetHomeFeed(ctx, userID, limit, offset, false, withCount)
+}
+
+func (r *fakePostRepo) RecordFeedRanking(ctx context.Context, viewerID uuid.UUID, feed, variant string, postIDs []uuid.UUID) error {
+	return nil
+}
+
+// fakeCommentRepo keeps comments in a map
+type fakeCommentRepo struct {
+	repository.CommentRepository
+
+	comments map[uuid.UUID]*models.Comment
+}
+
+func (r *fakeCommentRepo) GetComment(ctx context.Context, commentID uuid.UUID) (*models.Comment, error) {
+	comment, ok := r.comments[commentID]
+	if !ok {
+		return nil, models.ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+func (r *fakeCommentRepo) CreateComment(ctx context.Context, comment *models.Comment) error {
+	comment.ID = uuid.New()
+	r.comments[comment.ID] = comment
+	return nil
+}
+
+// fakeUserRepo serves users from a map
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	users   map[uuid.UUID]*models.User
+	lookups int
+}
+
+func (r *fakeUserRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.lookups++
+	user, ok := r.users[id]
+	if !ok {
+		return nil, models.ErrPostNotFound
+	}
+	return user, nil
+}
+
+// fakePollRepo serves one poll and keeps each user's votes in a map
+type fakePollRepo struct {
+	repository.PollRepository
+
+	poll    *models.Poll
+	votes   map[uuid.UUID][]uuid.UUID // Option IDs per user
+	changes int
+}
+
+func (r *fakePollRepo) GetPoll(ctx context.Context, pollID uuid.UUID) (*models.Poll, error) {
+	return r.poll, nil
+}
+
+func (r *fakePollRepo) GetUserVotes(ctx context.Context, pollID, userID uuid.UUID) ([]uuid.UUID, error) {
+	return r.votes[userID], nil
+}
+
+func (r *fakePollRepo) VotePoll(ctx context.Context, pollID, optionID, userID uuid.UUID) error {
+	r.votes[userID] = append(r.votes[userID], optionID)
+	return nil
+}
+
+func (r *fakePollRepo) ChangeVote(ctx context.Context, pollID, newOptionID, userID uuid.UUID) error {
+	r.changes++
+	r.votes[userID] = []uuid.UUID{newOptionID}
+	return nil
+}
+
+func (r *fakePollRepo) CloseExpiredPolls(ctx context.Context, now time.Time) ([]models.Poll, error) {
+	if r.poll.IsClosed || r.poll.EndsAt.After(now) {
+		return nil, nil
+	}
+	r.poll.IsClosed = true
+	r.poll.ClosedAt = &now
+	return []models.Poll{*r.poll}, nil
+}
+
+func (r *fakePollRepo) GetPollResults(ctx context.Context, pollID, viewerID uuid.UUID) (*models.PollResults, error) {
+	return nil, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"histeeria-backend/internal/cache"
 	"histeeria-backend/internal/models"
@@ -16,7 +18,39 @@ import (
 type FeedService struct {
 	postRepo         repository.PostRepository
 	relationshipRepo repository.RelationshipRepository
+	userRepo         repository.UserRepository
 	feedCache        *cache.FeedCacheService
+	diversity        FeedDiversityRules
+	ranker           *FeedRanker
+}
+
+// FeedDiversityRules limits how much of a feed page a single author can take up.
+// A zero value for a limit disables that rule.
+type FeedDiversityRules struct {
+	MaxConsecutivePerAuthor int // Max posts in a row from the same author
+	MaxPerAuthorPerPage     int // Max posts from the same author on one page
+	CandidateMultiplier     int // Fetch limit*N candidates so there are alternatives to pull in
+}
+
+// DefaultFeedDiversityRules returns the diversity rules used when none are configured
+func DefaultFeedDiversityRules() FeedDiversityRules {
+	return FeedDiversityRules{
+		MaxConsecutivePerAuthor: 2,
+		MaxPerAuthorPerPage:     3,
+		CandidateMultiplier:     2,
+	}
+}
+
+func (r FeedDiversityRules) enabled() bool {
+	return r.MaxConsecutivePerAuthor > 0 || r.MaxPerAuthorPerPage > 0
+}
+
+// candidateLimit returns how many posts to fetch to fill a page of limit posts
+func (r FeedDiversityRules) candidateLimit(limit int) int {
+	if !r.enabled() || r.CandidateMultiplier <= 1 {
+		return limit
+	}
+	return limit * r.CandidateMultiplier
 }
 
 // NewFeedService creates a new feed service
@@ -27,6 +61,8 @@ func NewFeedService(
 	return &FeedService{
 		postRepo:         postRepo,
 		relationshipRepo: relationshipRepo,
+		diversity:        DefaultFeedDiversityRules(),
+		ranker:           NewFeedRanker(DefaultRankingWeights(), nil),
 	}
 }
 
@@ -40,6 +76,8 @@ func NewFeedServiceWithCache(
 		postRepo:         postRepo,
 		relationshipRepo: relationshipRepo,
 		feedCache:        feedCache,
+		diversity:        DefaultFeedDiversityRules(),
+		ranker:           NewFeedRanker(DefaultRankingWeights(), nil),
 	}
 }
 
@@ -48,11 +86,44 @@ func (s *FeedService) SetFeedCache(feedCache *cache.FeedCacheService) {
 	s.feedCache = feedCache
 }
 
-// GetHomeFeed retrieves the home feed for a user with caching
+// SetDiversityRules overrides the per-author feed diversity rules
+func (s *FeedService) SetDiversityRules(rules FeedDiversityRules) {
+	s.diversity = rules
+}
+
+// SetRanker overrides the explore ranking weights and experiment
+func (s *FeedService) SetRanker(ranker *FeedRanker) {
+	s.ranker = ranker
+}
+
+// SetUserRepository sets the user repository used to read viewer feed preferences.
+// Without it every viewer gets the default feed settings.
+func (s *FeedService) SetUserRepository(userRepo repository.UserRepository) {
+	s.userRepo = userRepo
+}
+
+// GetHomeFeed retrieves the home feed for a user. NSFW posts are blurred unless the
+// viewer opted in.
+func (s *FeedService) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, models.Page, error) {
+	prefs := s.viewerFeedPrefs(ctx, userID)
+	posts, page, err := s.loadHomeFeed(ctx, userID, limit, offset, prefs.HideInteracted)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+	posts, page = s.privacy().filter(ctx, posts, page, userID)
+	posts, page = s.gateNSFW(posts, page, userID, prefs, nsfwBlur)
+	posts, page = filterLanguages(posts, page, userID, prefs.Languages)
+	return posts, page, nil
+}
+
+// loadHomeFeed retrieves the home feed for a user with caching. With hideInteracted
+// the query leaves out posts the user has interacted with; those pages go stale as
+// soon as the user likes something, so they're never cached.
 // Algorithm: Chronological feed from following + own posts
-func (s *FeedService) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+func (s *FeedService) loadHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int, hideInteracted bool) ([]models.Post, models.Page, error) {
 	// Only use cache for first page (offset 0) to avoid complexity
-	if s.feedCache != nil && s.feedCache.IsEnabled() && offset == 0 {
+	useCache := s.feedCache != nil && s.feedCache.IsEnabled() && offset == 0 && !hideInteracted
+	if useCache {
 		// Try to get from cache
 		cached, err := s.feedCache.GetCachedHomeFeed(ctx, userID)
 		if err != nil {
@@ -71,7 +142,7 @@ func (s *FeedService) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit,
 					}
 				}
 				log.Printf("[FeedService] Home feed cache HIT for user %s (%d posts)", userID, len(result))
-				return result, cached.Total, nil
+				return result, cached.Page(limit), nil
 			}
 			// Partial cache hit - fall through to database
 			log.Printf("[FeedService] Home feed partial cache hit (%d missing)", len(missingIDs))
@@ -79,17 +150,19 @@ func (s *FeedService) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit,
 	}
 
 	// Cache miss or disabled - get from database
-	posts, total, err := s.postRepo.GetHomeFeed(ctx, userID, limit, offset)
+	// Fetch a wider candidate window so the diversity pass has alternatives
+	posts, page, err := s.postRepo.GetHomeFeed(ctx, userID, s.diversity.candidateLimit(limit), offset, hideInteracted, false)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get home feed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to get home feed: %w", err)
 	}
+	posts, page = diversePage(posts, page, limit, s.diversity)
 
 	// Cache the result for first page
-	if s.feedCache != nil && s.feedCache.IsEnabled() && offset == 0 && len(posts) > 0 {
+	if useCache && len(posts) > 0 {
 		go func() {
 			// Cache in background to not block response
 			bgCtx := context.Background()
-			if err := s.feedCache.WarmUserFeed(bgCtx, userID, posts, total); err != nil {
+			if err := s.feedCache.WarmUserFeed(bgCtx, userID, posts, page); err != nil {
 				log.Printf("[FeedService] Failed to cache home feed: %v", err)
 			} else {
 				log.Printf("[FeedService] Home feed cached for user %s", userID)
@@ -97,19 +170,48 @@ func (s *FeedService) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit,
 		}()
 	}
 
-	return posts, total, nil
+	return posts, page, nil
 }
 
 // GetFollowingFeed retrieves posts only from users the viewer follows
-func (s *FeedService) GetFollowingFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
-	return s.postRepo.GetFollowingFeed(ctx, userID, limit, offset)
+func (s *FeedService) GetFollowingFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, models.Page, error) {
+	posts, page, err := s.postRepo.GetFollowingFeed(ctx, userID, limit, offset, false)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+	posts, page = s.privacy().filter(ctx, posts, page, userID)
+	posts, page = s.gateNSFW(posts, page, userID, s.viewerFeedPrefs(ctx, userID), nsfwBlur)
+	return posts, page, nil
 }
 
-// GetExploreFeed retrieves trending/popular posts for discovery with caching
+// GetExploreFeed retrieves trending/popular posts for discovery. NSFW posts are left
+// out unless the viewer opted in, and the viewer's own posts are never included.
 // filter can be: "posts", "polls", "articles", or "" for all
-func (s *FeedService) GetExploreFeed(ctx context.Context, userID uuid.UUID, limit, offset int, filter string) ([]models.Post, int, error) {
+func (s *FeedService) GetExploreFeed(ctx context.Context, userID uuid.UUID, limit, offset int, filter string) ([]models.Post, models.Page, error) {
+	variant := s.ranker.Variant(userID)
+	prefs := s.viewerFeedPrefs(ctx, userID)
+	posts, page, err := s.loadExploreFeed(ctx, userID, variant, limit, offset, filter, prefs.HideInteracted)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+	posts = s.applyAffinity(ctx, userID, posts, variant.Weights)
+	posts, page = excludeOwnPosts(posts, page, userID)
+	posts, page = s.privacy().filter(ctx, posts, page, userID)
+	posts, page = s.gateNSFW(posts, page, userID, prefs, nsfwExclude)
+	posts, page = filterLanguages(posts, page, userID, prefs.Languages)
+	s.recordRanking(userID, "explore", variant, posts)
+	return posts, page, nil
+}
+
+// loadExploreFeed retrieves trending/popular posts for discovery with caching. The page
+// is ranked without viewer affinity so it can be shared; only the control variant is
+// cached, and never for viewers hiding posts they've interacted with, whose page
+// isn't the shared one.
+func (s *FeedService) loadExploreFeed(ctx context.Context, userID uuid.UUID, variant RankingVariant, limit, offset int, filter string, hideInteracted bool) ([]models.Post, models.Page, error) {
+	useCache := s.feedCache != nil && s.feedCache.IsEnabled() && offset == 0 && variant.Name == ControlVariant && !hideInteracted
+
 	// Only cache first page
-	if s.feedCache != nil && s.feedCache.IsEnabled() && offset == 0 {
+	if useCache {
 		cached, err := s.feedCache.GetCachedExploreFeed(ctx)
 		if err != nil {
 			log.Printf("[FeedService] Cache error for explore feed: %v", err)
@@ -126,19 +228,22 @@ func (s *FeedService) GetExploreFeed(ctx context.Context, userID uuid.UUID, limi
 					}
 				}
 				log.Printf("[FeedService] Explore feed cache HIT (%d posts)", len(result))
-				return result, cached.Total, nil
+				return result, cached.Page(limit), nil
 			}
 		}
 	}
 
 	// Cache miss - get from database
-	posts, total, err := s.postRepo.GetExploreFeed(ctx, userID, limit, offset, filter)
+	rules := diversityFor(s.diversity, variant.Weights)
+	posts, page, err := s.postRepo.GetExploreFeed(ctx, userID, rules.candidateLimit(limit), offset, filter, hideInteracted, false)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get explore feed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to get explore feed: %w", err)
 	}
+	posts = rankPosts(posts, variant.Weights, nil, time.Now())
+	posts, page = diversePage(posts, page, limit, rules)
 
 	// Cache the result for first page (explore feed is shared across users)
-	if s.feedCache != nil && s.feedCache.IsEnabled() && offset == 0 && len(posts) > 0 {
+	if useCache && len(posts) > 0 {
 		go func() {
 			bgCtx := context.Background()
 			
@@ -152,7 +257,7 @@ func (s *FeedService) GetExploreFeed(ctx context.Context, userID uuid.UUID, limi
 			for i, p := range posts {
 				postIDs[i] = p.ID
 			}
-			if err := s.feedCache.CacheExploreFeed(bgCtx, postIDs, total); err != nil {
+			if err := s.feedCache.CacheExploreFeed(bgCtx, postIDs, page); err != nil {
 				log.Printf("[FeedService] Failed to cache explore feed: %v", err)
 			} else {
 				log.Printf("[FeedService] Explore feed cached")
@@ -160,7 +265,7 @@ func (s *FeedService) GetExploreFeed(ctx context.Context, userID uuid.UUID, limi
 		}()
 	}
 
-	return posts, total, nil
+	return posts, page, nil
 }
 
 // GetUserFeed retrieves posts by a specific user
@@ -170,13 +275,36 @@ func (s *FeedService) GetUserFeed(ctx context.Context, username string, viewerID
 	return []models.Post{}, 0, nil
 }
 
-// GetSavedFeed retrieves user's saved/bookmarked posts
+// GetSavedFeed retrieves user's saved/bookmarked posts. An empty collection lists every
+// saved post; a named one must exist.
 func (s *FeedService) GetSavedFeed(ctx context.Context, userID uuid.UUID, collection string, limit, offset int) ([]models.Post, int, error) {
+	if collection != "" {
+		exists, err := s.postRepo.SavedCollectionExists(ctx, userID, collection)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !exists {
+			return nil, 0, models.ErrCollectionNotFound
+		}
+	}
 	return s.postRepo.GetSavedPosts(ctx, userID, collection, limit, offset)
 }
 
-// GetHashtagFeed retrieves posts with a specific hashtag with caching
-func (s *FeedService) GetHashtagFeed(ctx context.Context, hashtag string, viewerID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+// GetHashtagFeed retrieves posts with a specific hashtag. It's a discovery feed, so
+// NSFW posts are left out unless the viewer opted in, as are the viewer's own posts.
+func (s *FeedService) GetHashtagFeed(ctx context.Context, hashtag string, viewerID uuid.UUID, limit, offset int) ([]models.Post, models.Page, error) {
+	posts, page, err := s.loadHashtagFeed(ctx, hashtag, limit, offset)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+	posts, page = excludeOwnPosts(posts, page, viewerID)
+	posts, page = s.privacy().filter(ctx, posts, page, viewerID)
+	posts, page = s.gateNSFW(posts, page, viewerID, s.viewerFeedPrefs(ctx, viewerID), nsfwExclude)
+	return posts, page, nil
+}
+
+// loadHashtagFeed retrieves posts with a specific hashtag with caching
+func (s *FeedService) loadHashtagFeed(ctx context.Context, hashtag string, limit, offset int) ([]models.Post, models.Page, error) {
 	// Only cache first page
 	if s.feedCache != nil && s.feedCache.IsEnabled() && offset == 0 {
 		cached, err := s.feedCache.GetCachedHashtagFeed(ctx, hashtag)
@@ -194,15 +322,15 @@ func (s *FeedService) GetHashtagFeed(ctx context.Context, hashtag string, viewer
 					}
 				}
 				log.Printf("[FeedService] Hashtag #%s feed cache HIT (%d posts)", hashtag, len(result))
-				return result, cached.Total, nil
+				return result, cached.Page(limit), nil
 			}
 		}
 	}
 
 	// Cache miss - get from database
-	posts, total, err := s.postRepo.GetHashtagFeed(ctx, hashtag, limit, offset)
+	posts, page, err := s.postRepo.GetHashtagFeed(ctx, hashtag, limit, offset, false)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get hashtag feed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to get hashtag feed: %w", err)
 	}
 
 	// Cache the result
@@ -220,30 +348,203 @@ func (s *FeedService) GetHashtagFeed(ctx context.Context, hashtag string, viewer
 			for i, p := range posts {
 				postIDs[i] = p.ID
 			}
-			if err := s.feedCache.CacheHashtagFeed(bgCtx, hashtag, postIDs, total); err != nil {
+			if err := s.feedCache.CacheHashtagFeed(bgCtx, hashtag, postIDs, page); err != nil {
 				log.Printf("[FeedService] Failed to cache hashtag feed: %v", err)
 			}
 		}()
 	}
 
-	return posts, total, nil
+	return posts, page, nil
 }
 
-// SearchPosts searches posts by query
-func (s *FeedService) SearchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
-	return s.postRepo.SearchPosts(ctx, query, userID, limit, offset)
+// nsfwMode is what a feed does with NSFW posts for viewers who haven't opted in
+type nsfwMode int
+
+const (
+	nsfwBlur    nsfwMode = iota // Keep them, flagged with Blur
+	nsfwExclude                 // Drop them
+)
+
+// feedPrefs are a viewer's feed settings, in the form they're cached in
+type feedPrefs = cache.ViewerFeedPrefs
+
+// privacy returns the gate that hides private accounts' posts from non-followers.
+// It's a no-op until SetUserRepository is called.
+func (s *FeedService) privacy() privacyGate {
+	return privacyGate{users: s.userRepo, relationships: s.relationshipRepo}
+}
+
+// viewerFeedPrefs loads the viewer's feed settings, from the feed cache when they're
+// in it. Lookup failures fall back to the defaults, which aren't cached.
+func (s *FeedService) viewerFeedPrefs(ctx context.Context, viewerID uuid.UUID) feedPrefs {
+	if viewerID == uuid.Nil || s.userRepo == nil {
+		return feedPrefs{}
+	}
+	useCache := s.feedCache != nil && s.feedCache.IsEnabled()
+	if useCache {
+		cached, err := s.feedCache.GetCachedViewerPrefs(ctx, viewerID)
+		if err != nil {
+			log.Printf("[FeedService] Cache error for feed prefs: %v", err)
+		} else if cached != nil {
+			return *cached
+		}
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, viewerID)
+	if err != nil || user == nil {
+		return feedPrefs{}
+	}
+	prefs := feedPrefs{
+		ShowNSFW:       user.ShowNSFW,
+		HideInteracted: user.HideInteracted,
+		Languages:      user.FeedLanguages,
+	}
+	if useCache {
+		if err := s.feedCache.CacheViewerPrefs(ctx, viewerID, prefs); err != nil {
+			log.Printf("[FeedService] Failed to cache feed prefs for %s: %v", viewerID, err)
+		}
+	}
+	return prefs
+}
+
+// gateNSFW applies the viewer's NSFW preference to a feed page. Opted-in viewers get
+// the page unchanged; anonymous viewers never get NSFW posts; other signed-in viewers
+// get them blurred or dropped per mode. A viewer's own posts are never gated.
+// Returns a new slice, so cached posts are left untouched.
+func (s *FeedService) gateNSFW(posts []models.Post, page models.Page, viewerID uuid.UUID, prefs feedPrefs, mode nsfwMode) ([]models.Post, models.Page) {
+	if prefs.ShowNSFW {
+		return posts, page
+	}
+	if viewerID == uuid.Nil {
+		mode = nsfwExclude
+	}
+
+	gated := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.IsNSFW && post.UserID != viewerID {
+			if mode == nsfwExclude {
+				continue
+			}
+			post.Blur = true
+		}
+		gated = append(gated, post)
+	}
+
+	return gated, page.Filtered(len(posts)-len(gated), len(gated))
+}
+
+// maxAffinityFollows caps how many followed accounts are loaded to score affinity
+const maxAffinityFollows = 500
+
+// applyAffinity re-ranks a page so posts by authors the viewer follows get the
+// variant's affinity boost. The diversity pass is re-run to break up any same-author
+// runs this creates; the page is already within the per-author cap, so nothing is dropped.
+func (s *FeedService) applyAffinity(ctx context.Context, viewerID uuid.UUID, posts []models.Post, weights RankingWeights) []models.Post {
+	if weights.AffinityWeight == 0 || viewerID == uuid.Nil || s.relationshipRepo == nil || len(posts) < 2 {
+		return posts
+	}
+
+	follows, _, err := s.relationshipRepo.GetRelationshipList(ctx, viewerID, "following", 1, maxAffinityFollows)
+	if err != nil {
+		log.Printf("[FeedService] Failed to load follows for ranking %s: %v", viewerID, err)
+		return posts
+	}
+	if len(follows) == 0 {
+		return posts
+	}
+	following := make(map[uuid.UUID]bool, len(follows))
+	for _, f := range follows {
+		following[f.UserID] = true
+	}
+
+	ranked := rankPosts(posts, weights, following, time.Now())
+	return applyFeedDiversity(ranked, len(ranked), diversityFor(s.diversity, weights))
+}
+
+// recordRanking stores which variant ranked the page a viewer was served, in the
+// background so it never slows the feed down
+func (s *FeedService) recordRanking(viewerID uuid.UUID, feed string, variant RankingVariant, posts []models.Post) {
+	if viewerID == uuid.Nil || len(posts) == 0 {
+		return
+	}
+	postIDs := make([]uuid.UUID, len(posts))
+	for i := range posts {
+		postIDs[i] = posts[i].ID
+	}
+	go func() {
+		if err := s.postRepo.RecordFeedRanking(context.Background(), viewerID, feed, variant.Name, postIDs); err != nil {
+			log.Printf("[FeedService] Failed to record %s ranking for %s: %v", feed, viewerID, err)
+		}
+	}()
+}
+
+// excludeOwnPosts drops the viewer's own posts from a discovery feed. These feeds are
+// cached for everyone, so this runs per viewer after loading.
+func excludeOwnPosts(posts []models.Post, page models.Page, viewerID uuid.UUID) ([]models.Post, models.Page) {
+	if viewerID == uuid.Nil {
+		return posts, page
+	}
+
+	others := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.UserID == viewerID {
+			continue
+		}
+		others = append(others, post)
+	}
+
+	return others, page.Filtered(len(posts)-len(others), len(others))
+}
+
+// filterLanguages keeps posts in the viewer's selected languages. Posts whose language
+// could not be detected and the viewer's own posts are always kept.
+func filterLanguages(posts []models.Post, page models.Page, viewerID uuid.UUID, languages []string) ([]models.Post, models.Page) {
+	if len(languages) == 0 {
+		return posts, page
+	}
+
+	allowed := make(map[string]bool, len(languages))
+	for _, lang := range languages {
+		allowed[strings.ToLower(lang)] = true
+	}
+
+	kept := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.Language != "" && !allowed[post.Language] && post.UserID != viewerID {
+			continue
+		}
+		kept = append(kept, post)
+	}
+
+	return kept, page.Filtered(len(posts)-len(kept), len(kept))
+}
+
+// SearchPosts searches posts by query. Posts the viewer can't see are left out, and
+// NSFW posts are unless the viewer opted in. withCount adds an exact total to the
+// page, counted before filtering.
+func (s *FeedService) SearchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
+	posts, page, err := s.postRepo.SearchPosts(ctx, query, userID, limit, offset, withCount)
+	if err != nil {
+		return nil, models.Page{}, err
+	}
+	posts, page = s.privacy().filter(ctx, posts, page, userID)
+	posts, page = s.gateNSFW(posts, page, userID, s.viewerFeedPrefs(ctx, userID), nsfwExclude)
+	return posts, page, nil
 }
 
 // ============================================
 // CACHE INVALIDATION METHODS
 // ============================================
 
-// InvalidateUserFeed invalidates a user's home feed cache
+// InvalidateUserFeed invalidates a user's home feed cache and cached feed settings
 // Call when user follows/unfollows someone or changes preferences
 func (s *FeedService) InvalidateUserFeed(ctx context.Context, userID uuid.UUID) error {
 	if s.feedCache == nil || !s.feedCache.IsEnabled() {
 		return nil
 	}
+	if err := s.feedCache.InvalidateViewerPrefs(ctx, userID); err != nil {
+		return err
+	}
 	return s.feedCache.InvalidateHomeFeed(ctx, userID)
 }
 
@@ -312,10 +613,14 @@ func (s *FeedService) WarmExploreFeed(ctx context.Context) error {
 
 	// Get explore feed from database (use a system user ID or uuid.Nil)
 	// No filter for cache warming - cache all types
-	posts, total, err := s.postRepo.GetExploreFeed(ctx, uuid.Nil, 50, 0, "")
+	variant := s.ranker.Variant(uuid.Nil)
+	rules := diversityFor(s.diversity, variant.Weights)
+	posts, page, err := s.postRepo.GetExploreFeed(ctx, uuid.Nil, rules.candidateLimit(50), 0, "", false, false)
 	if err != nil {
 		return fmt.Errorf("failed to get explore feed for warming: %w", err)
 	}
+	posts = rankPosts(posts, variant.Weights, nil, time.Now())
+	posts, page = diversePage(posts, page, 50, rules)
 
 	// Cache individual posts
 	for i := range posts {
@@ -328,7 +633,7 @@ func (s *FeedService) WarmExploreFeed(ctx context.Context) error {
 		postIDs[i] = p.ID
 	}
 
-	return s.feedCache.CacheExploreFeed(ctx, postIDs, total)
+	return s.feedCache.CacheExploreFeed(ctx, postIDs, page)
 }
 
 // GetCacheStats returns cache statistics
@@ -341,10 +646,80 @@ func (s *FeedService) GetCacheStats(ctx context.Context) map[string]interface{}
 	return s.feedCache.GetCacheStats(ctx)
 }
 
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// diversePage applies the diversity rules to a candidate window. Candidates left over
+// past the page mean another page follows, even if the window was the last of the rows.
+func diversePage(candidates []models.Post, page models.Page, limit int, rules FeedDiversityRules) ([]models.Post, models.Page) {
+	if len(candidates) > limit {
+		page.HasMore = true
+	}
+	return applyFeedDiversity(candidates, limit, rules), page
+}
+
+// applyFeedDiversity picks up to limit posts from candidates (in ranked order) so that
+// no author exceeds MaxPerAuthorPerPage on the page, and no author appears more than
+// MaxConsecutivePerAuthor times in a row unless no other author is left to interleave.
+// A post over the cap is replaced by the next-best post from a different author.
+// Since the candidate window is wider than the page, a post pulled in here may be
+// picked again on the next page - clients already dedupe feed items by ID.
+func applyFeedDiversity(candidates []models.Post, limit int, rules FeedDiversityRules) []models.Post {
+	if !rules.enabled() || len(candidates) == 0 {
+		if len(candidates) > limit {
+			return candidates[:limit]
+		}
+		return candidates
+	}
+
+	result := make([]models.Post, 0, min(limit, len(candidates)))
+	used := make([]bool, len(candidates))
+	perAuthor := make(map[uuid.UUID]int)
+	var lastAuthor uuid.UUID
+	run := 0
+
+	underPageCap := func(author uuid.UUID) bool {
+		return rules.MaxPerAuthorPerPage <= 0 || perAuthor[author] < rules.MaxPerAuthorPerPage
 	}
-	return b
+	breaksRun := func(author uuid.UUID) bool {
+		return rules.MaxConsecutivePerAuthor > 0 && author == lastAuthor && run >= rules.MaxConsecutivePerAuthor
+	}
+
+	for len(result) < limit {
+		pick := -1
+		fallback := -1 // Best post that only breaks the consecutive rule
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			author := candidates[i].UserID
+			if !underPageCap(author) {
+				continue
+			}
+			if breaksRun(author) {
+				if fallback < 0 {
+					fallback = i
+				}
+				continue
+			}
+			pick = i
+			break
+		}
+		if pick < 0 {
+			pick = fallback
+		}
+		if pick < 0 {
+			break // Every remaining candidate's author is at the page cap
+		}
+
+		used[pick] = true
+		author := candidates[pick].UserID
+		perAuthor[author]++
+		if author == lastAuthor {
+			run++
+		} else {
+			lastAuthor = author
+			run = 1
+		}
+		result = append(result, candidates[pick])
+	}
+
+	return result
 }
@@ -0,0 +1,319 @@
+package posts
+
+import (
+	"context"
+	"testing"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// postsBy returns one post per author, in order
+func postsBy(authors ...uuid.UUID) []models.Post {
+	posts := make([]models.Post, len(authors))
+	for i, author := range authors {
+		posts[i] = models.Post{ID: uuid.New(), UserID: author}
+	}
+	return posts
+}
+
+func TestApplyFeedDiversityBreaksRunsWithoutDropping(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	page := postsBy(a, a, a, a, b, b)
+
+	got := applyFeedDiversity(page, len(page), FeedDiversityRules{MaxConsecutivePerAuthor: 2})
+
+	assertSamePosts(t, page, got)
+	wantAuthors := []uuid.UUID{a, a, b, a, a, b}
+	for i, post := range got {
+		if post.UserID != wantAuthors[i] {
+			t.Fatalf("position %d is by the wrong author; got order %v", i, authorsOf(got, a, b))
+		}
+	}
+}
+
+func TestApplyFeedDiversityFillsOverCapSlotsFromTheCandidates(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	candidates := postsBy(a, a, a, a, b, c, d)
+
+	got := applyFeedDiversity(candidates, 4, FeedDiversityRules{MaxPerAuthorPerPage: 2})
+
+	// The author's third and fourth posts give way to the next-best by others
+	assertSamePosts(t, []models.Post{candidates[0], candidates[1], candidates[4], candidates[5]}, got)
+}
+
+func TestApplyFeedDiversityLeavesThePageShortRatherThanBreakTheCap(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	candidates := postsBy(a, a, a, a, b)
+
+	got := applyFeedDiversity(candidates, 4, FeedDiversityRules{MaxPerAuthorPerPage: 2})
+
+	assertSamePosts(t, []models.Post{candidates[0], candidates[1], candidates[4]}, got)
+}
+
+func TestApplyFeedDiversityKeepsRunsWhenNoOneElseIsLeft(t *testing.T) {
+	a := uuid.New()
+	page := postsBy(a, a, a, a)
+
+	got := applyFeedDiversity(page, len(page), FeedDiversityRules{MaxConsecutivePerAuthor: 2})
+	for i := range page {
+		if got[i].ID != page[i].ID {
+			t.Fatalf("a single-author page should keep its order")
+		}
+	}
+}
+
+func TestHomeFeedPagesKeepEachAuthorUnderTheCap(t *testing.T) {
+	prolific, others := uuid.New(), []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	var authors []uuid.UUID
+	for i := 0; i < 30; i++ {
+		// Mostly one author, as in a feed where one account posts in bursts
+		if i%3 == 2 {
+			authors = append(authors, others[i%len(others)])
+		} else {
+			authors = append(authors, prolific)
+		}
+	}
+	repo := &fakePostRepo{feed: postsBy(authors...)}
+	svc := NewFeedService(repo, nil)
+	rules := DefaultFeedDiversityRules()
+
+	for offset := 0; ; offset += 5 {
+		page, info, err := svc.loadHomeFeed(context.Background(), uuid.New(), 5, offset, false)
+		if err != nil {
+			t.Fatalf("loadHomeFeed: %v", err)
+		}
+		perAuthor := make(map[uuid.UUID]int)
+		for _, post := range page {
+			perAuthor[post.UserID]++
+		}
+		for author, n := range perAuthor {
+			if n > rules.MaxPerAuthorPerPage {
+				t.Errorf("page at %d has %d posts by %s, want at most %d", offset, n, author, rules.MaxPerAuthorPerPage)
+			}
+		}
+		if info.HasMore && len(page) != 5 {
+			t.Errorf("page at %d has %d posts, want the over-cap slots filled from the candidates", offset, len(page))
+		}
+		if !info.HasMore {
+			break
+		}
+	}
+}
+
+func TestViewerFeedPrefsAreCachedUntilInvalidated(t *testing.T) {
+	viewer := &models.User{ID: uuid.New(), HideInteracted: true, FeedLanguages: []string{"en"}}
+	users := &fakeUserRepo{users: map[uuid.UUID]*models.User{viewer.ID: viewer}}
+	svc := NewFeedServiceWithCache(&fakePostRepo{}, nil, cache.NewFeedCacheService(cache.NewMemoryProvider()))
+	svc.SetUserRepository(users)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		prefs := svc.viewerFeedPrefs(ctx, viewer.ID)
+		if !prefs.HideInteracted || len(prefs.Languages) != 1 || prefs.Languages[0] != "en" {
+			t.Fatalf("prefs = %+v, want the viewer's settings", prefs)
+		}
+	}
+	if users.lookups != 1 {
+		t.Errorf("looked the viewer up %d times, want once", users.lookups)
+	}
+
+	viewer.HideInteracted = false
+	if err := svc.InvalidateUserFeed(ctx, viewer.ID); err != nil {
+		t.Fatalf("InvalidateUserFeed: %v", err)
+	}
+	if prefs := svc.viewerFeedPrefs(ctx, viewer.ID); prefs.HideInteracted {
+		t.Error("changed settings should be read again after invalidation")
+	}
+	if users.lookups != 2 {
+		t.Errorf("looked the viewer up %d times, want twice", users.lookups)
+	}
+}
+
+func TestHomeFeedHidingInteractedSkipsTheCache(t *testing.T) {
+	viewer := uuid.New()
+	repo := &fakePostRepo{feed: postsBy(uuid.New(), uuid.New())}
+	feedCache := cache.NewFeedCacheService(cache.NewMemoryProvider())
+	svc := NewFeedServiceWithCache(repo, nil, feedCache)
+	ctx := context.Background()
+
+	if _, _, err := svc.loadHomeFeed(ctx, viewer, 5, 0, true); err != nil {
+		t.Fatalf("loadHomeFeed: %v", err)
+	}
+	if !repo.hideInteracted {
+		t.Error("the feed query should be asked to leave interacted posts out")
+	}
+	if cached, _ := feedCache.GetCachedHomeFeed(ctx, viewer); cached != nil {
+		t.Error("a page without interacted posts shouldn't be cached")
+	}
+
+	if _, _, err := svc.loadHomeFeed(ctx, viewer, 5, 0, false); err != nil {
+		t.Fatalf("loadHomeFeed: %v", err)
+	}
+	if repo.hideInteracted {
+		t.Error("the feed query shouldn't hide anything by default")
+	}
+}
+
+func TestHomeFeedNSFWGating(t *testing.T) {
+	author := uuid.New()
+	optedIn := &models.User{ID: uuid.New(), ShowNSFW: true}
+	optedOut := &models.User{ID: uuid.New()}
+	users := &fakeUserRepo{users: map[uuid.UUID]*models.User{optedIn.ID: optedIn, optedOut.ID: optedOut}}
+
+	tests := []struct {
+		name      string
+		viewer    uuid.UUID
+		wantPosts int
+		wantBlur  bool
+	}{
+		{"opted in", optedIn.ID, 2, false},
+		{"opted out", optedOut.ID, 2, true},
+		{"author", author, 2, false},
+		{"anonymous", uuid.Nil, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := postsBy(author, author)
+			feed[1].IsNSFW = true
+			svc := NewFeedService(&fakePostRepo{feed: feed}, nil)
+			svc.SetUserRepository(users)
+
+			posts, _, err := svc.GetHomeFeed(context.Background(), tt.viewer, 10, 0)
+			if err != nil {
+				t.Fatalf("GetHomeFeed: %v", err)
+			}
+			if len(posts) != tt.wantPosts {
+				t.Fatalf("got %d posts, want %d", len(posts), tt.wantPosts)
+			}
+			for _, post := range posts {
+				if post.Blur != (tt.wantBlur && post.IsNSFW) {
+					t.Errorf("post (nsfw %v) blur = %v", post.IsNSFW, post.Blur)
+				}
+			}
+			if feed[1].Blur {
+				t.Error("gating should not flag the posts it was given")
+			}
+		})
+	}
+}
+
+func TestGateNSFWExcludesForOptedOutViewers(t *testing.T) {
+	viewer, author := uuid.New(), uuid.New()
+	posts := postsBy(author, author, viewer)
+	posts[0].IsNSFW = true
+	posts[2].IsNSFW = true // The viewer's own post is never gated
+	svc := NewFeedService(&fakePostRepo{}, nil)
+
+	got, page := svc.gateNSFW(posts, models.Page{Total: 3}, viewer, feedPrefs{}, nsfwExclude)
+	if len(got) != 2 || got[0].ID != posts[1].ID || got[1].ID != posts[2].ID {
+		t.Fatalf("opted out: got %d posts, want the safe post and the viewer's own", len(got))
+	}
+	if page.Total != 2 {
+		t.Errorf("page total = %d, want 2", page.Total)
+	}
+
+	if got, _ := svc.gateNSFW(posts, models.Page{Total: 3}, viewer, feedPrefs{ShowNSFW: true}, nsfwExclude); len(got) != 3 {
+		t.Errorf("opted in: got %d posts, want all 3", len(got))
+	}
+}
+
+func TestHomeFeedFiltersNonPreferredLanguages(t *testing.T) {
+	author, other := uuid.New(), uuid.New()
+	viewer := &models.User{ID: uuid.New(), FeedLanguages: []string{"EN", "fr"}}
+	feed := postsBy(author, other, author, other, viewer.ID)
+	for i, language := range []string{"en", "es", "fr", "", "es"} {
+		feed[i].Language = language
+	}
+	svc := NewFeedService(&fakePostRepo{feed: feed}, nil)
+	svc.SetUserRepository(&fakeUserRepo{users: map[uuid.UUID]*models.User{viewer.ID: viewer}})
+
+	posts, _, err := svc.GetHomeFeed(context.Background(), viewer.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetHomeFeed: %v", err)
+	}
+	// The Spanish post goes; undetected posts and the viewer's own stay
+	assertSamePosts(t, []models.Post{feed[0], feed[2], feed[3], feed[4]}, posts)
+
+	// Without a setting every language is shown
+	viewer.FeedLanguages = nil
+	svc.SetUserRepository(&fakeUserRepo{users: map[uuid.UUID]*models.User{viewer.ID: viewer}})
+	if posts, _, _ := svc.GetHomeFeed(context.Background(), viewer.ID, 10, 0); len(posts) != len(feed) {
+		t.Errorf("got %d posts without a language setting, want %d", len(posts), len(feed))
+	}
+}
+
+func TestDiscoveryFeedsLeaveOutTheViewersOwnPosts(t *testing.T) {
+	viewer, stranger, other := uuid.New(), uuid.New(), uuid.New()
+	feed := postsBy(viewer, other)
+
+	feeds := []struct {
+		name     string
+		load     func(svc *FeedService, viewerID uuid.UUID) ([]models.Post, models.Page, error)
+		keepsOwn bool
+	}{
+		{"explore", func(svc *FeedService, viewerID uuid.UUID) ([]models.Post, models.Page, error) {
+			return svc.GetExploreFeed(context.Background(), viewerID, 10, 0, "")
+		}, false},
+		{"hashtag", func(svc *FeedService, viewerID uuid.UUID) ([]models.Post, models.Page, error) {
+			return svc.GetHashtagFeed(context.Background(), "go", viewerID, 10, 0)
+		}, false},
+		{"search", func(svc *FeedService, viewerID uuid.UUID) ([]models.Post, models.Page, error) {
+			return svc.SearchPosts(context.Background(), "go", viewerID, 10, 0, false)
+		}, true},
+	}
+
+	for _, tt := range feeds {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewFeedService(&fakePostRepo{feed: feed}, nil)
+
+			own, _, err := tt.load(svc, viewer)
+			if err != nil {
+				t.Fatalf("load for the author: %v", err)
+			}
+			if tt.keepsOwn {
+				assertSamePosts(t, feed, own)
+			} else {
+				assertSamePosts(t, feed[1:], own)
+			}
+
+			seen, _, err := tt.load(svc, stranger)
+			if err != nil {
+				t.Fatalf("load for a stranger: %v", err)
+			}
+			assertSamePosts(t, feed, seen)
+		})
+	}
+}
+
+func assertSamePosts(t *testing.T, want, got []models.Post) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d posts, want %d", len(got), len(want))
+	}
+	ids := make(map[uuid.UUID]bool, len(got))
+	for _, post := range got {
+		ids[post.ID] = true
+	}
+	for _, post := range want {
+		if !ids[post.ID] {
+			t.Fatalf("post %s was dropped", post.ID)
+		}
+	}
+}
+
+// authorsOf renders a page's authors as their index in authors, for failure messages
+func authorsOf(posts []models.Post, authors ...uuid.UUID) []int {
+	order := make([]int, len(posts))
+	for i, post := range posts {
+		for j, author := range authors {
+			if post.UserID == author {
+				order[i] = j
+			}
+		}
+	}
+	return order
+}
@@ -1,7 +1,6 @@
 package posts
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"log"
@@ -14,7 +13,9 @@ import (
 
 	"histeeria-backend/internal/messaging"
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/storage"
 	"histeeria-backend/internal/utils"
+	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -26,6 +27,7 @@ type Handlers struct {
 	feedService    *FeedService
 	storageService *utils.StorageService
 	mediaOptimizer *messaging.MediaOptimizer
+	objectStorage  *storage.StorageService // Provider-based storage used for presigned direct uploads
 }
 
 // NewHandlers creates new post handlers
@@ -38,6 +40,11 @@ func NewHandlers(service *Service, feedService *FeedService, storageService *uti
 	}
 }
 
+// SetObjectStorage sets the provider-based storage service used for presigned uploads
+func (h *Handlers) SetObjectStorage(objectStorage *storage.StorageService) {
+	h.objectStorage = objectStorage
+}
+
 // sanitizeFilename removes non-ASCII characters, spaces, and special characters from filename
 func sanitizeFilename(filename string) string {
 	ext := filepath.Ext(filename)
@@ -65,26 +72,32 @@ func sanitizeFilename(filename string) string {
 
 // CreatePost handles POST /api/v1/posts
 func (h *Handlers) CreatePost(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreatePostRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	post, err := h.service.CreatePost(c.Request.Context(), &req, uid)
 	if err != nil {
+		if err == models.ErrNewAccountLinks {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrNewAccountLinks.Code})
+			return
+		}
+		if validationErr, ok := err.(*models.AppError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message, "code": validationErr.Code})
+			return
+		}
+		if errors.IsAppError(err) {
+			appErr := errors.GetAppError(err)
+			c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -106,9 +119,7 @@ func (h *Handlers) GetPost(c *gin.Context) {
 
 	// Get viewer ID (optional)
 	var viewerID uuid.UUID
-	if userID, exists := c.Get("user_id"); exists {
-		viewerID, _ = uuid.Parse(userID.(string))
-	}
+	viewerID, _ = utils.CurrentUserID(c)
 
 	post, err := h.service.GetPost(c.Request.Context(), postID, viewerID)
 	if err != nil {
@@ -308,9 +319,7 @@ func (h *Handlers) GetArticleBySlug(c *gin.Context) {
 
 	// Get viewer ID (optional - for engagement state like is_liked, is_saved)
 	var viewerID uuid.UUID
-	if userID, exists := c.Get("user_id"); exists {
-		viewerID, _ = uuid.Parse(userID.(string))
-	}
+	viewerID, _ = utils.CurrentUserID(c)
 
 	post, err := h.service.GetArticleBySlug(c.Request.Context(), slug, viewerID)
 	if err != nil {
@@ -337,21 +346,27 @@ func (h *Handlers) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req models.UpdatePostRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
-	if err := h.service.UpdatePost(c.Request.Context(), postID, uid, &req); err != nil {
+	post, err := h.service.UpdatePost(c.Request.Context(), postID, uid, &req)
+	if err != nil {
+		if err == models.ErrTooManyPinnedPosts {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": models.ErrTooManyPinnedPosts.Code})
+			return
+		}
+		if err == models.ErrNewAccountLinks {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrNewAccountLinks.Code})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -359,6 +374,56 @@ func (h *Handlers) UpdatePost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Post updated successfully",
+		"post":    post,
+	})
+}
+
+// SaveDraft handles PATCH /api/v1/posts/:id/draft
+func (h *Handlers) SaveDraft(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.SaveDraftRequest
+	if !utils.BindJSON(c, &req) {
+		return
+	}
+
+	version, err := h.service.SaveDraft(c.Request.Context(), postID, uid, &req)
+	if err != nil {
+		if conflict, ok := err.(*models.DraftConflictError); ok {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "Draft was saved from another session",
+				"code":          "DRAFT_VERSION_CONFLICT",
+				"draft_version": conflict.CurrentVersion,
+			})
+			return
+		}
+		switch err {
+		case models.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case models.ErrUnauthorized:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case models.ErrPostNotDraft:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": models.ErrPostNotDraft.Code})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"draft_version": version,
+		"message":       "Draft saved",
 	})
 }
 
@@ -370,14 +435,12 @@ func (h *Handlers) DeletePost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.DeletePost(c.Request.Context(), postID, uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -397,14 +460,12 @@ func (h *Handlers) RestrictPost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.RestrictPost(c.Request.Context(), postID, uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -424,14 +485,12 @@ func (h *Handlers) UnrestrictPost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.UnrestrictPost(c.Request.Context(), postID, uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -456,31 +515,26 @@ func (h *Handlers) GetUserPosts(c *gin.Context) {
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
 	// Get viewer ID (optional)
-	var viewerID uuid.UUID
-	if val, exists := c.Get("user_id"); exists {
-		viewerID, _ = uuid.Parse(val.(string))
+	viewerID, authenticated := utils.CurrentUserID(c)
+	if authenticated {
 		fmt.Printf("[GetUserPosts] Viewer ID: %s\n", viewerID)
 	} else {
 		fmt.Println("[GetUserPosts] No viewer ID (public request)")
 	}
 
-	posts, total, err := h.service.GetUserPostsByUsername(c.Request.Context(), username, limit, offset, viewerID)
+	// The total is only counted when asked for; the profile grid just needs has_more
+	withCount := c.Query("count") == "true"
+
+	posts, page, err := h.service.GetUserPostsByUsername(c.Request.Context(), username, limit, offset, viewerID, withCount)
 	if err != nil {
 		fmt.Printf("[GetUserPosts] Error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("[GetUserPosts] Returning %d posts (total: %d) for %s\n", len(posts), total, username)
+	fmt.Printf("[GetUserPosts] Returning %d posts (total: %d) for %s\n", len(posts), page.Total, username)
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: offset+limit < total,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, page, offset, limit))
 }
 
 // ============================================
@@ -495,22 +549,26 @@ func (h *Handlers) LikePost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
-	if err := h.service.LikePost(c.Request.Context(), postID, uid); err != nil {
+	likesCount, err := h.service.LikePost(c.Request.Context(), postID, uid)
+	if err != nil {
+		if err == models.ErrPostNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Post liked",
+		"success":     true,
+		"message":     "Post liked",
+		"likes_count": likesCount,
 	})
 }
 
@@ -522,22 +580,27 @@ func (h *Handlers) UnlikePost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
-	if err := h.service.UnlikePost(c.Request.Context(), postID, uid); err != nil {
+	likesCount, changed, err := h.service.UnlikePost(c.Request.Context(), postID, uid)
+	if err != nil {
+		if err == models.ErrPostNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Post unliked",
+		"success":     true,
+		"message":     "Post unliked",
+		"likes_count": likesCount,
+		"changed":     changed,
 	})
 }
 
@@ -549,27 +612,64 @@ func (h *Handlers) SharePost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req struct {
 		Comment string `json:"comment"`
 	}
 	c.ShouldBindJSON(&req)
 
-	if err := h.service.SharePost(c.Request.Context(), postID, uid, req.Comment); err != nil {
+	sharesCount, changed, err := h.service.SharePost(c.Request.Context(), postID, uid, req.Comment)
+	if err != nil {
+		if err == models.ErrPostNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Post shared",
+		"success":      true,
+		"message":      "Post shared",
+		"shares_count": sharesCount,
+		"changed":      changed,
+	})
+}
+
+// UnsharePost handles DELETE /api/v1/posts/:id/share
+func (h *Handlers) UnsharePost(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sharesCount, changed, err := h.service.UnsharePost(c.Request.Context(), postID, uid)
+	if err != nil {
+		if err == models.ErrPostNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"message":      "Post unshared",
+		"shares_count": sharesCount,
+		"changed":      changed,
 	})
 }
 
@@ -581,25 +681,19 @@ func (h *Handlers) SavePost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req struct {
 		Collection string `json:"collection"`
 	}
 	c.ShouldBindJSON(&req)
 
-	if req.Collection == "" {
-		req.Collection = "Saved"
-	}
-
 	if err := h.service.SavePost(c.Request.Context(), postID, uid, req.Collection); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCollectionError(c, err)
 		return
 	}
 
@@ -617,15 +711,14 @@ func (h *Handlers) UnsavePost(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
-	if err := h.service.UnsavePost(c.Request.Context(), postID, uid); err != nil {
+	changed, err := h.service.UnsavePost(c.Request.Context(), postID, uid)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -633,6 +726,7 @@ func (h *Handlers) UnsavePost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Post unsaved",
+		"changed": changed,
 	})
 }
 
@@ -648,17 +742,14 @@ func (h *Handlers) CreateComment(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req models.CreateCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -666,7 +757,16 @@ func (h *Handlers) CreateComment(c *gin.Context) {
 
 	comment, err := h.service.CreateComment(c.Request.Context(), &req, uid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch err {
+		case models.ErrCommentNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+		case models.ErrParentCommentPost, models.ErrCommentTooDeep:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": err.(*models.AppError).Code})
+		case models.ErrNewAccountLinks:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrNewAccountLinks.Code})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -677,6 +777,9 @@ func (h *Handlers) CreateComment(c *gin.Context) {
 	})
 }
 
+// maxReplyPreview caps how many replies GetComments inlines per comment
+const maxReplyPreview = 10
+
 // GetComments handles GET /api/v1/posts/:id/comments
 func (h *Handlers) GetComments(c *gin.Context) {
 	postID, err := uuid.Parse(c.Param("id"))
@@ -688,7 +791,16 @@ func (h *Handlers) GetComments(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	comments, total, err := h.service.GetComments(c.Request.Context(), postID, limit, offset)
+	// replies=0 returns top-level comments with just replies_count, for clients
+	// that lazy-load threads through GET /comments/:id/replies
+	previewReplies, _ := strconv.Atoi(c.DefaultQuery("replies", "3"))
+	if previewReplies < 0 {
+		previewReplies = 0
+	} else if previewReplies > maxReplyPreview {
+		previewReplies = maxReplyPreview
+	}
+
+	comments, total, err := h.service.GetComments(c.Request.Context(), postID, limit, offset, previewReplies)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -704,6 +816,37 @@ func (h *Handlers) GetComments(c *gin.Context) {
 	})
 }
 
+// GetCommentReplies handles GET /api/v1/comments/:id/replies
+func (h *Handlers) GetCommentReplies(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	replies, total, err := h.service.GetCommentReplies(c.Request.Context(), commentID, limit, offset)
+	if err != nil {
+		if err == models.ErrCommentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CommentsResponse{
+		Success:  true,
+		Comments: replies,
+		Total:    total,
+		Page:     offset / limit,
+		Limit:    limit,
+		HasMore:  total > offset+limit,
+	})
+}
+
 // UpdateComment handles PUT /api/v1/comments/:id
 func (h *Handlers) UpdateComment(c *gin.Context) {
 	commentID, err := uuid.Parse(c.Param("id"))
@@ -712,22 +855,23 @@ func (h *Handlers) UpdateComment(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req models.UpdateCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	comment, err := h.service.UpdateComment(c.Request.Context(), commentID, req.Content, uid)
 	if err != nil {
+		if err == models.ErrNewAccountLinks {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrNewAccountLinks.Code})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -747,14 +891,12 @@ func (h *Handlers) DeleteComment(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.DeleteComment(c.Request.Context(), commentID, uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -774,14 +916,12 @@ func (h *Handlers) LikeComment(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.LikeComment(c.Request.Context(), commentID, uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -805,17 +945,14 @@ func (h *Handlers) VotePoll(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req models.VotePollRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
@@ -840,6 +977,11 @@ func (h *Handlers) VotePoll(c *gin.Context) {
 	}
 
 	if err := h.service.VotePoll(c.Request.Context(), poll.ID, req.OptionID, uid); err != nil {
+		if errors.IsAppError(err) {
+			appErr := errors.GetAppError(err)
+			c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -864,9 +1006,7 @@ func (h *Handlers) GetPollResults(c *gin.Context) {
 
 	// Get viewer ID (optional)
 	var viewerID uuid.UUID
-	if userID, exists := c.Get("user_id"); exists {
-		viewerID, _ = uuid.Parse(userID.(string))
-	}
+	viewerID, _ = utils.CurrentUserID(c)
 
 	// Get poll by post ID
 	poll, err := h.service.pollRepo.GetPollByPostID(c.Request.Context(), postID)
@@ -887,84 +1027,85 @@ func (h *Handlers) GetPollResults(c *gin.Context) {
 	})
 }
 
+// GetPollResultsBatch handles POST /api/v1/polls/results
+func (h *Handlers) GetPollResultsBatch(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.PollResultsBatchRequest
+	if !utils.BindJSON(c, &req) {
+		return
+	}
+
+	results, err := h.service.GetPollResultsBatch(c.Request.Context(), req.PollIDs, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"results": results,
+	})
+}
+
 // ============================================
 // FEED ENDPOINTS
 // ============================================
 
 // GetHomeFeed handles GET /api/v1/feed/home
 func (h *Handlers) GetHomeFeed(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	posts, total, err := h.feedService.GetHomeFeed(c.Request.Context(), uid, limit, offset)
+	posts, page, err := h.feedService.GetHomeFeed(c.Request.Context(), uid, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: total > offset+limit,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, page, offset, limit))
 }
 
 // GetFollowingFeed handles GET /api/v1/feed/following
 func (h *Handlers) GetFollowingFeed(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	posts, total, err := h.feedService.GetFollowingFeed(c.Request.Context(), uid, limit, offset)
+	posts, page, err := h.feedService.GetFollowingFeed(c.Request.Context(), uid, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: total > offset+limit,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, page, offset, limit))
 }
 
 // GetExploreFeed handles GET /api/v1/feed/explore
 // This endpoint now returns content "since last visit" for a dynamic experience
 func (h *Handlers) GetExploreFeed(c *gin.Context) {
 	// Get viewer ID (required for "since last visit")
-	userID, exists := c.Get("user_id")
-	if !exists {
+	viewerID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	viewerID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	filter := c.DefaultQuery("filter", "") // Filter by type: posts, polls, articles, users
@@ -994,73 +1135,156 @@ func (h *Handlers) GetExploreFeed(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: total > offset+limit,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, models.CountedPage(total, offset, limit), offset, limit))
 }
 
 // GetSavedFeed handles GET /api/v1/feed/saved
 func (h *Handlers) GetSavedFeed(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
-	collection := c.DefaultQuery("collection", "Saved")
+	collection := c.DefaultQuery("collection", models.DefaultSavedCollection)
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
 	posts, total, err := h.feedService.GetSavedFeed(c.Request.Context(), uid, collection, limit, offset)
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, models.CountedPage(total, offset, limit), offset, limit))
+}
+
+// GetSavedCollections handles GET /api/v1/feed/saved/collections
+func (h *Handlers) GetSavedCollections(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	collections, err := h.service.GetSavedCollections(c.Request.Context(), uid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: total > offset+limit,
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"collections": collections,
+	})
+}
+
+// CreateSavedCollection handles POST /api/v1/feed/saved/collections
+func (h *Handlers) CreateSavedCollection(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.SavedCollectionRequest
+	if !utils.BindJSON(c, &req) {
+		return
+	}
+
+	name, err := h.service.CreateSavedCollection(c.Request.Context(), uid, req.Name)
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":    true,
+		"collection": models.SavedCollection{Name: name},
+	})
+}
+
+// RenameSavedCollection handles PATCH /api/v1/feed/saved/collections/:name
+func (h *Handlers) RenameSavedCollection(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.SavedCollectionRequest
+	if !utils.BindJSON(c, &req) {
+		return
+	}
+
+	name, err := h.service.RenameSavedCollection(c.Request.Context(), uid, c.Param("name"), req.Name)
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"name":    name,
+		"message": "Collection renamed",
+	})
+}
+
+// DeleteSavedCollection handles DELETE /api/v1/feed/saved/collections/:name. Posts move
+// to the default collection unless ?unsave=true.
+func (h *Handlers) DeleteSavedCollection(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	unsave := c.Query("unsave") == "true"
+
+	if err := h.service.DeleteSavedCollection(c.Request.Context(), uid, c.Param("name"), unsave); err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Collection deleted",
 	})
 }
 
+// respondCollectionError maps saved collection errors to status codes
+func respondCollectionError(c *gin.Context, err error) {
+	switch err {
+	case models.ErrCollectionNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": models.ErrCollectionNotFound.Code})
+	case models.ErrCollectionExists:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": models.ErrCollectionExists.Code})
+	case models.ErrCollectionName:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": models.ErrCollectionName.Code})
+	case models.ErrDefaultCollection:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": models.ErrDefaultCollection.Code})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // GetHashtagFeed handles GET /api/v1/hashtags/:tag/posts
 func (h *Handlers) GetHashtagFeed(c *gin.Context) {
 	hashtag := c.Param("tag")
 
 	// Get viewer ID (optional)
 	var viewerID uuid.UUID
-	if userID, exists := c.Get("user_id"); exists {
-		viewerID, _ = uuid.Parse(userID.(string))
-	}
+	viewerID, _ = utils.CurrentUserID(c)
 
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	posts, total, err := h.feedService.GetHashtagFeed(c.Request.Context(), hashtag, viewerID, limit, offset)
+	posts, page, err := h.feedService.GetHashtagFeed(c.Request.Context(), hashtag, viewerID, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: total > offset+limit,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, page, offset, limit))
 }
 
 // GetTrendingHashtags handles GET /api/v1/hashtags/trending
@@ -1083,14 +1307,12 @@ func (h *Handlers) GetTrendingHashtags(c *gin.Context) {
 func (h *Handlers) FollowHashtag(c *gin.Context) {
 	tag := c.Param("tag")
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	// Get hashtag by tag
 	hashtag, err := h.service.postRepo.GetHashtagByTag(c.Request.Context(), tag)
 	if err != nil {
@@ -1113,14 +1335,12 @@ func (h *Handlers) FollowHashtag(c *gin.Context) {
 func (h *Handlers) UnfollowHashtag(c *gin.Context) {
 	tag := c.Param("tag")
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	// Get hashtag by tag
 	hashtag, err := h.service.postRepo.GetHashtagByTag(c.Request.Context(), tag)
 	if err != nil {
@@ -1141,18 +1361,12 @@ func (h *Handlers) UnfollowHashtag(c *gin.Context) {
 
 // GetSinceLastVisitCounts handles GET /api/v1/feed/since-last-visit
 func (h *Handlers) GetSinceLastVisitCounts(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	counts, err := h.service.GetSinceLastVisitCounts(c.Request.Context(), uid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -1167,18 +1381,12 @@ func (h *Handlers) GetSinceLastVisitCounts(c *gin.Context) {
 
 // GetUserInteractions handles GET /api/v1/activity/interactions
 func (h *Handlers) GetUserInteractions(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	filter := c.Query("filter") // 'likes', 'comments', 'reposts', etc.
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
@@ -1189,6 +1397,7 @@ func (h *Handlers) GetUserInteractions(c *gin.Context) {
 
 	var posts []models.Post
 	var total int
+	var err error
 
 	switch filter {
 	case "likes":
@@ -1205,14 +1414,7 @@ func (h *Handlers) GetUserInteractions(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: offset+limit < total,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, models.CountedPage(total, offset, limit), offset, limit))
 }
 
 // GetPostInsights handles GET /api/v1/posts/:id/insights
@@ -1223,14 +1425,12 @@ func (h *Handlers) GetPostInsights(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	// Verify post belongs to user (insights only for own posts)
 	post, err := h.service.postRepo.GetPostByID(c.Request.Context(), postID)
 	if err != nil {
@@ -1257,18 +1457,12 @@ func (h *Handlers) GetPostInsights(c *gin.Context) {
 
 // GetUserArchived handles GET /api/v1/activity/archived
 func (h *Handlers) GetUserArchived(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	filter := c.Query("filter") // 'deleted', 'archived', etc.
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
@@ -1279,6 +1473,7 @@ func (h *Handlers) GetUserArchived(c *gin.Context) {
 
 	var posts []models.Post
 	var total int
+	var err error
 
 	switch filter {
 	case "deleted":
@@ -1297,30 +1492,17 @@ func (h *Handlers) GetUserArchived(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: offset+limit < total,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, models.CountedPage(total, offset, limit), offset, limit))
 }
 
 // GetUserLikedPosts handles GET /api/v1/activity/liked
 func (h *Handlers) GetUserLikedPosts(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
@@ -1334,74 +1516,55 @@ func (h *Handlers) GetUserLikedPosts(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   posts,
-		Total:   total,
-		Page:    offset / limit,
-		Limit:   limit,
-		HasMore: offset+limit < total,
-	})
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, models.CountedPage(total, offset, limit), offset, limit))
 }
 
 // GetUserShared handles GET /api/v1/activity/shared
+// Shares with a comment come back as quote posts embedding the original
 func (h *Handlers) GetUserShared(c *gin.Context) {
-	_, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	// uid, err := uuid.Parse(userID.(string))
-	// if err != nil {
-	// 	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-	// 	return
-	// }
-
-	// filter := c.Query("filter") // 'posts', 'articles', 'reels', 'projects', etc.
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	// offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
+	if limit <= 0 {
+		limit = 20
+	}
 	if limit > 100 {
 		limit = 100
 	}
 
-	// Get user's own posts (created by them), not shared posts
-	// This endpoint is used for profile feed to show all content user created
-	// To get SHARED content (reposts/shares), we need a different query or table
-	// For now, let's assume 'shares' are tracked in a separate table or via a special PostType/IsRepost flag
-	// Since we don't have a 'shares' table yet, we'll return an empty list or implement it properly later
-	// For this task, I'll update it to check for posts where the user is the 'sharer' if such a concept exists
-	// Assuming `post_shares` table or similar. Auditing repository suggests likes/saves are tracked.
-	// Let's implement a placeholder that returns empty for now until the shares table is confirmed.
-	// Actually, looking at the code, there isn't a shares table.
-	// I will skip implementation of GetUserShared for now and focus on Comments and Search History which are more critical.
-	// Wait, I should implement GetUserComments here.
+	var postType string
+	switch c.Query("filter") {
+	case "posts":
+		postType = "post"
+	case "polls":
+		postType = "poll"
+	case "articles":
+		postType = "article"
+	}
 
-	c.JSON(http.StatusOK, models.PostsResponse{
-		Success: true,
-		Posts:   []models.Post{},
-		Total:   0,
-		Page:    0,
-		Limit:   limit,
-		HasMore: false,
-	})
+	posts, total, err := h.service.GetUserSharedPosts(c.Request.Context(), uid, postType, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewPostsResponse(posts, models.CountedPage(total, offset, limit), offset, limit))
 }
 
 // GetUserComments handles GET /api/v1/activity/comments
 func (h *Handlers) GetUserComments(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
@@ -1437,18 +1600,12 @@ func (h *Handlers) GetUserSearchHistory(c *gin.Context) {
 
 // UploadImage handles POST /api/v1/posts/upload-image
 func (h *Handlers) UploadImage(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get quality parameter
 	quality := c.DefaultQuery("quality", "standard")
 	var optimizeQuality messaging.OptimizeImageQuality
@@ -1473,6 +1630,11 @@ func (h *Handlers) UploadImage(c *gin.Context) {
 		return
 	}
 
+	// Check the actual bytes, not the client-declared type
+	if _, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryImage); !ok {
+		return
+	}
+
 	// Validate image
 	isValid, _, err := h.mediaOptimizer.ValidateImage(fileData)
 	if !isValid || err != nil {
@@ -1481,22 +1643,17 @@ func (h *Handlers) UploadImage(c *gin.Context) {
 	}
 
 	// Optimize image
-	optimizedData, newFormat, err := h.mediaOptimizer.OptimizeImageFromBytes(fileData, optimizeQuality)
+	optimizedData, newFormat, err := h.mediaOptimizer.OptimizeUploadImage(fileData, optimizeQuality, messaging.UploadTypePost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to optimize image"})
 		return
 	}
 
 	// Upload to Supabase Storage (use "media" bucket for posts - same as configured in main.go)
-	fileName := fmt.Sprintf("posts/%s/%s_%d.%s", uid.String(), uuid.New().String(), time.Now().Unix(), newFormat)
-	uploadedURL, err := h.storageService.UploadFile(c.Request.Context(), "media", fileName, bytes.NewReader(optimizedData), "image/"+newFormat)
-	if err != nil {
-		log.Printf("[UploadImage] Failed to upload to storage: %v", err)
-		log.Printf("[UploadImage] File name: %s, Size: %d bytes, Format: %s", fileName, len(optimizedData), newFormat)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to upload image",
-			"details": err.Error(),
-		})
+	filePrefix := fmt.Sprintf("posts/%s/%s_%d", uid.String(), uuid.New().String(), time.Now().Unix())
+	fileName := fmt.Sprintf("%s.%s", filePrefix, newFormat)
+	uploadedURL, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "media", fileName, optimizedData, "image/"+newFormat, "Failed to upload image")
+	if !ok {
 		return
 	}
 
@@ -1506,26 +1663,134 @@ func (h *Handlers) UploadImage(c *gin.Context) {
 	log.Printf("[UploadImage] URL length: %d", len(uploadedURL))
 	log.Printf("[UploadImage] URL starts with http: %v", strings.HasPrefix(uploadedURL, "http"))
 
+	// Generate smaller WebP variants so the feed can request an appropriate size, and
+	// save them for the post made with this image to pick up. Failures here are not
+	// fatal - clients fall back to the full-size URL.
+	variants := make(map[string]string)
+	if !h.mediaOptimizer.CanGenerateVariants() {
+		log.Printf("[UploadImage] ffmpeg not installed, %s gets no resized variants", fileName)
+	} else {
+		sizeURLs, err := h.mediaOptimizer.GenerateVariantsFromBytes(c.Request.Context(), h.storageService, uid, "media", fileName, optimizedData, messaging.DefaultVariantSizes)
+		if err != nil {
+			log.Printf("[UploadImage] Failed to generate image variants: %v", err)
+		}
+		for size, url := range sizeURLs {
+			variants[strconv.Itoa(size)] = url
+		}
+		if err := h.service.RecordMediaVariants(c.Request.Context(), uid, uploadedURL, variants); err != nil {
+			log.Printf("[UploadImage] Failed to save image variants: %v", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"url":     uploadedURL,
-		"name":    header.Filename,
-		"size":    len(optimizedData),
-		"type":    "image/" + newFormat,
+		"success":       true,
+		"url":           uploadedURL,
+		"name":          header.Filename,
+		"size":          len(optimizedData),
+		"type":          "image/" + newFormat,
+		"variants":      variants,
+		"storage_quota": quota,
 	})
 }
 
-// UploadVideo handles POST /api/v1/posts/upload-video
-func (h *Handlers) UploadVideo(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+// presignedUploadTypes maps content types allowed for direct uploads to file extension
+// and max size. Images aren't among them: they go through UploadImage, which checks
+// their content and strips EXIF metadata (GPS location included) that a direct upload
+// would publish untouched.
+var presignedUploadTypes = map[string]struct {
+	ext     string
+	maxSize int64
+}{
+	"video/mp4":       {"mp4", messaging.MaxFileSize},
+	"video/quicktime": {"mov", messaging.MaxFileSize},
+	"video/webm":      {"webm", messaging.MaxFileSize},
+}
+
+// presignedUploadExpiry is how long a presigned upload URL stays valid
+const presignedUploadExpiry = 15 * time.Minute
+
+// GetUploadURL handles POST /api/v1/posts/upload-url
+// Returns a presigned PUT URL so large media can be uploaded straight to R2,
+// plus the public URL to reference in media_urls when creating the post.
+func (h *Handlers) GetUploadURL(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
+	var req struct {
+		ContentType string `json:"content_type" binding:"required"`
+		Size        int64  `json:"size" binding:"required,gt=0"`
+	}
+	if !utils.BindJSON(c, &req) {
+		return
+	}
+
+	contentType := strings.ToLower(strings.TrimSpace(req.ContentType))
+	if strings.HasPrefix(contentType, "image/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Images can't be uploaded directly; use /posts/upload-image"})
+		return
+	}
+	allowed, ok := presignedUploadTypes[contentType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported content type: " + req.ContentType})
+		return
+	}
+	if req.Size > allowed.maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File too large (max %dMB)", allowed.maxSize/(1024*1024))})
+		return
+	}
+
+	if h.objectStorage == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": storage.ErrPresignedUploadNotSupported.Error()})
+		return
+	}
+
+	// The signed Content-Length pins the object size, so the space is reserved when the
+	// URL is issued. Unused URLs are corrected by the storage reconciliation job.
+	quota, err := h.storageService.ReserveUsage(c.Request.Context(), uid, req.Size)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return
+	}
+
+	key := fmt.Sprintf("posts/%s/%s_%d.%s", uid.String(), uuid.New().String(), time.Now().Unix(), allowed.ext)
+	uploadURL, publicURL, err := h.objectStorage.GeneratePresignedUploadURL(key, contentType, req.Size, presignedUploadExpiry)
+	if err != nil {
+		if quota != nil {
+			h.storageService.ReleaseUsage(c.Request.Context(), uid, req.Size)
+		}
+		if err == storage.ErrPresignedUploadNotSupported {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[GetUploadURL] Failed to presign upload: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload URL"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"upload_url": uploadURL,
+		"method":     "PUT",
+		"headers": gin.H{
+			"Content-Type":   contentType,
+			"Content-Length": strconv.FormatInt(req.Size, 10),
+		},
+		"public_url":    publicURL,
+		"key":           key,
+		"expires_at":    time.Now().Add(presignedUploadExpiry),
+		"storage_quota": quota,
+	})
+}
+
+// UploadVideo handles POST /api/v1/posts/upload-video
+func (h *Handlers) UploadVideo(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
@@ -1551,15 +1816,18 @@ func (h *Handlers) UploadVideo(c *gin.Context) {
 		return
 	}
 
+	contentType, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryVideo)
+	if !ok {
+		return
+	}
+
 	// Sanitize filename
 	sanitizedFilename := sanitizeFilename(header.Filename)
 
 	// Upload video to Supabase Storage (use "media" bucket - same as configured in main.go)
 	videoFileName := fmt.Sprintf("posts/%s/video_%s_%s", uid.String(), uuid.New().String(), sanitizedFilename)
-	uploadedURL, err := h.storageService.UploadFile(c.Request.Context(), "media", videoFileName, bytes.NewReader(fileData), header.Header.Get("Content-Type"))
-	if err != nil {
-		log.Printf("[UploadVideo] Failed to upload: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload video"})
+	uploadedURL, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "media", videoFileName, fileData, contentType, "Failed to upload video")
+	if !ok {
 		return
 	}
 
@@ -1570,8 +1838,14 @@ func (h *Handlers) UploadVideo(c *gin.Context) {
 		defer thumbnailFile.Close()
 		thumbnailData, err := io.ReadAll(thumbnailFile)
 		if err == nil {
-			thumbnailFileName := fmt.Sprintf("posts/%s/thumb_%s.jpg", uid.String(), uuid.New().String())
-			thumbnailURL, _ = h.storageService.UploadFile(c.Request.Context(), "public", thumbnailFileName, bytes.NewReader(thumbnailData), thumbnailHeader.Header.Get("Content-Type"))
+			// An invalid thumbnail is dropped rather than failing the video upload
+			thumbnailType, err := h.storageService.ValidateUploadContent(thumbnailData, thumbnailHeader.Header.Get("Content-Type"), utils.UploadCategoryImage)
+			if err != nil {
+				log.Printf("[UploadVideo] Rejected thumbnail: %v", err)
+			} else {
+				thumbnailFileName := fmt.Sprintf("posts/%s/thumb_%s.jpg", uid.String(), uuid.New().String())
+				thumbnailURL, _, _ = h.storageService.UploadUserFile(c.Request.Context(), uid, "public", thumbnailFileName, thumbnailData, thumbnailType)
+			}
 		}
 	}
 
@@ -1581,24 +1855,19 @@ func (h *Handlers) UploadVideo(c *gin.Context) {
 		"thumbnail_url": thumbnailURL,
 		"name":          header.Filename,
 		"size":          header.Size,
-		"type":          header.Header.Get("Content-Type"),
+		"type":          contentType,
+		"storage_quota": quota,
 	})
 }
 
 // UploadAudio handles POST /api/v1/posts/upload-audio
 func (h *Handlers) UploadAudio(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get file from form (expecting "file" field name)
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
@@ -1614,8 +1883,13 @@ func (h *Handlers) UploadAudio(c *gin.Context) {
 		return
 	}
 
+	contentType, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryAudio)
+	if !ok {
+		return
+	}
+
 	// Validate audio
-	isValid, err := h.mediaOptimizer.ValidateAudio(fileData, header.Header.Get("Content-Type"))
+	isValid, err := h.mediaOptimizer.ValidateAudio(fileData, contentType)
 	if !isValid {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audio format: " + err.Error()})
 		return
@@ -1623,18 +1897,17 @@ func (h *Handlers) UploadAudio(c *gin.Context) {
 
 	// Upload to Supabase Storage
 	fileName := fmt.Sprintf("posts/%s/audio_%s_%d.webm", uid.String(), uuid.New().String(), time.Now().Unix())
-	uploadedURL, err := h.storageService.UploadFile(c.Request.Context(), "public", fileName, bytes.NewReader(fileData), "audio/webm")
-	if err != nil {
-		log.Printf("[UploadAudio] Failed to upload: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload audio"})
+	uploadedURL, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "public", fileName, fileData, "audio/webm", "Failed to upload audio")
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"url":     uploadedURL,
-		"name":    header.Filename,
-		"size":    len(fileData),
-		"type":    "audio/webm",
+		"success":       true,
+		"url":           uploadedURL,
+		"name":          header.Filename,
+		"size":          len(fileData),
+		"type":          "audio/webm",
+		"storage_quota": quota,
 	})
 }
@@ -0,0 +1,41 @@
+package posts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// serveAs runs handler on a request from a signed-in user and returns the response
+func serveAs(t *testing.T, handler gin.HandlerFunc, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if err := utils.SetCurrentUser(c, &models.JWTClaims{UserID: uuid.NewString()}); err != nil {
+		t.Fatalf("SetCurrentUser: %v", err)
+	}
+	handler(c)
+	return w
+}
+
+func TestGetUploadURLRefusesImages(t *testing.T) {
+	h := NewHandlers(nil, nil, nil, nil)
+
+	for _, contentType := range []string{"image/jpeg", "image/png", "IMAGE/WEBP", "image/heic"} {
+		w := serveAs(t, h.GetUploadURL, http.MethodPost, "/api/v1/posts/upload-url",
+			`{"content_type": "`+contentType+`", "size": 1024}`)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "upload-image") {
+			t.Errorf("%s: got %d %s, want a 400 pointing at upload-image", contentType, w.Code, w.Body)
+		}
+	}
+}
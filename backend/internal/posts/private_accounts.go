@@ -0,0 +1,154 @@
+package posts
+
+import (
+	"context"
+	"log"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// privacyGate keeps posts by private accounts to the accounts' approved followers,
+// and posts shared with close friends to the author's close friends. A pending follow
+// request unlocks nothing.
+type privacyGate struct {
+	users         repository.UserRepository
+	relationships repository.RelationshipRepository
+}
+
+// enabled reports whether the gate can check anything; without both repositories
+// posts pass through as before
+func (g privacyGate) enabled() bool {
+	return g.users != nil && g.relationships != nil
+}
+
+// canView reports whether viewerID may see posts by author, whose profile privacy is
+// known. Anonymous viewers never see a private account's posts.
+func (g privacyGate) canView(ctx context.Context, author *models.User, viewerID uuid.UUID) (bool, error) {
+	if author.ProfilePrivacy != "private" || author.ID == viewerID {
+		return true, nil
+	}
+	if viewerID == uuid.Nil || g.relationships == nil {
+		return false, nil
+	}
+	return g.follows(ctx, viewerID, author.ID)
+}
+
+// follows reports whether viewerID has an approved follow of authorID
+func (g privacyGate) follows(ctx context.Context, viewerID, authorID uuid.UUID) (bool, error) {
+	follow, err := g.relationships.GetRelationship(ctx, viewerID, authorID, models.RelationshipFollowing)
+	if err != nil {
+		return false, err
+	}
+	return follow != nil && follow.Status == models.RelationshipActive, nil
+}
+
+// canSeePost reports whether viewerID may see post given who it was shared with. Only
+// the author's close friends see close_friends posts.
+func (g privacyGate) canSeePost(ctx context.Context, post *models.Post, viewerID uuid.UUID) (bool, error) {
+	audience := models.CloseFriendsOf{}
+	if post.Visibility == models.VisibilityCloseFriends && viewerID != uuid.Nil && post.UserID != viewerID {
+		if g.relationships == nil {
+			return false, nil
+		}
+		member, err := g.relationships.IsCloseFriend(ctx, post.UserID, viewerID)
+		if err != nil {
+			return false, err
+		}
+		audience[post.UserID] = member
+	}
+	return audience.CanSee(post.UserID, viewerID, post.Visibility), nil
+}
+
+// filter drops posts by private accounts viewerID doesn't follow, and posts shared
+// with an audience viewerID isn't in. It fails closed: when an account or audience
+// can't be checked its posts are left out.
+func (g privacyGate) filter(ctx context.Context, posts []models.Post, page models.Page, viewerID uuid.UUID) ([]models.Post, models.Page) {
+	if len(posts) == 0 {
+		return posts, page
+	}
+
+	hidden := g.hiddenAuthors(ctx, posts, viewerID)
+	audience := g.audience(ctx, posts, viewerID)
+
+	kept := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		if hidden[post.UserID] || !audience.CanSee(post.UserID, viewerID, post.Visibility) {
+			continue
+		}
+		kept = append(kept, post)
+	}
+	if len(kept) == len(posts) {
+		return posts, page
+	}
+
+	return kept, page.Filtered(len(posts)-len(kept), len(kept))
+}
+
+// hiddenAuthors returns the private accounts among the authors of posts that viewerID
+// doesn't follow
+func (g privacyGate) hiddenAuthors(ctx context.Context, posts []models.Post, viewerID uuid.UUID) map[uuid.UUID]bool {
+	hidden := make(map[uuid.UUID]bool)
+	if !g.enabled() {
+		return hidden
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	authorIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		if post.UserID != viewerID && !seen[post.UserID] {
+			seen[post.UserID] = true
+			authorIDs = append(authorIDs, post.UserID)
+		}
+	}
+	if len(authorIDs) == 0 {
+		return hidden
+	}
+
+	privateIDs, err := g.users.GetPrivateUserIDs(ctx, authorIDs)
+	if err != nil {
+		log.Printf("[Posts] Failed to check which authors are private: %v", err)
+		privateIDs = authorIDs
+	}
+	for _, authorID := range privateIDs {
+		visible := false
+		if viewerID != uuid.Nil {
+			visible, err = g.follows(ctx, viewerID, authorID)
+			if err != nil {
+				log.Printf("[Posts] Failed to check whether %s follows %s: %v", viewerID, authorID, err)
+			}
+		}
+		if !visible {
+			hidden[authorID] = true
+		}
+	}
+	return hidden
+}
+
+// audience returns the close friends lists viewerID is on, looked up only when posts
+// include someone else's close_friends post
+func (g privacyGate) audience(ctx context.Context, posts []models.Post, viewerID uuid.UUID) models.CloseFriendsOf {
+	if viewerID == uuid.Nil || g.relationships == nil {
+		return models.CloseFriendsOf{}
+	}
+
+	needed := false
+	for _, post := range posts {
+		if post.Visibility == models.VisibilityCloseFriends && post.UserID != viewerID {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return models.CloseFriendsOf{}
+	}
+
+	ownerIDs, err := g.relationships.GetCloseFriendsOf(ctx, viewerID)
+	if err != nil {
+		log.Printf("[Posts] Failed to get close friends lists of %s: %v", viewerID, err)
+		return models.CloseFriendsOf{}
+	}
+	return models.NewCloseFriendsOf(ownerIDs)
+}
@@ -0,0 +1,131 @@
+package posts
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"time"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// RankingWeights tune how explore candidates are scored. A zero weight turns that
+// signal off; with velocity and affinity both off the order is newest first.
+type RankingWeights struct {
+	RecencyHalfLife time.Duration // Score halves every time a post ages by this much
+	VelocityWeight  float64       // Boost per unit of engagement per hour since posting
+	AffinityWeight  float64       // Boost for posts by authors the viewer follows
+	MaxPerAuthor    int           // Per-author cap on a page; 0 keeps the diversity rules' cap
+}
+
+// DefaultRankingWeights returns the weights used when none are configured
+func DefaultRankingWeights() RankingWeights {
+	return RankingWeights{
+		RecencyHalfLife: 24 * time.Hour,
+		VelocityWeight:  1,
+		AffinityWeight:  0.5,
+	}
+}
+
+// RankingVariant is the weight set a viewer is ranked with
+type RankingVariant struct {
+	Name    string
+	Weights RankingWeights
+}
+
+// ControlVariant names the configured default weights
+const ControlVariant = "control"
+
+// RankingExperiment serves Weights to Percent of viewers instead of the control set.
+// Viewers are bucketed by a hash of the experiment name and their user ID, so a viewer
+// stays in one variant for the life of an experiment and renaming it reshuffles them.
+type RankingExperiment struct {
+	Name    string
+	Percent int // 0-100; 0 disables the experiment
+	Weights RankingWeights
+}
+
+// FeedRanker scores explore candidates and assigns viewers to a ranking variant
+type FeedRanker struct {
+	control    RankingWeights
+	experiment *RankingExperiment
+}
+
+// NewFeedRanker creates a ranker that serves control to every viewer outside the
+// experiment. experiment may be nil.
+func NewFeedRanker(control RankingWeights, experiment *RankingExperiment) *FeedRanker {
+	if experiment != nil && (experiment.Name == "" || experiment.Percent <= 0) {
+		experiment = nil
+	}
+	return &FeedRanker{control: control, experiment: experiment}
+}
+
+// Variant returns the weight set for a viewer. Anonymous viewers always get control,
+// which keeps the shared explore cache on control weights.
+func (r *FeedRanker) Variant(viewerID uuid.UUID) RankingVariant {
+	if r.experiment == nil || viewerID == uuid.Nil {
+		return RankingVariant{Name: ControlVariant, Weights: r.control}
+	}
+	if experimentBucket(r.experiment.Name, viewerID) < r.experiment.Percent {
+		return RankingVariant{Name: r.experiment.Name, Weights: r.experiment.Weights}
+	}
+	return RankingVariant{Name: ControlVariant, Weights: r.control}
+}
+
+// experimentBucket maps a viewer to 0-99 for the named experiment
+func experimentBucket(experiment string, viewerID uuid.UUID) int {
+	h := fnv.New32a()
+	h.Write([]byte(experiment))
+	h.Write(viewerID[:])
+	return int(h.Sum32() % 100)
+}
+
+// scorePost scores one candidate. followed reports whether the viewer follows the author.
+func scorePost(post *models.Post, weights RankingWeights, followed bool, now time.Time) float64 {
+	postedAt := post.CreatedAt
+	if post.PublishedAt != nil {
+		postedAt = *post.PublishedAt
+	}
+	ageHours := math.Max(now.Sub(postedAt).Hours(), 0)
+
+	score := 1.0
+	if weights.RecencyHalfLife > 0 {
+		score = math.Pow(0.5, ageHours/weights.RecencyHalfLife.Hours())
+	}
+
+	// Comments and shares take more effort than a like, so they count for more.
+	// The +2 hours keeps brand-new posts from spiking on their first like.
+	engagement := float64(post.LikesCount + 2*post.CommentsCount + 3*post.SharesCount)
+	velocity := engagement / (ageHours + 2)
+	score *= 1 + weights.VelocityWeight*velocity
+
+	if followed {
+		score *= 1 + weights.AffinityWeight
+	}
+	return score
+}
+
+// rankPosts orders candidates by score, best first. Ties keep their incoming order.
+func rankPosts(candidates []models.Post, weights RankingWeights, following map[uuid.UUID]bool, now time.Time) []models.Post {
+	scores := make(map[uuid.UUID]float64, len(candidates))
+	for i := range candidates {
+		scores[candidates[i].ID] = scorePost(&candidates[i], weights, following[candidates[i].UserID], now)
+	}
+
+	ranked := make([]models.Post, len(candidates))
+	copy(ranked, candidates)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].ID] > scores[ranked[j].ID]
+	})
+	return ranked
+}
+
+// diversityFor applies a variant's per-author cap on top of the base diversity rules
+func diversityFor(rules FeedDiversityRules, weights RankingWeights) FeedDiversityRules {
+	if weights.MaxPerAuthor > 0 {
+		rules.MaxPerAuthorPerPage = weights.MaxPerAuthor
+	}
+	return rules
+}
@@ -0,0 +1,107 @@
+package posts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestRankingVariantIsStablePerViewer(t *testing.T) {
+	experiment := RankingWeights{VelocityWeight: 5}
+	ranker := NewFeedRanker(DefaultRankingWeights(), &RankingExperiment{Name: "velocity-5", Percent: 30, Weights: experiment})
+
+	inExperiment := 0
+	for i := 0; i < 1000; i++ {
+		viewer := uuid.New()
+		first := ranker.Variant(viewer)
+		for j := 0; j < 3; j++ {
+			if ranker.Variant(viewer).Name != first.Name {
+				t.Fatalf("viewer %s moved between variants", viewer)
+			}
+		}
+		if first.Name == "velocity-5" {
+			inExperiment++
+			if first.Weights != experiment {
+				t.Fatalf("experiment viewer got weights %+v", first.Weights)
+			}
+		}
+	}
+	if inExperiment < 200 || inExperiment > 400 {
+		t.Errorf("%d of 1000 viewers in a 30%% experiment", inExperiment)
+	}
+
+	if got := ranker.Variant(uuid.Nil); got.Name != ControlVariant {
+		t.Errorf("anonymous viewer got variant %q, want control", got.Name)
+	}
+}
+
+func TestRankingExperimentNeedsANameAndShare(t *testing.T) {
+	viewer := uuid.New()
+	for _, experiment := range []*RankingExperiment{
+		nil,
+		{Name: "", Percent: 100},
+		{Name: "off", Percent: 0},
+	} {
+		if got := NewFeedRanker(DefaultRankingWeights(), experiment).Variant(viewer); got.Name != ControlVariant {
+			t.Errorf("experiment %+v served variant %q, want control", experiment, got.Name)
+		}
+	}
+	if got := NewFeedRanker(DefaultRankingWeights(), &RankingExperiment{Name: "all", Percent: 100}).Variant(viewer); got.Name != "all" {
+		t.Errorf("a 100%% experiment served %q", got.Name)
+	}
+}
+
+func TestRankingWeightsReachTheScorer(t *testing.T) {
+	now := time.Now()
+	fresh := models.Post{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now.Add(-time.Hour)}
+	popular := models.Post{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now.Add(-12 * time.Hour), LikesCount: 200, CommentsCount: 40}
+	followed := models.Post{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now.Add(-2 * time.Hour)}
+	candidates := []models.Post{fresh, popular}
+
+	recencyOnly := RankingWeights{RecencyHalfLife: 24 * time.Hour}
+	if got := rankPosts(candidates, recencyOnly, nil, now); got[0].ID != fresh.ID {
+		t.Error("without velocity the newest post should lead")
+	}
+	withVelocity := RankingWeights{RecencyHalfLife: 24 * time.Hour, VelocityWeight: 10}
+	if got := rankPosts(candidates, withVelocity, nil, now); got[0].ID != popular.ID {
+		t.Error("a velocity weight should lift the heavily engaged post")
+	}
+
+	following := map[uuid.UUID]bool{followed.UserID: true}
+	pair := []models.Post{fresh, followed}
+	if got := rankPosts(pair, RankingWeights{RecencyHalfLife: time.Hour}, following, now); got[0].ID != fresh.ID {
+		t.Error("without affinity the newer post should lead")
+	}
+	if got := rankPosts(pair, RankingWeights{RecencyHalfLife: time.Hour, AffinityWeight: 3}, following, now); got[0].ID != followed.ID {
+		t.Error("an affinity weight should lift the followed author's post")
+	}
+
+	if rules := diversityFor(DefaultFeedDiversityRules(), RankingWeights{MaxPerAuthor: 1}); rules.MaxPerAuthorPerPage != 1 {
+		t.Errorf("MaxPerAuthor should set the page cap, got %d", rules.MaxPerAuthorPerPage)
+	}
+}
+
+func TestExploreFeedRanksWithTheViewersVariant(t *testing.T) {
+	now := time.Now()
+	fresh := models.Post{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now.Add(-time.Hour)}
+	popular := models.Post{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now.Add(-12 * time.Hour), LikesCount: 200}
+	svc := NewFeedService(&fakePostRepo{feed: []models.Post{fresh, popular}}, nil)
+	svc.SetRanker(NewFeedRanker(
+		RankingWeights{RecencyHalfLife: 24 * time.Hour},
+		&RankingExperiment{Name: "velocity", Percent: 100, Weights: RankingWeights{RecencyHalfLife: 24 * time.Hour, VelocityWeight: 10}},
+	))
+
+	// Anonymous viewers get control; everyone signed in is in the 100% experiment
+	control, _, err := svc.GetExploreFeed(context.Background(), uuid.Nil, 10, 0, "")
+	if err != nil || len(control) != 2 || control[0].ID != fresh.ID {
+		t.Fatalf("control explore = %v, %v; want the fresh post first", control, err)
+	}
+	experiment, _, err := svc.GetExploreFeed(context.Background(), uuid.New(), 10, 0, "")
+	if err != nil || len(experiment) != 2 || experiment[0].ID != popular.ID {
+		t.Fatalf("experiment explore = %v, %v; want the popular post first", experiment, err)
+	}
+}
@@ -3,27 +3,56 @@ package posts
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/storage"
+	"histeeria-backend/internal/utils"
 
 	"histeeria-backend/internal/websocket"
+	apperr "histeeria-backend/pkg/errors"
 
 	"github.com/lib/pq"
 
 	"github.com/google/uuid"
 )
 
+// mentionSnippetLength is the number of characters of the post or comment included in a mention notification
+const mentionSnippetLength = 120
+
+var (
+	// linkPattern finds links in post and comment text
+	linkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+	// articleLinkPattern finds links in article HTML, where bare URLs are mostly embedded media
+	articleLinkPattern = regexp.MustCompile(`(?i)<a\s[^>]*href`)
+)
+
+// NotificationService interface for creating notifications (avoid circular dependency)
+type NotificationService interface {
+	CreateMentionNotification(ctx context.Context, actorID, mentionedID uuid.UUID, actorUsername string, postID uuid.UUID, commentID *uuid.UUID, snippet string) error
+}
+
 // Service handles post business logic
 type Service struct {
-	postRepo    repository.PostRepository
-	pollRepo    repository.PollRepository
-	articleRepo repository.ArticleRepository
-	commentRepo repository.CommentRepository
-	userRepo    repository.UserRepository
-	wsManager   *websocket.Manager
+	postRepo     repository.PostRepository
+	pollRepo     repository.PollRepository
+	articleRepo  repository.ArticleRepository
+	commentRepo  repository.CommentRepository
+	userRepo     repository.UserRepository
+	relRepo      repository.RelationshipRepository // Lets approved followers see private accounts' posts
+	wsManager    *websocket.Manager
+	notifService NotificationService
+	langDetector utils.LanguageDetector
+	objects      *storage.StorageService // Removes media of purged posts; nil leaves it in place
+	quota        *utils.StorageService   // Gives purged media's bytes back to its owner's quota
+	maxPinned    int                     // Pinned posts per profile, 0 for no limit
+	maxDepth     int                     // Comment thread levels
+	newAccounts  models.NewAccountRestrictions
 }
 
 // NewService creates a new post service
@@ -36,15 +65,91 @@ func NewService(
 	wsManager *websocket.Manager,
 ) *Service {
 	return &Service{
-		postRepo:    postRepo,
-		pollRepo:    pollRepo,
-		articleRepo: articleRepo,
-		commentRepo: commentRepo,
-		userRepo:    userRepo,
-		wsManager:   wsManager,
+		postRepo:     postRepo,
+		pollRepo:     pollRepo,
+		articleRepo:  articleRepo,
+		commentRepo:  commentRepo,
+		userRepo:     userRepo,
+		wsManager:    wsManager,
+		langDetector: utils.NewHeuristicLanguageDetector(),
+		maxDepth:     defaultCommentDepth,
 	}
 }
 
+// SetMaxPinnedPosts caps how many posts a user can pin to their profile. 0 removes
+// the cap.
+func (s *Service) SetMaxPinnedPosts(limit int) {
+	s.maxPinned = limit
+}
+
+// SetMaxCommentDepth sets how many levels a comment thread can have, 1 for flat comments
+func (s *Service) SetMaxCommentDepth(depth int) {
+	s.maxDepth = depth
+}
+
+// SetNewAccountRestrictions sets the probation rules; accounts under them can't post
+// links unless the rules allow it
+func (s *Service) SetNewAccountRestrictions(rules models.NewAccountRestrictions) {
+	s.newAccounts = rules
+}
+
+// checkLinks returns ErrNewAccountLinks if text has a link and userID is still in the
+// new account period. The user is only looked up when there is a link.
+func (s *Service) checkLinks(ctx context.Context, userID uuid.UUID, text string, pattern *regexp.Regexp) error {
+	if s.newAccounts.Period <= 0 || s.newAccounts.AllowLinks || !pattern.MatchString(text) {
+		return nil
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s.newAccounts.Applies(user) {
+		return models.ErrNewAccountLinks
+	}
+	return nil
+}
+
+// SetRelationshipRepository lets the service check follows, so a private account's
+// approved followers see its posts. Without it only the account itself does.
+func (s *Service) SetRelationshipRepository(relRepo repository.RelationshipRepository) {
+	s.relRepo = relRepo
+}
+
+// privacy returns the gate that hides private accounts' posts from non-followers
+func (s *Service) privacy() privacyGate {
+	return privacyGate{users: s.userRepo, relationships: s.relRepo}
+}
+
+// SetLanguageDetector replaces the detector used to tag posts and comments with a language
+func (s *Service) SetLanguageDetector(detector utils.LanguageDetector) {
+	s.langDetector = detector
+}
+
+// SetObjectStorage sets the storage the post media lives in, so purged posts take their
+// media with them
+func (s *Service) SetObjectStorage(objects *storage.StorageService) {
+	s.objects = objects
+}
+
+// SetStorageQuota sets the quota tracking post uploads, so purged posts' media stops
+// counting against their authors
+func (s *Service) SetStorageQuota(quota *utils.StorageService) {
+	s.quota = quota
+}
+
+// detectLanguage returns the language of text, or "" when no detector is set or it is unsure
+func (s *Service) detectLanguage(text string) string {
+	if s.langDetector == nil {
+		return ""
+	}
+	return s.langDetector.Detect(text)
+}
+
+// SetNotificationService sets the notification service (called after initialization to avoid circular dependency)
+func (s *Service) SetNotificationService(notificationService NotificationService) {
+	s.notifService = notificationService
+}
+
 // ============================================
 // POST OPERATIONS
 // ============================================
@@ -56,6 +161,31 @@ func (s *Service) CreatePost(ctx context.Context, req *models.CreatePostRequest,
 		return nil, err
 	}
 
+	if err := s.checkLinks(ctx, userID, req.Content, linkPattern); err != nil {
+		return nil, err
+	}
+	if req.Article != nil {
+		if err := s.checkLinks(ctx, userID, req.Article.ContentHTML, articleLinkPattern); err != nil {
+			return nil, err
+		}
+	}
+
+	// A scheduled time is read in the author's timezone unless the request names one
+	var scheduledAt *time.Time
+	timezone := req.Timezone
+	if req.ScheduledAt != nil {
+		if timezone == "" {
+			if author, err := s.userRepo.GetUserByID(ctx, userID); err == nil {
+				timezone = author.Timezone
+			}
+		}
+		t, err := utils.ResolveScheduledTime(*req.ScheduledAt, timezone, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		scheduledAt = &t
+	}
+
 	// Create base post
 	post := &models.Post{
 		UserID:         userID,
@@ -63,17 +193,29 @@ func (s *Service) CreatePost(ctx context.Context, req *models.CreatePostRequest,
 		Content:        req.Content,
 		MediaURLs:      pq.StringArray(req.MediaURLs),
 		MediaTypes:     pq.StringArray(req.MediaTypes),
+		MediaVariants:  s.storedMediaVariants(ctx, userID, req.MediaURLs),
 		Visibility:     req.Visibility,
 		AllowsComments: req.AllowsComments,
 		AllowsSharing:  req.AllowsSharing,
 		IsDraft:        req.IsDraft,
-		IsPublished:    !req.IsDraft,
+		IsPublished:    !req.IsDraft && scheduledAt == nil,
 		IsNSFW:         req.IsNSFW,
+		ScheduledAt:    scheduledAt,
 	}
 
+	// Polls and articles carry most of their text outside Content
+	languageText := req.Content
+	if req.Poll != nil {
+		languageText += "\n" + req.Poll.Question
+	}
+	if req.Article != nil {
+		languageText += "\n" + req.Article.Title + "\n" + req.Article.Subtitle
+	}
+	post.Language = s.detectLanguage(languageText)
+
 	// Set defaults
 	if post.Visibility == "" {
-		post.Visibility = "public"
+		post.Visibility = models.VisibilityPublic
 	}
 
 	if post.IsPublished {
@@ -92,7 +234,8 @@ func (s *Service) CreatePost(ctx context.Context, req *models.CreatePostRequest,
 	}
 
 	// Extract and create mentions
-	if err := s.postRepo.ExtractAndCreateMentions(ctx, post.ID, post.Content); err != nil {
+	mentionedIDs, err := s.postRepo.ExtractAndCreateMentions(ctx, post.ID, post.Content)
+	if err != nil {
 		fmt.Printf("Warning: failed to extract mentions: %v\n", err)
 	}
 
@@ -147,49 +290,87 @@ func (s *Service) CreatePost(ctx context.Context, req *models.CreatePostRequest,
 	// Broadcast to followers (if published)
 	if post.IsPublished {
 		s.broadcastNewPost(post)
+		s.notifyMentions(userID, mentionedIDs, post.ID, nil, post.Content)
 	}
 
+	if post.ScheduledAt != nil {
+		local := utils.InUserTimezone(*post.ScheduledAt, timezone)
+		post.ScheduledAt = &local
+	}
 	return post, nil
 }
 
-// GetUserPostsByUsername retrieves posts for a user by their username
-func (s *Service) GetUserPostsByUsername(ctx context.Context, username string, limit, offset int, viewerID uuid.UUID) ([]models.Post, int, error) {
+// PublishScheduledPosts publishes up to batchSize scheduled posts whose time has come,
+// announcing each as if it had just been created, and returns how many went out
+func (s *Service) PublishScheduledPosts(ctx context.Context, batchSize int) (int, error) {
+	posts, err := s.postRepo.PublishDueScheduledPosts(ctx, time.Now(), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range posts {
+		post := &posts[i]
+		s.broadcastNewPost(post)
+		mentionedIDs, err := s.postRepo.ResolveMentions(ctx, post.Content)
+		if err != nil {
+			fmt.Printf("Warning: failed to resolve mentions of scheduled post %s: %v\n", post.ID, err)
+			continue
+		}
+		s.notifyMentions(post.UserID, mentionedIDs, post.ID, nil, post.Content)
+	}
+	return len(posts), nil
+}
+
+// GetUserPostsByUsername retrieves posts for a user by their username. withCount adds an
+// exact total to the page.
+func (s *Service) GetUserPostsByUsername(ctx context.Context, username string, limit, offset int, viewerID uuid.UUID, withCount bool) ([]models.Post, models.Page, error) {
 	fmt.Printf("[Service.GetUserPostsByUsername] Username: %s, Viewer: %s\n", username, viewerID)
 	// 1. Get user by username
 	user, err := s.userRepo.GetUserByUsername(ctx, username)
 	if err != nil {
 		fmt.Printf("[Service.GetUserPostsByUsername] User lookup error: %v\n", err)
-		return nil, 0, fmt.Errorf("user not found: %w", err)
+		return nil, models.Page{}, fmt.Errorf("user not found: %w", err)
 	}
 	fmt.Printf("[Service.GetUserPostsByUsername] Found user ID: %s\n", user.ID)
 
+	// A private account's posts are for its approved followers
+	visible, err := s.privacy().canView(ctx, user, viewerID)
+	if err != nil {
+		return nil, models.Page{}, fmt.Errorf("failed to check follow: %w", err)
+	}
+	if !visible {
+		return []models.Post{}, models.Page{}, nil
+	}
+
 	// 2. Get posts for this user
-	posts, total, err := s.postRepo.GetUserPosts(ctx, user.ID, limit, offset)
+	posts, page, err := s.postRepo.GetUserPosts(ctx, user.ID, limit, offset, withCount)
 	if err != nil {
 		fmt.Printf("[Service.GetUserPostsByUsername] Repo error: %v\n", err)
-		return nil, 0, fmt.Errorf("failed to get user posts: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to get user posts: %w", err)
 	}
-	fmt.Printf("[Service.GetUserPostsByUsername] Found %d raw posts (total: %d)\n", len(posts), total)
+	fmt.Printf("[Service.GetUserPostsByUsername] Found %d raw posts (total: %d)\n", len(posts), page.Total)
 
 	// 3. Filter by visibility if viewer is not the owner
 	if viewerID != user.ID {
 		fmt.Printf("[Service.GetUserPostsByUsername] Filtering for public posts (Viewer %s != Owner %s)\n", viewerID, user.ID)
+		audience := s.privacy().audience(ctx, posts, viewerID)
 		filteredPosts := make([]models.Post, 0)
 		for _, post := range posts {
-			// Only show public posts to others
+			// Others see public posts, and close friends posts if they're on the list
 			// TODO: Handle "connections" visibility once implemented
-			if post.Visibility == "public" {
+			if audience.CanSee(post.UserID, viewerID, post.Visibility) {
 				filteredPosts = append(filteredPosts, post)
 			}
 		}
 		fmt.Printf("[Service.GetUserPostsByUsername] %d posts remains after filtering\n", len(filteredPosts))
+		page = page.Filtered(len(posts)-len(filteredPosts), len(filteredPosts))
 		posts = filteredPosts
 		// Note: total count will be slightly inaccurate for non-owners
 	} else {
 		fmt.Println("[Service.GetUserPostsByUsername] No filtering required (Owner is viewer)")
 	}
 
-	return posts, total, nil
+	return posts, page, nil
 }
 
 // GetPost retrieves a post with all data
@@ -199,6 +380,29 @@ func (s *Service) GetPost(ctx context.Context, postID, viewerID uuid.UUID) (*mod
 		return nil, err
 	}
 
+	if post.UserID != viewerID {
+		author, err := s.userRepo.GetUserByID(ctx, post.UserID)
+		if err != nil {
+			return nil, err
+		}
+		visible, err := s.privacy().canView(ctx, author, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			return nil, models.ErrPostNotFound
+		}
+
+		// Posts for an audience the viewer isn't in don't exist as far as they know
+		shared, err := s.privacy().canSeePost(ctx, post, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if !shared {
+			return nil, models.ErrPostNotFound
+		}
+	}
+
 	// Increment views (only if viewer is not the author)
 	if viewerID != post.UserID {
 		go s.postRepo.IncrementViews(context.Background(), postID, viewerID)
@@ -220,6 +424,9 @@ func (s *Service) GetArticleBySlug(ctx context.Context, slug string, viewerID uu
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
+	if shared, err := s.privacy().canSeePost(ctx, post, viewerID); err != nil || !shared {
+		return nil, fmt.Errorf("article not found: %w", models.ErrPostNotFound)
+	}
 
 	// Ensure article is attached (it should be from GetPost, but double-check)
 	if post.Article == nil {
@@ -232,16 +439,22 @@ func (s *Service) GetArticleBySlug(ctx context.Context, slug string, viewerID uu
 	return post, nil
 }
 
-// UpdatePost updates a post
-func (s *Service) UpdatePost(ctx context.Context, postID, userID uuid.UUID, updates *models.UpdatePostRequest) error {
+// UpdatePost updates a post and returns it as updated
+func (s *Service) UpdatePost(ctx context.Context, postID, userID uuid.UUID, updates *models.UpdatePostRequest) (*models.Post, error) {
 	// Verify ownership
 	post, err := s.postRepo.GetPostByID(ctx, postID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if post.UserID != userID {
-		return models.ErrUnauthorized
+		return nil, models.ErrUnauthorized
+	}
+
+	if updates.Content != nil {
+		if err := s.checkLinks(ctx, userID, *updates.Content, linkPattern); err != nil {
+			return nil, err
+		}
 	}
 
 	// Build updates map
@@ -249,6 +462,11 @@ func (s *Service) UpdatePost(ctx context.Context, postID, userID uuid.UUID, upda
 
 	if updates.Content != nil {
 		updatesMap["content"] = *updates.Content
+		// An uncertain result clears the old language rather than keeping a stale one
+		updatesMap["language"] = nil
+		if language := s.detectLanguage(*updates.Content); language != "" {
+			updatesMap["language"] = language
+		}
 	}
 	if updates.Visibility != nil {
 		updatesMap["visibility"] = *updates.Visibility
@@ -263,14 +481,118 @@ func (s *Service) UpdatePost(ctx context.Context, postID, userID uuid.UUID, upda
 		updatesMap["is_nsfw"] = *updates.IsNSFW
 	}
 	if updates.IsPinned != nil {
+		if *updates.IsPinned && !post.IsPinned && s.maxPinned > 0 {
+			pinned, err := s.postRepo.CountPinnedPosts(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			if pinned >= s.maxPinned {
+				return nil, models.ErrTooManyPinnedPosts
+			}
+		}
 		updatesMap["is_pinned"] = *updates.IsPinned
 	}
 
 	if len(updatesMap) == 0 {
+		return post, nil
+	}
+
+	updated, err := s.postRepo.UpdatePost(ctx, postID, updatesMap)
+	if err != nil {
+		return nil, err
+	}
+
+	// None of the editable fields touch the author or type-specific data, so carry
+	// them over from the ownership read
+	updated.Author = post.Author
+	updated.Poll = post.Poll
+	updated.Article = post.Article
+	return updated, nil
+}
+
+// SaveDraft autosaves an unpublished draft without publishing it and returns the new
+// draft_version. A save carrying a version other than the stored one returns a
+// DraftConflictError, so two open editors can't silently overwrite each other.
+func (s *Service) SaveDraft(ctx context.Context, postID, userID uuid.UUID, req *models.SaveDraftRequest) (int, error) {
+	post, err := s.postRepo.GetPostByID(ctx, postID)
+	if err != nil {
+		return 0, models.ErrPostNotFound
+	}
+	if post.UserID != userID {
+		return 0, models.ErrUnauthorized
+	}
+	if !post.IsDraft || post.IsPublished {
+		return 0, models.ErrPostNotDraft
+	}
+	if *req.DraftVersion != post.DraftVersion {
+		return 0, &models.DraftConflictError{CurrentVersion: post.DraftVersion}
+	}
+
+	updates := make(map[string]interface{})
+	if req.Content != nil {
+		updates["content"] = *req.Content
+	}
+
+	// The version check and bump happen in one conditional update, so of two concurrent
+	// saves from the same version only one wins
+	saved, err := s.postRepo.UpdateDraft(ctx, postID, post.DraftVersion, updates)
+	if err != nil {
+		return 0, err
+	}
+	if !saved {
+		current := post.DraftVersion + 1
+		if latest, err := s.postRepo.GetPostByID(ctx, postID); err == nil {
+			current = latest.DraftVersion
+		}
+		return 0, &models.DraftConflictError{CurrentVersion: current}
+	}
+
+	if req.Article != nil && post.PostType == "article" {
+		if err := s.saveArticleDraft(ctx, postID, req.Article); err != nil {
+			return 0, err
+		}
+	}
+
+	return post.DraftVersion + 1, nil
+}
+
+// saveArticleDraft writes the article fields of a draft autosave
+func (s *Service) saveArticleDraft(ctx context.Context, postID uuid.UUID, req *models.UpdateArticleRequest) error {
+	article, err := s.articleRepo.GetArticleByPostID(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to get draft article: %w", err)
+	}
+
+	updates := make(map[string]interface{})
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Subtitle != nil {
+		updates["subtitle"] = *req.Subtitle
+	}
+	if req.ContentHTML != nil {
+		updates["content_html"] = *req.ContentHTML
+	}
+	if req.CoverImageURL != nil {
+		updates["cover_image_url"] = *req.CoverImageURL
+	}
+	if req.MetaTitle != nil {
+		updates["meta_title"] = *req.MetaTitle
+	}
+	if req.MetaDescription != nil {
+		updates["meta_description"] = *req.MetaDescription
+	}
+	if req.Category != nil {
+		updates["category"] = *req.Category
+	}
+	if len(updates) == 0 {
 		return nil
 	}
 
-	return s.postRepo.UpdatePost(ctx, postID, updatesMap)
+	if err := s.articleRepo.UpdateArticle(ctx, article.ID, updates); err != nil {
+		return fmt.Errorf("failed to save draft article: %w", err)
+	}
+	return nil
 }
 
 // DeletePost deletes a post
@@ -295,6 +617,128 @@ func (s *Service) UnrestrictPost(ctx context.Context, postID, userID uuid.UUID)
 	return s.postRepo.UnrestrictPost(ctx, postID, userID)
 }
 
+// ============================================
+// RETENTION
+// ============================================
+
+// PurgeDeletedPosts permanently deletes posts soft-deleted more than retention ago,
+// batchSize posts per transaction, and returns how many posts and media bytes were
+// removed. A post whose media can't be deleted is kept for the next run so its files
+// aren't orphaned.
+func (s *Service) PurgeDeletedPosts(ctx context.Context, retention time.Duration, batchSize int) (int, int64, error) {
+	cutoff := time.Now().Add(-retention)
+	purged, freed := 0, int64(0)
+
+	for ctx.Err() == nil {
+		batch, err := s.postRepo.GetPurgeablePosts(ctx, cutoff, batchSize)
+		if err != nil {
+			return purged, freed, err
+		}
+
+		ids := make([]uuid.UUID, 0, len(batch))
+		for i := range batch {
+			bytes, err := s.deletePostMedia(ctx, &batch[i])
+			if err != nil {
+				fmt.Printf("[Posts] Keeping post %s for the next purge, media delete failed: %v\n", batch[i].ID, err)
+				continue
+			}
+			freed += bytes
+			ids = append(ids, batch[i].ID)
+			if bytes > 0 && s.quota != nil {
+				if _, err := s.quota.RecordUsage(ctx, batch[i].UserID, -bytes); err != nil {
+					fmt.Printf("[Posts] Failed to release %d bytes of usage for %s: %v\n", bytes, batch[i].UserID, err)
+				}
+			}
+		}
+
+		count, err := s.postRepo.PurgePosts(ctx, ids)
+		if err != nil {
+			return purged, freed, err
+		}
+		purged += count
+
+		// A short batch is the last one; a batch where nothing could be purged would
+		// just come back again
+		if len(batch) < batchSize || count == 0 {
+			break
+		}
+	}
+
+	return purged, freed, ctx.Err()
+}
+
+// RecordMediaVariants saves the variants UploadImage generated for an image so a post
+// using it picks them up
+func (s *Service) RecordMediaVariants(ctx context.Context, userID uuid.UUID, mediaURL string, variants map[string]string) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	return s.postRepo.SaveMediaVariants(ctx, userID, mediaURL, variants)
+}
+
+// storedMediaVariants returns the variants UploadImage saved for each of the author's
+// media URLs, parallel to them, or nil when there are none. Clients never supply
+// variants, so they can't point a post's thumbnails at someone else's files.
+func (s *Service) storedMediaVariants(ctx context.Context, userID uuid.UUID, mediaURLs []string) []map[string]string {
+	if len(mediaURLs) == 0 {
+		return nil
+	}
+
+	saved, err := s.postRepo.GetMediaVariants(ctx, userID, mediaURLs)
+	if err != nil {
+		// Clients fall back to the full-size URLs
+		fmt.Printf("Warning: failed to get media variants: %v\n", err)
+		return nil
+	}
+	if len(saved) == 0 {
+		return nil
+	}
+
+	variants := make([]map[string]string, len(mediaURLs))
+	for i, url := range mediaURLs {
+		variants[i] = saved[url]
+	}
+	return variants
+}
+
+// deletePostMedia deletes the files behind a post's media and their resized variants,
+// returning their total size. URLs not issued by our storage are skipped.
+func (s *Service) deletePostMedia(ctx context.Context, post *models.Post) (int64, error) {
+	if s.objects == nil {
+		return 0, nil
+	}
+
+	urls := append([]string{}, post.MediaURLs...)
+	for _, sizes := range post.MediaVariants {
+		for _, url := range sizes {
+			urls = append(urls, url)
+		}
+	}
+
+	seen := make(map[string]bool, len(urls))
+	keys := make([]string, 0, len(urls))
+	var bytes int64
+	for _, url := range urls {
+		key, ok := s.objects.KeyFromURL(url)
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+		if obj, err := s.objects.GetMetadata(ctx, key); err == nil && obj != nil {
+			bytes += obj.Size
+		}
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if err := s.objects.DeleteMany(ctx, keys); err != nil {
+		return 0, err
+	}
+	return bytes, nil
+}
+
 // ============================================
 // ENGAGEMENT
 // ============================================
@@ -303,59 +747,189 @@ func (s *Service) UnrestrictPost(ctx context.Context, postID, userID uuid.UUID)
 // ENGAGEMENT
 // ============================================
 
-// LikePost likes a post
-func (s *Service) LikePost(ctx context.Context, postID, userID uuid.UUID) error {
-	if err := s.postRepo.LikePost(ctx, postID, userID); err != nil {
-		return err
+// LikePost likes a post and returns its new likes_count
+func (s *Service) LikePost(ctx context.Context, postID, userID uuid.UUID) (int, error) {
+	likesCount, err := s.postRepo.LikePost(ctx, postID, userID)
+	if err != nil {
+		return 0, err
 	}
 
-	// Get updated post
+	// Get post author for the like event
 	post, _ := s.postRepo.GetPostByID(ctx, postID)
 	if post != nil {
-		// Broadcast like event
-		s.broadcastPostLiked(postID, post.LikesCount, userID, post.UserID)
+		s.broadcastPostLiked(postID, likesCount, userID, post.UserID)
 	}
 
-	return nil
+	return likesCount, nil
 }
 
-// UnlikePost unlikes a post
-func (s *Service) UnlikePost(ctx context.Context, postID, userID uuid.UUID) error {
+// UnlikePost unlikes a post and returns its new likes_count and whether a like was removed
+func (s *Service) UnlikePost(ctx context.Context, postID, userID uuid.UUID) (int, bool, error) {
 	return s.postRepo.UnlikePost(ctx, postID, userID)
 }
 
-// SharePost shares a post
-func (s *Service) SharePost(ctx context.Context, postID, userID uuid.UUID, comment string) error {
-	if err := s.postRepo.SharePost(ctx, postID, userID, comment); err != nil {
-		return err
+// SharePost shares a post and returns its new shares_count and whether a share was
+// added. The author is only notified of a new share, not a repeated one.
+func (s *Service) SharePost(ctx context.Context, postID, userID uuid.UUID, comment string) (int, bool, error) {
+	sharesCount, changed, err := s.postRepo.SharePost(ctx, postID, userID, comment)
+	if err != nil {
+		return 0, false, err
 	}
 
-	// Get post to notify author
-	post, _ := s.postRepo.GetPostByID(ctx, postID)
-	if post != nil {
-		s.broadcastPostShared(postID, userID, post.UserID)
+	if changed {
+		// Get post to notify author
+		post, _ := s.postRepo.GetPostByID(ctx, postID)
+		if post != nil {
+			s.broadcastPostShared(postID, userID, post.UserID)
+		}
 	}
 
-	return nil
+	return sharesCount, changed, nil
 }
 
-// SavePost saves a post to collection
+// UnsharePost removes the user's share of a post, returning its new shares_count and
+// whether there was a share
+func (s *Service) UnsharePost(ctx context.Context, postID, userID uuid.UUID) (int, bool, error) {
+	return s.postRepo.UnsharePost(ctx, postID, userID)
+}
+
+// SavePost saves a post to collection, creating the collection if it doesn't exist yet
 func (s *Service) SavePost(ctx context.Context, postID, userID uuid.UUID, collection string) error {
+	if collection == "" {
+		collection = models.DefaultSavedCollection
+	}
+	collection, err := normalizeCollectionName(collection)
+	if err != nil {
+		return err
+	}
 	return s.postRepo.SavePost(ctx, postID, userID, collection)
 }
 
-// UnsavePost removes a post from saved
-func (s *Service) UnsavePost(ctx context.Context, postID, userID uuid.UUID) error {
+// UnsavePost removes a post from saved, reporting whether it was saved
+func (s *Service) UnsavePost(ctx context.Context, postID, userID uuid.UUID) (bool, error) {
 	return s.postRepo.UnsavePost(ctx, postID, userID)
 }
 
+// ============================================
+// SAVED COLLECTIONS
+// ============================================
+
+// maxCollectionNameLength matches saved_collections.name
+const maxCollectionNameLength = 50
+
+// normalizeCollectionName trims surrounding whitespace and checks the name fits
+func normalizeCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxCollectionNameLength {
+		return "", models.ErrCollectionName
+	}
+	return name, nil
+}
+
+// GetSavedCollections lists the user's bookmark collections with post counts
+func (s *Service) GetSavedCollections(ctx context.Context, userID uuid.UUID) ([]models.SavedCollection, error) {
+	return s.postRepo.GetSavedCollections(ctx, userID)
+}
+
+// CreateSavedCollection creates an empty bookmark collection
+func (s *Service) CreateSavedCollection(ctx context.Context, userID uuid.UUID, name string) (string, error) {
+	name, err := normalizeCollectionName(name)
+	if err != nil {
+		return "", err
+	}
+	if err := s.postRepo.CreateSavedCollection(ctx, userID, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// RenameSavedCollection renames one of the user's collections, keeping its posts
+func (s *Service) RenameSavedCollection(ctx context.Context, userID uuid.UUID, name, newName string) (string, error) {
+	if err := s.checkCollectionEditable(ctx, userID, name); err != nil {
+		return "", err
+	}
+
+	newName, err := normalizeCollectionName(newName)
+	if err != nil {
+		return "", err
+	}
+	if newName == name {
+		return name, nil
+	}
+
+	if err := s.postRepo.RenameSavedCollection(ctx, userID, name, newName); err != nil {
+		return "", err
+	}
+	return newName, nil
+}
+
+// DeleteSavedCollection deletes one of the user's collections. Its posts move to the
+// default collection unless unsave is set, in which case they're unsaved.
+func (s *Service) DeleteSavedCollection(ctx context.Context, userID uuid.UUID, name string, unsave bool) error {
+	if err := s.checkCollectionEditable(ctx, userID, name); err != nil {
+		return err
+	}
+	return s.postRepo.DeleteSavedCollection(ctx, userID, name, unsave)
+}
+
+// checkCollectionEditable rejects the default collection and names the user doesn't have
+func (s *Service) checkCollectionEditable(ctx context.Context, userID uuid.UUID, name string) error {
+	if name == models.DefaultSavedCollection {
+		return models.ErrDefaultCollection
+	}
+
+	exists, err := s.postRepo.SavedCollectionExists(ctx, userID, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return models.ErrCollectionNotFound
+	}
+	return nil
+}
+
 // ============================================
 // POLLS
 // ============================================
 
 // VotePoll votes on a poll
 func (s *Service) VotePoll(ctx context.Context, pollID, optionID, userID uuid.UUID) error {
-	if err := s.pollRepo.VotePoll(ctx, pollID, optionID, userID); err != nil {
+	poll, err := s.pollRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		return err
+	}
+	// The close job runs periodically, so a poll past its end time may not be flagged yet
+	if poll.IsClosed || !time.Now().Before(poll.EndsAt) {
+		return apperr.ErrPollClosed
+	}
+
+	if !pollHasOption(poll, optionID) {
+		return apperr.ErrPollInvalidOption
+	}
+
+	existing, err := s.pollRepo.GetUserVotes(ctx, pollID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get existing votes: %w", err)
+	}
+
+	switch {
+	case len(existing) == 0:
+		err = s.pollRepo.VotePoll(ctx, pollID, optionID, userID)
+	case poll.AllowMultipleVotes:
+		// One vote per option; vote changes don't apply since earlier votes are kept
+		if containsUUID(existing, optionID) {
+			return apperr.ErrPollOptionAlreadyVoted
+		}
+		err = s.pollRepo.VotePoll(ctx, pollID, optionID, userID)
+	case containsUUID(existing, optionID):
+		// Re-submitting the current vote changes nothing
+		return nil
+	case poll.AllowVoteChanges:
+		err = s.pollRepo.ChangeVote(ctx, pollID, optionID, userID)
+	default:
+		return apperr.ErrPollAlreadyVoted
+	}
+	if err != nil {
 		return err
 	}
 
@@ -368,11 +942,55 @@ func (s *Service) VotePoll(ctx context.Context, pollID, optionID, userID uuid.UU
 	return nil
 }
 
+// pollHasOption reports whether optionID is one of the poll's options
+func pollHasOption(poll *models.Poll, optionID uuid.UUID) bool {
+	for _, option := range poll.Options {
+		if option.ID == optionID {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseExpiredPolls closes all polls past their end time and sends the final results
+// to each poll's author and voters. Returns the number of polls closed.
+func (s *Service) CloseExpiredPolls(ctx context.Context) (int, error) {
+	polls, err := s.pollRepo.CloseExpiredPolls(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, poll := range polls {
+		results, err := s.pollRepo.GetPollResults(ctx, poll.ID, uuid.Nil)
+		if err != nil {
+			fmt.Printf("[Posts] Failed to load final results for poll %s: %v\n", poll.ID, err)
+			continue
+		}
+		s.broadcastPollClosed(ctx, &poll, results)
+	}
+
+	return len(polls), nil
+}
+
 // GetPollResults retrieves poll results
 func (s *Service) GetPollResults(ctx context.Context, pollID, viewerID uuid.UUID) (*models.PollResults, error) {
 	return s.pollRepo.GetPollResults(ctx, pollID, viewerID)
 }
 
+// GetPollResultsBatch retrieves results for several polls at once, e.g. for polls in a feed page
+func (s *Service) GetPollResultsBatch(ctx context.Context, pollIDs []uuid.UUID, viewerID uuid.UUID) ([]models.PollResults, error) {
+	return s.pollRepo.GetPollResultsBatch(ctx, pollIDs, viewerID)
+}
+
 // ============================================
 // COMMENTS
 // ============================================
@@ -383,6 +1001,16 @@ func (s *Service) CreateComment(ctx context.Context, req *models.CreateCommentRe
 		return nil, err
 	}
 
+	if err := s.checkLinks(ctx, userID, req.Content, linkPattern); err != nil {
+		return nil, err
+	}
+
+	if req.ParentCommentID != nil {
+		if err := s.checkReplyDepth(ctx, req.PostID, *req.ParentCommentID); err != nil {
+			return nil, err
+		}
+	}
+
 	comment := &models.Comment{
 		PostID:          req.PostID,
 		UserID:          userID,
@@ -390,6 +1018,7 @@ func (s *Service) CreateComment(ctx context.Context, req *models.CreateCommentRe
 		Content:         req.Content,
 		MediaURL:        req.MediaURL,
 		MediaType:       req.MediaType,
+		Language:        s.detectLanguage(req.Content),
 	}
 
 	if err := s.commentRepo.CreateComment(ctx, comment); err != nil {
@@ -402,12 +1031,56 @@ func (s *Service) CreateComment(ctx context.Context, req *models.CreateCommentRe
 		s.broadcastNewComment(comment, post.UserID)
 	}
 
+	// Notify users mentioned in the comment
+	if mentionedIDs, err := s.postRepo.ResolveMentions(ctx, comment.Content); err != nil {
+		fmt.Printf("Warning: failed to resolve comment mentions: %v\n", err)
+	} else {
+		s.notifyMentions(userID, mentionedIDs, comment.PostID, &comment.ID, comment.Content)
+	}
+
 	return comment, nil
 }
 
-// GetComments retrieves comments for a post
-func (s *Service) GetComments(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.Comment, int, error) {
-	return s.commentRepo.GetComments(ctx, postID, limit, offset)
+// defaultCommentDepth is how many levels a comment thread has unless configured:
+// top-level comments and their replies
+const defaultCommentDepth = 2
+
+// checkReplyDepth walks up from parentID and refuses the reply with ErrCommentTooDeep
+// if it would sit deeper than maxDepth
+func (s *Service) checkReplyDepth(ctx context.Context, postID, parentID uuid.UUID) error {
+	// The reply's own level plus each ancestor's
+	depth := 1
+	for {
+		parent, err := s.commentRepo.GetComment(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		if parent.PostID != postID {
+			return models.ErrParentCommentPost
+		}
+		depth++
+		if depth > s.maxDepth {
+			return models.ErrCommentTooDeep
+		}
+		if parent.ParentCommentID == nil {
+			return nil
+		}
+		parentID = *parent.ParentCommentID
+	}
+}
+
+// GetComments retrieves top-level comments for a post, each with up to
+// previewReplies of its replies inline
+func (s *Service) GetComments(ctx context.Context, postID uuid.UUID, limit, offset, previewReplies int) ([]models.Comment, int, error) {
+	return s.commentRepo.GetComments(ctx, postID, limit, offset, previewReplies)
+}
+
+// GetCommentReplies retrieves the replies in a comment's thread
+func (s *Service) GetCommentReplies(ctx context.Context, commentID uuid.UUID, limit, offset int) ([]models.Comment, int, error) {
+	if _, err := s.commentRepo.GetComment(ctx, commentID); err != nil {
+		return nil, 0, err
+	}
+	return s.commentRepo.GetCommentReplies(ctx, commentID, limit, offset)
 }
 
 // UpdateComment updates a comment
@@ -422,6 +1095,10 @@ func (s *Service) UpdateComment(ctx context.Context, commentID uuid.UUID, conten
 		return nil, fmt.Errorf("unauthorized: you can only edit your own comments")
 	}
 
+	if err := s.checkLinks(ctx, userID, content, linkPattern); err != nil {
+		return nil, err
+	}
+
 	// Update comment
 	if err := s.commentRepo.UpdateComment(ctx, commentID, content); err != nil {
 		return nil, fmt.Errorf("failed to update comment: %w", err)
@@ -538,6 +1215,99 @@ func (s *Service) broadcastPollVote(pollID uuid.UUID, results *models.PollResult
 	fmt.Printf("[Posts] Poll vote recorded: %s\n", pollID)
 }
 
+func (s *Service) broadcastPollClosed(ctx context.Context, poll *models.Poll, results *models.PollResults) {
+	if s.wsManager == nil {
+		return
+	}
+
+	recipients, err := s.pollRepo.GetPollVoterIDs(ctx, poll.ID)
+	if err != nil {
+		fmt.Printf("[Posts] Failed to load voters for poll %s: %v\n", poll.ID, err)
+	}
+	if post, err := s.postRepo.GetPost(ctx, poll.PostID, uuid.Nil); err == nil {
+		recipients = append(recipients, post.UserID)
+	}
+
+	event := map[string]interface{}{
+		"type":      "poll_closed",
+		"poll_id":   poll.ID,
+		"post_id":   poll.PostID,
+		"closed_at": poll.ClosedAt,
+		"results":   results,
+	}
+	sent := make(map[uuid.UUID]bool, len(recipients))
+	for _, userID := range recipients {
+		if !sent[userID] {
+			sent[userID] = true
+			s.wsManager.BroadcastToUserWithData(userID, event)
+		}
+	}
+}
+
+// ============================================
+// MENTION NOTIFICATIONS
+// ============================================
+
+// notifyMentions sends one mention notification per mentioned user in the background.
+// Self-mentions are skipped, as are users who blocked or restricted the author and users
+// the author has blocked.
+func (s *Service) notifyMentions(actorID uuid.UUID, mentionedIDs []uuid.UUID, postID uuid.UUID, commentID *uuid.UUID, content string) {
+	if s.notifService == nil || len(mentionedIDs) == 0 {
+		return
+	}
+
+	go func() {
+		bgCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		actor, err := s.userRepo.GetUserByID(bgCtx, actorID)
+		if err != nil {
+			fmt.Printf("Warning: failed to load mention author %s: %v\n", actorID, err)
+			return
+		}
+		snippet := mentionSnippet(content)
+
+		notified := make(map[uuid.UUID]bool, len(mentionedIDs))
+		for _, mentionedID := range mentionedIDs {
+			if mentionedID == actorID || notified[mentionedID] {
+				continue
+			}
+			notified[mentionedID] = true
+
+			if !s.canNotifyMention(bgCtx, actorID, mentionedID) {
+				continue
+			}
+			if err := s.notifService.CreateMentionNotification(bgCtx, actorID, mentionedID, actor.Username, postID, commentID, snippet); err != nil {
+				fmt.Printf("Warning: failed to send mention notification to %s: %v\n", mentionedID, err)
+			}
+		}
+	}()
+}
+
+// canNotifyMention reports whether no block or restriction stands between the author and
+// the mentioned user. Lookup failures suppress the notification.
+func (s *Service) canNotifyMention(ctx context.Context, actorID, mentionedID uuid.UUID) bool {
+	if blocked, err := s.userRepo.IsUserBlocked(ctx, mentionedID, actorID); err != nil || blocked {
+		return false
+	}
+	if blocked, err := s.userRepo.IsUserBlocked(ctx, actorID, mentionedID); err != nil || blocked {
+		return false
+	}
+	if restricted, err := s.userRepo.IsUserRestricted(ctx, mentionedID, actorID); err != nil || restricted {
+		return false
+	}
+	return true
+}
+
+// mentionSnippet trims content to mentionSnippetLength characters for notification payloads
+func mentionSnippet(content string) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= mentionSnippetLength {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:mentionSnippetLength])) + "…"
+}
+
 // GetUserLikedPosts retrieves all posts that a user has liked
 func (s *Service) GetUserLikedPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
 	return s.postRepo.GetUserLikedPosts(ctx, userID, limit, offset)
@@ -0,0 +1,362 @@
+package posts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+func TestCreatePostSchedulesInAuthorsTimezone(t *testing.T) {
+	author := &models.User{ID: uuid.New(), Timezone: "America/New_York"}
+	posts := &fakePostRepo{}
+	svc := NewService(posts, nil, nil, nil, &fakeUserRepo{users: map[uuid.UUID]*models.User{author.ID: author}}, nil)
+
+	day := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+	post, err := svc.CreatePost(context.Background(), &models.CreatePostRequest{
+		PostType:    "post",
+		Content:     "Good morning",
+		Visibility:  models.VisibilityPublic,
+		ScheduledAt: ptr(day + "T09:00"),
+	}, author.ID)
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	if post.IsPublished || post.PublishedAt != nil {
+		t.Error("a scheduled post shouldn't be published yet")
+	}
+	newYork, _ := time.LoadLocation("America/New_York")
+	want, _ := time.ParseInLocation("2006-01-02T15:04", day+"T09:00", newYork)
+	if stored := posts.created[0].ScheduledAt; stored == nil || !stored.Equal(want) {
+		t.Errorf("stored %v, want %v", stored, want.UTC())
+	}
+	if post.ScheduledAt.Location().String() != "America/New_York" || post.ScheduledAt.Hour() != 9 {
+		t.Errorf("returned %v, want 09:00 in the author's zone", post.ScheduledAt)
+	}
+}
+
+func TestCreatePostTakesTheVariantsSavedOnUpload(t *testing.T) {
+	author, other := uuid.New(), uuid.New()
+	posts := &fakePostRepo{}
+	svc := NewService(posts, nil, nil, nil, &fakeUserRepo{}, nil)
+	ctx := context.Background()
+
+	public := "https://storage.example/storage/v1/object/public/media/posts/" + author.String() + "/"
+	// The first image got a 320px variant on upload, the second none
+	if err := svc.RecordMediaVariants(ctx, author, public+"a.jpeg", map[string]string{"320": public + "a_320.webp"}); err != nil {
+		t.Fatalf("RecordMediaVariants: %v", err)
+	}
+	if err := svc.RecordMediaVariants(ctx, author, public+"b.jpeg", map[string]string{}); err != nil {
+		t.Fatalf("RecordMediaVariants: %v", err)
+	}
+
+	req := &models.CreatePostRequest{
+		PostType:   "post",
+		Content:    "Two photos",
+		Visibility: models.VisibilityPublic,
+		MediaURLs:  []string{public + "a.jpeg", public + "b.jpeg"},
+		MediaTypes: []string{"image", "image"},
+	}
+	if _, err := svc.CreatePost(ctx, req, author); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	variants := posts.created[0].MediaVariants
+	if len(variants) != 2 || variants[0]["320"] != public+"a_320.webp" || len(variants[1]) != 0 {
+		t.Errorf("variants = %v, want a's 320px variant only", variants)
+	}
+	if posts.variantLookups != 1 {
+		t.Errorf("looked variants up %d times, want once for the whole post", posts.variantLookups)
+	}
+
+	// Someone else posting the same URLs doesn't get the author's variants
+	if _, err := svc.CreatePost(ctx, req, other); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if variants := posts.created[1].MediaVariants; variants != nil {
+		t.Errorf("another user's post got variants %v", variants)
+	}
+}
+
+func TestCreatePostScheduleRejections(t *testing.T) {
+	svc := NewService(&fakePostRepo{}, nil, nil, nil, &fakeUserRepo{}, nil)
+	tests := []struct {
+		name string
+		req  models.CreatePostRequest
+	}{
+		{"in the past", models.CreatePostRequest{ScheduledAt: ptr("2020-01-01T09:00"), Timezone: "UTC"}},
+		{"unknown timezone", models.CreatePostRequest{ScheduledAt: ptr("2099-01-01T09:00"), Timezone: "Mars/Olympus"}},
+		{"draft", models.CreatePostRequest{ScheduledAt: ptr("2099-01-01T09:00"), IsDraft: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.PostType, tt.req.Content = "post", "Hello"
+			if _, err := svc.CreatePost(context.Background(), &tt.req, uuid.New()); err == nil {
+				t.Error("expected the schedule to be rejected")
+			}
+		})
+	}
+}
+
+func TestPublishScheduledPostsOnlyPublishesDuePosts(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	posts := &fakePostRepo{due: []models.Post{
+		{ID: uuid.New(), ScheduledAt: &past},
+		{ID: uuid.New(), ScheduledAt: &future},
+	}}
+	svc := NewService(posts, nil, nil, nil, &fakeUserRepo{}, nil)
+
+	published, err := svc.PublishScheduledPosts(context.Background(), 10)
+	if err != nil || published != 1 {
+		t.Fatalf("PublishScheduledPosts = %d, %v; want 1", published, err)
+	}
+	if published, _ := svc.PublishScheduledPosts(context.Background(), 10); published != 0 {
+		t.Errorf("published %d posts again, want 0", published)
+	}
+}
+
+func TestVotePollRules(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	voter := uuid.New()
+	open := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		poll        models.Poll
+		earlier     []uuid.UUID // Options the voter already picked
+		vote        uuid.UUID
+		wantErr     error
+		wantVotes   []uuid.UUID
+		wantChanges int
+	}{
+		{"first vote", models.Poll{EndsAt: open}, nil, a, nil, []uuid.UUID{a}, 0},
+		{"closed", models.Poll{EndsAt: open, IsClosed: true}, nil, a, apperr.ErrPollClosed, nil, 0},
+		{"past its end", models.Poll{EndsAt: time.Now().Add(-time.Minute)}, nil, a, apperr.ErrPollClosed, nil, 0},
+		{"unknown option", models.Poll{EndsAt: open}, nil, uuid.New(), apperr.ErrPollInvalidOption, nil, 0},
+		{"second vote", models.Poll{EndsAt: open}, []uuid.UUID{a}, b, apperr.ErrPollAlreadyVoted, []uuid.UUID{a}, 0},
+		{"same vote again", models.Poll{EndsAt: open}, []uuid.UUID{a}, a, nil, []uuid.UUID{a}, 0},
+		{"changed vote", models.Poll{EndsAt: open, AllowVoteChanges: true}, []uuid.UUID{a}, b, nil, []uuid.UUID{b}, 1},
+		{"another option", models.Poll{EndsAt: open, AllowMultipleVotes: true}, []uuid.UUID{a}, b, nil, []uuid.UUID{a, b}, 0},
+		{"same option twice", models.Poll{EndsAt: open, AllowMultipleVotes: true}, []uuid.UUID{a}, a, apperr.ErrPollOptionAlreadyVoted, []uuid.UUID{a}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll := tt.poll
+			poll.ID = uuid.New()
+			poll.Options = []models.PollOption{{ID: a}, {ID: b}}
+			polls := &fakePollRepo{poll: &poll, votes: map[uuid.UUID][]uuid.UUID{}}
+			if tt.earlier != nil {
+				polls.votes[voter] = tt.earlier
+			}
+			svc := NewService(&fakePostRepo{}, polls, nil, nil, &fakeUserRepo{}, nil)
+
+			if err := svc.VotePoll(context.Background(), poll.ID, tt.vote, voter); err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			got := polls.votes[voter]
+			if len(got) != len(tt.wantVotes) {
+				t.Fatalf("votes = %v, want %v", got, tt.wantVotes)
+			}
+			for i := range got {
+				if got[i] != tt.wantVotes[i] {
+					t.Fatalf("votes = %v, want %v", got, tt.wantVotes)
+				}
+			}
+			if polls.changes != tt.wantChanges {
+				t.Errorf("changed the vote %d times, want %d", polls.changes, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestExpiredPollIsClosedAndRefusesVotes(t *testing.T) {
+	option := uuid.New()
+	poll := &models.Poll{ID: uuid.New(), EndsAt: time.Now().Add(-time.Second), Options: []models.PollOption{{ID: option}}}
+	polls := &fakePollRepo{poll: poll, votes: map[uuid.UUID][]uuid.UUID{}}
+	svc := NewService(&fakePostRepo{}, polls, nil, nil, &fakeUserRepo{}, nil)
+	voter := uuid.New()
+
+	// Before the job runs, the end time alone refuses the vote
+	if err := svc.VotePoll(context.Background(), poll.ID, option, voter); err != apperr.ErrPollClosed {
+		t.Fatalf("vote after ends_at: err = %v, want ErrPollClosed", err)
+	}
+
+	closed, err := svc.CloseExpiredPolls(context.Background())
+	if err != nil || closed != 1 {
+		t.Fatalf("CloseExpiredPolls = %d, %v; want 1, nil", closed, err)
+	}
+	if !poll.IsClosed || poll.ClosedAt == nil {
+		t.Error("expired poll should be closed with a closed_at")
+	}
+	if closed, _ := svc.CloseExpiredPolls(context.Background()); closed != 0 {
+		t.Errorf("second run closed %d polls, want 0", closed)
+	}
+
+	if err := svc.VotePoll(context.Background(), poll.ID, option, voter); err != apperr.ErrPollClosed {
+		t.Errorf("vote on closed poll: err = %v, want ErrPollClosed", err)
+	}
+	if len(polls.votes[voter]) != 0 {
+		t.Errorf("votes recorded on an expired poll: %v", polls.votes[voter])
+	}
+}
+
+// fixedLanguage is a LanguageDetector that always answers the same
+type fixedLanguage string
+
+func (l fixedLanguage) Detect(text string) string {
+	return string(l)
+}
+
+func TestCreatePostTagsDetectedLanguage(t *testing.T) {
+	author := &models.User{ID: uuid.New()}
+	posts := &fakePostRepo{}
+	svc := NewService(posts, nil, nil, nil, &fakeUserRepo{users: map[uuid.UUID]*models.User{author.ID: author}}, nil)
+	svc.SetLanguageDetector(fixedLanguage("pt"))
+
+	if _, err := svc.CreatePost(context.Background(), &models.CreatePostRequest{
+		PostType:   "post",
+		Content:    "Bom dia",
+		Visibility: models.VisibilityPublic,
+	}, author.ID); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if got := posts.created[0].Language; got != "pt" {
+		t.Errorf("stored language %q, want the detector's pt", got)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestPinnedPostCap(t *testing.T) {
+	author := uuid.New()
+	repo := &fakePostRepo{posts: map[uuid.UUID]*models.Post{}}
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		post := &models.Post{ID: uuid.New(), UserID: author}
+		repo.posts[post.ID] = post
+		ids = append(ids, post.ID)
+	}
+	svc := NewService(repo, nil, nil, nil, &fakeUserRepo{}, nil)
+	svc.SetMaxPinnedPosts(2)
+	ctx := context.Background()
+	pin := &models.UpdatePostRequest{IsPinned: ptr(true)}
+
+	for _, id := range ids[:2] {
+		if _, err := svc.UpdatePost(ctx, id, author, pin); err != nil {
+			t.Fatalf("pinning under the cap: %v", err)
+		}
+	}
+	if _, err := svc.UpdatePost(ctx, ids[2], author, pin); err != models.ErrTooManyPinnedPosts {
+		t.Fatalf("err = %v, want ErrTooManyPinnedPosts", err)
+	}
+	if repo.posts[ids[2]].IsPinned {
+		t.Error("a post over the cap shouldn't be pinned")
+	}
+
+	// Already pinned posts can be saved again, and unpinning makes room
+	if _, err := svc.UpdatePost(ctx, ids[0], author, pin); err != nil {
+		t.Errorf("re-pinning a pinned post: %v", err)
+	}
+	if _, err := svc.UpdatePost(ctx, ids[0], author, &models.UpdatePostRequest{IsPinned: ptr(false)}); err != nil {
+		t.Fatalf("unpinning: %v", err)
+	}
+	if _, err := svc.UpdatePost(ctx, ids[2], author, pin); err != nil {
+		t.Errorf("pinning after unpinning: %v", err)
+	}
+}
+
+func TestCreateCommentRejectsOverDepthReplies(t *testing.T) {
+	postID, user := uuid.New(), uuid.New()
+	tests := []struct {
+		maxDepth int
+		parents  int // Ancestors of the reply: 0 is a top-level comment
+		wantErr  error
+	}{
+		{1, 0, nil},
+		{1, 1, models.ErrCommentTooDeep}, // Flat comments take no replies
+		{2, 1, nil},
+		{2, 2, models.ErrCommentTooDeep},
+		{3, 2, nil},
+	}
+
+	for _, tt := range tests {
+		comments := &fakeCommentRepo{comments: map[uuid.UUID]*models.Comment{}}
+		var parent *uuid.UUID
+		for i := 0; i < tt.parents; i++ {
+			comment := &models.Comment{ID: uuid.New(), PostID: postID, ParentCommentID: parent}
+			comments.comments[comment.ID] = comment
+			parent = &comment.ID
+		}
+		svc := NewService(&fakePostRepo{}, nil, nil, comments, &fakeUserRepo{}, nil)
+		svc.SetMaxCommentDepth(tt.maxDepth)
+
+		_, err := svc.CreateComment(context.Background(), &models.CreateCommentRequest{
+			PostID:          postID,
+			ParentCommentID: parent,
+			Content:         "Agreed",
+		}, user)
+		if err != tt.wantErr {
+			t.Errorf("depth %d under %d levels: err = %v, want %v", tt.parents+1, tt.maxDepth, err, tt.wantErr)
+		}
+		wantStored := tt.parents
+		if tt.wantErr == nil {
+			wantStored++
+		}
+		if len(comments.comments) != wantStored {
+			t.Errorf("depth %d under %d levels: %d comments stored, want %d", tt.parents+1, tt.maxDepth, len(comments.comments), wantStored)
+		}
+	}
+}
+
+func TestCreateCommentRejectsParentOnAnotherPost(t *testing.T) {
+	parent := &models.Comment{ID: uuid.New(), PostID: uuid.New()}
+	comments := &fakeCommentRepo{comments: map[uuid.UUID]*models.Comment{parent.ID: parent}}
+	svc := NewService(&fakePostRepo{}, nil, nil, comments, &fakeUserRepo{}, nil)
+
+	_, err := svc.CreateComment(context.Background(), &models.CreateCommentRequest{
+		PostID:          uuid.New(),
+		ParentCommentID: &parent.ID,
+		Content:         "Agreed",
+	}, uuid.New())
+	if err != models.ErrParentCommentPost {
+		t.Errorf("err = %v, want ErrParentCommentPost", err)
+	}
+}
+
+func TestNewAccountsCantPostLinks(t *testing.T) {
+	fresh := &models.User{ID: uuid.New(), CreatedAt: time.Now().Add(-time.Hour)}
+	matured := &models.User{ID: uuid.New(), CreatedAt: time.Now().Add(-30 * 24 * time.Hour)}
+	users := &fakeUserRepo{users: map[uuid.UUID]*models.User{fresh.ID: fresh, matured.ID: matured}}
+	svc := NewService(&fakePostRepo{}, nil, nil, nil, users, nil)
+	svc.SetNewAccountRestrictions(models.NewAccountRestrictions{Period: 7 * 24 * time.Hour})
+
+	tests := []struct {
+		name    string
+		user    uuid.UUID
+		content string
+		wantErr error
+	}{
+		{"fresh with a link", fresh.ID, "Look at https://spam.example", models.ErrNewAccountLinks},
+		{"fresh with www", fresh.ID, "Go to www.spam.example", models.ErrNewAccountLinks},
+		{"fresh without a link", fresh.ID, "Hello everyone", nil},
+		{"matured with a link", matured.ID, "Read https://blog.example", nil},
+	}
+
+	for _, tt := range tests {
+		_, err := svc.CreatePost(context.Background(), &models.CreatePostRequest{
+			PostType:   "post",
+			Content:    tt.content,
+			Visibility: models.VisibilityPublic,
+		}, tt.user)
+		if err != tt.wantErr {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
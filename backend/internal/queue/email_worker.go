@@ -15,10 +15,13 @@ type EmailSender interface {
 	SendWelcomeEmail(to, name string) error
 	SendVerificationEmail(to, code string) error
 	SendPasswordResetEmail(to, token string) error
+	SendMagicLinkEmail(to, token string) error
 	SendNotificationEmail(to, subject, body string) error
+	SendDigestEmail(to, subject, htmlBody, textBody string) error
 }
 
-// EmailWorker processes email jobs from the queue
+// EmailWorker processes jobs from the email queue. Its email handlers go in the
+// pool's registry like any other, so the email queue can carry other job types too.
 type EmailWorker struct {
 	pool   *WorkerPool
 	sender EmailSender
@@ -43,7 +46,9 @@ func NewEmailWorker(provider QueueProvider, sender EmailSender, workers int) *Em
 	pool.RegisterHandler(JobTypeEmailWelcome, worker.handleWelcome)
 	pool.RegisterHandler(JobTypeEmailVerification, worker.handleVerification)
 	pool.RegisterHandler(JobTypeEmailPasswordReset, worker.handlePasswordReset)
+	pool.RegisterHandler(JobTypeEmailMagicLink, worker.handleMagicLink)
 	pool.RegisterHandler(JobTypeEmailNotification, worker.handleNotification)
+	pool.RegisterHandler(JobTypeEmailDigest, worker.handleDigest)
 
 	return worker
 }
@@ -108,6 +113,21 @@ func (w *EmailWorker) handlePasswordReset(ctx context.Context, job *Job) error {
 	return w.sender.SendPasswordResetEmail(payload.To, payload.Token)
 }
 
+// handleMagicLink handles login link email jobs
+func (w *EmailWorker) handleMagicLink(ctx context.Context, job *Job) error {
+	var payload struct {
+		To    string `json:"to"`
+		Token string `json:"token"`
+	}
+
+	if err := job.UnmarshalPayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	log.Printf("[EmailWorker] Sending login link email to: %s", payload.To)
+	return w.sender.SendMagicLinkEmail(payload.To, payload.Token)
+}
+
 // handleNotification handles notification email jobs
 func (w *EmailWorker) handleNotification(ctx context.Context, job *Job) error {
 	var payload EmailJobPayload
@@ -129,6 +149,18 @@ func (w *EmailWorker) handleNotification(ctx context.Context, job *Job) error {
 	return w.sender.SendNotificationEmail(payload.To, payload.Subject, body)
 }
 
+// handleDigest handles notification digest email jobs
+func (w *EmailWorker) handleDigest(ctx context.Context, job *Job) error {
+	var payload EmailJobPayload
+
+	if err := job.UnmarshalPayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	log.Printf("[EmailWorker] Sending digest email to: %s", payload.To)
+	return w.sender.SendDigestEmail(payload.To, payload.Subject, payload.Data["html"], payload.Data["text"])
+}
+
 // ============================================
 // QUEUE HELPER FUNCTIONS
 // ============================================
@@ -146,12 +178,12 @@ func QueueWelcomeEmail(ctx context.Context, provider QueueProvider, to, name str
 	return provider.Enqueue(ctx, QueueEmail, job)
 }
 
-// QueueVerificationEmail queues a verification email
+// QueueVerificationEmail queues a verification email, ahead of other emails
 func QueueVerificationEmail(ctx context.Context, provider QueueProvider, to, code string) error {
-	job, err := NewJob(JobTypeEmailVerification, map[string]string{
+	job, err := NewJobWithPriority(JobTypeEmailVerification, map[string]string{
 		"to":   to,
 		"code": code,
-	})
+	}, PriorityHigh)
 	if err != nil {
 		return err
 	}
@@ -159,9 +191,22 @@ func QueueVerificationEmail(ctx context.Context, provider QueueProvider, to, cod
 	return provider.Enqueue(ctx, QueueEmail, job)
 }
 
-// QueuePasswordResetEmail queues a password reset email
+// QueuePasswordResetEmail queues a password reset email, ahead of other emails
 func QueuePasswordResetEmail(ctx context.Context, provider QueueProvider, to, token string) error {
-	job, err := NewJob(JobTypeEmailPasswordReset, map[string]string{
+	job, err := NewJobWithPriority(JobTypeEmailPasswordReset, map[string]string{
+		"to":    to,
+		"token": token,
+	}, PriorityHigh)
+	if err != nil {
+		return err
+	}
+
+	return provider.Enqueue(ctx, QueueEmail, job)
+}
+
+// QueueMagicLinkEmail queues a passwordless login link email
+func QueueMagicLinkEmail(ctx context.Context, provider QueueProvider, to, token string) error {
+	job, err := NewJob(JobTypeEmailMagicLink, map[string]string{
 		"to":    to,
 		"token": token,
 	})
@@ -187,3 +232,19 @@ func QueueNotificationEmail(ctx context.Context, provider QueueProvider, to, sub
 
 	return provider.Enqueue(ctx, QueueEmail, job)
 }
+
+// QueueDigestEmail queues a notification digest email, already rendered
+func QueueDigestEmail(ctx context.Context, provider QueueProvider, to, subject, htmlBody, textBody string) error {
+	payload := EmailJobPayload{
+		To:      to,
+		Subject: subject,
+		Data:    map[string]string{"html": htmlBody, "text": textBody},
+	}
+
+	job, err := NewJob(JobTypeEmailDigest, payload)
+	if err != nil {
+		return err
+	}
+
+	return provider.Enqueue(ctx, QueueEmail, job)
+}
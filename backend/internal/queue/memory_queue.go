@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"sync"
@@ -11,109 +12,177 @@ import (
 // IN-MEMORY QUEUE PROVIDER
 // ============================================
 
-// MemoryQueueProvider implements QueueProvider using in-memory channels
+// MemoryQueueProvider implements QueueProvider using in-memory priority heaps
 // Suitable for development and single-server deployments
 type MemoryQueueProvider struct {
-	queues      map[string]chan *Job
-	deadLetters map[string][]*deadLetterEntry
+	queues      map[string]*memoryQueue
+	deadLetters map[string][]*DeadLetter
+	inFlight    map[string]int64 // Dequeued, not yet acknowledged or rejected
+	activity    *activityLog
 	mu          sync.RWMutex
 	closed      bool
 }
 
-type deadLetterEntry struct {
-	Job       *Job
-	Reason    string
-	FailedAt  time.Time
+// memoryQueueCapacity is how many jobs a queue holds before Enqueue fails
+const memoryQueueCapacity = 10000
+
+// memoryQueue is one queue's jobs, highest priority first and oldest first within a
+// priority. ready holds a token while there may be jobs to take.
+type memoryQueue struct {
+	jobs  jobHeap
+	seq   uint64
+	ready chan struct{}
+}
+
+type queuedJob struct {
+	job        *Job
+	seq        uint64
+	enqueuedAt time.Time
+}
+
+// jobHeap implements heap.Interface
+type jobHeap []queuedJob
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].job.Priority != h[j].job.Priority {
+		return h[i].job.Priority > h[j].job.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h jobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(queuedJob)) }
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
 }
 
 // NewMemoryQueueProvider creates a new in-memory queue provider
 func NewMemoryQueueProvider() *MemoryQueueProvider {
 	return &MemoryQueueProvider{
-		queues:      make(map[string]chan *Job),
-		deadLetters: make(map[string][]*deadLetterEntry),
+		queues:      make(map[string]*memoryQueue),
+		deadLetters: make(map[string][]*DeadLetter),
+		inFlight:    make(map[string]int64),
+		activity:    newActivityLog(),
 	}
 }
 
-// getOrCreateQueue gets or creates a queue channel
-func (mq *MemoryQueueProvider) getOrCreateQueue(queueName string) chan *Job {
-	mq.mu.Lock()
-	defer mq.mu.Unlock()
-
+// getOrCreateQueue gets or creates a queue; the caller holds mq.mu
+func (mq *MemoryQueueProvider) getOrCreateQueue(queueName string) *memoryQueue {
 	if queue, ok := mq.queues[queueName]; ok {
 		return queue
 	}
 
-	// Create buffered channel with capacity 10000
-	queue := make(chan *Job, 10000)
+	queue := &memoryQueue{ready: make(chan struct{}, 1)}
 	mq.queues[queueName] = queue
 	return queue
 }
 
+// signal wakes one waiting Dequeue, if any
+func (q *memoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
 // Enqueue adds a job to the queue
 func (mq *MemoryQueueProvider) Enqueue(ctx context.Context, queueName string, job *Job) error {
-	mq.mu.RLock()
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
 	if mq.closed {
-		mq.mu.RUnlock()
 		return fmt.Errorf("queue is closed")
 	}
-	mq.mu.RUnlock()
 
 	queue := mq.getOrCreateQueue(queueName)
-
-	select {
-	case queue <- job:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
+	if queue.jobs.Len() >= memoryQueueCapacity {
 		return fmt.Errorf("queue is full")
 	}
+
+	queue.seq++
+	heap.Push(&queue.jobs, queuedJob{job: job, seq: queue.seq, enqueuedAt: time.Now()})
+	queue.signal()
+	return nil
 }
 
-// Dequeue retrieves a job from the queue
+// Dequeue retrieves the highest priority job from the queue
 func (mq *MemoryQueueProvider) Dequeue(ctx context.Context, queueName string, timeout time.Duration) (*Job, error) {
-	mq.mu.RLock()
-	if mq.closed {
-		mq.mu.RUnlock()
-		return nil, fmt.Errorf("queue is closed")
-	}
-	mq.mu.RUnlock()
-
-	queue := mq.getOrCreateQueue(queueName)
-
 	// Create timeout context
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	select {
-	case job := <-queue:
-		job.SetMetadata("dequeued_at", time.Now().Format(time.RFC3339))
-		return job, nil
-	case <-timeoutCtx.Done():
-		return nil, nil // Timeout - no job available
+	for {
+		mq.mu.Lock()
+		if mq.closed {
+			mq.mu.Unlock()
+			return nil, fmt.Errorf("queue is closed")
+		}
+		queue := mq.getOrCreateQueue(queueName)
+		if queue.jobs.Len() > 0 {
+			queued := heap.Pop(&queue.jobs).(queuedJob)
+			if queue.jobs.Len() > 0 {
+				// Pass the wakeup on to the next waiting Dequeue
+				queue.signal()
+			}
+			mq.inFlight[queueName]++
+			mq.mu.Unlock()
+
+			mq.activity.dequeued(queueName, time.Since(queued.enqueuedAt))
+			job := queued.job
+			job.SetMetadata("dequeued_at", time.Now().Format(time.RFC3339))
+			return job, nil
+		}
+		mq.mu.Unlock()
+
+		select {
+		case <-queue.ready:
+		case <-timeoutCtx.Done():
+			return nil, nil // Timeout - no job available
+		}
 	}
 }
 
-// Acknowledge marks a job as successfully processed (no-op for in-memory)
-func (mq *MemoryQueueProvider) Acknowledge(ctx context.Context, queueName string, jobID string) error {
-	// In-memory queue doesn't need acknowledgment
+// Acknowledge marks a job as successfully processed. The job already left the queue
+// when it was dequeued, so this only counts it.
+func (mq *MemoryQueueProvider) Acknowledge(ctx context.Context, queueName string, job *Job) error {
+	mq.mu.Lock()
+	mq.finish(queueName)
+	mq.mu.Unlock()
+
+	mq.activity.processed(queueName)
 	return nil
 }
 
-// Reject marks a job as failed
-func (mq *MemoryQueueProvider) Reject(ctx context.Context, queueName string, jobID string, reason string) error {
+// finish counts a dequeued job as no longer in flight; the caller holds mq.mu
+func (mq *MemoryQueueProvider) finish(queueName string) {
+	if mq.inFlight[queueName] > 0 {
+		mq.inFlight[queueName]--
+	}
+}
+
+// Reject moves a job to the dead letter queue
+func (mq *MemoryQueueProvider) Reject(ctx context.Context, queueName string, job *Job, reason string) error {
 	mq.mu.Lock()
 	defer mq.mu.Unlock()
 
-	// Note: We can't recover the job from just the ID in this simple implementation
-	// In production, you'd want to store pending jobs with their IDs
-	entry := &deadLetterEntry{
-		Job:      &Job{ID: jobID},
-		Reason:   reason,
+	entries := append(mq.deadLetters[queueName], &DeadLetter{
+		ID:       generateJobID(),
+		Queue:    queueName,
+		Job:      job,
+		Error:    reason,
+		Attempts: job.Attempts,
 		FailedAt: time.Now(),
+	})
+	if len(entries) > deadLetterMaxLen {
+		entries = entries[len(entries)-deadLetterMaxLen:]
 	}
+	mq.deadLetters[queueName] = entries
+	mq.finish(queueName)
 
-	mq.deadLetters[queueName] = append(mq.deadLetters[queueName], entry)
+	mq.activity.deadLettered(queueName)
 	return nil
 }
 
@@ -123,7 +192,7 @@ func (mq *MemoryQueueProvider) GetPendingCount(ctx context.Context, queueName st
 	defer mq.mu.RUnlock()
 
 	if queue, ok := mq.queues[queueName]; ok {
-		return int64(len(queue)), nil
+		return int64(queue.jobs.Len()), nil
 	}
 	return 0, nil
 }
@@ -142,34 +211,84 @@ func (mq *MemoryQueueProvider) GetFailedCount(ctx context.Context, queueName str
 // RetryFailed moves failed jobs back to the queue
 func (mq *MemoryQueueProvider) RetryFailed(ctx context.Context, queueName string) (int64, error) {
 	mq.mu.Lock()
-	entries, ok := mq.deadLetters[queueName]
-	if !ok || len(entries) == 0 {
-		mq.mu.Unlock()
-		return 0, nil
-	}
-
-	// Clear dead letters
+	entries := mq.deadLetters[queueName]
 	mq.deadLetters[queueName] = nil
 	mq.mu.Unlock()
 
 	var retried int64
-	queue := mq.getOrCreateQueue(queueName)
-
 	for _, entry := range entries {
-		if entry.Job != nil {
-			entry.Job.Attempts++
-			select {
-			case queue <- entry.Job:
-				retried++
-			default:
-				// Queue full, ignore
-			}
+		if err := mq.requeue(ctx, entry); err != nil {
+			continue
 		}
+		retried++
 	}
 
 	return retried, nil
 }
 
+// ListDeadLetters returns up to limit failed jobs, oldest first
+func (mq *MemoryQueueProvider) ListDeadLetters(ctx context.Context, queueName string, limit int) ([]*DeadLetter, error) {
+	mq.mu.RLock()
+	defer mq.mu.RUnlock()
+
+	entries := mq.deadLetters[queueName]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return append([]*DeadLetter{}, entries...), nil
+}
+
+// RequeueDeadLetter moves one failed job back to the queue
+func (mq *MemoryQueueProvider) RequeueDeadLetter(ctx context.Context, queueName string, id string) error {
+	mq.mu.Lock()
+	entries := mq.deadLetters[queueName]
+	var entry *DeadLetter
+	for i, e := range entries {
+		if e.ID == id {
+			entry = e
+			mq.deadLetters[queueName] = append(entries[:i:i], entries[i+1:]...)
+			break
+		}
+	}
+	mq.mu.Unlock()
+
+	if entry == nil {
+		return ErrDeadLetterNotFound
+	}
+	return mq.requeue(ctx, entry)
+}
+
+// requeue puts a dead letter's job back on its queue, or the entry back in the dead
+// letter queue if the job can't be enqueued
+func (mq *MemoryQueueProvider) requeue(ctx context.Context, entry *DeadLetter) error {
+	entry.Job.resetForRequeue()
+	if err := mq.Enqueue(ctx, entry.Queue, entry.Job); err != nil {
+		mq.mu.Lock()
+		mq.deadLetters[entry.Queue] = append(mq.deadLetters[entry.Queue], entry)
+		mq.mu.Unlock()
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	return nil
+}
+
+// GetStats returns the queue's backlog and recent throughput
+func (mq *MemoryQueueProvider) GetStats(ctx context.Context, queueName string) (*QueueStats, error) {
+	mq.mu.RLock()
+	stats := &QueueStats{
+		Queue:          queueName,
+		PendingEntries: mq.inFlight[queueName],
+		DeadLetters:    int64(len(mq.deadLetters[queueName])),
+	}
+	if queue, ok := mq.queues[queueName]; ok {
+		stats.Depth = int64(queue.jobs.Len())
+	}
+	mq.mu.RUnlock()
+
+	stats.Length = stats.Depth + stats.PendingEntries
+	mq.activity.fill(stats)
+	return stats, nil
+}
+
 // Ping checks if the queue is available
 func (mq *MemoryQueueProvider) Ping(ctx context.Context) error {
 	mq.mu.RLock()
@@ -188,7 +307,8 @@ func (mq *MemoryQueueProvider) Close() error {
 
 	mq.closed = true
 	for name, queue := range mq.queues {
-		close(queue)
+		// Wakes every waiting Dequeue, which then sees the queue is closed
+		close(queue.ready)
 		delete(mq.queues, name)
 	}
 	return nil
@@ -202,7 +322,7 @@ func (mq *MemoryQueueProvider) GetQueueStats() map[string]map[string]int64 {
 	stats := make(map[string]map[string]int64)
 	for name, queue := range mq.queues {
 		stats[name] = map[string]int64{
-			"pending": int64(len(queue)),
+			"pending": int64(queue.jobs.Len()),
 			"failed":  int64(len(mq.deadLetters[name])),
 		}
 	}
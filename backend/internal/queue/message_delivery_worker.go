@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ============================================
+// MESSAGE DELIVERY WORKER
+// ============================================
+
+// MessageDeliverer finishes delivering a chat message to one recipient. It handles
+// its own failures, scheduling the next attempt or giving up, so an error here
+// means the job itself was unusable.
+type MessageDeliverer interface {
+	RetryDelivery(ctx context.Context, payload *MessageDeliveryJobPayload) error
+}
+
+// MessageDeliveryWorker processes message delivery retries from the queue
+type MessageDeliveryWorker struct {
+	pool      *WorkerPool
+	deliverer MessageDeliverer
+}
+
+// NewMessageDeliveryWorker creates a new message delivery worker
+func NewMessageDeliveryWorker(provider QueueProvider, deliverer MessageDeliverer, workers int) *MessageDeliveryWorker {
+	cfg := &WorkerPoolConfig{
+		Workers:    workers,
+		QueueName:  QueueMessageDelivery,
+		PollTime:   1000, // 1 second
+		MaxRetries: 1,    // Backoff between attempts is up to the deliverer
+	}
+
+	pool := NewWorkerPool(provider, cfg)
+	worker := &MessageDeliveryWorker{
+		pool:      pool,
+		deliverer: deliverer,
+	}
+
+	pool.RegisterHandler(JobTypeMessageDeliveryRetry, worker.handleRetry)
+
+	return worker
+}
+
+// Start starts the message delivery worker
+func (w *MessageDeliveryWorker) Start() {
+	w.pool.Start()
+}
+
+// Stop stops the message delivery worker
+func (w *MessageDeliveryWorker) Stop() {
+	w.pool.Stop()
+}
+
+// GetStats returns worker statistics
+func (w *MessageDeliveryWorker) GetStats() map[string]interface{} {
+	return w.pool.GetStats()
+}
+
+// handleRetry handles message delivery retry jobs
+func (w *MessageDeliveryWorker) handleRetry(ctx context.Context, job *Job) error {
+	var payload MessageDeliveryJobPayload
+
+	if err := job.UnmarshalPayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	log.Printf("[MessageDeliveryWorker] Retrying message %s to user %s (attempt %d)",
+		payload.MessageID, payload.RecipientID, payload.Attempt+1)
+	return w.deliverer.RetryDelivery(ctx, &payload)
+}
+
+// ============================================
+// QUEUE HELPER FUNCTIONS
+// ============================================
+
+// QueueMessageDeliveryRetry queues a delivery retry once delay has passed. The wait
+// happens in this process, so a retry still waiting when the server stops is lost;
+// the recipient then gets the message from the pending sync when they reconnect.
+func QueueMessageDeliveryRetry(provider QueueProvider, payload *MessageDeliveryJobPayload, delay time.Duration) error {
+	job, err := NewJob(JobTypeMessageDeliveryRetry, payload)
+	if err != nil {
+		return err
+	}
+
+	time.AfterFunc(delay, func() {
+		if err := provider.Enqueue(context.Background(), QueueMessageDelivery, job); err != nil {
+			log.Printf("[MessageDeliveryWorker] Failed to queue retry of message %s: %v", payload.MessageID, err)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// ============================================
+// PUSH WORKER
+// ============================================
+
+// PushSender delivers a push notification to all of a user's registered devices
+type PushSender interface {
+	SendPush(ctx context.Context, payload *NotificationJobPayload) error
+}
+
+// PushWorker processes push notification jobs from the queue
+type PushWorker struct {
+	pool   *WorkerPool
+	sender PushSender
+}
+
+// NewPushWorker creates a new push worker
+func NewPushWorker(provider QueueProvider, sender PushSender, workers int) *PushWorker {
+	cfg := &WorkerPoolConfig{
+		Workers:    workers,
+		QueueName:  QueueNotification,
+		PollTime:   5000, // 5 seconds
+		MaxRetries: 3,
+	}
+
+	pool := NewWorkerPool(provider, cfg)
+	worker := &PushWorker{
+		pool:   pool,
+		sender: sender,
+	}
+
+	pool.RegisterHandler(JobTypePushNotification, worker.handlePush)
+
+	return worker
+}
+
+// Start starts the push worker
+func (w *PushWorker) Start() {
+	w.pool.Start()
+}
+
+// Stop stops the push worker
+func (w *PushWorker) Stop() {
+	w.pool.Stop()
+}
+
+// GetStats returns worker statistics
+func (w *PushWorker) GetStats() map[string]interface{} {
+	return w.pool.GetStats()
+}
+
+// handlePush handles push notification jobs
+func (w *PushWorker) handlePush(ctx context.Context, job *Job) error {
+	var payload NotificationJobPayload
+
+	if err := job.UnmarshalPayload(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	log.Printf("[PushWorker] Sending push to user: %s, type: %s", payload.UserID, payload.Type)
+	return w.sender.SendPush(ctx, &payload)
+}
+
+// ============================================
+// QUEUE HELPER FUNCTIONS
+// ============================================
+
+// QueuePushNotification queues a push to a user's devices
+func QueuePushNotification(ctx context.Context, provider QueueProvider, payload *NotificationJobPayload) error {
+	payload.Channel = "push"
+
+	job, err := NewJob(JobTypePushNotification, payload)
+	if err != nil {
+		return err
+	}
+
+	return provider.Enqueue(ctx, QueueNotification, job)
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 )
 
@@ -14,17 +16,18 @@ import (
 
 // QueueProvider defines the interface for message queue implementations
 type QueueProvider interface {
-	// Enqueue adds a job to the queue
+	// Enqueue adds a job to the queue at the job's priority
 	Enqueue(ctx context.Context, queueName string, job *Job) error
 
-	// Dequeue retrieves a job from the queue (blocks until job available or timeout)
+	// Dequeue retrieves the highest priority job from the queue, oldest first within a
+	// priority (blocks until job available or timeout)
 	Dequeue(ctx context.Context, queueName string, timeout time.Duration) (*Job, error)
 
-	// Acknowledge marks a job as successfully processed
-	Acknowledge(ctx context.Context, queueName string, jobID string) error
+	// Acknowledge marks a dequeued job as successfully processed
+	Acknowledge(ctx context.Context, queueName string, job *Job) error
 
-	// Reject marks a job as failed (will be retried or moved to dead letter)
-	Reject(ctx context.Context, queueName string, jobID string, reason string) error
+	// Reject moves a job that failed for good to the queue's dead letter queue
+	Reject(ctx context.Context, queueName string, job *Job, reason string) error
 
 	// GetPendingCount returns the number of pending jobs in a queue
 	GetPendingCount(ctx context.Context, queueName string) (int64, error)
@@ -35,6 +38,15 @@ type QueueProvider interface {
 	// RetryFailed moves failed jobs back to the queue
 	RetryFailed(ctx context.Context, queueName string) (int64, error)
 
+	// ListDeadLetters returns up to limit failed jobs, oldest first
+	ListDeadLetters(ctx context.Context, queueName string, limit int) ([]*DeadLetter, error)
+
+	// RequeueDeadLetter moves one failed job back to the queue
+	RequeueDeadLetter(ctx context.Context, queueName string, id string) error
+
+	// GetStats returns the queue's backlog and recent throughput
+	GetStats(ctx context.Context, queueName string) (*QueueStats, error)
+
 	// Ping checks if the queue is available
 	Ping(ctx context.Context) error
 
@@ -58,6 +70,26 @@ type Job struct {
 	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
+// Job priorities. Any int works as a priority; these are the levels queues are
+// drained in, see PriorityLevel.
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// PriorityLevel maps a priority onto PriorityLow, PriorityNormal or PriorityHigh
+func PriorityLevel(priority int) int {
+	switch {
+	case priority > 0:
+		return PriorityHigh
+	case priority < 0:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
 // NewJob creates a new job with the given type and payload
 func NewJob(jobType string, payload interface{}) (*Job, error) {
 	data, err := json.Marshal(payload)
@@ -69,7 +101,7 @@ func NewJob(jobType string, payload interface{}) (*Job, error) {
 		ID:        generateJobID(),
 		Type:      jobType,
 		Payload:   data,
-		Priority:  0,
+		Priority:  PriorityNormal,
 		Attempts:  0,
 		MaxRetry:  3,
 		CreatedAt: time.Now(),
@@ -108,6 +140,35 @@ func (j *Job) GetMetadata(key string) string {
 	return j.Metadata[key]
 }
 
+// ============================================
+// DEAD LETTERS
+// ============================================
+
+// deadLetterMaxLen caps each dead letter queue; the oldest entries go first
+const deadLetterMaxLen = 10000
+
+// ErrDeadLetterNotFound is returned when requeueing a dead letter that isn't there
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is a job that was given up on, with the error that ended it
+type DeadLetter struct {
+	ID       string    `json:"id"` // Dead letter ID, for RequeueDeadLetter
+	Queue    string    `json:"queue"`
+	Job      *Job      `json:"job"` // Nil if the message couldn't be read as a job
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// resetForRequeue gives a dead-lettered job a fresh set of attempts
+func (j *Job) resetForRequeue() {
+	j.Attempts = 0
+	delete(j.Metadata, "stream")
+	delete(j.Metadata, "stream_id")
+	delete(j.Metadata, "retry_count")
+	j.SetMetadata("requeued_at", time.Now().Format(time.RFC3339))
+}
+
 // ============================================
 // QUEUE NAMES
 // ============================================
@@ -128,10 +189,39 @@ const (
 	// QueueWebhook for webhook deliveries
 	QueueWebhook = "queue:webhook"
 
+	// QueueMessageDelivery for retrying chat message deliveries
+	QueueMessageDelivery = "queue:message_delivery"
+
 	// QueueDefault for general tasks
 	QueueDefault = "queue:default"
 )
 
+// QueueFor returns the queue that carries a job type, going by the type's prefix
+func QueueFor(jobType string) string {
+	prefix, _, _ := strings.Cut(jobType, ":")
+	switch prefix {
+	case "email":
+		return QueueEmail
+	case "notification":
+		return QueueNotification
+	case "media":
+		return QueueMedia
+	case "feed":
+		return QueueFeed
+	case "webhook":
+		return QueueWebhook
+	case "message":
+		return QueueMessageDelivery
+	default:
+		return QueueDefault
+	}
+}
+
+// Enqueue adds a job to the queue for its type, see QueueFor
+func Enqueue(ctx context.Context, provider QueueProvider, job *Job) error {
+	return provider.Enqueue(ctx, QueueFor(job.Type), job)
+}
+
 // ============================================
 // JOB TYPES
 // ============================================
@@ -141,7 +231,9 @@ const (
 	JobTypeEmailWelcome       = "email:welcome"
 	JobTypeEmailVerification  = "email:verification"
 	JobTypeEmailPasswordReset = "email:password_reset"
+	JobTypeEmailMagicLink     = "email:magic_link"
 	JobTypeEmailNotification  = "email:notification"
+	JobTypeEmailDigest        = "email:digest"
 
 	// Notification jobs
 	JobTypePushNotification  = "notification:push"
@@ -159,6 +251,9 @@ const (
 
 	// Webhook jobs
 	JobTypeWebhookDeliver = "webhook:deliver"
+
+	// Messaging jobs
+	JobTypeMessageDeliveryRetry = "message:delivery_retry"
 )
 
 // ============================================
@@ -185,12 +280,13 @@ type EmailJobPayload struct {
 
 // NotificationJobPayload represents a notification job payload
 type NotificationJobPayload struct {
-	UserID  string            `json:"user_id"`
-	Type    string            `json:"type"`
-	Title   string            `json:"title"`
-	Body    string            `json:"body"`
-	Data    map[string]string `json:"data,omitempty"`
-	Channel string            `json:"channel,omitempty"` // "push", "email", "in_app"
+	UserID    string            `json:"user_id"`
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	ActionURL string            `json:"action_url,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+	Channel   string            `json:"channel,omitempty"` // "push", "email", "in_app"
 }
 
 // MediaJobPayload represents a media processing job payload
@@ -208,3 +304,11 @@ type FeedInvalidatePayload struct {
 	PostID   string `json:"post_id,omitempty"`
 	Hashtag  string `json:"hashtag,omitempty"`
 }
+
+// MessageDeliveryJobPayload represents a retry of a chat message delivery
+type MessageDeliveryJobPayload struct {
+	MessageID   string `json:"message_id"`
+	RecipientID string `json:"recipient_id"`
+	Attempt     int    `json:"attempt"`          // Attempts made so far, including the first
+	Pushed      bool   `json:"pushed,omitempty"` // The message reached the recipient's socket; only marking it delivered is left
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -19,6 +21,7 @@ type RedisQueueProvider struct {
 	client       *redis.Client
 	consumerName string
 	groupName    string
+	activity     *activityLog
 }
 
 // RedisQueueConfig holds configuration for the Redis queue
@@ -40,6 +43,7 @@ func NewRedisQueueProvider(client *redis.Client, cfg *RedisQueueConfig) *RedisQu
 		client:       client,
 		consumerName: cfg.ConsumerName,
 		groupName:    cfg.GroupName,
+		activity:     newActivityLog(),
 	}
 }
 
@@ -55,9 +59,9 @@ func (rq *RedisQueueProvider) Enqueue(ctx context.Context, queueName string, job
 		return fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	// Add to stream
+	// Add to the stream for the job's priority
 	_, err = rq.client.XAdd(ctx, &redis.XAddArgs{
-		Stream: queueName,
+		Stream: priorityStream(queueName, job.Priority),
 		ID:     "*", // Auto-generate ID
 		Values: map[string]interface{}{
 			"job_id":   job.ID,
@@ -73,23 +77,33 @@ func (rq *RedisQueueProvider) Enqueue(ctx context.Context, queueName string, job
 	return nil
 }
 
-// Dequeue retrieves a job from the queue using Redis XREADGROUP
+// Dequeue retrieves a job from the queue using Redis XREADGROUP. Each priority has its
+// own stream, read highest priority first; when they're all empty it blocks until a
+// job is added to any of them.
 func (rq *RedisQueueProvider) Dequeue(ctx context.Context, queueName string, timeout time.Duration) (*Job, error) {
 	if rq.client == nil {
 		return nil, fmt.Errorf("redis client not available")
 	}
 
-	// Ensure consumer group exists
-	rq.ensureConsumerGroup(ctx, queueName)
+	// Ensure consumer groups exist
+	streams := priorityStreams(queueName)
+	for _, stream := range streams {
+		rq.ensureConsumerGroup(ctx, stream)
+	}
 
-	// Read from stream with consumer group
-	streams, err := rq.client.XReadGroup(ctx, &redis.XReadGroupArgs{
-		Group:    rq.groupName,
-		Consumer: rq.consumerName,
-		Streams:  []string{queueName, ">"},
-		Count:    1,
-		Block:    timeout,
-	}).Result()
+	job, err := rq.readNext(ctx, queueName, streams)
+	if job != nil || err != nil {
+		return job, err
+	}
+
+	// Wait for a new message on any of the streams, then look again. XREAD only
+	// watches; delivery is still up to the consumer group, in priority order.
+	waitFor := append(append([]string{}, streams...), "$", "$", "$")
+	err = rq.client.XRead(ctx, &redis.XReadArgs{
+		Streams: waitFor,
+		Count:   1,
+		Block:   timeout,
+	}).Err()
 
 	if err == redis.Nil {
 		return nil, nil // No messages available
@@ -98,42 +112,84 @@ func (rq *RedisQueueProvider) Dequeue(ctx context.Context, queueName string, tim
 		return nil, fmt.Errorf("failed to dequeue job: %w", err)
 	}
 
-	// Parse the message
-	if len(streams) == 0 || len(streams[0].Messages) == 0 {
-		return nil, nil
+	return rq.readNext(ctx, queueName, streams)
+}
+
+// readNext reads the next message from the first of streams that has one, without
+// blocking
+func (rq *RedisQueueProvider) readNext(ctx context.Context, queueName string, streams []string) (*Job, error) {
+	for _, stream := range streams {
+		result, err := rq.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    rq.groupName,
+			Consumer: rq.consumerName,
+			Streams:  []string{stream, ">"},
+			Count:    1,
+			Block:    -1, // Don't block
+		}).Result()
+
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to dequeue job: %w", err)
+		}
+		if len(result) == 0 || len(result[0].Messages) == 0 {
+			continue
+		}
+
+		return rq.parseMessage(ctx, queueName, stream, result[0].Messages[0])
 	}
 
-	msg := streams[0].Messages[0]
+	return nil, nil
+}
+
+// parseMessage reads the job in a message delivered from stream. A message that isn't
+// a job is moved to the dead letter queue.
+func (rq *RedisQueueProvider) parseMessage(ctx context.Context, queueName, stream string, msg redis.XMessage) (*Job, error) {
 	jobData, ok := msg.Values["data"].(string)
 	if !ok {
+		rq.deadLetterUnreadable(ctx, queueName, stream, msg, "invalid job data format")
 		return nil, fmt.Errorf("invalid job data format")
 	}
 
 	var job Job
 	if err := json.Unmarshal([]byte(jobData), &job); err != nil {
+		rq.deadLetterUnreadable(ctx, queueName, stream, msg, "failed to unmarshal job: "+err.Error())
 		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
 	}
 
-	// Store message ID in metadata for acknowledgment
+	// Store stream and message ID in metadata for acknowledgment
+	job.SetMetadata("stream", stream)
 	job.SetMetadata("stream_id", msg.ID)
 
+	// Message IDs start with the time they were added, in milliseconds
+	if ms, err := strconv.ParseInt(strings.SplitN(msg.ID, "-", 2)[0], 10, 64); err == nil {
+		rq.activity.dequeued(queueName, time.Since(time.UnixMilli(ms)))
+	}
+
 	return &job, nil
 }
 
 // Acknowledge marks a job as successfully processed using Redis XACK
-func (rq *RedisQueueProvider) Acknowledge(ctx context.Context, queueName string, jobID string) error {
+func (rq *RedisQueueProvider) Acknowledge(ctx context.Context, queueName string, job *Job) error {
 	if rq.client == nil {
 		return fmt.Errorf("redis client not available")
 	}
 
-	// The jobID here is actually the stream message ID stored in metadata
-	_, err := rq.client.XAck(ctx, queueName, rq.groupName, jobID).Result()
+	streamID := job.GetMetadata("stream_id")
+	if streamID == "" {
+		return nil // Never dequeued, so there's nothing to acknowledge
+	}
+	stream := dequeuedFrom(queueName, job)
+
+	_, err := rq.client.XAck(ctx, stream, rq.groupName, streamID).Result()
 	if err != nil {
 		return fmt.Errorf("failed to acknowledge job: %w", err)
 	}
+	rq.activity.processed(queueName)
 
 	// Also delete the message from the stream to free memory
-	_, err = rq.client.XDel(ctx, queueName, jobID).Result()
+	_, err = rq.client.XDel(ctx, stream, streamID).Result()
 	if err != nil {
 		log.Printf("[Queue] Warning: failed to delete acknowledged message: %v", err)
 	}
@@ -141,38 +197,73 @@ func (rq *RedisQueueProvider) Acknowledge(ctx context.Context, queueName string,
 	return nil
 }
 
-// Reject marks a job as failed
-func (rq *RedisQueueProvider) Reject(ctx context.Context, queueName string, jobID string, reason string) error {
+// Reject moves a job to the dead letter queue, with the reason and attempts made
+func (rq *RedisQueueProvider) Reject(ctx context.Context, queueName string, job *Job, reason string) error {
 	if rq.client == nil {
 		return fmt.Errorf("redis client not available")
 	}
 
-	// Move to dead letter queue
-	deadLetterQueue := queueName + ":dead"
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	return rq.moveToDeadLetter(ctx, queueName, dequeuedFrom(queueName, job), job.GetMetadata("stream_id"), map[string]interface{}{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"data":     string(jobData),
+		"error":    reason,
+		"attempts": job.Attempts,
+	})
+}
 
-	// Store failure info
-	failureData := map[string]interface{}{
-		"original_queue": queueName,
-		"job_id":         jobID,
-		"reason":         reason,
-		"failed_at":      time.Now().Format(time.RFC3339),
+// deadLetterUnreadable moves a message that isn't a job to the dead letter queue, so
+// that it doesn't sit in the consumer group's pending list forever
+func (rq *RedisQueueProvider) deadLetterUnreadable(ctx context.Context, queueName, stream string, msg redis.XMessage, reason string) {
+	values := map[string]interface{}{"error": reason, "attempts": 0}
+	for _, key := range []string{"job_id", "job_type", "data"} {
+		if v, ok := msg.Values[key]; ok {
+			values[key] = v
+		}
 	}
+	if err := rq.moveToDeadLetter(ctx, queueName, stream, msg.ID, values); err != nil {
+		log.Printf("[Queue] Warning: failed to dead-letter unreadable message %s: %v", msg.ID, err)
+	}
+}
+
+// moveToDeadLetter adds an entry to the queue's dead letter stream, then acknowledges
+// and deletes the original message from stream. The message is left pending if the
+// entry can't be added, so nothing is lost.
+func (rq *RedisQueueProvider) moveToDeadLetter(ctx context.Context, queueName, stream, streamID string, values map[string]interface{}) error {
+	values["failed_at"] = time.Now().Format(time.RFC3339)
 
 	_, err := rq.client.XAdd(ctx, &redis.XAddArgs{
-		Stream: deadLetterQueue,
+		Stream: deadLetterStream(queueName),
+		MaxLen: deadLetterMaxLen,
+		Approx: true,
 		ID:     "*",
-		Values: failureData,
+		Values: values,
 	}).Result()
 	if err != nil {
-		log.Printf("[Queue] Warning: failed to add to dead letter queue: %v", err)
+		return fmt.Errorf("failed to add to dead letter queue: %w", err)
+	}
+	rq.activity.deadLettered(queueName)
+
+	if streamID == "" {
+		return nil
 	}
 
 	// Acknowledge original message (removes from pending)
-	_, err = rq.client.XAck(ctx, queueName, rq.groupName, jobID).Result()
+	_, err = rq.client.XAck(ctx, stream, rq.groupName, streamID).Result()
 	if err != nil {
 		return fmt.Errorf("failed to reject job: %w", err)
 	}
 
+	_, err = rq.client.XDel(ctx, stream, streamID).Result()
+	if err != nil {
+		log.Printf("[Queue] Warning: failed to delete rejected message: %v", err)
+	}
+
 	return nil
 }
 
@@ -182,13 +273,17 @@ func (rq *RedisQueueProvider) GetPendingCount(ctx context.Context, queueName str
 		return 0, fmt.Errorf("redis client not available")
 	}
 
-	// Get stream length
-	length, err := rq.client.XLen(ctx, queueName).Result()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get pending count: %w", err)
+	// Sum the stream lengths across priorities
+	var pending int64
+	for _, stream := range priorityStreams(queueName) {
+		length, err := rq.client.XLen(ctx, stream).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get pending count: %w", err)
+		}
+		pending += length
 	}
 
-	return length, nil
+	return pending, nil
 }
 
 // GetFailedCount returns the number of failed jobs
@@ -197,8 +292,7 @@ func (rq *RedisQueueProvider) GetFailedCount(ctx context.Context, queueName stri
 		return 0, fmt.Errorf("redis client not available")
 	}
 
-	deadLetterQueue := queueName + ":dead"
-	length, err := rq.client.XLen(ctx, deadLetterQueue).Result()
+	length, err := rq.client.XLen(ctx, deadLetterStream(queueName)).Result()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get failed count: %w", err)
 	}
@@ -212,35 +306,179 @@ func (rq *RedisQueueProvider) RetryFailed(ctx context.Context, queueName string)
 		return 0, fmt.Errorf("redis client not available")
 	}
 
-	deadLetterQueue := queueName + ":dead"
-
 	// Get all messages from dead letter queue
-	messages, err := rq.client.XRange(ctx, deadLetterQueue, "-", "+").Result()
+	messages, err := rq.client.XRange(ctx, deadLetterStream(queueName), "-", "+").Result()
 	if err != nil {
 		return 0, fmt.Errorf("failed to read dead letter queue: %w", err)
 	}
 
 	var retried int64
 	for _, msg := range messages {
-		// Re-add to main queue (simplified - would need original job data)
-		_, err := rq.client.XAdd(ctx, &redis.XAddArgs{
-			Stream: queueName,
-			ID:     "*",
-			Values: msg.Values,
-		}).Result()
-		if err != nil {
-			log.Printf("[Queue] Warning: failed to retry message: %v", err)
+		if err := rq.requeue(ctx, queueName, msg); err != nil {
+			log.Printf("[Queue] Warning: failed to retry message %s: %v", msg.ID, err)
 			continue
 		}
-
-		// Remove from dead letter
-		rq.client.XDel(ctx, deadLetterQueue, msg.ID)
 		retried++
 	}
 
 	return retried, nil
 }
 
+// ListDeadLetters returns up to limit failed jobs, oldest first
+func (rq *RedisQueueProvider) ListDeadLetters(ctx context.Context, queueName string, limit int) ([]*DeadLetter, error) {
+	if rq.client == nil {
+		return nil, fmt.Errorf("redis client not available")
+	}
+
+	var messages []redis.XMessage
+	var err error
+	if limit > 0 {
+		messages, err = rq.client.XRangeN(ctx, deadLetterStream(queueName), "-", "+", int64(limit)).Result()
+	} else {
+		messages, err = rq.client.XRange(ctx, deadLetterStream(queueName), "-", "+").Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter queue: %w", err)
+	}
+
+	deadLetters := make([]*DeadLetter, 0, len(messages))
+	for _, msg := range messages {
+		deadLetters = append(deadLetters, parseDeadLetter(queueName, msg))
+	}
+	return deadLetters, nil
+}
+
+// RequeueDeadLetter moves one failed job back to the queue
+func (rq *RedisQueueProvider) RequeueDeadLetter(ctx context.Context, queueName string, id string) error {
+	if rq.client == nil {
+		return fmt.Errorf("redis client not available")
+	}
+
+	messages, err := rq.client.XRange(ctx, deadLetterStream(queueName), id, id).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read dead letter queue: %w", err)
+	}
+	if len(messages) == 0 {
+		return ErrDeadLetterNotFound
+	}
+
+	return rq.requeue(ctx, queueName, messages[0])
+}
+
+// requeue puts a dead letter's job back on the queue and removes the dead letter
+func (rq *RedisQueueProvider) requeue(ctx context.Context, queueName string, msg redis.XMessage) error {
+	deadLetter := parseDeadLetter(queueName, msg)
+	if deadLetter.Job == nil {
+		return fmt.Errorf("dead letter %s has no readable job", msg.ID)
+	}
+
+	deadLetter.Job.resetForRequeue()
+	if err := rq.Enqueue(ctx, queueName, deadLetter.Job); err != nil {
+		return err
+	}
+
+	if err := rq.client.XDel(ctx, deadLetterStream(queueName), msg.ID).Err(); err != nil {
+		log.Printf("[Queue] Warning: failed to delete requeued dead letter %s: %v", msg.ID, err)
+	}
+	return nil
+}
+
+// priorityStream is the stream holding a queue's jobs of a priority. Normal priority
+// jobs go to the queue's own stream, where jobs queued before priorities were added are.
+func priorityStream(queueName string, priority int) string {
+	switch PriorityLevel(priority) {
+	case PriorityHigh:
+		return queueName + ":high"
+	case PriorityLow:
+		return queueName + ":low"
+	default:
+		return queueName
+	}
+}
+
+// priorityStreams lists a queue's streams, highest priority first
+func priorityStreams(queueName string) []string {
+	return []string{
+		priorityStream(queueName, PriorityHigh),
+		priorityStream(queueName, PriorityNormal),
+		priorityStream(queueName, PriorityLow),
+	}
+}
+
+// dequeuedFrom is the stream a dequeued job was read from
+func dequeuedFrom(queueName string, job *Job) string {
+	if stream := job.GetMetadata("stream"); stream != "" {
+		return stream
+	}
+	return queueName
+}
+
+// deadLetterStream is the stream holding a queue's failed jobs
+func deadLetterStream(queueName string) string {
+	return queueName + ":dlq"
+}
+
+// parseDeadLetter reads a dead letter stream entry. Its job is nil if the data isn't
+// a job, which happens for messages that were unreadable when dequeued.
+func parseDeadLetter(queueName string, msg redis.XMessage) *DeadLetter {
+	deadLetter := &DeadLetter{ID: msg.ID, Queue: queueName}
+
+	if data, ok := msg.Values["data"].(string); ok {
+		var job Job
+		if err := json.Unmarshal([]byte(data), &job); err == nil {
+			deadLetter.Job = &job
+		}
+	}
+	deadLetter.Error, _ = msg.Values["error"].(string)
+	if attempts, ok := msg.Values["attempts"].(string); ok {
+		deadLetter.Attempts, _ = strconv.Atoi(attempts)
+	}
+	if failedAt, ok := msg.Values["failed_at"].(string); ok {
+		deadLetter.FailedAt, _ = time.Parse(time.RFC3339, failedAt)
+	}
+	return deadLetter
+}
+
+// GetStats returns the queue's backlog and recent throughput. Length is the XLEN of
+// the queue's streams and PendingEntries the size of their pending entries lists.
+func (rq *RedisQueueProvider) GetStats(ctx context.Context, queueName string) (*QueueStats, error) {
+	if rq.client == nil {
+		return nil, fmt.Errorf("redis client not available")
+	}
+
+	stats := &QueueStats{Queue: queueName}
+	for _, stream := range priorityStreams(queueName) {
+		rq.ensureConsumerGroup(ctx, stream)
+
+		length, err := rq.client.XLen(ctx, stream).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stream length: %w", err)
+		}
+		pending, err := rq.client.XPending(ctx, stream, rq.groupName).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending entries: %w", err)
+		}
+
+		stats.Length += length
+		stats.PendingEntries += pending.Count
+	}
+
+	// Acknowledged messages are deleted, so whatever isn't pending is waiting
+	stats.Depth = stats.Length - stats.PendingEntries
+	if stats.Depth < 0 {
+		stats.Depth = 0
+	}
+
+	deadLetters, err := rq.GetFailedCount(ctx, queueName)
+	if err != nil {
+		return nil, err
+	}
+	stats.DeadLetters = deadLetters
+
+	rq.activity.fill(stats)
+	return stats, nil
+}
+
 // Ping checks if the queue is available
 func (rq *RedisQueueProvider) Ping(ctx context.Context) error {
 	if rq.client == nil {
@@ -279,52 +517,56 @@ func (rq *RedisQueueProvider) ClaimStaleMessages(ctx context.Context, queueName
 		return nil, fmt.Errorf("redis client not available")
 	}
 
-	// Get pending messages for this group
-	pending, err := rq.client.XPendingExt(ctx, &redis.XPendingExtArgs{
-		Stream: queueName,
-		Group:  rq.groupName,
-		Start:  "-",
-		End:    "+",
-		Count:  100,
-	}).Result()
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pending messages: %w", err)
-	}
-
+	// Claim from each priority's stream, highest first
 	var jobs []*Job
-	for _, p := range pending {
-		// Check if message has been idle for too long
-		if p.Idle > maxIdleTime {
-			// Claim the message
-			claimed, err := rq.client.XClaim(ctx, &redis.XClaimArgs{
-				Stream:   queueName,
-				Group:    rq.groupName,
-				Consumer: rq.consumerName,
-				MinIdle:  maxIdleTime,
-				Messages: []string{p.ID},
-			}).Result()
-
-			if err != nil {
-				log.Printf("[Queue] Warning: failed to claim message %s: %v", p.ID, err)
-				continue
-			}
+	for _, stream := range priorityStreams(queueName) {
+		// Get pending messages for this group
+		pending, err := rq.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  rq.groupName,
+			Start:  "-",
+			End:    "+",
+			Count:  100,
+		}).Result()
 
-			// Parse claimed messages
-			for _, msg := range claimed {
-				jobData, ok := msg.Values["data"].(string)
-				if !ok {
-					continue
-				}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending messages: %w", err)
+		}
 
-				var job Job
-				if err := json.Unmarshal([]byte(jobData), &job); err != nil {
+		for _, p := range pending {
+			// Check if message has been idle for too long
+			if p.Idle > maxIdleTime {
+				// Claim the message
+				claimed, err := rq.client.XClaim(ctx, &redis.XClaimArgs{
+					Stream:   stream,
+					Group:    rq.groupName,
+					Consumer: rq.consumerName,
+					MinIdle:  maxIdleTime,
+					Messages: []string{p.ID},
+				}).Result()
+
+				if err != nil {
+					log.Printf("[Queue] Warning: failed to claim message %s: %v", p.ID, err)
 					continue
 				}
 
-				job.SetMetadata("stream_id", msg.ID)
-				job.Attempts++
-				jobs = append(jobs, &job)
+				// Parse claimed messages
+				for _, msg := range claimed {
+					jobData, ok := msg.Values["data"].(string)
+					if !ok {
+						continue
+					}
+
+					var job Job
+					if err := json.Unmarshal([]byte(jobData), &job); err != nil {
+						continue
+					}
+
+					job.SetMetadata("stream", stream)
+					job.SetMetadata("stream_id", msg.ID)
+					job.Attempts++
+					jobs = append(jobs, &job)
+				}
 			}
 		}
 	}
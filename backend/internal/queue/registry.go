@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// ============================================
+// HANDLER REGISTRY
+// ============================================
+
+// HandlerRegistry maps job types to the handlers that process them. Workers look up
+// the handler for each job they dequeue by its type, so a queue can carry any mix of
+// job types.
+type HandlerRegistry struct {
+	handlers map[string]JobHandler
+	mu       sync.RWMutex
+}
+
+// NewHandlerRegistry creates an empty handler registry
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: make(map[string]JobHandler),
+	}
+}
+
+// DefaultRegistry is where workers find handlers unless given a registry of their own
+var DefaultRegistry = NewHandlerRegistry()
+
+// RegisterHandler registers a handler for a job type with DefaultRegistry
+func RegisterHandler(jobType string, handler JobHandler) {
+	DefaultRegistry.Register(jobType, handler)
+}
+
+// Register sets the handler for a job type, replacing any registered before
+func (r *HandlerRegistry) Register(jobType string, handler JobHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.handlers[jobType]; exists {
+		log.Printf("[Queue] Replacing handler for job type: %s", jobType)
+	}
+	r.handlers[jobType] = handler
+}
+
+// Handler returns the handler registered for a job type
+func (r *HandlerRegistry) Handler(jobType string) (JobHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handler, ok := r.handlers[jobType]
+	return handler, ok
+}
+
+// JobTypes returns the job types that have a handler, sorted
+func (r *HandlerRegistry) JobTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.handlers))
+	for jobType := range r.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
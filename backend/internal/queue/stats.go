@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// ============================================
+// QUEUE STATS
+// ============================================
+
+// QueueStats describes a queue's backlog and recent throughput. The counts are the
+// queue's own; rates and time in queue are measured on this instance only.
+type QueueStats struct {
+	Queue                 string  `json:"queue"`
+	Depth                 int64   `json:"depth"`           // Jobs waiting to be dequeued
+	Length                int64   `json:"length"`          // Depth plus jobs being processed (XLEN for Redis)
+	PendingEntries        int64   `json:"pending_entries"` // Dequeued, not yet acknowledged (the PEL for Redis)
+	DeadLetters           int64   `json:"dead_letters"`
+	ProcessedPerMinute    float64 `json:"processed_per_minute"` // Acknowledged attempts, retries included
+	DeadLetteredPerMinute float64 `json:"dead_lettered_per_minute"`
+	AvgTimeInQueueMs      float64 `json:"avg_time_in_queue_ms"` // From enqueue to dequeue
+	WindowSeconds         int     `json:"window_seconds"`       // What the rates and average cover
+}
+
+// statsBuckets is how many minutes back rates and time in queue are measured
+const statsBuckets = 5
+
+// activityLog counts what happened on each queue in one-minute buckets, keeping the
+// last statsBuckets minutes
+type activityLog struct {
+	mu     sync.Mutex
+	queues map[string]*[statsBuckets]activityBucket
+}
+
+type activityBucket struct {
+	minute       int64
+	dequeued     int64
+	waited       time.Duration
+	processed    int64
+	deadLettered int64
+}
+
+func newActivityLog() *activityLog {
+	return &activityLog{queues: make(map[string]*[statsBuckets]activityBucket)}
+}
+
+// bucket returns the current minute's bucket for a queue; the caller holds a.mu
+func (a *activityLog) bucket(queueName string) *activityBucket {
+	ring, ok := a.queues[queueName]
+	if !ok {
+		ring = &[statsBuckets]activityBucket{}
+		a.queues[queueName] = ring
+	}
+
+	minute := time.Now().Unix() / 60
+	b := &ring[minute%statsBuckets]
+	if b.minute != minute {
+		*b = activityBucket{minute: minute}
+	}
+	return b
+}
+
+// dequeued records a job taken off a queue after waiting in it for wait
+func (a *activityLog) dequeued(queueName string, wait time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b := a.bucket(queueName)
+	b.dequeued++
+	b.waited += wait
+}
+
+// processed records an acknowledged job
+func (a *activityLog) processed(queueName string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucket(queueName).processed++
+}
+
+// deadLettered records a job moved to the dead letter queue
+func (a *activityLog) deadLettered(queueName string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucket(queueName).deadLettered++
+}
+
+// fill sets the rates and average time in queue of stats
+func (a *activityLog) fill(stats *QueueStats) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats.WindowSeconds = statsBuckets * 60
+
+	ring, ok := a.queues[stats.Queue]
+	if !ok {
+		return
+	}
+
+	var total activityBucket
+	oldest := time.Now().Unix()/60 - statsBuckets
+	for _, b := range ring {
+		if b.minute <= oldest {
+			continue
+		}
+		total.dequeued += b.dequeued
+		total.waited += b.waited
+		total.processed += b.processed
+		total.deadLettered += b.deadLettered
+	}
+
+	stats.ProcessedPerMinute = float64(total.processed) / statsBuckets
+	stats.DeadLetteredPerMinute = float64(total.deadLettered) / statsBuckets
+	if total.dequeued > 0 {
+		stats.AvgTimeInQueueMs = float64(total.waited.Milliseconds()) / float64(total.dequeued)
+	}
+}
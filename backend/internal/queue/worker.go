@@ -18,13 +18,12 @@ type Worker struct {
 	ID           string
 	queue        QueueProvider
 	queueName    string
-	handlers     map[string]JobHandler
+	handlers     *HandlerRegistry
 	concurrency  int
 	pollInterval time.Duration
 	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
-	mu           sync.RWMutex
 }
 
 // JobHandler is a function that processes a job
@@ -33,8 +32,9 @@ type JobHandler func(ctx context.Context, job *Job) error
 // WorkerConfig holds worker configuration
 type WorkerConfig struct {
 	QueueName    string
-	Concurrency  int           // Number of concurrent job processors
-	PollInterval time.Duration // How often to poll for jobs
+	Concurrency  int              // Number of concurrent job processors
+	PollInterval time.Duration    // How often to poll for jobs
+	Handlers     *HandlerRegistry // Where to find job handlers; DefaultRegistry if nil
 }
 
 // NewWorker creates a new queue worker
@@ -45,6 +45,9 @@ func NewWorker(queue QueueProvider, cfg *WorkerConfig) *Worker {
 	if cfg.PollInterval <= 0 {
 		cfg.PollInterval = 1 * time.Second
 	}
+	if cfg.Handlers == nil {
+		cfg.Handlers = DefaultRegistry
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -52,7 +55,7 @@ func NewWorker(queue QueueProvider, cfg *WorkerConfig) *Worker {
 		ID:           generateJobID()[:8],
 		queue:        queue,
 		queueName:    cfg.QueueName,
-		handlers:     make(map[string]JobHandler),
+		handlers:     cfg.Handlers,
 		concurrency:  cfg.Concurrency,
 		pollInterval: cfg.PollInterval,
 		ctx:          ctx,
@@ -60,11 +63,10 @@ func NewWorker(queue QueueProvider, cfg *WorkerConfig) *Worker {
 	}
 }
 
-// RegisterHandler registers a job handler for a specific job type
+// RegisterHandler registers a job handler for a specific job type with the worker's
+// registry
 func (w *Worker) RegisterHandler(jobType string, handler JobHandler) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	w.handlers[jobType] = handler
+	w.handlers.Register(jobType, handler)
 	log.Printf("[Worker %s] Registered handler for job type: %s", w.ID, jobType)
 }
 
@@ -130,13 +132,9 @@ func (w *Worker) handleJob(workerNum int, job *Job) {
 			log.Printf("[Worker %s-%d] PANIC while processing job %s (type: %s): %v\n%s",
 				w.ID, workerNum, job.ID, job.Type, r, stack)
 
-			// Reject the job with panic information
-			streamID := job.GetMetadata("stream_id")
-			if streamID == "" {
-				streamID = job.ID
-			}
-
-			w.queue.Reject(context.Background(), w.queueName, streamID,
+			// A panicking job would likely panic again, so it goes straight to dead letter
+			job.Attempts++
+			w.queue.Reject(context.Background(), w.queueName, job,
 				fmt.Sprintf("panic: %v", r))
 		}
 	}()
@@ -146,20 +144,13 @@ func (w *Worker) handleJob(workerNum int, job *Job) {
 		w.ID, workerNum, job.ID, job.Type, job.Attempts+1, job.MaxRetry)
 
 	// Get handler for this job type
-	w.mu.RLock()
-	handler, exists := w.handlers[job.Type]
-	w.mu.RUnlock()
+	handler, exists := w.handlers.Handler(job.Type)
 
 	if !exists {
 		log.Printf("[Worker %s-%d] No handler registered for job type: %s",
 			w.ID, workerNum, job.Type)
-		
-		streamID := job.GetMetadata("stream_id")
-		if streamID == "" {
-			streamID = job.ID
-		}
-		
-		w.queue.Reject(context.Background(), w.queueName, streamID,
+
+		w.queue.Reject(context.Background(), w.queueName, job,
 			fmt.Sprintf("no handler for job type: %s", job.Type))
 		return
 	}
@@ -171,12 +162,6 @@ func (w *Worker) handleJob(workerNum int, job *Job) {
 	// Execute the handler
 	err := handler(jobCtx, job)
 
-	// Get stream ID for acknowledgment/rejection
-	streamID := job.GetMetadata("stream_id")
-	if streamID == "" {
-		streamID = job.ID
-	}
-
 	if err != nil {
 		job.Attempts++
 		
@@ -198,13 +183,13 @@ func (w *Worker) handleJob(workerNum int, job *Job) {
 			}
 
 			// Acknowledge the original message
-			w.queue.Acknowledge(context.Background(), w.queueName, streamID)
+			w.queue.Acknowledge(context.Background(), w.queueName, job)
 		} else {
 			// Max retries reached, move to dead letter
 			log.Printf("[Worker %s-%d] Job %s exceeded max retries, moving to dead letter",
 				w.ID, workerNum, job.ID)
 			
-			w.queue.Reject(context.Background(), w.queueName, streamID,
+			w.queue.Reject(context.Background(), w.queueName, job,
 				fmt.Sprintf("max retries exceeded: %v", err))
 		}
 	} else {
@@ -214,7 +199,7 @@ func (w *Worker) handleJob(workerNum int, job *Job) {
 			w.ID, workerNum, job.ID, duration)
 
 		// Acknowledge the job
-		if ackErr := w.queue.Acknowledge(context.Background(), w.queueName, streamID); ackErr != nil {
+		if ackErr := w.queue.Acknowledge(context.Background(), w.queueName, job); ackErr != nil {
 			log.Printf("[Worker %s-%d] Failed to acknowledge job %s: %v",
 				w.ID, workerNum, job.ID, ackErr)
 		}
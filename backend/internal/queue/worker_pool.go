@@ -20,11 +20,10 @@ type WorkerPool struct {
 	workers      int
 	pollTime     int
 	maxRetries   int
-	handlers     map[string]JobHandler
+	handlers     *HandlerRegistry
 	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
-	mu           sync.RWMutex
 }
 
 // WorkerPoolConfig holds worker pool configuration
@@ -33,6 +32,8 @@ type WorkerPoolConfig struct {
 	QueueName  string // Name of the queue to process
 	PollTime   int    // Poll interval in milliseconds
 	MaxRetries int    // Maximum number of retries for failed jobs
+
+	Handlers *HandlerRegistry // Where to find job handlers; DefaultRegistry if nil
 }
 
 // NewWorkerPool creates a new worker pool
@@ -46,6 +47,9 @@ func NewWorkerPool(provider QueueProvider, cfg *WorkerPoolConfig) *WorkerPool {
 	if cfg.MaxRetries <= 0 {
 		cfg.MaxRetries = 3
 	}
+	if cfg.Handlers == nil {
+		cfg.Handlers = DefaultRegistry
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -55,17 +59,16 @@ func NewWorkerPool(provider QueueProvider, cfg *WorkerPoolConfig) *WorkerPool {
 		workers:    cfg.Workers,
 		pollTime:   cfg.PollTime,
 		maxRetries: cfg.MaxRetries,
-		handlers:   make(map[string]JobHandler),
+		handlers:   cfg.Handlers,
 		ctx:        ctx,
 		cancel:     cancel,
 	}
 }
 
-// RegisterHandler registers a job handler for a specific job type
+// RegisterHandler registers a job handler for a specific job type with the pool's
+// registry
 func (wp *WorkerPool) RegisterHandler(jobType string, handler JobHandler) {
-	wp.mu.Lock()
-	defer wp.mu.Unlock()
-	wp.handlers[jobType] = handler
+	wp.handlers.Register(jobType, handler)
 }
 
 // Start starts the worker pool
@@ -129,14 +132,9 @@ func (wp *WorkerPool) handleJob(workerID int, job *Job) {
 			log.Printf("[WorkerPool-Worker %d] PANIC processing job %s: %v\n%s",
 				workerID, job.ID, r, stack)
 
-			// Get stream ID or use job ID
-			streamID := job.GetMetadata("stream_id")
-			if streamID == "" {
-				streamID = job.ID
-			}
-
-			// Reject the job
-			wp.provider.Reject(context.Background(), wp.queueName, streamID,
+			// A panicking job would likely panic again, so it goes straight to dead letter
+			job.Attempts++
+			wp.provider.Reject(context.Background(), wp.queueName, job,
 				fmt.Sprintf("panic: %v", r))
 		}
 	}()
@@ -146,17 +144,11 @@ func (wp *WorkerPool) handleJob(workerID int, job *Job) {
 		workerID, job.ID, job.Type, job.Attempts+1, wp.maxRetries)
 
 	// Get handler
-	wp.mu.RLock()
-	handler, exists := wp.handlers[job.Type]
-	wp.mu.RUnlock()
+	handler, exists := wp.handlers.Handler(job.Type)
 
 	if !exists {
 		log.Printf("[WorkerPool-Worker %d] No handler for job type: %s", workerID, job.Type)
-		streamID := job.GetMetadata("stream_id")
-		if streamID == "" {
-			streamID = job.ID
-		}
-		wp.provider.Reject(context.Background(), wp.queueName, streamID,
+		wp.provider.Reject(context.Background(), wp.queueName, job,
 			fmt.Sprintf("no handler for type: %s", job.Type))
 		return
 	}
@@ -168,12 +160,6 @@ func (wp *WorkerPool) handleJob(workerID int, job *Job) {
 	// Execute handler
 	err := handler(jobCtx, job)
 
-	// Get stream ID
-	streamID := job.GetMetadata("stream_id")
-	if streamID == "" {
-		streamID = job.ID
-	}
-
 	if err != nil {
 		job.Attempts++
 		log.Printf("[WorkerPool-Worker %d] Job %s failed: %v (attempt %d/%d)",
@@ -193,12 +179,12 @@ func (wp *WorkerPool) handleJob(workerID int, job *Job) {
 			}
 
 			// Acknowledge original message
-			wp.provider.Acknowledge(context.Background(), wp.queueName, streamID)
+			wp.provider.Acknowledge(context.Background(), wp.queueName, job)
 		} else {
 			// Max retries exceeded
 			log.Printf("[WorkerPool-Worker %d] Job %s exceeded max retries, moving to dead letter",
 				workerID, job.ID)
-			wp.provider.Reject(context.Background(), wp.queueName, streamID,
+			wp.provider.Reject(context.Background(), wp.queueName, job,
 				fmt.Sprintf("max retries exceeded: %v", err))
 		}
 	} else {
@@ -208,7 +194,7 @@ func (wp *WorkerPool) handleJob(workerID int, job *Job) {
 			workerID, job.ID, duration)
 
 		// Acknowledge
-		if ackErr := wp.provider.Acknowledge(context.Background(), wp.queueName, streamID); ackErr != nil {
+		if ackErr := wp.provider.Acknowledge(context.Background(), wp.queueName, job); ackErr != nil {
 			log.Printf("[WorkerPool-Worker %d] Failed to acknowledge job %s: %v",
 				workerID, job.ID, ackErr)
 		}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// closeFriendsOfQuery selects the owners of every close friends list userID is on,
+// from the close_friends table
+func closeFriendsOfQuery(userID uuid.UUID) string {
+	return fmt.Sprintf("?friend_id=eq.%s&select=owner_id", userID)
+}
+
+// parseCloseFriendOwners reads the owner IDs out of a closeFriendsOfQuery response
+func parseCloseFriendOwners(data []byte) ([]uuid.UUID, error) {
+	var rows []struct {
+		OwnerID uuid.UUID `json:"owner_id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse close friends: %w", err)
+	}
+	ownerIDs := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ownerIDs[i] = row.OwnerID
+	}
+	return ownerIDs, nil
+}
+
+// audienceFilter is the PostgREST predicate for rows a viewer may see by visibility:
+// public ones, plus close_friends ones by ownerIDs (the viewer and the owners of the
+// close friends lists the viewer is on)
+func audienceFilter(ownerIDs []uuid.UUID) string {
+	if len(ownerIDs) == 0 {
+		return "visibility=eq.public"
+	}
+	ids := make([]string, len(ownerIDs))
+	for i, id := range ownerIDs {
+		ids[i] = id.String()
+	}
+	return fmt.Sprintf("or=(visibility.eq.public,and(visibility.eq.close_friends,user_id.in.(%s)))", strings.Join(ids, ","))
+}
@@ -12,7 +12,7 @@ type CommentRepository interface {
 	// Comment CRUD
 	CreateComment(ctx context.Context, comment *models.Comment) error
 	GetComment(ctx context.Context, commentID uuid.UUID) (*models.Comment, error)
-	GetComments(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.Comment, int, error)
+	GetComments(ctx context.Context, postID uuid.UUID, limit, offset, previewReplies int) ([]models.Comment, int, error) // Top-level only
 	GetCommentReplies(ctx context.Context, parentCommentID uuid.UUID, limit, offset int) ([]models.Comment, int, error)
 	UpdateComment(ctx context.Context, commentID uuid.UUID, content string) error
 	DeleteComment(ctx context.Context, commentID, userID uuid.UUID) error
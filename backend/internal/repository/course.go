@@ -13,13 +13,23 @@ type CourseRepository interface {
 	// Courses
 	CreateCourse(ctx context.Context, course *models.Course) error
 	GetCourseByID(ctx context.Context, id uuid.UUID) (*models.Course, error)
+	GetCoursesByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Course, error)
 	GetCourseBySlug(ctx context.Context, slug string) (*models.Course, error)
 	UpdateCourse(ctx context.Context, course *models.Course) error
 	DeleteCourse(ctx context.Context, id uuid.UUID) error
-	ListCourses(ctx context.Context, filter *models.CourseFilter, userID *uuid.UUID) ([]*models.Course, error)
+	ListCourses(ctx context.Context, filter *models.CourseFilter, userID *uuid.UUID) ([]*models.Course, int, error) // Also returns the total matching the filter
+	// Only ID, title, slug, thumbnail and creator are loaded
+	SuggestCourses(ctx context.Context, prefix string, limit int) ([]*models.Course, error)
 	GetCoursesByCreator(ctx context.Context, creatorID uuid.UUID, limit, offset int) ([]*models.Course, error)
 	GetEnrolledCourses(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Course, error)
 	IncrementViewCount(ctx context.Context, courseID uuid.UUID) error
+	GetCourseForViewer(ctx context.Context, id, viewerID uuid.UUID) (*models.Course, error) // Sets IsEnrolled and IsWishlisted
+	GetCourseAnalytics(ctx context.Context, courseID uuid.UUID, days int) (*models.CourseAnalytics, error)
+
+	// Wishlist
+	AddToWishlist(ctx context.Context, courseID, userID uuid.UUID) error
+	RemoveFromWishlist(ctx context.Context, courseID, userID uuid.UUID) (bool, error)
+	GetWishlist(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Course, int, error)
 
 	// Modules
 	CreateModule(ctx context.Context, module *models.CourseModule) error
@@ -39,13 +49,15 @@ type CourseRepository interface {
 	ReorderLessons(ctx context.Context, moduleID uuid.UUID, lessonOrders map[uuid.UUID]int) error
 
 	// Enrollments
-	CreateEnrollment(ctx context.Context, enrollment *models.CourseEnrollment) error
+	CreateEnrollment(ctx context.Context, enrollment *models.CourseEnrollment) error // Redeems enrollment.CouponCode if set
 	GetEnrollment(ctx context.Context, courseID, userID uuid.UUID) (*models.CourseEnrollment, error)
+	DeleteEnrollment(ctx context.Context, courseID, userID uuid.UUID) (bool, error) // Decrements the course's counters
 	GetEnrollmentByID(ctx context.Context, id uuid.UUID) (*models.CourseEnrollment, error)
 	UpdateEnrollment(ctx context.Context, enrollment *models.CourseEnrollment) error
 	GetEnrollmentsByCourse(ctx context.Context, courseID uuid.UUID, limit, offset int) ([]*models.CourseEnrollment, error)
 	GetEnrollmentsByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.CourseEnrollment, error)
 	CheckEnrollment(ctx context.Context, courseID, userID uuid.UUID) (bool, error)
+	ReconcileEnrollmentCounts(ctx context.Context) (int, error)
 
 	// Collaborators
 	CreateCollaborator(ctx context.Context, collaborator *models.CourseCollaborator) error
@@ -55,12 +67,13 @@ type CourseRepository interface {
 	DeleteCollaborator(ctx context.Context, courseID, userID uuid.UUID) error
 	CheckCollaborator(ctx context.Context, courseID, userID uuid.UUID) (bool, error)
 
-	// Reviews
+	// Reviews. A course's average_rating and review_count follow its reviews.
 	CreateReview(ctx context.Context, review *models.CourseReview) error
 	GetReview(ctx context.Context, courseID, userID uuid.UUID) (*models.CourseReview, error)
 	GetReviewsByCourse(ctx context.Context, courseID uuid.UUID, limit, offset int) ([]*models.CourseReview, error)
 	UpdateReview(ctx context.Context, review *models.CourseReview) error
 	DeleteReview(ctx context.Context, courseID, userID uuid.UUID) error
+	ReconcileRatings(ctx context.Context) (int, error)
 
 	// Learning Materials
 	CreateMaterial(ctx context.Context, material *models.LearningMaterial) error
@@ -73,13 +86,26 @@ type CourseRepository interface {
 	IncrementMaterialDownloadCount(ctx context.Context, materialID uuid.UUID) error
 
 	// Progress
-	CreateOrUpdateProgress(ctx context.Context, progress *models.LessonProgress) error
+	CreateOrUpdateProgress(ctx context.Context, progress *models.LessonProgress) error // Also recomputes the enrollment's progress, completing it at 100%. Completing a quiz lesson requires passing its quiz.
 	GetProgress(ctx context.Context, enrollmentID, lessonID uuid.UUID) (*models.LessonProgress, error)
 	GetProgressByEnrollment(ctx context.Context, enrollmentID uuid.UUID) ([]*models.LessonProgress, error)
 	UpdateProgress(ctx context.Context, progress *models.LessonProgress) error
 
+	// Coupons
+	CreateCoupon(ctx context.Context, coupon *models.CourseCoupon) error
+	GetCouponByCode(ctx context.Context, code string) (*models.CourseCoupon, error)
+	QuoteCoupon(ctx context.Context, courseID uuid.UUID, code string) (*models.CouponQuote, error)
+
+	// Quizzes
+	CreateQuiz(ctx context.Context, quiz *models.Quiz) error // Creates its questions too
+	GetQuizByLesson(ctx context.Context, lessonID uuid.UUID) (*models.Quiz, error)
+	CreateQuizAttempt(ctx context.Context, attempt *models.QuizAttempt) error
+	GetQuizAttempts(ctx context.Context, enrollmentID, lessonID uuid.UUID) ([]*models.QuizAttempt, error)
+	HasPassedQuiz(ctx context.Context, enrollmentID, lessonID uuid.UUID) (bool, error)
+
 	// Certificates
 	CreateCertificate(ctx context.Context, certificate *models.CourseCertificate) error
 	GetCertificate(ctx context.Context, enrollmentID uuid.UUID) (*models.CourseCertificate, error)
 	GetCertificatesByUser(ctx context.Context, userID uuid.UUID) ([]*models.CourseCertificate, error)
+	SetCertificateURL(ctx context.Context, certificateID uuid.UUID, certificateURL string) error
 }
@@ -60,6 +60,70 @@ func (r *DeliveryRepositoryAdapter) GetUnreadCount(ctx context.Context, userID u
 	return r.baseRepo.GetUnreadCount(ctx, userID)
 }
 
+func (r *DeliveryRepositoryAdapter) CreateGroupConversation(ctx context.Context, creatorID uuid.UUID, name string, memberIDs []uuid.UUID) (*models.Conversation, error) {
+	return r.baseRepo.CreateGroupConversation(ctx, creatorID, name, memberIDs)
+}
+
+func (r *DeliveryRepositoryAdapter) GetConversationMembers(ctx context.Context, conversationID uuid.UUID) ([]*models.ConversationMember, error) {
+	return r.baseRepo.GetConversationMembers(ctx, conversationID)
+}
+
+func (r *DeliveryRepositoryAdapter) AddConversationMembers(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID) error {
+	return r.baseRepo.AddConversationMembers(ctx, conversationID, userIDs)
+}
+
+func (r *DeliveryRepositoryAdapter) RemoveConversationMember(ctx context.Context, conversationID, userID uuid.UUID) error {
+	return r.baseRepo.RemoveConversationMember(ctx, conversationID, userID)
+}
+
+func (r *DeliveryRepositoryAdapter) SetConversationMemberRole(ctx context.Context, conversationID, userID uuid.UUID, role models.ConversationRole) error {
+	return r.baseRepo.SetConversationMemberRole(ctx, conversationID, userID, role)
+}
+
+func (r *DeliveryRepositoryAdapter) RenameConversation(ctx context.Context, conversationID uuid.UUID, name string) error {
+	return r.baseRepo.RenameConversation(ctx, conversationID, name)
+}
+
+func (r *DeliveryRepositoryAdapter) SetConversationMessageTTL(ctx context.Context, conversationID uuid.UUID, ttlSeconds *int) error {
+	return r.baseRepo.SetConversationMessageTTL(ctx, conversationID, ttlSeconds)
+}
+
+func (r *DeliveryRepositoryAdapter) GetExpiredMessages(ctx context.Context, limit int) ([]*models.Message, error) {
+	return r.baseRepo.GetExpiredMessages(ctx, limit)
+}
+
+func (r *DeliveryRepositoryAdapter) DeleteExpiredMessages(ctx context.Context, messageIDs []uuid.UUID) (int, error) {
+	return r.baseRepo.DeleteExpiredMessages(ctx, messageIDs)
+}
+
+func (r *DeliveryRepositoryAdapter) CreateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return r.baseRepo.CreateScheduledMessage(ctx, scheduled)
+}
+
+func (r *DeliveryRepositoryAdapter) GetScheduledMessage(ctx context.Context, id uuid.UUID) (*models.ScheduledMessage, error) {
+	return r.baseRepo.GetScheduledMessage(ctx, id)
+}
+
+func (r *DeliveryRepositoryAdapter) GetPendingScheduledMessages(ctx context.Context, conversationID, senderID uuid.UUID) ([]*models.ScheduledMessage, error) {
+	return r.baseRepo.GetPendingScheduledMessages(ctx, conversationID, senderID)
+}
+
+func (r *DeliveryRepositoryAdapter) UpdateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return r.baseRepo.UpdateScheduledMessage(ctx, scheduled)
+}
+
+func (r *DeliveryRepositoryAdapter) CancelScheduledMessage(ctx context.Context, id uuid.UUID) error {
+	return r.baseRepo.CancelScheduledMessage(ctx, id)
+}
+
+func (r *DeliveryRepositoryAdapter) ClaimDueScheduledMessages(ctx context.Context, limit int) ([]*models.ScheduledMessage, error) {
+	return r.baseRepo.ClaimDueScheduledMessages(ctx, limit)
+}
+
+func (r *DeliveryRepositoryAdapter) FinishScheduledMessage(ctx context.Context, id uuid.UUID, messageID *uuid.UUID, failureReason string) error {
+	return r.baseRepo.FinishScheduledMessage(ctx, id, messageID, failureReason)
+}
+
 func (r *DeliveryRepositoryAdapter) CreateMessage(ctx context.Context, message *models.Message) error {
 	return r.baseRepo.CreateMessage(ctx, message)
 }
@@ -80,16 +144,20 @@ func (r *DeliveryRepositoryAdapter) MarkMessagesAsRead(ctx context.Context, conv
 	return r.baseRepo.MarkMessagesAsRead(ctx, conversationID, readerID)
 }
 
+func (r *DeliveryRepositoryAdapter) MarkMessageDeliveredTo(ctx context.Context, messageID, recipientID uuid.UUID) error {
+	return r.baseRepo.MarkMessageDeliveredTo(ctx, messageID, recipientID)
+}
+
 func (r *DeliveryRepositoryAdapter) DeleteMessage(ctx context.Context, messageID, userID uuid.UUID) error {
 	return r.baseRepo.DeleteMessage(ctx, messageID, userID)
 }
 
-func (r *DeliveryRepositoryAdapter) SearchMessages(ctx context.Context, userID uuid.UUID, query string, limit, offset int) ([]*models.Message, error) {
-	return r.baseRepo.SearchMessages(ctx, userID, query, limit, offset)
+func (r *DeliveryRepositoryAdapter) SearchMessages(ctx context.Context, userID uuid.UUID, query string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error) {
+	return r.baseRepo.SearchMessages(ctx, userID, query, filter, limit, offset)
 }
 
-func (r *DeliveryRepositoryAdapter) SearchConversationMessages(ctx context.Context, conversationID uuid.UUID, query string, limit, offset int) ([]*models.Message, error) {
-	return r.baseRepo.SearchConversationMessages(ctx, conversationID, query, limit, offset)
+func (r *DeliveryRepositoryAdapter) SearchConversationMessages(ctx context.Context, conversationID, userID uuid.UUID, query string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error) {
+	return r.baseRepo.SearchConversationMessages(ctx, conversationID, userID, query, filter, limit, offset)
 }
 
 func (r *DeliveryRepositoryAdapter) PinMessage(ctx context.Context, messageID, userID uuid.UUID) error {
@@ -132,6 +200,10 @@ func (r *DeliveryRepositoryAdapter) GetMessageReactions(ctx context.Context, mes
 	return r.baseRepo.GetMessageReactions(ctx, messageID)
 }
 
+func (r *DeliveryRepositoryAdapter) GetReactionSummaries(ctx context.Context, messageIDs []uuid.UUID, viewerID uuid.UUID, maxEmojis int) (map[uuid.UUID]*models.ReactionSummary, error) {
+	return r.baseRepo.GetReactionSummaries(ctx, messageIDs, viewerID, maxEmojis)
+}
+
 func (r *DeliveryRepositoryAdapter) StarMessage(ctx context.Context, messageID, userID uuid.UUID) error {
 	return r.baseRepo.StarMessage(ctx, messageID, userID)
 }
@@ -152,15 +224,19 @@ func (r *DeliveryRepositoryAdapter) IsMessageStarred(ctx context.Context, messag
 // DELIVERY TRACKING METHODS
 // ============================================
 
-// GetPendingMessagesForUser retrieves all pending messages for a user
-// Calls the database function get_pending_messages(user_id)
-func (r *DeliveryRepositoryAdapter) GetPendingMessagesForUser(ctx context.Context, userID uuid.UUID) ([]*models.Message, error) {
+// GetPendingMessagesForUser retrieves all pending messages for a user, and with since
+// every message sent to them after it as well
+// Calls the database function get_pending_messages(user_id, since)
+func (r *DeliveryRepositoryAdapter) GetPendingMessagesForUser(ctx context.Context, userID uuid.UUID, since *time.Time) ([]*models.Message, error) {
 	// Call Supabase RPC function: get_pending_messages
 	endpoint := fmt.Sprintf("%s/rest/v1/rpc/get_pending_messages", r.supabaseURL)
 	
 	reqBody := map[string]interface{}{
 		"p_user_id": userID.String(),
 	}
+	if since != nil {
+		reqBody["p_since"] = since.UTC().Format(time.RFC3339Nano)
+	}
 	
 	reqBodyJSON, err := json.Marshal(reqBody)
 	if err != nil {
@@ -177,7 +253,7 @@ func (r *DeliveryRepositoryAdapter) GetPendingMessagesForUser(ctx context.Contex
 	req.Header.Set("Authorization", "Bearer "+r.supabaseKey)
 	req.Header.Set("Prefer", "return=representation")
 	
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := newSupabaseHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call RPC: %w", err)
@@ -202,6 +278,7 @@ func (r *DeliveryRepositoryAdapter) GetPendingMessagesForUser(ctx context.Contex
 		AttachmentURL  *string  `json:"attachment_url"`
 		AttachmentName *string  `json:"attachment_name"`
 		AttachmentType *string  `json:"attachment_type"`
+		ExpiresAt         *string    `json:"expires_at"`
 	}
 	
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
@@ -234,6 +311,11 @@ func (r *DeliveryRepositoryAdapter) GetPendingMessagesForUser(ctx context.Contex
 			AttachmentType: res.AttachmentType,
 			Status:         models.MessageStatusSent,
 		}
+		if res.ExpiresAt != nil {
+			if expiresAt, err := parseSupabaseTime(*res.ExpiresAt); err == nil {
+				msg.ExpiresAt = &expiresAt
+			}
+		}
 		messages = append(messages, msg)
 	}
 	
@@ -263,7 +345,7 @@ func (r *DeliveryRepositoryAdapter) MarkMessageDelivered(ctx context.Context, me
 	req.Header.Set("apikey", r.supabaseKey)
 	req.Header.Set("Authorization", "Bearer "+r.supabaseKey)
 	
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := newSupabaseHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to call RPC: %w", err)
@@ -303,7 +385,7 @@ func (r *DeliveryRepositoryAdapter) MarkConversationDelivered(ctx context.Contex
 	req.Header.Set("Authorization", "Bearer "+r.supabaseKey)
 	req.Header.Set("Prefer", "return=representation")
 	
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := newSupabaseHTTPClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("failed to call RPC: %w", err)
@@ -327,6 +409,88 @@ func (r *DeliveryRepositoryAdapter) MarkConversationDelivered(ctx context.Contex
 	return result.Count, nil
 }
 
+// RecordDeliveryFailure counts a failed attempt at delivering a message to recipientID
+// and returns the attempts so far. failed is true once maxAttempts is reached.
+// Calls the database function record_delivery_failure(message_id, recipient_id, error, max_attempts)
+func (r *DeliveryRepositoryAdapter) RecordDeliveryFailure(ctx context.Context, messageID, recipientID uuid.UUID, reason string, maxAttempts int) (int, bool, error) {
+	endpoint := fmt.Sprintf("%s/rest/v1/rpc/record_delivery_failure", r.supabaseURL)
+
+	reqBody := map[string]interface{}{
+		"p_message_id":   messageID.String(),
+		"p_recipient_id": recipientID.String(),
+		"p_error":        reason,
+		"p_max_attempts": maxAttempts,
+	}
+
+	reqBodyJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBodyJSON))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apikey", r.supabaseKey)
+	req.Header.Set("Authorization", "Bearer "+r.supabaseKey)
+
+	client := newSupabaseHTTPClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to call RPC: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, false, fmt.Errorf("RPC call failed: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var rows []struct {
+		Attempts int  `json:"attempts"`
+		Failed   bool `json:"failed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return 0, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, false, fmt.Errorf("record_delivery_failure returned no rows")
+	}
+
+	return rows[0].Attempts, rows[0].Failed, nil
+}
+
+// ClearDeliveryAttempts forgets the failed attempts at delivering a message to
+// recipientID, once it has got through
+func (r *DeliveryRepositoryAdapter) ClearDeliveryAttempts(ctx context.Context, messageID, recipientID uuid.UUID) error {
+	endpoint := fmt.Sprintf("%s/rest/v1/message_delivery_attempts?message_id=eq.%s&recipient_id=eq.%s",
+		r.supabaseURL, messageID.String(), recipientID.String())
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("apikey", r.supabaseKey)
+	req.Header.Set("Authorization", "Bearer "+r.supabaseKey)
+
+	client := newSupabaseHTTPClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete attempts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("delete failed: %d - %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 // CleanupDeliveredMessages deletes messages that have been delivered and past grace period
 // Calls the database function cleanup_delivered_messages()
 func (r *DeliveryRepositoryAdapter) CleanupDeliveredMessages(ctx context.Context) (int, error) {
@@ -342,7 +506,7 @@ func (r *DeliveryRepositoryAdapter) CleanupDeliveredMessages(ctx context.Context
 	req.Header.Set("Authorization", "Bearer "+r.supabaseKey)
 	req.Header.Set("Prefer", "return=representation")
 	
-	client := &http.Client{Timeout: 10 * time.Minute}
+	client := newSupabaseHTTPClient(10 * time.Minute)
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("failed to call RPC: %w", err)
@@ -380,7 +544,7 @@ func (r *DeliveryRepositoryAdapter) CleanupUndeliveredMessages(ctx context.Conte
 	req.Header.Set("Authorization", "Bearer "+r.supabaseKey)
 	req.Header.Set("Prefer", "return=representation")
 	
-	client := &http.Client{Timeout: 15 * time.Minute}
+	client := newSupabaseHTTPClient(15 * time.Minute)
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("failed to call RPC: %w", err)
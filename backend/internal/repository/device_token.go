@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// DeviceTokenRepository defines the data-access contract for push device tokens
+type DeviceTokenRepository interface {
+	SaveDeviceToken(ctx context.Context, token *models.DeviceToken) error // Moves the token if another user holds it
+	GetDeviceTokens(ctx context.Context, userID uuid.UUID) ([]*models.DeviceToken, error)
+	DeleteDeviceToken(ctx context.Context, userID uuid.UUID, token string) error
+	DeleteDeviceTokens(ctx context.Context, platform models.PushPlatform, tokens []string) error // Pruning; any owner
+}
@@ -34,6 +34,67 @@ type MessageRepository interface {
 	// GetUnreadCount returns total unread message count for a user
 	GetUnreadCount(ctx context.Context, userID uuid.UUID) (int, error)
 
+	// ============================================
+	// GROUPS
+	// ============================================
+
+	// CreateGroupConversation creates a group with creatorID as its admin and memberIDs as members
+	CreateGroupConversation(ctx context.Context, creatorID uuid.UUID, name string, memberIDs []uuid.UUID) (*models.Conversation, error)
+
+	// GetConversationMembers retrieves a group's members, including those who left
+	GetConversationMembers(ctx context.Context, conversationID uuid.UUID) ([]*models.ConversationMember, error)
+
+	// AddConversationMembers adds users to a group as members; users who left before rejoin
+	AddConversationMembers(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID) error
+
+	// RemoveConversationMember marks userID as having left the group
+	RemoveConversationMember(ctx context.Context, conversationID, userID uuid.UUID) error
+
+	// SetConversationMemberRole changes a member's role
+	SetConversationMemberRole(ctx context.Context, conversationID, userID uuid.UUID, role models.ConversationRole) error
+
+	// RenameConversation changes a group's name
+	RenameConversation(ctx context.Context, conversationID uuid.UUID, name string) error
+
+	// ============================================
+	// DISAPPEARING MESSAGES
+	// ============================================
+
+	// SetConversationMessageTTL sets how long new messages in a conversation live; nil turns it off
+	SetConversationMessageTTL(ctx context.Context, conversationID uuid.UUID, ttlSeconds *int) error
+
+	// GetExpiredMessages retrieves up to limit expired messages nobody has starred, oldest expiry first.
+	// Only IDs, type and media are loaded; media another message still uses is left out.
+	GetExpiredMessages(ctx context.Context, limit int) ([]*models.Message, error)
+
+	// DeleteExpiredMessages hard-deletes those of messageIDs that are still expired and unstarred
+	DeleteExpiredMessages(ctx context.Context, messageIDs []uuid.UUID) (int, error)
+
+	// ============================================
+	// SCHEDULED MESSAGES
+	// ============================================
+
+	// CreateScheduledMessage saves a message to send later, filling in its ID and timestamps
+	CreateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error
+
+	// GetScheduledMessage retrieves a scheduled message by ID
+	GetScheduledMessage(ctx context.Context, id uuid.UUID) (*models.ScheduledMessage, error)
+
+	// GetPendingScheduledMessages retrieves senderID's unsent scheduled messages in a conversation, soonest first
+	GetPendingScheduledMessages(ctx context.Context, conversationID, senderID uuid.UUID) ([]*models.ScheduledMessage, error)
+
+	// UpdateScheduledMessage saves a pending scheduled message's message and send time
+	UpdateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error
+
+	// CancelScheduledMessage cancels a pending scheduled message
+	CancelScheduledMessage(ctx context.Context, id uuid.UUID) error
+
+	// ClaimDueScheduledMessages claims up to limit scheduled messages that are due for sending, soonest first
+	ClaimDueScheduledMessages(ctx context.Context, limit int) ([]*models.ScheduledMessage, error)
+
+	// FinishScheduledMessage records a claimed scheduled message as sent as messageID, or failed when it's nil
+	FinishScheduledMessage(ctx context.Context, id uuid.UUID, messageID *uuid.UUID, failureReason string) error
+
 	// ============================================
 	// MESSAGES
 	// ============================================
@@ -48,20 +109,27 @@ type MessageRepository interface {
 	// Returns messages in descending order (newest first) but should be reversed by caller
 	GetConversationMessages(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*models.Message, error)
 
+	// GetConversationMessagesAfter retrieves up to limit messages posted after the given
+	// one, oldest first, ordered by created_at then id so that catching up is exact
+	GetConversationMessagesAfter(ctx context.Context, conversationID uuid.UUID, after *models.Message, limit int) ([]*models.Message, error)
+
 	// UpdateMessageStatus updates the delivery/read status of a message
 	UpdateMessageStatus(ctx context.Context, messageID uuid.UUID, status models.MessageStatus) error
 
 	// MarkMessagesAsRead marks all unread messages in a conversation as read
 	MarkMessagesAsRead(ctx context.Context, conversationID, readerID uuid.UUID) error
 
+	// MarkMessageDeliveredTo marks a message as delivered to one recipient
+	MarkMessageDeliveredTo(ctx context.Context, messageID, recipientID uuid.UUID) error
+
 	// DeleteMessage soft-deletes a message for a user
 	DeleteMessage(ctx context.Context, messageID, userID uuid.UUID) error
 
-	// SearchMessages searches messages by content for a user
-	SearchMessages(ctx context.Context, userID uuid.UUID, query string, limit, offset int) ([]*models.Message, error)
+	// SearchMessages full-text searches the messages a user can read, best matches first, returning a page and the total matches
+	SearchMessages(ctx context.Context, userID uuid.UUID, query string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error)
 
-	// SearchConversationMessages searches messages within a specific conversation
-	SearchConversationMessages(ctx context.Context, conversationID uuid.UUID, query string, limit, offset int) ([]*models.Message, error)
+	// SearchConversationMessages full-text searches one conversation as a user sees it, returning a page and the total matches
+	SearchConversationMessages(ctx context.Context, conversationID, userID uuid.UUID, query string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error)
 
 	// ============================================
 	// PIN MESSAGES
@@ -73,7 +141,7 @@ type MessageRepository interface {
 	// UnpinMessage unpins a message
 	UnpinMessage(ctx context.Context, messageID, userID uuid.UUID) error
 
-	// GetPinnedMessages retrieves all pinned messages in a conversation
+	// GetPinnedMessages retrieves all pinned messages in a conversation, most recently pinned first
 	GetPinnedMessages(ctx context.Context, conversationID uuid.UUID) ([]*models.Message, error)
 
 	// ============================================
@@ -109,6 +177,11 @@ type MessageRepository interface {
 	// GetMessageReactions retrieves all reactions for a message
 	GetMessageReactions(ctx context.Context, messageID uuid.UUID) ([]*models.MessageReaction, error)
 
+	// GetReactionSummaries counts the reactions on each of messageIDs by emoji, as
+	// viewerID sees them, keeping each message's maxEmojis most used emojis. Messages
+	// nobody reacted to are left out.
+	GetReactionSummaries(ctx context.Context, messageIDs []uuid.UUID, viewerID uuid.UUID, maxEmojis int) (map[uuid.UUID]*models.ReactionSummary, error)
+
 	// ============================================
 	// STARRED MESSAGES
 	// ============================================
@@ -13,11 +13,13 @@ type NotificationRepository interface {
 	// Notification CRUD
 	Create(ctx context.Context, notification *models.Notification) error
 	GetByID(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*models.Notification, error)
-	GetByUser(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory, unread *bool, limit int, offset int) ([]*models.Notification, int, error)
+	GetByUser(ctx context.Context, userID uuid.UUID, filter models.NotificationFilter) ([]*models.Notification, int, error)
 	GetUnreadCount(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory) (int, error)
+	GetUnseenCount(ctx context.Context, userID uuid.UUID) (int, error) // Unread and not yet seen: the badge count
 	GetCategoryCounts(ctx context.Context, userID uuid.UUID) (map[string]int, error)
 	MarkAsRead(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
-	MarkAllAsRead(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory) error
+	MarkAllAsRead(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory) (int, error) // Returns how many were marked
+	MarkAllSeen(ctx context.Context, userID uuid.UUID) (int, error)                                          // Clears the badge without reading; returns how many
 	Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
 	UpdateActionTaken(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
 
@@ -30,4 +32,5 @@ type NotificationRepository interface {
 
 	// Batch operations for digest
 	GetUnreadForDigest(ctx context.Context, frequency models.EmailFrequency) (map[uuid.UUID][]*models.Notification, error)
+	MarkDigestSent(ctx context.Context, userID uuid.UUID, notificationIDs []uuid.UUID) error
 }
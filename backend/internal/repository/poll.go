@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"histeeria-backend/internal/models"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,14 +15,18 @@ type PollRepository interface {
 	GetPoll(ctx context.Context, pollID uuid.UUID) (*models.Poll, error)
 	GetPollByPostID(ctx context.Context, postID uuid.UUID) (*models.Poll, error)
 	ClosePoll(ctx context.Context, pollID uuid.UUID) error
+	CloseExpiredPolls(ctx context.Context, now time.Time) ([]models.Poll, error)
 
 	// Voting
 	VotePoll(ctx context.Context, pollID, optionID, userID uuid.UUID) error
 	ChangeVote(ctx context.Context, pollID, newOptionID, userID uuid.UUID) error
 	GetUserVote(ctx context.Context, pollID, userID uuid.UUID) (*uuid.UUID, error)
+	GetUserVotes(ctx context.Context, pollID, userID uuid.UUID) ([]uuid.UUID, error)
 	HasUserVoted(ctx context.Context, pollID, userID uuid.UUID) (bool, error)
+	GetPollVoterIDs(ctx context.Context, pollID uuid.UUID) ([]uuid.UUID, error)
 
 	// Results
 	GetPollResults(ctx context.Context, pollID, viewerID uuid.UUID) (*models.PollResults, error)
+	GetPollResultsBatch(ctx context.Context, pollIDs []uuid.UUID, viewerID uuid.UUID) ([]models.PollResults, error)
 	GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]models.PollOption, error)
 }
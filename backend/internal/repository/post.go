@@ -14,32 +14,52 @@ type PostRepository interface {
 	CreatePost(ctx context.Context, post *models.Post) error
 	GetPost(ctx context.Context, postID, viewerID uuid.UUID) (*models.Post, error)
 	GetPostByID(ctx context.Context, postID uuid.UUID) (*models.Post, error)
-	GetUserPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
-	UpdatePost(ctx context.Context, postID uuid.UUID, updates map[string]interface{}) error
+	GetUserPosts(ctx context.Context, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error)
+	UpdatePost(ctx context.Context, postID uuid.UUID, updates map[string]interface{}) (*models.Post, error)
+	CountPinnedPosts(ctx context.Context, userID uuid.UUID) (int, error)
+	UpdateDraft(ctx context.Context, postID uuid.UUID, expectedVersion int, updates map[string]interface{}) (bool, error)
+	PublishDueScheduledPosts(ctx context.Context, now time.Time, limit int) ([]models.Post, error)
 	DeletePost(ctx context.Context, postID, userID uuid.UUID) error
 
-	// Feed queries
-	GetHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
-	GetFollowingFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
-	GetExploreFeed(ctx context.Context, userID uuid.UUID, limit, offset int, filter string) ([]models.Post, int, error)
-	GetHashtagFeed(ctx context.Context, hashtag string, limit, offset int) ([]models.Post, int, error)
+	// Uploaded media. Variants are resized image URLs keyed by size in pixels.
+	SaveMediaVariants(ctx context.Context, userID uuid.UUID, mediaURL string, variants map[string]string) error
+	GetMediaVariants(ctx context.Context, userID uuid.UUID, mediaURLs []string) (map[string]map[string]string, error)
+
+	// Feed queries. Reads made without withCount skip the row count and report only
+	// whether another page follows. hideInteracted leaves out posts the user has
+	// liked, saved or commented on.
+	GetHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int, hideInteracted, withCount bool) ([]models.Post, models.Page, error)
+	GetFollowingFeed(ctx context.Context, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error)
+	GetExploreFeed(ctx context.Context, userID uuid.UUID, limit, offset int, filter string, hideInteracted, withCount bool) ([]models.Post, models.Page, error)
+	GetHashtagFeed(ctx context.Context, hashtag string, limit, offset int, withCount bool) ([]models.Post, models.Page, error)
+	RecordFeedRanking(ctx context.Context, userID uuid.UUID, feed, variant string, postIDs []uuid.UUID) error
 
 	// Engagement
-	LikePost(ctx context.Context, postID, userID uuid.UUID) error
-	UnlikePost(ctx context.Context, postID, userID uuid.UUID) error
+	LikePost(ctx context.Context, postID, userID uuid.UUID) (int, error)         // Returns the new likes_count
+	UnlikePost(ctx context.Context, postID, userID uuid.UUID) (int, bool, error) // New likes_count and whether a like was removed
+	ReconcileLikeCounts(ctx context.Context) (int, error)
 	IsPostLikedByUser(ctx context.Context, postID, userID uuid.UUID) (bool, error)
 	GetPostLikes(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.User, int, error)
 
-	SharePost(ctx context.Context, postID, userID uuid.UUID, comment string) error
-	UnsharePost(ctx context.Context, postID, userID uuid.UUID) error
+	SharePost(ctx context.Context, postID, userID uuid.UUID, comment string) (int, bool, error) // New shares_count and whether a share was added
+	UnsharePost(ctx context.Context, postID, userID uuid.UUID) (int, bool, error)               // New shares_count and whether a share was removed
+	IsPostSharedByUser(ctx context.Context, postID, userID uuid.UUID) (bool, error)
 
 	SavePost(ctx context.Context, postID, userID uuid.UUID, collection string) error
-	UnsavePost(ctx context.Context, postID, userID uuid.UUID) error
+	UnsavePost(ctx context.Context, postID, userID uuid.UUID) (bool, error) // Reports whether a bookmark was removed
 	IsPostSavedByUser(ctx context.Context, postID, userID uuid.UUID) (bool, error)
 	GetSavedPosts(ctx context.Context, userID uuid.UUID, collection string, limit, offset int) ([]models.Post, int, error)
 
+	// Saved collections
+	GetSavedCollections(ctx context.Context, userID uuid.UUID) ([]models.SavedCollection, error)
+	SavedCollectionExists(ctx context.Context, userID uuid.UUID, name string) (bool, error)
+	CreateSavedCollection(ctx context.Context, userID uuid.UUID, name string) error
+	RenameSavedCollection(ctx context.Context, userID uuid.UUID, name, newName string) error
+	DeleteSavedCollection(ctx context.Context, userID uuid.UUID, name string, unsave bool) error
+
 	// Stats
 	IncrementViews(ctx context.Context, postID, userID uuid.UUID) error
+	FlushPostViews(ctx context.Context) (int, error)
 	RecordProfileVisitFromPost(ctx context.Context, postID, visitorID, profileOwnerID uuid.UUID) error
 	
 	// Insights
@@ -51,14 +71,18 @@ type PostRepository interface {
 	ExtractAndCreateHashtags(ctx context.Context, postID uuid.UUID, content string) error
 	GetHashtagByTag(ctx context.Context, tag string) (*HashtagInfo, error)
 	GetTrendingHashtags(ctx context.Context, limit int) ([]HashtagInfo, error)
+	SearchHashtags(ctx context.Context, query string, limit, offset int) ([]HashtagInfo, error)
+	SuggestHashtags(ctx context.Context, prefix string, limit int) ([]HashtagInfo, error)
+	RecomputeTrendingHashtags(ctx context.Context, halfLife, window time.Duration) (int, error)
 	FollowHashtag(ctx context.Context, hashtagID, userID uuid.UUID) error
 	UnfollowHashtag(ctx context.Context, hashtagID, userID uuid.UUID) error
 
 	// Mentions
-	ExtractAndCreateMentions(ctx context.Context, postID uuid.UUID, content string) error
+	ExtractAndCreateMentions(ctx context.Context, postID uuid.UUID, content string) ([]uuid.UUID, error)
+	ResolveMentions(ctx context.Context, content string) ([]uuid.UUID, error)
 
 	// Search
-	SearchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
+	SearchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error)
 
 	// User Activity
 	GetUserLikedPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
@@ -66,6 +90,10 @@ type PostRepository interface {
 	GetUserDeletedPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error)
 	GetUserSharedPosts(ctx context.Context, userID uuid.UUID, postType string, limit, offset int) ([]models.Post, int, error)
 
+	// Retention
+	GetPurgeablePosts(ctx context.Context, deletedBefore time.Time, limit int) ([]models.Post, error) // ID and media only
+	PurgePosts(ctx context.Context, postIDs []uuid.UUID) (int, error)
+
 	// Author loading
 	LoadPostAuthor(ctx context.Context, post *models.Post) error
 
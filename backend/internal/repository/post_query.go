@@ -0,0 +1,45 @@
+package repository
+
+// postScope selects which posts a query is allowed to return. Every read from the
+// posts table goes through postQuery so the visibility rule can't drift between
+// feed, search, hashtag and profile paths.
+type postScope int
+
+const (
+	// postScopeExisting excludes soft-deleted posts only (single-post lookups, where
+	// the service decides whether the viewer may see a draft)
+	postScopeExisting postScope = iota
+	// postScopeVisible is published, non-draft, non-deleted posts (profiles, hashtags, search, saved/liked lists)
+	postScopeVisible
+	// postScopePublic is visible posts with public visibility (explore, home fallback, trending)
+	postScopePublic
+)
+
+// PostgREST predicates for each scope
+const (
+	notDeletedPostsFilter = "deleted_at=is.null"
+	visiblePostsFilter    = "is_published=eq.true&is_draft=eq.false&" + notDeletedPostsFilter
+	publicPostsFilter     = visiblePostsFilter + "&visibility=eq.public"
+)
+
+// predicate returns the PostgREST filter for the scope
+func (s postScope) predicate() string {
+	switch s {
+	case postScopeVisible:
+		return visiblePostsFilter
+	case postScopePublic:
+		return publicPostsFilter
+	default:
+		return notDeletedPostsFilter
+	}
+}
+
+// postQuery builds a posts table query string: the scope's predicates followed by
+// params (other filters, select, order, pagination), e.g.
+// postQuery(postScopeVisible, "user_id=eq.<id>&order=created_at.desc")
+func postQuery(scope postScope, params string) string {
+	if params == "" {
+		return "?" + scope.predicate()
+	}
+	return "?" + scope.predicate() + "&" + params
+}
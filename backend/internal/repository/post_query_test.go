@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestPostQueryPredicates(t *testing.T) {
+	tests := []struct {
+		scope  postScope
+		params string
+		want   string
+	}{
+		{postScopeExisting, "", "?deleted_at=is.null"},
+		{postScopeExisting, "id=eq.1", "?deleted_at=is.null&id=eq.1"},
+		{postScopeVisible, "user_id=eq.2", "?is_published=eq.true&is_draft=eq.false&deleted_at=is.null&user_id=eq.2"},
+		{postScopePublic, "limit=5", "?is_published=eq.true&is_draft=eq.false&deleted_at=is.null&visibility=eq.public&limit=5"},
+	}
+
+	for _, tt := range tests {
+		if got := postQuery(tt.scope, tt.params); got != tt.want {
+			t.Errorf("postQuery(%d, %q) = %q, want %q", tt.scope, tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestDeletedPostNeverAppears(t *testing.T) {
+	live, deleted := uuid.New(), uuid.New()
+	author := uuid.New().String()
+	row := func(id uuid.UUID, deletedAt string) string {
+		return `{"id": "` + id.String() + `", "user_id": "` + author + `", "content": "hi", "post_type": "post",
+			"visibility": "public", "is_published": true, "deleted_at": ` + deletedAt + `}`
+	}
+	refs := `[{"post_id": "` + live.String() + `"}, {"post_id": "` + deleted.String() + `"}]`
+
+	// Serves both posts and drops the deleted one only when the query asks PostgREST to
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, _ := url.QueryUnescape(r.URL.RawQuery)
+		switch strings.TrimPrefix(r.URL.Path, "/rest/v1/") {
+		case "posts":
+			rows := []string{row(live, "null")}
+			if !strings.Contains(query, "deleted_at=is.null") {
+				rows = append(rows, row(deleted, `"2026-01-01T00:00:00Z"`))
+			}
+			w.Write([]byte("[" + strings.Join(rows, ",") + "]"))
+		case "hashtags":
+			w.Write([]byte(`[{"id": "` + uuid.NewString() + `"}]`))
+		case "post_hashtags", "saved_posts":
+			w.Write([]byte(refs))
+		default:
+			w.Write([]byte("[]"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	repo := NewSupabasePostRepository(server.URL, "key")
+	ctx := context.Background()
+	viewer := uuid.New()
+
+	surfaces := map[string]func() ([]models.Post, error){
+		"profile": func() ([]models.Post, error) {
+			posts, _, err := repo.GetUserPosts(ctx, uuid.MustParse(author), 10, 0, false)
+			return posts, err
+		},
+		"explore": func() ([]models.Post, error) {
+			posts, _, err := repo.GetExploreFeed(ctx, viewer, 10, 0, "", false, false)
+			return posts, err
+		},
+		"hashtag": func() ([]models.Post, error) {
+			posts, _, err := repo.GetHashtagFeed(ctx, "go", 10, 0, false)
+			return posts, err
+		},
+		"search": func() ([]models.Post, error) {
+			posts, _, err := repo.SearchPosts(ctx, "hi", viewer, 10, 0, false)
+			return posts, err
+		},
+		"saved": func() ([]models.Post, error) {
+			posts, _, err := repo.GetSavedPosts(ctx, viewer, "", 10, 0)
+			return posts, err
+		},
+		"single post": func() ([]models.Post, error) {
+			var posts []models.Post
+			for _, id := range []uuid.UUID{live, deleted} {
+				if post, err := repo.GetPost(ctx, id, viewer); err == nil && post.ID == id {
+					posts = append(posts, *post)
+				}
+			}
+			return posts, nil
+		},
+	}
+
+	for name, load := range surfaces {
+		t.Run(name, func(t *testing.T) {
+			posts, err := load()
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if len(posts) != 1 || posts[0].ID != live {
+				t.Fatalf("got %d posts, want only the live one", len(posts))
+			}
+		})
+	}
+}
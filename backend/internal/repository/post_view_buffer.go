@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxBufferedPostViews triggers an early flush so a traffic spike can't grow the buffer
+// without bound between scheduled flushes
+const maxBufferedPostViews = 5000
+
+// postViewKey identifies a unique (post, viewer) view
+type postViewKey struct {
+	PostID uuid.UUID
+	UserID uuid.UUID
+}
+
+// postViewBuffer collects unique post views in memory until they're flushed in one
+// batch. Repeat views of the same post by the same user collapse to the first one.
+type postViewBuffer struct {
+	mu    sync.Mutex
+	views map[postViewKey]time.Time
+}
+
+func newPostViewBuffer() *postViewBuffer {
+	return &postViewBuffer{views: make(map[postViewKey]time.Time)}
+}
+
+// add records a view and reports whether the buffer is now full
+func (b *postViewBuffer) add(postID, userID uuid.UUID, viewedAt time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := postViewKey{PostID: postID, UserID: userID}
+	if _, exists := b.views[key]; !exists {
+		b.views[key] = viewedAt
+	}
+	return len(b.views) >= maxBufferedPostViews
+}
+
+// drain removes and returns everything buffered so far
+func (b *postViewBuffer) drain() map[postViewKey]time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	views := b.views
+	b.views = make(map[postViewKey]time.Time)
+	return views
+}
+
+// restore puts back views whose flush failed, keeping any newer entry for the same key
+func (b *postViewBuffer) restore(views map[postViewKey]time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, viewedAt := range views {
+		if _, exists := b.views[key]; !exists {
+			b.views[key] = viewedAt
+		}
+	}
+}
@@ -23,7 +23,19 @@ type RelationshipRepository interface {
 	GetRelationshipList(ctx context.Context, userID uuid.UUID, listType string, page, limit int) ([]*models.RelationshipListItem, int, error)
 	GetRelationshipStats(ctx context.Context, userID uuid.UUID) (*models.RelationshipStats, error)
 	GetRelationshipStatus(ctx context.Context, fromUserID, toUserID uuid.UUID) (*models.RelationshipStatusResponse, error)
-	GetPendingRequests(ctx context.Context, userID uuid.UUID, incoming bool, page, limit int) ([]*models.RelationshipRequest, int, error)
+	GetPendingRequests(ctx context.Context, userID uuid.UUID, relationshipType models.RelationshipType, incoming bool, page, limit int) ([]*models.RelationshipRequest, int, error)
+
+	// Follow lists, without users blocked either way with viewerID (uuid.Nil for none)
+	GetFollowList(ctx context.Context, userID, viewerID uuid.UUID, followers bool, page, limit int) ([]*models.FollowListUser, int, error)
+	GetMutualFollowers(ctx context.Context, viewerID, targetID uuid.UUID, page, limit int) ([]*models.FollowListUser, int, error)
+	GetFollowStates(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*models.ViewerFollowStatus, error)
+
+	// Close friends lists, the audience of close_friends posts and statuses
+	AddCloseFriend(ctx context.Context, ownerID, friendID uuid.UUID) error
+	RemoveCloseFriend(ctx context.Context, ownerID, friendID uuid.UUID) error
+	GetCloseFriends(ctx context.Context, ownerID uuid.UUID, page, limit int) ([]*models.CloseFriend, int, error)
+	IsCloseFriend(ctx context.Context, ownerID, friendID uuid.UUID) (bool, error)
+	GetCloseFriendsOf(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 
 	// Rate limiting
 	GetRateLimit(ctx context.Context, userID uuid.UUID, actionType string) (*models.RelationshipRateLimit, error)
@@ -12,11 +12,14 @@ import (
 type SessionRepository interface {
 	CreateSession(ctx context.Context, session *models.UserSession) error
 	GetSessionByID(ctx context.Context, sessionID uuid.UUID) (*models.UserSession, error)
-	GetSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.UserSession, error)
+	GetSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.UserSession, error) // Current refresh tokens only
 	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.UserSession, error)
+	MarkSessionReplaced(ctx context.Context, sessionID uuid.UUID) (bool, error) // False if it was already replaced
 	DeleteSession(ctx context.Context, sessionID uuid.UUID) error
 	DeleteSessionByTokenHash(ctx context.Context, tokenHash string) error
-	DeleteAllUserSessions(ctx context.Context, userID uuid.UUID, exceptSessionID *uuid.UUID) error
+	DeleteSessionFamily(ctx context.Context, familyID uuid.UUID) error
+	DeleteUserSessionFamily(ctx context.Context, userID, familyID uuid.UUID) (bool, error) // False if the user has no such session
+	DeleteAllUserSessions(ctx context.Context, userID uuid.UUID, exceptFamilyID *uuid.UUID) error
 	CleanupExpiredSessions(ctx context.Context) error
 }
 
@@ -23,6 +23,12 @@ type StatusRepository interface {
 	GetUserStatuses(ctx context.Context, userID, viewerID uuid.UUID, limit int) ([]models.Status, error)
 	GetStatusesForFeed(ctx context.Context, viewerID uuid.UUID, limit int) ([]models.Status, error)
 	DeleteStatus(ctx context.Context, statusID, userID uuid.UUID) error
+	GetStatusCreationTimesSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]time.Time, error)
+
+	// Expiry
+	GetExpiredStatuses(ctx context.Context, limit int) ([]models.Status, error)
+	PurgeExpiredStatuses(ctx context.Context, statusIDs []uuid.UUID) (int, error)
+	GetStatusSummaries(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.StatusSummary, error)
 
 	// Views
 	CreateStatusView(ctx context.Context, statusID, userID uuid.UUID) error
@@ -55,9 +61,7 @@ func NewSupabaseStatusRepository(supabaseURL, serviceKey string) *SupabaseStatus
 	return &SupabaseStatusRepository{
 		supabaseURL: supabaseURL,
 		serviceKey:  serviceKey,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:      newSupabaseHTTPClient(30 * time.Second),
 	}
 }
 
@@ -183,6 +187,23 @@ func (r *SupabaseStatusRepository) parseStatusFromMap(statusData map[string]inte
 	if bgColor, ok := statusData["background_color"].(string); ok {
 		status.BackgroundColor = bgColor
 	}
+	status.Visibility = models.VisibilityPublic
+	if visibility, ok := statusData["visibility"].(string); ok && visibility != "" {
+		status.Visibility = visibility
+	}
+
+	// Interaction settings (rows created before they existed allow everything)
+	status.AllowReactions = true
+	status.AllowReplies = true
+	if allowReactions, ok := statusData["allow_reactions"].(bool); ok {
+		status.AllowReactions = allowReactions
+	}
+	if allowReplies, ok := statusData["allow_replies"].(bool); ok {
+		status.AllowReplies = allowReplies
+	}
+	if engagementPrivate, ok := statusData["engagement_private"].(bool); ok {
+		status.EngagementPrivate = engagementPrivate
+	}
 
 	// Parse numeric fields
 	if viewsCount, ok := statusData["views_count"].(float64); ok {
@@ -227,14 +248,18 @@ func (r *SupabaseStatusRepository) CreateStatus(ctx context.Context, status *mod
 	}
 
 	payload := map[string]interface{}{
-		"id":               status.ID,
-		"user_id":          status.UserID,
-		"status_type":      status.StatusType,
-		"content":          status.Content,
-		"media_url":        status.MediaURL,
-		"media_type":       status.MediaType,
-		"background_color": status.BackgroundColor,
-		"expires_at":       status.ExpiresAt.Format(time.RFC3339),
+		"id":                 status.ID,
+		"user_id":            status.UserID,
+		"status_type":        status.StatusType,
+		"content":            status.Content,
+		"media_url":          status.MediaURL,
+		"media_type":         status.MediaType,
+		"background_color":   status.BackgroundColor,
+		"visibility":         status.Visibility,
+		"allow_reactions":    status.AllowReactions,
+		"allow_replies":      status.AllowReplies,
+		"engagement_private": status.EngagementPrivate,
+		"expires_at":         status.ExpiresAt.Format(time.RFC3339),
 	}
 
 	data, err := r.makeRequest("POST", "statuses", "", payload)
@@ -442,9 +467,22 @@ func (r *SupabaseStatusRepository) GetStatusesForFeed(ctx context.Context, viewe
 		return []models.Status{}, nil
 	}
 
+	// Close friends statuses only reach the viewer from lists they're on
+	audienceOwners := []uuid.UUID{viewerID}
+	cfData, err := r.makeRequest("GET", "close_friends", closeFriendsOfQuery(viewerID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get close friends lists: %w", err)
+	}
+	listOwners, err := parseCloseFriendOwners(cfData)
+	if err != nil {
+		return nil, err
+	}
+	audienceOwners = append(audienceOwners, listOwners...)
+
 	// Query statuses from these users with author info
-	statusQuery := fmt.Sprintf("?user_id=in.(%s)&expires_at=gt.%s&order=created_at.desc&limit=%d&select=*,users!statuses_user_id_fkey(id,username,display_name,profile_picture)",
+	statusQuery := fmt.Sprintf("?user_id=in.(%s)&%s&expires_at=gt.%s&order=created_at.desc&limit=%d&select=*,users!statuses_user_id_fkey(id,username,display_name,profile_picture)",
 		strings.Join(userIDs, ","),
+		audienceFilter(audienceOwners),
 		url.QueryEscape(time.Now().Format(time.RFC3339)),
 		limit)
 
@@ -518,6 +556,137 @@ func (r *SupabaseStatusRepository) DeleteStatus(ctx context.Context, statusID, u
 	return nil
 }
 
+// GetStatusCreationTimesSince returns when the user's statuses created after since were
+// posted, oldest first
+func (r *SupabaseStatusRepository) GetStatusCreationTimesSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]time.Time, error) {
+	query := fmt.Sprintf("?user_id=eq.%s&created_at=gt.%s&order=created_at.asc&select=created_at",
+		userID.String(), url.QueryEscape(since.UTC().Format(time.RFC3339)))
+
+	data, err := r.makeRequest("GET", "statuses", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent statuses: %w", err)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal recent statuses: %w", err)
+	}
+
+	times := make([]time.Time, 0, len(rows))
+	for _, row := range rows {
+		if createdAt := parseStatusTime(row["created_at"]); createdAt != nil {
+			times = append(times, *createdAt)
+		}
+	}
+
+	return times, nil
+}
+
+// GetExpiredStatuses returns up to limit statuses past their expiry, longest expired
+// first, with just what's needed to delete their media
+func (r *SupabaseStatusRepository) GetExpiredStatuses(ctx context.Context, limit int) ([]models.Status, error) {
+	query := fmt.Sprintf("?expires_at=lte.%s&order=expires_at.asc&limit=%d&select=id,user_id,media_url",
+		url.QueryEscape(time.Now().Format(time.RFC3339)), limit)
+
+	data, err := r.makeRequest("GET", "statuses", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired statuses: %w", err)
+	}
+
+	var rows []struct {
+		ID       uuid.UUID `json:"id"`
+		UserID   uuid.UUID `json:"user_id"`
+		MediaURL *string   `json:"media_url"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal expired statuses: %w", err)
+	}
+
+	statuses := make([]models.Status, len(rows))
+	for i, row := range rows {
+		statuses[i] = models.Status{ID: row.ID, UserID: row.UserID, MediaURL: row.MediaURL}
+	}
+	return statuses, nil
+}
+
+// PurgeExpiredStatuses permanently deletes expired statuses, with their views,
+// reactions and comments, in one transaction. A summary of each is kept for its
+// creator. Returns how many were deleted.
+func (r *SupabaseStatusRepository) PurgeExpiredStatuses(ctx context.Context, statusIDs []uuid.UUID) (int, error) {
+	if len(statusIDs) == 0 {
+		return 0, nil
+	}
+
+	data, err := r.makeRequest("POST", "rpc/purge_expired_statuses", "", map[string]interface{}{
+		"p_status_ids": statusIDs,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired statuses: %w", err)
+	}
+
+	var purged int
+	if err := json.Unmarshal(data, &purged); err != nil {
+		return 0, fmt.Errorf("failed to decode purge result: %w", err)
+	}
+	return purged, nil
+}
+
+// GetStatusSummaries returns a page of the summaries of userID's purged statuses,
+// newest status first
+func (r *SupabaseStatusRepository) GetStatusSummaries(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.StatusSummary, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := fmt.Sprintf("?user_id=eq.%s&order=created_at.desc&limit=%d&offset=%d",
+		userID.String(), limit, offset)
+
+	data, err := r.makeRequest("GET", "status_summaries", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status summaries: %w", err)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status summaries: %w", err)
+	}
+
+	summaries := make([]models.StatusSummary, 0, len(rows))
+	for _, row := range rows {
+		summary := models.StatusSummary{
+			UserID:    userID,
+			Reactions: make(map[string]int),
+			CreatedAt: parseRequiredStatusTime(row["created_at"]),
+			ExpiredAt: parseRequiredStatusTime(row["expired_at"]),
+		}
+		if id, ok := row["status_id"].(string); ok {
+			summary.StatusID, _ = uuid.Parse(id)
+		}
+		if statusType, ok := row["status_type"].(string); ok {
+			summary.StatusType = statusType
+		}
+		if count, ok := row["views_count"].(float64); ok {
+			summary.ViewsCount = int(count)
+		}
+		if count, ok := row["reactions_count"].(float64); ok {
+			summary.ReactionsCount = int(count)
+		}
+		if count, ok := row["comments_count"].(float64); ok {
+			summary.CommentsCount = int(count)
+		}
+		if reactions, ok := row["reactions"].(map[string]interface{}); ok {
+			for emoji, count := range reactions {
+				if n, ok := count.(float64); ok {
+					summary.Reactions[emoji] = int(n)
+				}
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
 // CreateStatusView records a view on a status
 func (r *SupabaseStatusRepository) CreateStatusView(ctx context.Context, statusID, userID uuid.UUID) error {
 	// Check if already viewed (idempotent)
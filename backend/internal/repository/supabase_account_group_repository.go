@@ -26,7 +26,7 @@ func NewSupabaseAccountGroupRepository(supabaseURL, supabaseAPIKey string) Accou
 	return &SupabaseAccountGroupRepository{
 		supabaseURL:    supabaseURL,
 		supabaseAPIKey: supabaseAPIKey,
-		httpClient:     &http.Client{},
+		httpClient:     newSupabaseHTTPClient(0),
 	}
 }
 
@@ -29,7 +29,7 @@ func NewSupabaseCompanyRepository(baseURL, serviceRoleKey string) *SupabaseCompa
 	return &SupabaseCompanyRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -320,7 +320,7 @@ func NewSupabaseCertificationRepository(baseURL, serviceRoleKey string) *Supabas
 	return &SupabaseCertificationRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -602,7 +602,7 @@ func NewSupabaseSkillRepository(baseURL, serviceRoleKey string) *SupabaseSkillRe
 	return &SupabaseSkillRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
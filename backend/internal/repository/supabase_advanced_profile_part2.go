@@ -29,7 +29,7 @@ func NewSupabaseLanguageRepository(baseURL, serviceRoleKey string) *SupabaseLang
 	return &SupabaseLanguageRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -236,7 +236,7 @@ func NewSupabaseVolunteeringRepository(baseURL, serviceRoleKey string) *Supabase
 	return &SupabaseVolunteeringRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -475,7 +475,7 @@ func NewSupabasePublicationRepository(baseURL, serviceRoleKey string) *SupabaseP
 	return &SupabasePublicationRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -732,7 +732,7 @@ func NewSupabaseInterestRepository(baseURL, serviceRoleKey string) *SupabaseInte
 	return &SupabaseInterestRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -939,7 +939,7 @@ func NewSupabaseAchievementRepository(baseURL, serviceRoleKey string) *SupabaseA
 	return &SupabaseAchievementRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
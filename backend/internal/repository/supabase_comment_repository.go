@@ -126,6 +126,9 @@ func (r *SupabaseCommentRepository) parseCommentFromMap(commentData map[string]i
 	if mediaType, ok := commentData["media_type"].(string); ok {
 		comment.MediaType = mediaType
 	}
+	if language, ok := commentData["language"].(string); ok {
+		comment.Language = language
+	}
 
 	// Parse integers
 	if likesCount, ok := commentData["likes_count"].(float64); ok {
@@ -214,6 +217,9 @@ func (r *SupabaseCommentRepository) CreateComment(ctx context.Context, comment *
 	if comment.MediaType != "" {
 		payload["media_type"] = comment.MediaType
 	}
+	if comment.Language != "" {
+		payload["language"] = comment.Language
+	}
 
 	data, err := r.makeRequest("POST", "post_comments", "", payload)
 	if err != nil {
@@ -250,7 +256,7 @@ func (r *SupabaseCommentRepository) GetComment(ctx context.Context, commentID uu
 	}
 
 	if len(comments) == 0 {
-		return nil, fmt.Errorf("comment not found")
+		return nil, models.ErrCommentNotFound
 	}
 
 	comment := &comments[0]
@@ -259,13 +265,68 @@ func (r *SupabaseCommentRepository) GetComment(ctx context.Context, commentID uu
 	return comment, nil
 }
 
-// GetComments retrieves top-level comments for a post
-func (r *SupabaseCommentRepository) GetComments(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.Comment, int, error) {
+// GetComments retrieves top-level comments for a post. Each one carries up to
+// previewReplies of its earliest replies; the rest are loaded with GetCommentReplies.
+func (r *SupabaseCommentRepository) GetComments(ctx context.Context, postID uuid.UUID, limit, offset, previewReplies int) ([]models.Comment, int, error) {
 	query := fmt.Sprintf(
 		"?post_id=eq.%s&parent_comment_id=is.null&deleted_at=is.null&select=*&order=created_at.desc&limit=%d&offset=%d",
 		postID.String(), limit, offset,
 	)
 
+	comments, totalCount, err := r.getCommentPage(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Load authors for all comments
+	for i := range comments {
+		if err := r.loadCommentAuthor(ctx, &comments[i]); err != nil {
+			// Log error but continue - comment will just have no author
+			log.Printf("[CommentRepo] Warning: Failed to load author for comment %s: %v", comments[i].ID, err)
+		}
+
+		if previewReplies <= 0 || comments[i].RepliesCount == 0 {
+			continue
+		}
+
+		// Load first few replies (preview) - skip if there's an error
+		replies, _, err := r.GetCommentReplies(ctx, comments[i].ID, previewReplies, 0)
+		if err != nil {
+			// Log error but continue - comment will just have no replies preview
+			log.Printf("[CommentRepo] Warning: Failed to load replies for comment %s: %v", comments[i].ID, err)
+		} else if len(replies) > 0 {
+			comments[i].Replies = replies
+		}
+	}
+
+	return comments, totalCount, nil
+}
+
+// GetCommentReplies retrieves replies to a comment, oldest first
+func (r *SupabaseCommentRepository) GetCommentReplies(ctx context.Context, parentCommentID uuid.UUID, limit, offset int) ([]models.Comment, int, error) {
+	query := fmt.Sprintf(
+		"?parent_comment_id=eq.%s&deleted_at=is.null&select=*&order=created_at.asc&limit=%d&offset=%d",
+		parentCommentID.String(), limit, offset,
+	)
+
+	replies, totalCount, err := r.getCommentPage(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Load authors
+	for i := range replies {
+		if err := r.loadCommentAuthor(ctx, &replies[i]); err != nil {
+			// Log error but continue - reply will just have no author
+			log.Printf("[CommentRepo] Warning: Failed to load author for reply %s: %v", replies[i].ID, err)
+		}
+	}
+
+	return replies, totalCount, nil
+}
+
+// getCommentPage runs a post_comments query and reads the exact total from Content-Range
+func (r *SupabaseCommentRepository) getCommentPage(ctx context.Context, query string) ([]models.Comment, int, error) {
 	// Make request and get response with headers
 	url := fmt.Sprintf("%s/rest/v1/post_comments%s", r.SupabasePostRepository.supabaseURL, query)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -318,23 +379,6 @@ func (r *SupabaseCommentRepository) GetComments(ctx context.Context, postID uuid
 		return nil, 0, fmt.Errorf("failed to parse comments: %w", err)
 	}
 
-	// Load authors for all comments
-	for i := range comments {
-		if err := r.loadCommentAuthor(ctx, &comments[i]); err != nil {
-			// Log error but continue - comment will just have no author
-			log.Printf("[CommentRepo] Warning: Failed to load author for comment %s: %v", comments[i].ID, err)
-		}
-
-		// Load first few replies (preview) - skip if there's an error
-		replies, _, err := r.GetCommentReplies(ctx, comments[i].ID, 3, 0)
-		if err != nil {
-			// Log error but continue - comment will just have no replies preview
-			log.Printf("[CommentRepo] Warning: Failed to load replies for comment %s: %v", comments[i].ID, err)
-		} else if len(replies) > 0 {
-			comments[i].Replies = replies
-		}
-	}
-
 	// Use totalCount from header if available, otherwise use len(comments)
 	if totalCount == 0 {
 		totalCount = len(comments)
@@ -343,34 +387,6 @@ func (r *SupabaseCommentRepository) GetComments(ctx context.Context, postID uuid
 	return comments, totalCount, nil
 }
 
-// GetCommentReplies retrieves replies to a comment
-func (r *SupabaseCommentRepository) GetCommentReplies(ctx context.Context, parentCommentID uuid.UUID, limit, offset int) ([]models.Comment, int, error) {
-	query := fmt.Sprintf(
-		"?parent_comment_id=eq.%s&deleted_at=is.null&select=*&order=created_at.asc&limit=%d&offset=%d",
-		parentCommentID.String(), limit, offset,
-	)
-
-	data, err := r.makeRequest("GET", "post_comments", query, nil)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	replies, err := r.parseCommentsFromJSON(data)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to parse comment replies: %w", err)
-	}
-
-	// Load authors
-	for i := range replies {
-		if err := r.loadCommentAuthor(ctx, &replies[i]); err != nil {
-			// Log error but continue - reply will just have no author
-			log.Printf("[CommentRepo] Warning: Failed to load author for reply %s: %v", replies[i].ID, err)
-		}
-	}
-
-	return replies, len(replies), nil
-}
-
 // UpdateComment updates a comment's content
 func (r *SupabaseCommentRepository) UpdateComment(ctx context.Context, commentID uuid.UUID, content string) error {
 	updates := map[string]interface{}{
@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -23,6 +27,9 @@ type SupabaseCourseRepository struct {
 	supabaseURL string
 	serviceKey  string
 	client      *http.Client
+
+	// Called with each certificate issued for a completed course
+	certificateIssued []func(ctx context.Context, certificate *models.CourseCertificate)
 }
 
 // NewSupabaseCourseRepository creates a new Supabase course repository
@@ -30,52 +37,86 @@ func NewSupabaseCourseRepository(supabaseURL, serviceKey string) *SupabaseCourse
 	return &SupabaseCourseRepository{
 		supabaseURL: supabaseURL,
 		serviceKey:  serviceKey,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:      newSupabaseHTTPClient(30 * time.Second),
 	}
 }
 
+// OnCertificateIssued registers fn to be called when completing a course issues a
+// certificate, such as to render it. Call during startup.
+func (r *SupabaseCourseRepository) OnCertificateIssued(fn func(ctx context.Context, certificate *models.CourseCertificate)) {
+	r.certificateIssued = append(r.certificateIssued, fn)
+}
+
 // Helper to make Supabase requests
 func (r *SupabaseCourseRepository) makeRequest(method, table, query string, body interface{}) ([]byte, error) {
+	data, _, err := r.doRequest(method, table, query, body, "return=representation")
+	return data, err
+}
+
+// makeCountedRequest is a GET that also returns the exact number of rows matching the
+// query, ignoring its limit and offset
+func (r *SupabaseCourseRepository) makeCountedRequest(table, query string) ([]byte, int, error) {
+	data, header, err := r.doRequest("GET", table, query, nil, "count=exact")
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, contentRangeTotal(header.Get("Content-Range")), nil
+}
+
+// contentRangeTotal reads the total from a Content-Range header such as "0-19/57" or
+// "*/0"
+func contentRangeTotal(contentRange string) int {
+	slash := strings.LastIndex(contentRange, "/")
+	if slash < 0 {
+		return 0
+	}
+	total, err := strconv.Atoi(contentRange[slash+1:])
+	if err != nil {
+		log.Printf("[SupabaseCourseRepo] Unexpected Content-Range: %q", contentRange)
+		return 0
+	}
+	return total
+}
+
+func (r *SupabaseCourseRepository) doRequest(method, table, query string, body interface{}, prefer string) ([]byte, http.Header, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s%s", r.supabaseURL, table, query)
 
 	var reqBody io.Reader
 	if body != nil {
 		jsonData, err := json.Marshal(body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+			return nil, nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
 	req, err := http.NewRequest(method, url, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("apikey", r.serviceKey)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.serviceKey))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Prefer", "return=representation")
+	req.Header.Set("Prefer", prefer)
 
 	resp, err := r.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
 		log.Printf("[SupabaseCourseRepo] Error response (status %d): %s", resp.StatusCode, string(data))
-		return nil, fmt.Errorf("supabase error (status %d): %s", resp.StatusCode, string(data))
+		return nil, nil, fmt.Errorf("supabase error (status %d): %s", resp.StatusCode, string(data))
 	}
 
-	return data, nil
+	return data, resp.Header, nil
 }
 
 // supabaseCourse represents the course structure from Supabase
@@ -243,7 +284,90 @@ func (r *SupabaseCourseRepository) GetCourseByID(ctx context.Context, id uuid.UU
 	return courses[0].toCourse()
 }
 
+// GetCoursesByIDs retrieves several courses (with creators) in a single query.
+// Results follow the order of ids; duplicates are collapsed and missing courses skipped.
+func (r *SupabaseCourseRepository) GetCoursesByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Course, error) {
+	if len(ids) == 0 {
+		return []*models.Course{}, nil
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ids))
+	idStrs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		idStrs = append(idStrs, id.String())
+	}
+
+	query := fmt.Sprintf("?id=in.(%s)&select=*,creator:users!courses_creator_id_fkey(id,username,display_name,profile_picture,is_verified)", strings.Join(idStrs, ","))
+	data, err := r.makeRequest("GET", "courses", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var supabaseCourses []supabaseCourse
+	if err := json.Unmarshal(data, &supabaseCourses); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal courses: %w", err)
+	}
+
+	byID := make(map[uuid.UUID]*models.Course, len(supabaseCourses))
+	for _, sc := range supabaseCourses {
+		course, err := sc.toCourse()
+		if err != nil {
+			return nil, err
+		}
+		byID[course.ID] = course
+	}
+
+	courses := make([]*models.Course, 0, len(byID))
+	for _, id := range ids {
+		if course, ok := byID[id]; ok {
+			courses = append(courses, course)
+			delete(byID, id) // Collapse duplicate IDs
+		}
+	}
+	return courses, nil
+}
+
 // GetCourseBySlug retrieves a course by slug
+// GetCourseForViewer gets a course along with whether the viewer is enrolled in it
+// and has it on their wishlist
+func (r *SupabaseCourseRepository) GetCourseForViewer(ctx context.Context, id, viewerID uuid.UUID) (*models.Course, error) {
+	course, err := r.GetCourseByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, _ := r.GetEnrollment(ctx, course.ID, viewerID)
+	if enrollment != nil {
+		course.IsEnrolled = true
+		course.Enrollment = enrollment
+	}
+	r.markWishlisted(ctx, viewerID, []*models.Course{course})
+
+	return course, nil
+}
+
+// GetCourseAnalytics computes a course's stats in the database, with enrollments per
+// day for the last days days
+func (r *SupabaseCourseRepository) GetCourseAnalytics(ctx context.Context, courseID uuid.UUID, days int) (*models.CourseAnalytics, error) {
+	data, err := r.makeRequest("POST", "rpc/get_course_analytics", "", map[string]interface{}{
+		"p_course_id": courseID,
+		"p_days":      days,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var analytics models.CourseAnalytics
+	if err := json.Unmarshal(data, &analytics); err != nil {
+		return nil, fmt.Errorf("failed to decode course analytics: %w", err)
+	}
+	analytics.CourseID = courseID
+	return &analytics, nil
+}
+
 func (r *SupabaseCourseRepository) GetCourseBySlug(ctx context.Context, slug string) (*models.Course, error) {
 	query := fmt.Sprintf("?slug=eq.%s&select=*,creator:users!courses_creator_id_fkey(id,username,display_name,profile_picture,is_verified)", url.QueryEscape(slug))
 
@@ -314,7 +438,9 @@ func (r *SupabaseCourseRepository) DeleteCourse(ctx context.Context, id uuid.UUI
 }
 
 // ListCourses lists courses with filters
-func (r *SupabaseCourseRepository) ListCourses(ctx context.Context, filter *models.CourseFilter, userID *uuid.UUID) ([]*models.Course, error) {
+// ListCourses returns a page of published courses matching the filter, along with how
+// many match in total
+func (r *SupabaseCourseRepository) ListCourses(ctx context.Context, filter *models.CourseFilter, userID *uuid.UUID) ([]*models.Course, int, error) {
 	var queryParams []string
 
 	// Base condition: only show published courses
@@ -326,8 +452,14 @@ func (r *SupabaseCourseRepository) ListCourses(ctx context.Context, filter *mode
 	}
 
 	// Apply filters
+	categories := filter.Categories
 	if filter.Category != nil {
-		queryParams = append(queryParams, fmt.Sprintf("category=eq.%s", url.QueryEscape(*filter.Category)))
+		categories = append([]string{*filter.Category}, categories...)
+	}
+	if len(categories) == 1 {
+		queryParams = append(queryParams, fmt.Sprintf("category=eq.%s", url.QueryEscape(categories[0])))
+	} else if len(categories) > 1 {
+		queryParams = append(queryParams, fmt.Sprintf("category=in.(%s)", postgrestList(categories)))
 	}
 
 	if filter.DifficultyLevel != nil {
@@ -338,6 +470,23 @@ func (r *SupabaseCourseRepository) ListCourses(ctx context.Context, filter *mode
 		queryParams = append(queryParams, fmt.Sprintf("is_free=eq.%t", *filter.IsFree))
 	}
 
+	// Free courses have no price, so a price range only matches paid ones
+	if filter.MinPrice != nil {
+		queryParams = append(queryParams, fmt.Sprintf("price=gte.%s", strconv.FormatFloat(*filter.MinPrice, 'f', -1, 64)))
+	}
+	if filter.MaxPrice != nil {
+		queryParams = append(queryParams, fmt.Sprintf("price=lte.%s", strconv.FormatFloat(*filter.MaxPrice, 'f', -1, 64)))
+	}
+
+	if filter.MinRating != nil {
+		queryParams = append(queryParams, fmt.Sprintf("average_rating=gte.%s", strconv.FormatFloat(*filter.MinRating, 'f', -1, 64)))
+	}
+
+	// Courses tagged with every one of the tags
+	if len(filter.Tags) > 0 {
+		queryParams = append(queryParams, fmt.Sprintf("tags=cs.{%s}", postgrestList(filter.Tags)))
+	}
+
 	if filter.Language != nil {
 		queryParams = append(queryParams, fmt.Sprintf("language=eq.%s", url.QueryEscape(*filter.Language)))
 	}
@@ -349,38 +498,38 @@ func (r *SupabaseCourseRepository) ListCourses(ctx context.Context, filter *mode
 	// Build query string
 	query := strings.Join(queryParams, "&")
 
-	// Add sorting
+	// Add sorting; id breaks ties so pages don't overlap
 	sortBy := "created_at.desc"
 	if filter.SortBy == "popular" {
 		sortBy = "enrollment_count.desc"
 	} else if filter.SortBy == "rating" {
 		sortBy = "average_rating.desc"
 	} else if filter.SortBy == "price_asc" {
-		sortBy = "price.asc"
+		sortBy = "price.asc.nullsfirst"
 	} else if filter.SortBy == "price_desc" {
-		sortBy = "price.desc"
+		sortBy = "price.desc.nullslast"
 	}
 
-	query = fmt.Sprintf("?%s&select=*,creator:users!courses_creator_id_fkey(id,username,display_name,profile_picture,is_verified)&order=%s&limit=%d&offset=%d",
+	query = fmt.Sprintf("?%s&select=*,creator:users!courses_creator_id_fkey(id,username,display_name,profile_picture,is_verified)&order=%s,id.asc&limit=%d&offset=%d",
 		query, sortBy, filter.Limit, filter.Offset)
 
 	log.Printf("[SupabaseCourseRepo] ListCourses query: %s", query)
 
-	data, err := r.makeRequest("GET", "courses", query, nil)
+	data, total, err := r.makeCountedRequest("courses", query)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var supabaseCourses []supabaseCourse
 	if err := json.Unmarshal(data, &supabaseCourses); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal courses: %w", err)
+		return nil, 0, fmt.Errorf("failed to unmarshal courses: %w", err)
 	}
 
 	courses := make([]*models.Course, len(supabaseCourses))
 	for i, sc := range supabaseCourses {
 		course, err := sc.toCourse()
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		// Check if user has enrolled
@@ -395,9 +544,60 @@ func (r *SupabaseCourseRepository) ListCourses(ctx context.Context, filter *mode
 		courses[i] = course
 	}
 
+	if userID != nil {
+		r.markWishlisted(ctx, *userID, courses)
+	}
+
+	return courses, total, nil
+}
+
+// SuggestCourses returns up to limit published, public courses whose title starts
+// with prefix, most enrolled first. It loads only what a suggestion shows.
+func (r *SupabaseCourseRepository) SuggestCourses(ctx context.Context, prefix string, limit int) ([]*models.Course, error) {
+	query := fmt.Sprintf("?status=eq.published&is_public=eq.true&title=ilike.%s*&select=id,title,slug,thumbnail_url,creator_id&order=enrollment_count.desc,id.asc&limit=%d",
+		url.QueryEscape(prefix), limit)
+
+	data, err := r.makeRequest("GET", "courses", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest courses: %w", err)
+	}
+
+	var rows []struct {
+		ID           uuid.UUID `json:"id"`
+		Title        string    `json:"title"`
+		Slug         string    `json:"slug"`
+		ThumbnailURL *string   `json:"thumbnail_url"`
+		CreatorID    uuid.UUID `json:"creator_id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal courses: %w", err)
+	}
+
+	courses := make([]*models.Course, len(rows))
+	for i, row := range rows {
+		courses[i] = &models.Course{
+			ID:           row.ID,
+			Title:        row.Title,
+			Slug:         row.Slug,
+			ThumbnailURL: row.ThumbnailURL,
+			CreatorID:    row.CreatorID,
+		}
+	}
 	return courses, nil
 }
 
+// postgrestList formats values for a PostgREST in.() list or array literal. Each is
+// double quoted, so commas, parentheses and braces in them are taken literally.
+func postgrestList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		quoted[i] = url.QueryEscape(`"` + v + `"`)
+	}
+	return strings.Join(quoted, ",")
+}
+
 // Helper function to generate slug from title
 func generateSlug(title string) string {
 	slug := strings.ToLower(title)
@@ -451,28 +651,17 @@ func (r *SupabaseCourseRepository) GetEnrolledCourses(ctx context.Context, userI
 	if len(enrollments) == 0 {
 		return []*models.Course{}, nil
 	}
-	// Get courses
-	courseIDs := make([]string, len(enrollments))
+	// Get courses in enrollment order
+	courseIDs := make([]uuid.UUID, len(enrollments))
 	for i, e := range enrollments {
-		courseIDs[i] = e.CourseID.String()
+		courseIDs[i] = e.CourseID
 	}
-	query = fmt.Sprintf("?id=in.(%s)&select=*,creator:users!courses_creator_id_fkey(id,username,display_name,profile_picture,is_verified)", strings.Join(courseIDs, ","))
-	data, err = r.makeRequest("GET", "courses", query, nil)
+	courses, err := r.GetCoursesByIDs(ctx, courseIDs)
 	if err != nil {
 		return nil, err
 	}
-	var supabaseCourses []supabaseCourse
-	if err := json.Unmarshal(data, &supabaseCourses); err != nil {
-		return nil, err
-	}
-	courses := make([]*models.Course, len(supabaseCourses))
-	for i, sc := range supabaseCourses {
-		course, err := sc.toCourse()
-		if err != nil {
-			return nil, err
-		}
+	for _, course := range courses {
 		course.IsEnrolled = true
-		courses[i] = course
 	}
 	return courses, nil
 }
@@ -602,23 +791,23 @@ func (r *SupabaseCourseRepository) UpdateModule(ctx context.Context, module *mod
 }
 
 func (r *SupabaseCourseRepository) DeleteModule(ctx context.Context, id uuid.UUID) error {
+	module, err := r.GetModuleByID(ctx, id)
+	if err != nil {
+		return err
+	}
 	query := fmt.Sprintf("?id=eq.%s", id.String())
-	_, err := r.makeRequest("DELETE", "course_modules", query, nil)
-	return err
+	if _, err := r.makeRequest("DELETE", "course_modules", query, nil); err != nil {
+		return err
+	}
+	// The module's lessons went with it
+	r.recomputeCourseProgress(ctx, module.CourseID)
+	return nil
 }
 
+// ReorderModules sets the order of every module in a course at once. moduleOrders must
+// give each of the course's modules a distinct position, with no gaps.
 func (r *SupabaseCourseRepository) ReorderModules(ctx context.Context, courseID uuid.UUID, moduleOrders map[uuid.UUID]int) error {
-	// Batch update order indices
-	for moduleID, orderIndex := range moduleOrders {
-		payload := map[string]interface{}{
-			"order_index": orderIndex,
-		}
-		query := fmt.Sprintf("?id=eq.%s", moduleID.String())
-		if _, err := r.makeRequest("PATCH", "course_modules", query, payload); err != nil {
-			return err
-		}
-	}
-	return nil
+	return r.reorder("course_modules", fmt.Sprintf("course_id=eq.%s", courseID.String()), moduleOrders)
 }
 
 // Lesson methods (stubs)
@@ -652,6 +841,9 @@ func (r *SupabaseCourseRepository) CreateLesson(ctx context.Context, lesson *mod
 	lesson.ID = created.ID
 	lesson.CreatedAt = parseCourseTime(created.CreatedAt)
 	lesson.UpdatedAt = parseCourseTime(created.UpdatedAt)
+
+	// Learners part way through now have one more lesson to go
+	r.recomputeCourseProgress(ctx, lesson.CourseID)
 	return nil
 }
 
@@ -822,25 +1014,86 @@ func (r *SupabaseCourseRepository) UpdateLesson(ctx context.Context, lesson *mod
 }
 
 func (r *SupabaseCourseRepository) DeleteLesson(ctx context.Context, id uuid.UUID) error {
+	lesson, err := r.GetLessonByID(ctx, id)
+	if err != nil {
+		return err
+	}
 	query := fmt.Sprintf("?id=eq.%s", id.String())
-	_, err := r.makeRequest("DELETE", "course_lessons", query, nil)
-	return err
+	if _, err := r.makeRequest("DELETE", "course_lessons", query, nil); err != nil {
+		return err
+	}
+	r.recomputeCourseProgress(ctx, lesson.CourseID)
+	return nil
 }
 
+// ReorderLessons sets the order of every lesson in a module at once, like ReorderModules
 func (r *SupabaseCourseRepository) ReorderLessons(ctx context.Context, moduleID uuid.UUID, lessonOrders map[uuid.UUID]int) error {
-	for lessonID, orderIndex := range lessonOrders {
-		payload := map[string]interface{}{
-			"order_index": orderIndex,
+	return r.reorder("course_lessons", fmt.Sprintf("module_id=eq.%s", moduleID.String()), lessonOrders)
+}
+
+// reorder writes new order_index values for all rows of table matching scope in one
+// bulk upsert, so either the whole new order is saved or none of it is. The upsert
+// sends back each row as read with only order_index changed, since an upsert has to
+// carry the columns an insert would need.
+func (r *SupabaseCourseRepository) reorder(table, scope string, orders map[uuid.UUID]int) error {
+	data, err := r.makeRequest("GET", table, "?"+scope+"&select=*", nil)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", table, err)
+	}
+
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		id, _ := row["id"].(string)
+		if ids[i], err = uuid.Parse(id); err != nil {
+			return fmt.Errorf("failed to read %s id: %w", table, err)
 		}
-		query := fmt.Sprintf("?id=eq.%s", lessonID.String())
-		if _, err := r.makeRequest("PATCH", "course_lessons", query, payload); err != nil {
-			return err
+	}
+	if err := validateOrdering(ids, orders); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	for i, row := range rows {
+		row["order_index"] = orders[ids[i]]
+	}
+	_, _, err = r.doRequest("POST", table, "?on_conflict=id", rows, "resolution=merge-duplicates,return=minimal")
+	return err
+}
+
+// validateOrdering checks that orders gives each of ids a position, and nothing else,
+// and that the positions are distinct and consecutive
+func validateOrdering(ids []uuid.UUID, orders map[uuid.UUID]int) error {
+	if len(orders) != len(ids) {
+		return apperr.NewAppError(http.StatusBadRequest, "The new order must include every item exactly once")
+	}
+	positions := make([]int, 0, len(ids))
+	for _, id := range ids {
+		position, ok := orders[id]
+		if !ok {
+			return apperr.NewAppError(http.StatusBadRequest, "The new order must include every item exactly once")
+		}
+		positions = append(positions, position)
+	}
+
+	sort.Ints(positions)
+	for i, position := range positions {
+		if position < 0 || (i > 0 && position != positions[i-1]+1) {
+			return apperr.NewAppError(http.StatusBadRequest, "Positions must be distinct with no gaps")
 		}
 	}
 	return nil
 }
 
 // Enrollment methods
+// CreateEnrollment enrolls a user. With a CouponCode set, the coupon is checked against
+// the course, one of its uses is taken and PaymentAmount becomes the discounted price;
+// an enrollment the coupon makes free is marked free.
 func (r *SupabaseCourseRepository) CreateEnrollment(ctx context.Context, enrollment *models.CourseEnrollment) error {
 	payload := map[string]interface{}{
 		"course_id":              enrollment.CourseID,
@@ -850,8 +1103,34 @@ func (r *SupabaseCourseRepository) CreateEnrollment(ctx context.Context, enrollm
 		"payment_currency":       enrollment.PaymentCurrency,
 		"payment_transaction_id": enrollment.PaymentTransactionID,
 	}
+
+	var coupon *models.CourseCoupon
+	if enrollment.CouponCode != "" {
+		quote, err := r.QuoteCoupon(ctx, enrollment.CourseID, enrollment.CouponCode)
+		if err != nil {
+			return err
+		}
+		if err := r.redeemCoupon(ctx, quote.Coupon.ID); err != nil {
+			return err
+		}
+		coupon = quote.Coupon
+
+		enrollment.PaymentAmount = &quote.FinalAmount
+		enrollment.PaymentCurrency = &quote.Currency
+		if quote.FinalAmount == 0 {
+			enrollment.PaymentStatus = "free"
+		}
+		payload["coupon_id"] = coupon.ID
+		payload["payment_amount"] = enrollment.PaymentAmount
+		payload["payment_currency"] = enrollment.PaymentCurrency
+		payload["payment_status"] = enrollment.PaymentStatus
+	}
+
 	data, err := r.makeRequest("POST", "course_enrollments", "", payload)
 	if err != nil {
+		if coupon != nil {
+			r.releaseCoupon(ctx, coupon.ID)
+		}
 		return err
 	}
 	var created struct {
@@ -865,9 +1144,82 @@ func (r *SupabaseCourseRepository) CreateEnrollment(ctx context.Context, enrollm
 	enrollment.ID = created.ID
 	enrollment.EnrolledAt = parseCourseTime(created.EnrolledAt)
 	enrollment.ProgressPercentage = created.ProgressPercentage
+
+	// Counter drift is corrected by ReconcileEnrollmentCounts, so don't fail the enrollment
+	if err := r.adjustCourseCounter(ctx, "adjust_course_enrollment_count", enrollment.CourseID, 1); err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to increment enrollment count for course %s: %v", enrollment.CourseID, err)
+	}
 	return nil
 }
 
+// ReconcileRatings repairs course average ratings and review counts that no longer
+// match the reviews, returning how many courses were corrected. A trigger on
+// course_reviews normally keeps them in step.
+func (r *SupabaseCourseRepository) ReconcileRatings(ctx context.Context) (int, error) {
+	data, err := r.makeRequest("POST", "rpc/reconcile_course_ratings", "", map[string]interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	var corrected int
+	if err := json.Unmarshal(data, &corrected); err != nil {
+		return 0, fmt.Errorf("failed to decode reconcile result: %w", err)
+	}
+	return corrected, nil
+}
+
+// adjustCourseCounter atomically adds delta to a course counter via RPC
+func (r *SupabaseCourseRepository) adjustCourseCounter(ctx context.Context, fn string, courseID uuid.UUID, delta int) error {
+	_, err := r.makeRequest("POST", "rpc/"+fn, "", map[string]interface{}{
+		"p_course_id": courseID,
+		"p_delta":     delta,
+	})
+	return err
+}
+
+// ReconcileEnrollmentCounts recomputes course enrollment/completion counters from
+// course_enrollments and returns how many courses were corrected
+func (r *SupabaseCourseRepository) ReconcileEnrollmentCounts(ctx context.Context) (int, error) {
+	data, err := r.makeRequest("POST", "rpc/reconcile_course_enrollment_counts", "", map[string]interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	var corrected int
+	if err := json.Unmarshal(data, &corrected); err != nil {
+		return 0, fmt.Errorf("failed to decode reconcile result: %w", err)
+	}
+	return corrected, nil
+}
+
+// DeleteEnrollment removes the user's enrollment in the course, reporting whether
+// there was one, and takes it back off the course's counters
+func (r *SupabaseCourseRepository) DeleteEnrollment(ctx context.Context, courseID, userID uuid.UUID) (bool, error) {
+	query := fmt.Sprintf("?course_id=eq.%s&user_id=eq.%s&select=id,completed_at", courseID.String(), userID.String())
+	data, err := r.makeRequest("DELETE", "course_enrollments", query, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete enrollment: %w", err)
+	}
+	var deleted []struct {
+		ID          uuid.UUID `json:"id"`
+		CompletedAt *string   `json:"completed_at"`
+	}
+	if err := json.Unmarshal(data, &deleted); err != nil {
+		return false, err
+	}
+	if len(deleted) == 0 {
+		return false, nil
+	}
+
+	if err := r.adjustCourseCounter(ctx, "adjust_course_enrollment_count", courseID, -1); err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to decrement enrollment count for course %s: %v", courseID, err)
+	}
+	if deleted[0].CompletedAt != nil {
+		if err := r.adjustCourseCounter(ctx, "adjust_course_completion_count", courseID, -1); err != nil {
+			log.Printf("[SupabaseCourseRepo] Failed to decrement completion count for course %s: %v", courseID, err)
+		}
+	}
+	return true, nil
+}
+
 func (r *SupabaseCourseRepository) GetEnrollment(ctx context.Context, courseID, userID uuid.UUID) (*models.CourseEnrollment, error) {
 	query := fmt.Sprintf("?course_id=eq.%s&user_id=eq.%s&select=*", courseID.String(), userID.String())
 	data, err := r.makeRequest("GET", "course_enrollments", query, nil)
@@ -969,12 +1321,29 @@ func (r *SupabaseCourseRepository) GetEnrollmentByID(ctx context.Context, id uui
 }
 
 func (r *SupabaseCourseRepository) UpdateEnrollment(ctx context.Context, enrollment *models.CourseEnrollment) error {
-	payload := map[string]interface{}{
-		"progress_percentage": enrollment.ProgressPercentage,
-		"last_accessed_at":    time.Now().Format(time.RFC3339),
-	}
 	if enrollment.CompletedAt != nil {
-		payload["completed_at"] = enrollment.CompletedAt.Format(time.RFC3339)
+		// Only matches while completed_at is still null, so the completion is counted exactly once
+		query := fmt.Sprintf("?id=eq.%s&completed_at=is.null", enrollment.ID.String())
+		data, err := r.makeRequest("PATCH", "course_enrollments", query, map[string]interface{}{
+			"completed_at": enrollment.CompletedAt.Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+		var completed []struct {
+			ID uuid.UUID `json:"id"`
+		}
+		if err := json.Unmarshal(data, &completed); err == nil && len(completed) > 0 {
+			if err := r.adjustCourseCounter(ctx, "adjust_course_completion_count", enrollment.CourseID, 1); err != nil {
+				log.Printf("[SupabaseCourseRepo] Failed to increment completion count for course %s: %v", enrollment.CourseID, err)
+			}
+		}
+	}
+
+	// progress_percentage is left alone: it's computed from lesson progress, see
+	// recomputeEnrollmentProgress, and the caller's copy may be stale
+	payload := map[string]interface{}{
+		"last_accessed_at": time.Now().Format(time.RFC3339),
 	}
 	if enrollment.LastAccessedLessonID != nil {
 		payload["last_accessed_lesson_id"] = enrollment.LastAccessedLessonID.String()
@@ -1681,11 +2050,40 @@ func (r *SupabaseCourseRepository) IncrementMaterialDownloadCount(ctx context.Co
 	return err
 }
 
-// Progress methods (stubs)
+// Progress methods
+
+// CreateOrUpdateProgress saves a learner's progress on a lesson, then brings the
+// enrollment's progress percentage up to date. Completing the last lesson completes
+// the enrollment and issues its certificate. A quiz lesson can only be completed
+// once its quiz is passed.
 func (r *SupabaseCourseRepository) CreateOrUpdateProgress(ctx context.Context, progress *models.LessonProgress) error {
+	if progress.IsCompleted {
+		if err := r.requireQuizPassed(ctx, progress.EnrollmentID, progress.LessonID); err != nil {
+			return err
+		}
+	}
+	if err := r.saveProgress(ctx, progress); err != nil {
+		return err
+	}
+
+	completed, err := r.recomputeEnrollmentProgress(ctx, progress.EnrollmentID)
+	if err != nil {
+		// The lesson is saved; the percentage catches up on the next update
+		log.Printf("[SupabaseCourseRepo] Failed to recompute progress of enrollment %s: %v", progress.EnrollmentID, err)
+		return nil
+	}
+	if completed {
+		r.issueCertificate(ctx, progress.EnrollmentID, progress.CourseID, progress.UserID)
+	}
+	return nil
+}
+
+// saveProgress inserts or updates the progress row for the lesson
+func (r *SupabaseCourseRepository) saveProgress(ctx context.Context, progress *models.LessonProgress) error {
 	// Check if progress exists
 	existing, _ := r.GetProgress(ctx, progress.EnrollmentID, progress.LessonID)
 	if existing != nil {
+		progress.ID = existing.ID
 		return r.UpdateProgress(ctx, progress)
 	}
 	payload := map[string]interface{}{
@@ -1721,6 +2119,78 @@ func (r *SupabaseCourseRepository) CreateOrUpdateProgress(ctx context.Context, p
 	return nil
 }
 
+// recomputeEnrollmentProgress sets an enrollment's progress percentage from the
+// lessons it has completed, reporting whether this completed the enrollment. The
+// database does it under a lock on the enrollment, so concurrent completions can't
+// leave a stale percentage or complete it twice.
+func (r *SupabaseCourseRepository) recomputeEnrollmentProgress(ctx context.Context, enrollmentID uuid.UUID) (bool, error) {
+	data, err := r.makeRequest("POST", "rpc/recompute_enrollment_progress", "", map[string]interface{}{
+		"p_enrollment_id": enrollmentID,
+	})
+	if err != nil {
+		return false, err
+	}
+	var rows []struct {
+		ProgressPercentage float64 `json:"progress_percentage"`
+		NewlyCompleted     bool    `json:"newly_completed"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("failed to decode progress: %w", err)
+	}
+	return len(rows) > 0 && rows[0].NewlyCompleted, nil
+}
+
+// recomputeCourseProgress updates the unfinished enrollments of a course after its
+// lessons change, issuing certificates to any it completes. Failures are logged;
+// each enrollment catches up on its next lesson update.
+func (r *SupabaseCourseRepository) recomputeCourseProgress(ctx context.Context, courseID uuid.UUID) {
+	data, err := r.makeRequest("POST", "rpc/recompute_course_progress", "", map[string]interface{}{
+		"p_course_id": courseID,
+	})
+	if err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to recompute progress for course %s: %v", courseID, err)
+		return
+	}
+	var completed []struct {
+		EnrollmentID uuid.UUID `json:"enrollment_id"`
+		UserID       uuid.UUID `json:"user_id"`
+	}
+	if err := json.Unmarshal(data, &completed); err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to decode recomputed progress for course %s: %v", courseID, err)
+		return
+	}
+	for _, c := range completed {
+		r.issueCertificate(ctx, c.EnrollmentID, courseID, c.UserID)
+	}
+}
+
+// issueCertificate issues the certificate for a completed enrollment unless it has one
+func (r *SupabaseCourseRepository) issueCertificate(ctx context.Context, enrollmentID, courseID, userID uuid.UUID) {
+	existing, err := r.GetCertificate(ctx, enrollmentID)
+	if err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to look up certificate for enrollment %s: %v", enrollmentID, err)
+		return
+	}
+	if existing != nil {
+		return
+	}
+
+	certificate := &models.CourseCertificate{
+		EnrollmentID: enrollmentID,
+		CourseID:     courseID,
+		UserID:       userID,
+	}
+	if err := r.CreateCertificate(ctx, certificate); err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to issue certificate for enrollment %s: %v", enrollmentID, err)
+		return
+	}
+	log.Printf("[SupabaseCourseRepo] Issued certificate %s for enrollment %s", certificate.CertificateNumber, enrollmentID)
+
+	for _, fn := range r.certificateIssued {
+		fn(ctx, certificate)
+	}
+}
+
 func (r *SupabaseCourseRepository) GetProgress(ctx context.Context, enrollmentID, lessonID uuid.UUID) (*models.LessonProgress, error) {
 	query := fmt.Sprintf("?enrollment_id=eq.%s&lesson_id=eq.%s&select=*", enrollmentID.String(), lessonID.String())
 	data, err := r.makeRequest("GET", "lesson_progress", query, nil)
@@ -1837,6 +2307,524 @@ func (r *SupabaseCourseRepository) UpdateProgress(ctx context.Context, progress
 	return err
 }
 
+// requireQuizPassed returns ErrQuizNotPassed if the lesson is a quiz lesson whose quiz
+// the enrollment hasn't passed. A quiz lesson without a quiz yet has nothing to pass.
+func (r *SupabaseCourseRepository) requireQuizPassed(ctx context.Context, enrollmentID, lessonID uuid.UUID) error {
+	lesson, err := r.GetLessonByID(ctx, lessonID)
+	if err != nil {
+		return err
+	}
+	if lesson.LessonType != "quiz" {
+		return nil
+	}
+
+	quiz, err := r.GetQuizByLesson(ctx, lessonID)
+	if err != nil {
+		return err
+	}
+	if quiz == nil {
+		return nil
+	}
+
+	passed, err := r.HasPassedQuiz(ctx, enrollmentID, lessonID)
+	if err != nil {
+		return err
+	}
+	if !passed {
+		return apperr.ErrQuizNotPassed
+	}
+	return nil
+}
+
+// Wishlist methods
+
+// AddToWishlist saves a course to the user's wishlist. Adding one that's already there
+// does nothing.
+func (r *SupabaseCourseRepository) AddToWishlist(ctx context.Context, courseID, userID uuid.UUID) error {
+	_, err := r.makeRequest("POST", "course_wishlist", "", map[string]interface{}{
+		"course_id": courseID,
+		"user_id":   userID,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return nil // Already wishlisted
+		}
+		return fmt.Errorf("failed to add to wishlist: %w", err)
+	}
+	return nil
+}
+
+// RemoveFromWishlist removes a course from the user's wishlist, reporting whether it
+// was on it
+func (r *SupabaseCourseRepository) RemoveFromWishlist(ctx context.Context, courseID, userID uuid.UUID) (bool, error) {
+	query := fmt.Sprintf("?course_id=eq.%s&user_id=eq.%s", courseID.String(), userID.String())
+	data, err := r.makeRequest("DELETE", "course_wishlist", query, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove from wishlist: %w", err)
+	}
+	var removed []json.RawMessage
+	if err := json.Unmarshal(data, &removed); err != nil {
+		return false, err
+	}
+	return len(removed) > 0, nil
+}
+
+// GetWishlist returns the published courses on a user's wishlist, most recently added
+// first, and how many there are
+func (r *SupabaseCourseRepository) GetWishlist(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Course, int, error) {
+	query := fmt.Sprintf("?user_id=eq.%s&course.status=eq.published"+
+		"&select=created_at,course:courses!inner(*,creator:users!courses_creator_id_fkey(id,username,display_name,profile_picture,is_verified))"+
+		"&order=created_at.desc&limit=%d&offset=%d", userID.String(), limit, offset)
+	data, total, err := r.makeCountedRequest("course_wishlist", query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		Course supabaseCourse `json:"course"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal wishlist: %w", err)
+	}
+
+	courses := make([]*models.Course, len(rows))
+	for i, row := range rows {
+		course, err := row.Course.toCourse()
+		if err != nil {
+			return nil, 0, err
+		}
+		course.IsWishlisted = true
+		courses[i] = course
+	}
+	return courses, total, nil
+}
+
+// markWishlisted sets IsWishlisted on the courses the user has on their wishlist, with
+// one lookup for all of them. A failed lookup leaves the flags unset.
+func (r *SupabaseCourseRepository) markWishlisted(ctx context.Context, userID uuid.UUID, courses []*models.Course) {
+	if len(courses) == 0 {
+		return
+	}
+	ids := make([]string, len(courses))
+	for i, course := range courses {
+		ids[i] = course.ID.String()
+	}
+
+	query := fmt.Sprintf("?user_id=eq.%s&course_id=in.(%s)&select=course_id", userID.String(), strings.Join(ids, ","))
+	data, err := r.makeRequest("GET", "course_wishlist", query, nil)
+	if err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to load wishlist flags: %v", err)
+		return
+	}
+	var rows []struct {
+		CourseID uuid.UUID `json:"course_id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to decode wishlist flags: %v", err)
+		return
+	}
+
+	wishlisted := make(map[uuid.UUID]bool, len(rows))
+	for _, row := range rows {
+		wishlisted[row.CourseID] = true
+	}
+	for _, course := range courses {
+		course.IsWishlisted = wishlisted[course.ID]
+	}
+}
+
+// Coupon methods
+
+// CreateCoupon saves a new coupon, returning ErrCouponCodeTaken if its code exists
+func (r *SupabaseCourseRepository) CreateCoupon(ctx context.Context, coupon *models.CourseCoupon) error {
+	coupon.Code = models.NormalizeCouponCode(coupon.Code)
+	data, err := r.makeRequest("POST", "course_coupons", "", map[string]interface{}{
+		"code":           coupon.Code,
+		"course_id":      coupon.CourseID,
+		"created_by":     coupon.CreatedBy,
+		"discount_type":  coupon.DiscountType,
+		"discount_value": coupon.DiscountValue,
+		"currency":       coupon.Currency,
+		"max_uses":       coupon.MaxUses,
+		"expires_at":     coupon.ExpiresAt,
+		"is_active":      true,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return apperr.ErrCouponCodeTaken
+		}
+		return err
+	}
+	var created []struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt string    `json:"created_at"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return fmt.Errorf("coupon not created")
+	}
+	coupon.ID = created[0].ID
+	coupon.IsActive = true
+	coupon.CreatedAt = parseCourseTime(created[0].CreatedAt)
+	return nil
+}
+
+// GetCouponByCode looks a coupon up by code, ignoring case, returning nil if none
+func (r *SupabaseCourseRepository) GetCouponByCode(ctx context.Context, code string) (*models.CourseCoupon, error) {
+	query := fmt.Sprintf("?code=eq.%s&select=*", url.QueryEscape(models.NormalizeCouponCode(code)))
+	data, err := r.makeRequest("GET", "course_coupons", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var coupons []struct {
+		ID            uuid.UUID  `json:"id"`
+		Code          string     `json:"code"`
+		CourseID      *uuid.UUID `json:"course_id"`
+		CreatedBy     *uuid.UUID `json:"created_by"`
+		DiscountType  string     `json:"discount_type"`
+		DiscountValue float64    `json:"discount_value"`
+		Currency      *string    `json:"currency"`
+		MaxUses       *int       `json:"max_uses"`
+		UsedCount     int        `json:"used_count"`
+		ExpiresAt     *string    `json:"expires_at"`
+		IsActive      bool       `json:"is_active"`
+		CreatedAt     string     `json:"created_at"`
+	}
+	if err := json.Unmarshal(data, &coupons); err != nil {
+		return nil, err
+	}
+	if len(coupons) == 0 {
+		return nil, nil
+	}
+	c := coupons[0]
+	coupon := &models.CourseCoupon{
+		ID:            c.ID,
+		Code:          c.Code,
+		CourseID:      c.CourseID,
+		CreatedBy:     c.CreatedBy,
+		DiscountType:  c.DiscountType,
+		DiscountValue: c.DiscountValue,
+		Currency:      c.Currency,
+		MaxUses:       c.MaxUses,
+		UsedCount:     c.UsedCount,
+		IsActive:      c.IsActive,
+		CreatedAt:     parseCourseTime(c.CreatedAt),
+	}
+	if c.ExpiresAt != nil {
+		expiresAt := parseCourseTime(*c.ExpiresAt)
+		coupon.ExpiresAt = &expiresAt
+	}
+	return coupon, nil
+}
+
+// QuoteCoupon checks that a coupon can be used on a course right now and prices the
+// course with it. It doesn't use the coupon up; CreateEnrollment does.
+func (r *SupabaseCourseRepository) QuoteCoupon(ctx context.Context, courseID uuid.UUID, code string) (*models.CouponQuote, error) {
+	coupon, err := r.GetCouponByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if coupon == nil || !coupon.IsActive {
+		return nil, apperr.ErrCouponNotFound
+	}
+	if coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(time.Now()) {
+		return nil, apperr.ErrCouponExpired
+	}
+	if coupon.MaxUses != nil && coupon.UsedCount >= *coupon.MaxUses {
+		return nil, apperr.ErrCouponExhausted
+	}
+	if coupon.CourseID != nil && *coupon.CourseID != courseID {
+		return nil, apperr.ErrCouponNotApplicable
+	}
+
+	course, err := r.GetCourseByID(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if course.IsFree || course.Price == nil || *course.Price <= 0 {
+		return nil, apperr.ErrCouponNotApplicable
+	}
+	price := *course.Price
+
+	var discount float64
+	switch coupon.DiscountType {
+	case models.CouponDiscountPercent:
+		discount = price * coupon.DiscountValue / 100
+	case models.CouponDiscountFixed:
+		if coupon.Currency == nil || !strings.EqualFold(*coupon.Currency, course.Currency) {
+			return nil, apperr.ErrCouponNotApplicable
+		}
+		discount = coupon.DiscountValue
+	default:
+		return nil, apperr.ErrCouponNotApplicable
+	}
+	discount = math.Min(math.Round(discount*100)/100, price)
+
+	return &models.CouponQuote{
+		Coupon:         coupon,
+		OriginalAmount: price,
+		DiscountAmount: discount,
+		FinalAmount:    math.Round((price-discount)*100) / 100,
+		Currency:       course.Currency,
+	}, nil
+}
+
+// redeemCoupon takes one use of a coupon, failing with the coupon's typed error if it
+// has none left. The database checks and counts under a lock, so concurrent
+// enrollments can't overspend it.
+func (r *SupabaseCourseRepository) redeemCoupon(ctx context.Context, couponID uuid.UUID) error {
+	data, err := r.makeRequest("POST", "rpc/redeem_course_coupon", "", map[string]interface{}{
+		"p_coupon_id": couponID,
+	})
+	if err != nil {
+		return err
+	}
+	var status string
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("failed to decode coupon redemption: %w", err)
+	}
+	switch status {
+	case "ok":
+		return nil
+	case "expired":
+		return apperr.ErrCouponExpired
+	case "exhausted":
+		return apperr.ErrCouponExhausted
+	default:
+		return apperr.ErrCouponNotFound
+	}
+}
+
+// releaseCoupon gives back a use taken by an enrollment that then failed
+func (r *SupabaseCourseRepository) releaseCoupon(ctx context.Context, couponID uuid.UUID) {
+	if _, err := r.makeRequest("POST", "rpc/release_course_coupon", "", map[string]interface{}{
+		"p_coupon_id": couponID,
+	}); err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to release coupon %s: %v", couponID, err)
+	}
+}
+
+// Quiz methods
+
+// CreateQuiz creates a quiz with its questions. If the questions can't be saved the
+// quiz is removed again, so a lesson never has a quiz without questions.
+func (r *SupabaseCourseRepository) CreateQuiz(ctx context.Context, quiz *models.Quiz) error {
+	data, err := r.makeRequest("POST", "quizzes", "", map[string]interface{}{
+		"lesson_id":      quiz.LessonID,
+		"course_id":      quiz.CourseID,
+		"title":          quiz.Title,
+		"description":    quiz.Description,
+		"pass_threshold": quiz.PassThreshold,
+	})
+	if err != nil {
+		return err
+	}
+	var created []struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt string    `json:"created_at"`
+		UpdatedAt string    `json:"updated_at"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return fmt.Errorf("quiz not created")
+	}
+	quiz.ID = created[0].ID
+	quiz.CreatedAt = parseCourseTime(created[0].CreatedAt)
+	quiz.UpdatedAt = parseCourseTime(created[0].UpdatedAt)
+
+	rows := make([]map[string]interface{}, len(quiz.Questions))
+	for i, q := range quiz.Questions {
+		q.QuizID = quiz.ID
+		rows[i] = map[string]interface{}{
+			"quiz_id":         quiz.ID,
+			"question_text":   q.QuestionText,
+			"question_type":   q.QuestionType,
+			"options":         q.Options,
+			"correct_options": pq.StringArray(q.CorrectOptions),
+			"points":          q.Points,
+			"explanation":     q.Explanation,
+			"order_index":     q.OrderIndex,
+		}
+	}
+	data, err = r.makeRequest("POST", "quiz_questions", "", rows)
+	if err != nil {
+		if _, delErr := r.makeRequest("DELETE", "quizzes", fmt.Sprintf("?id=eq.%s", quiz.ID), nil); delErr != nil {
+			log.Printf("[SupabaseCourseRepo] Failed to remove quiz %s after its questions failed: %v", quiz.ID, delErr)
+		}
+		return err
+	}
+	var questions []struct {
+		ID         uuid.UUID `json:"id"`
+		OrderIndex int       `json:"order_index"`
+	}
+	if err := json.Unmarshal(data, &questions); err != nil {
+		return err
+	}
+	for _, created := range questions {
+		if created.OrderIndex >= 0 && created.OrderIndex < len(quiz.Questions) {
+			quiz.Questions[created.OrderIndex].ID = created.ID
+		}
+	}
+	return nil
+}
+
+// GetQuizByLesson returns a lesson's quiz with its questions in order, or nil if the
+// lesson has none
+func (r *SupabaseCourseRepository) GetQuizByLesson(ctx context.Context, lessonID uuid.UUID) (*models.Quiz, error) {
+	query := fmt.Sprintf("?lesson_id=eq.%s&select=*,quiz_questions(*)", lessonID.String())
+	data, err := r.makeRequest("GET", "quizzes", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var quizzes []struct {
+		ID            uuid.UUID `json:"id"`
+		LessonID      uuid.UUID `json:"lesson_id"`
+		CourseID      uuid.UUID `json:"course_id"`
+		Title         string    `json:"title"`
+		Description   *string   `json:"description"`
+		PassThreshold float64   `json:"pass_threshold"`
+		CreatedAt     string    `json:"created_at"`
+		UpdatedAt     string    `json:"updated_at"`
+		Questions     []struct {
+			ID             uuid.UUID           `json:"id"`
+			QuizID         uuid.UUID           `json:"quiz_id"`
+			QuestionText   string              `json:"question_text"`
+			QuestionType   string              `json:"question_type"`
+			Options        []models.QuizOption `json:"options"`
+			CorrectOptions []string            `json:"correct_options"`
+			Points         int                 `json:"points"`
+			Explanation    *string             `json:"explanation"`
+			OrderIndex     int                 `json:"order_index"`
+		} `json:"quiz_questions"`
+	}
+	if err := json.Unmarshal(data, &quizzes); err != nil {
+		return nil, err
+	}
+	if len(quizzes) == 0 {
+		return nil, nil
+	}
+	q := quizzes[0]
+	quiz := &models.Quiz{
+		ID:            q.ID,
+		LessonID:      q.LessonID,
+		CourseID:      q.CourseID,
+		Title:         q.Title,
+		Description:   q.Description,
+		PassThreshold: q.PassThreshold,
+		Questions:     make([]*models.QuizQuestion, len(q.Questions)),
+		CreatedAt:     parseCourseTime(q.CreatedAt),
+		UpdatedAt:     parseCourseTime(q.UpdatedAt),
+	}
+	for i, question := range q.Questions {
+		quiz.Questions[i] = &models.QuizQuestion{
+			ID:             question.ID,
+			QuizID:         question.QuizID,
+			QuestionText:   question.QuestionText,
+			QuestionType:   question.QuestionType,
+			Options:        question.Options,
+			CorrectOptions: question.CorrectOptions,
+			Points:         question.Points,
+			Explanation:    question.Explanation,
+			OrderIndex:     question.OrderIndex,
+		}
+	}
+	sort.Slice(quiz.Questions, func(i, j int) bool {
+		return quiz.Questions[i].OrderIndex < quiz.Questions[j].OrderIndex
+	})
+	return quiz, nil
+}
+
+func (r *SupabaseCourseRepository) CreateQuizAttempt(ctx context.Context, attempt *models.QuizAttempt) error {
+	data, err := r.makeRequest("POST", "quiz_attempts", "", map[string]interface{}{
+		"quiz_id":         attempt.QuizID,
+		"lesson_id":       attempt.LessonID,
+		"enrollment_id":   attempt.EnrollmentID,
+		"user_id":         attempt.UserID,
+		"answers":         attempt.Answers,
+		"points_earned":   attempt.PointsEarned,
+		"points_possible": attempt.PointsPossible,
+		"score":           attempt.Score,
+		"passed":          attempt.Passed,
+	})
+	if err != nil {
+		return err
+	}
+	var created []struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt string    `json:"created_at"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return fmt.Errorf("quiz attempt not created")
+	}
+	attempt.ID = created[0].ID
+	attempt.CreatedAt = parseCourseTime(created[0].CreatedAt)
+	return nil
+}
+
+// GetQuizAttempts returns an enrollment's attempts at a lesson's quiz, newest first
+func (r *SupabaseCourseRepository) GetQuizAttempts(ctx context.Context, enrollmentID, lessonID uuid.UUID) ([]*models.QuizAttempt, error) {
+	query := fmt.Sprintf("?enrollment_id=eq.%s&lesson_id=eq.%s&order=created_at.desc&select=*", enrollmentID.String(), lessonID.String())
+	data, err := r.makeRequest("GET", "quiz_attempts", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var attempts []struct {
+		ID             uuid.UUID              `json:"id"`
+		QuizID         uuid.UUID              `json:"quiz_id"`
+		LessonID       uuid.UUID              `json:"lesson_id"`
+		EnrollmentID   uuid.UUID              `json:"enrollment_id"`
+		UserID         uuid.UUID              `json:"user_id"`
+		Answers        map[uuid.UUID][]string `json:"answers"`
+		PointsEarned   int                    `json:"points_earned"`
+		PointsPossible int                    `json:"points_possible"`
+		Score          float64                `json:"score"`
+		Passed         bool                   `json:"passed"`
+		CreatedAt      string                 `json:"created_at"`
+	}
+	if err := json.Unmarshal(data, &attempts); err != nil {
+		return nil, err
+	}
+	result := make([]*models.QuizAttempt, len(attempts))
+	for i, a := range attempts {
+		result[i] = &models.QuizAttempt{
+			ID:             a.ID,
+			QuizID:         a.QuizID,
+			LessonID:       a.LessonID,
+			EnrollmentID:   a.EnrollmentID,
+			UserID:         a.UserID,
+			Answers:        a.Answers,
+			PointsEarned:   a.PointsEarned,
+			PointsPossible: a.PointsPossible,
+			Score:          a.Score,
+			Passed:         a.Passed,
+			CreatedAt:      parseCourseTime(a.CreatedAt),
+		}
+	}
+	return result, nil
+}
+
+func (r *SupabaseCourseRepository) HasPassedQuiz(ctx context.Context, enrollmentID, lessonID uuid.UUID) (bool, error) {
+	query := fmt.Sprintf("?enrollment_id=eq.%s&lesson_id=eq.%s&passed=is.true&select=id&limit=1", enrollmentID.String(), lessonID.String())
+	data, err := r.makeRequest("GET", "quiz_attempts", query, nil)
+	if err != nil {
+		return false, err
+	}
+	var attempts []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(data, &attempts); err != nil {
+		return false, err
+	}
+	return len(attempts) > 0, nil
+}
+
 // Certificate methods (stubs)
 func (r *SupabaseCourseRepository) CreateCertificate(ctx context.Context, certificate *models.CourseCertificate) error {
 	certNumber := fmt.Sprintf("AST-%s-%d", certificate.CourseID.String()[:8], time.Now().Unix())
@@ -1897,6 +2885,15 @@ func (r *SupabaseCourseRepository) GetCertificate(ctx context.Context, enrollmen
 	}, nil
 }
 
+// SetCertificateURL records where a certificate's rendered PDF is stored
+func (r *SupabaseCourseRepository) SetCertificateURL(ctx context.Context, certificateID uuid.UUID, certificateURL string) error {
+	query := fmt.Sprintf("?id=eq.%s", certificateID.String())
+	_, err := r.makeRequest("PATCH", "course_certificates", query, map[string]interface{}{
+		"certificate_url": certificateURL,
+	})
+	return err
+}
+
 func (r *SupabaseCourseRepository) GetCertificatesByUser(ctx context.Context, userID uuid.UUID) ([]*models.CourseCertificate, error) {
 	query := fmt.Sprintf("?user_id=eq.%s&order=issued_at.desc&select=*", userID.String())
 	data, err := r.makeRequest("GET", "course_certificates", query, nil)
@@ -1916,6 +2913,7 @@ func (r *SupabaseCourseRepository) GetCertificatesByUser(ctx context.Context, us
 		return nil, err
 	}
 	result := make([]*models.CourseCertificate, len(certificates))
+	courseIDs := make([]uuid.UUID, len(certificates))
 	for i, c := range certificates {
 		result[i] = &models.CourseCertificate{
 			ID:                c.ID,
@@ -1926,6 +2924,21 @@ func (r *SupabaseCourseRepository) GetCertificatesByUser(ctx context.Context, us
 			CertificateURL:    c.CertificateURL,
 			IssuedAt:          parseCourseTime(c.IssuedAt),
 		}
+		courseIDs[i] = c.CourseID
+	}
+
+	// Attach courses with one batched lookup instead of one fetch per certificate
+	courses, err := r.GetCoursesByIDs(ctx, courseIDs)
+	if err != nil {
+		log.Printf("[SupabaseCourseRepo] Failed to load courses for certificates: %v", err)
+		return result, nil
+	}
+	byID := make(map[uuid.UUID]*models.Course, len(courses))
+	for _, course := range courses {
+		byID[course.ID] = course
+	}
+	for _, cert := range result {
+		cert.Course = byID[cert.CourseID]
 	}
 	return result, nil
 }
@@ -0,0 +1,475 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// courseCounterServer fakes PostgREST for the enrollment tables, answering writes
+// to course_enrollments with rows and totting up the counter RPCs per function
+type courseCounterServer struct {
+	rows     map[string]string // Response body per "METHOD table"
+	counters map[string]int
+}
+
+func newCourseCounterRepo(t *testing.T, rows map[string]string) (*SupabaseCourseRepository, *courseCounterServer) {
+	t.Helper()
+	fake := &courseCounterServer{rows: rows, counters: map[string]int{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table := strings.TrimPrefix(r.URL.Path, "/rest/v1/")
+		if fn, ok := strings.CutPrefix(table, "rpc/"); ok {
+			var body struct {
+				Delta int `json:"p_delta"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			fake.counters[fn] += body.Delta
+			w.Write([]byte("1"))
+			return
+		}
+		body, ok := fake.rows[r.Method+" "+table]
+		if !ok {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			body = "[]"
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewSupabaseCourseRepository(server.URL, "key"), fake
+}
+
+func TestCreateEnrollmentIncrementsEnrollmentCount(t *testing.T) {
+	repo, fake := newCourseCounterRepo(t, map[string]string{
+		"POST course_enrollments": `{"id": "` + uuid.NewString() + `", "enrolled_at": "2026-01-02T03:04:05Z"}`,
+	})
+
+	err := repo.CreateEnrollment(context.Background(), &models.CourseEnrollment{
+		CourseID:      uuid.New(),
+		UserID:        uuid.New(),
+		PaymentStatus: "free",
+	})
+	if err != nil {
+		t.Fatalf("CreateEnrollment: %v", err)
+	}
+	if got := fake.counters["adjust_course_enrollment_count"]; got != 1 {
+		t.Errorf("enrollment count moved by %d, want 1", got)
+	}
+}
+
+func TestUpdateEnrollmentCountsCompletionOnce(t *testing.T) {
+	completedAt := time.Now()
+	enrollment := &models.CourseEnrollment{ID: uuid.New(), CourseID: uuid.New(), CompletedAt: &completedAt}
+
+	repo, fake := newCourseCounterRepo(t, map[string]string{
+		"PATCH course_enrollments": `[{"id": "` + enrollment.ID.String() + `"}]`,
+	})
+	if err := repo.UpdateEnrollment(context.Background(), enrollment); err != nil {
+		t.Fatalf("UpdateEnrollment: %v", err)
+	}
+
+	// Already completed: the completed_at=is.null filter matches nothing
+	fake.rows["PATCH course_enrollments"] = `[]`
+	if err := repo.UpdateEnrollment(context.Background(), enrollment); err != nil {
+		t.Fatalf("UpdateEnrollment: %v", err)
+	}
+
+	if got := fake.counters["adjust_course_completion_count"]; got != 1 {
+		t.Errorf("completion count moved by %d, want 1", got)
+	}
+}
+
+func TestDeleteEnrollmentDecrementsCounters(t *testing.T) {
+	tests := []struct {
+		name            string
+		deleted         string
+		wantRemoved     bool
+		wantEnrollments int
+		wantCompletions int
+	}{
+		{"in progress", `[{"id": "` + uuid.NewString() + `", "completed_at": null}]`, true, -1, 0},
+		{"completed", `[{"id": "` + uuid.NewString() + `", "completed_at": "2026-01-02T03:04:05Z"}]`, true, -1, -1},
+		{"not enrolled", `[]`, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, fake := newCourseCounterRepo(t, map[string]string{"DELETE course_enrollments": tt.deleted})
+
+			removed, err := repo.DeleteEnrollment(context.Background(), uuid.New(), uuid.New())
+			if err != nil {
+				t.Fatalf("DeleteEnrollment: %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("removed = %v, want %v", removed, tt.wantRemoved)
+			}
+			if got := fake.counters["adjust_course_enrollment_count"]; got != tt.wantEnrollments {
+				t.Errorf("enrollment count moved by %d, want %d", got, tt.wantEnrollments)
+			}
+			if got := fake.counters["adjust_course_completion_count"]; got != tt.wantCompletions {
+				t.Errorf("completion count moved by %d, want %d", got, tt.wantCompletions)
+			}
+		})
+	}
+}
+
+func TestGetCoursesByIDsMakesOneQuery(t *testing.T) {
+	ids := make([]uuid.UUID, 10)
+	rows := make([]string, 0, len(ids))
+	for i := range ids {
+		ids[i] = uuid.New()
+		// PostgREST returns rows in its own order: serve them reversed
+		rows = append([]string{`{"id": "` + ids[i].String() + `", "title": "Course", "creator": {"id": "` + uuid.NewString() + `", "username": "creator"}}`}, rows...)
+	}
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Write([]byte("[" + strings.Join(rows, ",") + "]"))
+	}))
+	t.Cleanup(server.Close)
+	repo := NewSupabaseCourseRepository(server.URL, "key")
+
+	courses, err := repo.GetCoursesByIDs(context.Background(), append(ids, ids[0]))
+	if err != nil {
+		t.Fatalf("GetCoursesByIDs: %v", err)
+	}
+
+	if len(queries) != 1 {
+		t.Fatalf("made %d requests, want 1: %v", len(queries), queries)
+	}
+	if !strings.HasPrefix(queries[0], "/rest/v1/courses?id=in.(") || !strings.Contains(queries[0], "creator:users") {
+		t.Errorf("query %q should fetch the courses and embed their creators", queries[0])
+	}
+	if len(courses) != len(ids) {
+		t.Fatalf("got %d courses, want %d", len(courses), len(ids))
+	}
+	for i, course := range courses {
+		if course.ID != ids[i] {
+			t.Errorf("courses[%d] = %s, want %s (input order)", i, course.ID, ids[i])
+		}
+		if course.Creator == nil || course.Creator.Username != "creator" {
+			t.Errorf("courses[%d] creator not hydrated", i)
+		}
+	}
+}
+
+// orderingTable fakes one course's course_modules (or one module's course_lessons).
+// Like Postgres, it applies a bulk upsert as one statement: a bad row fails the whole
+// batch and changes nothing.
+type orderingTable struct {
+	t      *testing.T
+	table  string
+	orders map[string]int // order_index by row ID
+	failID string         // A row whose write the database rejects
+	writes int
+}
+
+func (db *orderingTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/rest/v1/"+db.table {
+		db.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var rows []map[string]interface{}
+		for id, order := range db.orders {
+			rows = append(rows, map[string]interface{}{"id": id, "title": "Item " + id[:4], "order_index": order})
+		}
+		json.NewEncoder(w).Encode(rows)
+	case http.MethodPost:
+		db.writes++
+		var rows []map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&rows)
+		for _, row := range rows {
+			if row["id"] == db.failID {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"message": "statement timeout"}`))
+				return
+			}
+			if row["title"] == nil {
+				db.t.Error("an upserted row should carry its other columns")
+			}
+		}
+		for _, row := range rows {
+			db.orders[row["id"].(string)] = int(row["order_index"].(float64))
+		}
+		w.WriteHeader(http.StatusCreated)
+	default:
+		db.writes++
+		db.t.Errorf("reordering should be one bulk upsert, got %s", r.Method)
+	}
+}
+
+// newOrderingTable returns a fake table of n rows in order, and their IDs
+func newOrderingTable(t *testing.T, table string, n int) (*orderingTable, []uuid.UUID) {
+	db := &orderingTable{t: t, table: table, orders: map[string]int{}}
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.New()
+		db.orders[ids[i].String()] = i
+	}
+	return db, ids
+}
+
+func TestReorderIsAllOrNothing(t *testing.T) {
+	for _, table := range []string{"course_modules", "course_lessons"} {
+		t.Run(table, func(t *testing.T) {
+			db, ids := newOrderingTable(t, table, 4)
+			server := httptest.NewServer(db)
+			defer server.Close()
+			repo := NewSupabaseCourseRepository(server.URL, "key")
+			reorder := repo.ReorderModules
+			if table == "course_lessons" {
+				reorder = repo.ReorderLessons
+			}
+
+			reversed := map[uuid.UUID]int{ids[0]: 3, ids[1]: 2, ids[2]: 1, ids[3]: 0}
+
+			// The write fails on a row in the middle of the batch
+			db.failID = ids[2].String()
+			if err := reorder(context.Background(), uuid.New(), reversed); err == nil {
+				t.Fatal("a failed write should be reported")
+			}
+			for i, id := range ids {
+				if got := db.orders[id.String()]; got != i {
+					t.Errorf("after the failure row %d is at %d, want the old order kept", i, got)
+				}
+			}
+
+			db.failID = ""
+			if err := reorder(context.Background(), uuid.New(), reversed); err != nil {
+				t.Fatalf("reorder: %v", err)
+			}
+			for id, want := range reversed {
+				if got := db.orders[id.String()]; got != want {
+					t.Errorf("row %s at %d, want %d", id, got, want)
+				}
+			}
+			if db.writes != 2 {
+				t.Errorf("made %d writes for two reorders, want one each", db.writes)
+			}
+		})
+	}
+}
+
+func TestReorderRejectsIncompleteOrderings(t *testing.T) {
+	db, ids := newOrderingTable(t, "course_modules", 3)
+	server := httptest.NewServer(db)
+	defer server.Close()
+	repo := NewSupabaseCourseRepository(server.URL, "key")
+
+	tests := []struct {
+		name   string
+		orders map[uuid.UUID]int
+	}{
+		{"missing item", map[uuid.UUID]int{ids[0]: 0, ids[1]: 1}},
+		{"unknown item", map[uuid.UUID]int{ids[0]: 0, ids[1]: 1, uuid.New(): 2}},
+		{"extra item", map[uuid.UUID]int{ids[0]: 0, ids[1]: 1, ids[2]: 2, uuid.New(): 3}},
+		{"duplicate position", map[uuid.UUID]int{ids[0]: 0, ids[1]: 1, ids[2]: 1}},
+		{"gap", map[uuid.UUID]int{ids[0]: 0, ids[1]: 1, ids[2]: 3}},
+		{"negative", map[uuid.UUID]int{ids[0]: -1, ids[1]: 0, ids[2]: 1}},
+	}
+
+	for _, tt := range tests {
+		err := repo.ReorderModules(context.Background(), uuid.New(), tt.orders)
+		var appErr *apperr.AppError
+		if !errors.As(err, &appErr) || appErr.Code != http.StatusBadRequest {
+			t.Errorf("%s: err = %v, want a 400", tt.name, err)
+		}
+	}
+	if db.writes != 0 {
+		t.Errorf("made %d writes for invalid orderings, want none", db.writes)
+	}
+}
+
+// courseReview is a course_reviews row
+type courseReview struct {
+	course, user uuid.UUID
+	rating       int
+}
+
+// reviewTable fakes course_reviews and the course rows they rate. Review writes
+// recompute the course's average_rating and review_count the way the trigger in
+// migration 55 does; reconcile_course_ratings repairs courses that drifted.
+type reviewTable struct {
+	t       *testing.T
+	reviews map[uuid.UUID]*courseReview
+	ratings map[uuid.UUID]courseRating
+}
+
+// courseRating is a course row's average_rating and review_count
+type courseRating struct {
+	average float64
+	count   int
+}
+
+func newReviewTable(t *testing.T) (*SupabaseCourseRepository, *reviewTable) {
+	db := &reviewTable{t: t, reviews: map[uuid.UUID]*courseReview{}, ratings: map[uuid.UUID]courseRating{}}
+	server := httptest.NewServer(db)
+	t.Cleanup(server.Close)
+	return NewSupabaseCourseRepository(server.URL, "key"), db
+}
+
+// actual returns a course's rating from its reviews: the average to two places
+func (db *reviewTable) actual(course uuid.UUID) (float64, int) {
+	sum, count := 0, 0
+	for _, review := range db.reviews {
+		if review.course == course {
+			sum += review.rating
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return math.Round(float64(sum)/float64(count)*100) / 100, count
+}
+
+func (db *reviewTable) recompute(course uuid.UUID) {
+	average, count := db.actual(course)
+	db.ratings[course] = courseRating{average, count}
+}
+
+func (db *reviewTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter := func(column string) uuid.UUID {
+		id, _ := uuid.Parse(strings.TrimPrefix(r.URL.Query().Get(column), "eq."))
+		return id
+	}
+	switch r.Method + " " + strings.TrimPrefix(r.URL.Path, "/rest/v1/") {
+	case "POST course_reviews":
+		var body struct {
+			Course uuid.UUID `json:"course_id"`
+			User   uuid.UUID `json:"user_id"`
+			Rating int       `json:"rating"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		id := uuid.New()
+		db.reviews[id] = &courseReview{body.Course, body.User, body.Rating}
+		db.recompute(body.Course)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "created_at": "2026-01-02T03:04:05Z", "updated_at": "2026-01-02T03:04:05Z"})
+	case "PATCH course_reviews":
+		var body struct {
+			Rating int `json:"rating"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if review, ok := db.reviews[filter("id")]; ok {
+			review.rating = body.Rating
+			db.recompute(review.course)
+		}
+		w.Write([]byte("[]"))
+	case "DELETE course_reviews":
+		course, user := filter("course_id"), filter("user_id")
+		for id, review := range db.reviews {
+			if review.course == course && review.user == user {
+				delete(db.reviews, id)
+			}
+		}
+		db.recompute(course)
+		w.Write([]byte("[]"))
+	case "GET courses":
+		id := filter("id")
+		rating := db.ratings[id]
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"id": id, "title": "Course", "average_rating": rating.average, "review_count": rating.count}})
+	case "POST rpc/reconcile_course_ratings":
+		corrected := 0
+		for course, stored := range db.ratings {
+			if average, count := db.actual(course); stored.average != average || stored.count != count {
+				db.recompute(course)
+				corrected++
+			}
+		}
+		json.NewEncoder(w).Encode(corrected)
+	default:
+		db.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestReviewChangesMoveTheCourseAverage(t *testing.T) {
+	repo, _ := newReviewTable(t)
+	ctx := context.Background()
+	course, other := uuid.New(), uuid.New()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+
+	assertRating := func(step string, wantAverage float64, wantCount int) {
+		t.Helper()
+		got, err := repo.GetCourseByID(ctx, course)
+		if err != nil {
+			t.Fatalf("%s: GetCourseByID: %v", step, err)
+		}
+		if got.AverageRating != wantAverage || got.ReviewCount != wantCount {
+			t.Errorf("%s: rating = %.2f from %d reviews, want %.2f from %d", step, got.AverageRating, got.ReviewCount, wantAverage, wantCount)
+		}
+	}
+
+	first := &models.CourseReview{CourseID: course, UserID: alice, Rating: 5}
+	for _, review := range []*models.CourseReview{
+		first,
+		{CourseID: course, UserID: bob, Rating: 2},
+		{CourseID: course, UserID: carol, Rating: 4},
+		{CourseID: other, UserID: alice, Rating: 1}, // Another course's review changes nothing here
+	} {
+		if err := repo.CreateReview(ctx, review); err != nil {
+			t.Fatalf("CreateReview: %v", err)
+		}
+	}
+	assertRating("after adding", 3.67, 3)
+
+	first.Rating = 1
+	if err := repo.UpdateReview(ctx, first); err != nil {
+		t.Fatalf("UpdateReview: %v", err)
+	}
+	assertRating("after updating", 2.33, 3)
+
+	if err := repo.DeleteReview(ctx, course, bob); err != nil {
+		t.Fatalf("DeleteReview: %v", err)
+	}
+	assertRating("after deleting", 2.5, 2)
+
+	for _, user := range []uuid.UUID{alice, carol} {
+		if err := repo.DeleteReview(ctx, course, user); err != nil {
+			t.Fatalf("DeleteReview: %v", err)
+		}
+	}
+	assertRating("with no reviews left", 0, 0)
+}
+
+func TestReconcileRatingsRepairsDrift(t *testing.T) {
+	repo, db := newReviewTable(t)
+	ctx := context.Background()
+	drifted, fine := uuid.New(), uuid.New()
+	for _, course := range []uuid.UUID{drifted, fine} {
+		if err := repo.CreateReview(ctx, &models.CourseReview{CourseID: course, UserID: uuid.New(), Rating: 4}); err != nil {
+			t.Fatalf("CreateReview: %v", err)
+		}
+	}
+	// A write made while the trigger was missing
+	db.reviews[uuid.New()] = &courseReview{drifted, uuid.New(), 1}
+
+	corrected, err := repo.ReconcileRatings(ctx)
+	if err != nil {
+		t.Fatalf("ReconcileRatings: %v", err)
+	}
+	if corrected != 1 {
+		t.Errorf("corrected %d courses, want 1", corrected)
+	}
+	if got, _ := repo.GetCourseByID(ctx, drifted); got.AverageRating != 2.5 || got.ReviewCount != 2 {
+		t.Errorf("repaired rating = %.2f from %d reviews, want 2.50 from 2", got.AverageRating, got.ReviewCount)
+	}
+
+	if corrected, _ := repo.ReconcileRatings(ctx); corrected != 0 {
+		t.Errorf("a second pass corrected %d courses, want none", corrected)
+	}
+}
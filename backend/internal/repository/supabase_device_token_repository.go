@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// SupabaseDeviceTokenRepository implements DeviceTokenRepository for Supabase
+type SupabaseDeviceTokenRepository struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewSupabaseDeviceTokenRepository creates a new Supabase device token repository
+func NewSupabaseDeviceTokenRepository(baseURL, apiKey string) *SupabaseDeviceTokenRepository {
+	return &SupabaseDeviceTokenRepository{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		http:    newSupabaseHTTPClient(10 * time.Second),
+	}
+}
+
+// request runs one PostgREST call against push_device_tokens and returns the response body
+func (r *SupabaseDeviceTokenRepository) request(ctx context.Context, method string, query url.Values, body interface{}, prefer string) ([]byte, error) {
+	u := fmt.Sprintf("%s/rest/v1/push_device_tokens", r.baseURL)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, apperr.ErrInternalServer
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, apperr.ErrInternalServer
+	}
+	req.Header.Set("apikey", r.apiKey)
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if prefer != "" {
+		req.Header.Set("Prefer", prefer)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, apperr.ErrDatabaseError
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, apperr.ErrDatabaseError
+	}
+	if resp.StatusCode >= 300 {
+		log.Printf("[Supabase] %s push_device_tokens failed: HTTP %d - %s", method, resp.StatusCode, string(data))
+		return nil, apperr.ErrDatabaseError
+	}
+	return data, nil
+}
+
+// SaveDeviceToken registers a token, or refreshes it if it's already known. The
+// upsert is keyed on the token, so a device that switches accounts follows the switch.
+func (r *SupabaseDeviceTokenRepository) SaveDeviceToken(ctx context.Context, token *models.DeviceToken) error {
+	q := url.Values{}
+	q.Set("on_conflict", "platform,token")
+
+	_, err := r.request(ctx, http.MethodPost, q, map[string]interface{}{
+		"user_id":      token.UserID,
+		"platform":     token.Platform,
+		"token":        token.Token,
+		"app_version":  token.AppVersion,
+		"last_seen_at": time.Now(),
+	}, "resolution=merge-duplicates,return=minimal")
+	return err
+}
+
+// GetDeviceTokens retrieves every token registered by a user's devices
+func (r *SupabaseDeviceTokenRepository) GetDeviceTokens(ctx context.Context, userID uuid.UUID) ([]*models.DeviceToken, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("select", "*")
+
+	data, err := r.request(ctx, http.MethodGet, q, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*models.DeviceToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, apperr.ErrDatabaseError
+	}
+	return tokens, nil
+}
+
+// DeleteDeviceToken removes one of a user's tokens
+func (r *SupabaseDeviceTokenRepository) DeleteDeviceToken(ctx context.Context, userID uuid.UUID, token string) error {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("token", "eq."+token)
+
+	_, err := r.request(ctx, http.MethodDelete, q, nil, "return=minimal")
+	return err
+}
+
+// DeleteDeviceTokens removes tokens a push provider reported as no longer valid
+func (r *SupabaseDeviceTokenRepository) DeleteDeviceTokens(ctx context.Context, platform models.PushPlatform, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	// Tokens can contain commas and colons, so each is quoted in the in.() list
+	quoted := make([]string, len(tokens))
+	for i, t := range tokens {
+		t = strings.ReplaceAll(t, `\`, `\\`)
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, `\"`) + `"`
+	}
+
+	q := url.Values{}
+	q.Set("platform", "eq."+string(platform))
+	q.Set("token", "in.("+strings.Join(quoted, ",")+")")
+
+	_, err := r.request(ctx, http.MethodDelete, q, nil, "return=minimal")
+	return err
+}
@@ -30,9 +30,7 @@ func NewSupabaseEventRepository(supabaseURL, serviceKey string) *SupabaseEventRe
 	return &SupabaseEventRepository{
 		supabaseURL: supabaseURL,
 		serviceKey:  serviceKey,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:      newSupabaseHTTPClient(30 * time.Second),
 	}
 }
 
@@ -28,7 +28,7 @@ func NewSupabaseExperienceRepository(baseURL, serviceRoleKey string) *SupabaseEx
 	return &SupabaseExperienceRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -369,7 +369,7 @@ func NewSupabaseEducationRepository(baseURL, serviceRoleKey string) *SupabaseEdu
 	return &SupabaseEducationRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/metrics"
+)
+
+// How the shared transport reacts to PostgREST answering 429
+const (
+	supabaseThrottleRetries    = 2                      // Extra attempts for a throttled read
+	supabaseThrottleBackoff    = 250 * time.Millisecond // First wait when there's no Retry-After; doubles per attempt
+	supabaseMaxThrottleBackoff = 2 * time.Second        // Longer waits aren't worth holding the request for
+)
+
+// supabaseTransport is shared by all Supabase repositories so PostgREST calls
+// are recorded in request/latency/error metrics and throttled reads are retried
+var supabaseTransport = &throttleTransport{base: metrics.NewSupabaseTransport(http.DefaultTransport)}
+
+// newSupabaseHTTPClient returns an HTTP client for Supabase REST calls with metrics instrumentation
+func newSupabaseHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: supabaseTransport,
+	}
+}
+
+// supabasePingClient is used by health checks; their own timeouts bound each ping
+var supabasePingClient = newSupabaseHTTPClient(30 * time.Second)
+
+// PingSupabase checks that PostgREST is up and the service key works, with the cheapest
+// query there is: a HEAD for one user, which returns no rows
+func PingSupabase(ctx context.Context, baseURL, serviceKey string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead,
+		strings.TrimRight(baseURL, "/")+"/rest/v1/users?select=id&limit=1", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", serviceKey)
+	req.Header.Set("Authorization", "Bearer "+serviceKey)
+
+	resp, err := supabasePingClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("supabase unreachable: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("supabase answered HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// throttleTransport retries reads that Supabase rejected with 429, waiting as long as
+// Retry-After asks. Writes aren't retried, since PostgREST may have applied them. A 429
+// that is given up on is passed through to the repository, and flagged on the request
+// context so the API answers 503 with Retry-After rather than a bare 500.
+type throttleTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip executes the request, retrying it while it is throttled and safe to repeat
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := (req.Method == http.MethodGet || req.Method == http.MethodHead) && req.Body == nil
+	backoff := supabaseThrottleBackoff
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+
+		wait := retryAfter(resp.Header, backoff)
+		table := metrics.TableFromPath(req.URL.Path)
+		if !retryable || attempt >= supabaseThrottleRetries || wait > supabaseMaxThrottleBackoff {
+			metrics.Supabase.ObserveThrottle(table, req.Method, false)
+			metrics.MarkThrottled(req.Context(), wait)
+			return resp, nil
+		}
+		metrics.Supabase.ObserveThrottle(table, req.Method, true)
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// retryAfter reads a Retry-After header in either of its forms, falling back to
+// fallback when it's missing or unparseable
+func retryAfter(header http.Header, fallback time.Duration) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return fallback
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return fallback
+}
+
+// sleepContext waits for d, returning early with the context's error if it ends first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
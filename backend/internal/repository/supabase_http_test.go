@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/metrics"
+
+	"github.com/google/uuid"
+)
+
+// throttledServer answers 429 with the given Retry-After for the first throttled
+// requests, then 200 with an empty list
+func throttledServer(throttled int, retryAfter string) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= throttled {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message": "rate limited"}`))
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	return server, &calls
+}
+
+func TestThrottledReadIsRetried(t *testing.T) {
+	server, calls := throttledServer(1, "0")
+	defer server.Close()
+	repo := NewSupabasePostRepository(server.URL, "key")
+	retried := metrics.Supabase.ThrottleCount("posts", http.MethodGet, "retried")
+
+	ctx, signal := metrics.WithThrottleSignal(context.Background())
+	if _, _, err := repo.GetUserPosts(ctx, uuid.New(), 5, 0, false); err != nil {
+		t.Fatalf("a read throttled once should succeed on retry: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("made %d requests, want 2", *calls)
+	}
+	if got := metrics.Supabase.ThrottleCount("posts", http.MethodGet, "retried") - retried; got != 1 {
+		t.Errorf("recorded %d retried throttles, want 1", got)
+	}
+	if _, throttled := signal.Throttled(); throttled {
+		t.Error("a request that recovered shouldn't be flagged as throttled")
+	}
+}
+
+func TestThrottledRequestsThatAreGivenUpOn(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		retryAfter string
+		wantCalls  int
+		wantWait   time.Duration
+	}{
+		{"write", http.MethodPost, "3", 1, 3 * time.Second},
+		{"retries used up", http.MethodGet, "0", supabaseThrottleRetries + 1, 0},
+		{"wait too long", http.MethodGet, "60", 1, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := throttledServer(10, tt.retryAfter)
+			defer server.Close()
+			surfaced := metrics.Supabase.ThrottleCount("throttle_test", tt.method, "surfaced")
+
+			ctx, signal := metrics.WithThrottleSignal(context.Background())
+			var body io.Reader
+			if tt.method == http.MethodPost {
+				body = strings.NewReader(`{}`)
+			}
+			req, _ := http.NewRequestWithContext(ctx, tt.method, server.URL+"/rest/v1/throttle_test", body)
+			resp, err := newSupabaseHTTPClient(5 * time.Second).Do(req)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusTooManyRequests {
+				t.Errorf("status = %d, want the 429 passed through", resp.StatusCode)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", *calls, tt.wantCalls)
+			}
+			if wait, throttled := signal.Throttled(); !throttled || wait != tt.wantWait {
+				t.Errorf("signal = %v, %v; want throttled with a %v wait", wait, throttled, tt.wantWait)
+			}
+			if got := metrics.Supabase.ThrottleCount("throttle_test", tt.method, "surfaced") - surfaced; got != 1 {
+				t.Errorf("recorded %d surfaced throttles, want 1", got)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", time.Second},
+		{"2", 2 * time.Second},
+		{"soon", time.Second},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		if got := retryAfter(header, time.Second); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
 
 	"github.com/google/uuid"
 )
@@ -107,6 +110,10 @@ type supabaseMessage struct {
 	// Forward feature
 	ForwardedFromID *uuid.UUID `json:"forwarded_from_id"`
 	IsForwarded     bool       `json:"is_forwarded"`
+	// Disappearing messages
+	ExpiresAt *string `json:"expires_at"`
+	// Voice messages
+	Waveform []float64 `json:"waveform"`
 	// Relations
 	Sender    *supabaseUser            `json:"sender"`
 	ReplyTo   *supabaseMessage         `json:"reply_to"`
@@ -210,12 +217,21 @@ func (sm *supabaseMessage) toMessage() (*models.Message, error) {
 		}
 	}
 
+	if sm.ExpiresAt != nil && *sm.ExpiresAt != "" {
+		t, err := parseSupabaseTime(*sm.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expires_at: %w", err)
+		}
+		msg.ExpiresAt = &t
+	}
+
 	// Set other new fields
 	msg.PinnedBy = sm.PinnedBy
 	msg.EditCount = sm.EditCount
 	msg.OriginalContent = sm.OriginalContent
 	msg.ForwardedFromID = sm.ForwardedFromID
 	msg.IsForwarded = sm.IsForwarded
+	msg.Waveform = sm.Waveform
 
 	// Set IsPinned based on whether pinned_at is not null
 	msg.IsPinned = msg.PinnedAt != nil
@@ -228,6 +244,10 @@ type supabaseConversation struct {
 	ID                   uuid.UUID     `json:"id"`
 	Participant1ID       uuid.UUID     `json:"participant1_id"`
 	Participant2ID       uuid.UUID     `json:"participant2_id"`
+	IsGroup              bool          `json:"is_group"`
+	Name                 *string       `json:"name"`
+	CreatedBy            *uuid.UUID    `json:"created_by"`
+	MessageTTLSeconds    *int          `json:"message_ttl_seconds"`
 	LastMessageContent   *string       `json:"last_message_content"`
 	LastMessageEncrypted *string       `json:"last_message_encrypted"`
 	LastMessageIV        *string       `json:"last_message_iv"`
@@ -274,6 +294,10 @@ func (sc *supabaseConversation) toConversation() (*models.Conversation, error) {
 		ID:                   sc.ID,
 		Participant1ID:       sc.Participant1ID,
 		Participant2ID:       sc.Participant2ID,
+		IsGroup:              sc.IsGroup,
+		Name:                 sc.Name,
+		CreatedBy:            sc.CreatedBy,
+		MessageTTLSeconds:    sc.MessageTTLSeconds,
 		LastMessageContent:   sc.LastMessageContent,
 		LastMessageEncrypted: sc.LastMessageEncrypted,
 		LastMessageIV:        sc.LastMessageIV,
@@ -345,6 +369,62 @@ func (sc *supabaseConversation) toConversation() (*models.Conversation, error) {
 	return conv, nil
 }
 
+// supabaseConversationMember is a helper for unmarshaling ConversationMember with string timestamps
+type supabaseConversationMember struct {
+	ConversationID uuid.UUID               `json:"conversation_id"`
+	UserID         uuid.UUID               `json:"user_id"`
+	Role           models.ConversationRole `json:"role"`
+	UnreadCount    int                     `json:"unread_count"`
+	JoinedAt       string                  `json:"joined_at"`
+	LeftAt         *string                 `json:"left_at"`
+	DeliveredAt    *string                 `json:"delivered_at"`
+	ReadAt         *string                 `json:"read_at"`
+	User           *supabaseUser           `json:"user"`
+}
+
+func (sm *supabaseConversationMember) toMember() (*models.ConversationMember, error) {
+	member := &models.ConversationMember{
+		ConversationID: sm.ConversationID,
+		UserID:         sm.UserID,
+		Role:           sm.Role,
+		UnreadCount:    sm.UnreadCount,
+	}
+
+	if sm.User != nil {
+		user, err := sm.User.toUser()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse member user: %w", err)
+		}
+		member.User = user
+	}
+
+	joinedAt, err := parseSupabaseTime(sm.JoinedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse joined_at: %w", err)
+	}
+	member.JoinedAt = joinedAt
+
+	for _, ts := range []struct {
+		raw *string
+		dst **time.Time
+	}{
+		{sm.LeftAt, &member.LeftAt},
+		{sm.DeliveredAt, &member.DeliveredAt},
+		{sm.ReadAt, &member.ReadAt},
+	} {
+		if ts.raw == nil || *ts.raw == "" {
+			continue
+		}
+		t, err := parseSupabaseTime(*ts.raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse member timestamp: %w", err)
+		}
+		*ts.dst = &t
+	}
+
+	return member, nil
+}
+
 type supabaseMessageRepository struct {
 	supabaseURL string
 	apiKey      string
@@ -356,7 +436,7 @@ func NewSupabaseMessageRepository(supabaseURL, serviceKey string) (MessageReposi
 	return &supabaseMessageRepository{
 		supabaseURL: supabaseURL,
 		apiKey:      serviceKey,
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		httpClient:  newSupabaseHTTPClient(30 * time.Second),
 	}, nil
 }
 
@@ -377,6 +457,14 @@ func (r *supabaseMessageRepository) messagesURL(query url.Values) string {
 	return base
 }
 
+func (r *supabaseMessageRepository) membersURL(query url.Values) string {
+	base := fmt.Sprintf("%s/rest/v1/conversation_members", r.supabaseURL)
+	if len(query) > 0 {
+		return fmt.Sprintf("%s?%s", base, query.Encode())
+	}
+	return base
+}
+
 func (r *supabaseMessageRepository) reactionsURL(query url.Values) string {
 	base := fmt.Sprintf("%s/rest/v1/message_reactions", r.supabaseURL)
 	if len(query) > 0 {
@@ -496,14 +584,34 @@ func (r *supabaseMessageRepository) GetConversation(ctx context.Context, convers
 		return nil, fmt.Errorf("conversation not found")
 	}
 
-	return sbConversations[0].toConversation()
+	conv, err := sbConversations[0].toConversation()
+	if err != nil {
+		return nil, err
+	}
+
+	if conv.IsGroup {
+		if conv.Members, err = r.GetConversationMembers(ctx, conv.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return conv, nil
 }
 
 // GetUserConversations retrieves all conversations for a user
 func (r *supabaseMessageRepository) GetUserConversations(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Conversation, error) {
+	groupIDs, err := r.activeGroupIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	query := url.Values{}
-	// Filter: user is participant AND has at least one message (Instagram-style)
-	query.Set("or", fmt.Sprintf("(participant1_id.eq.%s,participant2_id.eq.%s)", userID, userID))
+	// Filter: user is participant or group member AND has at least one message (Instagram-style)
+	if len(groupIDs) > 0 {
+		query.Set("or", fmt.Sprintf("(participant1_id.eq.%s,participant2_id.eq.%s,id.in.(%s))", userID, userID, strings.Join(groupIDs, ",")))
+	} else {
+		query.Set("or", fmt.Sprintf("(participant1_id.eq.%s,participant2_id.eq.%s)", userID, userID))
+	}
 	query.Set("last_message_at", "not.is.null") // Only conversations with messages
 	query.Set("select", "*,participant1:participant1_id(*),participant2:participant2_id(*)")
 	query.Set("order", "last_message_at.desc.nullslast,created_at.desc")
@@ -524,6 +632,24 @@ func (r *supabaseMessageRepository) GetUserConversations(ctx context.Context, us
 		return nil, err
 	}
 
+	// Load the member lists of every group on the page in one go
+	members := map[uuid.UUID][]*models.ConversationMember{}
+	var pageGroupIDs []string
+	for _, sbConv := range sbConversations {
+		if sbConv.IsGroup {
+			pageGroupIDs = append(pageGroupIDs, sbConv.ID.String())
+		}
+	}
+	if len(pageGroupIDs) > 0 {
+		list, err := r.getMembers(ctx, "in.("+strings.Join(pageGroupIDs, ",")+")")
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range list {
+			members[m.ConversationID] = append(members[m.ConversationID], m)
+		}
+	}
+
 	// Convert and set OtherUser and UnreadCount for each conversation
 	conversations := make([]*models.Conversation, 0, len(sbConversations))
 	for _, sbConv := range sbConversations {
@@ -533,7 +659,10 @@ func (r *supabaseMessageRepository) GetUserConversations(ctx context.Context, us
 			continue
 		}
 
-		if conv.Participant1ID == userID {
+		if conv.IsGroup {
+			conv.Members = members[conv.ID]
+			conv.SetViewer(userID)
+		} else if conv.Participant1ID == userID {
 			conv.OtherUser = conv.Participant2
 			conv.UnreadCount = conv.UnreadCountP1
 
@@ -581,6 +710,11 @@ func (r *supabaseMessageRepository) UpdateConversationTyping(ctx context.Context
 		return err
 	}
 
+	// Groups only have the cached, broadcast typing state
+	if conv.IsGroup {
+		return nil
+	}
+
 	updates := make(map[string]interface{})
 	now := time.Now().Format(time.RFC3339)
 
@@ -619,6 +753,10 @@ func (r *supabaseMessageRepository) MarkConversationAsRead(ctx context.Context,
 		return err
 	}
 
+	if conv.IsGroup {
+		return r.updateMember(ctx, conversationID, userID, map[string]interface{}{"unread_count": 0})
+	}
+
 	updates := make(map[string]interface{})
 	if conv.Participant1ID == userID {
 		updates["unread_count_p1"] = 0
@@ -677,6 +815,574 @@ func (r *supabaseMessageRepository) GetUnreadCount(ctx context.Context, userID u
 	return count, nil
 }
 
+// ============================================
+// GROUPS
+// ============================================
+
+// CreateGroupConversation creates a group with creatorID as its admin
+func (r *supabaseMessageRepository) CreateGroupConversation(ctx context.Context, creatorID uuid.UUID, name string, memberIDs []uuid.UUID) (*models.Conversation, error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"is_group":   true,
+		"name":       name,
+		"created_by": creatorID.String(),
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, r.conversationsURL(nil), bytes.NewReader(payload))
+	r.setHeaders(req, "return=representation")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to create group (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var created []supabaseConversation
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	if len(created) == 0 {
+		return nil, fmt.Errorf("failed to create group")
+	}
+
+	if err := r.upsertMembers(ctx, created[0].ID, []uuid.UUID{creatorID}, models.ConversationRoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := r.upsertMembers(ctx, created[0].ID, memberIDs, models.ConversationRoleMember); err != nil {
+		return nil, err
+	}
+
+	return r.GetConversation(ctx, created[0].ID)
+}
+
+// GetConversationMembers retrieves a group's members, including those who left
+func (r *supabaseMessageRepository) GetConversationMembers(ctx context.Context, conversationID uuid.UUID) ([]*models.ConversationMember, error) {
+	return r.getMembers(ctx, "eq."+conversationID.String())
+}
+
+// AddConversationMembers adds users to a group. Users who left before rejoin as
+// members with a fresh history.
+func (r *supabaseMessageRepository) AddConversationMembers(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID) error {
+	return r.upsertMembers(ctx, conversationID, userIDs, models.ConversationRoleMember)
+}
+
+// RemoveConversationMember marks userID as having left the group
+func (r *supabaseMessageRepository) RemoveConversationMember(ctx context.Context, conversationID, userID uuid.UUID) error {
+	return r.updateMember(ctx, conversationID, userID, map[string]interface{}{
+		"left_at":      time.Now().Format(time.RFC3339),
+		"unread_count": 0,
+	})
+}
+
+// SetConversationMemberRole changes a member's role
+func (r *supabaseMessageRepository) SetConversationMemberRole(ctx context.Context, conversationID, userID uuid.UUID, role models.ConversationRole) error {
+	return r.updateMember(ctx, conversationID, userID, map[string]interface{}{"role": string(role)})
+}
+
+// RenameConversation changes a group's name
+func (r *supabaseMessageRepository) RenameConversation(ctx context.Context, conversationID uuid.UUID, name string) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"name":       name,
+		"updated_at": time.Now().Format(time.RFC3339),
+	})
+	query := url.Values{}
+	query.Set("id", "eq."+conversationID.String())
+	query.Set("is_group", "eq.true")
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, r.conversationsURL(query), bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to rename conversation, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MarkMessageDeliveredTo marks a message as delivered to one recipient
+func (r *supabaseMessageRepository) MarkMessageDeliveredTo(ctx context.Context, messageID, recipientID uuid.UUID) error {
+	rpcURL := fmt.Sprintf("%s/rest/v1/rpc/mark_message_delivered_to", r.supabaseURL)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"p_message_id":   messageID.String(),
+		"p_recipient_id": recipientID.String(),
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to mark message delivered (status %d): %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// activeGroupIDs returns the IDs of the groups userID is currently a member of
+func (r *supabaseMessageRepository) activeGroupIDs(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := url.Values{}
+	query.Set("user_id", "eq."+userID.String())
+	query.Set("left_at", "is.null")
+	query.Set("select", "conversation_id")
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.membersURL(query), nil)
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rows []struct {
+		ConversationID uuid.UUID `json:"conversation_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ConversationID.String())
+	}
+	return ids, nil
+}
+
+// getMembers loads the members matching a conversation_id filter, oldest first
+func (r *supabaseMessageRepository) getMembers(ctx context.Context, conversationFilter string) ([]*models.ConversationMember, error) {
+	query := url.Values{}
+	query.Set("conversation_id", conversationFilter)
+	query.Set("select", "*,user:user_id(*)")
+	query.Set("order", "joined_at.asc")
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.membersURL(query), nil)
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rows []supabaseConversationMember
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	members := make([]*models.ConversationMember, 0, len(rows))
+	for i := range rows {
+		member, err := rows[i].toMember()
+		if err != nil {
+			log.Printf("Failed to convert conversation member: %v", err)
+			continue
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// upsertMembers adds userIDs to a group with role, resetting the rows of anyone who left
+func (r *supabaseMessageRepository) upsertMembers(ctx context.Context, conversationID uuid.UUID, userIDs []uuid.UUID, role models.ConversationRole) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	rows := make([]map[string]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		rows = append(rows, map[string]interface{}{
+			"conversation_id": conversationID.String(),
+			"user_id":         id.String(),
+			"role":            string(role),
+			"unread_count":    0,
+			"joined_at":       now,
+			"left_at":         nil,
+			"delivered_at":    nil,
+			"read_at":         nil,
+		})
+	}
+
+	payload, _ := json.Marshal(rows)
+	query := url.Values{}
+	query.Set("on_conflict", "conversation_id,user_id")
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, r.membersURL(query), bytes.NewReader(payload))
+	r.setHeaders(req, "resolution=merge-duplicates,return=minimal")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to add conversation members (status %d): %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// updateMember patches one member row
+func (r *supabaseMessageRepository) updateMember(ctx context.Context, conversationID, userID uuid.UUID, updates map[string]interface{}) error {
+	payload, _ := json.Marshal(updates)
+	query := url.Values{}
+	query.Set("conversation_id", "eq."+conversationID.String())
+	query.Set("user_id", "eq."+userID.String())
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, r.membersURL(query), bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to update conversation member, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ============================================
+// DISAPPEARING MESSAGES
+// ============================================
+
+// SetConversationMessageTTL sets how long new messages in a conversation live; nil
+// turns disappearing messages off. Messages already sent keep their expiry.
+func (r *supabaseMessageRepository) SetConversationMessageTTL(ctx context.Context, conversationID uuid.UUID, ttlSeconds *int) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"message_ttl_seconds": ttlSeconds,
+		"updated_at":          time.Now().Format(time.RFC3339),
+	})
+	query := url.Values{}
+	query.Set("id", "eq."+conversationID.String())
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, r.conversationsURL(query), bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to set message TTL, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetExpiredMessages retrieves up to limit expired messages nobody has starred,
+// oldest expiry first. The rows carry just enough to delete the message and its
+// media; attachments a forwarded copy still uses come back empty.
+func (r *supabaseMessageRepository) GetExpiredMessages(ctx context.Context, limit int) ([]*models.Message, error) {
+	rpcURL := fmt.Sprintf("%s/rest/v1/rpc/get_expired_messages", r.supabaseURL)
+
+	payload, _ := json.Marshal(map[string]interface{}{"p_limit": limit})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get expired messages (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var rows []supabaseMessage
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*models.Message, 0, len(rows))
+	for i := range rows {
+		msg, err := rows[i].toMessage()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// DeleteExpiredMessages hard-deletes those of messageIDs that are still expired and
+// unstarred, returning how many went
+func (r *supabaseMessageRepository) DeleteExpiredMessages(ctx context.Context, messageIDs []uuid.UUID) (int, error) {
+	if len(messageIDs) == 0 {
+		return 0, nil
+	}
+	rpcURL := fmt.Sprintf("%s/rest/v1/rpc/delete_expired_messages", r.supabaseURL)
+
+	ids := make([]string, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = id.String()
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"p_message_ids": ids})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("failed to delete expired messages (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var deleted int
+	if err := json.NewDecoder(resp.Body).Decode(&deleted); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// ============================================
+// SCHEDULED MESSAGES
+// ============================================
+
+// scheduledClaimTimeout is how long a claimed scheduled message can take to send
+// before another run of the job takes it over
+const scheduledClaimTimeout = 5 * time.Minute
+
+func (r *supabaseMessageRepository) scheduledURL(query url.Values) string {
+	base := fmt.Sprintf("%s/rest/v1/scheduled_messages", r.supabaseURL)
+	if len(query) > 0 {
+		return fmt.Sprintf("%s?%s", base, query.Encode())
+	}
+	return base
+}
+
+// CreateScheduledMessage saves a message to send later, filling in its ID and timestamps
+func (r *supabaseMessageRepository) CreateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"conversation_id": scheduled.ConversationID.String(),
+		"sender_id":       scheduled.SenderID.String(),
+		"message":         scheduled.Message,
+		"send_at":         scheduled.SendAt.UTC().Format(time.RFC3339),
+		"status":          string(models.ScheduledMessagePending),
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, r.scheduledURL(nil), bytes.NewReader(payload))
+	r.setHeaders(req, "return=representation")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to schedule message (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var created []*models.ScheduledMessage
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return err
+	}
+	if len(created) > 0 {
+		*scheduled = *created[0]
+	}
+	return nil
+}
+
+// GetScheduledMessage retrieves a scheduled message by ID
+func (r *supabaseMessageRepository) GetScheduledMessage(ctx context.Context, id uuid.UUID) (*models.ScheduledMessage, error) {
+	query := url.Values{}
+	query.Set("id", "eq."+id.String())
+
+	scheduled, err := r.getScheduledMessages(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(scheduled) == 0 {
+		return nil, apperr.ErrScheduledMessageNotFound
+	}
+	return scheduled[0], nil
+}
+
+// GetPendingScheduledMessages retrieves senderID's scheduled messages in a
+// conversation that haven't been sent yet, soonest first
+func (r *supabaseMessageRepository) GetPendingScheduledMessages(ctx context.Context, conversationID, senderID uuid.UUID) ([]*models.ScheduledMessage, error) {
+	query := url.Values{}
+	query.Set("conversation_id", "eq."+conversationID.String())
+	query.Set("sender_id", "eq."+senderID.String())
+	query.Set("status", "eq."+string(models.ScheduledMessagePending))
+	query.Set("order", "send_at.asc")
+
+	return r.getScheduledMessages(ctx, query)
+}
+
+func (r *supabaseMessageRepository) getScheduledMessages(ctx context.Context, query url.Values) ([]*models.ScheduledMessage, error) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.scheduledURL(query), nil)
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get scheduled messages (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var scheduled []*models.ScheduledMessage
+	if err := json.NewDecoder(resp.Body).Decode(&scheduled); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// UpdateScheduledMessage saves the message and send time of a scheduled message.
+// Only a pending one is changed; otherwise ErrScheduledMessageNotPending is returned.
+func (r *supabaseMessageRepository) UpdateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return r.updatePendingScheduledMessage(ctx, scheduled.ID, map[string]interface{}{
+		"message":    scheduled.Message,
+		"send_at":    scheduled.SendAt.UTC().Format(time.RFC3339),
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// CancelScheduledMessage cancels a pending scheduled message; otherwise
+// ErrScheduledMessageNotPending is returned
+func (r *supabaseMessageRepository) CancelScheduledMessage(ctx context.Context, id uuid.UUID) error {
+	return r.updatePendingScheduledMessage(ctx, id, map[string]interface{}{
+		"status":     string(models.ScheduledMessageCancelled),
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// updatePendingScheduledMessage patches a scheduled message if it's still pending, so
+// an edit or cancel can't race the send job once it has claimed the message
+func (r *supabaseMessageRepository) updatePendingScheduledMessage(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	payload, _ := json.Marshal(updates)
+	query := url.Values{}
+	query.Set("id", "eq."+id.String())
+	query.Set("status", "eq."+string(models.ScheduledMessagePending))
+	query.Set("select", "id")
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, r.scheduledURL(query), bytes.NewReader(payload))
+	r.setHeaders(req, "return=representation")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to update scheduled message (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var updated []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		return err
+	}
+	if len(updated) == 0 {
+		return apperr.ErrScheduledMessageNotPending
+	}
+	return nil
+}
+
+// ClaimDueScheduledMessages claims up to limit scheduled messages whose time has
+// come, soonest first. Claimed messages can't be edited or cancelled, and other
+// instances don't claim them unless this one doesn't finish them in time.
+func (r *supabaseMessageRepository) ClaimDueScheduledMessages(ctx context.Context, limit int) ([]*models.ScheduledMessage, error) {
+	rpcURL := fmt.Sprintf("%s/rest/v1/rpc/claim_due_scheduled_messages", r.supabaseURL)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"p_limit":         limit,
+		"p_stale_seconds": int(scheduledClaimTimeout.Seconds()),
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to claim scheduled messages (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var claimed []*models.ScheduledMessage
+	if err := json.NewDecoder(resp.Body).Decode(&claimed); err != nil {
+		return nil, err
+	}
+
+	// UPDATE ... RETURNING doesn't keep the subquery's order
+	sort.Slice(claimed, func(i, j int) bool {
+		return claimed[i].SendAt.Before(claimed[j].SendAt)
+	})
+	return claimed, nil
+}
+
+// FinishScheduledMessage records the outcome of sending a claimed scheduled message:
+// sent as messageID, or failed for failureReason when messageID is nil
+func (r *supabaseMessageRepository) FinishScheduledMessage(ctx context.Context, id uuid.UUID, messageID *uuid.UUID, failureReason string) error {
+	updates := map[string]interface{}{
+		"status":     string(models.ScheduledMessageSent),
+		"message_id": messageID,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if messageID == nil {
+		updates["status"] = string(models.ScheduledMessageFailed)
+		updates["failure_reason"] = failureReason
+	}
+
+	payload, _ := json.Marshal(updates)
+	query := url.Values{}
+	query.Set("id", "eq."+id.String())
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, r.scheduledURL(query), bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to finish scheduled message, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // ============================================
 // MESSAGES
 // ============================================
@@ -727,6 +1433,9 @@ func (r *supabaseMessageRepository) CreateMessage(ctx context.Context, message *
 	if message.ReplyToID != nil {
 		payload["reply_to_id"] = message.ReplyToID.String()
 	}
+	if len(message.Waveform) > 0 {
+		payload["waveform"] = message.Waveform
+	}
 
 	// Forward feature
 	if message.ForwardedFromID != nil {
@@ -806,7 +1515,8 @@ func (r *supabaseMessageRepository) GetMessage(ctx context.Context, messageID uu
 func (r *supabaseMessageRepository) GetConversationMessages(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*models.Message, error) {
 	query := url.Values{}
 	query.Set("conversation_id", "eq."+conversationID.String())
-	query.Set("select", "*,sender:sender_id(id,username,display_name,profile_picture),reply_to:reply_to_id(id,content,message_type,sender_id,sender:sender_id(id,username,display_name,profile_picture)),reactions:message_reactions(*,user:user_id(id,username,display_name,profile_picture))")
+	// Reactions are counted separately, see GetReactionSummaries
+	query.Set("select", "*,sender:sender_id(id,username,display_name,profile_picture),reply_to:reply_to_id(id,content,message_type,sender_id,sender:sender_id(id,username,display_name,profile_picture))")
 	query.Set("order", "created_at.desc") // Descending order (newest first) - client will reverse for display
 	query.Set("limit", fmt.Sprintf("%d", limit))
 	query.Set("offset", fmt.Sprintf("%d", offset))
@@ -843,6 +1553,49 @@ func (r *supabaseMessageRepository) GetConversationMessages(ctx context.Context,
 	return messages, nil
 }
 
+// GetConversationMessagesAfter retrieves the messages posted after a given one, oldest first
+func (r *supabaseMessageRepository) GetConversationMessagesAfter(ctx context.Context, conversationID uuid.UUID, after *models.Message, limit int) ([]*models.Message, error) {
+	ts := after.CreatedAt.UTC().Format("2006-01-02T15:04:05.999999Z07:00")
+
+	query := url.Values{}
+	query.Set("conversation_id", "eq."+conversationID.String())
+	query.Set("or", fmt.Sprintf(`(created_at.gt."%s",and(created_at.eq."%s",id.gt.%s))`, ts, ts, after.ID))
+	query.Set("select", "*,sender:sender_id(id,username,display_name,profile_picture),reply_to:reply_to_id(id,content,message_type,sender_id,sender:sender_id(id,username,display_name,profile_picture))")
+	query.Set("order", "created_at.asc,id.asc")
+	query.Set("limit", fmt.Sprintf("%d", limit))
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.messagesURL(query), nil)
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get messages: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var sbMessages []*supabaseMessage
+	if err := json.NewDecoder(resp.Body).Decode(&sbMessages); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*models.Message, 0, len(sbMessages))
+	for _, sbMsg := range sbMessages {
+		msg, err := sbMsg.toMessage()
+		if err != nil {
+			log.Printf("Failed to convert message: %v", err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
 // UpdateMessageStatus updates message status
 func (r *supabaseMessageRepository) UpdateMessageStatus(ctx context.Context, messageID uuid.UUID, status models.MessageStatus) error {
 	updates := map[string]interface{}{
@@ -872,21 +1625,17 @@ func (r *supabaseMessageRepository) UpdateMessageStatus(ctx context.Context, mes
 	return nil
 }
 
-// MarkMessagesAsRead marks all unread messages in a conversation as read
+// MarkMessagesAsRead marks all unread messages in a conversation as read. In a group
+// this advances the reader's read watermark; messages only turn read once everyone has.
 func (r *supabaseMessageRepository) MarkMessagesAsRead(ctx context.Context, conversationID, readerID uuid.UUID) error {
-	updates := map[string]interface{}{
-		"status":     string(models.MessageStatusRead),
-		"read_at":    time.Now().Format(time.RFC3339),
-		"updated_at": time.Now().Format(time.RFC3339),
-	}
+	rpcURL := fmt.Sprintf("%s/rest/v1/rpc/mark_messages_read", r.supabaseURL)
 
-	payload, _ := json.Marshal(updates)
-	query := url.Values{}
-	query.Set("conversation_id", "eq."+conversationID.String())
-	query.Set("sender_id", "neq."+readerID.String())
-	query.Set("status", "neq.read")
+	payload, _ := json.Marshal(map[string]interface{}{
+		"conv_id":   conversationID.String(),
+		"reader_id": readerID.String(),
+	})
 
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, r.messagesURL(query), bytes.NewReader(payload))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(payload))
 	r.setHeaders(req, "")
 
 	resp, err := r.httpClient.Do(req)
@@ -906,12 +1655,10 @@ func (r *supabaseMessageRepository) DeleteMessage(ctx context.Context, messageID
 	return nil
 }
 
-// SearchMessages searches messages by content
-func (r *supabaseMessageRepository) SearchMessages(ctx context.Context, userID uuid.UUID, searchQuery string, limit, offset int) ([]*models.Message, error) {
-	// Note: Full-text search requires RPC function or advanced PostgREST features
-	// For Phase 1, return empty results
-	log.Printf("[MessageRepo] SearchMessages not yet implemented")
-	return []*models.Message{}, nil
+// SearchMessages full-text searches the messages userID can read, best matches first,
+// and returns one page of them with the total number of matches
+func (r *supabaseMessageRepository) SearchMessages(ctx context.Context, userID uuid.UUID, searchQuery string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error) {
+	return r.searchMessages(ctx, userID, searchQuery, filter, limit, offset)
 }
 
 // ============================================
@@ -1071,6 +1818,66 @@ func (r *supabaseMessageRepository) GetMessageReactions(ctx context.Context, mes
 	return reactions, nil
 }
 
+// GetReactionSummaries counts the reactions on a page of messages in one call, so
+// listing messages doesn't fetch every reaction row
+func (r *supabaseMessageRepository) GetReactionSummaries(ctx context.Context, messageIDs []uuid.UUID, viewerID uuid.UUID, maxEmojis int) (map[uuid.UUID]*models.ReactionSummary, error) {
+	summaries := make(map[uuid.UUID]*models.ReactionSummary)
+	if len(messageIDs) == 0 {
+		return summaries, nil
+	}
+	rpcURL := fmt.Sprintf("%s/rest/v1/rpc/get_reaction_summaries", r.supabaseURL)
+
+	ids := make([]string, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = id.String()
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"p_message_ids": ids,
+		"p_user_id":     viewerID.String(),
+		"p_max_emojis":  maxEmojis,
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(payload))
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get reaction summaries (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var rows []struct {
+		MessageID     uuid.UUID `json:"message_id"`
+		Emoji         string    `json:"emoji"`
+		ReactionCount int       `json:"reaction_count"`
+		ReactedByMe   bool      `json:"reacted_by_me"`
+		ReactorCount  int       `json:"reactor_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	// Rows come grouped by message, most used emoji first
+	for _, row := range rows {
+		summary, ok := summaries[row.MessageID]
+		if !ok {
+			summary = &models.ReactionSummary{ReactorCount: row.ReactorCount}
+			summaries[row.MessageID] = summary
+		}
+		summary.Emojis = append(summary.Emojis, &models.EmojiReactionCount{
+			Emoji:       row.Emoji,
+			Count:       row.ReactionCount,
+			ReactedByMe: row.ReactedByMe,
+		})
+	}
+	return summaries, nil
+}
+
 // ============================================
 // STARRED MESSAGES
 // ============================================
@@ -1239,7 +2046,8 @@ func (r *supabaseMessageRepository) UnpinMessage(ctx context.Context, messageID,
 	return nil
 }
 
-// GetPinnedMessages retrieves all pinned messages in a conversation
+// GetPinnedMessages retrieves all pinned messages in a conversation, most recently
+// pinned first
 func (r *supabaseMessageRepository) GetPinnedMessages(ctx context.Context, conversationID uuid.UUID) ([]*models.Message, error) {
 	query := url.Values{}
 	query.Set("conversation_id", "eq."+conversationID.String())
@@ -1385,6 +2193,7 @@ func (r *supabaseMessageRepository) ForwardMessage(ctx context.Context, messageI
 		AttachmentName:  originalMsg.AttachmentName,
 		AttachmentSize:  originalMsg.AttachmentSize,
 		AttachmentType:  originalMsg.AttachmentType,
+		Waveform:        originalMsg.Waveform,
 		ForwardedFromID: &messageID,
 		IsForwarded:     true,
 		Status:          models.MessageStatusSent,
@@ -1403,42 +2212,91 @@ func (r *supabaseMessageRepository) ForwardMessage(ctx context.Context, messageI
 // SEARCH MESSAGES IN CONVERSATION
 // ============================================
 
-// SearchConversationMessages searches messages within a specific conversation
-func (r *supabaseMessageRepository) SearchConversationMessages(ctx context.Context, conversationID uuid.UUID, searchQuery string, limit, offset int) ([]*models.Message, error) {
-	query := url.Values{}
-	query.Set("conversation_id", "eq."+conversationID.String())
-	query.Set("content", "ilike.*"+searchQuery+"*")
-	query.Set("select", "*,sender:sender_id(id,username,display_name,profile_picture),reply_to:reply_to_id(id,content,message_type,sender_id,sender:sender_id(id,username,display_name,profile_picture)),reactions:message_reactions(*,user:user_id(id,username,display_name,profile_picture))")
-	query.Set("order", "created_at.desc")
-	query.Set("limit", fmt.Sprintf("%d", limit))
-	query.Set("offset", fmt.Sprintf("%d", offset))
+// SearchConversationMessages full-text searches one conversation as userID sees it,
+// best matches first, and returns one page of them with the total number of matches
+func (r *supabaseMessageRepository) SearchConversationMessages(ctx context.Context, conversationID, userID uuid.UUID, searchQuery string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error) {
+	filter.ConversationID = &conversationID
+	return r.searchMessages(ctx, userID, searchQuery, filter, limit, offset)
+}
 
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.messagesURL(query), nil)
+// supabaseSearchHit is a row of search_messages
+type supabaseSearchHit struct {
+	supabaseMessage
+	Rank          float64          `json:"rank"`
+	Snippet       string           `json:"snippet"`
+	TotalCount    int              `json:"total_count"`
+	BeforeMessage *supabaseMessage `json:"before_message"`
+	AfterMessage  *supabaseMessage `json:"after_message"`
+}
+
+// searchMessages runs search_messages, which matches, ranks, highlights and pages in
+// the database. Every row carries the total, so an empty page means no matches.
+func (r *supabaseMessageRepository) searchMessages(ctx context.Context, userID uuid.UUID, searchQuery string, filter models.MessageSearchFilter, limit, offset int) ([]*models.MessageSearchHit, int, error) {
+	rpcURL := fmt.Sprintf("%s/rest/v1/rpc/search_messages", r.supabaseURL)
+
+	params := map[string]interface{}{
+		"p_user_id": userID.String(),
+		"p_query":   searchQuery,
+		"p_limit":   limit,
+		"p_offset":  offset,
+	}
+	if filter.ConversationID != nil {
+		params["p_conversation_id"] = filter.ConversationID.String()
+	}
+	if filter.SenderID != nil {
+		params["p_sender_id"] = filter.SenderID.String()
+	}
+	if filter.From != nil {
+		params["p_from"] = filter.From.Format(time.RFC3339)
+	}
+	if filter.To != nil {
+		params["p_to"] = filter.To.Format(time.RFC3339)
+	}
+	payload, _ := json.Marshal(params)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(payload))
 	r.setHeaders(req, "")
 
 	resp, err := r.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
-	var sbMessages []*supabaseMessage
-	if err := json.NewDecoder(resp.Body).Decode(&sbMessages); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, 0, fmt.Errorf("failed to search messages, status: %d, body: %s", resp.StatusCode, string(body))
 	}
 
-	messages := make([]*models.Message, 0, len(sbMessages))
-	for _, sbMsg := range sbMessages {
-		msg, err := sbMsg.toMessage()
+	var rows []supabaseSearchHit
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, 0, err
+	}
+
+	total := 0
+	hits := make([]*models.MessageSearchHit, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		total = row.TotalCount
+
+		msg, err := row.supabaseMessage.toMessage()
 		if err != nil {
 			log.Printf("Failed to convert message: %v", err)
 			continue
 		}
-		messages = append(messages, msg)
+		hit := &models.MessageSearchHit{Message: msg, Rank: row.Rank, Snippet: row.Snippet}
+
+		// The neighbours only give context; one that doesn't parse is just left out
+		if hit.Before, err = row.BeforeMessage.toMessage(); err != nil {
+			log.Printf("Failed to convert message before %s: %v", msg.ID, err)
+		}
+		if hit.After, err = row.AfterMessage.toMessage(); err != nil {
+			log.Printf("Failed to convert message after %s: %v", msg.ID, err)
+		}
+		hits = append(hits, hit)
 	}
 
-	log.Printf("[MessageRepo] Found %d messages matching '%s' in conversation %s", len(messages), searchQuery, conversationID)
-	return messages, nil
+	return hits, total, nil
 }
 
 // ============================================
@@ -1486,7 +2344,7 @@ func (r *supabaseMessageRepository) StoreConversationKey(ctx context.Context, co
 	}
 
 	r.setHeaders(req, "return=representation")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := r.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to check existing key: %w", err)
 	}
@@ -1515,7 +2373,7 @@ func (r *supabaseMessageRepository) StoreConversationKey(ctx context.Context, co
 			}
 
 			r.setHeaders(updateReq, "return=representation")
-			updateResp, err := http.DefaultClient.Do(updateReq)
+			updateResp, err := r.httpClient.Do(updateReq)
 			if err != nil {
 				return fmt.Errorf("failed to update key: %w", err)
 			}
@@ -1550,7 +2408,7 @@ func (r *supabaseMessageRepository) StoreConversationKey(ctx context.Context, co
 	}
 
 	r.setHeaders(createReq, "return=representation")
-	createResp, err := http.DefaultClient.Do(createReq)
+	createResp, err := r.httpClient.Do(createReq)
 	if err != nil {
 		return fmt.Errorf("failed to store key: %w", err)
 	}
@@ -1582,7 +2440,7 @@ func (r *supabaseMessageRepository) GetConversationKey(ctx context.Context, conv
 	}
 
 	r.setHeaders(req, "")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := r.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to get key: %w", err)
 	}
@@ -1628,7 +2486,7 @@ func (r *supabaseMessageRepository) RevokeConversationKey(ctx context.Context, c
 	}
 
 	r.setHeaders(req, "return=representation")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := r.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to revoke key: %w", err)
 	}
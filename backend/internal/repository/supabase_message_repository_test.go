@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// messageHistoryDB fakes the search_messages RPC over a large message history. Like
+// the real function it filters to the caller's conversations, pages with p_limit and
+// p_offset and puts the total on every row; it records how many rows it sent back.
+type messageHistoryDB struct {
+	t        *testing.T
+	messages []map[string]interface{} // Newest first
+
+	queries    int
+	rowsServed int
+}
+
+func newMessageHistoryDB(t *testing.T, userID uuid.UUID, conversations []uuid.UUID, perConversation int) *messageHistoryDB {
+	db := &messageHistoryDB{t: t}
+	latest := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := perConversation - 1; i >= 0; i-- {
+		for _, conversationID := range conversations {
+			content := fmt.Sprintf("message %d", i)
+			if i%10 == 0 {
+				content = fmt.Sprintf("Deploy note %d", i)
+			}
+			db.messages = append(db.messages, map[string]interface{}{
+				"id":              uuid.NewString(),
+				"conversation_id": conversationID.String(),
+				"sender_id":       userID.String(),
+				"content":         content,
+				"message_type":    "text",
+				"created_at":      latest.Add(-time.Duration(len(db.messages)) * time.Minute).Format(time.RFC3339),
+			})
+		}
+	}
+	return db
+}
+
+func (db *messageHistoryDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/rest/v1/rpc/search_messages" {
+		db.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	db.queries++
+
+	var params struct {
+		Query          string  `json:"p_query"`
+		ConversationID *string `json:"p_conversation_id"`
+		Limit          *int    `json:"p_limit"`
+		Offset         int     `json:"p_offset"`
+	}
+	json.NewDecoder(r.Body).Decode(&params)
+	if params.Limit == nil {
+		db.t.Error("search without a limit")
+		params.Limit = new(int)
+	}
+
+	var matches []map[string]interface{}
+	for _, m := range db.messages {
+		if params.ConversationID != nil && m["conversation_id"] != *params.ConversationID {
+			continue
+		}
+		if strings.Contains(strings.ToLower(m["content"].(string)), strings.ToLower(params.Query)) {
+			matches = append(matches, m)
+		}
+	}
+	page := matches[min(params.Offset, len(matches)):min(params.Offset+*params.Limit, len(matches))]
+	db.rowsServed += len(page)
+
+	rows := make([]map[string]interface{}, len(page))
+	for i, m := range page {
+		row := map[string]interface{}{"rank": 1.0, "snippet": m["content"], "total_count": len(matches)}
+		for k, v := range m {
+			row[k] = v
+		}
+		rows[i] = row
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+func TestSearchConversationMessagesFetchesOnlyThePage(t *testing.T) {
+	user, conversation, other := uuid.New(), uuid.New(), uuid.New()
+	db := newMessageHistoryDB(t, user, []uuid.UUID{conversation, other}, 5000)
+	server := httptest.NewServer(db)
+	defer server.Close()
+	repo, _ := NewSupabaseMessageRepository(server.URL, "key")
+
+	hits, total, err := repo.SearchConversationMessages(context.Background(), conversation, user, "deploy", models.MessageSearchFilter{}, 20, 40)
+	if err != nil {
+		t.Fatalf("SearchConversationMessages: %v", err)
+	}
+	if len(hits) != 20 || total != 500 {
+		t.Errorf("got %d hits of %d, want a page of 20 of 500", len(hits), total)
+	}
+	if db.queries != 1 || db.rowsServed != 20 {
+		t.Errorf("made %d queries returning %d rows, want one query for the page alone", db.queries, db.rowsServed)
+	}
+	for _, hit := range hits {
+		if hit.ConversationID != conversation {
+			t.Fatalf("hit from conversation %s, want only %s", hit.ConversationID, conversation)
+		}
+	}
+	if hits[0].Content != "Deploy note 4590" {
+		t.Errorf("page starts at %q, want the 41st newest match", hits[0].Content)
+	}
+}
+
+func TestSearchMessagesAcrossConversationsFetchesOnlyThePage(t *testing.T) {
+	user := uuid.New()
+	db := newMessageHistoryDB(t, user, []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}, 2000)
+	server := httptest.NewServer(db)
+	defer server.Close()
+	repo, _ := NewSupabaseMessageRepository(server.URL, "key")
+
+	hits, total, err := repo.SearchMessages(context.Background(), user, "deploy", models.MessageSearchFilter{}, 25, 0)
+	if err != nil {
+		t.Fatalf("SearchMessages: %v", err)
+	}
+	if len(hits) != 25 || total != 600 {
+		t.Errorf("got %d hits of %d, want a page of 25 of 600", len(hits), total)
+	}
+	if db.queries != 1 || db.rowsServed != 25 {
+		t.Errorf("made %d queries returning %d rows, want one for the page alone", db.queries, db.rowsServed)
+	}
+
+	// Past the last match nothing comes back, so there's no row to carry the total
+	hits, total, _ = repo.SearchMessages(context.Background(), user, "deploy", models.MessageSearchFilter{}, 25, 600)
+	if len(hits) != 0 || total != 0 {
+		t.Errorf("past the end: got %d hits of %d, want none", len(hits), total)
+	}
+}
+
+func TestGetReactionSummariesWithMixedEmojis(t *testing.T) {
+	viewer := uuid.New()
+	busy, quiet, mine := uuid.New(), uuid.New(), uuid.New()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/v1/rpc/get_reaction_summaries" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var params struct {
+			MessageIDs []uuid.UUID `json:"p_message_ids"`
+			UserID     uuid.UUID   `json:"p_user_id"`
+			MaxEmojis  int         `json:"p_max_emojis"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("decoding params: %v", err)
+		}
+		if fmt.Sprint(params.MessageIDs) != fmt.Sprint([]uuid.UUID{busy, quiet, mine}) || params.UserID != viewer || params.MaxEmojis != 2 {
+			t.Errorf("params = %+v, want the page's messages, the viewer and the cap", params)
+		}
+
+		// Ranked and capped by the function (migration 49), grouped by message
+		w.Write([]byte(`[
+			{"message_id": "` + busy.String() + `", "emoji": "👍", "reaction_count": 2, "reacted_by_me": false, "reactor_count": 6},
+			{"message_id": "` + busy.String() + `", "emoji": "❤️", "reaction_count": 2, "reacted_by_me": true, "reactor_count": 6},
+			{"message_id": "` + mine.String() + `", "emoji": "🎉", "reaction_count": 1, "reacted_by_me": true, "reactor_count": 1}
+		]`))
+	}))
+	defer server.Close()
+	repo, _ := NewSupabaseMessageRepository(server.URL, "key")
+
+	summaries, err := repo.GetReactionSummaries(context.Background(), []uuid.UUID{busy, quiet, mine}, viewer, 2)
+	if err != nil {
+		t.Fatalf("GetReactionSummaries: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1", calls)
+	}
+
+	got := summaries[busy]
+	if got == nil || got.ReactorCount != 6 || len(got.Emojis) != 2 {
+		t.Fatalf("busy message = %+v, want 6 reactors over 2 emojis", got)
+	}
+	want := []models.EmojiReactionCount{
+		{Emoji: "👍", Count: 2},
+		{Emoji: "❤️", Count: 2, ReactedByMe: true},
+	}
+	for i, emoji := range got.Emojis {
+		if *emoji != want[i] {
+			t.Errorf("busy emoji %d = %+v, want %+v in the order sent", i, *emoji, want[i])
+		}
+	}
+
+	if _, ok := summaries[quiet]; ok {
+		t.Error("a message with no rows should be left out")
+	}
+	if got := summaries[mine]; got == nil || got.ReactorCount != 1 || len(got.Emojis) != 1 || !got.Emojis[0].ReactedByMe {
+		t.Errorf("viewer's message = %+v, want the viewer's one reaction", got)
+	}
+}
+
+func TestGetReactionSummariesOfNoMessages(t *testing.T) {
+	repo, _ := NewSupabaseMessageRepository("http://unused.invalid", "key")
+	summaries, err := repo.GetReactionSummaries(context.Background(), nil, uuid.New(), 6)
+	if err != nil || len(summaries) != 0 {
+		t.Errorf("GetReactionSummaries(nil) = %v, %v; want an empty map without a request", summaries, err)
+	}
+}
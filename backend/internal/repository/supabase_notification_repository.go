@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"histeeria-backend/internal/models"
@@ -28,7 +29,7 @@ func NewSupabaseNotificationRepository(supabaseURL, apiKey string) *SupabaseNoti
 	return &SupabaseNotificationRepository{
 		supabaseURL: supabaseURL,
 		apiKey:      apiKey,
-		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		httpClient:  newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -176,22 +177,61 @@ func (r *SupabaseNotificationRepository) GetByID(ctx context.Context, id uuid.UU
 	return r.parseNotification(notifications[0])
 }
 
-// GetByUser retrieves notifications for a user with filters
-func (r *SupabaseNotificationRepository) GetByUser(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory, unread *bool, limit int, offset int) ([]*models.Notification, int, error) {
+// GetByUser retrieves notifications for a user with filters. Newest come first, or
+// unread then newest with UnreadFirst; ties break on id so cursors are exact. With a
+// cursor the returned total counts only the notifications after it. With After the
+// notifications newer than it come instead, oldest first.
+func (r *SupabaseNotificationRepository) GetByUser(ctx context.Context, userID uuid.UUID, filter models.NotificationFilter) ([]*models.Notification, int, error) {
+	unreadOnly := filter.Unread != nil && *filter.Unread
+	unreadFirst := filter.UnreadFirst && !unreadOnly && filter.After == nil
+
 	q := url.Values{}
 	q.Set("user_id", "eq."+userID.String())
-	q.Set("order", "created_at.desc")
-	q.Set("limit", fmt.Sprintf("%d", limit))
-	q.Set("offset", fmt.Sprintf("%d", offset))
+	switch {
+	case filter.After != nil:
+		q.Set("order", "created_at.asc,id.asc")
+	case unreadFirst:
+		q.Set("order", "is_read.asc,created_at.desc,id.desc")
+	default:
+		q.Set("order", "created_at.desc,id.desc")
+	}
+	q.Set("limit", fmt.Sprintf("%d", filter.Limit))
 	q.Set("select", "*,actor:users!actor_id(id,username,display_name,profile_picture,is_verified)")
 
-	if category != nil {
-		q.Set("category", "eq."+string(*category))
+	if filter.Category != nil {
+		q.Set("category", "eq."+string(*filter.Category))
+	}
+	if len(filter.Types) > 0 {
+		types := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = string(t)
+		}
+		q.Set("type", "in.("+strings.Join(types, ",")+")")
 	}
-	if unread != nil && *unread {
+	if unreadOnly {
 		q.Set("is_read", "eq.false")
 	}
 
+	if a := filter.After; a != nil {
+		ts := a.CreatedAt.UTC().Format("2006-01-02T15:04:05.999999Z07:00")
+		q.Set("or", fmt.Sprintf(`(created_at.gt."%s",and(created_at.eq."%s",id.gt.%s))`, ts, ts, a.ID))
+	} else if c := filter.Cursor; c != nil {
+		ts := c.CreatedAt.UTC().Format("2006-01-02T15:04:05.999999Z07:00")
+		after := fmt.Sprintf(`created_at.lt."%s",and(created_at.eq."%s",id.lt.%s)`, ts, ts, c.ID)
+		switch {
+		case unreadFirst && !c.IsRead:
+			// Still among the unread: the rest of them, then every read one
+			q.Set("or", fmt.Sprintf("(is_read.eq.true,and(is_read.eq.false,or(%s)))", after))
+		case unreadFirst:
+			q.Set("is_read", "eq.true")
+			q.Set("or", "("+after+")")
+		default:
+			q.Set("or", "("+after+")")
+		}
+	} else {
+		q.Set("offset", fmt.Sprintf("%d", filter.Offset))
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.notificationsURL(q), nil)
 	if err != nil {
 		return nil, 0, err
@@ -278,6 +318,42 @@ func (r *SupabaseNotificationRepository) GetUnreadCount(ctx context.Context, use
 	return 0, nil
 }
 
+// GetUnseenCount gets the badge count: unread notifications not yet seen
+func (r *SupabaseNotificationRepository) GetUnseenCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("is_read", "eq.false")
+	q.Set("seen_at", "is.null")
+	q.Set("select", "id")
+	q.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.notificationsURL(q), nil)
+	if err != nil {
+		return 0, err
+	}
+	r.setHeaders(req, "count=exact")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("failed to count unseen notifications: %d", resp.StatusCode)
+	}
+
+	var start, end, total int
+	contentRange := resp.Header.Get("Content-Range")
+	if n, _ := fmt.Sscanf(contentRange, "*/%d", &total); n == 1 {
+		return total, nil
+	}
+	if n, _ := fmt.Sscanf(contentRange, "%d-%d/%d", &start, &end, &total); n == 3 {
+		return total, nil
+	}
+	return 0, nil
+}
+
 // GetCategoryCounts gets unread counts per category
 func (r *SupabaseNotificationRepository) GetCategoryCounts(ctx context.Context, userID uuid.UUID) (map[string]int, error) {
 	counts := make(map[string]int)
@@ -340,20 +416,23 @@ func (r *SupabaseNotificationRepository) MarkAsRead(ctx context.Context, id uuid
 	return nil
 }
 
-// MarkAllAsRead marks all notifications as read for a user
-func (r *SupabaseNotificationRepository) MarkAllAsRead(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory) error {
+// MarkAllAsRead marks every unread notification of a user (optionally in one category)
+// as read in a single update and returns how many it changed. Already-read notifications
+// don't match, so repeating it changes nothing.
+func (r *SupabaseNotificationRepository) MarkAllAsRead(ctx context.Context, userID uuid.UUID, category *models.NotificationCategory) (int, error) {
 	update := map[string]interface{}{
 		"is_read": true,
 	}
 
 	body, err := json.Marshal(update)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	q := url.Values{}
 	q.Set("user_id", "eq."+userID.String())
 	q.Set("is_read", "eq.false")
+	q.Set("select", "id")
 
 	if category != nil {
 		q.Set("category", "eq."+string(*category))
@@ -361,28 +440,76 @@ func (r *SupabaseNotificationRepository) MarkAllAsRead(ctx context.Context, user
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, r.notificationsURL(q), bytes.NewReader(body))
 	if err != nil {
-		return err
+		return 0, err
 	}
-	r.setHeaders(req, "return=minimal")
+	r.setHeaders(req, "return=representation")
 
 	resp, err := r.httpClient.Do(req)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		log.Printf("[NotificationRepo] MarkAllAsRead failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
-		return fmt.Errorf("failed to mark all as read: %d", resp.StatusCode)
+		return 0, fmt.Errorf("failed to mark all as read: %d", resp.StatusCode)
+	}
+
+	var updated []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		return 0, fmt.Errorf("failed to decode updated notifications: %w", err)
 	}
 
 	categoryStr := "all"
 	if category != nil {
 		categoryStr = string(*category)
 	}
-	log.Printf("[NotificationRepo] Marked all %s notifications as read for user %s", categoryStr, userID)
-	return nil
+	log.Printf("[NotificationRepo] Marked %d %s notifications as read for user %s", len(updated), categoryStr, userID)
+	return len(updated), nil
+}
+
+// MarkAllSeen marks every unseen, unread notification of a user as seen without
+// reading it, and returns how many it changed. Repeating it changes nothing.
+func (r *SupabaseNotificationRepository) MarkAllSeen(ctx context.Context, userID uuid.UUID) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{"seen_at": time.Now().UTC()})
+	if err != nil {
+		return 0, err
+	}
+
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("is_read", "eq.false")
+	q.Set("seen_at", "is.null")
+	q.Set("select", "id")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, r.notificationsURL(q), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	r.setHeaders(req, "return=representation")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[NotificationRepo] MarkAllSeen failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+		return 0, fmt.Errorf("failed to mark notifications seen: %d", resp.StatusCode)
+	}
+
+	var updated []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		return 0, fmt.Errorf("failed to decode seen notifications: %w", err)
+	}
+	return len(updated), nil
 }
 
 // Delete deletes a notification
@@ -490,27 +617,25 @@ func (r *SupabaseNotificationRepository) GetPreferences(ctx context.Context, use
 
 // UpsertPreferences creates or updates notification preferences
 func (r *SupabaseNotificationRepository) UpsertPreferences(ctx context.Context, prefs *models.NotificationPreferences) error {
+	// The toggles are always written: on a merge, a column left out keeps its old
+	// value, so leaving out false ones would make switching anything off impossible
 	payload := map[string]interface{}{
-		"user_id": prefs.UserID.String(),
+		"user_id":                prefs.UserID.String(),
+		"email_enabled":          prefs.EmailEnabled,
+		"in_app_enabled":         prefs.InAppEnabled,
+		"push_enabled":           prefs.PushEnabled,
+		"inline_actions_enabled": prefs.InlineActionsEnabled,
+		"muted_until":            prefs.MutedUntil,
 	}
 
-	if prefs.EmailEnabled {
-		payload["email_enabled"] = prefs.EmailEnabled
-	}
 	if prefs.EmailTypes != nil {
 		payload["email_types"] = prefs.EmailTypes
 	}
 	if prefs.EmailFrequency != "" {
 		payload["email_frequency"] = string(prefs.EmailFrequency)
 	}
-	if prefs.InAppEnabled {
-		payload["in_app_enabled"] = prefs.InAppEnabled
-	}
-	if prefs.PushEnabled {
-		payload["push_enabled"] = prefs.PushEnabled
-	}
-	if prefs.InlineActionsEnabled {
-		payload["inline_actions_enabled"] = prefs.InlineActionsEnabled
+	if prefs.PushTypes != nil {
+		payload["push_types"] = prefs.PushTypes
 	}
 	if prefs.CategoriesEnabled != nil {
 		payload["categories_enabled"] = prefs.CategoriesEnabled
@@ -577,11 +702,164 @@ func (r *SupabaseNotificationRepository) DeleteExpired(ctx context.Context) (int
 	return deletedCount, nil
 }
 
-// GetUnreadForDigest retrieves unread notifications for users based on email frequency
+// Limits on one digest run: users are picked up in batches across runs, and a digest
+// lists a user's most recent notifications only
+const (
+	digestUserBatch = 500
+	digestMaxItems  = 50
+)
+
+// GetUnreadForDigest returns, for each user on frequency whose last digest is at least a
+// period old, the unread notifications of the last period that no digest has included
+// yet. Users with nothing to send are left out.
 func (r *SupabaseNotificationRepository) GetUnreadForDigest(ctx context.Context, frequency models.EmailFrequency) (map[uuid.UUID][]*models.Notification, error) {
-	// This would need a more complex query joining with preferences
-	// For now, return empty map - will implement in digest service
-	return make(map[uuid.UUID][]*models.Notification), nil
+	period := frequency.DigestPeriod()
+	if period == 0 {
+		return nil, fmt.Errorf("%s is not a digest frequency", frequency)
+	}
+	cutoff := time.Now().Add(-period).UTC().Format(time.RFC3339)
+
+	q := url.Values{}
+	q.Set("email_frequency", "eq."+string(frequency))
+	q.Set("email_enabled", "is.true")
+	q.Set("or", fmt.Sprintf("(last_digest_at.is.null,last_digest_at.lte.%s)", cutoff))
+	q.Set("select", "user_id")
+	q.Set("limit", fmt.Sprintf("%d", digestUserBatch))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.preferencesURL(q), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[NotificationRepo] GetUnreadForDigest failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("failed to fetch digest users: %d", resp.StatusCode)
+	}
+
+	var due []struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&due); err != nil {
+		return nil, err
+	}
+
+	digests := make(map[uuid.UUID][]*models.Notification)
+	for _, d := range due {
+		notifications, err := r.getUndigested(ctx, d.UserID, cutoff)
+		if err != nil {
+			log.Printf("[NotificationRepo] Failed to fetch digest for user %s: %v", d.UserID, err)
+			continue
+		}
+		if len(notifications) > 0 {
+			digests[d.UserID] = notifications
+		}
+	}
+	return digests, nil
+}
+
+// getUndigested returns a user's unread notifications since cutoff that no digest has
+// included yet, newest first
+func (r *SupabaseNotificationRepository) getUndigested(ctx context.Context, userID uuid.UUID, cutoff string) ([]*models.Notification, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("is_read", "eq.false")
+	q.Set("digest_sent_at", "is.null")
+	q.Set("created_at", "gte."+cutoff)
+	q.Set("order", "created_at.desc")
+	q.Set("limit", fmt.Sprintf("%d", digestMaxItems))
+	q.Set("select", "*,actor:users!actor_id(id,username,display_name,profile_picture,is_verified)")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.notificationsURL(q), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.setHeaders(req, "")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch notifications: %d", resp.StatusCode)
+	}
+
+	var rawNotifications []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rawNotifications); err != nil {
+		return nil, err
+	}
+
+	notifications := make([]*models.Notification, 0, len(rawNotifications))
+	for _, raw := range rawNotifications {
+		notification, err := r.parseNotification(raw)
+		if err != nil {
+			log.Printf("[NotificationRepo] Failed to parse notification: %v", err)
+			continue
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// MarkDigestSent records that a digest went out to userID with the given notifications,
+// so they aren't sent again and the next digest waits a full period
+func (r *SupabaseNotificationRepository) MarkDigestSent(ctx context.Context, userID uuid.UUID, notificationIDs []uuid.UUID) error {
+	now := time.Now().UTC()
+
+	if len(notificationIDs) > 0 {
+		ids := make([]string, len(notificationIDs))
+		for i, id := range notificationIDs {
+			ids[i] = id.String()
+		}
+		q := url.Values{}
+		q.Set("user_id", "eq."+userID.String())
+		q.Set("id", "in.("+strings.Join(ids, ",")+")")
+		if err := r.patch(ctx, r.notificationsURL(q), map[string]interface{}{"digest_sent_at": now}); err != nil {
+			return fmt.Errorf("failed to mark notifications digested: %w", err)
+		}
+	}
+
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	if err := r.patch(ctx, r.preferencesURL(q), map[string]interface{}{"last_digest_at": now}); err != nil {
+		return fmt.Errorf("failed to record digest time: %w", err)
+	}
+	return nil
+}
+
+// patch applies update to the rows matched by target
+func (r *SupabaseNotificationRepository) patch(ctx context.Context, target string, update map[string]interface{}) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.setHeaders(req, "return=minimal")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
 }
 
 // =====================================================
@@ -667,6 +945,11 @@ func (r *SupabaseNotificationRepository) parseNotification(raw map[string]interf
 	if expiresAt, ok := raw["expires_at"].(string); ok {
 		notification.ExpiresAt, _ = time.Parse(time.RFC3339, expiresAt)
 	}
+	if seenAt, ok := raw["seen_at"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, seenAt); err == nil {
+			notification.SeenAt = &t
+		}
+	}
 
 	// Parse actor (joined user data)
 	if actor, ok := raw["actor"].(map[string]interface{}); ok && actor != nil {
@@ -695,6 +978,7 @@ func (r *SupabaseNotificationRepository) parseNotification(raw map[string]interf
 func (r *SupabaseNotificationRepository) parsePreferences(raw map[string]interface{}) (*models.NotificationPreferences, error) {
 	prefs := &models.NotificationPreferences{
 		EmailTypes:        make(map[string]bool),
+		PushTypes:         make(map[string]bool),
 		CategoriesEnabled: make(map[string]bool),
 	}
 
@@ -730,6 +1014,13 @@ func (r *SupabaseNotificationRepository) parsePreferences(raw map[string]interfa
 			}
 		}
 	}
+	if pushTypes, ok := raw["push_types"].(map[string]interface{}); ok {
+		for k, v := range pushTypes {
+			if boolVal, ok := v.(bool); ok {
+				prefs.PushTypes[k] = boolVal
+			}
+		}
+	}
 	if categoriesEnabled, ok := raw["categories_enabled"].(map[string]interface{}); ok {
 		for k, v := range categoriesEnabled {
 			if boolVal, ok := v.(bool); ok {
@@ -738,7 +1029,12 @@ func (r *SupabaseNotificationRepository) parsePreferences(raw map[string]interfa
 		}
 	}
 
-	// Parse timestamp
+	// Parse timestamps
+	if mutedUntil, ok := raw["muted_until"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, mutedUntil); err == nil {
+			prefs.MutedUntil = &t
+		}
+	}
 	if updatedAt, ok := raw["updated_at"].(string); ok {
 		prefs.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 	}
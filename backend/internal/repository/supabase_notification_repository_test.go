@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// notificationTable fakes PostgREST over one user's notifications, honouring the
+// is_read and seen_at filters, exact counts and PATCHes that set seen_at
+type notificationTable struct {
+	t    *testing.T
+	rows []map[string]interface{}
+}
+
+func (db *notificationTable) add(read bool) {
+	db.rows = append(db.rows, map[string]interface{}{"id": uuid.NewString(), "is_read": read, "seen_at": nil})
+}
+
+func (db *notificationTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/rest/v1/notifications" {
+		db.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	var matches []map[string]interface{}
+	for _, row := range db.rows {
+		if q.Get("is_read") == "eq.false" && row["is_read"] == true {
+			continue
+		}
+		if q.Get("seen_at") == "is.null" && row["seen_at"] != nil {
+			continue
+		}
+		matches = append(matches, row)
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["is_read"]; ok {
+			db.t.Error("marking seen shouldn't touch is_read")
+		}
+		for _, row := range matches {
+			row["seen_at"] = body["seen_at"]
+		}
+		json.NewEncoder(w).Encode(matches)
+	case http.MethodGet:
+		if strings.Contains(r.Header.Get("Prefer"), "count=exact") {
+			w.Header().Set("Content-Range", fmt.Sprintf("*/%d", len(matches)))
+			if len(matches) > 0 {
+				w.Header().Set("Content-Range", fmt.Sprintf("0-%d/%d", len(matches)-1, len(matches)))
+			}
+		}
+		json.NewEncoder(w).Encode(matches)
+	}
+}
+
+func TestMarkAllSeenZeroesTheBadgeButLeavesItemsUnread(t *testing.T) {
+	db := &notificationTable{t: t}
+	for _, read := range []bool{false, false, false, true} {
+		db.add(read)
+	}
+	server := httptest.NewServer(db)
+	defer server.Close()
+	repo := NewSupabaseNotificationRepository(server.URL, "key")
+	ctx := context.Background()
+	user := uuid.New()
+
+	counts := func() (unseen, unread int) {
+		t.Helper()
+		unseen, err := repo.GetUnseenCount(ctx, user)
+		if err != nil {
+			t.Fatalf("GetUnseenCount: %v", err)
+		}
+		unread, err = repo.GetUnreadCount(ctx, user, nil)
+		if err != nil {
+			t.Fatalf("GetUnreadCount: %v", err)
+		}
+		return unseen, unread
+	}
+
+	if unseen, unread := counts(); unseen != 3 || unread != 3 {
+		t.Fatalf("before: badge %d, unread %d; want 3 and 3", unseen, unread)
+	}
+
+	marked, err := repo.MarkAllSeen(ctx, user)
+	if err != nil || marked != 3 {
+		t.Fatalf("MarkAllSeen = %d, %v; want the 3 unread", marked, err)
+	}
+	if unseen, unread := counts(); unseen != 0 || unread != 3 {
+		t.Errorf("after: badge %d, unread %d; want 0 and still 3", unseen, unread)
+	}
+
+	// Only what arrives afterwards counts on the badge again
+	db.add(false)
+	if unseen, unread := counts(); unseen != 1 || unread != 4 {
+		t.Errorf("new arrival: badge %d, unread %d; want 1 and 4", unseen, unread)
+	}
+	if marked, _ := repo.MarkAllSeen(ctx, user); marked != 1 {
+		t.Errorf("second MarkAllSeen marked %d, want only the new one", marked)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
 	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
 
 	"github.com/google/uuid"
 )
@@ -345,48 +347,62 @@ func (r *SupabasePollRepository) ClosePoll(ctx context.Context, pollID uuid.UUID
 	return err
 }
 
-// VotePoll records a vote for a poll option
-func (r *SupabasePollRepository) VotePoll(ctx context.Context, pollID, optionID, userID uuid.UUID) error {
-	// Check if poll allows voting
-	poll, err := r.GetPoll(ctx, pollID)
+// CloseExpiredPolls closes every open poll whose end time has passed and returns the
+// polls it closed (ID and post ID only). The is_closed filter makes the update a no-op
+// for polls another instance already closed, so each poll is returned exactly once.
+func (r *SupabasePollRepository) CloseExpiredPolls(ctx context.Context, now time.Time) ([]models.Poll, error) {
+	closedAt := now.UTC()
+	updates := map[string]interface{}{
+		"is_closed": true,
+		"closed_at": closedAt,
+	}
+
+	query := fmt.Sprintf("?is_closed=eq.false&ends_at=lte.%s&select=id,post_id", url.QueryEscape(closedAt.Format(time.RFC3339)))
+	data, err := r.makeRequest("PATCH", "polls", query, updates)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to close expired polls: %w", err)
 	}
 
-	if poll.IsClosed || time.Now().After(poll.EndsAt) {
-		return fmt.Errorf("poll is closed")
+	var rows []struct {
+		ID     uuid.UUID `json:"id"`
+		PostID uuid.UUID `json:"post_id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse closed polls: %w", err)
 	}
 
-	// Check if user already voted
-	hasVoted, _ := r.HasUserVoted(ctx, pollID, userID)
-	if hasVoted {
-		if !poll.AllowVoteChanges {
-			return fmt.Errorf("vote changes not allowed")
-		}
-		// User has voted and changes are allowed - update the vote
-		return r.ChangeVote(ctx, pollID, optionID, userID)
+	polls := make([]models.Poll, 0, len(rows))
+	for _, row := range rows {
+		polls = append(polls, models.Poll{
+			ID:       row.ID,
+			PostID:   row.PostID,
+			IsClosed: true,
+			ClosedAt: &closedAt,
+		})
 	}
+	return polls, nil
+}
 
+// VotePoll records a vote for a poll option. Vote rules (closed polls, multiple votes,
+// vote changes) are checked by the posts service before calling this; the database
+// trigger check_poll_vote_rules rejects anything that slips through concurrently.
+func (r *SupabasePollRepository) VotePoll(ctx context.Context, pollID, optionID, userID uuid.UUID) error {
 	payload := map[string]interface{}{
 		"poll_id":   pollID,
 		"option_id": optionID,
 		"user_id":   userID,
 	}
 
-	fmt.Printf("[DEBUG] Creating vote: poll_id=%s, option_id=%s, user_id=%s\n", pollID.String(), optionID.String(), userID.String())
-	
-	_, err = r.makeRequest("POST", "poll_votes", "", payload)
-	if err != nil {
-		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-			// Already voted - try to update instead
-			fmt.Printf("[DEBUG] Duplicate vote detected, attempting to update\n")
-			return r.ChangeVote(ctx, pollID, optionID, userID)
+	if _, err := r.makeRequest("POST", "poll_votes", "", payload); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "poll is closed"):
+			return apperr.ErrPollClosed
+		case strings.Contains(err.Error(), "already voted"):
+			return apperr.ErrPollAlreadyVoted
 		}
-		fmt.Printf("[DEBUG] ERROR: Failed to create vote: %v\n", err)
 		return fmt.Errorf("failed to vote: %w", err)
 	}
 
-	fmt.Printf("[DEBUG] Vote created successfully\n")
 	return nil
 }
 
@@ -450,6 +466,30 @@ func (r *SupabasePollRepository) GetUserVote(ctx context.Context, pollID, userID
 	return &votes[0].OptionID, nil
 }
 
+// GetUserVotes retrieves every option ID a user voted for (more than one on
+// multiple-vote polls)
+func (r *SupabasePollRepository) GetUserVotes(ctx context.Context, pollID, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := fmt.Sprintf("?poll_id=eq.%s&user_id=eq.%s&select=option_id", pollID.String(), userID.String())
+
+	data, err := r.makeRequest("GET", "poll_votes", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var votes []struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+	if err := json.Unmarshal(data, &votes); err != nil {
+		return nil, err
+	}
+
+	optionIDs := make([]uuid.UUID, 0, len(votes))
+	for _, vote := range votes {
+		optionIDs = append(optionIDs, vote.OptionID)
+	}
+	return optionIDs, nil
+}
+
 // HasUserVoted checks if a user has voted on a poll
 func (r *SupabasePollRepository) HasUserVoted(ctx context.Context, pollID, userID uuid.UUID) (bool, error) {
 	voteID, err := r.GetUserVote(ctx, pollID, userID)
@@ -459,6 +499,32 @@ func (r *SupabasePollRepository) HasUserVoted(ctx context.Context, pollID, userI
 	return voteID != nil, nil
 }
 
+// GetPollVoterIDs returns the IDs of every user who voted on a poll
+func (r *SupabasePollRepository) GetPollVoterIDs(ctx context.Context, pollID uuid.UUID) ([]uuid.UUID, error) {
+	query := fmt.Sprintf("?poll_id=eq.%s&select=user_id", pollID.String())
+	data, err := r.makeRequest("GET", "poll_votes", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var votes []struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.Unmarshal(data, &votes); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool, len(votes))
+	voterIDs := make([]uuid.UUID, 0, len(votes))
+	for _, vote := range votes {
+		if !seen[vote.UserID] {
+			seen[vote.UserID] = true
+			voterIDs = append(voterIDs, vote.UserID)
+		}
+	}
+	return voterIDs, nil
+}
+
 // GetPollResults retrieves poll results with percentages
 func (r *SupabasePollRepository) GetPollResults(ctx context.Context, pollID, viewerID uuid.UUID) (*models.PollResults, error) {
 	poll, err := r.GetPoll(ctx, pollID)
@@ -466,18 +532,96 @@ func (r *SupabasePollRepository) GetPollResults(ctx context.Context, pollID, vie
 		return nil, err
 	}
 
-	// Check if user voted
-	userVoteID, _ := r.GetUserVote(ctx, pollID, viewerID)
+	userVoteIDs, _ := r.GetUserVotes(ctx, pollID, viewerID)
+	return buildPollResults(poll, userVoteIDs), nil
+}
+
+// GetPollResultsBatch retrieves results for several polls with one query each for polls,
+// options, the viewer's votes and the poll authors. Unknown poll IDs are skipped; results
+// keep the order of pollIDs. Tallies are hidden (ResultsHidden) on polls that don't show
+// results before voting, until the viewer votes or the poll ends. Authors always see them.
+func (r *SupabasePollRepository) GetPollResultsBatch(ctx context.Context, pollIDs []uuid.UUID, viewerID uuid.UUID) ([]models.PollResults, error) {
+	if len(pollIDs) == 0 {
+		return []models.PollResults{}, nil
+	}
+
+	idStrings := make([]string, len(pollIDs))
+	for i, id := range pollIDs {
+		idStrings[i] = id.String()
+	}
+	polls, userVotes, err := r.loadPolls(ctx, fmt.Sprintf("id=in.(%s)", strings.Join(idStrings, ",")), viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load poll authors so they can always see their own results
+	authors := make(map[uuid.UUID]uuid.UUID)
+	if len(polls) > 0 {
+		postIDStrings := make([]string, len(polls))
+		for i := range polls {
+			postIDStrings[i] = polls[i].PostID.String()
+		}
+		data, err := r.makeRequest("GET", "posts", fmt.Sprintf("?id=in.(%s)&select=id,user_id", strings.Join(postIDStrings, ",")), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get poll authors: %w", err)
+		}
+		var rows []struct {
+			ID     uuid.UUID `json:"id"`
+			UserID uuid.UUID `json:"user_id"`
+		}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal poll authors: %w", err)
+		}
+		for _, row := range rows {
+			authors[row.ID] = row.UserID
+		}
+	}
+
+	pollsByID := make(map[uuid.UUID]*models.Poll, len(polls))
+	for i := range polls {
+		pollsByID[polls[i].ID] = &polls[i]
+	}
+
+	results := make([]models.PollResults, 0, len(pollIDs))
+	for _, id := range pollIDs {
+		poll, ok := pollsByID[id]
+		if !ok {
+			continue
+		}
+		delete(pollsByID, id) // Duplicate IDs in the request are returned once
+
+		result := buildPollResults(poll, userVotes[id])
+		isAuthor := viewerID != uuid.Nil && authors[poll.PostID] == viewerID
+		if !poll.ShowResultsBeforeVote && !result.UserVoted && !result.IsEnded && !isAuthor {
+			hidePollTallies(result)
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// buildPollResults computes percentages and the viewer's votes for a loaded poll
+func buildPollResults(poll *models.Poll, userVoteIDs []uuid.UUID) *models.PollResults {
+	votedFor := make(map[uuid.UUID]bool, len(userVoteIDs))
+	for _, id := range userVoteIDs {
+		votedFor[id] = true
+	}
+	var userVoteID *uuid.UUID
+	if len(userVoteIDs) > 0 {
+		userVoteID = &userVoteIDs[0]
+	}
 	hasVoted := userVoteID != nil
 
 	// Build results
 	results := &models.PollResults{
-		Poll:       poll,
-		Options:    make([]models.PollOptionResult, len(poll.Options)),
-		TotalVotes: poll.TotalVotes,
-		UserVoted:  hasVoted,
-		UserVoteID: userVoteID,
-		IsEnded:    poll.IsClosed || time.Now().After(poll.EndsAt),
+		Poll:        poll,
+		Options:     make([]models.PollOptionResult, len(poll.Options)),
+		TotalVotes:  poll.TotalVotes,
+		UserVoted:   hasVoted,
+		UserVoteID:  userVoteID,
+		UserVoteIDs: userVoteIDs,
+		IsEnded:     poll.IsClosed || time.Now().After(poll.EndsAt),
 	}
 
 	// Calculate time left
@@ -499,11 +643,27 @@ func (r *SupabasePollRepository) GetPollResults(ctx context.Context, pollID, vie
 			OptionIndex: option.OptionIndex,
 			VotesCount:  option.VotesCount,
 			Percentage:  percentage,
-			IsUserVote:  userVoteID != nil && *userVoteID == option.ID,
+			IsUserVote:  votedFor[option.ID],
 		}
 	}
 
-	return results, nil
+	return results
+}
+
+// hidePollTallies zeroes vote counts in results, including those on the embedded poll
+func hidePollTallies(results *models.PollResults) {
+	results.ResultsHidden = true
+	results.TotalVotes = 0
+	for i := range results.Options {
+		results.Options[i].VotesCount = 0
+		results.Options[i].Percentage = 0
+	}
+	if results.Poll != nil {
+		results.Poll.TotalVotes = 0
+		for i := range results.Poll.Options {
+			results.Poll.Options[i].VotesCount = 0
+		}
+	}
 }
 
 // GetPollOptions retrieves all options for a poll
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCloseExpiredPollsOnlyTouchesOpenExpiredPolls(t *testing.T) {
+	pollID, postID := uuid.New(), uuid.New()
+	var method, filter string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		filter = r.URL.Query().Get("is_closed") + " " + r.URL.Query().Get("ends_at")
+		w.Write([]byte(`[{"id": "` + pollID.String() + `", "post_id": "` + postID.String() + `"}]`))
+	}))
+	t.Cleanup(server.Close)
+	repo := NewSupabasePollRepository(server.URL, "key")
+
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	polls, err := repo.CloseExpiredPolls(context.Background(), now)
+	if err != nil {
+		t.Fatalf("CloseExpiredPolls: %v", err)
+	}
+
+	if method != http.MethodPatch || filter != "eq.false lte.2026-03-04T05:06:07Z" {
+		t.Errorf("request = %s with filter %q, want a PATCH of open polls ending by now", method, filter)
+	}
+	if len(polls) != 1 || polls[0].ID != pollID || polls[0].PostID != postID {
+		t.Fatalf("closed polls = %+v", polls)
+	}
+	if !polls[0].IsClosed || polls[0].ClosedAt == nil || !polls[0].ClosedAt.Equal(now) {
+		t.Errorf("closed poll should carry is_closed and closed_at %v: %+v", now, polls[0])
+	}
+}
+
+func TestGetPollResultsBatchViewerState(t *testing.T) {
+	viewer := uuid.New()
+	voted, hidden, open := uuid.New(), uuid.New(), uuid.New()
+	votedOption, hiddenOption, openOption := uuid.New(), uuid.New(), uuid.New()
+	endsAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	poll := func(id uuid.UUID, showBefore bool) string {
+		return fmt.Sprintf(`{"id": %q, "post_id": %q, "total_votes": 4, "show_results_before_vote": %v, "ends_at": %q}`,
+			id, uuid.New(), showBefore, endsAt)
+	}
+	option := func(id, pollID uuid.UUID) string {
+		return fmt.Sprintf(`{"id": %q, "poll_id": %q, "option_text": "yes", "votes_count": 4}`, id, pollID)
+	}
+	tables := map[string]string{
+		"polls":        "[" + poll(voted, false) + "," + poll(hidden, false) + "," + poll(open, true) + "]",
+		"poll_options": "[" + option(votedOption, voted) + "," + option(hiddenOption, hidden) + "," + option(openOption, open) + "]",
+		"poll_votes":   fmt.Sprintf(`[{"poll_id": %q, "option_id": %q}]`, voted, votedOption),
+		"posts":        "[]",
+	}
+
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table := strings.TrimPrefix(r.URL.Path, "/rest/v1/")
+		requests[table]++
+		if table == "poll_votes" && !strings.Contains(r.URL.RawQuery, "user_id=eq."+viewer.String()) {
+			t.Errorf("votes query %q should be scoped to the viewer", r.URL.RawQuery)
+		}
+		w.Write([]byte(tables[table]))
+	}))
+	t.Cleanup(server.Close)
+	repo := NewSupabasePollRepository(server.URL, "key")
+
+	results, err := repo.GetPollResultsBatch(context.Background(), []uuid.UUID{open, voted, hidden}, viewer)
+	if err != nil {
+		t.Fatalf("GetPollResultsBatch: %v", err)
+	}
+
+	for table, n := range requests {
+		if n != 1 {
+			t.Errorf("queried %s %d times, want once for the whole batch", table, n)
+		}
+	}
+	if len(results) != 3 || results[0].Poll.ID != open || results[1].Poll.ID != voted || results[2].Poll.ID != hidden {
+		t.Fatalf("results should follow the requested order: %+v", results)
+	}
+
+	if r := results[0]; r.UserVoted || r.ResultsHidden || r.TotalVotes != 4 {
+		t.Errorf("open poll: voted %v, hidden %v, total %d; want tallies shown without a vote", r.UserVoted, r.ResultsHidden, r.TotalVotes)
+	}
+	if r := results[1]; !r.UserVoted || r.UserVoteID == nil || *r.UserVoteID != votedOption || r.ResultsHidden {
+		t.Errorf("voted poll: voted %v, vote %v, hidden %v; want the viewer's vote and tallies", r.UserVoted, r.UserVoteID, r.ResultsHidden)
+	}
+	if r := results[2]; r.UserVoted || !r.ResultsHidden || r.TotalVotes != 0 {
+		t.Errorf("hidden poll: voted %v, hidden %v, total %d; want tallies hidden until the viewer votes", r.UserVoted, r.ResultsHidden, r.TotalVotes)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -16,6 +17,8 @@ import (
 	"histeeria-backend/internal/models"
 
 	"github.com/google/uuid"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
 )
 
 // SupabasePostRepository implements PostRepository using Supabase REST API
@@ -23,6 +26,8 @@ type SupabasePostRepository struct {
 	supabaseURL string
 	serviceKey  string
 	client      *http.Client
+	views       *postViewBuffer
+	flushing    sync.Mutex // Serialises FlushPostViews
 }
 
 // NewSupabasePostRepository creates a new Supabase post repository
@@ -30,9 +35,8 @@ func NewSupabasePostRepository(supabaseURL, serviceKey string) *SupabasePostRepo
 	return &SupabasePostRepository{
 		supabaseURL: supabaseURL,
 		serviceKey:  serviceKey,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:      newSupabaseHTTPClient(30 * time.Second),
+		views:       newPostViewBuffer(),
 	}
 }
 
@@ -101,6 +105,12 @@ func (r *SupabasePostRepository) CreatePost(ctx context.Context, post *models.Po
 		"is_nsfw":         post.IsNSFW,
 		"published_at":    post.PublishedAt,
 	}
+	if post.Language != "" {
+		payload["language"] = post.Language
+	}
+	if post.ScheduledAt != nil {
+		payload["scheduled_at"] = post.ScheduledAt.UTC().Format(time.RFC3339)
+	}
 
 	// Handle media arrays - ensure they're sent as arrays, not null
 	// Convert pq.StringArray to []string for JSON serialization
@@ -116,6 +126,9 @@ func (r *SupabasePostRepository) CreatePost(ctx context.Context, post *models.Po
 		// Send empty array instead of null to ensure Supabase stores it correctly
 		payload["media_types"] = []string{}
 	}
+	if len(post.MediaVariants) > 0 {
+		payload["media_variants"] = post.MediaVariants
+	}
 
 	data, err := r.makeRequest("POST", "posts", "", payload)
 	if err != nil {
@@ -223,9 +236,15 @@ func (r *SupabasePostRepository) CreatePost(ctx context.Context, post *models.Po
 		if isDraft, ok := postData["is_draft"].(bool); ok {
 			post.IsDraft = isDraft
 		}
+		if draftVersion, ok := postData["draft_version"].(float64); ok {
+			post.DraftVersion = int(draftVersion)
+		}
 		if isNSFW, ok := postData["is_nsfw"].(bool); ok {
 			post.IsNSFW = isNSFW
 		}
+		if language, ok := postData["language"].(string); ok {
+			post.Language = language
+		}
 
 		// Parse numeric fields
 		if likesCount, ok := postData["likes_count"].(float64); ok {
@@ -254,6 +273,7 @@ func (r *SupabasePostRepository) CreatePost(ctx context.Context, post *models.Po
 		post.CreatedAt = parseRequiredTime(postData["created_at"])
 		post.UpdatedAt = parseRequiredTime(postData["updated_at"])
 		post.PublishedAt = parseTime(postData["published_at"])
+		post.ScheduledAt = parseTime(postData["scheduled_at"])
 		if deletedAt := parseTime(postData["deleted_at"]); deletedAt != nil {
 			post.DeletedAt = deletedAt
 		}
@@ -285,6 +305,7 @@ func (r *SupabasePostRepository) CreatePost(ctx context.Context, post *models.Po
 				post.MediaTypes = strSlice
 			}
 		}
+		post.MediaVariants = parseMediaVariants(postData["media_variants"])
 	}
 
 	return nil
@@ -386,9 +407,15 @@ func (r *SupabasePostRepository) parsePostFromMap(postData map[string]interface{
 	if isDraft, ok := postData["is_draft"].(bool); ok {
 		post.IsDraft = isDraft
 	}
+	if draftVersion, ok := postData["draft_version"].(float64); ok {
+		post.DraftVersion = int(draftVersion)
+	}
 	if isNSFW, ok := postData["is_nsfw"].(bool); ok {
 		post.IsNSFW = isNSFW
 	}
+	if language, ok := postData["language"].(string); ok {
+		post.Language = language
+	}
 
 	// Parse numeric fields
 	if likesCount, ok := postData["likes_count"].(float64); ok {
@@ -417,6 +444,7 @@ func (r *SupabasePostRepository) parsePostFromMap(postData map[string]interface{
 	post.CreatedAt = parseRequiredTime(postData["created_at"])
 	post.UpdatedAt = parseRequiredTime(postData["updated_at"])
 	post.PublishedAt = parseTime(postData["published_at"])
+	post.ScheduledAt = parseTime(postData["scheduled_at"])
 	if deletedAt := parseTime(postData["deleted_at"]); deletedAt != nil {
 		post.DeletedAt = deletedAt
 	}
@@ -469,9 +497,34 @@ func (r *SupabasePostRepository) parsePostFromMap(postData map[string]interface{
 		post.MediaTypes = []string{}
 	}
 
+	post.MediaVariants = parseMediaVariants(postData["media_variants"])
+
 	return post, nil
 }
 
+// parseMediaVariants parses the media_variants JSONB column (array of size -> URL objects)
+func parseMediaVariants(raw interface{}) []map[string]string {
+	entries, ok := raw.([]interface{})
+	if !ok || len(entries) == 0 {
+		return nil
+	}
+
+	variants := make([]map[string]string, 0, len(entries))
+	for _, entry := range entries {
+		sizes := make(map[string]string)
+		if obj, ok := entry.(map[string]interface{}); ok {
+			for size, url := range obj {
+				if str, ok := url.(string); ok && str != "" {
+					sizes[size] = str
+				}
+			}
+		}
+		variants = append(variants, sizes)
+	}
+
+	return variants
+}
+
 // parsePostsFromJSON parses multiple posts from JSON data
 func (r *SupabasePostRepository) parsePostsFromJSON(data []byte) ([]models.Post, error) {
 	var postsData []map[string]interface{}
@@ -536,7 +589,7 @@ func (r *SupabasePostRepository) parsePostsFromJSONWithAuthors(data []byte) ([]m
 
 // GetPost retrieves a single post with all relations
 func (r *SupabasePostRepository) GetPost(ctx context.Context, postID, viewerID uuid.UUID) (*models.Post, error) {
-	query := fmt.Sprintf("?id=eq.%s&select=*", postID.String())
+	query := postQuery(postScopeExisting, fmt.Sprintf("id=eq.%s&select=*", postID.String()))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
@@ -560,10 +613,11 @@ func (r *SupabasePostRepository) GetPost(ctx context.Context, postID, viewerID u
 		fmt.Printf("Warning: failed to load author for post %s: %v\n", postID, err)
 	}
 
-	// Check if viewer liked/saved
+	// Check if viewer liked/saved/shared
 	if viewerID != uuid.Nil {
 		post.IsLiked, _ = r.IsPostLikedByUser(ctx, postID, viewerID)
 		post.IsSaved, _ = r.IsPostSavedByUser(ctx, postID, viewerID)
+		post.IsShared, _ = r.IsPostSharedByUser(ctx, postID, viewerID)
 	}
 
 	// Load type-specific data
@@ -583,43 +637,105 @@ func (r *SupabasePostRepository) GetPost(ctx context.Context, postID, viewerID u
 	return post, nil
 }
 
+// CountPinnedPosts returns how many of a user's visible posts are pinned to their profile
+func (r *SupabasePostRepository) CountPinnedPosts(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := postQuery(postScopeVisible, fmt.Sprintf("user_id=eq.%s&is_pinned=eq.true&select=id", userID.String()))
+
+	data, err := r.makeRequest("GET", "posts", query, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pinned posts: %w", err)
+	}
+
+	var posts []map[string]interface{}
+	if err := json.Unmarshal(data, &posts); err != nil {
+		return 0, fmt.Errorf("failed to parse pinned posts: %w", err)
+	}
+
+	return len(posts), nil
+}
+
+// SaveMediaVariants records the variants generated for an image userID uploaded
+func (r *SupabasePostRepository) SaveMediaVariants(ctx context.Context, userID uuid.UUID, mediaURL string, variants map[string]string) error {
+	payload := map[string]interface{}{
+		"media_url": mediaURL,
+		"user_id":   userID,
+		"variants":  variants,
+	}
+
+	if _, err := r.makeRequest("POST", "media_variants", "", payload); err != nil {
+		return fmt.Errorf("failed to save media variants: %w", err)
+	}
+	return nil
+}
+
+// GetMediaVariants returns the saved variants of those of mediaURLs userID uploaded,
+// by URL, in one query. Other users' files have none.
+func (r *SupabasePostRepository) GetMediaVariants(ctx context.Context, userID uuid.UUID, mediaURLs []string) (map[string]map[string]string, error) {
+	if len(mediaURLs) == 0 {
+		return map[string]map[string]string{}, nil
+	}
+
+	// URLs can contain commas, so each is quoted in the in.() list
+	quoted := make([]string, len(mediaURLs))
+	for i, u := range mediaURLs {
+		u = strings.ReplaceAll(u, `\`, `\\`)
+		quoted[i] = `"` + strings.ReplaceAll(u, `"`, `\"`) + `"`
+	}
+	q := url.Values{}
+	q.Set("select", "media_url,variants")
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("media_url", "in.("+strings.Join(quoted, ",")+")")
+
+	data, err := r.makeRequest("GET", "media_variants", "?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media variants: %w", err)
+	}
+
+	var rows []struct {
+		MediaURL string            `json:"media_url"`
+		Variants map[string]string `json:"variants"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse media variants: %w", err)
+	}
+
+	variants := make(map[string]map[string]string, len(rows))
+	for _, row := range rows {
+		variants[row.MediaURL] = row.Variants
+	}
+	return variants, nil
+}
+
 // GetPostByID retrieves a post without viewer-specific data
 func (r *SupabasePostRepository) GetPostByID(ctx context.Context, postID uuid.UUID) (*models.Post, error) {
 	return r.GetPost(ctx, postID, uuid.Nil)
 }
 
 // GetUserPosts retrieves posts by a specific user
-func (r *SupabasePostRepository) GetUserPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
-	query := fmt.Sprintf(
-		"?user_id=eq.%s&is_published=eq.true&deleted_at=is.null&order=created_at.desc&limit=%d&offset=%d",
-		userID.String(), limit, offset,
-	)
+func (r *SupabasePostRepository) GetUserPosts(ctx context.Context, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
+	filter := fmt.Sprintf("user_id=eq.%s", userID.String())
+	query := postQuery(postScopeVisible, fmt.Sprintf(
+		"%s&order=created_at.desc&limit=%d&offset=%d",
+		filter, limit+1, offset,
+	))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get user posts: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to get user posts: %w", err)
 	}
 
 	posts, err := r.parsePostsFromJSON(data)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to parse user posts: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to parse user posts: %w", err)
 	}
-
-	// Get total count
-	countQuery := fmt.Sprintf("?user_id=eq.%s&is_published=eq.true&deleted_at=is.null&select=count", userID.String())
-	countData, _ := r.makeRequest("GET", "posts", countQuery, nil)
-	total := len(posts) // Fallback
-
-	if countData != nil {
-		var countResult []map[string]int
-		if err := json.Unmarshal(countData, &countResult); err == nil && len(countResult) > 0 {
-			total = countResult[0]["count"]
-		}
+	posts, page := models.LookaheadPage(posts, limit)
+	if withCount {
+		page = r.countedPage(page, postScopeVisible, filter, offset, limit)
 	}
 
 	// If no posts, return early
 	if len(posts) == 0 {
-		return posts, total, nil
+		return posts, page, nil
 	}
 
 	// Batch load authors
@@ -640,33 +756,83 @@ func (r *SupabasePostRepository) GetUserPosts(ctx context.Context, userID uuid.U
 		fmt.Printf("Warning: failed to load type-specific data: %v\n", err)
 	}
 
-	return posts, total, nil
+	return posts, page, nil
+}
+
+// homeFeedAudience returns whose close friends posts userID's home feed includes:
+// their own and those of everyone whose close friends list they're on. A failed
+// lookup leaves close friends posts out.
+func (r *SupabasePostRepository) homeFeedAudience(userID uuid.UUID) []uuid.UUID {
+	if userID == uuid.Nil {
+		return nil
+	}
+	ownerIDs := []uuid.UUID{userID}
+	data, err := r.makeRequest("GET", "close_friends", closeFriendsOfQuery(userID), nil)
+	if err != nil {
+		log.Printf("[PostRepo] Failed to get close friends lists of %s: %v", userID, err)
+		return ownerIDs
+	}
+	listOwners, err := parseCloseFriendOwners(data)
+	if err != nil {
+		log.Printf("[PostRepo] Failed to get close friends lists of %s: %v", userID, err)
+		return ownerIDs
+	}
+	return append(ownerIDs, listOwners...)
+}
+
+// countedPage swaps a lookahead page for an exact count of the posts filter matches.
+// If the count fails the lookahead page is kept, so callers still get HasMore.
+func (r *SupabasePostRepository) countedPage(page models.Page, scope postScope, filter string, offset, limit int) models.Page {
+	params := "select=count"
+	if filter != "" {
+		params = filter + "&" + params
+	}
+	data, err := r.makeRequest("GET", "posts", postQuery(scope, params), nil)
+	if err != nil {
+		return page
+	}
+
+	var countResult []map[string]int
+	if err := json.Unmarshal(data, &countResult); err != nil || len(countResult) == 0 {
+		return page
+	}
+	return models.CountedPage(countResult[0]["count"], offset, limit)
 }
 
-// GetHomeFeed retrieves the home feed for a user (chronological)
+// GetHomeFeed retrieves the home feed for a user (chronological). With hideInteracted
+// the posts they've liked, saved or commented on are left out by the query, so pages
+// still come back full; the count, if asked for, includes them.
 // OPTIMIZED: Uses batch loading and parallel execution
-func (r *SupabasePostRepository) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+func (r *SupabasePostRepository) GetHomeFeed(ctx context.Context, userID uuid.UUID, limit, offset int, hideInteracted, withCount bool) ([]models.Post, models.Page, error) {
+	// Public posts, plus close friends posts shared with the viewer
+	audience := audienceFilter(r.homeFeedAudience(userID))
+	embeds, exclusions := interactedExclusion(userID, hideInteracted)
+
 	// Use Supabase join to get posts with authors in one query
 	// This reduces N+1 queries from N+1 to just 1 query for posts + authors
-	query := fmt.Sprintf(
-		"?is_published=eq.true&deleted_at=is.null&visibility=eq.public&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
-		limit, offset,
-	)
+	query := postQuery(postScopeVisible, fmt.Sprintf(
+		"%s%s&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)%s",
+		audience, exclusions, limit+1, offset, embeds,
+	))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get home feed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to get home feed: %w", err)
 	}
 
 	// Parse posts with embedded author data
 	posts, err := r.parsePostsFromJSONWithAuthors(data)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to parse feed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to parse feed: %w", err)
+	}
+	posts, page := models.LookaheadPage(posts, limit)
+	if withCount {
+		page = r.countedPage(page, postScopeVisible, audience, offset, limit)
 	}
 
 	// If no posts, return early
 	if len(posts) == 0 {
-		return posts, 0, nil
+		return posts, page, nil
 	}
 
 	// Batch load engagement data (likes and saves) in parallel
@@ -678,11 +844,11 @@ func (r *SupabasePostRepository) GetHomeFeed(ctx context.Context, userID uuid.UU
 		}
 
 		// Use goroutines for parallel batch loading
-		var likedMap, savedMap map[uuid.UUID]bool
-		var likedErr, savedErr error
+		var likedMap, savedMap, sharedMap map[uuid.UUID]bool
+		var likedErr, savedErr, sharedErr error
 		var wg sync.WaitGroup
 
-		wg.Add(2)
+		wg.Add(3)
 
 		// Batch check likes in parallel
 		go func() {
@@ -696,6 +862,12 @@ func (r *SupabasePostRepository) GetHomeFeed(ctx context.Context, userID uuid.UU
 			savedMap, savedErr = r.batchCheckSaves(ctx, postIDs, userID)
 		}()
 
+		// Batch check shares in parallel
+		go func() {
+			defer wg.Done()
+			sharedMap, sharedErr = r.batchCheckShares(ctx, postIDs, userID)
+		}()
+
 		wg.Wait()
 
 		// Apply engagement data to posts
@@ -706,6 +878,9 @@ func (r *SupabasePostRepository) GetHomeFeed(ctx context.Context, userID uuid.UU
 			if savedErr == nil {
 				posts[i].IsSaved = savedMap[posts[i].ID]
 			}
+			if sharedErr == nil {
+				posts[i].IsShared = sharedMap[posts[i].ID]
+			}
 		}
 	}
 
@@ -717,11 +892,12 @@ func (r *SupabasePostRepository) GetHomeFeed(ctx context.Context, userID uuid.UU
 
 	// Filter blocked/restricted content if user is authenticated
 	if userID != uuid.Nil {
+		fetched := len(posts)
 		posts = r.filterBlockedRestrictedContent(ctx, posts, userID)
+		page = page.Filtered(fetched-len(posts), len(posts))
 	}
 
-	total := len(posts)
-	return posts, total, nil
+	return posts, page, nil
 }
 
 // filterBlockedRestrictedContent filters out posts from blocked/restricted users and restricted posts
@@ -737,7 +913,8 @@ func (r *SupabasePostRepository) filterBlockedRestrictedContent(ctx context.Cont
 		defer wg.Done()
 		// We need to call userRepo methods, but we don't have access to it here
 		// For now, we'll fetch directly from Supabase
-		query := fmt.Sprintf("?blocker_id=eq.%s&select=blocked_id", userID.String())
+		// Blocks hide content both ways: users who blocked the viewer are left out too
+		query := fmt.Sprintf("?or=(blocker_id.eq.%s,blocked_id.eq.%s)&select=blocker_id,blocked_id", userID.String(), userID.String())
 		data, err := r.makeRequest("GET", "blocked_users", query, nil)
 		if err != nil {
 			// Log error but continue - filtering will just be less effective
@@ -747,7 +924,11 @@ func (r *SupabasePostRepository) filterBlockedRestrictedContent(ctx context.Cont
 		if err := json.Unmarshal(data, &results); err == nil {
 			blockedUserIDs = make([]uuid.UUID, 0, len(results))
 			for _, result := range results {
-				if idStr, ok := result["blocked_id"].(string); ok {
+				otherKey := "blocked_id"
+				if result["blocked_id"] == userID.String() {
+					otherKey = "blocker_id"
+				}
+				if idStr, ok := result[otherKey].(string); ok {
 					if id, err := uuid.Parse(idStr); err == nil {
 						blockedUserIDs = append(blockedUserIDs, id)
 					}
@@ -832,48 +1013,54 @@ func (r *SupabasePostRepository) filterBlockedRestrictedContent(ctx context.Cont
 }
 
 // GetFollowingFeed retrieves posts only from users the viewer follows
-func (r *SupabasePostRepository) GetFollowingFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+func (r *SupabasePostRepository) GetFollowingFeed(ctx context.Context, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
 	// For now, return same as home feed
 	// TODO: Implement with relationship filtering
-	return r.GetHomeFeed(ctx, userID, limit, offset)
+	return r.GetHomeFeed(ctx, userID, limit, offset, false, withCount)
 }
 
-// GetExploreFeed retrieves trending/popular posts for discovery
+// GetExploreFeed retrieves trending/popular posts for discovery. hideInteracted
+// leaves out posts the user has interacted with, as for GetHomeFeed.
 // OPTIMIZED: Uses batch loading and parallel execution
 // filter can be: "posts", "polls", "articles", or "" for all
-func (r *SupabasePostRepository) GetExploreFeed(ctx context.Context, userID uuid.UUID, limit, offset int, filter string) ([]models.Post, int, error) {
-	// Build query with optional filter
-	query := "?is_published=eq.true&deleted_at=is.null&visibility=eq.public"
-
+func (r *SupabasePostRepository) GetExploreFeed(ctx context.Context, userID uuid.UUID, limit, offset int, filter string, hideInteracted, withCount bool) ([]models.Post, models.Page, error) {
 	// Add post type filter if specified
+	typeFilter := ""
 	if filter == "posts" {
-		query += "&post_type=eq.post"
+		typeFilter = "post_type=eq.post"
 	} else if filter == "polls" {
-		query += "&post_type=eq.poll"
+		typeFilter = "post_type=eq.poll"
 	} else if filter == "articles" {
-		query += "&post_type=eq.article"
+		typeFilter = "post_type=eq.article"
 	}
 	// If filter is empty or invalid, show all types
 
+	// Build query with optional filter
+	embeds, exclusions := interactedExclusion(userID, hideInteracted)
+	query := postQuery(postScopePublic, typeFilter)
 	query += fmt.Sprintf(
-		"&order=likes_count.desc,created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
-		limit, offset,
+		"%s&order=likes_count.desc,created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)%s",
+		exclusions, limit+1, offset, embeds,
 	)
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get explore feed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to get explore feed: %w", err)
 	}
 
 	// Parse posts with embedded author data
 	posts, err := r.parsePostsFromJSONWithAuthors(data)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to parse explore feed: %w", err)
+		return nil, models.Page{}, fmt.Errorf("failed to parse explore feed: %w", err)
+	}
+	posts, page := models.LookaheadPage(posts, limit)
+	if withCount {
+		page = r.countedPage(page, postScopePublic, typeFilter, offset, limit)
 	}
 
 	// If no posts, return early
 	if len(posts) == 0 {
-		return posts, 0, nil
+		return posts, page, nil
 	}
 
 	// Batch load engagement data (likes and saves) in parallel
@@ -885,11 +1072,11 @@ func (r *SupabasePostRepository) GetExploreFeed(ctx context.Context, userID uuid
 		}
 
 		// Use goroutines for parallel batch loading
-		var likedMap, savedMap map[uuid.UUID]bool
-		var likedErr, savedErr error
+		var likedMap, savedMap, sharedMap map[uuid.UUID]bool
+		var likedErr, savedErr, sharedErr error
 		var wg sync.WaitGroup
 
-		wg.Add(2)
+		wg.Add(3)
 
 		// Batch check likes in parallel
 		go func() {
@@ -903,6 +1090,12 @@ func (r *SupabasePostRepository) GetExploreFeed(ctx context.Context, userID uuid
 			savedMap, savedErr = r.batchCheckSaves(ctx, postIDs, userID)
 		}()
 
+		// Batch check shares in parallel
+		go func() {
+			defer wg.Done()
+			sharedMap, sharedErr = r.batchCheckShares(ctx, postIDs, userID)
+		}()
+
 		wg.Wait()
 
 		// Apply engagement data to posts
@@ -913,6 +1106,9 @@ func (r *SupabasePostRepository) GetExploreFeed(ctx context.Context, userID uuid
 			if savedErr == nil {
 				posts[i].IsSaved = savedMap[posts[i].ID]
 			}
+			if sharedErr == nil {
+				posts[i].IsShared = sharedMap[posts[i].ID]
+			}
 		}
 	}
 
@@ -924,24 +1120,95 @@ func (r *SupabasePostRepository) GetExploreFeed(ctx context.Context, userID uuid
 
 	// Filter blocked/restricted content if user is authenticated
 	if userID != uuid.Nil {
+		fetched := len(posts)
 		posts = r.filterBlockedRestrictedContent(ctx, posts, userID)
+		page = page.Filtered(fetched-len(posts), len(posts))
 	}
 
-	return posts, len(posts), nil
+	return posts, page, nil
 }
 
-// UpdatePost updates a post
-func (r *SupabasePostRepository) UpdatePost(ctx context.Context, postID uuid.UUID, updates map[string]interface{}) error {
+// UpdatePost updates a post and returns the updated row. The author and viewer state
+// aren't loaded; callers that show the post fill those in from what they already have.
+func (r *SupabasePostRepository) UpdatePost(ctx context.Context, postID uuid.UUID, updates map[string]interface{}) (*models.Post, error) {
 	updates["updated_at"] = time.Now()
 
 	query := fmt.Sprintf("?id=eq.%s", postID.String())
 
-	_, err := r.makeRequest("PATCH", "posts", query, updates)
+	data, err := r.makeRequest("PATCH", "posts", query, updates)
 	if err != nil {
-		return fmt.Errorf("failed to update post: %w", err)
+		return nil, fmt.Errorf("failed to update post: %w", err)
 	}
 
-	return nil
+	posts, err := r.parsePostsFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse updated post: %w", err)
+	}
+	if len(posts) == 0 {
+		return nil, models.ErrPostNotFound
+	}
+
+	return &posts[0], nil
+}
+
+// PublishDueScheduledPosts publishes up to limit scheduled posts whose time is at or
+// before now and returns them. A post is only returned by the call that published
+// it, so two instances running this at once don't both announce it.
+func (r *SupabasePostRepository) PublishDueScheduledPosts(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	const waiting = "is_published=eq.false&is_draft=eq.false"
+	query := postQuery(postScopeExisting, fmt.Sprintf("%s&scheduled_at=lte.%s&select=id&order=scheduled_at.asc&limit=%d",
+		waiting, url.QueryEscape(now.UTC().Format(time.RFC3339)), limit))
+	data, err := r.makeRequest("GET", "posts", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due scheduled posts: %w", err)
+	}
+	var due []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(data, &due); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal due scheduled posts: %w", err)
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(due))
+	for i, p := range due {
+		ids[i] = p.ID.String()
+	}
+	// Still waiting when patched, so a post another instance got to first is left out
+	query = postQuery(postScopeExisting, fmt.Sprintf("id=in.(%s)&%s", strings.Join(ids, ","), waiting))
+	data, err = r.makeRequest("PATCH", "posts", query, map[string]interface{}{
+		"is_published": true,
+		"published_at": now.UTC().Format(time.RFC3339),
+		"updated_at":   now.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish scheduled posts: %w", err)
+	}
+	return r.parsePostsFromJSON(data)
+}
+
+// UpdateDraft applies updates to an unpublished draft and bumps its draft_version, but
+// only if the stored version still equals expectedVersion. It reports whether a row was
+// updated, so false means the draft changed since the caller loaded it (or is gone).
+func (r *SupabasePostRepository) UpdateDraft(ctx context.Context, postID uuid.UUID, expectedVersion int, updates map[string]interface{}) (bool, error) {
+	updates["draft_version"] = expectedVersion + 1
+	updates["updated_at"] = time.Now()
+
+	query := postQuery(postScopeExisting, fmt.Sprintf("id=eq.%s&is_draft=eq.true&draft_version=eq.%d", postID.String(), expectedVersion))
+
+	data, err := r.makeRequest("PATCH", "posts", query, updates)
+	if err != nil {
+		return false, fmt.Errorf("failed to save draft: %w", err)
+	}
+
+	var updated []map[string]interface{}
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return false, fmt.Errorf("failed to unmarshal saved draft: %w", err)
+	}
+
+	return len(updated) > 0, nil
 }
 
 // DeletePost soft deletes a post
@@ -961,37 +1228,66 @@ func (r *SupabasePostRepository) DeletePost(ctx context.Context, postID, userID
 		"deleted_at": time.Now(),
 	}
 
-	return r.UpdatePost(ctx, postID, updates)
+	_, err = r.UpdatePost(ctx, postID, updates)
+	return err
 }
 
-// LikePost adds a like to a post
-func (r *SupabasePostRepository) LikePost(ctx context.Context, postID, userID uuid.UUID) error {
-	payload := map[string]interface{}{
-		"post_id": postID,
-		"user_id": userID,
+// LikePost adds a like to a post and returns the post's new likes_count. Liking an
+// already-liked post leaves the count unchanged.
+func (r *SupabasePostRepository) LikePost(ctx context.Context, postID, userID uuid.UUID) (int, error) {
+	count, _, err := r.setPostLike(postID, userID, true)
+	if err != nil && err != models.ErrPostNotFound {
+		return 0, fmt.Errorf("failed to like post: %w", err)
 	}
+	return count, err
+}
 
-	_, err := r.makeRequest("POST", "post_likes", "", payload)
+// UnlikePost removes a like from a post and returns the post's new likes_count and
+// whether a like was actually removed. Unliking a post that wasn't liked is a no-op.
+func (r *SupabasePostRepository) UnlikePost(ctx context.Context, postID, userID uuid.UUID) (int, bool, error) {
+	count, changed, err := r.setPostLike(postID, userID, false)
+	if err != nil && err != models.ErrPostNotFound {
+		return 0, false, fmt.Errorf("failed to unlike post: %w", err)
+	}
+	return count, changed, err
+}
+
+// setPostLike writes the like and adjusts likes_count in one transaction via RPC
+func (r *SupabasePostRepository) setPostLike(postID, userID uuid.UUID, liked bool) (int, bool, error) {
+	data, err := r.makeRequest("POST", "rpc/toggle_post_like", "", map[string]interface{}{
+		"p_post_id": postID,
+		"p_user_id": userID,
+		"p_liked":   liked,
+	})
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-			return nil // Already liked
-		}
-		return fmt.Errorf("failed to like post: %w", err)
+		return 0, false, err
 	}
 
-	return nil
+	var rows []struct {
+		LikesCount int  `json:"likes_count"`
+		Changed    bool `json:"changed"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, false, fmt.Errorf("failed to decode like result: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, false, models.ErrPostNotFound
+	}
+	return rows[0].LikesCount, rows[0].Changed, nil
 }
 
-// UnlikePost removes a like from a post
-func (r *SupabasePostRepository) UnlikePost(ctx context.Context, postID, userID uuid.UUID) error {
-	query := fmt.Sprintf("?post_id=eq.%s&user_id=eq.%s", postID.String(), userID.String())
-
-	_, err := r.makeRequest("DELETE", "post_likes", query, nil)
+// ReconcileLikeCounts recomputes likes_count from post_likes and returns how many posts
+// were corrected
+func (r *SupabasePostRepository) ReconcileLikeCounts(ctx context.Context) (int, error) {
+	data, err := r.makeRequest("POST", "rpc/reconcile_post_like_counts", "", map[string]interface{}{})
 	if err != nil {
-		return fmt.Errorf("failed to unlike post: %w", err)
+		return 0, err
 	}
-
-	return nil
+	var corrected int
+	if err := json.Unmarshal(data, &corrected); err != nil {
+		return 0, fmt.Errorf("failed to decode reconcile result: %w", err)
+	}
+	return corrected, nil
 }
 
 // IsPostLikedByUser checks if a user has liked a post
@@ -1047,6 +1343,22 @@ func (r *SupabasePostRepository) batchCheckLikes(ctx context.Context, postIDs []
 	return likedMap, nil
 }
 
+// interactedExclusion returns the select embeds and filters that leave out of a posts
+// query the posts userID has liked, saved or commented on: each embed holds only
+// userID's rows and must come back empty. Both are empty when hide is false or
+// there's no user.
+func interactedExclusion(userID uuid.UUID, hide bool) (embeds, filters string) {
+	if !hide || userID == uuid.Nil {
+		return "", ""
+	}
+	id := userID.String()
+	embeds = ",viewer_likes:post_likes(post_id),viewer_saves:saved_posts(post_id),viewer_comments:post_comments(post_id)"
+	filters = "&viewer_likes.user_id=eq." + id + "&viewer_likes=is.null" +
+		"&viewer_saves.user_id=eq." + id + "&viewer_saves=is.null" +
+		"&viewer_comments.user_id=eq." + id + "&viewer_comments.deleted_at=is.null&viewer_comments=is.null"
+	return embeds, filters
+}
+
 // GetPostLikes retrieves users who liked a post
 func (r *SupabasePostRepository) GetPostLikes(ctx context.Context, postID uuid.UUID, limit, offset int) ([]models.User, int, error) {
 	// This would require a join or RPC function
@@ -1054,37 +1366,119 @@ func (r *SupabasePostRepository) GetPostLikes(ctx context.Context, postID uuid.U
 	return []models.User{}, 0, nil
 }
 
-// SharePost creates a share/repost
-func (r *SupabasePostRepository) SharePost(ctx context.Context, postID, userID uuid.UUID, comment string) error {
+// SharePost reposts a post, with comment as the optional quote, and returns the post's
+// new shares_count and whether a share was actually added. Sharing a post again is a
+// no-op that keeps the first share's comment.
+func (r *SupabasePostRepository) SharePost(ctx context.Context, postID, userID uuid.UUID, comment string) (int, bool, error) {
+	count, changed, err := r.setPostShare(postID, userID, true, comment)
+	if err != nil && err != models.ErrPostNotFound {
+		return 0, false, fmt.Errorf("failed to share post: %w", err)
+	}
+	return count, changed, err
+}
+
+// UnsharePost removes a share and returns the post's new shares_count and whether a
+// share was actually removed
+func (r *SupabasePostRepository) UnsharePost(ctx context.Context, postID, userID uuid.UUID) (int, bool, error) {
+	count, changed, err := r.setPostShare(postID, userID, false, "")
+	if err != nil && err != models.ErrPostNotFound {
+		return 0, false, fmt.Errorf("failed to unshare post: %w", err)
+	}
+	return count, changed, err
+}
+
+// setPostShare writes the share and adjusts shares_count in one transaction via RPC
+func (r *SupabasePostRepository) setPostShare(postID, userID uuid.UUID, shared bool, comment string) (int, bool, error) {
 	payload := map[string]interface{}{
-		"post_id":        postID,
-		"user_id":        userID,
-		"repost_comment": comment,
+		"p_post_id": postID,
+		"p_user_id": userID,
+		"p_shared":  shared,
+		"p_comment": nil,
+	}
+	if comment != "" {
+		payload["p_comment"] = comment
 	}
 
-	_, err := r.makeRequest("POST", "post_shares", "", payload)
+	data, err := r.makeRequest("POST", "rpc/toggle_post_share", "", payload)
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate") {
-			return nil // Already shared
-		}
-		return fmt.Errorf("failed to share post: %w", err)
+		return 0, false, err
 	}
 
-	return nil
+	var rows []struct {
+		SharesCount int  `json:"shares_count"`
+		Changed     bool `json:"changed"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, false, fmt.Errorf("failed to decode share result: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, false, models.ErrPostNotFound
+	}
+	return rows[0].SharesCount, rows[0].Changed, nil
 }
 
-// UnsharePost removes a share
-func (r *SupabasePostRepository) UnsharePost(ctx context.Context, postID, userID uuid.UUID) error {
-	query := fmt.Sprintf("?post_id=eq.%s&user_id=eq.%s", postID.String(), userID.String())
+// IsPostSharedByUser checks if a user has shared a post
+func (r *SupabasePostRepository) IsPostSharedByUser(ctx context.Context, postID, userID uuid.UUID) (bool, error) {
+	query := fmt.Sprintf("?post_id=eq.%s&user_id=eq.%s&select=id", postID.String(), userID.String())
 
-	_, err := r.makeRequest("DELETE", "post_shares", query, nil)
-	return err
+	data, err := r.makeRequest("GET", "post_shares", query, nil)
+	if err != nil {
+		return false, nil
+	}
+
+	var shares []map[string]interface{}
+	if err := json.Unmarshal(data, &shares); err != nil {
+		return false, nil
+	}
+
+	return len(shares) > 0, nil
+}
+
+// batchCheckShares checks which posts a user has shared (batch operation)
+func (r *SupabasePostRepository) batchCheckShares(ctx context.Context, postIDs []uuid.UUID, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	if len(postIDs) == 0 {
+		return make(map[uuid.UUID]bool), nil
+	}
+
+	postIDStrings := make([]string, len(postIDs))
+	for i, id := range postIDs {
+		postIDStrings[i] = id.String()
+	}
+	query := fmt.Sprintf("?post_id=in.(%s)&user_id=eq.%s&select=post_id", strings.Join(postIDStrings, ","), userID.String())
+
+	data, err := r.makeRequest("GET", "post_shares", query, nil)
+	if err != nil {
+		return make(map[uuid.UUID]bool), nil // Return empty map on error
+	}
+
+	var shares []map[string]interface{}
+	if err := json.Unmarshal(data, &shares); err != nil {
+		return make(map[uuid.UUID]bool), nil
+	}
+
+	sharedMap := make(map[uuid.UUID]bool)
+	for _, share := range shares {
+		if postIDStr, ok := share["post_id"].(string); ok {
+			if postID, err := uuid.Parse(postIDStr); err == nil {
+				sharedMap[postID] = true
+			}
+		}
+	}
+
+	return sharedMap, nil
 }
 
 // SavePost bookmarks a post
 func (r *SupabasePostRepository) SavePost(ctx context.Context, postID, userID uuid.UUID, collection string) error {
 	if collection == "" {
-		collection = "Saved"
+		collection = models.DefaultSavedCollection
+	}
+
+	// Saving into a new collection creates it
+	if collection != models.DefaultSavedCollection {
+		if err := r.CreateSavedCollection(ctx, userID, collection); err != nil && err != models.ErrCollectionExists {
+			return err
+		}
 	}
 
 	payload := map[string]interface{}{
@@ -1104,12 +1498,28 @@ func (r *SupabasePostRepository) SavePost(ctx context.Context, postID, userID uu
 	return nil
 }
 
-// UnsavePost removes a bookmark
-func (r *SupabasePostRepository) UnsavePost(ctx context.Context, postID, userID uuid.UUID) error {
-	query := fmt.Sprintf("?post_id=eq.%s&user_id=eq.%s", postID.String(), userID.String())
+// UnsavePost removes a bookmark and reports whether there was one. saves_count is only
+// decremented by the row trigger, so a no-op delete leaves it alone.
+func (r *SupabasePostRepository) UnsavePost(ctx context.Context, postID, userID uuid.UUID) (bool, error) {
+	return r.deleteEngagementRow("saved_posts", postID, userID)
+}
 
-	_, err := r.makeRequest("DELETE", "saved_posts", query, nil)
-	return err
+// deleteEngagementRow deletes the user's row for a post from an engagement table and
+// reports whether one existed. makeRequest asks for the deleted rows back, so an empty
+// result means nothing was there.
+func (r *SupabasePostRepository) deleteEngagementRow(table string, postID, userID uuid.UUID) (bool, error) {
+	query := fmt.Sprintf("?post_id=eq.%s&user_id=eq.%s&select=id", postID.String(), userID.String())
+
+	data, err := r.makeRequest("DELETE", table, query, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var deleted []map[string]interface{}
+	if err := json.Unmarshal(data, &deleted); err != nil {
+		return false, fmt.Errorf("failed to decode deleted rows: %w", err)
+	}
+	return len(deleted) > 0, nil
 }
 
 // IsPostSavedByUser checks if a user has saved a post
@@ -1203,7 +1613,7 @@ func (r *SupabasePostRepository) GetSavedPosts(ctx context.Context, userID uuid.
 	query := fmt.Sprintf("?user_id=eq.%s&select=post_id&order=saved_at.desc&limit=%d&offset=%d", userID.String(), limit, offset)
 	if collection != "" {
 		query = fmt.Sprintf("?user_id=eq.%s&collection_name=eq.%s&select=post_id&order=saved_at.desc&limit=%d&offset=%d",
-			userID.String(), collection, limit, offset)
+			userID.String(), url.QueryEscape(collection), limit, offset)
 	}
 
 	data, err := r.makeRequest("GET", "saved_posts", query, nil)
@@ -1228,7 +1638,7 @@ func (r *SupabasePostRepository) GetSavedPosts(ctx context.Context, userID uuid.
 		postIDs[i] = record.PostID.String()
 	}
 
-	postsQuery := fmt.Sprintf("?id=in.(%s)&select=*", strings.Join(postIDs, ","))
+	postsQuery := postQuery(postScopeVisible, fmt.Sprintf("id=in.(%s)&select=*", strings.Join(postIDs, ",")))
 	postsData, err := r.makeRequest("GET", "posts", postsQuery, nil)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get posts: %w", err)
@@ -1245,43 +1655,251 @@ func (r *SupabasePostRepository) GetSavedPosts(ctx context.Context, userID uuid.
 		posts[i].IsSaved = true // All these are saved
 	}
 
-	return posts, len(savedRecords), nil
+	return posts, len(savedRecords), nil
+}
+
+// GetSavedCollections lists the default collection followed by the user's collections
+// in creation order, each with the number of posts saved in it
+func (r *SupabasePostRepository) GetSavedCollections(ctx context.Context, userID uuid.UUID) ([]models.SavedCollection, error) {
+	query := fmt.Sprintf("?user_id=eq.%s&select=name,created_at&order=created_at.asc", userID.String())
+	data, err := r.makeRequest("GET", "saved_collections", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved collections: %w", err)
+	}
+
+	var rows []struct {
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal saved collections: %w", err)
+	}
+
+	query = fmt.Sprintf("?user_id=eq.%s&select=collection_name", userID.String())
+	data, err = r.makeRequest("GET", "saved_posts", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count saved posts: %w", err)
+	}
+
+	var saved []struct {
+		CollectionName *string `json:"collection_name"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal saved posts: %w", err)
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, s := range saved {
+		name := models.DefaultSavedCollection
+		if s.CollectionName != nil && *s.CollectionName != "" {
+			name = *s.CollectionName
+		}
+		if _, seen := counts[name]; !seen {
+			order = append(order, name)
+		}
+		counts[name]++
+	}
+
+	collections := []models.SavedCollection{{
+		Name:      models.DefaultSavedCollection,
+		PostCount: counts[models.DefaultSavedCollection],
+		IsDefault: true,
+	}}
+	listed := map[string]bool{models.DefaultSavedCollection: true}
+	for i := range rows {
+		if listed[rows[i].Name] {
+			continue
+		}
+		listed[rows[i].Name] = true
+		collections = append(collections, models.SavedCollection{
+			Name:      rows[i].Name,
+			PostCount: counts[rows[i].Name],
+			CreatedAt: &rows[i].CreatedAt,
+		})
+	}
+
+	// Names that only exist on saved posts (saved before collections were tracked)
+	for _, name := range order {
+		if !listed[name] {
+			listed[name] = true
+			collections = append(collections, models.SavedCollection{Name: name, PostCount: counts[name]})
+		}
+	}
+
+	return collections, nil
+}
+
+// SavedCollectionExists reports whether the user has a collection with this name. The
+// default collection always exists.
+func (r *SupabasePostRepository) SavedCollectionExists(ctx context.Context, userID uuid.UUID, name string) (bool, error) {
+	if name == models.DefaultSavedCollection {
+		return true, nil
+	}
+
+	query := fmt.Sprintf("?user_id=eq.%s&name=eq.%s&select=id&limit=1", userID.String(), url.QueryEscape(name))
+	data, err := r.makeRequest("GET", "saved_collections", query, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to check saved collection: %w", err)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("failed to unmarshal saved collection: %w", err)
+	}
+	if len(rows) > 0 {
+		return true, nil
+	}
+
+	// Collections saved into before they were tracked only exist as names on saved posts
+	query = fmt.Sprintf("?user_id=eq.%s&collection_name=eq.%s&select=id&limit=1", userID.String(), url.QueryEscape(name))
+	data, err = r.makeRequest("GET", "saved_posts", query, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to check saved collection: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("failed to unmarshal saved posts: %w", err)
+	}
+	return len(rows) > 0, nil
+}
+
+// CreateSavedCollection creates an empty collection, returning models.ErrCollectionExists
+// if the user already has one with this name
+func (r *SupabasePostRepository) CreateSavedCollection(ctx context.Context, userID uuid.UUID, name string) error {
+	if name == models.DefaultSavedCollection {
+		return models.ErrCollectionExists
+	}
+
+	payload := map[string]interface{}{
+		"user_id": userID,
+		"name":    name,
+	}
+
+	_, err := r.makeRequest("POST", "saved_collections", "", payload)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return models.ErrCollectionExists
+		}
+		return fmt.Errorf("failed to create saved collection: %w", err)
+	}
+
+	return nil
+}
+
+// RenameSavedCollection renames a collection and moves its saved posts to the new name
+func (r *SupabasePostRepository) RenameSavedCollection(ctx context.Context, userID uuid.UUID, name, newName string) error {
+	if err := r.CreateSavedCollection(ctx, userID, newName); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("?user_id=eq.%s&collection_name=eq.%s", userID.String(), url.QueryEscape(name))
+	if _, err := r.makeRequest("PATCH", "saved_posts", query, map[string]interface{}{"collection_name": newName}); err != nil {
+		return fmt.Errorf("failed to move saved posts: %w", err)
+	}
+
+	query = fmt.Sprintf("?user_id=eq.%s&name=eq.%s", userID.String(), url.QueryEscape(name))
+	if _, err := r.makeRequest("DELETE", "saved_collections", query, nil); err != nil {
+		return fmt.Errorf("failed to remove old saved collection: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSavedCollection deletes a collection. Its posts are unsaved when unsave is set,
+// otherwise they move to the default collection.
+func (r *SupabasePostRepository) DeleteSavedCollection(ctx context.Context, userID uuid.UUID, name string, unsave bool) error {
+	query := fmt.Sprintf("?user_id=eq.%s&collection_name=eq.%s", userID.String(), url.QueryEscape(name))
+	var err error
+	if unsave {
+		_, err = r.makeRequest("DELETE", "saved_posts", query, nil)
+	} else {
+		_, err = r.makeRequest("PATCH", "saved_posts", query, map[string]interface{}{"collection_name": models.DefaultSavedCollection})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update saved posts: %w", err)
+	}
+
+	query = fmt.Sprintf("?user_id=eq.%s&name=eq.%s", userID.String(), url.QueryEscape(name))
+	if _, err := r.makeRequest("DELETE", "saved_collections", query, nil); err != nil {
+		return fmt.Errorf("failed to delete saved collection: %w", err)
+	}
+
+	return nil
 }
 
 // IncrementViews increments the view count for a post and records unique view
+// The unique view is buffered and written by FlushPostViews; repeat views by the same
+// user are deduplicated there and by the post_views unique constraint.
 func (r *SupabasePostRepository) IncrementViews(ctx context.Context, postID, userID uuid.UUID) error {
-	// Record unique view (idempotent - unique constraint handles duplicates)
-	viewPayload := map[string]interface{}{
-		"post_id":   postID,
-		"user_id":   userID,
-		"viewed_at": time.Now().Format(time.RFC3339),
+	if userID != uuid.Nil {
+		if full := r.views.add(postID, userID, time.Now()); full {
+			go func() {
+				if _, err := r.FlushPostViews(context.Background()); err != nil {
+					log.Printf("[PostRepo] Early post view flush failed: %v", err)
+				}
+			}()
+		}
 	}
 
-	_, err := r.makeRequest("POST", "post_views", "", viewPayload)
+	// Atomic increment so concurrent views don't overwrite each other
+	_, err := r.makeRequest("POST", "rpc/increment_post_views", "", map[string]interface{}{
+		"p_post_id": postID,
+	})
 	if err != nil {
-		// Ignore unique constraint errors (already viewed)
-		if !strings.Contains(err.Error(), "duplicate key") && !strings.Contains(err.Error(), "unique") {
-			return fmt.Errorf("failed to record post view: %w", err)
-		}
+		return fmt.Errorf("failed to increment views count: %w", err)
 	}
 
-	// Increment views_count in posts table
-	// Get current count first
-	post, err := r.GetPostByID(ctx, postID)
-	if err != nil {
-		return fmt.Errorf("failed to get post: %w", err)
+	return nil
+}
+
+// FlushPostViews writes all buffered unique views in one batch and returns how many
+// were new. On failure the views are put back to be retried on the next flush.
+func (r *SupabasePostRepository) FlushPostViews(ctx context.Context) (int, error) {
+	r.flushing.Lock()
+	defer r.flushing.Unlock()
+
+	buffered := r.views.drain()
+	if len(buffered) == 0 {
+		return 0, nil
 	}
 
-	updates := map[string]interface{}{
-		"views_count": post.ViewsCount + 1,
+	batch := make([]map[string]interface{}, 0, len(buffered))
+	for key, viewedAt := range buffered {
+		batch = append(batch, map[string]interface{}{
+			"post_id":   key.PostID,
+			"user_id":   key.UserID,
+			"viewed_at": viewedAt.UTC().Format(time.RFC3339),
+		})
 	}
 
-	query := fmt.Sprintf("?id=eq.%s", postID.String())
-	_, err = r.makeRequest("PATCH", "posts", query, updates)
+	data, err := r.makeRequest("POST", "rpc/record_post_views", "", map[string]interface{}{
+		"p_views": batch,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to increment views count: %w", err)
+		r.views.restore(buffered)
+		return 0, fmt.Errorf("failed to record post views: %w", err)
+	}
+
+	var inserted int
+	if err := json.Unmarshal(data, &inserted); err != nil {
+		return 0, fmt.Errorf("failed to decode post view flush result: %w", err)
+	}
+	return inserted, nil
+}
+
+// RecordFeedRanking logs which ranking variant ordered a feed page, for comparing
+// experiments against control
+func (r *SupabasePostRepository) RecordFeedRanking(ctx context.Context, userID uuid.UUID, feed, variant string, postIDs []uuid.UUID) error {
+	payload := map[string]interface{}{
+		"user_id":  userID,
+		"feed":     feed,
+		"variant":  variant,
+		"post_ids": postIDs,
 	}
 
+	if _, err := r.makeRequest("POST", "feed_ranking_exposures", "", payload); err != nil {
+		return fmt.Errorf("failed to record feed ranking: %w", err)
+	}
 	return nil
 }
 
@@ -1356,19 +1974,19 @@ func (r *SupabasePostRepository) GetProfileVisitsFromPost(ctx context.Context, p
 }
 
 // GetHashtagFeed retrieves posts with a specific hashtag
-func (r *SupabasePostRepository) GetHashtagFeed(ctx context.Context, hashtag string, limit, offset int) ([]models.Post, int, error) {
+func (r *SupabasePostRepository) GetHashtagFeed(ctx context.Context, hashtag string, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
 	// First, get the hashtag ID
-	hashtagQuery := fmt.Sprintf("?tag=eq.%s&select=id", hashtag)
+	hashtagQuery := fmt.Sprintf("?tag=eq.%s&select=id", url.QueryEscape(normalizeHashtag(hashtag)))
 	hashtagData, err := r.makeRequest("GET", "hashtags", hashtagQuery, nil)
 	if err != nil {
-		return []models.Post{}, 0, nil
+		return []models.Post{}, models.Page{}, nil
 	}
 
 	var hashtags []struct {
 		ID uuid.UUID `json:"id"`
 	}
 	if err := json.Unmarshal(hashtagData, &hashtags); err != nil || len(hashtags) == 0 {
-		return []models.Post{}, 0, nil
+		return []models.Post{}, models.Page{}, nil
 	}
 
 	hashtagID := hashtags[0].ID
@@ -1377,18 +1995,18 @@ func (r *SupabasePostRepository) GetHashtagFeed(ctx context.Context, hashtag str
 	postHashtagQuery := fmt.Sprintf("?hashtag_id=eq.%s&select=post_id", hashtagID.String())
 	postHashtagData, err := r.makeRequest("GET", "post_hashtags", postHashtagQuery, nil)
 	if err != nil {
-		return []models.Post{}, 0, nil
+		return []models.Post{}, models.Page{}, nil
 	}
 
 	var postHashtags []struct {
 		PostID uuid.UUID `json:"post_id"`
 	}
 	if err := json.Unmarshal(postHashtagData, &postHashtags); err != nil {
-		return []models.Post{}, 0, nil
+		return []models.Post{}, models.Page{}, nil
 	}
 
 	if len(postHashtags) == 0 {
-		return []models.Post{}, 0, nil
+		return []models.Post{}, models.Page{}, nil
 	}
 
 	// Get the posts
@@ -1397,17 +2015,22 @@ func (r *SupabasePostRepository) GetHashtagFeed(ctx context.Context, hashtag str
 		postIDs[i] = ph.PostID.String()
 	}
 
-	postsQuery := fmt.Sprintf("?id=in.(%s)&is_published=eq.true&deleted_at=is.null&order=published_at.desc&limit=%d&offset=%d",
-		strings.Join(postIDs, ","), limit, offset)
+	idFilter := fmt.Sprintf("id=in.(%s)", strings.Join(postIDs, ","))
+	postsQuery := postQuery(postScopeVisible, fmt.Sprintf("%s&order=published_at.desc&limit=%d&offset=%d",
+		idFilter, limit+1, offset))
 
 	postsData, err := r.makeRequest("GET", "posts", postsQuery, nil)
 	if err != nil {
-		return []models.Post{}, 0, fmt.Errorf("failed to get posts: %w", err)
+		return []models.Post{}, models.Page{}, fmt.Errorf("failed to get posts: %w", err)
 	}
 
 	posts, err := r.parsePostsFromJSON(postsData)
 	if err != nil {
-		return []models.Post{}, 0, nil
+		return []models.Post{}, models.Page{}, nil
+	}
+	posts, page := models.LookaheadPage(posts, limit)
+	if withCount {
+		page = r.countedPage(page, postScopeVisible, idFilter, offset, limit)
 	}
 
 	// Load authors
@@ -1415,26 +2038,31 @@ func (r *SupabasePostRepository) GetHashtagFeed(ctx context.Context, hashtag str
 		r.loadPostAuthor(ctx, &posts[i])
 	}
 
-	return posts, len(posts), nil
+	return posts, page, nil
 }
 
 // SearchPosts searches posts by content
-func (r *SupabasePostRepository) SearchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int) ([]models.Post, int, error) {
+func (r *SupabasePostRepository) SearchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
 	// Use full-text search
 	// For now, simple LIKE search
-	searchQuery := fmt.Sprintf(
-		"?content=ilike.*%s*&is_published=eq.true&deleted_at=is.null&order=published_at.desc&limit=%d&offset=%d",
-		query, limit, offset,
-	)
+	contentFilter := fmt.Sprintf("content=ilike.*%s*", url.QueryEscape(query))
+	searchQuery := postQuery(postScopeVisible, fmt.Sprintf(
+		"%s&order=published_at.desc&limit=%d&offset=%d",
+		contentFilter, limit+1, offset,
+	))
 
 	data, err := r.makeRequest("GET", "posts", searchQuery, nil)
 	if err != nil {
-		return []models.Post{}, 0, fmt.Errorf("failed to search posts: %w", err)
+		return []models.Post{}, models.Page{}, fmt.Errorf("failed to search posts: %w", err)
 	}
 
 	posts, err := r.parsePostsFromJSON(data)
 	if err != nil {
-		return []models.Post{}, 0, nil
+		return []models.Post{}, models.Page{}, nil
+	}
+	posts, page := models.LookaheadPage(posts, limit)
+	if withCount {
+		page = r.countedPage(page, postScopeVisible, contentFilter, offset, limit)
 	}
 
 	// Load authors
@@ -1442,7 +2070,14 @@ func (r *SupabasePostRepository) SearchPosts(ctx context.Context, query string,
 		r.loadPostAuthor(ctx, &posts[i])
 	}
 
-	return posts, len(posts), nil
+	// Filter blocked/restricted content if user is authenticated
+	if userID != uuid.Nil {
+		fetched := len(posts)
+		posts = r.filterBlockedRestrictedContent(ctx, posts, userID)
+		page = page.Filtered(fetched-len(posts), len(posts))
+	}
+
+	return posts, page, nil
 }
 
 // ExtractAndCreateHashtags extracts hashtags from content and creates associations
@@ -1468,40 +2103,63 @@ func (r *SupabasePostRepository) ExtractAndCreateHashtags(ctx context.Context, p
 	return nil
 }
 
-// ExtractAndCreateMentions extracts @mentions and creates associations
-func (r *SupabasePostRepository) ExtractAndCreateMentions(ctx context.Context, postID uuid.UUID, content string) error {
-	mentions := extractMentions(content)
-
-	for _, username := range mentions {
-		// Get user ID by username
-		userQuery := fmt.Sprintf("?username=eq.%s&select=id", username)
-		userData, err := r.makeRequest("GET", "users", userQuery, nil)
-		if err != nil {
-			continue
-		}
-
-		var users []struct {
-			ID uuid.UUID `json:"id"`
-		}
-		if err := json.Unmarshal(userData, &users); err != nil || len(users) == 0 {
-			continue
-		}
+// ExtractAndCreateMentions extracts @mentions, creates associations and returns the
+// mentioned user IDs (each user once)
+func (r *SupabasePostRepository) ExtractAndCreateMentions(ctx context.Context, postID uuid.UUID, content string) ([]uuid.UUID, error) {
+	userIDs, err := r.ResolveMentions(ctx, content)
+	if err != nil {
+		return nil, err
+	}
 
+	for _, userID := range userIDs {
 		// Create mention
 		payload := map[string]interface{}{
 			"post_id":           postID,
-			"mentioned_user_id": users[0].ID,
+			"mentioned_user_id": userID,
 		}
 
 		r.makeRequest("POST", "post_mentions", "", payload)
 	}
 
-	return nil
+	return userIDs, nil
+}
+
+// ResolveMentions returns the IDs of existing users @mentioned in content, each once.
+// Unknown usernames are ignored.
+func (r *SupabasePostRepository) ResolveMentions(ctx context.Context, content string) ([]uuid.UUID, error) {
+	mentions := extractMentions(content)
+	if len(mentions) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	userQuery := fmt.Sprintf("?username=in.(%s)&select=id", strings.Join(mentions, ","))
+	userData, err := r.makeRequest("GET", "users", userQuery, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentions: %w", err)
+	}
+
+	var users []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(userData, &users); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mentioned users: %w", err)
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(users))
+	seen := make(map[uuid.UUID]bool, len(users))
+	for _, user := range users {
+		if !seen[user.ID] {
+			seen[user.ID] = true
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+
+	return userIDs, nil
 }
 
 // GetHashtagByTag retrieves a hashtag by its tag
 func (r *SupabasePostRepository) GetHashtagByTag(ctx context.Context, tag string) (*HashtagInfo, error) {
-	query := fmt.Sprintf("?tag=eq.%s&select=*", tag)
+	query := fmt.Sprintf("?tag=eq.%s&select=*", url.QueryEscape(normalizeHashtag(tag)))
 
 	data, err := r.makeRequest("GET", "hashtags", query, nil)
 	if err != nil {
@@ -1537,6 +2195,67 @@ func (r *SupabasePostRepository) GetTrendingHashtags(ctx context.Context, limit
 	return hashtags, nil
 }
 
+// SearchHashtags returns a page of the hashtags containing query, most used first
+func (r *SupabasePostRepository) SearchHashtags(ctx context.Context, query string, limit, offset int) ([]HashtagInfo, error) {
+	tag := normalizeHashtag(query)
+	if tag == "" {
+		return []HashtagInfo{}, nil
+	}
+
+	searchQuery := fmt.Sprintf("?tag=ilike.*%s*&order=posts_count.desc,trending_score.desc,id.asc&limit=%d&offset=%d",
+		url.QueryEscape(tag), limit, offset)
+
+	data, err := r.makeRequest("GET", "hashtags", searchQuery, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search hashtags: %w", err)
+	}
+
+	var hashtags []HashtagInfo
+	if err := json.Unmarshal(data, &hashtags); err != nil {
+		return nil, fmt.Errorf("failed to parse hashtags: %w", err)
+	}
+	return hashtags, nil
+}
+
+// SuggestHashtags returns up to limit hashtags starting with prefix, most used first
+func (r *SupabasePostRepository) SuggestHashtags(ctx context.Context, prefix string, limit int) ([]HashtagInfo, error) {
+	tag := normalizeHashtag(prefix)
+	if tag == "" {
+		return []HashtagInfo{}, nil
+	}
+
+	query := fmt.Sprintf("?tag=ilike.%s*&select=id,tag,posts_count&order=posts_count.desc,trending_score.desc&limit=%d",
+		url.QueryEscape(tag), limit)
+
+	data, err := r.makeRequest("GET", "hashtags", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest hashtags: %w", err)
+	}
+
+	var hashtags []HashtagInfo
+	if err := json.Unmarshal(data, &hashtags); err != nil {
+		return nil, fmt.Errorf("failed to parse hashtags: %w", err)
+	}
+	return hashtags, nil
+}
+
+// RecomputeTrendingHashtags recomputes time-decayed trending scores and live post counts
+// for active hashtags and returns how many were updated
+func (r *SupabasePostRepository) RecomputeTrendingHashtags(ctx context.Context, halfLife, window time.Duration) (int, error) {
+	data, err := r.makeRequest("POST", "rpc/recompute_hashtag_trending_scores", "", map[string]interface{}{
+		"p_half_life_hours": halfLife.Hours(),
+		"p_window_hours":    int(window.Hours()),
+	})
+	if err != nil {
+		return 0, err
+	}
+	var updated int
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return 0, fmt.Errorf("failed to decode trending recompute result: %w", err)
+	}
+	return updated, nil
+}
+
 // FollowHashtag allows a user to follow a hashtag
 func (r *SupabasePostRepository) FollowHashtag(ctx context.Context, hashtagID, userID uuid.UUID) error {
 	payload := map[string]interface{}{
@@ -1674,17 +2393,49 @@ func (r *SupabasePostRepository) batchLoadPolls(ctx context.Context, posts []mod
 	for i, id := range pollPostIDs {
 		postIDStrings[i] = id.String()
 	}
-	pollQuery := fmt.Sprintf("?post_id=in.(%s)&select=*", strings.Join(postIDStrings, ","))
 
-	pollData, err := r.makeRequest("GET", "polls", pollQuery, nil)
+	polls, _, err := r.loadPolls(ctx, fmt.Sprintf("post_id=in.(%s)", strings.Join(postIDStrings, ",")), userID)
+	if err != nil {
+		return err
+	}
+
+	// Create map of polls by post_id (for assigning to posts)
+	pollMapByPostID := make(map[uuid.UUID]*models.Poll)
+	for i := range polls {
+		pollMapByPostID[polls[i].PostID] = &polls[i]
+	}
+
+	// Assign polls to posts (using post ID)
+	assignedCount := 0
+	for i := range posts {
+		if poll, ok := pollMapByPostID[posts[i].ID]; ok {
+			// Ensure Options is always initialized (even if empty)
+			if poll.Options == nil {
+				poll.Options = []models.PollOption{}
+			}
+			posts[i].Poll = poll
+			assignedCount++
+			fmt.Printf("[DEBUG] Assigned poll %s to post %s with %d options\n", poll.ID.String(), posts[i].ID.String(), len(poll.Options))
+		}
+	}
+	fmt.Printf("[DEBUG] Assigned %d polls to posts (out of %d poll posts)\n", assignedCount, len(pollPostIDs))
+
+	return nil
+}
+
+// loadPolls fetches the polls matching a PostgREST filter (e.g. "id=in.(...)") with their
+// options and, when userID is set, the user's votes, using one query for each. The returned
+// map holds every option the user voted for, keyed by poll ID.
+func (r *SupabasePostRepository) loadPolls(ctx context.Context, filter string, userID uuid.UUID) ([]models.Poll, map[uuid.UUID][]uuid.UUID, error) {
+	pollData, err := r.makeRequest("GET", "polls", "?"+filter+"&select=*", nil)
 	if err != nil {
-		return fmt.Errorf("failed to get polls: %w", err)
+		return nil, nil, fmt.Errorf("failed to get polls: %w", err)
 	}
 
 	// Parse polls with custom timestamp handling
 	var pollsData []map[string]interface{}
 	if err := json.Unmarshal(pollData, &pollsData); err != nil {
-		return fmt.Errorf("failed to unmarshal polls: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal polls: %w", err)
 	}
 
 	// Parse timestamps that might not have timezone
@@ -1791,12 +2542,9 @@ func (r *SupabasePostRepository) batchLoadPolls(ctx context.Context, posts []mod
 		poll.ClosedAt = parseTime(pollData["closed_at"])
 	}
 
-	// Create map of polls by post_id (for assigning to posts)
-	pollMapByPostID := make(map[uuid.UUID]*models.Poll)
-	// Also create map by poll ID (for assigning options)
+	// Map by poll ID (for assigning options and votes)
 	pollMapByPollID := make(map[uuid.UUID]*models.Poll)
 	for i := range polls {
-		pollMapByPostID[polls[i].PostID] = &polls[i]
 		pollMapByPollID[polls[i].ID] = &polls[i]
 	}
 
@@ -1872,6 +2620,7 @@ func (r *SupabasePostRepository) batchLoadPolls(ctx context.Context, posts []mod
 	}
 
 	// Load user votes for polls (if userID is provided)
+	userVotes := make(map[uuid.UUID][]uuid.UUID)
 	if len(pollIDs) > 0 && userID != uuid.Nil {
 		pollIDStrings := make([]string, len(pollIDs))
 		for i, id := range pollIDs {
@@ -1886,15 +2635,15 @@ func (r *SupabasePostRepository) batchLoadPolls(ctx context.Context, posts []mod
 				OptionID uuid.UUID `json:"option_id"`
 			}
 			if err := json.Unmarshal(votesData, &votes); err == nil {
-				// Create map of votes by poll_id
-				votesMap := make(map[uuid.UUID]uuid.UUID)
+				// Group votes by poll_id (several per poll on multiple-vote polls)
 				for _, vote := range votes {
-					votesMap[vote.PollID] = vote.OptionID
+					userVotes[vote.PollID] = append(userVotes[vote.PollID], vote.OptionID)
 				}
 
 				// Assign user votes to polls
-				for pollID, optionID := range votesMap {
+				for pollID, optionIDs := range userVotes {
 					if poll, ok := pollMapByPollID[pollID]; ok {
+						optionID := optionIDs[0]
 						poll.UserVote = &optionID
 						fmt.Printf("[DEBUG] Assigned user vote %s to poll %s\n", optionID.String(), pollID.String())
 					}
@@ -1903,22 +2652,7 @@ func (r *SupabasePostRepository) batchLoadPolls(ctx context.Context, posts []mod
 		}
 	}
 
-	// Assign polls to posts (using post ID)
-	assignedCount := 0
-	for i := range posts {
-		if poll, ok := pollMapByPostID[posts[i].ID]; ok {
-			// Ensure Options is always initialized (even if empty)
-			if poll.Options == nil {
-				poll.Options = []models.PollOption{}
-			}
-			posts[i].Poll = poll
-			assignedCount++
-			fmt.Printf("[DEBUG] Assigned poll %s to post %s with %d options\n", poll.ID.String(), posts[i].ID.String(), len(poll.Options))
-		}
-	}
-	fmt.Printf("[DEBUG] Assigned %d polls to posts (out of %d poll posts)\n", assignedCount, len(pollPostIDs))
-
-	return nil
+	return polls, userVotes, nil
 }
 
 // batchLoadArticles loads article data for multiple posts
@@ -2194,11 +2928,11 @@ func (r *SupabasePostRepository) loadArticleData(ctx context.Context, post *mode
 
 // getOrCreateHashtag gets or creates a hashtag and returns its ID
 func (r *SupabasePostRepository) getOrCreateHashtag(ctx context.Context, tag string) (uuid.UUID, error) {
-	// Normalize tag (lowercase, remove #)
-	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	// Normalize tag (case-fold, NFC, remove #)
+	tag = normalizeHashtag(tag)
 
 	// Try to get existing
-	query := fmt.Sprintf("?tag=eq.%s&select=id", tag)
+	query := fmt.Sprintf("?tag=eq.%s&select=id", url.QueryEscape(tag))
 	data, err := r.makeRequest("GET", "hashtags", query, nil)
 	if err == nil {
 		var hashtags []struct {
@@ -2235,18 +2969,36 @@ func (r *SupabasePostRepository) getOrCreateHashtag(ctx context.Context, tag str
 	return newID, nil
 }
 
-// extractHashtags extracts hashtags from text
+// hashtagPattern matches #tags made of Unicode letters, combining marks, digits and
+// underscores, so #café and #日本語 are captured while punctuation and emoji end the tag
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{M}\p{N}_]+)`)
+
+// maxHashtagLength matches the hashtags.tag column (VARCHAR(100))
+const maxHashtagLength = 100
+
+// normalizeHashtag returns the canonical form a tag is stored and looked up under:
+// without the leading #, case-folded and NFC-normalized (so a precomposed and a
+// decomposed "café" are the same tag), truncated to maxHashtagLength characters
+func normalizeHashtag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	tag = norm.NFC.String(cases.Fold().String(norm.NFC.String(tag)))
+	if runes := []rune(tag); len(runes) > maxHashtagLength {
+		tag = string(runes[:maxHashtagLength])
+	}
+	return tag
+}
+
+// extractHashtags extracts normalized hashtags from text
 func extractHashtags(text string) []string {
-	re := regexp.MustCompile(`#([a-zA-Z0-9_]+)`)
-	matches := re.FindAllStringSubmatch(text, -1)
+	matches := hashtagPattern.FindAllStringSubmatch(text, -1)
 
 	hashtags := make([]string, 0, len(matches))
 	seen := make(map[string]bool)
 
 	for _, match := range matches {
 		if len(match) > 1 {
-			tag := strings.ToLower(match[1])
-			if !seen[tag] {
+			tag := normalizeHashtag(match[1])
+			if tag != "" && !seen[tag] {
 				hashtags = append(hashtags, tag)
 				seen[tag] = true
 			}
@@ -2431,7 +3183,7 @@ func (r *SupabasePostRepository) GetUserDeletedPosts(ctx context.Context, userID
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 	thirtyDaysAgoStr := url.QueryEscape(thirtyDaysAgo.Format(time.RFC3339))
 
-	// Query posts that are deleted and within last 30 days
+	// Query posts that are deleted and within last 30 days (deliberately bypasses postQuery)
 	query := fmt.Sprintf(
 		"?user_id=eq.%s&deleted_at=not.is.null&deleted_at=gte.%s&order=deleted_at.desc&limit=%d&offset=%d",
 		userID.String(), thirtyDaysAgoStr, limit, offset,
@@ -2484,11 +3236,69 @@ func (r *SupabasePostRepository) GetUserDeletedPosts(ctx context.Context, userID
 	return posts, total, nil
 }
 
+// GetPurgeablePosts returns posts soft-deleted before deletedBefore, oldest first. Only
+// ID, author and media fields are populated.
+func (r *SupabasePostRepository) GetPurgeablePosts(ctx context.Context, deletedBefore time.Time, limit int) ([]models.Post, error) {
+	// Deleted posts are invisible to every postQuery scope, so query the table directly
+	query := fmt.Sprintf(
+		"?deleted_at=not.is.null&deleted_at=lt.%s&select=id,user_id,media_urls,media_variants&order=deleted_at.asc&limit=%d",
+		url.QueryEscape(deletedBefore.UTC().Format(time.RFC3339)), limit,
+	)
+
+	data, err := r.makeRequest("GET", "posts", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purgeable posts: %w", err)
+	}
+
+	var rows []struct {
+		ID            uuid.UUID   `json:"id"`
+		UserID        uuid.UUID   `json:"user_id"`
+		MediaURLs     []string    `json:"media_urls"`
+		MediaVariants interface{} `json:"media_variants"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse purgeable posts: %w", err)
+	}
+
+	posts := make([]models.Post, len(rows))
+	for i, row := range rows {
+		posts[i] = models.Post{
+			ID:            row.ID,
+			UserID:        row.UserID,
+			MediaURLs:     row.MediaURLs,
+			MediaVariants: parseMediaVariants(row.MediaVariants),
+		}
+	}
+	return posts, nil
+}
+
+// PurgePosts permanently deletes soft-deleted posts and their engagement rows in one
+// transaction, returning how many were deleted
+func (r *SupabasePostRepository) PurgePosts(ctx context.Context, postIDs []uuid.UUID) (int, error) {
+	if len(postIDs) == 0 {
+		return 0, nil
+	}
+
+	data, err := r.makeRequest("POST", "rpc/purge_deleted_posts", "", map[string]interface{}{
+		"p_post_ids": postIDs,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge posts: %w", err)
+	}
+
+	var purged int
+	if err := json.Unmarshal(data, &purged); err != nil {
+		return 0, fmt.Errorf("failed to decode purge result: %w", err)
+	}
+	return purged, nil
+}
+
 // GetUserSharedPosts retrieves all posts/articles that a user has shared
 // postType can be "post", "article", or "" for all
+// Shares with a repost comment are returned as "quote" posts embedding the original
 func (r *SupabasePostRepository) GetUserSharedPosts(ctx context.Context, userID uuid.UUID, postType string, limit, offset int) ([]models.Post, int, error) {
 	// Query post_shares to get post IDs, then get full post data
-	query := fmt.Sprintf("?user_id=eq.%s&order=created_at.desc&select=post_id,created_at&limit=%d&offset=%d",
+	query := fmt.Sprintf("?user_id=eq.%s&order=created_at.desc&select=id,post_id,repost_comment,created_at&limit=%d&offset=%d",
 		userID.String(), limit*2, offset) // Get more to account for filtering
 
 	data, err := r.makeRequest("GET", "post_shares", query, nil)
@@ -2497,8 +3307,10 @@ func (r *SupabasePostRepository) GetUserSharedPosts(ctx context.Context, userID
 	}
 
 	var shares []struct {
-		PostID    string `json:"post_id"`
-		CreatedAt string `json:"created_at"`
+		ID            uuid.UUID   `json:"id"`
+		PostID        uuid.UUID   `json:"post_id"`
+		RepostComment *string     `json:"repost_comment"`
+		CreatedAt     interface{} `json:"created_at"`
 	}
 	if err := json.Unmarshal(data, &shares); err != nil {
 		return nil, 0, fmt.Errorf("failed to parse shared posts: %w", err)
@@ -2508,34 +3320,13 @@ func (r *SupabasePostRepository) GetUserSharedPosts(ctx context.Context, userID
 		return []models.Post{}, 0, nil
 	}
 
-	// Get post IDs
-	postIDs := make([]uuid.UUID, 0, len(shares))
-	for _, share := range shares {
-		postID, err := uuid.Parse(share.PostID)
-		if err != nil {
-			continue
-		}
-		postIDs = append(postIDs, postID)
-	}
-
-	if len(postIDs) == 0 {
-		return []models.Post{}, 0, nil
-	}
-
-	// Build query to get posts
-	postIDsStr := make([]string, len(postIDs))
-	for i, id := range postIDs {
-		postIDsStr[i] = id.String()
-	}
-
-	postsQuery := fmt.Sprintf("?id=in.(%s)&is_published=eq.true&deleted_at=is.null&order=published_at.desc&limit=%d&offset=%d",
-		strings.Join(postIDsStr, ","), limit*2, offset)
-
-	// Filter by post_type if specified
-	if postType != "" {
-		postsQuery += fmt.Sprintf("&post_type=eq.%s", postType)
+	// Load the originals; deleted and unpublished ones are excluded by the scope
+	postIDsStr := make([]string, len(shares))
+	for i, share := range shares {
+		postIDsStr[i] = share.PostID.String()
 	}
 
+	postsQuery := postQuery(postScopeVisible, fmt.Sprintf("id=in.(%s)&select=*", strings.Join(postIDsStr, ",")))
 	postsData, err := r.makeRequest("GET", "posts", postsQuery, nil)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get posts: %w", err)
@@ -2546,85 +3337,94 @@ func (r *SupabasePostRepository) GetUserSharedPosts(ctx context.Context, userID
 		return nil, 0, fmt.Errorf("failed to parse posts: %w", err)
 	}
 
-	// Filter to only include posts that were actually shared (maintain order from shares)
-	// Create a map of post IDs to share order
-	shareOrder := make(map[string]int)
-	for i, share := range shares {
-		shareOrder[share.PostID] = i
+	originals := make(map[uuid.UUID]*models.Post, len(posts))
+	for i := range posts {
+		originals[posts[i].ID] = &posts[i]
 	}
 
-	// Sort posts by share order
-	sortedPosts := make([]models.Post, 0, len(posts))
-	for _, share := range shares {
-		for _, post := range posts {
-			if post.ID.String() == share.PostID {
-				// Only include if post type matches (or no filter)
-				if postType == "" || post.PostType == postType {
-					sortedPosts = append(sortedPosts, post)
-					if len(sortedPosts) >= limit {
-						break
-					}
-				}
-			}
+	// Originals the user restricted are shown as unavailable inside quotes
+	restricted := make(map[uuid.UUID]bool)
+	if restrictedIDs, err := r.GetRestrictedPostIDs(ctx, userID); err == nil {
+		for _, id := range restrictedIDs {
+			restricted[id] = true
 		}
+	}
+
+	// Build entries in share order: plain shares are the original post, shares with a
+	// comment are quote posts embedding the original
+	sortedPosts := make([]models.Post, 0, limit)
+	authorIDs := []uuid.UUID{userID}
+	for _, share := range shares {
 		if len(sortedPosts) >= limit {
 			break
 		}
-	}
 
-	// Limit to requested amount
-	if len(sortedPosts) > limit {
-		sortedPosts = sortedPosts[:limit]
-	}
+		original, available := originals[share.PostID]
+		if available && restricted[share.PostID] {
+			available = false
+		}
 
-	// Load authors
-	for i := range sortedPosts {
-		r.loadPostAuthor(ctx, &sortedPosts[i])
-	}
+		if share.RepostComment == nil || strings.TrimSpace(*share.RepostComment) == "" {
+			if available && (postType == "" || original.PostType == postType) {
+				sortedPosts = append(sortedPosts, *original)
+				authorIDs = append(authorIDs, original.UserID)
+			}
+			continue
+		}
 
-	// Get total count
-	countQuery := fmt.Sprintf("?user_id=eq.%s&select=post_id", userID.String())
-	countData, err := r.makeRequest("GET", "post_shares", countQuery, nil)
-	total := len(sortedPosts) // Default to current count
+		// Quotes are filtered by the type of the post they quote
+		if postType != "" && (!available || original.PostType != postType) {
+			continue
+		}
 
-	if err == nil && countData != nil {
-		var allShares []struct {
-			PostID string `json:"post_id"`
+		quote := models.Post{
+			ID:          share.ID,
+			UserID:      userID,
+			PostType:    "quote",
+			Content:     *share.RepostComment,
+			Visibility:  "public",
+			IsPublished: true,
+			QuotedPost:  &models.QuotedPost{ID: share.PostID, Unavailable: !available},
 		}
-		if err := json.Unmarshal(countData, &allShares); err == nil {
-			// Get unique post IDs
-			uniquePostIDs := make(map[string]bool)
-			for _, share := range allShares {
-				uniquePostIDs[share.PostID] = true
-			}
+		if createdAt := parseTime(share.CreatedAt); createdAt != nil {
+			quote.CreatedAt = *createdAt
+			quote.UpdatedAt = *createdAt
+			quote.PublishedAt = createdAt
+		}
+		if available {
+			authorID, createdAt := original.UserID, original.CreatedAt
+			quote.QuotedPost.UserID = &authorID
+			quote.QuotedPost.PostType = original.PostType
+			quote.QuotedPost.Content = original.Content
+			quote.QuotedPost.MediaURLs = original.MediaURLs
+			quote.QuotedPost.MediaTypes = original.MediaTypes
+			quote.QuotedPost.CreatedAt = &createdAt
+			authorIDs = append(authorIDs, original.UserID)
+		}
+		sortedPosts = append(sortedPosts, quote)
+	}
 
-			// If filtering by post type, we need to check each post
-			if postType != "" {
-				// Fetch all shared post IDs to check their types
-				allPostIDsStr := make([]string, 0, len(uniquePostIDs))
-				for postID := range uniquePostIDs {
-					allPostIDsStr = append(allPostIDsStr, postID)
-				}
+	// Load authors for entries and quoted originals in one request
+	authors, _ := r.batchLoadAuthors(ctx, authorIDs)
+	for i := range sortedPosts {
+		sortedPosts[i].Author = authors[sortedPosts[i].UserID]
+		if quoted := sortedPosts[i].QuotedPost; quoted != nil && quoted.UserID != nil {
+			quoted.Author = authors[*quoted.UserID]
+		}
+	}
 
-				if len(allPostIDsStr) > 0 {
-					typeQuery := fmt.Sprintf("?id=in.(%s)&post_type=eq.%s&select=id&limit=1",
-						strings.Join(allPostIDsStr, ","), postType)
-					typeData, err := r.makeRequest("GET", "posts", typeQuery, nil)
-					if err == nil && typeData != nil {
-						var typedPosts []map[string]interface{}
-						if err := json.Unmarshal(typeData, &typedPosts); err == nil {
-							total = len(typedPosts)
-						}
-					}
-				}
-			} else {
-				total = len(uniquePostIDs)
+	// Count every share; with a type filter only the current page can be counted cheaply
+	total := offset + len(sortedPosts)
+	if postType == "" {
+		countQuery := fmt.Sprintf("?user_id=eq.%s&select=id", userID.String())
+		if countData, err := r.makeRequest("GET", "post_shares", countQuery, nil); err == nil {
+			var allShares []map[string]interface{}
+			if err := json.Unmarshal(countData, &allShares); err == nil {
+				total = len(allShares)
 			}
 		}
 	} else if len(sortedPosts) == limit {
 		total = offset + limit + 1
-	} else {
-		total = offset + len(sortedPosts)
 	}
 
 	return sortedPosts, total, nil
@@ -2634,7 +3434,7 @@ func (r *SupabasePostRepository) GetUserSharedPosts(ctx context.Context, userID
 func (r *SupabasePostRepository) GetPostsCountSince(ctx context.Context, since time.Time) (int, error) {
 	// Format time for Supabase query
 	sinceStr := since.Format(time.RFC3339)
-	query := fmt.Sprintf("?is_published=eq.true&deleted_at=is.null&visibility=eq.public&post_type=eq.post&created_at=gte.%s&select=id", url.QueryEscape(sinceStr))
+	query := postQuery(postScopePublic, fmt.Sprintf("post_type=eq.post&created_at=gte.%s&select=id", url.QueryEscape(sinceStr)))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
@@ -2653,7 +3453,7 @@ func (r *SupabasePostRepository) GetPostsCountSince(ctx context.Context, since t
 func (r *SupabasePostRepository) GetPollsCountSince(ctx context.Context, since time.Time) (int, error) {
 	// Format time for Supabase query
 	sinceStr := since.Format(time.RFC3339)
-	query := fmt.Sprintf("?is_published=eq.true&deleted_at=is.null&visibility=eq.public&post_type=eq.poll&created_at=gte.%s&select=id", url.QueryEscape(sinceStr))
+	query := postQuery(postScopePublic, fmt.Sprintf("post_type=eq.poll&created_at=gte.%s&select=id", url.QueryEscape(sinceStr)))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
@@ -2672,7 +3472,7 @@ func (r *SupabasePostRepository) GetPollsCountSince(ctx context.Context, since t
 func (r *SupabasePostRepository) GetArticlesCountSince(ctx context.Context, since time.Time) (int, error) {
 	// Format time for Supabase query
 	sinceStr := since.Format(time.RFC3339)
-	query := fmt.Sprintf("?is_published=eq.true&deleted_at=is.null&visibility=eq.public&post_type=eq.article&created_at=gte.%s&select=id", url.QueryEscape(sinceStr))
+	query := postQuery(postScopePublic, fmt.Sprintf("post_type=eq.article&created_at=gte.%s&select=id", url.QueryEscape(sinceStr)))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
@@ -2690,10 +3490,10 @@ func (r *SupabasePostRepository) GetArticlesCountSince(ctx context.Context, sinc
 // GetPostsSince returns posts created since the given time
 func (r *SupabasePostRepository) GetPostsSince(ctx context.Context, userID uuid.UUID, since time.Time, limit, offset int) ([]models.Post, int, error) {
 	sinceStr := since.Format(time.RFC3339)
-	query := fmt.Sprintf(
-		"?is_published=eq.true&deleted_at=is.null&visibility=eq.public&post_type=eq.post&created_at=gte.%s&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
+	query := postQuery(postScopePublic, fmt.Sprintf(
+		"post_type=eq.post&created_at=gte.%s&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
 		url.QueryEscape(sinceStr), limit, offset,
-	)
+	))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
@@ -2716,11 +3516,11 @@ func (r *SupabasePostRepository) GetPostsSince(ctx context.Context, userID uuid.
 			postIDs[i] = posts[i].ID
 		}
 
-		var likedMap, savedMap map[uuid.UUID]bool
-		var likedErr, savedErr error
+		var likedMap, savedMap, sharedMap map[uuid.UUID]bool
+		var likedErr, savedErr, sharedErr error
 		var wg sync.WaitGroup
 
-		wg.Add(2)
+		wg.Add(3)
 		go func() {
 			defer wg.Done()
 			likedMap, likedErr = r.batchCheckLikes(ctx, postIDs, userID)
@@ -2729,6 +3529,10 @@ func (r *SupabasePostRepository) GetPostsSince(ctx context.Context, userID uuid.
 			defer wg.Done()
 			savedMap, savedErr = r.batchCheckSaves(ctx, postIDs, userID)
 		}()
+		go func() {
+			defer wg.Done()
+			sharedMap, sharedErr = r.batchCheckShares(ctx, postIDs, userID)
+		}()
 		wg.Wait()
 
 		for i := range posts {
@@ -2738,6 +3542,9 @@ func (r *SupabasePostRepository) GetPostsSince(ctx context.Context, userID uuid.
 			if savedErr == nil {
 				posts[i].IsSaved = savedMap[posts[i].ID]
 			}
+			if sharedErr == nil {
+				posts[i].IsShared = sharedMap[posts[i].ID]
+			}
 		}
 	}
 
@@ -2747,10 +3554,10 @@ func (r *SupabasePostRepository) GetPostsSince(ctx context.Context, userID uuid.
 // GetPollsSince returns polls created since the given time
 func (r *SupabasePostRepository) GetPollsSince(ctx context.Context, userID uuid.UUID, since time.Time, limit, offset int) ([]models.Post, int, error) {
 	sinceStr := since.Format(time.RFC3339)
-	query := fmt.Sprintf(
-		"?is_published=eq.true&deleted_at=is.null&visibility=eq.public&post_type=eq.poll&created_at=gte.%s&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
+	query := postQuery(postScopePublic, fmt.Sprintf(
+		"post_type=eq.poll&created_at=gte.%s&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
 		url.QueryEscape(sinceStr), limit, offset,
-	)
+	))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
@@ -2773,11 +3580,11 @@ func (r *SupabasePostRepository) GetPollsSince(ctx context.Context, userID uuid.
 			postIDs[i] = posts[i].ID
 		}
 
-		var likedMap, savedMap map[uuid.UUID]bool
-		var likedErr, savedErr error
+		var likedMap, savedMap, sharedMap map[uuid.UUID]bool
+		var likedErr, savedErr, sharedErr error
 		var wg sync.WaitGroup
 
-		wg.Add(2)
+		wg.Add(3)
 		go func() {
 			defer wg.Done()
 			likedMap, likedErr = r.batchCheckLikes(ctx, postIDs, userID)
@@ -2786,6 +3593,10 @@ func (r *SupabasePostRepository) GetPollsSince(ctx context.Context, userID uuid.
 			defer wg.Done()
 			savedMap, savedErr = r.batchCheckSaves(ctx, postIDs, userID)
 		}()
+		go func() {
+			defer wg.Done()
+			sharedMap, sharedErr = r.batchCheckShares(ctx, postIDs, userID)
+		}()
 		wg.Wait()
 
 		for i := range posts {
@@ -2795,6 +3606,9 @@ func (r *SupabasePostRepository) GetPollsSince(ctx context.Context, userID uuid.
 			if savedErr == nil {
 				posts[i].IsSaved = savedMap[posts[i].ID]
 			}
+			if sharedErr == nil {
+				posts[i].IsShared = sharedMap[posts[i].ID]
+			}
 		}
 	}
 
@@ -2809,10 +3623,10 @@ func (r *SupabasePostRepository) GetPollsSince(ctx context.Context, userID uuid.
 // GetArticlesSince returns articles created since the given time
 func (r *SupabasePostRepository) GetArticlesSince(ctx context.Context, userID uuid.UUID, since time.Time, limit, offset int) ([]models.Post, int, error) {
 	sinceStr := since.Format(time.RFC3339)
-	query := fmt.Sprintf(
-		"?is_published=eq.true&deleted_at=is.null&visibility=eq.public&post_type=eq.article&created_at=gte.%s&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
+	query := postQuery(postScopePublic, fmt.Sprintf(
+		"post_type=eq.article&created_at=gte.%s&order=created_at.desc&limit=%d&offset=%d&select=*,author:user_id(id,username,display_name,profile_picture,is_verified)",
 		url.QueryEscape(sinceStr), limit, offset,
-	)
+	))
 
 	data, err := r.makeRequest("GET", "posts", query, nil)
 	if err != nil {
@@ -2835,11 +3649,11 @@ func (r *SupabasePostRepository) GetArticlesSince(ctx context.Context, userID uu
 			postIDs[i] = posts[i].ID
 		}
 
-		var likedMap, savedMap map[uuid.UUID]bool
-		var likedErr, savedErr error
+		var likedMap, savedMap, sharedMap map[uuid.UUID]bool
+		var likedErr, savedErr, sharedErr error
 		var wg sync.WaitGroup
 
-		wg.Add(2)
+		wg.Add(3)
 		go func() {
 			defer wg.Done()
 			likedMap, likedErr = r.batchCheckLikes(ctx, postIDs, userID)
@@ -2848,6 +3662,10 @@ func (r *SupabasePostRepository) GetArticlesSince(ctx context.Context, userID uu
 			defer wg.Done()
 			savedMap, savedErr = r.batchCheckSaves(ctx, postIDs, userID)
 		}()
+		go func() {
+			defer wg.Done()
+			sharedMap, sharedErr = r.batchCheckShares(ctx, postIDs, userID)
+		}()
 		wg.Wait()
 
 		for i := range posts {
@@ -2857,6 +3675,9 @@ func (r *SupabasePostRepository) GetArticlesSince(ctx context.Context, userID uu
 			if savedErr == nil {
 				posts[i].IsSaved = savedMap[posts[i].ID]
 			}
+			if sharedErr == nil {
+				posts[i].IsShared = sharedMap[posts[i].ID]
+			}
 		}
 	}
 
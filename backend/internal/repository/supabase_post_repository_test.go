@@ -0,0 +1,397 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// newFeedQueryRepo fakes PostgREST with no rows anywhere, recording the query of
+// every posts read
+func newFeedQueryRepo(t *testing.T) (*SupabasePostRepository, *[]string) {
+	t.Helper()
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/v1/posts" {
+			query, _ := url.QueryUnescape(r.URL.RawQuery)
+			queries = append(queries, query)
+		}
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(server.Close)
+	return NewSupabasePostRepository(server.URL, "key"), &queries
+}
+
+func TestFeedQueriesExcludeInteractedPosts(t *testing.T) {
+	viewer := uuid.New()
+	exclusions := []string{
+		"viewer_likes:post_likes(post_id)", "viewer_likes.user_id=eq." + viewer.String(), "viewer_likes=is.null",
+		"viewer_saves:saved_posts(post_id)", "viewer_saves.user_id=eq." + viewer.String(), "viewer_saves=is.null",
+		"viewer_comments:post_comments(post_id)", "viewer_comments.user_id=eq." + viewer.String(),
+		"viewer_comments.deleted_at=is.null", "viewer_comments=is.null",
+	}
+
+	feeds := map[string]func(repo *SupabasePostRepository, hide bool) error{
+		"home": func(repo *SupabasePostRepository, hide bool) error {
+			_, _, err := repo.GetHomeFeed(context.Background(), viewer, 10, 0, hide, false)
+			return err
+		},
+		"explore": func(repo *SupabasePostRepository, hide bool) error {
+			_, _, err := repo.GetExploreFeed(context.Background(), viewer, 10, 0, "", hide, false)
+			return err
+		},
+	}
+
+	for name, load := range feeds {
+		t.Run(name, func(t *testing.T) {
+			for _, hide := range []bool{true, false} {
+				repo, queries := newFeedQueryRepo(t)
+				if err := load(repo, hide); err != nil {
+					t.Fatalf("load with hide=%v: %v", hide, err)
+				}
+				if len(*queries) != 1 {
+					t.Fatalf("made %d posts queries, want 1", len(*queries))
+				}
+				query := (*queries)[0]
+				for _, part := range exclusions {
+					if strings.Contains(query, part) != hide {
+						t.Errorf("hide=%v: query %q containing %q is %v", hide, query, part, !hide)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestInteractedExclusionNeedsAViewer(t *testing.T) {
+	if embeds, filters := interactedExclusion(uuid.Nil, true); embeds != "" || filters != "" {
+		t.Errorf("anonymous viewers have nothing to exclude, got %q %q", embeds, filters)
+	}
+}
+
+func TestExtractHashtagsUnicode(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"ascii", "Loving #Golang and #go_lang!", []string{"golang", "go_lang"}},
+		{"accented", "Coffee at the #Café, #CAFÉ again", []string{"café"}},
+		{"decomposed accent", "#cafe\u0301 and #caf\u00e9", []string{"café"}},
+		{"cjk", "旅行 #日本語 #東京2026。", []string{"日本語", "東京2026"}},
+		{"mixed script", "#Tokyo東京 #MoscowМосква", []string{"tokyo東京", "moscowмосква"}},
+		{"punctuation and emoji end a tag", "#hello, #wow🎉 #a-b", []string{"hello", "wow", "a"}},
+		{"bare hash", "# nothing #", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractHashtags(tt.text)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("extractHashtags(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtagMatchesExtraction(t *testing.T) {
+	// A follow or lookup typed by the user must land on the tag extraction stored
+	for _, typed := range []string{"#Café", "CAFÉ", " café "} {
+		if got := normalizeHashtag(typed); got != extractHashtags("#café")[0] {
+			t.Errorf("normalizeHashtag(%q) = %q, want %q", typed, got, "café")
+		}
+	}
+}
+
+// engagementDB fakes the toggle_post_like and toggle_post_share RPCs (as defined in
+// migrations 31 and 42) and the saved_posts and post_shares tables for one post
+type engagementDB struct {
+	post   string // The post's ID, for reads of post_shares
+	exists bool
+	likes  map[string]bool // Keyed by user ID
+	shares map[string]bool
+	saves  map[string]bool
+	counts map[string]int // likes_count and shares_count
+}
+
+func newEngagementRepo(t *testing.T, db *engagementDB) *SupabasePostRepository {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/rest/v1/rpc/toggle_post_like", "/rest/v1/rpc/toggle_post_share":
+			rows, flag, counter := db.likes, "p_liked", "likes_count"
+			if strings.HasSuffix(r.URL.Path, "share") {
+				rows, flag, counter = db.shares, "p_shared", "shares_count"
+			}
+			if !db.exists {
+				w.Write([]byte("[]"))
+				return
+			}
+			user, on := body["p_user_id"].(string), body[flag] == true
+			changed := rows[user] != on
+			if changed {
+				rows[user] = on
+				if on {
+					db.counts[counter]++
+				} else {
+					db.counts[counter] = max(db.counts[counter]-1, 0)
+				}
+			}
+			fmt.Fprintf(w, `[{%q: %d, "changed": %v}]`, counter, db.counts[counter], changed)
+		case "/rest/v1/saved_posts":
+			user := strings.TrimPrefix(r.URL.Query().Get("user_id"), "eq.")
+			if r.Method == http.MethodDelete && db.saves[user] {
+				delete(db.saves, user)
+				w.Write([]byte(`[{"id": "` + uuid.NewString() + `"}]`))
+				return
+			}
+			w.Write([]byte("[]"))
+		case "/rest/v1/post_shares":
+			user := strings.TrimPrefix(r.URL.Query().Get("user_id"), "eq.")
+			if db.shares[user] && strings.Contains(r.URL.Query().Get("post_id"), db.post) {
+				fmt.Fprintf(w, `[{"id": %q, "post_id": %q}]`, uuid.NewString(), db.post)
+				return
+			}
+			w.Write([]byte("[]"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return NewSupabasePostRepository(server.URL, "key")
+}
+
+func TestUnlikeOnlyDecrementsRemovedLikes(t *testing.T) {
+	liker, stranger := uuid.New(), uuid.New()
+	db := &engagementDB{exists: true, likes: map[string]bool{liker.String(): true}, counts: map[string]int{"likes_count": 1}}
+	repo := newEngagementRepo(t, db)
+	ctx := context.Background()
+	post := uuid.New()
+
+	count, changed, err := repo.UnlikePost(ctx, post, stranger)
+	if err != nil || changed || count != 1 {
+		t.Errorf("unlike when not liked = %d, %v, %v; want 1, false, nil", count, changed, err)
+	}
+
+	count, changed, err = repo.UnlikePost(ctx, post, liker)
+	if err != nil || !changed || count != 0 {
+		t.Errorf("unlike = %d, %v, %v; want 0, true, nil", count, changed, err)
+	}
+
+	count, changed, err = repo.UnlikePost(ctx, post, liker)
+	if err != nil || changed || count != 0 {
+		t.Errorf("repeated unlike = %d, %v, %v; want 0, false, nil", count, changed, err)
+	}
+
+	db.exists = false
+	if _, _, err := repo.UnlikePost(ctx, post, liker); err != models.ErrPostNotFound {
+		t.Errorf("unlike of a deleted post: err = %v, want ErrPostNotFound", err)
+	}
+}
+
+func TestUnshareAndUnsaveReportChanges(t *testing.T) {
+	user := uuid.New()
+	db := &engagementDB{
+		exists: true,
+		shares: map[string]bool{user.String(): true},
+		saves:  map[string]bool{user.String(): true},
+		counts: map[string]int{"shares_count": 3},
+	}
+	repo := newEngagementRepo(t, db)
+	ctx := context.Background()
+	post := uuid.New()
+
+	for i, want := range []struct {
+		count   int
+		changed bool
+	}{{2, true}, {2, false}} {
+		count, changed, err := repo.UnsharePost(ctx, post, user)
+		if err != nil || count != want.count || changed != want.changed {
+			t.Errorf("unshare #%d = %d, %v, %v; want %d, %v", i+1, count, changed, err, want.count, want.changed)
+		}
+	}
+
+	for i, want := range []bool{true, false} {
+		if changed, err := repo.UnsavePost(ctx, post, user); err != nil || changed != want {
+			t.Errorf("unsave #%d = %v, %v; want %v", i+1, changed, err, want)
+		}
+	}
+}
+
+func TestResharerSeesIsSharedAndCountMatchesShares(t *testing.T) {
+	post, other := uuid.New(), uuid.New()
+	resharer, second, viewer := uuid.New(), uuid.New(), uuid.New()
+	db := &engagementDB{post: post.String(), exists: true, shares: map[string]bool{}, counts: map[string]int{}}
+	repo := newEngagementRepo(t, db)
+	ctx := context.Background()
+
+	// shareRows counts the share rows the fake holds
+	shareRows := func() int {
+		n := 0
+		for _, shared := range db.shares {
+			if shared {
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, step := range []struct {
+		user    uuid.UUID
+		share   bool
+		changed bool
+	}{
+		{resharer, true, true},
+		{second, true, true},
+		{resharer, true, false}, // Resharing again adds nothing
+		{second, false, true},
+		{second, false, false},
+	} {
+		var count int
+		var changed bool
+		var err error
+		if step.share {
+			count, changed, err = repo.SharePost(ctx, post, step.user, "")
+		} else {
+			count, changed, err = repo.UnsharePost(ctx, post, step.user)
+		}
+		if err != nil || changed != step.changed {
+			t.Fatalf("share %v: changed %v, err %v; want changed %v", step.share, changed, err, step.changed)
+		}
+		if count != shareRows() {
+			t.Errorf("shares_count = %d, want the %d share rows", count, shareRows())
+		}
+	}
+
+	if shared, _ := repo.IsPostSharedByUser(ctx, post, resharer); !shared {
+		t.Error("the resharer should see is_shared")
+	}
+	for _, user := range []uuid.UUID{second, viewer} {
+		if shared, _ := repo.IsPostSharedByUser(ctx, post, user); shared {
+			t.Error("a viewer without a share shouldn't see is_shared")
+		}
+	}
+
+	shared, err := repo.batchCheckShares(ctx, []uuid.UUID{post, other}, resharer)
+	if err != nil || !shared[post] || shared[other] {
+		t.Errorf("batch check = %v, %v; want only the reshared post", shared, err)
+	}
+}
+
+func TestUserPostsHasMoreAtPageBoundaries(t *testing.T) {
+	author := uuid.New()
+	for _, total := range []int{0, 4, 5, 10, 11} {
+		rows := make([]string, total)
+		for i := range rows {
+			rows[i] = `{"id": "` + uuid.NewString() + `", "user_id": "` + author.String() + `", "post_type": "text"}`
+		}
+		// Serves rows[offset:offset+limit] like PostgREST would
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/rest/v1/posts" {
+				w.Write([]byte("[]"))
+				return
+			}
+			var limit, offset int
+			fmt.Sscan(r.URL.Query().Get("limit"), &limit)
+			fmt.Sscan(r.URL.Query().Get("offset"), &offset)
+			start, end := min(offset, total), min(offset+limit, total)
+			w.Write([]byte("[" + strings.Join(rows[start:end], ",") + "]"))
+		}))
+		repo := NewSupabasePostRepository(server.URL, "key")
+
+		seen, pages := 0, 0
+		for offset := 0; ; offset += 5 {
+			posts, page, err := repo.GetUserPosts(context.Background(), author, 5, offset, false)
+			if err != nil {
+				t.Fatalf("total %d, offset %d: %v", total, offset, err)
+			}
+			seen += len(posts)
+			pages++
+			wantMore := offset+5 < total
+			if page.HasMore != wantMore || page.Counted() {
+				t.Errorf("total %d, offset %d: page %+v, want HasMore %v and no count", total, offset, page, wantMore)
+			}
+			if !page.HasMore || pages > 5 {
+				break
+			}
+		}
+		server.Close()
+
+		if seen != total {
+			t.Errorf("total %d: paged through %d posts", total, seen)
+		}
+	}
+}
+
+func TestUpdatePostReturnsTheUpdatedPost(t *testing.T) {
+	id, author := uuid.New(), uuid.New()
+	server, calls := returningServer(t, "posts", `{
+		"id": "`+id.String()+`", "user_id": "`+author.String()+`", "post_type": "text",
+		"content": "Edited", "updated_at": "2026-10-15T09:30:00.123456+00:00"
+	}`)
+	defer server.Close()
+
+	post, err := NewSupabasePostRepository(server.URL, "key").UpdatePost(context.Background(), id,
+		map[string]interface{}{"content": "Edited"})
+	if err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("made %d requests, want the update alone", *calls)
+	}
+	if post.ID != id || post.Content != "Edited" || post.UpdatedAt.IsZero() {
+		t.Errorf("post = %+v, want the new values", post)
+	}
+}
+
+func TestGetMediaVariantsReadsEveryURLInOneQuery(t *testing.T) {
+	author := uuid.New()
+	a, b := "https://cdn.example/posts/a,1.jpeg", "https://cdn.example/posts/b.jpeg"
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != http.MethodGet || r.URL.Path != "/rest/v1/media_variants" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query := r.URL.Query()
+		if got := query.Get("user_id"); got != "eq."+author.String() {
+			t.Errorf("user_id = %q, want only the author's uploads", got)
+		}
+		// The comma in a's URL mustn't split it in two
+		if got, want := query.Get("media_url"), `in.("`+a+`","`+b+`")`; got != want {
+			t.Errorf("media_url = %q, want %q", got, want)
+		}
+		w.Write([]byte(`[{"media_url": "` + a + `", "variants": {"320": "https://cdn.example/posts/a,1_320.webp"}}]`))
+	}))
+	defer server.Close()
+	repo := NewSupabasePostRepository(server.URL, "key")
+
+	variants, err := repo.GetMediaVariants(context.Background(), author, []string{a, b})
+	if err != nil {
+		t.Fatalf("GetMediaVariants: %v", err)
+	}
+	if len(variants) != 1 || variants[a]["320"] != "https://cdn.example/posts/a,1_320.webp" {
+		t.Errorf("variants = %v, want a's 320px variant", variants)
+	}
+
+	if variants, err := repo.GetMediaVariants(context.Background(), author, nil); err != nil || len(variants) != 0 {
+		t.Errorf("no URLs: got %v, %v", variants, err)
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1", calls)
+	}
+}
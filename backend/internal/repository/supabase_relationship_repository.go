@@ -27,7 +27,7 @@ func NewSupabaseRelationshipRepository(supabaseURL, apiKey string) *SupabaseRela
 	return &SupabaseRelationshipRepository{
 		supabaseURL: supabaseURL,
 		apiKey:      apiKey,
-		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		httpClient:  newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -513,6 +513,8 @@ func (r *SupabaseRelationshipRepository) GetRelationshipStatus(ctx context.Conte
 		status.IsFollower = true
 	}
 
+	status.IsMutual = status.IsFollowing && status.IsFollower
+
 	// Check connection (bidirectional)
 	connectedRel, _ := r.GetRelationship(ctx, fromUserID, toUserID, models.RelationshipConnected)
 	if connectedRel != nil && connectedRel.Status == models.RelationshipActive {
@@ -538,12 +540,354 @@ func (r *SupabaseRelationshipRepository) GetRelationshipStatus(ctx context.Conte
 	return status, nil
 }
 
-// GetPendingRequests gets pending relationship requests
-func (r *SupabaseRelationshipRepository) GetPendingRequests(ctx context.Context, userID uuid.UUID, incoming bool, page, limit int) ([]*models.RelationshipRequest, int, error) {
-	// This is a simplified implementation
-	// In production, you'd join with users table to get full user details
-	requests := make([]*models.RelationshipRequest, 0)
-	return requests, 0, nil
+// GetPendingRequests gets pending relationship requests, newest first. incoming lists
+// requests sent to userID, otherwise those userID sent. An empty relationshipType
+// includes every type.
+func (r *SupabaseRelationshipRepository) GetPendingRequests(ctx context.Context, userID uuid.UUID, relationshipType models.RelationshipType, incoming bool, page, limit int) ([]*models.RelationshipRequest, int, error) {
+	offset := (page - 1) * limit
+
+	userField := "from_user_id"
+	if incoming {
+		userField = "to_user_id"
+	}
+	queryParams := fmt.Sprintf("%s=eq.%s&status=eq.pending", userField, userID.String())
+	if relationshipType != "" {
+		queryParams += fmt.Sprintf("&relationship_type=eq.%s", relationshipType)
+	}
+
+	const userColumns = "id,username,display_name,profile_picture,is_verified"
+	requestURL := fmt.Sprintf("%s/rest/v1/user_relationships?%s&select=id,relationship_type,request_message,created_at,"+
+		"from_user:users!from_user_id(%s),to_user:users!to_user_id(%s)&limit=%d&offset=%d&order=created_at.desc",
+		r.supabaseURL, queryParams, userColumns, userColumns, limit, offset)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set("apikey", r.apiKey)
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	req.Header.Set("Prefer", "count=exact")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[GetPendingRequests] Failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+		return nil, 0, fmt.Errorf("failed to get pending requests: %d", resp.StatusCode)
+	}
+
+	totalCount := 0
+	if contentRange := resp.Header.Get("Content-Range"); contentRange != "" {
+		var start, end, total int
+		if n, _ := fmt.Sscanf(contentRange, "%d-%d/%d", &start, &end, &total); n == 3 {
+			totalCount = total
+		} else if n, _ := fmt.Sscanf(contentRange, "*/%d", &total); n == 1 {
+			totalCount = total
+		}
+	}
+
+	var rows []struct {
+		ID               uuid.UUID               `json:"id"`
+		RelationshipType models.RelationshipType `json:"relationship_type"`
+		RequestMessage   *string                 `json:"request_message"`
+		CreatedAt        string                  `json:"created_at"`
+		FromUser         *models.User            `json:"from_user"`
+		ToUser           *models.User            `json:"to_user"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, 0, err
+	}
+
+	requests := make([]*models.RelationshipRequest, 0, len(rows))
+	for _, row := range rows {
+		requests = append(requests, &models.RelationshipRequest{
+			ID:               row.ID,
+			FromUser:         row.FromUser,
+			ToUser:           row.ToUser,
+			RelationshipType: row.RelationshipType,
+			RequestMessage:   row.RequestMessage,
+			CreatedAt:        parseFlexibleTime(row.CreatedAt),
+		})
+	}
+
+	return requests, totalCount, nil
+}
+
+// GetFollowList gets a page of userID's followers, or of the accounts userID follows,
+// through list_follows
+func (r *SupabaseRelationshipRepository) GetFollowList(ctx context.Context, userID, viewerID uuid.UUID, followers bool, page, limit int) ([]*models.FollowListUser, int, error) {
+	params := map[string]interface{}{
+		"p_user_id":   userID.String(),
+		"p_viewer_id": nil,
+		"p_followers": followers,
+		"p_limit":     limit,
+		"p_offset":    (page - 1) * limit,
+	}
+	if viewerID != uuid.Nil {
+		params["p_viewer_id"] = viewerID.String()
+	}
+	return r.followListRPC(ctx, "list_follows", params)
+}
+
+// GetMutualFollowers gets a page of the accounts viewerID follows that follow targetID,
+// through list_mutual_followers
+func (r *SupabaseRelationshipRepository) GetMutualFollowers(ctx context.Context, viewerID, targetID uuid.UUID, page, limit int) ([]*models.FollowListUser, int, error) {
+	return r.followListRPC(ctx, "list_mutual_followers", map[string]interface{}{
+		"p_viewer_id": viewerID.String(),
+		"p_target_id": targetID.String(),
+		"p_limit":     limit,
+		"p_offset":    (page - 1) * limit,
+	})
+}
+
+// followListRPC calls one of the follow list functions, which return users with the
+// size of the whole list on every row
+func (r *SupabaseRelationshipRepository) followListRPC(ctx context.Context, function string, params map[string]interface{}) ([]*models.FollowListUser, int, error) {
+	var rows []struct {
+		UserID         uuid.UUID `json:"user_id"`
+		Username       string    `json:"username"`
+		DisplayName    string    `json:"display_name"`
+		ProfilePicture *string   `json:"profile_picture"`
+		Bio            *string   `json:"bio"`
+		IsVerified     bool      `json:"is_verified"`
+		FollowedAt     string    `json:"followed_at"`
+		TotalCount     int       `json:"total_count"`
+	}
+	if err := r.rpc(ctx, function, params, &rows); err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]*models.FollowListUser, 0, len(rows))
+	total := 0
+	for _, row := range rows {
+		total = row.TotalCount
+		users = append(users, &models.FollowListUser{
+			ID:             row.UserID,
+			Username:       row.Username,
+			DisplayName:    row.DisplayName,
+			ProfilePicture: row.ProfilePicture,
+			Bio:            row.Bio,
+			IsVerified:     row.IsVerified,
+			FollowedAt:     parseFlexibleTime(row.FollowedAt),
+		})
+	}
+	return users, total, nil
+}
+
+// GetFollowStates gets how viewerID relates to each of userIDs through
+// get_follow_states. Users with no follow either way get a zero status.
+func (r *SupabaseRelationshipRepository) GetFollowStates(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*models.ViewerFollowStatus, error) {
+	states := make(map[uuid.UUID]*models.ViewerFollowStatus, len(userIDs))
+	if len(userIDs) == 0 {
+		return states, nil
+	}
+
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+	var rows []struct {
+		UserID          uuid.UUID `json:"user_id"`
+		IsFollowing     bool      `json:"is_following"`
+		IsFollower      bool      `json:"is_follower"`
+		FollowRequested bool      `json:"follow_requested"`
+	}
+	if err := r.rpc(ctx, "get_follow_states", map[string]interface{}{
+		"p_viewer_id": viewerID.String(),
+		"p_user_ids":  ids,
+	}, &rows); err != nil {
+		return nil, err
+	}
+
+	for _, id := range userIDs {
+		states[id] = &models.ViewerFollowStatus{IsSelf: id == viewerID}
+	}
+	for _, row := range rows {
+		if state, ok := states[row.UserID]; ok {
+			state.IsFollowing = row.IsFollowing
+			state.IsFollower = row.IsFollower
+			state.IsMutual = row.IsFollowing && row.IsFollower
+			state.FollowRequested = row.FollowRequested
+		}
+	}
+	return states, nil
+}
+
+// rpc calls a database function and decodes its result into out
+func (r *SupabaseRelationshipRepository) rpc(ctx context.Context, function string, params map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s params: %w", function, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.supabaseURL+"/rest/v1/rpc/"+function, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("apikey", r.apiKey)
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Supabase] %s failed: HTTP %d - %s", function, resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("%s failed: %d", function, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// AddCloseFriend puts friendID on ownerID's close friends list. Adding someone
+// already on it is a no-op.
+func (r *SupabaseRelationshipRepository) AddCloseFriend(ctx context.Context, ownerID, friendID uuid.UUID) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"owner_id":  ownerID.String(),
+		"friend_id": friendID.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal close friend: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.supabaseURL+"/rest/v1/close_friends?on_conflict=owner_id,friend_id", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("apikey", r.apiKey)
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "resolution=ignore-duplicates,return=minimal")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to add close friend: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// RemoveCloseFriend takes friendID off ownerID's close friends list
+func (r *SupabaseRelationshipRepository) RemoveCloseFriend(ctx context.Context, ownerID, friendID uuid.UUID) error {
+	url := fmt.Sprintf("%s/rest/v1/close_friends?owner_id=eq.%s&friend_id=eq.%s",
+		r.supabaseURL, ownerID.String(), friendID.String())
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("apikey", r.apiKey)
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to remove close friend: %s", string(bodyBytes))
+	}
+
+	return nil
+}
+
+// GetCloseFriends gets a page of ownerID's close friends through list_close_friends
+func (r *SupabaseRelationshipRepository) GetCloseFriends(ctx context.Context, ownerID uuid.UUID, page, limit int) ([]*models.CloseFriend, int, error) {
+	var rows []struct {
+		UserID         uuid.UUID `json:"user_id"`
+		Username       string    `json:"username"`
+		DisplayName    string    `json:"display_name"`
+		ProfilePicture *string   `json:"profile_picture"`
+		IsVerified     bool      `json:"is_verified"`
+		AddedAt        string    `json:"added_at"`
+		TotalCount     int       `json:"total_count"`
+	}
+	if err := r.rpc(ctx, "list_close_friends", map[string]interface{}{
+		"p_owner_id": ownerID.String(),
+		"p_limit":    limit,
+		"p_offset":   (page - 1) * limit,
+	}, &rows); err != nil {
+		return nil, 0, err
+	}
+
+	friends := make([]*models.CloseFriend, 0, len(rows))
+	total := 0
+	for _, row := range rows {
+		total = row.TotalCount
+		friends = append(friends, &models.CloseFriend{
+			ID:             row.UserID,
+			Username:       row.Username,
+			DisplayName:    row.DisplayName,
+			ProfilePicture: row.ProfilePicture,
+			IsVerified:     row.IsVerified,
+			AddedAt:        parseFlexibleTime(row.AddedAt),
+		})
+	}
+	return friends, total, nil
+}
+
+// IsCloseFriend reports whether friendID is on ownerID's close friends list
+func (r *SupabaseRelationshipRepository) IsCloseFriend(ctx context.Context, ownerID, friendID uuid.UUID) (bool, error) {
+	url := fmt.Sprintf("%s/rest/v1/close_friends?owner_id=eq.%s&friend_id=eq.%s&select=owner_id",
+		r.supabaseURL, ownerID.String(), friendID.String())
+
+	ownerIDs, err := r.closeFriendOwners(ctx, url)
+	if err != nil {
+		return false, err
+	}
+	return len(ownerIDs) > 0, nil
+}
+
+// GetCloseFriendsOf gets the owners of every close friends list userID is on
+func (r *SupabaseRelationshipRepository) GetCloseFriendsOf(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return r.closeFriendOwners(ctx, r.supabaseURL+"/rest/v1/close_friends"+closeFriendsOfQuery(userID))
+}
+
+// closeFriendOwners runs a close_friends query selecting owner_id
+func (r *SupabaseRelationshipRepository) closeFriendOwners(ctx context.Context, url string) ([]uuid.UUID, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("apikey", r.apiKey)
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get close friends: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseCloseFriendOwners(data)
 }
 
 // GetRateLimit gets the rate limit record for a user and action type
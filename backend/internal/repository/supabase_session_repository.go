@@ -29,7 +29,7 @@ func NewSupabaseSessionRepository(baseURL, apiKey string) *SupabaseSessionReposi
 	return &SupabaseSessionRepository{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -61,6 +61,12 @@ func (r *SupabaseSessionRepository) CreateSession(ctx context.Context, session *
 		"expires_at":  session.ExpiresAt,
 		"created_at":  time.Now(),
 	}
+	if session.FamilyID != uuid.Nil {
+		sessionData["family_id"] = session.FamilyID
+	}
+	if !session.StartedAt.IsZero() {
+		sessionData["started_at"] = session.StartedAt
+	}
 
 	body, err := json.Marshal(sessionData)
 	if err != nil {
@@ -94,6 +100,7 @@ func (r *SupabaseSessionRepository) CreateSession(ctx context.Context, session *
 
 	if len(sessions) > 0 {
 		session.ID = sessions[0].ID
+		session.FamilyID = sessions[0].FamilyID
 		session.CreatedAt = sessions[0].CreatedAt
 	}
 
@@ -135,10 +142,11 @@ func (r *SupabaseSessionRepository) GetSessionByID(ctx context.Context, sessionI
 	return &sessions[0], nil
 }
 
-// GetSessionsByUserID retrieves all sessions for a user
+// GetSessionsByUserID retrieves all sessions for a user, one per login
 func (r *SupabaseSessionRepository) GetSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.UserSession, error) {
 	q := url.Values{}
 	q.Set("user_id", "eq."+userID.String())
+	q.Set("replaced_at", "is.null")
 	q.Set("select", "*")
 	q.Set("order", "created_at.desc")
 
@@ -208,6 +216,45 @@ func (r *SupabaseSessionRepository) GetSessionByTokenHash(ctx context.Context, t
 	return &sessions[0], nil
 }
 
+// MarkSessionReplaced flags a refresh token as rotated. The update only matches a
+// token that is still current, so of two concurrent refreshes only one wins.
+func (r *SupabaseSessionRepository) MarkSessionReplaced(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	q := url.Values{}
+	q.Set("id", "eq."+sessionID.String())
+	q.Set("replaced_at", "is.null")
+
+	body, err := json.Marshal(map[string]interface{}{"replaced_at": time.Now()})
+	if err != nil {
+		return false, apperr.ErrInternalServer
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, r.sessionsURL(q), bytes.NewReader(body))
+	if err != nil {
+		return false, apperr.ErrInternalServer
+	}
+
+	r.setHeaders(req, "return=representation")
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return false, apperr.ErrDatabaseError
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Supabase] MarkSessionReplaced failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+		return false, apperr.ErrDatabaseError
+	}
+
+	var updated []models.UserSession
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		return false, apperr.ErrDatabaseError
+	}
+
+	return len(updated) > 0, nil
+}
+
 // DeleteSession deletes a specific session
 func (r *SupabaseSessionRepository) DeleteSession(ctx context.Context, sessionID uuid.UUID) error {
 	q := url.Values{}
@@ -262,14 +309,75 @@ func (r *SupabaseSessionRepository) DeleteSessionByTokenHash(ctx context.Context
 	return nil
 }
 
-// DeleteAllUserSessions deletes all sessions for a user (optionally except current session)
-func (r *SupabaseSessionRepository) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID, exceptSessionID *uuid.UUID) error {
+// DeleteSessionFamily deletes every refresh token of a login, current and rotated
+func (r *SupabaseSessionRepository) DeleteSessionFamily(ctx context.Context, familyID uuid.UUID) error {
+	q := url.Values{}
+	q.Set("family_id", "eq."+familyID.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, r.sessionsURL(q), nil)
+	if err != nil {
+		return apperr.ErrInternalServer
+	}
+
+	r.setHeaders(req, "return=minimal")
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return apperr.ErrDatabaseError
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Supabase] DeleteSessionFamily failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+		return apperr.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// DeleteUserSessionFamily deletes a session family only if it belongs to the user, so
+// callers can't end someone else's login by guessing its ID
+func (r *SupabaseSessionRepository) DeleteUserSessionFamily(ctx context.Context, userID, familyID uuid.UUID) (bool, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("family_id", "eq."+familyID.String())
+	q.Set("select", "id")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, r.sessionsURL(q), nil)
+	if err != nil {
+		return false, apperr.ErrInternalServer
+	}
+
+	r.setHeaders(req, "return=representation")
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return false, apperr.ErrDatabaseError
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Supabase] DeleteUserSessionFamily failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+		return false, apperr.ErrDatabaseError
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, apperr.ErrDatabaseError
+	}
+	return affectedRows(data)
+}
+
+// DeleteAllUserSessions deletes all sessions for a user, optionally keeping one login
+func (r *SupabaseSessionRepository) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID, exceptFamilyID *uuid.UUID) error {
 	q := url.Values{}
 	q.Set("user_id", "eq."+userID.String())
 
-	// If we want to keep the current session, exclude it
-	if exceptSessionID != nil {
-		q.Set("id", "neq."+exceptSessionID.String())
+	// Keep every refresh token of the current login, not just its latest row
+	if exceptFamilyID != nil {
+		q.Set("family_id", "neq."+exceptFamilyID.String())
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, r.sessionsURL(q), nil)
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"histeeria-backend/internal/models"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// SupabaseTwoFactorRepository implements TwoFactorRepository for Supabase
+type SupabaseTwoFactorRepository struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewSupabaseTwoFactorRepository creates a new Supabase two-factor repository
+func NewSupabaseTwoFactorRepository(baseURL, apiKey string) *SupabaseTwoFactorRepository {
+	return &SupabaseTwoFactorRepository{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		http:    newSupabaseHTTPClient(10 * time.Second),
+	}
+}
+
+// request runs one PostgREST call and returns the response body
+func (r *SupabaseTwoFactorRepository) request(ctx context.Context, method, table string, query url.Values, body interface{}, prefer string) ([]byte, error) {
+	u := fmt.Sprintf("%s/rest/v1/%s", r.baseURL, table)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, apperr.ErrInternalServer
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, apperr.ErrInternalServer
+	}
+	req.Header.Set("apikey", r.apiKey)
+	req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if prefer != "" {
+		req.Header.Set("Prefer", prefer)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, apperr.ErrDatabaseError
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, apperr.ErrDatabaseError
+	}
+	if resp.StatusCode >= 300 {
+		log.Printf("[Supabase] %s %s failed: HTTP %d - %s", method, table, resp.StatusCode, string(data))
+		return nil, apperr.ErrDatabaseError
+	}
+	return data, nil
+}
+
+// GetTwoFactor retrieves a user's TOTP secret, enabled or pending
+func (r *SupabaseTwoFactorRepository) GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactorSecret, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("select", "*")
+
+	data, err := r.request(ctx, http.MethodGet, "user_two_factor", q, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []models.TwoFactorSecret
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, apperr.ErrDatabaseError
+	}
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	return &secrets[0], nil
+}
+
+// SavePendingTwoFactor stores a new secret awaiting confirmation. Only a pending row is
+// cleared first; an enabled one stays, and the insert then fails on the primary key.
+func (r *SupabaseTwoFactorRepository) SavePendingTwoFactor(ctx context.Context, secret *models.TwoFactorSecret) error {
+	q := url.Values{}
+	q.Set("user_id", "eq."+secret.UserID.String())
+	q.Set("enabled_at", "is.null")
+	if _, err := r.request(ctx, http.MethodDelete, "user_two_factor", q, nil, "return=minimal"); err != nil {
+		return err
+	}
+
+	_, err := r.request(ctx, http.MethodPost, "user_two_factor", nil, map[string]interface{}{
+		"user_id":          secret.UserID,
+		"secret_encrypted": secret.SecretEncrypted,
+		"secret_iv":        secret.SecretIV,
+		"created_at":       time.Now(),
+	}, "return=minimal")
+	return err
+}
+
+// EnableTwoFactor confirms a pending secret, recording the step of the confirming code
+func (r *SupabaseTwoFactorRepository) EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("enabled_at", "is.null")
+
+	data, err := r.request(ctx, http.MethodPatch, "user_two_factor", q, map[string]interface{}{
+		"enabled_at":     time.Now(),
+		"last_used_step": step,
+	}, "return=representation")
+	if err != nil {
+		return false, err
+	}
+	return affectedRows(data)
+}
+
+// MarkTOTPStepUsed records that a code was accepted. The update is conditional on
+// last_used_step, so two requests racing with the same code can't both win.
+func (r *SupabaseTwoFactorRepository) MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("last_used_step", fmt.Sprintf("lt.%d", step))
+
+	data, err := r.request(ctx, http.MethodPatch, "user_two_factor", q, map[string]interface{}{
+		"last_used_step": step,
+	}, "return=representation")
+	if err != nil {
+		return false, err
+	}
+	return affectedRows(data)
+}
+
+// DeleteTwoFactor turns 2FA off, removing the secret and every recovery code
+func (r *SupabaseTwoFactorRepository) DeleteTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+
+	if _, err := r.request(ctx, http.MethodDelete, "two_factor_recovery_codes", q, nil, "return=minimal"); err != nil {
+		return err
+	}
+	_, err := r.request(ctx, http.MethodDelete, "user_two_factor", q, nil, "return=minimal")
+	return err
+}
+
+// ReplaceRecoveryCodes swaps a user's recovery codes for a new set
+func (r *SupabaseTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	if _, err := r.request(ctx, http.MethodDelete, "two_factor_recovery_codes", q, nil, "return=minimal"); err != nil {
+		return err
+	}
+
+	rows := make([]map[string]interface{}, len(codeHashes))
+	for i, hash := range codeHashes {
+		rows[i] = map[string]interface{}{
+			"user_id":   userID,
+			"code_hash": hash,
+		}
+	}
+	_, err := r.request(ctx, http.MethodPost, "two_factor_recovery_codes", nil, rows, "return=minimal")
+	return err
+}
+
+// ConsumeRecoveryCode marks a recovery code used. Like MarkTOTPStepUsed it's a
+// conditional update, so a code can only ever be spent once.
+func (r *SupabaseTwoFactorRepository) ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	q := url.Values{}
+	q.Set("user_id", "eq."+userID.String())
+	q.Set("code_hash", "eq."+codeHash)
+	q.Set("used_at", "is.null")
+
+	data, err := r.request(ctx, http.MethodPatch, "two_factor_recovery_codes", q, map[string]interface{}{
+		"used_at": time.Now(),
+	}, "return=representation")
+	if err != nil {
+		return false, err
+	}
+	return affectedRows(data)
+}
+
+// affectedRows reports whether a return=representation write matched any row
+func affectedRows(data []byte) (bool, error) {
+	var rows []json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, apperr.ErrDatabaseError
+	}
+	return len(rows) > 0, nil
+}
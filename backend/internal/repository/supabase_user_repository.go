@@ -77,7 +77,7 @@ func NewSupabaseUserRepository(baseURL, serviceRoleKey string) *SupabaseUserRepo
 	return &SupabaseUserRepository{
 		baseURL: strings.TrimRight(baseURL, "/") + "/rest/v1",
 		apiKey:  serviceRoleKey,
-		http:    &http.Client{Timeout: 10 * time.Second},
+		http:    newSupabaseHTTPClient(10 * time.Second),
 	}
 }
 
@@ -138,6 +138,12 @@ func (r *SupabaseUserRepository) CreateUser(ctx context.Context, user *models.Us
 	if user.LinkedInID != nil {
 		userData["linkedin_id"] = *user.LinkedInID
 	}
+	if user.AppleID != nil {
+		userData["apple_id"] = *user.AppleID
+	}
+	if user.TwitterID != nil {
+		userData["twitter_id"] = *user.TwitterID
+	}
 	if user.OAuthProvider != nil {
 		userData["oauth_provider"] = *user.OAuthProvider
 	}
@@ -219,9 +225,13 @@ func (r *SupabaseUserRepository) fetchOne(ctx context.Context, q url.Values) (*m
 		return nil, apperr.ErrUserNotFound
 	}
 
-	// Manually convert to User model, handling timestamps
+	return decodeUserRow(rawUsers[0]), nil
+}
+
+// decodeUserRow converts a users row decoded into a map to the User model. Rows are
+// decoded by hand because PostgREST timestamps don't always parse as RFC 3339.
+func decodeUserRow(rawUser map[string]interface{}) *models.User {
 	user := &models.User{}
-	rawUser := rawUsers[0]
 
 	if idStr, ok := rawUser["id"].(string); ok {
 		if id, err := uuid.Parse(idStr); err == nil {
@@ -260,6 +270,12 @@ func (r *SupabaseUserRepository) fetchOne(ctx context.Context, q url.Values) (*m
 	if linkedinID, ok := rawUser["linkedin_id"].(string); ok && linkedinID != "" {
 		user.LinkedInID = &linkedinID
 	}
+	if appleID, ok := rawUser["apple_id"].(string); ok && appleID != "" {
+		user.AppleID = &appleID
+	}
+	if twitterID, ok := rawUser["twitter_id"].(string); ok && twitterID != "" {
+		user.TwitterID = &twitterID
+	}
 
 	// Parse pending email change fields
 	if pendingEmail, ok := rawUser["pending_email"].(string); ok && pendingEmail != "" {
@@ -300,6 +316,11 @@ func (r *SupabaseUserRepository) fetchOne(ctx context.Context, q url.Values) (*m
 	if website, ok := rawUser["website"].(string); ok && website != "" {
 		user.Website = &website
 	}
+	if timezone, ok := rawUser["timezone"].(string); ok && timezone != "" {
+		user.Timezone = timezone
+	} else {
+		user.Timezone = "UTC" // default
+	}
 	if isVerified, ok := rawUser["is_verified"].(bool); ok {
 		user.IsVerified = isVerified
 	}
@@ -308,6 +329,24 @@ func (r *SupabaseUserRepository) fetchOne(ctx context.Context, q url.Values) (*m
 	} else {
 		user.ProfilePrivacy = "public" // default
 	}
+	if discoverable, ok := rawUser["discoverable_by_email"].(bool); ok {
+		user.Discoverable = discoverable
+	} else {
+		user.Discoverable = true // default
+	}
+	if showNSFW, ok := rawUser["show_nsfw"].(bool); ok {
+		user.ShowNSFW = showNSFW
+	}
+	if hideInteracted, ok := rawUser["hide_interacted_posts"].(bool); ok {
+		user.HideInteracted = hideInteracted
+	}
+	if feedLanguages, ok := rawUser["feed_languages"].([]interface{}); ok {
+		for _, lang := range feedLanguages {
+			if code, ok := lang.(string); ok {
+				user.FeedLanguages = append(user.FeedLanguages, code)
+			}
+		}
+	}
 	if fieldVisibilityRaw, ok := rawUser["field_visibility"].(map[string]interface{}); ok {
 		fieldVisibility := make(map[string]bool)
 		for k, v := range fieldVisibilityRaw {
@@ -382,12 +421,7 @@ func (r *SupabaseUserRepository) fetchOne(ctx context.Context, q url.Values) (*m
 		}
 	}
 
-	var users []models.User
-	users = append(users, *user)
-	if len(users) == 0 {
-		return nil, apperr.ErrUserNotFound
-	}
-	return &users[0], nil
+	return user
 }
 
 func (r *SupabaseUserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
@@ -432,6 +466,40 @@ func (r *SupabaseUserRepository) GetUserByLinkedInID(ctx context.Context, linked
 	return r.fetchOne(ctx, q)
 }
 
+func (r *SupabaseUserRepository) GetUserByAppleID(ctx context.Context, appleID string) (*models.User, error) {
+	q := url.Values{}
+	q.Set("apple_id", "eq."+appleID)
+	return r.fetchOne(ctx, q)
+}
+
+func (r *SupabaseUserRepository) GetUserByTwitterID(ctx context.Context, twitterID string) (*models.User, error) {
+	q := url.Values{}
+	q.Set("twitter_id", "eq."+twitterID)
+	return r.fetchOne(ctx, q)
+}
+
+// oauthIDColumns maps an OAuth provider name to the users column holding its account ID
+var oauthIDColumns = map[string]string{
+	"google":   "google_id",
+	"github":   "github_id",
+	"linkedin": "linkedin_id",
+	"apple":    "apple_id",
+	"twitter":  "twitter_id",
+}
+
+// LinkOAuthID stores providerID as the user's account ID with provider. UpdateUser
+// can't do this, since the ID fields are left out of the user's JSON.
+func (r *SupabaseUserRepository) LinkOAuthID(ctx context.Context, userID uuid.UUID, provider, providerID string) error {
+	column, ok := oauthIDColumns[provider]
+	if !ok {
+		return apperr.ErrInvalidInput
+	}
+	return r.updateUserFields(ctx, userID, map[string]interface{}{
+		column:       providerID,
+		"updated_at": time.Now(),
+	})
+}
+
 func (r *SupabaseUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
 	body, _ := json.Marshal(user)
 	q := url.Values{}
@@ -724,39 +792,17 @@ func (r *SupabaseUserRepository) CheckUsernameExists(ctx context.Context, userna
 	return len(users) > 0, nil
 }
 
-// UpdateProfile updates user profile fields
-func (r *SupabaseUserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) error {
+// UpdateProfile updates user profile fields and returns the updated user
+func (r *SupabaseUserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) (*models.User, error) {
 	// Always update the updated_at timestamp
 	updates["updated_at"] = time.Now()
 
-	body, err := json.Marshal(updates)
+	user, err := r.updateUserReturning(ctx, userID, updates)
 	if err != nil {
-		return apperr.ErrInternalServer
-	}
-
-	q := url.Values{}
-	q.Set("id", "eq."+userID.String())
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, r.usersURL(q), bytes.NewReader(body))
-	if err != nil {
-		return apperr.ErrInternalServer
-	}
-
-	r.setHeaders(req, "return=minimal")
-
-	resp, err := r.http.Do(req)
-	if err != nil {
-		return apperr.ErrDatabaseError
+		log.Printf("[Supabase] UpdateProfile failed: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 300 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("[Supabase] UpdateProfile failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
-		return apperr.ErrDatabaseError
-	}
-
-	return nil
+	return user, nil
 }
 
 // UpdatePassword updates user's password hash
@@ -1024,6 +1070,9 @@ func (r *SupabaseUserRepository) UpdateBasicProfile(ctx context.Context, userID
 	if req.Website != nil {
 		update["website"] = *req.Website
 	}
+	if req.Timezone != nil {
+		update["timezone"] = *req.Timezone
+	}
 
 	update["updated_at"] = time.Now().Format("2006-01-02T15:04:05.999999999Z07:00")
 
@@ -1064,6 +1113,22 @@ func (r *SupabaseUserRepository) UpdatePrivacySettings(ctx context.Context, user
 		"field_visibility": req.FieldVisibility,
 		"updated_at":       time.Now().Format("2006-01-02T15:04:05.999999999Z07:00"),
 	}
+	if req.Discoverable != nil {
+		update["discoverable_by_email"] = *req.Discoverable
+	}
+	if req.ShowNSFW != nil {
+		update["show_nsfw"] = *req.ShowNSFW
+	}
+	if req.HideInteracted != nil {
+		update["hide_interacted_posts"] = *req.HideInteracted
+	}
+	if req.FeedLanguages != nil {
+		languages := make([]string, 0, len(req.FeedLanguages))
+		for _, lang := range req.FeedLanguages {
+			languages = append(languages, strings.ToLower(lang))
+		}
+		update["feed_languages"] = languages
+	}
 
 	body, err := json.Marshal(update)
 	if err != nil {
@@ -1367,6 +1432,175 @@ func (r *SupabaseUserRepository) SearchUsers(ctx context.Context, query string,
 	return users, totalCount, nil
 }
 
+// SuggestUsers returns up to limit active users whose username or display name starts
+// with prefix, verified and popular accounts first. It loads only what a suggestion
+// shows.
+func (r *SupabaseUserRepository) SuggestUsers(ctx context.Context, prefix string, limit int) ([]*models.User, error) {
+	q := url.Values{}
+	q.Set("select", "id,username,display_name,profile_picture,is_verified")
+	q.Set("or", fmt.Sprintf("(username.ilike.%s*,display_name.ilike.%s*)", prefix, prefix))
+	q.Set("is_active", "eq.true")
+	q.Set("order", "is_verified.desc,followers_count.desc")
+	q.Set("limit", fmt.Sprintf("%d", limit))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.usersURL(q), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	r.setHeaders(req, "")
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest users: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to suggest users: HTTP %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		ID             uuid.UUID `json:"id"`
+		Username       string    `json:"username"`
+		DisplayName    string    `json:"display_name"`
+		ProfilePicture *string   `json:"profile_picture"`
+		IsVerified     bool      `json:"is_verified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to parse suggested users: %w", err)
+	}
+
+	users := make([]*models.User, len(results))
+	for i, result := range results {
+		users[i] = &models.User{
+			ID:             result.ID,
+			Username:       result.Username,
+			DisplayName:    result.DisplayName,
+			ProfilePicture: result.ProfilePicture,
+			IsVerified:     result.IsVerified,
+		}
+	}
+	return users, nil
+}
+
+// GetDiscoverableUsersByEmailHashes returns active users whose email hash is in the given list
+// and who have not opted out of contact discovery. Raw emails are never queried or returned.
+func (r *SupabaseUserRepository) GetDiscoverableUsersByEmailHashes(ctx context.Context, emailHashes []string) ([]*models.User, error) {
+	if len(emailHashes) == 0 {
+		return []*models.User{}, nil
+	}
+
+	q := url.Values{}
+	q.Set("select", "id,email_hash,username,display_name,profile_picture,is_verified,profile_privacy")
+	q.Set("email_hash", "in.("+strings.Join(emailHashes, ",")+")")
+	q.Set("discoverable_by_email", "eq.true")
+	q.Set("is_active", "eq.true")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.usersURL(q), nil)
+	if err != nil {
+		return nil, apperr.ErrInternalServer
+	}
+	r.setHeaders(req, "")
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		log.Printf("[Supabase] GetDiscoverableUsersByEmailHashes request failed: %v", err)
+		return nil, apperr.ErrDatabaseError
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("[Supabase] GetDiscoverableUsersByEmailHashes failed: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
+		return nil, apperr.ErrDatabaseError
+	}
+
+	var rawUsers []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rawUsers); err != nil {
+		log.Printf("[Supabase] GetDiscoverableUsersByEmailHashes decode error: %v", err)
+		return nil, apperr.ErrDatabaseError
+	}
+
+	users := make([]*models.User, 0, len(rawUsers))
+	for _, rawUser := range rawUsers {
+		user := &models.User{Discoverable: true, IsActive: true}
+		if idStr, ok := rawUser["id"].(string); ok {
+			if id, err := uuid.Parse(idStr); err == nil {
+				user.ID = id
+			}
+		}
+		if user.ID == uuid.Nil {
+			continue
+		}
+		if emailHash, ok := rawUser["email_hash"].(string); ok {
+			user.EmailHash = emailHash
+		}
+		if username, ok := rawUser["username"].(string); ok {
+			user.Username = username
+		}
+		if displayName, ok := rawUser["display_name"].(string); ok {
+			user.DisplayName = displayName
+		}
+		if profilePicture, ok := rawUser["profile_picture"].(string); ok && profilePicture != "" {
+			user.ProfilePicture = &profilePicture
+		}
+		if isVerified, ok := rawUser["is_verified"].(bool); ok {
+			user.IsVerified = isVerified
+		}
+		if profilePrivacy, ok := rawUser["profile_privacy"].(string); ok {
+			user.ProfilePrivacy = profilePrivacy
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// GetPrivateUserIDs returns which of userIDs have private profiles, in one query
+func (r *SupabaseUserRepository) GetPrivateUserIDs(ctx context.Context, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+	q := url.Values{}
+	q.Set("select", "id")
+	q.Set("id", "in.("+strings.Join(ids, ",")+")")
+	q.Set("profile_privacy", "eq.private")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.usersURL(q), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	r.setHeaders(req, "")
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get private users: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to get private users: HTTP %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to parse private users: %w", err)
+	}
+
+	private := make([]uuid.UUID, len(results))
+	for i, result := range results {
+		private[i] = result.ID
+	}
+	return private, nil
+}
+
 // IncrementFollowerCount increments the followers_count for a user
 func (r *SupabaseUserRepository) IncrementFollowerCount(ctx context.Context, userID uuid.UUID) error {
 	log.Printf("[IncrementFollowerCount] Starting for user %s", userID)
@@ -1530,11 +1764,52 @@ func (r *SupabaseUserRepository) updateUserFields(ctx context.Context, userID uu
 	return nil
 }
 
-// BlockUser blocks a user (complete barrier - no messaging, content hidden)
+// updateUserReturning applies fields like updateUserFields but asks PostgREST for the
+// updated row, for callers that would otherwise read the user back
+func (r *SupabaseUserRepository) updateUserReturning(ctx context.Context, userID uuid.UUID, fields map[string]interface{}) (*models.User, error) {
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, apperr.ErrInternalServer
+	}
+
+	q := url.Values{}
+	q.Set("id", "eq."+userID.String())
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPatch, r.usersURL(q), bytes.NewReader(body))
+	if err != nil {
+		return nil, apperr.ErrInternalServer
+	}
+
+	r.setHeaders(httpReq, "return=representation")
+
+	resp, err := r.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: request failed: %v", apperr.ErrDatabaseError, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: HTTP %d - %s", apperr.ErrDatabaseError, resp.StatusCode, string(bodyBytes))
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &rows); err != nil {
+		return nil, fmt.Errorf("%w: decode error: %v", apperr.ErrDatabaseError, err)
+	}
+	if len(rows) == 0 {
+		return nil, apperr.ErrUserNotFound
+	}
+	return decodeUserRow(rows[0]), nil
+}
+
+// BlockUser blocks a user (complete barrier - no messaging, content hidden). The
+// block and the removal of every relationship between the two users happen in one
+// database transaction.
 func (r *SupabaseUserRepository) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
 	payload := map[string]interface{}{
-		"blocker_id": blockerID.String(),
-		"blocked_id": blockedID.String(),
+		"p_blocker": blockerID.String(),
+		"p_blocked": blockedID.String(),
 	}
 
 	body, err := json.Marshal(payload)
@@ -1542,7 +1817,7 @@ func (r *SupabaseUserRepository) BlockUser(ctx context.Context, blockerID, block
 		return fmt.Errorf("failed to marshal block request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/blocked_users", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/rpc/block_user", bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -1556,10 +1831,6 @@ func (r *SupabaseUserRepository) BlockUser(ctx context.Context, blockerID, block
 
 	if resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		// Check if it's a unique constraint violation (already blocked)
-		if strings.Contains(string(bodyBytes), "duplicate") || strings.Contains(string(bodyBytes), "unique") {
-			return nil // Already blocked, no error
-		}
 		return fmt.Errorf("failed to block user: HTTP %d - %s", resp.StatusCode, string(bodyBytes))
 	}
 
@@ -1662,6 +1933,98 @@ func (r *SupabaseUserRepository) GetBlockedUserIDs(ctx context.Context, userID u
 	return userIDs, nil
 }
 
+// GetBlockedEitherWayIDs gets the IDs of everyone userID has blocked or been blocked by
+func (r *SupabaseUserRepository) GetBlockedEitherWayIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	q := url.Values{}
+	q.Set("or", fmt.Sprintf("(blocker_id.eq.%s,blocked_id.eq.%s)", userID, userID))
+	q.Set("select", "blocker_id,blocked_id")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/blocked_users?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	r.setHeaders(req, "")
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to get blocks: HTTP %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		BlockerID uuid.UUID `json:"blocker_id"`
+		BlockedID uuid.UUID `json:"blocked_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to parse blocks: %w", err)
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(results))
+	for _, result := range results {
+		if result.BlockerID == userID {
+			userIDs = append(userIDs, result.BlockedID)
+		} else {
+			userIDs = append(userIDs, result.BlockerID)
+		}
+	}
+	return userIDs, nil
+}
+
+// GetBlockedEitherWayAmong returns which of otherIDs userID has blocked or been blocked
+// by, in one query
+func (r *SupabaseUserRepository) GetBlockedEitherWayAmong(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if len(otherIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(otherIDs))
+	for i, id := range otherIDs {
+		ids[i] = id.String()
+	}
+	others := strings.Join(ids, ",")
+
+	q := url.Values{}
+	q.Set("or", fmt.Sprintf("(and(blocker_id.eq.%s,blocked_id.in.(%s)),and(blocked_id.eq.%s,blocker_id.in.(%s)))", userID, others, userID, others))
+	q.Set("select", "blocker_id,blocked_id")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/blocked_users?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	r.setHeaders(req, "")
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to get blocks: HTTP %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		BlockerID uuid.UUID `json:"blocker_id"`
+		BlockedID uuid.UUID `json:"blocked_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to parse blocks: %w", err)
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(results))
+	for _, result := range results {
+		if result.BlockerID == userID {
+			userIDs = append(userIDs, result.BlockedID)
+		} else {
+			userIDs = append(userIDs, result.BlockerID)
+		}
+	}
+	return userIDs, nil
+}
+
 // RestrictUser restricts a user (their content is hidden from feed)
 func (r *SupabaseUserRepository) RestrictUser(ctx context.Context, restrictorID, restrictedID uuid.UUID) error {
 	payload := map[string]interface{}{
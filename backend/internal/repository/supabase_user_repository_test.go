@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+func TestBlockUserCallsBlockRPC(t *testing.T) {
+	blocker, blocked := uuid.New(), uuid.New()
+	var calls int
+	var params map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/v1/rpc/block_user" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		calls++
+		json.NewDecoder(r.Body).Decode(&params)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := NewSupabaseUserRepository(server.URL, "key")
+	if err := repo.BlockUser(context.Background(), blocker, blocked); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+	if calls != 1 || params["p_blocker"] != blocker.String() || params["p_blocked"] != blocked.String() {
+		t.Errorf("block_user called %d times with %v", calls, params)
+	}
+}
+
+// returningServer answers PATCHes on table with row, failing the test on any other
+// request or if the row wasn't asked for, and counts the requests made
+func returningServer(t *testing.T, table, row string) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != http.MethodPatch || r.URL.Path != "/rest/v1/"+table {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if prefer := r.Header.Get("Prefer"); prefer != "return=representation" {
+			t.Errorf("Prefer = %q, want the updated row returned", prefer)
+		}
+		w.Write([]byte("[" + row + "]"))
+	}))
+	return server, &calls
+}
+
+func TestUpdateProfileReturnsTheUpdatedUser(t *testing.T) {
+	id := uuid.New()
+	server, calls := returningServer(t, "users", `{
+		"id": "`+id.String()+`", "username": "ada", "display_name": "Ada L.", "bio": "Engines",
+		"updated_at": "2026-10-15T09:30:00.123456+00:00"
+	}`)
+	defer server.Close()
+
+	user, err := NewSupabaseUserRepository(server.URL, "key").UpdateProfile(context.Background(), id,
+		map[string]interface{}{"display_name": "Ada L.", "bio": "Engines"})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("made %d requests, want the update alone", *calls)
+	}
+	if user.ID != id || user.DisplayName != "Ada L." || user.Bio == nil || *user.Bio != "Engines" {
+		t.Errorf("user = %+v, want the new values", user)
+	}
+	if want := time.Date(2026, 10, 15, 9, 30, 0, 123456000, time.UTC); !user.UpdatedAt.Equal(want) {
+		t.Errorf("updated_at = %v, want %v", user.UpdatedAt, want)
+	}
+}
+
+func TestUpdateProfileOfMissingUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	_, err := NewSupabaseUserRepository(server.URL, "key").UpdateProfile(context.Background(), uuid.New(),
+		map[string]interface{}{"bio": "x"})
+	if err != apperr.ErrUserNotFound {
+		t.Errorf("err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestGetDiscoverableUsersByEmailHashesQueriesOnlyOptedInMatches(t *testing.T) {
+	hashes := []string{strings.Repeat("a", 64), strings.Repeat("b", 64)}
+	id := uuid.New()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != http.MethodGet || r.URL.Path != "/rest/v1/users" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query := r.URL.Query()
+		want := map[string]string{
+			"email_hash":            "in.(" + hashes[0] + "," + hashes[1] + ")",
+			"discoverable_by_email": "eq.true",
+			"is_active":             "eq.true",
+		}
+		for param, value := range want {
+			if got := query.Get(param); got != value {
+				t.Errorf("%s = %q, want %q", param, got, value)
+			}
+		}
+		if strings.Contains(query.Get("select"), "email,") {
+			t.Errorf("select = %q, raw emails should never be read", query.Get("select"))
+		}
+		w.Write([]byte(`[{"id": "` + id.String() + `", "email_hash": "` + hashes[1] + `", "username": "ada", "profile_privacy": "public"}]`))
+	}))
+	defer server.Close()
+
+	users, err := NewSupabaseUserRepository(server.URL, "key").GetDiscoverableUsersByEmailHashes(context.Background(), hashes)
+	if err != nil {
+		t.Fatalf("GetDiscoverableUsersByEmailHashes: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != id || users[0].EmailHash != hashes[1] || users[0].Username != "ada" {
+		t.Fatalf("users = %+v, want the one match with its hash", users)
+	}
+
+	// Nothing to look up makes no request
+	if users, err := NewSupabaseUserRepository(server.URL, "key").GetDiscoverableUsersByEmailHashes(context.Background(), nil); err != nil || len(users) != 0 {
+		t.Errorf("no hashes: got %v, %v", users, err)
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1", calls)
+	}
+}
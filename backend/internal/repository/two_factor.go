@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactorRepository defines the data-access contract for TOTP secrets and recovery codes
+type TwoFactorRepository interface {
+	GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactorSecret, error) // Nil if never set up
+	SavePendingTwoFactor(ctx context.Context, secret *models.TwoFactorSecret) error      // Replaces any pending secret
+	EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64) (bool, error)     // False if already enabled
+	MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) (bool, error)    // False if the step, or a later one, was already used
+	DeleteTwoFactor(ctx context.Context, userID uuid.UUID) error                         // Also deletes recovery codes
+
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) // False if unknown or already used
+}
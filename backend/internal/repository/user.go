@@ -23,6 +23,9 @@ type UserRepository interface {
 	GetUserByGoogleID(ctx context.Context, googleID string) (*models.User, error)
 	GetUserByGitHubID(ctx context.Context, githubID string) (*models.User, error)
 	GetUserByLinkedInID(ctx context.Context, linkedinID string) (*models.User, error)
+	GetUserByAppleID(ctx context.Context, appleID string) (*models.User, error)
+	GetUserByTwitterID(ctx context.Context, twitterID string) (*models.User, error)
+	LinkOAuthID(ctx context.Context, userID uuid.UUID, provider, providerID string) error // provider is e.g. "apple"
 	UpdateUser(ctx context.Context, user *models.User) error
 	UpdateEmailVerification(ctx context.Context, email, code string, expiresAt time.Time) error
 	VerifyEmail(ctx context.Context, email, code string) error
@@ -32,7 +35,7 @@ type UserRepository interface {
 	UpdateLastUsed(ctx context.Context, userID uuid.UUID) error
 	CheckEmailExists(ctx context.Context, email string) (bool, error)
 	CheckUsernameExists(ctx context.Context, username string) (bool, error)
-	UpdateProfile(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) error
+	UpdateProfile(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) (*models.User, error) // Returns the updated row
 	UpdatePassword(ctx context.Context, userID uuid.UUID, newPasswordHash string) error
 	DeleteUser(ctx context.Context, userID uuid.UUID) error
 	InitiateEmailChange(ctx context.Context, userID uuid.UUID, newEmail, code string, expiresAt time.Time) error
@@ -50,6 +53,13 @@ type UserRepository interface {
 
 	// Search
 	SearchUsers(ctx context.Context, query string, limit int, offset int) ([]*models.User, int, error)
+	SuggestUsers(ctx context.Context, prefix string, limit int) ([]*models.User, error) // Only ID, names, picture and verification are loaded
+
+	// Contact discovery (only active users who haven't opted out)
+	GetDiscoverableUsersByEmailHashes(ctx context.Context, emailHashes []string) ([]*models.User, error)
+
+	// Privacy: which of userIDs have private profiles
+	GetPrivateUserIDs(ctx context.Context, userIDs []uuid.UUID) ([]uuid.UUID, error)
 
 	// Stats updates
 	IncrementFollowerCount(ctx context.Context, userID uuid.UUID) error
@@ -65,6 +75,8 @@ type UserRepository interface {
 	UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
 	IsUserBlocked(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error)
 	GetBlockedUserIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	GetBlockedEitherWayIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	GetBlockedEitherWayAmong(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) ([]uuid.UUID, error) // Which of otherIDs
 	
 	RestrictUser(ctx context.Context, restrictorID, restrictedID uuid.UUID) error
 	UnrestrictUser(ctx context.Context, restrictorID, restrictedID uuid.UUID) error
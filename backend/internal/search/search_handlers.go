@@ -4,11 +4,12 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"histeeria-backend/internal/utils"
 	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // SearchHandlers handles HTTP requests for search
@@ -57,8 +58,9 @@ func (h *SearchHandlers) SearchUsers(c *gin.Context) {
 
 	log.Printf("[SearchHandler] Calling service.SearchUsers with query='%s', page=%d, limit=%d", query, page, limit)
 	
-	// Search users
-	users, total, err := h.service.SearchUsers(c.Request.Context(), query, page, limit)
+	// Search users; signed-in searchers don't see users blocked either way
+	viewerID, _ := utils.CurrentUserID(c)
+	users, total, err := h.service.SearchUsers(c.Request.Context(), query, viewerID, page, limit)
 	if err != nil {
 		log.Printf("[SearchHandler] Error from service.SearchUsers: %v", err)
 		appErr := errors.GetAppError(err)
@@ -72,20 +74,9 @@ func (h *SearchHandlers) SearchUsers(c *gin.Context) {
 	log.Printf("[SearchHandler] Found %d users (total: %d)", len(users), total)
 
 	// Convert to safe user objects (remove sensitive data)
-	safeUsers := make([]interface{}, 0, len(users))
+	safeUsers := make([]*UserHit, 0, len(users))
 	for _, user := range users {
-		safeUser := map[string]interface{}{
-			"id":              user.ID,
-			"username":        user.Username,
-			"display_name":    user.DisplayName,
-			"profile_picture": user.ProfilePicture,
-			"bio":             user.Bio,
-			"location":        user.Location,
-			"is_verified":     user.IsVerified,
-			"followers_count": user.FollowersCount,
-			"following_count": user.FollowingCount,
-		}
-		safeUsers = append(safeUsers, safeUser)
+		safeUsers = append(safeUsers, newUserHit(user))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -123,21 +114,17 @@ func (h *SearchHandlers) SearchPosts(c *gin.Context) {
 	}
 	
 	// Get current user ID from context (optional - can search without auth)
-	userID := uuid.Nil // Default to nil UUID if not authenticated
-	if userIDVal, exists := c.Get("userID"); exists {
-		if userIDStr, ok := userIDVal.(string); ok {
-			if parsedID, err := uuid.Parse(userIDStr); err == nil {
-				userID = parsedID
-			}
-		}
-	}
+	userID, _ := utils.CurrentUserID(c) // uuid.Nil if not authenticated
 
 	// Get pagination params
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 
+	// Counting every match is expensive, so the total is only returned when asked for
+	withCount := c.Query("count") == "true"
+
 	// Search posts
-	posts, total, err := h.service.SearchPosts(c.Request.Context(), query, userID, page, limit)
+	posts, postsPage, err := h.service.SearchPosts(c.Request.Context(), query, userID, page, limit, withCount)
 	if err != nil {
 		appErr := errors.GetAppError(err)
 		c.JSON(appErr.Code, gin.H{
@@ -147,12 +134,93 @@ func (h *SearchHandlers) SearchPosts(c *gin.Context) {
 		return
 	}
 
+	response := gin.H{
+		"success":  true,
+		"posts":    posts,
+		"page":     page,
+		"limit":    limit,
+		"has_more": postsPage.HasMore,
+	}
+	if postsPage.Counted() {
+		response["total"] = postsPage.Total
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Search handles GET /api/v1/search?q=&types=users,posts,hashtags,courses: one page
+// of each type, searched concurrently and grouped by type. Each group has its own
+// next_cursor; passing it back as ?cursor= (repeatable, one per type) continues that
+// type. Signed-in searchers don't see blocked users or content they can't access.
+func (h *SearchHandlers) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Search query is required",
+		})
+		return
+	}
+
+	types, err := ParseTypes(c.Query("types"))
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"success": false, "message": appErr.Message})
+		return
+	}
+
+	offsets := make(map[string]int)
+	for _, cursor := range c.QueryArray("cursor") {
+		resultType, offset, err := DecodeCursor(cursor)
+		if err != nil {
+			appErr := errors.GetAppError(err)
+			c.JSON(appErr.Code, gin.H{"success": false, "message": appErr.Message})
+			return
+		}
+		offsets[resultType] = offset
+	}
+
+	// Minimum query length, as for the single-type searches
+	if len([]rune(query)) < 2 {
+		groups := make(map[string]*ResultGroup, len(types))
+		for _, t := range types {
+			groups[t] = &ResultGroup{Results: []Hit{}}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"query":   query,
+			"results": groups,
+			"message": "Please enter at least 2 characters to search",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	viewerID, _ := utils.CurrentUserID(c)
+
+	groups := h.service.Search(c.Request.Context(), UnifiedQuery{
+		Query:    query,
+		Types:    types,
+		Limit:    limit,
+		Offsets:  offsets,
+		ViewerID: viewerID,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"posts":   posts,
-		"total":   total,
-		"page":    page,
-		"limit":   limit,
+		"query":   query,
+		"results": groups,
+	})
+}
+
+// Suggest handles GET /api/v1/search/suggest?q=: autocomplete for the search box.
+// A leading @ or # in q narrows the suggestions to users or hashtags.
+func (h *SearchHandlers) Suggest(c *gin.Context) {
+	prefix := NormalizeSuggestPrefix(c.Query("q"))
+	viewerID, _ := utils.CurrentUserID(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"suggestions": h.service.Suggest(c.Request.Context(), prefix, viewerID),
 	})
 }
 
@@ -160,6 +228,8 @@ func (h *SearchHandlers) SearchPosts(c *gin.Context) {
 func (h *SearchHandlers) SetupRoutes(router *gin.RouterGroup) {
 	search := router.Group("/search")
 	{
+		search.GET("", h.Search)
+		search.GET("/suggest", h.Suggest)
 		search.GET("/users", h.SearchUsers)
 		search.GET("/posts", h.SearchPosts)
 	}
@@ -2,7 +2,11 @@ package search
 
 import (
 	"context"
+	"log"
+
+	"histeeria-backend/internal/cache"
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/posts"
 	"histeeria-backend/internal/repository"
 	
 	"github.com/google/uuid"
@@ -10,8 +14,11 @@ import (
 
 // SearchService handles search business logic
 type SearchService struct {
-	userRepo repository.UserRepository
-	postRepo repository.PostRepository
+	userRepo      repository.UserRepository
+	postRepo      repository.PostRepository
+	feeds         *posts.FeedService
+	courseRepo    repository.CourseRepository
+	cacheProvider cache.CacheProvider
 }
 
 // NewSearchService creates a new search service
@@ -22,8 +29,21 @@ func NewSearchService(userRepo repository.UserRepository, postRepo repository.Po
 	}
 }
 
-// SearchUsers searches for users matching the query
-func (s *SearchService) SearchUsers(ctx context.Context, query string, page int, limit int) ([]*models.User, int, error) {
+// SetFeedService sets the feed service posts are searched through, so results leave
+// out private accounts' posts, close friends posts and NSFW posts like the other
+// discovery feeds. Without it post search only drops blocked and restricted content.
+func (s *SearchService) SetFeedService(feeds *posts.FeedService) {
+	s.feeds = feeds
+}
+
+// SetCourseRepository enables course search
+func (s *SearchService) SetCourseRepository(courseRepo repository.CourseRepository) {
+	s.courseRepo = courseRepo
+}
+
+// SearchUsers searches for users matching the query. Users blocked either way with
+// viewerID are left out of the page.
+func (s *SearchService) SearchUsers(ctx context.Context, query string, viewerID uuid.UUID, page int, limit int) ([]*models.User, int, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20 // Default limit
 	}
@@ -33,11 +53,50 @@ func (s *SearchService) SearchUsers(ctx context.Context, query string, page int,
 
 	offset := (page - 1) * limit
 
-	return s.userRepo.SearchUsers(ctx, query, limit, offset)
+	users, total, err := s.userRepo.SearchUsers(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.withoutBlocked(ctx, users, viewerID), total, nil
+}
+
+// withoutBlocked drops the users blocked either way with viewerID
+func (s *SearchService) withoutBlocked(ctx context.Context, users []*models.User, viewerID uuid.UUID) []*models.User {
+	blocked := s.blockedWith(ctx, viewerID)
+	if len(blocked) == 0 {
+		return users
+	}
+
+	kept := make([]*models.User, 0, len(users))
+	for _, user := range users {
+		if !blocked[user.ID] {
+			kept = append(kept, user)
+		}
+	}
+	return kept
+}
+
+// blockedWith returns the users viewerID has blocked or been blocked by. Anonymous
+// viewers have none.
+func (s *SearchService) blockedWith(ctx context.Context, viewerID uuid.UUID) map[uuid.UUID]bool {
+	blocked := make(map[uuid.UUID]bool)
+	if viewerID == uuid.Nil {
+		return blocked
+	}
+
+	ids, err := s.userRepo.GetBlockedEitherWayIDs(ctx, viewerID)
+	if err != nil {
+		log.Printf("[SearchService] Failed to get users blocked with %s: %v", viewerID, err)
+		return blocked
+	}
+	for _, id := range ids {
+		blocked[id] = true
+	}
+	return blocked
 }
 
 // SearchPosts searches for posts matching the query
-func (s *SearchService) SearchPosts(ctx context.Context, query string, userID uuid.UUID, page int, limit int) ([]models.Post, int, error) {
+func (s *SearchService) SearchPosts(ctx context.Context, query string, userID uuid.UUID, page int, limit int, withCount bool) ([]models.Post, models.Page, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 20 // Default limit
 	}
@@ -47,6 +106,15 @@ func (s *SearchService) SearchPosts(ctx context.Context, query string, userID uu
 
 	offset := (page - 1) * limit
 
+	return s.searchPosts(ctx, query, userID, limit, offset, withCount)
+}
+
+// searchPosts searches posts through the feed service when there is one
+func (s *SearchService) searchPosts(ctx context.Context, query string, userID uuid.UUID, limit, offset int, withCount bool) ([]models.Post, models.Page, error) {
+	if s.feeds != nil {
+		return s.feeds.SearchPosts(ctx, query, userID, limit, offset, withCount)
+	}
+
 	// Call the repository's SearchPosts (which matches the interface signature)
-	return s.postRepo.SearchPosts(ctx, query, userID, limit, offset)
+	return s.postRepo.SearchPosts(ctx, query, userID, limit, offset, withCount)
 }
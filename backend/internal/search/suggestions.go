@@ -0,0 +1,233 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"histeeria-backend/internal/cache"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// suggestionsPerType caps each source so a keystroke costs three small queries
+	suggestionsPerType = 5
+	// maxSuggestPrefix is how much of the typed text is matched on
+	maxSuggestPrefix = 50
+	// suggestionsTTL is how long a prefix's suggestions are reused. Short, so new
+	// accounts and hashtags show up quickly.
+	suggestionsTTL    = 30 * time.Second
+	suggestionsPrefix = "search_suggest:"
+)
+
+// Suggestion types
+const (
+	suggestionUser    = "user"
+	suggestionHashtag = "hashtag"
+	suggestionCourse  = "course"
+)
+
+// Suggestion is one autocomplete entry for the search box
+type Suggestion struct {
+	ID     uuid.UUID `json:"id"`
+	Label  string    `json:"label"`            // @username, #tag or course title
+	Type   string    `json:"type"`             // user, hashtag or course
+	Avatar *string   `json:"avatar,omitempty"` // Profile picture or course thumbnail
+}
+
+// suggestionEntry is a suggestion as cached. The cache is shared by every searcher, so
+// it keeps whose account or course each entry is, for filtering blocks per request.
+type suggestionEntry struct {
+	Suggestion
+	OwnerID uuid.UUID `json:"owner_id"`
+}
+
+// SetCacheProvider sets where suggestions are cached by prefix. Without it every
+// keystroke queries the database.
+func (s *SearchService) SetCacheProvider(provider cache.CacheProvider) {
+	s.cacheProvider = provider
+}
+
+// NormalizeSuggestPrefix lowercases and trims typed text and drops characters no
+// username, hashtag or title lookup needs. A leading @ or # is kept: it narrows the
+// suggestions to users or hashtags.
+func NormalizeSuggestPrefix(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	sigil := ""
+	if strings.HasPrefix(raw, "@") || strings.HasPrefix(raw, "#") {
+		sigil, raw = raw[:1], raw[1:]
+	}
+
+	var b strings.Builder
+	for _, r := range raw {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '_' || r == '.' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	prefix := strings.Join(strings.Fields(b.String()), " ")
+	if runes := []rune(prefix); len(runes) > maxSuggestPrefix {
+		prefix = string(runes[:maxSuggestPrefix])
+	}
+	if prefix == "" {
+		return ""
+	}
+	return sigil + prefix
+}
+
+// Suggest returns autocomplete suggestions for a prefix normalized by
+// NormalizeSuggestPrefix: usernames, hashtags and course titles starting with it, up to
+// five of each, without duplicates. Users blocked either way with viewerID and their
+// courses are left out.
+func (s *SearchService) Suggest(ctx context.Context, prefix string, viewerID uuid.UUID) []Suggestion {
+	if prefix == "" {
+		return []Suggestion{}
+	}
+
+	entries, ok := s.cachedSuggestions(ctx, prefix)
+	if !ok {
+		entries = s.loadSuggestions(ctx, prefix)
+		s.cacheSuggestions(ctx, prefix, entries)
+	}
+
+	// Hashtag-only suggestions have nobody to be blocked
+	blocked := map[uuid.UUID]bool{}
+	for _, entry := range entries {
+		if entry.OwnerID != uuid.Nil {
+			blocked = s.blockedWith(ctx, viewerID)
+			break
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(entries))
+	for _, entry := range entries {
+		if !blocked[entry.OwnerID] {
+			suggestions = append(suggestions, entry.Suggestion)
+		}
+	}
+	return suggestions
+}
+
+// loadSuggestions queries each source the prefix allows concurrently. A source that
+// fails is logged and left out. Users come first, then hashtags, then courses.
+func (s *SearchService) loadSuggestions(ctx context.Context, prefix string) []suggestionEntry {
+	only := ""
+	switch prefix[0] {
+	case '@':
+		only, prefix = suggestionUser, prefix[1:]
+	case '#':
+		only, prefix = suggestionHashtag, prefix[1:]
+	}
+	wants := func(source string) bool { return only == "" || only == source }
+
+	// One slot per source, in the order they're listed
+	var sources [3][]suggestionEntry
+	var wg sync.WaitGroup
+
+	if wants(suggestionUser) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users, err := s.userRepo.SuggestUsers(ctx, prefix, suggestionsPerType)
+			if err != nil {
+				log.Printf("[SearchService] Failed to suggest users for '%s': %v", prefix, err)
+				return
+			}
+			for _, user := range users {
+				sources[0] = append(sources[0], suggestionEntry{
+					Suggestion: Suggestion{ID: user.ID, Label: "@" + user.Username, Type: suggestionUser, Avatar: user.ProfilePicture},
+					OwnerID:    user.ID,
+				})
+			}
+		}()
+	}
+
+	if wants(suggestionHashtag) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hashtags, err := s.postRepo.SuggestHashtags(ctx, prefix, suggestionsPerType)
+			if err != nil {
+				log.Printf("[SearchService] Failed to suggest hashtags for '%s': %v", prefix, err)
+				return
+			}
+			for _, hashtag := range hashtags {
+				sources[1] = append(sources[1], suggestionEntry{
+					Suggestion: Suggestion{ID: hashtag.ID, Label: "#" + hashtag.Tag, Type: suggestionHashtag},
+				})
+			}
+		}()
+	}
+
+	if wants(suggestionCourse) && s.courseRepo != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			courses, err := s.courseRepo.SuggestCourses(ctx, prefix, suggestionsPerType)
+			if err != nil {
+				log.Printf("[SearchService] Failed to suggest courses for '%s': %v", prefix, err)
+				return
+			}
+			for _, course := range courses {
+				sources[2] = append(sources[2], suggestionEntry{
+					Suggestion: Suggestion{ID: course.ID, Label: course.Title, Type: suggestionCourse, Avatar: course.ThumbnailURL},
+					OwnerID:    course.CreatorID,
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// The same entry can't come back twice from one source, but a label can repeat
+	// across them (two courses with one title); only the first is kept
+	seen := make(map[string]bool)
+	entries := make([]suggestionEntry, 0, len(sources)*suggestionsPerType)
+	for _, source := range sources {
+		for _, entry := range source {
+			key := entry.Type + ":" + strings.ToLower(entry.Label)
+			if seen[key] || seen[entry.ID.String()] {
+				continue
+			}
+			seen[key] = true
+			seen[entry.ID.String()] = true
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// cachedSuggestions returns the cached suggestions for prefix, if there are any
+func (s *SearchService) cachedSuggestions(ctx context.Context, prefix string) ([]suggestionEntry, bool) {
+	if s.cacheProvider == nil {
+		return nil, false
+	}
+	data, err := s.cacheProvider.Get(ctx, suggestionsPrefix+prefix)
+	if err != nil {
+		return nil, false
+	}
+
+	var entries []suggestionEntry
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, false
+	}
+	return entries, true
+}
+
+// cacheSuggestions remembers a prefix's suggestions for suggestionsTTL
+func (s *SearchService) cacheSuggestions(ctx context.Context, prefix string, entries []suggestionEntry) {
+	if s.cacheProvider == nil {
+		return
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := s.cacheProvider.Set(ctx, suggestionsPrefix+prefix, string(data), suggestionsTTL); err != nil {
+		log.Printf("[SearchService] Failed to cache suggestions for '%s': %v", prefix, err)
+	}
+}
@@ -0,0 +1,337 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// Result types a unified search can cover, as named in ?types= and in the response
+const (
+	TypeUsers    = "users"
+	TypePosts    = "posts"
+	TypeHashtags = "hashtags"
+	TypeCourses  = "courses"
+)
+
+// AllTypes is what a unified search covers when no types are asked for
+var AllTypes = []string{TypeUsers, TypePosts, TypeHashtags, TypeCourses}
+
+// hitTypes is the type discriminator carried by each result of a group
+var hitTypes = map[string]string{
+	TypeUsers:    "user",
+	TypePosts:    "post",
+	TypeHashtags: "hashtag",
+	TypeCourses:  "course",
+}
+
+// UnifiedQuery is a search across several result types at once
+type UnifiedQuery struct {
+	Query    string
+	Types    []string
+	Limit    int            // Per type
+	Offsets  map[string]int // Where each type's page starts, from its cursor
+	ViewerID uuid.UUID      // uuid.Nil for anonymous searches
+}
+
+// UserHit is the public view of a user in search results
+type UserHit struct {
+	ID             uuid.UUID `json:"id"`
+	Username       string    `json:"username"`
+	DisplayName    string    `json:"display_name"`
+	ProfilePicture *string   `json:"profile_picture"`
+	Bio            *string   `json:"bio"`
+	Location       *string   `json:"location"`
+	IsVerified     bool      `json:"is_verified"`
+	FollowersCount int       `json:"followers_count"`
+	FollowingCount int       `json:"following_count"`
+}
+
+// newUserHit keeps the fields of user that are safe to show anyone
+func newUserHit(user *models.User) *UserHit {
+	return &UserHit{
+		ID:             user.ID,
+		Username:       user.Username,
+		DisplayName:    user.DisplayName,
+		ProfilePicture: user.ProfilePicture,
+		Bio:            user.Bio,
+		Location:       user.Location,
+		IsVerified:     user.IsVerified,
+		FollowersCount: user.FollowersCount,
+		FollowingCount: user.FollowingCount,
+	}
+}
+
+// Hit is one search result. Type says which of the other fields is set.
+type Hit struct {
+	Type    string                  `json:"type"` // user, post, hashtag or course
+	User    *UserHit                `json:"user,omitempty"`
+	Post    *models.Post            `json:"post,omitempty"`
+	Hashtag *repository.HashtagInfo `json:"hashtag,omitempty"`
+	Course  *models.Course          `json:"course,omitempty"`
+}
+
+// ResultGroup is a page of results of one type, best matches first
+type ResultGroup struct {
+	Results    []Hit   `json:"results"`
+	NextCursor *string `json:"next_cursor"`     // Null on the last page
+	Error      string  `json:"error,omitempty"` // Set when this type couldn't be searched
+}
+
+// ParseTypes reads a comma-separated ?types= list. Empty means every type.
+func ParseTypes(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return AllTypes, nil
+	}
+
+	seen := make(map[string]bool)
+	types := make([]string, 0, len(AllTypes))
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if _, ok := hitTypes[t]; !ok {
+			return nil, errors.ErrInvalidSearchType
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// EncodeCursor returns the opaque cursor that continues a type's results at offset
+func EncodeCursor(resultType string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(resultType + ":" + strconv.Itoa(offset)))
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor into its type and offset
+func DecodeCursor(cursor string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, errors.ErrInvalidSearchCursor
+	}
+	resultType, offsetStr, ok := strings.Cut(string(raw), ":")
+	if _, known := hitTypes[resultType]; !ok || !known {
+		return "", 0, errors.ErrInvalidSearchCursor
+	}
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		return "", 0, errors.ErrInvalidSearchCursor
+	}
+	return resultType, offset, nil
+}
+
+// Search runs q against each of its types concurrently and returns a group of results
+// per type. A type that fails doesn't fail the others; its group carries the error.
+func (s *SearchService) Search(ctx context.Context, q UnifiedQuery) map[string]*ResultGroup {
+	if q.Limit <= 0 || q.Limit > 50 {
+		q.Limit = 10
+	}
+
+	groups := make(map[string]*ResultGroup, len(q.Types))
+	for _, t := range q.Types {
+		groups[t] = &ResultGroup{Results: []Hit{}}
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range q.Types {
+		wg.Add(1)
+		go func(resultType string, group *ResultGroup) {
+			defer wg.Done()
+			offset := q.Offsets[resultType]
+			hits, next, err := s.searchType(ctx, resultType, q, offset)
+			if err != nil {
+				log.Printf("[SearchService] Failed to search %s for '%s': %v", resultType, q.Query, err)
+				group.Error = "Search failed"
+				return
+			}
+			rankHits(hits, q.Query)
+			group.Results = hits
+			if next > offset {
+				cursor := EncodeCursor(resultType, next)
+				group.NextCursor = &cursor
+			}
+		}(t, groups[t])
+	}
+	wg.Wait()
+
+	return groups
+}
+
+// searchType fetches one page of resultType starting at offset. It returns the offset
+// the next page starts at, or 0 when this is the last page.
+func (s *SearchService) searchType(ctx context.Context, resultType string, q UnifiedQuery, offset int) ([]Hit, int, error) {
+	switch resultType {
+	case TypeUsers:
+		return s.searchUserHits(ctx, q, offset)
+	case TypePosts:
+		return s.searchPostHits(ctx, q, offset)
+	case TypeHashtags:
+		return s.searchHashtagHits(ctx, q, offset)
+	case TypeCourses:
+		return s.searchCourseHits(ctx, q, offset)
+	}
+	return nil, 0, errors.ErrInvalidSearchType
+}
+
+func (s *SearchService) searchUserHits(ctx context.Context, q UnifiedQuery, offset int) ([]Hit, int, error) {
+	users, total, err := s.userRepo.SearchUsers(ctx, q.Query, q.Limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	fetched := len(users)
+	users = s.withoutBlocked(ctx, users, q.ViewerID)
+
+	hits := make([]Hit, 0, len(users))
+	for _, user := range users {
+		hits = append(hits, Hit{Type: hitTypes[TypeUsers], User: newUserHit(user)})
+	}
+
+	next := 0
+	if offset+fetched < total {
+		next = offset + fetched
+	}
+	return hits, next, nil
+}
+
+func (s *SearchService) searchPostHits(ctx context.Context, q UnifiedQuery, offset int) ([]Hit, int, error) {
+	found, page, err := s.searchPosts(ctx, q.Query, q.ViewerID, q.Limit, offset, false)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]Hit, 0, len(found))
+	for i := range found {
+		hits = append(hits, Hit{Type: hitTypes[TypePosts], Post: &found[i]})
+	}
+
+	next := 0
+	if page.HasMore {
+		next = offset + q.Limit
+	}
+	return hits, next, nil
+}
+
+func (s *SearchService) searchHashtagHits(ctx context.Context, q UnifiedQuery, offset int) ([]Hit, int, error) {
+	hashtags, err := s.postRepo.SearchHashtags(ctx, q.Query, q.Limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]Hit, 0, len(hashtags))
+	for i := range hashtags {
+		hits = append(hits, Hit{Type: hitTypes[TypeHashtags], Hashtag: &hashtags[i]})
+	}
+
+	next := 0
+	if len(hashtags) == q.Limit {
+		next = offset + q.Limit
+	}
+	return hits, next, nil
+}
+
+// searchCourseHits searches published courses. Anonymous viewers only find public
+// ones, and courses by creators blocked either way with the viewer are left out.
+func (s *SearchService) searchCourseHits(ctx context.Context, q UnifiedQuery, offset int) ([]Hit, int, error) {
+	if s.courseRepo == nil {
+		return []Hit{}, 0, nil
+	}
+
+	var viewer *uuid.UUID
+	if q.ViewerID != uuid.Nil {
+		viewer = &q.ViewerID
+	}
+	query := q.Query
+	courses, total, err := s.courseRepo.ListCourses(ctx, &models.CourseFilter{
+		Search: &query,
+		SortBy: "popular",
+		Limit:  q.Limit,
+		Offset: offset,
+	}, viewer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	blocked := s.blockedWith(ctx, q.ViewerID)
+	hits := make([]Hit, 0, len(courses))
+	for _, course := range courses {
+		if blocked[course.CreatorID] {
+			continue
+		}
+		hits = append(hits, Hit{Type: hitTypes[TypeCourses], Course: course})
+	}
+
+	next := 0
+	if offset+len(courses) < total {
+		next = offset + len(courses)
+	}
+	return hits, next, nil
+}
+
+// rankHits orders a page by how well each result matches query: exact matches, then
+// prefix matches, then matches at the start of a word, then the rest. Results that
+// match equally well keep the order they were fetched in (popularity or recency).
+func rankHits(hits []Hit, query string) {
+	query = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query), "#"))
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hitRank(hits[i], query) < hitRank(hits[j], query)
+	})
+}
+
+// hitRank is the best match rank of any of a hit's searchable texts
+func hitRank(hit Hit, query string) int {
+	var texts []string
+	switch {
+	case hit.User != nil:
+		texts = []string{hit.User.Username, hit.User.DisplayName}
+	case hit.Post != nil:
+		texts = []string{hit.Post.Content}
+	case hit.Hashtag != nil:
+		texts = []string{hit.Hashtag.Tag}
+	case hit.Course != nil:
+		texts = []string{hit.Course.Title}
+	}
+
+	best := matchNone
+	for _, text := range texts {
+		if rank := matchRank(strings.ToLower(text), query); rank < best {
+			best = rank
+		}
+	}
+	return best
+}
+
+// How well a text matches a query, best first
+const (
+	matchExact = iota
+	matchPrefix
+	matchWordPrefix
+	matchContains
+	matchNone
+)
+
+func matchRank(text, query string) int {
+	switch {
+	case query == "":
+		return matchNone
+	case text == query:
+		return matchExact
+	case strings.HasPrefix(text, query):
+		return matchPrefix
+	case strings.Contains(text, " "+query):
+		return matchWordPrefix
+	case strings.Contains(text, query):
+		return matchContains
+	}
+	return matchNone
+}
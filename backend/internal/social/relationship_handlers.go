@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/utils"
 	"histeeria-backend/pkg/errors"
 
 	"github.com/gin-gonic/gin"
@@ -29,8 +30,8 @@ func (h *RelationshipHandlers) FollowUser(c *gin.Context) {
 	log.Println("[FollowHandler] === FOLLOW REQUEST RECEIVED ===")
 
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	fromUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		log.Println("[FollowHandler] No user_id in context - unauthorized")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
@@ -38,16 +39,7 @@ func (h *RelationshipHandlers) FollowUser(c *gin.Context) {
 		})
 		return
 	}
-	log.Printf("[FollowHandler] User ID from JWT: %v", userID)
-
-	fromUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
+	log.Printf("[FollowHandler] User ID from JWT: %v", fromUserID)
 
 	// Get target user ID from URL
 	targetUserIDStr := c.Param("userId")
@@ -95,8 +87,8 @@ func (h *RelationshipHandlers) FollowUser(c *gin.Context) {
 // ConnectWithUser handles POST /api/v1/relationships/connect/:userId
 func (h *RelationshipHandlers) ConnectWithUser(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	fromUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -104,15 +96,6 @@ func (h *RelationshipHandlers) ConnectWithUser(c *gin.Context) {
 		return
 	}
 
-	fromUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get target user ID from URL
 	targetUserIDStr := c.Param("userId")
 	toUserID, err := uuid.Parse(targetUserIDStr)
@@ -163,8 +146,8 @@ func (h *RelationshipHandlers) ConnectWithUser(c *gin.Context) {
 // CollaborateWithUser handles POST /api/v1/relationships/collaborate/:userId
 func (h *RelationshipHandlers) CollaborateWithUser(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	fromUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -172,15 +155,6 @@ func (h *RelationshipHandlers) CollaborateWithUser(c *gin.Context) {
 		return
 	}
 
-	fromUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get target user ID from URL
 	targetUserIDStr := c.Param("userId")
 	toUserID, err := uuid.Parse(targetUserIDStr)
@@ -231,8 +205,8 @@ func (h *RelationshipHandlers) CollaborateWithUser(c *gin.Context) {
 // AcceptRequest handles POST /api/v1/relationships/requests/:requestId/accept
 func (h *RelationshipHandlers) AcceptRequest(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -240,15 +214,6 @@ func (h *RelationshipHandlers) AcceptRequest(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get request ID from URL
 	requestIDStr := c.Param("requestId")
 	requestID, err := uuid.Parse(requestIDStr)
@@ -277,8 +242,8 @@ func (h *RelationshipHandlers) AcceptRequest(c *gin.Context) {
 // RejectRequest handles POST /api/v1/relationships/requests/:requestId/reject
 func (h *RelationshipHandlers) RejectRequest(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -286,15 +251,6 @@ func (h *RelationshipHandlers) RejectRequest(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get request ID from URL
 	requestIDStr := c.Param("requestId")
 	requestID, err := uuid.Parse(requestIDStr)
@@ -320,11 +276,13 @@ func (h *RelationshipHandlers) RejectRequest(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// RemoveRelationship handles DELETE /api/v1/relationships/:userId/:type
-func (h *RelationshipHandlers) RemoveRelationship(c *gin.Context) {
-	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+// GetRequests handles GET /api/v1/relationships/requests/incoming and
+// GET /api/v1/relationships/requests/outgoing: requests waiting on the current user,
+// or sent by them and not yet answered. ?type= narrows them to following, connected
+// or collaborating.
+func (h *RelationshipHandlers) GetRequests(c *gin.Context) {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -332,11 +290,54 @@ func (h *RelationshipHandlers) RemoveRelationship(c *gin.Context) {
 		return
 	}
 
-	fromUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	relationshipType := models.RelationshipType(c.Query("type"))
+	switch relationshipType {
+	case "", models.RelationshipFollowing, models.RelationshipConnected, models.RelationshipCollaborating:
+	default:
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Invalid user ID",
+			"message": "Invalid relationship type. Must be: following, connected, or collaborating",
+		})
+		return
+	}
+
+	incoming := c.FullPath() != "/api/v1/relationships/requests/outgoing"
+	requests, total, err := h.service.GetPendingRequests(c.Request.Context(), currentUserID, relationshipType, incoming, page, limit)
+	if err != nil {
+		log.Printf("[GetRequests] Failed to list requests for %s: %v", currentUserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to fetch requests",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"requests": requests,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// RemoveRelationship handles DELETE /api/v1/relationships/:userId/:type
+func (h *RelationshipHandlers) RemoveRelationship(c *gin.Context) {
+	// Get current user ID from JWT
+	fromUserID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
 		})
 		return
 	}
@@ -387,19 +388,7 @@ func (h *RelationshipHandlers) RemoveRelationship(c *gin.Context) {
 // GetRelationshipStatus handles GET /api/v1/relationships/status/:userId
 func (h *RelationshipHandlers) GetRelationshipStatus(c *gin.Context) {
 	// Get current user ID from JWT (optional for this endpoint)
-	var fromUserID uuid.UUID
-	userID, exists := c.Get("user_id")
-	if exists {
-		var err error
-		fromUserID, err = uuid.Parse(userID.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Invalid user ID",
-			})
-			return
-		}
-	}
+	fromUserID, _ := utils.CurrentUserID(c)
 
 	// Get target user ID from URL
 	targetUserIDStr := c.Param("userId")
@@ -448,8 +437,8 @@ func (h *RelationshipHandlers) GetRelationshipStatus(c *gin.Context) {
 // GetRelationshipStats handles GET /api/v1/relationships/stats
 func (h *RelationshipHandlers) GetRelationshipStats(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -457,15 +446,6 @@ func (h *RelationshipHandlers) GetRelationshipStats(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Call service
 	stats, err := h.service.GetRelationshipStats(c.Request.Context(), currentUserID)
 	if err != nil {
@@ -486,8 +466,8 @@ func (h *RelationshipHandlers) GetRelationshipStats(c *gin.Context) {
 // GetRateLimitStatus handles GET /api/v1/relationships/rate-limit-status
 func (h *RelationshipHandlers) GetRateLimitStatus(c *gin.Context) {
 	// Get current user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -495,15 +475,6 @@ func (h *RelationshipHandlers) GetRateLimitStatus(c *gin.Context) {
 		return
 	}
 
-	currentUserID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid user ID",
-		})
-		return
-	}
-
 	// Get action type from query param (default to all)
 	actionType := c.Query("action")
 	if actionType == "" {
@@ -540,8 +511,7 @@ func (h *RelationshipHandlers) GetRateLimitStatus(c *gin.Context) {
 // GetFollowers handles GET /api/v1/relationships/followers
 func (h *RelationshipHandlers) GetFollowers(c *gin.Context) {
 	// Get current user ID from JWT (for authorization check)
-	_, exists := c.Get("user_id")
-	if !exists {
+	if _, err := utils.CurrentUser(c); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 			"message": "Unauthorized",
@@ -577,15 +547,7 @@ func (h *RelationshipHandlers) GetFollowers(c *gin.Context) {
 		}
 	} else {
 		// Default to current user if no user_id provided
-		userID, _ := c.Get("user_id")
-		targetUserID, err = uuid.Parse(userID.(string))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Invalid user ID",
-			})
-			return
-		}
+		targetUserID = utils.MustUserID(c)
 	}
 
 	// Determine list type from URL path
@@ -596,8 +558,6 @@ func (h *RelationshipHandlers) GetFollowers(c *gin.Context) {
 		listType = "connections"
 	} else if c.FullPath() == "/api/v1/relationships/collaborators" {
 		listType = "collaborators"
-	} else if c.FullPath() == "/api/v1/relationships/pending" {
-		listType = "pending"
 	}
 
 	log.Printf("[GetFollowers] Fetching %s for user %s", listType, targetUserID)
@@ -621,6 +581,218 @@ func (h *RelationshipHandlers) GetFollowers(c *gin.Context) {
 	})
 }
 
+// GetUserFollows handles GET /api/v1/users/:id/followers and
+// GET /api/v1/users/:id/following. Signing in is optional; signed-in viewers also
+// get their own relationship to each listed user.
+func (h *RelationshipHandlers) GetUserFollows(c *gin.Context) {
+	viewerID, _ := utils.CurrentUserID(c)
+
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid user ID",
+		})
+		return
+	}
+	page, limit := followListPage(c)
+
+	followers := c.FullPath() == "/api/v1/users/:id/followers"
+	users, total, err := h.service.GetFollowList(c.Request.Context(), viewerID, targetID, followers, page, limit)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.FollowListResponse{
+		Success: true,
+		Users:   users,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	})
+}
+
+// GetMutualFollowers handles GET /api/v1/users/:id/mutual-followers: the accounts the
+// current user follows that follow this user
+func (h *RelationshipHandlers) GetMutualFollowers(c *gin.Context) {
+	viewerID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid user ID",
+		})
+		return
+	}
+	page, limit := followListPage(c)
+
+	users, total, err := h.service.GetMutualFollowers(c.Request.Context(), viewerID, targetID, page, limit)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.FollowListResponse{
+		Success: true,
+		Users:   users,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	})
+}
+
+// GetCloseFriends handles GET /api/v1/relationships/close-friends
+func (h *RelationshipHandlers) GetCloseFriends(c *gin.Context) {
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+	page, limit := followListPage(c)
+
+	friends, total, err := h.service.GetCloseFriends(c.Request.Context(), userID, page, limit)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.CloseFriendsResponse{
+		Success: true,
+		Users:   friends,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	})
+}
+
+// AddCloseFriend handles PUT /api/v1/relationships/close-friends/:userId
+func (h *RelationshipHandlers) AddCloseFriend(c *gin.Context) {
+	h.updateCloseFriends(c, true)
+}
+
+// RemoveCloseFriend handles DELETE /api/v1/relationships/close-friends/:userId
+func (h *RelationshipHandlers) RemoveCloseFriend(c *gin.Context) {
+	h.updateCloseFriends(c, false)
+}
+
+// updateCloseFriends adds the :userId user to the signed-in user's close friends, or
+// removes them
+func (h *RelationshipHandlers) updateCloseFriends(c *gin.Context, add bool) {
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	friendID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	message := "Added to close friends"
+	if add {
+		err = h.service.AddCloseFriend(c.Request.Context(), userID, friendID)
+	} else {
+		err = h.service.RemoveCloseFriend(c.Request.Context(), userID, friendID)
+		message = "Removed from close friends"
+	}
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+	})
+}
+
+// followListPage reads ?page= and ?limit=, 20 per page by default and at most 100
+func followListPage(c *gin.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// FindByContacts handles POST /api/v1/relationships/find-by-contacts
+// Accepts SHA-256 email hashes computed on the client; raw emails are never sent to the server.
+func (h *RelationshipHandlers) FindByContacts(c *gin.Context) {
+	currentUserID, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	var req models.FindByContactsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.EmailHashes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "email_hashes is required",
+		})
+		return
+	}
+
+	matches, checked, err := h.service.FindUsersByContacts(c.Request.Context(), currentUserID, req.EmailHashes)
+	if err != nil {
+		appErr := errors.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{
+			"success": false,
+			"message": appErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.FindByContactsResponse{
+		Success: true,
+		Matches: matches,
+		Checked: checked,
+	})
+}
+
 // SetupRoutes registers relationship routes
 func (h *RelationshipHandlers) SetupRoutes(router *gin.RouterGroup) {
 	relationships := router.Group("/relationships")
@@ -631,9 +803,16 @@ func (h *RelationshipHandlers) SetupRoutes(router *gin.RouterGroup) {
 		relationships.POST("/collaborate/:userId", h.CollaborateWithUser)
 
 		// Request management
+		relationships.GET("/requests/incoming", h.GetRequests)
+		relationships.GET("/requests/outgoing", h.GetRequests)
 		relationships.POST("/requests/:requestId/accept", h.AcceptRequest)
 		relationships.POST("/requests/:requestId/reject", h.RejectRequest)
 
+		// Close friends list
+		relationships.GET("/close-friends", h.GetCloseFriends)
+		relationships.PUT("/close-friends/:userId", h.AddCloseFriend)
+		relationships.DELETE("/close-friends/:userId", h.RemoveCloseFriend)
+
 		// Remove relationships
 		relationships.DELETE("/:userId/:type", h.RemoveRelationship)
 
@@ -647,6 +826,6 @@ func (h *RelationshipHandlers) SetupRoutes(router *gin.RouterGroup) {
 		relationships.GET("/following", h.GetFollowers)
 		relationships.GET("/connections", h.GetFollowers)
 		relationships.GET("/collaborators", h.GetFollowers)
-		relationships.GET("/pending", h.GetFollowers)
+		relationships.GET("/pending", h.GetRequests) // Incoming requests
 	}
 }
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/repository"
+	"histeeria-backend/pkg/errors"
 
 	"github.com/google/uuid"
 )
@@ -26,6 +29,9 @@ const (
 
 	// Base cooldown period
 	BaseCooldownHours = 24
+
+	// Maximum number of contact hashes accepted per find-by-contacts request
+	MaxContactHashesPerRequest = 500
 )
 
 // RelationshipService handles relationship business logic
@@ -34,11 +40,14 @@ type RelationshipService struct {
 	userRepo            repository.UserRepository
 	spamDetector        *SpamDetector
 	notificationService NotificationService // Interface for notifications
+	newAccounts         models.NewAccountRestrictions
 }
 
 // NotificationService interface for creating notifications (avoid circular dependency)
 type NotificationService interface {
 	CreateFollowNotification(ctx context.Context, followerID, followedID uuid.UUID, followerUsername string) error
+	CreateFollowRequestNotification(ctx context.Context, requesterID, targetID, requestID uuid.UUID, requesterUsername string) error
+	CreateFollowRequestAcceptedNotification(ctx context.Context, acceptorID, requesterID uuid.UUID, acceptorUsername string) error
 	CreateConnectionRequestNotification(ctx context.Context, fromID, toID uuid.UUID, fromUsername string) error
 	CreateConnectionAcceptedNotification(ctx context.Context, acceptorID, requesterID uuid.UUID, acceptorUsername string) error
 	CreateCollaborationRequestNotification(ctx context.Context, fromID, toID uuid.UUID, fromUsername string) error
@@ -59,6 +68,11 @@ func (s *RelationshipService) SetNotificationService(notificationService Notific
 	s.notificationService = notificationService
 }
 
+// SetNewAccountRestrictions lowers the daily limits of accounts in their probation period
+func (s *RelationshipService) SetNewAccountRestrictions(rules models.NewAccountRestrictions) {
+	s.newAccounts = rules
+}
+
 // FollowUser creates a follow relationship
 func (s *RelationshipService) FollowUser(ctx context.Context, fromUserID, toUserID uuid.UUID) (*models.RelationshipResponse, error) {
 	// Check rate limit
@@ -90,28 +104,72 @@ func (s *RelationshipService) FollowUser(ctx context.Context, fromUserID, toUser
 		return nil, fmt.Errorf("target user not found")
 	}
 
-	// Cannot follow private profiles
-	if targetUser.ProfilePrivacy == "private" {
-		return &models.RelationshipResponse{
-			Success: false,
-			Message: "Cannot follow private profiles",
-		}, nil
-	}
-
 	// Check if relationship already exists
 	existing, err := s.relationshipRepo.GetRelationship(ctx, fromUserID, toUserID, models.RelationshipFollowing)
 	if err != nil {
 		return nil, err
 	}
 
+	// Private accounts approve their followers; the follow waits as a request until then
+	private := targetUser.ProfilePrivacy == "private"
+
 	if existing != nil {
+		switch {
+		case existing.Status == models.RelationshipActive:
+			return &models.RelationshipResponse{
+				Success: false,
+				Message: "You are already following this user",
+			}, nil
+		case existing.Status != models.RelationshipPending && existing.Status != models.RelationshipRejected:
+			return &models.RelationshipResponse{
+				Success: false,
+				Message: "You can't follow this user",
+			}, nil
+		case !private:
+			// The account went public since the request was made; it needs no approval now
+			return s.approveFollow(ctx, existing, rateLimitStatus, "Successfully followed user")
+		case existing.Status == models.RelationshipPending:
+			return &models.RelationshipResponse{
+				Success: false,
+				Message: "Your follow request is pending",
+			}, nil
+		default:
+			// Asking again after a rejection reuses the row; inserting would collide with it
+			if err := s.relationshipRepo.UpdateRelationshipStatus(ctx, existing.ID, models.RelationshipPending); err != nil {
+				return nil, err
+			}
+			existing.Status = models.RelationshipPending
+			s.afterFollowRequest(fromUserID, toUserID, existing.ID)
+			return &models.RelationshipResponse{
+				Success:      true,
+				Message:      "Follow request sent. Waiting for approval.",
+				Relationship: existing,
+				RateLimit:    rateLimitStatus,
+			}, nil
+		}
+	}
+
+	if private {
+		request := &models.UserRelationship{
+			FromUserID:       fromUserID,
+			ToUserID:         toUserID,
+			RelationshipType: models.RelationshipFollowing,
+			Status:           models.RelationshipPending,
+		}
+		if err := s.relationshipRepo.CreateRelationship(ctx, request); err != nil {
+			return nil, err
+		}
+		s.afterFollowRequest(fromUserID, toUserID, request.ID)
+
 		return &models.RelationshipResponse{
-			Success: false,
-			Message: "You are already following this user",
+			Success:      true,
+			Message:      "Follow request sent. Waiting for approval.",
+			Relationship: request,
+			RateLimit:    rateLimitStatus,
 		}, nil
 	}
 
-	// Create follow relationship (always instant, no approval needed)
+	// Create follow relationship (public accounts need no approval)
 	relationship := &models.UserRelationship{
 		FromUserID:       fromUserID,
 		ToUserID:         toUserID,
@@ -149,6 +207,40 @@ func (s *RelationshipService) FollowUser(ctx context.Context, fromUserID, toUser
 	}, nil
 }
 
+// approveFollow turns a follow that was waiting into an active one, and counts it
+func (s *RelationshipService) approveFollow(ctx context.Context, follow *models.UserRelationship, rateLimit *models.RateLimitStatus, message string) (*models.RelationshipResponse, error) {
+	if err := s.relationshipRepo.UpdateRelationshipStatus(ctx, follow.ID, models.RelationshipActive); err != nil {
+		return nil, err
+	}
+	follow.Status = models.RelationshipActive
+
+	if err := s.updateFollowerCounts(ctx, follow.FromUserID, follow.ToUserID, true); err != nil {
+		log.Printf("[RelationshipService] Failed to update follower counts for %s -> %s: %v", follow.FromUserID, follow.ToUserID, err)
+	}
+
+	return &models.RelationshipResponse{
+		Success:      true,
+		Message:      message,
+		Relationship: follow,
+		RateLimit:    rateLimit,
+	}, nil
+}
+
+// afterFollowRequest records a follow request against the rate limit and tells the
+// private account about it, in the background
+func (s *RelationshipService) afterFollowRequest(fromUserID, toUserID, requestID uuid.UUID) {
+	go func() {
+		bgCtx := context.Background()
+		_ = s.recordAction(bgCtx, fromUserID, "follow")
+		if s.notificationService != nil {
+			fromUser, err := s.userRepo.GetUserByID(bgCtx, fromUserID)
+			if err == nil {
+				_ = s.notificationService.CreateFollowRequestNotification(bgCtx, fromUserID, toUserID, requestID, fromUser.Username)
+			}
+		}
+	}()
+}
+
 // ConnectWithUser creates a connection (may require approval)
 func (s *RelationshipService) ConnectWithUser(ctx context.Context, fromUserID, toUserID uuid.UUID, message *string) (*models.RelationshipResponse, error) {
 	// Check rate limit
@@ -388,6 +480,13 @@ func (s *RelationshipService) AcceptRelationshipRequest(ctx context.Context, use
 		return nil, err
 	}
 
+	// An accepted follow request counts from now on
+	if relationship.RelationshipType == models.RelationshipFollowing {
+		if err := s.updateFollowerCounts(ctx, relationship.FromUserID, relationship.ToUserID, true); err != nil {
+			log.Printf("[RelationshipService] Failed to update follower counts for %s -> %s: %v", relationship.FromUserID, relationship.ToUserID, err)
+		}
+	}
+
 	// For connections and collaborations, create reverse relationship
 	if relationship.RelationshipType == models.RelationshipConnected || relationship.RelationshipType == models.RelationshipCollaborating {
 		reverseRelationship := &models.UserRelationship{
@@ -405,7 +504,9 @@ func (s *RelationshipService) AcceptRelationshipRequest(ctx context.Context, use
 	if s.notificationService != nil {
 		acceptorUser, err := s.userRepo.GetUserByID(ctx, userID)
 		if err == nil {
-			if relationship.RelationshipType == models.RelationshipConnected {
+			if relationship.RelationshipType == models.RelationshipFollowing {
+				_ = s.notificationService.CreateFollowRequestAcceptedNotification(ctx, userID, relationship.FromUserID, acceptorUser.Username)
+			} else if relationship.RelationshipType == models.RelationshipConnected {
 				_ = s.notificationService.CreateConnectionAcceptedNotification(ctx, userID, relationship.FromUserID, acceptorUser.Username)
 			} else if relationship.RelationshipType == models.RelationshipCollaborating {
 				_ = s.notificationService.CreateCollaborationAcceptedNotification(ctx, userID, relationship.FromUserID, acceptorUser.Username)
@@ -436,6 +537,13 @@ func (s *RelationshipService) RejectRelationshipRequest(ctx context.Context, use
 		}, nil
 	}
 
+	if relationship.Status != models.RelationshipPending {
+		return &models.RelationshipResponse{
+			Success: false,
+			Message: "This request is no longer pending",
+		}, nil
+	}
+
 	// Update status to rejected
 	if err := s.relationshipRepo.UpdateRelationshipStatus(ctx, requestID, models.RelationshipRejected); err != nil {
 		return nil, err
@@ -451,6 +559,16 @@ func (s *RelationshipService) RejectRelationshipRequest(ctx context.Context, use
 func (s *RelationshipService) RemoveRelationship(ctx context.Context, fromUserID, toUserID uuid.UUID, relationshipType models.RelationshipType) (*models.RelationshipResponse, error) {
 	fmt.Printf("[RelationshipService] Removing relationship: from=%s to=%s type=%s\n", fromUserID, toUserID, relationshipType)
 
+	// Only an active follow was counted; withdrawing a follow request changes no counts
+	wasFollowing := false
+	if relationshipType == models.RelationshipFollowing {
+		existing, err := s.relationshipRepo.GetRelationship(ctx, fromUserID, toUserID, relationshipType)
+		if err != nil {
+			return nil, err
+		}
+		wasFollowing = existing != nil && existing.Status == models.RelationshipActive
+	}
+
 	// Delete the relationship
 	if err := s.relationshipRepo.DeleteRelationship(ctx, fromUserID, toUserID, relationshipType); err != nil {
 		fmt.Printf("[RelationshipService] Delete relationship failed: %v\n", err)
@@ -476,7 +594,7 @@ func (s *RelationshipService) RemoveRelationship(ctx context.Context, fromUserID
 	}
 
 	// Update follower counts if it was a follow
-	if relationshipType == models.RelationshipFollowing {
+	if wasFollowing {
 		fmt.Printf("[RelationshipService] Updating follower counts for unfollow\n")
 		if err := s.updateFollowerCounts(ctx, fromUserID, toUserID, false); err != nil {
 			fmt.Printf("[RelationshipService] Failed to update follower counts: %v\n", err)
@@ -491,6 +609,157 @@ func (s *RelationshipService) RemoveRelationship(ctx context.Context, fromUserID
 	}, nil
 }
 
+// GetPendingRequests lists requests waiting on userID (incoming) or sent by userID and
+// not yet answered (outgoing). An empty relationshipType lists every kind of request.
+func (s *RelationshipService) GetPendingRequests(ctx context.Context, userID uuid.UUID, relationshipType models.RelationshipType, incoming bool, page, limit int) ([]*models.RelationshipRequest, int, error) {
+	return s.relationshipRepo.GetPendingRequests(ctx, userID, relationshipType, incoming, page, limit)
+}
+
+// GetFollowList gets a page of targetID's followers (followers) or of the accounts
+// targetID follows, as viewerID may see it: a private account's lists are only for the
+// account and its approved followers, nobody blocked either way with the viewer is
+// listed, and each user comes with how the viewer relates to them. viewerID is
+// uuid.Nil for anonymous viewers.
+func (s *RelationshipService) GetFollowList(ctx context.Context, viewerID, targetID uuid.UUID, followers bool, page, limit int) ([]*models.FollowListUser, int, error) {
+	if err := s.checkCanSeeFollows(ctx, viewerID, targetID); err != nil {
+		return nil, 0, err
+	}
+
+	users, total, err := s.relationshipRepo.GetFollowList(ctx, targetID, viewerID, followers, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.attachViewerStatus(ctx, viewerID, users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// GetMutualFollowers gets a page of the accounts viewerID follows that also follow
+// targetID, for "followed by people you know"
+func (s *RelationshipService) GetMutualFollowers(ctx context.Context, viewerID, targetID uuid.UUID, page, limit int) ([]*models.FollowListUser, int, error) {
+	if err := s.checkCanSeeFollows(ctx, viewerID, targetID); err != nil {
+		return nil, 0, err
+	}
+
+	users, total, err := s.relationshipRepo.GetMutualFollowers(ctx, viewerID, targetID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.attachViewerStatus(ctx, viewerID, users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// IsMutual reports whether two users follow each other, both follows approved
+func (s *RelationshipService) IsMutual(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	for _, pair := range [2][2]uuid.UUID{{userID, otherID}, {otherID, userID}} {
+		follow, err := s.relationshipRepo.GetRelationship(ctx, pair[0], pair[1], models.RelationshipFollowing)
+		if err != nil {
+			return false, err
+		}
+		if follow == nil || follow.Status != models.RelationshipActive {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkCanSeeFollows returns an error unless viewerID may see targetID's follow lists.
+// Blocks either way hide the account altogether.
+func (s *RelationshipService) checkCanSeeFollows(ctx context.Context, viewerID, targetID uuid.UUID) error {
+	target, err := s.userRepo.GetUserByID(ctx, targetID)
+	if err != nil || target == nil {
+		return errors.NewAppError(http.StatusNotFound, "User not found")
+	}
+	if viewerID == targetID {
+		return nil
+	}
+
+	if viewerID != uuid.Nil {
+		for _, pair := range [2][2]uuid.UUID{{viewerID, targetID}, {targetID, viewerID}} {
+			blocked, err := s.userRepo.IsUserBlocked(ctx, pair[0], pair[1])
+			if err != nil {
+				return err
+			}
+			if blocked {
+				return errors.NewAppError(http.StatusNotFound, "User not found")
+			}
+		}
+	}
+
+	if target.ProfilePrivacy != "private" {
+		return nil
+	}
+	if viewerID != uuid.Nil {
+		follow, err := s.relationshipRepo.GetRelationship(ctx, viewerID, targetID, models.RelationshipFollowing)
+		if err != nil {
+			return err
+		}
+		if follow != nil && follow.Status == models.RelationshipActive {
+			return nil
+		}
+	}
+	return errors.NewAppError(http.StatusForbidden, "This account is private")
+}
+
+// attachViewerStatus fills in how viewerID relates to each listed user. Anonymous
+// viewers relate to nobody, so their lists go without.
+func (s *RelationshipService) attachViewerStatus(ctx context.Context, viewerID uuid.UUID, users []*models.FollowListUser) error {
+	if viewerID == uuid.Nil || len(users) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	states, err := s.relationshipRepo.GetFollowStates(ctx, viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		user.Viewer = states[user.ID]
+	}
+	return nil
+}
+
+// AddCloseFriend puts friendID on userID's close friends list, the audience of their
+// close_friends posts and statuses. The friend isn't told.
+func (s *RelationshipService) AddCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error {
+	if userID == friendID {
+		return errors.NewAppError(http.StatusBadRequest, "You can't add yourself to your close friends")
+	}
+
+	friend, err := s.userRepo.GetUserByID(ctx, friendID)
+	if err != nil || friend == nil {
+		return errors.NewAppError(http.StatusNotFound, "User not found")
+	}
+	for _, pair := range [2][2]uuid.UUID{{userID, friendID}, {friendID, userID}} {
+		blocked, err := s.userRepo.IsUserBlocked(ctx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return errors.NewAppError(http.StatusNotFound, "User not found")
+		}
+	}
+
+	return s.relationshipRepo.AddCloseFriend(ctx, userID, friendID)
+}
+
+// RemoveCloseFriend takes friendID off userID's close friends list. They lose sight of
+// userID's close_friends posts and statuses straight away.
+func (s *RelationshipService) RemoveCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error {
+	return s.relationshipRepo.RemoveCloseFriend(ctx, userID, friendID)
+}
+
+// GetCloseFriends gets a page of userID's close friends list
+func (s *RelationshipService) GetCloseFriends(ctx context.Context, userID uuid.UUID, page, limit int) ([]*models.CloseFriend, int, error) {
+	return s.relationshipRepo.GetCloseFriends(ctx, userID, page, limit)
+}
+
 // GetRelationshipStatus gets the relationship status between two users
 func (s *RelationshipService) GetRelationshipStatus(ctx context.Context, fromUserID, toUserID uuid.UUID) (*models.RelationshipStatusResponse, error) {
 	return s.relationshipRepo.GetRelationshipStatus(ctx, fromUserID, toUserID)
@@ -538,6 +807,98 @@ func (s *RelationshipService) GetRelationshipList(ctx context.Context, userID uu
 	return users, total, nil
 }
 
+// FindUsersByContacts matches client-computed SHA-256 email hashes against users who allow
+// contact discovery. Private profiles, blocked users and the caller are never returned.
+func (s *RelationshipService) FindUsersByContacts(ctx context.Context, userID uuid.UUID, emailHashes []string) ([]*models.ContactMatch, int, error) {
+	if len(emailHashes) > MaxContactHashesPerRequest {
+		return nil, 0, errors.NewAppError(http.StatusBadRequest,
+			fmt.Sprintf("At most %d contacts can be checked per request", MaxContactHashesPerRequest))
+	}
+
+	// Normalize and de-duplicate hashes
+	seen := make(map[string]bool, len(emailHashes))
+	hashes := make([]string, 0, len(emailHashes))
+	for _, hash := range emailHashes {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if !isValidEmailHash(hash) {
+			return nil, 0, errors.NewAppError(http.StatusBadRequest, "Invalid email hash", "email hashes must be 64-character hex SHA-256 digests")
+		}
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+
+	users, err := s.userRepo.GetDiscoverableUsersByEmailHashes(ctx, hashes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Only surface users the caller is allowed to follow
+	candidates := make([]*models.User, 0, len(users))
+	candidateIDs := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		if user.ID == userID || user.ProfilePrivacy == "private" {
+			continue
+		}
+		candidates = append(candidates, user)
+		candidateIDs = append(candidateIDs, user.ID)
+	}
+	if len(candidates) == 0 {
+		return []*models.ContactMatch{}, len(hashes), nil
+	}
+
+	// One query each for blocks and follow state across every candidate
+	blockedIDs, err := s.userRepo.GetBlockedEitherWayAmong(ctx, userID, candidateIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	blocked := make(map[uuid.UUID]bool, len(blockedIDs))
+	for _, id := range blockedIDs {
+		blocked[id] = true
+	}
+	states, err := s.relationshipRepo.GetFollowStates(ctx, userID, candidateIDs)
+	if err != nil {
+		log.Printf("[RelationshipService] Failed to get follow states for contact match of %s: %v", userID, err)
+		states = nil
+	}
+
+	matches := make([]*models.ContactMatch, 0, len(candidates))
+	for _, user := range candidates {
+		if blocked[user.ID] {
+			continue
+		}
+		match := &models.ContactMatch{
+			EmailHash:      user.EmailHash,
+			UserID:         user.ID.String(),
+			Username:       user.Username,
+			DisplayName:    user.DisplayName,
+			ProfilePicture: user.ProfilePicture,
+			IsVerified:     user.IsVerified,
+		}
+		if state := states[user.ID]; state != nil {
+			match.IsFollowing = state.IsFollowing
+		}
+		matches = append(matches, match)
+	}
+
+	log.Printf("[RelationshipService] Contact match for %s: %d hashes checked, %d matches", userID, len(hashes), len(matches))
+	return matches, len(hashes), nil
+}
+
+// isValidEmailHash reports whether s is a lowercase hex-encoded SHA-256 digest
+func isValidEmailHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
 // GetRateLimitInfo gets the current rate limit status
 func (s *RelationshipService) GetRateLimitInfo(ctx context.Context, userID uuid.UUID, actionType string) (*models.RateLimitStatus, error) {
 	rateLimit, err := s.relationshipRepo.GetRateLimit(ctx, userID, actionType)
@@ -553,6 +914,9 @@ func (s *RelationshipService) GetRateLimitInfo(ctx context.Context, userID uuid.
 
 	trustLevel := user.IsVerified || user.IsEmailVerified
 	limit := s.getLimitForAction(actionType, trustLevel)
+	if s.newAccounts.FollowLimit > 0 && s.newAccounts.Applies(user) && s.newAccounts.FollowLimit < limit {
+		limit = s.newAccounts.FollowLimit
+	}
 
 	if rateLimit == nil {
 		// No rate limit record yet
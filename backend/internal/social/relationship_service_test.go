@@ -0,0 +1,151 @@
+package social
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakeContactUsers serves discoverable users and blocks, counting block queries;
+// anything else panics through the nil embedded interface
+type fakeContactUsers struct {
+	repository.UserRepository
+
+	users      []*models.User
+	blocked    map[uuid.UUID]bool // Blocked either way with the caller
+	blockReads int
+}
+
+// GetDiscoverableUsersByEmailHashes returns the users whose hash was asked for and
+// who haven't opted out of contact discovery, like the users query does
+func (r *fakeContactUsers) GetDiscoverableUsersByEmailHashes(ctx context.Context, emailHashes []string) ([]*models.User, error) {
+	asked := make(map[string]bool, len(emailHashes))
+	for _, hash := range emailHashes {
+		asked[hash] = true
+	}
+	var matches []*models.User
+	for _, user := range r.users {
+		if asked[user.EmailHash] && user.Discoverable {
+			matches = append(matches, user)
+		}
+	}
+	return matches, nil
+}
+
+// contactUser returns a discoverable user whose email hashes to hash
+func contactUser(username, hash string) *models.User {
+	return &models.User{ID: uuid.New(), Username: username, EmailHash: strings.Repeat(hash, 64), Discoverable: true}
+}
+
+// hashesOf returns the users' email hashes
+func hashesOf(users ...*models.User) []string {
+	hashes := make([]string, len(users))
+	for i, user := range users {
+		hashes[i] = user.EmailHash
+	}
+	return hashes
+}
+
+func (r *fakeContactUsers) GetBlockedEitherWayAmong(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) ([]uuid.UUID, error) {
+	r.blockReads++
+	var ids []uuid.UUID
+	for _, id := range otherIDs {
+		if r.blocked[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// fakeFollowStates serves follow states, counting the queries
+type fakeFollowStates struct {
+	repository.RelationshipRepository
+
+	following  map[uuid.UUID]bool
+	stateReads int
+}
+
+func (r *fakeFollowStates) GetFollowStates(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*models.ViewerFollowStatus, error) {
+	r.stateReads++
+	states := make(map[uuid.UUID]*models.ViewerFollowStatus, len(userIDs))
+	for _, id := range userIDs {
+		states[id] = &models.ViewerFollowStatus{IsFollowing: r.following[id]}
+	}
+	return states, nil
+}
+
+func TestFindUsersByContactsBatchesLookups(t *testing.T) {
+	caller := contactUser("caller", "1")
+	followed := contactUser("followed", "2")
+	stranger := contactUser("stranger", "3")
+	blocked := contactUser("blocked", "4")
+	private := contactUser("private", "5")
+	private.ProfilePrivacy = "private"
+
+	users := &fakeContactUsers{
+		users:   []*models.User{caller, followed, stranger, blocked, private},
+		blocked: map[uuid.UUID]bool{blocked.ID: true},
+	}
+	relationships := &fakeFollowStates{following: map[uuid.UUID]bool{followed.ID: true}}
+	svc := NewRelationshipService(relationships, users)
+
+	matches, checked, err := svc.FindUsersByContacts(context.Background(), caller.ID, hashesOf(caller, followed, stranger, blocked, private))
+	if err != nil {
+		t.Fatalf("FindUsersByContacts: %v", err)
+	}
+	if checked != 5 {
+		t.Errorf("checked %d hashes, want 5", checked)
+	}
+
+	got := make(map[string]bool)
+	for _, match := range matches {
+		got[match.Username] = match.IsFollowing
+	}
+	if len(got) != 2 {
+		t.Fatalf("matched %v, want followed and stranger", got)
+	}
+	if following, ok := got["followed"]; !ok || !following {
+		t.Error("followed should match and show as followed")
+	}
+	if following, ok := got["stranger"]; !ok || following {
+		t.Error("stranger should match and show as not followed")
+	}
+	if users.blockReads != 1 || relationships.stateReads != 1 {
+		t.Errorf("made %d block and %d follow state queries, want one each", users.blockReads, relationships.stateReads)
+	}
+}
+
+func TestFindUsersByContactsMatchesOnlyKnownOptedInHashes(t *testing.T) {
+	known := contactUser("known", "a")
+	optedOut := contactUser("opted_out", "b")
+	optedOut.Discoverable = false
+	users := &fakeContactUsers{users: []*models.User{known, optedOut}}
+	svc := NewRelationshipService(&fakeFollowStates{}, users)
+
+	unknown := []string{strings.Repeat("c", 64), strings.Repeat("d", 64)}
+	matches, checked, err := svc.FindUsersByContacts(context.Background(), uuid.New(), unknown)
+	if err != nil {
+		t.Fatalf("FindUsersByContacts: %v", err)
+	}
+	if len(matches) != 0 || checked != 2 {
+		t.Errorf("unmatched hashes: got %d matches of %d checked, want none of 2", len(matches), checked)
+	}
+	if users.blockReads != 0 {
+		t.Error("with no matches there's nothing to check blocks for")
+	}
+
+	// Upper-case input is normalized; the opted-out user's hash matches nobody
+	matches, _, err = svc.FindUsersByContacts(context.Background(), uuid.New(),
+		append(unknown, strings.ToUpper(known.EmailHash), optedOut.EmailHash))
+	if err != nil {
+		t.Fatalf("FindUsersByContacts: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Username != "known" || matches[0].EmailHash != known.EmailHash {
+		t.Errorf("matches = %+v, want only known, with the hash it matched", matches)
+	}
+}
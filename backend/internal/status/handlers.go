@@ -1,10 +1,9 @@
 package status
 
 import (
-	"bytes"
 	"fmt"
 	"io"
-	"log"
+	"math"
 	"net/http"
 	"path/filepath"
 	"regexp"
@@ -63,26 +62,29 @@ func sanitizeFilename(filename string) string {
 
 // CreateStatus handles POST /api/v1/statuses
 func (h *Handlers) CreateStatus(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	var req models.CreateStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	status, err := h.service.CreateStatus(c.Request.Context(), &req, uid)
 	if err != nil {
+		if limitErr, ok := err.(*models.StatusLimitError); ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(limitErr.ResetAt).Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":    limitErr.Message,
+				"code":     "STATUS_LIMIT_REACHED",
+				"limit":    limitErr.Limit,
+				"reset_at": limitErr.ResetAt,
+			})
+			return
+		}
 		if appErr, ok := err.(*models.AppError); ok {
 			c.JSON(http.StatusBadRequest, gin.H{"error": appErr.Message, "code": appErr.Code})
 			return
@@ -108,9 +110,7 @@ func (h *Handlers) GetStatus(c *gin.Context) {
 
 	// Get viewer ID (optional)
 	var viewerID uuid.UUID
-	if userID, exists := c.Get("user_id"); exists {
-		viewerID, _ = uuid.Parse(userID.(string))
-	}
+	viewerID, _ = utils.CurrentUserID(c)
 
 	status, err := h.service.GetStatus(c.Request.Context(), statusID, viewerID)
 	if err != nil {
@@ -138,9 +138,7 @@ func (h *Handlers) GetUserStatuses(c *gin.Context) {
 
 	// Get viewer ID (optional)
 	var viewerID uuid.UUID
-	if userIDFromContext, exists := c.Get("user_id"); exists {
-		viewerID, _ = uuid.Parse(userIDFromContext.(string))
-	}
+	viewerID, _ = utils.CurrentUserID(c)
 
 	limit := 50
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -165,18 +163,12 @@ func (h *Handlers) GetUserStatuses(c *gin.Context) {
 
 // GetStatusesForFeed handles GET /api/v1/statuses/feed
 func (h *Handlers) GetStatusesForFeed(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	limit := 100
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
@@ -198,6 +190,42 @@ func (h *Handlers) GetStatusesForFeed(c *gin.Context) {
 	})
 }
 
+// GetStatusArchive handles GET /api/v1/statuses/archive: view and reaction totals of
+// the current user's expired statuses
+func (h *Handlers) GetStatusArchive(c *gin.Context) {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	summaries, err := h.service.GetStatusSummaries(c.Request.Context(), uid, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.StatusSummariesResponse{
+		Success:   true,
+		Summaries: summaries,
+		HasMore:   len(summaries) == limit,
+	})
+}
+
 // DeleteStatus handles DELETE /api/v1/statuses/:id
 func (h *Handlers) DeleteStatus(c *gin.Context) {
 	statusID, err := uuid.Parse(c.Param("id"))
@@ -206,14 +234,12 @@ func (h *Handlers) DeleteStatus(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.DeleteStatus(c.Request.Context(), statusID, uid); err != nil {
 		if err == models.ErrStatusUnauthorized {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
@@ -241,15 +267,17 @@ func (h *Handlers) ViewStatus(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.ViewStatus(c.Request.Context(), statusID, uid); err != nil {
+		if err == models.ErrStatusNotFound || err == models.ErrStatusExpired {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -275,8 +303,20 @@ func (h *Handlers) GetStatusViews(c *gin.Context) {
 		}
 	}
 
-	views, err := h.service.GetStatusViews(c.Request.Context(), statusID, limit)
+	// Get viewer ID (optional)
+	var viewerID uuid.UUID
+	viewerID, _ = utils.CurrentUserID(c)
+
+	views, total, err := h.service.GetStatusViews(c.Request.Context(), statusID, viewerID, limit)
 	if err != nil {
+		if err == models.ErrStatusEngagementPrivate {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrStatusEngagementPrivate.Code})
+			return
+		}
+		if err == models.ErrStatusNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": models.ErrStatusNotFound.Code})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -284,6 +324,7 @@ func (h *Handlers) GetStatusViews(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"views":   views,
+		"total":   total,
 	})
 }
 
@@ -299,21 +340,22 @@ func (h *Handlers) ReactToStatus(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req models.ReactToStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	if err := h.service.ReactToStatus(c.Request.Context(), statusID, uid, req.Emoji); err != nil {
+		if err == models.ErrStatusReactionsDisabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrStatusReactionsDisabled.Code})
+			return
+		}
 		if err == models.ErrInvalidEmoji || err == models.ErrStatusNotFound || err == models.ErrStatusExpired {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -336,14 +378,12 @@ func (h *Handlers) RemoveStatusReaction(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.RemoveStatusReaction(c.Request.Context(), statusID, uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -370,8 +410,16 @@ func (h *Handlers) GetStatusReactions(c *gin.Context) {
 		}
 	}
 
-	reactions, err := h.service.GetStatusReactions(c.Request.Context(), statusID, limit)
+	// Get viewer ID (optional)
+	var viewerID uuid.UUID
+	viewerID, _ = utils.CurrentUserID(c)
+
+	reactions, err := h.service.GetStatusReactions(c.Request.Context(), statusID, viewerID, limit)
 	if err != nil {
+		if err == models.ErrStatusEngagementPrivate {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrStatusEngagementPrivate.Code})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -394,22 +442,23 @@ func (h *Handlers) CreateStatusComment(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	var req models.CreateStatusCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	comment, err := h.service.CreateStatusComment(c.Request.Context(), statusID, uid, req.Content)
 	if err != nil {
+		if err == models.ErrStatusRepliesDisabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrStatusRepliesDisabled.Code})
+			return
+		}
 		if err == models.ErrCommentTooLong || err == models.ErrStatusNotFound || err == models.ErrStatusExpired {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -469,14 +518,12 @@ func (h *Handlers) DeleteStatusComment(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, _ := uuid.Parse(userID.(string))
-
 	if err := h.service.DeleteStatusComment(c.Request.Context(), commentID, uid); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -500,24 +547,25 @@ func (h *Handlers) CreateStatusMessageReply(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
+	fromUserID, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	fromUserID, _ := uuid.Parse(userID.(string))
-
 	var req struct {
 		ToUserID uuid.UUID `json:"to_user_id" binding:"required"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !utils.BindJSON(c, &req) {
 		return
 	}
 
 	replyID, err := h.service.CreateStatusMessageReply(c.Request.Context(), statusID, fromUserID, req.ToUserID)
 	if err != nil {
+		if err == models.ErrStatusRepliesDisabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": models.ErrStatusRepliesDisabled.Code})
+			return
+		}
 		if err == models.ErrStatusNotFound || err == models.ErrStatusExpired {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -539,18 +587,12 @@ func (h *Handlers) CreateStatusMessageReply(c *gin.Context) {
 
 // UploadStatusImage handles POST /api/v1/statuses/upload-image
 func (h *Handlers) UploadStatusImage(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get quality parameter
 	quality := c.DefaultQuery("quality", "standard")
 	var optimizeQuality messaging.OptimizeImageQuality
@@ -561,7 +603,7 @@ func (h *Handlers) UploadStatusImage(c *gin.Context) {
 	}
 
 	// Get file from form
-	file, _, err := c.Request.FormFile("file")
+	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
 		return
@@ -575,6 +617,11 @@ func (h *Handlers) UploadStatusImage(c *gin.Context) {
 		return
 	}
 
+	// Check the actual bytes, not the client-declared type
+	if _, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryImage); !ok {
+		return
+	}
+
 	// Validate image
 	isValid, _, err := h.mediaOptimizer.ValidateImage(fileData)
 	if !isValid || err != nil {
@@ -583,7 +630,7 @@ func (h *Handlers) UploadStatusImage(c *gin.Context) {
 	}
 
 	// Optimize image
-	optimizedData, newFormat, err := h.mediaOptimizer.OptimizeImageFromBytes(fileData, optimizeQuality)
+	optimizedData, newFormat, err := h.mediaOptimizer.OptimizeUploadImage(fileData, optimizeQuality, messaging.UploadTypeStatus)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to optimize image"})
 		return
@@ -591,34 +638,27 @@ func (h *Handlers) UploadStatusImage(c *gin.Context) {
 
 	// Upload to Supabase Storage (use "statuses" bucket or "public" bucket)
 	fileName := fmt.Sprintf("statuses/%s/%s_%d.%s", uid.String(), uuid.New().String(), time.Now().Unix(), newFormat)
-	uploadedURL, err := h.storageService.UploadFile(c.Request.Context(), "statuses", fileName, bytes.NewReader(optimizedData), "image/"+newFormat)
-	if err != nil {
-		log.Printf("[UploadStatusImage] Failed to upload: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
+	uploadedURL, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "statuses", fileName, optimizedData, "image/"+newFormat, "Failed to upload image")
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":    true,
-		"media_url":  uploadedURL,
-		"media_type": "image/" + newFormat,
+		"success":       true,
+		"media_url":     uploadedURL,
+		"media_type":    "image/" + newFormat,
+		"storage_quota": quota,
 	})
 }
 
 // UploadStatusVideo handles POST /api/v1/statuses/upload-video
 func (h *Handlers) UploadStatusVideo(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := utils.CurrentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get video file from form
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
@@ -630,7 +670,6 @@ func (h *Handlers) UploadStatusVideo(c *gin.Context) {
 	// Validate file size (max 100MB for status videos)
 	maxSize := int64(100 * 1024 * 1024)
 	fileSize := header.Size
-	contentType := header.Header.Get("Content-Type")
 	if fileSize > maxSize {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Video too large (max 100MB). Your file: %.1fMB", float64(fileSize)/(1024*1024))})
 		return
@@ -643,21 +682,25 @@ func (h *Handlers) UploadStatusVideo(c *gin.Context) {
 		return
 	}
 
+	contentType, ok := utils.ValidateUpload(c, h.storageService, fileData, header.Header.Get("Content-Type"), utils.UploadCategoryVideo)
+	if !ok {
+		return
+	}
+
 	// Sanitize filename
 	sanitizedFilename := sanitizeFilename(header.Filename)
 
 	// Upload video to Supabase Storage
 	videoFileName := fmt.Sprintf("statuses/%s/video_%s_%s", uid.String(), uuid.New().String(), sanitizedFilename)
-	uploadedURL, err := h.storageService.UploadFile(c.Request.Context(), "statuses", videoFileName, bytes.NewReader(fileData), contentType)
-	if err != nil {
-		log.Printf("[UploadStatusVideo] Failed to upload: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to upload video: %v", err)})
+	uploadedURL, quota, ok := utils.StoreUserUpload(c, h.storageService, uid, "statuses", videoFileName, fileData, contentType, "Failed to upload video")
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":    true,
-		"media_url":  uploadedURL,
-		"media_type": contentType,
+		"success":       true,
+		"media_url":     uploadedURL,
+		"media_type":    contentType,
+		"storage_quota": quota,
 	})
 }
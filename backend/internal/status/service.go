@@ -3,48 +3,105 @@ package status
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
 
 	"github.com/google/uuid"
 )
 
+// statusLimitWindow is the rolling window the daily status cap applies to
+const statusLimitWindow = 24 * time.Hour
+
+// defaultStatusLifetime is how long a status is shown when no lifetime is configured
+const defaultStatusLifetime = 24 * time.Hour
+
+// DailyLimit caps how many statuses a user can post per rolling 24 hours (0 disables)
+type DailyLimit struct {
+	MaxPerDay      int
+	ExemptVerified bool // Verified accounts aren't limited
+}
+
 // Service handles status business logic
 type Service struct {
 	statusRepo repository.StatusRepository
+	userRepo   repository.UserRepository
+	relRepo    repository.RelationshipRepository
+	media      *utils.StorageService
+	dailyLimit DailyLimit
+	lifetime   time.Duration
 }
 
 // NewService creates a new status service
-func NewService(statusRepo repository.StatusRepository) *Service {
+func NewService(statusRepo repository.StatusRepository, userRepo repository.UserRepository) *Service {
 	return &Service{
 		statusRepo: statusRepo,
+		userRepo:   userRepo,
+		lifetime:   defaultStatusLifetime,
+	}
+}
+
+// SetDailyLimit sets the per-user status creation cap
+func (s *Service) SetDailyLimit(limit DailyLimit) {
+	s.dailyLimit = limit
+}
+
+// SetLifetime sets how long new statuses are shown before they expire. Values of 0
+// or less keep the 24 hour default.
+func (s *Service) SetLifetime(lifetime time.Duration) {
+	if lifetime > 0 {
+		s.lifetime = lifetime
 	}
 }
 
-// CreateStatus creates a new status (24-hour story)
+// SetMediaStorage sets the storage status images and videos are uploaded to, so
+// expired statuses take their media with them
+func (s *Service) SetMediaStorage(media *utils.StorageService) {
+	s.media = media
+}
+
+// SetRelationshipRepository sets the repository close friends lists are read from.
+// Without it close_friends statuses are seen by their authors only.
+func (s *Service) SetRelationshipRepository(relRepo repository.RelationshipRepository) {
+	s.relRepo = relRepo
+}
+
+// CreateStatus creates a new status, shown until its lifetime runs out
 func (s *Service) CreateStatus(ctx context.Context, req *models.CreateStatusRequest, userID uuid.UUID) (*models.Status, error) {
 	// Validate request
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
+	if err := s.checkDailyLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	// Create status
 	status := &models.Status{
-		UserID:          userID,
-		StatusType:      req.StatusType,
-		Content:         req.Content,
-		MediaURL:        req.MediaURL,
-		MediaType:       req.MediaType,
-		BackgroundColor: "#1a1f3a",                      // Default
-		ExpiresAt:       time.Now().Add(24 * time.Hour), // 24 hours from now
+		UserID:            userID,
+		StatusType:        req.StatusType,
+		Content:           req.Content,
+		MediaURL:          req.MediaURL,
+		MediaType:         req.MediaType,
+		BackgroundColor:   "#1a1f3a", // Default
+		Visibility:        models.VisibilityPublic,
+		AllowReactions:    req.AllowReactions == nil || *req.AllowReactions,
+		AllowReplies:      req.AllowReplies == nil || *req.AllowReplies,
+		EngagementPrivate: req.EngagementPrivate,
+		ExpiresAt:         time.Now().Add(s.lifetime),
 	}
 
 	// Set background color for text statuses
 	if req.BackgroundColor != nil && *req.BackgroundColor != "" {
 		status.BackgroundColor = *req.BackgroundColor
 	}
+	if req.Visibility != "" {
+		status.Visibility = req.Visibility
+	}
 
 	// Create status in database
 	if err := s.statusRepo.CreateStatus(ctx, status); err != nil {
@@ -65,11 +122,17 @@ func (s *Service) GetStatus(ctx context.Context, statusID, viewerID uuid.UUID) (
 	if time.Now().After(status.ExpiresAt) {
 		return nil, models.ErrStatusExpired
 	}
+	if err := s.checkAudience(ctx, status, viewerID); err != nil {
+		return nil, err
+	}
 
 	// Record view if not already viewed (async in production)
-	if !status.IsViewed && viewerID != uuid.Nil {
+	if !status.IsViewed {
+		viewed := *status
 		go func() {
-			s.statusRepo.CreateStatusView(context.Background(), statusID, viewerID)
+			if err := s.recordView(context.Background(), &viewed, viewerID); err != nil {
+				log.Printf("[Status] Failed to record view of %s by %s: %v", statusID, viewerID, err)
+			}
 		}()
 	}
 
@@ -87,11 +150,16 @@ func (s *Service) GetUserStatuses(ctx context.Context, userID, viewerID uuid.UUI
 		return nil, fmt.Errorf("failed to get user statuses: %w", err)
 	}
 
-	// Filter expired statuses
+	audience, err := s.audienceOf(ctx, userID, viewerID, statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter expired statuses, and those shared with an audience the viewer isn't in
 	activeStatuses := make([]models.Status, 0)
 	now := time.Now()
 	for _, status := range statuses {
-		if now.Before(status.ExpiresAt) {
+		if now.Before(status.ExpiresAt) && audience.CanSee(status.UserID, viewerID, status.Visibility) {
 			activeStatuses = append(activeStatuses, status)
 		}
 	}
@@ -141,26 +209,129 @@ func (s *Service) DeleteStatus(ctx context.Context, statusID, userID uuid.UUID)
 	return nil
 }
 
+// PurgeExpiredStatuses permanently deletes expired statuses and their media,
+// batchSize at a time, keeping a view and reaction summary of each for its creator.
+// A status whose media can't be deleted is kept for the next purge.
+func (s *Service) PurgeExpiredStatuses(ctx context.Context, batchSize int) (int, error) {
+	purged := 0
+
+	for ctx.Err() == nil {
+		batch, err := s.statusRepo.GetExpiredStatuses(ctx, batchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		ids := make([]uuid.UUID, 0, len(batch))
+		for _, status := range batch {
+			if err := s.deleteStatusMedia(ctx, status); err != nil {
+				log.Printf("[Status] Keeping expired status %s for the next purge, media delete failed: %v", status.ID, err)
+				continue
+			}
+			ids = append(ids, status.ID)
+		}
+
+		count, err := s.statusRepo.PurgeExpiredStatuses(ctx, ids)
+		if err != nil {
+			return purged, err
+		}
+		purged += count
+
+		// A short batch is the last one; a batch where nothing could be purged would
+		// just come back again
+		if len(batch) < batchSize || count == 0 {
+			break
+		}
+	}
+
+	return purged, ctx.Err()
+}
+
+// deleteStatusMedia deletes the image or video behind a status
+func (s *Service) deleteStatusMedia(ctx context.Context, status models.Status) error {
+	if s.media == nil || status.MediaURL == nil || *status.MediaURL == "" {
+		return nil
+	}
+	return s.media.DeleteFile(ctx, *status.MediaURL)
+}
+
+// GetStatusSummaries returns a page of what's left of userID's expired statuses
+func (s *Service) GetStatusSummaries(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.StatusSummary, error) {
+	summaries, err := s.statusRepo.GetStatusSummaries(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status summaries: %w", err)
+	}
+	return summaries, nil
+}
+
 // ViewStatus records a view on a status
 func (s *Service) ViewStatus(ctx context.Context, statusID, viewerID uuid.UUID) error {
-	if err := s.statusRepo.CreateStatusView(ctx, statusID, viewerID); err != nil {
+	status, err := s.getActiveStatus(ctx, statusID, viewerID)
+	if err != nil {
+		return err
+	}
+	return s.recordView(ctx, status, viewerID)
+}
+
+// recordView counts viewerID's view of status. Each viewer is counted once, at their
+// first view. Authors looking at their own status and users blocked either way with
+// the author leave no view behind.
+func (s *Service) recordView(ctx context.Context, status *models.Status, viewerID uuid.UUID) error {
+	if viewerID == uuid.Nil || viewerID == status.UserID {
+		return nil
+	}
+	for _, pair := range [2][2]uuid.UUID{{status.UserID, viewerID}, {viewerID, status.UserID}} {
+		blocked, err := s.userRepo.IsUserBlocked(ctx, pair[0], pair[1])
+		if err != nil {
+			return fmt.Errorf("failed to check blocks: %w", err)
+		}
+		if blocked {
+			return nil
+		}
+	}
+
+	if err := s.statusRepo.CreateStatusView(ctx, status.ID, viewerID); err != nil {
 		return fmt.Errorf("failed to record view: %w", err)
 	}
 	return nil
 }
 
-// GetStatusViews retrieves views for a status
-func (s *Service) GetStatusViews(ctx context.Context, statusID uuid.UUID, limit int) ([]models.StatusView, error) {
+// GetStatusViews retrieves who viewed a status, most recent view first, with the
+// status's total view count. For signed-in viewers each entry carries how they relate
+// to the person who viewed. Private engagement lists are author-only.
+func (s *Service) GetStatusViews(ctx context.Context, statusID, viewerID uuid.UUID, limit int) ([]models.StatusView, int, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 
+	status, err := s.checkEngagementVisible(ctx, statusID, viewerID)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	views, err := s.statusRepo.GetStatusViews(ctx, statusID, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get status views: %w", err)
+		return nil, 0, fmt.Errorf("failed to get status views: %w", err)
+	}
+
+	if viewerID != uuid.Nil && s.relRepo != nil && len(views) > 0 {
+		ids := make([]uuid.UUID, len(views))
+		for i, view := range views {
+			ids[i] = view.UserID
+		}
+		states, err := s.relRepo.GetFollowStates(ctx, viewerID, ids)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get follow states: %w", err)
+		}
+		for i := range views {
+			views[i].Relationship = states[views[i].UserID]
+		}
 	}
 
-	return views, nil
+	total := status.ViewsCount
+	if total < len(views) {
+		total = len(views)
+	}
+	return views, total, nil
 }
 
 // ReactToStatus adds or updates a reaction on a status
@@ -171,6 +342,14 @@ func (s *Service) ReactToStatus(ctx context.Context, statusID, userID uuid.UUID,
 		return models.ErrInvalidEmoji
 	}
 
+	status, err := s.getActiveStatus(ctx, statusID, userID)
+	if err != nil {
+		return err
+	}
+	if !status.AllowReactions && status.UserID != userID {
+		return models.ErrStatusReactionsDisabled
+	}
+
 	if err := s.statusRepo.ReactToStatus(ctx, statusID, userID, emoji); err != nil {
 		return fmt.Errorf("failed to react to status: %w", err)
 	}
@@ -187,12 +366,16 @@ func (s *Service) RemoveStatusReaction(ctx context.Context, statusID, userID uui
 	return nil
 }
 
-// GetStatusReactions retrieves reactions for a status
-func (s *Service) GetStatusReactions(ctx context.Context, statusID uuid.UUID, limit int) ([]models.StatusReaction, error) {
+// GetStatusReactions retrieves reactions for a status. Private engagement lists are author-only.
+func (s *Service) GetStatusReactions(ctx context.Context, statusID, viewerID uuid.UUID, limit int) ([]models.StatusReaction, error) {
 	if limit <= 0 {
 		limit = 50
 	}
 
+	if _, err := s.checkEngagementVisible(ctx, statusID, viewerID); err != nil {
+		return nil, err
+	}
+
 	reactions, err := s.statusRepo.GetStatusReactions(ctx, statusID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reactions: %w", err)
@@ -208,14 +391,13 @@ func (s *Service) CreateStatusComment(ctx context.Context, statusID, userID uuid
 		return nil, models.ErrCommentTooLong
 	}
 
-	// Verify status exists and is not expired
-	status, err := s.statusRepo.GetStatus(ctx, statusID, userID)
+	// Verify status exists, is not expired and accepts replies
+	status, err := s.getActiveStatus(ctx, statusID, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get status: %w", err)
+		return nil, err
 	}
-
-	if time.Now().After(status.ExpiresAt) {
-		return nil, models.ErrStatusExpired
+	if !status.AllowReplies && status.UserID != userID {
+		return nil, models.ErrStatusRepliesDisabled
 	}
 
 	comment := &models.StatusComment{
@@ -259,20 +441,19 @@ func (s *Service) DeleteStatusComment(ctx context.Context, commentID, userID uui
 
 // CreateStatusMessageReply creates a record of a direct message reply to a status
 func (s *Service) CreateStatusMessageReply(ctx context.Context, statusID, fromUserID, toUserID uuid.UUID) (*uuid.UUID, error) {
-	// Verify status exists and is not expired
-	status, err := s.statusRepo.GetStatus(ctx, statusID, fromUserID)
+	// Verify status exists, is not expired and accepts replies
+	status, err := s.getActiveStatus(ctx, statusID, fromUserID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get status: %w", err)
-	}
-
-	if time.Now().After(status.ExpiresAt) {
-		return nil, models.ErrStatusExpired
+		return nil, err
 	}
 
 	// Verify toUserID is the status owner
 	if status.UserID != toUserID {
 		return nil, fmt.Errorf("status owner mismatch")
 	}
+	if !status.AllowReplies {
+		return nil, models.ErrStatusRepliesDisabled
+	}
 
 	replyID, err := s.statusRepo.CreateStatusMessageReply(ctx, statusID, fromUserID, toUserID)
 	if err != nil {
@@ -281,3 +462,105 @@ func (s *Service) CreateStatusMessageReply(ctx context.Context, statusID, fromUs
 
 	return replyID, nil
 }
+
+// getActiveStatus loads a status and rejects it once expired
+func (s *Service) getActiveStatus(ctx context.Context, statusID, viewerID uuid.UUID) (*models.Status, error) {
+	status, err := s.statusRepo.GetStatus(ctx, statusID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	if time.Now().After(status.ExpiresAt) {
+		return nil, models.ErrStatusExpired
+	}
+	if err := s.checkAudience(ctx, status, viewerID); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// checkAudience returns ErrStatusNotFound when status was shared with an audience
+// viewerID isn't in, so close friends statuses don't give themselves away
+func (s *Service) checkAudience(ctx context.Context, status *models.Status, viewerID uuid.UUID) error {
+	audience, err := s.audienceOf(ctx, status.UserID, viewerID, []models.Status{*status})
+	if err != nil {
+		return err
+	}
+	if !audience.CanSee(status.UserID, viewerID, status.Visibility) {
+		return models.ErrStatusNotFound
+	}
+	return nil
+}
+
+// audienceOf returns whether viewerID is on ownerID's close friends list, as a
+// CloseFriendsOf set. The list is only read when statuses include a close_friends one
+// viewerID didn't post.
+func (s *Service) audienceOf(ctx context.Context, ownerID, viewerID uuid.UUID, statuses []models.Status) (models.CloseFriendsOf, error) {
+	audience := models.CloseFriendsOf{}
+	if ownerID == viewerID || viewerID == uuid.Nil || s.relRepo == nil {
+		return audience, nil
+	}
+	for _, status := range statuses {
+		if status.Visibility != models.VisibilityCloseFriends {
+			continue
+		}
+		member, err := s.relRepo.IsCloseFriend(ctx, ownerID, viewerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check close friends: %w", err)
+		}
+		audience[ownerID] = member
+		break
+	}
+	return audience, nil
+}
+
+// checkEngagementVisible returns ErrStatusEngagementPrivate when the author has made the
+// views/reactions lists private and viewerID isn't the author. Otherwise it returns the
+// status.
+func (s *Service) checkEngagementVisible(ctx context.Context, statusID, viewerID uuid.UUID) (*models.Status, error) {
+	status, err := s.statusRepo.GetStatus(ctx, statusID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	if err := s.checkAudience(ctx, status, viewerID); err != nil {
+		return nil, err
+	}
+	if status.EngagementPrivate && status.UserID != viewerID {
+		return nil, models.ErrStatusEngagementPrivate
+	}
+
+	return status, nil
+}
+
+// checkDailyLimit returns a StatusLimitError when userID has already posted MaxPerDay
+// statuses within the last 24 hours
+func (s *Service) checkDailyLimit(ctx context.Context, userID uuid.UUID) error {
+	if s.dailyLimit.MaxPerDay <= 0 {
+		return nil
+	}
+
+	if s.dailyLimit.ExemptVerified && s.userRepo != nil {
+		if user, err := s.userRepo.GetUserByID(ctx, userID); err == nil && user.IsVerified {
+			return nil
+		}
+	}
+
+	now := time.Now()
+	recent, err := s.statusRepo.GetStatusCreationTimesSince(ctx, userID, now.Add(-statusLimitWindow))
+	if err != nil {
+		return fmt.Errorf("failed to check status limit: %w", err)
+	}
+	if len(recent) < s.dailyLimit.MaxPerDay {
+		return nil
+	}
+
+	// A slot frees up once enough of the oldest statuses age out of the window
+	resetAt := recent[len(recent)-s.dailyLimit.MaxPerDay].Add(statusLimitWindow)
+	return &models.StatusLimitError{
+		Message: fmt.Sprintf("You can post up to %d statuses per day", s.dailyLimit.MaxPerDay),
+		Limit:   s.dailyLimit.MaxPerDay,
+		ResetAt: resetAt,
+	}
+}
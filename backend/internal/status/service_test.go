@@ -0,0 +1,271 @@
+package status
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/config"
+	"histeeria-backend/internal/models"
+	"histeeria-backend/internal/repository"
+	"histeeria-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// fakeStatusRepo serves feed and expired statuses from slices; anything else panics
+// through the nil embedded interface
+type fakeStatusRepo struct {
+	repository.StatusRepository
+
+	feed      []models.Status
+	expired   []models.Status // Waiting to be purged, longest expired first
+	purged    []uuid.UUID
+	status    *models.Status // Served by GetStatus
+	views     []models.StatusView
+	reactions map[uuid.UUID]string // Emoji per user
+	replies   int
+	posted    []time.Time // Creation times, oldest first
+}
+
+func (r *fakeStatusRepo) CreateStatus(ctx context.Context, status *models.Status) error {
+	r.posted = append(r.posted, time.Now())
+	return nil
+}
+
+func (r *fakeStatusRepo) GetStatusCreationTimesSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]time.Time, error) {
+	var recent []time.Time
+	for _, at := range r.posted {
+		if at.After(since) {
+			recent = append(recent, at)
+		}
+	}
+	return recent, nil
+}
+
+// fakeUserRepo serves one user for the verified exemption
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	user *models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return r.user, nil
+}
+
+func (r *fakeStatusRepo) GetStatus(ctx context.Context, statusID, viewerID uuid.UUID) (*models.Status, error) {
+	return r.status, nil
+}
+
+func (r *fakeStatusRepo) GetStatusViews(ctx context.Context, statusID uuid.UUID, limit int) ([]models.StatusView, error) {
+	return r.views, nil
+}
+
+func (r *fakeStatusRepo) ReactToStatus(ctx context.Context, statusID, userID uuid.UUID, emoji string) error {
+	r.reactions[userID] = emoji
+	return nil
+}
+
+func (r *fakeStatusRepo) CreateStatusMessageReply(ctx context.Context, statusID, fromUserID, toUserID uuid.UUID) (*uuid.UUID, error) {
+	r.replies++
+	id := uuid.New()
+	return &id, nil
+}
+
+func (r *fakeStatusRepo) GetStatusesForFeed(ctx context.Context, viewerID uuid.UUID, limit int) ([]models.Status, error) {
+	return r.feed, nil
+}
+
+func (r *fakeStatusRepo) GetExpiredStatuses(ctx context.Context, limit int) ([]models.Status, error) {
+	return r.expired[:min(limit, len(r.expired))], nil
+}
+
+func (r *fakeStatusRepo) PurgeExpiredStatuses(ctx context.Context, statusIDs []uuid.UUID) (int, error) {
+	purge := make(map[uuid.UUID]bool, len(statusIDs))
+	for _, id := range statusIDs {
+		purge[id] = true
+	}
+	kept := r.expired[:0]
+	for _, status := range r.expired {
+		if purge[status.ID] {
+			r.purged = append(r.purged, status.ID)
+		} else {
+			kept = append(kept, status)
+		}
+	}
+	r.expired = kept
+	return len(statusIDs), nil
+}
+
+func TestExpiredStatusLeavesTheFeed(t *testing.T) {
+	now := time.Now()
+	live := models.Status{ID: uuid.New(), ExpiresAt: now.Add(time.Hour)}
+	expired := models.Status{ID: uuid.New(), ExpiresAt: now.Add(-time.Second)}
+	svc := NewService(&fakeStatusRepo{feed: []models.Status{live, expired}}, nil)
+
+	statuses, err := svc.GetStatusesForFeed(context.Background(), uuid.New(), 10)
+	if err != nil {
+		t.Fatalf("GetStatusesForFeed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].ID != live.ID {
+		t.Errorf("feed = %v, want just the live status", statuses)
+	}
+}
+
+func TestPurgeKeepsStatusesWhoseMediaDeleteFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "broken") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("0"))
+	}))
+	defer server.Close()
+	media := utils.NewStorageService(&config.StorageConfig{BucketName: "statuses"}, server.URL, "key")
+
+	owner := uuid.New().String()
+	mediaURL := func(name string) *string {
+		url := server.URL + "/storage/v1/object/public/statuses/" + owner + "/" + name
+		return &url
+	}
+	text := models.Status{ID: uuid.New()}
+	image := models.Status{ID: uuid.New(), MediaURL: mediaURL("ok.jpg")}
+	stuck := models.Status{ID: uuid.New(), MediaURL: mediaURL("broken.jpg")}
+	repo := &fakeStatusRepo{expired: []models.Status{text, stuck, image}}
+	svc := NewService(repo, nil)
+	svc.SetMediaStorage(media)
+
+	purged, err := svc.PurgeExpiredStatuses(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("PurgeExpiredStatuses: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("purged %d statuses, want 2", purged)
+	}
+	if len(repo.expired) != 1 || repo.expired[0].ID != stuck.ID {
+		t.Errorf("left %v, want just the status whose media is still stored", repo.expired)
+	}
+}
+
+func TestStatusEngagementSettings(t *testing.T) {
+	author, viewer := uuid.New(), uuid.New()
+	newStatus := func(allowReactions, allowReplies bool) *fakeStatusRepo {
+		return &fakeStatusRepo{
+			status: &models.Status{
+				ID: uuid.New(), UserID: author, ExpiresAt: time.Now().Add(time.Hour),
+				AllowReactions: allowReactions, AllowReplies: allowReplies,
+			},
+			reactions: map[uuid.UUID]string{},
+		}
+	}
+
+	t.Run("reactions disabled", func(t *testing.T) {
+		repo := newStatus(false, true)
+		svc := NewService(repo, nil)
+		if err := svc.ReactToStatus(context.Background(), repo.status.ID, viewer, "👍"); err != models.ErrStatusReactionsDisabled {
+			t.Errorf("err = %v, want ErrStatusReactionsDisabled", err)
+		}
+		if len(repo.reactions) != 0 {
+			t.Errorf("reaction recorded on a reactions-disabled status: %v", repo.reactions)
+		}
+	})
+
+	t.Run("reactions allowed", func(t *testing.T) {
+		repo := newStatus(true, true)
+		svc := NewService(repo, nil)
+		if err := svc.ReactToStatus(context.Background(), repo.status.ID, viewer, "👍"); err != nil {
+			t.Fatalf("ReactToStatus: %v", err)
+		}
+		if repo.reactions[viewer] != "👍" {
+			t.Errorf("reaction not recorded: %v", repo.reactions)
+		}
+	})
+
+	t.Run("replies disabled", func(t *testing.T) {
+		repo := newStatus(true, false)
+		svc := NewService(repo, nil)
+		if _, err := svc.CreateStatusMessageReply(context.Background(), repo.status.ID, viewer, author); err != models.ErrStatusRepliesDisabled {
+			t.Errorf("err = %v, want ErrStatusRepliesDisabled", err)
+		}
+		if repo.replies != 0 {
+			t.Errorf("reply recorded on a replies-disabled status")
+		}
+	})
+}
+
+func TestPrivateViewsListIsAuthorOnly(t *testing.T) {
+	author, viewer := uuid.New(), uuid.New()
+	views := []models.StatusView{{UserID: viewer}}
+
+	for _, private := range []bool{true, false} {
+		repo := &fakeStatusRepo{
+			status: &models.Status{ID: uuid.New(), UserID: author, ExpiresAt: time.Now().Add(time.Hour), EngagementPrivate: private},
+			views:  views,
+		}
+		svc := NewService(repo, nil)
+
+		if got, _, err := svc.GetStatusViews(context.Background(), repo.status.ID, author, 0); err != nil || len(got) != 1 {
+			t.Errorf("private=%v: author got %d views, err %v; want the list", private, len(got), err)
+		}
+
+		got, _, err := svc.GetStatusViews(context.Background(), repo.status.ID, viewer, 0)
+		if private && err != models.ErrStatusEngagementPrivate {
+			t.Errorf("private: err = %v, want ErrStatusEngagementPrivate", err)
+		}
+		if !private && (err != nil || len(got) != 1) {
+			t.Errorf("public: viewer got %d views, err %v; want the list", len(got), err)
+		}
+	}
+}
+
+func TestDailyStatusLimit(t *testing.T) {
+	now := time.Now()
+	content := "hello"
+	req := &models.CreateStatusRequest{StatusType: "text", Content: &content}
+
+	tests := []struct {
+		name      string
+		posted    []time.Time
+		verified  bool
+		wantReset time.Time // Zero when the status should be created
+	}{
+		{"under the limit", []time.Time{now.Add(-2 * time.Hour)}, false, time.Time{}},
+		{"at the limit", []time.Time{now.Add(-3 * time.Hour), now.Add(-time.Hour)}, false, now.Add(21 * time.Hour)},
+		{"oldest aged out", []time.Time{now.Add(-25 * time.Hour), now.Add(-time.Hour)}, false, time.Time{}},
+		{"verified exempt", []time.Time{now.Add(-3 * time.Hour), now.Add(-time.Hour)}, true, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeStatusRepo{posted: tt.posted}
+			svc := NewService(repo, &fakeUserRepo{user: &models.User{IsVerified: tt.verified}})
+			svc.SetDailyLimit(DailyLimit{MaxPerDay: 2, ExemptVerified: true})
+
+			_, err := svc.CreateStatus(context.Background(), req, uuid.New())
+			if tt.wantReset.IsZero() {
+				if err != nil {
+					t.Fatalf("CreateStatus: %v", err)
+				}
+				if len(repo.posted) != len(tt.posted)+1 {
+					t.Error("status was not created")
+				}
+				return
+			}
+
+			limitErr, ok := err.(*models.StatusLimitError)
+			if !ok {
+				t.Fatalf("err = %v, want a StatusLimitError", err)
+			}
+			if limitErr.Limit != 2 || !limitErr.ResetAt.Equal(tt.wantReset) {
+				t.Errorf("limit %d resetting at %v, want 2 at %v", limitErr.Limit, limitErr.ResetAt, tt.wantReset)
+			}
+			if len(repo.posted) != len(tt.posted) {
+				t.Error("status created over the limit")
+			}
+		})
+	}
+}
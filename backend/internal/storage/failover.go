@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Circuit breaker defaults for the primary provider
+const (
+	defaultFailureThreshold = 5 // Consecutive primary failures before routing everything to fallback
+)
+
+// Circuit states reported by ProviderStatus
+const (
+	CircuitClosed = "closed" // Primary serving traffic
+	CircuitOpen   = "open"   // Primary failing, all traffic on fallback until ProbePrimary succeeds
+)
+
+// StatusError is returned by providers when the storage API responds with an error status
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("%s failed with status %d: %s", e.Op, e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("%s failed with status %d", e.Op, e.StatusCode)
+}
+
+func newStatusError(op string, statusCode int, body string) error {
+	return &StatusError{Op: op, StatusCode: statusCode, Body: body}
+}
+
+// isProviderFailure reports whether err means the provider itself is unavailable
+// (timeout, connection error, 5xx or throttling) rather than a problem with the request.
+// Cancellations by the caller are never counted against the provider.
+func isProviderFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == 429
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// circuitBreaker tracks primary provider health. After threshold consecutive failures
+// it opens and stays open until a health probe against the primary succeeds.
+type circuitBreaker struct {
+	mu          sync.RWMutex
+	threshold   int
+	failures    int
+	open        bool
+	openedAt    time.Time
+	lastError   string
+	lastFailure time.Time
+}
+
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold}
+}
+
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.open
+}
+
+// recordFailure counts a primary failure and reports whether it just opened the circuit
+func (b *circuitBreaker) recordFailure(err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastError = err.Error()
+	b.lastFailure = time.Now()
+	if !b.open && b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.lastFailure
+		return true
+	}
+	return false
+}
+
+// recordSuccess resets the failure count and reports whether it closed an open circuit
+func (b *circuitBreaker) recordSuccess() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := b.open
+	b.failures = 0
+	b.open = false
+	return wasOpen
+}
+
+// ProviderStatus describes which provider is serving storage traffic
+type ProviderStatus struct {
+	Primary             string     `json:"primary"`
+	Fallback            string     `json:"fallback,omitempty"`
+	Active              string     `json:"active"`
+	Circuit             string     `json:"circuit"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// ProviderStatus returns the current primary/fallback routing state
+func (s *StorageService) ProviderStatus() ProviderStatus {
+	s.breaker.mu.RLock()
+	defer s.breaker.mu.RUnlock()
+
+	status := ProviderStatus{
+		Primary:             s.primary.GetProviderName(),
+		Active:              s.primary.GetProviderName(),
+		Circuit:             CircuitClosed,
+		ConsecutiveFailures: s.breaker.failures,
+		LastError:           s.breaker.lastError,
+	}
+	if s.fallback != nil {
+		status.Fallback = s.fallback.GetProviderName()
+	}
+	if s.breaker.open {
+		status.Circuit = CircuitOpen
+		openedAt := s.breaker.openedAt
+		status.OpenedAt = &openedAt
+		if s.fallback != nil {
+			status.Active = s.fallback.GetProviderName()
+		}
+	}
+	if !s.breaker.lastFailure.IsZero() {
+		lastFailure := s.breaker.lastFailure
+		status.LastFailureAt = &lastFailure
+	}
+	return status
+}
+
+// primaryAvailable reports whether requests should go to the primary provider first
+func (s *StorageService) primaryAvailable() bool {
+	return s.fallback == nil || !s.breaker.isOpen()
+}
+
+// withFallback runs fn against the primary provider, or straight against the fallback
+// while the circuit is open. Primary provider failures are counted by the breaker and
+// retried on the fallback. When retryAnyError is set (reads), any primary error is
+// retried on the fallback, since objects written during an outage only exist there.
+func (s *StorageService) withFallback(ctx context.Context, op, key string, retryAnyError bool, fn func(StorageProvider) error) error {
+	if s.fallback == nil {
+		return fn(s.primary)
+	}
+	if !s.primaryAvailable() {
+		return fn(s.fallback)
+	}
+
+	err := fn(s.primary)
+	if err == nil {
+		if s.breaker.recordSuccess() {
+			log.Printf("[Storage] Primary provider %s recovered, circuit closed", s.primary.GetProviderName())
+		}
+		return nil
+	}
+
+	providerFailure := isProviderFailure(ctx, err)
+	if !providerFailure && !retryAnyError {
+		return err
+	}
+
+	if providerFailure {
+		log.Printf("[Storage] WARN primary provider failure: provider=%s op=%s key=%q error=%q fallback=%s",
+			s.primary.GetProviderName(), op, key, err.Error(), s.fallback.GetProviderName())
+		if s.breaker.recordFailure(err) {
+			log.Printf("[Storage] WARN circuit opened: routing all traffic to %s until %s passes a health probe",
+				s.fallback.GetProviderName(), s.primary.GetProviderName())
+		}
+	}
+
+	return fn(s.fallback)
+}
+
+// ProbePrimary health-checks the primary provider while the circuit is open and
+// closes the circuit once it responds. It is a no-op while the primary is serving
+// traffic, so it can run frequently as a scheduled job.
+func (s *StorageService) ProbePrimary(ctx context.Context) error {
+	if s.fallback == nil || !s.breaker.isOpen() {
+		return nil
+	}
+
+	if err := s.primary.HealthCheck(ctx); err != nil {
+		return fmt.Errorf("primary storage provider %s still unavailable: %w", s.primary.GetProviderName(), err)
+	}
+	if s.breaker.recordSuccess() {
+		log.Printf("[Storage] Health probe: primary %s recovered, circuit closed", s.primary.GetProviderName())
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
@@ -88,7 +89,7 @@ func (r *R2Provider) Upload(ctx context.Context, key string, data io.Reader, opt
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, newStatusError("upload", resp.StatusCode, string(bodyBytes))
 	}
 
 	return &StorageObject{
@@ -121,7 +122,7 @@ func (r *R2Provider) Download(ctx context.Context, key string, opts *DownloadOpt
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
 		resp.Body.Close()
-		return nil, nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+		return nil, nil, newStatusError("download", resp.StatusCode, "")
 	}
 
 	obj := &StorageObject{
@@ -157,7 +158,7 @@ func (r *R2Provider) Delete(ctx context.Context, key string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
-		return fmt.Errorf("delete failed with status %d", resp.StatusCode)
+		return newStatusError("delete", resp.StatusCode, "")
 	}
 
 	return nil
@@ -208,7 +209,7 @@ func (r *R2Provider) GetMetadata(ctx context.Context, key string) (*StorageObjec
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get metadata failed with status %d", resp.StatusCode)
+		return nil, newStatusError("get metadata", resp.StatusCode, "")
 	}
 
 	obj := &StorageObject{
@@ -271,15 +272,45 @@ func (r *R2Provider) List(ctx context.Context, opts *ListOptions) (*ListResult,
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("list failed with status %d", resp.StatusCode)
+		return nil, newStatusError("list", resp.StatusCode, "")
 	}
 
-	// Parse XML response (simplified - in production, use proper XML parsing)
-	// For now, return empty result as placeholder
-	return &ListResult{
-		Objects:     []StorageObject{},
-		IsTruncated: false,
-	}, nil
+	// Parse S3 ListObjectsV2 XML response
+	var listResp struct {
+		IsTruncated bool `xml:"IsTruncated"`
+		Contents    []struct {
+			Key          string `xml:"Key"`
+			Size         int64  `xml:"Size"`
+			ETag         string `xml:"ETag"`
+			LastModified string `xml:"LastModified"`
+		} `xml:"Contents"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+		return nil, fmt.Errorf("failed to parse list response: %w", err)
+	}
+
+	objects := make([]StorageObject, 0, len(listResp.Contents))
+	for _, item := range listResp.Contents {
+		obj := StorageObject{
+			Key:  item.Key,
+			Size: item.Size,
+			ETag: strings.Trim(item.ETag, "\""),
+		}
+		if t, err := time.Parse(time.RFC3339, item.LastModified); err == nil {
+			obj.LastModified = t
+		}
+		objects = append(objects, obj)
+	}
+
+	result := &ListResult{
+		Objects:     objects,
+		IsTruncated: listResp.IsTruncated,
+	}
+	if listResp.IsTruncated && len(objects) > 0 {
+		// Continue after the last key (start-after)
+		result.NextStartKey = objects[len(objects)-1].Key
+	}
+	return result, nil
 }
 
 // GetSignedUploadURL generates a pre-signed URL for uploads
@@ -292,6 +323,27 @@ func (r *R2Provider) GetSignedUploadURL(ctx context.Context, key string, opts *S
 	return r.generatePresignedURL("PUT", key, expiration, opts)
 }
 
+// GeneratePresignedUploadURL generates a pre-signed PUT URL for a direct client upload.
+// Content-Type and Content-Length are part of the signature, so the client must send
+// exactly these values - R2 rejects an upload of a different type or size.
+func (r *R2Provider) GeneratePresignedUploadURL(key, contentType string, contentLength int64, expiry time.Duration) (string, error) {
+	if contentType == "" {
+		return "", fmt.Errorf("content type is required")
+	}
+	if contentLength <= 0 {
+		return "", fmt.Errorf("content length must be positive")
+	}
+	if expiry <= 0 {
+		expiry = 15 * time.Minute
+	}
+
+	headers := map[string]string{
+		"content-type":   contentType,
+		"content-length": fmt.Sprintf("%d", contentLength),
+	}
+	return r.generatePresignedURLWithHeaders("PUT", key, expiry, headers, nil)
+}
+
 // GetSignedDownloadURL generates a pre-signed URL for downloads
 func (r *R2Provider) GetSignedDownloadURL(ctx context.Context, key string, opts *SignedURLOptions) (string, error) {
 	expiration := 1 * time.Hour
@@ -333,7 +385,7 @@ func (r *R2Provider) Copy(ctx context.Context, sourceKey, destKey string) error
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("copy failed with status %d", resp.StatusCode)
+		return newStatusError("copy", resp.StatusCode, "")
 	}
 
 	return nil
@@ -433,6 +485,12 @@ func (r *R2Provider) deriveSigningKey(dateStamp, region, service string) []byte
 }
 
 func (r *R2Provider) generatePresignedURL(method, key string, expiration time.Duration, opts *SignedURLOptions) (string, error) {
+	return r.generatePresignedURLWithHeaders(method, key, expiration, nil, opts)
+}
+
+// generatePresignedURLWithHeaders presigns a URL that additionally binds the given
+// headers (lowercase names) into the signature
+func (r *R2Provider) generatePresignedURLWithHeaders(method, key string, expiration time.Duration, headers map[string]string, opts *SignedURLOptions) (string, error) {
 	t := time.Now().UTC()
 	amzDate := t.Format("20060102T150405Z")
 	dateStamp := t.Format("20060102")
@@ -447,7 +505,6 @@ func (r *R2Provider) generatePresignedURL(method, key string, expiration time.Du
 		r.config.AccessKeyID, dateStamp, region, service))
 	params.Set("X-Amz-Date", amzDate)
 	params.Set("X-Amz-Expires", fmt.Sprintf("%d", int(expiration.Seconds())))
-	params.Set("X-Amz-SignedHeaders", "host")
 
 	if opts != nil && opts.ResponseDisposition != "" {
 		params.Set("response-content-disposition", opts.ResponseDisposition)
@@ -457,12 +514,30 @@ func (r *R2Provider) generatePresignedURL(method, key string, expiration time.Du
 	objectPath := fmt.Sprintf("/%s/%s", r.config.BucketName, key)
 	host := fmt.Sprintf("%s.r2.cloudflarestorage.com", r.config.AccountID)
 
+	// Signed headers must be sorted by name; host is always signed
+	signed := map[string]string{"host": host}
+	for name, value := range headers {
+		signed[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	params.Set("X-Amz-SignedHeaders", signedHeaders)
+
 	canonicalRequest := strings.Join([]string{
 		method,
 		objectPath,
 		params.Encode(),
-		fmt.Sprintf("host:%s\n", host),
-		"host",
+		canonicalHeaders.String(),
+		signedHeaders,
 		"UNSIGNED-PAYLOAD",
 	}, "\n")
 
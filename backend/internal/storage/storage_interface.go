@@ -1,11 +1,19 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"time"
 )
 
+// ErrPresignedUploadNotSupported is returned when the active storage provider
+// cannot issue presigned direct-upload URLs (only R2 supports them)
+var ErrPresignedUploadNotSupported = errors.New("presigned direct uploads are not supported by the current storage provider")
+
 // StorageObject represents an object in storage
 type StorageObject struct {
 	Key          string            `json:"key"`
@@ -101,6 +109,7 @@ type StorageService struct {
 	primary   StorageProvider
 	fallback  StorageProvider
 	providers map[string]StorageProvider
+	breaker   *circuitBreaker
 }
 
 // NewStorageService creates a new storage service with the given primary provider
@@ -110,6 +119,7 @@ func NewStorageService(primary StorageProvider) *StorageService {
 		providers: map[string]StorageProvider{
 			primary.GetProviderName(): primary,
 		},
+		breaker: newCircuitBreaker(defaultFailureThreshold),
 	}
 }
 
@@ -130,36 +140,48 @@ func (s *StorageService) GetProvider(name string) (StorageProvider, bool) {
 	return provider, ok
 }
 
-// Upload uploads data using the primary provider with fallback
+// Upload uploads data using the primary provider, retrying on the fallback if the primary is unavailable
 func (s *StorageService) Upload(ctx context.Context, key string, data io.Reader, opts *UploadOptions) (*StorageObject, error) {
-	obj, err := s.primary.Upload(ctx, key, data, opts)
-	if err != nil && s.fallback != nil {
-		// If data is seekable, reset it for retry
-		if seeker, ok := data.(io.Seeker); ok {
-			seeker.Seek(0, io.SeekStart)
-		}
-		return s.fallback.Upload(ctx, key, data, opts)
+	if s.fallback == nil {
+		return s.primary.Upload(ctx, key, data, opts)
+	}
+
+	// Buffer so the body can be replayed against the fallback
+	body, err := io.ReadAll(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
 	}
+
+	var obj *StorageObject
+	err = s.withFallback(ctx, "upload", key, false, func(p StorageProvider) error {
+		var uploadErr error
+		obj, uploadErr = p.Upload(ctx, key, bytes.NewReader(body), opts)
+		return uploadErr
+	})
 	return obj, err
 }
 
 // Download downloads data using the primary provider with fallback
 func (s *StorageService) Download(ctx context.Context, key string, opts *DownloadOptions) (io.ReadCloser, *StorageObject, error) {
-	reader, obj, err := s.primary.Download(ctx, key, opts)
-	if err != nil && s.fallback != nil {
-		return s.fallback.Download(ctx, key, opts)
-	}
+	var reader io.ReadCloser
+	var obj *StorageObject
+	err := s.withFallback(ctx, "download", key, true, func(p StorageProvider) error {
+		var downloadErr error
+		reader, obj, downloadErr = p.Download(ctx, key, opts)
+		return downloadErr
+	})
 	return reader, obj, err
 }
 
-// Delete removes an object using the primary provider
+// Delete removes an object using the primary provider, retrying on the fallback if the primary is unavailable
 func (s *StorageService) Delete(ctx context.Context, key string) error {
-	err := s.primary.Delete(ctx, key)
-	if err != nil && s.fallback != nil {
-		// Also try to delete from fallback
-		s.fallback.Delete(ctx, key)
+	if s.fallback != nil && s.primaryAvailable() {
+		// Objects written while the circuit was open only exist on the fallback
+		defer s.fallback.Delete(ctx, key)
 	}
-	return err
+	return s.withFallback(ctx, "delete", key, false, func(p StorageProvider) error {
+		return p.Delete(ctx, key)
+	})
 }
 
 // DeleteMany removes multiple objects
@@ -174,43 +196,80 @@ func (s *StorageService) DeleteMany(ctx context.Context, keys []string) error {
 
 // Exists checks if an object exists
 func (s *StorageService) Exists(ctx context.Context, key string) (bool, error) {
-	exists, err := s.primary.Exists(ctx, key)
-	if err != nil && s.fallback != nil {
-		return s.fallback.Exists(ctx, key)
-	}
+	var exists bool
+	err := s.withFallback(ctx, "exists", key, true, func(p StorageProvider) error {
+		var existsErr error
+		exists, existsErr = p.Exists(ctx, key)
+		return existsErr
+	})
 	return exists, err
 }
 
 // GetMetadata retrieves object metadata
 func (s *StorageService) GetMetadata(ctx context.Context, key string) (*StorageObject, error) {
-	obj, err := s.primary.GetMetadata(ctx, key)
-	if err != nil && s.fallback != nil {
-		return s.fallback.GetMetadata(ctx, key)
-	}
+	var obj *StorageObject
+	err := s.withFallback(ctx, "get_metadata", key, true, func(p StorageProvider) error {
+		var metaErr error
+		obj, metaErr = p.GetMetadata(ctx, key)
+		return metaErr
+	})
 	return obj, err
 }
 
 // List lists objects
 func (s *StorageService) List(ctx context.Context, opts *ListOptions) (*ListResult, error) {
-	result, err := s.primary.List(ctx, opts)
-	if err != nil && s.fallback != nil {
-		return s.fallback.List(ctx, opts)
+	prefix := ""
+	if opts != nil {
+		prefix = opts.Prefix
 	}
+
+	var result *ListResult
+	err := s.withFallback(ctx, "list", prefix, true, func(p StorageProvider) error {
+		var listErr error
+		result, listErr = p.List(ctx, opts)
+		return listErr
+	})
 	return result, err
 }
 
 // GetSignedUploadURL generates a pre-signed upload URL
 func (s *StorageService) GetSignedUploadURL(ctx context.Context, key string, opts *SignedURLOptions) (string, error) {
-	return s.primary.GetSignedUploadURL(ctx, key, opts)
+	var signedURL string
+	err := s.withFallback(ctx, "signed_upload_url", key, false, func(p StorageProvider) error {
+		var signErr error
+		signedURL, signErr = p.GetSignedUploadURL(ctx, key, opts)
+		return signErr
+	})
+	return signedURL, err
+}
+
+// GeneratePresignedUploadURL generates a presigned PUT URL for a direct client upload
+// and returns it with the public URL the object will have once uploaded.
+// This never falls back: the Supabase and local providers cannot enforce the signed
+// content type and size, so they return ErrPresignedUploadNotSupported.
+func (s *StorageService) GeneratePresignedUploadURL(key, contentType string, contentLength int64, expiry time.Duration) (uploadURL, publicURL string, err error) {
+	r2, ok := s.primary.(*R2Provider)
+	if !ok || !s.primaryAvailable() {
+		// While the circuit is open clients must upload through the API so the fallback is used
+		return "", "", ErrPresignedUploadNotSupported
+	}
+
+	uploadURL, err = r2.GeneratePresignedUploadURL(key, contentType, contentLength, expiry)
+	if err != nil {
+		return "", "", err
+	}
+	return uploadURL, r2.GetPublicURL(key), nil
 }
 
 // GetSignedDownloadURL generates a pre-signed download URL
 func (s *StorageService) GetSignedDownloadURL(ctx context.Context, key string, opts *SignedURLOptions) (string, error) {
-	url, err := s.primary.GetSignedDownloadURL(ctx, key, opts)
-	if err != nil && s.fallback != nil {
-		return s.fallback.GetSignedDownloadURL(ctx, key, opts)
-	}
-	return url, err
+	var signedURL string
+	err := s.withFallback(ctx, "signed_download_url", key, true, func(p StorageProvider) error {
+		var signErr error
+		signedURL, signErr = p.GetSignedDownloadURL(ctx, key, opts)
+		return signErr
+	})
+	return signedURL, err
 }
 
 // GetPublicURL returns the public URL for an object
@@ -218,6 +277,28 @@ func (s *StorageService) GetPublicURL(key string) string {
 	return s.primary.GetPublicURL(key)
 }
 
+// KeyFromURL returns the object key behind a public URL issued by the primary or
+// fallback provider. ok is false for URLs this service didn't issue, such as external
+// links or signed URLs.
+func (s *StorageService) KeyFromURL(url string) (key string, ok bool) {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	for _, p := range []StorageProvider{s.primary, s.fallback} {
+		if p == nil {
+			continue
+		}
+		prefix := p.GetPublicURL("")
+		if prefix == "" || strings.Contains(prefix, "?") {
+			continue // Provider only hands out signed URLs
+		}
+		if key := strings.TrimPrefix(url, prefix); key != url && key != "" {
+			return key, true
+		}
+	}
+	return "", false
+}
+
 // Copy copies an object within storage
 func (s *StorageService) Copy(ctx context.Context, sourceKey, destKey string) error {
 	return s.primary.Copy(ctx, sourceKey, destKey)
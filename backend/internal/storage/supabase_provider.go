@@ -7,7 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -80,7 +81,7 @@ func (s *SupabaseProvider) Upload(ctx context.Context, key string, data io.Reade
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, newStatusError("upload", resp.StatusCode, string(bodyBytes))
 	}
 
 	// Parse response
@@ -124,7 +125,7 @@ func (s *SupabaseProvider) Download(ctx context.Context, key string, opts *Downl
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
 		resp.Body.Close()
-		return nil, nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+		return nil, nil, newStatusError("download", resp.StatusCode, "")
 	}
 
 	obj := &StorageObject{
@@ -161,7 +162,7 @@ func (s *SupabaseProvider) Delete(ctx context.Context, key string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
-		return fmt.Errorf("delete failed with status %d", resp.StatusCode)
+		return newStatusError("delete", resp.StatusCode, "")
 	}
 
 	return nil
@@ -199,7 +200,7 @@ func (s *SupabaseProvider) DeleteMany(ctx context.Context, keys []string) error
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("batch delete failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return newStatusError("batch delete", resp.StatusCode, string(bodyBytes))
 	}
 
 	return nil
@@ -240,7 +241,7 @@ func (s *SupabaseProvider) GetMetadata(ctx context.Context, key string) (*Storag
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get metadata failed with status %d", resp.StatusCode)
+		return nil, newStatusError("get metadata", resp.StatusCode, "")
 	}
 
 	var info struct {
@@ -273,29 +274,120 @@ func (s *SupabaseProvider) GetMetadata(ctx context.Context, key string) (*Storag
 	return obj, nil
 }
 
-// List lists objects with optional prefix
+// supabaseListItem is an entry of a Supabase Storage folder listing. Folders have no
+// ID or metadata.
+type supabaseListItem struct {
+	Name      string  `json:"name"`
+	ID        *string `json:"id"`
+	UpdatedAt string  `json:"updated_at"`
+	Metadata  *struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimetype"`
+		ETag     string `json:"eTag"`
+	} `json:"metadata"`
+}
+
+// List lists objects with optional prefix. Supabase lists one folder at a time, so a
+// prefix up to a "/" names the folder and anything after it filters names within it.
+// Without a delimiter subfolders are listed too, as S3 does. StartKey is the offset
+// to continue from, as returned in NextStartKey.
 func (s *SupabaseProvider) List(ctx context.Context, opts *ListOptions) (*ListResult, error) {
-	listURL := fmt.Sprintf("%s/object/list/%s", s.storageURL, s.config.BucketName)
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	limit := opts.MaxKeys
+	if limit <= 0 {
+		limit = 100
+	}
+	offset := 0
+	if opts.StartKey != "" {
+		n, err := strconv.Atoi(opts.StartKey)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid list start key: %s", opts.StartKey)
+		}
+		offset = n
+	}
+
+	folder, search := opts.Prefix, ""
+	if i := strings.LastIndex(folder, "/"); i >= 0 {
+		folder, search = folder[:i], folder[i+1:]
+	} else {
+		folder, search = "", folder
+	}
+
+	items, err := s.listFolder(ctx, folder, search, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]StorageObject, 0, len(items))
+	for _, item := range items {
+		key := path.Join(folder, item.Name)
+		if item.ID == nil {
+			if opts.Delimiter == "" {
+				nested, err := s.listTree(ctx, key)
+				if err != nil {
+					return nil, err
+				}
+				objects = append(objects, nested...)
+			}
+			continue
+		}
+		objects = append(objects, item.object(key))
+	}
+
+	result := &ListResult{Objects: objects}
+	if len(items) == limit {
+		result.IsTruncated = true
+		result.NextStartKey = strconv.Itoa(offset + limit)
+	}
+	return result, nil
+}
 
-	// Build query parameters
-	params := url.Values{}
-	if opts != nil {
-		if opts.Prefix != "" {
-			params.Set("prefix", opts.Prefix)
+// listTree lists every object in folder and its subfolders
+func (s *SupabaseProvider) listTree(ctx context.Context, folder string) ([]StorageObject, error) {
+	const pageSize = 1000
+	var objects []StorageObject
+	for offset := 0; ; offset += pageSize {
+		items, err := s.listFolder(ctx, folder, "", pageSize, offset)
+		if err != nil {
+			return nil, err
 		}
-		if opts.MaxKeys > 0 {
-			params.Set("limit", fmt.Sprintf("%d", opts.MaxKeys))
+		for _, item := range items {
+			key := path.Join(folder, item.Name)
+			if item.ID == nil {
+				nested, err := s.listTree(ctx, key)
+				if err != nil {
+					return nil, err
+				}
+				objects = append(objects, nested...)
+				continue
+			}
+			objects = append(objects, item.object(key))
 		}
-		if opts.StartKey != "" {
-			params.Set("offset", opts.StartKey)
+		if len(items) < pageSize {
+			return objects, nil
 		}
 	}
+}
 
-	if len(params) > 0 {
-		listURL += "?" + params.Encode()
+// listFolder fetches one page of a folder's entries. The Storage API takes the
+// folder and paging in the request body, not the query string.
+func (s *SupabaseProvider) listFolder(ctx context.Context, folder, search string, limit, offset int) ([]supabaseListItem, error) {
+	listURL := fmt.Sprintf("%s/object/list/%s", s.storageURL, s.config.BucketName)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"prefix": folder,
+		"search": search,
+		"limit":  limit,
+		"offset": offset,
+		"sortBy": map[string]string{"column": "name", "order": "asc"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", listURL, bytes.NewReader([]byte("{}")))
+	req, err := http.NewRequestWithContext(ctx, "POST", listURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -311,41 +403,30 @@ func (s *SupabaseProvider) List(ctx context.Context, opts *ListOptions) (*ListRe
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("list failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var items []struct {
-		Name         string            `json:"name"`
-		Size         int64             `json:"size,omitempty"`
-		ContentType  string            `json:"content_type,omitempty"`
-		LastModified string            `json:"updated_at,omitempty"`
-		Metadata     map[string]string `json:"metadata,omitempty"`
+		return nil, newStatusError("list", resp.StatusCode, string(bodyBytes))
 	}
 
+	var items []supabaseListItem
 	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
 		return nil, fmt.Errorf("failed to parse list response: %w", err)
 	}
+	return items, nil
+}
 
-	objects := make([]StorageObject, 0, len(items))
-	for _, item := range items {
-		obj := StorageObject{
-			Key:         item.Name,
-			ContentType: item.ContentType,
-			Size:        item.Size,
-			Metadata:    item.Metadata,
-		}
-		if item.LastModified != "" {
-			if t, err := time.Parse(time.RFC3339, item.LastModified); err == nil {
-				obj.LastModified = t
-			}
+// object converts a listed file to a StorageObject stored under key
+func (item supabaseListItem) object(key string) StorageObject {
+	obj := StorageObject{Key: key}
+	if item.Metadata != nil {
+		obj.Size = item.Metadata.Size
+		obj.ContentType = item.Metadata.MimeType
+		obj.ETag = item.Metadata.ETag
+	}
+	if item.UpdatedAt != "" {
+		if t, err := time.Parse(time.RFC3339, item.UpdatedAt); err == nil {
+			obj.LastModified = t
 		}
-		objects = append(objects, obj)
 	}
-
-	return &ListResult{
-		Objects:     objects,
-		IsTruncated: opts != nil && opts.MaxKeys > 0 && len(objects) >= opts.MaxKeys,
-	}, nil
+	return obj
 }
 
 // GetSignedUploadURL generates a pre-signed URL for uploads
@@ -432,7 +513,7 @@ func (s *SupabaseProvider) Copy(ctx context.Context, sourceKey, destKey string)
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("copy failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return newStatusError("copy", resp.StatusCode, string(bodyBytes))
 	}
 
 	return nil
@@ -457,7 +538,7 @@ func (s *SupabaseProvider) HealthCheck(ctx context.Context) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+		return newStatusError("health check", resp.StatusCode, "")
 	}
 
 	return nil
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// listRequest is the body the Storage API expects for a folder listing
+type listRequest struct {
+	Prefix string `json:"prefix"`
+	Search string `json:"search"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+func newListServer(t *testing.T, folders map[string]string) (*SupabaseProvider, *[]listRequest) {
+	t.Helper()
+	var requests []listRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/storage/v1/object/list/media" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.URL.RawQuery != "" {
+			t.Errorf("list options belong in the body, got query %q", r.URL.RawQuery)
+		}
+		var body listRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode list body: %v", err)
+		}
+		requests = append(requests, body)
+		w.Write([]byte(folders[body.Prefix]))
+	}))
+	t.Cleanup(server.Close)
+
+	provider := NewSupabaseProvider(SupabaseConfig{ProjectURL: server.URL, ServiceKey: "key", BucketName: "media"})
+	return provider, &requests
+}
+
+func TestSupabaseListReadsMetadataSizeAndRecurses(t *testing.T) {
+	provider, requests := newListServer(t, map[string]string{
+		"posts/u1": `[
+			{"name": "a.jpg", "id": "1", "metadata": {"size": 100, "mimetype": "image/jpeg"}},
+			{"name": "variants", "id": null, "metadata": null}
+		]`,
+		"posts/u1/variants": `[{"name": "a_320.webp", "id": "2", "metadata": {"size": 30}}]`,
+	})
+
+	result, err := provider.List(context.Background(), &ListOptions{Prefix: "posts/u1/", MaxKeys: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	want := map[string]int64{"posts/u1/a.jpg": 100, "posts/u1/variants/a_320.webp": 30}
+	if len(result.Objects) != len(want) {
+		t.Fatalf("got %d objects, want %d: %+v", len(result.Objects), len(want), result.Objects)
+	}
+	for _, obj := range result.Objects {
+		if want[obj.Key] != obj.Size {
+			t.Errorf("%s: size %d, want %d", obj.Key, obj.Size, want[obj.Key])
+		}
+	}
+	if result.IsTruncated {
+		t.Error("a short page should not be truncated")
+	}
+	if first := (*requests)[0]; first.Prefix != "posts/u1" || first.Search != "" || first.Limit != 10 {
+		t.Errorf("first request = %+v", first)
+	}
+}
+
+func TestSupabaseListDelimiterSkipsFolders(t *testing.T) {
+	provider, requests := newListServer(t, map[string]string{
+		"posts": `[{"name": "u1", "id": null}, {"name": "top.jpg", "id": "1", "metadata": {"size": 5}}]`,
+	})
+
+	result, err := provider.List(context.Background(), &ListOptions{Prefix: "posts/", Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Key != "posts/top.jpg" {
+		t.Errorf("objects = %+v", result.Objects)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("listed %d folders, want 1", len(*requests))
+	}
+}
+
+func TestSupabaseListPagination(t *testing.T) {
+	provider, requests := newListServer(t, map[string]string{
+		"posts": `[{"name": "a", "id": "1", "metadata": {"size": 1}}, {"name": "b", "id": "2", "metadata": {"size": 2}}]`,
+	})
+
+	result, err := provider.List(context.Background(), &ListOptions{Prefix: "posts/ab", MaxKeys: 2, StartKey: "4"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !result.IsTruncated || result.NextStartKey != "6" {
+		t.Errorf("IsTruncated = %v, NextStartKey = %q; want true, \"6\"", result.IsTruncated, result.NextStartKey)
+	}
+	if got := (*requests)[0]; got.Prefix != "posts" || got.Search != "ab" || got.Offset != 4 {
+		t.Errorf("request = %+v", got)
+	}
+
+	if _, err := provider.List(context.Background(), &ListOptions{StartKey: "next"}); err == nil {
+		t.Error("a start key that isn't an offset should be rejected")
+	}
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is one failed rule on a request field, keyed by its JSON name
+// (nested fields use dots, e.g. "poll.options")
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+var registerFieldNamesOnce sync.Once
+
+// jsonFieldName reports struct fields by their json tag so errors match the request body
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// registerFieldNames makes gin's validator (and ValidateStruct's) name fields by JSON key
+func registerFieldNames() {
+	registerFieldNamesOnce.Do(func() {
+		if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			engine.RegisterTagNameFunc(jsonFieldName)
+		}
+		validate.RegisterTagNameFunc(jsonFieldName)
+	})
+}
+
+// BindJSON binds and validates the JSON body into obj. On failure it writes a 400 with
+// per-field errors and returns false, so handlers can simply return.
+func BindJSON(c *gin.Context, obj interface{}) bool {
+	registerFieldNames()
+
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	fields := FieldErrors(err)
+	message := "Invalid request body"
+	if len(fields) > 0 {
+		message = fields[0].Message
+		if len(fields) > 1 {
+			message = fmt.Sprintf("%s (and %d more)", message, len(fields)-1)
+		}
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"success": false,
+		"error":   "Validation failed",
+		"message": message,
+		"fields":  fields,
+	})
+	return false
+}
+
+// FieldErrors translates binding/validation errors into per-field errors. Malformed JSON
+// that can't be attributed to a field yields an empty slice.
+func FieldErrors(err error) []FieldError {
+	fields := []FieldError{}
+
+	var validationErrors validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrors):
+		for _, fieldErr := range validationErrors {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fieldErr),
+				Rule:    fieldErr.Tag(),
+				Param:   fieldErr.Param(),
+				Message: fieldErrorMessage(fieldErr),
+			})
+		}
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		fields = append(fields, FieldError{
+			Field:   field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("%s must be of type %s", field, jsonTypeName(typeErr.Type)),
+		})
+	case errors.Is(err, io.EOF):
+		fields = append(fields, FieldError{
+			Field:   "body",
+			Rule:    "required",
+			Message: "Request body is required",
+		})
+	}
+
+	return fields
+}
+
+// fieldPath returns the JSON path of a field without the top-level struct name
+func fieldPath(err validator.FieldError) string {
+	namespace := err.Namespace()
+	if idx := strings.Index(namespace, "."); idx >= 0 {
+		return namespace[idx+1:]
+	}
+	return err.Field()
+}
+
+// fieldErrorMessage builds a human message for a failed rule. Length rules read as
+// characters for strings, items for lists and plain values for numbers.
+func fieldErrorMessage(err validator.FieldError) string {
+	field := fieldPath(err)
+	param := err.Param()
+
+	unit := ""
+	switch err.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "min":
+		if unit == "" {
+			return fmt.Sprintf("%s must be at least %s", field, param)
+		}
+		return fmt.Sprintf("%s must have at least %s%s", field, param, unit)
+	case "max":
+		if unit == "" {
+			return fmt.Sprintf("%s must be at most %s", field, param)
+		}
+		return fmt.Sprintf("%s must have at most %s%s", field, param, unit)
+	case "len":
+		return fmt.Sprintf("%s must have exactly %s%s", field, param, unit)
+	case "gt", "gte", "lt", "lte":
+		comparisons := map[string]string{"gt": "greater than", "gte": "at least", "lt": "less than", "lte": "at most"}
+		return fmt.Sprintf("%s must be %s %s", field, comparisons[err.Tag()], param)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(param, " ", ", "))
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", field)
+	case "uuid":
+		return fmt.Sprintf("%s must be a valid UUID", field)
+	default:
+		return fmt.Sprintf("%s failed the %s rule", field, err.Tag())
+	}
+}
+
+// jsonTypeName names a Go type the way a JSON client thinks of it
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reviewRequest struct {
+	Content string `json:"content" binding:"required"`
+	Rating  int    `json:"rating" binding:"min=1,max=5"`
+	Poll    struct {
+		Options []string `json:"options" binding:"omitempty,min=2"`
+	} `json:"poll"`
+}
+
+// bindResponse posts body to a handler that binds a reviewRequest and returns the
+// status and decoded error envelope
+func bindResponse(t *testing.T, body string) (int, []FieldError) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/reviews", func(c *gin.Context) {
+		var req reviewRequest
+		if !BindJSON(c, &req) {
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reviews", strings.NewReader(body)))
+	if w.Code == http.StatusCreated {
+		return w.Code, nil
+	}
+
+	var envelope struct {
+		Success bool         `json:"success"`
+		Error   string       `json:"error"`
+		Fields  []FieldError `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+	if envelope.Success || envelope.Error != "Validation failed" {
+		t.Errorf("envelope = %+v, want the validation failure envelope", envelope)
+	}
+	return w.Code, envelope.Fields
+}
+
+func TestBindJSONFieldErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []FieldError // nil when the body is valid
+	}{
+		{"valid", `{"content": "great", "rating": 5}`, nil},
+		{"missing required field", `{"rating": 3}`, []FieldError{
+			{Field: "content", Rule: "required", Message: "content is required"},
+		}},
+		{"out of range", `{"content": "great", "rating": 9}`, []FieldError{
+			{Field: "rating", Rule: "max", Param: "5", Message: "rating must be at most 5"},
+		}},
+		{"nested list too short", `{"content": "great", "rating": 4, "poll": {"options": ["a"]}}`, []FieldError{
+			{Field: "poll.options", Rule: "min", Param: "2", Message: "poll.options must have at least 2 items"},
+		}},
+		{"wrong type", `{"content": "great", "rating": "five"}`, []FieldError{
+			{Field: "rating", Rule: "type", Param: "int", Message: "rating must be of type number"},
+		}},
+		{"empty body", ``, []FieldError{
+			{Field: "body", Rule: "required", Message: "Request body is required"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, fields := bindResponse(t, tt.body)
+			if tt.want == nil {
+				if status != http.StatusCreated {
+					t.Fatalf("status = %d, fields %+v; want the body accepted", status, fields)
+				}
+				return
+			}
+			if status != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", status)
+			}
+			if len(fields) != len(tt.want) {
+				t.Fatalf("fields = %+v, want %+v", fields, tt.want)
+			}
+			for i := range fields {
+				if fields[i] != tt.want[i] {
+					t.Errorf("fields[%d] = %+v, want %+v", i, fields[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	apperr "histeeria-backend/pkg/errors"
+)
+
+// Upload categories, each with its own allowlist of sniffed content types
+const (
+	UploadCategoryImage    = "image"
+	UploadCategoryAudio    = "audio"
+	UploadCategoryVideo    = "video"
+	UploadCategoryDocument = "document"
+)
+
+// Default allowlists used when none are configured
+const (
+	DefaultUploadImageTypes    = "image/jpeg,image/png,image/gif,image/webp"
+	DefaultUploadAudioTypes    = "audio/webm,audio/ogg,audio/mpeg,audio/mp4,audio/aac,audio/wave"
+	DefaultUploadVideoTypes    = "video/mp4,video/webm,video/quicktime,video/ogg"
+	DefaultUploadDocumentTypes = "application/pdf,application/msword,application/zip,text/plain"
+)
+
+// sniffLen is the number of leading bytes inspected, matching http.DetectContentType
+const sniffLen = 512
+
+// SniffContentType detects a file's content type from its leading bytes, ignoring
+// whatever the client declared. It extends http.DetectContentType with containers it
+// doesn't recognise (QuickTime/M4A, AAC, frame-synced MP3, legacy Office documents).
+// Parameters such as charset are dropped.
+func SniffContentType(data []byte) string {
+	head := data
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+
+	// ISO base media (MP4 family): "ftyp" box at offset 4, brand decides the type
+	if len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) {
+		switch brand := string(head[8:12]); {
+		case brand == "qt  ":
+			return "video/quicktime"
+		case strings.HasPrefix(brand, "M4A"):
+			return "audio/mp4"
+		case brand == "heic" || brand == "heix" || brand == "mif1" || brand == "msf1":
+			return "image/heic"
+		default:
+			return "video/mp4"
+		}
+	}
+
+	// Legacy Office (OLE compound document)
+	if bytes.HasPrefix(head, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}) {
+		return "application/msword"
+	}
+
+	detected := http.DetectContentType(head)
+	if idx := strings.Index(detected, ";"); idx >= 0 {
+		detected = strings.TrimSpace(detected[:idx])
+	}
+
+	// MPEG audio frame sync without an ID3 tag: ADTS AAC has layer bits 00, MP3 doesn't
+	if detected == "application/octet-stream" && len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0 {
+		if head[1]&0x06 == 0 {
+			return "audio/aac"
+		}
+		return "audio/mpeg"
+	}
+
+	return detected
+}
+
+// sniffForCategory maps container types that carry both audio and video onto the
+// category being uploaded (a WebM voice note sniffs as video/webm)
+func sniffForCategory(sniffed, category string) string {
+	switch category {
+	case UploadCategoryAudio:
+		switch sniffed {
+		case "video/webm":
+			return "audio/webm"
+		case "video/mp4":
+			return "audio/mp4"
+		case "application/ogg":
+			return "audio/ogg"
+		}
+	case UploadCategoryVideo:
+		if sniffed == "application/ogg" {
+			return "video/ogg"
+		}
+	}
+	return sniffed
+}
+
+// parseTypeList splits a comma-separated content type list into a set
+func parseTypeList(list string) map[string]bool {
+	types := make(map[string]bool)
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types[t] = true
+		}
+	}
+	return types
+}
+
+// allowedUploadTypes returns the configured allowlist for a category
+func (s *StorageService) allowedUploadTypes(category string) string {
+	var configured, fallback string
+	switch category {
+	case UploadCategoryImage:
+		fallback = DefaultUploadImageTypes
+		if s != nil && s.config != nil {
+			configured = s.config.UploadImageTypes
+		}
+	case UploadCategoryAudio:
+		fallback = DefaultUploadAudioTypes
+		if s != nil && s.config != nil {
+			configured = s.config.UploadAudioTypes
+		}
+	case UploadCategoryVideo:
+		fallback = DefaultUploadVideoTypes
+		if s != nil && s.config != nil {
+			configured = s.config.UploadVideoTypes
+		}
+	case UploadCategoryDocument:
+		fallback = DefaultUploadDocumentTypes
+		if s != nil && s.config != nil {
+			configured = s.config.UploadDocumentTypes
+		}
+	}
+	if configured == "" {
+		return fallback
+	}
+	return configured
+}
+
+// ValidateUploadContent sniffs data and checks it against the allowlist for category.
+// Returns the content type to store the file with, or a 415 AppError on a mismatch.
+// OOXML documents (docx/xlsx/pptx) are zip containers, so a specific declared OOXML
+// type is kept when the data sniffs as a zip.
+func (s *StorageService) ValidateUploadContent(data []byte, declared, category string) (string, error) {
+	sniffed := sniffForCategory(SniffContentType(data), category)
+	allowed := s.allowedUploadTypes(category)
+
+	if !parseTypeList(allowed)[sniffed] {
+		return "", apperr.NewAppError(http.StatusUnsupportedMediaType, "Unsupported file type",
+			fmt.Sprintf("file content is %s (declared %q); allowed %s types: %s", sniffed, declared, category, allowed))
+	}
+
+	if sniffed == "application/zip" && strings.HasPrefix(declared, "application/vnd.openxmlformats-officedocument.") {
+		return declared, nil
+	}
+	return sniffed, nil
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"errors"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// currentUserKey is the only gin context key the authenticated user is stored under
+const currentUserKey = "current_user"
+
+// ErrNoCurrentUser is returned when a request carries no authenticated user
+var ErrNoCurrentUser = errors.New("no authenticated user in request context")
+
+// AuthUser is the authenticated caller, decoded once from the access token by the auth
+// middleware
+type AuthUser struct {
+	ID        uuid.UUID
+	Email     string
+	Username  string
+	Role      string
+	SessionID string
+}
+
+// SetCurrentUser stores the user described by validated token claims on the request.
+// Claims with a malformed user ID are rejected rather than stored.
+func SetCurrentUser(c *gin.Context, claims *models.JWTClaims) error {
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = models.RoleUser // Tokens issued before roles were added
+	}
+
+	c.Set(currentUserKey, &AuthUser{
+		ID:        id,
+		Email:     claims.Email,
+		Username:  claims.Username,
+		Role:      role,
+		SessionID: claims.SessionID,
+	})
+	return nil
+}
+
+// CurrentUser returns the authenticated user, or ErrNoCurrentUser when the request is
+// anonymous
+func CurrentUser(c *gin.Context) (*AuthUser, error) {
+	value, exists := c.Get(currentUserKey)
+	if !exists {
+		return nil, ErrNoCurrentUser
+	}
+	user, ok := value.(*AuthUser)
+	if !ok || user == nil {
+		return nil, ErrNoCurrentUser
+	}
+	return user, nil
+}
+
+// CurrentUserID returns the authenticated user's ID; ok is false for anonymous requests,
+// which lets optional-auth handlers fall back to uuid.Nil
+func CurrentUserID(c *gin.Context) (uuid.UUID, bool) {
+	user, err := CurrentUser(c)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return user.ID, true
+}
+
+// MustUserID returns the authenticated user's ID for handlers behind JWTAuthMiddleware.
+// It panics if there is none, since that means the route was registered without auth.
+func MustUserID(c *gin.Context) uuid.UUID {
+	user, err := CurrentUser(c)
+	if err != nil {
+		panic("utils.MustUserID: " + err.Error() + " (route is missing auth middleware)")
+	}
+	return user.ID
+}
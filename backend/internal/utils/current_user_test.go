@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newTestContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+func TestSetCurrentUserRejectsMalformedID(t *testing.T) {
+	c := newTestContext()
+
+	if err := SetCurrentUser(c, &models.JWTClaims{UserID: "not-a-uuid"}); err == nil {
+		t.Fatal("a malformed user ID should be rejected")
+	}
+	if _, err := CurrentUser(c); err != ErrNoCurrentUser {
+		t.Errorf("err = %v, want ErrNoCurrentUser; nothing should have been stored", err)
+	}
+}
+
+func TestSetCurrentUserDefaultsRole(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		role string
+		want string
+	}{
+		{"", models.RoleUser}, // Tokens issued before roles were added
+		{"admin", "admin"},    // Set roles are kept
+	}
+
+	for _, tt := range tests {
+		c := newTestContext()
+		claims := &models.JWTClaims{UserID: id.String(), Email: "a@example.com", Username: "a", Role: tt.role, SessionID: "s"}
+		if err := SetCurrentUser(c, claims); err != nil {
+			t.Fatalf("SetCurrentUser: %v", err)
+		}
+		user, err := CurrentUser(c)
+		if err != nil {
+			t.Fatalf("CurrentUser: %v", err)
+		}
+		if user.ID != id || user.Role != tt.want || user.SessionID != "s" {
+			t.Errorf("role %q: user = %+v, want role %q", tt.role, user, tt.want)
+		}
+	}
+}
+
+func TestCurrentUserIDWhenAnonymous(t *testing.T) {
+	id, ok := CurrentUserID(newTestContext())
+	if ok || id != uuid.Nil {
+		t.Errorf("CurrentUserID = %s, %v; want uuid.Nil, false", id, ok)
+	}
+}
+
+func TestMustUserIDPanicsWhenAnonymous(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustUserID should panic without an authenticated user")
+		}
+	}()
+	MustUserID(newTestContext())
+}
@@ -0,0 +1,334 @@
+package utils
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Transactional email templates, one file each under email_templates/. A template
+// defines "subject", "content" (the HTML inside the shared layout) and "text" (the
+// plain-text part), and may override "footer_note". Its wording lives in
+// email_templates/locales/<lang>.json, looked up with {{t "key" args...}}.
+const (
+	EmailVerification       = "verification"
+	EmailPasswordReset      = "password_reset"
+	EmailMagicLink          = "magic_link"
+	EmailWelcome            = "welcome"
+	EmailPasswordChanged    = "password_changed"
+	EmailAccountDeleted     = "account_deleted"
+	EmailChangeVerification = "email_change_verification"
+	EmailUsernameChanged    = "username_changed"
+	EmailChangeNotice       = "email_change_notice"
+	EmailNotification       = "notification"
+	EmailDigest             = "digest"
+)
+
+const (
+	defaultEmailLanguage = "en"
+	emailTemplateDir     = "email_templates"
+	emailLayoutHTML      = "layout.html"
+	emailLayoutText      = "layout.txt"
+)
+
+//go:embed email_templates
+var emailTemplateFS embed.FS
+
+// RenderedEmail is a template rendered for one recipient
+type RenderedEmail struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+type emailTemplateSet struct {
+	templates map[string]*emailTemplate
+	catalogs  map[string]map[string]string // language -> key -> text
+}
+
+// The templates are embedded, so a broken one fails at startup rather than on send
+var emailTemplates = mustLoadEmailTemplates()
+
+func mustLoadEmailTemplates() *emailTemplateSet {
+	set := &emailTemplateSet{
+		templates: make(map[string]*emailTemplate),
+		catalogs:  make(map[string]map[string]string),
+	}
+
+	locales, err := fs.Glob(emailTemplateFS, emailTemplateDir+"/locales/*.json")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range locales {
+		raw, err := emailTemplateFS.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(raw, &catalog); err != nil {
+			panic(fmt.Sprintf("email locale %s: %v", file, err))
+		}
+		set.catalogs[strings.TrimSuffix(path.Base(file), ".json")] = catalog
+	}
+	if set.catalogs[defaultEmailLanguage] == nil {
+		panic("email locale " + defaultEmailLanguage + " is missing")
+	}
+
+	files, err := fs.Glob(emailTemplateFS, emailTemplateDir+"/*.html")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		if name == "layout" || name == "partials" {
+			continue
+		}
+		funcs := set.funcs(defaultEmailLanguage)
+		html := htmltemplate.Must(htmltemplate.New(emailLayoutHTML).Funcs(htmltemplate.FuncMap(funcs.html)).ParseFS(emailTemplateFS,
+			emailTemplateDir+"/"+emailLayoutHTML, emailTemplateDir+"/partials.html", file))
+		text := texttemplate.Must(texttemplate.New(emailLayoutText).Funcs(texttemplate.FuncMap(funcs.text)).ParseFS(emailTemplateFS,
+			emailTemplateDir+"/"+emailLayoutText, file))
+		set.templates[name] = &emailTemplate{html: html, text: text}
+	}
+	return set
+}
+
+// EmailTemplateNames lists the transactional email templates
+func EmailTemplateNames() []string {
+	names := make([]string, 0, len(emailTemplates.templates))
+	for name := range emailTemplates.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EmailLanguages lists the languages emails are translated into
+func EmailLanguages() []string {
+	langs := make([]string, 0, len(emailTemplates.catalogs))
+	for lang := range emailTemplates.catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// language picks the catalog for lang, trying its base language ("pt" for "pt-BR")
+// before falling back to ""
+func (s *emailTemplateSet) language(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if _, ok := s.catalogs[lang]; ok {
+		return lang
+	}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		if _, ok := s.catalogs[lang[:i]]; ok {
+			return lang[:i]
+		}
+	}
+	return ""
+}
+
+// translate looks key up in lang, then in English, then gives the key itself
+func (s *emailTemplateSet) translate(lang, key string) string {
+	if text, ok := s.catalogs[lang][key]; ok {
+		return text
+	}
+	if text, ok := s.catalogs[defaultEmailLanguage][key]; ok {
+		return text
+	}
+	return key
+}
+
+var emailTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+type emailFuncs struct {
+	html map[string]interface{}
+	text map[string]interface{}
+}
+
+// funcs are the template functions for lang. Translations may hold markup, which the
+// HTML part keeps (escaping the arguments) and the text part strips.
+func (s *emailTemplateSet) funcs(lang string) emailFuncs {
+	format := func(text string, args []interface{}) string {
+		if len(args) == 0 {
+			return text
+		}
+		return fmt.Sprintf(text, args...)
+	}
+	date := func(t time.Time) string {
+		return t.Format(s.translate(lang, "common.date_format"))
+	}
+	dict := func(pairs ...interface{}) (map[string]interface{}, error) {
+		if len(pairs)%2 != 0 {
+			return nil, fmt.Errorf("dict needs key/value pairs")
+		}
+		m := make(map[string]interface{}, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			key, ok := pairs[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+			}
+			m[key] = pairs[i+1]
+		}
+		return m, nil
+	}
+
+	return emailFuncs{
+		html: map[string]interface{}{
+			"t": func(key string, args ...interface{}) htmltemplate.HTML {
+				escaped := make([]interface{}, len(args))
+				for i, arg := range args {
+					switch v := arg.(type) {
+					case htmltemplate.HTML:
+						escaped[i] = v
+					case string:
+						escaped[i] = htmltemplate.HTMLEscapeString(v)
+					default:
+						escaped[i] = arg
+					}
+				}
+				return htmltemplate.HTML(format(s.translate(lang, key), escaped))
+			},
+			"date": date,
+			"dict": dict,
+		},
+		text: map[string]interface{}{
+			"t": func(key string, args ...interface{}) string {
+				return format(emailTagPattern.ReplaceAllString(s.translate(lang, key), ""), args)
+			},
+			"date": date,
+			"dict": dict,
+		},
+	}
+}
+
+var emailBlankLines = regexp.MustCompile(`\n\s*\n(\s*\n)+`)
+
+// RenderEmail renders the named template in lang (the configured language if it has
+// no translation). data holds the template's own values; Lang, Year and FrontendURL
+// are added.
+func (e *EmailService) RenderEmail(name, lang string, data map[string]interface{}) (*RenderedEmail, error) {
+	tmpl, ok := emailTemplates.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	resolved := emailTemplates.language(lang)
+	if resolved == "" {
+		resolved = e.Language()
+	}
+
+	values := map[string]interface{}{
+		"Lang":           resolved,
+		"Year":           time.Now().Year(),
+		"FrontendURL":    e.frontendURL(),
+		"PreferencesURL": "",
+	}
+	for k, v := range data {
+		values[k] = v
+	}
+
+	funcs := emailTemplates.funcs(resolved)
+	html, err := tmpl.html.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone email template %s: %w", name, err)
+	}
+	text, err := tmpl.text.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone email template %s: %w", name, err)
+	}
+	html.Funcs(htmltemplate.FuncMap(funcs.html))
+	text.Funcs(texttemplate.FuncMap(funcs.text))
+
+	var subject, htmlBody, textBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", values); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := html.ExecuteTemplate(&htmlBody, emailLayoutHTML, values); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	if err := text.ExecuteTemplate(&textBody, emailLayoutText, values); err != nil {
+		return nil, fmt.Errorf("failed to render text of %s: %w", name, err)
+	}
+
+	return &RenderedEmail{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    htmlBody.String(),
+		Text:    strings.TrimSpace(emailBlankLines.ReplaceAllString(textBody.String(), "\n\n")),
+	}, nil
+}
+
+// EmailPreviewData is sample data for each template, for looking at a template
+// without sending it
+func EmailPreviewData(name string) map[string]interface{} {
+	now := time.Now()
+	switch name {
+	case EmailVerification, EmailChangeVerification:
+		return map[string]interface{}{"Code": "482913"}
+	case EmailPasswordReset:
+		return map[string]interface{}{"URL": "https://histeeria.app/auth/reset-password?token=preview"}
+	case EmailMagicLink:
+		return map[string]interface{}{
+			"URL":           "https://histeeria.app/auth/magic-link?token=preview",
+			"ExpiryMinutes": int(MagicLinkTTL / time.Minute),
+		}
+	case EmailWelcome:
+		return map[string]interface{}{"DisplayName": "Ada Lovelace", "Features": welcomeFeatures}
+	case EmailPasswordChanged, EmailAccountDeleted:
+		return map[string]interface{}{"DisplayName": "Ada Lovelace", "Time": now}
+	case EmailUsernameChanged:
+		return map[string]interface{}{"DisplayName": "Ada Lovelace", "OldUsername": "ada", "NewUsername": "ada.lovelace"}
+	case EmailChangeNotice:
+		return map[string]interface{}{
+			"DisplayName": "Ada Lovelace",
+			"OldEmail":    "ada@example.com",
+			"NewEmail":    "ada.lovelace@example.com",
+			"Time":        now,
+		}
+	case EmailNotification:
+		return map[string]interface{}{
+			"Kind":           "connection_request",
+			"ActorName":      "Charles Babbage",
+			"URL":            "https://histeeria.app/notifications",
+			"PreferencesURL": "https://histeeria.app/settings",
+		}
+	case EmailDigest:
+		return map[string]interface{}{
+			"Period": "weekly",
+			"Notifications": []EmailDigestItem{
+				{Title: "Charles Babbage started following you"},
+				{Title: "New comment on your post", Message: "Fascinating work on the engine notes!"},
+			},
+			"PreferencesURL": "https://histeeria.app/settings",
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// EmailDigestItem is one notification listed in a digest email
+type EmailDigestItem struct {
+	Title   string
+	Message string
+}
+
+// The welcome email's feature list, as catalog keys
+var welcomeFeatures = []string{
+	"welcome.feature_network",
+	"welcome.feature_portfolio",
+	"welcome.feature_opportunities",
+	"welcome.feature_resources",
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"histeeria-backend/internal/config"
+)
+
+func TestGenerateVerificationCodeMatchesConfiguredFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  config.EmailConfig
+		length  int
+		charset string
+	}{
+		{"default", config.EmailConfig{}, 6, numericCodeChars},
+		{"numeric", config.EmailConfig{VerificationCodeLength: 8, VerificationCodeCharset: CodeCharsetNumeric}, 8, numericCodeChars},
+		{"alphanumeric", config.EmailConfig{VerificationCodeLength: 10, VerificationCodeCharset: CodeCharsetAlphanumeric}, 10, alphanumericCodeChars},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewEmailService(&tt.config)
+			for i := 0; i < 200; i++ {
+				code := svc.GenerateVerificationCode()
+				if len(code) != tt.length || strings.Trim(code, tt.charset) != "" {
+					t.Fatalf("code %q isn't %d characters from %q", code, tt.length, tt.charset)
+				}
+				if !svc.IsValidVerificationCode(code) {
+					t.Fatalf("generated code %q doesn't validate", code)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeVerificationCode(t *testing.T) {
+	alpha := NewEmailService(&config.EmailConfig{VerificationCodeCharset: CodeCharsetAlphanumeric})
+	if got := alpha.NormalizeVerificationCode(" ab3k7m "); got != "AB3K7M" {
+		t.Errorf("alphanumeric code normalized to %q, want AB3K7M", got)
+	}
+	numeric := NewEmailService(&config.EmailConfig{})
+	if got := numeric.NormalizeVerificationCode(" 123456 "); got != "123456" {
+		t.Errorf("numeric code normalized to %q, want 123456", got)
+	}
+}
+
+func TestGenerateSecureCodeDrawsFromCryptoRand(t *testing.T) {
+	if codeRandom != rand.Reader {
+		t.Fatal("codes should be drawn from crypto/rand")
+	}
+
+	// 250 and up would bias a 10 character set and are skipped
+	defer func() { codeRandom = rand.Reader }()
+	codeRandom = bytes.NewReader([]byte{0, 255, 13, 250, 7, 99, 0, 0, 0, 0, 0, 0})
+	if got := GenerateSecureCode(4, numericCodeChars); got != "0379" {
+		t.Errorf("code = %q, want 0379 from the reader's unbiased bytes", got)
+	}
+
+	codeRandom = rand.Reader
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code := GenerateSecureCode(12, alphanumericCodeChars)
+		if seen[code] {
+			t.Fatalf("code %q came up twice", code)
+		}
+		seen[code] = true
+	}
+}
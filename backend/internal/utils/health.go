@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -22,11 +23,20 @@ type HealthChecker struct {
 	mu        sync.RWMutex
 }
 
+// ErrDegraded can be wrapped by a check's error to report the component as degraded
+// (still serving, e.g. on a fallback) rather than down
+var ErrDegraded = errors.New("degraded")
+
 // HealthCheck represents a single health check
 type HealthCheck struct {
 	Name    string
 	Check   func(ctx context.Context) error
+	Details func() interface{} // Optional component state included in the result
 	Timeout time.Duration
+
+	// NonCritical checks report a failure as degraded rather than down, so the
+	// service stays ready without the component (email, say)
+	NonCritical bool
 }
 
 // HealthStatus represents the overall health status
@@ -42,9 +52,10 @@ type HealthStatus struct {
 
 // CheckResult represents the result of a single health check
 type CheckResult struct {
-	Status  string `json:"status"` // up, down
-	Latency string `json:"latency,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Status  string      `json:"status"` // up, degraded, down
+	Latency string      `json:"latency,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
 }
 
 // RuntimeStats contains runtime statistics
@@ -124,19 +135,24 @@ func (hc *HealthChecker) Check(ctx context.Context, includeStats bool) *HealthSt
 			mu.Lock()
 			defer mu.Unlock()
 
+			result := CheckResult{
+				Status:  "up",
+				Latency: latency.String(),
+			}
 			if err != nil {
-				results[c.Name] = CheckResult{
-					Status:  "down",
-					Latency: latency.String(),
-					Error:   err.Error(),
-				}
-				anyDown = true
-			} else {
-				results[c.Name] = CheckResult{
-					Status:  "up",
-					Latency: latency.String(),
+				result.Error = err.Error()
+				if c.NonCritical || errors.Is(err, ErrDegraded) {
+					result.Status = "degraded"
+					allHealthy = false
+				} else {
+					result.Status = "down"
+					anyDown = true
 				}
 			}
+			if c.Details != nil {
+				result.Details = c.Details()
+			}
+			results[c.Name] = result
 		}(check)
 	}
 
@@ -245,31 +261,29 @@ func (hc *HealthChecker) LivenessHandler() gin.HandlerFunc {
 	}
 }
 
-// MetricsHandler returns a Gin handler for metrics (Prometheus-compatible)
-func (hc *HealthChecker) MetricsHandler() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		stats := hc.getRuntimeStats()
-		status := hc.Check(c.Request.Context(), false)
-
-		// Return metrics in Prometheus format
-		var sb strings.Builder
-
-		sb.WriteString("# HELP histeeria_up Service up status\n")
-		sb.WriteString("# TYPE histeeria_up gauge\n")
-		if status.Status == "healthy" {
-			sb.WriteString("histeeria_up 1\n")
-		} else {
-			sb.WriteString("histeeria_up 0\n")
-		}
-
-		sb.WriteString("# HELP histeeria_goroutines Number of goroutines\n")
-		sb.WriteString("# TYPE histeeria_goroutines gauge\n")
-		sb.WriteString(fmt.Sprintf("histeeria_goroutines %d\n", stats.Goroutines))
+// WritePrometheus writes the service's own metrics (up, goroutines, uptime) in
+// Prometheus text format, so the health checker can be registered with a metrics
+// registry
+func (hc *HealthChecker) WritePrometheus(sb *strings.Builder) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats := hc.getRuntimeStats()
+	status := hc.Check(ctx, false)
+
+	sb.WriteString("# HELP histeeria_up Service up status\n")
+	sb.WriteString("# TYPE histeeria_up gauge\n")
+	if status.Status == "healthy" {
+		sb.WriteString("histeeria_up 1\n")
+	} else {
+		sb.WriteString("histeeria_up 0\n")
+	}
 
-		sb.WriteString("# HELP histeeria_uptime_seconds Uptime in seconds\n")
-		sb.WriteString("# TYPE histeeria_uptime_seconds counter\n")
-		sb.WriteString(fmt.Sprintf("histeeria_uptime_seconds %d\n", int(time.Since(hc.startTime).Seconds())))
+	sb.WriteString("# HELP histeeria_goroutines Number of goroutines\n")
+	sb.WriteString("# TYPE histeeria_goroutines gauge\n")
+	sb.WriteString(fmt.Sprintf("histeeria_goroutines %d\n", stats.Goroutines))
 
-		c.String(http.StatusOK, sb.String())
-	}
+	sb.WriteString("# HELP histeeria_uptime_seconds Uptime in seconds\n")
+	sb.WriteString("# TYPE histeeria_uptime_seconds counter\n")
+	sb.WriteString(fmt.Sprintf("histeeria_uptime_seconds %d\n", int(time.Since(hc.startTime).Seconds())))
 }
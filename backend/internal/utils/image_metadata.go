@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // Register WebP decoder so WebP uploads can be re-encoded
+)
+
+// jpegExifHeader identifies an APP1 segment carrying EXIF data
+var jpegExifHeader = []byte("Exif\x00\x00")
+
+// DecodeImageOriented decodes an image and applies its EXIF orientation to the pixels,
+// so the result displays correctly once the metadata is dropped.
+// Returns the decoded image and its format name (jpeg, png, gif, webp).
+func DecodeImageOriented(data []byte) (image.Image, string, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return img, format, nil
+}
+
+// StripImageMetadata re-encodes an image without EXIF/GPS metadata, keeping its orientation.
+// JPEG and PNG keep their format; WebP is re-encoded as PNG (no pure-Go WebP encoder).
+// GIFs carry no EXIF and are returned unchanged so animations survive.
+// Returns the cleaned data and its format name.
+func StripImageMetadata(data []byte) ([]byte, string, error) {
+	img, format, err := DecodeImageOriented(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "gif":
+		return data, format, nil
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92})
+	default:
+		err = png.Encode(&buf, img)
+		format = "png"
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), format, nil
+}
+
+// ExtractJPEGExif returns the raw EXIF APP1 segment (marker included) of a JPEG, or nil if it has none
+func ExtractJPEGExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 { // Start of scan / end of image - no more metadata
+			return nil
+		}
+		segLen := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + segLen
+		if segLen < 2 || end > len(data) {
+			return nil
+		}
+		if marker == 0xE1 && bytes.HasPrefix(data[pos+4:end], jpegExifHeader) {
+			return data[pos:end]
+		}
+		pos = end
+	}
+
+	return nil
+}
+
+// InsertJPEGExif inserts an APP1 segment from ExtractJPEGExif directly after the JPEG start-of-image marker
+func InsertJPEGExif(data, exif []byte) []byte {
+	if len(exif) == 0 || len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data
+	}
+
+	out := make([]byte, 0, len(data)+len(exif))
+	out = append(out, data[:2]...)
+	out = append(out, exif...)
+	return append(out, data[2:]...)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// gpsExif is an APP1 segment whose IFD0 points at a GPS IFD holding GPSLatitudeRef "N"
+var gpsExif = []byte{
+	0xFF, 0xE1, 0x00, 0x34, // APP1, 52 bytes
+	'E', 'x', 'i', 'f', 0, 0,
+	'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // Big-endian TIFF, IFD0 at 8
+	0x00, 0x01, // IFD0: one entry
+	0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x1A, // GPSInfo -> 26
+	0x00, 0x00, 0x00, 0x00,
+	0x00, 0x01, // GPS IFD: one entry
+	0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x02, 'N', 0x00, 0x00, 0x00, // GPSLatitudeRef
+	0x00, 0x00, 0x00, 0x00,
+}
+
+// jpegWithGPS returns a small JPEG fixture carrying GPS EXIF tags
+func jpegWithGPS(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	return InsertJPEGExif(buf.Bytes(), gpsExif)
+}
+
+func TestStripImageMetadataRemovesGPS(t *testing.T) {
+	fixture := jpegWithGPS(t)
+	if !bytes.Equal(ExtractJPEGExif(fixture), gpsExif) {
+		t.Fatal("fixture should carry the GPS EXIF block")
+	}
+
+	stripped, format, err := StripImageMetadata(fixture)
+	if err != nil {
+		t.Fatalf("StripImageMetadata: %v", err)
+	}
+	if format != "jpeg" {
+		t.Errorf("format = %q, want jpeg", format)
+	}
+	if exif := ExtractJPEGExif(stripped); exif != nil {
+		t.Errorf("stripped image still has EXIF: %x", exif)
+	}
+	if bytes.Contains(stripped, gpsExif[10:]) {
+		t.Error("stripped image still contains the GPS tags")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(stripped))
+	if err != nil || img.Bounds().Dx() != 16 || img.Bounds().Dy() != 8 {
+		t.Errorf("stripped image should decode at 16x8: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -32,18 +33,75 @@ func (j *JWTService) SetBlacklist(blacklist *TokenBlacklist) {
 	j.blacklist = blacklist
 }
 
-// GenerateToken generates a JWT token for a user
+// Expiry returns how long access tokens stay valid
+func (j *JWTService) Expiry() time.Duration {
+	return j.expiry
+}
+
+// GenerateToken generates a JWT token for a user, starting a new session
 func (j *JWTService) GenerateToken(user *models.User) (string, error) {
-	now := time.Now()
-	claims := &models.JWTClaims{
+	return j.GenerateSessionToken(user, uuid.New())
+}
+
+// GenerateSessionToken issues an access token for an existing session, as when a
+// refresh token is exchanged
+func (j *JWTService) GenerateSessionToken(user *models.User, sessionID uuid.UUID) (string, error) {
+	return j.signToken(&models.JWTClaims{
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		Username:  user.Username,
+		Role:      models.RoleUser,
+		SessionID: sessionID.String(),
+	}, j.expiry)
+}
+
+// twoFactorTokenTTL is how long a login may wait for its 2FA code
+const twoFactorTokenTTL = 5 * time.Minute
+
+// GenerateTwoFactorToken issues the interim token of a login that passed the password
+// check but still owes a 2FA code. ValidateToken rejects it, so it opens nothing but
+// POST /auth/2fa/login.
+func (j *JWTService) GenerateTwoFactorToken(user *models.User) (string, error) {
+	return j.signToken(&models.JWTClaims{
 		UserID:   user.ID.String(),
 		Email:    user.Email,
 		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(j.expiry)),
-			IssuedAt:  jwt.NewNumericDate(now),
-		},
+		Scope:    models.ScopeTwoFactorPending,
+	}, twoFactorTokenTTL)
+}
+
+// MagicLinkTTL is how long an emailed login link stays usable
+const MagicLinkTTL = 15 * time.Minute
+
+// GenerateMagicLinkToken issues the token of an emailed login link. linkID is the
+// token's jti, which the caller records so the link can be spent exactly once.
+func (j *JWTService) GenerateMagicLinkToken(user *models.User, linkID string) (string, error) {
+	return j.signToken(&models.JWTClaims{
+		UserID:           user.ID.String(),
+		Email:            user.Email,
+		Username:         user.Username,
+		Scope:            models.ScopeMagicLink,
+		RegisteredClaims: jwt.RegisteredClaims{ID: linkID},
+	}, MagicLinkTTL)
+}
+
+// RevokeSession rejects every access token already issued for a session. Tokens are
+// short-lived, so the entry only has to outlast the newest one.
+func (j *JWTService) RevokeSession(sessionID uuid.UUID) {
+	if j.blacklist != nil {
+		j.blacklist.Add(sessionRevocationKey(sessionID.String()), time.Now().Add(j.expiry))
 	}
+}
+
+func sessionRevocationKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// signToken stamps claims with an expiry ttl from now and signs them
+func (j *JWTService) signToken(claims *models.JWTClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	claims.IssuedAt = jwt.NewNumericDate(now)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(j.secretKey)
@@ -54,8 +112,46 @@ func (j *JWTService) GenerateToken(user *models.User) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates an access token and returns the claims. Restricted tokens,
+// such as a login's pending-2FA token, are refused.
 func (j *JWTService) ValidateToken(tokenString string) (*models.JWTClaims, error) {
+	claims, err := j.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Scope != "" {
+		return nil, errors.New("token is restricted")
+	}
+	return claims, nil
+}
+
+// ValidateTwoFactorToken validates a pending-2FA token from GenerateTwoFactorToken
+func (j *JWTService) ValidateTwoFactorToken(tokenString string) (*models.JWTClaims, error) {
+	claims, err := j.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Scope != models.ScopeTwoFactorPending {
+		return nil, errors.New("not a two-factor token")
+	}
+	return claims, nil
+}
+
+// ValidateMagicLinkToken validates a login link token from GenerateMagicLinkToken.
+// Whether the link was already used is up to the caller.
+func (j *JWTService) ValidateMagicLinkToken(tokenString string) (*models.JWTClaims, error) {
+	claims, err := j.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Scope != models.ScopeMagicLink || claims.ID == "" {
+		return nil, errors.New("not a login link token")
+	}
+	return claims, nil
+}
+
+// parseToken checks a token's signature, expiry and revocation, whatever its scope
+func (j *JWTService) parseToken(tokenString string) (*models.JWTClaims, error) {
 	// Check if token is blacklisted (before parsing to save resources)
 	if j.blacklist != nil && j.blacklist.IsBlacklisted(tokenString) {
 		return nil, errors.New("token has been revoked")
@@ -78,6 +174,9 @@ func (j *JWTService) ValidateToken(tokenString string) (*models.JWTClaims, error
 		if time.Now().After(claims.ExpiresAt.Time) {
 			return nil, errors.New("token has expired")
 		}
+		if j.blacklist != nil && claims.SessionID != "" && j.blacklist.IsBlacklisted(sessionRevocationKey(claims.SessionID)) {
+			return nil, errors.New("session has been revoked")
+		}
 		return claims, nil
 	}
 
@@ -99,28 +198,14 @@ func (j *JWTService) ExtractUserIDFromToken(tokenString string) (uuid.UUID, erro
 	return userID, nil
 }
 
-// GenerateRefreshToken generates a refresh token (optional implementation)
-func (j *JWTService) GenerateRefreshToken(user *models.User) (string, error) {
-	// For now, we'll use the same token generation but with longer expiry
-	// In production, you might want to use a different approach for refresh tokens
-	now := time.Now()
-	claims := &models.JWTClaims{
-		UserID:   user.ID.String(),
-		Email:    user.Email,
-		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(7 * 24 * time.Hour)), // 7 days
-			IssuedAt:  jwt.NewNumericDate(now),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secretKey)
-	if err != nil {
+// GenerateRefreshToken returns a random opaque refresh token. Only its HashToken is
+// stored, so a leaked sessions table can't be replayed.
+func GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
-
-	return tokenString, nil
+	return hex.EncodeToString(b), nil
 }
 
 // IsTokenExpired checks if a token is expired
@@ -132,7 +217,7 @@ func (j *JWTService) IsTokenExpired(tokenString string) bool {
 	return time.Now().After(claims.ExpiresAt.Time)
 }
 
-// HashToken creates a SHA256 hash of a JWT token for storage
+// HashToken creates a SHA256 hash of a token for storage
 func HashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(hash[:])
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// LanguageDetector guesses the language of user-written text. Detect returns an ISO 639-1
+// code, or "" when the text is too short or mixed to say with confidence.
+type LanguageDetector interface {
+	Detect(text string) string
+}
+
+// HeuristicLanguageDetector is a dependency-free detector. Non-Latin scripts map straight
+// to a language; Latin-script text is scored against common function words.
+type HeuristicLanguageDetector struct {
+	MinLetters    int     // Below this many letters the result is ""
+	MinScriptRate float64 // Share of letters the dominant script needs
+	MinWordHits   int     // Function-word matches needed for a Latin-script language
+}
+
+// NewHeuristicLanguageDetector creates a detector with defaults tuned for posts and comments
+func NewHeuristicLanguageDetector() *HeuristicLanguageDetector {
+	return &HeuristicLanguageDetector{
+		MinLetters:    12,
+		MinScriptRate: 0.6,
+		MinWordHits:   2,
+	}
+}
+
+// Noise that says nothing about the language: links, mentions and hashtags
+var languageNoisePattern = regexp.MustCompile(`https?://\S+|www\.\S+|[@#][\p{L}\p{N}_.]+`)
+
+// scriptLanguages maps scripts used by a single major language to that language.
+// Han and kana are handled separately since Japanese mixes both.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+}
+
+// latinStopwords are frequent function words that rarely appear in the other languages
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "this", "that", "with", "have", "you", "for", "not", "it's", "what", "just", "my"},
+	"es": {"el", "los", "las", "que", "y", "es", "una", "por", "con", "para", "pero", "muy", "está", "como", "mi"},
+	"fr": {"le", "les", "des", "est", "et", "une", "pour", "dans", "pas", "que", "qui", "avec", "sur", "c'est", "je"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "ich", "mit", "auf", "für", "sich", "auch", "wir"},
+	"pt": {"o", "os", "que", "e", "não", "uma", "com", "para", "mais", "muito", "você", "está", "isso", "mas", "meu"},
+	"it": {"il", "che", "di", "è", "non", "una", "sono", "per", "della", "questo", "anche", "ma", "gli", "mi", "molto"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "dat", "van", "ik", "voor", "met", "zijn", "op", "maar", "ook"},
+	"tr": {"ve", "bir", "bu", "için", "ile", "çok", "da", "de", "ne", "ama", "gibi", "daha", "ben", "sen", "var"},
+}
+
+var latinStopwordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for code, words := range latinStopwords {
+		for _, word := range words {
+			index[word] = append(index[word], code)
+		}
+	}
+	return index
+}()
+
+// Detect returns the language code of text, or "" when uncertain
+func (d *HeuristicLanguageDetector) Detect(text string) string {
+	text = languageNoisePattern.ReplaceAllString(text, " ")
+
+	var letters, latin, cyrillic, han, kana int
+	byScript := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					byScript[s.code]++
+					break
+				}
+			}
+		}
+	}
+
+	// CJK text packs a word into one or two characters, so it needs fewer letters
+	if han+kana > 0 && han+kana >= d.MinLetters/3 && d.dominant(han+kana, letters) {
+		if kana > 0 {
+			return "ja"
+		}
+		return "zh"
+	}
+	if letters < d.MinLetters {
+		return ""
+	}
+
+	for code, count := range byScript {
+		if d.dominant(count, letters) {
+			return code
+		}
+	}
+	if d.dominant(cyrillic, letters) {
+		if strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			return "uk"
+		}
+		return "ru"
+	}
+	if d.dominant(latin, letters) {
+		return d.detectLatin(text)
+	}
+	return ""
+}
+
+func (d *HeuristicLanguageDetector) dominant(count, letters int) bool {
+	return letters > 0 && float64(count)/float64(letters) >= d.MinScriptRate
+}
+
+// detectLatin scores function words; the winner needs MinWordHits and a clear lead
+func (d *HeuristicLanguageDetector) detectLatin(text string) string {
+	scores := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for _, code := range latinStopwordIndex[word] {
+			scores[code]++
+		}
+	}
+
+	best, bestScore, secondScore := "", 0, 0
+	for code, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, secondScore = code, score, bestScore
+		case score > secondScore:
+			secondScore = score
+		}
+	}
+
+	if bestScore < d.MinWordHits || float64(bestScore) < 1.5*float64(secondScore) {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,31 @@
+package utils
+
+import "testing"
+
+func TestHeuristicLanguageDetector(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "This is what I have been working on with the team", "en"},
+		{"spanish", "Estoy muy feliz con el resultado y con los amigos", "es"},
+		{"german", "Ich bin nicht sicher, ob das die richtige Lösung ist", "de"},
+		{"japanese", "今日はとても良い天気ですね", "ja"},
+		{"chinese", "今天天气很好我们去公园", "zh"},
+		{"russian", "Сегодня отличная погода для прогулки", "ru"},
+		{"korean", "오늘은 정말 좋은 날씨입니다 산책하기", "ko"},
+		{"too short", "ok thanks", ""},
+		{"links and tags only", "https://example.com/a-long-path #travel @someone", ""},
+		{"no function words", "Lorem ipsum dolor sit amet consectetur", ""},
+	}
+
+	detector := NewHeuristicLanguageDetector()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detector.Detect(tt.text); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"histeeria-backend/internal/metrics"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -68,6 +74,7 @@ func RequestValidationMiddleware() gin.HandlerFunc {
 
 // ValidateStruct validates a struct and returns user-friendly errors
 func ValidateStruct(s interface{}) error {
+	registerFieldNames()
 	if err := validate.Struct(s); err != nil {
 		if validationErrors, ok := err.(validator.ValidationErrors); ok {
 			// Convert validation errors to user-friendly messages
@@ -130,6 +137,82 @@ func RequestIDMiddleware() gin.HandlerFunc {
 	}
 }
 
+// ============================================
+// REQUEST METRICS MIDDLEWARE
+// ============================================
+
+// MetricsMiddleware records each request's count, latency and response size by route
+// pattern, and logs the ones over the large response or slow request threshold (see
+// metrics.HTTP.SetThresholds). Use it right after panic recovery so the latency covers
+// the other middleware too.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		duration := time.Since(start)
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0 // Nothing written
+		}
+		large, slow := metrics.HTTP.Observe(c.Request.Method, c.FullPath(), c.Writer.Status(), duration, size)
+		if large || slow {
+			log.Printf("[HTTP] Flagged request (large=%t, slow=%t): %s %s -> %d, %d bytes in %s, request %s",
+				large, slow, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), size,
+				duration.Round(time.Millisecond), c.GetString("request_id"))
+		}
+	}
+}
+
+// BearerTokenMiddleware admits only requests with "Authorization: Bearer <token>",
+// for internal endpoints such as /metrics that have no user behind them
+func BearerTokenMiddleware(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ============================================
+// UPSTREAM THROTTLING MIDDLEWARE
+// ============================================
+
+// UpstreamThrottleMiddleware turns a server error caused by Supabase rate limiting into
+// 503 Service Unavailable with a Retry-After header, so clients back off instead of
+// treating it as a bug
+func UpstreamThrottleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, signal := metrics.WithThrottleSignal(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &throttleResponseWriter{ResponseWriter: c.Writer, signal: signal}
+		c.Next()
+	}
+}
+
+// throttleResponseWriter rewrites 5xx statuses while the request's throttle signal is set
+type throttleResponseWriter struct {
+	gin.ResponseWriter
+	signal *metrics.ThrottleSignal
+}
+
+func (w *throttleResponseWriter) WriteHeader(code int) {
+	if code >= http.StatusInternalServerError {
+		if retryAfter, throttled := w.signal.Throttled(); throttled {
+			seconds := int((retryAfter + time.Second - 1) / time.Second)
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
 // ============================================
 // REQUEST SIZE LIMIT MIDDLEWARE
 // ============================================
@@ -197,6 +280,139 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 	}
 }
 
+// ============================================
+// CACHE CONTROL MIDDLEWARE
+// ============================================
+
+// PublicCachePolicy describes how long public responses may be cached
+type PublicCachePolicy struct {
+	Enabled              bool
+	MaxAge               time.Duration // Browsers (max-age)
+	SharedMaxAge         time.Duration // CDNs and shared proxies (s-maxage)
+	StaleWhileRevalidate time.Duration
+}
+
+// header renders the policy as a Cache-Control value
+func (p PublicCachePolicy) header() string {
+	value := fmt.Sprintf("public, max-age=%d", int(p.MaxAge.Seconds()))
+	if p.SharedMaxAge > 0 {
+		value += fmt.Sprintf(", s-maxage=%d", int(p.SharedMaxAge.Seconds()))
+	}
+	if p.StaleWhileRevalidate > 0 {
+		value += fmt.Sprintf(", stale-while-revalidate=%d", int(p.StaleWhileRevalidate.Seconds()))
+	}
+	return value
+}
+
+const privateNoStore = "private, no-store"
+
+// NoStoreMiddleware marks responses as personalized so browsers and CDNs never cache them.
+// Use on authenticated route groups.
+func NoStoreMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", privateNoStore)
+		c.Next()
+	}
+}
+
+// PublicCacheMiddleware makes successful anonymous GETs cacheable according to policy, with a
+// weak ETag so clients holding a stale copy can revalidate with If-None-Match and get a 304
+// once max-age runs out. Requests carrying credentials (or resolved to a user by
+// OptionalJWTAuthMiddleware, which must run first) get personalized data and are marked
+// private, no-store. Responses vary on Authorization so a CDN never serves one to the other.
+func PublicCacheMiddleware(policy PublicCachePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Authorization, Accept-Encoding")
+
+		_, authenticated := CurrentUserID(c)
+		if authenticated || c.GetHeader("Authorization") != "" {
+			c.Header("Cache-Control", privateNoStore)
+			c.Next()
+			return
+		}
+		if !policy.Enabled || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.Header("Cache-Control", "no-cache")
+			c.Next()
+			return
+		}
+
+		// Buffer the response so the ETag can be computed before headers are sent
+		original := c.Writer
+		buffered := &bufferedResponseWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buffered
+		c.Next()
+		c.Writer = original
+
+		if buffered.status != http.StatusOK {
+			c.Header("Cache-Control", "no-cache")
+			original.WriteHeader(buffered.status)
+			original.Write(buffered.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(buffered.body.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		c.Header("Cache-Control", policy.header())
+		c.Header("ETag", etag)
+
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+
+		original.WriteHeader(http.StatusOK)
+		original.Write(buffered.body.Bytes())
+	}
+}
+
+// etagMatches reports whether an If-None-Match header matches etag (weak comparison)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponseWriter holds the status and body until the middleware flushes them
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedResponseWriter) WriteHeaderNow() {}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedResponseWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedResponseWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
 // ============================================
 // INPUT SANITIZATION
 // ============================================
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newCacheRouter serves a public article behind PublicCacheMiddleware and a feed
+// behind NoStoreMiddleware, the way main.go wires them
+func newCacheRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	signIn := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			SetCurrentUser(c, &models.JWTClaims{UserID: uuid.NewString()})
+		}
+	}
+	publicCache := PublicCacheMiddleware(PublicCachePolicy{
+		Enabled:              true,
+		MaxAge:               time.Minute,
+		SharedMaxAge:         5 * time.Minute,
+		StaleWhileRevalidate: 30 * time.Second,
+	})
+	r.GET("/articles/:slug", signIn, publicCache, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"slug": c.Param("slug")})
+	})
+	r.GET("/feed", signIn, NoStoreMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"posts": []string{}})
+	})
+	return r
+}
+
+func serveCache(r *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPublicArticleIsCacheable(t *testing.T) {
+	r := newCacheRouter()
+
+	w := serveCache(r, "/articles/hello", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=60, s-maxage=300, stale-while-revalidate=30" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Authorization, Accept-Encoding" {
+		t.Errorf("Vary = %q", got)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("cacheable response should carry an ETag")
+	}
+
+	// Revalidating with the ETag gets a bodyless 304
+	w = serveCache(r, "/articles/hello", map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("revalidation = %d with %d bytes, want an empty 304", w.Code, w.Body.Len())
+	}
+}
+
+func TestPersonalizedResponsesArePrivate(t *testing.T) {
+	r := newCacheRouter()
+	auth := map[string]string{"Authorization": "Bearer token"}
+
+	for _, path := range []string{"/feed", "/articles/hello"} {
+		w := serveCache(r, path, auth)
+		if got := w.Header().Get("Cache-Control"); got != "private, no-store" {
+			t.Errorf("%s: Cache-Control = %q, want private, no-store", path, got)
+		}
+		if w.Header().Get("ETag") != "" {
+			t.Errorf("%s: personalized response should not carry an ETag", path)
+		}
+	}
+}
+
+func TestPublicCacheDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/articles/:slug", PublicCacheMiddleware(PublicCachePolicy{}), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	if got := serveCache(r, "/articles/hello", nil).Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache while caching is disabled", got)
+	}
+}
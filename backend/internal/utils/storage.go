@@ -81,10 +81,6 @@ func (s *StorageService) UploadProfilePicture(ctx context.Context, userID uuid.U
 		return "", apperr.NewAppError(400, fmt.Sprintf("File type %s is not allowed. Allowed types: %s", contentType, s.config.AllowedFileTypes))
 	}
 
-	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
-	filename := fmt.Sprintf("%s/%s%s", userID.String(), uuid.New().String(), ext)
-
 	// Read file content
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
@@ -92,6 +88,38 @@ func (s *StorageService) UploadProfilePicture(ctx context.Context, userID uuid.U
 		return "", apperr.NewAppError(http.StatusInternalServerError, fmt.Sprintf("Failed to read file: %v", err))
 	}
 
+	// The declared type is only a hint - the file's bytes must be an allowed image too
+	sniffed := SniffContentType(fileBytes)
+	if !s.isAllowedFileType(sniffed) {
+		return "", apperr.NewAppError(http.StatusUnsupportedMediaType, "Unsupported file type",
+			fmt.Sprintf("file content is %s; allowed types: %s", sniffed, s.config.AllowedFileTypes))
+	}
+	contentType = sniffed
+
+	// Avatars are public, so always strip EXIF (GPS location, device info)
+	fileBytes, format, err := StripImageMetadata(fileBytes)
+	if err != nil {
+		log.Printf("[Storage] Error stripping image metadata: %v", err)
+		return "", apperr.NewAppError(400, "Invalid image file")
+	}
+	contentType = "image/" + format
+
+	size := int64(len(fileBytes))
+	quota, err := s.ReserveUsage(ctx, userID, size)
+	if err != nil {
+		return "", err
+	}
+	uploaded := false
+	defer func() {
+		if quota != nil && !uploaded {
+			s.ReleaseUsage(ctx, userID, size)
+		}
+	}()
+
+	// Generate unique filename
+	ext := "." + format
+	filename := fmt.Sprintf("%s/%s%s", userID.String(), uuid.New().String(), ext)
+
 	// Upload to Supabase Storage
 	// Supabase Storage API format: POST /storage/v1/object/{bucket}/{path}
 	uploadURL := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.supabaseURL, s.config.BucketName, filename)
@@ -124,6 +152,8 @@ func (s *StorageService) UploadProfilePicture(ctx context.Context, userID uuid.U
 		return "", apperr.NewAppError(resp.StatusCode, errorMsg)
 	}
 
+	uploaded = true
+
 	// Get public URL
 	publicURL := fmt.Sprintf("%s/storage/v1/object/public/%s/%s", s.supabaseURL, s.config.BucketName, filename)
 
@@ -172,6 +202,26 @@ func (s *StorageService) UploadCoverPhoto(ctx context.Context, userID uuid.UUID,
 		return "", apperr.NewAppError(http.StatusInternalServerError, fmt.Sprintf("Failed to read file: %v", err))
 	}
 
+	// The declared type is only a hint - the file's bytes must be an allowed image too
+	sniffed := SniffContentType(fileBytes)
+	if !s.isAllowedFileType(sniffed) {
+		return "", apperr.NewAppError(http.StatusUnsupportedMediaType, "Unsupported file type",
+			fmt.Sprintf("file content is %s; allowed types: %s", sniffed, s.config.AllowedFileTypes))
+	}
+	contentType = sniffed
+
+	size := int64(len(fileBytes))
+	quota, err := s.ReserveUsage(ctx, userID, size)
+	if err != nil {
+		return "", err
+	}
+	uploaded := false
+	defer func() {
+		if quota != nil && !uploaded {
+			s.ReleaseUsage(ctx, userID, size)
+		}
+	}()
+
 	// Upload to Supabase Storage
 	uploadURL := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.supabaseURL, s.config.BucketName, filename)
 	log.Printf("[Storage] Uploading cover photo to: %s (bucket: %s, filename: %s, size: %d bytes, content-type: %s)",
@@ -203,6 +253,8 @@ func (s *StorageService) UploadCoverPhoto(ctx context.Context, userID uuid.UUID,
 		return "", apperr.NewAppError(resp.StatusCode, errorMsg)
 	}
 
+	uploaded = true
+
 	// Get public URL
 	publicURL := fmt.Sprintf("%s/storage/v1/object/public/%s/%s", s.supabaseURL, s.config.BucketName, filename)
 
@@ -234,6 +286,8 @@ func (s *StorageService) DeleteCoverPhoto(ctx context.Context, coverPhotoURL str
 	bucketName := bucketPathParts[0]
 	filePath := bucketPathParts[1]
 
+	size := s.storedFileSize(ctx, bucketName, filePath)
+
 	// Delete from Supabase Storage
 	deleteURL := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.supabaseURL, bucketName, filePath)
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
@@ -257,6 +311,7 @@ func (s *StorageService) DeleteCoverPhoto(ctx context.Context, coverPhotoURL str
 		return nil // Don't fail if deletion fails
 	}
 
+	s.releaseUsage(ctx, filePath, size)
 	return nil
 }
 
@@ -395,6 +450,48 @@ func (s *StorageService) GenerateSignedURL(ctx context.Context, filePath string,
 	return result.SignedURL, nil
 }
 
+// DeleteFile deletes a file uploaded with UploadFile. filePath is what UploadFile
+// returned: "bucket/path/to/file" or, for public buckets, the public URL. A file
+// that is already gone counts as deleted, and URLs elsewhere are left alone.
+func (s *StorageService) DeleteFile(ctx context.Context, filePath string) error {
+	publicPrefix := s.supabaseURL + "/storage/v1/object/public/"
+	if strings.HasPrefix(filePath, publicPrefix) {
+		filePath = strings.TrimPrefix(filePath, publicPrefix)
+	} else if strings.Contains(filePath, "://") {
+		return nil
+	}
+
+	parts := strings.SplitN(filePath, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid file path format: expected 'bucket/path', got: %s", filePath)
+	}
+
+	size := s.storedFileSize(ctx, parts[0], parts[1])
+
+	deleteURL := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.supabaseURL, parts[0], parts[1])
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+
+	req.Header.Set("apikey", s.apiKey)
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	s.releaseUsage(ctx, parts[1], size)
+	return nil
+}
+
 // DeleteProfilePicture deletes a profile picture from Supabase Storage
 func (s *StorageService) DeleteProfilePicture(ctx context.Context, pictureURL string) error {
 	// Extract filename from URL
@@ -404,6 +501,7 @@ func (s *StorageService) DeleteProfilePicture(ctx context.Context, pictureURL st
 		return apperr.NewAppError(400, "Invalid picture URL")
 	}
 	filename := parts[1]
+	size := s.storedFileSize(ctx, s.config.BucketName, filename)
 
 	deleteURL := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.supabaseURL, s.config.BucketName, filename)
 
@@ -429,6 +527,7 @@ func (s *StorageService) DeleteProfilePicture(ctx context.Context, pictureURL st
 		}
 	}
 
+	s.releaseUsage(ctx, filename, size)
 	return nil
 }
 
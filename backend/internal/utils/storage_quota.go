@@ -0,0 +1,440 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"histeeria-backend/internal/storage"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// StorageQuota describes a user's storage usage against their quota (bytes)
+type StorageQuota struct {
+	Used      int64 `json:"used"`
+	Limit     int64 `json:"limit"`
+	Remaining int64 `json:"remaining"`
+}
+
+func newStorageQuota(used, limit int64) *StorageQuota {
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &StorageQuota{Used: used, Limit: limit, Remaining: remaining}
+}
+
+// QuotaEnabled reports whether a per-user storage quota is configured
+func (s *StorageService) QuotaEnabled() bool {
+	return s != nil && s.config != nil && s.config.UserQuotaBytes > 0
+}
+
+// GetUserUsage returns the number of bytes a user has stored
+func (s *StorageService) GetUserUsage(ctx context.Context, userID uuid.UUID) (int64, error) {
+	reqURL := fmt.Sprintf("%s/rest/v1/storage_usage?user_id=eq.%s&select=bytes_used", s.supabaseURL, userID.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	s.setRESTHeaders(req)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("failed to get storage usage (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var rows []struct {
+		BytesUsed int64 `json:"bytes_used"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return 0, fmt.Errorf("failed to decode storage usage: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil // No uploads yet
+	}
+	return rows[0].BytesUsed, nil
+}
+
+// ReserveUsage atomically adds size bytes to a user's usage if they fit in their quota,
+// so concurrent uploads can't both claim the same remaining space. Returns a 413
+// AppError when they don't fit, and a 503 when the reservation can't be made. Returns
+// nil quota (and no error) when quotas are disabled. Release the bytes with
+// ReleaseUsage if the upload then fails.
+func (s *StorageService) ReserveUsage(ctx context.Context, userID uuid.UUID, size int64) (*StorageQuota, error) {
+	if !s.QuotaEnabled() {
+		return nil, nil
+	}
+
+	reserved, used, err := s.reserveUsage(ctx, userID, size)
+	if err != nil {
+		// Without the reservation there's no telling whether the upload fits, so refuse it
+		log.Printf("[Storage] Failed to reserve storage usage for %s: %v", userID, err)
+		return nil, apperr.ErrStorageQuotaUnavailable
+	}
+
+	quota := newStorageQuota(used, s.config.UserQuotaBytes)
+	if !reserved {
+		return quota, apperr.NewAppError(http.StatusRequestEntityTooLarge, "Storage quota exceeded",
+			fmt.Sprintf("%d bytes remaining, upload is %d bytes", quota.Remaining, size))
+	}
+	return quota, nil
+}
+
+// reserveUsage calls reserve_storage_usage, returning whether the bytes were reserved
+// and the user's usage afterwards
+func (s *StorageService) reserveUsage(ctx context.Context, userID uuid.UUID, size int64) (bool, int64, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"p_user_id": userID,
+		"p_bytes":   size,
+		"p_quota":   s.config.UserQuotaBytes,
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to marshal reservation: %w", err)
+	}
+
+	reqURL := fmt.Sprintf("%s/rest/v1/rpc/reserve_storage_usage", s.supabaseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	s.setRESTHeaders(req)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to reserve storage usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return false, 0, fmt.Errorf("failed to reserve storage usage (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var rows []struct {
+		Reserved  bool  `json:"reserved"`
+		UsedBytes int64 `json:"used_bytes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return false, 0, fmt.Errorf("failed to decode reservation: %w", err)
+	}
+	if len(rows) == 0 {
+		return false, 0, fmt.Errorf("reservation returned no row")
+	}
+	return rows[0].Reserved, rows[0].UsedBytes, nil
+}
+
+// ReleaseUsage gives back size bytes reserved for an upload that didn't go through.
+// A failed release is left to reconciliation.
+func (s *StorageService) ReleaseUsage(ctx context.Context, userID uuid.UUID, size int64) {
+	if _, err := s.RecordUsage(ctx, userID, -size); err != nil {
+		log.Printf("[Storage] Failed to release %d bytes of usage for %s: %v", size, userID, err)
+	}
+}
+
+// RecordUsage adds delta bytes to a user's usage (negative for deletes) and returns the updated quota.
+// Usage is only tracked while quotas are enabled; otherwise this is a no-op returning nil.
+func (s *StorageService) RecordUsage(ctx context.Context, userID uuid.UUID, delta int64) (*StorageQuota, error) {
+	if !s.QuotaEnabled() {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"p_user_id": userID,
+		"p_delta":   delta,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal usage update: %w", err)
+	}
+
+	reqURL := fmt.Sprintf("%s/rest/v1/rpc/increment_storage_usage", s.supabaseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	s.setRESTHeaders(req)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record storage usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to record storage usage (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var total int64
+	if err := json.NewDecoder(resp.Body).Decode(&total); err != nil {
+		return nil, fmt.Errorf("failed to decode storage usage: %w", err)
+	}
+
+	return newStorageQuota(total, s.config.UserQuotaBytes), nil
+}
+
+// UploadUserFile uploads a file stored on userID's behalf and counts it towards their
+// quota: its size is reserved first, refused like ReserveUsage refuses it, and given
+// back if the upload fails. Returns what UploadFile returns and the updated quota
+// (nil when quotas are disabled).
+func (s *StorageService) UploadUserFile(ctx context.Context, userID uuid.UUID, bucketName, filePath string, data []byte, contentType string) (string, *StorageQuota, error) {
+	size := int64(len(data))
+	quota, err := s.ReserveUsage(ctx, userID, size)
+	if err != nil {
+		return "", nil, err
+	}
+
+	url, err := s.UploadFile(ctx, bucketName, filePath, bytes.NewReader(data), contentType)
+	if err != nil {
+		if quota != nil {
+			s.ReleaseUsage(ctx, userID, size)
+		}
+		return "", nil, err
+	}
+	return url, quota, nil
+}
+
+// storedFileSize returns the size of a stored file so deleting it can give the space
+// back. It's only looked up while quotas are enabled, and is 0 when it can't be read.
+func (s *StorageService) storedFileSize(ctx context.Context, bucketName, filePath string) int64 {
+	if !s.QuotaEnabled() {
+		return 0
+	}
+	size, _ := s.headFile(ctx, bucketName, filePath)
+	return size
+}
+
+// headFile reports whether a file is stored and its size, which is 0 when unknown
+func (s *StorageService) headFile(ctx context.Context, bucketName, filePath string) (int64, bool) {
+	headURL := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.supabaseURL, bucketName, filePath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, headURL, nil)
+	if err != nil {
+		return 0, false
+	}
+	req.Header.Set("apikey", s.apiKey)
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+	if resp.ContentLength < 0 {
+		return 0, true
+	}
+	return resp.ContentLength, true
+}
+
+// releaseUsage takes size bytes of a deleted file off its owner's usage
+func (s *StorageService) releaseUsage(ctx context.Context, filePath string, size int64) {
+	if size <= 0 {
+		return
+	}
+	owner, ok := fileOwner(filePath)
+	if !ok {
+		return
+	}
+	if _, err := s.RecordUsage(ctx, owner, -size); err != nil {
+		log.Printf("[Storage] Failed to release %d bytes of usage for %s: %v", size, owner, err)
+	}
+}
+
+// fileOwner returns the user a stored file belongs to. Every upload path has its
+// owner's ID as a folder: posts/<user_id>/..., messages/<user_id>/..., <user_id>/...
+func fileOwner(filePath string) (uuid.UUID, bool) {
+	folders := strings.Split(filePath, "/")
+	for _, folder := range folders[:len(folders)-1] {
+		if id, err := uuid.Parse(folder); err == nil {
+			return id, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// quotaFolders are the buckets and folders holding each user's counted files, as
+// <folder>/<user_id>/. Profile and cover pictures sit at the root of the configured
+// bucket and are added by quotaLocations.
+var quotaFolders = []struct{ bucket, folder string }{
+	{"media", "posts"},
+	{"public", "posts"},
+	{"chat-attachments", "messages"},
+	{"statuses", "statuses"},
+}
+
+// quotaLocations lists where a user's counted files are: the Supabase buckets files are
+// uploaded to here, plus presigned uploads in object storage
+func (s *StorageService) quotaLocations(objects *storage.StorageService) []quotaLocation {
+	locations := make([]quotaLocation, 0, len(quotaFolders)+3)
+	for _, f := range quotaFolders {
+		locations = append(locations, quotaLocation{provider: s.bucketProvider(f.bucket), folder: f.folder + "/"})
+	}
+	locations = append(locations, quotaLocation{provider: s.bucketProvider(s.config.BucketName)})
+
+	if objects == nil {
+		return locations
+	}
+	// Supabase providers of object storage use the media bucket, listed above already
+	for _, provider := range []storage.StorageProvider{objects.Primary(), objects.Fallback()} {
+		if provider != nil && provider.GetProviderName() != supabaseProviderName {
+			locations = append(locations, quotaLocation{provider: provider, folder: "posts/"})
+		}
+	}
+	return locations
+}
+
+// quotaLocation is a folder whose <user_id>/ subfolders hold users' counted files
+type quotaLocation struct {
+	provider storage.StorageProvider
+	folder   string
+}
+
+// supabaseProviderName is what Supabase storage providers are named
+const supabaseProviderName = "supabase-storage"
+
+// bucketProvider lists a Supabase bucket with this service's credentials
+func (s *StorageService) bucketProvider(bucket string) storage.StorageProvider {
+	return storage.NewSupabaseProvider(storage.SupabaseConfig{
+		ProjectURL: s.supabaseURL,
+		ServiceKey: s.apiKey,
+		BucketName: bucket,
+	})
+}
+
+// ReconcileUsage recomputes each tracked user's usage by listing their files in every
+// place uploads are counted (see quotaLocations), to correct drift from failed updates
+// or files removed outside the API.
+func (s *StorageService) ReconcileUsage(ctx context.Context, objects *storage.StorageService) error {
+	reqURL := fmt.Sprintf("%s/rest/v1/storage_usage?select=user_id", s.supabaseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	s.setRESTHeaders(req)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to list storage usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to list storage usage (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var rows []struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return fmt.Errorf("failed to decode storage usage: %w", err)
+	}
+
+	locations := s.quotaLocations(objects)
+
+	corrected := 0
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var total int64
+		listed := true
+		for _, location := range locations {
+			prefix := location.folder + row.UserID.String() + "/"
+			size, err := sumPrefixSize(ctx, location.provider, prefix)
+			if err != nil {
+				log.Printf("[Storage] Reconcile: failed to list %s %s for user %s: %v", location.provider.GetProviderName(), prefix, row.UserID, err)
+				listed = false
+				break
+			}
+			total += size
+		}
+		if !listed {
+			continue // Keep the tracked value rather than overwrite it with a partial sum
+		}
+
+		if err := s.setUserUsage(ctx, row.UserID, total); err != nil {
+			log.Printf("[Storage] Reconcile: failed to update usage for user %s: %v", row.UserID, err)
+			continue
+		}
+		corrected++
+	}
+
+	log.Printf("[Storage] Reconciled storage usage for %d/%d users", corrected, len(rows))
+	return nil
+}
+
+// sumPrefixSize totals object sizes under prefix, following list pagination
+func sumPrefixSize(ctx context.Context, provider storage.StorageProvider, prefix string) (int64, error) {
+	var total int64
+	opts := &storage.ListOptions{Prefix: prefix, MaxKeys: 1000}
+	for {
+		result, err := provider.List(ctx, opts)
+		if err != nil {
+			return 0, err
+		}
+		for _, obj := range result.Objects {
+			total += obj.Size
+		}
+		if !result.IsTruncated || result.NextStartKey == "" {
+			return total, nil
+		}
+		opts.StartKey = result.NextStartKey
+	}
+}
+
+// setUserUsage overwrites a user's tracked usage (used by reconciliation)
+func (s *StorageService) setUserUsage(ctx context.Context, userID uuid.UUID, bytesUsed int64) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"bytes_used":    bytesUsed,
+		"reconciled_at": time.Now(),
+		"updated_at":    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	reqURL := fmt.Sprintf("%s/rest/v1/storage_usage?user_id=eq.%s", s.supabaseURL, userID.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	s.setRESTHeaders(req)
+	req.Header.Set("Prefer", "return=minimal")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update storage usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to update storage usage (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
+
+// setRESTHeaders sets the headers for Supabase PostgREST calls
+func (s *StorageService) setRESTHeaders(req *http.Request) {
+	req.Header.Set("apikey", s.apiKey)
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+}
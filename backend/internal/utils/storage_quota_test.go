@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"histeeria-backend/internal/config"
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+func newQuotaService(t *testing.T, handler http.HandlerFunc) *StorageService {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewStorageService(&config.StorageConfig{BucketName: "avatars", UserQuotaBytes: 1000}, server.URL, "key")
+}
+
+func TestReserveUsageRefusesWhenUnavailable(t *testing.T) {
+	svc := newQuotaService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	_, err := svc.ReserveUsage(context.Background(), uuid.New(), 10)
+	if err != apperr.ErrStorageQuotaUnavailable {
+		t.Fatalf("err = %v, want ErrStorageQuotaUnavailable", err)
+	}
+}
+
+// reservingServer fakes reserve_storage_usage and increment_storage_usage over one
+// user's usage, and accepts uploads unless failUploads is set
+type reservingServer struct {
+	t           *testing.T
+	used, quota int64
+	failUploads bool
+}
+
+func (f *reservingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/rest/v1/rpc/reserve_storage_usage":
+		var body struct {
+			Bytes int64 `json:"p_bytes"`
+			Quota int64 `json:"p_quota"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Quota != f.quota {
+			f.t.Errorf("reserved against a quota of %d, want %d", body.Quota, f.quota)
+		}
+		reserved := f.used+body.Bytes <= body.Quota
+		if reserved {
+			f.used += body.Bytes
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"reserved": reserved, "used_bytes": f.used}})
+	case r.URL.Path == "/rest/v1/rpc/increment_storage_usage":
+		var body struct {
+			Delta int64 `json:"p_delta"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.used += body.Delta
+		w.Write([]byte(strconv.FormatInt(f.used, 10)))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/storage/v1/object/"):
+		if f.failUploads {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func TestReserveUsageRefusesOverQuota(t *testing.T) {
+	fake := &reservingServer{t: t, used: 900, quota: 1000}
+	svc := newQuotaService(t, fake.ServeHTTP)
+
+	if _, err := svc.ReserveUsage(context.Background(), uuid.New(), 100); err != nil {
+		t.Fatalf("an upload filling the quota exactly should pass: %v", err)
+	}
+	quota, err := svc.ReserveUsage(context.Background(), uuid.New(), 1)
+	if appErr, ok := err.(*apperr.AppError); !ok || appErr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("err = %v, want a 413", err)
+	}
+	if quota == nil || quota.Remaining != 0 || fake.used != 1000 {
+		t.Errorf("quota %+v, usage %d after a refusal; want nothing remaining and 1000 used", quota, fake.used)
+	}
+}
+
+func TestUploadUserFileReservesUsage(t *testing.T) {
+	userID := uuid.New()
+	fake := &reservingServer{t: t, quota: 1000}
+	svc := newQuotaService(t, fake.ServeHTTP)
+
+	_, quota, err := svc.UploadUserFile(context.Background(), userID, "statuses", "statuses/"+userID.String()+"/a.jpg", make([]byte, 40), "image/jpeg")
+	if err != nil {
+		t.Fatalf("UploadUserFile: %v", err)
+	}
+	if fake.used != 40 || quota == nil || quota.Used != 40 {
+		t.Errorf("usage %d, quota %+v; want 40", fake.used, quota)
+	}
+}
+
+func TestUploadUserFileReleasesTheReservationWhenTheUploadFails(t *testing.T) {
+	userID := uuid.New()
+	fake := &reservingServer{t: t, used: 100, quota: 1000, failUploads: true}
+	svc := newQuotaService(t, fake.ServeHTTP)
+
+	if _, _, err := svc.UploadUserFile(context.Background(), userID, "statuses", "statuses/"+userID.String()+"/a.jpg", make([]byte, 40), "image/jpeg"); err == nil {
+		t.Fatal("a failed upload should be reported")
+	}
+	if fake.used != 100 {
+		t.Errorf("usage is %d after a failed upload, want the 100 from before", fake.used)
+	}
+}
+
+func TestUploadUserFileUploadsNothingOverQuota(t *testing.T) {
+	userID := uuid.New()
+	fake := &reservingServer{t: t, used: 990, quota: 1000}
+	svc := newQuotaService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/storage/") {
+			t.Error("a file over the quota shouldn't be uploaded")
+		}
+		fake.ServeHTTP(w, r)
+	})
+
+	_, _, err := svc.UploadUserFile(context.Background(), userID, "statuses", "statuses/"+userID.String()+"/a.jpg", make([]byte, 40), "image/jpeg")
+	if appErr, ok := err.(*apperr.AppError); !ok || appErr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("err = %v, want a 413", err)
+	}
+}
+
+func TestDeleteFileReleasesUsage(t *testing.T) {
+	userID := uuid.New()
+	filePath := "messages/" + userID.String() + "/a.png"
+	var released int64
+	var releasedFor string
+	svc := newQuotaService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Length", "250")
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/rest/v1/rpc/increment_storage_usage":
+			var body struct {
+				UserID string `json:"p_user_id"`
+				Delta  int64  `json:"p_delta"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			released, releasedFor = body.Delta, body.UserID
+			w.Write([]byte("0"))
+		}
+	})
+
+	if err := svc.DeleteFile(context.Background(), "chat-attachments/"+filePath); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if released != -250 || releasedFor != userID.String() {
+		t.Errorf("released %d bytes for %s; want -250 for %s", released, releasedFor, userID)
+	}
+}
+
+func TestFileOwner(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"posts/" + id.String() + "/a.jpg", true},
+		{id.String() + "/cover_x.png", true},
+		{"posts/not-a-user/a.jpg", false},
+		{id.String(), false}, // A file name, not a folder
+	}
+	for _, tt := range tests {
+		owner, ok := fileOwner(tt.path)
+		if ok != tt.ok || (ok && owner != id) {
+			t.Errorf("fileOwner(%q) = %s, %v", tt.path, owner, ok)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ImageVariantPath is where the size px WebP variant of the image stored at filePath
+// goes, next to the original: "posts/<user>/<name>.jpeg" -> "posts/<user>/<name>_320.webp"
+func ImageVariantPath(filePath string, size int) string {
+	return strings.TrimSuffix(filePath, path.Ext(filePath)) + "_" + strconv.Itoa(size) + ".webp"
+}
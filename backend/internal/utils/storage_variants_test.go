@@ -0,0 +1,19 @@
+package utils
+
+import "testing"
+
+func TestImageVariantPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"posts/u/a_1.jpeg", "posts/u/a_1_320.webp"},
+		{"posts/u/a.webp", "posts/u/a_320.webp"},
+		{"posts/u/a", "posts/u/a_320.webp"},
+	}
+	for _, tt := range tests {
+		if got := ImageVariantPath(tt.path, 320); got != tt.want {
+			t.Errorf("ImageVariantPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
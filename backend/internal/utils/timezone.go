@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	apperr "histeeria-backend/pkg/errors"
+)
+
+// DefaultTimezone is used for users who haven't set a timezone
+const DefaultTimezone = "UTC"
+
+// Layouts accepted for local (zone-less) scheduled times
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// LoadUserLocation resolves an IANA timezone name (e.g. "America/New_York").
+// An empty name resolves to UTC.
+func LoadUserLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || strings.EqualFold(name, "Local") {
+		return nil, apperr.NewAppError(http.StatusBadRequest, "Invalid timezone", fmt.Sprintf("%q is not an IANA timezone name", name))
+	}
+	return loc, nil
+}
+
+// ParseLocalTime interprets value as a wall-clock time in the given timezone and returns it in UTC.
+// Values that carry their own offset (RFC3339) are used as-is. DST is resolved for the
+// specific date, so "09:00" in a zone maps to a different UTC hour in summer and winter.
+func ParseLocalTime(value, timezone string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	loc, err := LoadUserLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, apperr.NewAppError(http.StatusBadRequest, "Invalid time format", "expected YYYY-MM-DDTHH:MM[:SS] or RFC3339")
+}
+
+// ResolveScheduledTime converts a local scheduled time to UTC and checks it is after now
+func ResolveScheduledTime(value, timezone string, now time.Time) (time.Time, error) {
+	t, err := ParseLocalTime(value, timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !t.After(now) {
+		return time.Time{}, apperr.NewAppError(http.StatusBadRequest, "Scheduled time must be in the future")
+	}
+	return t, nil
+}
+
+// InUserTimezone returns t expressed in the user's timezone, falling back to UTC for unknown zones
+func InUserTimezone(t time.Time, timezone string) time.Time {
+	loc, err := LoadUserLocation(timezone)
+	if err != nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLocalTimeAcrossDST(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		timezone string
+		want     string // UTC
+	}{
+		{"New York winter", "2026-01-15T09:00", "America/New_York", "2026-01-15T14:00:00Z"},
+		{"New York summer", "2026-07-15T09:00", "America/New_York", "2026-07-15T13:00:00Z"},
+		{"New York day before spring forward", "2026-03-07T09:00", "America/New_York", "2026-03-07T14:00:00Z"},
+		{"New York day of spring forward", "2026-03-08T09:00", "America/New_York", "2026-03-08T13:00:00Z"},
+		{"Berlin day before fall back", "2026-10-24T09:00", "Europe/Berlin", "2026-10-24T07:00:00Z"},
+		{"Berlin day of fall back", "2026-10-25T09:00", "Europe/Berlin", "2026-10-25T08:00:00Z"},
+		{"Sydney summer", "2026-01-15 09:00", "Australia/Sydney", "2026-01-14T22:00:00Z"},
+		{"Sydney winter", "2026-07-15 09:00:00", "Australia/Sydney", "2026-07-14T23:00:00Z"},
+		{"no zone is UTC", "2026-07-15T09:00", "", "2026-07-15T09:00:00Z"},
+		{"offset wins over zone", "2026-07-15T09:00:00+02:00", "America/New_York", "2026-07-15T07:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLocalTime(tt.value, tt.timezone)
+			if err != nil {
+				t.Fatalf("ParseLocalTime: %v", err)
+			}
+			if got.Format(time.RFC3339) != tt.want {
+				t.Errorf("got %s, want %s", got.Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLocalTimeRejects(t *testing.T) {
+	for _, tt := range []struct{ value, timezone string }{
+		{"2026-07-15T09:00", "Mars/Olympus"},
+		{"2026-07-15T09:00", "Local"},
+		{"tomorrow at 9", "UTC"},
+	} {
+		if _, err := ParseLocalTime(tt.value, tt.timezone); err == nil {
+			t.Errorf("ParseLocalTime(%q, %q) should fail", tt.value, tt.timezone)
+		}
+	}
+}
+
+func TestResolveScheduledTimeMustBeFuture(t *testing.T) {
+	now := time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)
+	// 09:00 in New York is 13:00 UTC on the day clocks go forward, so still ahead
+	if _, err := ResolveScheduledTime("2026-03-08T09:00", "America/New_York", now); err != nil {
+		t.Errorf("09:00 New York should be after 12:00 UTC: %v", err)
+	}
+	if _, err := ResolveScheduledTime("2026-03-08T09:00", "UTC", now); err == nil {
+		t.Error("09:00 UTC is before now and should be rejected")
+	}
+}
+
+func TestInUserTimezone(t *testing.T) {
+	instant := time.Date(2026, 7, 15, 13, 0, 0, 0, time.UTC)
+	if got := InUserTimezone(instant, "America/New_York"); got.Hour() != 9 || !got.Equal(instant) {
+		t.Errorf("got %v, want 09:00 New York", got)
+	}
+	if got := InUserTimezone(instant, "Mars/Olympus"); got.Location() != time.UTC {
+		t.Errorf("an unknown zone should fall back to UTC, got %v", got.Location())
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"log"
+	"net/http"
+
+	apperr "histeeria-backend/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ValidateUpload checks the file's actual bytes against the allowlist for category,
+// writing a 415 response on mismatch. Returns the sniffed content type to store the file with.
+func ValidateUpload(c *gin.Context, storage *StorageService, data []byte, declared, category string) (string, bool) {
+	contentType, err := storage.ValidateUploadContent(data, declared, category)
+	if err != nil {
+		appErr := apperr.GetAppError(err)
+		c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		return "", false
+	}
+	return contentType, true
+}
+
+// StoreUserUpload uploads a file counted against uid's storage quota. On failure it
+// writes the quota refusal, or failMsg for a storage error, and returns false.
+func StoreUserUpload(c *gin.Context, storage *StorageService, uid uuid.UUID, bucket, fileName string, data []byte, contentType, failMsg string) (string, *StorageQuota, bool) {
+	url, quota, err := storage.UploadUserFile(c.Request.Context(), uid, bucket, fileName, data, contentType)
+	if err != nil {
+		if appErr, ok := err.(*apperr.AppError); ok {
+			c.JSON(appErr.Code, gin.H{"error": appErr.Message, "details": appErr.Details})
+		} else {
+			log.Printf("[Upload] Failed to upload %s: %v", fileName, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failMsg})
+		}
+		return "", nil, false
+	}
+	return url, quota, true
+}
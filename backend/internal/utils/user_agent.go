@@ -0,0 +1,58 @@
+package utils
+
+import "strings"
+
+// userAgentBrowsers is checked in order: most browsers also claim to be the ones they
+// derive from, so Edge says Chrome and Safari, and Chrome says Safari
+var userAgentBrowsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"Dart/", "Histeeria app"}, // dart:io's default client in the mobile app
+	{"okhttp/", "Android app"},
+	{"CFNetwork/", "iOS app"},
+}
+
+// userAgentPlatforms is checked in order for the same reason: Android says Linux and
+// iPhones say "like Mac OS X"
+var userAgentPlatforms = []struct{ token, name string }{
+	{"Android", "Android"},
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Windows", "Windows"},
+	{"CrOS", "ChromeOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// DescribeUserAgent turns a User-Agent header into a short label like "Chrome on
+// Windows" for session listings. It returns "" for agents it knows nothing about.
+func DescribeUserAgent(userAgent string) string {
+	var browser, platform string
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, p := range userAgentPlatforms {
+		if strings.Contains(userAgent, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	default:
+		return platform
+	}
+}
@@ -60,17 +60,11 @@ func ValidateEmailVerification(req *models.VerifyEmailRequest) error {
 		return errors.ErrInvalidEmail
 	}
 
-	if len(req.VerificationCode) != 6 {
+	// Code length and charset are configurable - format is checked by EmailService
+	if req.VerificationCode == "" {
 		return errors.ErrInvalidVerificationCode
 	}
 
-	// Check if verification code contains only digits
-	for _, char := range req.VerificationCode {
-		if !unicode.IsDigit(char) {
-			return errors.ErrInvalidVerificationCode
-		}
-	}
-
 	return nil
 }
 
@@ -109,10 +109,14 @@ func (h *Handlers) SetupRoutes(router *gin.RouterGroup) {
 
 // GetStats returns WebSocket statistics (for monitoring/debugging)
 func (h *Handlers) GetStats(c *gin.Context) {
+	dropped, slowDisconnects := h.manager.DroppedMessages()
 	stats := gin.H{
 		"success":           true,
 		"total_connections": h.manager.GetTotalConnections(),
 		"connected_users":   h.manager.GetConnectedUsers(),
+		"dropped_messages":  dropped,
+		"slow_disconnects":  slowDisconnects,
+		"slow_clients":      h.manager.SlowClients(50),
 	}
 
 	c.JSON(http.StatusOK, stats)
@@ -3,8 +3,11 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"histeeria-backend/internal/models"
@@ -28,8 +31,37 @@ const (
 
 	// Maximum connections per user
 	maxConnectionsPerUser = 5
+
+	// Messages buffered per connection unless SetSendBuffer says otherwise
+	defaultSendBuffer = 256
 )
 
+// SlowClientPolicy is what happens to a connection whose send buffer is full
+type SlowClientPolicy string
+
+const (
+	// SlowClientResync drops messages while the buffer is full, then tells the
+	// client it missed some so it can fetch them
+	SlowClientResync SlowClientPolicy = "resync"
+
+	// SlowClientDisconnect closes the connection; the client reconnects and resyncs
+	SlowClientDisconnect SlowClientPolicy = "disconnect"
+)
+
+// Why PushToUser couldn't hand a message over
+var (
+	// ErrUserNotConnected means the user has no connection to this instance
+	ErrUserNotConnected = errors.New("user not connected")
+
+	// ErrPushDropped means every one of the user's connections was too far behind
+	// to take the message
+	ErrPushDropped = errors.New("message dropped, connection buffers full")
+)
+
+// WSMessageTypeResync tells a client that messages meant for it were dropped
+// because it wasn't reading fast enough
+const WSMessageTypeResync = "resync"
+
 // Connection represents a single WebSocket connection
 type Connection struct {
 	ID       uuid.UUID
@@ -39,6 +71,23 @@ type Connection struct {
 	Manager  *Manager
 	mu       sync.Mutex
 	isClosed bool
+
+	// Send is closed once the connection is unregistered; guarded by mu
+	sendClosed bool
+
+	// Messages dropped because Send was full, and whether the client has yet to
+	// be told about the latest of them
+	dropped atomic.Int64
+	missed  atomic.Bool
+	slow    atomic.Bool
+}
+
+// SlowClient describes a connection that has had messages dropped
+type SlowClient struct {
+	ConnectionID uuid.UUID `json:"connection_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Dropped      int64     `json:"dropped"`
+	Buffered     int       `json:"buffered"`
 }
 
 // PendingMessage represents a message awaiting acknowledgment
@@ -81,6 +130,16 @@ type Manager struct {
 
 	// ACK timeout duration
 	ackTimeout time.Duration
+
+	// Outbound buffering per connection, and what to do when a client falls behind
+	sendBuffer       int
+	slowClientPolicy SlowClientPolicy
+	droppedMessages  atomic.Int64
+	slowDisconnects  atomic.Int64
+
+	// Called when a user's first connection opens, and when their last one closes
+	connectHooks    []func(userID uuid.UUID)
+	disconnectHooks []func(userID uuid.UUID)
 }
 
 // BroadcastMessage represents a message to be broadcast to specific users
@@ -93,14 +152,16 @@ type BroadcastMessage struct {
 func NewManager() *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 	m := &Manager{
-		connections:     make(map[uuid.UUID][]*Connection),
-		register:        make(chan *Connection, 256),
-		unregister:      make(chan *Connection, 256),
-		broadcast:       make(chan *BroadcastMessage, 1024),
-		ctx:             ctx,
-		cancel:          cancel,
-		pendingMessages: make(map[string]*PendingMessage),
-		ackTimeout:      5 * time.Second,
+		connections:      make(map[uuid.UUID][]*Connection),
+		register:         make(chan *Connection, 256),
+		unregister:       make(chan *Connection, 256),
+		broadcast:        make(chan *BroadcastMessage, 1024),
+		ctx:              ctx,
+		cancel:           cancel,
+		pendingMessages:  make(map[string]*PendingMessage),
+		ackTimeout:       5 * time.Second,
+		sendBuffer:       defaultSendBuffer,
+		slowClientPolicy: SlowClientResync,
 	}
 
 	// Start retry goroutine for pending messages
@@ -132,13 +193,45 @@ func (m *Manager) Run() {
 	}
 }
 
+// SetSendBuffer sets how many outbound messages each new connection can have queued
+// before it counts as slow
+func (m *Manager) SetSendBuffer(size int) {
+	if size > 0 {
+		m.sendBuffer = size
+	}
+}
+
+// SetSlowClientPolicy sets what happens to a connection whose send buffer is full
+func (m *Manager) SetSlowClientPolicy(policy SlowClientPolicy) {
+	m.slowClientPolicy = policy
+}
+
+// OnConnect registers fn to be called, on its own goroutine, whenever a user who had
+// no connections to this instance opens one
+func (m *Manager) OnConnect(fn func(userID uuid.UUID)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connectHooks = append(m.connectHooks, fn)
+}
+
+// OnDisconnect registers fn to be called, on its own goroutine, whenever a user's
+// last connection closes. Used to clean up state that only makes sense while the
+// user is online, such as typing indicators.
+func (m *Manager) OnDisconnect(fn func(userID uuid.UUID)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.disconnectHooks = append(m.disconnectHooks, fn)
+}
+
 // Register adds a new connection
 func (m *Manager) Register(userID uuid.UUID, conn *websocket.Conn) *Connection {
 	connection := &Connection{
 		ID:      uuid.New(),
 		UserID:  userID,
 		Conn:    conn,
-		Send:    make(chan []byte, 256),
+		Send:    make(chan []byte, m.sendBuffer),
 		Manager: m,
 	}
 
@@ -188,12 +281,14 @@ func (m *Manager) BroadcastNotification(userID uuid.UUID, notification *models.N
 	m.BroadcastToUser(userID, messageBytes)
 }
 
-// BroadcastCountUpdate sends an unread count update to a user
-func (m *Manager) BroadcastCountUpdate(userID uuid.UUID, total int, categoryCounts map[string]int) {
+// BroadcastCountUpdate sends an unread count update to a user. unseen is the badge
+// count, total the unread count.
+func (m *Manager) BroadcastCountUpdate(userID uuid.UUID, total, unseen int, categoryCounts map[string]int) {
 	message := models.WSMessage{
 		Type: "count_update",
 		Data: map[string]interface{}{
 			"total":           total,
+			"unseen":          unseen,
 			"category_counts": categoryCounts,
 		},
 	}
@@ -260,6 +355,12 @@ func (m *Manager) handleRegister(conn *Connection) {
 	// Add new connection
 	m.connections[conn.UserID] = append(m.connections[conn.UserID], conn)
 	log.Printf("[WebSocket] User %s connected (id: %s), total connections: %d", conn.UserID, conn.ID, len(m.connections[conn.UserID]))
+
+	if len(m.connections[conn.UserID]) == 1 {
+		for _, hook := range m.connectHooks {
+			go hook(conn.UserID)
+		}
+	}
 }
 
 func (m *Manager) handleUnregister(conn *Connection) {
@@ -271,7 +372,7 @@ func (m *Manager) handleUnregister(conn *Connection) {
 		for i, c := range connections {
 			if c.ID == conn.ID {
 				// Close the connection's send channel
-				close(c.Send)
+				c.closeSend()
 
 				// Remove from slice
 				m.connections[conn.UserID] = append(connections[:i], connections[i+1:]...)
@@ -280,6 +381,10 @@ func (m *Manager) handleUnregister(conn *Connection) {
 				if len(m.connections[conn.UserID]) == 0 {
 					delete(m.connections, conn.UserID)
 					log.Printf("[WebSocket] User %s disconnected (no more connections)", conn.UserID)
+
+					for _, hook := range m.disconnectHooks {
+						go hook(conn.UserID)
+					}
 				} else {
 					log.Printf("[WebSocket] User %s connection closed (id: %s), remaining: %d", conn.UserID, conn.ID, len(m.connections[conn.UserID]))
 				}
@@ -296,19 +401,98 @@ func (m *Manager) handleBroadcast(message *BroadcastMessage) {
 	for _, userID := range message.UserIDs {
 		if connections, ok := m.connections[userID]; ok {
 			for _, conn := range connections {
-				select {
-				case conn.Send <- message.Message:
-					// Message sent successfully
-				default:
-					// Buffer full, close connection
-					log.Printf("[WebSocket] Connection buffer full for user %s, closing", userID)
-					go m.Unregister(conn)
-				}
+				m.deliver(conn, message.Message)
 			}
 		}
 	}
 }
 
+// deliver queues message for conn without ever blocking, so one client that isn't
+// reading can't hold up the others. If conn's buffer is full the message is dropped
+// and the slow client policy applied. Reports whether it was queued.
+func (m *Manager) deliver(conn *Connection, message []byte) bool {
+	if conn.enqueue(message) {
+		return true
+	}
+
+	conn.dropped.Add(1)
+	m.droppedMessages.Add(1)
+
+	if m.slowClientPolicy == SlowClientDisconnect {
+		if !conn.slow.Swap(true) {
+			m.slowDisconnects.Add(1)
+			log.Printf("[WebSocket] Connection buffer full for user %s (id: %s), closing", conn.UserID, conn.ID)
+			go m.Unregister(conn)
+		}
+		return false
+	}
+
+	if !conn.missed.Swap(true) {
+		log.Printf("[WebSocket] Connection buffer full for user %s (id: %s), dropping until it catches up", conn.UserID, conn.ID)
+	}
+	return false
+}
+
+// PushToUser queues data for each of a user's connections right away, instead of
+// through the broadcast loop like BroadcastToUser, so the caller learns whether it
+// got anywhere: ErrUserNotConnected, or ErrPushDropped if no connection took it.
+func (m *Manager) PushToUser(userID uuid.UUID, data interface{}) error {
+	message, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	connections := m.connections[userID]
+	if len(connections) == 0 {
+		return ErrUserNotConnected
+	}
+	queued := false
+	for _, conn := range connections {
+		if m.deliver(conn, message) {
+			queued = true
+		}
+	}
+	if !queued {
+		return ErrPushDropped
+	}
+	return nil
+}
+
+// DroppedMessages returns how many messages have been dropped for slow clients,
+// and how many connections were closed for being slow
+func (m *Manager) DroppedMessages() (dropped, disconnects int64) {
+	return m.droppedMessages.Load(), m.slowDisconnects.Load()
+}
+
+// SlowClients returns the connected clients that have had messages dropped, most
+// dropped first, at most limit of them
+func (m *Manager) SlowClients(limit int) []SlowClient {
+	m.mu.RLock()
+	var clients []SlowClient
+	for _, connections := range m.connections {
+		for _, conn := range connections {
+			if dropped := conn.dropped.Load(); dropped > 0 {
+				clients = append(clients, SlowClient{
+					ConnectionID: conn.ID,
+					UserID:       conn.UserID,
+					Dropped:      dropped,
+					Buffered:     len(conn.Send),
+				})
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Dropped > clients[j].Dropped })
+	if limit > 0 && len(clients) > limit {
+		clients = clients[:limit]
+	}
+	return clients
+}
+
 func (m *Manager) shutdown() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -388,6 +572,12 @@ func (conn *Connection) writePump() {
 				w.Write(<-conn.Send)
 			}
 
+			// The queue has drained; if anything was dropped meanwhile, say so
+			if conn.missed.Swap(false) {
+				w.Write([]byte{'\n'})
+				w.Write(conn.resyncMessage())
+			}
+
 			if err := w.Close(); err != nil {
 				return
 			}
@@ -397,6 +587,11 @@ func (conn *Connection) writePump() {
 			if err := conn.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+			if conn.missed.Swap(false) {
+				if err := conn.Conn.WriteMessage(websocket.TextMessage, conn.resyncMessage()); err != nil {
+					return
+				}
+			}
 		}
 	}
 }
@@ -414,7 +609,7 @@ func (conn *Connection) handleMessage(message []byte) {
 		// Respond with pong
 		pong := models.WSMessage{Type: "pong"}
 		if pongBytes, err := json.Marshal(pong); err == nil {
-			conn.Send <- pongBytes
+			conn.Manager.deliver(conn, pongBytes)
 		}
 
 	case "pong":
@@ -442,7 +637,7 @@ func (conn *Connection) SendMessage(message []byte) error {
 	conn.mu.Lock()
 	defer conn.mu.Unlock()
 
-	if conn.isClosed {
+	if conn.isClosed || conn.sendClosed {
 		return websocket.ErrCloseSent
 	}
 
@@ -454,6 +649,46 @@ func (conn *Connection) SendMessage(message []byte) error {
 	}
 }
 
+// enqueue adds message to the send buffer if there's room. It reports false only
+// when the buffer is full; a connection already unregistered takes nothing more
+// and isn't slow.
+func (conn *Connection) enqueue(message []byte) bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.sendClosed {
+		return true
+	}
+
+	select {
+	case conn.Send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend closes the send channel, once, so writePump says goodbye and exits
+func (conn *Connection) closeSend() {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if !conn.sendClosed {
+		conn.sendClosed = true
+		close(conn.Send)
+	}
+}
+
+// resyncMessage tells the client it missed messages and should refetch what it
+// has open
+func (conn *Connection) resyncMessage() []byte {
+	message, _ := json.Marshal(models.WSMessage{
+		Type: WSMessageTypeResync,
+		Data: map[string]int64{"dropped": conn.dropped.Load()},
+	})
+	return message
+}
+
 // =====================================================
 // ACK TRACKING & MESSAGING ENHANCEMENTS
 // =====================================================
@@ -562,6 +797,18 @@ func (m *Manager) retryUnacknowledged() {
 	}
 }
 
+// ConnectedUserIDs returns the users with at least one connection to this instance
+func (m *Manager) ConnectedUserIDs() []uuid.UUID {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userIDs := make([]uuid.UUID, 0, len(m.connections))
+	for userID := range m.connections {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
 // IsUserConnected checks if a user has any active connections
 func (m *Manager) IsUserConnected(userID uuid.UUID) bool {
 	m.mu.RLock()
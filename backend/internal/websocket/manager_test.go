@@ -0,0 +1,43 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// withConnection adds a connection for userID with a send buffer of size, not backed
+// by a socket, straight to the manager
+func withConnection(m *Manager, userID uuid.UUID, size int) *Connection {
+	conn := &Connection{ID: uuid.New(), UserID: userID, Send: make(chan []byte, size), Manager: m}
+	m.connections[userID] = append(m.connections[userID], conn)
+	return conn
+}
+
+func TestPushToUser(t *testing.T) {
+	m := NewManager()
+	defer m.Shutdown()
+
+	if err := m.PushToUser(uuid.New(), "hello"); err != ErrUserNotConnected {
+		t.Errorf("offline user: err = %v, want ErrUserNotConnected", err)
+	}
+
+	userID := uuid.New()
+	behind := withConnection(m, userID, 1)
+	behind.Send <- []byte("backlog")
+	if err := m.PushToUser(userID, "hello"); err != ErrPushDropped {
+		t.Errorf("full buffer: err = %v, want ErrPushDropped", err)
+	}
+
+	// One connection that keeps up is enough
+	keepingUp := withConnection(m, userID, 1)
+	if err := m.PushToUser(userID, "hello"); err != nil {
+		t.Errorf("err = %v, want the push queued", err)
+	}
+	if got := string(<-keepingUp.Send); got != `"hello"` {
+		t.Errorf("queued %s", got)
+	}
+	if dropped, _ := m.DroppedMessages(); dropped != 2 {
+		t.Errorf("dropped %d messages for the slow connection, want 2", dropped)
+	}
+}
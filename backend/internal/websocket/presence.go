@@ -0,0 +1,205 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"histeeria-backend/internal/cache"
+	"histeeria-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ============================================
+// PRESENCE ACROSS INSTANCES
+// ============================================
+
+// PresenceTracker keeps the shared presence store in step with the connections on
+// this instance. Users connected here are heartbeated into the store, so they stay
+// online everywhere while this instance runs and go offline on their own if it
+// stops. Presence changes are published to the people who can see them.
+type PresenceTracker struct {
+	presence   *cache.MessageCacheService
+	manager    *Manager
+	pubsub     *PubSubManager
+	instanceID string
+
+	// audience returns who should hear about a user going online or offline
+	audience func(ctx context.Context, userID uuid.UUID) []uuid.UUID
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewPresenceTracker creates a presence tracker for wsManager's connections. pubsub
+// may be nil, in which case changes only reach users connected to this instance.
+func NewPresenceTracker(presence *cache.MessageCacheService, wsManager *Manager, pubsub *PubSubManager) *PresenceTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	hostname, _ := os.Hostname()
+	t := &PresenceTracker{
+		presence:   presence,
+		manager:    wsManager,
+		pubsub:     pubsub,
+		instanceID: fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8]),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	wsManager.OnConnect(t.userConnected)
+	wsManager.OnDisconnect(t.userDisconnected)
+
+	return t
+}
+
+// SetAudience sets who is told when a user goes online or offline
+func (t *PresenceTracker) SetAudience(fn func(ctx context.Context, userID uuid.UUID) []uuid.UUID) {
+	t.audience = fn
+}
+
+// Start begins heartbeating this instance's users
+func (t *PresenceTracker) Start() {
+	log.Printf("[Presence] Tracking presence as instance %s", t.instanceID)
+	go t.heartbeat()
+}
+
+// Stop ends the heartbeat and takes this instance's users offline, rather than
+// leaving them to expire
+func (t *PresenceTracker) Stop() {
+	t.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, userID := range t.manager.ConnectedUserIDs() {
+		t.setOffline(ctx, userID)
+	}
+}
+
+// heartbeat refreshes every connected user's presence until the tracker stops
+func (t *PresenceTracker) heartbeat() {
+	ticker := time.NewTicker(cache.PresenceHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+			userIDs := t.manager.ConnectedUserIDs()
+			if len(userIDs) == 0 {
+				continue
+			}
+
+			t.refresh(userIDs)
+		}
+	}
+}
+
+// refresh heartbeats userIDs in batches, each with its own timeout, so one slow
+// or failed batch doesn't leave the users after it to expire
+func (t *PresenceTracker) refresh(userIDs []uuid.UUID) {
+	for start := 0; start < len(userIDs); start += cache.PresenceRefreshBatchSize {
+		batch := userIDs[start:min(start+cache.PresenceRefreshBatchSize, len(userIDs))]
+
+		ctx, cancel := context.WithTimeout(t.ctx, 5*time.Second)
+		if err := t.presence.RefreshPresence(ctx, t.instanceID, batch); err != nil {
+			log.Printf("[Presence] Failed to refresh presence of %d users: %v", len(batch), err)
+		}
+		cancel()
+	}
+}
+
+func (t *PresenceTracker) userConnected(userID uuid.UUID) {
+	// Hooks run on their own goroutines; the connection may already be gone
+	if !t.manager.IsUserConnected(userID) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, 5*time.Second)
+	defer cancel()
+
+	changed, err := t.presence.SetUserOnline(ctx, userID, t.instanceID)
+	if err != nil {
+		log.Printf("[Presence] Failed to set %s online: %v", userID, err)
+		return
+	}
+	if changed {
+		t.publish(ctx, userID, true)
+	}
+}
+
+func (t *PresenceTracker) userDisconnected(userID uuid.UUID) {
+	// Reconnected before the hook ran, or the tracker stopped and already took
+	// everyone offline
+	if t.manager.IsUserConnected(userID) || t.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, 5*time.Second)
+	defer cancel()
+
+	t.setOffline(ctx, userID)
+}
+
+func (t *PresenceTracker) setOffline(ctx context.Context, userID uuid.UUID) {
+	changed, err := t.presence.SetUserOffline(ctx, userID, t.instanceID)
+	if err != nil {
+		log.Printf("[Presence] Failed to set %s offline: %v", userID, err)
+		return
+	}
+	if changed {
+		t.publish(ctx, userID, false)
+	}
+}
+
+// publish tells userID's audience, on whichever instance they're connected to, that
+// userID went online or offline
+func (t *PresenceTracker) publish(ctx context.Context, userID uuid.UUID, online bool) {
+	if t.audience == nil {
+		return
+	}
+	recipients := t.audience(ctx, userID)
+	if len(recipients) == 0 {
+		return
+	}
+
+	now := time.Now()
+	msgType := models.WSMessageTypeOffline
+	if online {
+		msgType = models.WSMessageTypeOnline
+	}
+	envelope := models.WSMessageEnvelope{
+		ID:      uuid.NewString(),
+		Type:    msgType,
+		Channel: "presence",
+		Data: models.PresenceInfo{
+			UserID:   userID,
+			IsOnline: online,
+			LastSeen: &now,
+		},
+		Timestamp: now.Unix(),
+	}
+
+	if t.pubsub != nil && t.pubsub.IsEnabled() {
+		ids := make([]string, len(recipients))
+		for i, id := range recipients {
+			ids[i] = id.String()
+		}
+		if err := t.pubsub.PublishPresence(ids, envelope); err != nil {
+			log.Printf("[Presence] Failed to publish presence of %s: %v", userID, err)
+		}
+		return
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("[Presence] Failed to marshal presence of %s: %v", userID, err)
+		return
+	}
+	t.manager.BroadcastToUsers(recipients, payload)
+}
@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"histeeria-backend/internal/cache"
+
+	"github.com/google/uuid"
+)
+
+// flakyProvider fails the first presence batch written to it and counts the batches
+type flakyProvider struct {
+	*cache.MemoryProvider
+	batches int
+}
+
+func (p *flakyProvider) MHSet(ctx context.Context, hashes map[string]map[string]string, ttl time.Duration) error {
+	p.batches++
+	if p.batches == 1 {
+		return errors.New("timed out")
+	}
+	return p.MemoryProvider.MHSet(ctx, hashes, ttl)
+}
+
+func TestRefreshKeepsGoingPastAFailedBatch(t *testing.T) {
+	provider := &flakyProvider{MemoryProvider: cache.NewMemoryProvider()}
+	presence := cache.NewMessageCacheService(provider)
+	tracker := &PresenceTracker{presence: presence, instanceID: "instance-1", ctx: context.Background()}
+
+	userIDs := make([]uuid.UUID, 2*cache.PresenceRefreshBatchSize+1)
+	for i := range userIDs {
+		userIDs[i] = uuid.New()
+	}
+	tracker.refresh(userIDs)
+
+	if provider.batches != 3 {
+		t.Fatalf("wrote %d batches, want 3", provider.batches)
+	}
+	for i, userID := range userIDs {
+		online, _, err := presence.GetUserPresence(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("GetUserPresence: %v", err)
+		}
+		if want := i >= cache.PresenceRefreshBatchSize; online != want {
+			t.Fatalf("user %d online = %v, want %v", i, online, want)
+		}
+	}
+}
@@ -118,6 +118,8 @@ func (ps *PubSubManager) handleMessage(msg cache.Message) {
 		ps.broadcastToUsers(pubsubMsg.UserIDs, pubsubMsg.Payload)
 	case "notification":
 		ps.broadcastNotification(pubsubMsg.UserIDs, pubsubMsg.Payload)
+	case "presence":
+		ps.broadcastToUsers(pubsubMsg.UserIDs, pubsubMsg.Payload)
 	default:
 		log.Printf("[PubSub] Unknown message type: %s", pubsubMsg.Type)
 	}
@@ -139,13 +141,7 @@ func (ps *PubSubManager) broadcastToUsers(userIDStrs []string, payload json.RawM
 		// Check if user is connected to this instance
 		if connections, ok := ps.manager.connections[userID]; ok {
 			for _, conn := range connections {
-				select {
-				case conn.Send <- payload:
-					// Message sent
-				default:
-					// Buffer full, log warning
-					log.Printf("[PubSub] Buffer full for user %s", userID)
-				}
+				ps.manager.deliver(conn, payload)
 			}
 		}
 	}
@@ -159,36 +155,24 @@ func (ps *PubSubManager) broadcastNotification(userIDStrs []string, payload json
 
 // PublishBroadcast publishes a broadcast message to all instances
 func (ps *PubSubManager) PublishBroadcast(userIDStrs []string, payload interface{}) error {
-	if !ps.enabled {
-		// Fallback to local broadcast
-		return nil
-	}
-
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	msg := PubSubMessage{
-		Type:    "broadcast",
-		UserIDs: userIDStrs,
-		Payload: payloadBytes,
-	}
-
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		return err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-
-	return ps.provider.Publish(ctx, "ws:broadcast", string(msgBytes))
+	return ps.publish("ws:broadcast", "broadcast", userIDStrs, payload)
 }
 
 // PublishNotification publishes a notification to all instances
 func (ps *PubSubManager) PublishNotification(userIDStrs []string, payload interface{}) error {
+	return ps.publish("ws:notification", "notification", userIDStrs, payload)
+}
+
+// PublishPresence publishes a user going online or offline to all instances, which
+// pass it on to whichever of userIDStrs are connected to them
+func (ps *PubSubManager) PublishPresence(userIDStrs []string, payload interface{}) error {
+	return ps.publish("ws:broadcast", "presence", userIDStrs, payload)
+}
+
+// publish sends payload for userIDStrs to every instance listening on channel
+func (ps *PubSubManager) publish(channel, msgType string, userIDStrs []string, payload interface{}) error {
 	if !ps.enabled {
+		// Fallback to local broadcast
 		return nil
 	}
 
@@ -198,7 +182,7 @@ func (ps *PubSubManager) PublishNotification(userIDStrs []string, payload interf
 	}
 
 	msg := PubSubMessage{
-		Type:    "notification",
+		Type:    msgType,
 		UserIDs: userIDStrs,
 		Payload: payloadBytes,
 	}
@@ -211,7 +195,7 @@ func (ps *PubSubManager) PublishNotification(userIDStrs []string, payload interf
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	return ps.provider.Publish(ctx, "ws:notification", string(msgBytes))
+	return ps.provider.Publish(ctx, channel, string(msgBytes))
 }
 
 // IsEnabled returns whether Pub/Sub is enabled
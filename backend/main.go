@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -16,7 +19,10 @@ import (
 	"histeeria-backend/internal/cache"
 	"histeeria-backend/internal/config"
 	"histeeria-backend/internal/jobs"
+	"histeeria-backend/internal/learning"
 	"histeeria-backend/internal/messaging"
+	"histeeria-backend/internal/metrics"
+	"histeeria-backend/internal/models"
 	"histeeria-backend/internal/notifications"
 	"histeeria-backend/internal/posts"
 	"histeeria-backend/internal/queue"
@@ -129,23 +135,8 @@ func main() {
 	// Only initialize if Supabase URL is configured
 	var legacyStorageSvc *utils.StorageService
 	if cfg.Database.SupabaseURL != "" {
-		// Validate Supabase URL format
-		if !strings.HasPrefix(cfg.Database.SupabaseURL, "http://") && !strings.HasPrefix(cfg.Database.SupabaseURL, "https://") {
-			log.Printf("[Storage] WARNING: SUPABASE_URL doesn't start with http/https: %s", cfg.Database.SupabaseURL)
-			log.Printf("[Storage] Attempting to auto-correct...")
-			if strings.Contains(cfg.Database.SupabaseURL, ".supabase.co") {
-				cfg.Database.SupabaseURL = "https://" + cfg.Database.SupabaseURL
-				log.Printf("[Storage] Auto-corrected to: %s", cfg.Database.SupabaseURL)
-			} else {
-				log.Printf("[Storage] WARNING: SUPABASE_URL format is invalid. Expected format: https://xxx.supabase.co")
-				log.Printf("[Storage] Skipping legacy storage service initialization")
-				legacyStorageSvc = nil
-			}
-		}
-		if legacyStorageSvc == nil && cfg.Database.SupabaseURL != "" {
-			log.Printf("[Storage] Initializing legacy storage service with Supabase URL: %s", cfg.Database.SupabaseURL)
-			legacyStorageSvc = utils.NewStorageService(&cfg.Storage, cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey)
-		}
+		log.Printf("[Storage] Initializing legacy storage service with Supabase URL: %s", cfg.Database.SupabaseURL)
+		legacyStorageSvc = utils.NewStorageService(&cfg.Storage, cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey)
 	} else {
 		log.Println("[Storage] SUPABASE_URL not configured - using primary storage service only")
 	}
@@ -204,13 +195,16 @@ func main() {
 	// Status repository
 	statusRepo := repository.NewSupabaseStatusRepository(cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey)
 
+	// Course repository
+	courseRepo := repository.NewSupabaseCourseRepository(cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey)
+
 	log.Println("[Repositories] All repositories initialized")
 
 	// ============================================
 	// 5. INITIALIZE CORE SERVICES
 	// ============================================
 	emailSvc := utils.NewEmailService(&cfg.Email)
-	jwtSvc := utils.NewJWTService(cfg.JWT.Secret, 30*24*time.Hour) // 30 days validity
+	jwtSvc := utils.NewJWTService(cfg.JWT.Secret, cfg.JWT.AccessTTL())
 	tokenBlacklist := utils.NewTokenBlacklist()
 	jwtSvc.SetBlacklist(tokenBlacklist)
 
@@ -230,6 +224,10 @@ func main() {
 	// Legacy rate limiter for backward compatibility
 	legacyRateLimiter := utils.NewRateLimiter(cfg.RateLimit.Forgiveness)
 
+	// Probation for new accounts: lower limits, no links, no bulk operations
+	newAccountRules := cfg.NewAccount.Restrictions()
+	newAccountGuard := auth.NewNewAccountGuard(newAccountRules, userRepo, hybridRateLimiter, cacheProvider)
+
 	// Auth service (queue provider will be passed after queue initialization)
 	// We'll initialize this after the queue is set up
 	var authSvc *auth.AuthService
@@ -238,6 +236,8 @@ func main() {
 	googleOAuth := auth.NewGoogleOAuthService(&cfg.Google, userRepo, jwtSvc)
 	githubOAuth := auth.NewGitHubOAuthService(&cfg.GitHub, userRepo, jwtSvc)
 	linkedinOAuth := auth.NewLinkedInOAuthService(&cfg.LinkedIn, userRepo, jwtSvc)
+	appleOAuth := auth.NewAppleOAuthService(&cfg.Apple, userRepo, jwtSvc)
+	twitterOAuth := auth.NewTwitterOAuthService(&cfg.Twitter, userRepo, jwtSvc)
 
 	// Account services
 	accountSvc := account.NewAccountService(userRepo, sessionRepo, emailSvc, legacyStorageSvc)
@@ -252,6 +252,8 @@ func main() {
 	// 6. INITIALIZE WEBSOCKET MANAGER WITH PUB/SUB
 	// ============================================
 	wsManager := websocket.NewManager()
+	wsManager.SetSendBuffer(cfg.WebSocket.SendBuffer)
+	wsManager.SetSlowClientPolicy(websocket.SlowClientPolicy(cfg.WebSocket.SlowClient))
 	go wsManager.Run()
 
 	// Initialize Redis Pub/Sub for multi-instance WebSocket scaling
@@ -282,11 +284,14 @@ func main() {
 	// ============================================
 	relationshipSvc := social.NewRelationshipService(relationshipRepo, userRepo)
 	relationshipSvc.SetNotificationService(notificationSvc)
+	relationshipSvc.SetNewAccountRestrictions(newAccountRules)
 
 	// ============================================
 	// 9. INITIALIZE SEARCH SERVICE
 	// ============================================
 	searchSvc := search.NewSearchService(userRepo, postRepo)
+	searchSvc.SetCourseRepository(courseRepo)
+	searchSvc.SetCacheProvider(cacheProvider)
 
 	// ============================================
 	// 10. INITIALIZE MESSAGING SYSTEM
@@ -305,7 +310,17 @@ func main() {
 	deliverySvc := messaging.NewDeliveryService(deliveryRepoAdapter, wsManager)
 
 	mediaOptimizer := messaging.NewMediaOptimizer()
+	mediaOptimizer.SetMetadataStripping(strings.Split(cfg.Storage.StripMetadataTypes, ","))
 	messagingSvc := messaging.NewMessagingService(messageRepo, messageCacheSvc, wsManager, userRepo, notificationSvc)
+	messagingSvc.SetMaxPinnedMessages(cfg.Pins.MaxPerConversation)
+	messagingSvc.SetAttachmentStorage(legacyStorageSvc)
+	messagingSvc.SetDeliveryService(deliverySvc)
+	wsManager.OnDisconnect(messagingSvc.StopAllTyping)
+
+	// Presence lives in the cache so that every instance sees users connected to the others
+	presenceTracker := websocket.NewPresenceTracker(messageCacheSvc, wsManager, wsPubSub)
+	presenceTracker.SetAudience(messagingSvc.PresenceAudience)
+	presenceTracker.Start()
 	messageHandlers := messaging.NewMessageHandlers(messagingSvc, deliverySvc, legacyStorageSvc, mediaOptimizer)
 
 	log.Println("[Messaging] Messaging system initialized (DeliveryService ready)")
@@ -321,23 +336,69 @@ func main() {
 	// 12. INITIALIZE POSTS & FEED SYSTEM
 	// ============================================
 	postSvc := posts.NewService(postRepo, pollRepo, articleRepo, commentRepo, userRepo, wsManager)
+	postSvc.SetMaxPinnedPosts(cfg.Pins.MaxPerProfile)
+	postSvc.SetMaxCommentDepth(cfg.Comments.MaxDepth)
+	postSvc.SetNewAccountRestrictions(newAccountRules)
+	postSvc.SetRelationshipRepository(relationshipRepo)
 	feedSvc := posts.NewFeedServiceWithCache(postRepo, relationshipRepo, feedCacheSvc)
+	feedSvc.SetUserRepository(userRepo)
+	feedSvc.SetDiversityRules(posts.FeedDiversityRules{
+		MaxConsecutivePerAuthor: cfg.Feed.MaxConsecutivePerAuthor,
+		MaxPerAuthorPerPage:     cfg.Feed.MaxPerAuthorPerPage,
+		CandidateMultiplier:     cfg.Feed.CandidateMultiplier,
+	})
+	feedSvc.SetRanker(posts.NewFeedRanker(rankingWeights(cfg.Feed.Ranking), &posts.RankingExperiment{
+		Name:    cfg.Feed.Experiment.Name,
+		Percent: cfg.Feed.Experiment.Percent,
+		Weights: rankingWeights(cfg.Feed.Experiment.Weights),
+	}))
+	searchSvc.SetFeedService(feedSvc)
+
+	// Blocking someone drops both users' cached feeds and conversation lists
+	accountSvc.SetFeedInvalidator(feedSvc)
+	accountSvc.SetConversationInvalidator(messagingSvc)
+	// Changing feed settings drops the cached copy every feed request reads
+	profileSvc.SetFeedInvalidator(feedSvc)
+
 	postHandlers := posts.NewHandlers(postSvc, feedSvc, legacyStorageSvc, mediaOptimizer)
+	postSvc.SetStorageQuota(legacyStorageSvc)
+	if storageService != nil {
+		postHandlers.SetObjectStorage(storageService)
+		postSvc.SetObjectStorage(storageService)
+	}
 
 	log.Println("[Posts] Post & feed system initialized (caching:", feedCacheSvc.IsEnabled(), ")")
 
 	// ============================================
 	// 13. INITIALIZE STATUS SYSTEM
 	// ============================================
-	statusSvc := status.NewService(statusRepo)
+	statusSvc := status.NewService(statusRepo, userRepo)
+	statusSvc.SetRelationshipRepository(relationshipRepo)
+	statusSvc.SetDailyLimit(status.DailyLimit{
+		MaxPerDay:      cfg.Status.MaxPerDay,
+		ExemptVerified: cfg.Status.ExemptVerified,
+	})
+	statusSvc.SetLifetime(time.Duration(cfg.Status.LifetimeHours) * time.Hour)
+	statusSvc.SetMediaStorage(legacyStorageSvc)
 	statusHandlers := status.NewHandlers(statusSvc, legacyStorageSvc, mediaOptimizer)
 
 	log.Println("[Statuses] Status system initialized")
 
+	// Learning platform (lesson video playback)
+	learningSvc := learning.NewService(courseRepo, userRepo, storageService)
+	learningHandlers := learning.NewHandlers(learningSvc)
+	courseRepo.OnCertificateIssued(learningSvc.RenderCertificateInBackground)
+
 	// ============================================
 	// 14. INITIALIZE BACKGROUND JOB SCHEDULER
 	// ============================================
 	jobScheduler := jobs.NewJobScheduler()
+	// Instances sharing Redis share the schedule too: each job runs on one of them at a time
+	if redisConnected {
+		if rp, ok := cacheProvider.(*cache.RedisProvider); ok {
+			jobScheduler.SetLock(jobs.NewRedisJobLock(rp.GetClient()))
+		}
+	}
 
 	// Create job factory and register common jobs
 	// All services are now ready and wired
@@ -348,7 +409,54 @@ func main() {
 		feedCacheSvc,     // feedCacheSvc (used for cache warming)
 		deliverySvc,      // deliveryService (used for WhatsApp-style cleanup)
 	)
+	jobFactory.SetMessagingService(messagingSvc)
+	jobFactory.SetPostService(postSvc)
+	jobFactory.SetStatusService(statusSvc)
+	jobFactory.SetPostPurge(time.Duration(cfg.Posts.PurgeRetentionDays)*24*time.Hour, cfg.Posts.PurgeBatchSize)
 	jobFactory.RegisterCommonJobs(jobScheduler)
+	if err := jobScheduler.RegisterJob(jobs.CreateSupabaseAlertJob(metrics.Supabase.CheckAlerts)); err != nil {
+		log.Printf("[Jobs] Failed to register Supabase alert job: %v", err)
+	}
+	trendingJob := jobs.CreateTrendingHashtagsJob(func(ctx context.Context) (int, error) {
+		return postRepo.RecomputeTrendingHashtags(ctx,
+			time.Duration(cfg.Feed.TrendingHalfLifeHours)*time.Hour,
+			time.Duration(cfg.Feed.TrendingWindowHours)*time.Hour)
+	})
+	if err := jobScheduler.RegisterJob(trendingJob); err != nil {
+		log.Printf("[Jobs] Failed to register trending hashtags job: %v", err)
+	}
+	if err := jobScheduler.RegisterJob(jobs.CreatePostViewFlushJob(postRepo.FlushPostViews)); err != nil {
+		log.Printf("[Jobs] Failed to register post view flush job: %v", err)
+	}
+	if err := jobScheduler.RegisterJob(jobs.CreateLikeCountReconciliationJob(postRepo.ReconcileLikeCounts)); err != nil {
+		log.Printf("[Jobs] Failed to register like count reconciliation job: %v", err)
+	}
+	if err := jobScheduler.RegisterJob(jobs.CreateCourseRatingReconciliationJob(courseRepo.ReconcileRatings)); err != nil {
+		log.Printf("[Jobs] Failed to register course rating reconciliation job: %v", err)
+	}
+	if err := jobScheduler.RegisterJob(jobs.CreateCourseCounterReconciliationJob(courseRepo.ReconcileEnrollmentCounts)); err != nil {
+		log.Printf("[Jobs] Failed to register course counter reconciliation job: %v", err)
+	}
+	// notificationSvc is only created further down; the job first runs an hour from now
+	digestJob := jobs.CreateNotificationDigestJob(func(ctx context.Context) (int, error) {
+		return notificationSvc.SendDigests(ctx)
+	})
+	if err := jobScheduler.RegisterJob(digestJob); err != nil {
+		log.Printf("[Jobs] Failed to register notification digest job: %v", err)
+	}
+	if storageService != nil && storageService.Fallback() != nil {
+		if err := jobScheduler.RegisterJob(jobs.CreateStorageProbeJob(storageService.ProbePrimary)); err != nil {
+			log.Printf("[Jobs] Failed to register storage probe job: %v", err)
+		}
+	}
+	if legacyStorageSvc != nil && legacyStorageSvc.QuotaEnabled() {
+		reconcileJob := jobs.CreateStorageReconciliationJob(func(ctx context.Context) error {
+			return legacyStorageSvc.ReconcileUsage(ctx, storageService)
+		})
+		if err := jobScheduler.RegisterJob(reconcileJob); err != nil {
+			log.Printf("[Jobs] Failed to register storage reconciliation job: %v", err)
+		}
+	}
 
 	// Start job scheduler
 	jobScheduler.Start()
@@ -360,6 +468,8 @@ func main() {
 	// ============================================
 	var queueProvider queue.QueueProvider
 	var emailWorker *queue.EmailWorker
+	var pushWorker *queue.PushWorker
+	var deliveryWorker *queue.MessageDeliveryWorker
 
 	if redisConnected {
 		if rp, ok := cacheProvider.(*cache.RedisProvider); ok {
@@ -379,6 +489,11 @@ func main() {
 	emailWorker = queue.NewEmailWorker(queueProvider, &emailSenderAdapter{emailSvc}, 2)
 	emailWorker.Start()
 
+	// Retries chat message deliveries that failed, with backoff
+	deliverySvc.SetRetryQueue(queueProvider)
+	deliveryWorker = queue.NewMessageDeliveryWorker(queueProvider, deliverySvc, 2)
+	deliveryWorker.Start()
+
 	log.Println("[Queue] Message queue system initialized")
 
 	// ============================================
@@ -388,6 +503,14 @@ func main() {
 	authSvc = auth.NewAuthService(userRepo, emailSvc, queueProvider, jwtSvc, tokenBlacklist)
 	// Set cache provider for auth service OTP caching (if available)
 	authSvc.SetCacheProvider(cacheProvider)
+	authSvc.SetSessions(sessionRepo, cfg.JWT.RefreshTTL())
+	authSvc.SetLoginLockout(cfg.Lockout.MaxFailures, cfg.Lockout.WindowTTL(), cfg.Lockout.LockTTL())
+	twoFactorRepo := repository.NewSupabaseTwoFactorRepository(cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey)
+	authSvc.SetTwoFactor(twoFactorRepo, cfg.TwoFactor.Key(), cfg.TwoFactor.Issuer)
+	accountSvc.SetSessionRevoker(authSvc)
+	if cfg.TwoFactor.Key() == nil {
+		log.Println("[Auth] TWO_FACTOR_ENCRYPTION_KEY not set - 2FA setup is disabled")
+	}
 
 	// Initialize account group repository and multi-account service
 	accountGroupRepo := repository.NewSupabaseAccountGroupRepository(cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey)
@@ -395,15 +518,26 @@ func main() {
 
 	// Notification service requires queue provider for async emails
 	notificationSvc = notifications.NewNotificationService(notificationRepo, userRepo, wsManager, notificationEmailSvc, queueProvider)
+	notificationSvc.SetPush(repository.NewSupabaseDeviceTokenRepository(cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey))
+	// FCM serves Android and web; without it (and for iOS, until APNs) devices can
+	// register but pushes are dropped
+	if account, _ := cfg.Push.ServiceAccount(); account != nil {
+		notificationSvc.RegisterPushProvider(notifications.NewFCMProvider(account, models.PushPlatformAndroid, cfg.Email.FrontendURL))
+		notificationSvc.RegisterPushProvider(notifications.NewFCMProvider(account, models.PushPlatformWeb, cfg.Email.FrontendURL))
+		log.Printf("[Push] FCM enabled for project %s", account.ProjectID)
+	}
+	pushWorker = queue.NewPushWorker(queueProvider, notificationSvc, 2)
+	pushWorker.Start()
 
 	// Set notification service in services that were initialized before it
 	relationshipSvc.SetNotificationService(notificationSvc)
 	messagingSvc.SetNotificationService(notificationSvc)
+	postSvc.SetNotificationService(notificationSvc)
 
 	// ============================================
 	// 15. INITIALIZE HANDLERS
 	// ============================================
-	authHandlers := auth.NewAuthHandlers(authSvc, multiAccountSvc, jwtSvc, legacyRateLimiter, hybridRateLimiter, cfg, googleOAuth, githubOAuth, linkedinOAuth, sessionRepo)
+	authHandlers := auth.NewAuthHandlers(authSvc, multiAccountSvc, jwtSvc, legacyRateLimiter, hybridRateLimiter, cfg, googleOAuth, githubOAuth, linkedinOAuth, appleOAuth, twitterOAuth)
 	accountHandlers := account.NewAccountHandlers(accountSvc, profileSvc, advancedProfileSvc)
 	expEduHandlers := account.NewExperienceEducationHandlers(expEduSvc)
 	relationshipHandlers := social.NewRelationshipHandlers(relationshipSvc)
@@ -427,6 +561,77 @@ func main() {
 		},
 		Timeout: 5 * time.Second,
 	})
+	healthChecker.AddCheck(utils.HealthCheck{
+		Name: "supabase",
+		Check: func(ctx context.Context) error {
+			return repository.PingSupabase(ctx, cfg.Database.SupabaseURL, cfg.Database.SupabaseServiceKey)
+		},
+		Timeout: 5 * time.Second,
+	})
+	healthChecker.AddCheck(utils.HealthCheck{
+		Name:        "email",
+		Check:       emailSvc.Ping,
+		Timeout:     5 * time.Second,
+		NonCritical: true,
+	})
+	if storageService != nil {
+		healthChecker.AddCheck(utils.HealthCheck{
+			Name: "storage",
+			Check: func(ctx context.Context) error {
+				// Down only if nothing can serve files; losing the primary while the
+				// fallback works is degraded
+				primaryErr := storageService.Primary().HealthCheck(ctx)
+				if primaryErr == nil {
+					status := storageService.ProviderStatus()
+					if status.Circuit == storage.CircuitOpen {
+						return fmt.Errorf("%w: primary %s unavailable, serving from %s", utils.ErrDegraded, status.Primary, status.Active)
+					}
+					return nil
+				}
+				fallback := storageService.Fallback()
+				if fallback == nil {
+					return fmt.Errorf("primary %s: %w", storageService.Primary().GetProviderName(), primaryErr)
+				}
+				if err := fallback.HealthCheck(ctx); err != nil {
+					return fmt.Errorf("primary %s: %v; fallback %s: %w", storageService.Primary().GetProviderName(), primaryErr, fallback.GetProviderName(), err)
+				}
+				return fmt.Errorf("%w: primary %s failed its ping (%v), fallback %s is up", utils.ErrDegraded, storageService.Primary().GetProviderName(), primaryErr, fallback.GetProviderName())
+			},
+			Details: func() interface{} {
+				return storageService.ProviderStatus()
+			},
+			Timeout: 10 * time.Second,
+		})
+	}
+
+	// Metrics read from running components at scrape time; request, cache, job and
+	// Supabase metrics are recorded as they happen
+	metrics.Default.Register(healthChecker)
+	metrics.Default.RegisterGauge("histeeria_websocket_connections", "Open WebSocket connections on this instance", func() float64 {
+		return float64(wsManager.GetTotalConnections())
+	})
+	metrics.Default.RegisterGauge("histeeria_websocket_users", "Users with a WebSocket connection on this instance", func() float64 {
+		return float64(wsManager.GetConnectedUsers())
+	})
+	metrics.Default.RegisterCounter("histeeria_websocket_dropped_messages_total", "Messages dropped for WebSocket clients that couldn't keep up", func() float64 {
+		dropped, _ := wsManager.DroppedMessages()
+		return float64(dropped)
+	})
+	metrics.Default.RegisterGaugeVec("histeeria_queue_depth", "Jobs waiting to be dequeued", "queue", func() map[string]float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		depths := make(map[string]float64)
+		for _, name := range []string{queue.QueueEmail, queue.QueueMessageDelivery, queue.QueueNotification} {
+			queueStats, err := queueProvider.GetStats(ctx, name)
+			if err != nil {
+				log.Printf("[Metrics] Failed to get stats of queue %s: %v", name, err)
+				continue
+			}
+			depths[name] = float64(queueStats.Depth)
+		}
+		return depths
+	})
 
 	// ============================================
 	// 17. CREATE GIN ROUTER WITH MIDDLEWARE
@@ -437,9 +642,16 @@ func main() {
 	// 1. CRITICAL: Panic Recovery Middleware (MUST BE FIRST)
 	r.Use(utils.PanicRecoveryMiddleware())
 
+	// 1b. Request count, latency and response size by route
+	metrics.HTTP.SetThresholds(cfg.Metrics.LargeResponseBytes, cfg.Metrics.SlowRequestThreshold())
+	r.Use(utils.MetricsMiddleware())
+
 	// 2. Request ID Middleware
 	r.Use(utils.RequestIDMiddleware())
 
+	// 2b. Supabase 429s answer 503 + Retry-After instead of 500
+	r.Use(utils.UpstreamThrottleMiddleware())
+
 	// 3. Gin Logger (after panic recovery)
 	r.Use(gin.Logger())
 
@@ -525,6 +737,12 @@ func main() {
 	// Liveness probe (Kubernetes)
 	r.GET("/health/live", healthChecker.LivenessHandler())
 
+	// Prometheus metrics on the API port only when there's no separate listener, and
+	// then only for scrapers with the token
+	if cfg.Metrics.Enabled && cfg.Metrics.BindAddress == "" {
+		r.GET("/metrics", utils.BearerTokenMiddleware(cfg.Metrics.Token), gin.WrapH(metrics.Default.Handler()))
+	}
+
 	// Public configuration endpoint - returns Supabase storage URL for frontend
 	r.GET("/config/storage-url", func(c *gin.Context) {
 		supabaseURL := cfg.Database.SupabaseURL
@@ -557,6 +775,14 @@ func main() {
 	// ============================================
 	// 19. API ROUTES
 	// ============================================
+	// Cache-Control for public, viewer-independent responses (anonymous requests only)
+	publicCache := utils.PublicCacheMiddleware(utils.PublicCachePolicy{
+		Enabled:              cfg.HTTPCache.Enabled,
+		MaxAge:               time.Duration(cfg.HTTPCache.MaxAge) * time.Second,
+		SharedMaxAge:         time.Duration(cfg.HTTPCache.SharedMaxAge) * time.Second,
+		StaleWhileRevalidate: time.Duration(cfg.HTTPCache.StaleWhileRevalidate) * time.Second,
+	})
+
 	api := r.Group("/api/v1")
 	{
 		api.GET("/status", func(c *gin.Context) {
@@ -610,9 +836,92 @@ func main() {
 				if emailWorker != nil {
 					stats["email_worker"] = emailWorker.GetStats()
 				}
+				if pushWorker != nil {
+					stats["push_worker"] = pushWorker.GetStats()
+				}
+
+				// Depth, throughput and time in queue of each queue that has a worker
+				queues := make(map[string]interface{})
+				for _, name := range []string{queue.QueueEmail, queue.QueueMessageDelivery, queue.QueueNotification} {
+					queueStats, err := queueProvider.GetStats(c.Request.Context(), name)
+					if err != nil {
+						queues[name] = gin.H{"error": err.Error()}
+						continue
+					}
+					queues[name] = queueStats
+				}
+				stats["queues"] = queues
+
 				c.JSON(http.StatusOK, stats)
 			})
 
+			// Dead letter queue endpoints (debug only). ?queue= takes a queue name with or
+			// without its "queue:" prefix and defaults to the email queue.
+			dlqName := func(c *gin.Context) string {
+				name := c.DefaultQuery("queue", queue.QueueEmail)
+				if !strings.HasPrefix(name, "queue:") {
+					name = "queue:" + name
+				}
+				return name
+			}
+			api.GET("/queue/dlq", func(c *gin.Context) {
+				queueName := dlqName(c)
+				limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+				if err != nil || limit <= 0 || limit > 500 {
+					limit = 50
+				}
+				deadLetters, err := queueProvider.ListDeadLetters(c.Request.Context(), queueName, limit)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+					return
+				}
+				total, _ := queueProvider.GetFailedCount(c.Request.Context(), queueName)
+				c.JSON(http.StatusOK, gin.H{
+					"queue":        queueName,
+					"total":        total,
+					"dead_letters": deadLetters,
+				})
+			})
+			api.POST("/queue/dlq/:id/requeue", func(c *gin.Context) {
+				err := queueProvider.RequeueDeadLetter(c.Request.Context(), dlqName(c), c.Param("id"))
+				if errors.Is(err, queue.ErrDeadLetterNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Dead letter not found"})
+					return
+				}
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"success": true, "message": "Job requeued"})
+			})
+
+			// Transactional email previews (debug only): the list of templates, and one
+			// rendered with sample data. ?lang= picks the language, ?format=text shows the
+			// plain-text part.
+			api.GET("/emails/preview", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{
+					"templates": utils.EmailTemplateNames(),
+					"languages": utils.EmailLanguages(),
+				})
+			})
+			api.GET("/emails/preview/:template", func(c *gin.Context) {
+				name := c.Param("template")
+				if !slices.Contains(utils.EmailTemplateNames(), name) {
+					c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Unknown email template"})
+					return
+				}
+				rendered, err := emailSvc.RenderEmail(name, c.Query("lang"), utils.EmailPreviewData(name))
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+					return
+				}
+				if c.Query("format") == "text" {
+					c.String(http.StatusOK, "Subject: %s\n\n%s", rendered.Subject, rendered.Text)
+					return
+				}
+				c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+			})
+
 			// Detailed health endpoint (debug only)
 			api.GET("/health/details", healthChecker.HealthHandler())
 		}
@@ -622,7 +931,7 @@ func main() {
 
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(auth.JWTAuthMiddleware(jwtSvc))
+		protected.Use(auth.JWTAuthMiddleware(jwtSvc), utils.NoStoreMiddleware())
 
 		// Account management
 		accountHandlers.SetupRoutes(protected)
@@ -640,6 +949,11 @@ func main() {
 
 		// Relationships
 		relationshipHandlers.SetupRoutes(protected)
+		// Contact matching is capped per request and rate limited to prevent enumeration
+		protected.POST("/relationships/find-by-contacts",
+			newAccountGuard.BlockBulk(),
+			auth.EndpointRateLimitMiddleware(hybridRateLimiter, "find-by-contacts", 10, time.Hour),
+			relationshipHandlers.FindByContacts)
 
 		// Notifications
 		notificationHandlers.SetupRoutes(protected)
@@ -653,14 +967,27 @@ func main() {
 			// Message sending with rate limiting (60 messages per minute)
 			messagingGroup.POST("/:id/messages",
 				auth.MessageRateLimitMiddleware(hybridRateLimiter),
+				newAccountGuard.Limit("message", cfg.NewAccount.MessageLimit, time.Minute),
 				messageHandlers.SendMessage)
+			messagingGroup.GET("/:id/scheduled", messageHandlers.GetScheduledMessages)
 			messagingGroup.PATCH("/:id/read", messageHandlers.MarkAsRead)
 			messagingGroup.GET("/:id/pinned", messageHandlers.GetPinnedMessages)
 			messagingGroup.GET("/:id/search", messageHandlers.SearchConversationMessages)
 			messagingGroup.POST("/:id/typing/start", messageHandlers.StartTyping)
 			messagingGroup.POST("/:id/typing/stop", messageHandlers.StopTyping)
 			messagingGroup.GET("/:id", messageHandlers.GetConversation)
-			messagingGroup.POST("/start/:userId", messageHandlers.StartConversation)
+			messagingGroup.POST("/start/:userId",
+				newAccountGuard.Limit("conversation", cfg.NewAccount.ConversationLimit, time.Hour),
+				messageHandlers.StartConversation)
+
+			// Group conversations; members leave by removing themselves
+			messagingGroup.POST("/group",
+				newAccountGuard.Limit("conversation", cfg.NewAccount.ConversationLimit, time.Hour),
+				messageHandlers.CreateGroup)
+			messagingGroup.PATCH("/:id", messageHandlers.RenameGroup)
+			messagingGroup.PUT("/:id/disappearing", messageHandlers.SetDisappearingMessages)
+			messagingGroup.POST("/:id/members", messageHandlers.AddGroupMembers)
+			messagingGroup.DELETE("/:id/members/:userId", messageHandlers.RemoveGroupMember)
 
 			// Delivery tracking endpoints (WhatsApp-style - batch)
 			messagingGroup.POST("/:id/mark-delivered", messageHandlers.MarkConversationDelivered)
@@ -674,6 +1001,8 @@ func main() {
 		{
 			messageGroup.GET("/search", messageHandlers.SearchMessages)
 			messageGroup.GET("/starred", messageHandlers.GetStarredMessages)
+			messageGroup.PATCH("/scheduled/:id", messageHandlers.EditScheduledMessage)
+			messageGroup.DELETE("/scheduled/:id", messageHandlers.CancelScheduledMessage)
 			messageGroup.DELETE("/:id", messageHandlers.DeleteMessage)
 			messageGroup.POST("/:id/reactions", messageHandlers.AddReaction)
 			messageGroup.DELETE("/:id/reactions", messageHandlers.RemoveReaction)
@@ -683,7 +1012,10 @@ func main() {
 			messageGroup.DELETE("/:id/pin", messageHandlers.UnpinMessage)
 			messageGroup.PATCH("/:id", messageHandlers.EditMessage)
 			messageGroup.GET("/:id/edit-history", messageHandlers.GetMessageEditHistory)
-			messageGroup.POST("/:id/forward", messageHandlers.ForwardMessage)
+			messageGroup.GET("/:id/receipts", messageHandlers.GetMessageReceipts)
+			messageGroup.POST("/:id/forward",
+				newAccountGuard.Limit("message", cfg.NewAccount.MessageLimit, time.Minute),
+				messageHandlers.ForwardMessage)
 			messageGroup.POST("/upload-image", messageHandlers.UploadImage)
 			messageGroup.POST("/upload-audio", messageHandlers.UploadAudio)
 			messageGroup.POST("/upload-file", messageHandlers.UploadFile)
@@ -702,26 +1034,55 @@ func main() {
 		{
 			presenceGroup.GET("/:id/presence", messageHandlers.GetUserPresence)
 			presenceGroup.GET("/presence/bulk", messageHandlers.GetBulkPresence)
+			presenceGroup.GET("/:id/mutual-followers", relationshipHandlers.GetMutualFollowers)
 		}
 
-		// Profiles & Posts (public with optional auth)
-		api.GET("/profile/:username", auth.OptionalJWTAuthMiddleware(jwtSvc), accountHandlers.GetPublicProfile)
-		api.GET("/posts/user/:username", auth.OptionalJWTAuthMiddleware(jwtSvc), postHandlers.GetUserPosts)
+		// Follower lists (public with optional auth; private accounts' lists need an approved follow)
+		api.GET("/users/:id/followers", auth.OptionalJWTAuthMiddleware(jwtSvc), relationshipHandlers.GetUserFollows)
+		api.GET("/users/:id/following", auth.OptionalJWTAuthMiddleware(jwtSvc), relationshipHandlers.GetUserFollows)
 
-		// Search (public)
-		searchHandlers.SetupRoutes(api)
+		// Profiles & Posts (public with optional auth)
+		api.GET("/profile/:username", auth.OptionalJWTAuthMiddleware(jwtSvc), publicCache, accountHandlers.GetPublicProfile)
+		api.GET("/posts/user/:username", auth.OptionalJWTAuthMiddleware(jwtSvc), publicCache, postHandlers.GetUserPosts)
+
+		// Lesson video playback (preview lessons are public, others require enrollment)
+		api.GET("/lessons/:id/video-url", auth.OptionalJWTAuthMiddleware(jwtSvc), utils.NoStoreMiddleware(), learningHandlers.GetLessonVideoURL)
+
+		// Courses
+		protected.GET("/courses/:id/certificate", utils.NoStoreMiddleware(), learningHandlers.GetCourseCertificate)
+		protected.GET("/courses/wishlist", utils.NoStoreMiddleware(), learningHandlers.GetWishlist)
+		protected.POST("/courses/:id/wishlist", learningHandlers.AddToWishlist)
+		protected.DELETE("/courses/:id/wishlist", learningHandlers.RemoveFromWishlist)
+		protected.POST("/courses/:id/enroll", learningHandlers.Enroll)
+		protected.DELETE("/courses/:id/enroll", learningHandlers.Unenroll)
+		protected.GET("/courses/:id/analytics", utils.NoStoreMiddleware(), learningHandlers.GetCourseAnalytics)
+		protected.POST("/courses/:id/coupons", learningHandlers.CreateCourseCoupon)
+		protected.GET("/courses/:id/coupons/:code", utils.NoStoreMiddleware(), learningHandlers.ValidateCourseCoupon)
+
+		// Lesson quizzes
+		protected.POST("/lessons/:id/quiz", learningHandlers.CreateLessonQuiz)
+		protected.GET("/lessons/:id/quiz", utils.NoStoreMiddleware(), learningHandlers.GetLessonQuiz)
+		protected.POST("/lessons/:id/quiz/attempts", learningHandlers.SubmitLessonQuiz)
+		protected.GET("/lessons/:id/quiz/attempts", utils.NoStoreMiddleware(), learningHandlers.GetLessonQuizAttempts)
+
+		// Search (public; signed-in searchers get block and privacy filtering)
+		searchHandlers.SetupRoutes(api.Group("", auth.OptionalJWTAuthMiddleware(jwtSvc)))
 
 		// Posts & Feed
-		api.GET("/articles/:slug", postHandlers.GetArticleBySlug) // Public
+		api.GET("/articles/:slug", auth.OptionalJWTAuthMiddleware(jwtSvc), publicCache, postHandlers.GetArticleBySlug) // Public
 
 		postsGroup := protected.Group("/posts")
 		{
 			postsGroup.POST("/upload-image", postHandlers.UploadImage)
 			postsGroup.POST("/upload-video", postHandlers.UploadVideo)
 			postsGroup.POST("/upload-audio", postHandlers.UploadAudio)
-			postsGroup.POST("", postHandlers.CreatePost)
+			postsGroup.POST("/upload-url", postHandlers.GetUploadURL)
+			postsGroup.POST("",
+				newAccountGuard.Limit("post", cfg.NewAccount.PostLimit, time.Hour),
+				postHandlers.CreatePost)
 			postsGroup.GET("/:id", postHandlers.GetPost)
 			postsGroup.PUT("/:id", postHandlers.UpdatePost)
+			postsGroup.PATCH("/:id/draft", postHandlers.SaveDraft)
 			postsGroup.DELETE("/:id", postHandlers.DeletePost)
 			postsGroup.POST("/:id/restrict", postHandlers.RestrictPost)
 			postsGroup.DELETE("/:id/restrict", postHandlers.UnrestrictPost)
@@ -729,18 +1090,24 @@ func main() {
 			postsGroup.POST("/:id/like", postHandlers.LikePost)
 			postsGroup.DELETE("/:id/like", postHandlers.UnlikePost)
 			postsGroup.POST("/:id/share", postHandlers.SharePost)
+			postsGroup.DELETE("/:id/share", postHandlers.UnsharePost)
 			postsGroup.POST("/:id/save", postHandlers.SavePost)
 			postsGroup.DELETE("/:id/save", postHandlers.UnsavePost)
-			postsGroup.POST("/:id/comments", postHandlers.CreateComment)
+			postsGroup.POST("/:id/comments",
+				auth.CommentRateLimitMiddleware(hybridRateLimiter, cfg.Comments.RateLimit, cfg.Comments.RateWindowTTL()),
+				postHandlers.CreateComment)
 			postsGroup.GET("/:id/comments", postHandlers.GetComments)
 			postsGroup.POST("/:id/vote", postHandlers.VotePoll)
 			postsGroup.GET("/:id/results", postHandlers.GetPollResults)
 		}
 
+		protected.POST("/polls/results", postHandlers.GetPollResultsBatch)
+
 		commentsGroup := protected.Group("/comments")
 		{
 			commentsGroup.PUT("/:id", postHandlers.UpdateComment)
 			commentsGroup.DELETE("/:id", postHandlers.DeleteComment)
+			commentsGroup.GET("/:id/replies", postHandlers.GetCommentReplies)
 			commentsGroup.POST("/:id/like", postHandlers.LikeComment)
 		}
 
@@ -750,6 +1117,10 @@ func main() {
 			feedGroup.GET("/following", postHandlers.GetFollowingFeed)
 			feedGroup.GET("/explore", postHandlers.GetExploreFeed)
 			feedGroup.GET("/saved", postHandlers.GetSavedFeed)
+			feedGroup.GET("/saved/collections", postHandlers.GetSavedCollections)
+			feedGroup.POST("/saved/collections", postHandlers.CreateSavedCollection)
+			feedGroup.PATCH("/saved/collections/:name", postHandlers.RenameSavedCollection)
+			feedGroup.DELETE("/saved/collections/:name", postHandlers.DeleteSavedCollection)
 			feedGroup.GET("/since-last-visit", postHandlers.GetSinceLastVisitCounts)
 		}
 
@@ -765,7 +1136,7 @@ func main() {
 
 		hashtagGroup := api.Group("/hashtags")
 		{
-			hashtagGroup.GET("/:tag/posts", postHandlers.GetHashtagFeed)
+			hashtagGroup.GET("/:tag/posts", auth.OptionalJWTAuthMiddleware(jwtSvc), postHandlers.GetHashtagFeed)
 			hashtagGroup.GET("/trending", postHandlers.GetTrendingHashtags)
 
 			hashtagProtected := hashtagGroup.Group("")
@@ -784,6 +1155,7 @@ func main() {
 			statusesGroup.POST("", statusHandlers.CreateStatus)
 			statusesGroup.GET("/feed", statusHandlers.GetStatusesForFeed)
 			statusesGroup.GET("/user/:userID", statusHandlers.GetUserStatuses)
+			statusesGroup.GET("/archive", statusHandlers.GetStatusArchive)
 			statusesGroup.GET("/:id", statusHandlers.GetStatus)
 			statusesGroup.DELETE("/:id", statusHandlers.DeleteStatus)
 			statusesGroup.POST("/:id/view", statusHandlers.ViewStatus)
@@ -822,6 +1194,25 @@ func main() {
 		}
 	}()
 
+	// Metrics listener, kept apart from the API so it needn't be reachable publicly
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled && cfg.Metrics.BindAddress != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Default.Handler())
+		metricsServer = &http.Server{
+			Addr:         cfg.Metrics.BindAddress,
+			Handler:      metricsMux,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		}
+		go func() {
+			log.Printf("[Metrics] Serving on %s/metrics", cfg.Metrics.BindAddress)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[Metrics] Server failed: %v", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -837,16 +1228,27 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("[Server] HTTP server shutdown error: %v", err)
 	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			log.Printf("[Server] Metrics server shutdown error: %v", err)
+		}
+	}
 
 	// Stop job scheduler
 	log.Println("[Server] Stopping job scheduler...")
 	jobScheduler.Stop()
 
+	// Write post views still buffered in memory
+	if _, err := postRepo.FlushPostViews(ctx); err != nil {
+		log.Printf("[Server] Post view flush error: %v", err)
+	}
+
 	// Stop rate limiter cleanup
 	log.Println("[Server] Stopping rate limiter...")
 	hybridRateLimiter.Stop()
 
-	// Close WebSocket connections
+	// Take this instance's users offline, then close WebSocket connections
+	presenceTracker.Stop()
 	log.Println("[Server] Closing WebSocket connections...")
 	wsManager.Shutdown()
 
@@ -862,6 +1264,18 @@ func main() {
 		emailWorker.Stop()
 	}
 
+	// Stop push worker
+	log.Println("[Server] Stopping push worker...")
+	if pushWorker != nil {
+		pushWorker.Stop()
+	}
+
+	// Stop message delivery worker
+	log.Println("[Server] Stopping message delivery worker...")
+	if deliveryWorker != nil {
+		deliveryWorker.Stop()
+	}
+
 	// Close queue provider
 	log.Println("[Server] Closing queue provider...")
 	if queueProvider != nil {
@@ -871,6 +1285,16 @@ func main() {
 	log.Println("[Server] Shutdown complete")
 }
 
+// rankingWeights converts configured explore ranking weights for the feed service
+func rankingWeights(w config.RankingWeightsConfig) posts.RankingWeights {
+	return posts.RankingWeights{
+		RecencyHalfLife: time.Duration(w.HalfLifeHours * float64(time.Hour)),
+		VelocityWeight:  w.VelocityWeight,
+		AffinityWeight:  w.AffinityWeight,
+		MaxPerAuthor:    w.MaxPerAuthor,
+	}
+}
+
 // emailSenderAdapter adapts utils.EmailService to queue.EmailSender interface
 type emailSenderAdapter struct {
 	svc *utils.EmailService
@@ -888,7 +1312,15 @@ func (a *emailSenderAdapter) SendPasswordResetEmail(to, token string) error {
 	return a.svc.SendPasswordResetEmail(to, token)
 }
 
+func (a *emailSenderAdapter) SendMagicLinkEmail(to, token string) error {
+	return a.svc.SendMagicLinkEmail(to, token)
+}
+
 func (a *emailSenderAdapter) SendNotificationEmail(to, subject, body string) error {
 	// Use the general SendEmail method
 	return a.svc.SendEmail(to, subject, body, body)
 }
+
+func (a *emailSenderAdapter) SendDigestEmail(to, subject, htmlBody, textBody string) error {
+	return a.svc.SendEmail(to, subject, htmlBody, textBody)
+}
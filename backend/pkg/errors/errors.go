@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // AppError represents an application error with HTTP status code
@@ -44,6 +45,20 @@ var (
 	ErrInvalidToken       = NewAppError(http.StatusUnauthorized, "Invalid or expired token")
 	ErrTokenExpired       = NewAppError(http.StatusUnauthorized, "Token has expired")
 	ErrUnauthorized       = NewAppError(http.StatusUnauthorized, "Unauthorized access")
+	ErrInvalidRefresh     = NewAppError(http.StatusUnauthorized, "Invalid or expired refresh token")
+	ErrRefreshReused      = NewAppError(http.StatusUnauthorized, "Refresh token was already used; please log in again")
+	ErrSessionNotFound    = NewAppError(http.StatusNotFound, "Session not found")
+	ErrAccountLocked      = NewAppError(http.StatusTooManyRequests, "Account temporarily locked after too many failed login attempts")
+	ErrMagicLinkInvalid   = NewAppError(http.StatusUnauthorized, "This login link is invalid, expired or already used")
+	ErrMagicLinkDisabled  = NewAppError(http.StatusServiceUnavailable, "Email login links are not available")
+	ErrOAuthStateInvalid  = NewAppError(http.StatusUnauthorized, "This login attempt is invalid or has expired; please start again")
+
+	// Two-factor errors
+	ErrTwoFactorInvalidCode    = NewAppError(http.StatusUnauthorized, "Invalid two-factor code")
+	ErrTwoFactorAlreadyEnabled = NewAppError(http.StatusConflict, "Two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp       = NewAppError(http.StatusBadRequest, "Two-factor authentication has not been set up")
+	ErrTwoFactorUnavailable    = NewAppError(http.StatusServiceUnavailable, "Two-factor authentication is not configured on this server")
+	ErrTwoFactorTooManyCodes   = NewAppError(http.StatusUnauthorized, "Too many invalid two-factor codes; please log in again")
 
 	// Validation errors
 	ErrInvalidInput       = NewAppError(http.StatusBadRequest, "Invalid input data")
@@ -74,6 +89,59 @@ var (
 	// Multi-account errors
 	ErrAccountsAlreadyLinked = NewAppError(http.StatusConflict, "Accounts are already linked")
 
+	// Poll errors
+	ErrPollClosed             = NewAppError(http.StatusConflict, "Poll is closed")
+	ErrPollInvalidOption      = NewAppError(http.StatusBadRequest, "Option does not belong to this poll")
+	ErrPollAlreadyVoted       = NewAppError(http.StatusConflict, "You have already voted on this poll")
+	ErrPollOptionAlreadyVoted = NewAppError(http.StatusConflict, "You have already voted for this option")
+
+	// Direct message errors
+	ErrCannotMessageUser = NewAppError(http.StatusForbidden, "You can't message this user")
+
+	// Group conversation errors
+	ErrNotGroupConversation = NewAppError(http.StatusBadRequest, "Not a group conversation")
+	ErrNotGroupAdmin        = NewAppError(http.StatusForbidden, "Only group admins can do that")
+	ErrNotGroupMember       = NewAppError(http.StatusNotFound, "User is not a member of this group")
+	ErrGroupNeedsMembers    = NewAppError(http.StatusBadRequest, "A group needs at least one other member")
+	ErrGroupFull            = NewAppError(http.StatusConflict, "This group is full")
+	ErrInvalidGroupName     = NewAppError(http.StatusBadRequest, "Group name must be 1-100 characters")
+	ErrInvalidMessageTTL    = NewAppError(http.StatusBadRequest, "Disappearing messages timer must be between 1 minute and 90 days")
+
+	// Scheduled message errors
+	ErrInvalidSendAt              = NewAppError(http.StatusBadRequest, "Scheduled time must be in the future and within a year")
+	ErrScheduledMessageNotFound   = NewAppError(http.StatusNotFound, "Scheduled message not found")
+	ErrScheduledMessageNotPending = NewAppError(http.StatusConflict, "Scheduled message has already been sent or cancelled")
+
+	// Catching up on a conversation
+	ErrInvalidAfterMessage = NewAppError(http.StatusBadRequest, "after must be a message in this conversation")
+
+	// Course quiz errors
+	ErrQuizNotFound  = NewAppError(http.StatusNotFound, "This lesson has no quiz")
+	ErrQuizExists    = NewAppError(http.StatusConflict, "This lesson already has a quiz")
+	ErrNotQuizLesson = NewAppError(http.StatusBadRequest, "Only quiz lessons can have a quiz")
+	ErrQuizNotPassed = NewAppError(http.StatusConflict, "Pass the lesson's quiz to complete it")
+
+	// Course coupon errors
+	ErrCouponNotFound      = NewAppError(http.StatusNotFound, "Coupon code not found")
+	ErrCouponExpired       = NewAppError(http.StatusGone, "This coupon has expired")
+	ErrCouponExhausted     = NewAppError(http.StatusConflict, "This coupon has been used up")
+	ErrCouponNotApplicable = NewAppError(http.StatusBadRequest, "This coupon can't be used for this course")
+	ErrCouponCodeTaken     = NewAppError(http.StatusConflict, "That coupon code is already in use")
+
+	// Push notification errors
+	ErrPushUnavailable    = NewAppError(http.StatusServiceUnavailable, "Push notifications are not available")
+	ErrInvalidDeviceToken = NewAppError(http.StatusBadRequest, "Invalid device token")
+
+	// Notification list errors
+	ErrInvalidAfterNotification = NewAppError(http.StatusBadRequest, "after must be one of your notifications")
+
+	// Storage errors
+	ErrStorageQuotaUnavailable = NewAppError(http.StatusServiceUnavailable, "Storage quota can't be checked right now, try again shortly")
+
+	// Search errors
+	ErrInvalidSearchType   = NewAppError(http.StatusBadRequest, "types must be users, posts, hashtags or courses")
+	ErrInvalidSearchCursor = NewAppError(http.StatusBadRequest, "Invalid search cursor")
+
 	// Request errors
 	ErrBadRequest = NewAppError(http.StatusBadRequest, "Bad request")
 
@@ -86,6 +154,25 @@ var (
 	ErrEmailSendError = NewAppError(http.StatusInternalServerError, "Failed to send email")
 )
 
+// AccountLockedError is ErrAccountLocked carrying how long the lock has left
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return ErrAccountLocked.Message
+}
+
+// Unwrap lets GetAppError map it to ErrAccountLocked
+func (e *AccountLockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// AccountLocked returns ErrAccountLocked for a lock expiring after retryAfter
+func AccountLocked(retryAfter time.Duration) *AccountLockedError {
+	return &AccountLockedError{RetryAfter: retryAfter}
+}
+
 // IsAppError checks if an error is an AppError
 func IsAppError(err error) bool {
 	_, ok := err.(*AppError)
@@ -0,0 +1,17 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 17: CONTACT DISCOVERY
+-- ============================================================================
+-- Contains: Opt-out flag for "find friends" contact matching by email hash
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- Users are discoverable by their email hash unless they opt out
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS discoverable_by_email BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Contact matching filters on email_hash (already indexed) and this flag
+CREATE INDEX IF NOT EXISTS idx_users_email_hash_discoverable
+    ON users(email_hash)
+    WHERE discoverable_by_email = TRUE AND is_active = TRUE;
+
+COMMENT ON COLUMN users.discoverable_by_email IS 'Whether other users can find this account by importing contacts (SHA-256 email hash match)';
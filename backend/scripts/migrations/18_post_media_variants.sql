@@ -0,0 +1,13 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 18: POST MEDIA VARIANTS
+-- ============================================================================
+-- Contains: Resized image variant URLs stored alongside posts.media_urls
+-- Dependencies: 03_content.sql
+-- ============================================================================
+
+-- One entry per media_urls element, e.g. [{"320": "...", "640": "...", "1280": "..."}]
+-- Non-image media (video) gets an empty object so indexes stay aligned
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS media_variants JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+COMMENT ON COLUMN posts.media_variants IS 'Resized image URLs keyed by longest-edge size in pixels, parallel to media_urls';
@@ -0,0 +1,32 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 19: STORAGE USAGE
+-- ============================================================================
+-- Contains: Per-user uploaded bytes for storage quota enforcement
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS storage_usage (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    bytes_used BIGINT NOT NULL DEFAULT 0 CHECK (bytes_used >= 0),
+    reconciled_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Atomically add (or subtract, for deletes) bytes for a user, creating the row on first upload
+CREATE OR REPLACE FUNCTION increment_storage_usage(p_user_id UUID, p_delta BIGINT)
+RETURNS BIGINT AS $$
+DECLARE
+    new_total BIGINT;
+BEGIN
+    INSERT INTO storage_usage (user_id, bytes_used, updated_at)
+    VALUES (p_user_id, GREATEST(p_delta, 0), NOW())
+    ON CONFLICT (user_id) DO UPDATE
+        SET bytes_used = GREATEST(storage_usage.bytes_used + p_delta, 0),
+            updated_at = NOW()
+    RETURNING bytes_used INTO new_total;
+
+    RETURN new_total;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON TABLE storage_usage IS 'Bytes each user has uploaded; updated on upload/delete and periodically reconciled from object storage listings';
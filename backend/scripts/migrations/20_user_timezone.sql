@@ -0,0 +1,11 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 20: USER TIMEZONE
+-- ============================================================================
+-- Contains: IANA timezone on users for resolving scheduled times
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+COMMENT ON COLUMN users.timezone IS 'IANA timezone name (e.g. Europe/Berlin) used to interpret local scheduled times';
@@ -0,0 +1,67 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 21: COURSE ENROLLMENT COUNTERS
+-- ============================================================================
+-- Contains: Atomic enrollment/completion counter RPCs and reconciliation
+-- Dependencies: courses, course_enrollments (learning platform schema)
+-- ============================================================================
+
+-- Atomically adjust a course's enrollment_count (never below zero)
+CREATE OR REPLACE FUNCTION adjust_course_enrollment_count(p_course_id UUID, p_delta INTEGER)
+RETURNS INTEGER AS $$
+DECLARE
+    new_count INTEGER;
+BEGIN
+    UPDATE courses
+    SET enrollment_count = GREATEST(COALESCE(enrollment_count, 0) + p_delta, 0)
+    WHERE id = p_course_id
+    RETURNING enrollment_count INTO new_count;
+
+    RETURN COALESCE(new_count, 0);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Atomically adjust a course's completion_count (never below zero)
+CREATE OR REPLACE FUNCTION adjust_course_completion_count(p_course_id UUID, p_delta INTEGER)
+RETURNS INTEGER AS $$
+DECLARE
+    new_count INTEGER;
+BEGIN
+    UPDATE courses
+    SET completion_count = GREATEST(COALESCE(completion_count, 0) + p_delta, 0)
+    WHERE id = p_course_id
+    RETURNING completion_count INTO new_count;
+
+    RETURN COALESCE(new_count, 0);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Recompute both counters from course_enrollments, returning how many courses were corrected
+CREATE OR REPLACE FUNCTION reconcile_course_enrollment_counts()
+RETURNS INTEGER AS $$
+DECLARE
+    corrected INTEGER;
+BEGIN
+    WITH actual AS (
+        SELECT c.id,
+               COUNT(e.id)::INTEGER AS enrollments,
+               COUNT(e.completed_at)::INTEGER AS completions
+        FROM courses c
+        LEFT JOIN course_enrollments e ON e.course_id = c.id
+        GROUP BY c.id
+    )
+    UPDATE courses
+    SET enrollment_count = actual.enrollments,
+        completion_count = actual.completions
+    FROM actual
+    WHERE courses.id = actual.id
+      AND (courses.enrollment_count IS DISTINCT FROM actual.enrollments
+           OR courses.completion_count IS DISTINCT FROM actual.completions);
+
+    GET DIAGNOSTICS corrected = ROW_COUNT;
+    RETURN corrected;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION adjust_course_enrollment_count(UUID, INTEGER) IS 'Atomically adjust courses.enrollment_count on enroll/un-enroll';
+COMMENT ON FUNCTION adjust_course_completion_count(UUID, INTEGER) IS 'Atomically adjust courses.completion_count when an enrollment completes';
+COMMENT ON FUNCTION reconcile_course_enrollment_counts() IS 'Recompute course enrollment/completion counters from course_enrollments';
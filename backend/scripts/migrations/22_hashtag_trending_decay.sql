@@ -0,0 +1,58 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 22: HASHTAG TRENDING DECAY
+-- ============================================================================
+-- Contains: Time-decayed trending score recomputation for hashtags
+-- Dependencies: hashtags, post_hashtags, posts (03_content)
+-- ============================================================================
+
+-- Recompute trending_score and posts_count for every active hashtag.
+-- Each live post tagged within the window contributes 10 points, halved every
+-- p_half_life_hours of age; a hashtag's score is the sum over its posts. Hashtags
+-- that fall out of the window decay to zero. Returns how many hashtags were updated.
+CREATE OR REPLACE FUNCTION recompute_hashtag_trending_scores(
+    p_half_life_hours NUMERIC DEFAULT 6,
+    p_window_hours INTEGER DEFAULT 24
+)
+RETURNS INTEGER AS $$
+DECLARE
+    updated INTEGER;
+BEGIN
+    WITH live AS (
+        SELECT ph.hashtag_id, p.published_at
+        FROM post_hashtags ph
+        JOIN posts p ON p.id = ph.post_id
+        WHERE p.deleted_at IS NULL
+          AND p.is_published = TRUE
+    ),
+    scores AS (
+        SELECT h.id,
+               COUNT(live.hashtag_id)::INTEGER AS posts_count,
+               ROUND(COALESCE(SUM(
+                   CASE WHEN live.published_at >= NOW() - make_interval(hours => p_window_hours)
+                        THEN 10 * POWER(0.5, EXTRACT(EPOCH FROM (NOW() - live.published_at)) / 3600.0 / p_half_life_hours)
+                        ELSE 0
+                   END
+               ), 0)::NUMERIC, 2) AS trending_score
+        FROM hashtags h
+        LEFT JOIN live ON live.hashtag_id = h.id
+        WHERE h.trending_score > 0
+           OR EXISTS (
+               SELECT 1 FROM live recent
+               WHERE recent.hashtag_id = h.id
+                 AND recent.published_at >= NOW() - make_interval(hours => p_window_hours)
+           )
+        GROUP BY h.id
+    )
+    UPDATE hashtags
+    SET trending_score = scores.trending_score,
+        posts_count = scores.posts_count,
+        last_trending_update = NOW()
+    FROM scores
+    WHERE hashtags.id = scores.id;
+
+    GET DIAGNOSTICS updated = ROW_COUNT;
+    RETURN updated;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION recompute_hashtag_trending_scores(NUMERIC, INTEGER) IS 'Recompute time-decayed hashtag trending scores and live post counts';
@@ -0,0 +1,15 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 23: STATUS INTERACTION SETTINGS
+-- ============================================================================
+-- Contains: Per-status reaction/reply toggles and private engagement lists
+-- Dependencies: 06_statuses.sql
+-- ============================================================================
+
+ALTER TABLE statuses
+ADD COLUMN IF NOT EXISTS allow_reactions BOOLEAN NOT NULL DEFAULT TRUE,
+ADD COLUMN IF NOT EXISTS allow_replies BOOLEAN NOT NULL DEFAULT TRUE,
+ADD COLUMN IF NOT EXISTS engagement_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN statuses.allow_reactions IS 'Whether viewers may react to the status';
+COMMENT ON COLUMN statuses.allow_replies IS 'Whether viewers may comment on or message-reply to the status';
+COMMENT ON COLUMN statuses.engagement_private IS 'When true, only the author can list who viewed or reacted to the status';
@@ -0,0 +1,11 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 24: POST DRAFT VERSION
+-- ============================================================================
+-- Contains: Draft autosave version counter for optimistic concurrency
+-- Dependencies: 03_content.sql
+-- ============================================================================
+
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS draft_version INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN posts.draft_version IS 'Incremented by every draft autosave; saves carrying an older version are rejected';
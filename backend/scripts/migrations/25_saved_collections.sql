@@ -0,0 +1,40 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 25: SAVED COLLECTIONS
+-- ============================================================================
+-- Contains: Named bookmark collections so empty collections can exist and be managed
+-- Dependencies: 04_engagement.sql
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS saved_collections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_user_saved_collection UNIQUE (user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_collections_user ON saved_collections(user_id, created_at);
+
+-- Backfill collections that so far only existed as names on saved_posts
+INSERT INTO saved_collections (user_id, name, created_at)
+SELECT user_id, collection_name, MIN(saved_at)
+FROM saved_posts
+WHERE collection_name IS NOT NULL AND collection_name <> 'Saved'
+GROUP BY user_id, collection_name
+ON CONFLICT (user_id, name) DO NOTHING;
+
+-- saved_posts had no UPDATE policy; renaming or deleting a collection moves rows
+DROP POLICY IF EXISTS saved_posts_update_own ON saved_posts;
+CREATE POLICY saved_posts_update_own ON saved_posts
+    FOR UPDATE USING (user_id = auth.uid());
+
+ALTER TABLE saved_collections ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS saved_collections_all_own ON saved_collections;
+CREATE POLICY saved_collections_all_own ON saved_collections
+    FOR ALL USING (user_id = auth.uid()) WITH CHECK (user_id = auth.uid());
+
+GRANT ALL ON saved_collections TO authenticated;
+
+COMMENT ON TABLE saved_collections IS 'User-created bookmark collections; the default "Saved" collection is implicit and never stored';
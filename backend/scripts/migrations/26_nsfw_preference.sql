@@ -0,0 +1,12 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 26: NSFW PREFERENCE
+-- ============================================================================
+-- Contains: Per-user opt-in for seeing NSFW posts in feeds
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- NSFW posts are hidden from discovery feeds and blurred elsewhere until the viewer opts in
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS show_nsfw BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN users.show_nsfw IS 'Whether the user opted in to seeing NSFW posts unblurred in feeds';
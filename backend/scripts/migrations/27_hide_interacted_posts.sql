@@ -0,0 +1,12 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 27: HIDE INTERACTED POSTS
+-- ============================================================================
+-- Contains: Per-user opt-in for hiding already-engaged posts from feeds
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- Off by default: feeds show posts the viewer already liked, saved or commented on
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS hide_interacted_posts BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN users.hide_interacted_posts IS 'Whether home and explore feeds leave out posts the user already liked, saved or commented on';
@@ -0,0 +1,45 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 28: POST VIEW COUNTERS
+-- ============================================================================
+-- Contains: Atomic views_count increment and batched unique-view recording
+-- Dependencies: 03_content.sql, 15_post_views_tracking.sql
+-- ============================================================================
+
+-- Add p_count views to a post in a single UPDATE, so concurrent views can't overwrite
+-- each other. Returns the new count (NULL if the post doesn't exist).
+CREATE OR REPLACE FUNCTION increment_post_views(p_post_id UUID, p_count INTEGER DEFAULT 1)
+RETURNS INTEGER AS $$
+DECLARE
+    new_count INTEGER;
+BEGIN
+    UPDATE posts
+    SET views_count = COALESCE(views_count, 0) + p_count
+    WHERE id = p_post_id
+    RETURNING views_count INTO new_count;
+
+    RETURN new_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Insert a batch of unique views given as [{"post_id", "user_id", "viewed_at"}, ...].
+-- Views already recorded, or for posts deleted since, are skipped. Returns how many
+-- rows were inserted.
+CREATE OR REPLACE FUNCTION record_post_views(p_views JSONB)
+RETURNS INTEGER AS $$
+DECLARE
+    inserted INTEGER;
+BEGIN
+    INSERT INTO post_views (post_id, user_id, viewed_at)
+    SELECT v.post_id, v.user_id, COALESCE(v.viewed_at, NOW())
+    FROM jsonb_to_recordset(p_views) AS v(post_id UUID, user_id UUID, viewed_at TIMESTAMP)
+    WHERE EXISTS (SELECT 1 FROM posts p WHERE p.id = v.post_id)
+      AND EXISTS (SELECT 1 FROM users u WHERE u.id = v.user_id)
+    ON CONFLICT (post_id, user_id) DO NOTHING;
+
+    GET DIAGNOSTICS inserted = ROW_COUNT;
+    RETURN inserted;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION increment_post_views(UUID, INTEGER) IS 'Atomically add to posts.views_count';
+COMMENT ON FUNCTION record_post_views(JSONB) IS 'Bulk insert buffered unique post views, ignoring duplicates';
@@ -0,0 +1,23 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 29: CONTENT LANGUAGE
+-- ============================================================================
+-- Contains: Detected language on posts and comments, per-user feed language filter
+-- Dependencies: 01_core_schema.sql, 03_content.sql, 04_engagement.sql
+-- ============================================================================
+
+-- NULL means detection was uncertain; such posts are shown regardless of filters
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+
+ALTER TABLE post_comments
+ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+
+CREATE INDEX IF NOT EXISTS idx_posts_language ON posts(language) WHERE language IS NOT NULL;
+
+-- Empty array shows every language
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS feed_languages TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN posts.language IS 'ISO 639-1 code detected from the post text, NULL when uncertain';
+COMMENT ON COLUMN post_comments.language IS 'ISO 639-1 code detected from the comment text, NULL when uncertain';
+COMMENT ON COLUMN users.feed_languages IS 'Languages the user wants in home and explore feeds; empty means all';
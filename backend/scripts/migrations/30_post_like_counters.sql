@@ -0,0 +1,79 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 30: POST LIKE COUNTERS
+-- ============================================================================
+-- Contains: Transactional like/unlike RPC and likes_count reconciliation
+-- Dependencies: 03_content.sql, 04_engagement.sql
+-- ============================================================================
+
+-- toggle_post_like now owns likes_count; keeping the trigger would count twice
+DROP TRIGGER IF EXISTS trigger_post_likes_count ON post_likes;
+
+-- Set whether p_user_id likes p_post_id and adjust likes_count in the same transaction.
+-- The post row is locked first so concurrent likes on one post are serialized. Repeating
+-- a like or unlike changes nothing. Returns the new count, or NULL if the post doesn't
+-- exist or was deleted.
+CREATE OR REPLACE FUNCTION toggle_post_like(p_post_id UUID, p_user_id UUID, p_liked BOOLEAN)
+RETURNS INTEGER AS $$
+DECLARE
+    new_count INTEGER;
+    changed INTEGER;
+BEGIN
+    SELECT COALESCE(likes_count, 0) INTO new_count
+    FROM posts
+    WHERE id = p_post_id AND deleted_at IS NULL
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF p_liked THEN
+        INSERT INTO post_likes (post_id, user_id)
+        VALUES (p_post_id, p_user_id)
+        ON CONFLICT (post_id, user_id) DO NOTHING;
+    ELSE
+        DELETE FROM post_likes
+        WHERE post_id = p_post_id AND user_id = p_user_id;
+    END IF;
+
+    GET DIAGNOSTICS changed = ROW_COUNT;
+    IF changed = 0 THEN
+        RETURN new_count;
+    END IF;
+
+    UPDATE posts
+    SET likes_count = GREATEST(COALESCE(likes_count, 0) + CASE WHEN p_liked THEN 1 ELSE -1 END, 0),
+        updated_at = NOW()
+    WHERE id = p_post_id
+    RETURNING likes_count INTO new_count;
+
+    RETURN new_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Recompute likes_count from post_likes, returning how many posts were corrected
+CREATE OR REPLACE FUNCTION reconcile_post_like_counts()
+RETURNS INTEGER AS $$
+DECLARE
+    corrected INTEGER;
+BEGIN
+    WITH actual AS (
+        SELECT p.id, COUNT(l.id)::INTEGER AS likes
+        FROM posts p
+        LEFT JOIN post_likes l ON l.post_id = p.id
+        WHERE p.deleted_at IS NULL
+        GROUP BY p.id
+    )
+    UPDATE posts
+    SET likes_count = actual.likes
+    FROM actual
+    WHERE posts.id = actual.id
+      AND posts.likes_count IS DISTINCT FROM actual.likes;
+
+    GET DIAGNOSTICS corrected = ROW_COUNT;
+    RETURN corrected;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION toggle_post_like(UUID, UUID, BOOLEAN) IS 'Like or unlike a post and adjust posts.likes_count atomically';
+COMMENT ON FUNCTION reconcile_post_like_counts() IS 'Recompute posts.likes_count from post_likes';
@@ -0,0 +1,54 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 31: POST LIKE CHANGE FLAG
+-- ============================================================================
+-- Contains: toggle_post_like reporting whether the like state actually changed
+-- Dependencies: 30_post_like_counters.sql
+-- ============================================================================
+
+-- The return type changes, so the old definition has to be dropped first
+DROP FUNCTION IF EXISTS toggle_post_like(UUID, UUID, BOOLEAN);
+
+-- Same as before, but returns (likes_count, changed) so callers can tell a repeated
+-- like/unlike from a real one. No row is returned if the post doesn't exist or was
+-- deleted.
+CREATE OR REPLACE FUNCTION toggle_post_like(p_post_id UUID, p_user_id UUID, p_liked BOOLEAN)
+RETURNS TABLE (likes_count INTEGER, changed BOOLEAN) AS $$
+DECLARE
+    new_count INTEGER;
+    affected INTEGER;
+BEGIN
+    SELECT COALESCE(p.likes_count, 0) INTO new_count
+    FROM posts p
+    WHERE p.id = p_post_id AND p.deleted_at IS NULL
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    IF p_liked THEN
+        INSERT INTO post_likes (post_id, user_id)
+        VALUES (p_post_id, p_user_id)
+        ON CONFLICT (post_id, user_id) DO NOTHING;
+    ELSE
+        DELETE FROM post_likes l
+        WHERE l.post_id = p_post_id AND l.user_id = p_user_id;
+    END IF;
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    IF affected = 0 THEN
+        RETURN QUERY SELECT new_count, FALSE;
+        RETURN;
+    END IF;
+
+    UPDATE posts p
+    SET likes_count = GREATEST(COALESCE(p.likes_count, 0) + CASE WHEN p_liked THEN 1 ELSE -1 END, 0),
+        updated_at = NOW()
+    WHERE p.id = p_post_id
+    RETURNING p.likes_count INTO new_count;
+
+    RETURN QUERY SELECT new_count, TRUE;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION toggle_post_like(UUID, UUID, BOOLEAN) IS 'Like or unlike a post, adjust posts.likes_count atomically and report whether anything changed';
@@ -0,0 +1,39 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 32: PURGE DELETED POSTS
+-- ============================================================================
+-- Contains: Hard delete of soft-deleted posts past the restore window
+-- Dependencies: 03_content.sql, 04_engagement.sql, 15_post_views_tracking.sql
+-- ============================================================================
+
+-- Permanently delete a batch of soft-deleted posts in one transaction. The large
+-- engagement tables are cleared explicitly first; anything else referencing posts goes
+-- with ON DELETE CASCADE. Posts restored in the meantime are skipped. Returns how many
+-- posts were deleted.
+CREATE OR REPLACE FUNCTION purge_deleted_posts(p_post_ids UUID[])
+RETURNS INTEGER AS $$
+DECLARE
+    purged INTEGER;
+    doomed UUID[];
+BEGIN
+    SELECT ARRAY_AGG(id) INTO doomed
+    FROM posts
+    WHERE id = ANY(p_post_ids) AND deleted_at IS NOT NULL;
+
+    IF doomed IS NULL THEN
+        RETURN 0;
+    END IF;
+
+    DELETE FROM post_likes WHERE post_id = ANY(doomed);
+    DELETE FROM post_comments WHERE post_id = ANY(doomed);
+    DELETE FROM post_hashtags WHERE post_id = ANY(doomed);
+    DELETE FROM post_views WHERE post_id = ANY(doomed);
+    DELETE FROM posts WHERE id = ANY(doomed);
+
+    GET DIAGNOSTICS purged = ROW_COUNT;
+    RETURN purged;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMENT ON FUNCTION purge_deleted_posts(UUID[]) IS 'Hard delete soft-deleted posts and their engagement rows';
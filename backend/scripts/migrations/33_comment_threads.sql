@@ -0,0 +1,70 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 33: COMMENT THREADS
+-- ============================================================================
+-- Contains: Two-level comment threads, soft-delete aware replies_count
+-- Dependencies: 04_engagement.sql
+-- ============================================================================
+
+-- replies_count only followed hard inserts and deletes, but comments are soft-deleted.
+-- Track deleted_at the same way update_post_comments_count does.
+CREATE OR REPLACE FUNCTION update_comment_replies_count()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.parent_comment_id IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        UPDATE post_comments SET replies_count = replies_count + 1 WHERE id = NEW.parent_comment_id;
+    ELSIF TG_OP = 'DELETE' AND OLD.parent_comment_id IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        UPDATE post_comments SET replies_count = GREATEST(0, replies_count - 1) WHERE id = OLD.parent_comment_id;
+    ELSIF TG_OP = 'UPDATE' AND NEW.parent_comment_id IS NOT NULL THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            UPDATE post_comments SET replies_count = GREATEST(0, replies_count - 1) WHERE id = NEW.parent_comment_id;
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            UPDATE post_comments SET replies_count = replies_count + 1 WHERE id = NEW.parent_comment_id;
+        END IF;
+    END IF;
+    RETURN COALESCE(NEW, OLD);
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_comment_replies_count ON post_comments;
+CREATE TRIGGER trigger_comment_replies_count
+    AFTER INSERT OR DELETE OR UPDATE OF deleted_at ON post_comments
+    FOR EACH ROW EXECUTE FUNCTION update_comment_replies_count();
+
+-- Threads are two levels deep: replies to a reply join the top-level comment's thread.
+-- The API enforces this on insert; flatten anything older to match.
+WITH RECURSIVE chain AS (
+    SELECT id, parent_comment_id AS root_id
+    FROM post_comments
+    WHERE parent_comment_id IS NOT NULL
+    UNION ALL
+    SELECT chain.id, parent.parent_comment_id
+    FROM chain
+    JOIN post_comments parent ON parent.id = chain.root_id
+    WHERE parent.parent_comment_id IS NOT NULL
+)
+UPDATE post_comments c
+SET parent_comment_id = roots.root_id
+FROM (
+    SELECT DISTINCT ON (chain.id) chain.id, chain.root_id
+    FROM chain
+    JOIN post_comments root ON root.id = chain.root_id
+    WHERE root.parent_comment_id IS NULL
+) roots
+WHERE c.id = roots.id AND c.parent_comment_id <> roots.root_id;
+
+-- Resync counters now that they include soft deletes and flattened threads
+UPDATE post_comments c
+SET replies_count = (
+    SELECT COUNT(*) FROM post_comments r
+    WHERE r.parent_comment_id = c.id AND r.deleted_at IS NULL
+);
+
+UPDATE posts p
+SET comments_count = (
+    SELECT COUNT(*) FROM post_comments c
+    WHERE c.post_id = p.id AND c.deleted_at IS NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_comments_thread
+    ON post_comments(parent_comment_id, created_at)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,22 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 34: FEED RANKING EXPOSURES
+-- ============================================================================
+-- Contains: Log of which ranking variant served each ranked feed page
+-- Dependencies: 01_core_schema.sql, 03_content.sql
+-- ============================================================================
+
+-- One row per ranked page served. post_ids keeps the served order so engagement can
+-- be attributed back to position and variant.
+CREATE TABLE IF NOT EXISTS feed_ranking_exposures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed VARCHAR(20) NOT NULL,
+    variant VARCHAR(50) NOT NULL,
+    post_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feed_ranking_exposures_variant
+    ON feed_ranking_exposures(feed, variant, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_feed_ranking_exposures_user
+    ON feed_ranking_exposures(user_id, created_at DESC);
@@ -0,0 +1,24 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 35: SESSION REFRESH TOKENS
+-- ============================================================================
+-- Contains: Rotating refresh tokens grouped into session families
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- Each row is one refresh token. Rotation marks the old row replaced and inserts a new
+-- one with the same family_id (the access tokens' sid); a replaced token coming back
+-- means it was copied, and the whole family is deleted.
+ALTER TABLE user_sessions
+    ADD COLUMN IF NOT EXISTS family_id UUID,
+    ADD COLUMN IF NOT EXISTS replaced_at TIMESTAMP;
+
+-- Older rows hashed the access token rather than a refresh token, so they can never be
+-- refreshed; they stay listed as devices until they expire
+UPDATE user_sessions SET family_id = id WHERE family_id IS NULL;
+
+ALTER TABLE user_sessions
+    ALTER COLUMN family_id SET DEFAULT gen_random_uuid(),
+    ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_family ON user_sessions(family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_current ON user_sessions(user_id) WHERE replaced_at IS NULL;
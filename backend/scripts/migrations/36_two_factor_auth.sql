@@ -0,0 +1,34 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 36: TWO-FACTOR AUTHENTICATION
+-- ============================================================================
+-- Contains: TOTP secrets and one-time recovery codes
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- Kept out of users so no user read can pull a secret along with the profile.
+-- The secret is AES-GCM encrypted with the server's TWO_FACTOR_ENCRYPTION_KEY.
+-- A row with enabled_at NULL is a setup still waiting for its first code.
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    secret_iv TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    -- Newest TOTP time step accepted; a code is only good once
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Only hashes are stored; the codes are shown once, when 2FA is enabled
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_user_hash ON two_factor_recovery_codes(user_id, code_hash);
+
+-- No policies: only the backend, using the service role, may touch secrets
+ALTER TABLE user_two_factor ENABLE ROW LEVEL SECURITY;
+ALTER TABLE two_factor_recovery_codes ENABLE ROW LEVEL SECURITY;
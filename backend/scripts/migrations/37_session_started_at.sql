@@ -0,0 +1,25 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 37: SESSION STARTED AT
+-- ============================================================================
+-- Contains: Login time carried across refresh token rotations
+-- Dependencies: 35_session_refresh_tokens.sql
+-- ============================================================================
+
+-- created_at is when a row's refresh token was issued, which after rotation is the
+-- session's last refresh; started_at is copied from row to row and keeps the login time
+ALTER TABLE user_sessions
+    ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+
+-- Replaced rows are kept, so a family's oldest row still has the login time
+UPDATE user_sessions s
+SET started_at = f.first_created_at
+FROM (
+    SELECT family_id, MIN(created_at) AS first_created_at
+    FROM user_sessions
+    GROUP BY family_id
+) f
+WHERE s.family_id = f.family_id AND s.started_at IS NULL;
+
+ALTER TABLE user_sessions
+    ALTER COLUMN started_at SET DEFAULT NOW(),
+    ALTER COLUMN started_at SET NOT NULL;
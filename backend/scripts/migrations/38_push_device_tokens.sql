@@ -0,0 +1,22 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 38: PUSH DEVICE TOKENS
+-- ============================================================================
+-- Contains: Push tokens registered by each user's devices, per platform
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- One row per device token. A token identifies an app install, so registering one
+-- that another account already holds moves it to the new account (shared devices).
+-- Tokens the provider reports as invalid are deleted when a push to them fails.
+CREATE TABLE IF NOT EXISTS push_device_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL CHECK (platform IN ('ios', 'android', 'web')),
+    token TEXT NOT NULL,
+    app_version VARCHAR(50),
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_seen_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_push_device_tokens_token ON push_device_tokens(platform, token);
+CREATE INDEX IF NOT EXISTS idx_push_device_tokens_user ON push_device_tokens(user_id);
@@ -0,0 +1,15 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 39: APPLE AND X (TWITTER) SIGN-IN
+-- ============================================================================
+-- Contains: Account IDs for Sign in with Apple and X OAuth
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- Apple's ID is the stable "sub" of its identity token; the email it shares may be a
+-- private relay address, so accounts are found by this ID rather than by email
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS apple_id VARCHAR(255) UNIQUE,
+    ADD COLUMN IF NOT EXISTS twitter_id VARCHAR(255) UNIQUE;
+
+CREATE INDEX IF NOT EXISTS idx_users_apple_id ON users(apple_id) WHERE apple_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_twitter_id ON users(twitter_id) WHERE twitter_id IS NOT NULL;
@@ -0,0 +1,16 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 40: MESSAGE SEARCH INDEX
+-- ============================================================================
+-- Contains: Trigram index for searching message content within conversations
+-- Dependencies: 01_core_schema.sql (pg_trgm), 05_messaging.sql
+-- ============================================================================
+
+-- btree_gin lets a GIN index lead with the plain conversation_id column
+CREATE EXTENSION IF NOT EXISTS btree_gin;
+
+-- Message search is always scoped to conversations (one, or all of a user's) and
+-- matches content with ILIKE '%...%'. Keying the trigram index by conversation first
+-- means a search in a huge conversation reads only that conversation's matching
+-- entries, instead of filtering every message in it or every match site-wide.
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_content_trgm
+    ON messages USING gin (conversation_id, content gin_trgm_ops);
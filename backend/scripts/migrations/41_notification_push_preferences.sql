@@ -0,0 +1,20 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 41: PUSH NOTIFICATION PREFERENCES
+-- ============================================================================
+-- Contains: Per-type push settings and a temporary mute on notification_preferences
+-- Dependencies: 07_notifications.sql
+-- ============================================================================
+
+-- Per-type push settings. Types missing from the map are pushed, so new types are
+-- on until the user turns them off.
+ALTER TABLE notification_preferences
+    ADD COLUMN IF NOT EXISTS push_types JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN notification_preferences.muted_until IS
+    'No push or email until this time; notifications still reach the in-app list';
+
+-- Push defaulted to off while there was nothing to deliver it, so no one has chosen
+-- it: turn it on everywhere along with the new default
+ALTER TABLE notification_preferences ALTER COLUMN push_enabled SET DEFAULT TRUE;
+UPDATE notification_preferences SET push_enabled = TRUE WHERE push_enabled = FALSE;
@@ -0,0 +1,70 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 42: POST SHARE COUNTERS
+-- ============================================================================
+-- Contains: Transactional share/unshare RPC and a one-time shares_count recount
+-- Dependencies: 03_content.sql, 04_engagement.sql
+-- ============================================================================
+
+-- toggle_post_share now owns shares_count; keeping the trigger would count twice
+DROP TRIGGER IF EXISTS trigger_post_shares_count ON post_shares;
+DROP FUNCTION IF EXISTS update_post_shares_count();
+
+-- Set whether p_user_id has reposted p_post_id and adjust shares_count in the same
+-- transaction, the same way toggle_post_like does for likes. p_comment is the quote
+-- text of a new share; sharing again keeps the original. Returns (shares_count, changed),
+-- or no row if the post doesn't exist or was deleted.
+CREATE OR REPLACE FUNCTION toggle_post_share(p_post_id UUID, p_user_id UUID, p_shared BOOLEAN, p_comment TEXT DEFAULT NULL)
+RETURNS TABLE (shares_count INTEGER, changed BOOLEAN) AS $$
+DECLARE
+    new_count INTEGER;
+    affected INTEGER;
+BEGIN
+    SELECT COALESCE(p.shares_count, 0) INTO new_count
+    FROM posts p
+    WHERE p.id = p_post_id AND p.deleted_at IS NULL
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    IF p_shared THEN
+        INSERT INTO post_shares (post_id, user_id, repost_comment)
+        VALUES (p_post_id, p_user_id, p_comment)
+        ON CONFLICT (post_id, user_id) DO NOTHING;
+    ELSE
+        DELETE FROM post_shares s
+        WHERE s.post_id = p_post_id AND s.user_id = p_user_id;
+    END IF;
+
+    GET DIAGNOSTICS affected = ROW_COUNT;
+    IF affected = 0 THEN
+        RETURN QUERY SELECT new_count, FALSE;
+        RETURN;
+    END IF;
+
+    UPDATE posts p
+    SET shares_count = GREATEST(COALESCE(p.shares_count, 0) + CASE WHEN p_shared THEN 1 ELSE -1 END, 0),
+        updated_at = NOW()
+    WHERE p.id = p_post_id
+    RETURNING p.shares_count INTO new_count;
+
+    RETURN QUERY SELECT new_count, TRUE;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Start the RPC from the real number of shares, correcting any drift left from before
+WITH actual AS (
+    SELECT p.id, COUNT(s.id)::INTEGER AS shares
+    FROM posts p
+    LEFT JOIN post_shares s ON s.post_id = p.id
+    WHERE p.deleted_at IS NULL
+    GROUP BY p.id
+)
+UPDATE posts
+SET shares_count = actual.shares
+FROM actual
+WHERE posts.id = actual.id
+  AND posts.shares_count IS DISTINCT FROM actual.shares;
+
+COMMENT ON FUNCTION toggle_post_share(UUID, UUID, BOOLEAN, TEXT) IS 'Share or unshare a post, adjust posts.shares_count atomically and report whether anything changed';
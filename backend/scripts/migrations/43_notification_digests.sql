@@ -0,0 +1,24 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 43: NOTIFICATION DIGESTS
+-- ============================================================================
+-- Contains: Digest bookkeeping for daily/weekly notification emails
+-- Dependencies: 07_notifications.sql
+-- ============================================================================
+
+-- Set once a digest email has included the notification, so it isn't sent twice
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP WITH TIME ZONE;
+
+-- When the user's last digest went out; the next one waits a full day or week
+ALTER TABLE notification_preferences
+    ADD COLUMN IF NOT EXISTS last_digest_at TIMESTAMP WITH TIME ZONE;
+
+-- The digest job reads each due user's recent unread, undigested notifications
+CREATE INDEX IF NOT EXISTS idx_notifications_user_undigested
+    ON notifications(user_id, created_at DESC)
+    WHERE is_read = FALSE AND digest_sent_at IS NULL;
+
+-- ...and finds the due users by frequency
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_digest
+    ON notification_preferences(email_frequency, last_digest_at)
+    WHERE email_enabled = TRUE AND email_frequency IN ('daily', 'weekly');
@@ -0,0 +1,21 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 44: NOTIFICATION SEEN STATE
+-- ============================================================================
+-- Contains: seen_at on notifications, separating the badge from read state
+-- Dependencies: 07_notifications.sql
+-- ============================================================================
+
+-- Set when the user opens the notification list and the badge is cleared;
+-- the notification stays unread until it is opened on its own
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS seen_at TIMESTAMP WITH TIME ZONE;
+
+-- Anything already read has been seen
+UPDATE notifications
+SET seen_at = created_at
+WHERE is_read = TRUE AND seen_at IS NULL;
+
+-- The badge count and POST /notifications/seen both look at unread, unseen rows
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unseen
+    ON notifications(user_id)
+    WHERE is_read = FALSE AND seen_at IS NULL;
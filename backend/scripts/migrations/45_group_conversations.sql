@@ -0,0 +1,330 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 45: GROUP CONVERSATIONS
+-- ============================================================================
+-- Contains: Group conversations, members with roles, per-member delivery/read state
+-- Dependencies: 05_messaging.sql, 09_dm_store_and_forward.sql
+-- ============================================================================
+
+-- A group has a name and a member list instead of two participants
+ALTER TABLE conversations
+    ADD COLUMN IF NOT EXISTS is_group BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS name VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE conversations ALTER COLUMN participant1_id DROP NOT NULL;
+ALTER TABLE conversations ALTER COLUMN participant2_id DROP NOT NULL;
+
+ALTER TABLE conversations DROP CONSTRAINT IF EXISTS direct_has_participants;
+ALTER TABLE conversations ADD CONSTRAINT direct_has_participants
+    CHECK (is_group OR (participant1_id IS NOT NULL AND participant2_id IS NOT NULL));
+
+-- ============================================================================
+-- CONVERSATION MEMBERS TABLE
+-- Members of group conversations. Leaving sets left_at rather than deleting the
+-- row, so the member's old messages and receipts still resolve.
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS conversation_members (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member')),
+    unread_count INTEGER NOT NULL DEFAULT 0,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    left_at TIMESTAMP WITH TIME ZONE,
+    -- Watermarks: every message sent up to these times reached / was read by the member
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    read_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+-- Listing a user's groups
+CREATE INDEX IF NOT EXISTS idx_conversation_members_user_active
+    ON conversation_members(user_id)
+    WHERE left_at IS NULL;
+
+-- ============================================================================
+-- TRIGGERS AND FUNCTIONS
+-- Group branches for the 1-on-1 functions from 05 and 09
+-- ============================================================================
+
+-- Increment unread count for every recipient
+CREATE OR REPLACE FUNCTION increment_unread_count()
+RETURNS TRIGGER AS $$
+DECLARE
+    conv RECORD;
+BEGIN
+    SELECT participant1_id, participant2_id, is_group INTO conv
+    FROM conversations WHERE id = NEW.conversation_id;
+
+    IF conv.is_group THEN
+        UPDATE conversation_members SET unread_count = unread_count + 1
+        WHERE conversation_id = NEW.conversation_id
+        AND user_id != NEW.sender_id
+        AND left_at IS NULL;
+    ELSIF conv.participant1_id = NEW.sender_id THEN
+        UPDATE conversations SET unread_count_p2 = unread_count_p2 + 1 WHERE id = NEW.conversation_id;
+    ELSE
+        UPDATE conversations SET unread_count_p1 = unread_count_p1 + 1 WHERE id = NEW.conversation_id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get total unread count for a user, groups included
+CREATE OR REPLACE FUNCTION get_user_total_unread_count(user_uuid UUID)
+RETURNS INTEGER AS $$
+DECLARE
+    total INTEGER;
+BEGIN
+    SELECT COALESCE(SUM(
+        CASE
+            WHEN participant1_id = user_uuid THEN unread_count_p1
+            WHEN participant2_id = user_uuid THEN unread_count_p2
+            ELSE 0
+        END
+    ), 0) INTO total
+    FROM conversations
+    WHERE participant1_id = user_uuid OR participant2_id = user_uuid;
+
+    total := total + (
+        SELECT COALESCE(SUM(unread_count), 0)
+        FROM conversation_members
+        WHERE user_id = user_uuid AND left_at IS NULL
+    );
+    RETURN total;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Group messages move to delivered/read once every member who was there when they
+-- were sent has got that far. Members who have since left don't hold them back.
+CREATE OR REPLACE FUNCTION settle_group_message_status(p_conversation_id UUID)
+RETURNS VOID AS $$
+BEGIN
+    UPDATE messages m
+    SET
+        status = 'read',
+        read_at = COALESCE(m.read_at, NOW()),
+        delivered_at = COALESCE(m.delivered_at, NOW())
+    WHERE m.conversation_id = p_conversation_id
+    AND m.status != 'read'
+    AND NOT EXISTS (
+        SELECT 1 FROM conversation_members cm
+        WHERE cm.conversation_id = m.conversation_id
+        AND cm.user_id != m.sender_id
+        AND cm.left_at IS NULL
+        AND cm.joined_at <= m.created_at
+        AND (cm.read_at IS NULL OR cm.read_at < m.created_at)
+    );
+
+    UPDATE messages m
+    SET
+        status = 'delivered',
+        delivered_at = COALESCE(m.delivered_at, NOW()),
+        downloaded_by_recipient = TRUE,
+        delete_scheduled_at = COALESCE(m.delete_scheduled_at, NOW() + INTERVAL '24 hours')
+    WHERE m.conversation_id = p_conversation_id
+    AND m.status = 'sent'
+    AND NOT EXISTS (
+        SELECT 1 FROM conversation_members cm
+        WHERE cm.conversation_id = m.conversation_id
+        AND cm.user_id != m.sender_id
+        AND cm.left_at IS NULL
+        AND cm.joined_at <= m.created_at
+        AND (cm.delivered_at IS NULL OR cm.delivered_at < m.created_at)
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Mark messages as read
+CREATE OR REPLACE FUNCTION mark_messages_read(
+    conv_id UUID,
+    reader_id UUID
+)
+RETURNS INTEGER AS $$
+DECLARE
+    updated_count INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM conversations WHERE id = conv_id AND is_group) THEN
+        SELECT COUNT(*) INTO updated_count
+        FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = reader_id
+        WHERE m.conversation_id = conv_id
+        AND m.sender_id != reader_id
+        AND (cm.read_at IS NULL OR m.created_at > cm.read_at);
+
+        UPDATE conversation_members
+        SET read_at = NOW(), delivered_at = NOW(), unread_count = 0
+        WHERE conversation_id = conv_id AND user_id = reader_id AND left_at IS NULL;
+
+        PERFORM settle_group_message_status(conv_id);
+        RETURN updated_count;
+    END IF;
+
+    WITH updated AS (
+        UPDATE messages
+        SET status = 'read', read_at = NOW()
+        WHERE conversation_id = conv_id
+        AND sender_id != reader_id
+        AND status != 'read'
+        RETURNING id
+    )
+    SELECT COUNT(*) INTO updated_count FROM updated;
+
+    -- Reset unread count
+    UPDATE conversations
+    SET
+        unread_count_p1 = CASE WHEN participant1_id = reader_id THEN 0 ELSE unread_count_p1 END,
+        unread_count_p2 = CASE WHEN participant2_id = reader_id THEN 0 ELSE unread_count_p2 END
+    WHERE id = conv_id;
+
+    RETURN updated_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Batch mark messages as delivered (when recipient opens conversation)
+CREATE OR REPLACE FUNCTION mark_conversation_delivered(
+    p_conversation_id UUID,
+    p_recipient_id UUID
+)
+RETURNS INTEGER AS $$
+DECLARE
+    updated_count INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM conversations WHERE id = p_conversation_id AND is_group) THEN
+        SELECT COUNT(*) INTO updated_count
+        FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id AND cm.user_id = p_recipient_id
+        WHERE m.conversation_id = p_conversation_id
+        AND m.sender_id != p_recipient_id
+        AND (cm.delivered_at IS NULL OR m.created_at > cm.delivered_at);
+
+        UPDATE conversation_members
+        SET delivered_at = NOW(), unread_count = 0
+        WHERE conversation_id = p_conversation_id AND user_id = p_recipient_id AND left_at IS NULL;
+
+        PERFORM settle_group_message_status(p_conversation_id);
+        RETURN updated_count;
+    END IF;
+
+    WITH updated AS (
+        UPDATE messages
+        SET
+            status = CASE WHEN status = 'sent' THEN 'delivered' ELSE status END,
+            delivered_at = COALESCE(delivered_at, NOW()),
+            downloaded_by_recipient = TRUE,
+            delete_scheduled_at = COALESCE(delete_scheduled_at, NOW() + INTERVAL '24 hours')
+        WHERE conversation_id = p_conversation_id
+        AND sender_id != p_recipient_id  -- Only mark messages FROM the other person
+        AND downloaded_by_recipient = FALSE
+        RETURNING id
+    )
+    SELECT COUNT(*) INTO updated_count FROM updated;
+
+    -- Update unread count in conversation
+    UPDATE conversations
+    SET
+        unread_count_p1 = CASE WHEN participant1_id = p_recipient_id THEN 0 ELSE unread_count_p1 END,
+        unread_count_p2 = CASE WHEN participant2_id = p_recipient_id THEN 0 ELSE unread_count_p2 END
+    WHERE id = p_conversation_id;
+
+    RETURN updated_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Mark one message as delivered to one recipient. In a 1-on-1 chat that is the same
+-- as mark_message_delivered; in a group it advances the recipient's watermark.
+DROP FUNCTION IF EXISTS mark_message_delivered_to(UUID, UUID);
+CREATE OR REPLACE FUNCTION mark_message_delivered_to(p_message_id UUID, p_recipient_id UUID)
+RETURNS VOID AS $$
+DECLARE
+    msg RECORD;
+BEGIN
+    SELECT m.conversation_id, m.created_at, c.is_group INTO msg
+    FROM messages m JOIN conversations c ON c.id = m.conversation_id
+    WHERE m.id = p_message_id;
+
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    IF NOT msg.is_group THEN
+        PERFORM mark_message_delivered(p_message_id);
+        RETURN;
+    END IF;
+
+    UPDATE conversation_members
+    SET delivered_at = GREATEST(COALESCE(delivered_at, msg.created_at), msg.created_at)
+    WHERE conversation_id = msg.conversation_id AND user_id = p_recipient_id AND left_at IS NULL;
+
+    PERFORM settle_group_message_status(msg.conversation_id);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get pending messages for a user, including group messages past their watermark
+CREATE OR REPLACE FUNCTION get_pending_messages(p_user_id UUID)
+RETURNS TABLE (
+    message_id UUID,
+    conversation_id UUID,
+    sender_id UUID,
+    content TEXT,
+    encrypted_content TEXT,
+    encryption_version INTEGER,
+    message_type VARCHAR,
+    created_at TIMESTAMP,
+    reply_to_id UUID,
+    attachment_url TEXT,
+    attachment_name TEXT,
+    attachment_type VARCHAR
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT * FROM (
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type
+        FROM messages m
+        JOIN conversations c ON m.conversation_id = c.id
+        WHERE (c.participant1_id = p_user_id OR c.participant2_id = p_user_id)
+        AND m.sender_id != p_user_id  -- Messages TO this user
+        AND m.downloaded_by_recipient = FALSE
+        AND m.status = 'sent'
+
+        UNION ALL
+
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type
+        FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE cm.user_id = p_user_id
+        AND cm.left_at IS NULL
+        AND m.sender_id != p_user_id
+        AND m.created_at >= cm.joined_at
+        AND (cm.delivered_at IS NULL OR m.created_at > cm.delivered_at)
+    ) pending
+    ORDER BY 8 ASC;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON TABLE conversation_members IS 'Members of group conversations; left_at is set when a member leaves or is removed';
+COMMENT ON COLUMN conversation_members.delivered_at IS 'Every message sent up to this time has reached the member';
+COMMENT ON COLUMN conversation_members.read_at IS 'Every message sent up to this time has been read by the member';
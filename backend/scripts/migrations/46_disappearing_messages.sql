@@ -0,0 +1,185 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 46: DISAPPEARING MESSAGES
+-- ============================================================================
+-- Contains: Per-conversation message TTL, message expiry, expired message purge
+-- Dependencies: 05_messaging.sql, 45_group_conversations.sql
+-- ============================================================================
+
+-- NULL leaves messages in place; otherwise new messages expire this long after sending
+ALTER TABLE conversations
+    ADD COLUMN IF NOT EXISTS message_ttl_seconds INTEGER CHECK (message_ttl_seconds > 0);
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+-- The purge job walks expired messages oldest first
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at
+    ON messages (expires_at)
+    WHERE expires_at IS NOT NULL;
+
+-- Stamp the expiry when a message is sent, so changing the setting later only
+-- affects messages sent after the change. System messages (such as the notice of
+-- the change itself) stay.
+CREATE OR REPLACE FUNCTION set_message_expiry()
+RETURNS TRIGGER AS $$
+DECLARE
+    ttl INTEGER;
+BEGIN
+    IF NEW.message_type = 'system' OR NEW.expires_at IS NOT NULL THEN
+        RETURN NEW;
+    END IF;
+
+    SELECT message_ttl_seconds INTO ttl FROM conversations WHERE id = NEW.conversation_id;
+    IF ttl IS NOT NULL THEN
+        NEW.expires_at := COALESCE(NEW.created_at, NOW()) + make_interval(secs => ttl);
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_set_message_expiry ON messages;
+CREATE TRIGGER trigger_set_message_expiry
+    BEFORE INSERT ON messages
+    FOR EACH ROW
+    EXECUTE FUNCTION set_message_expiry();
+
+-- Forwarded copies point at the same attachment, so the purge looks up other users
+-- of a file before deleting it
+CREATE INDEX IF NOT EXISTS idx_messages_attachment_url
+    ON messages (attachment_url)
+    WHERE attachment_url IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_thumbnail_url
+    ON messages (thumbnail_url)
+    WHERE thumbnail_url IS NOT NULL;
+
+-- Expired messages nobody has starred, oldest first. Starred messages are kept until
+-- every star is removed. Attachments another message still uses come back NULL so
+-- the purge leaves the file alone.
+DROP FUNCTION IF EXISTS get_expired_messages(INTEGER);
+CREATE OR REPLACE FUNCTION get_expired_messages(p_limit INTEGER)
+RETURNS TABLE (
+    id UUID,
+    conversation_id UUID,
+    sender_id UUID,
+    message_type VARCHAR,
+    attachment_url TEXT,
+    thumbnail_url TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+    SELECT
+        m.id,
+        m.conversation_id,
+        m.sender_id,
+        m.message_type::VARCHAR,
+        CASE WHEN EXISTS (
+            SELECT 1 FROM messages o WHERE o.attachment_url = m.attachment_url AND o.id != m.id
+        ) THEN NULL ELSE m.attachment_url END,
+        CASE WHEN EXISTS (
+            SELECT 1 FROM messages o WHERE o.thumbnail_url = m.thumbnail_url AND o.id != m.id
+        ) THEN NULL ELSE m.thumbnail_url END,
+        m.expires_at
+    FROM messages m
+    WHERE m.expires_at IS NOT NULL
+    AND m.expires_at <= NOW()
+    AND NOT EXISTS (SELECT 1 FROM starred_messages s WHERE s.message_id = m.id)
+    ORDER BY m.expires_at ASC
+    LIMIT p_limit;
+$$ LANGUAGE sql STABLE;
+
+-- Hard-deletes the given messages, rechecking that they are still expired and
+-- unstarred in case one was starred after it was fetched
+DROP FUNCTION IF EXISTS delete_expired_messages(UUID[]);
+CREATE OR REPLACE FUNCTION delete_expired_messages(p_message_ids UUID[])
+RETURNS INTEGER AS $$
+DECLARE
+    deleted_count INTEGER;
+BEGIN
+    WITH deleted AS (
+        DELETE FROM messages m
+        WHERE m.id = ANY(p_message_ids)
+        AND m.expires_at IS NOT NULL
+        AND m.expires_at <= NOW()
+        AND NOT EXISTS (SELECT 1 FROM starred_messages s WHERE s.message_id = m.id)
+        RETURNING m.id
+    )
+    SELECT COUNT(*) INTO deleted_count FROM deleted;
+
+    RETURN deleted_count;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The store-and-forward sync now carries the expiry so clients can count down
+DROP FUNCTION IF EXISTS get_pending_messages(UUID);
+CREATE OR REPLACE FUNCTION get_pending_messages(p_user_id UUID)
+RETURNS TABLE (
+    message_id UUID,
+    conversation_id UUID,
+    sender_id UUID,
+    content TEXT,
+    encrypted_content TEXT,
+    encryption_version INTEGER,
+    message_type VARCHAR,
+    created_at TIMESTAMP,
+    reply_to_id UUID,
+    attachment_url TEXT,
+    attachment_name TEXT,
+    attachment_type VARCHAR,
+    expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT * FROM (
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type,
+            m.expires_at
+        FROM messages m
+        JOIN conversations c ON m.conversation_id = c.id
+        WHERE (c.participant1_id = p_user_id OR c.participant2_id = p_user_id)
+        AND m.sender_id != p_user_id  -- Messages TO this user
+        AND m.downloaded_by_recipient = FALSE
+        AND m.status = 'sent'
+
+        UNION ALL
+
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type,
+            m.expires_at
+        FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE cm.user_id = p_user_id
+        AND cm.left_at IS NULL
+        AND m.sender_id != p_user_id
+        AND m.created_at >= cm.joined_at
+        AND (cm.delivered_at IS NULL OR m.created_at > cm.delivered_at)
+    ) pending
+    WHERE pending.expires_at IS NULL OR pending.expires_at > NOW()
+    ORDER BY 8 ASC;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON COLUMN conversations.message_ttl_seconds IS 'Disappearing messages: seconds new messages live after sending; NULL when off';
+COMMENT ON COLUMN messages.expires_at IS 'When the purge job hard-deletes the message and its media, unless it is starred';
@@ -0,0 +1,165 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 47: MESSAGE FULL-TEXT SEARCH
+-- ============================================================================
+-- Contains: tsvector on message content, ranked and highlighted message search
+-- Dependencies: 05_messaging.sql, 40_message_search_index.sql,
+--               45_group_conversations.sql, 46_disappearing_messages.sql
+-- ============================================================================
+
+-- Encrypted messages have empty content, so only plaintext is searchable
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('english'::regconfig, COALESCE(content, ''))) STORED;
+
+-- Searches are always scoped to conversations, so the index leads with
+-- conversation_id the same way the trigram index did (btree_gin, from 40)
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_search_vector
+    ON messages USING gin (conversation_id, search_vector);
+
+-- Search no longer uses ILIKE; the trigram index only slowed down writes
+DROP INDEX IF EXISTS idx_messages_conversation_content_trgm;
+
+-- Searches the messages p_user_id can read: their 1-on-1 chats, and the groups they
+-- are in, from when they joined. Optionally narrowed to one conversation, one
+-- sender and a [p_from, p_to) time range. Messages the user deleted, expired
+-- disappearing messages and system messages are left out.
+--
+-- Best matches come first. Each row carries a snippet with the matched terms in
+-- <mark></mark>, the number of matches overall, and the messages either side of
+-- the match so the client can show it in context and jump to it.
+DROP FUNCTION IF EXISTS search_messages(UUID, TEXT, UUID, UUID, TIMESTAMPTZ, TIMESTAMPTZ, INTEGER, INTEGER);
+CREATE OR REPLACE FUNCTION search_messages(
+    p_user_id UUID,
+    p_query TEXT,
+    p_conversation_id UUID DEFAULT NULL,
+    p_sender_id UUID DEFAULT NULL,
+    p_from TIMESTAMPTZ DEFAULT NULL,
+    p_to TIMESTAMPTZ DEFAULT NULL,
+    p_limit INTEGER DEFAULT 20,
+    p_offset INTEGER DEFAULT 0
+)
+RETURNS TABLE (
+    id UUID,
+    conversation_id UUID,
+    sender_id UUID,
+    content TEXT,
+    message_type VARCHAR,
+    attachment_url TEXT,
+    attachment_name TEXT,
+    reply_to_id UUID,
+    created_at TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    rank REAL,
+    snippet TEXT,
+    total_count BIGINT,
+    sender JSONB,
+    before_message JSONB,
+    after_message JSONB
+) AS $$
+    WITH q AS (
+        SELECT websearch_to_tsquery('english', p_query) AS query
+    ),
+    readable AS (
+        SELECT c.id AS conversation_id, NULL::TIMESTAMPTZ AS since
+        FROM conversations c
+        WHERE (c.participant1_id = p_user_id OR c.participant2_id = p_user_id)
+        AND (p_conversation_id IS NULL OR c.id = p_conversation_id)
+
+        UNION ALL
+
+        SELECT cm.conversation_id, cm.joined_at
+        FROM conversation_members cm
+        WHERE cm.user_id = p_user_id
+        AND cm.left_at IS NULL
+        AND (p_conversation_id IS NULL OR cm.conversation_id = p_conversation_id)
+    ),
+    page AS (
+        -- Paged before the snippets and context are built, so only this page pays for them
+        SELECT
+            m.*,
+            r.since,
+            ts_rank_cd(m.search_vector, q.query) AS hit_rank,
+            COUNT(*) OVER () AS hit_count
+        FROM readable r
+        JOIN messages m ON m.conversation_id = r.conversation_id
+        CROSS JOIN q
+        WHERE m.search_vector @@ q.query
+        AND m.message_type != 'system'
+        AND (r.since IS NULL OR m.created_at >= r.since)
+        AND (m.deleted_by IS NULL OR NOT p_user_id = ANY(m.deleted_by))
+        AND (m.expires_at IS NULL OR m.expires_at > NOW())
+        AND (p_sender_id IS NULL OR m.sender_id = p_sender_id)
+        AND (p_from IS NULL OR m.created_at >= p_from)
+        AND (p_to IS NULL OR m.created_at < p_to)
+        ORDER BY hit_rank DESC, m.created_at DESC
+        LIMIT p_limit OFFSET p_offset
+    )
+    SELECT
+        p.id,
+        p.conversation_id,
+        p.sender_id,
+        p.content,
+        p.message_type::VARCHAR,
+        p.attachment_url,
+        p.attachment_name,
+        p.reply_to_id,
+        p.created_at,
+        p.expires_at,
+        p.hit_rank,
+        ts_headline('english', p.content, q.query,
+            'StartSel=<mark>, StopSel=</mark>, MinWords=8, MaxWords=24, MaxFragments=2, FragmentDelimiter=" … "'),
+        p.hit_count,
+        jsonb_build_object(
+            'id', u.id,
+            'username', u.username,
+            'display_name', u.display_name,
+            'profile_picture', u.profile_picture
+        ),
+        before_msg.msg,
+        after_msg.msg
+    FROM page p
+    CROSS JOIN q
+    LEFT JOIN users u ON u.id = p.sender_id
+    LEFT JOIN LATERAL (
+        SELECT jsonb_build_object(
+            'id', b.id,
+            'conversation_id', b.conversation_id,
+            'sender_id', b.sender_id,
+            'content', b.content,
+            'encrypted_content', b.encrypted_content,
+            'content_iv', b.content_iv,
+            'message_type', b.message_type,
+            'created_at', b.created_at
+        ) AS msg
+        FROM messages b
+        WHERE b.conversation_id = p.conversation_id
+        AND b.created_at < p.created_at
+        AND (p.since IS NULL OR b.created_at >= p.since)
+        AND (b.deleted_by IS NULL OR NOT p_user_id = ANY(b.deleted_by))
+        AND (b.expires_at IS NULL OR b.expires_at > NOW())
+        ORDER BY b.created_at DESC
+        LIMIT 1
+    ) before_msg ON TRUE
+    LEFT JOIN LATERAL (
+        SELECT jsonb_build_object(
+            'id', a.id,
+            'conversation_id', a.conversation_id,
+            'sender_id', a.sender_id,
+            'content', a.content,
+            'encrypted_content', a.encrypted_content,
+            'content_iv', a.content_iv,
+            'message_type', a.message_type,
+            'created_at', a.created_at
+        ) AS msg
+        FROM messages a
+        WHERE a.conversation_id = p.conversation_id
+        AND a.created_at > p.created_at
+        AND (a.deleted_by IS NULL OR NOT p_user_id = ANY(a.deleted_by))
+        AND (a.expires_at IS NULL OR a.expires_at > NOW())
+        ORDER BY a.created_at ASC
+        LIMIT 1
+    ) after_msg ON TRUE
+    ORDER BY p.hit_rank DESC, p.created_at DESC;
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON COLUMN messages.search_vector IS 'Full-text index of plaintext content; empty for encrypted messages';
@@ -0,0 +1,145 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 48: MESSAGE DELIVERY RETRIES
+-- ============================================================================
+-- Contains: Per-recipient delivery attempt tracking, 'failed' message status
+-- Dependencies: 05_messaging.sql, 09_dm_store_and_forward.sql,
+--               45_group_conversations.sql, 46_disappearing_messages.sql
+-- ============================================================================
+
+-- A message the server gave up delivering is shown to its sender as failed
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages
+    ADD CONSTRAINT messages_status_check CHECK (status IN ('sent', 'delivered', 'read', 'failed'));
+
+-- One row per message and recipient while delivery is being retried. The row is
+-- removed once the message gets through.
+CREATE TABLE IF NOT EXISTS message_delivery_attempts (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    recipient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    failed_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (message_id, recipient_id)
+);
+
+ALTER TABLE message_delivery_attempts ENABLE ROW LEVEL SECURITY;
+
+-- Counts a failed delivery attempt and returns the new count. Once p_max_attempts
+-- is reached the attempt is recorded as failed and a 1-on-1 message that still
+-- hasn't been delivered moves to 'failed'. Group messages keep their status, which
+-- sums up every member; the sender is told about the member it failed for.
+DROP FUNCTION IF EXISTS record_delivery_failure(UUID, UUID, TEXT, INTEGER);
+CREATE OR REPLACE FUNCTION record_delivery_failure(
+    p_message_id UUID,
+    p_recipient_id UUID,
+    p_error TEXT,
+    p_max_attempts INTEGER
+)
+RETURNS TABLE (attempts INTEGER, failed BOOLEAN) AS $$
+DECLARE
+    attempt_count INTEGER;
+BEGIN
+    INSERT INTO message_delivery_attempts AS a (message_id, recipient_id, attempts, last_error)
+    VALUES (p_message_id, p_recipient_id, 1, p_error)
+    ON CONFLICT (message_id, recipient_id) DO UPDATE
+    SET attempts = a.attempts + 1,
+        last_error = EXCLUDED.last_error,
+        last_attempt_at = NOW()
+    RETURNING a.attempts INTO attempt_count;
+
+    IF attempt_count < p_max_attempts THEN
+        RETURN QUERY SELECT attempt_count, FALSE;
+        RETURN;
+    END IF;
+
+    UPDATE message_delivery_attempts
+    SET failed_at = COALESCE(failed_at, NOW())
+    WHERE message_id = p_message_id AND recipient_id = p_recipient_id;
+
+    UPDATE messages m
+    SET status = 'failed'
+    FROM conversations c
+    WHERE m.id = p_message_id
+    AND c.id = m.conversation_id
+    AND NOT c.is_group
+    AND m.status = 'sent';
+
+    RETURN QUERY SELECT attempt_count, TRUE;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Failed 1-on-1 messages are still handed over when the recipient syncs, which
+-- moves them on to 'delivered'
+DROP FUNCTION IF EXISTS get_pending_messages(UUID);
+CREATE OR REPLACE FUNCTION get_pending_messages(p_user_id UUID)
+RETURNS TABLE (
+    message_id UUID,
+    conversation_id UUID,
+    sender_id UUID,
+    content TEXT,
+    encrypted_content TEXT,
+    encryption_version INTEGER,
+    message_type VARCHAR,
+    created_at TIMESTAMP,
+    reply_to_id UUID,
+    attachment_url TEXT,
+    attachment_name TEXT,
+    attachment_type VARCHAR,
+    expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT * FROM (
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type,
+            m.expires_at
+        FROM messages m
+        JOIN conversations c ON m.conversation_id = c.id
+        WHERE (c.participant1_id = p_user_id OR c.participant2_id = p_user_id)
+        AND m.sender_id != p_user_id  -- Messages TO this user
+        AND m.downloaded_by_recipient = FALSE
+        AND m.status IN ('sent', 'failed')
+
+        UNION ALL
+
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type,
+            m.expires_at
+        FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE cm.user_id = p_user_id
+        AND cm.left_at IS NULL
+        AND m.sender_id != p_user_id
+        AND m.created_at >= cm.joined_at
+        AND (cm.delivered_at IS NULL OR m.created_at > cm.delivered_at)
+    ) pending
+    WHERE pending.expires_at IS NULL OR pending.expires_at > NOW()
+    ORDER BY 8 ASC;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON TABLE message_delivery_attempts IS 'Delivery retries in progress, per message and recipient; failed_at is set once the server gives up';
+COMMENT ON COLUMN messages.status IS 'sent=✓, delivered=✓✓, read=✓✓ (blue), failed=delivery retries ran out';
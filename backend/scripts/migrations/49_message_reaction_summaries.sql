@@ -0,0 +1,53 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 49: MESSAGE REACTION SUMMARIES
+-- ============================================================================
+-- Contains: Per-emoji reaction counts for a page of messages
+-- Dependencies: 05_messaging.sql
+-- ============================================================================
+
+-- Reaction counts for each of p_message_ids, one row per message and emoji, with
+-- whether p_user_id used that emoji. Only the p_max_emojis most used emojis of each
+-- message are returned (ties go to the emoji used first); reactor_count still counts
+-- everyone who reacted, so the client can show how many are left out.
+DROP FUNCTION IF EXISTS get_reaction_summaries(UUID[], UUID, INTEGER);
+CREATE OR REPLACE FUNCTION get_reaction_summaries(
+    p_message_ids UUID[],
+    p_user_id UUID,
+    p_max_emojis INTEGER DEFAULT 6
+)
+RETURNS TABLE (
+    message_id UUID,
+    emoji VARCHAR,
+    reaction_count BIGINT,
+    reacted_by_me BOOLEAN,
+    reactor_count BIGINT
+) AS $$
+    WITH counts AS (
+        SELECT
+            r.message_id,
+            r.emoji,
+            COUNT(*) AS reaction_count,
+            BOOL_OR(r.user_id = p_user_id) AS reacted_by_me,
+            MIN(r.created_at) AS first_at
+        FROM message_reactions r
+        WHERE r.message_id = ANY(p_message_ids)
+        GROUP BY r.message_id, r.emoji
+    ),
+    ranked AS (
+        SELECT
+            c.*,
+            ROW_NUMBER() OVER (PARTITION BY c.message_id ORDER BY c.reaction_count DESC, c.first_at ASC) AS position,
+            -- One reaction per user per message, so this is the number of reactors
+            SUM(c.reaction_count) OVER (PARTITION BY c.message_id) AS reactor_count
+        FROM counts c
+    )
+    SELECT
+        ranked.message_id,
+        ranked.emoji::VARCHAR,
+        ranked.reaction_count,
+        ranked.reacted_by_me,
+        ranked.reactor_count::BIGINT
+    FROM ranked
+    WHERE ranked.position <= p_max_emojis
+    ORDER BY ranked.message_id, ranked.position;
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,13 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 50: VOICE MESSAGE WAVEFORMS
+-- ============================================================================
+-- Contains: Waveform peaks for audio messages
+-- Dependencies: 05_messaging.sql
+-- ============================================================================
+
+-- Extracted from the audio when it's uploaded; empty or NULL when it couldn't be decoded
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS waveform REAL[]
+    CHECK (waveform IS NULL OR cardinality(waveform) <= 64);
+
+COMMENT ON COLUMN messages.waveform IS 'Voice messages: peak amplitude of each waveform bar, scaled 0-1';
@@ -0,0 +1,60 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 51: SCHEDULED MESSAGES
+-- ============================================================================
+-- Contains: Messages queued to send later, claiming of due scheduled messages
+-- Dependencies: 05_messaging.sql
+-- ============================================================================
+
+-- A message its sender asked to send later. Nothing is added to the conversation
+-- until it sends: message holds the request as the sender made it, and message_id
+-- points at the message once it's out. failure_reason says why one couldn't be sent.
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message JSONB NOT NULL,
+    send_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sending', 'sent', 'cancelled', 'failed')),
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- The send job walks pending messages by due time
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due
+    ON scheduled_messages (send_at)
+    WHERE status = 'pending';
+
+-- A sender's queue in one conversation
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_sender
+    ON scheduled_messages (sender_id, conversation_id, send_at)
+    WHERE status = 'pending';
+
+ALTER TABLE scheduled_messages ENABLE ROW LEVEL SECURITY;
+
+-- Claims up to p_limit due messages, moving them to 'sending' so that they can no
+-- longer be edited or cancelled and another instance running the job skips them.
+-- A claim older than p_stale_seconds was left by an instance that stopped mid-send
+-- and is taken over.
+DROP FUNCTION IF EXISTS claim_due_scheduled_messages(INTEGER, INTEGER);
+CREATE OR REPLACE FUNCTION claim_due_scheduled_messages(p_limit INTEGER, p_stale_seconds INTEGER)
+RETURNS SETOF scheduled_messages AS $$
+    UPDATE scheduled_messages s
+    SET status = 'sending',
+        updated_at = NOW()
+    WHERE s.id IN (
+        SELECT d.id
+        FROM scheduled_messages d
+        WHERE (d.status = 'pending' AND d.send_at <= NOW())
+        OR (d.status = 'sending' AND d.updated_at <= NOW() - make_interval(secs => p_stale_seconds))
+        ORDER BY d.send_at ASC
+        LIMIT p_limit
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING s.*;
+$$ LANGUAGE sql;
+
+COMMENT ON TABLE scheduled_messages IS 'Messages waiting to be sent at send_at; editable and cancellable by the sender while pending';
+COMMENT ON COLUMN scheduled_messages.message IS 'The send request: content or encrypted content, type, attachment, reply';
@@ -0,0 +1,101 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 52: COURSE PROGRESS
+-- ============================================================================
+-- Contains: Enrollment progress recomputed from completed lessons, completion
+-- Dependencies: 21_course_enrollment_counters.sql, course_lessons, lesson_progress
+--               (learning platform schema)
+-- ============================================================================
+
+-- Recomputes an enrollment's progress_percentage as the share of the course's current
+-- lessons it has completed, so lessons added or removed after enrolling are taken
+-- into account. The enrollment row is locked first, so concurrent lesson completions
+-- are applied one after the other and the last one sees them all.
+--
+-- At 100% the enrollment is completed: completed_at is set and the course's
+-- completion_count goes up, once. newly_completed is true only for that call. A
+-- completed enrollment stays complete, at 100%, even if lessons are added later.
+DROP FUNCTION IF EXISTS recompute_enrollment_progress(UUID);
+CREATE OR REPLACE FUNCTION recompute_enrollment_progress(p_enrollment_id UUID)
+RETURNS TABLE (progress_percentage NUMERIC, newly_completed BOOLEAN) AS $$
+DECLARE
+    enrollment RECORD;
+    total_lessons INTEGER;
+    completed_lessons INTEGER;
+    pct NUMERIC;
+BEGIN
+    SELECT e.id, e.course_id, e.completed_at
+    INTO enrollment
+    FROM course_enrollments e
+    WHERE e.id = p_enrollment_id
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    IF enrollment.completed_at IS NOT NULL THEN
+        UPDATE course_enrollments SET progress_percentage = 100 WHERE id = p_enrollment_id;
+        RETURN QUERY SELECT 100::NUMERIC, FALSE;
+        RETURN;
+    END IF;
+
+    SELECT COUNT(*) INTO total_lessons
+    FROM course_lessons l
+    WHERE l.course_id = enrollment.course_id;
+
+    -- Only lessons still in the course count
+    SELECT COUNT(DISTINCT p.lesson_id) INTO completed_lessons
+    FROM lesson_progress p
+    JOIN course_lessons l ON l.id = p.lesson_id AND l.course_id = enrollment.course_id
+    WHERE p.enrollment_id = p_enrollment_id
+    AND p.is_completed;
+
+    IF total_lessons = 0 THEN
+        pct := 0;
+    ELSE
+        pct := ROUND(completed_lessons * 100.0 / total_lessons, 2);
+    END IF;
+
+    IF total_lessons > 0 AND completed_lessons >= total_lessons THEN
+        UPDATE course_enrollments
+        SET progress_percentage = 100,
+            completed_at = NOW()
+        WHERE id = p_enrollment_id;
+
+        PERFORM adjust_course_completion_count(enrollment.course_id, 1);
+
+        RETURN QUERY SELECT 100::NUMERIC, TRUE;
+        RETURN;
+    END IF;
+
+    UPDATE course_enrollments SET progress_percentage = pct WHERE id = p_enrollment_id;
+    RETURN QUERY SELECT pct, FALSE;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Recomputes every unfinished enrollment in a course after its lessons change, and
+-- returns the enrollments that this completed (say, by removing the last lesson a
+-- learner hadn't done)
+DROP FUNCTION IF EXISTS recompute_course_progress(UUID);
+CREATE OR REPLACE FUNCTION recompute_course_progress(p_course_id UUID)
+RETURNS TABLE (enrollment_id UUID, user_id UUID) AS $$
+DECLARE
+    e RECORD;
+BEGIN
+    FOR e IN
+        SELECT ce.id, ce.user_id
+        FROM course_enrollments ce
+        WHERE ce.course_id = p_course_id
+        AND ce.completed_at IS NULL
+    LOOP
+        IF (SELECT r.newly_completed FROM recompute_enrollment_progress(e.id) r) THEN
+            enrollment_id := e.id;
+            user_id := e.user_id;
+            RETURN NEXT;
+        END IF;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION recompute_enrollment_progress(UUID) IS 'Set progress_percentage from completed lessons, completing the enrollment at 100%';
+COMMENT ON FUNCTION recompute_course_progress(UUID) IS 'Recompute unfinished enrollments of a course after lessons are added or removed';
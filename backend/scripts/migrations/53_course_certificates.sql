@@ -0,0 +1,19 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 53: COURSE CERTIFICATES
+-- ============================================================================
+-- Contains: One certificate per enrollment
+-- Dependencies: course_certificates (learning platform schema)
+-- ============================================================================
+
+-- Certificates are issued when an enrollment completes and again, if missing, when
+-- the learner asks for theirs; the two can race. Keep the oldest of any duplicates
+-- so the number a learner may already have shared stays valid.
+DELETE FROM course_certificates c
+USING course_certificates older
+WHERE c.enrollment_id = older.enrollment_id
+AND (older.issued_at, older.id) < (c.issued_at, c.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_course_certificates_enrollment
+    ON course_certificates (enrollment_id);
+
+COMMENT ON COLUMN course_certificates.certificate_url IS 'The rendered PDF in storage; sign it to download';
@@ -0,0 +1,66 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 54: COURSE QUIZZES
+-- ============================================================================
+-- Contains: Quizzes on quiz lessons, their questions, learners' attempts
+-- Dependencies: course_lessons, course_enrollments (learning platform schema)
+-- ============================================================================
+
+-- The quiz of a lesson with lesson_type 'quiz'. A learner passes by scoring at
+-- least pass_threshold percent of the quiz's points, and can't complete the
+-- lesson until they have.
+CREATE TABLE IF NOT EXISTS quizzes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    lesson_id UUID NOT NULL UNIQUE REFERENCES course_lessons(id) ON DELETE CASCADE,
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    pass_threshold NUMERIC(5,2) NOT NULL DEFAULT 70
+        CHECK (pass_threshold > 0 AND pass_threshold <= 100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- options is an array of {id, text}; correct_options holds the ids of the right
+-- ones, exactly one for single_choice. Never sent to learners.
+CREATE TABLE IF NOT EXISTS quiz_questions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    question_text TEXT NOT NULL,
+    question_type VARCHAR(20) NOT NULL DEFAULT 'single_choice'
+        CHECK (question_type IN ('single_choice', 'multiple_choice')),
+    options JSONB NOT NULL,
+    correct_options TEXT[] NOT NULL,
+    points INTEGER NOT NULL DEFAULT 1 CHECK (points > 0),
+    explanation TEXT,
+    order_index INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_questions_quiz
+    ON quiz_questions (quiz_id, order_index);
+
+-- Every submission is kept. answers maps question ids to the option ids chosen.
+CREATE TABLE IF NOT EXISTS quiz_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    lesson_id UUID NOT NULL REFERENCES course_lessons(id) ON DELETE CASCADE,
+    enrollment_id UUID NOT NULL REFERENCES course_enrollments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    answers JSONB NOT NULL DEFAULT '{}',
+    points_earned INTEGER NOT NULL,
+    points_possible INTEGER NOT NULL,
+    score NUMERIC(5,2) NOT NULL,
+    passed BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A learner's attempts at a lesson's quiz, and whether any passed
+CREATE INDEX IF NOT EXISTS idx_quiz_attempts_enrollment
+    ON quiz_attempts (enrollment_id, lesson_id, created_at DESC);
+
+ALTER TABLE quizzes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE quiz_questions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE quiz_attempts ENABLE ROW LEVEL SECURITY;
+
+COMMENT ON TABLE quizzes IS 'Quiz of a quiz lesson; passing it is required to complete the lesson';
+COMMENT ON COLUMN quiz_questions.correct_options IS 'Ids of the correct options; hidden from learners';
+COMMENT ON TABLE quiz_attempts IS 'Every quiz submission with its score';
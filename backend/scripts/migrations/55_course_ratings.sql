@@ -0,0 +1,79 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 55: COURSE RATINGS
+-- ============================================================================
+-- Contains: average_rating/review_count kept in step with course_reviews,
+--           reconciliation
+-- Dependencies: courses, course_reviews (learning platform schema)
+-- ============================================================================
+
+-- Sets a course's average_rating and review_count from its reviews
+DROP FUNCTION IF EXISTS recompute_course_rating(UUID);
+CREATE OR REPLACE FUNCTION recompute_course_rating(p_course_id UUID)
+RETURNS VOID AS $$
+    UPDATE courses c
+    SET average_rating = r.average_rating,
+        review_count = r.review_count
+    FROM (
+        SELECT COALESCE(ROUND(AVG(rating), 2), 0) AS average_rating,
+               COUNT(*)::INTEGER AS review_count
+        FROM course_reviews
+        WHERE course_id = p_course_id
+    ) r
+    WHERE c.id = p_course_id;
+$$ LANGUAGE sql;
+
+-- Recomputes from scratch rather than adjusting by the change, so concurrent review
+-- writes can't drift the average. A review moved to another course updates both.
+DROP FUNCTION IF EXISTS course_reviews_recompute_rating() CASCADE;
+CREATE OR REPLACE FUNCTION course_reviews_recompute_rating()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM recompute_course_rating(NEW.course_id);
+    END IF;
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.course_id IS DISTINCT FROM NEW.course_id) THEN
+        PERFORM recompute_course_rating(OLD.course_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS course_reviews_rating ON course_reviews;
+CREATE TRIGGER course_reviews_rating
+    AFTER INSERT OR DELETE OR UPDATE OF rating, course_id ON course_reviews
+    FOR EACH ROW EXECUTE FUNCTION course_reviews_recompute_rating();
+
+-- Repairs courses whose rating doesn't match their reviews, returning how many
+-- were corrected
+DROP FUNCTION IF EXISTS reconcile_course_ratings();
+CREATE OR REPLACE FUNCTION reconcile_course_ratings()
+RETURNS INTEGER AS $$
+DECLARE
+    corrected INTEGER;
+BEGIN
+    WITH actual AS (
+        SELECT c.id,
+               COALESCE(ROUND(AVG(r.rating), 2), 0) AS average_rating,
+               COUNT(r.id)::INTEGER AS review_count
+        FROM courses c
+        LEFT JOIN course_reviews r ON r.course_id = c.id
+        GROUP BY c.id
+    )
+    UPDATE courses
+    SET average_rating = actual.average_rating,
+        review_count = actual.review_count
+    FROM actual
+    WHERE courses.id = actual.id
+      AND (courses.average_rating IS DISTINCT FROM actual.average_rating
+           OR courses.review_count IS DISTINCT FROM actual.review_count);
+
+    GET DIAGNOSTICS corrected = ROW_COUNT;
+    RETURN corrected;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Bring existing courses up to date
+SELECT reconcile_course_ratings();
+
+COMMENT ON FUNCTION recompute_course_rating(UUID) IS 'Set courses.average_rating/review_count from course_reviews';
+COMMENT ON FUNCTION reconcile_course_ratings() IS 'Repair course ratings that drifted from course_reviews';
@@ -0,0 +1,73 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 56: COURSE COUPONS
+-- ============================================================================
+-- Contains: Discount codes for courses, atomic redemption
+-- Dependencies: courses, course_enrollments (learning platform schema)
+-- ============================================================================
+
+-- A discount code. course_id limits it to one course; a coupon without one is global
+-- and works on any paid course. Codes are stored upper case and unique. A fixed
+-- discount is in its currency and only applies to courses priced in it.
+CREATE TABLE IF NOT EXISTS course_coupons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) NOT NULL,
+    course_id UUID REFERENCES courses(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    discount_type VARCHAR(10) NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+    discount_value NUMERIC(10,2) NOT NULL CHECK (discount_value > 0),
+    currency VARCHAR(3),
+    max_uses INTEGER CHECK (max_uses > 0),
+    used_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (discount_type <> 'percent' OR discount_value <= 100),
+    CHECK (discount_type <> 'fixed' OR currency IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_course_coupons_code ON course_coupons (code);
+CREATE INDEX IF NOT EXISTS idx_course_coupons_course ON course_coupons (course_id);
+
+ALTER TABLE course_coupons ENABLE ROW LEVEL SECURITY;
+
+-- The coupon an enrollment was bought with
+ALTER TABLE course_enrollments
+    ADD COLUMN IF NOT EXISTS coupon_id UUID REFERENCES course_coupons(id) ON DELETE SET NULL;
+
+-- Uses up one redemption of a coupon. The check and the increment happen under the
+-- row's lock, so a coupon with one use left can't be redeemed twice. Returns 'ok',
+-- or why it couldn't be: 'not_found', 'inactive', 'expired' or 'exhausted'.
+DROP FUNCTION IF EXISTS redeem_course_coupon(UUID);
+CREATE OR REPLACE FUNCTION redeem_course_coupon(p_coupon_id UUID)
+RETURNS TEXT AS $$
+DECLARE
+    coupon RECORD;
+BEGIN
+    SELECT * INTO coupon FROM course_coupons WHERE id = p_coupon_id FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN 'not_found';
+    ELSIF NOT coupon.is_active THEN
+        RETURN 'inactive';
+    ELSIF coupon.expires_at IS NOT NULL AND coupon.expires_at <= NOW() THEN
+        RETURN 'expired';
+    ELSIF coupon.max_uses IS NOT NULL AND coupon.used_count >= coupon.max_uses THEN
+        RETURN 'exhausted';
+    END IF;
+
+    UPDATE course_coupons SET used_count = used_count + 1 WHERE id = p_coupon_id;
+    RETURN 'ok';
+END;
+$$ LANGUAGE plpgsql;
+
+-- Gives back a redemption whose enrollment couldn't be created
+DROP FUNCTION IF EXISTS release_course_coupon(UUID);
+CREATE OR REPLACE FUNCTION release_course_coupon(p_coupon_id UUID)
+RETURNS VOID AS $$
+    UPDATE course_coupons
+    SET used_count = GREATEST(used_count - 1, 0)
+    WHERE id = p_coupon_id;
+$$ LANGUAGE sql;
+
+COMMENT ON TABLE course_coupons IS 'Percent or fixed discount codes for one course, or all courses when course_id is null';
+COMMENT ON FUNCTION redeem_course_coupon(UUID) IS 'Atomically use one redemption of a coupon, returning ok or why not';
@@ -0,0 +1,21 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 57: COURSE WISHLIST
+-- ============================================================================
+-- Contains: Courses learners have saved for later
+-- Dependencies: courses (learning platform schema), 01_core_schema.sql
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS course_wishlist (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, course_id)
+);
+
+-- A learner's wishlist, most recently added first
+CREATE INDEX IF NOT EXISTS idx_course_wishlist_user
+    ON course_wishlist (user_id, created_at DESC);
+
+ALTER TABLE course_wishlist ENABLE ROW LEVEL SECURITY;
+
+COMMENT ON TABLE course_wishlist IS 'Courses a learner saved to buy or take later';
@@ -0,0 +1,96 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 58: COURSE ANALYTICS
+-- ============================================================================
+-- Contains: Aggregate course stats for its instructors
+-- Dependencies: courses, course_enrollments, course_lessons, lesson_progress,
+--               course_reviews (learning platform schema)
+-- ============================================================================
+
+CREATE INDEX IF NOT EXISTS idx_course_enrollments_course_enrolled
+    ON course_enrollments (course_id, enrolled_at);
+
+-- Everything the analytics page shows, computed in the database so that no rows
+-- leave it. Enrollments per day cover the last p_days days, including days with
+-- none. The most dropped lesson is the one the fewest enrollees completed, the
+-- earliest in the course on a tie; it's null for a course without lessons.
+DROP FUNCTION IF EXISTS get_course_analytics(UUID, INTEGER);
+CREATE OR REPLACE FUNCTION get_course_analytics(p_course_id UUID, p_days INTEGER DEFAULT 30)
+RETURNS JSONB AS $$
+DECLARE
+    result JSONB;
+BEGIN
+    WITH totals AS (
+        SELECT COUNT(*)::INTEGER AS enrollments,
+               COUNT(completed_at)::INTEGER AS completions,
+               COALESCE(ROUND(AVG(progress_percentage), 2), 0) AS average_progress,
+               COALESCE(SUM(payment_amount) FILTER (WHERE payment_status = 'paid'), 0) AS revenue,
+               COUNT(*) FILTER (WHERE payment_status = 'paid')::INTEGER AS paid_enrollments
+        FROM course_enrollments
+        WHERE course_id = p_course_id
+    ),
+    days AS (
+        SELECT d::DATE AS day
+        FROM generate_series(CURRENT_DATE - (p_days - 1), CURRENT_DATE, INTERVAL '1 day') d
+    ),
+    per_day AS (
+        SELECT days.day, COUNT(e.id)::INTEGER AS enrollments
+        FROM days
+        LEFT JOIN course_enrollments e
+            ON e.course_id = p_course_id
+            AND e.enrolled_at >= days.day
+            AND e.enrolled_at < days.day + 1
+        GROUP BY days.day
+    ),
+    ratings AS (
+        SELECT rating, COUNT(*)::INTEGER AS reviews
+        FROM course_reviews
+        WHERE course_id = p_course_id
+        GROUP BY rating
+    ),
+    lesson_completions AS (
+        SELECT l.id, l.title,
+               COUNT(p.id) FILTER (WHERE p.is_completed)::INTEGER AS completions
+        FROM course_lessons l
+        LEFT JOIN course_modules m ON m.id = l.module_id
+        LEFT JOIN lesson_progress p ON p.lesson_id = l.id
+        WHERE l.course_id = p_course_id
+        GROUP BY l.id, l.title, m.order_index, l.order_index
+        ORDER BY completions ASC, m.order_index ASC, l.order_index ASC
+        LIMIT 1
+    )
+    SELECT jsonb_build_object(
+        'total_enrollments', t.enrollments,
+        'completed_enrollments', t.completions,
+        'completion_rate', CASE WHEN t.enrollments = 0 THEN 0
+                                ELSE ROUND(t.completions * 100.0 / t.enrollments, 2) END,
+        'average_progress', t.average_progress,
+        'paid_enrollments', t.paid_enrollments,
+        'revenue', t.revenue,
+        'enrollments_by_day', (
+            SELECT COALESCE(jsonb_agg(jsonb_build_object('date', day, 'enrollments', enrollments) ORDER BY day), '[]'::JSONB)
+            FROM per_day
+        ),
+        'rating_distribution', (
+            SELECT jsonb_object_agg(stars, COALESCE(r.reviews, 0))
+            FROM generate_series(1, 5) stars
+            LEFT JOIN ratings r ON r.rating = stars
+        ),
+        'most_dropped_lesson', (
+            SELECT jsonb_build_object(
+                'lesson_id', lc.id,
+                'title', lc.title,
+                'completions', lc.completions,
+                'completion_rate', CASE WHEN t.enrollments = 0 THEN 0
+                                        ELSE ROUND(lc.completions * 100.0 / t.enrollments, 2) END
+            )
+            FROM lesson_completions lc
+        )
+    )
+    INTO result
+    FROM totals t;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+COMMENT ON FUNCTION get_course_analytics(UUID, INTEGER) IS 'Enrollment, completion, revenue, rating and lesson drop-off stats for a course';
@@ -0,0 +1,91 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 59: PENDING MESSAGES SINCE A SYNC
+-- ============================================================================
+-- Contains: get_pending_messages with an optional since, for catching up after a
+--           reconnect
+-- Dependencies: 48_message_delivery_retries.sql
+-- ============================================================================
+
+-- A message pushed over a WebSocket is marked delivered as it's sent. If the socket
+-- dropped just then it never arrived, yet it's no longer pending. With p_since, the
+-- time of the client's previous sync, every message sent to the user after it comes
+-- back as well, delivered or not; clients drop the ones they already have by id.
+-- Ties on created_at break on id so that the order is the same on every call.
+DROP FUNCTION IF EXISTS get_pending_messages(UUID);
+DROP FUNCTION IF EXISTS get_pending_messages(UUID, TIMESTAMP WITH TIME ZONE);
+CREATE OR REPLACE FUNCTION get_pending_messages(p_user_id UUID, p_since TIMESTAMP WITH TIME ZONE DEFAULT NULL)
+RETURNS TABLE (
+    message_id UUID,
+    conversation_id UUID,
+    sender_id UUID,
+    content TEXT,
+    encrypted_content TEXT,
+    encryption_version INTEGER,
+    message_type VARCHAR,
+    created_at TIMESTAMP,
+    reply_to_id UUID,
+    attachment_url TEXT,
+    attachment_name TEXT,
+    attachment_type VARCHAR,
+    expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT * FROM (
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type,
+            m.expires_at
+        FROM messages m
+        JOIN conversations c ON m.conversation_id = c.id
+        WHERE (c.participant1_id = p_user_id OR c.participant2_id = p_user_id)
+        AND m.sender_id != p_user_id  -- Messages TO this user
+        AND (
+            (m.downloaded_by_recipient = FALSE AND m.status IN ('sent', 'failed'))
+            OR m.created_at > p_since
+        )
+
+        UNION ALL
+
+        SELECT
+            m.id,
+            m.conversation_id,
+            m.sender_id,
+            m.content,
+            m.encrypted_content,
+            m.encryption_version,
+            m.message_type::VARCHAR,
+            m.created_at,
+            m.reply_to_id,
+            m.attachment_url,
+            m.attachment_name,
+            m.attachment_type,
+            m.expires_at
+        FROM messages m
+        JOIN conversation_members cm ON cm.conversation_id = m.conversation_id
+        WHERE cm.user_id = p_user_id
+        AND cm.left_at IS NULL
+        AND m.sender_id != p_user_id
+        AND m.created_at >= cm.joined_at
+        AND (
+            cm.delivered_at IS NULL
+            OR m.created_at > cm.delivered_at
+            OR m.created_at > p_since
+        )
+    ) pending
+    WHERE pending.expires_at IS NULL OR pending.expires_at > NOW()
+    ORDER BY 8 ASC, 1 ASC;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION get_pending_messages(UUID, TIMESTAMP WITH TIME ZONE) IS 'Messages not yet delivered to a user, plus everything sent to them after p_since';
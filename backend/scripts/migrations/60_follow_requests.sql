@@ -0,0 +1,18 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 60: FOLLOW REQUESTS
+-- ============================================================================
+-- Contains: Notification types for follow requests to private accounts
+-- Dependencies: 02_relationships.sql, 07_notifications.sql
+-- ============================================================================
+
+-- Following a private account leaves a 'pending' follow in user_relationships until
+-- the account accepts it. Pending follows don't count toward followers_count (see
+-- update_relationship_counts), so nothing changes in the relationship tables.
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'follow_request';
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'follow_request_accepted';
+
+-- Incoming and outgoing request lists
+CREATE INDEX IF NOT EXISTS idx_relationships_pending_to ON user_relationships(to_user_id, created_at DESC)
+WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_relationships_pending_from ON user_relationships(from_user_id, created_at DESC)
+WHERE status = 'pending';
@@ -0,0 +1,123 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 61: FOLLOWER AND FOLLOWING LISTS
+-- ============================================================================
+-- Contains: list_follows, list_mutual_followers, get_follow_states
+-- Dependencies: 02_relationships.sql, 16_post_and_user_moderation.sql
+-- ============================================================================
+
+-- blocked_between is true if either user has blocked the other. Lists leave such
+-- users out, so nobody sees someone they blocked or who blocked them.
+DROP FUNCTION IF EXISTS blocked_between(UUID, UUID);
+CREATE OR REPLACE FUNCTION blocked_between(p_a UUID, p_b UUID)
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM blocked_users
+        WHERE (blocker_id = p_a AND blocked_id = p_b)
+           OR (blocker_id = p_b AND blocked_id = p_a)
+    );
+$$ LANGUAGE sql STABLE;
+
+-- A page of p_user_id's followers (p_followers) or of the accounts they follow,
+-- newest follow first. Only approved follows of active accounts are listed, and with
+-- a p_viewer_id, none that are blocked either way with the viewer. total_count is the
+-- size of the whole list.
+DROP FUNCTION IF EXISTS list_follows(UUID, UUID, BOOLEAN, INTEGER, INTEGER);
+CREATE OR REPLACE FUNCTION list_follows(
+    p_user_id UUID,
+    p_viewer_id UUID,
+    p_followers BOOLEAN,
+    p_limit INTEGER,
+    p_offset INTEGER
+)
+RETURNS TABLE (
+    user_id UUID,
+    username VARCHAR,
+    display_name VARCHAR,
+    profile_picture TEXT,
+    bio VARCHAR,
+    is_verified BOOLEAN,
+    followed_at TIMESTAMP,
+    total_count BIGINT
+) AS $$
+    SELECT u.id, u.username, u.display_name, u.profile_picture, u.bio, u.is_verified,
+           r.created_at, COUNT(*) OVER ()
+    FROM user_relationships r
+    JOIN users u ON u.id = CASE WHEN p_followers THEN r.from_user_id ELSE r.to_user_id END
+    WHERE (CASE WHEN p_followers THEN r.to_user_id ELSE r.from_user_id END) = p_user_id
+      AND r.relationship_type = 'following'
+      AND r.status = 'active'
+      AND u.is_active = TRUE
+      AND (p_viewer_id IS NULL OR NOT blocked_between(p_viewer_id, u.id))
+    ORDER BY r.created_at DESC, u.id
+    LIMIT p_limit OFFSET p_offset;
+$$ LANGUAGE sql STABLE;
+
+-- A page of the accounts p_viewer_id follows that also follow p_target_id, for
+-- "followed by people you know". followed_at is when they followed the target.
+DROP FUNCTION IF EXISTS list_mutual_followers(UUID, UUID, INTEGER, INTEGER);
+CREATE OR REPLACE FUNCTION list_mutual_followers(
+    p_viewer_id UUID,
+    p_target_id UUID,
+    p_limit INTEGER,
+    p_offset INTEGER
+)
+RETURNS TABLE (
+    user_id UUID,
+    username VARCHAR,
+    display_name VARCHAR,
+    profile_picture TEXT,
+    bio VARCHAR,
+    is_verified BOOLEAN,
+    followed_at TIMESTAMP,
+    total_count BIGINT
+) AS $$
+    SELECT u.id, u.username, u.display_name, u.profile_picture, u.bio, u.is_verified,
+           target_follow.created_at, COUNT(*) OVER ()
+    FROM user_relationships viewer_follow
+    JOIN user_relationships target_follow
+      ON target_follow.from_user_id = viewer_follow.to_user_id
+     AND target_follow.to_user_id = p_target_id
+     AND target_follow.relationship_type = 'following'
+     AND target_follow.status = 'active'
+    JOIN users u ON u.id = viewer_follow.to_user_id
+    WHERE viewer_follow.from_user_id = p_viewer_id
+      AND viewer_follow.relationship_type = 'following'
+      AND viewer_follow.status = 'active'
+      AND u.is_active = TRUE
+      AND NOT blocked_between(p_viewer_id, u.id)
+    ORDER BY u.is_verified DESC, target_follow.created_at DESC, u.id
+    LIMIT p_limit OFFSET p_offset;
+$$ LANGUAGE sql STABLE;
+
+-- How p_viewer_id relates to each of p_user_ids: whether they follow them, are
+-- followed by them, or have a follow request waiting. Users with no follow either
+-- way are left out.
+DROP FUNCTION IF EXISTS get_follow_states(UUID, UUID[]);
+CREATE OR REPLACE FUNCTION get_follow_states(p_viewer_id UUID, p_user_ids UUID[])
+RETURNS TABLE (
+    user_id UUID,
+    is_following BOOLEAN,
+    is_follower BOOLEAN,
+    follow_requested BOOLEAN
+) AS $$
+    SELECT other_id,
+           BOOL_OR(outgoing AND status = 'active'),
+           BOOL_OR(NOT outgoing AND status = 'active'),
+           BOOL_OR(outgoing AND status = 'pending')
+    FROM (
+        SELECT to_user_id AS other_id, TRUE AS outgoing, status
+        FROM user_relationships
+        WHERE from_user_id = p_viewer_id AND to_user_id = ANY(p_user_ids)
+          AND relationship_type = 'following'
+        UNION ALL
+        SELECT from_user_id, FALSE, status
+        FROM user_relationships
+        WHERE to_user_id = p_viewer_id AND from_user_id = ANY(p_user_ids)
+          AND relationship_type = 'following'
+    ) follows
+    GROUP BY other_id;
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON FUNCTION list_follows(UUID, UUID, BOOLEAN, INTEGER, INTEGER) IS 'A page of followers or followed accounts, without users blocked either way with the viewer';
+COMMENT ON FUNCTION list_mutual_followers(UUID, UUID, INTEGER, INTEGER) IS 'A page of accounts the viewer follows that follow the target';
+COMMENT ON FUNCTION get_follow_states(UUID, UUID[]) IS 'Follow state between a viewer and each of a set of users';
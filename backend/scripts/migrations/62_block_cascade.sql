@@ -0,0 +1,33 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 62: BLOCKS SEVER RELATIONSHIPS
+-- ============================================================================
+-- Contains: block_user
+-- Dependencies: 02_relationships.sql, 16_post_and_user_moderation.sql
+-- ============================================================================
+
+-- block_user records that p_blocker blocked p_blocked and, in the same transaction,
+-- removes every relationship between the two in either direction: follows, pending
+-- follow requests, connections and collaborations. The delete trigger
+-- (update_relationship_counts) takes active ones off both users' counts. Blocking
+-- someone already blocked still clears anything left between them. Returns whether
+-- the block is new.
+DROP FUNCTION IF EXISTS block_user(UUID, UUID);
+CREATE OR REPLACE FUNCTION block_user(p_blocker UUID, p_blocked UUID)
+RETURNS BOOLEAN AS $$
+DECLARE
+    v_created BOOLEAN;
+BEGIN
+    INSERT INTO blocked_users (blocker_id, blocked_id)
+    VALUES (p_blocker, p_blocked)
+    ON CONFLICT ON CONSTRAINT unique_blocked_user DO NOTHING;
+    v_created := FOUND;
+
+    DELETE FROM user_relationships
+    WHERE (from_user_id = p_blocker AND to_user_id = p_blocked)
+       OR (from_user_id = p_blocked AND to_user_id = p_blocker);
+
+    RETURN v_created;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION block_user(UUID, UUID) IS 'Blocks a user and removes all relationships between the two in one transaction';
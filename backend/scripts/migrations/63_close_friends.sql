@@ -0,0 +1,91 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 63: CLOSE FRIENDS
+-- ============================================================================
+-- Contains: close_friends table, close_friends visibility for posts and statuses,
+--           list_close_friends, block_user clearing close friends
+-- Dependencies: 03_content.sql, 06_statuses.sql, 62_block_cascade.sql
+-- ============================================================================
+
+-- ============================================================================
+-- CLOSE FRIENDS TABLE
+-- Each user's close friends list: the audience of their close_friends posts and
+-- statuses. Membership is one-way and the friend isn't told.
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS close_friends (
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    friend_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    PRIMARY KEY (owner_id, friend_id),
+    CONSTRAINT no_self_close_friend CHECK (owner_id != friend_id)
+);
+
+-- The lists a user is on, to work out which close_friends content they can see
+CREATE INDEX IF NOT EXISTS idx_close_friends_friend_id ON close_friends(friend_id);
+
+-- ============================================================================
+-- VISIBILITY
+-- ============================================================================
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_visibility_check;
+ALTER TABLE posts ADD CONSTRAINT posts_visibility_check
+    CHECK (visibility IN ('public', 'connections', 'private', 'close_friends'));
+
+ALTER TABLE statuses ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'public';
+ALTER TABLE statuses DROP CONSTRAINT IF EXISTS statuses_visibility_check;
+ALTER TABLE statuses ADD CONSTRAINT statuses_visibility_check
+    CHECK (visibility IN ('public', 'close_friends'));
+
+-- ============================================================================
+-- FUNCTIONS
+-- ============================================================================
+
+-- A page of p_owner_id's close friends, most recently added first. total_count is the
+-- size of the whole list.
+DROP FUNCTION IF EXISTS list_close_friends(UUID, INTEGER, INTEGER);
+CREATE OR REPLACE FUNCTION list_close_friends(p_owner_id UUID, p_limit INTEGER, p_offset INTEGER)
+RETURNS TABLE (
+    user_id UUID,
+    username VARCHAR,
+    display_name VARCHAR,
+    profile_picture TEXT,
+    is_verified BOOLEAN,
+    added_at TIMESTAMP,
+    total_count BIGINT
+) AS $$
+    SELECT u.id, u.username, u.display_name, u.profile_picture, u.is_verified,
+           cf.created_at, COUNT(*) OVER ()
+    FROM close_friends cf
+    JOIN users u ON u.id = cf.friend_id
+    WHERE cf.owner_id = p_owner_id
+      AND u.is_active = TRUE
+    ORDER BY cf.created_at DESC, u.id
+    LIMIT p_limit OFFSET p_offset;
+$$ LANGUAGE sql STABLE;
+
+-- block_user (62) now also takes each user off the other's close friends list
+DROP FUNCTION IF EXISTS block_user(UUID, UUID);
+CREATE OR REPLACE FUNCTION block_user(p_blocker UUID, p_blocked UUID)
+RETURNS BOOLEAN AS $$
+DECLARE
+    v_created BOOLEAN;
+BEGIN
+    INSERT INTO blocked_users (blocker_id, blocked_id)
+    VALUES (p_blocker, p_blocked)
+    ON CONFLICT ON CONSTRAINT unique_blocked_user DO NOTHING;
+    v_created := FOUND;
+
+    DELETE FROM user_relationships
+    WHERE (from_user_id = p_blocker AND to_user_id = p_blocked)
+       OR (from_user_id = p_blocked AND to_user_id = p_blocker);
+
+    DELETE FROM close_friends
+    WHERE (owner_id = p_blocker AND friend_id = p_blocked)
+       OR (owner_id = p_blocked AND friend_id = p_blocker);
+
+    RETURN v_created;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON TABLE close_friends IS 'Per-user close friends lists, the audience of close_friends posts and statuses';
+COMMENT ON FUNCTION list_close_friends(UUID, INTEGER, INTEGER) IS 'A page of a user''s close friends';
+COMMENT ON FUNCTION block_user(UUID, UUID) IS 'Blocks a user and removes all relationships and close friends entries between the two in one transaction';
@@ -0,0 +1,78 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 64: STATUS EXPIRY
+-- ============================================================================
+-- Contains: status_summaries table, purge_expired_statuses
+-- Dependencies: 06_statuses.sql
+-- ============================================================================
+
+-- ============================================================================
+-- STATUS SUMMARIES TABLE
+-- What's left of a status once it has expired and been purged: how many people
+-- saw it and how they reacted, for its creator only.
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS status_summaries (
+    status_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status_type VARCHAR(20) NOT NULL,
+    views_count INTEGER NOT NULL DEFAULT 0,
+    reactions_count INTEGER NOT NULL DEFAULT 0,
+    comments_count INTEGER NOT NULL DEFAULT 0,
+    reactions JSONB NOT NULL DEFAULT '{}',  -- Reaction count per emoji
+    created_at TIMESTAMP NOT NULL,
+    expired_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_summaries_user_created ON status_summaries(user_id, created_at DESC);
+
+-- ============================================================================
+-- FUNCTIONS
+-- ============================================================================
+
+-- Permanently delete a batch of expired statuses in one transaction, keeping a
+-- summary of each for its creator. Views, reactions and comments go with ON DELETE
+-- CASCADE. Statuses that haven't expired are skipped. Returns how many statuses were
+-- deleted.
+DROP FUNCTION IF EXISTS purge_expired_statuses(UUID[]);
+CREATE OR REPLACE FUNCTION purge_expired_statuses(p_status_ids UUID[])
+RETURNS INTEGER AS $$
+DECLARE
+    purged INTEGER;
+    doomed UUID[];
+BEGIN
+    SELECT ARRAY_AGG(id) INTO doomed
+    FROM statuses
+    WHERE id = ANY(p_status_ids) AND expires_at <= NOW();
+
+    IF doomed IS NULL THEN
+        RETURN 0;
+    END IF;
+
+    INSERT INTO status_summaries (status_id, user_id, status_type, views_count,
+                                  reactions_count, comments_count, reactions,
+                                  created_at, expired_at)
+    SELECT s.id, s.user_id, s.status_type, COALESCE(s.views_count, 0),
+           COALESCE(s.reactions_count, 0), COALESCE(s.comments_count, 0),
+           COALESCE((
+               SELECT jsonb_object_agg(emoji, total)
+               FROM (
+                   SELECT emoji, COUNT(*) AS total
+                   FROM status_reactions
+                   WHERE status_id = s.id
+                   GROUP BY emoji
+               ) counts
+           ), '{}'),
+           s.created_at, s.expires_at
+    FROM statuses s
+    WHERE s.id = ANY(doomed)
+    ON CONFLICT (status_id) DO NOTHING;
+
+    DELETE FROM statuses WHERE id = ANY(doomed);
+
+    GET DIAGNOSTICS purged = ROW_COUNT;
+    RETURN purged;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON TABLE status_summaries IS 'View and reaction totals of purged statuses, kept for their creators';
+COMMENT ON FUNCTION purge_expired_statuses(UUID[]) IS 'Hard delete expired statuses, archiving a summary of each';
+COMMENT ON COLUMN statuses.expires_at IS 'When the status stops being shown; the purge-expired-statuses job deletes it and its media afterwards';
@@ -0,0 +1,16 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 65: SCHEDULED POSTS
+-- ============================================================================
+-- Contains: scheduled_at on posts and the index the publish job reads
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ;
+
+-- Unpublished, non-draft posts with a time are waiting to be published
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled_due
+ON posts (scheduled_at)
+WHERE scheduled_at IS NOT NULL AND is_published = false AND is_draft = false AND deleted_at IS NULL;
+
+COMMENT ON COLUMN posts.scheduled_at IS 'When a scheduled post is published (UTC); null for posts published on creation';
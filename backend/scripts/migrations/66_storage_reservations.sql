@@ -0,0 +1,38 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 66: STORAGE RESERVATIONS
+-- ============================================================================
+-- Contains: Atomic quota-checked reservation of storage usage before an upload
+-- Dependencies: 19_storage_usage.sql
+-- ============================================================================
+
+-- Adds p_bytes to a user's usage only if the total stays within p_quota, so two
+-- uploads can't both pass a check against the same remaining space. The conditional
+-- UPDATE is re-checked against the latest row when it waits on a concurrent one.
+-- Returns whether the space was reserved and the user's usage afterwards; callers
+-- release a reservation whose upload fails with increment_storage_usage(-p_bytes).
+DROP FUNCTION IF EXISTS reserve_storage_usage(UUID, BIGINT, BIGINT);
+CREATE OR REPLACE FUNCTION reserve_storage_usage(p_user_id UUID, p_bytes BIGINT, p_quota BIGINT)
+RETURNS TABLE (reserved BOOLEAN, used_bytes BIGINT) AS $$
+DECLARE
+    new_total BIGINT;
+BEGIN
+    INSERT INTO storage_usage (user_id, bytes_used, updated_at)
+    VALUES (p_user_id, 0, NOW())
+    ON CONFLICT (user_id) DO NOTHING;
+
+    UPDATE storage_usage u
+    SET bytes_used = u.bytes_used + p_bytes,
+        updated_at = NOW()
+    WHERE u.user_id = p_user_id
+      AND u.bytes_used + p_bytes <= p_quota
+    RETURNING u.bytes_used INTO new_total;
+
+    IF new_total IS NOT NULL THEN
+        RETURN QUERY SELECT TRUE, new_total;
+    ELSE
+        RETURN QUERY SELECT FALSE, u.bytes_used FROM storage_usage u WHERE u.user_id = p_user_id;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION reserve_storage_usage(UUID, BIGINT, BIGINT) IS 'Reserve upload bytes against a user''s storage quota, refusing atomically when they would not fit';
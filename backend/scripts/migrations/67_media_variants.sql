@@ -0,0 +1,19 @@
+-- ============================================================================
+-- HISTEERIA DATABASE - 67: MEDIA VARIANTS
+-- ============================================================================
+-- Contains: The resized variants generated for each uploaded post image
+-- Dependencies: 01_core_schema.sql
+-- ============================================================================
+
+-- Written when an image is uploaded and read when a post using it is created, so
+-- the post's variants come from what the server generated rather than the client
+CREATE TABLE IF NOT EXISTS media_variants (
+    media_url TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    variants JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_variants_user ON media_variants (user_id);
+
+COMMENT ON TABLE media_variants IS 'Resized image URLs keyed by size in pixels, per uploaded post image';
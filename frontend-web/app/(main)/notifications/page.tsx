@@ -28,6 +28,7 @@ export default function NotificationsPage() {
     fetchNotifications,
     loadMore,
     markAllAsRead,
+    markAllSeen,
   } = useNotifications();
 
   // Fetch notifications when category changes
@@ -36,11 +37,10 @@ export default function NotificationsPage() {
     fetchNotifications(category);
   }, [activeCategory, fetchNotifications]);
 
-  // Mobile-first: mark all as read when opening the notifications page
+  // Opening the page clears the badge; notifications stay unread until opened
   useEffect(() => {
-    const category = activeCategory === 'all' ? undefined : activeCategory;
     // Fire and forget; UI will sync via context
-    markAllAsRead(category);
+    markAllSeen();
   // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []);
 
@@ -27,7 +27,7 @@ export default function SessionsSettingsPage() {
     try {
       setLoading(true);
       const token = localStorage.getItem('token');
-      const response = await fetch('/api/proxy/v1/account/sessions', {
+      const response = await fetch('/api/proxy/v1/auth/sessions', {
         headers: { 'Authorization': `Bearer ${token}` },
       });
 
@@ -50,7 +50,7 @@ export default function SessionsSettingsPage() {
     setActionLoading(sessionId);
     try {
       const token = localStorage.getItem('token');
-      const response = await fetch(`/api/proxy/v1/account/sessions/${sessionId}`, {
+      const response = await fetch(`/api/proxy/v1/auth/sessions/${sessionId}`, {
         method: 'DELETE',
         headers: { 'Authorization': `Bearer ${token}` },
       });
@@ -70,19 +70,19 @@ export default function SessionsSettingsPage() {
   };
 
   const handleLogoutAll = async () => {
-    if (!confirm('Logout from all devices? You will be logged out.')) return;
+    if (!confirm('Log out from all other devices?')) return;
 
     setActionLoading('all');
     try {
       const token = localStorage.getItem('token');
-      const response = await fetch('/api/proxy/v1/account/logout-all', {
-        method: 'POST',
+      const response = await fetch('/api/proxy/v1/auth/sessions', {
+        method: 'DELETE',
         headers: { 'Authorization': `Bearer ${token}` },
       });
 
       if (response.ok) {
-        localStorage.removeItem('token');
-        window.location.href = '/auth';
+        setMessage('Logged out of other devices successfully');
+        fetchSessions();
       } else {
         const data = await response.json();
         setMessage(data.message || 'Failed to logout');
@@ -165,7 +165,7 @@ export default function SessionsSettingsPage() {
                               {session.ip_address || 'Unknown IP'}
                             </p>
                             <p className="text-xs text-neutral-500 dark:text-neutral-400 mt-1">
-                              Last active: {new Date(session.created_at).toLocaleString()}
+                              Last active: {new Date(session.last_seen_at || session.created_at).toLocaleString()}
                             </p>
                           </div>
                         </div>
@@ -193,7 +193,7 @@ export default function SessionsSettingsPage() {
                       isLoading={actionLoading === 'all'}
                       className="w-full"
                     >
-                      Logout All Devices
+                      Log Out Other Devices
                     </Button>
                   </div>
                 </div>
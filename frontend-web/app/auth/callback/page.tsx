@@ -60,7 +60,8 @@ function OAuthCallbackContent() {
             headers: {
               'Content-Type': 'application/json',
             },
-            body: JSON.stringify({ code }),
+            // X needs the state back (it seeds PKCE); Apple sends the user's name only once
+            body: JSON.stringify({ code, state, user: searchParams.get('user') || undefined }),
           }
         );
         const data = await response.json();
@@ -0,0 +1,131 @@
+'use client';
+
+import { useEffect, useRef, useState, Suspense } from 'react';
+import { useRouter, useSearchParams } from 'next/navigation';
+import Image from 'next/image';
+import { AuthErrorBoundary } from '@/components/auth/AuthErrorBoundary';
+
+const API_BASE_URL = '/api/proxy';
+
+function MagicLinkContent() {
+  const [status, setStatus] = useState<'loading' | 'success' | 'error'>('loading');
+  const [message, setMessage] = useState('Logging you in...');
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  // Links are single-use, so the verify call must not repeat on re-render
+  const verified = useRef(false);
+
+  useEffect(() => {
+    if (verified.current) return;
+    verified.current = true;
+
+    const verifyLink = async () => {
+      const token = searchParams.get('token');
+      if (!token) {
+        setStatus('error');
+        setMessage('Invalid login link');
+        setTimeout(() => router.push('/auth'), 3000);
+        return;
+      }
+
+      try {
+        const response = await fetch(
+          `${API_BASE_URL}/v1/auth/magic-link/verify?token=${encodeURIComponent(token)}`
+        );
+        const data = await response.json();
+
+        if (data.success && data.token) {
+          const { storeToken } = await import('@/lib/auth/tokenManager');
+          storeToken(data.token, data.refresh_token);
+          setStatus('success');
+          setMessage('Successfully logged in!');
+          setTimeout(() => router.push('/'), 1500);
+        } else if (data.two_factor_required) {
+          setStatus('error');
+          setMessage('This account uses two-factor authentication. Please sign in with your password.');
+          setTimeout(() => router.push('/auth'), 3000);
+        } else {
+          setStatus('error');
+          setMessage(data.message || 'This login link is invalid or has expired');
+          setTimeout(() => router.push('/auth'), 3000);
+        }
+      } catch (err) {
+        setStatus('error');
+        setMessage('Failed to log in');
+        setTimeout(() => router.push('/auth'), 3000);
+      }
+    };
+
+    verifyLink();
+  }, [searchParams, router]);
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-white">
+      <div className="w-full max-w-md px-8 py-12 text-center">
+        {/* Logo and Brand */}
+        <div className="mb-12 flex flex-col items-center gap-3">
+          <Image
+            src="/assets/u.png"
+            alt="UpVista"
+            width={70}
+            height={70}
+            className="object-contain"
+          />
+          <h2 className="text-2xl font-bold tracking-tight text-gray-900">
+            <span className="bg-gradient-to-r from-purple-600 to-blue-600 bg-clip-text text-transparent">
+              Histeeria
+            </span>
+          </h2>
+        </div>
+
+        {status === 'loading' && (
+          <div className="space-y-6">
+            <div className="mx-auto h-12 w-12 animate-spin rounded-full border-4 border-gray-200 border-t-blue-600"></div>
+            <p className="text-base text-gray-600">{message}</p>
+          </div>
+        )}
+
+        {status === 'success' && (
+          <div className="rounded-2xl border-2 border-green-400 bg-green-50 p-6">
+            <p className="text-base font-semibold text-green-700">{message}</p>
+            <p className="mt-2 text-sm text-green-600">Redirecting to your account...</p>
+          </div>
+        )}
+
+        {status === 'error' && (
+          <div className="space-y-4">
+            <div className="rounded-2xl border-2 border-red-400 bg-red-50 p-6">
+              <p className="text-base font-semibold text-red-700">{message}</p>
+            </div>
+            <p className="text-sm text-gray-600">Redirecting to sign in...</p>
+          </div>
+        )}
+
+        {/* Footer Links */}
+        <div className="mt-10 flex justify-center gap-4 text-xs text-gray-500">
+          <a href="#" className="cursor-pointer transition-colors hover:text-gray-700 hover:underline">
+            Terms of Use
+          </a>
+          <span className="text-gray-300">|</span>
+          <a href="#" className="cursor-pointer transition-colors hover:text-gray-700 hover:underline">
+            Privacy Policy
+          </a>
+        </div>
+      </div>
+    </div>
+  );
+}
+
+export default function MagicLinkPage() {
+  return (
+    <AuthErrorBoundary>
+      <Suspense fallback={
+        <div className="flex min-h-screen items-center justify-center bg-white">
+          <div className="h-12 w-12 animate-spin rounded-full border-4 border-gray-200 border-t-blue-600"></div>
+        </div>
+      }>
+        <MagicLinkContent />
+      </Suspense>
+    </AuthErrorBoundary>
+  );
+}
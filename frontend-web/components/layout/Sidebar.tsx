@@ -64,7 +64,7 @@ export function Sidebar() {
   const pathname = usePathname();
   const { theme, toggleTheme } = useTheme();
   const { user } = useUser();
-  const { unreadCount } = useNotifications();
+  const { unseenCount } = useNotifications();
   const { unreadCount: unreadMessages } = useUnreadMessages();
   const [showMore, setShowMore] = useState(false);
 
@@ -73,7 +73,7 @@ export function Sidebar() {
   // Add dynamic badges
   const navigation = navigationBase.map(item => {
     if (item.name === 'Notifications') {
-      return { ...item, badge: unreadCount > 0 ? unreadCount : undefined };
+      return { ...item, badge: unseenCount > 0 ? unseenCount : undefined };
     }
     if (item.name === 'Messages') {
       return { ...item, badge: unreadMessages > 0 ? unreadMessages : undefined };
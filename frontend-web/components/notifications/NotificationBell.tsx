@@ -2,7 +2,7 @@
 
 /**
  * Notification Bell Component
- * Displays bell icon with unread count badge
+ * Displays bell icon with a badge of unseen notifications
  * Navigates to /notifications page (Instagram-style)
  */
 
@@ -13,7 +13,7 @@ import { useNotifications } from '@/lib/contexts/NotificationContext';
 export default function NotificationBell() {
   const router = useRouter();
   const pathname = usePathname();
-  const { unreadCount, isConnected } = useNotifications();
+  const { unseenCount, isConnected } = useNotifications();
 
   const handleClick = () => {
     router.push('/notifications');
@@ -43,10 +43,10 @@ export default function NotificationBell() {
         fill={isActive ? 'currentColor' : 'none'}
       />
       
-      {/* Unread Badge */}
-      {unreadCount > 0 && (
+      {/* Unseen Badge */}
+      {unseenCount > 0 && (
         <span className="absolute -top-1 -right-1 min-w-[18px] h-[18px] flex items-center justify-center bg-red-500 text-white text-xs font-semibold rounded-full px-1">
-          {unreadCount > 99 ? '99+' : unreadCount}
+          {unseenCount > 99 ? '99+' : unseenCount}
         </span>
       )}
 
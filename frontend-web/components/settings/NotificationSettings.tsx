@@ -15,6 +15,7 @@ export default function NotificationSettings() {
   const [isLoading, setIsLoading] = useState(true);
   const [isSaving, setIsSaving] = useState(false);
   const [successMessage, setSuccessMessage] = useState('');
+  const [mute, setMute] = useState<boolean | undefined>(undefined);
 
   useEffect(() => {
     loadPreferences();
@@ -38,15 +39,19 @@ export default function NotificationSettings() {
     setSuccessMessage('');
 
     try {
-      await notificationAPI.updatePreferences({
+      const response = await notificationAPI.updatePreferences({
         email_enabled: preferences.email_enabled,
         email_types: preferences.email_types,
         email_frequency: preferences.email_frequency,
         in_app_enabled: preferences.in_app_enabled,
         push_enabled: preferences.push_enabled,
+        push_types: preferences.push_types,
         inline_actions_enabled: preferences.inline_actions_enabled,
         categories_enabled: preferences.categories_enabled,
+        mute,
       });
+      setPreferences(response.preferences);
+      setMute(undefined);
 
       setSuccessMessage('Preferences saved successfully');
       setTimeout(() => setSuccessMessage(''), 3000);
@@ -78,6 +83,8 @@ export default function NotificationSettings() {
     );
   }
 
+  const isMuted = mute ?? (!!preferences.muted_until && new Date(preferences.muted_until) > new Date());
+
   return (
     <div className="space-y-5">
       {/* Success Message */}
@@ -137,6 +144,83 @@ export default function NotificationSettings() {
         </div>
       </Card>
 
+      {/* Push Notifications */}
+      <Card variant="solid">
+        <div className="p-6">
+          <h3 className="text-lg font-semibold text-neutral-900 dark:text-neutral-50 mb-4">
+            Push Notifications
+          </h3>
+
+          <div className="space-y-4">
+            <div className="flex items-center justify-between">
+              <div>
+                <p className="font-medium text-neutral-700 dark:text-neutral-300">Enable push notifications</p>
+                <p className="text-sm text-neutral-500 dark:text-neutral-400">
+                  Receive notifications on your devices
+                </p>
+              </div>
+              <label className="relative inline-block w-12 h-6 cursor-pointer">
+                <input
+                  type="checkbox"
+                  checked={preferences.push_enabled}
+                  onChange={(e) => setPreferences({ ...preferences, push_enabled: e.target.checked })}
+                  className="sr-only peer"
+                />
+                <div className="w-full h-full bg-neutral-300 dark:bg-neutral-700 peer-checked:bg-brand-purple-600 dark:peer-checked:bg-brand-purple-600 rounded-full peer-focus:ring-2 peer-focus:ring-brand-purple-300 dark:peer-focus:ring-brand-purple-800 transition-colors" />
+                <div className="absolute top-0.5 left-0.5 w-5 h-5 bg-white dark:bg-white rounded-full peer-checked:translate-x-6 transition-transform shadow-sm" />
+              </label>
+            </div>
+
+            <div className="flex items-center justify-between">
+              <div>
+                <p className="font-medium text-neutral-700 dark:text-neutral-300">Mute for 24 hours</p>
+                <p className="text-sm text-neutral-500 dark:text-neutral-400">
+                  {isMuted && preferences.muted_until
+                    ? `Push and email are paused until ${new Date(preferences.muted_until).toLocaleString()}`
+                    : 'Pause push and email notifications for a day'}
+                </p>
+              </div>
+              <label className="relative inline-block w-12 h-6 cursor-pointer">
+                <input
+                  type="checkbox"
+                  checked={isMuted}
+                  onChange={(e) => setMute(e.target.checked)}
+                  className="sr-only peer"
+                />
+                <div className="w-full h-full bg-neutral-300 dark:bg-neutral-700 peer-checked:bg-brand-purple-600 dark:peer-checked:bg-brand-purple-600 rounded-full peer-focus:ring-2 peer-focus:ring-brand-purple-300 dark:peer-focus:ring-brand-purple-800 transition-colors" />
+                <div className="absolute top-0.5 left-0.5 w-5 h-5 bg-white dark:bg-white rounded-full peer-checked:translate-x-6 transition-transform shadow-sm" />
+              </label>
+            </div>
+
+            {preferences.push_enabled && Object.keys(preferences.push_types).length > 0 && (
+              <div>
+                <p className="text-sm font-medium text-neutral-700 dark:text-neutral-300 mb-3">
+                  Which types to push:
+                </p>
+                <div className="space-y-2">
+                  {Object.entries(preferences.push_types).map(([type, enabled]) => (
+                    <label key={type} className="flex items-center gap-3 p-3 hover:bg-neutral-50 dark:hover:bg-neutral-800/50 rounded-lg cursor-pointer">
+                      <input
+                        type="checkbox"
+                        checked={enabled}
+                        onChange={(e) => setPreferences({
+                          ...preferences,
+                          push_types: { ...preferences.push_types, [type]: e.target.checked }
+                        })}
+                        className="w-4 h-4 text-brand-purple-600 border-neutral-300 dark:border-neutral-600 rounded focus:ring-brand-purple-500"
+                      />
+                      <span className="text-sm text-neutral-700 dark:text-neutral-300 capitalize">
+                        {type.replace(/_/g, ' ')}
+                      </span>
+                    </label>
+                  ))}
+                </div>
+              </div>
+            )}
+          </div>
+        </div>
+      </Card>
+
       {/* Email Notifications */}
       <Card variant="solid">
         <div className="p-6">
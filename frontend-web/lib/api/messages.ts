@@ -19,7 +19,7 @@ export interface Message {
   video_duration?: number;
   video_width?: number;
   video_height?: number;
-  status: 'sent' | 'delivered' | 'read';
+  status: 'sent' | 'delivered' | 'read' | 'failed'; // failed: the server gave up delivering it
   delivered_at?: string;
   read_at?: string;
   reply_to_id?: string;
@@ -36,10 +36,15 @@ export interface Message {
   // Forward feature
   forwarded_from_id?: string;
   is_forwarded?: boolean;
+  // Disappearing messages: when the server deletes it (starred messages are kept)
+  expires_at?: string;
+  // Voice messages: peak amplitude of each waveform bar, 0-1
+  waveform?: number[];
   // Relations
   sender?: User;
   reply_to?: Message;
   reactions?: MessageReaction[];
+  reaction_counts?: ReactionSummary; // Set when listing a conversation's messages
   is_mine?: boolean;
   is_starred?: boolean;
   // Client-side only fields for reliability
@@ -53,10 +58,47 @@ export interface Message {
   decryption_error?: boolean; // Client-side flag for decryption failures
 }
 
+export interface ConversationMember {
+  conversation_id: string;
+  user_id: string;
+  role: 'admin' | 'member';
+  unread_count: number;
+  joined_at: string;
+  left_at?: string;
+  delivered_at?: string;
+  read_at?: string;
+  user?: User;
+}
+
+export interface MessageReceipt {
+  user_id: string;
+  status: 'sent' | 'delivered' | 'read';
+  delivered_at?: string;
+  read_at?: string;
+}
+
+export interface MessageSearchHit extends Message {
+  rank: number;
+  snippet: string; // Matched terms wrapped in <mark></mark>; the rest is raw message text, escape it before rendering
+  before?: Message; // Neighbouring messages, for showing the match in context
+  after?: Message;
+}
+
+export interface MessageSearchFilters {
+  senderId?: string;
+  from?: string; // RFC 3339 time or YYYY-MM-DD
+  to?: string; // Exclusive; a YYYY-MM-DD date includes that day
+}
+
 export interface Conversation {
   id: string;
   participant1_id: string;
   participant2_id: string;
+  is_group: boolean;
+  name?: string;
+  created_by?: string;
+  message_ttl_seconds: number | null; // Disappearing messages timer; null when off
+  members?: ConversationMember[];
   last_message_content?: string;
   last_message_sender_id?: string;
   last_message_at?: string;
@@ -82,6 +124,38 @@ export interface MessageReaction {
   user?: User;
 }
 
+export interface ReactionSummary {
+  emojis: EmojiReactionCount[]; // Most used first, at most 6
+  reactor_count: number; // Everyone who reacted, including with emojis not listed
+}
+
+export interface EmojiReactionCount {
+  emoji: string;
+  count: number;
+  reacted_by_me: boolean;
+}
+
+export interface ScheduledMessage {
+  id: string;
+  conversation_id: string;
+  sender_id: string;
+  message: {
+    content: string;
+    encrypted_content?: string;
+    iv?: string;
+    message_type: Message['message_type'];
+    attachment_url?: string;
+    attachment_name?: string;
+    reply_to_id?: string;
+  };
+  send_at: string;
+  status: 'pending' | 'sending' | 'sent' | 'cancelled' | 'failed';
+  message_id?: string; // Set once sent
+  failure_reason?: string;
+  created_at: string;
+  updated_at: string;
+}
+
 export interface User {
   id: string;
   username: string;
@@ -139,6 +213,15 @@ async function fetchAPI(endpoint: string, options: RequestInit = {}) {
   return data;
 }
 
+/** Builds a message search query string */
+function searchParams(query: string, limit: number, offset: number, filters: MessageSearchFilters = {}) {
+  const params = new URLSearchParams({ q: query, limit: String(limit), offset: String(offset) });
+  if (filters.senderId) params.set('sender_id', filters.senderId);
+  if (filters.from) params.set('from', filters.from);
+  if (filters.to) params.set('to', filters.to);
+  return params.toString();
+}
+
 export const messagesAPI = {
   // ==================== CONVERSATIONS ====================
 
@@ -163,6 +246,54 @@ export const messagesAPI = {
     return fetchAPI(`/conversations/start/${userId}`, { method: 'POST' });
   },
 
+  /**
+   * Create a group conversation
+   */
+  async createGroup(name: string, memberIds: string[]) {
+    return fetchAPI('/conversations/group', {
+      method: 'POST',
+      body: JSON.stringify({ name, member_ids: memberIds }),
+    });
+  },
+
+  /**
+   * Rename a group (admins only)
+   */
+  async renameGroup(conversationId: string, name: string) {
+    return fetchAPI(`/conversations/${conversationId}`, {
+      method: 'PATCH',
+      body: JSON.stringify({ name }),
+    });
+  },
+
+  /**
+   * Turn disappearing messages on (1 minute to 90 days) or off with 0.
+   * In groups only admins can change it.
+   */
+  async setDisappearingMessages(conversationId: string, ttlSeconds: number) {
+    return fetchAPI(`/conversations/${conversationId}/disappearing`, {
+      method: 'PUT',
+      body: JSON.stringify({ ttl_seconds: ttlSeconds }),
+    });
+  },
+
+  /**
+   * Add members to a group (admins only)
+   */
+  async addGroupMembers(conversationId: string, userIds: string[]) {
+    return fetchAPI(`/conversations/${conversationId}/members`, {
+      method: 'POST',
+      body: JSON.stringify({ user_ids: userIds }),
+    });
+  },
+
+  /**
+   * Remove a member from a group; pass your own ID to leave
+   */
+  async removeGroupMember(conversationId: string, userId: string) {
+    return fetchAPI(`/conversations/${conversationId}/members/${userId}`, { method: 'DELETE' });
+  },
+
   /**
    * Get total unread message count
    */
@@ -227,7 +358,8 @@ export const messagesAPI = {
     attachmentSize: number,
     attachmentType: string,
     messageType: 'image' | 'audio' | 'file' | 'video',
-    replyToId?: string
+    replyToId?: string,
+    waveform?: number[] // Audio: the waveform uploadAudio returned
   ) {
     return fetchAPI(`/conversations/${conversationId}/messages`, {
       method: 'POST',
@@ -239,10 +371,74 @@ export const messagesAPI = {
         attachment_size: attachmentSize,
         attachment_type: attachmentType,
         reply_to_id: replyToId,
+        waveform,
       }),
     });
   },
 
+  // ==================== SCHEDULED MESSAGES ====================
+
+  /**
+   * Send a text message later. sendAt must be in the future and within a year;
+   * the message goes out within a minute of it.
+   */
+  async scheduleMessage(
+    conversationId: string,
+    content: string,
+    sendAt: Date,
+    replyToId?: string,
+    iv?: string
+  ): Promise<{ success: boolean; scheduled_message: ScheduledMessage }> {
+    const payload: any = {
+      message_type: 'text',
+      reply_to_id: replyToId,
+      send_at: sendAt.toISOString(),
+    };
+    if (iv && content) {
+      payload.encrypted_content = content;
+      payload.iv = iv;
+    } else {
+      payload.content = content;
+    }
+
+    return fetchAPI(`/conversations/${conversationId}/messages`, {
+      method: 'POST',
+      body: JSON.stringify(payload),
+    });
+  },
+
+  /**
+   * Get your scheduled messages in a conversation that haven't been sent, soonest first
+   */
+  async getScheduledMessages(conversationId: string): Promise<{ success: boolean; scheduled_messages: ScheduledMessage[] }> {
+    return fetchAPI(`/conversations/${conversationId}/scheduled`);
+  },
+
+  /**
+   * Change the text or time of a scheduled message before it sends
+   */
+  async editScheduledMessage(
+    scheduledId: string,
+    changes: { content?: string; encryptedContent?: string; iv?: string; sendAt?: Date }
+  ): Promise<{ success: boolean; scheduled_message: ScheduledMessage }> {
+    return fetchAPI(`/messages/scheduled/${scheduledId}`, {
+      method: 'PATCH',
+      body: JSON.stringify({
+        content: changes.content,
+        encrypted_content: changes.encryptedContent,
+        iv: changes.iv,
+        send_at: changes.sendAt?.toISOString(),
+      }),
+    });
+  },
+
+  /**
+   * Cancel a scheduled message before it sends
+   */
+  async cancelScheduledMessage(scheduledId: string) {
+    return fetchAPI(`/messages/scheduled/${scheduledId}`, { method: 'DELETE' });
+  },
+
   /**
    * Mark all messages in a conversation as read
    */
@@ -258,10 +454,10 @@ export const messagesAPI = {
   },
 
   /**
-   * Search messages by content
+   * Full-text search across all your conversations, best matches first
    */
-  async searchMessages(query: string, limit = 20, offset = 0) {
-    return fetchAPI(`/messages/search?q=${encodeURIComponent(query)}&limit=${limit}&offset=${offset}`);
+  async searchMessages(query: string, limit = 20, offset = 0, filters?: MessageSearchFilters) {
+    return fetchAPI(`/messages/search?${searchParams(query, limit, offset, filters)}`);
   },
 
   // ==================== MEDIA UPLOADS ====================
@@ -581,6 +777,13 @@ export const messagesAPI = {
     return fetchAPI(`/messages/${messageId}/edit-history`);
   },
 
+  /**
+   * Get per-recipient delivery and read state of your own message
+   */
+  async getMessageReceipts(messageId: string): Promise<{ success: boolean; receipts: MessageReceipt[] }> {
+    return fetchAPI(`/messages/${messageId}/receipts`);
+  },
+
   // ==================== FORWARD MESSAGES ====================
 
   /**
@@ -596,16 +799,17 @@ export const messagesAPI = {
   // ==================== SEARCH MESSAGES ====================
 
   /**
-   * Search messages in a conversation
+   * Full-text search within a conversation, best matches first
    */
-  async searchConversationMessages(conversationId: string, query: string, limit = 50, offset = 0) {
-    return fetchAPI(`/conversations/${conversationId}/search?q=${encodeURIComponent(query)}&limit=${limit}&offset=${offset}`);
+  async searchConversationMessages(conversationId: string, query: string, limit = 50, offset = 0, filters?: MessageSearchFilters) {
+    return fetchAPI(`/conversations/${conversationId}/search?${searchParams(query, limit, offset, filters)}`);
   },
 
   // ==================== TYPING INDICATORS ====================
 
   /**
-   * Start typing indicator
+   * Start typing indicator. It stops on its own after `expires_in` seconds, so call
+   * again while the user keeps typing.
    */
   async startTyping(conversationId: string, isRecording: boolean = false) {
     return fetchAPI(`/conversations/${conversationId}/typing/start`, { 
@@ -22,6 +22,7 @@ export interface Notification {
   actor?: NotificationActor;
   action_url?: string;
   is_read: boolean;
+  is_seen: boolean;
   is_actionable: boolean;
   action_type?: string;
   action_taken: boolean;
@@ -38,11 +39,13 @@ export interface NotificationListResponse {
   unread: number;
   limit: number;
   offset: number;
+  next_cursor: string | null;
 }
 
 export interface NotificationCountResponse {
   success: boolean;
   total: number;
+  unseen: number;
   category_counts: Record<string, number>;
 }
 
@@ -53,8 +56,10 @@ export interface NotificationPreferences {
   email_frequency: 'instant' | 'daily' | 'weekly' | 'never';
   in_app_enabled: boolean;
   push_enabled: boolean;
+  push_types: Record<string, boolean>;
   inline_actions_enabled: boolean;
   categories_enabled: Record<string, boolean>;
+  muted_until?: string;
   updated_at: string;
 }
 
@@ -64,15 +69,21 @@ export const notificationAPI = {
    */
   async getNotifications(params?: {
     category?: string;
+    type?: string | string[];
     unread?: boolean;
+    unreadFirst?: boolean;
     limit?: number;
     offset?: number;
+    cursor?: string;
   }): Promise<NotificationListResponse> {
     const queryParams = new URLSearchParams();
     if (params?.category) queryParams.set('category', params.category);
+    if (params?.type) queryParams.set('type', [params.type].flat().join(','));
     if (params?.unread !== undefined) queryParams.set('unread', params.unread.toString());
+    if (params?.unreadFirst) queryParams.set('unread_first', 'true');
     if (params?.limit) queryParams.set('limit', params.limit.toString());
     if (params?.offset) queryParams.set('offset', params.offset.toString());
+    if (params?.cursor) queryParams.set('cursor', params.cursor);
 
     try {
       const response = await fetch(
@@ -156,7 +167,7 @@ export const notificationAPI = {
   /**
    * Mark all notifications as read
    */
-  async markAllAsRead(category?: string): Promise<{ success: boolean; message: string }> {
+  async markAllAsRead(category?: string): Promise<{ success: boolean; message: string; updated: number }> {
     const response = await fetch(
       `${API_BASE_URL}/notifications/read-all`,
       {
@@ -176,6 +187,28 @@ export const notificationAPI = {
     return response.json();
   },
 
+  /**
+   * Mark all notifications as seen (clears the badge, leaves them unread)
+   */
+  async markAllSeen(): Promise<{ success: boolean; message: string; updated: number }> {
+    const response = await fetch(
+      `${API_BASE_URL}/notifications/seen`,
+      {
+        method: 'POST',
+        headers: {
+          'Authorization': `Bearer ${localStorage.getItem('token')}`,
+          'Content-Type': 'application/json',
+        },
+      }
+    );
+
+    if (!response.ok) {
+      throw new Error('Failed to mark notifications as seen');
+    }
+
+    return response.json();
+  },
+
   /**
    * Delete notification
    */
@@ -249,8 +282,8 @@ export const notificationAPI = {
    * Update notification preferences
    */
   async updatePreferences(
-    preferences: Partial<Omit<NotificationPreferences, 'user_id' | 'updated_at'>>
-  ): Promise<{ success: boolean; message: string }> {
+    preferences: Partial<Omit<NotificationPreferences, 'user_id' | 'updated_at' | 'muted_until'>> & { mute?: boolean }
+  ): Promise<{ success: boolean; message: string; preferences: NotificationPreferences }> {
     const response = await fetch(
       `${API_BASE_URL}/notifications/preferences`,
       {
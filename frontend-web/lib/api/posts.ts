@@ -35,6 +35,7 @@ export interface Post {
   article?: Article;
   is_liked: boolean;
   is_saved: boolean;
+  is_shared: boolean;
   top_comments?: Comment[];
   hashtags?: string[];
 }
@@ -187,7 +188,7 @@ export interface PostResponse {
 export interface PostsResponse {
   success: boolean;
   posts: Post[];
-  total: number;
+  total?: number; // Omitted by count-less reads such as feeds; use has_more to paginate
   page: number;
   limit: number;
   has_more: boolean;
@@ -325,13 +326,20 @@ export const postsAPI = {
   },
   
   // Share post
-  sharePost: async (postId: string, comment?: string): Promise<{success: boolean; message: string}> => {
+  sharePost: async (postId: string, comment?: string): Promise<{success: boolean; message: string; shares_count: number; changed: boolean}> => {
     return fetchAPI(`/posts/${postId}/share`, {
       method: 'POST',
       body: JSON.stringify({ comment }),
     });
   },
   
+  // Unshare post
+  unsharePost: async (postId: string): Promise<{success: boolean; message: string; shares_count: number; changed: boolean}> => {
+    return fetchAPI(`/posts/${postId}/share`, {
+      method: 'DELETE',
+    });
+  },
+  
   // Save post
   savePost: async (postId: string, collection = 'Saved'): Promise<{success: boolean; message: string}> => {
     return fetchAPI(`/posts/${postId}/save`, {
@@ -13,6 +13,7 @@ import NotificationWebSocket from '../websocket/NotificationWebSocket';
 interface NotificationContextType {
   notifications: Notification[];
   unreadCount: number;
+  unseenCount: number;
   categoryCounts: Record<string, number>;
   isConnected: boolean;
   isLoading: boolean;
@@ -22,6 +23,7 @@ interface NotificationContextType {
   loadMore: () => Promise<void>;
   markAsRead: (id: string) => Promise<void>;
   markAllAsRead: (category?: string) => Promise<void>;
+  markAllSeen: () => Promise<void>;
   deleteNotification: (id: string) => Promise<void>;
   refreshCount: () => Promise<void>;
 }
@@ -31,6 +33,7 @@ const NotificationContext = createContext<NotificationContextType | undefined>(u
 export function NotificationProvider({ children }: { children: React.ReactNode }) {
   const [notifications, setNotifications] = useState<Notification[]>([]);
   const [unreadCount, setUnreadCount] = useState(0);
+  const [unseenCount, setUnseenCount] = useState(0);
   const [categoryCounts, setCategoryCounts] = useState<Record<string, number>>({});
   const [isConnected, setIsConnected] = useState(false);
   const [isLoading, setIsLoading] = useState(false);
@@ -40,7 +43,7 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
   
   const wsClient = useRef<NotificationWebSocket | null>(null);
   const limit = 20;
-  const offsetRef = useRef(0);
+  const cursorRef = useRef<string | null>(null);
 
   // Initialize WebSocket connection
   useEffect(() => {
@@ -84,9 +87,10 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
     });
 
     // Listen for count updates
-    const unsubscribeCountUpdate = wsClient.current.on('count_update', (data: { total: number; category_counts: Record<string, number> }) => {
+    const unsubscribeCountUpdate = wsClient.current.on('count_update', (data: { total: number; unseen: number; category_counts: Record<string, number> }) => {
       console.log('[NotificationContext] Count update received:', data);
       setUnreadCount(data.total);
+      setUnseenCount(data.unseen);
       setCategoryCounts(data.category_counts);
     });
 
@@ -119,19 +123,19 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
     setIsLoading(true);
     setCurrentCategory(category);
     setCurrentUnreadFilter(unread);
-    offsetRef.current = 0;
+    cursorRef.current = null;
 
     try {
       const response = await notificationAPI.getNotifications({
         category,
         unread,
         limit,
-        offset: 0,
       });
 
       setNotifications(response.notifications);
       setUnreadCount(response.unread);
-      setHasMore(response.notifications.length >= limit);
+      cursorRef.current = response.next_cursor;
+      setHasMore(response.next_cursor !== null);
     } catch (error) {
       console.error('[NotificationContext] Failed to fetch notifications:', error);
     } finally {
@@ -140,21 +144,21 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
   }, []);
 
   const loadMore = useCallback(async () => {
-    if (isLoading || !hasMore) return;
+    if (isLoading || !hasMore || !cursorRef.current) return;
 
     setIsLoading(true);
-    offsetRef.current += limit;
 
     try {
       const response = await notificationAPI.getNotifications({
         category: currentCategory,
         unread: currentUnreadFilter,
         limit,
-        offset: offsetRef.current,
+        cursor: cursorRef.current,
       });
 
       setNotifications(prev => [...prev, ...response.notifications]);
-      setHasMore(response.notifications.length >= limit);
+      cursorRef.current = response.next_cursor;
+      setHasMore(response.next_cursor !== null);
     } catch (error) {
       console.error('[NotificationContext] Failed to load more notifications:', error);
     } finally {
@@ -167,7 +171,7 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
       await notificationAPI.markAsRead(id);
       
       setNotifications(prev =>
-        prev.map(n => n.id === id ? { ...n, is_read: true } : n)
+        prev.map(n => n.id === id ? { ...n, is_read: true, is_seen: true } : n)
       );
       
       refreshCount();
@@ -182,7 +186,7 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
       
       setNotifications(prev =>
         prev.map(n => 
-          (!category || n.category === category) ? { ...n, is_read: true } : n
+          (!category || n.category === category) ? { ...n, is_read: true, is_seen: true } : n
         )
       );
       
@@ -192,6 +196,17 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
     }
   }, []);
 
+  const markAllSeen = useCallback(async () => {
+    try {
+      await notificationAPI.markAllSeen();
+
+      setNotifications(prev => prev.map(n => ({ ...n, is_seen: true })));
+      setUnseenCount(0);
+    } catch (error) {
+      console.error('[NotificationContext] Failed to mark all as seen:', error);
+    }
+  }, []);
+
   const deleteNotification = useCallback(async (id: string) => {
     try {
       await notificationAPI.deleteNotification(id);
@@ -208,6 +223,7 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
     try {
       const response = await notificationAPI.getUnreadCount();
       setUnreadCount(response.total);
+      setUnseenCount(response.unseen);
       setCategoryCounts(response.category_counts);
     } catch (error) {
       console.error('[NotificationContext] Failed to refresh count:', error);
@@ -217,6 +233,7 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
   const value: NotificationContextType = {
     notifications,
     unreadCount,
+    unseenCount,
     categoryCounts,
     isConnected,
     isLoading,
@@ -224,6 +241,7 @@ export function NotificationProvider({ children }: { children: React.ReactNode }
     loadMore,
     markAsRead,
     markAllAsRead,
+    markAllSeen,
     deleteNotification,
     refreshCount,
   };
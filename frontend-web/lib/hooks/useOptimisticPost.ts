@@ -35,6 +35,7 @@ export function useOptimisticPost() {
       shares_count: 0,
       is_liked: false,
       is_saved: false,
+      is_shared: false,
       is_pinned: false,
       views_count: 0,
       saves_count: 0,